@@ -0,0 +1,1644 @@
+package main
+
+// Developed by PowerShield, as an alternative to Ginkgo and Gomega
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GinkgoWriter receives the suite's progress and failure output
+var GinkgoWriter io.Writer = os.Stdout
+
+// GinkgoTestingT is the part of *testing.T RunSpecs reports to
+type GinkgoTestingT interface {
+	Fail()
+}
+
+// SpecState is the outcome of a spec
+type SpecState int
+
+const (
+	SpecStateInvalid SpecState = iota
+	SpecStatePassed
+	SpecStateFailed
+	SpecStatePanicked
+	SpecStateSkipped
+	SpecStatePending
+)
+
+func (s SpecState) String() string {
+	switch s {
+	case SpecStatePassed:
+		return "passed"
+	case SpecStateFailed:
+		return "failed"
+	case SpecStatePanicked:
+		return "panicked"
+	case SpecStateSkipped:
+		return "skipped"
+	case SpecStatePending:
+		return "pending"
+	default:
+		return "invalid"
+	}
+}
+
+// Failure describes why a spec did not pass
+type Failure struct {
+	Message  string
+	Location string
+}
+
+// SpecReport is the result of one spec
+type SpecReport struct {
+	ContainerHierarchyTexts []string
+	LeafNodeText            string
+	LeafNodeLocation        string
+	State                   SpecState
+	Failure                 Failure
+	RunTime                 time.Duration
+}
+
+// FullText joins the spec's container texts and its own
+func (r SpecReport) FullText() string {
+	return strings.Join(append(append([]string{}, r.ContainerHierarchyTexts...), r.LeafNodeText), " ")
+}
+
+// Failed reports whether the spec failed or panicked
+func (r SpecReport) Failed() bool {
+	return r.State == SpecStateFailed || r.State == SpecStatePanicked
+}
+
+// Report is the result of a suite, passed to ReportAfterSuite callbacks
+type Report struct {
+	SuiteDescription string
+	SuiteSucceeded   bool
+	// SuiteHasProgrammaticFocus is set when focused specs kept others
+	// from running
+	SuiteHasProgrammaticFocus bool
+	SpecReports               []SpecReport
+	RunTime                   time.Duration
+}
+
+// Count returns how many specs ended in state
+func (r Report) Count(state SpecState) int {
+	n := 0
+	for _, spec := range r.SpecReports {
+		if spec.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+type nodeKind int
+
+const (
+	containerNode nodeKind = iota
+	itNode
+)
+
+// node is a container or a spec in the tree Describe and It build
+type node struct {
+	kind     nodeKind
+	text     string
+	location string
+	focused  bool
+	pending  bool
+	body     func()
+	parent   *node
+	children []*node
+
+	beforeEach     []func()
+	justBeforeEach []func()
+	afterEach      []func()
+	justAfterEach  []func()
+}
+
+// suite holds the spec tree being built and the spec being run
+type suite struct {
+	root    *node
+	current *node
+	running bool
+
+	beforeSuite      []func()
+	afterSuite       []func()
+	reportAfterSuite []func(Report)
+
+	mu      sync.Mutex
+	spec    *SpecReport
+	failure *Failure
+}
+
+var global = newSuite()
+
+func newSuite() *suite {
+	root := &node{kind: containerNode}
+	return &suite{root: root, current: root}
+}
+
+// failurePanic and skipPanic stop a spec from Fail and Skip
+type failurePanic struct {
+	failure Failure
+}
+
+type skipPanic struct {
+	failure Failure
+}
+
+// codeLocation returns the file and line skip frames above its caller
+func codeLocation(skip int) string {
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		return filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	return "unknown location"
+}
+
+// pushContainer adds a container and runs its body to collect what it
+// holds
+func pushContainer(text string, focused, pending bool, body func()) bool {
+	if global.running {
+		Fail(fmt.Sprintf("Describe %q was called while specs were running; containers must be declared when the tree is built", text), 2)
+	}
+	n := &node{
+		kind:     containerNode,
+		text:     text,
+		location: codeLocation(2),
+		focused:  focused,
+		pending:  pending,
+		parent:   global.current,
+	}
+	global.current.children = append(global.current.children, n)
+
+	global.current = n
+	defer func() { global.current = n.parent }()
+	body()
+	return true
+}
+
+func pushIt(text string, focused, pending bool, body func()) bool {
+	if global.running {
+		Fail(fmt.Sprintf("It %q was called while specs were running; specs must be declared when the tree is built", text), 2)
+	}
+	global.current.children = append(global.current.children, &node{
+		kind:     itNode,
+		text:     text,
+		location: codeLocation(2),
+		focused:  focused,
+		pending:  pending || body == nil,
+		body:     body,
+		parent:   global.current,
+	})
+	return true
+}
+
+// Describe groups specs about one thing. The body runs straight away to
+// declare what the container holds.
+func Describe(text string, body func()) bool { return pushContainer(text, false, false, body) }
+
+// FDescribe is Describe, focused: only focused specs run
+func FDescribe(text string, body func()) bool { return pushContainer(text, true, false, body) }
+
+// PDescribe is Describe, pending: its specs are reported but not run
+func PDescribe(text string, body func()) bool { return pushContainer(text, false, true, body) }
+
+// XDescribe is PDescribe
+func XDescribe(text string, body func()) bool { return pushContainer(text, false, true, body) }
+
+// Context is Describe, for the circumstances specs run under
+func Context(text string, body func()) bool { return pushContainer(text, false, false, body) }
+
+// FContext is Context, focused
+func FContext(text string, body func()) bool { return pushContainer(text, true, false, body) }
+
+// PContext is Context, pending
+func PContext(text string, body func()) bool { return pushContainer(text, false, true, body) }
+
+// XContext is PContext
+func XContext(text string, body func()) bool { return pushContainer(text, false, true, body) }
+
+// When is Context, reading "when <text>"
+func When(text string, body func()) bool { return pushContainer("when "+text, false, false, body) }
+
+// It declares a spec. A spec with no body is pending.
+func It(text string, body func()) bool { return pushIt(text, false, false, body) }
+
+// FIt is It, focused
+func FIt(text string, body func()) bool { return pushIt(text, true, false, body) }
+
+// PIt is It, pending
+func PIt(text string, body ...func()) bool { return pushIt(text, false, true, nil) }
+
+// XIt is PIt
+func XIt(text string, body ...func()) bool { return pushIt(text, false, true, nil) }
+
+// Specify is It
+func Specify(text string, body func()) bool { return pushIt(text, false, false, body) }
+
+func setupNode(name string) *node {
+	if global.running {
+		Fail(name+" was called while specs were running; setup must be declared when the tree is built", 2)
+	}
+	return global.current
+}
+
+// BeforeEach runs body before each spec in the container, outer
+// containers first
+func BeforeEach(body func()) bool {
+	n := setupNode("BeforeEach")
+	n.beforeEach = append(n.beforeEach, body)
+	return true
+}
+
+// JustBeforeEach runs body after every BeforeEach, right before each spec
+func JustBeforeEach(body func()) bool {
+	n := setupNode("JustBeforeEach")
+	n.justBeforeEach = append(n.justBeforeEach, body)
+	return true
+}
+
+// AfterEach runs body after each spec in the container, inner containers
+// first, even when the spec failed
+func AfterEach(body func()) bool {
+	n := setupNode("AfterEach")
+	n.afterEach = append(n.afterEach, body)
+	return true
+}
+
+// JustAfterEach runs body right after each spec, before any AfterEach
+func JustAfterEach(body func()) bool {
+	n := setupNode("JustAfterEach")
+	n.justAfterEach = append(n.justAfterEach, body)
+	return true
+}
+
+// BeforeSuite runs body once before any spec. If it fails, no spec runs.
+func BeforeSuite(body func()) bool {
+	global.beforeSuite = append(global.beforeSuite, body)
+	return true
+}
+
+// AfterSuite runs body once after every spec
+func AfterSuite(body func()) bool {
+	global.afterSuite = append(global.afterSuite, body)
+	return true
+}
+
+// ReportAfterSuite passes the suite's Report to body once it has run
+func ReportAfterSuite(text string, body func(Report)) bool {
+	global.reportAfterSuite = append(global.reportAfterSuite, body)
+	return true
+}
+
+// TableEntry is one row of a DescribeTable
+type TableEntry struct {
+	description string
+	parameters  []interface{}
+	focused     bool
+	pending     bool
+}
+
+// Entry is a row of a table: a description and the parameters the table's
+// body is called with. An empty description is made from the parameters.
+func Entry(description string, parameters ...interface{}) TableEntry {
+	return TableEntry{description: description, parameters: parameters}
+}
+
+// FEntry is Entry, focused
+func FEntry(description string, parameters ...interface{}) TableEntry {
+	return TableEntry{description: description, parameters: parameters, focused: true}
+}
+
+// PEntry is Entry, pending
+func PEntry(description string, parameters ...interface{}) TableEntry {
+	return TableEntry{description: description, parameters: parameters, pending: true}
+}
+
+// DescribeTable declares a container with one spec per entry, each
+// calling body with the entry's parameters
+func DescribeTable(description string, body interface{}, entries ...TableEntry) bool {
+	fn := reflect.ValueOf(body)
+	if fn.Kind() != reflect.Func {
+		panic(fmt.Sprintf("DescribeTable %q needs a function body, got %T", description, body))
+	}
+	return pushContainer(description, false, false, func() {
+		for _, entry := range entries {
+			entry := entry
+			text := entry.description
+			if text == "" {
+				parts := make([]string, len(entry.parameters))
+				for i, p := range entry.parameters {
+					parts[i] = fmt.Sprintf("%v", p)
+				}
+				text = "Entry: " + strings.Join(parts, ", ")
+			}
+			pushIt(text, entry.focused, entry.pending, func() {
+				args := make([]reflect.Value, len(entry.parameters))
+				for i, p := range entry.parameters {
+					if p == nil {
+						args[i] = reflect.Zero(fn.Type().In(i))
+					} else {
+						args[i] = reflect.ValueOf(p)
+					}
+				}
+				fn.Call(args)
+			})
+		}
+	})
+}
+
+// By documents a step of a long spec
+func By(text string, callback ...func()) {
+	fmt.Fprintf(GinkgoWriter, "STEP: %s\n", text)
+	for _, cb := range callback {
+		cb()
+	}
+}
+
+// Fail fails the running spec with message and stops it. callerSkip moves
+// the reported location up the stack from Fail's caller.
+func Fail(message string, callerSkip ...int) {
+	skip := 0
+	if len(callerSkip) > 0 {
+		skip = callerSkip[0]
+	}
+	panic(failurePanic{Failure{Message: message, Location: codeLocation(skip + 1)}})
+}
+
+// Skip stops the running spec and reports it skipped
+func Skip(message string, callerSkip ...int) {
+	skip := 0
+	if len(callerSkip) > 0 {
+		skip = callerSkip[0]
+	}
+	panic(skipPanic{Failure{Message: message, Location: codeLocation(skip + 1)}})
+}
+
+// GinkgoRecover records a failure from a goroutine a spec started. Defer
+// it at the top of the goroutine; otherwise Fail there crashes the
+// program.
+func GinkgoRecover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	f, ok := r.(failurePanic)
+	if !ok {
+		f = failurePanic{Failure{Message: fmt.Sprintf("Test Panicked\n%v", r), Location: codeLocation(2)}}
+	}
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	if global.failure == nil {
+		global.failure = &f.failure
+	}
+}
+
+// CurrentSpecReport returns the report of the running spec so far
+func CurrentSpecReport() SpecReport {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	if global.spec == nil {
+		return SpecReport{}
+	}
+	return *global.spec
+}
+
+// spec is a leaf of the tree with the containers above it, starting with
+// the root, which holds top-level setup
+type spec struct {
+	leaf       *node
+	containers []*node
+	focused    bool
+	pending    bool
+}
+
+func collectSpecs(n *node, containers []*node, focused, pending bool, specs []spec) []spec {
+	for _, child := range n.children {
+		f, p := focused || child.focused, pending || child.pending
+		if child.kind == itNode {
+			specs = append(specs, spec{
+				leaf:       child,
+				containers: append([]*node{}, containers...),
+				focused:    f,
+				pending:    p,
+			})
+			continue
+		}
+		specs = collectSpecs(child, append(containers, child), f, p, specs)
+	}
+	return specs
+}
+
+// RunSpecs runs the specs declared so far and reports to t. When any spec
+// is focused, only focused specs run. The tree is cleared afterwards, so
+// a program can build and run several suites.
+func RunSpecs(t GinkgoTestingT, description string) bool {
+	s := global
+	defer func() { global = newSuite() }()
+
+	start := time.Now()
+	report := Report{SuiteDescription: description}
+	specs := collectSpecs(s.root, []*node{s.root}, false, false, nil)
+	for _, sp := range specs {
+		if sp.focused && !sp.pending {
+			report.SuiteHasProgrammaticFocus = true
+		}
+	}
+
+	fmt.Fprintf(GinkgoWriter, "Running Suite: %s\n", description)
+	s.running = true
+	defer func() { s.running = false }()
+
+	// A failing BeforeSuite fails every spec
+	var suiteFailure *Failure
+	for _, body := range s.beforeSuite {
+		if state, failure := s.runNode(body); state != SpecStatePassed {
+			suiteFailure = &failure
+			fmt.Fprintf(GinkgoWriter, "[BeforeSuite] %s\n%s\n%s\n", strings.ToUpper(state.String()), failure.Message, failure.Location)
+			break
+		}
+	}
+
+	ran := 0
+	for _, sp := range specs {
+		r := SpecReport{LeafNodeText: sp.leaf.text, LeafNodeLocation: sp.leaf.location}
+		for _, c := range sp.containers[1:] {
+			r.ContainerHierarchyTexts = append(r.ContainerHierarchyTexts, c.text)
+		}
+		switch {
+		case sp.pending:
+			r.State = SpecStatePending
+		case report.SuiteHasProgrammaticFocus && !sp.focused:
+			r.State = SpecStateSkipped
+		case suiteFailure != nil:
+			r.State = SpecStateSkipped
+			r.Failure = Failure{Message: "BeforeSuite failed", Location: suiteFailure.Location}
+		default:
+			ran++
+			r = s.runSpec(sp, r)
+		}
+		report.SpecReports = append(report.SpecReports, r)
+
+		if r.Failed() {
+			fmt.Fprintf(GinkgoWriter, "[%s] %s\n%s\n%s\n", strings.ToUpper(r.State.String()), r.FullText(), r.Failure.Message, r.Failure.Location)
+		}
+	}
+
+	for _, body := range s.afterSuite {
+		if state, failure := s.runNode(body); state != SpecStatePassed && suiteFailure == nil {
+			suiteFailure = &failure
+			fmt.Fprintf(GinkgoWriter, "[AfterSuite] %s\n%s\n%s\n", strings.ToUpper(state.String()), failure.Message, failure.Location)
+		}
+	}
+
+	failed := report.Count(SpecStateFailed) + report.Count(SpecStatePanicked)
+	report.SuiteSucceeded = failed == 0 && suiteFailure == nil
+	report.RunTime = time.Since(start)
+
+	verdict := "SUCCESS!"
+	if !report.SuiteSucceeded {
+		verdict = "FAIL!"
+	}
+	fmt.Fprintf(GinkgoWriter, "Ran %d of %d Specs in %.3f seconds\n", ran, len(specs), report.RunTime.Seconds())
+	fmt.Fprintf(GinkgoWriter, "%s -- %d Passed | %d Failed | %d Pending | %d Skipped\n", verdict,
+		report.Count(SpecStatePassed), failed, report.Count(SpecStatePending), report.Count(SpecStateSkipped))
+	if report.SuiteHasProgrammaticFocus {
+		fmt.Fprintln(GinkgoWriter, "Detected Programmatic Focus")
+	}
+
+	for _, body := range s.reportAfterSuite {
+		body(report)
+	}
+	if !report.SuiteSucceeded {
+		t.Fail()
+	}
+	return report.SuiteSucceeded
+}
+
+// runSpec runs a spec's setup, body and teardown. Setup stops at the first
+// failure; teardown always runs.
+func (s *suite) runSpec(sp spec, r SpecReport) SpecReport {
+	start := time.Now()
+	r.State = SpecStatePassed
+	s.mu.Lock()
+	s.spec = &r
+	s.failure = nil
+	s.mu.Unlock()
+
+	record := func(state SpecState, failure Failure) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// A failure from a goroutine counts if the node itself passed
+		if state == SpecStatePassed && s.failure != nil {
+			state, failure = SpecStateFailed, *s.failure
+		}
+		s.failure = nil
+		if r.State == SpecStatePassed && state != SpecStatePassed {
+			r.State, r.Failure = state, failure
+		}
+	}
+
+	var steps []func()
+	for _, c := range sp.containers {
+		steps = append(steps, c.beforeEach...)
+	}
+	for _, c := range sp.containers {
+		steps = append(steps, c.justBeforeEach...)
+	}
+	steps = append(steps, sp.leaf.body)
+	for _, step := range steps {
+		record(s.runNode(step))
+		if r.State != SpecStatePassed {
+			break
+		}
+	}
+
+	var teardown []func()
+	for i := len(sp.containers) - 1; i >= 0; i-- {
+		teardown = append(teardown, sp.containers[i].justAfterEach...)
+	}
+	for i := len(sp.containers) - 1; i >= 0; i-- {
+		teardown = append(teardown, sp.containers[i].afterEach...)
+	}
+	for _, step := range teardown {
+		record(s.runNode(step))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.RunTime = time.Since(start)
+	s.spec = nil
+	return r
+}
+
+// runNode runs body, turning Fail, Skip and panics into a state
+func (s *suite) runNode(body func()) (state SpecState, failure Failure) {
+	defer func() {
+		switch r := recover().(type) {
+		case nil:
+		case failurePanic:
+			state, failure = SpecStateFailed, r.failure
+		case skipPanic:
+			state, failure = SpecStateSkipped, r.failure
+		default:
+			state, failure = SpecStatePanicked, Failure{Message: fmt.Sprintf("Test Panicked\n%v", r), Location: panicLocation()}
+		}
+	}()
+	body()
+	return SpecStatePassed, Failure{}
+}
+
+// panicLocation finds the frame that panicked, skipping the runtime
+func panicLocation() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown location"
+		}
+	}
+}
+
+// GinkgoT returns a TestingT for libraries written against *testing.T,
+// such as the testify emulator's assertions. Errorf and Fatalf fail the
+// spec.
+func GinkgoT() *GinkgoTInterface {
+	return &GinkgoTInterface{}
+}
+
+// GinkgoTInterface reports through Fail
+type GinkgoTInterface struct{}
+
+func (GinkgoTInterface) Errorf(format string, args ...interface{}) {
+	Fail(fmt.Sprintf(format, args...), 1)
+}
+
+func (GinkgoTInterface) Fatalf(format string, args ...interface{}) {
+	Fail(fmt.Sprintf(format, args...), 1)
+}
+
+func (GinkgoTInterface) FailNow() {
+	Fail("FailNow called", 1)
+}
+
+func (GinkgoTInterface) Fail() {
+	Fail("Fail called", 1)
+}
+
+func (GinkgoTInterface) Failed() bool {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	return global.failure != nil || global.spec != nil && global.spec.Failed()
+}
+
+func (GinkgoTInterface) Helper() {}
+
+func (GinkgoTInterface) Logf(format string, args ...interface{}) {
+	fmt.Fprintf(GinkgoWriter, format+"\n", args...)
+}
+
+// Comparer holds the comparisons Equal, BeNil, BeEmpty, HaveLen,
+// ContainElement and BeNumerically are built on. The testify emulator's
+// Comparer implements it, as can any type with these methods.
+type Comparer interface {
+	ObjectsAreEqual(expected, actual interface{}) bool
+	IsNil(object interface{}) bool
+	IsEmpty(object interface{}) bool
+	Len(object interface{}) int
+	Contains(haystack, needle interface{}) bool
+	Compare(e1, e2 interface{}) (int, bool)
+}
+
+// Comparisons is the Comparer matchers use
+var Comparisons Comparer = defaultComparer{}
+
+// defaultComparer is a small Comparer for when no other is set
+type defaultComparer struct{}
+
+func (defaultComparer) ObjectsAreEqual(expected, actual interface{}) bool {
+	if expected == nil || actual == nil {
+		return expected == actual
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func (defaultComparer) IsNil(object interface{}) bool {
+	if object == nil {
+		return true
+	}
+	v := reflect.ValueOf(object)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (c defaultComparer) IsEmpty(object interface{}) bool {
+	return object == nil || c.Len(object) == 0
+}
+
+func (defaultComparer) Len(object interface{}) int {
+	v := reflect.ValueOf(object)
+	switch v.Kind() {
+	case reflect.Array, reflect.Chan, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len()
+	}
+	return 0
+}
+
+func (c defaultComparer) Contains(haystack, needle interface{}) bool {
+	v := reflect.ValueOf(haystack)
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := needle.(string)
+		return ok && strings.Contains(v.String(), s)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if c.ObjectsAreEqual(v.Index(i).Interface(), needle) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if c.ObjectsAreEqual(v.MapIndex(key).Interface(), needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (defaultComparer) Compare(e1, e2 interface{}) (int, bool) {
+	v1, v2 := reflect.ValueOf(e1), reflect.ValueOf(e2)
+	if v1.Kind() != v2.Kind() {
+		return 0, false
+	}
+	sign := func(less, greater bool) (int, bool) {
+		switch {
+		case less:
+			return -1, true
+		case greater:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch v1.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(v1.Int() < v2.Int(), v1.Int() > v2.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sign(v1.Uint() < v2.Uint(), v1.Uint() > v2.Uint())
+	case reflect.Float32, reflect.Float64:
+		return sign(v1.Float() < v2.Float(), v1.Float() > v2.Float())
+	case reflect.String:
+		return sign(v1.String() < v2.String(), v1.String() > v2.String())
+	}
+	return 0, false
+}
+
+// GomegaMatcher decides whether an actual value is acceptable and
+// explains why not
+type GomegaMatcher interface {
+	Match(actual interface{}) (success bool, err error)
+	FailureMessage(actual interface{}) string
+	NegatedFailureMessage(actual interface{}) string
+}
+
+// GomegaFailHandler is called with the message of a failed assertion
+type GomegaFailHandler func(message string, callerSkip ...int)
+
+// failHandler is Fail until RegisterFailHandler replaces it
+var failHandler GomegaFailHandler = Fail
+
+// RegisterFailHandler sets what Expect calls when an assertion fails
+func RegisterFailHandler(handler GomegaFailHandler) {
+	failHandler = handler
+}
+
+// Assertion checks one actual value against matchers
+type Assertion struct {
+	actual interface{}
+	extra  []interface{}
+	fail   GomegaFailHandler
+}
+
+// Expect starts an assertion about actual. Extra values, such as the
+// error a function returned alongside actual, must be nil or zero.
+func Expect(actual interface{}, extra ...interface{}) Assertion {
+	return Assertion{actual: actual, extra: extra, fail: failHandler}
+}
+
+// To asserts that matcher matches
+func (a Assertion) To(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, true, optionalDescription...)
+}
+
+// ToNot asserts that matcher does not match
+func (a Assertion) ToNot(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, false, optionalDescription...)
+}
+
+// NotTo is ToNot
+func (a Assertion) NotTo(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, false, optionalDescription...)
+}
+
+// Should is To
+func (a Assertion) Should(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, true, optionalDescription...)
+}
+
+// ShouldNot is ToNot
+func (a Assertion) ShouldNot(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, false, optionalDescription...)
+}
+
+func (a Assertion) match(matcher GomegaMatcher, desired bool, optionalDescription ...interface{}) bool {
+	// The location reported is the caller of To or Should
+	const skip = 2
+
+	for i, extra := range a.extra {
+		if extra != nil && !reflect.ValueOf(extra).IsZero() {
+			a.fail(describe(optionalDescription)+fmt.Sprintf("Unexpected non-nil/non-zero argument at index %d:\n\t<%T>: %v", i+1, extra, extra), skip)
+			return false
+		}
+	}
+
+	message, ok := check(matcher, a.actual, desired)
+	if !ok {
+		a.fail(describe(optionalDescription)+message, skip)
+	}
+	return ok
+}
+
+// check runs a matcher and returns the failure message if the result is
+// not the desired one
+func check(matcher GomegaMatcher, actual interface{}, desired bool) (string, bool) {
+	success, err := matcher.Match(actual)
+	if err != nil {
+		return err.Error(), false
+	}
+	if success == desired {
+		return "", true
+	}
+	if desired {
+		return matcher.FailureMessage(actual), false
+	}
+	return matcher.NegatedFailureMessage(actual), false
+}
+
+// describe formats an assertion's optional description as its first line
+func describe(optionalDescription []interface{}) string {
+	switch len(optionalDescription) {
+	case 0:
+		return ""
+	case 1:
+		if fn, ok := optionalDescription[0].(func() string); ok {
+			return fn() + "\n"
+		}
+		return fmt.Sprintf("%v", optionalDescription[0]) + "\n"
+	default:
+		return fmt.Sprintf(fmt.Sprintf("%v", optionalDescription[0]), optionalDescription[1:]...) + "\n"
+	}
+}
+
+const (
+	defaultEventuallyTimeout    = time.Second
+	defaultEventuallyPolling    = 10 * time.Millisecond
+	defaultConsistentlyDuration = 100 * time.Millisecond
+	defaultConsistentlyPolling  = 10 * time.Millisecond
+)
+
+// The kinds of AsyncAssertion
+const (
+	asyncAssertionEventually   = "Eventually"
+	asyncAssertionConsistently = "Consistently"
+)
+
+// AsyncAssertion polls a value until a matcher matches, or for as long as
+// it keeps matching
+type AsyncAssertion struct {
+	kind    string
+	actual  interface{}
+	timeout time.Duration
+	polling time.Duration
+	fail    GomegaFailHandler
+}
+
+// Eventually polls actual, a function or a value, until a matcher matches
+// or a second passes. The timeout and polling interval can be given as
+// durations or strings such as "2s".
+func Eventually(actual interface{}, intervals ...interface{}) AsyncAssertion {
+	return newAsyncAssertion(asyncAssertionEventually, actual, failHandler, defaultEventuallyTimeout, defaultEventuallyPolling, intervals)
+}
+
+// Consistently polls actual for 100ms and fails if a matcher ever stops
+// matching
+func Consistently(actual interface{}, intervals ...interface{}) AsyncAssertion {
+	return newAsyncAssertion(asyncAssertionConsistently, actual, failHandler, defaultConsistentlyDuration, defaultConsistentlyPolling, intervals)
+}
+
+func newAsyncAssertion(kind string, actual interface{}, fail GomegaFailHandler, timeout, polling time.Duration, intervals []interface{}) AsyncAssertion {
+	a := AsyncAssertion{kind: kind, actual: actual, timeout: timeout, polling: polling, fail: fail}
+	if len(intervals) > 0 {
+		a.timeout = toDuration(intervals[0])
+	}
+	if len(intervals) > 1 {
+		a.polling = toDuration(intervals[1])
+	}
+	return a
+}
+
+func toDuration(v interface{}) time.Duration {
+	switch d := v.(type) {
+	case time.Duration:
+		return d
+	case string:
+		parsed, err := time.ParseDuration(d)
+		if err != nil {
+			panic(fmt.Sprintf("%q is not a valid duration", d))
+		}
+		return parsed
+	case int:
+		return time.Duration(d) * time.Second
+	case float64:
+		return time.Duration(d * float64(time.Second))
+	}
+	panic(fmt.Sprintf("%v is not a valid interval; use a time.Duration or a string such as \"1s\"", v))
+}
+
+// WithTimeout sets how long to poll
+func (a AsyncAssertion) WithTimeout(timeout time.Duration) AsyncAssertion {
+	a.timeout = timeout
+	return a
+}
+
+// WithPolling sets how often to poll
+func (a AsyncAssertion) WithPolling(interval time.Duration) AsyncAssertion {
+	a.polling = interval
+	return a
+}
+
+// Should asserts that matcher matches: eventually, or the whole time
+func (a AsyncAssertion) Should(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, true, optionalDescription...)
+}
+
+// ShouldNot asserts that matcher does not match: eventually, or the whole
+// time
+func (a AsyncAssertion) ShouldNot(matcher GomegaMatcher, optionalDescription ...interface{}) bool {
+	return a.match(matcher, false, optionalDescription...)
+}
+
+// poll returns the current value of actual. A function is called, and an
+// error it returns after its first result counts as a mismatch.
+func (a AsyncAssertion) poll() (interface{}, error) {
+	fn := reflect.ValueOf(a.actual)
+	if fn.Kind() != reflect.Func {
+		return a.actual, nil
+	}
+	if fn.Type().NumIn() != 0 || fn.Type().NumOut() == 0 {
+		return nil, fmt.Errorf("%s needs a function with no arguments and at least one result, got %T", a.kind, a.actual)
+	}
+	out := fn.Call(nil)
+	for i, extra := range out[1:] {
+		if !extra.IsZero() {
+			return nil, fmt.Errorf("%s function returned a non-zero value at index %d: %v", a.kind, i+1, extra.Interface())
+		}
+	}
+	return out[0].Interface(), nil
+}
+
+func (a AsyncAssertion) match(matcher GomegaMatcher, desired bool, optionalDescription ...interface{}) bool {
+	const skip = 2
+
+	deadline := time.Now().Add(a.timeout)
+	var message string
+	for {
+		actual, err := a.poll()
+		ok := false
+		if err != nil {
+			message = err.Error()
+		} else {
+			message, ok = check(matcher, actual, desired)
+		}
+
+		switch a.kind {
+		case asyncAssertionEventually:
+			if ok {
+				return true
+			}
+			if time.Now().After(deadline) {
+				a.fail(describe(optionalDescription)+fmt.Sprintf("Timed out after %.3fs.\n%s", a.timeout.Seconds(), message), skip)
+				return false
+			}
+		case asyncAssertionConsistently:
+			if !ok {
+				a.fail(describe(optionalDescription)+fmt.Sprintf("Failed after %.3fs.\n%s", a.timeout.Seconds()-time.Until(deadline).Seconds(), message), skip)
+				return false
+			}
+			if time.Now().After(deadline) {
+				return true
+			}
+		}
+		time.Sleep(a.polling)
+	}
+}
+
+// GomegaTestingT is the part of *testing.T a WithT reports to
+type GomegaTestingT interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// WithT makes assertions in plain Go tests, outside Ginkgo specs
+type WithT struct {
+	t GomegaTestingT
+}
+
+// NewWithT returns assertions that fail t
+func NewWithT(t GomegaTestingT) *WithT {
+	return &WithT{t: t}
+}
+
+func (g *WithT) fail(message string, callerSkip ...int) {
+	g.t.Helper()
+	g.t.Fatalf("\n%s", message)
+}
+
+// Expect is the package's Expect, failing the WithT's test
+func (g *WithT) Expect(actual interface{}, extra ...interface{}) Assertion {
+	return Assertion{actual: actual, extra: extra, fail: g.fail}
+}
+
+// Eventually is the package's Eventually, failing the WithT's test
+func (g *WithT) Eventually(actual interface{}, intervals ...interface{}) AsyncAssertion {
+	return newAsyncAssertion(asyncAssertionEventually, actual, g.fail, defaultEventuallyTimeout, defaultEventuallyPolling, intervals)
+}
+
+// Consistently is the package's Consistently, failing the WithT's test
+func (g *WithT) Consistently(actual interface{}, intervals ...interface{}) AsyncAssertion {
+	return newAsyncAssertion(asyncAssertionConsistently, actual, g.fail, defaultConsistentlyDuration, defaultConsistentlyPolling, intervals)
+}
+
+// formatObject shows a value with its type, as failure messages do
+func formatObject(v interface{}) string {
+	if v == nil {
+		return "<nil>: nil"
+	}
+	if err, ok := v.(error); ok {
+		return fmt.Sprintf("<%T>: %s", v, err.Error())
+	}
+	return fmt.Sprintf("<%T>: %v", v, v)
+}
+
+// message builds "Expected <actual> <text> <expected>"
+func message(actual interface{}, text string, expected ...interface{}) string {
+	m := "Expected\n    " + formatObject(actual) + "\n" + text
+	if len(expected) > 0 {
+		m += "\n    " + formatObject(expected[0])
+	}
+	return m
+}
+
+// simpleMatcher is a matcher made from a function and the words for its
+// failure messages
+type simpleMatcher struct {
+	match    func(actual interface{}) (bool, error)
+	text     string
+	expected []interface{}
+}
+
+func (m simpleMatcher) Match(actual interface{}) (bool, error) { return m.match(actual) }
+
+func (m simpleMatcher) FailureMessage(actual interface{}) string {
+	return message(actual, "to "+m.text, m.expected...)
+}
+
+func (m simpleMatcher) NegatedFailureMessage(actual interface{}) string {
+	return message(actual, "not to "+m.text, m.expected...)
+}
+
+func newMatcher(text string, match func(actual interface{}) (bool, error), expected ...interface{}) GomegaMatcher {
+	return simpleMatcher{match: match, text: text, expected: expected}
+}
+
+// Equal matches a value deeply equal to expected, of the same type
+func Equal(expected interface{}) GomegaMatcher {
+	return newMatcher("equal", func(actual interface{}) (bool, error) {
+		if actual == nil && expected == nil {
+			return false, errors.New("Refusing to compare <nil> to <nil>.\nBe explicit and use BeNil() instead.  This is to avoid mistakes where both sides of an assertion are erroneously uninitialized.")
+		}
+		return Comparisons.ObjectsAreEqual(expected, actual), nil
+	}, expected)
+}
+
+// BeEquivalentTo matches a value equal to expected once converted to the
+// actual value's type, so int64(5) is equivalent to 5
+func BeEquivalentTo(expected interface{}) GomegaMatcher {
+	return newMatcher("be equivalent to", func(actual interface{}) (bool, error) {
+		if actual == nil && expected == nil {
+			return false, errors.New("Both actual and expected must not be nil.")
+		}
+		if actual == nil || expected == nil {
+			return false, nil
+		}
+		want := reflect.ValueOf(expected)
+		if !want.Type().ConvertibleTo(reflect.TypeOf(actual)) {
+			return false, nil
+		}
+		return Comparisons.ObjectsAreEqual(want.Convert(reflect.TypeOf(actual)).Interface(), actual), nil
+	}, expected)
+}
+
+// BeIdenticalTo matches the same value by ==, such as the same pointer
+func BeIdenticalTo(expected interface{}) GomegaMatcher {
+	return newMatcher("be identical to", func(actual interface{}) (success bool, err error) {
+		defer func() {
+			if recover() != nil {
+				success, err = false, fmt.Errorf("%T values cannot be compared with ==", actual)
+			}
+		}()
+		return actual == expected, nil
+	}, expected)
+}
+
+// BeNil matches nil and nil pointers, maps, slices, channels and funcs
+func BeNil() GomegaMatcher {
+	return newMatcher("be nil", func(actual interface{}) (bool, error) {
+		return Comparisons.IsNil(actual), nil
+	})
+}
+
+// BeTrue matches true
+func BeTrue() GomegaMatcher {
+	return newMatcher("be true", func(actual interface{}) (bool, error) {
+		b, ok := actual.(bool)
+		if !ok {
+			return false, fmt.Errorf("Expected a boolean.  Got:\n    %s", formatObject(actual))
+		}
+		return b, nil
+	})
+}
+
+// BeFalse matches false
+func BeFalse() GomegaMatcher {
+	return newMatcher("be false", func(actual interface{}) (bool, error) {
+		b, ok := actual.(bool)
+		if !ok {
+			return false, fmt.Errorf("Expected a boolean.  Got:\n    %s", formatObject(actual))
+		}
+		return !b, nil
+	})
+}
+
+// BeZero matches nil or the zero value of the actual value's type
+func BeZero() GomegaMatcher {
+	return newMatcher("be zero-valued", func(actual interface{}) (bool, error) {
+		return actual == nil || reflect.ValueOf(actual).IsZero(), nil
+	})
+}
+
+func lengthOf(actual interface{}, matcher string) (int, error) {
+	switch reflect.ValueOf(actual).Kind() {
+	case reflect.Array, reflect.Chan, reflect.Map, reflect.Slice, reflect.String:
+		return Comparisons.Len(actual), nil
+	}
+	return 0, fmt.Errorf("%s matcher expects a string/array/map/channel/slice.  Got:\n    %s", matcher, formatObject(actual))
+}
+
+// BeEmpty matches a string, array, map, channel or slice with no elements
+func BeEmpty() GomegaMatcher {
+	return newMatcher("be empty", func(actual interface{}) (bool, error) {
+		if _, err := lengthOf(actual, "BeEmpty"); err != nil {
+			return false, err
+		}
+		return Comparisons.IsEmpty(actual), nil
+	})
+}
+
+// HaveLen matches a string, array, map, channel or slice of length count
+func HaveLen(count int) GomegaMatcher {
+	return newMatcher(fmt.Sprintf("have length %d", count), func(actual interface{}) (bool, error) {
+		n, err := lengthOf(actual, "HaveLen")
+		return n == count, err
+	})
+}
+
+// ContainElement matches an array, slice or map holding element. element
+// can be a matcher.
+func ContainElement(element interface{}) GomegaMatcher {
+	return newMatcher("contain element matching", func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Map:
+		default:
+			return false, fmt.Errorf("ContainElement matcher expects an array/slice/map.  Got:\n    %s", formatObject(actual))
+		}
+		m, ok := element.(GomegaMatcher)
+		if !ok {
+			return Comparisons.Contains(actual, element), nil
+		}
+		for _, item := range elements(v) {
+			if success, _ := m.Match(item); success {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, element)
+}
+
+// elements returns the values of an array, slice or map
+func elements(v reflect.Value) []interface{} {
+	var items []interface{}
+	if v.Kind() == reflect.Map {
+		for _, key := range v.MapKeys() {
+			items = append(items, v.MapIndex(key).Interface())
+		}
+		return items
+	}
+	for i := 0; i < v.Len(); i++ {
+		items = append(items, v.Index(i).Interface())
+	}
+	return items
+}
+
+// ConsistOf matches an array, slice or map holding exactly the elements
+// given, in any order. Elements can be matchers; a single slice argument
+// gives the elements.
+func ConsistOf(elems ...interface{}) GomegaMatcher {
+	if len(elems) == 1 {
+		if v := reflect.ValueOf(elems[0]); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			elems = elements(v)
+		}
+	}
+	return newMatcher("consist of", func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice, reflect.Map:
+		default:
+			return false, fmt.Errorf("ConsistOf matcher expects an array/slice/map.  Got:\n    %s", formatObject(actual))
+		}
+		items := elements(v)
+		if len(items) != len(elems) {
+			return false, nil
+		}
+		used := make([]bool, len(items))
+	outer:
+		for _, want := range elems {
+			m, ok := want.(GomegaMatcher)
+			if !ok {
+				m = Equal(want)
+			}
+			for i, item := range items {
+				if !used[i] {
+					if success, _ := m.Match(item); success {
+						used[i] = true
+						continue outer
+					}
+				}
+			}
+			return false, nil
+		}
+		return true, nil
+	}, elems)
+}
+
+func stringOf(actual interface{}, matcher string) (string, error) {
+	switch s := actual.(type) {
+	case string:
+		return s, nil
+	case []byte:
+		return string(s), nil
+	case error:
+		return s.Error(), nil
+	case fmt.Stringer:
+		return s.String(), nil
+	}
+	return "", fmt.Errorf("%s matcher requires a string or stringer.  Got:\n    %s", matcher, formatObject(actual))
+}
+
+// formatted applies fmt.Sprintf when args are given
+func formatted(format string, args []interface{}) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// ContainSubstring matches a string containing substr, formatted with
+// args if any are given
+func ContainSubstring(substr string, args ...interface{}) GomegaMatcher {
+	substr = formatted(substr, args)
+	return newMatcher("contain substring", func(actual interface{}) (bool, error) {
+		s, err := stringOf(actual, "ContainSubstring")
+		return strings.Contains(s, substr), err
+	}, substr)
+}
+
+// HavePrefix matches a string starting with prefix
+func HavePrefix(prefix string, args ...interface{}) GomegaMatcher {
+	prefix = formatted(prefix, args)
+	return newMatcher("have prefix", func(actual interface{}) (bool, error) {
+		s, err := stringOf(actual, "HavePrefix")
+		return strings.HasPrefix(s, prefix), err
+	}, prefix)
+}
+
+// HaveSuffix matches a string ending with suffix
+func HaveSuffix(suffix string, args ...interface{}) GomegaMatcher {
+	suffix = formatted(suffix, args)
+	return newMatcher("have suffix", func(actual interface{}) (bool, error) {
+		s, err := stringOf(actual, "HaveSuffix")
+		return strings.HasSuffix(s, suffix), err
+	}, suffix)
+}
+
+// MatchRegexp matches a string the expression matches
+func MatchRegexp(regex string, args ...interface{}) GomegaMatcher {
+	regex = formatted(regex, args)
+	return newMatcher("match regular expression", func(actual interface{}) (bool, error) {
+		s, err := stringOf(actual, "MatchRegexp")
+		if err != nil {
+			return false, err
+		}
+		re, err := regexp.Compile(regex)
+		if err != nil {
+			return false, fmt.Errorf("RegExp match failed to compile with error:\n\t%s", err.Error())
+		}
+		return re.MatchString(s), nil
+	}, regex)
+}
+
+// HaveKey matches a map with key. key can be a matcher.
+func HaveKey(key interface{}) GomegaMatcher {
+	return HaveKeyWithValue(key, nil)
+}
+
+// HaveKeyWithValue matches a map with key set to value. Both can be
+// matchers; a nil value matches any value.
+func HaveKeyWithValue(key, value interface{}) GomegaMatcher {
+	text, expected := "have key", []interface{}{key}
+	if value != nil {
+		text, expected = "have {key: value}", []interface{}{map[interface{}]interface{}{key: value}}
+	}
+	return newMatcher(text, func(actual interface{}) (bool, error) {
+		v := reflect.ValueOf(actual)
+		if v.Kind() != reflect.Map {
+			return false, fmt.Errorf("HaveKey matcher expects a map.  Got:\n    %s", formatObject(actual))
+		}
+		keyMatcher, ok := key.(GomegaMatcher)
+		if !ok {
+			keyMatcher = Equal(key)
+		}
+		valueMatcher, ok := value.(GomegaMatcher)
+		if !ok && value != nil {
+			valueMatcher = Equal(value)
+		}
+		for _, k := range v.MapKeys() {
+			if success, _ := keyMatcher.Match(k.Interface()); !success {
+				continue
+			}
+			if valueMatcher == nil {
+				return true, nil
+			}
+			if success, _ := valueMatcher.Match(v.MapIndex(k).Interface()); success {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, expected...)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// BeNumerically compares numbers of any type with ==, ~, >, >=, < or <=.
+// "~" allows a difference of up to a threshold, 1e-8 by default:
+// BeNumerically("~", 3.14, 0.01).
+func BeNumerically(comparator string, compareTo ...interface{}) GomegaMatcher {
+	text := "be " + comparator
+	var expected []interface{}
+	if len(compareTo) > 0 {
+		expected = compareTo[:1]
+	}
+	return newMatcher(text, func(actual interface{}) (bool, error) {
+		if len(compareTo) == 0 || len(compareTo) > 2 || len(compareTo) == 2 && comparator != "~" {
+			return false, fmt.Errorf("BeNumerically requires 1 argument to compare against, and a threshold for \"~\".  Got %d", len(compareTo))
+		}
+		a, ok := toFloat(actual)
+		if !ok {
+			return false, fmt.Errorf("Expected a number.  Got:\n    %s", formatObject(actual))
+		}
+		b, ok := toFloat(compareTo[0])
+		if !ok {
+			return false, fmt.Errorf("Expected a number.  Got:\n    %s", formatObject(compareTo[0]))
+		}
+
+		// Same-kind values are ordered exactly, without going through
+		// float64
+		order, exact := Comparisons.Compare(actual, compareTo[0])
+		if !exact {
+			switch {
+			case a < b:
+				order = -1
+			case a > b:
+				order = 1
+			default:
+				order = 0
+			}
+		}
+
+		switch comparator {
+		case "==":
+			return order == 0, nil
+		case "~":
+			threshold := 1e-8
+			if len(compareTo) == 2 {
+				if threshold, ok = toFloat(compareTo[1]); !ok {
+					return false, fmt.Errorf("Expected a number for the threshold.  Got:\n    %s", formatObject(compareTo[1]))
+				}
+			}
+			diff := a - b
+			return -threshold <= diff && diff <= threshold, nil
+		case ">":
+			return order > 0, nil
+		case ">=":
+			return order >= 0, nil
+		case "<":
+			return order < 0, nil
+		case "<=":
+			return order <= 0, nil
+		}
+		return false, fmt.Errorf("Unknown comparator: %s", comparator)
+	}, expected...)
+}
+
+// HaveOccurred matches a non-nil error
+func HaveOccurred() GomegaMatcher {
+	return newMatcher("have occurred", func(actual interface{}) (bool, error) {
+		if Comparisons.IsNil(actual) {
+			return false, nil
+		}
+		if _, ok := actual.(error); !ok {
+			return false, fmt.Errorf("Expected an error-type.  Got:\n    %s", formatObject(actual))
+		}
+		return true, nil
+	})
+}
+
+// Succeed matches a nil error, usually the only result of a function:
+// Expect(store.Save(x)).To(Succeed())
+func Succeed() GomegaMatcher {
+	return newMatcher("succeed", func(actual interface{}) (bool, error) {
+		if actual == nil {
+			return true, nil
+		}
+		if _, ok := actual.(error); !ok {
+			return false, fmt.Errorf("Expected an error-type.  Got:\n    %s", formatObject(actual))
+		}
+		return Comparisons.IsNil(actual), nil
+	})
+}
+
+// MatchError matches an error that is expected, wraps it, or whose
+// message is expected or matches it when expected is a string or matcher
+func MatchError(expected interface{}) GomegaMatcher {
+	return newMatcher("match error", func(actual interface{}) (bool, error) {
+		err, ok := actual.(error)
+		if !ok || Comparisons.IsNil(actual) {
+			return false, fmt.Errorf("Expected an error.  Got:\n    %s", formatObject(actual))
+		}
+		switch want := expected.(type) {
+		case error:
+			return errors.Is(err, want) || Comparisons.ObjectsAreEqual(want, err), nil
+		case string:
+			return err.Error() == want, nil
+		case GomegaMatcher:
+			return want.Match(err.Error())
+		}
+		return false, fmt.Errorf("MatchError must be passed an error, a string, or a Matcher that can match on strings.  Got:\n    %s", formatObject(expected))
+	}, expected)
+}
+
+// BeAssignableToTypeOf matches a value assignable to expected's type
+func BeAssignableToTypeOf(expected interface{}) GomegaMatcher {
+	return newMatcher("be assignable to the type", func(actual interface{}) (bool, error) {
+		if actual == nil || expected == nil {
+			return false, errors.New("Refusing to compare <nil> to <nil>.\nBe explicit and use BeNil() instead.  This is to avoid mistakes where both sides of an assertion are erroneously uninitialized.")
+		}
+		return reflect.TypeOf(actual).AssignableTo(reflect.TypeOf(expected)), nil
+	}, expected)
+}
+
+// Panic matches a function with no arguments that panics when called
+func Panic() GomegaMatcher {
+	return newMatcher("panic", func(actual interface{}) (success bool, err error) {
+		fn := reflect.ValueOf(actual)
+		if fn.Kind() != reflect.Func || fn.Type().NumIn() != 0 {
+			return false, fmt.Errorf("Panic expects a function with no arguments.  Got:\n    %s", formatObject(actual))
+		}
+		defer func() {
+			if recover() != nil {
+				success = true
+			}
+		}()
+		fn.Call(nil)
+		return false, nil
+	})
+}
+
+type notMatcher struct {
+	matcher GomegaMatcher
+}
+
+func (m notMatcher) Match(actual interface{}) (bool, error) {
+	success, err := m.matcher.Match(actual)
+	return !success, err
+}
+
+func (m notMatcher) FailureMessage(actual interface{}) string {
+	return m.matcher.NegatedFailureMessage(actual)
+}
+
+func (m notMatcher) NegatedFailureMessage(actual interface{}) string {
+	return m.matcher.FailureMessage(actual)
+}
+
+// Not negates a matcher
+func Not(matcher GomegaMatcher) GomegaMatcher {
+	return notMatcher{matcher}
+}
+
+// andMatcher remembers which matcher failed, for its message
+type andMatcher struct {
+	matchers []GomegaMatcher
+	failed   *GomegaMatcher
+}
+
+func (m andMatcher) Match(actual interface{}) (bool, error) {
+	for _, matcher := range m.matchers {
+		success, err := matcher.Match(actual)
+		if err != nil || !success {
+			*m.failed = matcher
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (m andMatcher) FailureMessage(actual interface{}) string {
+	return (*m.failed).FailureMessage(actual)
+}
+
+func (m andMatcher) NegatedFailureMessage(actual interface{}) string {
+	return message(actual, "not to satisfy all of the matchers")
+}
+
+// And matches what every one of matchers does, reporting the first that
+// does not
+func And(matchers ...GomegaMatcher) GomegaMatcher {
+	return andMatcher{matchers: matchers, failed: new(GomegaMatcher)}
+}
+
+// SatisfyAll is And
+func SatisfyAll(matchers ...GomegaMatcher) GomegaMatcher {
+	return And(matchers...)
+}
+
+type orMatcher struct {
+	matchers []GomegaMatcher
+	matched  *GomegaMatcher
+}
+
+func (m orMatcher) Match(actual interface{}) (bool, error) {
+	for _, matcher := range m.matchers {
+		success, err := matcher.Match(actual)
+		if err != nil {
+			return false, err
+		}
+		if success {
+			*m.matched = matcher
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m orMatcher) FailureMessage(actual interface{}) string {
+	return message(actual, "to satisfy at least one of the matchers")
+}
+
+func (m orMatcher) NegatedFailureMessage(actual interface{}) string {
+	return (*m.matched).NegatedFailureMessage(actual)
+}
+
+// Or matches what any one of matchers does
+func Or(matchers ...GomegaMatcher) GomegaMatcher {
+	return orMatcher{matchers: matchers, matched: new(GomegaMatcher)}
+}
+
+// SatisfyAny is Or
+func SatisfyAny(matchers ...GomegaMatcher) GomegaMatcher {
+	return Or(matchers...)
+}
+
+type transformMatcher struct {
+	transform   reflect.Value
+	matcher     GomegaMatcher
+	transformed interface{}
+}
+
+// WithTransform matches when matcher matches transform(actual), as in
+// WithTransform(func(u User) string { return u.Name }, Equal("ann"))
+func WithTransform(transform interface{}, matcher GomegaMatcher) GomegaMatcher {
+	fn := reflect.ValueOf(transform)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() != 1 || fn.Type().NumOut() != 1 {
+		panic("WithTransform needs a function with one argument and one result")
+	}
+	return &transformMatcher{transform: fn, matcher: matcher}
+}
+
+func (m *transformMatcher) Match(actual interface{}) (bool, error) {
+	in := reflect.ValueOf(actual)
+	want := m.transform.Type().In(0)
+	if actual == nil {
+		in = reflect.Zero(want)
+	} else if !in.Type().AssignableTo(want) {
+		return false, fmt.Errorf("Transform function expects '%s' but we have '%T'", want, actual)
+	}
+	m.transformed = m.transform.Call([]reflect.Value{in})[0].Interface()
+	return m.matcher.Match(m.transformed)
+}
+
+func (m *transformMatcher) FailureMessage(interface{}) string {
+	return m.matcher.FailureMessage(m.transformed)
+}
+
+func (m *transformMatcher) NegatedFailureMessage(interface{}) string {
+	return m.matcher.NegatedFailureMessage(m.transformed)
+}
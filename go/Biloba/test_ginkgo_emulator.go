@@ -0,0 +1,458 @@
+package main
+
+// Developed by PowerShield, as an alternative to Ginkgo and Gomega
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// fakeT records whether RunSpecs failed it
+type fakeT struct {
+	failed bool
+}
+
+func (t *fakeT) Fail() { t.failed = true }
+
+// fatalT records what a WithT reports
+type fatalT struct {
+	messages []string
+}
+
+func (t *fatalT) Helper() {}
+
+func (t *fatalT) Fatalf(format string, args ...interface{}) {
+	t.messages = append(t.messages, fmt.Sprintf(format, args...))
+}
+
+// suiteResult is what runSuite captures from a suite
+type suiteResult struct {
+	report Report
+	passed bool
+	failed bool
+	output string
+}
+
+// runSuite declares specs and runs them, capturing the report and output
+func runSuite(declare func()) suiteResult {
+	var out bytes.Buffer
+	GinkgoWriter = &out
+	defer func() { GinkgoWriter = os.Stdout }()
+
+	var result suiteResult
+	declare()
+	ReportAfterSuite("capture", func(r Report) { result.report = r })
+	t := &fakeT{}
+	result.passed = RunSpecs(t, "Test Suite")
+	result.failed = t.failed
+	result.output = out.String()
+	return result
+}
+
+// states lists each spec's full text and state
+func states(r Report) []string {
+	var out []string
+	for _, spec := range r.SpecReports {
+		out = append(out, spec.FullText()+": "+spec.State.String())
+	}
+	return out
+}
+
+// failureOf runs fn with a recording fail handler and returns the message
+func failureOf(fn func()) string {
+	var message string
+	RegisterFailHandler(func(m string, callerSkip ...int) { message = m })
+	defer RegisterFailHandler(Fail)
+	fn()
+	return message
+}
+
+// matches runs a matcher directly
+func matches(m GomegaMatcher, actual interface{}) bool {
+	success, err := m.Match(actual)
+	return success && err == nil
+}
+
+func testHookOrder() bool {
+	var log []string
+	add := func(s string) func() { return func() { log = append(log, s) } }
+
+	result := runSuite(func() {
+		Describe("outer", func() {
+			BeforeEach(add("before outer"))
+			JustBeforeEach(add("just before outer"))
+			AfterEach(add("after outer"))
+			JustAfterEach(add("just after outer"))
+
+			Context("inner", func() {
+				BeforeEach(add("before inner"))
+				JustBeforeEach(add("just before inner"))
+				AfterEach(add("after inner"))
+
+				It("runs", add("it"))
+			})
+			It("runs alone", add("alone"))
+		})
+	})
+
+	want := []string{
+		"before outer", "before inner", "just before outer", "just before inner", "it",
+		"just after outer", "after inner", "after outer",
+		"before outer", "just before outer", "alone", "just after outer", "after outer",
+	}
+	return reflect.DeepEqual(log, want) && result.passed && !result.failed &&
+		reflect.DeepEqual(states(result.report), []string{"outer inner runs: passed", "outer runs alone: passed"}) &&
+		strings.Contains(result.output, "Ran 2 of 2 Specs") &&
+		strings.Contains(result.output, "SUCCESS! -- 2 Passed | 0 Failed | 0 Pending | 0 Skipped")
+}
+
+func testFailures() bool {
+	var log []string
+	result := runSuite(func() {
+		Describe("cart", func() {
+			BeforeEach(func() {
+				log = append(log, "before")
+				Expect(1).To(Equal(2))
+			})
+			BeforeEach(func() { log = append(log, "second before") })
+			AfterEach(func() { log = append(log, "after") })
+			It("never runs", func() { log = append(log, "it") })
+		})
+		It("fails with a location", func() {
+			Expect("apple").To(Equal("pear"), "fruit should be %s", "pear")
+		})
+		It("fails directly", func() { Fail("gave up") })
+	})
+
+	reports := result.report.SpecReports
+	return reflect.DeepEqual(log, []string{"before", "after"}) && !result.passed && result.failed &&
+		reports[0].State == SpecStateFailed &&
+		reports[0].Failure.Message == "Expected\n    <int>: 1\nto equal\n    <int>: 2" &&
+		reports[1].Failure.Message == "fruit should be pear\nExpected\n    <string>: apple\nto equal\n    <string>: pear" &&
+		strings.HasPrefix(reports[1].Failure.Location, "test_ginkgo_emulator.go:") &&
+		reports[1].Failure.Location != reports[0].Failure.Location &&
+		reports[2].Failure.Message == "gave up" &&
+		strings.Contains(result.output, "[FAILED] cart never runs") &&
+		strings.Contains(result.output, "FAIL! -- 0 Passed | 3 Failed")
+}
+
+func testFocus() bool {
+	var ran []string
+	result := runSuite(func() {
+		Describe("a", func() {
+			It("1", func() { ran = append(ran, "a1") })
+			FIt("2", func() { ran = append(ran, "a2") })
+		})
+		FDescribe("b", func() {
+			It("1", func() { ran = append(ran, "b1") })
+			PIt("2")
+		})
+		It("c", func() { ran = append(ran, "c") })
+	})
+	return reflect.DeepEqual(ran, []string{"a2", "b1"}) && result.passed &&
+		result.report.SuiteHasProgrammaticFocus &&
+		reflect.DeepEqual(states(result.report), []string{
+			"a 1: skipped", "a 2: passed", "b 1: passed", "b 2: pending", "c: skipped",
+		}) &&
+		strings.Contains(result.output, "Ran 2 of 5 Specs") &&
+		strings.Contains(result.output, "Detected Programmatic Focus")
+}
+
+func testPending() bool {
+	ran := false
+	result := runSuite(func() {
+		PDescribe("later", func() {
+			It("waits", func() { ran = true })
+		})
+		XDescribe("also later", func() {
+			It("waits", func() { ran = true })
+		})
+		XIt("crossed out", func() { ran = true })
+		It("no body", nil)
+		It("runs", func() {})
+	})
+	return !ran && result.passed && result.report.Count(SpecStatePending) == 4 &&
+		result.report.Count(SpecStatePassed) == 1 && !result.report.SuiteHasProgrammaticFocus
+}
+
+func testSkipAndPanics() bool {
+	afterRan := false
+	result := runSuite(func() {
+		AfterEach(func() { afterRan = true })
+		It("skips", func() {
+			Skip("not on this platform")
+			panic("unreachable")
+		})
+		It("panics", func() {
+			var m map[string]int
+			m["boom"] = 1
+		})
+	})
+	reports := result.report.SpecReports
+	return afterRan && !result.passed &&
+		reports[0].State == SpecStateSkipped && reports[0].Failure.Message == "not on this platform" &&
+		reports[1].State == SpecStatePanicked && strings.Contains(reports[1].Failure.Message, "Test Panicked\nassignment to entry in nil map") &&
+		strings.HasPrefix(reports[1].Failure.Location, "test_ginkgo_emulator.go:")
+}
+
+func testSuiteNodes() bool {
+	var log []string
+	result := runSuite(func() {
+		BeforeSuite(func() { log = append(log, "before suite") })
+		AfterSuite(func() { log = append(log, "after suite") })
+		It("one", func() { log = append(log, "one") })
+		It("two", func() { log = append(log, "two") })
+	})
+	if !reflect.DeepEqual(log, []string{"before suite", "one", "two", "after suite"}) || !result.passed {
+		return false
+	}
+
+	// A failing BeforeSuite skips every spec and fails the suite
+	ran := false
+	result = runSuite(func() {
+		BeforeSuite(func() { Expect(errors.New("no database")).NotTo(HaveOccurred()) })
+		It("needs the database", func() { ran = true })
+	})
+	return !ran && !result.passed && result.report.SpecReports[0].State == SpecStateSkipped &&
+		strings.Contains(result.output, "[BeforeSuite] FAILED")
+}
+
+func testTables() bool {
+	var got []int
+	result := runSuite(func() {
+		DescribeTable("addition",
+			func(a, b, sum int) {
+				got = append(got, a+b)
+				Expect(a + b).To(Equal(sum))
+			},
+			Entry("small numbers", 1, 2, 3),
+			Entry("", 10, 20, 30),
+			PEntry("pending", 0, 0, 1),
+			Entry("wrong", 2, 2, 5),
+		)
+	})
+	return reflect.DeepEqual(got, []int{3, 30, 4}) && !result.passed &&
+		reflect.DeepEqual(states(result.report), []string{
+			"addition small numbers: passed", "addition Entry: 10, 20, 30: passed",
+			"addition pending: pending", "addition wrong: failed",
+		})
+}
+
+func testBasicMatchers() bool {
+	type point struct{ X, Y int }
+	p := &point{1, 2}
+	var nilPtr *point
+	return matches(Equal(point{1, 2}), point{1, 2}) && !matches(Equal(5), int64(5)) &&
+		matches(BeEquivalentTo(5), int64(5)) && matches(BeEquivalentTo(5.0), 5) &&
+		matches(BeIdenticalTo(p), p) && !matches(BeIdenticalTo(&point{1, 2}), p) &&
+		matches(BeNil(), nil) && matches(BeNil(), nilPtr) && !matches(BeNil(), 0) &&
+		matches(BeTrue(), true) && matches(BeFalse(), false) && !matches(BeTrue(), "true") &&
+		matches(BeZero(), point{}) && matches(BeZero(), "") && !matches(BeZero(), 1) &&
+		!matches(Equal(nil), nil)
+}
+
+func testCollectionMatchers() bool {
+	m := map[string]int{"a": 1, "b": 2}
+	return matches(BeEmpty(), []int{}) && matches(BeEmpty(), "") && !matches(BeEmpty(), m) && !matches(BeEmpty(), 0) &&
+		matches(HaveLen(2), m) && matches(HaveLen(3), "abc") && !matches(HaveLen(1), 1) &&
+		matches(ContainElement(2), []int{1, 2}) && matches(ContainElement(BeNumerically(">", 1)), m) &&
+		!matches(ContainElement("a"), "abc") &&
+		matches(ConsistOf(2, 1), []int{1, 2}) && matches(ConsistOf([]int{2, 1}), []int{1, 2}) &&
+		matches(ConsistOf(HavePrefix("b"), "apple"), []string{"apple", "banana"}) &&
+		!matches(ConsistOf(1, 1), []int{1, 2}) && !matches(ConsistOf(1), []int{1, 1}) &&
+		matches(HaveKey("a"), m) && matches(HaveKey(HavePrefix("b")), m) && !matches(HaveKey("z"), m) &&
+		matches(HaveKeyWithValue("b", 2), m) && !matches(HaveKeyWithValue("b", 1), m)
+}
+
+func testStringMatchers() bool {
+	return matches(ContainSubstring("ell"), "hello") && matches(ContainSubstring("%d items", 3), "3 items left") &&
+		matches(HavePrefix("he"), []byte("hello")) && matches(HaveSuffix("lo"), errors.New("hello")) &&
+		matches(MatchRegexp(`^\d{3}-\d{4}$`), "555-1234") && !matches(MatchRegexp(`^\d+$`), "12a") &&
+		!matches(ContainSubstring("1"), 1) && !matches(MatchRegexp("("), "x")
+}
+
+func testNumbersAndErrors() bool {
+	notFound := errors.New("not found")
+	wrapped := fmt.Errorf("loading user: %w", notFound)
+	return matches(BeNumerically("==", 5), 5.0) && matches(BeNumerically(">", 1), uint8(2)) &&
+		matches(BeNumerically("<=", 3), 3) && matches(BeNumerically("~", 3.14, 0.01), 3.141) &&
+		!matches(BeNumerically("~", 3.14), 3.15) && !matches(BeNumerically("!=", 1), 2) &&
+		matches(BeNumerically(">=", int64(1<<62)), int64(1<<62)) &&
+		matches(HaveOccurred(), notFound) && !matches(HaveOccurred(), nil) &&
+		matches(Succeed(), nil) && !matches(Succeed(), notFound) &&
+		matches(MatchError(notFound), wrapped) && matches(MatchError("not found"), notFound) &&
+		matches(MatchError(ContainSubstring("user")), wrapped) && !matches(MatchError("x"), nil) &&
+		matches(Panic(), func() { panic("boom") }) && !matches(Panic(), func() {}) &&
+		matches(BeAssignableToTypeOf(""), "s") && !matches(BeAssignableToTypeOf(0), "s")
+}
+
+func testComposedMatchers() bool {
+	type user struct{ Name string }
+	name := WithTransform(func(u user) string { return u.Name }, Equal("ann"))
+
+	andMessage := failureOf(func() { Expect(7).To(And(BeNumerically(">", 1), BeNumerically("<", 5))) })
+	notMessage := failureOf(func() { Expect("x").To(Not(Equal("x"))) })
+	transformMessage := failureOf(func() { Expect(user{"bob"}).To(name) })
+	extraMessage := failureOf(func() { Expect(1, errors.New("oops")).To(Equal(1)) })
+	errorMessage := failureOf(func() { Expect(3).To(BeTrue()) })
+
+	return matches(Not(BeNil()), 1) && matches(And(HavePrefix("a"), HaveSuffix("z")), "abcz") &&
+		matches(SatisfyAny(Equal(1), Equal(2)), 2) && !matches(Or(Equal(1), Equal(2)), 3) &&
+		matches(name, user{"ann"}) && !matches(name, "ann") &&
+		andMessage == "Expected\n    <int>: 7\nto be <\n    <int>: 5" &&
+		notMessage == "Expected\n    <string>: x\nnot to equal\n    <string>: x" &&
+		transformMessage == "Expected\n    <string>: bob\nto equal\n    <string>: ann" &&
+		strings.HasPrefix(extraMessage, "Unexpected non-nil/non-zero argument at index 1:") &&
+		strings.HasPrefix(errorMessage, "Expected a boolean.")
+}
+
+func testAsyncAssertions() bool {
+	var mu sync.Mutex
+	count := 0
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			count++
+			mu.Unlock()
+		}
+	}()
+	current := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+
+	eventually := failureOf(func() { Eventually(current).Should(Equal(3)) })
+	timedOut := failureOf(func() { Eventually(current, "30ms", "5ms").Should(Equal(4)) })
+	consistent := failureOf(func() { Consistently(current).WithTimeout(30 * time.Millisecond).Should(Equal(3)) })
+	inconsistent := failureOf(func() {
+		n := 0
+		Consistently(func() int { n++; return n }, 50*time.Millisecond).Should(BeNumerically("<", 3))
+	})
+	withError := failureOf(func() {
+		Eventually(func() (int, error) { return 0, errors.New("not ready") }).WithTimeout(20 * time.Millisecond).Should(Equal(0))
+	})
+
+	return eventually == "" && consistent == "" &&
+		strings.HasPrefix(timedOut, "Timed out after 0.030s.\nExpected\n    <int>: 3\nto equal\n    <int>: 4") &&
+		strings.HasPrefix(inconsistent, "Failed after ") && strings.Contains(inconsistent, "to be <") &&
+		strings.Contains(withError, "Eventually function returned a non-zero value at index 1: not ready")
+}
+
+func testWithTAndGinkgoT() bool {
+	t := &fatalT{}
+	g := NewWithT(t)
+	passed := g.Expect([]int{1, 2}).To(ContainElement(2))
+	failed := g.Expect("a").To(Equal("b"))
+	g.Eventually(func() bool { return false }, "10ms").Should(BeTrue())
+	if !passed || failed || len(t.messages) != 2 || !strings.HasPrefix(t.messages[0], "\nExpected\n    <string>: a") {
+		return false
+	}
+
+	// GinkgoT lets *testing.T-style assertions fail a spec
+	var tt interface {
+		Errorf(format string, args ...interface{})
+		FailNow()
+		Failed() bool
+	} = GinkgoT()
+	failedBefore := true
+	result := runSuite(func() {
+		It("uses a testing.T library", func() {
+			failedBefore = tt.Failed()
+			tt.Errorf("values differ: %d != %d", 1, 2)
+		})
+	})
+	report := result.report.SpecReports[0]
+	return !failedBefore && report.State == SpecStateFailed && report.Failure.Message == "values differ: 1 != 2"
+}
+
+// strictComparer treats every value as unequal, standing in for the
+// testify emulator's Comparer
+type strictComparer struct {
+	defaultComparer
+	calls int
+}
+
+func (c *strictComparer) ObjectsAreEqual(expected, actual interface{}) bool {
+	c.calls++
+	return false
+}
+
+func testComparer() bool {
+	var _ Comparer = defaultComparer{}
+	comparer := &strictComparer{}
+	Comparisons = comparer
+	defer func() { Comparisons = defaultComparer{} }()
+
+	return !matches(Equal(1), 1) && comparer.calls == 1 &&
+		matches(HaveLen(1), []int{1}) && matches(BeNumerically(">", 1), 2)
+}
+
+func testGoroutines() bool {
+	var wg sync.WaitGroup
+	result := runSuite(func() {
+		It("fails in a goroutine", func() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				Expect(false).To(BeTrue())
+			}()
+			wg.Wait()
+		})
+		It("reports the running spec", func() {
+			Expect(CurrentSpecReport().LeafNodeText).To(Equal("reports the running spec"))
+		})
+		It("cannot declare specs while running", func() {
+			It("nested", func() {})
+		})
+	})
+	reports := result.report.SpecReports
+	return reports[0].State == SpecStateFailed && strings.Contains(reports[0].Failure.Message, "to be true") &&
+		reports[1].State == SpecStatePassed &&
+		reports[2].State == SpecStateFailed && strings.Contains(reports[2].Failure.Message, "specs must be declared when the tree is built") &&
+		CurrentSpecReport().LeafNodeText == ""
+}
+
+func main() {
+	GinkgoWriter = io.Discard
+
+	fmt.Println("Running Ginkgo Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Hook Order", testHookOrder)
+	runTest("Failures", testFailures)
+	runTest("Focus", testFocus)
+	runTest("Pending", testPending)
+	runTest("Skip and Panics", testSkipAndPanics)
+	runTest("Suite Nodes", testSuiteNodes)
+	runTest("Tables", testTables)
+	runTest("Basic Matchers", testBasicMatchers)
+	runTest("Collection Matchers", testCollectionMatchers)
+	runTest("String Matchers", testStringMatchers)
+	runTest("Numbers and Errors", testNumbersAndErrors)
+	runTest("Composed Matchers", testComposedMatchers)
+	runTest("Async Assertions", testAsyncAssertions)
+	runTest("WithT and GinkgoT", testWithTAndGinkgoT)
+	runTest("Comparer", testComparer)
+	runTest("Goroutines", testGoroutines)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,2428 @@
+package main
+
+// Developed by PowerShield, as an alternative to Amazon S3 and the AWS SDK for Go
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pointer helpers, as in the aws package
+
+// String returns a pointer to v
+func String(v string) *string { return &v }
+
+// ToString returns the value p points to, or "" if p is nil
+func ToString(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// Int32 returns a pointer to v
+func Int32(v int32) *int32 { return &v }
+
+// ToInt32 returns the value p points to, or 0 if p is nil
+func ToInt32(p *int32) int32 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+// Int64 returns a pointer to v
+func Int64(v int64) *int64 { return &v }
+
+// ToInt64 returns the value p points to, or 0 if p is nil
+func ToInt64(p *int64) int64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+// Bool returns a pointer to v
+func Bool(v bool) *bool { return &v }
+
+// ToBool returns the value p points to, or false if p is nil
+func ToBool(p *bool) bool {
+	if p == nil {
+		return false
+	}
+	return *p
+}
+
+// Errors
+
+// APIError is an error returned by the service, as smithy.APIError
+type APIError interface {
+	error
+	ErrorCode() string
+	ErrorMessage() string
+}
+
+// GenericAPIError is an APIError without a dedicated type
+type GenericAPIError struct {
+	Code    string
+	Message string
+}
+
+func (e *GenericAPIError) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.Code, e.Message)
+}
+
+// ErrorCode returns the S3 error code, e.g. "BucketNotEmpty"
+func (e *GenericAPIError) ErrorCode() string { return e.Code }
+
+// ErrorMessage returns the error message
+func (e *GenericAPIError) ErrorMessage() string { return e.Message }
+
+// NoSuchBucket is returned for buckets that do not exist
+type NoSuchBucket struct{ Message *string }
+
+func (e *NoSuchBucket) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.ErrorCode(), e.ErrorMessage())
+}
+
+// ErrorCode returns "NoSuchBucket"
+func (e *NoSuchBucket) ErrorCode() string { return "NoSuchBucket" }
+
+// ErrorMessage returns the error message
+func (e *NoSuchBucket) ErrorMessage() string { return ToString(e.Message) }
+
+// NoSuchKey is returned for objects that do not exist
+type NoSuchKey struct{ Message *string }
+
+func (e *NoSuchKey) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.ErrorCode(), e.ErrorMessage())
+}
+
+// ErrorCode returns "NoSuchKey"
+func (e *NoSuchKey) ErrorCode() string { return "NoSuchKey" }
+
+// ErrorMessage returns the error message
+func (e *NoSuchKey) ErrorMessage() string { return ToString(e.Message) }
+
+// NoSuchUpload is returned for multipart uploads that do not exist or
+// were completed or aborted
+type NoSuchUpload struct{ Message *string }
+
+func (e *NoSuchUpload) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.ErrorCode(), e.ErrorMessage())
+}
+
+// ErrorCode returns "NoSuchUpload"
+func (e *NoSuchUpload) ErrorCode() string { return "NoSuchUpload" }
+
+// ErrorMessage returns the error message
+func (e *NoSuchUpload) ErrorMessage() string { return ToString(e.Message) }
+
+// NotFound is returned by HeadBucket and HeadObject, whose responses
+// have no body to say what was missing
+type NotFound struct{ Message *string }
+
+func (e *NotFound) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.ErrorCode(), e.ErrorMessage())
+}
+
+// ErrorCode returns "NotFound"
+func (e *NotFound) ErrorCode() string { return "NotFound" }
+
+// ErrorMessage returns the error message
+func (e *NotFound) ErrorMessage() string { return ToString(e.Message) }
+
+// BucketAlreadyOwnedByYou is returned when creating a bucket that exists
+type BucketAlreadyOwnedByYou struct{ Message *string }
+
+func (e *BucketAlreadyOwnedByYou) Error() string {
+	return fmt.Sprintf("api error %s: %s", e.ErrorCode(), e.ErrorMessage())
+}
+
+// ErrorCode returns "BucketAlreadyOwnedByYou"
+func (e *BucketAlreadyOwnedByYou) ErrorCode() string { return "BucketAlreadyOwnedByYou" }
+
+// ErrorMessage returns the error message
+func (e *BucketAlreadyOwnedByYou) ErrorMessage() string { return ToString(e.Message) }
+
+// OperationError wraps the error of a failed operation, as
+// smithy.OperationError
+type OperationError struct {
+	ServiceID     string
+	OperationName string
+	Err           error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation error %s: %s, %v", e.ServiceID, e.OperationName, e.Err)
+}
+
+func (e *OperationError) Unwrap() error { return e.Err }
+
+// s3Error is an error as the service reports it: an HTTP status and an
+// error code
+type s3Error struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *s3Error) Error() string { return e.code + ": " + e.message }
+
+func errNoSuchBucket() *s3Error {
+	return &s3Error{http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist"}
+}
+
+func errNoSuchKey() *s3Error {
+	return &s3Error{http.StatusNotFound, "NoSuchKey", "The specified key does not exist."}
+}
+
+func errNoSuchUpload() *s3Error {
+	return &s3Error{http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist. The upload ID may be invalid, or the upload may have been aborted or completed."}
+}
+
+func errInvalidArgument(message string) *s3Error {
+	return &s3Error{http.StatusBadRequest, "InvalidArgument", message}
+}
+
+func errAccessDenied(message string) *s3Error {
+	return &s3Error{http.StatusForbidden, "AccessDenied", message}
+}
+
+func errSignatureDoesNotMatch() *s3Error {
+	return &s3Error{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your key and signing method."}
+}
+
+// sdkError converts a service error into the error the SDK returns for
+// operation op
+func sdkError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *s3Error
+	if !errors.As(err, &se) {
+		return &OperationError{ServiceID: "S3", OperationName: op, Err: err}
+	}
+	var apiErr error
+	switch {
+	case op == "HeadBucket" || op == "HeadObject":
+		if se.status == http.StatusNotFound {
+			apiErr = &NotFound{Message: String("Not Found")}
+		} else {
+			apiErr = &GenericAPIError{Code: strconv.Itoa(se.status), Message: http.StatusText(se.status)}
+		}
+	case se.code == "NoSuchBucket":
+		apiErr = &NoSuchBucket{Message: String(se.message)}
+	case se.code == "NoSuchKey":
+		apiErr = &NoSuchKey{Message: String(se.message)}
+	case se.code == "NoSuchUpload":
+		apiErr = &NoSuchUpload{Message: String(se.message)}
+	case se.code == "BucketAlreadyOwnedByYou":
+		apiErr = &BucketAlreadyOwnedByYou{Message: String(se.message)}
+	default:
+		apiErr = &GenericAPIError{Code: se.code, Message: se.message}
+	}
+	return &OperationError{ServiceID: "S3", OperationName: op, Err: apiErr}
+}
+
+// Backend
+
+// DefaultMinPartSize is the smallest size of every multipart upload part
+// but the last
+const DefaultMinPartSize = 5 << 20
+
+const maxKeyLength = 1024
+
+// object is a stored object. Objects are never changed once stored, so
+// readers may keep them without locking.
+type object struct {
+	key          string
+	data         []byte
+	etag         string
+	lastModified time.Time
+	contentType  string
+	metadata     map[string]string
+	headers      objectHeaders
+}
+
+// objectHeaders are the standard headers stored with an object
+type objectHeaders struct {
+	cacheControl       string
+	contentDisposition string
+	contentEncoding    string
+	contentLanguage    string
+}
+
+type bucket struct {
+	name    string
+	created time.Time
+	objects map[string]*object
+}
+
+type part struct {
+	number       int32
+	data         []byte
+	etag         string
+	lastModified time.Time
+}
+
+type multipartUpload struct {
+	id          string
+	bucket      string
+	key         string
+	initiated   time.Time
+	contentType string
+	metadata    map[string]string
+	headers     objectHeaders
+	parts       map[int32]*part
+}
+
+// Backend is the in-memory store behind clients and the HTTP handler.
+// Clients made with New and the handler from NewHandler can share one,
+// so objects written through either are visible to both.
+type Backend struct {
+	// MinPartSize is the minimum size of every multipart upload part but
+	// the last; it defaults to 5 MiB, as on S3
+	MinPartSize int64
+
+	mu          sync.RWMutex
+	buckets     map[string]*bucket
+	uploads     map[string]*multipartUpload
+	credentials map[string]string
+	timeFunc    func() time.Time
+}
+
+// NewBackend creates an empty store
+func NewBackend() *Backend {
+	return &Backend{
+		MinPartSize: DefaultMinPartSize,
+		buckets:     make(map[string]*bucket),
+		uploads:     make(map[string]*multipartUpload),
+		credentials: make(map[string]string),
+	}
+}
+
+// AddCredentials registers an access key. Once any key is registered the
+// HTTP handler requires every request to be signed with one, in the
+// Authorization header or as a presigned URL.
+func (b *Backend) AddCredentials(accessKeyID, secretAccessKey string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.credentials[accessKeyID] = secretAccessKey
+}
+
+// SetTimeFunc replaces the clock used for timestamps and for checking
+// presigned URL expiry
+func (b *Backend) SetTimeFunc(f func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timeFunc = f
+}
+
+func (b *Backend) now() time.Time {
+	b.mu.RLock()
+	f := b.timeFunc
+	b.mu.RUnlock()
+	if f != nil {
+		return f().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// validBucketName applies the S3 bucket naming rules
+func validBucketName(name string) bool {
+	if len(name) < 3 || len(name) > 63 || strings.Contains(name, "..") {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case (c == '-' || c == '.') && i > 0 && i < len(name)-1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func etagOf(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[strings.ToLower(k)] = v
+	}
+	return c
+}
+
+// bucketLocked returns the named bucket; b.mu must be held
+func (b *Backend) bucketLocked(name string) (*bucket, error) {
+	bkt, ok := b.buckets[name]
+	if !ok {
+		return nil, errNoSuchBucket()
+	}
+	return bkt, nil
+}
+
+func (b *Backend) createBucket(name string) error {
+	if !validBucketName(name) {
+		return &s3Error{http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid."}
+	}
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.buckets[name]; ok {
+		return &s3Error{http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it."}
+	}
+	b.buckets[name] = &bucket{name: name, created: now, objects: make(map[string]*object)}
+	return nil
+}
+
+func (b *Backend) deleteBucket(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.bucketLocked(name)
+	if err != nil {
+		return err
+	}
+	if len(bkt.objects) > 0 {
+		return &s3Error{http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty"}
+	}
+	delete(b.buckets, name)
+	return nil
+}
+
+func (b *Backend) headBucket(name string) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, err := b.bucketLocked(name)
+	return err
+}
+
+// listBuckets returns the buckets sorted by name
+func (b *Backend) listBuckets() []Bucket {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	buckets := make([]Bucket, 0, len(b.buckets))
+	for _, bkt := range b.buckets {
+		created := bkt.created
+		buckets = append(buckets, Bucket{Name: String(bkt.name), CreationDate: &created})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return *buckets[i].Name < *buckets[j].Name })
+	return buckets
+}
+
+// putObject stores obj, filling in its ETag and modification time. With
+// ifNoneMatch "*" it fails if the key exists.
+func (b *Backend) putObject(bucketName string, obj *object, ifNoneMatch string) error {
+	if obj.key == "" {
+		return errInvalidArgument("Object key must not be empty")
+	}
+	if len(obj.key) > maxKeyLength {
+		return &s3Error{http.StatusBadRequest, "KeyTooLongError", "Your key is too long"}
+	}
+	if obj.etag == "" {
+		obj.etag = etagOf(obj.data)
+	}
+	if obj.contentType == "" {
+		obj.contentType = "binary/octet-stream"
+	}
+	obj.lastModified = b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.bucketLocked(bucketName)
+	if err != nil {
+		return err
+	}
+	if ifNoneMatch == "*" && bkt.objects[obj.key] != nil {
+		return &s3Error{http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold"}
+	}
+	bkt.objects[obj.key] = obj
+	return nil
+}
+
+func (b *Backend) getObject(bucketName, key string) (*object, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	bkt, err := b.bucketLocked(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := bkt.objects[key]
+	if !ok {
+		return nil, errNoSuchKey()
+	}
+	return obj, nil
+}
+
+// checkConditions applies If-Match and If-None-Match to obj
+func checkConditions(obj *object, ifMatch, ifNoneMatch string) error {
+	if ifMatch != "" && ifMatch != "*" && strings.Trim(ifMatch, `"`) != strings.Trim(obj.etag, `"`) {
+		return &s3Error{http.StatusPreconditionFailed, "PreconditionFailed", "At least one of the pre-conditions you specified did not hold"}
+	}
+	if ifNoneMatch != "" && (ifNoneMatch == "*" || strings.Trim(ifNoneMatch, `"`) == strings.Trim(obj.etag, `"`)) {
+		return &s3Error{http.StatusNotModified, "NotModified", "Not Modified"}
+	}
+	return nil
+}
+
+// parseRange resolves an HTTP Range header against size, returning the
+// first and last byte offsets
+func parseRange(spec string, size int64) (int64, int64, error) {
+	invalid := &s3Error{http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable"}
+	if !strings.HasPrefix(spec, "bytes=") || strings.Contains(spec, ",") {
+		return 0, 0, invalid
+	}
+	from, to, ok := strings.Cut(strings.TrimPrefix(spec, "bytes="), "-")
+	if !ok {
+		return 0, 0, invalid
+	}
+	if from == "" {
+		n, err := strconv.ParseInt(to, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, invalid
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+	}
+	first, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || first >= size {
+		return 0, 0, invalid
+	}
+	last := size - 1
+	if to != "" {
+		if last, err = strconv.ParseInt(to, 10, 64); err != nil || last < first {
+			return 0, 0, invalid
+		}
+		if last >= size {
+			last = size - 1
+		}
+	}
+	return first, last, nil
+}
+
+// deleteObject removes a key; deleting a missing key succeeds
+func (b *Backend) deleteObject(bucketName, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bkt, err := b.bucketLocked(bucketName)
+	if err != nil {
+		return err
+	}
+	delete(bkt.objects, key)
+	return nil
+}
+
+// copyObject copies srcKey to dstKey. Unless replace is set the copy
+// keeps the source's content type and metadata.
+func (b *Backend) copyObject(srcBucket, srcKey, dstBucket, dstKey string, replace bool, contentType string, metadata map[string]string) (*object, error) {
+	src, err := b.getObject(srcBucket, srcKey)
+	if err != nil {
+		return nil, err
+	}
+	dst := &object{
+		key:         dstKey,
+		data:        src.data,
+		etag:        src.etag,
+		contentType: src.contentType,
+		metadata:    src.metadata,
+		headers:     src.headers,
+	}
+	if replace {
+		dst.contentType = contentType
+		dst.metadata = copyMetadata(metadata)
+	} else if srcBucket == dstBucket && srcKey == dstKey {
+		return nil, &s3Error{http.StatusBadRequest, "InvalidRequest", "This copy request is illegal because it is trying to copy an object to itself without changing the object's metadata, storage class, website redirect location or encryption attributes."}
+	}
+	if err := b.putObject(dstBucket, dst, ""); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// listing is a page of ListObjects results
+type listing struct {
+	objects     []*object
+	prefixes    []string
+	isTruncated bool
+	next        string
+}
+
+// listObjects lists keys after marker that start with prefix, rolling
+// keys that contain delimiter after the prefix up into common prefixes.
+// Each object and each common prefix counts towards maxKeys; next is the
+// last entry returned, to resume from.
+func (b *Backend) listObjects(bucketName, prefix, delimiter, marker string, maxKeys int) (*listing, error) {
+	b.mu.RLock()
+	bkt, err := b.bucketLocked(bucketName)
+	if err != nil {
+		b.mu.RUnlock()
+		return nil, err
+	}
+	keys := make([]string, 0, len(bkt.objects))
+	objects := make(map[string]*object, len(bkt.objects))
+	for key, obj := range bkt.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+			objects[key] = obj
+		}
+	}
+	b.mu.RUnlock()
+	sort.Strings(keys)
+
+	// A marker that is a common prefix skips the keys it rolled up
+	skipPrefix := ""
+	if delimiter != "" && strings.HasSuffix(marker, delimiter) && strings.HasPrefix(marker, prefix) {
+		skipPrefix = marker
+	}
+
+	result := &listing{}
+	if maxKeys == 0 {
+		return result, nil
+	}
+	count := 0
+	lastPrefix := ""
+	for _, key := range keys {
+		if skipPrefix != "" && strings.HasPrefix(key, skipPrefix) {
+			continue
+		}
+		commonPrefix := ""
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				commonPrefix = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if commonPrefix != "" && commonPrefix == lastPrefix {
+			continue
+		}
+		if count == maxKeys {
+			result.isTruncated = true
+			break
+		}
+		count++
+		if commonPrefix != "" {
+			lastPrefix = commonPrefix
+			result.prefixes = append(result.prefixes, commonPrefix)
+			result.next = commonPrefix
+		} else {
+			result.objects = append(result.objects, objects[key])
+			result.next = key
+		}
+	}
+	if !result.isTruncated {
+		result.next = ""
+	}
+	return result, nil
+}
+
+func newUploadID() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func (b *Backend) createMultipartUpload(bucketName, key, contentType string, metadata map[string]string, headers objectHeaders) (string, error) {
+	if key == "" {
+		return "", errInvalidArgument("Object key must not be empty")
+	}
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.bucketLocked(bucketName); err != nil {
+		return "", err
+	}
+	upload := &multipartUpload{
+		id:          newUploadID(),
+		bucket:      bucketName,
+		key:         key,
+		initiated:   now,
+		contentType: contentType,
+		metadata:    copyMetadata(metadata),
+		headers:     headers,
+		parts:       make(map[int32]*part),
+	}
+	b.uploads[upload.id] = upload
+	return upload.id, nil
+}
+
+// uploadLocked finds an upload of key in bucketName; b.mu must be held
+func (b *Backend) uploadLocked(bucketName, key, uploadID string) (*multipartUpload, error) {
+	if _, err := b.bucketLocked(bucketName); err != nil {
+		return nil, err
+	}
+	upload, ok := b.uploads[uploadID]
+	if !ok || upload.bucket != bucketName || upload.key != key {
+		return nil, errNoSuchUpload()
+	}
+	return upload, nil
+}
+
+func (b *Backend) uploadPart(bucketName, key, uploadID string, number int32, data []byte) (string, error) {
+	if number < 1 || number > 10000 {
+		return "", errInvalidArgument("Part number must be an integer between 1 and 10000, inclusive")
+	}
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	upload, err := b.uploadLocked(bucketName, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	p := &part{number: number, data: data, etag: etagOf(data), lastModified: now}
+	upload.parts[number] = p
+	return p.etag, nil
+}
+
+// listParts returns the parts uploaded so far, by part number
+func (b *Backend) listParts(bucketName, key, uploadID string) ([]*part, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	upload, err := b.uploadLocked(bucketName, key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*part, 0, len(upload.parts))
+	for _, p := range upload.parts {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].number < parts[j].number })
+	return parts, nil
+}
+
+// completeMultipartUpload joins the listed parts into the object. Its
+// ETag is the MD5 of the parts' MD5s followed by the number of parts.
+func (b *Backend) completeMultipartUpload(bucketName, key, uploadID string, completed []CompletedPart) (*object, error) {
+	b.mu.Lock()
+	upload, err := b.uploadLocked(bucketName, key, uploadID)
+	if err != nil {
+		b.mu.Unlock()
+		return nil, err
+	}
+	if len(completed) == 0 {
+		b.mu.Unlock()
+		return nil, &s3Error{http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema"}
+	}
+	var data, sums []byte
+	for i, c := range completed {
+		number := ToInt32(c.PartNumber)
+		if i > 0 && number <= ToInt32(completed[i-1].PartNumber) {
+			b.mu.Unlock()
+			return nil, &s3Error{http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order. The parts list must be specified in order by part number."}
+		}
+		p, ok := upload.parts[number]
+		if !ok || strings.Trim(ToString(c.ETag), `"`) != strings.Trim(p.etag, `"`) {
+			b.mu.Unlock()
+			return nil, &s3Error{http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found.  The part may not have been uploaded, or the specified entity tag may not match the part's entity tag."}
+		}
+		if i < len(completed)-1 && int64(len(p.data)) < b.MinPartSize {
+			b.mu.Unlock()
+			return nil, &s3Error{http.StatusBadRequest, "EntityTooSmall", "Your proposed upload is smaller than the minimum allowed object size."}
+		}
+		data = append(data, p.data...)
+		sum := md5.Sum(p.data)
+		sums = append(sums, sum[:]...)
+	}
+	delete(b.uploads, uploadID)
+	b.mu.Unlock()
+
+	sum := md5.Sum(sums)
+	obj := &object{
+		key:         key,
+		data:        data,
+		etag:        fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), len(completed)),
+		contentType: upload.contentType,
+		metadata:    upload.metadata,
+		headers:     upload.headers,
+	}
+	if err := b.putObject(bucketName, obj, ""); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (b *Backend) abortMultipartUpload(bucketName, key, uploadID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.uploadLocked(bucketName, key, uploadID); err != nil {
+		return err
+	}
+	delete(b.uploads, uploadID)
+	return nil
+}
+
+// Client
+
+// Credentials are an AWS access key, as aws.Credentials
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Options configure a Client
+type Options struct {
+	// Region is used to sign presigned URLs; it defaults to us-east-1
+	Region string
+	// Credentials sign presigned URLs
+	Credentials Credentials
+	// BaseEndpoint is the URL presigned URLs point at, usually the URL of
+	// a server running NewHandler. It defaults to the region's S3
+	// endpoint.
+	BaseEndpoint *string
+}
+
+// Client is an S3 client working directly on a Backend
+type Client struct {
+	backend *Backend
+	options Options
+}
+
+// New creates a client for backend
+func New(backend *Backend, optFns ...func(*Options)) *Client {
+	options := Options{Region: "us-east-1"}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	return &Client{backend: backend, options: options}
+}
+
+// Bucket describes a bucket in ListBuckets results
+type Bucket struct {
+	Name         *string
+	CreationDate *time.Time
+}
+
+// Object describes an object in ListObjectsV2 results
+type Object struct {
+	Key          *string
+	ETag         *string
+	LastModified *time.Time
+	Size         *int64
+	StorageClass string
+}
+
+// CommonPrefix is a prefix that keys were rolled up into
+type CommonPrefix struct {
+	Prefix *string
+}
+
+// CompletedPart names an uploaded part when completing an upload
+type CompletedPart struct {
+	ETag       *string
+	PartNumber *int32
+}
+
+// CompletedMultipartUpload lists the parts of a completed upload
+type CompletedMultipartUpload struct {
+	Parts []CompletedPart
+}
+
+// Part describes an uploaded part in ListParts results
+type Part struct {
+	ETag         *string
+	LastModified *time.Time
+	PartNumber   *int32
+	Size         *int64
+}
+
+// CreateBucketInput is the input of CreateBucket
+type CreateBucketInput struct {
+	Bucket *string
+}
+
+// CreateBucketOutput is the output of CreateBucket
+type CreateBucketOutput struct {
+	Location *string
+}
+
+// CreateBucket creates a bucket
+func (c *Client) CreateBucket(ctx context.Context, params *CreateBucketInput) (*CreateBucketOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("CreateBucket", err)
+	}
+	if err := c.backend.createBucket(ToString(params.Bucket)); err != nil {
+		return nil, sdkError("CreateBucket", err)
+	}
+	return &CreateBucketOutput{Location: String("/" + ToString(params.Bucket))}, nil
+}
+
+// DeleteBucketInput is the input of DeleteBucket
+type DeleteBucketInput struct {
+	Bucket *string
+}
+
+// DeleteBucketOutput is the output of DeleteBucket
+type DeleteBucketOutput struct{}
+
+// DeleteBucket deletes an empty bucket
+func (c *Client) DeleteBucket(ctx context.Context, params *DeleteBucketInput) (*DeleteBucketOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("DeleteBucket", err)
+	}
+	if err := c.backend.deleteBucket(ToString(params.Bucket)); err != nil {
+		return nil, sdkError("DeleteBucket", err)
+	}
+	return &DeleteBucketOutput{}, nil
+}
+
+// HeadBucketInput is the input of HeadBucket
+type HeadBucketInput struct {
+	Bucket *string
+}
+
+// HeadBucketOutput is the output of HeadBucket
+type HeadBucketOutput struct {
+	BucketRegion *string
+}
+
+// HeadBucket checks that a bucket exists
+func (c *Client) HeadBucket(ctx context.Context, params *HeadBucketInput) (*HeadBucketOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("HeadBucket", err)
+	}
+	if err := c.backend.headBucket(ToString(params.Bucket)); err != nil {
+		return nil, sdkError("HeadBucket", err)
+	}
+	return &HeadBucketOutput{BucketRegion: String(c.options.Region)}, nil
+}
+
+// ListBucketsInput is the input of ListBuckets
+type ListBucketsInput struct{}
+
+// ListBucketsOutput is the output of ListBuckets
+type ListBucketsOutput struct {
+	Buckets []Bucket
+}
+
+// ListBuckets lists all buckets by name
+func (c *Client) ListBuckets(ctx context.Context, params *ListBucketsInput) (*ListBucketsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("ListBuckets", err)
+	}
+	return &ListBucketsOutput{Buckets: c.backend.listBuckets()}, nil
+}
+
+// PutObjectInput is the input of PutObject
+type PutObjectInput struct {
+	Bucket             *string
+	Key                *string
+	Body               io.Reader
+	ContentType        *string
+	CacheControl       *string
+	ContentDisposition *string
+	ContentEncoding    *string
+	ContentLanguage    *string
+	Metadata           map[string]string
+	// IfNoneMatch "*" only writes the object if the key is free
+	IfNoneMatch *string
+}
+
+// PutObjectOutput is the output of PutObject
+type PutObjectOutput struct {
+	ETag *string
+}
+
+// PutObject stores an object, replacing any object with the same key
+func (c *Client) PutObject(ctx context.Context, params *PutObjectInput) (*PutObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("PutObject", err)
+	}
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, sdkError("PutObject", err)
+		}
+	}
+	obj := &object{
+		key:         ToString(params.Key),
+		data:        data,
+		contentType: ToString(params.ContentType),
+		metadata:    copyMetadata(params.Metadata),
+		headers: objectHeaders{
+			cacheControl:       ToString(params.CacheControl),
+			contentDisposition: ToString(params.ContentDisposition),
+			contentEncoding:    ToString(params.ContentEncoding),
+			contentLanguage:    ToString(params.ContentLanguage),
+		},
+	}
+	if err := c.backend.putObject(ToString(params.Bucket), obj, ToString(params.IfNoneMatch)); err != nil {
+		return nil, sdkError("PutObject", err)
+	}
+	return &PutObjectOutput{ETag: String(obj.etag)}, nil
+}
+
+// GetObjectInput is the input of GetObject
+type GetObjectInput struct {
+	Bucket      *string
+	Key         *string
+	Range       *string
+	IfMatch     *string
+	IfNoneMatch *string
+}
+
+// GetObjectOutput is the output of GetObject; the caller closes Body
+type GetObjectOutput struct {
+	Body               io.ReadCloser
+	ContentLength      *int64
+	ContentRange       *string
+	ContentType        *string
+	CacheControl       *string
+	ContentDisposition *string
+	ContentEncoding    *string
+	ContentLanguage    *string
+	ETag               *string
+	LastModified       *time.Time
+	Metadata           map[string]string
+}
+
+// GetObject reads an object, or a byte range of it
+func (c *Client) GetObject(ctx context.Context, params *GetObjectInput) (*GetObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("GetObject", err)
+	}
+	obj, err := c.backend.getObject(ToString(params.Bucket), ToString(params.Key))
+	if err == nil {
+		err = checkConditions(obj, ToString(params.IfMatch), ToString(params.IfNoneMatch))
+	}
+	if err != nil {
+		return nil, sdkError("GetObject", err)
+	}
+	head := headOutput(obj)
+	out := &GetObjectOutput{
+		ContentLength:      head.ContentLength,
+		ContentType:        head.ContentType,
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		ETag:               head.ETag,
+		LastModified:       head.LastModified,
+		Metadata:           head.Metadata,
+	}
+	data := obj.data
+	if params.Range != nil {
+		first, last, err := parseRange(*params.Range, int64(len(data)))
+		if err != nil {
+			return nil, sdkError("GetObject", err)
+		}
+		out.ContentRange = String(fmt.Sprintf("bytes %d-%d/%d", first, last, len(data)))
+		data = data[first : last+1]
+		out.ContentLength = Int64(int64(len(data)))
+	}
+	out.Body = io.NopCloser(bytes.NewReader(data))
+	return out, nil
+}
+
+// HeadObjectInput is the input of HeadObject
+type HeadObjectInput struct {
+	Bucket      *string
+	Key         *string
+	IfMatch     *string
+	IfNoneMatch *string
+}
+
+// HeadObjectOutput is the output of HeadObject
+type HeadObjectOutput struct {
+	ContentLength      *int64
+	ContentType        *string
+	CacheControl       *string
+	ContentDisposition *string
+	ContentEncoding    *string
+	ContentLanguage    *string
+	ETag               *string
+	LastModified       *time.Time
+	Metadata           map[string]string
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return String(s)
+}
+
+func headOutput(obj *object) *HeadObjectOutput {
+	lastModified := obj.lastModified
+	return &HeadObjectOutput{
+		ContentLength:      Int64(int64(len(obj.data))),
+		ContentType:        String(obj.contentType),
+		CacheControl:       optional(obj.headers.cacheControl),
+		ContentDisposition: optional(obj.headers.contentDisposition),
+		ContentEncoding:    optional(obj.headers.contentEncoding),
+		ContentLanguage:    optional(obj.headers.contentLanguage),
+		ETag:               String(obj.etag),
+		LastModified:       &lastModified,
+		Metadata:           copyMetadata(obj.metadata),
+	}
+}
+
+// HeadObject reads an object's metadata
+func (c *Client) HeadObject(ctx context.Context, params *HeadObjectInput) (*HeadObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("HeadObject", err)
+	}
+	obj, err := c.backend.getObject(ToString(params.Bucket), ToString(params.Key))
+	if err == nil {
+		err = checkConditions(obj, ToString(params.IfMatch), ToString(params.IfNoneMatch))
+	}
+	if err != nil {
+		return nil, sdkError("HeadObject", err)
+	}
+	return headOutput(obj), nil
+}
+
+// DeleteObjectInput is the input of DeleteObject
+type DeleteObjectInput struct {
+	Bucket *string
+	Key    *string
+}
+
+// DeleteObjectOutput is the output of DeleteObject
+type DeleteObjectOutput struct{}
+
+// DeleteObject deletes an object; deleting a missing key succeeds
+func (c *Client) DeleteObject(ctx context.Context, params *DeleteObjectInput) (*DeleteObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("DeleteObject", err)
+	}
+	if err := c.backend.deleteObject(ToString(params.Bucket), ToString(params.Key)); err != nil {
+		return nil, sdkError("DeleteObject", err)
+	}
+	return &DeleteObjectOutput{}, nil
+}
+
+// Metadata directives for CopyObject
+const (
+	MetadataDirectiveCopy    = "COPY"
+	MetadataDirectiveReplace = "REPLACE"
+)
+
+// CopyObjectInput is the input of CopyObject. CopySource is
+// "bucket/key", with the key URL-encoded.
+type CopyObjectInput struct {
+	Bucket            *string
+	Key               *string
+	CopySource        *string
+	MetadataDirective string
+	ContentType       *string
+	Metadata          map[string]string
+}
+
+// CopyObjectResult describes the new copy
+type CopyObjectResult struct {
+	ETag         *string
+	LastModified *time.Time
+}
+
+// CopyObjectOutput is the output of CopyObject
+type CopyObjectOutput struct {
+	CopyObjectResult *CopyObjectResult
+}
+
+// parseCopySource splits "bucket/key" or "/bucket/key"
+func parseCopySource(source string) (string, string, error) {
+	source = strings.TrimPrefix(source, "/")
+	if i := strings.Index(source, "?"); i >= 0 {
+		source = source[:i]
+	}
+	bucketName, key, ok := strings.Cut(source, "/")
+	if !ok || key == "" {
+		return "", "", errInvalidArgument("Copy Source must mention the source bucket and key: sourcebucket/sourcekey")
+	}
+	if unescaped, err := url.PathUnescape(key); err == nil {
+		key = unescaped
+	}
+	return bucketName, key, nil
+}
+
+// CopyObject copies an object within or between buckets
+func (c *Client) CopyObject(ctx context.Context, params *CopyObjectInput) (*CopyObjectOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("CopyObject", err)
+	}
+	srcBucket, srcKey, err := parseCopySource(ToString(params.CopySource))
+	if err != nil {
+		return nil, sdkError("CopyObject", err)
+	}
+	obj, err := c.backend.copyObject(srcBucket, srcKey, ToString(params.Bucket), ToString(params.Key),
+		params.MetadataDirective == MetadataDirectiveReplace, ToString(params.ContentType), params.Metadata)
+	if err != nil {
+		return nil, sdkError("CopyObject", err)
+	}
+	lastModified := obj.lastModified
+	return &CopyObjectOutput{CopyObjectResult: &CopyObjectResult{ETag: String(obj.etag), LastModified: &lastModified}}, nil
+}
+
+// ListObjectsV2Input is the input of ListObjectsV2
+type ListObjectsV2Input struct {
+	Bucket            *string
+	Prefix            *string
+	Delimiter         *string
+	MaxKeys           *int32
+	StartAfter        *string
+	ContinuationToken *string
+}
+
+// ListObjectsV2Output is the output of ListObjectsV2
+type ListObjectsV2Output struct {
+	Name                  *string
+	Prefix                *string
+	Delimiter             *string
+	MaxKeys               *int32
+	KeyCount              *int32
+	IsTruncated           *bool
+	Contents              []Object
+	CommonPrefixes        []CommonPrefix
+	ContinuationToken     *string
+	NextContinuationToken *string
+	StartAfter            *string
+}
+
+func encodeToken(marker string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(marker))
+}
+
+func decodeToken(token string) (string, error) {
+	marker, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", errInvalidArgument("The continuation token provided is incorrect")
+	}
+	return string(marker), nil
+}
+
+// listV2 runs a ListObjectsV2 request for the client and the handler
+func (b *Backend) listV2(params *ListObjectsV2Input) (*ListObjectsV2Output, error) {
+	maxKeys := int32(1000)
+	if params.MaxKeys != nil {
+		if *params.MaxKeys < 0 {
+			return nil, errInvalidArgument("maxKeys should be non-negative")
+		}
+		if *params.MaxKeys < maxKeys {
+			maxKeys = *params.MaxKeys
+		}
+	}
+	marker := ToString(params.StartAfter)
+	if params.ContinuationToken != nil {
+		token, err := decodeToken(*params.ContinuationToken)
+		if err != nil {
+			return nil, err
+		}
+		if token > marker {
+			marker = token
+		}
+	}
+	page, err := b.listObjects(ToString(params.Bucket), ToString(params.Prefix), ToString(params.Delimiter), marker, int(maxKeys))
+	if err != nil {
+		return nil, err
+	}
+	out := &ListObjectsV2Output{
+		Name:              params.Bucket,
+		Prefix:            params.Prefix,
+		Delimiter:         params.Delimiter,
+		MaxKeys:           Int32(maxKeys),
+		KeyCount:          Int32(int32(len(page.objects) + len(page.prefixes))),
+		IsTruncated:       Bool(page.isTruncated),
+		ContinuationToken: params.ContinuationToken,
+		StartAfter:        params.StartAfter,
+	}
+	for _, obj := range page.objects {
+		lastModified := obj.lastModified
+		out.Contents = append(out.Contents, Object{
+			Key:          String(obj.key),
+			ETag:         String(obj.etag),
+			LastModified: &lastModified,
+			Size:         Int64(int64(len(obj.data))),
+			StorageClass: "STANDARD",
+		})
+	}
+	for _, prefix := range page.prefixes {
+		out.CommonPrefixes = append(out.CommonPrefixes, CommonPrefix{Prefix: String(prefix)})
+	}
+	if page.isTruncated {
+		out.NextContinuationToken = String(encodeToken(page.next))
+	}
+	return out, nil
+}
+
+// ListObjectsV2 lists up to MaxKeys (at most 1000) keys by name. Keys
+// containing Delimiter after Prefix are rolled up into CommonPrefixes,
+// e.g. "photos/2024/" for "photos/2024/jan.jpg" with Prefix "photos/"
+// and Delimiter "/".
+func (c *Client) ListObjectsV2(ctx context.Context, params *ListObjectsV2Input) (*ListObjectsV2Output, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("ListObjectsV2", err)
+	}
+	out, err := c.backend.listV2(params)
+	if err != nil {
+		return nil, sdkError("ListObjectsV2", err)
+	}
+	return out, nil
+}
+
+// ListObjectsV2Paginator pages through ListObjectsV2 results
+type ListObjectsV2Paginator struct {
+	client    *Client
+	params    ListObjectsV2Input
+	nextToken *string
+	firstPage bool
+}
+
+// NewListObjectsV2Paginator creates a paginator for params
+func NewListObjectsV2Paginator(client *Client, params *ListObjectsV2Input) *ListObjectsV2Paginator {
+	if params == nil {
+		params = &ListObjectsV2Input{}
+	}
+	return &ListObjectsV2Paginator{client: client, params: *params, nextToken: params.ContinuationToken, firstPage: true}
+}
+
+// HasMorePages reports whether NextPage has a page to return
+func (p *ListObjectsV2Paginator) HasMorePages() bool {
+	return p.firstPage || p.nextToken != nil
+}
+
+// NextPage returns the next page of results
+func (p *ListObjectsV2Paginator) NextPage(ctx context.Context) (*ListObjectsV2Output, error) {
+	if !p.HasMorePages() {
+		return nil, fmt.Errorf("no more pages available")
+	}
+	params := p.params
+	params.ContinuationToken = p.nextToken
+	out, err := p.client.ListObjectsV2(ctx, &params)
+	if err != nil {
+		return nil, err
+	}
+	p.firstPage = false
+	p.nextToken = nil
+	if ToBool(out.IsTruncated) {
+		p.nextToken = out.NextContinuationToken
+	}
+	return out, nil
+}
+
+// CreateMultipartUploadInput is the input of CreateMultipartUpload
+type CreateMultipartUploadInput struct {
+	Bucket             *string
+	Key                *string
+	ContentType        *string
+	CacheControl       *string
+	ContentDisposition *string
+	ContentEncoding    *string
+	ContentLanguage    *string
+	Metadata           map[string]string
+}
+
+// CreateMultipartUploadOutput is the output of CreateMultipartUpload
+type CreateMultipartUploadOutput struct {
+	Bucket   *string
+	Key      *string
+	UploadId *string
+}
+
+// CreateMultipartUpload starts a multipart upload; the object appears
+// when the upload is completed
+func (c *Client) CreateMultipartUpload(ctx context.Context, params *CreateMultipartUploadInput) (*CreateMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("CreateMultipartUpload", err)
+	}
+	headers := objectHeaders{
+		cacheControl:       ToString(params.CacheControl),
+		contentDisposition: ToString(params.ContentDisposition),
+		contentEncoding:    ToString(params.ContentEncoding),
+		contentLanguage:    ToString(params.ContentLanguage),
+	}
+	id, err := c.backend.createMultipartUpload(ToString(params.Bucket), ToString(params.Key), ToString(params.ContentType), params.Metadata, headers)
+	if err != nil {
+		return nil, sdkError("CreateMultipartUpload", err)
+	}
+	return &CreateMultipartUploadOutput{Bucket: params.Bucket, Key: params.Key, UploadId: String(id)}, nil
+}
+
+// UploadPartInput is the input of UploadPart
+type UploadPartInput struct {
+	Bucket     *string
+	Key        *string
+	UploadId   *string
+	PartNumber *int32
+	Body       io.Reader
+}
+
+// UploadPartOutput is the output of UploadPart
+type UploadPartOutput struct {
+	ETag *string
+}
+
+// UploadPart uploads part PartNumber (1 to 10000), replacing an earlier
+// upload of the same part
+func (c *Client) UploadPart(ctx context.Context, params *UploadPartInput) (*UploadPartOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("UploadPart", err)
+	}
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, sdkError("UploadPart", err)
+		}
+	}
+	etag, err := c.backend.uploadPart(ToString(params.Bucket), ToString(params.Key), ToString(params.UploadId), ToInt32(params.PartNumber), data)
+	if err != nil {
+		return nil, sdkError("UploadPart", err)
+	}
+	return &UploadPartOutput{ETag: String(etag)}, nil
+}
+
+// ListPartsInput is the input of ListParts
+type ListPartsInput struct {
+	Bucket   *string
+	Key      *string
+	UploadId *string
+}
+
+// ListPartsOutput is the output of ListParts
+type ListPartsOutput struct {
+	Bucket   *string
+	Key      *string
+	UploadId *string
+	Parts    []Part
+}
+
+// ListParts lists the parts uploaded so far
+func (c *Client) ListParts(ctx context.Context, params *ListPartsInput) (*ListPartsOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("ListParts", err)
+	}
+	parts, err := c.backend.listParts(ToString(params.Bucket), ToString(params.Key), ToString(params.UploadId))
+	if err != nil {
+		return nil, sdkError("ListParts", err)
+	}
+	out := &ListPartsOutput{Bucket: params.Bucket, Key: params.Key, UploadId: params.UploadId}
+	for _, p := range parts {
+		lastModified := p.lastModified
+		out.Parts = append(out.Parts, Part{
+			ETag:         String(p.etag),
+			LastModified: &lastModified,
+			PartNumber:   Int32(p.number),
+			Size:         Int64(int64(len(p.data))),
+		})
+	}
+	return out, nil
+}
+
+// CompleteMultipartUploadInput is the input of CompleteMultipartUpload
+type CompleteMultipartUploadInput struct {
+	Bucket          *string
+	Key             *string
+	UploadId        *string
+	MultipartUpload *CompletedMultipartUpload
+}
+
+// CompleteMultipartUploadOutput is the output of CompleteMultipartUpload
+type CompleteMultipartUploadOutput struct {
+	Bucket   *string
+	Key      *string
+	ETag     *string
+	Location *string
+}
+
+// CompleteMultipartUpload joins the listed parts, in ascending part
+// number order, into the object
+func (c *Client) CompleteMultipartUpload(ctx context.Context, params *CompleteMultipartUploadInput) (*CompleteMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("CompleteMultipartUpload", err)
+	}
+	var parts []CompletedPart
+	if params.MultipartUpload != nil {
+		parts = params.MultipartUpload.Parts
+	}
+	obj, err := c.backend.completeMultipartUpload(ToString(params.Bucket), ToString(params.Key), ToString(params.UploadId), parts)
+	if err != nil {
+		return nil, sdkError("CompleteMultipartUpload", err)
+	}
+	return &CompleteMultipartUploadOutput{
+		Bucket:   params.Bucket,
+		Key:      params.Key,
+		ETag:     String(obj.etag),
+		Location: String(c.objectURL(ToString(params.Bucket), obj.key)),
+	}, nil
+}
+
+// AbortMultipartUploadInput is the input of AbortMultipartUpload
+type AbortMultipartUploadInput struct {
+	Bucket   *string
+	Key      *string
+	UploadId *string
+}
+
+// AbortMultipartUploadOutput is the output of AbortMultipartUpload
+type AbortMultipartUploadOutput struct{}
+
+// AbortMultipartUpload discards an upload and its parts
+func (c *Client) AbortMultipartUpload(ctx context.Context, params *AbortMultipartUploadInput) (*AbortMultipartUploadOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, sdkError("AbortMultipartUpload", err)
+	}
+	if err := c.backend.abortMultipartUpload(ToString(params.Bucket), ToString(params.Key), ToString(params.UploadId)); err != nil {
+		return nil, sdkError("AbortMultipartUpload", err)
+	}
+	return &AbortMultipartUploadOutput{}, nil
+}
+
+// endpoint returns the base URL of the client's endpoint
+func (c *Client) endpoint() string {
+	if c.options.BaseEndpoint != nil {
+		return strings.TrimSuffix(*c.options.BaseEndpoint, "/")
+	}
+	return "https://s3." + c.options.Region + ".amazonaws.com"
+}
+
+// objectURL returns the path-style URL of an object
+func (c *Client) objectURL(bucketName, key string) string {
+	return c.endpoint() + "/" + bucketName + "/" + escapePath(key)
+}
+
+// Signing
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+
+	// UnsignedPayload is the payload hash of requests whose body is not
+	// signed, such as presigned URLs
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// EmptyPayloadHash is the SHA-256 of an empty body
+	EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	maxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// Signer signs requests with AWS Signature Version 4, as v4.Signer
+type Signer struct{}
+
+// NewSigner creates a Signer
+func NewSigner() *Signer {
+	return &Signer{}
+}
+
+// escapePath URI-encodes each segment of an S3 path
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode percent-encodes everything but unreserved characters
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// canonicalQuery sorts and encodes query for signing
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func requestHost(r *http.Request) string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.URL.Host
+}
+
+// canonicalRequest is the request as SigV4 hashes it
+func canonicalRequest(r *http.Request, query url.Values, signedHeaders []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := strings.Join(r.Header.Values(name), ",")
+		if name == "host" {
+			value = requestHost(r)
+		}
+		headers.WriteString(name + ":" + strings.Join(strings.Fields(value), " ") + "\n")
+	}
+	path := r.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		r.Method,
+		escapePath(path),
+		canonicalQuery(query),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signature signs canonical with a key derived from secret and scope
+func signature(secret, amzDate, scope, canonical string) string {
+	parts := strings.Split(scope, "/")
+	key := []byte("AWS4" + secret)
+	for _, p := range parts {
+		key = hmacSHA256(key, p)
+	}
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := signingAlgorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func credentialScope(signingTime time.Time, region, service string) string {
+	return signingTime.UTC().Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+}
+
+// headersToSign returns the sorted, lowercase names of the headers to
+// sign: Host, Content-Type, Content-MD5 and all X-Amz-* headers
+func headersToSign(header http.Header) []string {
+	names := []string{"host"}
+	for name := range header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || lower == "content-md5" || strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SignHTTP signs r in its Authorization header. payloadHash is the hex
+// SHA-256 of the body, or UnsignedPayload.
+func (s *Signer) SignHTTP(ctx context.Context, credentials Credentials, r *http.Request, payloadHash, service, region string, signingTime time.Time) error {
+	amzDate := signingTime.UTC().Format(amzDateFormat)
+	r.Header.Set("X-Amz-Date", amzDate)
+	r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	signed := headersToSign(r.Header)
+	scope := credentialScope(signingTime, region, service)
+	sig := signature(credentials.SecretAccessKey, amzDate, scope, canonicalRequest(r, r.URL.Query(), signed, payloadHash))
+	r.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, credentials.AccessKeyID, scope, strings.Join(signed, ";"), sig))
+	return nil
+}
+
+// PresignHTTP returns a URL for r signed in its query, valid for the
+// seconds in r's X-Amz-Expires parameter (15 minutes if unset), and the
+// headers that were signed, which requests to the URL must send.
+func (s *Signer) PresignHTTP(ctx context.Context, credentials Credentials, r *http.Request, payloadHash, service, region string, signingTime time.Time) (string, http.Header, error) {
+	amzDate := signingTime.UTC().Format(amzDateFormat)
+	scope := credentialScope(signingTime, region, service)
+	signed := headersToSign(r.Header)
+
+	query := r.URL.Query()
+	if query.Get("X-Amz-Expires") == "" {
+		query.Set("X-Amz-Expires", "900")
+	}
+	query.Set("X-Amz-Algorithm", signingAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-SignedHeaders", strings.Join(signed, ";"))
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	sig := signature(credentials.SecretAccessKey, amzDate, scope, canonicalRequest(r, query, signed, payloadHash))
+
+	u := *r.URL
+	u.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + sig
+	header := http.Header{}
+	for _, name := range signed {
+		if name == "host" {
+			header.Set("Host", requestHost(r))
+		} else {
+			header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+	return u.String(), header, nil
+}
+
+// PresignOptions configure presigned requests
+type PresignOptions struct {
+	// Expires is how long the URL is valid, 15 minutes by default and
+	// at most 7 days
+	Expires time.Duration
+}
+
+// WithPresignExpires sets how long presigned URLs are valid
+func WithPresignExpires(d time.Duration) func(*PresignOptions) {
+	return func(o *PresignOptions) {
+		o.Expires = d
+	}
+}
+
+// PresignedHTTPRequest is a presigned request. Requests to URL must use
+// Method and send SignedHeader.
+type PresignedHTTPRequest struct {
+	URL          string
+	Method       string
+	SignedHeader http.Header
+}
+
+// PresignClient creates presigned URLs for a client's endpoint and
+// credentials
+type PresignClient struct {
+	client  *Client
+	options PresignOptions
+	signer  *Signer
+}
+
+// NewPresignClient creates a PresignClient
+func NewPresignClient(client *Client, optFns ...func(*PresignOptions)) *PresignClient {
+	options := PresignOptions{Expires: 15 * time.Minute}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	return &PresignClient{client: client, options: options, signer: NewSigner()}
+}
+
+func (p *PresignClient) presign(method, bucketName, key string, header http.Header, optFns []func(*PresignOptions)) (*PresignedHTTPRequest, error) {
+	options := p.options
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	if options.Expires <= 0 || options.Expires > maxPresignExpiry {
+		return nil, fmt.Errorf("presign: expires must be between 1 second and 7 days, got %v", options.Expires)
+	}
+	creds := p.client.options.Credentials
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("presign: no credentials configured")
+	}
+	req, err := http.NewRequest(method, p.client.objectURL(bucketName, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	query := req.URL.Query()
+	query.Set("X-Amz-Expires", strconv.Itoa(int(options.Expires/time.Second)))
+	req.URL.RawQuery = query.Encode()
+
+	signedURL, signedHeader, err := p.signer.PresignHTTP(context.Background(), creds, req, UnsignedPayload, "s3", p.client.options.Region, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedHTTPRequest{URL: signedURL, Method: method, SignedHeader: signedHeader}, nil
+}
+
+// PresignGetObject presigns a GetObject request
+func (p *PresignClient) PresignGetObject(ctx context.Context, params *GetObjectInput, optFns ...func(*PresignOptions)) (*PresignedHTTPRequest, error) {
+	return p.presign(http.MethodGet, ToString(params.Bucket), ToString(params.Key), nil, optFns)
+}
+
+// PresignPutObject presigns a PutObject request. A ContentType in params
+// is signed, so the upload must send it.
+func (p *PresignClient) PresignPutObject(ctx context.Context, params *PutObjectInput, optFns ...func(*PresignOptions)) (*PresignedHTTPRequest, error) {
+	header := http.Header{}
+	if params.ContentType != nil {
+		header.Set("Content-Type", *params.ContentType)
+	}
+	for k, v := range params.Metadata {
+		header.Set("X-Amz-Meta-"+k, v)
+	}
+	return p.presign(http.MethodPut, ToString(params.Bucket), ToString(params.Key), header, optFns)
+}
+
+// PresignHeadObject presigns a HeadObject request
+func (p *PresignClient) PresignHeadObject(ctx context.Context, params *HeadObjectInput, optFns ...func(*PresignOptions)) (*PresignedHTTPRequest, error) {
+	return p.presign(http.MethodHead, ToString(params.Bucket), ToString(params.Key), nil, optFns)
+}
+
+// PresignDeleteObject presigns a DeleteObject request
+func (p *PresignClient) PresignDeleteObject(ctx context.Context, params *DeleteObjectInput, optFns ...func(*PresignOptions)) (*PresignedHTTPRequest, error) {
+	return p.presign(http.MethodDelete, ToString(params.Bucket), ToString(params.Key), nil, optFns)
+}
+
+// Verification
+
+// parseCredential splits "AKID/20240102/us-east-1/s3/aws4_request" into
+// the access key and the scope
+func parseCredential(credential string) (string, string, bool) {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" {
+		return "", "", false
+	}
+	return parts[0], strings.Join(parts[1:], "/"), true
+}
+
+// checkSignature compares sig with the signature of r made with the
+// secret of accessKey
+func (b *Backend) checkSignature(r *http.Request, query url.Values, accessKey, scope, amzDate string, signedHeaders []string, payloadHash, sig string) error {
+	b.mu.RLock()
+	secret, ok := b.credentials[accessKey]
+	b.mu.RUnlock()
+	if !ok {
+		return &s3Error{http.StatusForbidden, "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records."}
+	}
+	if !strings.HasPrefix(amzDate, strings.SplitN(scope, "/", 2)[0]) {
+		return errSignatureDoesNotMatch()
+	}
+	expected := signature(secret, amzDate, scope, canonicalRequest(r, query, signedHeaders, payloadHash))
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return errSignatureDoesNotMatch()
+	}
+	return nil
+}
+
+// verifyPresigned checks a request made to a presigned URL
+func (b *Backend) verifyPresigned(r *http.Request) error {
+	query := r.URL.Query()
+	malformed := &s3Error{http.StatusBadRequest, "AuthorizationQueryParametersError", "Query-string authentication version 4 requires the X-Amz-Algorithm, X-Amz-Credential, X-Amz-Signature, X-Amz-Date, X-Amz-SignedHeaders, and X-Amz-Expires parameters."}
+	sig := query.Get("X-Amz-Signature")
+	if query.Get("X-Amz-Algorithm") != signingAlgorithm || sig == "" {
+		return malformed
+	}
+	accessKey, scope, ok := parseCredential(query.Get("X-Amz-Credential"))
+	if !ok {
+		return malformed
+	}
+	signedAt, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return malformed
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 {
+		return malformed
+	}
+	if time.Duration(expires)*time.Second > maxPresignExpiry {
+		return &s3Error{http.StatusBadRequest, "AuthorizationQueryParametersError", "X-Amz-Expires must be less than a week (in seconds) that is 604800"}
+	}
+	if b.now().After(signedAt.Add(time.Duration(expires) * time.Second)) {
+		return errAccessDenied("Request has expired")
+	}
+	query.Del("X-Amz-Signature")
+	signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	return b.checkSignature(r, query, accessKey, scope, query.Get("X-Amz-Date"), signedHeaders, UnsignedPayload, sig)
+}
+
+// verifyAuthorization checks a request signed in its Authorization header
+func (b *Backend) verifyAuthorization(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	malformed := &s3Error{http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed"}
+	if !strings.HasPrefix(auth, signingAlgorithm+" ") {
+		return malformed
+	}
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, signingAlgorithm+" "), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(field), "=")
+		fields[k] = v
+	}
+	accessKey, scope, ok := parseCredential(fields["Credential"])
+	if !ok || fields["SignedHeaders"] == "" || fields["Signature"] == "" {
+		return malformed
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = EmptyPayloadHash
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	return b.checkSignature(r, r.URL.Query(), accessKey, scope, r.Header.Get("X-Amz-Date"), signedHeaders, payloadHash, fields["Signature"])
+}
+
+// verifyRequest authenticates a request to the HTTP handler. Requests
+// are anonymous, and allowed, until credentials are added.
+func (b *Backend) verifyRequest(r *http.Request) error {
+	b.mu.RLock()
+	open := len(b.credentials) == 0
+	b.mu.RUnlock()
+	if open {
+		return nil
+	}
+	if r.URL.Query().Get("X-Amz-Signature") != "" {
+		return b.verifyPresigned(r)
+	}
+	if r.Header.Get("Authorization") == "" {
+		return errAccessDenied("Access Denied")
+	}
+	return b.verifyAuthorization(r)
+}
+
+// ValidatePresignedURL checks that a request with method and header to
+// rawURL is allowed by the URL's signature and has not expired. The URL
+// must be signed with credentials added to the backend.
+func (b *Backend) ValidatePresignedURL(method, rawURL string, header http.Header) error {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		if strings.EqualFold(name, "Host") {
+			req.Host = values[0]
+		} else {
+			req.Header[name] = values
+		}
+	}
+	if req.URL.Query().Get("X-Amz-Signature") == "" {
+		return errAccessDenied("Access Denied")
+	}
+	return b.verifyPresigned(req)
+}
+
+// HTTP handler
+
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+const iso8601Format = "2006-01-02T15:04:05.000Z"
+
+// Handler serves a Backend over the S3 REST API with path-style URLs
+// (http://host/bucket/key), so the AWS SDK, the CLI and presigned URLs
+// can reach it through httptest.NewServer or http.ListenAndServe
+type Handler struct {
+	backend *Backend
+}
+
+// NewHandler creates a handler for backend
+func NewHandler(backend *Backend) *Handler {
+	return &Handler{backend: backend}
+}
+
+type xmlError struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource"`
+	RequestID string   `xml:"RequestId"`
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var se *s3Error
+	if !errors.As(err, &se) {
+		se = &s3Error{http.StatusInternalServerError, "InternalError", err.Error()}
+	}
+	if r.Method == http.MethodHead || se.status == http.StatusNotModified {
+		w.WriteHeader(se.status)
+		return
+	}
+	writeXML(w, se.status, xmlError{Code: se.code, Message: se.message, Resource: r.URL.Path, RequestID: newUploadID()[:16]})
+}
+
+// ServeHTTP dispatches S3 REST requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Amz-Request-Id", newUploadID()[:16])
+	if err := h.backend.verifyRequest(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	var err error
+	switch {
+	case bucketName == "" && r.Method == http.MethodGet:
+		err = h.listBuckets(w)
+	case bucketName == "":
+		err = &s3Error{http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource."}
+	case key == "":
+		err = h.serveBucket(w, r, bucketName, query)
+	default:
+		err = h.serveObject(w, r, bucketName, key, query)
+	}
+	if err != nil {
+		writeError(w, r, err)
+	}
+}
+
+func notImplemented() error {
+	return &s3Error{http.StatusNotImplemented, "NotImplemented", "A header or query you provided implies functionality that is not implemented."}
+}
+
+func (h *Handler) serveBucket(w http.ResponseWriter, r *http.Request, bucketName string, query url.Values) error {
+	switch r.Method {
+	case http.MethodPut:
+		if err := h.backend.createBucket(bucketName); err != nil {
+			return err
+		}
+		w.Header().Set("Location", "/"+bucketName)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if err := h.backend.deleteBucket(bucketName); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		if err := h.backend.headBucket(bucketName); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		if _, ok := query["uploads"]; ok {
+			return notImplemented()
+		}
+		return h.listObjects(w, bucketName, query)
+	default:
+		return notImplemented()
+	}
+	return nil
+}
+
+type xmlBucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type xmlListBuckets struct {
+	XMLName xml.Name    `xml:"ListAllMyBucketsResult"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	OwnerID string      `xml:"Owner>ID"`
+	Buckets []xmlBucket `xml:"Buckets>Bucket"`
+}
+
+func (h *Handler) listBuckets(w http.ResponseWriter) error {
+	result := xmlListBuckets{Xmlns: s3Namespace, OwnerID: "emulator"}
+	for _, bkt := range h.backend.listBuckets() {
+		result.Buckets = append(result.Buckets, xmlBucket{Name: *bkt.Name, CreationDate: bkt.CreationDate.Format(iso8601Format)})
+	}
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
+
+type xmlContent struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type xmlPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type xmlListObjects struct {
+	XMLName               xml.Name     `xml:"ListBucketResult"`
+	Xmlns                 string       `xml:"xmlns,attr"`
+	Name                  string       `xml:"Name"`
+	Prefix                string       `xml:"Prefix"`
+	Delimiter             string       `xml:"Delimiter,omitempty"`
+	MaxKeys               int32        `xml:"MaxKeys"`
+	KeyCount              *int32       `xml:"KeyCount,omitempty"`
+	IsTruncated           bool         `xml:"IsTruncated"`
+	Marker                *string      `xml:"Marker,omitempty"`
+	NextMarker            string       `xml:"NextMarker,omitempty"`
+	ContinuationToken     string       `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string       `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string       `xml:"StartAfter,omitempty"`
+	Contents              []xmlContent `xml:"Contents"`
+	CommonPrefixes        []xmlPrefix  `xml:"CommonPrefixes"`
+}
+
+func queryString(query url.Values, name string) *string {
+	if _, ok := query[name]; !ok {
+		return nil
+	}
+	return String(query.Get(name))
+}
+
+// listObjects serves ListObjectsV2 (list-type=2) and the original
+// ListObjects, which pages with Marker instead of continuation tokens
+func (h *Handler) listObjects(w http.ResponseWriter, bucketName string, query url.Values) error {
+	params := &ListObjectsV2Input{
+		Bucket:            String(bucketName),
+		Prefix:            queryString(query, "prefix"),
+		Delimiter:         queryString(query, "delimiter"),
+		StartAfter:        queryString(query, "start-after"),
+		ContinuationToken: queryString(query, "continuation-token"),
+	}
+	v1 := query.Get("list-type") != "2"
+	if v1 {
+		params.StartAfter = queryString(query, "marker")
+		params.ContinuationToken = nil
+	}
+	if maxKeys := query.Get("max-keys"); maxKeys != "" {
+		n, err := strconv.Atoi(maxKeys)
+		if err != nil {
+			return errInvalidArgument("Provided max-keys not an integer or within integer range")
+		}
+		params.MaxKeys = Int32(int32(n))
+	}
+	out, err := h.backend.listV2(params)
+	if err != nil {
+		return err
+	}
+
+	result := xmlListObjects{
+		Xmlns:       s3Namespace,
+		Name:        bucketName,
+		Prefix:      ToString(params.Prefix),
+		Delimiter:   ToString(params.Delimiter),
+		MaxKeys:     ToInt32(out.MaxKeys),
+		IsTruncated: ToBool(out.IsTruncated),
+	}
+	for _, obj := range out.Contents {
+		result.Contents = append(result.Contents, xmlContent{
+			Key:          *obj.Key,
+			LastModified: obj.LastModified.Format(iso8601Format),
+			ETag:         *obj.ETag,
+			Size:         *obj.Size,
+			StorageClass: obj.StorageClass,
+		})
+	}
+	for _, p := range out.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, xmlPrefix{Prefix: *p.Prefix})
+	}
+	if v1 {
+		result.Marker = String(ToString(params.StartAfter))
+		if out.NextContinuationToken != nil {
+			result.NextMarker, _ = decodeToken(*out.NextContinuationToken)
+		}
+	} else {
+		result.KeyCount = out.KeyCount
+		result.ContinuationToken = ToString(params.ContinuationToken)
+		result.NextContinuationToken = ToString(out.NextContinuationToken)
+		result.StartAfter = ToString(params.StartAfter)
+	}
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
+
+// readBody reads a request body, decoding the aws-chunked encoding the
+// SDK uses for streaming uploads
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	if !strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") &&
+		!strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, errInvalidArgument("Invalid aws-chunked body")
+		}
+		sizeField, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeField, 16, 64)
+		if err != nil || size < 0 {
+			return nil, errInvalidArgument("Invalid aws-chunked body")
+		}
+		if size == 0 {
+			// Trailing checksums are not verified
+			io.Copy(io.Discard, br)
+			return data, nil
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, errInvalidArgument("Invalid aws-chunked body")
+		}
+		data = append(data, chunk...)
+		br.ReadString('\n')
+	}
+}
+
+// metadataFromHeader collects x-amz-meta-* headers
+func metadataFromHeader(header http.Header) map[string]string {
+	var metadata map[string]string
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-meta-") {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[strings.TrimPrefix(lower, "x-amz-meta-")] = values[0]
+		}
+	}
+	return metadata
+}
+
+func headersFromRequest(header http.Header) objectHeaders {
+	var encodings []string
+	for _, enc := range strings.Split(header.Get("Content-Encoding"), ",") {
+		if enc = strings.TrimSpace(enc); enc != "" && enc != "aws-chunked" {
+			encodings = append(encodings, enc)
+		}
+	}
+	return objectHeaders{
+		cacheControl:       header.Get("Cache-Control"),
+		contentDisposition: header.Get("Content-Disposition"),
+		contentEncoding:    strings.Join(encodings, ","),
+		contentLanguage:    header.Get("Content-Language"),
+	}
+}
+
+func writeObjectHeaders(w http.ResponseWriter, obj *object) {
+	h := w.Header()
+	h.Set("ETag", obj.etag)
+	h.Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+	h.Set("Content-Type", obj.contentType)
+	h.Set("Accept-Ranges", "bytes")
+	for name, value := range map[string]string{
+		"Cache-Control":       obj.headers.cacheControl,
+		"Content-Disposition": obj.headers.contentDisposition,
+		"Content-Encoding":    obj.headers.contentEncoding,
+		"Content-Language":    obj.headers.contentLanguage,
+	} {
+		if value != "" {
+			h.Set(name, value)
+		}
+	}
+	for k, v := range obj.metadata {
+		h.Set("X-Amz-Meta-"+k, v)
+	}
+}
+
+type xmlCopyResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+type xmlInitiateUpload struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type xmlCompleteUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []struct {
+		PartNumber int32  `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type xmlCompleteResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+type xmlPart struct {
+	PartNumber   int32  `xml:"PartNumber"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+}
+
+type xmlListParts struct {
+	XMLName  xml.Name  `xml:"ListPartsResult"`
+	Xmlns    string    `xml:"xmlns,attr"`
+	Bucket   string    `xml:"Bucket"`
+	Key      string    `xml:"Key"`
+	UploadID string    `xml:"UploadId"`
+	Parts    []xmlPart `xml:"Part"`
+}
+
+func (h *Handler) serveObject(w http.ResponseWriter, r *http.Request, bucketName, key string, query url.Values) error {
+	_, uploads := query["uploads"]
+	uploadID := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodGet && uploadID != "":
+		return h.listParts(w, bucketName, key, uploadID)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return h.getObject(w, r, bucketName, key)
+	case r.Method == http.MethodPut && uploadID != "":
+		return h.uploadPart(w, r, bucketName, key, uploadID, query.Get("partNumber"))
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		return h.copyObject(w, r, bucketName, key)
+	case r.Method == http.MethodPut:
+		return h.putObject(w, r, bucketName, key)
+	case r.Method == http.MethodDelete && uploadID != "":
+		if err := h.backend.abortMultipartUpload(bucketName, key, uploadID); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete:
+		if err := h.backend.deleteObject(bucketName, key); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && uploads:
+		return h.createUpload(w, r, bucketName, key)
+	case r.Method == http.MethodPost && uploadID != "":
+		return h.completeUpload(w, r, bucketName, key, uploadID)
+	default:
+		return notImplemented()
+	}
+	return nil
+}
+
+func (h *Handler) getObject(w http.ResponseWriter, r *http.Request, bucketName, key string) error {
+	obj, err := h.backend.getObject(bucketName, key)
+	if err != nil {
+		return err
+	}
+	if err := checkConditions(obj, r.Header.Get("If-Match"), r.Header.Get("If-None-Match")); err != nil {
+		return err
+	}
+	writeObjectHeaders(w, obj)
+	data := obj.data
+	status := http.StatusOK
+	if spec := r.Header.Get("Range"); spec != "" && r.Method == http.MethodGet {
+		first, last, err := parseRange(spec, int64(len(data)))
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, len(data)))
+		data = data[first : last+1]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+	return nil
+}
+
+func (h *Handler) putObject(w http.ResponseWriter, r *http.Request, bucketName, key string) error {
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	obj := &object{
+		key:         key,
+		data:        data,
+		contentType: r.Header.Get("Content-Type"),
+		metadata:    metadataFromHeader(r.Header),
+		headers:     headersFromRequest(r.Header),
+	}
+	if err := h.backend.putObject(bucketName, obj, r.Header.Get("If-None-Match")); err != nil {
+		return err
+	}
+	w.Header().Set("ETag", obj.etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (h *Handler) copyObject(w http.ResponseWriter, r *http.Request, bucketName, key string) error {
+	srcBucket, srcKey, err := parseCopySource(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		return err
+	}
+	replace := strings.EqualFold(r.Header.Get("X-Amz-Metadata-Directive"), MetadataDirectiveReplace)
+	obj, err := h.backend.copyObject(srcBucket, srcKey, bucketName, key, replace, r.Header.Get("Content-Type"), metadataFromHeader(r.Header))
+	if err != nil {
+		return err
+	}
+	writeXML(w, http.StatusOK, xmlCopyResult{Xmlns: s3Namespace, LastModified: obj.lastModified.Format(iso8601Format), ETag: obj.etag})
+	return nil
+}
+
+func (h *Handler) createUpload(w http.ResponseWriter, r *http.Request, bucketName, key string) error {
+	id, err := h.backend.createMultipartUpload(bucketName, key, r.Header.Get("Content-Type"), metadataFromHeader(r.Header), headersFromRequest(r.Header))
+	if err != nil {
+		return err
+	}
+	writeXML(w, http.StatusOK, xmlInitiateUpload{Xmlns: s3Namespace, Bucket: bucketName, Key: key, UploadID: id})
+	return nil
+}
+
+func (h *Handler) uploadPart(w http.ResponseWriter, r *http.Request, bucketName, key, uploadID, partNumber string) error {
+	number, err := strconv.Atoi(partNumber)
+	if err != nil {
+		return errInvalidArgument("Part number must be an integer between 1 and 10000, inclusive")
+	}
+	data, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	etag, err := h.backend.uploadPart(bucketName, key, uploadID, int32(number), data)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (h *Handler) completeUpload(w http.ResponseWriter, r *http.Request, bucketName, key, uploadID string) error {
+	var body xmlCompleteUpload
+	if err := xml.NewDecoder(r.Body).Decode(&body); err != nil {
+		return &s3Error{http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema"}
+	}
+	parts := make([]CompletedPart, len(body.Parts))
+	for i, p := range body.Parts {
+		parts[i] = CompletedPart{PartNumber: Int32(p.PartNumber), ETag: String(p.ETag)}
+	}
+	obj, err := h.backend.completeMultipartUpload(bucketName, key, uploadID, parts)
+	if err != nil {
+		return err
+	}
+	location := "http://" + r.Host + "/" + bucketName + "/" + escapePath(key)
+	writeXML(w, http.StatusOK, xmlCompleteResult{Xmlns: s3Namespace, Location: location, Bucket: bucketName, Key: key, ETag: obj.etag})
+	return nil
+}
+
+func (h *Handler) listParts(w http.ResponseWriter, bucketName, key, uploadID string) error {
+	parts, err := h.backend.listParts(bucketName, key, uploadID)
+	if err != nil {
+		return err
+	}
+	result := xmlListParts{Xmlns: s3Namespace, Bucket: bucketName, Key: key, UploadID: uploadID}
+	for _, p := range parts {
+		result.Parts = append(result.Parts, xmlPart{
+			PartNumber:   p.number,
+			LastModified: p.lastModified.Format(iso8601Format),
+			ETag:         p.etag,
+			Size:         int64(len(p.data)),
+		})
+	}
+	writeXML(w, http.StatusOK, result)
+	return nil
+}
@@ -0,0 +1,846 @@
+package main
+
+// Developed by PowerShield, as an alternative to Amazon S3 and the AWS SDK for Go
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+var ctx = context.Background()
+
+// newClient returns a client on a fresh backend with the named buckets
+func newClient(buckets ...string) (*Client, *Backend) {
+	backend := NewBackend()
+	client := New(backend)
+	for _, name := range buckets {
+		client.CreateBucket(ctx, &CreateBucketInput{Bucket: String(name)})
+	}
+	return client, backend
+}
+
+func errorCode(err error) string {
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
+func put(client *Client, bucket, key, body string) {
+	client.PutObject(ctx, &PutObjectInput{Bucket: String(bucket), Key: String(key), Body: strings.NewReader(body)})
+}
+
+func readAll(out *GetObjectOutput) string {
+	defer out.Body.Close()
+	data, _ := io.ReadAll(out.Body)
+	return string(data)
+}
+
+func keysOf(out *ListObjectsV2Output) string {
+	var keys []string
+	for _, obj := range out.Contents {
+		keys = append(keys, *obj.Key)
+	}
+	for _, p := range out.CommonPrefixes {
+		keys = append(keys, *p.Prefix)
+	}
+	return strings.Join(keys, ",")
+}
+
+func testBuckets() bool {
+	client, _ := newClient()
+	for _, name := range []string{"photos", "backups", "logs.example.com"} {
+		if _, err := client.CreateBucket(ctx, &CreateBucketInput{Bucket: String(name)}); err != nil {
+			return false
+		}
+	}
+	out, _ := client.ListBuckets(ctx, &ListBucketsInput{})
+	if len(out.Buckets) != 3 || *out.Buckets[0].Name != "backups" || out.Buckets[2].CreationDate.IsZero() {
+		return false
+	}
+
+	_, err := client.CreateBucket(ctx, &CreateBucketInput{Bucket: String("photos")})
+	var owned *BucketAlreadyOwnedByYou
+	if !errors.As(err, &owned) {
+		return false
+	}
+	for _, bad := range []string{"ab", "Photos", "-photos", "my_bucket", "a..b"} {
+		if _, err := client.CreateBucket(ctx, &CreateBucketInput{Bucket: String(bad)}); errorCode(err) != "InvalidBucketName" {
+			return false
+		}
+	}
+
+	put(client, "photos", "cat.jpg", "meow")
+	if _, err := client.DeleteBucket(ctx, &DeleteBucketInput{Bucket: String("photos")}); errorCode(err) != "BucketNotEmpty" {
+		return false
+	}
+	client.DeleteObject(ctx, &DeleteObjectInput{Bucket: String("photos"), Key: String("cat.jpg")})
+	if _, err := client.DeleteBucket(ctx, &DeleteBucketInput{Bucket: String("photos")}); err != nil {
+		return false
+	}
+
+	_, err = client.HeadBucket(ctx, &HeadBucketInput{Bucket: String("photos")})
+	var notFound *NotFound
+	_, err2 := client.PutObject(ctx, &PutObjectInput{Bucket: String("photos"), Key: String("k")})
+	var noBucket *NoSuchBucket
+	return errors.As(err, &notFound) && errors.As(err2, &noBucket) &&
+		strings.HasPrefix(err2.Error(), "operation error S3: PutObject, api error NoSuchBucket")
+}
+
+func testPutAndGet() bool {
+	client, backend := newClient("docs")
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	backend.SetTimeFunc(func() time.Time { return fixed })
+
+	put, err := client.PutObject(ctx, &PutObjectInput{
+		Bucket:       String("docs"),
+		Key:          String("reports/q1.txt"),
+		Body:         strings.NewReader("revenue up"),
+		ContentType:  String("text/plain"),
+		CacheControl: String("max-age=60"),
+		Metadata:     map[string]string{"Author": "ann"},
+	})
+	sum := md5.Sum([]byte("revenue up"))
+	if err != nil || *put.ETag != `"`+hex.EncodeToString(sum[:])+`"` {
+		return false
+	}
+
+	out, err := client.GetObject(ctx, &GetObjectInput{Bucket: String("docs"), Key: String("reports/q1.txt")})
+	if err != nil || readAll(out) != "revenue up" || *out.ContentType != "text/plain" || *out.ContentLength != 10 ||
+		*out.CacheControl != "max-age=60" || out.Metadata["author"] != "ann" || *out.ETag != *put.ETag ||
+		!out.LastModified.Equal(fixed) || out.ContentRange != nil {
+		return false
+	}
+
+	head, err := client.HeadObject(ctx, &HeadObjectInput{Bucket: String("docs"), Key: String("reports/q1.txt")})
+	if err != nil || *head.ContentLength != 10 || head.Metadata["author"] != "ann" || head.ContentEncoding != nil {
+		return false
+	}
+
+	// Overwrites replace the object; empty bodies get a default type
+	client.PutObject(ctx, &PutObjectInput{Bucket: String("docs"), Key: String("reports/q1.txt")})
+	out, _ = client.GetObject(ctx, &GetObjectInput{Bucket: String("docs"), Key: String("reports/q1.txt")})
+	if readAll(out) != "" || *out.ContentType != "binary/octet-stream" || out.Metadata != nil {
+		return false
+	}
+
+	_, err = client.GetObject(ctx, &GetObjectInput{Bucket: String("docs"), Key: String("missing")})
+	var noKey *NoSuchKey
+	_, headErr := client.HeadObject(ctx, &HeadObjectInput{Bucket: String("docs"), Key: String("missing")})
+	_, longErr := client.PutObject(ctx, &PutObjectInput{Bucket: String("docs"), Key: String(strings.Repeat("k", 1025))})
+	return errors.As(err, &noKey) && errorCode(headErr) == "NotFound" && errorCode(longErr) == "KeyTooLongError"
+}
+
+func testDeleteAndCopy() bool {
+	client, _ := newClient("src", "dst")
+	client.PutObject(ctx, &PutObjectInput{
+		Bucket:      String("src"),
+		Key:         String("a b/photo+1.jpg"),
+		Body:        strings.NewReader("pixels"),
+		ContentType: String("image/jpeg"),
+		Metadata:    map[string]string{"camera": "x100"},
+	})
+
+	copied, err := client.CopyObject(ctx, &CopyObjectInput{
+		Bucket:     String("dst"),
+		Key:        String("copy.jpg"),
+		CopySource: String("src/a%20b/photo%2B1.jpg"),
+	})
+	if err != nil || copied.CopyObjectResult.ETag == nil {
+		return false
+	}
+	out, _ := client.GetObject(ctx, &GetObjectInput{Bucket: String("dst"), Key: String("copy.jpg")})
+	if readAll(out) != "pixels" || *out.ContentType != "image/jpeg" || out.Metadata["camera"] != "x100" {
+		return false
+	}
+
+	// REPLACE swaps the metadata, which also allows copying in place
+	_, err = client.CopyObject(ctx, &CopyObjectInput{
+		Bucket:            String("src"),
+		Key:               String("a b/photo+1.jpg"),
+		CopySource:        String("/src/a%20b/photo%2B1.jpg"),
+		MetadataDirective: MetadataDirectiveReplace,
+		ContentType:       String("image/png"),
+		Metadata:          map[string]string{"edited": "yes"},
+	})
+	head, _ := client.HeadObject(ctx, &HeadObjectInput{Bucket: String("src"), Key: String("a b/photo+1.jpg")})
+	if err != nil || *head.ContentType != "image/png" || head.Metadata["edited"] != "yes" || head.Metadata["camera"] != "" {
+		return false
+	}
+	_, err = client.CopyObject(ctx, &CopyObjectInput{Bucket: String("dst"), Key: String("copy.jpg"), CopySource: String("dst/copy.jpg")})
+	if errorCode(err) != "InvalidRequest" {
+		return false
+	}
+	_, err = client.CopyObject(ctx, &CopyObjectInput{Bucket: String("dst"), Key: String("x"), CopySource: String("src/nothing")})
+	if errorCode(err) != "NoSuchKey" {
+		return false
+	}
+
+	// Deleting is idempotent
+	for i := 0; i < 2; i++ {
+		if _, err := client.DeleteObject(ctx, &DeleteObjectInput{Bucket: String("dst"), Key: String("copy.jpg")}); err != nil {
+			return false
+		}
+	}
+	_, err = client.GetObject(ctx, &GetObjectInput{Bucket: String("dst"), Key: String("copy.jpg")})
+	_, err2 := client.DeleteObject(ctx, &DeleteObjectInput{Bucket: String("nope"), Key: String("k")})
+	return errorCode(err) == "NoSuchKey" && errorCode(err2) == "NoSuchBucket"
+}
+
+func testConditionsAndRanges() bool {
+	client, _ := newClient("data")
+	put(client, "data", "digits", "0123456789")
+
+	_, err := client.PutObject(ctx, &PutObjectInput{Bucket: String("data"), Key: String("digits"), Body: strings.NewReader("x"), IfNoneMatch: String("*")})
+	if errorCode(err) != "PreconditionFailed" {
+		return false
+	}
+	if _, err := client.PutObject(ctx, &PutObjectInput{Bucket: String("data"), Key: String("new"), IfNoneMatch: String("*")}); err != nil {
+		return false
+	}
+
+	head, _ := client.HeadObject(ctx, &HeadObjectInput{Bucket: String("data"), Key: String("digits")})
+	if _, err := client.GetObject(ctx, &GetObjectInput{Bucket: String("data"), Key: String("digits"), IfMatch: head.ETag}); err != nil {
+		return false
+	}
+	_, err = client.GetObject(ctx, &GetObjectInput{Bucket: String("data"), Key: String("digits"), IfMatch: String(`"stale"`)})
+	if errorCode(err) != "PreconditionFailed" {
+		return false
+	}
+	_, err = client.GetObject(ctx, &GetObjectInput{Bucket: String("data"), Key: String("digits"), IfNoneMatch: head.ETag})
+	if errorCode(err) != "NotModified" {
+		return false
+	}
+
+	ranges := map[string]string{"bytes=2-4": "234", "bytes=7-": "789", "bytes=-3": "789", "bytes=8-100": "89", "bytes=-50": "0123456789"}
+	for spec, want := range ranges {
+		out, err := client.GetObject(ctx, &GetObjectInput{Bucket: String("data"), Key: String("digits"), Range: String(spec)})
+		if err != nil || readAll(out) != want || *out.ContentLength != int64(len(want)) {
+			return false
+		}
+	}
+	out, _ := client.GetObject(ctx, &GetObjectInput{Bucket: String("data"), Key: String("digits"), Range: String("bytes=2-4")})
+	if *out.ContentRange != "bytes 2-4/10" {
+		return false
+	}
+	for _, bad := range []string{"bytes=10-", "bytes=5-2", "items=0-1", "bytes=0-1,3-4"} {
+		if _, err := client.GetObject(ctx, &GetObjectInput{Bucket: String("data"), Key: String("digits"), Range: String(bad)}); errorCode(err) != "InvalidRange" {
+			return false
+		}
+	}
+	return true
+}
+
+func testListing() bool {
+	client, _ := newClient("media")
+	for _, key := range []string{
+		"photos/2023/dec.jpg", "photos/2024/jan.jpg", "photos/2024/feb.jpg",
+		"photos/cover.jpg", "videos/intro.mp4", "readme.txt",
+	} {
+		put(client, "media", key, key)
+	}
+
+	list := func(prefix, delimiter string) *ListObjectsV2Output {
+		in := &ListObjectsV2Input{Bucket: String("media")}
+		if prefix != "" {
+			in.Prefix = String(prefix)
+		}
+		if delimiter != "" {
+			in.Delimiter = String(delimiter)
+		}
+		out, _ := client.ListObjectsV2(ctx, in)
+		return out
+	}
+
+	all := list("", "")
+	if keysOf(all) != "photos/2023/dec.jpg,photos/2024/feb.jpg,photos/2024/jan.jpg,photos/cover.jpg,readme.txt,videos/intro.mp4" ||
+		*all.KeyCount != 6 || *all.IsTruncated || *all.Contents[0].Size != int64(len("photos/2023/dec.jpg")) {
+		return false
+	}
+	if got := keysOf(list("", "/")); got != "readme.txt,photos/,videos/" {
+		return false
+	}
+	photos := list("photos/", "/")
+	if keysOf(photos) != "photos/cover.jpg,photos/2023/,photos/2024/" || *photos.KeyCount != 3 || *photos.Prefix != "photos/" {
+		return false
+	}
+	if keysOf(list("photos/2024/", "/")) != "photos/2024/feb.jpg,photos/2024/jan.jpg" {
+		return false
+	}
+	if keysOf(list("photos/20", "")) != "photos/2023/dec.jpg,photos/2024/feb.jpg,photos/2024/jan.jpg" {
+		return false
+	}
+	if keysOf(list("nothing/", "/")) != "" {
+		return false
+	}
+
+	_, err := client.ListObjectsV2(ctx, &ListObjectsV2Input{Bucket: String("missing")})
+	return errorCode(err) == "NoSuchBucket"
+}
+
+func testPagination() bool {
+	client, _ := newClient("logs")
+	for i := 0; i < 7; i++ {
+		put(client, "logs", fmt.Sprintf("app/%02d.log", i), "line")
+	}
+	put(client, "logs", "db/a.log", "line")
+	put(client, "logs", "db/b.log", "line")
+	put(client, "logs", "web/a.log", "line")
+
+	// Pages of three, resumed with continuation tokens
+	var pages []string
+	in := &ListObjectsV2Input{Bucket: String("logs"), Prefix: String("app/"), MaxKeys: Int32(3)}
+	for {
+		out, err := client.ListObjectsV2(ctx, in)
+		if err != nil {
+			return false
+		}
+		pages = append(pages, keysOf(out))
+		if !*out.IsTruncated {
+			break
+		}
+		in.ContinuationToken = out.NextContinuationToken
+	}
+	if strings.Join(pages, "|") != "app/00.log,app/01.log,app/02.log|app/03.log,app/04.log,app/05.log|app/06.log" {
+		return false
+	}
+
+	// Common prefixes count towards MaxKeys and are not repeated
+	paginator := NewListObjectsV2Paginator(client, &ListObjectsV2Input{Bucket: String("logs"), Delimiter: String("/"), MaxKeys: Int32(2)})
+	var seen []string
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return false
+		}
+		seen = append(seen, keysOf(page))
+	}
+	if strings.Join(seen, "|") != "app/,db/|web/" {
+		return false
+	}
+	if _, err := paginator.NextPage(ctx); err == nil {
+		return false
+	}
+
+	after, _ := client.ListObjectsV2(ctx, &ListObjectsV2Input{Bucket: String("logs"), StartAfter: String("app/05.log")})
+	none, _ := client.ListObjectsV2(ctx, &ListObjectsV2Input{Bucket: String("logs"), MaxKeys: Int32(0)})
+	_, err := client.ListObjectsV2(ctx, &ListObjectsV2Input{Bucket: String("logs"), ContinuationToken: String("!!!")})
+	return keysOf(after) == "app/06.log,db/a.log,db/b.log,web/a.log" && *none.KeyCount == 0 && !*none.IsTruncated &&
+		errorCode(err) == "InvalidArgument"
+}
+
+func testMultipartUpload() bool {
+	client, backend := newClient("big")
+	backend.MinPartSize = 4
+
+	created, err := client.CreateMultipartUpload(ctx, &CreateMultipartUploadInput{
+		Bucket:      String("big"),
+		Key:         String("video.mp4"),
+		ContentType: String("video/mp4"),
+		Metadata:    map[string]string{"codec": "h264"},
+	})
+	if err != nil || *created.UploadId == "" {
+		return false
+	}
+	id := created.UploadId
+
+	var completed []CompletedPart
+	var sums []byte
+	for i, chunk := range []string{"aaaa", "bbbb", "cc"} {
+		part, err := client.UploadPart(ctx, &UploadPartInput{
+			Bucket: String("big"), Key: String("video.mp4"), UploadId: id,
+			PartNumber: Int32(int32(i + 1)), Body: strings.NewReader(chunk),
+		})
+		if err != nil {
+			return false
+		}
+		completed = append(completed, CompletedPart{ETag: part.ETag, PartNumber: Int32(int32(i + 1))})
+		sum := md5.Sum([]byte(chunk))
+		sums = append(sums, sum[:]...)
+	}
+
+	parts, err := client.ListParts(ctx, &ListPartsInput{Bucket: String("big"), Key: String("video.mp4"), UploadId: id})
+	if err != nil || len(parts.Parts) != 3 || *parts.Parts[2].Size != 2 || *parts.Parts[0].PartNumber != 1 {
+		return false
+	}
+	// Nothing is visible until the upload is completed
+	if _, err := client.HeadObject(ctx, &HeadObjectInput{Bucket: String("big"), Key: String("video.mp4")}); errorCode(err) != "NotFound" {
+		return false
+	}
+
+	done, err := client.CompleteMultipartUpload(ctx, &CompleteMultipartUploadInput{
+		Bucket: String("big"), Key: String("video.mp4"), UploadId: id,
+		MultipartUpload: &CompletedMultipartUpload{Parts: completed},
+	})
+	sum := md5.Sum(sums)
+	if err != nil || *done.ETag != `"`+hex.EncodeToString(sum[:])+`-3"` ||
+		*done.Location != "https://s3.us-east-1.amazonaws.com/big/video.mp4" {
+		return false
+	}
+	out, _ := client.GetObject(ctx, &GetObjectInput{Bucket: String("big"), Key: String("video.mp4")})
+	if readAll(out) != "aaaabbbbcc" || *out.ContentType != "video/mp4" || out.Metadata["codec"] != "h264" || *out.ETag != *done.ETag {
+		return false
+	}
+
+	_, err = client.UploadPart(ctx, &UploadPartInput{Bucket: String("big"), Key: String("video.mp4"), UploadId: id, PartNumber: Int32(4)})
+	var noUpload *NoSuchUpload
+	return errors.As(err, &noUpload)
+}
+
+func testMultipartErrors() bool {
+	client, backend := newClient("big")
+	backend.MinPartSize = 4
+	start := func() *string {
+		out, _ := client.CreateMultipartUpload(ctx, &CreateMultipartUploadInput{Bucket: String("big"), Key: String("f")})
+		return out.UploadId
+	}
+	upload := func(id *string, n int32, body string) CompletedPart {
+		out, _ := client.UploadPart(ctx, &UploadPartInput{Bucket: String("big"), Key: String("f"), UploadId: id, PartNumber: Int32(n), Body: strings.NewReader(body)})
+		return CompletedPart{ETag: out.ETag, PartNumber: Int32(n)}
+	}
+	complete := func(id *string, parts ...CompletedPart) error {
+		_, err := client.CompleteMultipartUpload(ctx, &CompleteMultipartUploadInput{
+			Bucket: String("big"), Key: String("f"), UploadId: id,
+			MultipartUpload: &CompletedMultipartUpload{Parts: parts},
+		})
+		return err
+	}
+
+	id := start()
+	small, last := upload(id, 1, "ab"), upload(id, 2, "cd")
+	if errorCode(complete(id, small, last)) != "EntityTooSmall" {
+		return false
+	}
+	p1, p2 := upload(id, 1, "aaaa"), upload(id, 2, "bbbb")
+	if errorCode(complete(id, p2, p1)) != "InvalidPartOrder" ||
+		errorCode(complete(id, p1, CompletedPart{ETag: String(`"wrong"`), PartNumber: Int32(2)})) != "InvalidPart" ||
+		errorCode(complete(id, p1, CompletedPart{ETag: p2.ETag, PartNumber: Int32(3)})) != "InvalidPart" ||
+		errorCode(complete(id)) != "MalformedXML" {
+		return false
+	}
+	// Failed completions keep the upload, so it can be retried
+	if err := complete(id, p1, p2); err != nil {
+		return false
+	}
+
+	_, err := client.UploadPart(ctx, &UploadPartInput{Bucket: String("big"), Key: String("f"), UploadId: start(), PartNumber: Int32(10001)})
+	if errorCode(err) != "InvalidArgument" {
+		return false
+	}
+
+	aborted := start()
+	upload(aborted, 1, "data")
+	if _, err := client.AbortMultipartUpload(ctx, &AbortMultipartUploadInput{Bucket: String("big"), Key: String("f"), UploadId: aborted}); err != nil {
+		return false
+	}
+	_, err = client.ListParts(ctx, &ListPartsInput{Bucket: String("big"), Key: String("f"), UploadId: aborted})
+	_, err2 := client.AbortMultipartUpload(ctx, &AbortMultipartUploadInput{Bucket: String("big"), Key: String("other"), UploadId: start()})
+	return errorCode(err) == "NoSuchUpload" && errorCode(err2) == "NoSuchUpload"
+}
+
+func testPresignedURLs() bool {
+	backend := NewBackend()
+	backend.AddCredentials("AKIDEXAMPLE", "secret")
+	client := New(backend, func(o *Options) {
+		o.Region = "eu-west-1"
+		o.Credentials = Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}
+		o.BaseEndpoint = String("http://localhost:9000/")
+	})
+	presigner := NewPresignClient(client)
+
+	req, err := presigner.PresignGetObject(ctx, &GetObjectInput{Bucket: String("files"), Key: String("reports/q1 final.pdf")},
+		WithPresignExpires(time.Hour))
+	if err != nil || req.Method != "GET" || req.SignedHeader.Get("Host") != "localhost:9000" ||
+		!strings.HasPrefix(req.URL, "http://localhost:9000/files/reports/q1%20final.pdf?") ||
+		!strings.Contains(req.URL, "X-Amz-Expires=3600") ||
+		!strings.Contains(req.URL, "X-Amz-Credential=AKIDEXAMPLE%2F") || !strings.Contains(req.URL, "%2Feu-west-1%2Fs3%2Faws4_request") {
+		return false
+	}
+	if err := backend.ValidatePresignedURL("GET", req.URL, nil); err != nil {
+		return false
+	}
+
+	// Any change to the request breaks the signature
+	if backendErrorCode(backend.ValidatePresignedURL("PUT", req.URL, nil)) != "SignatureDoesNotMatch" ||
+		backendErrorCode(backend.ValidatePresignedURL("GET", strings.Replace(req.URL, "q1", "q2", 1), nil)) != "SignatureDoesNotMatch" ||
+		backendErrorCode(backend.ValidatePresignedURL("GET", strings.Replace(req.URL, "X-Amz-Expires=3600", "X-Amz-Expires=7200", 1), nil)) != "SignatureDoesNotMatch" ||
+		backendErrorCode(backend.ValidatePresignedURL("GET", "http://localhost:9000/files/a", nil)) != "AccessDenied" {
+		return false
+	}
+
+	// Expiry follows the backend's clock
+	backend.SetTimeFunc(func() time.Time { return time.Now().Add(59 * time.Minute) })
+	if backend.ValidatePresignedURL("GET", req.URL, nil) != nil {
+		return false
+	}
+	backend.SetTimeFunc(func() time.Time { return time.Now().Add(61 * time.Minute) })
+	if se := backendErrorCode(backend.ValidatePresignedURL("GET", req.URL, nil)); se != "AccessDenied" {
+		return false
+	}
+	backend.SetTimeFunc(nil)
+
+	// Signed headers must be sent
+	putReq, _ := presigner.PresignPutObject(ctx, &PutObjectInput{Bucket: String("files"), Key: String("up.csv"), ContentType: String("text/csv")})
+	if putReq.SignedHeader.Get("Content-Type") != "text/csv" || !strings.Contains(putReq.URL, "X-Amz-Expires=900") ||
+		backend.ValidatePresignedURL("PUT", putReq.URL, putReq.SignedHeader) != nil ||
+		backendErrorCode(backend.ValidatePresignedURL("PUT", putReq.URL, http.Header{"Content-Type": {"text/html"}})) != "SignatureDoesNotMatch" {
+		return false
+	}
+
+	other := New(backend, func(o *Options) { o.Credentials = Credentials{AccessKeyID: "UNKNOWN", SecretAccessKey: "x"} })
+	unknown, _ := NewPresignClient(other).PresignGetObject(ctx, &GetObjectInput{Bucket: String("files"), Key: String("a")})
+	_, tooLong := presigner.PresignGetObject(ctx, &GetObjectInput{Bucket: String("files"), Key: String("a")}, WithPresignExpires(8*24*time.Hour))
+	_, noCreds := NewPresignClient(New(backend)).PresignGetObject(ctx, &GetObjectInput{Bucket: String("files"), Key: String("a")})
+	return backendErrorCode(backend.ValidatePresignedURL("GET", unknown.URL, nil)) == "InvalidAccessKeyId" &&
+		strings.HasPrefix(unknown.URL, "https://s3.us-east-1.amazonaws.com/files/a?") && tooLong != nil && noCreds != nil
+}
+
+// backendErrorCode returns the S3 error code of a backend error
+func backendErrorCode(err error) string {
+	var se *s3Error
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return ""
+}
+
+// do sends a request to the handler and returns the status and body
+func do(method, url string, body io.Reader, header http.Header) (*http.Response, string) {
+	req, _ := http.NewRequest(method, url, body)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &http.Response{StatusCode: 0}, err.Error()
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func testHTTPFacade() bool {
+	backend := NewBackend()
+	server := httptest.NewServer(NewHandler(backend))
+	defer server.Close()
+	client := New(backend)
+
+	if resp, _ := do("PUT", server.URL+"/site", nil, nil); resp.StatusCode != 200 || resp.Header.Get("Location") != "/site" {
+		return false
+	}
+	resp, _ := do("PUT", server.URL+"/site/css/main%20v2.css", strings.NewReader("body{}"), http.Header{
+		"Content-Type":   {"text/css"},
+		"X-Amz-Meta-Rev": {"7"},
+	})
+	if resp.StatusCode != 200 || resp.Header.Get("ETag") == "" {
+		return false
+	}
+
+	// Objects written over HTTP are visible to clients and back
+	out, err := client.GetObject(ctx, &GetObjectInput{Bucket: String("site"), Key: String("css/main v2.css")})
+	if err != nil || readAll(out) != "body{}" || out.Metadata["rev"] != "7" {
+		return false
+	}
+	put(client, "site", "index.html", "<h1>hi</h1>")
+
+	resp, body := do("GET", server.URL+"/site/index.html", nil, nil)
+	if resp.StatusCode != 200 || body != "<h1>hi</h1>" || resp.Header.Get("Content-Length") != "11" {
+		return false
+	}
+	resp, body = do("GET", server.URL+"/site/index.html", nil, http.Header{"Range": {"bytes=1-2"}})
+	if resp.StatusCode != 206 || body != "h1" || resp.Header.Get("Content-Range") != "bytes 1-2/11" {
+		return false
+	}
+	resp, body = do("HEAD", server.URL+"/site/css/main%20v2.css", nil, nil)
+	if resp.StatusCode != 200 || body != "" || resp.Header.Get("X-Amz-Meta-Rev") != "7" || resp.Header.Get("Content-Type") != "text/css" {
+		return false
+	}
+
+	resp, body = do("GET", server.URL+"/site?list-type=2&delimiter=/", nil, nil)
+	var listing struct {
+		KeyCount       int
+		Contents       []struct{ Key string }
+		CommonPrefixes []struct{ Prefix string }
+	}
+	if resp.StatusCode != 200 || xml.Unmarshal([]byte(body), &listing) != nil || listing.KeyCount != 2 ||
+		listing.Contents[0].Key != "index.html" || listing.CommonPrefixes[0].Prefix != "css/" {
+		return false
+	}
+
+	resp, body = do("GET", server.URL+"/site/missing", nil, nil)
+	var apiErr struct{ Code, Message string }
+	if resp.StatusCode != 404 || xml.Unmarshal([]byte(body), &apiErr) != nil || apiErr.Code != "NoSuchKey" {
+		return false
+	}
+	if resp, body = do("HEAD", server.URL+"/site/missing", nil, nil); resp.StatusCode != 404 || body != "" {
+		return false
+	}
+
+	resp, _ = do("PUT", server.URL+"/site/copy.css", nil, http.Header{"X-Amz-Copy-Source": {"/site/css/main%20v2.css"}})
+	if resp.StatusCode != 200 {
+		return false
+	}
+	if resp, _ = do("DELETE", server.URL+"/site/copy.css", nil, nil); resp.StatusCode != 204 {
+		return false
+	}
+	resp, body = do("GET", server.URL+"/", nil, nil)
+	return resp.StatusCode == 200 && strings.Contains(body, "<Name>site</Name>")
+}
+
+func testPresignedHTTP() bool {
+	backend := NewBackend()
+	backend.AddCredentials("AKID", "secret")
+	server := httptest.NewServer(NewHandler(backend))
+	defer server.Close()
+	client := New(backend, func(o *Options) {
+		o.Credentials = Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}
+		o.BaseEndpoint = String(server.URL)
+	})
+	client.CreateBucket(ctx, &CreateBucketInput{Bucket: String("uploads")})
+	presigner := NewPresignClient(client, WithPresignExpires(5*time.Minute))
+
+	// A browser uploads with a presigned PUT, sending the signed headers
+	putReq, _ := presigner.PresignPutObject(ctx, &PutObjectInput{Bucket: String("uploads"), Key: String("avatar.png"), ContentType: String("image/png")})
+	resp, body := do(putReq.Method, putReq.URL, strings.NewReader("png-bytes"), putReq.SignedHeader)
+	if resp.StatusCode != 200 {
+		return false
+	}
+	if resp, _ = do("PUT", putReq.URL, strings.NewReader("png-bytes"), http.Header{"Content-Type": {"image/gif"}}); resp.StatusCode != 403 {
+		return false
+	}
+
+	getReq, _ := presigner.PresignGetObject(ctx, &GetObjectInput{Bucket: String("uploads"), Key: String("avatar.png")})
+	httpResp, err := http.Get(getReq.URL)
+	if err != nil {
+		return false
+	}
+	data, _ := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if httpResp.StatusCode != 200 || string(data) != "png-bytes" || httpResp.Header.Get("Content-Type") != "image/png" {
+		return false
+	}
+
+	// Unsigned and expired requests are refused
+	resp, body = do("GET", server.URL+"/uploads/avatar.png", nil, nil)
+	if resp.StatusCode != 403 || !strings.Contains(body, "<Code>AccessDenied</Code>") {
+		return false
+	}
+	backend.SetTimeFunc(func() time.Time { return time.Now().Add(time.Hour) })
+	resp, body = do("GET", getReq.URL, nil, nil)
+	if resp.StatusCode != 403 || !strings.Contains(body, "Request has expired") {
+		return false
+	}
+	backend.SetTimeFunc(nil)
+
+	delReq, _ := presigner.PresignDeleteObject(ctx, &DeleteObjectInput{Bucket: String("uploads"), Key: String("avatar.png")})
+	resp, _ = do("DELETE", delReq.URL, nil, nil)
+	_, err = client.HeadObject(ctx, &HeadObjectInput{Bucket: String("uploads"), Key: String("avatar.png")})
+	return resp.StatusCode == 204 && errorCode(err) == "NotFound"
+}
+
+// signedRequest builds a request signed in its Authorization header, as
+// the SDK sends them
+func signedRequest(method, url string, body []byte, creds Credentials) *http.Request {
+	req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "s3", "us-east-1", time.Now())
+	return req
+}
+
+func send(req *http.Request) (int, string) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func testSignedRequests() bool {
+	backend := NewBackend()
+	backend.AddCredentials("AKID", "secret")
+	server := httptest.NewServer(NewHandler(backend))
+	defer server.Close()
+	good := Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}
+
+	if status, _ := send(signedRequest("PUT", server.URL+"/signed", nil, good)); status != 200 {
+		return false
+	}
+	req := signedRequest("PUT", server.URL+"/signed/notes/a.txt?x-id=PutObject", []byte("hello"), good)
+	if !strings.Contains(req.Header.Get("Authorization"), "x-amz-security-token") {
+		return false
+	}
+	if status, _ := send(req); status != 200 {
+		return false
+	}
+
+	if status, body := send(signedRequest("GET", server.URL+"/signed/notes/a.txt", nil, Credentials{AccessKeyID: "AKID", SecretAccessKey: "wrong"})); status != 403 ||
+		!strings.Contains(body, "SignatureDoesNotMatch") {
+		return false
+	}
+	if status, body := send(signedRequest("GET", server.URL+"/signed/notes/a.txt", nil, Credentials{AccessKeyID: "NOPE", SecretAccessKey: "secret"})); status != 403 ||
+		!strings.Contains(body, "InvalidAccessKeyId") {
+		return false
+	}
+	tampered := signedRequest("GET", server.URL+"/signed/notes/a.txt", nil, good)
+	tampered.URL.Path = "/signed/notes/b.txt"
+	if status, _ := send(tampered); status != 403 {
+		return false
+	}
+	if status, _ := send(signedRequest("GET", server.URL+"/signed/notes/a.txt", nil, good)); status != 200 {
+		return false
+	}
+
+	// Streaming uploads use the aws-chunked encoding
+	chunked := "5;chunk-signature=abc\r\nhello\r\n6;chunk-signature=def\r\n world\r\n0\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"
+	req, _ = http.NewRequest("PUT", server.URL+"/signed/stream.txt", strings.NewReader(chunked))
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	req.Header.Set("X-Amz-Decoded-Content-Length", "11")
+	NewSigner().SignHTTP(ctx, good, req, "STREAMING-UNSIGNED-PAYLOAD-TRAILER", "s3", "us-east-1", time.Now())
+	if status, _ := send(req); status != 200 {
+		return false
+	}
+	obj, err := backend.getObject("signed", "stream.txt")
+	return err == nil && string(obj.data) == "hello world" && obj.headers.contentEncoding == ""
+}
+
+func testMultipartHTTP() bool {
+	backend := NewBackend()
+	backend.MinPartSize = 3
+	server := httptest.NewServer(NewHandler(backend))
+	defer server.Close()
+	do("PUT", server.URL+"/big", nil, nil)
+
+	resp, body := do("POST", server.URL+"/big/archive.tar?uploads", nil, http.Header{"Content-Type": {"application/x-tar"}})
+	var initiated struct{ UploadId string }
+	if resp.StatusCode != 200 || xml.Unmarshal([]byte(body), &initiated) != nil || initiated.UploadId == "" {
+		return false
+	}
+	base := server.URL + "/big/archive.tar?uploadId=" + initiated.UploadId
+
+	var etags []string
+	for i, chunk := range []string{"abc", "def", "g"} {
+		resp, _ := do("PUT", fmt.Sprintf("%s&partNumber=%d", base, i+1), strings.NewReader(chunk), nil)
+		if resp.StatusCode != 200 {
+			return false
+		}
+		etags = append(etags, resp.Header.Get("ETag"))
+	}
+
+	resp, body = do("GET", base, nil, nil)
+	if resp.StatusCode != 200 || strings.Count(body, "<Part>") != 3 {
+		return false
+	}
+
+	var complete strings.Builder
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range etags {
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, etag)
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	resp, body = do("POST", base, strings.NewReader(complete.String()), nil)
+	var result struct{ ETag, Key string }
+	if resp.StatusCode != 200 || xml.Unmarshal([]byte(body), &result) != nil || !strings.HasSuffix(result.ETag, `-3"`) || result.Key != "archive.tar" {
+		return false
+	}
+	resp, body = do("GET", server.URL+"/big/archive.tar", nil, nil)
+	if body != "abcdefg" || resp.Header.Get("Content-Type") != "application/x-tar" {
+		return false
+	}
+
+	// The upload is gone once completed
+	if resp, body = do("POST", base, strings.NewReader(complete.String()), nil); resp.StatusCode != 404 || !strings.Contains(body, "NoSuchUpload") {
+		return false
+	}
+	resp, body = do("DELETE", base, nil, nil)
+	return resp.StatusCode == 404 && strings.Contains(body, "NoSuchUpload")
+}
+
+func testConcurrentAccess() bool {
+	client, _ := newClient("shared")
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failures := 0
+	fail := func() {
+		mu.Lock()
+		failures++
+		mu.Unlock()
+	}
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				key := fmt.Sprintf("w%d/%02d", w, i)
+				if _, err := client.PutObject(ctx, &PutObjectInput{Bucket: String("shared"), Key: String(key), Body: strings.NewReader(key)}); err != nil {
+					fail()
+					continue
+				}
+				out, err := client.GetObject(ctx, &GetObjectInput{Bucket: String("shared"), Key: String(key)})
+				if err != nil || readAll(out) != key {
+					fail()
+				}
+				if _, err := client.ListObjectsV2(ctx, &ListObjectsV2Input{Bucket: String("shared"), Prefix: String(fmt.Sprintf("w%d/", w))}); err != nil {
+					fail()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0
+	paginator := NewListObjectsV2Paginator(client, &ListObjectsV2Input{Bucket: String("shared"), MaxKeys: Int32(30)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return false
+		}
+		total += len(page.Contents)
+	}
+	return failures == 0 && total == 200
+}
+
+func main() {
+	fmt.Println("Running S3 Emulator Tests...")
+	fmt.Println("============================")
+
+	runTest("Buckets", testBuckets)
+	runTest("Put And Get", testPutAndGet)
+	runTest("Delete And Copy", testDeleteAndCopy)
+	runTest("Conditions And Ranges", testConditionsAndRanges)
+	runTest("Listing", testListing)
+	runTest("Pagination", testPagination)
+	runTest("Multipart Upload", testMultipartUpload)
+	runTest("Multipart Errors", testMultipartErrors)
+	runTest("Presigned URLs", testPresignedURLs)
+	runTest("HTTP Facade", testHTTPFacade)
+	runTest("Presigned HTTP", testPresignedHTTP)
+	runTest("Signed Requests", testSignedRequests)
+	runTest("Multipart HTTP", testMultipartHTTP)
+	runTest("Concurrent Access", testConcurrentAccess)
+
+	fmt.Println("============================")
+	fmt.Println("All tests completed!")
+}
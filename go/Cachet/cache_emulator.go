@@ -0,0 +1,996 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-cache and bigcache
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Expiration durations accepted by Set, Add and Replace
+const (
+	// NoExpiration keeps an item until it is deleted or evicted
+	NoExpiration time.Duration = -1
+	// DefaultExpiration uses the expiration given to New
+	DefaultExpiration time.Duration = 0
+)
+
+// Item is a cached value and its expiration in Unix nanoseconds, zero if
+// it never expires
+type Item struct {
+	Object     interface{}
+	Expiration int64
+}
+
+// Expired reports whether the item has expired
+func (item Item) Expired() bool {
+	if item.Expiration == 0 {
+		return false
+	}
+	return time.Now().UnixNano() > item.Expiration
+}
+
+// RemoveReason says why an entry left the cache
+type RemoveReason uint32
+
+const (
+	// Expired entries outlived their expiration
+	Expired RemoveReason = iota + 1
+	// NoSpace entries were evicted to stay within the size bound
+	NoSpace
+	// Deleted entries were removed by Delete
+	Deleted
+)
+
+func (r RemoveReason) String() string {
+	switch r {
+	case Expired:
+		return "Expired"
+	case NoSpace:
+		return "NoSpace"
+	case Deleted:
+		return "Deleted"
+	}
+	return fmt.Sprintf("RemoveReason(%d)", uint32(r))
+}
+
+// Hasher picks the shard for a key
+type Hasher interface {
+	Sum64(string) uint64
+}
+
+// fnv64a is the default Hasher
+type fnv64a struct{}
+
+const (
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+func (fnv64a) Sum64(key string) uint64 {
+	var hash uint64 = offset64
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+	return hash
+}
+
+// Stats counts cache operations
+type Stats struct {
+	// Hits and Misses count lookups of present and missing (or expired) keys
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// DelHits and DelMisses count deletes of present and missing keys
+	DelHits   int64 `json:"delete_hits"`
+	DelMisses int64 `json:"delete_misses"`
+	// Evictions counts entries removed to stay within the size bound
+	Evictions int64 `json:"evictions"`
+	// Expirations counts expired entries removed by cleanup
+	Expirations int64 `json:"expirations"`
+}
+
+// HitRate returns hits as a fraction of lookups
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Sharded storage
+
+// entry is an item in a shard's LRU list
+type entry struct {
+	key  string
+	item Item
+	cost int64
+}
+
+// removal is an entry to report to the remove callback once the shard
+// lock is released
+type removal struct {
+	key    string
+	value  interface{}
+	reason RemoveReason
+}
+
+// shard is one lock's worth of the cache. Entries are kept in LRU order,
+// most recently used first; when maxCost is set the least recently used
+// are evicted to stay within it.
+type shard struct {
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List
+	cost    int64
+	maxCost int64
+}
+
+func newShard(maxCost int64, capacity int) *shard {
+	return &shard{items: make(map[string]*list.Element, capacity), order: list.New(), maxCost: maxCost}
+}
+
+func (s *shard) get(key string, now int64) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return Item{}, false
+	}
+	e := elem.Value.(*entry)
+	if e.item.Expiration > 0 && now > e.item.Expiration {
+		return Item{}, false
+	}
+	s.order.MoveToFront(elem)
+	return e.item, true
+}
+
+// set stores an item, returning the entries evicted to make room
+func (s *shard) set(key string, item Item, cost int64) []removal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setLocked(key, item, cost)
+}
+
+// setLocked stores an item; the lock must be held
+func (s *shard) setLocked(key string, item Item, cost int64) []removal {
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*entry)
+		s.cost += cost - e.cost
+		e.item, e.cost = item, cost
+		s.order.MoveToFront(elem)
+	} else {
+		s.items[key] = s.order.PushFront(&entry{key: key, item: item, cost: cost})
+		s.cost += cost
+	}
+	return s.evict()
+}
+
+// add stores an item unless an unexpired one exists under key
+func (s *shard) add(key string, item Item, cost int64, now int64) (bool, []removal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*entry)
+		if e.item.Expiration == 0 || now <= e.item.Expiration {
+			return false, nil
+		}
+	}
+	return true, s.setLocked(key, item, cost)
+}
+
+// replace stores an item only if an unexpired one exists under key
+func (s *shard) replace(key string, item Item, cost int64, now int64) (bool, []removal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return false, nil
+	}
+	if e := elem.Value.(*entry); e.item.Expiration > 0 && now > e.item.Expiration {
+		return false, nil
+	}
+	return true, s.setLocked(key, item, cost)
+}
+
+// evict removes least recently used entries until within maxCost; the
+// lock must be held
+func (s *shard) evict() []removal {
+	var evicted []removal
+	for s.maxCost > 0 && s.cost > s.maxCost {
+		e := s.remove(s.order.Back())
+		evicted = append(evicted, removal{e.key, e.item.Object, NoSpace})
+	}
+	return evicted
+}
+
+// remove unlinks an element; the lock must be held
+func (s *shard) remove(elem *list.Element) *entry {
+	e := s.order.Remove(elem).(*entry)
+	delete(s.items, e.key)
+	s.cost -= e.cost
+	return e
+}
+
+func (s *shard) delete(key string) (removal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return removal{}, false
+	}
+	e := s.remove(elem)
+	return removal{e.key, e.item.Object, Deleted}, true
+}
+
+func (s *shard) deleteExpired(now int64) []removal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []removal
+	for elem := s.order.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry)
+		if e.item.Expiration > 0 && now > e.item.Expiration {
+			s.remove(elem)
+			expired = append(expired, removal{e.key, e.item.Object, Expired})
+		}
+		elem = next
+	}
+	return expired
+}
+
+// update changes an item in place without touching its LRU position or
+// expiration
+func (s *shard) update(key string, now int64, fn func(Item) (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, itemNotFound(key)
+	}
+	e := elem.Value.(*entry)
+	if e.item.Expiration > 0 && now > e.item.Expiration {
+		return nil, itemNotFound(key)
+	}
+	value, err := fn(e.item)
+	if err != nil {
+		return nil, err
+	}
+	e.item.Object = value
+	return value, nil
+}
+
+// itemNotFound is the error for updates of missing keys
+type itemNotFound string
+
+func (k itemNotFound) Error() string {
+	return fmt.Sprintf("Item %s not found", string(k))
+}
+
+func (s *shard) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[string]*list.Element)
+	s.order.Init()
+	s.cost = 0
+}
+
+// store is the sharded storage behind Cache and BigCache
+type store struct {
+	shards []*shard
+	mask   uint64
+	hasher Hasher
+
+	hits, misses, delHits, delMisses, evictions, expirations int64
+
+	mu       sync.RWMutex
+	onRemove func(key string, value interface{}, reason RemoveReason)
+}
+
+func newStore(shards int, maxCost int64, capacity int, hasher Hasher) *store {
+	s := &store{shards: make([]*shard, shards), mask: uint64(shards - 1), hasher: hasher}
+	for i := range s.shards {
+		s.shards[i] = newShard(maxCost, capacity)
+	}
+	return s
+}
+
+func (s *store) shard(key string) *shard {
+	return s.shards[s.hasher.Sum64(key)&s.mask]
+}
+
+func (s *store) get(key string) (Item, bool) {
+	item, ok := s.shard(key).get(key, time.Now().UnixNano())
+	if ok {
+		atomic.AddInt64(&s.hits, 1)
+	} else {
+		atomic.AddInt64(&s.misses, 1)
+	}
+	return item, ok
+}
+
+func (s *store) delete(key string) bool {
+	r, ok := s.shard(key).delete(key)
+	if !ok {
+		atomic.AddInt64(&s.delMisses, 1)
+		return false
+	}
+	atomic.AddInt64(&s.delHits, 1)
+	s.removed([]removal{r})
+	return true
+}
+
+func (s *store) deleteExpired() {
+	now := time.Now().UnixNano()
+	for _, sh := range s.shards {
+		s.removed(sh.deleteExpired(now))
+	}
+}
+
+// removed counts removals and reports them to the callback
+func (s *store) removed(removals []removal) {
+	if len(removals) == 0 {
+		return
+	}
+	s.mu.RLock()
+	onRemove := s.onRemove
+	s.mu.RUnlock()
+	for _, r := range removals {
+		switch r.reason {
+		case NoSpace:
+			atomic.AddInt64(&s.evictions, 1)
+		case Expired:
+			atomic.AddInt64(&s.expirations, 1)
+		}
+		if onRemove != nil {
+			onRemove(r.key, r.value, r.reason)
+		}
+	}
+}
+
+func (s *store) len() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		n += len(sh.items)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+func (s *store) flush() {
+	for _, sh := range s.shards {
+		sh.flush()
+	}
+}
+
+func (s *store) stats() Stats {
+	return Stats{
+		Hits:        atomic.LoadInt64(&s.hits),
+		Misses:      atomic.LoadInt64(&s.misses),
+		DelHits:     atomic.LoadInt64(&s.delHits),
+		DelMisses:   atomic.LoadInt64(&s.delMisses),
+		Evictions:   atomic.LoadInt64(&s.evictions),
+		Expirations: atomic.LoadInt64(&s.expirations),
+	}
+}
+
+// Cache
+
+// Options configures a Cache created with NewWithOptions
+type Options struct {
+	// DefaultExpiration applies to items set with DefaultExpiration;
+	// NoExpiration (or zero) keeps them until deleted
+	DefaultExpiration time.Duration
+	// CleanupInterval is how often expired items are deleted; zero
+	// leaves them until DeleteExpired is called
+	CleanupInterval time.Duration
+	// MaxItems bounds the number of items, evicting the least recently
+	// used. The bound is split evenly across shards.
+	MaxItems int
+	// Shards is the number of independently locked shards, a power of
+	// two; defaults to 16, or 1 when MaxItems is set so LRU order is exact
+	Shards int
+	// Hasher picks a key's shard; defaults to FNV-1a
+	Hasher Hasher
+}
+
+// Cache is a thread-safe in-memory key/value store with per-item
+// expiration. It wraps cache so a finalizer can stop the janitor once
+// the Cache is unreachable.
+type Cache struct {
+	*cache
+}
+
+type cache struct {
+	*store
+	defaultExpiration time.Duration
+	janitor           *janitor
+}
+
+// New creates a Cache whose items expire after defaultExpiration unless
+// set otherwise, with expired items deleted every cleanupInterval
+func New(defaultExpiration, cleanupInterval time.Duration) *Cache {
+	c, _ := NewWithOptions(Options{DefaultExpiration: defaultExpiration, CleanupInterval: cleanupInterval})
+	return c
+}
+
+// NewFrom creates a Cache holding items, e.g. restored from Items()
+func NewFrom(defaultExpiration, cleanupInterval time.Duration, items map[string]Item) *Cache {
+	c := New(defaultExpiration, cleanupInterval)
+	for k, item := range items {
+		c.shard(k).set(k, item, 1)
+	}
+	return c
+}
+
+// NewWithOptions creates a Cache with a size bound or custom sharding
+func NewWithOptions(opts Options) (*Cache, error) {
+	shards := opts.Shards
+	if shards == 0 {
+		shards = 16
+		if opts.MaxItems > 0 {
+			shards = 1
+		}
+	}
+	if shards < 0 || shards&(shards-1) != 0 {
+		return nil, errors.New("Shards number must be power of two")
+	}
+	if opts.MaxItems < 0 {
+		return nil, errors.New("MaxItems must not be negative")
+	}
+	if opts.Hasher == nil {
+		opts.Hasher = fnv64a{}
+	}
+	var perShard int64
+	if opts.MaxItems > 0 {
+		perShard = int64(opts.MaxItems / shards)
+		if perShard == 0 {
+			perShard = 1
+		}
+	}
+
+	de := opts.DefaultExpiration
+	if de == 0 {
+		de = NoExpiration
+	}
+	c := &cache{store: newStore(shards, perShard, 0, opts.Hasher), defaultExpiration: de}
+	C := &Cache{c}
+	if opts.CleanupInterval > 0 {
+		runJanitor(c, opts.CleanupInterval)
+		runtime.SetFinalizer(C, stopJanitor)
+	}
+	return C, nil
+}
+
+// expiration converts a duration to an Item expiration
+func (c *cache) expiration(d time.Duration) int64 {
+	if d == DefaultExpiration {
+		d = c.defaultExpiration
+	}
+	if d > 0 {
+		return time.Now().Add(d).UnixNano()
+	}
+	return 0
+}
+
+// Set stores x under k, replacing any existing item. d is the item's
+// lifetime, DefaultExpiration or NoExpiration.
+func (c *cache) Set(k string, x interface{}, d time.Duration) {
+	c.removed(c.shard(k).set(k, Item{Object: x, Expiration: c.expiration(d)}, 1))
+}
+
+// SetDefault stores x under k with the default expiration
+func (c *cache) SetDefault(k string, x interface{}) {
+	c.Set(k, x, DefaultExpiration)
+}
+
+// Add stores x under k only if no unexpired item exists
+func (c *cache) Add(k string, x interface{}, d time.Duration) error {
+	added, evicted := c.shard(k).add(k, Item{Object: x, Expiration: c.expiration(d)}, 1, time.Now().UnixNano())
+	if !added {
+		return fmt.Errorf("Item %s already exists", k)
+	}
+	c.removed(evicted)
+	return nil
+}
+
+// Replace stores x under k only if an unexpired item exists
+func (c *cache) Replace(k string, x interface{}, d time.Duration) error {
+	replaced, evicted := c.shard(k).replace(k, Item{Object: x, Expiration: c.expiration(d)}, 1, time.Now().UnixNano())
+	if !replaced {
+		return fmt.Errorf("Item %s doesn't exist", k)
+	}
+	c.removed(evicted)
+	return nil
+}
+
+// Get returns the item under k, if present and unexpired
+func (c *cache) Get(k string) (interface{}, bool) {
+	item, found := c.get(k)
+	if !found {
+		return nil, false
+	}
+	return item.Object, true
+}
+
+// GetWithExpiration returns the item under k and when it expires, the
+// zero time if never
+func (c *cache) GetWithExpiration(k string) (interface{}, time.Time, bool) {
+	item, found := c.get(k)
+	if !found {
+		return nil, time.Time{}, false
+	}
+	if item.Expiration > 0 {
+		return item.Object, time.Unix(0, item.Expiration), true
+	}
+	return item.Object, time.Time{}, true
+}
+
+// Delete removes the item under k, calling the eviction callback if set
+func (c *cache) Delete(k string) {
+	c.delete(k)
+}
+
+// DeleteExpired removes all expired items
+func (c *cache) DeleteExpired() {
+	c.deleteExpired()
+}
+
+// OnEvicted sets a function called with each item deleted, expired or
+// evicted for space. It is called outside the cache's locks.
+func (c *cache) OnEvicted(f func(string, interface{})) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f == nil {
+		c.onRemove = nil
+		return
+	}
+	c.onRemove = func(key string, value interface{}, _ RemoveReason) { f(key, value) }
+}
+
+// OnEvictedWithReason is OnEvicted with the reason the item was removed
+func (c *cache) OnEvictedWithReason(f func(key string, value interface{}, reason RemoveReason)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onRemove = f
+}
+
+// Items returns a copy of the unexpired items
+func (c *cache) Items() map[string]Item {
+	now := time.Now().UnixNano()
+	items := make(map[string]Item)
+	for _, sh := range c.shards {
+		sh.mu.Lock()
+		for k, elem := range sh.items {
+			item := elem.Value.(*entry).item
+			if item.Expiration > 0 && now > item.Expiration {
+				continue
+			}
+			items[k] = item
+		}
+		sh.mu.Unlock()
+	}
+	return items
+}
+
+// ItemCount returns the number of items, including expired ones not
+// yet deleted
+func (c *cache) ItemCount() int {
+	return c.len()
+}
+
+// Flush removes all items without calling the eviction callback
+func (c *cache) Flush() {
+	c.flush()
+}
+
+// Stats returns the hit, miss and eviction counts
+func (c *cache) Stats() Stats {
+	return c.stats()
+}
+
+// Increment adds n to the integer item under k
+func (c *cache) Increment(k string, n int64) error {
+	_, err := c.shard(k).update(k, time.Now().UnixNano(), func(item Item) (interface{}, error) {
+		return addInteger(k, item.Object, n)
+	})
+	return err
+}
+
+// Decrement subtracts n from the integer item under k
+func (c *cache) Decrement(k string, n int64) error {
+	return c.Increment(k, -n)
+}
+
+// IncrementFloat adds n to the float32 or float64 item under k
+func (c *cache) IncrementFloat(k string, n float64) error {
+	_, err := c.shard(k).update(k, time.Now().UnixNano(), func(item Item) (interface{}, error) {
+		v := reflect.ValueOf(item.Object)
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			sum := reflect.New(v.Type()).Elem()
+			sum.SetFloat(v.Float() + n)
+			return sum.Interface(), nil
+		}
+		return nil, fmt.Errorf("The value for %s does not have type float32 or float64", k)
+	})
+	return err
+}
+
+// IncrementInt adds n to the int item under k and returns the result
+func (c *cache) IncrementInt(k string, n int) (int, error) {
+	v, err := c.shard(k).update(k, time.Now().UnixNano(), func(item Item) (interface{}, error) {
+		i, ok := item.Object.(int)
+		if !ok {
+			return nil, fmt.Errorf("The value for %s is not an int", k)
+		}
+		return i + n, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// IncrementInt64 adds n to the int64 item under k and returns the result
+func (c *cache) IncrementInt64(k string, n int64) (int64, error) {
+	v, err := c.shard(k).update(k, time.Now().UnixNano(), func(item Item) (interface{}, error) {
+		i, ok := item.Object.(int64)
+		if !ok {
+			return nil, fmt.Errorf("The value for %s is not an int64", k)
+		}
+		return i + n, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
+
+// DecrementInt subtracts n from the int item under k and returns the result
+func (c *cache) DecrementInt(k string, n int) (int, error) {
+	return c.IncrementInt(k, -n)
+}
+
+// DecrementInt64 subtracts n from the int64 item under k and returns the
+// result
+func (c *cache) DecrementInt64(k string, n int64) (int64, error) {
+	return c.IncrementInt64(k, -n)
+}
+
+// addInteger adds n to any integer value, keeping its type
+func addInteger(k string, value interface{}, n int64) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	sum := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sum.SetInt(v.Int() + n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		sum.SetUint(v.Uint() + uint64(n))
+	default:
+		return nil, fmt.Errorf("The value for %s is not an integer", k)
+	}
+	return sum.Interface(), nil
+}
+
+// janitor deletes expired items periodically
+type janitor struct {
+	interval time.Duration
+	stop     chan bool
+}
+
+func (j *janitor) run(c *cache) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.DeleteExpired()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+func stopJanitor(c *Cache) {
+	c.janitor.stop <- true
+}
+
+func runJanitor(c *cache, ci time.Duration) {
+	j := &janitor{interval: ci, stop: make(chan bool)}
+	c.janitor = j
+	go j.run(c)
+}
+
+// BigCache
+
+// ErrEntryNotFound is returned for missing or expired keys
+var ErrEntryNotFound = errors.New("Entry not found")
+
+// Config configures a BigCache
+type Config struct {
+	// Shards is the number of shards, a power of two
+	Shards int
+	// LifeWindow is how long an entry lives after it is set
+	LifeWindow time.Duration
+	// CleanWindow is how often expired entries are removed; zero
+	// disables cleanup, though expired entries are still not returned
+	CleanWindow time.Duration
+	// MaxEntriesInWindow sizes the shards up front
+	MaxEntriesInWindow int
+	// MaxEntrySize is the expected entry size in bytes, for sizing
+	MaxEntrySize int
+	// HardMaxCacheSize bounds the cache in megabytes, evicting the least
+	// recently used entries; zero means unbounded
+	HardMaxCacheSize int
+	// Hasher picks a key's shard; defaults to FNV-1a
+	Hasher Hasher
+	// OnRemove is called with each entry that expires, is evicted or
+	// is deleted
+	OnRemove func(key string, entry []byte)
+	// OnRemoveWithReason is OnRemove with the reason; it takes
+	// precedence when both are set
+	OnRemoveWithReason func(key string, entry []byte, reason RemoveReason)
+}
+
+// DefaultConfig returns a Config whose entries live for eviction
+func DefaultConfig(eviction time.Duration) Config {
+	return Config{
+		Shards:             1024,
+		LifeWindow:         eviction,
+		CleanWindow:        1 * time.Second,
+		MaxEntriesInWindow: 1000 * 10 * 60,
+		MaxEntrySize:       500,
+		Hasher:             fnv64a{},
+	}
+}
+
+// BigCache stores byte slices in many shards so concurrent access rarely
+// contends on a lock
+type BigCache struct {
+	store      *store
+	lifeWindow time.Duration
+	close      chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewBigCache creates a BigCache; its cleanup stops when ctx is done or
+// Close is called
+func NewBigCache(ctx context.Context, config Config) (*BigCache, error) {
+	if config.Shards <= 0 || config.Shards&(config.Shards-1) != 0 {
+		return nil, errors.New("Shards number must be power of two")
+	}
+	if config.HardMaxCacheSize < 0 {
+		return nil, errors.New("HardMaxCacheSize must not be negative")
+	}
+	if config.Hasher == nil {
+		config.Hasher = fnv64a{}
+	}
+
+	var maxCost int64
+	if config.HardMaxCacheSize > 0 {
+		maxCost = int64(config.HardMaxCacheSize) * 1024 * 1024 / int64(config.Shards)
+	}
+	capacity := config.MaxEntriesInWindow / config.Shards
+	cache := &BigCache{
+		store:      newStore(config.Shards, maxCost, capacity, config.Hasher),
+		lifeWindow: config.LifeWindow,
+		close:      make(chan struct{}),
+	}
+
+	switch {
+	case config.OnRemoveWithReason != nil:
+		onRemove := config.OnRemoveWithReason
+		cache.store.onRemove = func(key string, value interface{}, reason RemoveReason) {
+			onRemove(key, value.([]byte), reason)
+		}
+	case config.OnRemove != nil:
+		onRemove := config.OnRemove
+		cache.store.onRemove = func(key string, value interface{}, _ RemoveReason) {
+			onRemove(key, value.([]byte))
+		}
+	}
+
+	if config.CleanWindow > 0 {
+		go func() {
+			ticker := time.NewTicker(config.CleanWindow)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-cache.close:
+					return
+				case <-ticker.C:
+					cache.store.deleteExpired()
+				}
+			}
+		}()
+	}
+	return cache, nil
+}
+
+// Get returns a copy of the entry under key, or ErrEntryNotFound
+func (c *BigCache) Get(key string) ([]byte, error) {
+	item, ok := c.store.get(key)
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	return append([]byte(nil), item.Object.([]byte)...), nil
+}
+
+// Set stores a copy of entry under key
+func (c *BigCache) Set(key string, entry []byte) error {
+	sh := c.store.shard(key)
+	cost := int64(len(key) + len(entry))
+	if sh.maxCost > 0 && cost > sh.maxCost {
+		return errors.New("entry is bigger than max shard size")
+	}
+	var expiration int64
+	if c.lifeWindow > 0 {
+		expiration = time.Now().Add(c.lifeWindow).UnixNano()
+	}
+	item := Item{Object: append([]byte(nil), entry...), Expiration: expiration}
+	c.store.removed(sh.set(key, item, cost))
+	return nil
+}
+
+// Append adds entry to the end of the entry under key, setting it if
+// missing
+func (c *BigCache) Append(key string, entry []byte) error {
+	current, err := c.Get(key)
+	if err != nil && err != ErrEntryNotFound {
+		return err
+	}
+	return c.Set(key, append(current, entry...))
+}
+
+// Delete removes the entry under key, or returns ErrEntryNotFound
+func (c *BigCache) Delete(key string) error {
+	if !c.store.delete(key) {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+// Reset removes all entries
+func (c *BigCache) Reset() error {
+	c.store.flush()
+	return nil
+}
+
+// Len returns the number of entries
+func (c *BigCache) Len() int {
+	return c.store.len()
+}
+
+// Capacity returns the bytes held by keys and entries
+func (c *BigCache) Capacity() int {
+	var total int64
+	for _, sh := range c.store.shards {
+		sh.mu.Lock()
+		total += sh.cost
+		sh.mu.Unlock()
+	}
+	return int(total)
+}
+
+// Stats returns the hit, miss and eviction counts
+func (c *BigCache) Stats() Stats {
+	return c.store.stats()
+}
+
+// Close stops the cleanup goroutine
+func (c *BigCache) Close() error {
+	c.closeOnce.Do(func() { close(c.close) })
+	return nil
+}
+
+// Gin cache store
+
+// Errors returned by InMemoryStore, as in gin-contrib/cache/persistence
+var (
+	ErrCacheMiss  = errors.New("persistence cache miss")
+	ErrNotStored  = errors.New("persistence: item not stored")
+	ErrNotSupport = errors.New("persistence: not support")
+)
+
+// InMemoryStore adapts a Cache to the Gin emulator's CacheStore, so
+// CachePage can keep pages in it
+type InMemoryStore struct {
+	*Cache
+}
+
+// NewInMemoryStore creates a store on a new Cache cleaned up every minute
+func NewInMemoryStore(defaultExpiration time.Duration) *InMemoryStore {
+	return &InMemoryStore{New(defaultExpiration, time.Minute)}
+}
+
+// Get copies the value under key into value, which must be a pointer to
+// the stored type
+func (s *InMemoryStore) Get(key string, value interface{}) error {
+	item, found := s.Cache.Get(key)
+	if !found {
+		return ErrCacheMiss
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("persistence: Get into non-pointer %T", value)
+	}
+	stored := reflect.ValueOf(item)
+	if !stored.Type().AssignableTo(v.Elem().Type()) {
+		return fmt.Errorf("persistence: cannot assign %T to %s", item, v.Elem().Type())
+	}
+	v.Elem().Set(stored)
+	return nil
+}
+
+// Set stores value under key
+func (s *InMemoryStore) Set(key string, value interface{}, expires time.Duration) error {
+	s.Cache.Set(key, value, expires)
+	return nil
+}
+
+// Add stores value under key unless it exists
+func (s *InMemoryStore) Add(key string, value interface{}, expires time.Duration) error {
+	if err := s.Cache.Add(key, value, expires); err != nil {
+		return ErrNotStored
+	}
+	return nil
+}
+
+// Replace stores value under key only if it exists
+func (s *InMemoryStore) Replace(key string, value interface{}, expires time.Duration) error {
+	if err := s.Cache.Replace(key, value, expires); err != nil {
+		return ErrNotStored
+	}
+	return nil
+}
+
+// Delete removes key, or returns ErrCacheMiss
+func (s *InMemoryStore) Delete(key string) error {
+	if !s.Cache.delete(key) {
+		return ErrCacheMiss
+	}
+	return nil
+}
+
+// Increment adds n to the integer under key
+func (s *InMemoryStore) Increment(key string, n uint64) (uint64, error) {
+	return s.adjust(key, int64(n))
+}
+
+// Decrement subtracts n from the integer under key
+func (s *InMemoryStore) Decrement(key string, n uint64) (uint64, error) {
+	return s.adjust(key, -int64(n))
+}
+
+func (s *InMemoryStore) adjust(key string, n int64) (uint64, error) {
+	v, err := s.shard(key).update(key, time.Now().UnixNano(), func(item Item) (interface{}, error) {
+		return addInteger(key, item.Object, n)
+	})
+	if _, missing := err.(itemNotFound); missing {
+		return 0, ErrCacheMiss
+	}
+	if err != nil {
+		return 0, err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.CanInt() {
+		return uint64(rv.Int()), nil
+	}
+	return rv.Uint(), nil
+}
+
+// Flush removes every item
+func (s *InMemoryStore) Flush() error {
+	s.Cache.Flush()
+	return nil
+}
@@ -0,0 +1,599 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-cache and bigcache
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// evictionLog records eviction callbacks
+type evictionLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *evictionLog) record(key string, value interface{}, reason RemoveReason) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf("%s=%v:%s", key, value, reason))
+}
+
+func (l *evictionLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.entries, ",")
+}
+
+// eventually polls cond for up to a second
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+// zeroHasher sends every key to the first shard
+type zeroHasher struct{}
+
+func (zeroHasher) Sum64(string) uint64 { return 0 }
+
+func testSetAndGet() bool {
+	c := New(NoExpiration, 0)
+	c.Set("a", 1, DefaultExpiration)
+	c.SetDefault("b", "two")
+	c.Set("c", []int{3}, time.Hour)
+
+	a, okA := c.Get("a")
+	b, okB := c.Get("b")
+	if !okA || !okB || a.(int) != 1 || b.(string) != "two" {
+		return false
+	}
+	if _, ok := c.Get("missing"); ok {
+		return false
+	}
+
+	// Expirations are reported as times, zero for items that never expire
+	_, never, _ := c.GetWithExpiration("a")
+	_, expires, ok := c.GetWithExpiration("c")
+	if !ok || !never.IsZero() || expires.Before(time.Now().Add(59*time.Minute)) {
+		return false
+	}
+
+	// Setting again replaces the value
+	c.Set("a", 10, NoExpiration)
+	if a, _ := c.Get("a"); a.(int) != 10 || c.ItemCount() != 3 {
+		return false
+	}
+
+	// Items round-trip through NewFrom
+	restored := NewFrom(NoExpiration, 0, c.Items())
+	if v, ok := restored.Get("c"); !ok || v.([]int)[0] != 3 || restored.ItemCount() != 3 {
+		return false
+	}
+
+	c.Delete("a")
+	c.Delete("missing")
+	c.Flush()
+	_, ok = c.Get("b")
+	return !ok && c.ItemCount() == 0
+}
+
+func testExpiration() bool {
+	c := New(30*time.Millisecond, 0)
+	var log evictionLog
+	c.OnEvictedWithReason(log.record)
+	c.SetDefault("short", 1)
+	c.Set("long", 2, time.Hour)
+	c.Set("forever", 3, NoExpiration)
+
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		return false
+	}
+	if _, ok := c.Get("long"); !ok {
+		return false
+	}
+
+	// Expired items are hidden from Items but counted until deleted
+	if len(c.Items()) != 2 || c.ItemCount() != 3 {
+		return false
+	}
+	c.DeleteExpired()
+	if c.ItemCount() != 2 || log.String() != "short=1:Expired" {
+		return false
+	}
+
+	// An expired item can be added again
+	c.Set("blink", 1, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if err := c.Add("blink", 2, NoExpiration); err != nil {
+		return false
+	}
+	v, ok := c.Get("blink")
+	return ok && v.(int) == 2 && c.Stats().Expirations == 1
+}
+
+func testAddAndReplace() bool {
+	c := New(NoExpiration, 0)
+	if err := c.Add("k", "v1", DefaultExpiration); err != nil {
+		return false
+	}
+	if err := c.Add("k", "v2", DefaultExpiration); err == nil || err.Error() != "Item k already exists" {
+		return false
+	}
+	if err := c.Replace("k", "v3", DefaultExpiration); err != nil {
+		return false
+	}
+	if err := c.Replace("nope", "v", DefaultExpiration); err == nil || err.Error() != "Item nope doesn't exist" {
+		return false
+	}
+	v, _ := c.Get("k")
+	return v.(string) == "v3"
+}
+
+func testIncrementAndDecrement() bool {
+	c := New(NoExpiration, 0)
+	c.Set("int", 1, DefaultExpiration)
+	c.Set("uint8", uint8(250), DefaultExpiration)
+	c.Set("float", 1.5, DefaultExpiration)
+	c.Set("int64", int64(100), DefaultExpiration)
+	c.Set("word", "hello", DefaultExpiration)
+
+	if c.Increment("int", 5) != nil || c.Decrement("int", 2) != nil {
+		return false
+	}
+	if v, _ := c.Get("int"); v.(int) != 4 {
+		return false
+	}
+	// Increments keep the stored type
+	if c.Increment("uint8", 3) != nil {
+		return false
+	}
+	if v, _ := c.Get("uint8"); v.(uint8) != 253 {
+		return false
+	}
+	if c.IncrementFloat("float", 0.25) != nil {
+		return false
+	}
+	if v, _ := c.Get("float"); v.(float64) != 1.75 {
+		return false
+	}
+	if n, err := c.IncrementInt("int", 10); err != nil || n != 14 {
+		return false
+	}
+	if n, err := c.DecrementInt64("int64", 1); err != nil || n != 99 {
+		return false
+	}
+
+	if err := c.Increment("word", 1); err == nil || err.Error() != "The value for word is not an integer" {
+		return false
+	}
+	if err := c.Increment("float", 1); err == nil {
+		return false
+	}
+	if _, err := c.IncrementInt("int64", 1); err == nil || err.Error() != "The value for int64 is not an int" {
+		return false
+	}
+	if err := c.Increment("missing", 1); err == nil || err.Error() != "Item missing not found" {
+		return false
+	}
+	return true
+}
+
+func testEvictionCallbacks() bool {
+	c := New(NoExpiration, 0)
+	var evicted []string
+	c.OnEvicted(func(key string, value interface{}) {
+		evicted = append(evicted, fmt.Sprintf("%s=%v", key, value))
+		// Callbacks run outside the cache's locks
+		c.Set("seen-"+key, true, NoExpiration)
+	})
+	c.Set("a", 1, DefaultExpiration)
+	c.Set("b", 2, DefaultExpiration)
+	c.Delete("a")
+	c.Delete("nope")
+	if strings.Join(evicted, ",") != "a=1" {
+		return false
+	}
+	if _, ok := c.Get("seen-a"); !ok {
+		return false
+	}
+
+	// Flush does not report items
+	c.Flush()
+	if len(evicted) != 1 {
+		return false
+	}
+
+	var log evictionLog
+	c.OnEvictedWithReason(log.record)
+	c.Set("x", "y", DefaultExpiration)
+	c.Delete("x")
+	c.OnEvicted(nil)
+	c.Set("z", 0, DefaultExpiration)
+	c.Delete("z")
+	return log.String() == "x=y:Deleted"
+}
+
+func testLRUBound() bool {
+	c, err := NewWithOptions(Options{MaxItems: 3})
+	if err != nil {
+		return false
+	}
+	var log evictionLog
+	c.OnEvictedWithReason(log.record)
+	c.Set("a", 1, NoExpiration)
+	c.Set("b", 2, NoExpiration)
+	c.Set("c", 3, NoExpiration)
+
+	// Reading a makes b the least recently used
+	c.Get("a")
+	c.Set("d", 4, NoExpiration)
+	if _, ok := c.Get("b"); ok {
+		return false
+	}
+	if c.ItemCount() != 3 || log.String() != "b=2:NoSpace" {
+		return false
+	}
+
+	// Replacing a value refreshes it too
+	c.Set("c", 30, NoExpiration)
+	c.Set("e", 5, NoExpiration)
+	c.Set("f", 6, NoExpiration)
+	items := c.Items()
+	if len(items) != 3 || items["c"].Object.(int) != 30 || items["e"].Object == nil || items["f"].Object == nil {
+		return false
+	}
+	if log.String() != "b=2:NoSpace,a=1:NoSpace,d=4:NoSpace" || c.Stats().Evictions != 3 {
+		return false
+	}
+
+	// Increments do not count as use
+	c.Increment("c", 1)
+	c.Set("g", 7, NoExpiration)
+	_, ok := c.Get("c")
+	return !ok
+}
+
+func testSharding() bool {
+	if _, err := NewWithOptions(Options{Shards: 3}); err == nil || err.Error() != "Shards number must be power of two" {
+		return false
+	}
+	if _, err := NewWithOptions(Options{MaxItems: -1}); err == nil {
+		return false
+	}
+
+	// The default cache spreads keys over 16 shards
+	c := New(NoExpiration, 0)
+	if len(c.shards) != 16 {
+		return false
+	}
+	for i := 0; i < 100; i++ {
+		c.Set("key"+strconv.Itoa(i), i, NoExpiration)
+	}
+	used := 0
+	for _, sh := range c.shards {
+		if len(sh.items) > 0 {
+			used++
+		}
+	}
+	if used < 12 || c.ItemCount() != 100 {
+		return false
+	}
+
+	// The size bound is split across shards
+	bounded, _ := NewWithOptions(Options{MaxItems: 16, Shards: 8})
+	for i := 0; i < 100; i++ {
+		bounded.Set("key"+strconv.Itoa(i), i, NoExpiration)
+	}
+	for _, sh := range bounded.shards {
+		if len(sh.items) > 2 {
+			return false
+		}
+	}
+	if bounded.ItemCount() > 16 {
+		return false
+	}
+
+	// A custom Hasher decides the shard
+	skewed, _ := NewWithOptions(Options{MaxItems: 8, Shards: 4, Hasher: zeroHasher{}})
+	for i := 0; i < 4; i++ {
+		skewed.Set("key"+strconv.Itoa(i), i, NoExpiration)
+	}
+	return len(skewed.shards[0].items) == 2 && skewed.ItemCount() == 2
+}
+
+func testStats() bool {
+	c := New(NoExpiration, 0)
+	c.Set("a", 1, NoExpiration)
+	c.Get("a")
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.GetWithExpiration("c")
+	c.Delete("a")
+	c.Delete("a")
+
+	stats := c.Stats()
+	return stats.Hits == 3 && stats.Misses == 2 && stats.DelHits == 1 && stats.DelMisses == 1 &&
+		stats.HitRate() == 0.6 && (Stats{}).HitRate() == 0
+}
+
+func testJanitor() bool {
+	c := New(20*time.Millisecond, 10*time.Millisecond)
+	var log evictionLog
+	c.OnEvictedWithReason(log.record)
+	c.SetDefault("session", "abc")
+	c.Set("config", "x", NoExpiration)
+
+	// Expired items are deleted in the background
+	if !eventually(func() bool { return log.String() == "session=abc:Expired" }) {
+		return false
+	}
+	return c.ItemCount() == 1
+}
+
+func testBigCache() bool {
+	config := DefaultConfig(time.Hour)
+	config.Shards = 64
+	cache, err := NewBigCache(context.Background(), config)
+	if err != nil {
+		return false
+	}
+	defer cache.Close()
+
+	entry := []byte("payload")
+	cache.Set("k", entry)
+	entry[0] = 'X'
+	got, err := cache.Get("k")
+	if err != nil || string(got) != "payload" {
+		return false
+	}
+	// Get returns a copy
+	got[0] = 'Y'
+	if again, _ := cache.Get("k"); string(again) != "payload" {
+		return false
+	}
+	if _, err := cache.Get("missing"); err != ErrEntryNotFound {
+		return false
+	}
+
+	cache.Append("k", []byte("+more"))
+	cache.Append("new", []byte("start"))
+	appended, _ := cache.Get("k")
+	started, _ := cache.Get("new")
+	if string(appended) != "payload+more" || string(started) != "start" {
+		return false
+	}
+	if cache.Len() != 2 || cache.Capacity() != len("k")+len("payload+more")+len("new")+len("start") {
+		return false
+	}
+	if cache.Delete("k") != nil || cache.Delete("k") != ErrEntryNotFound {
+		return false
+	}
+	cache.Reset()
+	if cache.Len() != 0 {
+		return false
+	}
+	stats := cache.Stats()
+	if stats.Hits != 5 || stats.Misses != 2 || stats.DelHits != 1 || stats.DelMisses != 1 {
+		return false
+	}
+
+	if _, err := NewBigCache(context.Background(), Config{Shards: 10}); err == nil {
+		return false
+	}
+	return true
+}
+
+func testBigCacheEviction() bool {
+	var log evictionLog
+	config := Config{
+		Shards:           1,
+		LifeWindow:       20 * time.Millisecond,
+		CleanWindow:      10 * time.Millisecond,
+		HardMaxCacheSize: 1,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			log.record(key, len(entry), reason)
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache, err := NewBigCache(ctx, config)
+	if err != nil {
+		return false
+	}
+
+	// Entries beyond the hard limit evict the least recently used
+	block := make([]byte, 400*1024)
+	cache.Set("a", block)
+	cache.Set("b", block)
+	cache.Get("a")
+	cache.Set("c", block)
+	if _, err := cache.Get("b"); err != ErrEntryNotFound {
+		return false
+	}
+	if log.String() != "b=409600:NoSpace" {
+		return false
+	}
+	if err := cache.Set("huge", make([]byte, 2*1024*1024)); err == nil {
+		return false
+	}
+
+	// Entries outliving LifeWindow are cleaned up
+	if !eventually(func() bool { return cache.Len() == 0 }) {
+		return false
+	}
+	if !strings.Contains(log.String(), "a=409600:Expired") || !strings.Contains(log.String(), "c=409600:Expired") {
+		return false
+	}
+
+	// OnRemove is used without a reason
+	var removed []string
+	var mu sync.Mutex
+	plain, _ := NewBigCache(ctx, Config{Shards: 2, OnRemove: func(key string, entry []byte) {
+		mu.Lock()
+		removed = append(removed, key+"="+string(entry))
+		mu.Unlock()
+	}})
+	plain.Set("x", []byte("1"))
+	plain.Delete("x")
+	mu.Lock()
+	defer mu.Unlock()
+	return strings.Join(removed, ",") == "x=1"
+}
+
+// page mirrors the Gin emulator's CachedPage
+type page struct {
+	Status int
+	Header map[string]string
+	Data   []byte
+}
+
+// ginCacheStore mirrors the Gin emulator's CacheStore
+type ginCacheStore interface {
+	Get(key string, value interface{}) error
+	Set(key string, value interface{}, expire time.Duration) error
+	Delete(key string) error
+}
+
+func testGinStore() bool {
+	var store ginCacheStore = NewInMemoryStore(time.Minute)
+	cached := page{Status: 200, Header: map[string]string{"Content-Type": "text/plain"}, Data: []byte("hi")}
+	if store.Set("gincontrib.page.cache:%2F", cached, DefaultExpiration) != nil {
+		return false
+	}
+	var got page
+	if err := store.Get("gincontrib.page.cache:%2F", &got); err != nil || got.Status != 200 || string(got.Data) != "hi" {
+		return false
+	}
+	if err := store.Get("other", &got); err != ErrCacheMiss {
+		return false
+	}
+	var wrongType string
+	if err := store.Get("gincontrib.page.cache:%2F", &wrongType); err == nil {
+		return false
+	}
+	if err := store.Get("gincontrib.page.cache:%2F", got); err == nil {
+		return false
+	}
+	if store.Delete("gincontrib.page.cache:%2F") != nil || store.Delete("gincontrib.page.cache:%2F") != ErrCacheMiss {
+		return false
+	}
+
+	s := NewInMemoryStore(time.Minute)
+	if s.Add("n", 5, DefaultExpiration) != nil || s.Add("n", 6, DefaultExpiration) != ErrNotStored {
+		return false
+	}
+	if s.Replace("m", 1, DefaultExpiration) != ErrNotStored || s.Replace("n", 10, DefaultExpiration) != nil {
+		return false
+	}
+	if n, err := s.Increment("n", 5); err != nil || n != 15 {
+		return false
+	}
+	if n, err := s.Decrement("n", 3); err != nil || n != 12 {
+		return false
+	}
+	if _, err := s.Increment("absent", 1); err != ErrCacheMiss {
+		return false
+	}
+
+	// The store can wrap an existing, bounded Cache
+	bounded, _ := NewWithOptions(Options{MaxItems: 1})
+	shared := &InMemoryStore{bounded}
+	shared.Set("a", 1, DefaultExpiration)
+	shared.Set("b", 2, DefaultExpiration)
+	var n int
+	if shared.Get("a", &n) != ErrCacheMiss || shared.Get("b", &n) != nil || n != 2 {
+		return false
+	}
+	return s.Flush() == nil && s.ItemCount() == 0
+}
+
+// queryCache mirrors the GORM emulator's QueryCache
+type queryCache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, d time.Duration)
+}
+
+func testGormQueryCache() bool {
+	var qc queryCache = New(time.Minute, time.Minute)
+	rows := []map[string]interface{}{{"ID": uint(1), "Name": "Alice"}}
+	qc.Set("gorm:users:0:find:[]:-1:0:", rows, 20*time.Millisecond)
+	cached, ok := qc.Get("gorm:users:0:find:[]:-1:0:")
+	if !ok || cached.([]map[string]interface{})[0]["Name"] != "Alice" {
+		return false
+	}
+	// Results expire after the TTL the DB asks for
+	time.Sleep(30 * time.Millisecond)
+	_, ok = qc.Get("gorm:users:0:find:[]:-1:0:")
+	return !ok
+}
+
+func testConcurrentAccess() bool {
+	c, _ := NewWithOptions(Options{MaxItems: 256, Shards: 16})
+	var log evictionLog
+	c.OnEvictedWithReason(log.record)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := "k" + strconv.Itoa((w*500+i)%600)
+				c.Set(key, i, time.Minute)
+				c.Get(key)
+				c.Add("counter", 0, NoExpiration)
+				c.Increment("counter", 1)
+				if i%50 == 0 {
+					c.Delete(key)
+					c.Items()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	stats := c.Stats()
+	return c.ItemCount() <= 256 && stats.Hits+stats.Misses == 8*500 && stats.Evictions > 0
+}
+
+func main() {
+	fmt.Println("Running Cache Emulator Tests...")
+	fmt.Println("===============================")
+
+	runTest("Set And Get", testSetAndGet)
+	runTest("Expiration", testExpiration)
+	runTest("Add And Replace", testAddAndReplace)
+	runTest("Increment And Decrement", testIncrementAndDecrement)
+	runTest("Eviction Callbacks", testEvictionCallbacks)
+	runTest("LRU Bound", testLRUBound)
+	runTest("Sharding", testSharding)
+	runTest("Stats", testStats)
+	runTest("Janitor", testJanitor)
+	runTest("BigCache", testBigCache)
+	runTest("BigCache Eviction", testBigCacheEviction)
+	runTest("Gin Store", testGinStore)
+	runTest("GORM Query Cache", testGormQueryCache)
+	runTest("Concurrent Access", testConcurrentAccess)
+
+	fmt.Println("===============================")
+	fmt.Println("All tests completed!")
+}
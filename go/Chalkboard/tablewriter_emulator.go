@@ -0,0 +1,1422 @@
+package main
+
+// Developed by PowerShield, as an alternative to tablewriter and fatih/color
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// timeNow is the clock progress bars measure rates and estimates with
+var timeNow = time.Now
+
+// Text width
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+// wideRanges are the East Asian wide and emoji ranges, which take two
+// terminal columns
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F}, {0x2E80, 0x303E}, {0x3041, 0x33FF}, {0x3400, 0x4DBF},
+	{0x4E00, 0x9FFF}, {0xA000, 0xA4CF}, {0xAC00, 0xD7A3}, {0xF900, 0xFAFF},
+	{0xFE30, 0xFE4F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x1F300, 0x1F64F},
+	{0x1F680, 0x1F6FF}, {0x1F900, 0x1F9FF}, {0x20000, 0x3FFFD},
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r < 32 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || r == '\u200b':
+		return 0
+	}
+	for _, rg := range wideRanges {
+		if r >= rg[0] && r <= rg[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// DisplayWidth returns how many terminal columns s takes, ignoring ANSI
+// escape codes and counting wide characters twice
+func DisplayWidth(s string) int {
+	s = ansiEscape.ReplaceAllString(s, "")
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// Pad centers s in width columns
+func Pad(s, pad string, width int) string {
+	gap := width - DisplayWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	left := gap / 2
+	return strings.Repeat(pad, left) + s + strings.Repeat(pad, gap-left)
+}
+
+// PadRight aligns s left in width columns
+func PadRight(s, pad string, width int) string {
+	gap := width - DisplayWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	return s + strings.Repeat(pad, gap)
+}
+
+// PadLeft aligns s right in width columns
+func PadLeft(s, pad string, width int) string {
+	gap := width - DisplayWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	return strings.Repeat(pad, gap) + s
+}
+
+// WrapString splits s into lines of at most lim columns at spaces. Words
+// longer than lim get a line of their own. It also returns the widest
+// line's width.
+func WrapString(s string, lim int) ([]string, int) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return []string{""}, 0
+	}
+	var lines []string
+	line, width, widest := words[0], DisplayWidth(words[0]), 0
+	for _, word := range words[1:] {
+		w := DisplayWidth(word)
+		if width+1+w > lim {
+			lines = append(lines, line)
+			if width > widest {
+				widest = width
+			}
+			line, width = word, w
+			continue
+		}
+		line += " " + word
+		width += 1 + w
+	}
+	lines = append(lines, line)
+	if width > widest {
+		widest = width
+	}
+	return lines, widest
+}
+
+// Title formats a header: upper case, with underscores and dots that
+// aren't decimal points turned into spaces
+func Title(name string) string {
+	rs := []rune(name)
+	for i, r := range rs {
+		switch r {
+		case '_':
+			rs[i] = ' '
+		case '.':
+			if i == 0 || i == len(rs)-1 || !unicode.IsDigit(rs[i-1]) || !unicode.IsDigit(rs[i+1]) {
+				rs[i] = ' '
+			}
+		}
+	}
+	return strings.ToUpper(strings.TrimSpace(string(rs)))
+}
+
+// Colors, as in the fatih/color package
+
+// Attribute is an SGR parameter: a style, foreground or background color
+type Attribute int
+
+// Styles
+const (
+	Reset Attribute = iota
+	Bold
+	Faint
+	Italic
+	Underline
+	BlinkSlow
+	BlinkRapid
+	ReverseVideo
+	Concealed
+	CrossedOut
+)
+
+// Foreground colors
+const (
+	FgBlack Attribute = iota + 30
+	FgRed
+	FgGreen
+	FgYellow
+	FgBlue
+	FgMagenta
+	FgCyan
+	FgWhite
+)
+
+// Bright foreground colors
+const (
+	FgHiBlack Attribute = iota + 90
+	FgHiRed
+	FgHiGreen
+	FgHiYellow
+	FgHiBlue
+	FgHiMagenta
+	FgHiCyan
+	FgHiWhite
+)
+
+// Background colors
+const (
+	BgBlack Attribute = iota + 40
+	BgRed
+	BgGreen
+	BgYellow
+	BgBlue
+	BgMagenta
+	BgCyan
+	BgWhite
+)
+
+// Bright background colors
+const (
+	BgHiBlack Attribute = iota + 100
+	BgHiRed
+	BgHiGreen
+	BgHiYellow
+	BgHiBlue
+	BgHiMagenta
+	BgHiCyan
+	BgHiWhite
+)
+
+// isTerminal reports whether w is a terminal rather than a file, pipe or
+// buffer
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// IsTerminal reports whether w is a terminal
+func IsTerminal(w io.Writer) bool { return isTerminal(w) }
+
+// noColorFor reports whether output to w should be plain: NO_COLOR is set,
+// the terminal is dumb, or w isn't a terminal
+func noColorFor(w io.Writer, getenv func(string) string) bool {
+	return getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" || !isTerminal(w)
+}
+
+// NoColor turns every Color off. It is set when standard output isn't a
+// terminal, or NO_COLOR is set.
+var NoColor = noColorFor(os.Stdout, os.Getenv)
+
+// ShouldColor reports whether output to w should be colored, such as a
+// command's OutOrStdout
+func ShouldColor(w io.Writer) bool {
+	return !noColorFor(w, os.Getenv)
+}
+
+var (
+	// Output is where Color's Print functions write
+	Output io.Writer = os.Stdout
+	// Error is standard error, for Fprint
+	Error io.Writer = os.Stderr
+)
+
+// Color is a set of attributes applied to text
+type Color struct {
+	params  []Attribute
+	noColor *bool
+}
+
+// NewColor returns a Color with the attributes
+func NewColor(value ...Attribute) *Color {
+	return &Color{params: append([]Attribute(nil), value...)}
+}
+
+// Add adds attributes to c
+func (c *Color) Add(value ...Attribute) *Color {
+	c.params = append(c.params, value...)
+	return c
+}
+
+// DisableColor turns c off, whatever NoColor says
+func (c *Color) DisableColor() {
+	off := true
+	c.noColor = &off
+}
+
+// EnableColor turns c on, whatever NoColor says
+func (c *Color) EnableColor() {
+	off := false
+	c.noColor = &off
+}
+
+func (c *Color) isNoColorSet() bool {
+	if c.noColor != nil {
+		return *c.noColor
+	}
+	return NoColor
+}
+
+// Equals reports whether c and c2 have the same attributes
+func (c *Color) Equals(c2 *Color) bool {
+	if c == nil || c2 == nil {
+		return c == c2
+	}
+	if len(c.params) != len(c2.params) {
+		return false
+	}
+	for _, p := range c.params {
+		found := false
+		for _, p2 := range c2.params {
+			if p == p2 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Color) sequence() string {
+	parts := make([]string, len(c.params))
+	for i, p := range c.params {
+		parts[i] = strconv.Itoa(int(p))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (c *Color) wrap(s string) string {
+	if c.isNoColorSet() || len(c.params) == 0 {
+		return s
+	}
+	return "\x1b[" + c.sequence() + "m" + s + "\x1b[0m"
+}
+
+// Sprint formats like fmt.Sprint, in c's colors
+func (c *Color) Sprint(a ...interface{}) string {
+	return c.wrap(fmt.Sprint(a...))
+}
+
+// Sprintf formats like fmt.Sprintf, in c's colors
+func (c *Color) Sprintf(format string, a ...interface{}) string {
+	return c.wrap(fmt.Sprintf(format, a...))
+}
+
+// Sprintln formats like fmt.Sprintln, in c's colors. The newline is
+// after the reset, so the next line starts plain.
+func (c *Color) Sprintln(a ...interface{}) string {
+	return c.wrap(strings.TrimSuffix(fmt.Sprintln(a...), "\n")) + "\n"
+}
+
+// SprintFunc returns c.Sprint, to pass around
+func (c *Color) SprintFunc() func(a ...interface{}) string {
+	return c.Sprint
+}
+
+// SprintfFunc returns c.Sprintf, to pass around
+func (c *Color) SprintfFunc() func(format string, a ...interface{}) string {
+	return c.Sprintf
+}
+
+// Fprint writes to w like fmt.Fprint, in c's colors
+func (c *Color) Fprint(w io.Writer, a ...interface{}) (int, error) {
+	return io.WriteString(w, c.Sprint(a...))
+}
+
+// Fprintf writes to w like fmt.Fprintf, in c's colors
+func (c *Color) Fprintf(w io.Writer, format string, a ...interface{}) (int, error) {
+	return io.WriteString(w, c.Sprintf(format, a...))
+}
+
+// Fprintln writes to w like fmt.Fprintln, in c's colors
+func (c *Color) Fprintln(w io.Writer, a ...interface{}) (int, error) {
+	return io.WriteString(w, c.Sprintln(a...))
+}
+
+// Print writes to Output
+func (c *Color) Print(a ...interface{}) (int, error) { return c.Fprint(Output, a...) }
+
+// Printf writes to Output
+func (c *Color) Printf(format string, a ...interface{}) (int, error) {
+	return c.Fprintf(Output, format, a...)
+}
+
+// Println writes to Output
+func (c *Color) Println(a ...interface{}) (int, error) { return c.Fprintln(Output, a...) }
+
+func colorPrint(format string, p Attribute, a ...interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	c := NewColor(p)
+	if len(a) == 0 {
+		c.Print(format)
+		return
+	}
+	c.Printf(format, a...)
+}
+
+func colorString(format string, p Attribute, a ...interface{}) string {
+	c := NewColor(p)
+	if len(a) == 0 {
+		return c.Sprint(format)
+	}
+	return c.Sprintf(format, a...)
+}
+
+// Black prints a line to Output in black; the others print in their colors
+func Black(format string, a ...interface{})   { colorPrint(format, FgBlack, a...) }
+func Red(format string, a ...interface{})     { colorPrint(format, FgRed, a...) }
+func Green(format string, a ...interface{})   { colorPrint(format, FgGreen, a...) }
+func Yellow(format string, a ...interface{})  { colorPrint(format, FgYellow, a...) }
+func Blue(format string, a ...interface{})    { colorPrint(format, FgBlue, a...) }
+func Magenta(format string, a ...interface{}) { colorPrint(format, FgMagenta, a...) }
+func Cyan(format string, a ...interface{})    { colorPrint(format, FgCyan, a...) }
+func White(format string, a ...interface{})   { colorPrint(format, FgWhite, a...) }
+
+// BlackString formats in black; the others format in their colors
+func BlackString(format string, a ...interface{}) string { return colorString(format, FgBlack, a...) }
+func RedString(format string, a ...interface{}) string   { return colorString(format, FgRed, a...) }
+func GreenString(format string, a ...interface{}) string { return colorString(format, FgGreen, a...) }
+func YellowString(format string, a ...interface{}) string {
+	return colorString(format, FgYellow, a...)
+}
+func BlueString(format string, a ...interface{}) string { return colorString(format, FgBlue, a...) }
+func MagentaString(format string, a ...interface{}) string {
+	return colorString(format, FgMagenta, a...)
+}
+func CyanString(format string, a ...interface{}) string  { return colorString(format, FgCyan, a...) }
+func WhiteString(format string, a ...interface{}) string { return colorString(format, FgWhite, a...) }
+
+// colorNames maps the names spinners take to attributes
+var colorNames = func() map[string]Attribute {
+	names := map[string]Attribute{
+		"reset": Reset, "bold": Bold, "faint": Faint, "italic": Italic,
+		"underline": Underline, "blinkslow": BlinkSlow, "blinkrapid": BlinkRapid,
+		"reversevideo": ReverseVideo, "concealed": Concealed, "crossedout": CrossedOut,
+	}
+	for i, name := range []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"} {
+		title := strings.ToUpper(name[:1]) + name[1:]
+		names[name] = FgBlack + Attribute(i)
+		names["fg"+title] = FgBlack + Attribute(i)
+		names["fgHi"+title] = FgHiBlack + Attribute(i)
+		names["bg"+title] = BgBlack + Attribute(i)
+		names["bgHi"+title] = BgHiBlack + Attribute(i)
+	}
+	return names
+}()
+
+// Tables, as in the tablewriter package
+
+// Alignments
+const (
+	ALIGN_DEFAULT = iota
+	ALIGN_CENTER
+	ALIGN_RIGHT
+	ALIGN_LEFT
+)
+
+// MAX_ROW_WIDTH is the default column width text wraps at
+const MAX_ROW_WIDTH = 30
+
+var (
+	decimal = regexp.MustCompile(`^-?(?:\d{1,3}(?:,\d{3})*|\d+)(?:\.\d+)?$`)
+	percent = regexp.MustCompile(`^-?\d+\.?\d*%$`)
+)
+
+// Border says which outer borders a table has
+type Border struct {
+	Left   bool
+	Right  bool
+	Top    bool
+	Bottom bool
+}
+
+// Colors are the attributes of one cell
+type Colors []Attribute
+
+// Table renders rows as an aligned text table
+type Table struct {
+	out          io.Writer
+	header       []string
+	footer       []string
+	rows         [][]string
+	rowColors    map[int][]Colors
+	colWidth     int
+	minWidths    map[int]int
+	autoWrap     bool
+	autoFmt      bool
+	autoMerge    bool
+	align        int
+	columnAlign  []int
+	hAlign       int
+	fAlign       int
+	borders      Border
+	rowLine      bool
+	headerLine   bool
+	pCenter      string
+	pRow         string
+	pColumn      string
+	tablePadding string
+	noWhiteSpace bool
+	caption      bool
+	captionText  string
+	headerColors []Colors
+	columnColors []Colors
+	footerColors []Colors
+	newLine      string
+
+	// widths of the columns being rendered
+	cs []int
+}
+
+// NewWriter returns a table that renders to w, such as a command's
+// OutOrStdout
+func NewWriter(w io.Writer) *Table {
+	return &Table{
+		out:          w,
+		rowColors:    map[int][]Colors{},
+		colWidth:     MAX_ROW_WIDTH,
+		minWidths:    map[int]int{},
+		autoWrap:     true,
+		autoFmt:      true,
+		borders:      Border{Left: true, Right: true, Top: true, Bottom: true},
+		headerLine:   true,
+		pCenter:      "+",
+		pRow:         "-",
+		pColumn:      "|",
+		tablePadding: "   ",
+		newLine:      "\n",
+	}
+}
+
+// SetHeader sets the column headers
+func (t *Table) SetHeader(keys []string) { t.header = keys }
+
+// SetFooter sets the footer, such as totals
+func (t *Table) SetFooter(keys []string) { t.footer = keys }
+
+// ClearFooter removes the footer
+func (t *Table) ClearFooter() { t.footer = nil }
+
+// Append adds a row
+func (t *Table) Append(row []string) { t.rows = append(t.rows, row) }
+
+// AppendBulk adds rows
+func (t *Table) AppendBulk(rows [][]string) {
+	for _, row := range rows {
+		t.Append(row)
+	}
+}
+
+// Rich adds a row with colors for each cell
+func (t *Table) Rich(row []string, colors []Colors) {
+	t.rowColors[len(t.rows)] = colors
+	t.Append(row)
+}
+
+// NumLines returns how many rows the table has
+func (t *Table) NumLines() int { return len(t.rows) }
+
+// ClearRows removes the rows, keeping the header and settings
+func (t *Table) ClearRows() {
+	t.rows = nil
+	t.rowColors = map[int][]Colors{}
+}
+
+// SetAutoWrapText sets whether long cells wrap at the column width
+func (t *Table) SetAutoWrapText(auto bool) { t.autoWrap = auto }
+
+// SetColWidth sets the width cells wrap at
+func (t *Table) SetColWidth(width int) { t.colWidth = width }
+
+// SetColMinWidth sets a column's minimum width
+func (t *Table) SetColMinWidth(column, width int) { t.minWidths[column] = width }
+
+// SetAutoFormatHeaders sets whether headers go through Title
+func (t *Table) SetAutoFormatHeaders(auto bool) { t.autoFmt = auto }
+
+// SetAutoMergeCells sets whether a cell equal to the one above is left
+// blank
+func (t *Table) SetAutoMergeCells(auto bool) { t.autoMerge = auto }
+
+// SetAlignment sets how cells are aligned. By default, numbers align
+// right and text left.
+func (t *Table) SetAlignment(align int) { t.align = align }
+
+// SetColumnAlignment sets each column's alignment
+func (t *Table) SetColumnAlignment(keys []int) { t.columnAlign = keys }
+
+// SetHeaderAlignment sets how headers are aligned; centered by default
+func (t *Table) SetHeaderAlignment(align int) { t.hAlign = align }
+
+// SetFooterAlignment sets how the footer is aligned; centered by default
+func (t *Table) SetFooterAlignment(align int) { t.fAlign = align }
+
+// SetBorder turns all the outer borders on or off
+func (t *Table) SetBorder(border bool) {
+	t.borders = Border{Left: border, Right: border, Top: border, Bottom: border}
+}
+
+// SetBorders sets each outer border
+func (t *Table) SetBorders(border Border) { t.borders = border }
+
+// SetRowLine sets whether lines separate rows
+func (t *Table) SetRowLine(line bool) { t.rowLine = line }
+
+// SetHeaderLine sets whether a line follows the header
+func (t *Table) SetHeaderLine(line bool) { t.headerLine = line }
+
+// SetCenterSeparator sets where lines cross, "+" by default
+func (t *Table) SetCenterSeparator(sep string) { t.pCenter = sep }
+
+// SetRowSeparator sets the line character, "-" by default
+func (t *Table) SetRowSeparator(sep string) { t.pRow = sep }
+
+// SetColumnSeparator sets the column separator, "|" by default
+func (t *Table) SetColumnSeparator(sep string) { t.pColumn = sep }
+
+// SetTablePadding sets what separates columns when there is no white
+// space
+func (t *Table) SetTablePadding(padding string) { t.tablePadding = padding }
+
+// SetNoWhiteSpace drops the separators and the space around cells, for
+// plain output like kubectl's; columns are separated by the table padding
+func (t *Table) SetNoWhiteSpace(allow bool) { t.noWhiteSpace = allow }
+
+// SetCaption sets a caption printed under the table
+func (t *Table) SetCaption(caption bool, captionText ...string) {
+	t.caption = caption
+	if len(captionText) > 0 {
+		t.captionText = captionText[0]
+	}
+}
+
+// SetHeaderColor sets each header's colors
+func (t *Table) SetHeaderColor(colors ...Colors) { t.headerColors = colors }
+
+// SetColumnColor sets each column's colors
+func (t *Table) SetColumnColor(colors ...Colors) { t.columnColors = colors }
+
+// SetFooterColor sets each footer cell's colors
+func (t *Table) SetFooterColor(colors ...Colors) { t.footerColors = colors }
+
+// SetNewLine sets the line ending, "\n" by default
+func (t *Table) SetNewLine(nl string) { t.newLine = nl }
+
+// cellLines splits a cell into the lines it is rendered as
+func (t *Table) cellLines(cell string) []string {
+	var lines []string
+	for _, para := range strings.Split(cell, "\n") {
+		if t.autoWrap && DisplayWidth(para) > t.colWidth {
+			wrapped, _ := WrapString(para, t.colWidth)
+			lines = append(lines, wrapped...)
+			continue
+		}
+		lines = append(lines, para)
+	}
+	return lines
+}
+
+func (t *Table) splitRow(row []string, header bool) [][]string {
+	cells := make([][]string, len(row))
+	for i, cell := range row {
+		if header && t.autoFmt {
+			cell = Title(cell)
+		}
+		cells[i] = t.cellLines(cell)
+	}
+	return cells
+}
+
+// Render writes the table
+func (t *Table) Render() {
+	header := t.splitRow(t.header, true)
+	footer := t.splitRow(t.footer, true)
+	rows := make([][][]string, len(t.rows))
+	for i, row := range t.rows {
+		if t.autoMerge && i > 0 {
+			merged := append([]string(nil), row...)
+			for col := range merged {
+				if col < len(t.rows[i-1]) && merged[col] == t.rows[i-1][col] && merged[col] != "" {
+					merged[col] = ""
+				}
+			}
+			row = merged
+		}
+		rows[i] = t.splitRow(row, false)
+	}
+
+	t.cs = nil
+	measure := func(cells [][]string) {
+		for col, lines := range cells {
+			for len(t.cs) <= col {
+				t.cs = append(t.cs, t.minWidths[len(t.cs)])
+			}
+			for _, line := range lines {
+				if w := DisplayWidth(line); w > t.cs[col] {
+					t.cs[col] = w
+				}
+			}
+		}
+	}
+	measure(header)
+	measure(footer)
+	for _, row := range rows {
+		measure(row)
+	}
+
+	if t.borders.Top && !t.noWhiteSpace {
+		t.printLine()
+	}
+	if len(header) > 0 {
+		t.printRow(header, nil, t.headerColors, func(int, string) int { return t.hAlign })
+		if t.headerLine && !t.noWhiteSpace {
+			t.printLine()
+		}
+	}
+	for i, row := range rows {
+		if i > 0 && t.rowLine && !t.noWhiteSpace {
+			t.printLine()
+		}
+		t.printRow(row, t.rowColors[i], t.columnColors, t.cellAlign)
+	}
+	if len(footer) > 0 {
+		if !t.noWhiteSpace {
+			t.printLine()
+		}
+		t.printRow(footer, nil, t.footerColors, func(int, string) int { return t.fAlign })
+	}
+	if t.borders.Bottom && !t.noWhiteSpace {
+		t.printLine()
+	}
+	if t.caption {
+		lines, _ := WrapString(t.captionText, DisplayWidth(t.line()))
+		for _, line := range lines {
+			fmt.Fprint(t.out, line, t.newLine)
+		}
+	}
+}
+
+func (t *Table) line() string {
+	var b strings.Builder
+	if t.borders.Left {
+		b.WriteString(t.pCenter)
+	}
+	for i, w := range t.cs {
+		if i > 0 {
+			b.WriteString(t.pCenter)
+		}
+		b.WriteString(strings.Repeat(t.pRow, w+2))
+	}
+	if t.borders.Right {
+		b.WriteString(t.pCenter)
+	}
+	return b.String()
+}
+
+func (t *Table) printLine() {
+	fmt.Fprint(t.out, t.line(), t.newLine)
+}
+
+// cellAlign returns the alignment of a row's cell
+func (t *Table) cellAlign(col int, cell string) int {
+	align := t.align
+	if col < len(t.columnAlign) && t.columnAlign[col] != ALIGN_DEFAULT {
+		align = t.columnAlign[col]
+	}
+	if align != ALIGN_DEFAULT {
+		return align
+	}
+	if s := strings.TrimSpace(cell); decimal.MatchString(s) || percent.MatchString(s) {
+		return ALIGN_RIGHT
+	}
+	return ALIGN_LEFT
+}
+
+// printRow writes the lines of a row, each cell padded to its column and
+// colored by its own colors or its column's
+func (t *Table) printRow(cells [][]string, cellColors, colColors []Colors, alignOf func(col int, cell string) int) {
+	height := 0
+	for _, lines := range cells {
+		if len(lines) > height {
+			height = len(lines)
+		}
+	}
+	for i := 0; i < height; i++ {
+		var b strings.Builder
+		if t.borders.Left && !t.noWhiteSpace {
+			b.WriteString(t.pColumn)
+		}
+		for col, w := range t.cs {
+			text := ""
+			if col < len(cells) && i < len(cells[col]) {
+				text = cells[col][i]
+			}
+			var cell string
+			if col < len(cells) {
+				cell = strings.Join(cells[col], "\n")
+			}
+			switch alignOf(col, cell) {
+			case ALIGN_RIGHT:
+				text = PadLeft(text, " ", w)
+			case ALIGN_LEFT:
+				text = PadRight(text, " ", w)
+			default:
+				text = Pad(text, " ", w)
+			}
+			var colors Colors
+			if col < len(cellColors) {
+				colors = cellColors[col]
+			}
+			if len(colors) == 0 && col < len(colColors) {
+				colors = colColors[col]
+			}
+			if len(colors) > 0 {
+				text = NewColor(colors...).Sprint(text)
+			}
+			if t.noWhiteSpace {
+				if col > 0 {
+					b.WriteString(t.tablePadding)
+				}
+				b.WriteString(text)
+				continue
+			}
+			if col > 0 {
+				b.WriteString(t.pColumn)
+			}
+			b.WriteString(" " + text + " ")
+		}
+		if t.borders.Right && !t.noWhiteSpace {
+			b.WriteString(t.pColumn)
+		}
+		out := b.String()
+		if t.noWhiteSpace {
+			out = strings.TrimRight(out, " ")
+		}
+		fmt.Fprint(t.out, out, t.newLine)
+	}
+}
+
+// Progress bars, as in the schollz/progressbar package
+
+// Theme is how a progress bar is drawn
+type Theme struct {
+	Saucer        string
+	SaucerHead    string
+	SaucerPadding string
+	BarStart      string
+	BarEnd        string
+}
+
+// State is a progress bar's progress
+type State struct {
+	Max            int64
+	CurrentNum     int64
+	CurrentPercent float64
+	CurrentBytes   float64
+	SecondsSince   float64
+	SecondsLeft    float64
+	KBsPerSecond   float64
+	Description    string
+}
+
+// ProgressBar draws progress on one line, redrawn with carriage returns
+type ProgressBar struct {
+	mu            sync.Mutex
+	w             io.Writer
+	max           int64
+	current       int64
+	description   string
+	width         int
+	theme         Theme
+	showCount     bool
+	showIts       bool
+	showBytes     bool
+	predictTime   bool
+	throttle      time.Duration
+	clearOnFinish bool
+	renderBlank   bool
+	onCompletion  func()
+	startTime     time.Time
+	lastRender    time.Time
+	lastWidth     int
+	lastOutput    string
+	finished      bool
+	spinnerFrame  int
+}
+
+// BarOption configures a progress bar
+type BarOption func(p *ProgressBar)
+
+// OptionSetWriter sets where the bar is drawn
+func OptionSetWriter(w io.Writer) BarOption { return func(p *ProgressBar) { p.w = w } }
+
+// OptionSetDescription sets the text before the bar
+func OptionSetDescription(description string) BarOption {
+	return func(p *ProgressBar) { p.description = description }
+}
+
+// OptionSetWidth sets the bar's width in characters
+func OptionSetWidth(width int) BarOption { return func(p *ProgressBar) { p.width = width } }
+
+// OptionSetTheme sets the bar's characters
+func OptionSetTheme(theme Theme) BarOption { return func(p *ProgressBar) { p.theme = theme } }
+
+// OptionShowCount shows the count out of the maximum
+func OptionShowCount() BarOption { return func(p *ProgressBar) { p.showCount = true } }
+
+// OptionShowIts shows iterations per second
+func OptionShowIts() BarOption { return func(p *ProgressBar) { p.showIts = true } }
+
+// OptionShowBytes shows the count and rate as bytes
+func OptionShowBytes(show bool) BarOption { return func(p *ProgressBar) { p.showBytes = show } }
+
+// OptionSetPredictTime sets whether the elapsed and remaining time are
+// shown; they are by default
+func OptionSetPredictTime(predict bool) BarOption {
+	return func(p *ProgressBar) { p.predictTime = predict }
+}
+
+// OptionThrottle redraws at most once per duration
+func OptionThrottle(d time.Duration) BarOption { return func(p *ProgressBar) { p.throttle = d } }
+
+// OptionClearOnFinish erases the bar once it finishes
+func OptionClearOnFinish() BarOption { return func(p *ProgressBar) { p.clearOnFinish = true } }
+
+// OptionSetRenderBlankState draws the empty bar when it is created
+func OptionSetRenderBlankState(r bool) BarOption { return func(p *ProgressBar) { p.renderBlank = r } }
+
+// OptionOnCompletion is called once the bar finishes
+func OptionOnCompletion(fn func()) BarOption { return func(p *ProgressBar) { p.onCompletion = fn } }
+
+// NewProgressBar returns a bar to max drawn on standard output. A max of
+// -1 draws a spinner, for work of unknown size.
+func NewProgressBar(max int) *ProgressBar {
+	return NewProgressBarOptions(max)
+}
+
+// DefaultProgressBar returns a bar drawn on standard error with a count
+// and rate, redrawn at most every 65ms
+func DefaultProgressBar(max int64, description ...string) *ProgressBar {
+	desc := ""
+	if len(description) > 0 {
+		desc = description[0]
+	}
+	return NewProgressBarOptions(int(max),
+		OptionSetWriter(os.Stderr),
+		OptionSetDescription(desc),
+		OptionSetWidth(10),
+		OptionShowCount(),
+		OptionShowIts(),
+		OptionThrottle(65*time.Millisecond),
+		OptionSetRenderBlankState(true),
+	)
+}
+
+// NewProgressBarOptions returns a bar to max with options
+func NewProgressBarOptions(max int, options ...BarOption) *ProgressBar {
+	p := &ProgressBar{
+		w:           os.Stdout,
+		max:         int64(max),
+		width:       40,
+		theme:       Theme{Saucer: "█", SaucerPadding: " ", BarStart: "|", BarEnd: "|"},
+		predictTime: true,
+		startTime:   timeNow(),
+	}
+	for _, opt := range options {
+		opt(p)
+	}
+	if p.renderBlank {
+		p.mu.Lock()
+		p.draw(true)
+		p.mu.Unlock()
+	}
+	return p
+}
+
+// Add adds n to the progress
+func (p *ProgressBar) Add(n int) error { return p.Add64(int64(n)) }
+
+// Add64 adds n to the progress
+func (p *ProgressBar) Add64(n int64) error {
+	p.mu.Lock()
+	return p.setLocked(p.current + n)
+}
+
+// Set sets the progress
+func (p *ProgressBar) Set(n int) error { return p.Set64(int64(n)) }
+
+// Set64 sets the progress
+func (p *ProgressBar) Set64(n int64) error {
+	p.mu.Lock()
+	return p.setLocked(n)
+}
+
+// setLocked updates the progress and redraws, finishing the bar when it
+// reaches its maximum. It unlocks p.mu.
+func (p *ProgressBar) setLocked(n int64) error {
+	if p.finished {
+		p.mu.Unlock()
+		return nil
+	}
+	p.current = n
+	if p.max >= 0 && p.current > p.max {
+		p.current = p.max
+		p.mu.Unlock()
+		return errors.New("current number exceeds max")
+	}
+	if p.max >= 0 && p.current == p.max {
+		return p.finishLocked()
+	}
+	p.draw(false)
+	p.mu.Unlock()
+	return nil
+}
+
+// Write adds len(b) to the progress, so a bar can count bytes copied with
+// io.Copy or io.MultiWriter
+func (p *ProgressBar) Write(b []byte) (int, error) {
+	return len(b), p.Add(len(b))
+}
+
+// Finish fills the bar and ends it
+func (p *ProgressBar) Finish() error {
+	p.mu.Lock()
+	if p.finished {
+		p.mu.Unlock()
+		return nil
+	}
+	if p.max >= 0 {
+		p.current = p.max
+	}
+	return p.finishLocked()
+}
+
+// finishLocked draws the finished bar, then clears it or calls the
+// completion callback. It unlocks p.mu.
+func (p *ProgressBar) finishLocked() error {
+	p.finished = true
+	p.draw(true)
+	if p.clearOnFinish {
+		p.clearLocked()
+	}
+	fn := p.onCompletion
+	p.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+	return nil
+}
+
+// Clear erases the bar
+func (p *ProgressBar) Clear() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+	return nil
+}
+
+func (p *ProgressBar) clearLocked() {
+	fmt.Fprint(p.w, "\r"+strings.Repeat(" ", p.lastWidth)+"\r")
+	p.lastWidth = 0
+}
+
+// Reset starts the bar again from zero
+func (p *ProgressBar) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = 0
+	p.finished = false
+	p.startTime = timeNow()
+	p.lastRender = time.Time{}
+}
+
+// Describe changes the description and redraws
+func (p *ProgressBar) Describe(description string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.description = description
+	p.draw(true)
+}
+
+// ChangeMax changes the maximum
+func (p *ProgressBar) ChangeMax(max int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.max = int64(max)
+}
+
+// GetMax returns the maximum
+func (p *ProgressBar) GetMax() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int(p.max)
+}
+
+// IsFinished reports whether the bar has finished
+func (p *ProgressBar) IsFinished() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.finished
+}
+
+// String returns the bar as last drawn
+func (p *ProgressBar) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lastOutput
+}
+
+// State returns the progress
+func (p *ProgressBar) State() State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elapsed := timeNow().Sub(p.startTime).Seconds()
+	s := State{
+		Max:          p.max,
+		CurrentNum:   p.current,
+		CurrentBytes: float64(p.current),
+		SecondsSince: elapsed,
+		Description:  p.description,
+	}
+	if p.max > 0 {
+		s.CurrentPercent = float64(p.current) / float64(p.max)
+	}
+	if elapsed > 0 {
+		s.KBsPerSecond = float64(p.current) / 1024 / elapsed
+	}
+	if p.current > 0 && p.max > 0 {
+		s.SecondsLeft = elapsed / float64(p.current) * float64(p.max-p.current)
+	}
+	return s
+}
+
+// draw renders the bar unless it was drawn within the throttle
+func (p *ProgressBar) draw(force bool) {
+	now := timeNow()
+	if !force && p.throttle > 0 && now.Sub(p.lastRender) < p.throttle {
+		return
+	}
+	p.lastRender = now
+	out := p.render(now)
+	width := DisplayWidth(out)
+	pad := ""
+	if width < p.lastWidth {
+		pad = strings.Repeat(" ", p.lastWidth-width)
+	}
+	fmt.Fprint(p.w, "\r"+out+pad)
+	p.lastWidth = width
+	p.lastOutput = out
+}
+
+var barSpinner = []string{"-", "\\", "|", "/"}
+
+func (p *ProgressBar) render(now time.Time) string {
+	var b strings.Builder
+	if p.description != "" {
+		b.WriteString(p.description + " ")
+	}
+	elapsed := now.Sub(p.startTime)
+
+	var stats []string
+	if p.max < 0 {
+		b.WriteString(barSpinner[p.spinnerFrame%len(barSpinner)])
+		p.spinnerFrame++
+		if p.showBytes {
+			stats = append(stats, humanizeBytes(float64(p.current)))
+		} else {
+			stats = append(stats, fmt.Sprintf("%d it", p.current))
+		}
+	} else {
+		pct := 100
+		filled := p.width
+		if p.max > 0 {
+			pct = int(p.current * 100 / p.max)
+			filled = int(float64(p.width) * float64(p.current) / float64(p.max))
+		}
+		fmt.Fprintf(&b, "%3d%% %s", pct, p.theme.BarStart)
+		if filled > 0 && filled < p.width && p.theme.SaucerHead != "" {
+			b.WriteString(strings.Repeat(p.theme.Saucer, filled-1) + p.theme.SaucerHead)
+		} else {
+			b.WriteString(strings.Repeat(p.theme.Saucer, filled))
+		}
+		b.WriteString(strings.Repeat(p.theme.SaucerPadding, p.width-filled))
+		b.WriteString(p.theme.BarEnd)
+		if p.showCount {
+			if p.showBytes {
+				stats = append(stats, humanizeBytes(float64(p.current))+"/"+humanizeBytes(float64(p.max)))
+			} else {
+				stats = append(stats, fmt.Sprintf("%d/%d", p.current, p.max))
+			}
+		}
+	}
+	if p.showBytes || p.showIts {
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(p.current) / elapsed.Seconds()
+		}
+		if p.showBytes {
+			stats = append(stats, humanizeBytes(rate)+"/s")
+		} else {
+			stats = append(stats, fmt.Sprintf("%.1f it/s", rate))
+		}
+	}
+	if len(stats) > 0 {
+		b.WriteString(" (" + strings.Join(stats, ", ") + ")")
+	}
+	if p.predictTime {
+		if p.max < 0 {
+			fmt.Fprintf(&b, " [%s]", elapsed.Round(time.Second))
+		} else {
+			left := "?"
+			if p.current > 0 {
+				remaining := time.Duration(float64(elapsed) / float64(p.current) * float64(p.max-p.current))
+				left = remaining.Round(time.Second).String()
+			}
+			fmt.Fprintf(&b, " [%s:%s]", elapsed.Round(time.Second), left)
+		}
+	}
+	return b.String()
+}
+
+// humanizeBytes formats a byte count in decimal units
+func humanizeBytes(n float64) string {
+	units := []string{"B", "kB", "MB", "GB", "TB"}
+	i := 0
+	for n >= 1000 && i < len(units)-1 {
+		n /= 1000
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+	if n < 10 {
+		return fmt.Sprintf("%.1f %s", math.Floor(n*10)/10, units[i])
+	}
+	return fmt.Sprintf("%.0f %s", math.Floor(n), units[i])
+}
+
+// Spinners, as in the briandowns/spinner package
+
+// CharSets are the spinners' frames, numbered as in the spinner package
+var CharSets = map[int][]string{
+	1:  {"←", "↖", "↑", "↗", "→", "↘", "↓", "↙"},
+	4:  {"|", "/", "-", "\\"},
+	9:  {"|", "/", "-", "\\"},
+	11: {"⣾", "⣽", "⣻", "⢿", "⡿", "⣟", "⣯", "⣷"},
+	14: {"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"},
+	21: {"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█", "▇", "▆", "▅", "▄", "▃", "▂"},
+	35: {".  ", ".. ", "...", " ..", "  .", "   "},
+}
+
+// Spinner animates a frame on one line while work runs
+type Spinner struct {
+	mu       sync.Mutex
+	Delay    time.Duration
+	chars    []string
+	Prefix   string
+	Suffix   string
+	FinalMSG string
+	// Writer is where the spinner draws, standard output by default
+	Writer     io.Writer
+	HideCursor bool
+	PreUpdate  func(s *Spinner)
+	PostUpdate func(s *Spinner)
+
+	color     func(a ...interface{}) string
+	active    bool
+	stop      chan struct{}
+	done      chan struct{}
+	lastWidth int
+}
+
+// SpinnerOption configures a spinner
+type SpinnerOption func(s *Spinner)
+
+// WithWriter sets where the spinner draws
+func WithWriter(w io.Writer) SpinnerOption { return func(s *Spinner) { s.Writer = w } }
+
+// WithSuffix sets the text after the frame
+func WithSuffix(suffix string) SpinnerOption { return func(s *Spinner) { s.Suffix = suffix } }
+
+// WithFinalMSG sets what is printed when the spinner stops
+func WithFinalMSG(msg string) SpinnerOption { return func(s *Spinner) { s.FinalMSG = msg } }
+
+// WithHiddenCursor hides a terminal's cursor while the spinner runs
+func WithHiddenCursor(hide bool) SpinnerOption { return func(s *Spinner) { s.HideCursor = hide } }
+
+// WithColor colors the frames, as Color does
+func WithColor(color string) SpinnerOption {
+	return func(s *Spinner) { s.Color(color) }
+}
+
+// NewSpinner returns a spinner showing the frames of cs, one every d
+func NewSpinner(cs []string, d time.Duration, options ...SpinnerOption) *Spinner {
+	s := &Spinner{
+		Delay:  d,
+		chars:  append([]string(nil), cs...),
+		Writer: os.Stdout,
+		color:  fmt.Sprint,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Active reports whether the spinner is running
+func (s *Spinner) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Lock stops the spinner redrawing, while fields such as Suffix change
+func (s *Spinner) Lock() { s.mu.Lock() }
+
+// Unlock lets the spinner redraw again
+func (s *Spinner) Unlock() { s.mu.Unlock() }
+
+// Start starts the animation in a goroutine
+func (s *Spinner) Start() {
+	s.mu.Lock()
+	if s.active {
+		s.mu.Unlock()
+		return
+	}
+	if s.HideCursor && isTerminal(s.Writer) {
+		fmt.Fprint(s.Writer, "\x1b[?25l")
+	}
+	s.active = true
+	stop, done := make(chan struct{}), make(chan struct{})
+	s.stop, s.done = stop, done
+	s.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			s.mu.Lock()
+			if s.PreUpdate != nil {
+				s.PreUpdate(s)
+			}
+			frame := s.Prefix + s.color(s.chars[i%len(s.chars)]) + s.Suffix
+			s.erase()
+			fmt.Fprint(s.Writer, frame)
+			s.lastWidth = DisplayWidth(frame)
+			if s.PostUpdate != nil {
+				s.PostUpdate(s)
+			}
+			delay := s.Delay
+			s.mu.Unlock()
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(delay):
+			}
+		}
+	}()
+}
+
+// erase clears the last frame: with an escape code on terminals, and by
+// overwriting it with spaces elsewhere
+func (s *Spinner) erase() {
+	if isTerminal(s.Writer) {
+		fmt.Fprint(s.Writer, "\r\x1b[K")
+	} else if s.lastWidth > 0 {
+		fmt.Fprint(s.Writer, "\r"+strings.Repeat(" ", s.lastWidth)+"\r")
+	}
+	s.lastWidth = 0
+}
+
+// Stop stops the animation, erases the frame and prints FinalMSG
+func (s *Spinner) Stop() {
+	s.mu.Lock()
+	if !s.active {
+		s.mu.Unlock()
+		return
+	}
+	s.active = false
+	close(s.stop)
+	done := s.done
+	s.mu.Unlock()
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.erase()
+	if s.FinalMSG != "" {
+		fmt.Fprint(s.Writer, s.FinalMSG)
+	}
+	if s.HideCursor && isTerminal(s.Writer) {
+		fmt.Fprint(s.Writer, "\x1b[?25h")
+	}
+}
+
+// Restart stops and starts the spinner
+func (s *Spinner) Restart() {
+	s.Stop()
+	s.Start()
+}
+
+// Reverse runs the frames backwards
+func (s *Spinner) Reverse() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, j := 0, len(s.chars)-1; i < j; i, j = i+1, j-1 {
+		s.chars[i], s.chars[j] = s.chars[j], s.chars[i]
+	}
+}
+
+// UpdateSpeed changes the delay between frames
+func (s *Spinner) UpdateSpeed(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Delay = d
+}
+
+// UpdateCharSet changes the frames
+func (s *Spinner) UpdateCharSet(cs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chars = append([]string(nil), cs...)
+}
+
+// Color colors the frames with named attributes, such as "red", "bold"
+// or "fgHiGreen". Like other colors, it is off when NoColor is set.
+func (s *Spinner) Color(colors ...string) error {
+	attrs := make([]Attribute, 0, len(colors))
+	for _, name := range colors {
+		attr, ok := colorNames[name]
+		if !ok {
+			return fmt.Errorf("invalid color %q", name)
+		}
+		attrs = append(attrs, attr)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.color = NewColor(attrs...).SprintFunc()
+	return nil
+}
@@ -0,0 +1,685 @@
+package main
+
+// Developed by PowerShield, as an alternative to tablewriter and fatih/color
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// withColor runs fn with colors on or off, whatever the test's output is
+func withColor(on bool, fn func()) {
+	saved := NoColor
+	NoColor = !on
+	defer func() { NoColor = saved }()
+	fn()
+}
+
+// fakeClock makes time advance only when the test says
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) install() func() {
+	timeNow = func() time.Time { return c.now }
+	return func() { timeNow = time.Now }
+}
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// lastFrame returns what a carriage-return-redrawn line shows last
+func lastFrame(s string) string {
+	frames := strings.Split(s, "\r")
+	return strings.TrimRight(frames[len(frames)-1], " ")
+}
+
+func testBasicTable() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetHeader([]string{"Name", "Sign", "Rating"})
+	t.AppendBulk([][]string{
+		{"A", "The Good", "500"},
+		{"B", "The Very very Bad Man", "288"},
+		{"C", "The Ugly", "120"},
+	})
+	t.Render()
+	expected := `+------+-----------------------+--------+
+| NAME |         SIGN          | RATING |
++------+-----------------------+--------+
+| A    | The Good              |    500 |
+| B    | The Very very Bad Man |    288 |
+| C    | The Ugly              |    120 |
++------+-----------------------+--------+
+`
+	if buf.String() != expected || t.NumLines() != 3 {
+		return false
+	}
+
+	// Rendering again gives the same table; cleared rows leave the header
+	buf.Reset()
+	t.Render()
+	if buf.String() != expected {
+		return false
+	}
+	t.ClearRows()
+	buf.Reset()
+	t.Render()
+	return buf.String() == "+------+------+--------+\n| NAME | SIGN | RATING |\n+------+------+--------+\n+------+------+--------+\n"
+}
+
+func testAlignment() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetHeader([]string{"item", "qty", "share", "note"})
+	t.Append([]string{"apples", "1,200", "-4.5%", "12 left"})
+	t.Append([]string{"kiwis", "3.25", "100%", "n/a"})
+	t.Render()
+	// Numbers and percentages align right; text, even starting with a
+	// digit, aligns left
+	if !strings.Contains(buf.String(), "| apples | 1,200 | -4.5% | 12 left |") ||
+		!strings.Contains(buf.String(), "| kiwis  |  3.25 |  100% | n/a     |") {
+		return false
+	}
+
+	buf.Reset()
+	t.SetAlignment(ALIGN_LEFT)
+	t.SetColumnAlignment([]int{ALIGN_RIGHT, ALIGN_DEFAULT, ALIGN_CENTER})
+	t.SetHeaderAlignment(ALIGN_RIGHT)
+	t.Render()
+	out := buf.String()
+	return strings.Contains(out, "|   ITEM |   QTY | SHARE |    NOTE |") &&
+		strings.Contains(out, "| apples | 1,200 | -4.5% | 12 left |") &&
+		strings.Contains(out, "|  kiwis | 3.25  | 100%  | n/a     |")
+}
+
+func testWrapping() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetColWidth(10)
+	t.SetHeader([]string{"id", "summary"})
+	t.Append([]string{"1", "short"})
+	t.Append([]string{"2", "a longer summary that wraps"})
+	t.Append([]string{"3", "line one\nline two"})
+	t.Append([]string{"4", "supercalifragilistic word"})
+	t.Render()
+	expected := `+----+----------------------+
+| ID |       SUMMARY        |
++----+----------------------+
+|  1 | short                |
+|  2 | a longer             |
+|    | summary              |
+|    | that wraps           |
+|  3 | line one             |
+|    | line two             |
+|  4 | supercalifragilistic |
+|    | word                 |
++----+----------------------+
+`
+	if buf.String() != expected {
+		return false
+	}
+
+	buf.Reset()
+	t.SetAutoWrapText(false)
+	t.ClearRows()
+	t.Append([]string{"2", "a longer summary that wraps"})
+	t.Render()
+	if !strings.Contains(buf.String(), "|  2 | a longer summary that wraps |") {
+		return false
+	}
+
+	lines, widest := WrapString("the quick brown fox", 9)
+	return len(lines) == 2 && lines[0] == "the quick" && lines[1] == "brown fox" && widest == 9
+}
+
+func testHeadersAndFooters() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetHeader([]string{"first_name", "v1.2", "user.email"})
+	t.SetFooter([]string{"", "total", "3"})
+	t.Append([]string{"Ann", "x", "ann@example.com"})
+	t.Render()
+	out := buf.String()
+	if !strings.Contains(out, "| FIRST NAME | V1.2  |   USER EMAIL    |") ||
+		!strings.Contains(out, "|            | TOTAL |        3        |") {
+		return false
+	}
+	if strings.Count(out, "+------------+-------+-----------------+") != 4 {
+		return false
+	}
+
+	buf.Reset()
+	t.SetAutoFormatHeaders(false)
+	t.SetFooterAlignment(ALIGN_RIGHT)
+	t.Render()
+	if !strings.Contains(buf.String(), "| first_name | v1.2  |   user.email    |") ||
+		!strings.Contains(buf.String(), "|            | total |               3 |") {
+		return false
+	}
+
+	buf.Reset()
+	t.ClearFooter()
+	t.Render()
+	return strings.Count(buf.String(), "\n") == 5 && Title("amount_in.usd") == "AMOUNT IN USD" && Title("rate_1.5") == "RATE 1.5"
+}
+
+func testBordersAndSeparators() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetHeader([]string{"a", "b"})
+	t.Append([]string{"1", "2"})
+	t.Append([]string{"3", "4"})
+	t.SetRowLine(true)
+	t.SetCenterSeparator("*")
+	t.SetRowSeparator("=")
+	t.SetColumnSeparator(":")
+	t.Render()
+	expected := "*===*===*\n: A : B :\n*===*===*\n: 1 : 2 :\n*===*===*\n: 3 : 4 :\n*===*===*\n"
+	if buf.String() != expected {
+		return false
+	}
+
+	buf.Reset()
+	t = NewWriter(&buf)
+	t.SetHeader([]string{"a", "b"})
+	t.Append([]string{"1", "2"})
+	t.SetBorders(Border{Left: true, Top: false, Right: true, Bottom: false})
+	t.SetHeaderLine(false)
+	t.Render()
+	if buf.String() != "| A | B |\n| 1 | 2 |\n" {
+		return false
+	}
+
+	// Plain columns, as kubectl prints them
+	buf.Reset()
+	t = NewWriter(&buf)
+	t.SetHeader([]string{"name", "ready", "restarts"})
+	t.SetBorder(false)
+	t.SetHeaderLine(false)
+	t.SetNoWhiteSpace(true)
+	t.SetAlignment(ALIGN_LEFT)
+	t.SetHeaderAlignment(ALIGN_LEFT)
+	t.SetTablePadding("  ")
+	t.AppendBulk([][]string{{"web-1", "1/1", "0"}, {"worker-12", "0/1", "14"}})
+	t.Render()
+	if buf.String() != "NAME       READY  RESTARTS\nweb-1      1/1    0\nworker-12  0/1    14\n" {
+		return false
+	}
+
+	buf.Reset()
+	t.SetNewLine("\r\n")
+	t.Render()
+	return strings.HasSuffix(buf.String(), "0/1    14\r\n")
+}
+
+func testMergeCellsAndCaptions() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetAutoMergeCells(true)
+	t.SetHeader([]string{"region", "host", "status"})
+	t.AppendBulk([][]string{
+		{"eu", "eu-1", "up"},
+		{"eu", "eu-2", "up"},
+		{"us", "us-1", "down"},
+	})
+	t.SetCaption(true, "Hosts by region, as of the last health check.")
+	t.Render()
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 9 {
+		return false
+	}
+	if lines[3] != "| eu     | eu-1 | up     |" || lines[4] != "|        | eu-2 |        |" || lines[5] != "| us     | us-1 | down   |" {
+		return false
+	}
+	// The caption wraps to the table's width
+	if lines[7] != "Hosts by region, as of the" || lines[8] != "last health check." {
+		return false
+	}
+
+	buf.Reset()
+	t.SetCaption(false)
+	t.SetColMinWidth(1, 8)
+	t.Render()
+	return strings.Count(buf.String(), "\n") == 7 && strings.Contains(buf.String(), "| eu-1     |")
+}
+
+func testTableColors() bool {
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetHeader([]string{"svc", "status"})
+	t.SetHeaderColor(Colors{Bold}, Colors{Bold, FgCyan})
+	t.SetColumnColor(Colors{}, Colors{FgGreen})
+	t.Append([]string{"api", "ok"})
+	t.Rich([]string{"db", "down"}, []Colors{{}, {FgRed, Bold}})
+
+	withColor(false, t.Render)
+	plain := buf.String()
+	if strings.Contains(plain, "\x1b[") || !strings.Contains(plain, "| db  | down   |") {
+		return false
+	}
+
+	buf.Reset()
+	withColor(true, t.Render)
+	out := buf.String()
+	if !strings.Contains(out, "| \x1b[1mSVC\x1b[0m | \x1b[1;36mSTATUS\x1b[0m |") ||
+		!strings.Contains(out, "| api | \x1b[32mok    \x1b[0m |") ||
+		!strings.Contains(out, "| db  | \x1b[31;1mdown  \x1b[0m |") {
+		return false
+	}
+	// Escape codes don't widen the columns
+	return strings.Split(out, "\n")[0] == strings.Split(plain, "\n")[0]
+}
+
+func testDisplayWidth() bool {
+	if DisplayWidth("hello") != 5 || DisplayWidth("\x1b[1;31mhello\x1b[0m") != 5 {
+		return false
+	}
+	if DisplayWidth("日本語") != 6 || DisplayWidth("café") != 4 || DisplayWidth("café") != 4 || DisplayWidth("🚀 go") != 5 {
+		return false
+	}
+	if Pad("ab", "*", 7) != "**ab***" || PadLeft("ab", " ", 4) != "  ab" || PadRight("日本", ".", 6) != "日本.." || Pad("toolong", " ", 3) != "toolong" {
+		return false
+	}
+
+	var buf bytes.Buffer
+	t := NewWriter(&buf)
+	t.SetHeader([]string{"city", "name"})
+	t.Append([]string{"Tokyo", "東京"})
+	t.Append([]string{"Zürich", "Zürich"})
+	t.Render()
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if DisplayWidth(line) != 19 {
+			return false
+		}
+	}
+	return strings.Contains(buf.String(), "| Tokyo  | 東京   |")
+}
+
+func testColorHelpers() bool {
+	ok := true
+	withColor(true, func() {
+		red := NewColor(FgRed)
+		if red.Sprint("error") != "\x1b[31merror\x1b[0m" || red.Sprintf("%d failed", 2) != "\x1b[31m2 failed\x1b[0m" {
+			ok = false
+		}
+		if red.Sprintln("a", "b") != "\x1b[31ma b\x1b[0m\n" {
+			ok = false
+		}
+		boldRed := NewColor(FgRed).Add(Bold)
+		if boldRed.Sprint("x") != "\x1b[31;1mx\x1b[0m" || !boldRed.Equals(NewColor(Bold, FgRed)) || boldRed.Equals(red) {
+			ok = false
+		}
+		warn := NewColor(FgYellow).SprintFunc()
+		info := NewColor(FgCyan).SprintfFunc()
+		if warn("careful") != "\x1b[33mcareful\x1b[0m" || info("%s!", "hi") != "\x1b[36mhi!\x1b[0m" {
+			ok = false
+		}
+
+		var buf bytes.Buffer
+		n, err := NewColor(FgGreen).Fprintf(&buf, "%s %d", "done", 3)
+		if err != nil || n != buf.Len() || buf.String() != "\x1b[32mdone 3\x1b[0m" {
+			ok = false
+		}
+
+		// Print functions write to Output, adding a newline
+		saved := Output
+		Output = &buf
+		buf.Reset()
+		Red("failed: %s", "disk")
+		Green("ok\n")
+		Output = saved
+		if buf.String() != "\x1b[31mfailed: disk\n\x1b[0m\x1b[32mok\n\x1b[0m" {
+			ok = false
+		}
+		if RedString("%d%%", 5) != "\x1b[31m5%\x1b[0m" || CyanString("100%") != "\x1b[36m100%\x1b[0m" {
+			ok = false
+		}
+
+		// One color can be turned off while the rest stay on
+		quiet := NewColor(FgRed)
+		quiet.DisableColor()
+		if quiet.Sprint("plain") != "plain" {
+			ok = false
+		}
+	})
+	withColor(false, func() {
+		forced := NewColor(FgBlue)
+		forced.EnableColor()
+		if forced.Sprint("x") != "\x1b[34mx\x1b[0m" || NewColor(FgBlue).Sprint("x") != "x" || BlueString("x") != "x" {
+			ok = false
+		}
+	})
+	return ok
+}
+
+func testColorDetection() bool {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	savedIsTerminal := isTerminal
+	defer func() { isTerminal = savedIsTerminal }()
+
+	// Buffers and files aren't terminals
+	var buf bytes.Buffer
+	if IsTerminal(&buf) || !noColorFor(&buf, env(nil)) {
+		return false
+	}
+	f, err := os.CreateTemp("", "chalkboard")
+	if err != nil {
+		return false
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if IsTerminal(f) {
+		return false
+	}
+
+	isTerminal = func(w io.Writer) bool { return w == io.Writer(&buf) }
+	if noColorFor(&buf, env(map[string]string{"TERM": "xterm-256color"})) {
+		return false
+	}
+	// NO_COLOR wins when it is set to anything
+	if !noColorFor(&buf, env(map[string]string{"NO_COLOR": "1", "TERM": "xterm"})) {
+		return false
+	}
+	if noColorFor(&buf, env(map[string]string{"NO_COLOR": ""})) {
+		return false
+	}
+	if !noColorFor(&buf, env(map[string]string{"TERM": "dumb"})) {
+		return false
+	}
+	return noColorFor(f, env(nil))
+}
+
+func testProgressBar() bool {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	defer clock.install()()
+
+	var buf bytes.Buffer
+	done := false
+	bar := NewProgressBarOptions(100,
+		OptionSetWriter(&buf),
+		OptionSetDescription("Uploading"),
+		OptionSetWidth(10),
+		OptionShowCount(),
+		OptionOnCompletion(func() { done = true }),
+	)
+	if buf.Len() != 0 || bar.GetMax() != 100 {
+		return false
+	}
+	clock.advance(2 * time.Second)
+	bar.Add(25)
+	if bar.String() != "Uploading  25% |██        | (25/100) [2s:6s]" || buf.String() != "\rUploading  25% |██        | (25/100) [2s:6s]" {
+		return false
+	}
+	clock.advance(2 * time.Second)
+	bar.Set(50)
+	state := bar.State()
+	if state.CurrentNum != 50 || state.CurrentPercent != 0.5 || state.SecondsSince != 4 || state.SecondsLeft != 4 {
+		return false
+	}
+	bar.Describe("Almost")
+	if lastFrame(buf.String()) != "Almost  50% |█████     | (50/100) [4s:4s]" {
+		return false
+	}
+	// The longer description left nothing behind
+	if !strings.HasSuffix(buf.String(), "[4s:4s]   ") || strings.Count(buf.String(), "\r") != 3 {
+		return false
+	}
+
+	// Reaching the maximum finishes the bar
+	if bar.Add(50) != nil || !bar.IsFinished() || !done || bar.String() != "Almost 100% |██████████| (100/100) [4s:0s]" {
+		return false
+	}
+	bar.Reset()
+	bar.Add(90)
+	if err := bar.Add(20); err == nil || err.Error() != "current number exceeds max" || bar.State().CurrentNum != 100 {
+		return false
+	}
+	return bar.Finish() == nil && bar.IsFinished()
+}
+
+func testProgressBarOptions() bool {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	defer clock.install()()
+
+	var buf bytes.Buffer
+	bar := NewProgressBarOptions(4_000_000,
+		OptionSetWriter(&buf),
+		OptionSetWidth(8),
+		OptionShowBytes(true),
+		OptionShowCount(),
+		OptionSetPredictTime(false),
+		OptionSetTheme(Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: ".", BarStart: "[", BarEnd: "]"}),
+		OptionSetRenderBlankState(true),
+	)
+	if buf.String() != "\r  0% [........] (0 B/4.0 MB, 0 B/s)" {
+		return false
+	}
+	// Bars count the bytes written through them
+	clock.advance(time.Second)
+	n, err := io.Copy(io.MultiWriter(io.Discard, bar), strings.NewReader(strings.Repeat("x", 1_500_000)))
+	if err != nil || n != 1_500_000 || bar.String() != " 37% [==>.....] (1.5 MB/4.0 MB, 1.5 MB/s)" {
+		return false
+	}
+
+	// Throttled bars skip redraws, but always draw the end
+	buf.Reset()
+	clear := false
+	throttled := NewProgressBarOptions(10, OptionSetWriter(&buf), OptionSetWidth(5), OptionSetPredictTime(false),
+		OptionThrottle(time.Second), OptionClearOnFinish(), OptionOnCompletion(func() { clear = true }))
+	for i := 0; i < 9; i++ {
+		throttled.Add(1)
+	}
+	if strings.Count(buf.String(), "\r") != 1 || throttled.String() != " 10% |     |" {
+		return false
+	}
+	clock.advance(time.Second)
+	throttled.Add(1)
+	throttled.Finish()
+	return clear && strings.HasSuffix(buf.String(), "\r100% |█████|\r            \r") && throttled.IsFinished()
+}
+
+func testIndeterminateProgress() bool {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	defer clock.install()()
+
+	var buf bytes.Buffer
+	bar := NewProgressBarOptions(-1, OptionSetWriter(&buf), OptionSetDescription("Scanning"), OptionShowIts())
+	clock.advance(2 * time.Second)
+	bar.Add(10)
+	if bar.String() != "Scanning - (10 it, 5.0 it/s) [2s]" {
+		return false
+	}
+	bar.Add(10)
+	if bar.String() != "Scanning \\ (20 it, 10.0 it/s) [2s]" || bar.IsFinished() {
+		return false
+	}
+	// Unknown totals never run over
+	if bar.Add(1_000_000) != nil {
+		return false
+	}
+	bar.ChangeMax(2000)
+	bar.Set(1000)
+	if !strings.HasPrefix(bar.String(), "Scanning  50% |") {
+		return false
+	}
+	bar.Finish()
+	bar.Clear()
+	return strings.HasSuffix(buf.String(), "\r"+strings.Repeat(" ", DisplayWidth(bar.String()))+"\r")
+}
+
+// syncBuffer is a buffer a spinner's goroutine can write while a test
+// reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func testSpinners() bool {
+	out := &syncBuffer{}
+	s := NewSpinner(CharSets[9], 2*time.Millisecond, WithWriter(out), WithSuffix(" deploying"), WithFinalMSG("deployed\n"))
+	s.Prefix = "["
+	if s.Active() {
+		return false
+	}
+	s.Start()
+	s.Start()
+	time.Sleep(30 * time.Millisecond)
+	if !s.Active() {
+		return false
+	}
+	s.Lock()
+	s.Suffix = " verifying"
+	s.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	s.Stop()
+	s.Stop()
+	text := out.String()
+	for _, frame := range []string{"[| deploying", "[/ deploying", "[- deploying", "[\\ deploying", "verifying"} {
+		if !strings.Contains(text, frame) {
+			return false
+		}
+	}
+	// Stopping erases the frame and leaves the final message
+	if !strings.HasSuffix(text, "\r            \rdeployed\n") || s.Active() {
+		return false
+	}
+
+	// Updates happen between frames
+	out = &syncBuffer{}
+	frames := 0
+	s = NewSpinner([]string{"a", "b", "c"}, time.Millisecond, WithWriter(out))
+	s.PostUpdate = func(*Spinner) { frames++ }
+	s.Reverse()
+	s.Start()
+	time.Sleep(10 * time.Millisecond)
+	s.UpdateCharSet([]string{"z"})
+	s.UpdateSpeed(2 * time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	s.Restart()
+	s.Stop()
+	text = out.String()
+	if !strings.HasPrefix(text, "c\r \rb\r \ra") || !strings.Contains(text, "z") {
+		return false
+	}
+	s.Lock()
+	counted := frames
+	s.Unlock()
+	if counted < 3 {
+		return false
+	}
+
+	if err := s.Color("bogus"); err == nil || err.Error() != `invalid color "bogus"` {
+		return false
+	}
+	ok := true
+	withColor(true, func() {
+		out = &syncBuffer{}
+		c := NewSpinner([]string{"*"}, time.Hour, WithWriter(out), WithColor("fgHiGreen"))
+		c.Color("red", "bold")
+		c.Start()
+		c.Stop()
+		ok = strings.HasPrefix(out.String(), "\x1b[31;1m*\x1b[0m")
+	})
+	return ok
+}
+
+// command stands in for a Cobra command: the module only needs its
+// writers
+type command struct {
+	out io.Writer
+	err io.Writer
+}
+
+func (c *command) OutOrStdout() io.Writer { return c.out }
+func (c *command) ErrOrStderr() io.Writer { return c.err }
+
+func testCommandOutput() bool {
+	var out, errOut bytes.Buffer
+	cmd := &command{out: &out, err: &errOut}
+
+	// A "list" command: the table goes to stdout, progress to stderr, and
+	// colors only if stdout is a terminal
+	status := NewColor(FgRed)
+	if !ShouldColor(cmd.OutOrStdout()) {
+		status.DisableColor()
+	}
+	bar := NewProgressBarOptions(2, OptionSetWriter(cmd.ErrOrStderr()), OptionSetWidth(4), OptionSetPredictTime(false),
+		OptionClearOnFinish())
+	t := NewWriter(cmd.OutOrStdout())
+	t.SetHeader([]string{"service", "status"})
+	for _, svc := range []string{"api", "db"} {
+		t.Append([]string{svc, status.Sprint("down")})
+		bar.Add(1)
+	}
+	t.Render()
+	if out.String() != "+---------+--------+\n| SERVICE | STATUS |\n+---------+--------+\n| api     | down   |\n| db      | down   |\n+---------+--------+\n" {
+		return false
+	}
+	if !strings.Contains(errOut.String(), " 50% |██  |") || !strings.HasSuffix(errOut.String(), "\r           \r") {
+		return false
+	}
+
+	// Concurrent workers can share a bar
+	var wg sync.WaitGroup
+	shared := NewProgressBarOptions(400, OptionSetWriter(io.Discard))
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				shared.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return shared.IsFinished() && shared.State().CurrentNum == 400
+}
+
+func main() {
+	fmt.Println("Running tablewriter Emulator Tests...")
+	fmt.Println("=====================================")
+
+	runTest("Basic Table", testBasicTable)
+	runTest("Alignment", testAlignment)
+	runTest("Wrapping", testWrapping)
+	runTest("Headers and Footers", testHeadersAndFooters)
+	runTest("Borders and Separators", testBordersAndSeparators)
+	runTest("Merged Cells and Captions", testMergeCellsAndCaptions)
+	runTest("Table Colors", testTableColors)
+	runTest("Display Width", testDisplayWidth)
+	runTest("Color Helpers", testColorHelpers)
+	runTest("Color Detection", testColorDetection)
+	runTest("Progress Bar", testProgressBar)
+	runTest("Progress Bar Options", testProgressBarOptions)
+	runTest("Indeterminate Progress", testIndeterminateProgress)
+	runTest("Spinners", testSpinners)
+	runTest("Command Output", testCommandOutput)
+
+	fmt.Println("=====================================")
+	fmt.Println("All tests completed!")
+}
@@ -2,62 +2,361 @@ package main
 
 // Developed by PowerShield, as an alternative to Cobra
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
+	"unicode"
 )
 
 // Command represents a CLI command
 type Command struct {
-	Use   string
-	Short string
-	Long  string
-	Run   func(cmd *Command, args []string)
-	
+	Use     string
+	Short   string
+	Long    string
+	Example string
+	Run     func(cmd *Command, args []string)
+	// RunE is like Run but returns an error, which Execute reports and
+	// returns; it takes precedence over Run
+	RunE func(cmd *Command, args []string) error
+
+	// PersistentPreRun runs before PreRun on this command and on its
+	// children; only the nearest one up the tree runs unless
+	// EnableTraverseRunHooks is set
+	PersistentPreRun  func(cmd *Command, args []string)
+	PersistentPreRunE func(cmd *Command, args []string) error
+	// PreRun runs before Run on this command only
+	PreRun  func(cmd *Command, args []string)
+	PreRunE func(cmd *Command, args []string) error
+	// PostRun runs after Run on this command only
+	PostRun  func(cmd *Command, args []string)
+	PostRunE func(cmd *Command, args []string) error
+	// PersistentPostRun runs after PostRun on this command and on its
+	// children, with the same inheritance as PersistentPreRun
+	PersistentPostRun  func(cmd *Command, args []string)
+	PersistentPostRunE func(cmd *Command, args []string) error
+
+	// Version adds a --version flag printing it with the version template
+	Version string
+	// Annotations holds key/value metadata, e.g. build details for the
+	// version template
+	Annotations map[string]string
+
+	// DisableAutoGenTag leaves the "Auto generated" line out of
+	// generated docs
+	DisableAutoGenTag bool
+
+	// Hidden commands still run but are left out of help, completions
+	// and suggestions
+	Hidden bool
+	// Deprecated marks the command as deprecated: it still runs, after
+	// printing this message, but is hidden like Hidden commands
+	Deprecated string
+
+	// GroupID is the ID of the parent's Group the command is listed
+	// under in help
+	GroupID string
+
+	// Aliases are other names the command can be called by
+	Aliases []string
+	// SuggestFor lists names to suggest this command for, on top of the
+	// names within SuggestionsMinimumDistance edits of it
+	SuggestFor []string
+	// SuggestionsMinimumDistance is the edit distance within which
+	// subcommands and flags are suggested for unknown names (default 2)
+	SuggestionsMinimumDistance int
+	// DisableSuggestions turns off "Did you mean this?" suggestions
+	DisableSuggestions bool
+
+	// SilenceErrors stops Execute from printing returned errors; setting
+	// it on the root silences every subcommand
+	SilenceErrors bool
+	// SilenceUsage stops Execute from printing usage after an error;
+	// setting it on the root silences every subcommand
+	SilenceUsage bool
+
+	// Args validates the positional arguments after flag parsing, e.g.
+	// ExactArgs(1); any arguments are accepted if nil
+	Args PositionalArgs
+	// ValidArgs lists the accepted positional arguments for OnlyValidArgs
+	ValidArgs []string
+	// ValidArgsFunction completes positional arguments dynamically
+	ValidArgsFunction CompletionFunc
+
+	// FParseErrWhitelist lists flag parsing errors to ignore
+	FParseErrWhitelist FParseErrWhitelist
+	// TraverseChildren, set on the root, parses the flags of each
+	// parent before the subcommand name, as in "app --config x sub"
+	TraverseChildren bool
+
+	ctx             context.Context
+	in              io.Reader
+	out             io.Writer
+	err             io.Writer
+	errPrefix       string
+	helpTemplate    string
+	usageTemplate   string
+	versionTemplate string
+	helpFunc        func(*Command, []string)
+	usageFunc       func(*Command) error
+	flagErrorFunc   func(*Command, error) error
+
 	commands    []*Command
 	parent      *Command
 	flags       map[string]*Flag
 	args        []string
 	parsedArgs  []string
+
+	helpCommand     *Command
+	commandGroups   []*Group
+	helpGroupID     string
+	argsLenAtDash   int
+	flagCompletions map[string]CompletionFunc
+	configStores    []ConfigStore
+	middleware      []Middleware
+}
+
+// Group is a heading subcommands are listed under in help, e.g.
+// "Management Commands:"
+type Group struct {
+	ID    string
+	Title string
+}
+
+// FParseErrWhitelist configures which flag parsing errors are ignored
+type FParseErrWhitelist struct {
+	// UnknownFlags ignores unknown flags instead of failing
+	UnknownFlags bool
 }
 
+// RunFunc runs a command with its arguments
+type RunFunc func(cmd *Command, args []string) error
+
+// Middleware wraps the run of a command, hooks included, like HTTP
+// middleware: it can act before and after calling next, or not call it
+// at all
+type Middleware func(next RunFunc) RunFunc
+
+// EnableTraverseRunHooks makes every persistent hook up the command tree
+// run, instead of only the nearest one
+var EnableTraverseRunHooks = false
+
 // Flag represents a command-line flag
 type Flag struct {
 	Name      string
 	Shorthand string
 	Usage     string
-	Value     interface{}
-	DefValue  interface{}
-	Changed   bool
+	// Value holds the flag's value; custom types can be added with Var
+	Value Value
+	// DefValue is the default: the Go value for built-in types, the
+	// Value's String() for Var flags
+	DefValue interface{}
+	Changed  bool
+	// NoOptDefVal is the value used when the flag is given without one,
+	// e.g. "true" for bool flags; flags without it need a value
+	NoOptDefVal string
+	// Annotations holds metadata such as required flag markers
+	Annotations map[string][]string
+	// Hidden flags still work but are left out of usage and completions
+	Hidden bool
+	// Deprecated is printed when the flag is used; the flag is hidden
+	Deprecated string
+	// ShorthandDeprecated is printed when the shorthand is used; the
+	// shorthand is left out of usage
+	ShorthandDeprecated string
+	// EnvVar names an environment variable the flag falls back to when
+	// it isn't given on the command line; usage shows it as [$NAME]
+	EnvVar string
 }
 
-// Execute runs the root command
+// Value is the value of a flag, as in pflag, so flag values of any type
+// can be set from and shown as strings
+type Value interface {
+	String() string
+	Set(string) error
+	Type() string
+}
+
+// Execute runs the root command with the arguments from SetArgs, or
+// os.Args[1:] if none were set
 func (c *Command) Execute() error {
+	if c.args != nil {
+		return c.ExecuteWithArgs(c.args)
+	}
 	return c.ExecuteWithArgs(os.Args[1:])
 }
 
-// ExecuteWithArgs runs the command with provided arguments (for testing)
+// ExecuteContext is like Execute, but the command that runs (and its
+// hooks) can get ctx from cmd.Context()
+func (c *Command) ExecuteContext(ctx context.Context) error {
+	c.ctx = ctx
+	return c.Execute()
+}
+
+// ExecuteWithArgs runs the command with provided arguments (for testing).
+// -h/--help and "help [command]" print help instead of running anything,
+// as does selecting a command without a Run function. Errors are printed
+// with the usage of the failing command unless silenced, then returned.
 func (c *Command) ExecuteWithArgs(args []string) error {
+	cmd, err := c.execute(args)
+	if err != nil {
+		if !cmd.SilenceErrors && !c.SilenceErrors {
+			fmt.Fprintln(c.ErrOrStderr(), c.ErrPrefix(), err.Error())
+		}
+		if !cmd.SilenceUsage && !c.SilenceUsage {
+			fmt.Fprintln(c.ErrOrStderr(), cmd.UsageString())
+		}
+	}
+	return err
+}
+
+// execute resolves and runs the command for args, returning the command
+// that was selected along with any error
+func (c *Command) execute(args []string) (*Command, error) {
+	c.InitDefaultHelpCmd()
+	c.checkCommandGroups()
+	if len(args) > 0 && (args[0] == ShellCompRequestCmd || args[0] == ShellCompNoDescRequestCmd) {
+		return c, c.complete(args[1:], args[0] == ShellCompRequestCmd)
+	}
+
 	// Parse the command tree
-	cmd, cmdArgs, err := c.traverse(args)
+	traverse := c.traverse
+	if c.TraverseChildren {
+		traverse = c.traverseChildren
+	}
+	cmd, cmdArgs, err := traverse(args)
 	if err != nil {
-		return err
+		return cmd, err
 	}
-	
+	if c.ctx == nil {
+		c.ctx = context.Background()
+	}
+	cmd.ctx = c.ctx
+
 	// Parse flags
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
 	err = cmd.parseFlags(cmdArgs)
 	if err != nil {
+		return cmd, cmd.FlagErrorFunc()(cmd, err)
+	}
+	if err := cmd.applyEnvVars(); err != nil {
+		return cmd, cmd.FlagErrorFunc()(cmd, err)
+	}
+	cmd.bindConfigStores()
+	if cmd.Args == nil {
+		if err := legacyArgs(cmd, cmd.parsedArgs); err != nil {
+			return cmd, err
+		}
+	}
+	if cmd.GetBool("help") {
+		return cmd, cmd.Help()
+	}
+	if cmd.Version != "" && cmd.GetBool("version") {
+		return cmd, cmd.renderTemplate(cmd.OutOrStdout(), cmd.VersionTemplate())
+	}
+	if !cmd.Runnable() {
+		return cmd, cmd.Help()
+	}
+
+	if err := cmd.ValidateArgs(cmd.parsedArgs); err != nil {
+		return cmd, err
+	}
+
+	if cmd.Deprecated != "" {
+		fmt.Fprintf(cmd.OutOrStderr(), "Command %q is deprecated, %s\n", cmd.Name(), cmd.Deprecated)
+	}
+	return cmd, cmd.runMiddleware(cmd.parsedArgs)
+}
+
+// runMiddleware runs the command's hooks wrapped in the middleware of
+// the command and its parents, the root's outermost
+func (c *Command) runMiddleware(args []string) error {
+	run := func(cmd *Command, args []string) error {
+		return cmd.runHooks(args)
+	}
+	for p := c; p != nil; p = p.parent {
+		for i := len(p.middleware) - 1; i >= 0; i-- {
+			run = p.middleware[i](run)
+		}
+	}
+	return run(c, args)
+}
+
+// runHooks runs the command between its pre and post hooks, stopping at
+// the first error: PersistentPreRun, PreRun, Run, PostRun, then
+// PersistentPostRun. The E variant of a hook replaces the plain one.
+// Required flags and flag groups are checked just before Run, so pre
+// hooks can still set flags.
+func (c *Command) runHooks(args []string) error {
+	for _, p := range c.persistentHookCommands(true) {
+		if err := runHook(c, args, p.PersistentPreRun, p.PersistentPreRunE); err != nil {
+			return err
+		}
+	}
+	if err := runHook(c, args, c.PreRun, c.PreRunE); err != nil {
 		return err
 	}
-	
-	// Store remaining args
-	cmd.args = cmd.parsedArgs
-	
-	// Run the command
-	if cmd.Run != nil {
-		cmd.Run(cmd, cmd.args)
+	if err := c.ValidateRequiredFlags(); err != nil {
+		return err
+	}
+	if err := c.ValidateFlagGroups(); err != nil {
+		return err
+	}
+	if err := runHook(c, args, c.Run, c.RunE); err != nil {
+		return err
+	}
+	if err := runHook(c, args, c.PostRun, c.PostRunE); err != nil {
+		return err
+	}
+	for _, p := range c.persistentHookCommands(false) {
+		if err := runHook(c, args, p.PersistentPostRun, p.PersistentPostRunE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// persistentHookCommands returns the commands whose persistent pre (or
+// post) hooks apply to c: the nearest one up the tree, or with
+// EnableTraverseRunHooks every one, pre hooks ordered root first and
+// post hooks ordered c first
+func (c *Command) persistentHookCommands(pre bool) []*Command {
+	var found []*Command
+	for p := c; p != nil; p = p.parent {
+		if pre && (p.PersistentPreRun != nil || p.PersistentPreRunE != nil) ||
+			!pre && (p.PersistentPostRun != nil || p.PersistentPostRunE != nil) {
+			found = append(found, p)
+			if !EnableTraverseRunHooks {
+				break
+			}
+		}
+	}
+	if pre {
+		for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+			found[i], found[j] = found[j], found[i]
+		}
+	}
+	return found
+}
+
+// runHook calls fnE if set, otherwise fn
+func runHook(cmd *Command, args []string, fn func(*Command, []string), fnE func(*Command, []string) error) error {
+	if fnE != nil {
+		return fnE(cmd, args)
+	}
+	if fn != nil {
+		fn(cmd, args)
 	}
-	
 	return nil
 }
 
@@ -69,8 +368,7 @@ func (c *Command) traverse(args []string) (*Command, []string, error) {
 	
 	// Check if the first arg is a subcommand
 	for _, subcmd := range c.commands {
-		cmdName := strings.Split(subcmd.Use, " ")[0]
-		if args[0] == cmdName {
+		if subcmd.Name() == args[0] || subcmd.HasAlias(args[0]) {
 			return subcmd.traverse(args[1:])
 		}
 	}
@@ -79,61 +377,331 @@ func (c *Command) traverse(args []string) (*Command, []string, error) {
 	return c, args, nil
 }
 
+// traverseChildren is traverse for TraverseChildren: the flags before
+// each subcommand name are parsed by the command they follow, so the
+// values of parent flags are set on the parent
+func (c *Command) traverseChildren(args []string) (*Command, []string, error) {
+	var flags []string
+	inFlag := false
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return c, args, nil
+		case inFlag:
+			// The value of the previous flag
+			inFlag = false
+			flags = append(flags, arg)
+			continue
+		case strings.HasPrefix(arg, "-") && len(arg) > 1 && !c.isNegativeNumber(arg):
+			// --name or -n without a value takes the next argument
+			flag := c.lookupFlag(arg)
+			inFlag = flag != nil && flagNeedsValue(flag)
+			flags = append(flags, arg)
+			continue
+		}
+
+		var next *Command
+		for _, subcmd := range c.commands {
+			if subcmd.Name() == arg || subcmd.HasAlias(arg) {
+				next = subcmd
+				break
+			}
+		}
+		if next == nil {
+			return c, args, nil
+		}
+		if err := c.parseFlags(flags); err != nil {
+			return c, args, c.FlagErrorFunc()(c, err)
+		}
+		return next.traverseChildren(args[i+1:])
+	}
+	return c, args, nil
+}
+
 // parseFlags parses command-line flags
 func (c *Command) parseFlags(args []string) error {
 	var parsedArgs []string
-	
+	c.argsLenAtDash = -1
+
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
-		
+
 		// Check if it's a flag
-		if strings.HasPrefix(arg, "--") {
+		if arg == "--" {
+			// Everything after -- is an argument
+			c.argsLenAtDash = len(parsedArgs)
+			parsedArgs = append(parsedArgs, args[i+1:]...)
+			break
+		} else if strings.HasPrefix(arg, "--") {
 			// Long flag
 			flagName := arg[2:]
 			parts := strings.SplitN(flagName, "=", 2)
 			flagName = parts[0]
-			
-			if flag, exists := c.flags[flagName]; exists {
+
+			flag, exists := c.flags[flagName]
+			if !exists {
+				if !c.FParseErrWhitelist.UnknownFlags {
+					return c.unknownFlagError(flagName)
+				}
+				// Like pflag, also drop the value that may follow
+				if len(parts) == 1 && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					i++
+				}
+			} else {
+				var value string
 				if len(parts) == 2 {
 					// Value provided with =
-					flag.Value = parts[1]
-				} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					value = parts[1]
+				} else if flag.NoOptDefVal != "" {
+					// Bool and count flags don't take the next arg
+					value = flag.NoOptDefVal
+				} else if i+1 < len(args) && looksLikeValue(args[i+1]) {
 					// Value in next arg
 					i++
-					flag.Value = args[i]
+					value = args[i]
 				} else {
-					// Boolean flag
-					flag.Value = "true"
+					return fmt.Errorf("flag needs an argument: --%s", flagName)
 				}
-				flag.Changed = true
-			}
-		} else if strings.HasPrefix(arg, "-") && len(arg) == 2 {
-			// Short flag
-			shorthand := arg[1:2]
-			
-			// Find flag by shorthand
-			for _, flag := range c.flags {
-				if flag.Shorthand == shorthand {
-					if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-						i++
-						flag.Value = args[i]
-					} else {
-						flag.Value = "true"
-					}
-					flag.Changed = true
-					break
+				if err := flag.set(value); err != nil {
+					return err
 				}
+				c.warnDeprecatedFlag(flag, false)
 			}
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 && !c.isNegativeNumber(arg) {
+			// Short flags, possibly grouped
+			consumed, err := c.parseShortFlags(arg, args[i+1:])
+			if err != nil {
+				return err
+			}
+			i += consumed
 		} else {
 			// Regular argument
 			parsedArgs = append(parsedArgs, arg)
 		}
 	}
-	
+
 	c.parsedArgs = parsedArgs
 	return nil
 }
 
+// parseShortFlags parses a group of shorthands like -abc. The last one
+// may take a value from the rest of the group (-p8080 or -p=8080) or
+// from the next argument; the number of following arguments consumed is
+// returned.
+func (c *Command) parseShortFlags(arg string, rest []string) (int, error) {
+	shorthands := arg[1:]
+	for len(shorthands) > 0 {
+		short := shorthands[:1]
+		flag := c.lookupFlag("-" + short)
+		if flag == nil {
+			if !c.FParseErrWhitelist.UnknownFlags {
+				return 0, fmt.Errorf("unknown shorthand flag: '%s' in %s", short, arg)
+			}
+			// Like pflag, also drop the value that may follow
+			if len(shorthands) == 1 && len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+				return 1, nil
+			}
+			shorthands = shorthands[1:]
+			continue
+		}
+
+		consumed := 0
+		var value string
+		switch {
+		case len(shorthands) > 2 && shorthands[1] == '=':
+			value = shorthands[2:]
+			shorthands = ""
+		case flag.NoOptDefVal != "":
+			value = flag.NoOptDefVal
+			shorthands = shorthands[1:]
+		case len(shorthands) > 1:
+			value = shorthands[1:]
+			shorthands = ""
+		case len(rest) > 0 && looksLikeValue(rest[0]):
+			value = rest[0]
+			consumed = 1
+			shorthands = ""
+		default:
+			return 0, fmt.Errorf("flag needs an argument: '%s' in -%s", short, shorthands)
+		}
+		if err := flag.set(value); err != nil {
+			return 0, err
+		}
+		c.warnDeprecatedFlag(flag, true)
+		if consumed > 0 {
+			return consumed, nil
+		}
+	}
+	return 0, nil
+}
+
+// warnDeprecatedFlag prints the deprecation messages for a flag that was
+// just used, by name or by shorthand
+func (c *Command) warnDeprecatedFlag(flag *Flag, shorthand bool) {
+	if shorthand && flag.ShorthandDeprecated != "" {
+		fmt.Fprintf(c.OutOrStderr(), "Flag shorthand -%s has been deprecated, %s\n", flag.Shorthand, flag.ShorthandDeprecated)
+	}
+	if flag.Deprecated != "" {
+		fmt.Fprintf(c.OutOrStderr(), "Flag --%s has been deprecated, %s\n", flag.Name, flag.Deprecated)
+	}
+}
+
+// isNegativeNumber reports whether arg is a negative number rather than a
+// shorthand flag
+func (c *Command) isNegativeNumber(arg string) bool {
+	if c.lookupFlag(arg[:2]) != nil {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// looksLikeValue reports whether arg can be the value of the flag before
+// it: anything but a flag, though "-" and negative numbers are allowed
+func looksLikeValue(arg string) bool {
+	if arg == "-" || !strings.HasPrefix(arg, "-") {
+		return true
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// applyEnvVars sets the flags that weren't given on the command line
+// from their EnvVar, if it is set and not empty. Such flags count as
+// changed, so they satisfy required flags and flag groups.
+func (c *Command) applyEnvVars() error {
+	for _, name := range sortedFlagNames(c.flags) {
+		flag := c.flags[name]
+		if flag.Changed || flag.EnvVar == "" {
+			continue
+		}
+		if value := os.Getenv(flag.EnvVar); value != "" {
+			if err := flag.set(value); err != nil {
+				return fmt.Errorf("%v (from $%s)", err, flag.EnvVar)
+			}
+		}
+	}
+	return nil
+}
+
+// ArgsLenAtDash returns the number of arguments before "--", or -1 if
+// there was none
+func (c *Command) ArgsLenAtDash() int {
+	return c.argsLenAtDash
+}
+
+// unknownFlagError reports an unknown long flag, suggesting the flags
+// with similar names
+func (c *Command) unknownFlagError(name string) error {
+	msg := "unknown flag: --" + name
+	var suggestions []string
+	if !c.DisableSuggestions {
+		for _, candidate := range sortedFlagNames(c.flags) {
+			if c.isSuggestion(name, candidate) {
+				suggestions = append(suggestions, "--"+candidate)
+			}
+		}
+	}
+	return fmt.Errorf("%s%s", msg, formatSuggestions(suggestions))
+}
+
+// legacyArgs is used when a command has no Args validator: a root
+// command with subcommands rejects arguments that aren't a subcommand
+func legacyArgs(cmd *Command, args []string) error {
+	if !cmd.HasSubCommands() || cmd.HasParent() || len(args) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unknown command %q for %q%s", args[0], cmd.CommandPath(), cmd.findSuggestions(args[0]))
+}
+
+// findSuggestions formats the suggestions for an unknown subcommand
+func (c *Command) findSuggestions(arg string) string {
+	if c.DisableSuggestions {
+		return ""
+	}
+	return formatSuggestions(c.SuggestionsFor(arg))
+}
+
+// SuggestionsFor returns the subcommands to suggest for typedName: those
+// within SuggestionsMinimumDistance edits, starting with it, or listing
+// it in SuggestFor
+func (c *Command) SuggestionsFor(typedName string) []string {
+	var suggestions []string
+	for _, cmd := range c.commands {
+		if !cmd.IsAvailableCommand() {
+			continue
+		}
+		if c.isSuggestion(typedName, cmd.Name()) {
+			suggestions = append(suggestions, cmd.Name())
+			continue
+		}
+		for _, explicit := range cmd.SuggestFor {
+			if strings.EqualFold(typedName, explicit) {
+				suggestions = append(suggestions, cmd.Name())
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// isSuggestion reports whether candidate is close enough to typed to be
+// suggested for it
+func (c *Command) isSuggestion(typed, candidate string) bool {
+	distance := c.SuggestionsMinimumDistance
+	if distance <= 0 {
+		distance = 2
+	}
+	return ld(typed, candidate, true) <= distance ||
+		strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(typed))
+}
+
+// formatSuggestions formats suggestions the way cobra appends them to
+// errors
+func formatSuggestions(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	msg := "\n\nDid you mean this?\n"
+	for _, s := range suggestions {
+		msg += "\t" + s + "\n"
+	}
+	return msg
+}
+
+// ld returns the Levenshtein distance between s and t
+func ld(s, t string, ignoreCase bool) int {
+	if ignoreCase {
+		s = strings.ToLower(s)
+		t = strings.ToLower(t)
+	}
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for j := 1; j <= len(t); j++ {
+		for i := 1; i <= len(s); i++ {
+			if s[i-1] == t[j-1] {
+				d[i][j] = d[i-1][j-1]
+			} else {
+				min := d[i-1][j]
+				if d[i][j-1] < min {
+					min = d[i][j-1]
+				}
+				if d[i-1][j-1] < min {
+					min = d[i-1][j-1]
+				}
+				d[i][j] = min + 1
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
 // AddCommand adds a subcommand
 func (c *Command) AddCommand(commands ...*Command) {
 	for _, cmd := range commands {
@@ -161,161 +729,2501 @@ type FlagSet struct {
 	cmd *Command
 }
 
-// StringP adds a string flag with shorthand
-func (fs *FlagSet) StringP(name, shorthand string, value string, usage string) *string {
-	result := value
+// add registers a flag whose value is stored in value, a pointer of the
+// Go type matching typ
+func (fs *FlagSet) add(name, shorthand, usage, typ string, value, defValue interface{}) {
 	flag := &Flag{
 		Name:      name,
 		Shorthand: shorthand,
 		Usage:     usage,
-		Value:     &result,
-		DefValue:  value,
+		Value:     &typedValue{ptr: value, typ: typ},
+		DefValue:  defValue,
+	}
+	switch typ {
+	case "bool":
+		flag.NoOptDefVal = "true"
+	case "count":
+		flag.NoOptDefVal = "+1"
 	}
 	fs.cmd.flags[name] = flag
-	return &result
-}
-
-// String adds a string flag
-func (fs *FlagSet) String(name string, value string, usage string) *string {
-	return fs.StringP(name, "", value, usage)
 }
 
-// IntP adds an int flag with shorthand
-func (fs *FlagSet) IntP(name, shorthand string, value int, usage string) *int {
-	result := value
+// VarP adds a flag with shorthand whose value is a custom Value. Values
+// with an IsBoolFlag() method returning true don't need an argument.
+func (fs *FlagSet) VarP(value Value, name, shorthand, usage string) {
 	flag := &Flag{
 		Name:      name,
 		Shorthand: shorthand,
 		Usage:     usage,
-		Value:     &result,
-		DefValue:  value,
+		Value:     value,
+		DefValue:  value.String(),
+	}
+	if bv, ok := value.(interface{ IsBoolFlag() bool }); ok && bv.IsBoolFlag() {
+		flag.NoOptDefVal = "true"
 	}
 	fs.cmd.flags[name] = flag
-	return &result
 }
 
-// Int adds an int flag
-func (fs *FlagSet) Int(name string, value int, usage string) *int {
-	return fs.IntP(name, "", value, usage)
+// Var adds a flag whose value is a custom Value
+func (fs *FlagSet) Var(value Value, name, usage string) {
+	fs.VarP(value, name, "", usage)
 }
 
-// BoolP adds a boolean flag with shorthand
-func (fs *FlagSet) BoolP(name, shorthand string, value bool, usage string) *bool {
-	result := value
-	flag := &Flag{
-		Name:      name,
-		Shorthand: shorthand,
-		Usage:     usage,
-		Value:     &result,
-		DefValue:  value,
+// Lookup returns the named flag, or nil if there is none
+func (fs *FlagSet) Lookup(name string) *Flag {
+	return fs.cmd.flags[name]
+}
+
+// ShorthandLookup returns the flag with the given shorthand, or nil if
+// there is none
+func (fs *FlagSet) ShorthandLookup(shorthand string) *Flag {
+	for _, name := range sortedFlagNames(fs.cmd.flags) {
+		if flag := fs.cmd.flags[name]; flag.Shorthand == shorthand {
+			return flag
+		}
 	}
-	fs.cmd.flags[name] = flag
-	return &result
+	return nil
 }
 
-// Bool adds a boolean flag
-func (fs *FlagSet) Bool(name string, value bool, usage string) *bool {
-	return fs.BoolP(name, "", value, usage)
+// Changed reports whether the named flag was set, on the command line,
+// from its EnvVar or with Set
+func (fs *FlagSet) Changed(name string) bool {
+	flag := fs.Lookup(name)
+	return flag != nil && flag.Changed
 }
 
-// GetString gets a string flag value
-func (c *Command) GetString(name string) string {
-	if flag, exists := c.flags[name]; exists {
-		if str, ok := flag.Value.(*string); ok {
-			return *str
-		}
-		if str, ok := flag.Value.(string); ok {
-			return str
-		}
+// Set sets the named flag as if it was given on the command line
+func (fs *FlagSet) Set(name, value string) error {
+	flag := fs.Lookup(name)
+	if flag == nil {
+		return fmt.Errorf("no such flag -%v", name)
 	}
-	return ""
+	if err := flag.set(value); err != nil {
+		return err
+	}
+	fs.cmd.warnDeprecatedFlag(flag, false)
+	return nil
 }
 
-// GetInt gets an int flag value
-// Note: String to int conversion errors are silently ignored, returning 0
-func (c *Command) GetInt(name string) int {
-	if flag, exists := c.flags[name]; exists {
-		if i, ok := flag.Value.(*int); ok {
-			return *i
-		}
-		if str, ok := flag.Value.(string); ok {
-			// Try to parse string as int
-			var result int
-			fmt.Sscanf(str, "%d", &result) // Parse errors return 0
-			return result
-		}
+// VisitAll calls fn for every flag in lexicographical order
+func (fs *FlagSet) VisitAll(fn func(*Flag)) {
+	for _, name := range sortedFlagNames(fs.cmd.flags) {
+		fn(fs.cmd.flags[name])
 	}
-	return 0
 }
 
-// GetBool gets a boolean flag value
-func (c *Command) GetBool(name string) bool {
-	if flag, exists := c.flags[name]; exists {
-		if b, ok := flag.Value.(*bool); ok {
-			return *b
-		}
-		if str, ok := flag.Value.(string); ok {
-			return str == "true"
+// Visit calls fn for every changed flag in lexicographical order
+func (fs *FlagSet) Visit(fn func(*Flag)) {
+	fs.VisitAll(func(flag *Flag) {
+		if flag.Changed {
+			fn(flag)
 		}
-	}
-	return false
+	})
 }
 
-// Printf prints formatted output
-func (c *Command) Printf(format string, args ...interface{}) {
-	fmt.Printf(format, args...)
+// NFlag returns the number of flags that have been set
+func (fs *FlagSet) NFlag() int {
+	n := 0
+	fs.Visit(func(*Flag) { n++ })
+	return n
 }
 
-// Println prints a line
-func (c *Command) Println(args ...interface{}) {
-	fmt.Println(args...)
+// HasFlags reports whether any flags are defined
+func (fs *FlagSet) HasFlags() bool {
+	return len(fs.cmd.flags) > 0
 }
 
-// Print prints output
-func (c *Command) Print(args ...interface{}) {
-	fmt.Print(args...)
+// Parse parses flags from arguments, which shouldn't include the
+// command name; the remaining arguments are returned by Args
+func (fs *FlagSet) Parse(arguments []string) error {
+	return fs.cmd.parseFlags(arguments)
 }
 
-// SetArgs sets arguments for the command (for testing)
-func (c *Command) SetArgs(args []string) {
-	c.args = args
+// Args returns the arguments left after parsing flags
+func (fs *FlagSet) Args() []string {
+	return fs.cmd.parsedArgs
 }
 
-// Args returns the non-flag arguments
-func (c *Command) Args() []string {
-	return c.args
+// NArg returns the number of arguments left after parsing flags
+func (fs *FlagSet) NArg() int {
+	return len(fs.cmd.parsedArgs)
 }
 
-// Help displays help information
-func (c *Command) Help() error {
-	fmt.Printf("%s\n\n", c.Long)
-	if c.Short != "" {
-		fmt.Printf("%s\n\n", c.Short)
-	}
-	fmt.Printf("Usage:\n  %s\n\n", c.Use)
-	
-	if len(c.commands) > 0 {
-		fmt.Println("Available Commands:")
-		for _, cmd := range c.commands {
-			cmdName := strings.Split(cmd.Use, " ")[0]
-			fmt.Printf("  %-12s %s\n", cmdName, cmd.Short)
-		}
-		fmt.Println()
-	}
-	
-	if len(c.flags) > 0 {
-		fmt.Println("Flags:")
-		for _, flag := range c.flags {
-			shorthand := ""
-			if flag.Shorthand != "" {
-				shorthand = fmt.Sprintf("-%s, ", flag.Shorthand)
-			}
-			fmt.Printf("  %s--%s\t%s\n", shorthand, flag.Name, flag.Usage)
-		}
-		fmt.Println()
+// Arg returns the i'th argument left after parsing flags, or "" if
+// there is no such argument
+func (fs *FlagSet) Arg(i int) string {
+	if i < 0 || i >= len(fs.cmd.parsedArgs) {
+		return ""
 	}
-	
-	return nil
+	return fs.cmd.parsedArgs[i]
+}
+
+// StringP adds a string flag with shorthand
+func (fs *FlagSet) StringP(name, shorthand string, value string, usage string) *string {
+	result := value
+	fs.add(name, shorthand, usage, "string", &result, value)
+	return &result
+}
+
+// String adds a string flag
+func (fs *FlagSet) String(name string, value string, usage string) *string {
+	return fs.StringP(name, "", value, usage)
+}
+
+// IntP adds an int flag with shorthand
+func (fs *FlagSet) IntP(name, shorthand string, value int, usage string) *int {
+	result := value
+	fs.add(name, shorthand, usage, "int", &result, value)
+	return &result
+}
+
+// Int adds an int flag
+func (fs *FlagSet) Int(name string, value int, usage string) *int {
+	return fs.IntP(name, "", value, usage)
+}
+
+// BoolP adds a boolean flag with shorthand
+func (fs *FlagSet) BoolP(name, shorthand string, value bool, usage string) *bool {
+	result := value
+	fs.add(name, shorthand, usage, "bool", &result, value)
+	return &result
+}
+
+// Bool adds a boolean flag
+func (fs *FlagSet) Bool(name string, value bool, usage string) *bool {
+	return fs.BoolP(name, "", value, usage)
+}
+
+// Float64P adds a float64 flag with shorthand
+func (fs *FlagSet) Float64P(name, shorthand string, value float64, usage string) *float64 {
+	result := value
+	fs.add(name, shorthand, usage, "float64", &result, value)
+	return &result
+}
+
+// Float64 adds a float64 flag
+func (fs *FlagSet) Float64(name string, value float64, usage string) *float64 {
+	return fs.Float64P(name, "", value, usage)
+}
+
+// DurationP adds a time.Duration flag with shorthand, parsed with
+// time.ParseDuration (e.g. "1m30s")
+func (fs *FlagSet) DurationP(name, shorthand string, value time.Duration, usage string) *time.Duration {
+	result := value
+	fs.add(name, shorthand, usage, "duration", &result, value)
+	return &result
+}
+
+// Duration adds a time.Duration flag
+func (fs *FlagSet) Duration(name string, value time.Duration, usage string) *time.Duration {
+	return fs.DurationP(name, "", value, usage)
+}
+
+// CountP adds a flag counting how often it is given, e.g. -vvv is 3;
+// --name=n sets the count directly
+func (fs *FlagSet) CountP(name, shorthand string, usage string) *int {
+	result := 0
+	fs.add(name, shorthand, usage, "count", &result, 0)
+	return &result
+}
+
+// Count adds a count flag
+func (fs *FlagSet) Count(name string, usage string) *int {
+	return fs.CountP(name, "", usage)
+}
+
+// StringSliceP adds a string slice flag with shorthand. Values are
+// comma-separated and the flag may be repeated; the first value given
+// replaces the default.
+func (fs *FlagSet) StringSliceP(name, shorthand string, value []string, usage string) *[]string {
+	result := append([]string{}, value...)
+	fs.add(name, shorthand, usage, "stringSlice", &result, value)
+	return &result
+}
+
+// StringSlice adds a string slice flag
+func (fs *FlagSet) StringSlice(name string, value []string, usage string) *[]string {
+	return fs.StringSliceP(name, "", value, usage)
+}
+
+// IntSliceP adds an int slice flag with shorthand, with the same syntax as
+// StringSliceP
+func (fs *FlagSet) IntSliceP(name, shorthand string, value []int, usage string) *[]int {
+	result := append([]int{}, value...)
+	fs.add(name, shorthand, usage, "intSlice", &result, value)
+	return &result
+}
+
+// IntSlice adds an int slice flag
+func (fs *FlagSet) IntSlice(name string, value []int, usage string) *[]int {
+	return fs.IntSliceP(name, "", value, usage)
+}
+
+// StringToStringP adds a map flag with shorthand, given as
+// comma-separated key=value pairs; repeating the flag adds more pairs
+func (fs *FlagSet) StringToStringP(name, shorthand string, value map[string]string, usage string) *map[string]string {
+	result := make(map[string]string, len(value))
+	for k, v := range value {
+		result[k] = v
+	}
+	fs.add(name, shorthand, usage, "stringToString", &result, value)
+	return &result
+}
+
+// StringToString adds a map flag
+func (fs *FlagSet) StringToString(name string, value map[string]string, usage string) *map[string]string {
+	return fs.StringToStringP(name, "", value, usage)
+}
+
+// MarkHidden hides the named flag from usage and completions
+func (fs *FlagSet) MarkHidden(name string) error {
+	flag, exists := fs.cmd.flags[name]
+	if !exists {
+		return fmt.Errorf("flag %q does not exist", name)
+	}
+	flag.Hidden = true
+	return nil
+}
+
+// MarkDeprecated marks the named flag as deprecated: it is hidden and
+// prints "Flag --name has been deprecated, <usageMessage>" when used
+func (fs *FlagSet) MarkDeprecated(name string, usageMessage string) error {
+	flag, exists := fs.cmd.flags[name]
+	if !exists {
+		return fmt.Errorf("flag %q does not exist", name)
+	}
+	if usageMessage == "" {
+		return fmt.Errorf("deprecated message for flag %q must be set", name)
+	}
+	flag.Deprecated = usageMessage
+	return nil
+}
+
+// MarkShorthandDeprecated marks the named flag's shorthand as
+// deprecated: the long name keeps working silently
+func (fs *FlagSet) MarkShorthandDeprecated(name string, usageMessage string) error {
+	flag, exists := fs.cmd.flags[name]
+	if !exists {
+		return fmt.Errorf("flag %q does not exist", name)
+	}
+	if usageMessage == "" {
+		return fmt.Errorf("deprecated message for flag %q must be set", name)
+	}
+	flag.ShorthandDeprecated = usageMessage
+	return nil
+}
+
+// SetEnvVar makes the named flag fall back to the environment variable
+// envVar when it isn't given on the command line
+func (fs *FlagSet) SetEnvVar(name, envVar string) error {
+	flag, exists := fs.cmd.flags[name]
+	if !exists {
+		return fmt.Errorf("flag %q does not exist", name)
+	}
+	flag.EnvVar = envVar
+	return nil
+}
+
+// typedValue is the Value of the built-in flag types; ptr is a pointer
+// of the Go type matching typ
+type typedValue struct {
+	ptr     interface{}
+	typ     string
+	changed bool
+}
+
+// Set parses value according to the flag's type and stores it. Slice
+// and map values replace the default on the first Set and add to it
+// after that.
+func (v *typedValue) Set(value string) error {
+	var err error
+	switch v.typ {
+	case "string":
+		*v.ptr.(*string) = value
+	case "int":
+		var n int64
+		if n, err = strconv.ParseInt(value, 0, 64); err == nil {
+			*v.ptr.(*int) = int(n)
+		}
+	case "bool":
+		var b bool
+		if b, err = strconv.ParseBool(value); err == nil {
+			*v.ptr.(*bool) = b
+		}
+	case "float64":
+		var f float64
+		if f, err = strconv.ParseFloat(value, 64); err == nil {
+			*v.ptr.(*float64) = f
+		}
+	case "duration":
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err == nil {
+			*v.ptr.(*time.Duration) = d
+		}
+	case "count":
+		count := v.ptr.(*int)
+		if value == "+1" {
+			*count++
+		} else {
+			var n int64
+			if n, err = strconv.ParseInt(value, 0, 64); err == nil {
+				*count = int(n)
+			}
+		}
+	case "stringSlice":
+		var items []string
+		if items, err = readCSV(value); err == nil {
+			slice := v.ptr.(*[]string)
+			if !v.changed {
+				*slice = nil
+			}
+			*slice = append(*slice, items...)
+		}
+	case "intSlice":
+		var items []string
+		items, err = readCSV(value)
+		ints := make([]int, 0, len(items))
+		for _, item := range items {
+			if err != nil {
+				break
+			}
+			var n int64
+			n, err = strconv.ParseInt(strings.TrimSpace(item), 0, 64)
+			ints = append(ints, int(n))
+		}
+		if err == nil {
+			slice := v.ptr.(*[]int)
+			if !v.changed {
+				*slice = nil
+			}
+			*slice = append(*slice, ints...)
+		}
+	case "stringToString":
+		var pairs []string
+		pairs, err = readCSV(value)
+		parsed := make(map[string]string, len(pairs))
+		for _, pair := range pairs {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				err = fmt.Errorf("%s must be formatted as key=value", pair)
+				break
+			}
+			parsed[kv[0]] = kv[1]
+		}
+		if err == nil {
+			m := v.ptr.(*map[string]string)
+			if !v.changed {
+				*m = make(map[string]string, len(parsed))
+			}
+			for k, v := range parsed {
+				(*m)[k] = v
+			}
+		}
+	}
+	if err == nil {
+		v.changed = true
+	}
+	return err
+}
+
+// String formats the current value the way it would be given
+func (v *typedValue) String() string {
+	switch p := v.ptr.(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *float64:
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return "[" + strings.Join(*p, ",") + "]"
+	case *[]int:
+		items := make([]string, len(*p))
+		for i, n := range *p {
+			items[i] = strconv.Itoa(n)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case *map[string]string:
+		pairs := make([]string, 0, len(*p))
+		for k, val := range *p {
+			pairs = append(pairs, k+"="+val)
+		}
+		sort.Strings(pairs)
+		return "[" + strings.Join(pairs, ",") + "]"
+	}
+	return ""
+}
+
+// Type returns the flag type name, e.g. "stringSlice"
+func (v *typedValue) Type() string {
+	return v.typ
+}
+
+// set sets the flag from a command-line value and marks it changed
+func (f *Flag) set(value string) error {
+	if err := f.Value.Set(value); err != nil {
+		name := "--" + f.Name
+		if f.Shorthand != "" {
+			name = "-" + f.Shorthand + ", " + name
+		}
+		return fmt.Errorf("invalid argument %q for %q flag: %v", value, name, err)
+	}
+	f.Changed = true
+	return nil
+}
+
+// ptr returns the pointer a built-in flag stores its value in, or nil
+// for Var flags
+func (f *Flag) ptr() interface{} {
+	if v, ok := f.Value.(*typedValue); ok {
+		return v.ptr
+	}
+	return nil
+}
+
+// readCSV splits a comma-separated flag value, honouring quotes
+func readCSV(value string) ([]string, error) {
+	if value == "" {
+		return []string{}, nil
+	}
+	return csv.NewReader(strings.NewReader(value)).Read()
+}
+
+// GetString gets a string flag value
+func (c *Command) GetString(name string) string {
+	if flag, exists := c.flags[name]; exists {
+		if str, ok := flag.ptr().(*string); ok {
+			return *str
+		}
+		if flag.Value.Type() == "string" {
+			return flag.Value.String()
+		}
+	}
+	return ""
+}
+
+// GetInt gets an int flag value
+// Note: String to int conversion errors are silently ignored, returning 0
+func (c *Command) GetInt(name string) int {
+	if flag, exists := c.flags[name]; exists {
+		if i, ok := flag.ptr().(*int); ok {
+			return *i
+		}
+		if flag.ptr() == nil {
+			// Try to parse a Var flag's value as int
+			var result int
+			fmt.Sscanf(flag.Value.String(), "%d", &result) // Parse errors return 0
+			return result
+		}
+	}
+	return 0
+}
+
+// GetBool gets a boolean flag value
+func (c *Command) GetBool(name string) bool {
+	if flag, exists := c.flags[name]; exists {
+		if b, ok := flag.ptr().(*bool); ok {
+			return *b
+		}
+		if flag.ptr() == nil {
+			return flag.Value.String() == "true"
+		}
+	}
+	return false
+}
+
+// GetFloat64 gets a float64 flag value
+func (c *Command) GetFloat64(name string) float64 {
+	if flag, exists := c.flags[name]; exists {
+		if f, ok := flag.ptr().(*float64); ok {
+			return *f
+		}
+	}
+	return 0
+}
+
+// GetDuration gets a time.Duration flag value
+func (c *Command) GetDuration(name string) time.Duration {
+	if flag, exists := c.flags[name]; exists {
+		if d, ok := flag.ptr().(*time.Duration); ok {
+			return *d
+		}
+	}
+	return 0
+}
+
+// GetCount gets a count flag value
+func (c *Command) GetCount(name string) int {
+	if flag, exists := c.flags[name]; exists && flag.Value.Type() == "count" {
+		return *flag.ptr().(*int)
+	}
+	return 0
+}
+
+// GetStringSlice gets a string slice flag value
+func (c *Command) GetStringSlice(name string) []string {
+	if flag, exists := c.flags[name]; exists {
+		if s, ok := flag.ptr().(*[]string); ok {
+			return *s
+		}
+	}
+	return []string{}
+}
+
+// GetIntSlice gets an int slice flag value
+func (c *Command) GetIntSlice(name string) []int {
+	if flag, exists := c.flags[name]; exists {
+		if s, ok := flag.ptr().(*[]int); ok {
+			return *s
+		}
+	}
+	return []int{}
+}
+
+// GetStringToString gets a map flag value
+func (c *Command) GetStringToString(name string) map[string]string {
+	if flag, exists := c.flags[name]; exists {
+		if m, ok := flag.ptr().(*map[string]string); ok {
+			return *m
+		}
+	}
+	return map[string]string{}
+}
+
+// Annotations cobra records on flags for required flags and flag groups
+const (
+	BashCompOneRequiredFlag = "cobra_annotation_bash_completion_one_required_flag"
+
+	requiredAsGroup   = "cobra_annotation_required_if_others_set"
+	oneRequired       = "cobra_annotation_one_required"
+	mutuallyExclusive = "cobra_annotation_mutually_exclusive"
+)
+
+// MarkFlagRequired makes Execute fail if the named flag isn't set
+func (c *Command) MarkFlagRequired(name string) error {
+	flag, exists := c.flags[name]
+	if !exists {
+		return fmt.Errorf("no such flag -%v", name)
+	}
+	flag.annotate(BashCompOneRequiredFlag, "true")
+	return nil
+}
+
+// MarkPersistentFlagRequired makes Execute fail if the named persistent
+// flag isn't set
+func (c *Command) MarkPersistentFlagRequired(name string) error {
+	return c.MarkFlagRequired(name)
+}
+
+// MarkFlagsRequiredTogether makes Execute fail if some but not all of
+// the named flags are set
+func (c *Command) MarkFlagsRequiredTogether(flagNames ...string) {
+	c.markFlagGroup(requiredAsGroup, flagNames)
+}
+
+// MarkFlagsOneRequired makes Execute fail unless at least one of the
+// named flags is set
+func (c *Command) MarkFlagsOneRequired(flagNames ...string) {
+	c.markFlagGroup(oneRequired, flagNames)
+}
+
+// MarkFlagsMutuallyExclusive makes Execute fail if more than one of the
+// named flags is set
+func (c *Command) MarkFlagsMutuallyExclusive(flagNames ...string) {
+	c.markFlagGroup(mutuallyExclusive, flagNames)
+}
+
+// markFlagGroup records the group on each of its flags under annotation.
+// Like cobra it panics on an unknown flag, as that is a programming error.
+func (c *Command) markFlagGroup(annotation string, flagNames []string) {
+	group := strings.Join(flagNames, " ")
+	for _, name := range flagNames {
+		flag, exists := c.flags[name]
+		if !exists {
+			panic(fmt.Sprintf("Failed to find flag %q and mark it as being required in a flag group", name))
+		}
+		flag.annotate(annotation, group)
+	}
+}
+
+// annotate appends value to the flag's annotation key
+func (f *Flag) annotate(key, value string) {
+	if f.Annotations == nil {
+		f.Annotations = make(map[string][]string)
+	}
+	f.Annotations[key] = append(f.Annotations[key], value)
+}
+
+// ValidateRequiredFlags returns an error naming every required flag that
+// wasn't set
+func (c *Command) ValidateRequiredFlags() error {
+	var missing []string
+	for _, name := range sortedFlagNames(c.flags) {
+		flag := c.flags[name]
+		if required := flag.Annotations[BashCompOneRequiredFlag]; len(required) > 0 && required[0] == "true" && !flag.Changed {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf(`required flag(s) "%s" not set`, strings.Join(missing, `", "`))
+	}
+	return nil
+}
+
+// ValidateFlagGroups checks the flag groups set up with
+// MarkFlagsRequiredTogether, MarkFlagsOneRequired and
+// MarkFlagsMutuallyExclusive
+func (c *Command) ValidateFlagGroups() error {
+	// Set status of every flag in each group, by annotation
+	groups := map[string]map[string]map[string]bool{
+		requiredAsGroup:   {},
+		oneRequired:       {},
+		mutuallyExclusive: {},
+	}
+	for _, flag := range c.flags {
+		for annotation, status := range groups {
+			for _, group := range flag.Annotations[annotation] {
+				if status[group] == nil {
+					status[group] = make(map[string]bool)
+				}
+				status[group][flag.Name] = flag.Changed
+			}
+		}
+	}
+
+	for _, group := range sortedGroupNames(groups[requiredAsGroup]) {
+		set, unset := splitFlagGroup(groups[requiredAsGroup][group])
+		if len(set) > 0 && len(unset) > 0 {
+			return fmt.Errorf("if any flags in the group [%v] are set they must all be set; missing %v", group, unset)
+		}
+	}
+	for _, group := range sortedGroupNames(groups[oneRequired]) {
+		if set, _ := splitFlagGroup(groups[oneRequired][group]); len(set) == 0 {
+			return fmt.Errorf("at least one of the flags in the group [%v] is required", group)
+		}
+	}
+	for _, group := range sortedGroupNames(groups[mutuallyExclusive]) {
+		if set, _ := splitFlagGroup(groups[mutuallyExclusive][group]); len(set) > 1 {
+			return fmt.Errorf("if any flags in the group [%v] are set none of the others can be; %v were all set", group, set)
+		}
+	}
+	return nil
+}
+
+// splitFlagGroup returns the sorted names of the set and unset flags in a
+// group's status
+func splitFlagGroup(status map[string]bool) (set, unset []string) {
+	for name, changed := range status {
+		if changed {
+			set = append(set, name)
+		} else {
+			unset = append(unset, name)
+		}
+	}
+	sort.Strings(set)
+	sort.Strings(unset)
+	return set, unset
+}
+
+// sortedGroupNames returns the groups of a status map in order
+func sortedGroupNames(groups map[string]map[string]bool) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Printf prints formatted output to OutOrStderr
+func (c *Command) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(c.OutOrStderr(), format, args...)
+}
+
+// Println prints a line to OutOrStderr
+func (c *Command) Println(args ...interface{}) {
+	fmt.Fprintln(c.OutOrStderr(), args...)
+}
+
+// Print prints output to OutOrStderr
+func (c *Command) Print(args ...interface{}) {
+	fmt.Fprint(c.OutOrStderr(), args...)
+}
+
+// PrintErrf prints formatted output to ErrOrStderr
+func (c *Command) PrintErrf(format string, args ...interface{}) {
+	fmt.Fprintf(c.ErrOrStderr(), format, args...)
+}
+
+// PrintErrln prints a line to ErrOrStderr
+func (c *Command) PrintErrln(args ...interface{}) {
+	fmt.Fprintln(c.ErrOrStderr(), args...)
+}
+
+// PrintErr prints output to ErrOrStderr
+func (c *Command) PrintErr(args ...interface{}) {
+	fmt.Fprint(c.ErrOrStderr(), args...)
+}
+
+// Context returns the context the command was executed with: the one
+// given to ExecuteContext or SetContext on the root, or
+// context.Background(). It is nil before the command is executed.
+func (c *Command) Context() context.Context {
+	return c.ctx
+}
+
+// SetContext sets the context for the command; on the root command it
+// is passed to the command that gets executed
+func (c *Command) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// SetArgs sets the arguments Execute uses instead of os.Args (for testing)
+func (c *Command) SetArgs(args []string) {
+	c.args = args
+}
+
+// PositionalArgs validates a command's positional arguments
+type PositionalArgs func(cmd *Command, args []string) error
+
+// ValidateArgs checks args against the command's Args validator
+func (c *Command) ValidateArgs(args []string) error {
+	if c.Args == nil {
+		return nil
+	}
+	return c.Args(c, args)
+}
+
+// NoArgs rejects any positional arguments
+func NoArgs(cmd *Command, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+	}
+	return nil
+}
+
+// ArbitraryArgs accepts any positional arguments
+func ArbitraryArgs(cmd *Command, args []string) error {
+	return nil
+}
+
+// MinimumNArgs requires at least n arguments
+func MinimumNArgs(n int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) < n {
+			return fmt.Errorf("requires at least %d arg(s), only received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// MaximumNArgs allows at most n arguments
+func MaximumNArgs(n int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) > n {
+			return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// ExactArgs requires exactly n arguments
+func ExactArgs(n int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// RangeArgs requires between min and max arguments, inclusive
+func RangeArgs(min, max int) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		if len(args) < min || len(args) > max {
+			return fmt.Errorf("accepts between %d and %d arg(s), received %d", min, max, len(args))
+		}
+		return nil
+	}
+}
+
+// OnlyValidArgs rejects arguments not listed in the command's ValidArgs.
+// A ValidArgs entry may carry a description after a tab, which is ignored.
+func OnlyValidArgs(cmd *Command, args []string) error {
+	if len(cmd.ValidArgs) == 0 {
+		return nil
+	}
+	valid := make(map[string]bool, len(cmd.ValidArgs))
+	for _, v := range cmd.ValidArgs {
+		name, _, _ := strings.Cut(v, "\t")
+		valid[name] = true
+	}
+	for _, arg := range args {
+		if !valid[arg] {
+			return fmt.Errorf("invalid argument %q for %q", arg, cmd.CommandPath())
+		}
+	}
+	return nil
+}
+
+// MatchAll combines validators, returning the first error
+func MatchAll(validators ...PositionalArgs) PositionalArgs {
+	return func(cmd *Command, args []string) error {
+		for _, validate := range validators {
+			if err := validate(cmd, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Help writes the command's help to its output, using the help function
+func (c *Command) Help() error {
+	c.HelpFunc()(c, []string{})
+	return nil
+}
+
+// Usage writes the command's usage, using the usage function
+func (c *Command) Usage() error {
+	return c.UsageFunc()(c)
+}
+
+// UsageString returns the usage section of the help: usage line,
+// examples, subcommands and flags
+func (c *Command) UsageString() string {
+	out, errOut := c.out, c.err
+	var buf bytes.Buffer
+	c.out, c.err = &buf, &buf
+	c.Usage()
+	c.out, c.err = out, errOut
+	return buf.String()
+}
+
+// SetHelpFunc replaces the function Help calls; subcommands inherit it
+func (c *Command) SetHelpFunc(f func(*Command, []string)) {
+	c.helpFunc = f
+}
+
+// HelpFunc returns the help function, inherited from the parent. The
+// default renders HelpTemplate to OutOrStdout.
+func (c *Command) HelpFunc() func(*Command, []string) {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.helpFunc != nil {
+			return cmd.helpFunc
+		}
+	}
+	return func(cmd *Command, args []string) {
+		cmd.renderTemplate(cmd.OutOrStdout(), cmd.HelpTemplate())
+	}
+}
+
+// SetUsageFunc replaces the function Usage calls; subcommands inherit it
+func (c *Command) SetUsageFunc(f func(*Command) error) {
+	c.usageFunc = f
+}
+
+// UsageFunc returns the usage function, inherited from the parent. The
+// default renders UsageTemplate to OutOrStderr.
+func (c *Command) UsageFunc() func(*Command) error {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.usageFunc != nil {
+			return cmd.usageFunc
+		}
+	}
+	return func(cmd *Command) error {
+		return cmd.renderTemplate(cmd.OutOrStderr(), cmd.UsageTemplate())
+	}
+}
+
+// SetUsageTemplate sets the text/template used for usage, both on its
+// own and within the default help; subcommands inherit it
+func (c *Command) SetUsageTemplate(s string) {
+	c.usageTemplate = s
+}
+
+// UsageTemplate returns the usage template, inherited from the parent
+func (c *Command) UsageTemplate() string {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.usageTemplate != "" {
+			return cmd.usageTemplate
+		}
+	}
+	return defaultUsageTemplate
+}
+
+// SetErrPrefix sets the prefix Execute prints before errors, "Error:" by
+// default; subcommands inherit it
+func (c *Command) SetErrPrefix(s string) {
+	c.errPrefix = s
+}
+
+// ErrPrefix returns the error prefix, inherited from the parent
+func (c *Command) ErrPrefix() string {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.errPrefix != "" {
+			return cmd.errPrefix
+		}
+	}
+	return "Error:"
+}
+
+// SetFlagErrorFunc sets a function to transform flag parsing errors,
+// e.g. to add a hint; subcommands inherit it
+func (c *Command) SetFlagErrorFunc(f func(*Command, error) error) {
+	c.flagErrorFunc = f
+}
+
+// FlagErrorFunc returns the flag error function, inherited from the
+// parent; the default returns the error unchanged
+func (c *Command) FlagErrorFunc() func(*Command, error) error {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.flagErrorFunc != nil {
+			return cmd.flagErrorFunc
+		}
+	}
+	return func(cmd *Command, err error) error {
+		return err
+	}
+}
+
+func (c *Command) renderTemplate(w io.Writer, text string) error {
+	tmpl, err := template.New("help").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, c)
+}
+
+var templateFuncs = template.FuncMap{
+	"trimTrailingWhitespaces": func(s string) string {
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	},
+	"rpad": func(s string, padding int) string {
+		return fmt.Sprintf("%-*s", padding, s)
+	},
+}
+
+const defaultHelpTemplate = `{{with (or .Long .Short)}}{{. | trimTrailingWhitespaces}}
+
+{{end}}{{if or .Runnable .HasSubCommands}}{{.UsageString}}{{end}}`
+
+const defaultVersionTemplate = `{{with .Name}}{{printf "%s " .}}{{end}}{{printf "version %s" .Version}}
+`
+
+const defaultUsageTemplate = `Usage:{{if .Runnable}}
+  {{.UseLine}}{{end}}{{if .HasAvailableSubCommands}}
+  {{.CommandPath}} [command]{{end}}{{if gt (len .Aliases) 0}}
+
+Aliases:
+  {{.NameAndAliases}}{{end}}{{if .HasExample}}
+
+Examples:
+{{.Example}}{{end}}{{if .HasAvailableSubCommands}}{{$cmds := .Commands}}{{if eq (len .Groups) 0}}
+
+Available Commands:{{range $cmds}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
+  {{rpad .Name .NamePadding}} {{.Short}}{{end}}{{end}}{{else}}{{range $group := .Groups}}
+
+{{.Title}}{{range $cmds}}{{if (and (eq .GroupID $group.ID) (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding}} {{.Short}}{{end}}{{end}}{{end}}{{if not .AllChildCommandsHaveGroup}}
+
+Additional Commands:{{range $cmds}}{{if (and (eq .GroupID "") (or .IsAvailableCommand (eq .Name "help")))}}
+  {{rpad .Name .NamePadding}} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableFlags}}
+
+Flags:
+{{.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableSubCommands}}
+
+Use "{{.CommandPath}} [command] --help" for more information about a command.{{end}}
+`
+
+// SetHelpTemplate sets the text/template used by Help. The template is
+// executed with the command, so it can use fields and methods such as
+// .Long, .UsageString and .Commands; subcommands inherit it.
+func (c *Command) SetHelpTemplate(s string) {
+	c.helpTemplate = s
+}
+
+// HelpTemplate returns the help template, inherited from the parent
+func (c *Command) HelpTemplate() string {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.helpTemplate != "" {
+			return cmd.helpTemplate
+		}
+	}
+	return defaultHelpTemplate
+}
+
+// SetOut sets the writer for help output; subcommands inherit it
+func (c *Command) SetOut(w io.Writer) {
+	c.out = w
+}
+
+// OutOrStdout returns the output writer, inherited from the parent, or
+// os.Stdout
+func (c *Command) OutOrStdout() io.Writer {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.out != nil {
+			return cmd.out
+		}
+	}
+	return os.Stdout
+}
+
+// OutOrStderr returns the output writer, inherited from the parent, or
+// os.Stderr; warnings such as deprecation notices go here
+func (c *Command) OutOrStderr() io.Writer {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.out != nil {
+			return cmd.out
+		}
+	}
+	return os.Stderr
+}
+
+// SetErr sets the destination for error messages; nil means os.Stderr
+func (c *Command) SetErr(w io.Writer) {
+	c.err = w
+}
+
+// ErrOrStderr returns the error writer, inherited from the parent, or
+// os.Stderr
+func (c *Command) ErrOrStderr() io.Writer {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.err != nil {
+			return cmd.err
+		}
+	}
+	return os.Stderr
+}
+
+// SetIn sets the reader prompts read answers from; nil means os.Stdin
+func (c *Command) SetIn(r io.Reader) {
+	c.in = r
+}
+
+// InOrStdin returns the input reader, inherited from the parent, or
+// os.Stdin
+func (c *Command) InOrStdin() io.Reader {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.in != nil {
+			return cmd.in
+		}
+	}
+	return os.Stdin
+}
+
+// SetVersionTemplate sets the text/template printed for --version. Like
+// the help template it is executed with the command, so build details
+// can come from .Version and .Annotations; subcommands inherit it.
+func (c *Command) SetVersionTemplate(s string) {
+	c.versionTemplate = s
+}
+
+// VersionTemplate returns the version template, inherited from the
+// parent
+func (c *Command) VersionTemplate() string {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.versionTemplate != "" {
+			return cmd.versionTemplate
+		}
+	}
+	return defaultVersionTemplate
+}
+
+// InitDefaultVersionFlag adds a -v/--version flag if the command has a
+// Version, unless it defines its own; -v is left out if the shorthand is
+// taken
+func (c *Command) InitDefaultVersionFlag() {
+	if c.Version == "" {
+		return
+	}
+	flags := c.Flags()
+	if _, exists := c.flags["version"]; exists {
+		return
+	}
+	shorthand := "v"
+	for _, flag := range c.flags {
+		if flag.Shorthand == "v" {
+			shorthand = ""
+		}
+	}
+	flags.BoolP("version", shorthand, false, "version for "+c.Name())
+}
+
+// InitDefaultHelpFlag adds a -h/--help flag unless the command defines
+// its own; -h is left out if the shorthand is taken
+func (c *Command) InitDefaultHelpFlag() {
+	flags := c.Flags()
+	if _, exists := c.flags["help"]; exists {
+		return
+	}
+	shorthand := "h"
+	for _, flag := range c.flags {
+		if flag.Shorthand == "h" {
+			shorthand = ""
+		}
+	}
+	flags.BoolP("help", shorthand, false, "help for "+c.Name())
+}
+
+// InitDefaultHelpCmd adds a "help [command]" subcommand to commands that
+// have subcommands, unless one exists
+func (c *Command) InitDefaultHelpCmd() {
+	if !c.HasSubCommands() {
+		return
+	}
+	for _, cmd := range c.commands {
+		if cmd.Name() == "help" {
+			return
+		}
+	}
+	c.helpCommand = &Command{
+		Use:     "help [command]",
+		Short:   "Help about any command",
+		Long:    "Help provides help for any command in the application.",
+		GroupID: c.helpGroupID,
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			target, rest, _ := c.traverse(args)
+			var completions []string
+			if len(rest) == 0 {
+				for _, sub := range target.Commands() {
+					if sub.Name() != "help" && sub.IsAvailableCommand() && strings.HasPrefix(sub.Name(), toComplete) {
+						completions = append(completions, sub.Name()+"\t"+sub.Short)
+					}
+				}
+			}
+			return completions, ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *Command, args []string) {
+			target, rest, _ := c.traverse(args)
+			if len(rest) > 0 {
+				fmt.Fprintf(c.OutOrStdout(), "Unknown help topic %q\n", strings.Join(args, " "))
+				fmt.Fprint(c.OutOrStdout(), c.UsageString())
+				return
+			}
+			target.InitDefaultHelpFlag()
+			target.Help()
+		},
+	}
+	c.AddCommand(c.helpCommand)
+}
+
+// Name returns the command's name, the first word of Use
+func (c *Command) Name() string {
+	name, _, _ := strings.Cut(c.Use, " ")
+	return name
+}
+
+// HasAlias reports whether s is one of the command's aliases
+func (c *Command) HasAlias(s string) bool {
+	for _, alias := range c.Aliases {
+		if alias == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NameAndAliases returns the command's name followed by its aliases,
+// comma-separated
+func (c *Command) NameAndAliases() string {
+	return strings.Join(append([]string{c.Name()}, c.Aliases...), ", ")
+}
+
+// HasParent reports whether the command is a subcommand
+func (c *Command) HasParent() bool {
+	return c.parent != nil
+}
+
+// IsAvailableCommand reports whether the command is shown in help,
+// completions, suggestions and generated docs: it must not be hidden,
+// deprecated or the help command, and must be runnable or have available
+// subcommands
+func (c *Command) IsAvailableCommand() bool {
+	if c.Hidden || c.Deprecated != "" {
+		return false
+	}
+	// The help command is listed in help and completions specially
+	if c.HasParent() && c.parent.helpCommand == c {
+		return false
+	}
+	return c.Runnable() || c.HasAvailableSubCommands()
+}
+
+// HasAvailableSubCommands reports whether any subcommand is available
+func (c *Command) HasAvailableSubCommands() bool {
+	for _, sub := range c.commands {
+		if sub.IsAvailableCommand() {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandPath returns the names from the root to this command
+func (c *Command) CommandPath() string {
+	if c.parent == nil {
+		return c.Name()
+	}
+	return c.parent.CommandPath() + " " + c.Name()
+}
+
+// UseLine returns the usage line: the command path, Use's argument
+// syntax, and [flags] if the command has any
+func (c *Command) UseLine() string {
+	line := c.CommandPath()
+	if _, rest, ok := strings.Cut(c.Use, " "); ok {
+		line += " " + rest
+	}
+	if c.HasFlags() && !strings.Contains(line, "[flags]") {
+		line += " [flags]"
+	}
+	return line
+}
+
+// Runnable reports whether the command has a Run or RunE function
+func (c *Command) Runnable() bool {
+	return c.Run != nil || c.RunE != nil
+}
+
+// HasSubCommands reports whether the command has subcommands
+func (c *Command) HasSubCommands() bool {
+	return len(c.commands) > 0
+}
+
+// HasExample reports whether the command has an Example
+func (c *Command) HasExample() bool {
+	return c.Example != ""
+}
+
+// HasFlags reports whether the command defines flags
+func (c *Command) HasFlags() bool {
+	return len(c.flags) > 0
+}
+
+// HasAvailableFlags reports whether the command has flags that aren't
+// hidden or deprecated
+func (c *Command) HasAvailableFlags() bool {
+	for _, flag := range c.flags {
+		if !flag.Hidden && flag.Deprecated == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands returns the subcommands sorted by name
+func (c *Command) Commands() []*Command {
+	commands := append([]*Command(nil), c.commands...)
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].Name() < commands[j].Name()
+	})
+	return commands
+}
+
+// AddGroup adds groups that subcommands can be listed under in help, in
+// the order given, by setting their GroupID
+func (c *Command) AddGroup(groups ...*Group) {
+	c.commandGroups = append(c.commandGroups, groups...)
+}
+
+// Groups returns the command's groups in the order they were added
+func (c *Command) Groups() []*Group {
+	return c.commandGroups
+}
+
+// ContainsGroup reports whether groupID is one of the command's groups
+func (c *Command) ContainsGroup(groupID string) bool {
+	for _, group := range c.commandGroups {
+		if group.ID == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllChildCommandsHaveGroup reports whether every subcommand shown in
+// help belongs to a group, so no "Additional Commands" are listed
+func (c *Command) AllChildCommandsHaveGroup() bool {
+	for _, sub := range c.commands {
+		if (sub.IsAvailableCommand() || sub == c.helpCommand) && sub.GroupID == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// SetHelpCommandGroupID sets the group the help command is listed under
+func (c *Command) SetHelpCommandGroupID(groupID string) {
+	if c.helpCommand != nil {
+		c.helpCommand.GroupID = groupID
+	}
+	c.helpGroupID = groupID
+}
+
+// checkCommandGroups panics if a command in the tree has a GroupID its
+// parent doesn't define
+func (c *Command) checkCommandGroups() {
+	for _, sub := range c.commands {
+		if sub.GroupID != "" && !c.ContainsGroup(sub.GroupID) {
+			panic(fmt.Sprintf("group id '%s' is not defined for subcommand '%s'", sub.GroupID, sub.CommandPath()))
+		}
+		sub.checkCommandGroups()
+	}
+}
+
+// AddMiddleware adds middleware that wraps every run of the command and
+// its subcommands. Middleware added first is outermost; middleware on a
+// parent wraps middleware on its children.
+func (c *Command) AddMiddleware(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// RecoverPanics is middleware that turns a panic during the run into an
+// error
+func RecoverPanics(next RunFunc) RunFunc {
+	return func(cmd *Command, args []string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic in %q: %v", cmd.CommandPath(), r)
+			}
+		}()
+		return next(cmd, args)
+	}
+}
+
+// Parent returns the parent command, or nil for the root
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// Root returns the root of the command tree
+func (c *Command) Root() *Command {
+	if c.parent == nil {
+		return c
+	}
+	return c.parent.Root()
+}
+
+// NamePadding returns the width to pad names to when listing the
+// command alongside its siblings
+func (c *Command) NamePadding() int {
+	padding := 11
+	if c.parent != nil {
+		for _, sibling := range c.parent.commands {
+			if len(sibling.Name()) > padding {
+				padding = len(sibling.Name())
+			}
+		}
+	}
+	return padding
+}
+
+// FlagUsages returns the command's flags formatted for help, sorted by
+// name, with their types and non-zero defaults
+func (c *Command) FlagUsages() string {
+	names := make([]string, 0, len(c.flags))
+	for _, name := range sortedFlagNames(c.flags) {
+		if flag := c.flags[name]; !flag.Hidden && flag.Deprecated == "" {
+			names = append(names, name)
+		}
+	}
+
+	lines := make([]string, len(names))
+	width := 0
+	for i, name := range names {
+		flag := c.flags[name]
+		line := "      --" + flag.Name
+		if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
+			line = "  -" + flag.Shorthand + ", --" + flag.Name
+		}
+		if name := flagTypeName(flag); name != "" {
+			line += " " + name
+		}
+		lines[i] = line
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+
+	var buf strings.Builder
+	for i, name := range names {
+		flag := c.flags[name]
+		usage := flag.Usage
+		if def := flagDefaultString(flag); def != "" {
+			usage += " (default " + def + ")"
+		}
+		if flag.EnvVar != "" {
+			usage += " [$" + flag.EnvVar + "]"
+		}
+		fmt.Fprintf(&buf, "%-*s   %s\n", width, lines[i], usage)
+	}
+	return buf.String()
+}
+
+// ShellCompRequestCmd is the hidden command shell completion scripts call
+// to get completions; ShellCompNoDescRequestCmd does the same without
+// descriptions
+const (
+	ShellCompRequestCmd       = "__complete"
+	ShellCompNoDescRequestCmd = "__completeNoDesc"
+)
+
+// ShellCompDirective tells the completion script how to treat the
+// completions returned by a completion function
+type ShellCompDirective int
+
+const (
+	// ShellCompDirectiveError means an error occurred and completions
+	// should be ignored
+	ShellCompDirectiveError ShellCompDirective = 1 << iota
+	// ShellCompDirectiveNoSpace stops the shell adding a space after the
+	// completion
+	ShellCompDirectiveNoSpace
+	// ShellCompDirectiveNoFileComp stops the shell falling back to file
+	// completion when there are no completions
+	ShellCompDirectiveNoFileComp
+	// ShellCompDirectiveFilterFileExt treats the completions as file
+	// extensions to filter file completion by
+	ShellCompDirectiveFilterFileExt
+	// ShellCompDirectiveFilterDirs completes directory names only
+	ShellCompDirectiveFilterDirs
+	// ShellCompDirectiveKeepOrder keeps the completions in the order given
+	ShellCompDirectiveKeepOrder
+
+	// ShellCompDirectiveDefault lets the shell use its default behavior
+	ShellCompDirectiveDefault ShellCompDirective = 0
+)
+
+// String returns the names of the directive's bits
+func (d ShellCompDirective) String() string {
+	if d == ShellCompDirectiveDefault {
+		return "ShellCompDirectiveDefault"
+	}
+	names := []string{
+		"ShellCompDirectiveError",
+		"ShellCompDirectiveNoSpace",
+		"ShellCompDirectiveNoFileComp",
+		"ShellCompDirectiveFilterFileExt",
+		"ShellCompDirectiveFilterDirs",
+		"ShellCompDirectiveKeepOrder",
+	}
+	var set []string
+	for i, name := range names {
+		if d&(1<<i) != 0 {
+			set = append(set, name)
+		}
+	}
+	if len(set) == 0 {
+		return fmt.Sprintf("ERROR: unexpected ShellCompDirective value: %d", int(d))
+	}
+	return strings.Join(set, ", ")
+}
+
+// CompletionFunc returns completions for toComplete, the partial word
+// under the cursor, given the positional arguments before it. A
+// completion may carry a description after a tab.
+type CompletionFunc func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective)
+
+// NoFileCompletions is a CompletionFunc that completes nothing, not even
+// file names
+func NoFileCompletions(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+	return nil, ShellCompDirectiveNoFileComp
+}
+
+// FixedCompletions returns a CompletionFunc that always returns choices
+// with directive; the shell filters them by prefix
+func FixedCompletions(choices []string, directive ShellCompDirective) CompletionFunc {
+	return func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+		return choices, directive
+	}
+}
+
+// RegisterFlagCompletionFunc sets the function completing the values of
+// the named flag
+func (c *Command) RegisterFlagCompletionFunc(flagName string, f CompletionFunc) error {
+	if _, exists := c.flags[flagName]; !exists {
+		return fmt.Errorf("RegisterFlagCompletionFunc: flag '%s' does not exist", flagName)
+	}
+	if _, exists := c.flagCompletions[flagName]; exists {
+		return fmt.Errorf("RegisterFlagCompletionFunc: flag '%s' already registered", flagName)
+	}
+	if c.flagCompletions == nil {
+		c.flagCompletions = make(map[string]CompletionFunc)
+	}
+	c.flagCompletions[flagName] = f
+	return nil
+}
+
+// GetFlagCompletionFunc returns the completion function registered for
+// the named flag on the command or one of its parents
+func (c *Command) GetFlagCompletionFunc(flagName string) (CompletionFunc, bool) {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if f, ok := cmd.flagCompletions[flagName]; ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// complete implements the __complete command: it prints one completion
+// per line followed by ":<directive>"
+func (c *Command) complete(args []string, includeDesc bool) error {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	_, completions, directive := c.getCompletions(args)
+
+	out := c.OutOrStdout()
+	for _, comp := range completions {
+		if !includeDesc {
+			comp = strings.SplitN(comp, "\t", 2)[0]
+		}
+		// Only the first line of a description is kept
+		comp = strings.SplitN(comp, "\n", 2)[0]
+		fmt.Fprintln(out, strings.TrimSpace(comp))
+	}
+	fmt.Fprintf(out, ":%d\n", directive)
+	fmt.Fprintf(c.ErrOrStderr(), "Completion ended with directive: %s\n", directive)
+	return nil
+}
+
+// getCompletions returns the completions for the last of args, the word
+// being completed, along with the command it was completed for
+func (c *Command) getCompletions(args []string) (*Command, []string, ShellCompDirective) {
+	toComplete := args[len(args)-1]
+	cmd, rest, _ := c.traverse(args[:len(args)-1])
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
+
+	// Completing a flag value, either --flag=<value> or --flag <value>
+	var valueFlag *Flag
+	prefix := ""
+	if strings.HasPrefix(toComplete, "-") && strings.Contains(toComplete, "=") {
+		parts := strings.SplitN(toComplete, "=", 2)
+		valueFlag = cmd.lookupFlag(parts[0])
+		prefix = parts[0] + "="
+		toComplete = parts[1]
+	} else if len(rest) > 0 {
+		last := rest[len(rest)-1]
+		if flag := cmd.lookupFlag(last); flag != nil && !strings.Contains(last, "=") && flagNeedsValue(flag) {
+			valueFlag = flag
+			rest = rest[:len(rest)-1]
+		}
+	}
+	cmd.parseFlags(rest)
+	args = cmd.parsedArgs
+
+	if valueFlag != nil {
+		f, ok := cmd.GetFlagCompletionFunc(valueFlag.Name)
+		if !ok {
+			return cmd, nil, ShellCompDirectiveDefault
+		}
+		completions, directive := f(cmd, args, toComplete)
+		for i := range completions {
+			completions[i] = prefix + completions[i]
+		}
+		return cmd, completions, directive
+	}
+
+	var completions []string
+	if strings.HasPrefix(toComplete, "-") {
+		for _, name := range sortedFlagNames(cmd.flags) {
+			flag := cmd.flags[name]
+			if flag.Changed || flag.Hidden || flag.Deprecated != "" {
+				continue
+			}
+			if strings.HasPrefix("--"+flag.Name, toComplete) {
+				completions = append(completions, "--"+flag.Name+"\t"+flag.Usage)
+			}
+			if flag.Shorthand != "" && flag.ShorthandDeprecated == "" && strings.HasPrefix("-"+flag.Shorthand, toComplete) {
+				completions = append(completions, "-"+flag.Shorthand+"\t"+flag.Usage)
+			}
+		}
+		return cmd, completions, ShellCompDirectiveNoFileComp
+	}
+
+	directive := ShellCompDirectiveDefault
+	if len(cmd.ValidArgs) > 0 {
+		// ValidArgs only apply to the first argument
+		if len(args) == 0 {
+			for _, valid := range cmd.ValidArgs {
+				if strings.HasPrefix(valid, toComplete) {
+					completions = append(completions, valid)
+				}
+			}
+			if len(completions) > 0 {
+				directive = ShellCompDirectiveNoFileComp
+			}
+		}
+	} else if len(args) == 0 {
+		for _, sub := range cmd.Commands() {
+			if !sub.IsAvailableCommand() && sub != cmd.helpCommand {
+				continue
+			}
+			if strings.HasPrefix(sub.Name(), toComplete) {
+				completions = append(completions, sub.Name()+"\t"+sub.Short)
+			}
+			directive = ShellCompDirectiveNoFileComp
+		}
+	}
+	if cmd.ValidArgsFunction != nil {
+		dynamic, d := cmd.ValidArgsFunction(cmd, args, toComplete)
+		completions = append(completions, dynamic...)
+		directive = d
+	}
+	return cmd, completions, directive
+}
+
+// lookupFlag finds the flag for an argument like --name or -n
+func (c *Command) lookupFlag(arg string) *Flag {
+	if strings.HasPrefix(arg, "--") {
+		return c.flags[arg[2:]]
+	}
+	if strings.HasPrefix(arg, "-") && len(arg) == 2 {
+		for _, flag := range c.flags {
+			if flag.Shorthand == arg[1:] {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+// flagNeedsValue reports whether the flag takes a value argument
+func flagNeedsValue(flag *Flag) bool {
+	return flag.NoOptDefVal == ""
+}
+
+// sortedFlagNames returns the names of flags in order
+func sortedFlagNames(flags map[string]*Flag) []string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenBashCompletion writes a bash completion script for the root command
+// to w. The script asks the program for completions via __complete.
+func (c *Command) GenBashCompletion(w io.Writer) error {
+	return c.genCompletion(w, bashCompletionTemplate)
+}
+
+// GenZshCompletion writes a zsh completion script for the root command
+// to w
+func (c *Command) GenZshCompletion(w io.Writer) error {
+	return c.genCompletion(w, zshCompletionTemplate)
+}
+
+// GenFishCompletion writes a fish completion script for the root command
+// to w, with completion descriptions if includeDesc is set
+func (c *Command) GenFishCompletion(w io.Writer, includeDesc bool) error {
+	script := fishCompletionTemplate
+	if !includeDesc {
+		script = strings.Replace(script, ShellCompRequestCmd, ShellCompNoDescRequestCmd, -1)
+	}
+	return c.genCompletion(w, script)
+}
+
+// GenPowerShellCompletion writes a PowerShell completion script for the
+// root command to w
+func (c *Command) GenPowerShellCompletion(w io.Writer) error {
+	return c.genCompletion(w, powerShellCompletionTemplate)
+}
+
+// genCompletion renders a completion script template for the root command
+func (c *Command) genCompletion(w io.Writer, script string) error {
+	name := c.Root().Name()
+	tmpl, err := template.New("completion").Parse(script)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, map[string]string{
+		"Name":     name,
+		"FuncName": strings.NewReplacer("-", "_", ":", "_", ".", "_").Replace(name),
+	})
+}
+
+const bashCompletionTemplate = `# bash completion for {{.Name}}                   -*- shell-script -*-
+
+__{{.FuncName}}_complete()
+{
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local out directive comp
+    COMPREPLY=()
+
+    out=$("${COMP_WORDS[0]}" __completeNoDesc "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null)
+    directive=${out##*:}
+    out=${out%:*}
+
+    if (( (directive & 1) != 0 )); then
+        return
+    fi
+    if (( (directive & 2) != 0 )); then
+        compopt -o nospace
+    fi
+    if (( (directive & 4) != 0 )); then
+        compopt +o default
+    fi
+    if (( (directive & 8) != 0 )); then
+        local exts
+        exts=$(echo $out | tr ' ' '|')
+        COMPREPLY=($(compgen -f -X "!*.@(${exts})" -- "$cur"))
+        return
+    fi
+    if (( (directive & 16) != 0 )); then
+        COMPREPLY=($(compgen -d -- "$cur"))
+        return
+    fi
+
+    while IFS='' read -r comp; do
+        [[ -n $comp ]] && COMPREPLY+=("$comp")
+    done < <(compgen -W "$out" -- "$cur")
+}
+
+complete -o default -F __{{.FuncName}}_complete {{.Name}}
+`
+
+const zshCompletionTemplate = `#compdef {{.Name}}
+compdef _{{.FuncName}} {{.Name}}
+
+_{{.FuncName}}()
+{
+    local out directive comp
+    local -a described
+
+    out=$(${words[1]} __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)
+    directive=${out##*:}
+    out=${out%:*}
+
+    if (( directive & 1 )); then
+        return 1
+    fi
+    if (( directive & 16 )); then
+        _path_files -/
+        return
+    fi
+    if (( directive & 8 )); then
+        _files -g "*.(${(j:|:)${(f)out}})"
+        return
+    fi
+
+    for comp in ${(f)out}; do
+        if [[ $comp == *$'\t'* ]]; then
+            described+=("${${comp%%$'\t'*}//:/\\:}:${comp#*$'\t'}")
+        else
+            described+=("${comp//:/\\:}")
+        fi
+    done
+
+    if (( ${#described} == 0 )); then
+        (( directive & 4 )) || _files
+        return
+    fi
+    if (( directive & 2 )); then
+        _describe 'completions' described -S ''
+    else
+        _describe 'completions' described
+    fi
+}
+
+if [ "$funcstack[1]" = "_{{.FuncName}}" ]; then
+    _{{.FuncName}} "$@"
+fi
+`
+
+const fishCompletionTemplate = `# fish completion for {{.Name}}                   -*- shell-script -*-
+
+function __{{.FuncName}}_perform_completion
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    {{.Name}} __complete $args 2>/dev/null
+end
+
+function __{{.FuncName}}_completions
+    set -l out (__{{.FuncName}}_perform_completion)
+    set -l directive (string replace -r '^:' '' -- $out[-1])
+    set -e out[-1]
+
+    if test (math "$directive % 2") -eq 1
+        return
+    end
+    for comp in $out
+        echo $comp
+    end
+    if test (count $out) -eq 0; and test (math "floor($directive / 4) % 2") -eq 0
+        __fish_complete_path (commandline -ct)
+    end
+end
+
+complete -c {{.Name}} -f -a '(__{{.FuncName}}_completions)'
+`
+
+const powerShellCompletionTemplate = `# powershell completion for {{.Name}}              -*- shell-script -*-
+
+Register-ArgumentCompleter -CommandName '{{.Name}}' -Native -ScriptBlock {
+    param($WordToComplete, $CommandAst, $CursorPosition)
+
+    $Line = $CommandAst.Extent.ToString()
+    $Line = $Line.Substring(0, [Math]::Min($Line.Length, $CursorPosition - $CommandAst.Extent.StartOffset))
+    $Program, $Arguments = $Line.Split(' ', 2)
+    $RequestComp = "& '$Program' __complete $Arguments"
+    if ($WordToComplete -eq '') {
+        $RequestComp = "$RequestComp" + ' ""'
+    }
+
+    $Out = @(Invoke-Expression -Command $RequestComp 2>$null)
+    if ($Out.Count -eq 0) {
+        return
+    }
+    $Directive = [int]($Out[-1].TrimStart(':'))
+    $Out = $Out | Select-Object -SkipLast 1
+    if ($Directive -band 1) {
+        return
+    }
+
+    $Out | Where-Object { $_ -like "$WordToComplete*" } | ForEach-Object {
+        $Name, $Description = $_.Split("` + "`" + `t", 2)
+        if (-not $Description) {
+            $Description = " "
+        }
+        [System.Management.Automation.CompletionResult]::new($Name, $Name, 'ParameterValue', $Description)
+    }
+}
+`
+
+// CompletionResult is what the shell gets back for a partial command
+// line, for checking completions in tests
+type CompletionResult struct {
+	// Completions are the candidates, without descriptions
+	Completions []string
+	// Descriptions maps candidates to their descriptions, if any
+	Descriptions map[string]string
+	// Directive tells the shell what to do with the candidates
+	Directive ShellCompDirective
+}
+
+// Contains reports whether candidate is one of the completions
+func (r *CompletionResult) Contains(candidate string) bool {
+	for _, comp := range r.Completions {
+		if comp == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDirective reports whether every bit of d is set in the directive
+func (r *CompletionResult) HasDirective(d ShellCompDirective) bool {
+	return r.Directive&d == d
+}
+
+// Complete completes args, the words typed after the root command's
+// name, the way the shell scripts do through the __complete command; the
+// last word is the one being completed, "" to start a new word. Flags
+// set while completing are put back afterwards, so one command tree can
+// serve many calls.
+func Complete(root *Command, args ...string) (*CompletionResult, error) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	saved := saveFlags(root)
+	defer saved.restore()
+
+	var out, errOut bytes.Buffer
+	prevOut, prevErr := root.out, root.err
+	root.SetOut(&out)
+	root.SetErr(&errOut)
+	defer func() { root.out, root.err = prevOut, prevErr }()
+	if err := root.ExecuteWithArgs(append([]string{ShellCompRequestCmd}, args...)); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	last := lines[len(lines)-1]
+	directive, err := strconv.Atoi(strings.TrimPrefix(last, ":"))
+	if !strings.HasPrefix(last, ":") || err != nil {
+		return nil, fmt.Errorf("completion output has no directive: %q", out.String())
+	}
+	result := &CompletionResult{
+		Completions:  []string{},
+		Descriptions: map[string]string{},
+		Directive:    ShellCompDirective(directive),
+	}
+	for _, line := range lines[:len(lines)-1] {
+		value, desc, _ := strings.Cut(line, "\t")
+		result.Completions = append(result.Completions, value)
+		if desc != "" {
+			result.Descriptions[value] = desc
+		}
+	}
+	return result, nil
+}
+
+// CompleteLine is Complete for a command line as typed, split on
+// whitespace; a trailing space starts a new word, so "app serve " lists
+// what can follow serve. The first word, the root command's name, is
+// dropped.
+func CompleteLine(root *Command, line string) (*CompletionResult, error) {
+	words := strings.Fields(line)
+	if len(words) > 0 {
+		words = words[1:]
+	}
+	if line == "" || unicode.IsSpace(rune(line[len(line)-1])) {
+		words = append(words, "")
+	}
+	return Complete(root, words...)
+}
+
+// savedFlags holds the values of every flag in a command tree
+type savedFlags []func()
+
+// saveFlags records every flag value in the tree under c so restore can
+// put them back
+func saveFlags(c *Command) savedFlags {
+	var saved savedFlags
+	for _, flag := range c.flags {
+		flag := flag
+		changed := flag.Changed
+		if v, ok := flag.Value.(*typedValue); ok {
+			elem := reflect.ValueOf(v.ptr).Elem()
+			value, valueChanged := reflect.ValueOf(elem.Interface()), v.changed
+			saved = append(saved, func() {
+				elem.Set(value)
+				v.changed = valueChanged
+				flag.Changed = changed
+			})
+		} else {
+			value := flag.Value.String()
+			saved = append(saved, func() {
+				flag.Value.Set(value)
+				flag.Changed = changed
+			})
+		}
+	}
+	for _, sub := range c.commands {
+		saved = append(saved, saveFlags(sub)...)
+	}
+	return saved
+}
+
+// restore puts back the flag values recorded by saveFlags
+func (s savedFlags) restore() {
+	for _, fn := range s {
+		fn()
+	}
+}
+
+// flagTypeName returns the value name shown for a flag in usage, e.g.
+// "strings" for a string slice; bool flags show none
+func flagTypeName(flag *Flag) string {
+	switch flag.Value.Type() {
+	case "bool":
+		return ""
+	case "float64":
+		return "float"
+	case "stringSlice":
+		return "strings"
+	case "intSlice":
+		return "ints"
+	}
+	return flag.Value.Type()
+}
+
+// flagDefaultString formats a flag's default for usage, or returns "" if
+// it is the zero value
+func flagDefaultString(flag *Flag) string {
+	switch def := flag.DefValue.(type) {
+	case string:
+		if def != "" && flag.Value.Type() == "string" {
+			return fmt.Sprintf("%q", def)
+		}
+		if def != "" {
+			return def
+		}
+	case int:
+		if def != 0 {
+			return strconv.Itoa(def)
+		}
+	case bool:
+		if def {
+			return "true"
+		}
+	case float64:
+		if def != 0 {
+			return strconv.FormatFloat(def, 'g', -1, 64)
+		}
+	case time.Duration:
+		if def != 0 {
+			return def.String()
+		}
+	case []string:
+		if len(def) > 0 {
+			return "[" + strings.Join(def, ",") + "]"
+		}
+	case []int:
+		if len(def) > 0 {
+			items := make([]string, len(def))
+			for i, n := range def {
+				items[i] = strconv.Itoa(n)
+			}
+			return "[" + strings.Join(items, ",") + "]"
+		}
+	case map[string]string:
+		if len(def) > 0 {
+			pairs := make([]string, 0, len(def))
+			for k, v := range def {
+				pairs = append(pairs, k+"="+v)
+			}
+			sort.Strings(pairs)
+			return "[" + strings.Join(pairs, ",") + "]"
+		}
+	}
+	return ""
+}
+
+// GenMarkdown writes Markdown documentation for cmd to w, in the format
+// of cobra/doc: synopsis, usage, examples, options and see-also links
+func GenMarkdown(cmd *Command, w io.Writer) error {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+
+	var buf bytes.Buffer
+	name := cmd.CommandPath()
+	buf.WriteString("## " + name + "\n\n")
+	buf.WriteString(cmd.Short + "\n\n")
+	if cmd.Long != "" {
+		buf.WriteString("### Synopsis\n\n")
+		buf.WriteString(cmd.Long + "\n\n")
+	}
+	if cmd.Runnable() {
+		fmt.Fprintf(&buf, "```\n%s\n```\n\n", cmd.UseLine())
+	}
+	if cmd.Example != "" {
+		buf.WriteString("### Examples\n\n")
+		fmt.Fprintf(&buf, "```\n%s\n```\n\n", cmd.Example)
+	}
+	if cmd.HasAvailableFlags() {
+		buf.WriteString("### Options\n\n")
+		fmt.Fprintf(&buf, "```\n%s```\n\n", cmd.FlagUsages())
+	}
+	if cmd.HasParent() || cmd.HasAvailableSubCommands() {
+		buf.WriteString("### SEE ALSO\n\n")
+		if cmd.HasParent() {
+			parent := cmd.Parent()
+			fmt.Fprintf(&buf, "* [%s](%s)\t - %s\n", parent.CommandPath(), docBasename(parent, "_")+".md", parent.Short)
+		}
+		for _, child := range cmd.Commands() {
+			if child.IsAvailableCommand() {
+				fmt.Fprintf(&buf, "* [%s](%s)\t - %s\n", child.CommandPath(), docBasename(child, "_")+".md", child.Short)
+			}
+		}
+		buf.WriteString("\n")
+	}
+	if !cmd.autoGenTagDisabled() {
+		buf.WriteString("###### Auto generated by spf13/cobra on " + time.Now().Format("2-Jan-2006") + "\n")
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenMarkdownTree writes a Markdown file for cmd and every available
+// command below it into dir, named after the command path, e.g.
+// app_serve.md
+func GenMarkdownTree(cmd *Command, dir string) error {
+	return GenMarkdownTreeFs(cmd, osDocFs{}, dir)
+}
+
+// GenMarkdownTreeFs is GenMarkdownTree writing to fs instead of the disk
+func GenMarkdownTreeFs(cmd *Command, fs DocFs, dir string) error {
+	for _, child := range cmd.Commands() {
+		if !child.IsAvailableCommand() {
+			continue
+		}
+		if err := GenMarkdownTreeFs(child, fs, dir); err != nil {
+			return err
+		}
+	}
+	return genDocFile(fs, filepath.Join(dir, docBasename(cmd, "_")+".md"), func(w io.Writer) error {
+		return GenMarkdown(cmd, w)
+	})
+}
+
+// GenManHeader is the header of a man page; empty fields get defaults
+// from the command
+type GenManHeader struct {
+	Title   string
+	Section string
+	Date    *time.Time
+	Source  string
+	Manual  string
+}
+
+// GenMan writes a roff man page for cmd to w, with NAME, SYNOPSIS,
+// DESCRIPTION, OPTIONS, EXAMPLE and SEE ALSO sections. A nil header uses
+// the defaults: the dashed command path as title and section 1.
+func GenMan(cmd *Command, header *GenManHeader, w io.Writer) error {
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+	if header == nil {
+		header = &GenManHeader{}
+	}
+	h := *header
+	dashedName := docBasename(cmd, "-")
+	if h.Title == "" {
+		h.Title = strings.ToUpper(dashedName)
+	}
+	if h.Section == "" {
+		h.Section = "1"
+	}
+	date := time.Now()
+	if h.Date != nil {
+		date = *h.Date
+	}
+	if h.Source == "" && !cmd.autoGenTagDisabled() {
+		h.Source = "Auto generated by spf13/cobra"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(".nh\n")
+	fmt.Fprintf(&buf, ".TH %q %q %q %q %q\n", h.Title, h.Section, date.Format("Jan 2006"), h.Source, h.Manual)
+
+	buf.WriteString("\n.SH NAME\n")
+	fmt.Fprintf(&buf, "%s \\- %s\n", dashedName, roffEscape(cmd.Short))
+
+	buf.WriteString("\n.SH SYNOPSIS\n")
+	fmt.Fprintf(&buf, "\\fB%s\\fP\n", roffEscape(cmd.UseLine()))
+
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	buf.WriteString("\n.SH DESCRIPTION\n")
+	buf.WriteString(roffEscape(description) + "\n")
+
+	if cmd.HasAvailableFlags() {
+		buf.WriteString("\n.SH OPTIONS\n")
+		for _, name := range sortedFlagNames(cmd.flags) {
+			flag := cmd.flags[name]
+			if flag.Hidden || flag.Deprecated != "" {
+				continue
+			}
+			buf.WriteString(".PP\n")
+			if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
+				fmt.Fprintf(&buf, "\\fB\\-%s\\fP, ", flag.Shorthand)
+			}
+			fmt.Fprintf(&buf, "\\fB\\-\\-%s\\fP", flag.Name)
+			def := fmt.Sprint(flag.DefValue)
+			if flag.Value.Type() == "string" {
+				def = fmt.Sprintf("%q", def)
+			}
+			if flag.NoOptDefVal != "" {
+				fmt.Fprintf(&buf, "[=%s]\n", roffEscape(def))
+			} else {
+				fmt.Fprintf(&buf, "=%s\n", roffEscape(def))
+			}
+			usage := flag.Usage
+			if flag.EnvVar != "" {
+				usage += " [$" + flag.EnvVar + "]"
+			}
+			buf.WriteString(".RS 4\n" + roffEscape(usage) + "\n.RE\n")
+		}
+	}
+
+	if cmd.Example != "" {
+		buf.WriteString("\n.SH EXAMPLE\n.EX\n")
+		buf.WriteString(roffEscape(cmd.Example) + "\n")
+		buf.WriteString(".EE\n")
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s(%s)\\fP", docBasename(cmd.Parent(), "-"), h.Section))
+	}
+	for _, child := range cmd.Commands() {
+		if child.IsAvailableCommand() {
+			seeAlso = append(seeAlso, fmt.Sprintf("\\fB%s(%s)\\fP", docBasename(child, "-"), h.Section))
+		}
+	}
+	if len(seeAlso) > 0 {
+		buf.WriteString("\n.SH SEE ALSO\n")
+		buf.WriteString(strings.Join(seeAlso, ", ") + "\n")
+	}
+
+	if !cmd.autoGenTagDisabled() {
+		buf.WriteString("\n.SH HISTORY\n")
+		buf.WriteString(date.Format("2-Jan-2006") + " Auto generated by spf13/cobra\n")
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// GenManTree writes a man page for cmd and every available command below
+// it into dir, named after the dashed command path and the section, e.g.
+// app-serve.1
+func GenManTree(cmd *Command, header *GenManHeader, dir string) error {
+	return GenManTreeFs(cmd, header, osDocFs{}, dir)
+}
+
+// GenManTreeFs is GenManTree writing to fs instead of the disk
+func GenManTreeFs(cmd *Command, header *GenManHeader, fs DocFs, dir string) error {
+	if header == nil {
+		header = &GenManHeader{}
+	}
+	for _, child := range cmd.Commands() {
+		if !child.IsAvailableCommand() {
+			continue
+		}
+		if err := GenManTreeFs(child, header, fs, dir); err != nil {
+			return err
+		}
+	}
+	section := header.Section
+	if section == "" {
+		section = "1"
+	}
+	return genDocFile(fs, filepath.Join(dir, docBasename(cmd, "-")+"."+section), func(w io.Writer) error {
+		return GenMan(cmd, header, w)
+	})
+}
+
+// DocFs is where the Gen*TreeFs functions write doc files. The afero
+// emulator's Afero implements it, as can any file system with this method.
+type DocFs interface {
+	WriteFile(filename string, data []byte, perm os.FileMode) error
+}
+
+// osDocFs writes doc files to the disk
+type osDocFs struct{}
+
+func (osDocFs) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(filename, data, perm)
+}
+
+// genDocFile writes what gen generates to path in fs
+func genDocFile(fs DocFs, path string, gen func(io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := gen(&buf); err != nil {
+		return err
+	}
+	return fs.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// docBasename returns the command path joined by sep, the base name of
+// the command's doc file
+func docBasename(cmd *Command, sep string) string {
+	return strings.Replace(cmd.CommandPath(), " ", sep, -1)
+}
+
+// autoGenTagDisabled reports whether DisableAutoGenTag is set on the
+// command or one of its parents
+func (c *Command) autoGenTagDisabled() bool {
+	for cmd := c; cmd != nil; cmd = cmd.parent {
+		if cmd.DisableAutoGenTag {
+			return true
+		}
+	}
+	return false
+}
+
+// roffEscape escapes backslashes and keeps lines from being read as roff
+// requests
+func roffEscape(s string) string {
+	s = strings.Replace(s, `\`, `\e`, -1)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ConfigStore is the part of a Viper instance BindFlagsToViper needs
+type ConfigStore interface {
+	Set(key string, value interface{})
+	SetDefault(key string, value interface{})
+}
+
+// BindFlagsToViper registers every flag of cmd and its subcommands as a
+// key in v. Flag defaults become Viper defaults, so config files and
+// environment variables win over them; once the command line is parsed,
+// flags the user changed are Set, overriding config.
+func BindFlagsToViper(cmd *Command, v ConfigStore) {
+	cmd.configStores = append(cmd.configStores, v)
+	var register func(c *Command)
+	register = func(c *Command) {
+		for _, name := range sortedFlagNames(c.flags) {
+			if name != "help" && name != "version" {
+				v.SetDefault(name, c.flags[name].DefValue)
+			}
+		}
+		for _, sub := range c.commands {
+			register(sub)
+		}
+	}
+	register(cmd)
+}
+
+// bindConfigStores pushes the parsed flags of c to the stores bound on c
+// and its parents
+func (c *Command) bindConfigStores() {
+	for p := c; p != nil; p = p.parent {
+		for _, v := range p.configStores {
+			for _, name := range sortedFlagNames(c.flags) {
+				flag := c.flags[name]
+				if name == "help" || name == "version" {
+					continue
+				}
+				if flag.Changed {
+					v.Set(name, flag.value())
+				} else {
+					v.SetDefault(name, flag.DefValue)
+				}
+			}
+		}
+	}
+}
+
+// value returns the flag's current value: the Go value for built-in
+// types, the Value's String() for Var flags
+func (f *Flag) value() interface{} {
+	if ptr := f.ptr(); ptr != nil {
+		return reflect.ValueOf(ptr).Elem().Interface()
+	}
+	return f.Value.String()
+}
+
+// Prompter asks interactive questions, reading one line per answer from
+// in and writing the questions to out. Nothing is read ahead, so several
+// prompters can share a reader and tests can script whole wizards.
+type Prompter struct {
+	in  io.Reader
+	out io.Writer
+}
+
+// NewPrompter returns a Prompter reading from in and writing to out
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: in, out: out}
+}
+
+// Prompter returns a Prompter on the command's input (SetIn) and its
+// OutOrStderr writer, keeping stdout clean for the command's output
+func (c *Command) Prompter() *Prompter {
+	return NewPrompter(c.InOrStdin(), c.OutOrStderr())
+}
+
+// Input asks for a line of text; an empty answer gives def
+func (p *Prompter) Input(label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+	answer, err := p.readLine()
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Password asks for a secret. The answer is never echoed back or
+// written to out; a newline is printed after it instead, since the
+// terminal's own echo of the typed line is left alone.
+func (p *Prompter) Password(label string) (string, error) {
+	fmt.Fprintf(p.out, "%s: ", label)
+	answer, err := p.readLine()
+	fmt.Fprintln(p.out)
+	return answer, err
+}
+
+// Confirm asks a yes/no question, repeating it until the answer is y,
+// yes, n or no (any case); an empty answer gives def
+func (p *Prompter) Confirm(label string, def bool) (bool, error) {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, choices)
+		answer, err := p.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "Please answer y or n.")
+	}
+}
+
+// Select lists items numbered from 1 and asks for one, by number or by
+// name, repeating the question until the answer matches. It returns the
+// index and value of the chosen item.
+func (p *Prompter) Select(label string, items []string) (int, string, error) {
+	if len(items) == 0 {
+		return -1, "", fmt.Errorf("nothing to select for %q", label)
+	}
+	for {
+		fmt.Fprintf(p.out, "%s:\n", label)
+		for i, item := range items {
+			fmt.Fprintf(p.out, "  %d) %s\n", i+1, item)
+		}
+		fmt.Fprintf(p.out, "Choose 1-%d: ", len(items))
+		answer, err := p.readLine()
+		if err != nil {
+			return -1, "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(items) {
+			return n - 1, items[n-1], nil
+		}
+		for i, item := range items {
+			if answer == item {
+				return i, item, nil
+			}
+		}
+		fmt.Fprintf(p.out, "Invalid choice %q.\n", answer)
+	}
+}
+
+// readLine reads up to the next newline a byte at a time, so nothing
+// past the answer is consumed, and trims surrounding whitespace. A final
+// line without a newline is returned; io.EOF is returned only when there
+// is no answer at all.
+func (p *Prompter) readLine() (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := p.in.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(line)), nil
 }
 
 // Root command helper
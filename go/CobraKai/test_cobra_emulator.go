@@ -2,8 +2,14 @@ package main
 
 // Developed by PowerShield, as an alternative to Cobra
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Helper function to run a test
@@ -379,6 +385,1488 @@ func testNewRootCommand() bool {
 	return root != nil && root.Use == "app"
 }
 
+// Test --help, -h and the help subcommand
+func testHelpOutput() bool {
+	served := false
+	rootCmd := &Command{
+		Use:  "app",
+		Long: "App manages the app server.",
+	}
+	serveCmd := &Command{
+		Use:     "serve [dir]",
+		Short:   "Start the server",
+		Example: "  app serve ./public --port 9000",
+		Run: func(cmd *Command, args []string) {
+			served = true
+		},
+	}
+	serveCmd.Flags().IntP("port", "p", 8080, "Port to listen on")
+	serveCmd.Flags().String("name", "", "Server name")
+	versionCmd := &Command{
+		Use:   "version",
+		Short: "Print the version",
+		Run:   func(cmd *Command, args []string) {},
+	}
+	rootCmd.AddCommand(serveCmd, versionCmd)
+
+	var rootHelp, serveHelp, helpServe, unknown bytes.Buffer
+	rootCmd.SetOut(&rootHelp)
+	rootCmd.ExecuteWithArgs([]string{"--help"})
+	rootCmd.SetOut(&serveHelp)
+	rootCmd.ExecuteWithArgs([]string{"serve", "-h"})
+	rootCmd.SetOut(&helpServe)
+	rootCmd.ExecuteWithArgs([]string{"help", "serve"})
+	rootCmd.SetOut(&unknown)
+	rootCmd.ExecuteWithArgs([]string{"help", "nope"})
+
+	root := rootHelp.String()
+	serve := serveHelp.String()
+	return !served &&
+		strings.HasPrefix(root, "App manages the app server.\n\nUsage:\n  app [command]\n") &&
+		strings.Contains(root, "Available Commands:\n  help        Help about any command\n  serve       Start the server\n  version     Print the version\n") &&
+		strings.Contains(root, "Flags:\n  -h, --help   help for app\n") &&
+		strings.Contains(root, `Use "app [command] --help" for more information about a command.`) &&
+		strings.HasPrefix(serve, "Start the server\n\nUsage:\n  app serve [dir] [flags]\n\nExamples:\n  app serve ./public --port 9000\n") &&
+		strings.Contains(serve, "  -h, --help          help for serve\n      --name string   Server name\n  -p, --port int      Port to listen on (default 8080)\n") &&
+		helpServe.String() == serve &&
+		strings.HasPrefix(unknown.String(), `Unknown help topic "nope"`)
+}
+
+// Test custom help templates and the help flag without -h
+func testHelpTemplate() bool {
+	var out bytes.Buffer
+	rootCmd := &Command{Use: "app"}
+	rootCmd.SetOut(&out)
+	rootCmd.SetHelpTemplate("{{.CommandPath}}: {{.Short}}\n")
+	connectCmd := &Command{
+		Use:   "connect",
+		Short: "Connect to a host",
+		Run:   func(cmd *Command, args []string) {},
+	}
+	connectCmd.Flags().StringP("host", "h", "localhost", "Host")
+	rootCmd.AddCommand(connectCmd)
+
+	rootCmd.ExecuteWithArgs([]string{"connect", "--help"})
+	custom := out.String()
+
+	var flags bytes.Buffer
+	connectCmd.SetHelpTemplate("{{.FlagUsages}}")
+	connectCmd.SetOut(&flags)
+	connectCmd.Help()
+
+	return custom == "app connect: Connect to a host\n" &&
+		strings.Contains(flags.String(), "      --help          help for connect\n") &&
+		strings.Contains(flags.String(), `  -h, --host string   Host (default "localhost")`)
+}
+
+// Test positional argument validators
+func testArgsValidators() bool {
+	ran := 0
+	run := func(cmd *Command, args []string) { ran++ }
+	validate := func(args PositionalArgs, given ...string) error {
+		cmd := &Command{Use: "get", Args: args, Run: run, SilenceErrors: true, SilenceUsage: true}
+		return cmd.ExecuteWithArgs(given)
+	}
+
+	errorIs := func(err error, message string) bool {
+		return err != nil && err.Error() == message
+	}
+
+	return validate(ExactArgs(1), "a") == nil &&
+		errorIs(validate(ExactArgs(1), "a", "b"), "accepts 1 arg(s), received 2") &&
+		errorIs(validate(MinimumNArgs(2), "a"), "requires at least 2 arg(s), only received 1") &&
+		errorIs(validate(MaximumNArgs(1), "a", "b"), "accepts at most 1 arg(s), received 2") &&
+		validate(RangeArgs(1, 2), "a", "b") == nil &&
+		errorIs(validate(RangeArgs(1, 2)), "accepts between 1 and 2 arg(s), received 0") &&
+		errorIs(validate(NoArgs, "extra"), `unknown command "extra" for "get"`) &&
+		validate(ArbitraryArgs, "a", "b", "c") == nil &&
+		validate(nil, "a", "b") == nil &&
+		ran == 4
+}
+
+// Test ValidArgs with OnlyValidArgs after flag parsing
+func testValidArgs() bool {
+	var got []string
+	rootCmd := &Command{Use: "kubectl"}
+	rootCmd.SetErr(&bytes.Buffer{})
+	getCmd := &Command{
+		Use:       "get [resource]",
+		ValidArgs: []string{"pods\tList pods", "services", "nodes"},
+		Args:      MatchAll(ExactArgs(1), OnlyValidArgs),
+		Run: func(cmd *Command, args []string) {
+			got = args
+		},
+	}
+	getCmd.Flags().StringP("output", "o", "table", "Output format")
+	rootCmd.AddCommand(getCmd)
+
+	ok := rootCmd.ExecuteWithArgs([]string{"get", "-o", "json", "pods"})
+	invalid := rootCmd.ExecuteWithArgs([]string{"get", "deployments"})
+	tooMany := rootCmd.ExecuteWithArgs([]string{"get", "pods", "nodes"})
+
+	return ok == nil && len(got) == 1 && got[0] == "pods" &&
+		invalid != nil && invalid.Error() == `invalid argument "deployments" for "kubectl get"` &&
+		tooMany != nil && tooMany.Error() == "accepts 1 arg(s), received 2"
+}
+
+// Test RunE errors are returned and reported with usage
+func testRunE() bool {
+	var stderr bytes.Buffer
+	rootCmd := &Command{Use: "app"}
+	rootCmd.SetErr(&stderr)
+	deployCmd := &Command{
+		Use:   "deploy [env]",
+		Short: "Deploy the app",
+		RunE: func(cmd *Command, args []string) error {
+			return fmt.Errorf("environment %q not found", args[0])
+		},
+	}
+	rootCmd.AddCommand(deployCmd)
+
+	err := rootCmd.ExecuteWithArgs([]string{"deploy", "staging"})
+	output := stderr.String()
+
+	return err != nil && err.Error() == `environment "staging" not found` &&
+		strings.HasPrefix(output, "Error: environment \"staging\" not found\n") &&
+		strings.Contains(output, "Usage:\n  app deploy [env] [flags]") &&
+		deployCmd.Runnable()
+}
+
+// Test SilenceErrors and SilenceUsage, including inheritance from the root
+func testSilenceErrorsAndUsage() bool {
+	var stderr bytes.Buffer
+	fail := func(cmd *Command, args []string) error {
+		return fmt.Errorf("boom")
+	}
+	rootCmd := &Command{Use: "app", SilenceUsage: true}
+	rootCmd.SetErr(&stderr)
+	quietCmd := &Command{Use: "quiet", RunE: fail, SilenceErrors: true}
+	loudCmd := &Command{Use: "loud", RunE: fail}
+	rootCmd.AddCommand(quietCmd, loudCmd)
+
+	quietErr := rootCmd.ExecuteWithArgs([]string{"quiet"})
+	quiet := stderr.String()
+	stderr.Reset()
+	loudErr := rootCmd.ExecuteWithArgs([]string{"loud"})
+	loud := stderr.String()
+
+	rootCmd.SilenceErrors = true
+	stderr.Reset()
+	rootCmd.ExecuteWithArgs([]string{"loud"})
+
+	return quietErr != nil && loudErr != nil &&
+		quiet == "" &&
+		loud == "Error: boom\n" &&
+		stderr.Len() == 0
+}
+
+// Test the order and inheritance of run hooks
+func testRunHooks() bool {
+	var calls []string
+	hook := func(name string) func(cmd *Command, args []string) {
+		return func(cmd *Command, args []string) {
+			calls = append(calls, name+":"+cmd.Name())
+		}
+	}
+	rootCmd := &Command{
+		Use:               "app",
+		PersistentPreRun:  hook("root-ppre"),
+		PersistentPostRun: hook("root-ppost"),
+	}
+	dbCmd := &Command{Use: "db", PersistentPreRun: hook("db-ppre")}
+	migrateCmd := &Command{
+		Use:     "migrate",
+		PreRun:  hook("pre"),
+		Run:     hook("run"),
+		PostRun: hook("post"),
+		PostRunE: func(cmd *Command, args []string) error {
+			calls = append(calls, "postE:"+cmd.Name())
+			return nil
+		},
+	}
+	dbCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(dbCmd)
+
+	rootCmd.ExecuteWithArgs([]string{"db", "migrate"})
+	nearest := strings.Join(calls, ",")
+
+	EnableTraverseRunHooks = true
+	defer func() { EnableTraverseRunHooks = false }()
+	calls = nil
+	rootCmd.ExecuteWithArgs([]string{"db", "migrate"})
+	traversed := strings.Join(calls, ",")
+
+	return nearest == "db-ppre:migrate,pre:migrate,run:migrate,postE:migrate,root-ppost:migrate" &&
+		traversed == "root-ppre:migrate,db-ppre:migrate,pre:migrate,run:migrate,postE:migrate,root-ppost:migrate"
+}
+
+// Test an error from PersistentPreRunE stops the command from running
+func testPersistentPreRunE() bool {
+	ran := false
+	rootCmd := &Command{
+		Use:           "app",
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *Command, args []string) error {
+			if cmd.GetString("token") == "" {
+				return fmt.Errorf("not logged in")
+			}
+			return nil
+		},
+	}
+	syncCmd := &Command{
+		Use: "sync",
+		Run: func(cmd *Command, args []string) { ran = true },
+	}
+	syncCmd.Flags().String("token", "", "API token")
+	rootCmd.AddCommand(syncCmd)
+	rootCmd.SetErr(&bytes.Buffer{})
+
+	err := rootCmd.ExecuteWithArgs([]string{"sync"})
+	blocked := err != nil && err.Error() == "not logged in" && !ran
+
+	err = rootCmd.ExecuteWithArgs([]string{"sync", "--token=abc"})
+	return blocked && err == nil && ran
+}
+
+// newCompletionApp builds a command tree for the completion tests, with
+// output captured in out
+func newCompletionApp(out *bytes.Buffer) *Command {
+	rootCmd := &Command{Use: "kubectl"}
+	rootCmd.SetOut(out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	getCmd := &Command{
+		Use:       "get [resource]",
+		Short:     "Display resources",
+		ValidArgs: []string{"pods", "services", "nodes"},
+		Run:       func(cmd *Command, args []string) {},
+	}
+	getCmd.Flags().StringP("output", "o", "table", "Output format")
+	getCmd.Flags().Bool("watch", false, "Watch for changes")
+	getCmd.RegisterFlagCompletionFunc("output", FixedCompletions([]string{"json", "yaml", "table"}, ShellCompDirectiveNoFileComp))
+	logsCmd := &Command{
+		Use:   "logs [pod]",
+		Short: "Print pod logs",
+		ValidArgsFunction: func(cmd *Command, args []string, toComplete string) ([]string, ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, ShellCompDirectiveNoFileComp
+			}
+			return []string{"web-1\tRunning", "web-2\tPending"}, ShellCompDirectiveNoFileComp | ShellCompDirectiveKeepOrder
+		},
+		Run: func(cmd *Command, args []string) {},
+	}
+	rootCmd.AddCommand(getCmd, logsCmd)
+	return rootCmd
+}
+
+// Test completing subcommand names and ValidArgs through __complete
+func testCompleteCommands() bool {
+	var out bytes.Buffer
+	rootCmd := newCompletionApp(&out)
+
+	complete := func(args ...string) string {
+		out.Reset()
+		rootCmd.ExecuteWithArgs(append([]string{ShellCompRequestCmd}, args...))
+		return out.String()
+	}
+
+	commands := complete("")
+	withPrefix := complete("g")
+	validArgs := complete("get", "p")
+	noDesc := func() string {
+		out.Reset()
+		rootCmd.ExecuteWithArgs([]string{ShellCompNoDescRequestCmd, "h"})
+		return out.String()
+	}()
+	helpTopics := complete("help", "l")
+
+	return commands == "get\tDisplay resources\nhelp\tHelp about any command\nlogs\tPrint pod logs\n:4\n" &&
+		withPrefix == "get\tDisplay resources\n:4\n" &&
+		validArgs == "pods\n:4\n" &&
+		noDesc == "help\n:4\n" &&
+		helpTopics == "logs\tPrint pod logs\n:4\n"
+}
+
+// Test completing flag names and flag values
+func testCompleteFlags() bool {
+	var out bytes.Buffer
+	rootCmd := newCompletionApp(&out)
+
+	complete := func(args ...string) string {
+		out.Reset()
+		rootCmd.ExecuteWithArgs(append([]string{ShellCompRequestCmd}, args...))
+		return out.String()
+	}
+
+	names := complete("get", "--")
+	values := complete("get", "-o", "j")
+	inline := complete("get", "--output=y")
+	err := newCompletionApp(&out).RegisterFlagCompletionFunc("missing", NoFileCompletions)
+
+	return names == "--help\thelp for get\n--output\tOutput format\n--watch\tWatch for changes\n:4\n" &&
+		values == "json\nyaml\ntable\n:4\n" &&
+		inline == "--output=json\n--output=yaml\n--output=table\n:4\n" &&
+		err != nil && err.Error() == "RegisterFlagCompletionFunc: flag 'missing' does not exist"
+}
+
+// Test ValidArgsFunction and directives
+func testValidArgsFunction() bool {
+	var out, stderr bytes.Buffer
+	rootCmd := newCompletionApp(&out)
+	rootCmd.SetErr(&stderr)
+
+	rootCmd.ExecuteWithArgs([]string{ShellCompRequestCmd, "logs", ""})
+	pods := out.String()
+	out.Reset()
+	rootCmd.ExecuteWithArgs([]string{ShellCompRequestCmd, "logs", "web-1", ""})
+
+	return pods == "web-1\tRunning\nweb-2\tPending\n:36\n" &&
+		out.String() == ":4\n" &&
+		strings.Contains(stderr.String(), "Completion ended with directive: ShellCompDirectiveNoFileComp, ShellCompDirectiveKeepOrder\n")
+}
+
+// Test generating completion scripts for each shell
+func testCompletionScripts() bool {
+	rootCmd := &Command{Use: "my-app"}
+	rootCmd.AddCommand(&Command{Use: "serve", Run: func(cmd *Command, args []string) {}})
+
+	var bash, zsh, fish, fishNoDesc, powershell bytes.Buffer
+	rootCmd.GenBashCompletion(&bash)
+	rootCmd.GenZshCompletion(&zsh)
+	rootCmd.GenFishCompletion(&fish, true)
+	rootCmd.GenFishCompletion(&fishNoDesc, false)
+	rootCmd.Commands()[0].GenPowerShellCompletion(&powershell)
+
+	return strings.Contains(bash.String(), "complete -o default -F __my_app_complete my-app\n") &&
+		strings.Contains(bash.String(), "__completeNoDesc") &&
+		strings.HasPrefix(zsh.String(), "#compdef my-app\ncompdef _my_app my-app\n") &&
+		strings.Contains(fish.String(), "my-app __complete $args") &&
+		strings.Contains(fishNoDesc.String(), "my-app __completeNoDesc $args") &&
+		strings.Contains(powershell.String(), "Register-ArgumentCompleter -CommandName 'my-app'") &&
+		strings.Contains(powershell.String(), "Split(\"`t\", 2)")
+}
+
+// Test flag pointers are updated when flags are parsed
+func testFlagPointers() bool {
+	cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+	name := cmd.Flags().String("name", "", "Name")
+	port := cmd.Flags().IntP("port", "p", 8080, "Port")
+	debug := cmd.Flags().Bool("debug", false, "Debug")
+
+	cmd.ExecuteWithArgs([]string{"--name", "api", "-p", "9000", "--debug", "extra"})
+
+	return *name == "api" && *port == 9000 && *debug &&
+		len(cmd.parsedArgs) == 1 && cmd.parsedArgs[0] == "extra"
+}
+
+// Test StringSlice and IntSlice flags
+func testSliceFlags() bool {
+	cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+	tags := cmd.Flags().StringSliceP("tag", "t", []string{"latest"}, "Image tags")
+	ports := cmd.Flags().IntSlice("port", nil, "Ports")
+	defaults := cmd.Flags().StringSlice("env", []string{"dev"}, "Environments")
+
+	cmd.ExecuteWithArgs([]string{"-t", "v1,v2", "--tag=stable", "--port", "80,443", "--port=8080"})
+
+	ports2 := cmd.GetIntSlice("port")
+	return strings.Join(*tags, " ") == "v1 v2 stable" &&
+		strings.Join(cmd.GetStringSlice("tag"), " ") == "v1 v2 stable" &&
+		len(*ports) == 3 && ports2[0] == 80 && ports2[1] == 443 && ports2[2] == 8080 &&
+		len(*defaults) == 1 && (*defaults)[0] == "dev" &&
+		len(cmd.GetStringSlice("missing")) == 0
+}
+
+// Test Duration and Float64 flags, including invalid values
+func testDurationAndFloatFlags() bool {
+	var ran bool
+	cmd := &Command{Use: "test", SilenceErrors: true, SilenceUsage: true, Run: func(cmd *Command, args []string) { ran = true }}
+	timeout := cmd.Flags().Duration("timeout", 30*time.Second, "Timeout")
+	ratio := cmd.Flags().Float64P("ratio", "r", 0.5, "Sample ratio")
+
+	err := cmd.ExecuteWithArgs([]string{"--timeout=1m30s", "-r", "0.25"})
+	parsed := err == nil && ran && *timeout == 90*time.Second && *ratio == 0.25 &&
+		cmd.GetDuration("timeout") == 90*time.Second && cmd.GetFloat64("ratio") == 0.25
+
+	ran = false
+	err = cmd.ExecuteWithArgs([]string{"--timeout", "soon"})
+	return parsed && !ran && err != nil &&
+		err.Error() == `invalid argument "soon" for "--timeout" flag: time: invalid duration "soon"`
+}
+
+// Test Count flags with repeated shorthands
+func testCountFlag() bool {
+	counts := func(args ...string) int {
+		cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+		cmd.Flags().CountP("verbose", "v", "Verbosity")
+		cmd.ExecuteWithArgs(args)
+		return cmd.GetCount("verbose")
+	}
+
+	return counts() == 0 &&
+		counts("-v") == 1 &&
+		counts("-vvv") == 3 &&
+		counts("--verbose", "-v", "arg") == 2 &&
+		counts("--verbose=5") == 5
+}
+
+// Test StringToString flags
+func testStringToStringFlag() bool {
+	cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+	labels := cmd.Flags().StringToStringP("label", "l", map[string]string{"team": "core"}, "Labels")
+
+	cmd.ExecuteWithArgs([]string{"-l", "app=web,tier=frontend", "--label", "env=prod"})
+
+	m := cmd.GetStringToString("label")
+	return len(*labels) == 3 && m["app"] == "web" && m["tier"] == "frontend" && m["env"] == "prod"
+}
+
+// Test usage output for the typed flags
+func testTypedFlagUsages() bool {
+	cmd := &Command{Use: "test"}
+	cmd.Flags().StringSlice("tag", []string{"a", "b"}, "Tags")
+	cmd.Flags().Duration("timeout", 5*time.Second, "Timeout")
+	cmd.Flags().Float64("ratio", 0, "Ratio")
+	cmd.Flags().CountP("verbose", "v", "Verbosity")
+	cmd.Flags().StringToString("label", map[string]string{"b": "2", "a": "1"}, "Labels")
+
+	usages := cmd.FlagUsages()
+	return strings.Contains(usages, "      --label stringToString   Labels (default [a=1,b=2])\n") &&
+		strings.Contains(usages, "      --ratio float            Ratio\n") &&
+		strings.Contains(usages, "      --tag strings            Tags (default [a,b])\n") &&
+		strings.Contains(usages, "      --timeout duration       Timeout (default 5s)\n") &&
+		strings.Contains(usages, "  -v, --verbose count          Verbosity\n")
+}
+
+// Test MarkFlagRequired
+func testRequiredFlags() bool {
+	run := func(args ...string) error {
+		cmd := &Command{Use: "deploy", SilenceErrors: true, SilenceUsage: true, Run: func(cmd *Command, args []string) {}}
+		cmd.Flags().String("env", "", "Environment")
+		cmd.Flags().String("region", "", "Region")
+		cmd.Flags().Bool("dry-run", false, "Dry run")
+		cmd.MarkFlagRequired("region")
+		cmd.MarkFlagRequired("env")
+		return cmd.ExecuteWithArgs(args)
+	}
+
+	cmd := &Command{Use: "deploy"}
+	missing := cmd.MarkFlagRequired("nope")
+
+	err := run("--dry-run")
+	partial := run("--env", "prod")
+	return err != nil && err.Error() == `required flag(s) "env", "region" not set` &&
+		partial != nil && partial.Error() == `required flag(s) "region" not set` &&
+		run("--env=prod", "--region=eu") == nil &&
+		missing != nil && missing.Error() == "no such flag -nope"
+}
+
+// Test flag group constraints
+func testFlagGroups() bool {
+	run := func(args ...string) error {
+		cmd := &Command{Use: "login", SilenceErrors: true, SilenceUsage: true, Run: func(cmd *Command, args []string) {}}
+		cmd.Flags().String("user", "", "User")
+		cmd.Flags().String("password", "", "Password")
+		cmd.Flags().String("token", "", "Token")
+		cmd.Flags().Bool("json", false, "JSON output")
+		cmd.Flags().Bool("yaml", false, "YAML output")
+		cmd.MarkFlagsRequiredTogether("user", "password")
+		cmd.MarkFlagsOneRequired("user", "token")
+		cmd.MarkFlagsMutuallyExclusive("json", "yaml")
+		return cmd.ExecuteWithArgs(args)
+	}
+
+	together := run("--user", "bob")
+	oneOf := run("--json")
+	exclusive := run("--token", "t", "--json", "--yaml")
+
+	return together != nil && together.Error() == "if any flags in the group [user password] are set they must all be set; missing [password]" &&
+		oneOf != nil && oneOf.Error() == "at least one of the flags in the group [user token] is required" &&
+		exclusive != nil && exclusive.Error() == "if any flags in the group [json yaml] are set none of the others can be; [json yaml] were all set" &&
+		run("--user", "bob", "--password", "pw", "--yaml") == nil &&
+		run("--token", "t") == nil
+}
+
+// Test unknown flags are reported with suggestions
+func testUnknownFlags() bool {
+	var stderr bytes.Buffer
+	ran := false
+	cmd := &Command{Use: "build", Run: func(cmd *Command, args []string) { ran = true }}
+	cmd.Flags().BoolP("verbose", "v", false, "Verbose output")
+	cmd.Flags().String("output", "", "Output file")
+	cmd.SetErr(&stderr)
+
+	typo := cmd.ExecuteWithArgs([]string{"--verbos"})
+	prefix := cmd.ExecuteWithArgs([]string{"--out=bin"})
+	unrelated := cmd.ExecuteWithArgs([]string{"--zzz"})
+	shorthand := cmd.ExecuteWithArgs([]string{"-x"})
+
+	return !ran &&
+		typo != nil && typo.Error() == "unknown flag: --verbos\n\nDid you mean this?\n\t--verbose\n" &&
+		prefix != nil && prefix.Error() == "unknown flag: --out\n\nDid you mean this?\n\t--output\n" &&
+		unrelated != nil && unrelated.Error() == "unknown flag: --zzz" &&
+		shorthand != nil && shorthand.Error() == "unknown shorthand flag: 'x' in -x" &&
+		strings.HasPrefix(stderr.String(), "Error: unknown flag: --verbos\n")
+}
+
+// Test FParseErrWhitelist.UnknownFlags ignores unknown flags
+func testUnknownFlagsWhitelist() bool {
+	var got []string
+	cmd := &Command{
+		Use:                "wrapper",
+		FParseErrWhitelist: FParseErrWhitelist{UnknownFlags: true},
+		Run: func(cmd *Command, args []string) {
+			got = args
+		},
+	}
+	cmd.Flags().Bool("debug", false, "Debug")
+
+	err := cmd.ExecuteWithArgs([]string{"--legacy", "value", "--debug", "-x", "--mode=fast", "file.txt"})
+	return err == nil && cmd.GetBool("debug") && len(got) == 1 && got[0] == "file.txt"
+}
+
+// Test grouped shorthands and shorthand values
+func testGroupedShorthands() bool {
+	cmd := &Command{Use: "tar", SilenceErrors: true, SilenceUsage: true, Run: func(cmd *Command, args []string) {}}
+	extract := cmd.Flags().BoolP("extract", "x", false, "Extract")
+	verbose := cmd.Flags().CountP("verbose", "v", "Verbosity")
+	file := cmd.Flags().StringP("file", "f", "", "Archive file")
+	port := cmd.Flags().IntP("port", "p", 0, "Port")
+
+	err := cmd.ExecuteWithArgs([]string{"-xvvf", "backup.tar", "-p8080"})
+	grouped := err == nil && *extract && *verbose == 2 && *file == "backup.tar" && *port == 8080
+
+	err = cmd.ExecuteWithArgs([]string{"-xfdata.tar", "-p=9090"})
+	inline := err == nil && *file == "data.tar" && *port == 9090
+
+	missing := cmd.ExecuteWithArgs([]string{"-xf"})
+	return grouped && inline &&
+		missing != nil && missing.Error() == "flag needs an argument: 'f' in -f"
+}
+
+// Test explicit boolean values
+func testExplicitBoolValues() bool {
+	cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+	color := cmd.Flags().BoolP("color", "c", true, "Color output")
+	cache := cmd.Flags().Bool("cache", false, "Use cache")
+
+	cmd.ExecuteWithArgs([]string{"--color=false", "--cache=true"})
+	long := !*color && *cache
+
+	cmd.ExecuteWithArgs([]string{"-c=true", "--cache=0"})
+	return long && *color && !*cache
+}
+
+// Test negative numbers are arguments and flag values, not flags
+func testNegativeNumbers() bool {
+	var got []string
+	cmd := &Command{Use: "calc", Run: func(cmd *Command, args []string) { got = args }}
+	offset := cmd.Flags().IntP("offset", "o", 0, "Offset")
+	scale := cmd.Flags().Float64("scale", 1, "Scale")
+
+	err := cmd.ExecuteWithArgs([]string{"--offset", "-5", "--scale", "-0.5", "-3", "4", "-2.5"})
+	long := err == nil && *offset == -5 && *scale == -0.5 && strings.Join(got, " ") == "-3 4 -2.5"
+
+	err = cmd.ExecuteWithArgs([]string{"-o", "-7"})
+	return long && err == nil && *offset == -7 && len(got) == 0
+}
+
+// Test -- ends flag parsing
+func testDashTerminator() bool {
+	var got []string
+	cmd := &Command{Use: "exec", Run: func(cmd *Command, args []string) { got = args }}
+	verbose := cmd.Flags().BoolP("verbose", "v", false, "Verbose")
+
+	err := cmd.ExecuteWithArgs([]string{"-v", "pod", "--", "ls", "-la", "--color=auto"})
+	args := strings.Join(got, " ")
+	dash := cmd.ArgsLenAtDash()
+	cmd.ExecuteWithArgs([]string{"pod"})
+
+	return err == nil && *verbose &&
+		args == "pod ls -la --color=auto" &&
+		dash == 1 && cmd.ArgsLenAtDash() == -1
+}
+
+// Test command aliases
+func testAliases() bool {
+	var out bytes.Buffer
+	ran := 0
+	rootCmd := &Command{Use: "kubectl"}
+	rootCmd.SetOut(&out)
+	getCmd := &Command{
+		Use:     "get",
+		Aliases: []string{"g", "fetch"},
+		Short:   "Display resources",
+		Run:     func(cmd *Command, args []string) { ran++ },
+	}
+	rootCmd.AddCommand(getCmd)
+
+	rootCmd.ExecuteWithArgs([]string{"g", "pods"})
+	rootCmd.ExecuteWithArgs([]string{"fetch"})
+	rootCmd.ExecuteWithArgs([]string{"help", "fetch"})
+
+	return ran == 2 && getCmd.HasAlias("g") && !getCmd.HasAlias("get") &&
+		strings.Contains(out.String(), "Aliases:\n  get, g, fetch\n")
+}
+
+// Test "did you mean" suggestions for unknown commands
+func testCommandSuggestions() bool {
+	run := func(rootCmd *Command, args ...string) string {
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+		return rootCmd.ExecuteWithArgs(args).Error()
+	}
+	newApp := func() *Command {
+		rootCmd := &Command{Use: "app"}
+		noop := func(cmd *Command, args []string) {}
+		rootCmd.AddCommand(
+			&Command{Use: "status", Run: noop},
+			&Command{Use: "stash", Run: noop},
+			&Command{Use: "remove", SuggestFor: []string{"delete", "rm"}, Run: noop},
+		)
+		return rootCmd
+	}
+
+	far := newApp()
+	far.SuggestionsMinimumDistance = 3
+	disabled := newApp()
+	disabled.DisableSuggestions = true
+
+	return run(newApp(), "sttus") == "unknown command \"sttus\" for \"app\"\n\nDid you mean this?\n\tstatus\n" &&
+		run(newApp(), "sta") == "unknown command \"sta\" for \"app\"\n\nDid you mean this?\n\tstatus\n\tstash\n" &&
+		run(newApp(), "delete") == "unknown command \"delete\" for \"app\"\n\nDid you mean this?\n\tremove\n" &&
+		run(far, "sttus") == "unknown command \"sttus\" for \"app\"\n\nDid you mean this?\n\tstatus\n\tstash\n" &&
+		run(disabled, "sttus") == `unknown command "sttus" for "app"`
+}
+
+// Test the --version flag
+func testVersionFlag() bool {
+	var out bytes.Buffer
+	ran := false
+	rootCmd := &Command{Use: "app", Version: "1.4.2", Run: func(cmd *Command, args []string) { ran = true }}
+	rootCmd.SetOut(&out)
+	rootCmd.ExecuteWithArgs([]string{"--version"})
+	long := out.String()
+	out.Reset()
+	rootCmd.ExecuteWithArgs([]string{"-v"})
+	short := out.String()
+
+	verboseCmd := &Command{Use: "tool", Version: "0.1.0", Run: func(cmd *Command, args []string) {}}
+	verboseCmd.Flags().BoolP("verbose", "v", false, "Verbose")
+	verboseCmd.SetOut(&out)
+	out.Reset()
+	verboseCmd.ExecuteWithArgs([]string{"-v"})
+	verbose := out.String()
+	verboseCmd.ExecuteWithArgs([]string{"--version"})
+
+	return !ran && long == "app version 1.4.2\n" && short == long &&
+		verbose == "" && out.String() == "tool version 0.1.0\n" &&
+		strings.Contains(verboseCmd.FlagUsages(), "      --version   version for tool\n")
+}
+
+// Test custom version templates with build metadata
+func testVersionTemplate() bool {
+	var out bytes.Buffer
+	rootCmd := &Command{
+		Use:         "app",
+		Version:     "2.0.0",
+		Annotations: map[string]string{"commit": "a1b2c3d", "date": "2024-05-01"},
+	}
+	rootCmd.SetVersionTemplate(`{{.Name}} {{.Version}} (commit {{index .Annotations "commit"}}, built {{index .Annotations "date"}})` + "\n")
+	subCmd := &Command{Use: "server", Version: "2.0.0-server", Run: func(cmd *Command, args []string) {}}
+	rootCmd.AddCommand(subCmd)
+	rootCmd.SetOut(&out)
+
+	rootCmd.ExecuteWithArgs([]string{"--version"})
+	root := out.String()
+	out.Reset()
+	rootCmd.ExecuteWithArgs([]string{"server", "--version"})
+
+	return root == "app 2.0.0 (commit a1b2c3d, built 2024-05-01)\n" &&
+		out.String() == "server 2.0.0-server (commit , built )\n"
+}
+
+// Test hidden and deprecated commands
+func testHiddenAndDeprecatedCommands() bool {
+	var out bytes.Buffer
+	ran := []string{}
+	record := func(cmd *Command, args []string) { ran = append(ran, cmd.Name()) }
+	rootCmd := &Command{Use: "app"}
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.AddCommand(
+		&Command{Use: "serve", Short: "Start the server", Run: record},
+		&Command{Use: "debug-dump", Short: "Dump internals", Hidden: true, Run: record},
+		&Command{Use: "start", Short: "Start the server", Deprecated: `use "serve" instead`, Run: record},
+	)
+
+	rootCmd.ExecuteWithArgs([]string{"--help"})
+	help := out.String()
+	out.Reset()
+	rootCmd.ExecuteWithArgs([]string{"debug-dump"})
+	rootCmd.ExecuteWithArgs([]string{"start"})
+	warning := out.String()
+	out.Reset()
+	rootCmd.ExecuteWithArgs([]string{ShellCompNoDescRequestCmd, ""})
+	completions := out.String()
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	suggestion := rootCmd.ExecuteWithArgs([]string{"stat"})
+
+	return strings.Contains(help, "  serve       Start the server\n") &&
+		!strings.Contains(help, "debug-dump") && !strings.Contains(help, "start ") &&
+		strings.Join(ran, ",") == "debug-dump,start" &&
+		warning == "Command \"start\" is deprecated, use \"serve\" instead\n" &&
+		completions == "help\nserve\n:4\n" &&
+		suggestion != nil && suggestion.Error() == `unknown command "stat" for "app"`
+}
+
+// Test hidden and deprecated flags
+func testHiddenAndDeprecatedFlags() bool {
+	var out bytes.Buffer
+	cmd := &Command{Use: "build", Run: func(cmd *Command, args []string) {}}
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	output := cmd.Flags().StringP("output", "o", "", "Output file")
+	cmd.Flags().String("out", "", "Output file")
+	cmd.Flags().Bool("trace", false, "Trace internals")
+	cmd.Flags().BoolP("quiet", "q", false, "Quiet")
+	cmd.Flags().MarkDeprecated("out", "use --output instead")
+	cmd.Flags().MarkHidden("trace")
+	cmd.Flags().MarkShorthandDeprecated("quiet", "use --quiet instead")
+	empty := cmd.Flags().MarkDeprecated("output", "")
+	missing := cmd.Flags().MarkHidden("nope")
+
+	usages := cmd.FlagUsages()
+	cmd.ExecuteWithArgs([]string{"--out", "a.bin", "--trace", "-q", "--quiet"})
+	warnings := out.String()
+	out.Reset()
+	cmd.ExecuteWithArgs([]string{ShellCompNoDescRequestCmd, "-"})
+
+	return !strings.Contains(usages, "--out ") && !strings.Contains(usages, "--trace") &&
+		strings.Contains(usages, "      --quiet ") && strings.Contains(usages, "  -o, --output string") &&
+		cmd.GetString("out") == "a.bin" && *output == "" && cmd.GetBool("trace") &&
+		warnings == "Flag --out has been deprecated, use --output instead\nFlag shorthand -q has been deprecated, use --quiet instead\n" &&
+		out.String() == "--help\n-h\n--output\n-o\n:4\n" &&
+		empty != nil && empty.Error() == `deprecated message for flag "output" must be set` &&
+		missing != nil
+}
+
+// Test Print methods go through the configured writers
+func testOutputWriters() bool {
+	var out, errOut bytes.Buffer
+	rootCmd := &Command{Use: "app"}
+	subCmd := &Command{
+		Use: "sync",
+		Run: func(cmd *Command, args []string) {
+			cmd.Printf("synced %d files\n", 3)
+			cmd.Println("done")
+			cmd.Print("bye")
+			cmd.PrintErrf("warning: %s\n", "slow")
+			cmd.PrintErrln("skipped", 1)
+			cmd.PrintErr("!")
+		},
+	}
+	rootCmd.AddCommand(subCmd)
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+
+	rootCmd.ExecuteWithArgs([]string{"sync"})
+
+	return out.String() == "synced 3 files\ndone\nbye" &&
+		errOut.String() == "warning: slow\nskipped 1\n!" &&
+		subCmd.OutOrStdout() == &out && subCmd.OutOrStderr() == &out && subCmd.ErrOrStderr() == &errOut
+}
+
+// Test custom usage templates and usage functions
+func testUsageTemplateAndFunc() bool {
+	var out, errOut bytes.Buffer
+	newApp := func() (*Command, *Command) {
+		rootCmd := &Command{Use: "app", Short: "My app", SilenceErrors: true}
+		subCmd := &Command{Use: "run", Args: ExactArgs(1), Run: func(cmd *Command, args []string) {}}
+		rootCmd.AddCommand(subCmd)
+		rootCmd.SetOut(&out)
+		rootCmd.SetErr(&errOut)
+		rootCmd.SetUsageTemplate("USAGE: {{.UseLine}}\n")
+		return rootCmd, subCmd
+	}
+
+	rootCmd, _ := newApp()
+	rootCmd.ExecuteWithArgs([]string{"run", "--help"})
+	rootCmd, _ = newApp()
+	rootCmd.ExecuteWithArgs([]string{"run"})
+
+	calls := 0
+	rootCmd, subCmd := newApp()
+	subCmd.SetUsageFunc(func(cmd *Command) error {
+		calls++
+		fmt.Fprintf(cmd.OutOrStderr(), "see the manual for %s\n", cmd.CommandPath())
+		return nil
+	})
+	usage := subCmd.UsageString()
+
+	return out.String() == "USAGE: app run [flags]\n" &&
+		errOut.String() == "USAGE: app run [flags]\n\n" &&
+		usage == "see the manual for app run\n" && calls == 1 &&
+		rootCmd.UsageString() == "USAGE: app\n"
+}
+
+// Test SetErrPrefix, SetFlagErrorFunc and SetHelpFunc
+func testErrorCustomization() bool {
+	var out, errOut bytes.Buffer
+	rootCmd := &Command{Use: "app", SilenceUsage: true, Run: func(cmd *Command, args []string) {}}
+	rootCmd.Flags().Int("port", 8080, "Port")
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&errOut)
+	rootCmd.SetErrPrefix("app failed:")
+	rootCmd.SetFlagErrorFunc(func(cmd *Command, err error) error {
+		return fmt.Errorf("%v (see '%s --help')", err, cmd.CommandPath())
+	})
+	rootCmd.SetHelpFunc(func(cmd *Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), "custom help for", cmd.Name())
+	})
+
+	err := rootCmd.ExecuteWithArgs([]string{"--prot", "80"})
+	rootCmd.ExecuteWithArgs([]string{"-h"})
+
+	return err != nil && strings.HasSuffix(err.Error(), "(see 'app --help')") &&
+		strings.HasPrefix(errOut.String(), "app failed: unknown flag: --prot") &&
+		out.String() == "custom help for app\n"
+}
+
+// Test ExecuteContext passes the context to the executed command
+func testExecuteContext() bool {
+	type ctxKey string
+	var hookValue, runValue interface{}
+	rootCmd := &Command{
+		Use: "app",
+		PersistentPreRun: func(cmd *Command, args []string) {
+			hookValue = cmd.Context().Value(ctxKey("request-id"))
+		},
+	}
+	subCmd := &Command{
+		Use: "fetch",
+		Run: func(cmd *Command, args []string) {
+			runValue = cmd.Context().Value(ctxKey("request-id"))
+		},
+	}
+	rootCmd.AddCommand(subCmd)
+
+	ctx := context.WithValue(context.Background(), ctxKey("request-id"), "req-42")
+	rootCmd.SetArgs([]string{"fetch"})
+	err := rootCmd.ExecuteContext(ctx)
+
+	plain := &Command{Use: "plain", Run: func(cmd *Command, args []string) {}}
+	unset := plain.Context() == nil
+	plain.ExecuteWithArgs([]string{})
+
+	return err == nil && hookValue == "req-42" && runValue == "req-42" &&
+		subCmd.Context() == ctx && unset && plain.Context() == context.Background()
+}
+
+// Test commands can observe cancellation and deadlines
+func testContextCancellation() bool {
+	cmd := &Command{
+		Use:           "wait",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		RunE: func(cmd *Command, args []string) error {
+			select {
+			case <-cmd.Context().Done():
+				return cmd.Context().Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		},
+	}
+	cmd.SetArgs([]string{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := cmd.ExecuteContext(ctx)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	timedOut := cmd.ExecuteContext(ctx)
+
+	return canceled == context.Canceled && timedOut == context.DeadlineExceeded
+}
+
+// newDocsApp builds a command tree for the doc generation tests
+func newDocsApp() *Command {
+	rootCmd := &Command{Use: "app", Short: "My application", Long: "App manages the app server.", DisableAutoGenTag: true}
+	serveCmd := &Command{
+		Use:     "serve [dir]",
+		Short:   "Start the server",
+		Example: "  app serve ./public --port 9000",
+		Run:     func(cmd *Command, args []string) {},
+	}
+	serveCmd.Flags().IntP("port", "p", 8080, "Port to listen on")
+	serveCmd.Flags().String("host", "localhost", "Host to bind")
+	configCmd := &Command{Use: "config", Short: "Manage configuration"}
+	configCmd.AddCommand(&Command{Use: "get [key]", Short: "Print a value", Run: func(cmd *Command, args []string) {}})
+	rootCmd.AddCommand(serveCmd, configCmd, &Command{Use: "internal", Hidden: true, Run: func(cmd *Command, args []string) {}})
+	return rootCmd
+}
+
+// Test Markdown generation for a single command
+func testGenMarkdown() bool {
+	var buf bytes.Buffer
+	rootCmd := newDocsApp()
+	GenMarkdown(rootCmd.Commands()[2], &buf)
+
+	expected := "## app serve\n\n" +
+		"Start the server\n\n" +
+		"```\napp serve [dir] [flags]\n```\n\n" +
+		"### Examples\n\n```\n  app serve ./public --port 9000\n```\n\n" +
+		"### Options\n\n```\n" +
+		"  -h, --help          help for serve\n" +
+		"      --host string   Host to bind (default \"localhost\")\n" +
+		"  -p, --port int      Port to listen on (default 8080)\n" +
+		"```\n\n" +
+		"### SEE ALSO\n\n" +
+		"* [app](app.md)\t - My application\n\n"
+	return buf.String() == expected
+}
+
+// Test GenMarkdownTree and GenManTree write a file per available command
+func testGenDocTrees() bool {
+	dir, err := os.MkdirTemp("", "cobra-docs")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(dir)
+
+	rootCmd := newDocsApp()
+	if err := GenMarkdownTree(rootCmd, dir); err != nil {
+		return false
+	}
+	date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if err := GenManTree(rootCmd, &GenManHeader{Section: "8", Date: &date}, dir); err != nil {
+		return false
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	root, _ := os.ReadFile(filepath.Join(dir, "app.md"))
+	man, _ := os.ReadFile(filepath.Join(dir, "app-serve.8"))
+
+	return strings.Join(names, " ") == "app-config-get.8 app-config.8 app-serve.8 app.8 app.md app_config.md app_config_get.md app_serve.md" &&
+		strings.Contains(string(root), "### Synopsis\n\nApp manages the app server.\n\n") &&
+		strings.Contains(string(root), "* [app config](app_config.md)\t - Manage configuration\n* [app serve](app_serve.md)\t - Start the server\n") &&
+		!strings.Contains(string(root), "internal") &&
+		strings.HasPrefix(string(man), ".nh\n.TH \"APP-SERVE\" \"8\" \"Mar 2024\" \"\" \"\"\n") &&
+		strings.Contains(string(man), ".SH NAME\napp-serve \\- Start the server\n") &&
+		strings.Contains(string(man), ".PP\n\\fB\\-p\\fP, \\fB\\-\\-port\\fP=8080\n.RS 4\nPort to listen on\n.RE\n") &&
+		strings.Contains(string(man), ".SH SEE ALSO\n\\fBapp(8)\\fP\n")
+}
+
+// memDocFs is a DocFs keeping files in a map
+type memDocFs map[string]string
+
+func (m memDocFs) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	m[filename] = string(data)
+	return nil
+}
+
+// Test GenMarkdownTreeFs and GenManTreeFs write to a DocFs instead of the disk
+func testGenDocTreesFs() bool {
+	rootCmd := newDocsApp()
+	fs := memDocFs{}
+	if err := GenMarkdownTreeFs(rootCmd, fs, "/docs"); err != nil {
+		return false
+	}
+	if err := GenManTreeFs(rootCmd, nil, fs, "/man"); err != nil {
+		return false
+	}
+	var names []string
+	for name := range fs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ") == "/docs/app.md /docs/app_config.md /docs/app_config_get.md /docs/app_serve.md /man/app-config-get.1 /man/app-config.1 /man/app-serve.1 /man/app.1" &&
+		strings.HasPrefix(fs["/docs/app_serve.md"], "## app serve\n\nStart the server\n\n")
+}
+
+// configStore mimics Viper's precedence: set values over defaults
+type configStore struct {
+	values   map[string]interface{}
+	defaults map[string]interface{}
+}
+
+func newConfigStore() *configStore {
+	return &configStore{values: map[string]interface{}{}, defaults: map[string]interface{}{}}
+}
+
+func (s *configStore) Set(key string, value interface{}) { s.values[key] = value }
+
+func (s *configStore) SetDefault(key string, value interface{}) { s.defaults[key] = value }
+
+func (s *configStore) Get(key string) interface{} {
+	if v, ok := s.values[key]; ok {
+		return v
+	}
+	return s.defaults[key]
+}
+
+// Test binding flags to a Viper-like store
+func testBindFlagsToViper() bool {
+	newApp := func() (*Command, *configStore) {
+		root := &Command{Use: "app"}
+		root.PersistentFlags().String("config", "", "config file")
+		serve := &Command{Use: "serve", Run: func(cmd *Command, args []string) {}}
+		serve.Flags().Int("port", 8080, "port")
+		serve.Flags().String("host", "localhost", "host")
+		serve.Flags().StringSlice("tags", nil, "tags")
+		root.AddCommand(serve)
+
+		store := newConfigStore()
+		BindFlagsToViper(root, store)
+		return root, store
+	}
+
+	// Defaults are registered up front, so config values win over them
+	root, store := newApp()
+	if store.Get("port") != 8080 || store.Get("config") != "" {
+		return false
+	}
+	store.Set("host", "example.com")
+	if err := root.ExecuteWithArgs([]string{"serve"}); err != nil {
+		return false
+	}
+	if store.Get("host") != "example.com" || store.Get("port") != 8080 {
+		return false
+	}
+	if _, ok := store.defaults["help"]; ok {
+		return false
+	}
+
+	// Changed flags override config
+	root, store = newApp()
+	store.Set("port", 9000)
+	if err := root.ExecuteWithArgs([]string{"serve", "--port", "7000", "--tags", "a,b"}); err != nil {
+		return false
+	}
+	tags, _ := store.Get("tags").([]string)
+	return store.Get("port") == 7000 && len(tags) == 2 && tags[1] == "b" &&
+		store.Get("host") == "localhost"
+}
+
+// Test text, password, confirm and select prompts
+func testPrompts() bool {
+	var out bytes.Buffer
+	in := strings.NewReader("alice\n\nhunter2\nmaybe\nY\n\n7\nstaging\n")
+	p := NewPrompter(in, &out)
+
+	name, err := p.Input("Name", "bob")
+	if err != nil || name != "alice" {
+		return false
+	}
+	region, err := p.Input("Region", "us-east")
+	if err != nil || region != "us-east" {
+		return false
+	}
+	secret, err := p.Password("Password")
+	if err != nil || secret != "hunter2" || strings.Contains(out.String(), "hunter2") {
+		return false
+	}
+	ok, err := p.Confirm("Continue?", false)
+	if err != nil || !ok || !strings.Contains(out.String(), "Please answer y or n.") {
+		return false
+	}
+	ok, err = p.Confirm("Overwrite?", false)
+	if err != nil || ok || !strings.Contains(out.String(), "Overwrite? [y/N]: ") {
+		return false
+	}
+	i, env, err := p.Select("Environment", []string{"dev", "staging", "prod"})
+	if err != nil || i != 1 || env != "staging" {
+		return false
+	}
+	if !strings.Contains(out.String(), "  3) prod\n") || !strings.Contains(out.String(), `Invalid choice "7".`) {
+		return false
+	}
+
+	// Running out of input is an error, but a last unterminated line is not
+	p = NewPrompter(strings.NewReader("last"), &out)
+	if answer, err := p.Input("Last", ""); err != nil || answer != "last" {
+		return false
+	}
+	_, err = p.Confirm("More?", true)
+	return err != nil
+}
+
+// Test scripting an interactive command through SetIn
+func testPromptWizard() bool {
+	var project string
+	var useGit bool
+	root := &Command{Use: "app"}
+	initCmd := &Command{
+		Use: "init",
+		RunE: func(cmd *Command, args []string) error {
+			p := cmd.Prompter()
+			var err error
+			if project, err = p.Input("Project name", "demo"); err != nil {
+				return err
+			}
+			useGit, err = p.Confirm("Initialize git?", true)
+			return err
+		},
+	}
+	root.AddCommand(initCmd)
+
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetIn(strings.NewReader("myproj\nno\n"))
+	if err := root.ExecuteWithArgs([]string{"init"}); err != nil {
+		return false
+	}
+	return project == "myproj" && !useGit &&
+		out.String() == "Project name [demo]: Initialize git? [Y/n]: "
+}
+
+// Test flags falling back to environment variables
+func testFlagEnvVars() bool {
+	newCmd := func() *Command {
+		cmd := &Command{Use: "deploy", Run: func(cmd *Command, args []string) {}}
+		cmd.Flags().String("token", "", "API token")
+		cmd.Flags().IntP("replicas", "r", 1, "replica count")
+		cmd.Flags().SetEnvVar("token", "EMU_TEST_TOKEN")
+		cmd.Flags().SetEnvVar("replicas", "EMU_TEST_REPLICAS")
+		cmd.MarkFlagRequired("token")
+		return cmd
+	}
+	defer os.Unsetenv("EMU_TEST_TOKEN")
+	defer os.Unsetenv("EMU_TEST_REPLICAS")
+
+	// The environment satisfies required flags
+	os.Setenv("EMU_TEST_TOKEN", "s3cret")
+	os.Setenv("EMU_TEST_REPLICAS", "3")
+	cmd := newCmd()
+	if err := cmd.ExecuteWithArgs([]string{}); err != nil {
+		return false
+	}
+	if cmd.GetString("token") != "s3cret" || cmd.GetInt("replicas") != 3 {
+		return false
+	}
+
+	// The command line wins over the environment
+	cmd = newCmd()
+	if err := cmd.ExecuteWithArgs([]string{"-r", "5"}); err != nil || cmd.GetInt("replicas") != 5 {
+		return false
+	}
+
+	// Bad values name the variable
+	os.Setenv("EMU_TEST_REPLICAS", "many")
+	cmd = newCmd()
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	err := cmd.ExecuteWithArgs([]string{})
+	if err == nil || !strings.Contains(err.Error(), "(from $EMU_TEST_REPLICAS)") {
+		return false
+	}
+
+	// Unset variables leave the default; usage documents the variable
+	os.Unsetenv("EMU_TEST_TOKEN")
+	os.Unsetenv("EMU_TEST_REPLICAS")
+	cmd = newCmd()
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	if err := cmd.ExecuteWithArgs([]string{}); err == nil {
+		return false
+	}
+	usage := cmd.FlagUsages()
+	return cmd.GetInt("replicas") == 1 &&
+		strings.Contains(usage, "replica count (default 1) [$EMU_TEST_REPLICAS]") &&
+		strings.Contains(usage, "API token [$EMU_TEST_TOKEN]") &&
+		cmd.Flags().SetEnvVar("missing", "X") != nil
+}
+
+// Test the pflag-style FlagSet API
+func testFlagSetLookupAndVisit() bool {
+	cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+	cmd.Flags().StringP("name", "n", "anon", "name")
+	cmd.Flags().Int("port", 80, "port")
+	cmd.Flags().StringSlice("tags", []string{"x"}, "tags")
+	if err := cmd.ExecuteWithArgs([]string{"a", "-n", "bob", "b", "--tags", "p,q"}); err != nil {
+		return false
+	}
+
+	fs := cmd.Flags()
+	name := fs.Lookup("name")
+	if name == nil || name.Value.String() != "bob" || name.Value.Type() != "string" || fs.Lookup("nope") != nil {
+		return false
+	}
+	if fs.ShorthandLookup("n") != name || fs.Lookup("tags").Value.String() != "[p,q]" {
+		return false
+	}
+	if !fs.Changed("name") || fs.Changed("port") || fs.Changed("nope") {
+		return false
+	}
+	if fs.NArg() != 2 || fs.Arg(1) != "b" || fs.Arg(2) != "" || len(fs.Args()) != 2 {
+		return false
+	}
+
+	if err := fs.Set("port", "8080"); err != nil || cmd.GetInt("port") != 8080 || !fs.Changed("port") {
+		return false
+	}
+	if err := fs.Set("port", "http"); err == nil || cmd.GetInt("port") != 8080 {
+		return false
+	}
+	if err := fs.Set("nope", "1"); err == nil || err.Error() != "no such flag -nope" {
+		return false
+	}
+
+	var all, changed []string
+	fs.VisitAll(func(f *Flag) { all = append(all, f.Name) })
+	fs.Visit(func(f *Flag) { changed = append(changed, f.Name+"="+f.Value.String()) })
+	return strings.Join(all, ",") == "help,name,port,tags" &&
+		strings.Join(changed, " ") == "name=bob port=8080 tags=[p,q]" && fs.NFlag() == 3
+}
+
+// levelValue is a custom flag Value accepting a fixed set of levels
+type levelValue string
+
+func (l *levelValue) String() string { return string(*l) }
+
+func (l *levelValue) Set(s string) error {
+	switch s {
+	case "debug", "info", "warn":
+		*l = levelValue(s)
+		return nil
+	}
+	return fmt.Errorf("must be one of debug, info, warn")
+}
+
+func (l *levelValue) Type() string { return "level" }
+
+// Test custom flag values added with Var
+func testCustomFlagValues() bool {
+	level := levelValue("info")
+	cmd := &Command{Use: "test", Run: func(cmd *Command, args []string) {}}
+	cmd.Flags().VarP(&level, "level", "l", "log level")
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	if err := cmd.ExecuteWithArgs([]string{"-l", "debug"}); err != nil || level != "debug" {
+		return false
+	}
+	if !strings.Contains(cmd.FlagUsages(), "-l, --level level   log level (default info)") {
+		return false
+	}
+	err := cmd.ExecuteWithArgs([]string{"--level", "loud"})
+	return err != nil && strings.Contains(err.Error(), `invalid argument "loud" for "-l, --level" flag`)
+}
+
+// Test grouping subcommands under headings in help
+func testCommandGroups() bool {
+	noop := func(cmd *Command, args []string) {}
+	root := &Command{Use: "app"}
+	root.AddGroup(
+		&Group{ID: "manage", Title: "Management Commands:"},
+		&Group{ID: "debug", Title: "Debug Commands:"},
+	)
+	root.AddCommand(
+		&Command{Use: "volume", Short: "Manage volumes", GroupID: "manage", Run: noop},
+		&Command{Use: "image", Short: "Manage images", GroupID: "manage", Run: noop},
+		&Command{Use: "trace", Short: "Trace calls", GroupID: "debug", Run: noop},
+		&Command{Use: "version", Short: "Show version", Run: noop},
+	)
+
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.ExecuteWithArgs([]string{"--help"})
+	expected := "Management Commands:\n" +
+		"  image       Manage images\n" +
+		"  volume      Manage volumes\n" +
+		"\n" +
+		"Debug Commands:\n" +
+		"  trace       Trace calls\n" +
+		"\n" +
+		"Additional Commands:\n" +
+		"  help        Help about any command\n" +
+		"  version     Show version\n"
+	if !strings.Contains(out.String(), expected) || root.AllChildCommandsHaveGroup() {
+		return false
+	}
+
+	// Grouping help as well leaves no additional commands
+	root.SetHelpCommandGroupID("debug")
+	root.Commands()[3].GroupID = "manage"
+	out.Reset()
+	root.ExecuteWithArgs([]string{"help"})
+	if strings.Contains(out.String(), "Additional Commands:") ||
+		!strings.Contains(out.String(), "Debug Commands:\n  help        Help about any command\n  trace") {
+		return false
+	}
+
+	// Undefined groups are a programming error
+	bad := &Command{Use: "app"}
+	bad.AddCommand(&Command{Use: "run", GroupID: "nope", Run: noop})
+	panicked := func() (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		bad.ExecuteWithArgs([]string{"run"})
+		return
+	}()
+	return panicked
+}
+
+// Test parsing parent flags before subcommand names
+func testTraverseChildren() bool {
+	var config string
+	var verbose bool
+	var port int
+	var runArgs []string
+	newApp := func(traverse bool) *Command {
+		root := &Command{Use: "app", TraverseChildren: traverse}
+		root.PersistentFlags().String("config", "", "config file")
+		root.PersistentFlags().BoolP("verbose", "v", false, "verbose")
+		remote := &Command{Use: "remote", Aliases: []string{"r"}}
+		add := &Command{
+			Use: "add",
+			Run: func(cmd *Command, args []string) {
+				config = cmd.Root().GetString("config")
+				verbose = cmd.Root().GetBool("verbose")
+				port = cmd.GetInt("port")
+				runArgs = args
+			},
+		}
+		add.Flags().Int("port", 0, "port")
+		remote.AddCommand(add)
+		root.AddCommand(remote)
+		root.SilenceErrors = true
+		root.SilenceUsage = true
+		return root
+	}
+
+	err := newApp(true).ExecuteWithArgs([]string{"--config", "x.json", "-v", "r", "add", "--port", "22", "origin"})
+	if err != nil || config != "x.json" || !verbose || port != 22 ||
+		len(runArgs) != 1 || runArgs[0] != "origin" {
+		return false
+	}
+
+	// Flag values that look like subcommands stay flag values
+	err = newApp(true).ExecuteWithArgs([]string{"--config=remote", "remote", "add"})
+	if err != nil || config != "remote" {
+		return false
+	}
+
+	// Unknown flags fail on the command they follow
+	err = newApp(true).ExecuteWithArgs([]string{"--port", "1", "remote", "add"})
+	if err == nil || err.Error() != "unknown flag: --port" {
+		return false
+	}
+
+	// Without TraverseChildren the subcommand isn't found
+	runArgs = nil
+	newApp(false).ExecuteWithArgs([]string{"--config", "x.json", "remote", "add"})
+	return runArgs == nil
+}
+
+// Test completing command lines programmatically
+func testCompletionHarness() bool {
+	var out bytes.Buffer
+	root := newCompletionApp(&out)
+
+	res, err := CompleteLine(root, "kubectl ")
+	if err != nil || strings.Join(res.Completions, ",") != "get,help,logs" ||
+		res.Descriptions["get"] != "Display resources" || !res.HasDirective(ShellCompDirectiveNoFileComp) {
+		return false
+	}
+
+	// Completing a flag value marks --output as changed only while completing
+	res, err = CompleteLine(root, "kubectl get -o ")
+	if err != nil || strings.Join(res.Completions, ",") != "json,yaml,table" {
+		return false
+	}
+	res, err = Complete(root, "get", "--output", "json", "--")
+	if err != nil || res.Contains("--output") || !res.Contains("--watch") {
+		return false
+	}
+	res, err = Complete(root, "get", "--")
+	if err != nil || !res.Contains("--output") || root.Commands()[0].GetString("output") != "table" {
+		return false
+	}
+
+	res, err = CompleteLine(root, "kubectl logs w")
+	if err != nil || len(res.Completions) != 2 || res.Descriptions["web-2"] != "Pending" ||
+		!res.HasDirective(ShellCompDirectiveNoFileComp|ShellCompDirectiveKeepOrder) {
+		return false
+	}
+	res, err = CompleteLine(root, "kubectl get pods ")
+	return err == nil && len(res.Completions) == 0 && res.Directive == ShellCompDirectiveDefault &&
+		out.Len() == 0
+}
+
+// Test middleware wrapping command runs
+func testMiddleware() bool {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next RunFunc) RunFunc {
+			return func(cmd *Command, args []string) error {
+				calls = append(calls, name+">"+cmd.Name())
+				err := next(cmd, args)
+				calls = append(calls, name+"<")
+				return err
+			}
+		}
+	}
+	requireToken := func(next RunFunc) RunFunc {
+		return func(cmd *Command, args []string) error {
+			if cmd.Annotations["auth"] == "required" && cmd.Root().GetString("token") == "" {
+				return fmt.Errorf("%s requires --token", cmd.CommandPath())
+			}
+			return next(cmd, args)
+		}
+	}
+
+	root := &Command{Use: "app", TraverseChildren: true, SilenceErrors: true, SilenceUsage: true}
+	root.Flags().String("token", "", "token")
+	deploy := &Command{
+		Use:         "deploy",
+		Annotations: map[string]string{"auth": "required"},
+		PreRun:      func(cmd *Command, args []string) { calls = append(calls, "prerun") },
+		Run:         func(cmd *Command, args []string) { calls = append(calls, "run") },
+	}
+	deploy.AddMiddleware(trace("deploy"))
+	root.AddCommand(deploy)
+	root.AddMiddleware(trace("outer"), requireToken, trace("inner"))
+
+	if err := root.ExecuteWithArgs([]string{"--token", "t", "deploy"}); err != nil {
+		return false
+	}
+	expected := "outer>deploy inner>deploy deploy>deploy prerun run deploy< inner< outer<"
+	if strings.Join(calls, " ") != expected {
+		return false
+	}
+
+	// Middleware can stop the run; help doesn't go through it
+	calls = nil
+	root.Flags().Set("token", "")
+	err := root.ExecuteWithArgs([]string{"deploy"})
+	if err == nil || err.Error() != "app deploy requires --token" || strings.Join(calls, " ") != "outer>deploy outer<" {
+		return false
+	}
+	calls = nil
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.ExecuteWithArgs([]string{"deploy", "--help"})
+	return len(calls) == 0
+}
+
+// Test recovering from panics in commands
+func testRecoverPanics() bool {
+	root := &Command{Use: "app", SilenceErrors: true, SilenceUsage: true}
+	root.AddCommand(&Command{
+		Use: "crash",
+		Run: func(cmd *Command, args []string) { panic("boom") },
+	})
+	root.AddMiddleware(RecoverPanics)
+	err := root.ExecuteWithArgs([]string{"crash"})
+	return err != nil && err.Error() == `panic in "app crash": boom`
+}
+
 func main() {
 	fmt.Println("Running Cobra Emulator Tests...")
 	fmt.Println("==============================")
@@ -403,6 +1891,57 @@ func main() {
 	runTest("IntP Flag", testIntPFlag)
 	runTest("BoolP Flag", testBoolPFlag)
 	runTest("NewRootCommand", testNewRootCommand)
+	runTest("Help Output", testHelpOutput)
+	runTest("Help Template", testHelpTemplate)
+	runTest("Args Validators", testArgsValidators)
+	runTest("Valid Args", testValidArgs)
+	runTest("RunE", testRunE)
+	runTest("Silence Errors And Usage", testSilenceErrorsAndUsage)
+	runTest("Run Hooks", testRunHooks)
+	runTest("PersistentPreRunE", testPersistentPreRunE)
+	runTest("Complete Commands", testCompleteCommands)
+	runTest("Complete Flags", testCompleteFlags)
+	runTest("ValidArgsFunction", testValidArgsFunction)
+	runTest("Completion Scripts", testCompletionScripts)
+	runTest("Flag Pointers", testFlagPointers)
+	runTest("Slice Flags", testSliceFlags)
+	runTest("Duration And Float Flags", testDurationAndFloatFlags)
+	runTest("Count Flag", testCountFlag)
+	runTest("StringToString Flag", testStringToStringFlag)
+	runTest("Typed Flag Usages", testTypedFlagUsages)
+	runTest("Required Flags", testRequiredFlags)
+	runTest("Flag Groups", testFlagGroups)
+	runTest("Unknown Flags", testUnknownFlags)
+	runTest("Unknown Flags Whitelist", testUnknownFlagsWhitelist)
+	runTest("Grouped Shorthands", testGroupedShorthands)
+	runTest("Explicit Bool Values", testExplicitBoolValues)
+	runTest("Negative Numbers", testNegativeNumbers)
+	runTest("Dash Terminator", testDashTerminator)
+	runTest("Aliases", testAliases)
+	runTest("Command Suggestions", testCommandSuggestions)
+	runTest("Version Flag", testVersionFlag)
+	runTest("Version Template", testVersionTemplate)
+	runTest("Hidden And Deprecated Commands", testHiddenAndDeprecatedCommands)
+	runTest("Hidden And Deprecated Flags", testHiddenAndDeprecatedFlags)
+	runTest("Output Writers", testOutputWriters)
+	runTest("Usage Template And Func", testUsageTemplateAndFunc)
+	runTest("Error Customization", testErrorCustomization)
+	runTest("ExecuteContext", testExecuteContext)
+	runTest("Context Cancellation", testContextCancellation)
+	runTest("GenMarkdown", testGenMarkdown)
+	runTest("Doc Trees", testGenDocTrees)
+	runTest("Doc Trees Fs", testGenDocTreesFs)
+	runTest("BindFlagsToViper", testBindFlagsToViper)
+	runTest("Prompts", testPrompts)
+	runTest("Prompt Wizard", testPromptWizard)
+	runTest("Flag Env Vars", testFlagEnvVars)
+	runTest("FlagSet Lookup and Visit", testFlagSetLookupAndVisit)
+	runTest("Custom Flag Values", testCustomFlagValues)
+	runTest("Command Groups", testCommandGroups)
+	runTest("TraverseChildren", testTraverseChildren)
+	runTest("Completion Harness", testCompletionHarness)
+	runTest("Middleware", testMiddleware)
+	runTest("RecoverPanics", testRecoverPanics)
 
 	fmt.Println("==============================")
 	fmt.Println("All tests completed!")
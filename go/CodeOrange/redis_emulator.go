@@ -7,11 +7,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Client represents a Redis client connection
+// Client represents a Redis client connection. It is safe for concurrent
+// use; each command runs atomically, as on a Redis server.
 type Client struct {
+	mu       sync.Mutex
 	data     map[string]string
 	lists    map[string][]string
 	sets     map[string]map[string]bool
@@ -43,6 +46,12 @@ type Options struct {
 
 // Set sets a key to hold a string value
 func (c *Client) Set(key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set(key, value, expiration)
+}
+
+func (c *Client) set(key string, value interface{}, expiration time.Duration) error {
 	c.data[key] = fmt.Sprintf("%v", value)
 	if expiration > 0 {
 		c.expires[key] = time.Now().Add(expiration)
@@ -50,8 +59,26 @@ func (c *Client) Set(key string, value interface{}, expiration time.Duration) er
 	return nil
 }
 
+// SetNX sets a key only if it does not exist, reporting whether it did.
+// Locks and unique jobs use it to claim a key.
+func (c *Client) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n, _ := c.exists(key); n > 0 {
+		return false, nil
+	}
+	delete(c.expires, key)
+	return true, c.set(key, value, expiration)
+}
+
 // Get retrieves the value of a key
 func (c *Client) Get(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(key)
+}
+
+func (c *Client) get(key string) (string, error) {
 	if c.isExpired(key) {
 		delete(c.data, key)
 		delete(c.expires, key)
@@ -67,6 +94,12 @@ func (c *Client) Get(key string) (string, error) {
 
 // Del deletes one or more keys
 func (c *Client) Del(keys ...string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.del(keys...)
+}
+
+func (c *Client) del(keys ...string) (int, error) {
 	count := 0
 	for _, key := range keys {
 		if _, exists := c.data[key]; exists {
@@ -96,10 +129,16 @@ func (c *Client) Del(keys ...string) (int, error) {
 
 // Exists checks if keys exist
 func (c *Client) Exists(keys ...string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exists(keys...)
+}
+
+func (c *Client) exists(keys ...string) (int, error) {
 	count := 0
 	for _, key := range keys {
 		if c.isExpired(key) {
-			c.Del(key)
+			c.del(key)
 			continue
 		}
 		if _, exists := c.data[key]; exists {
@@ -119,12 +158,16 @@ func (c *Client) Exists(keys ...string) (int, error) {
 
 // Expire sets a timeout on a key
 func (c *Client) Expire(key string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.expires[key] = time.Now().Add(expiration)
 	return nil
 }
 
 // TTL returns the remaining time to live of a key
 func (c *Client) TTL(key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	expireTime, exists := c.expires[key]
 	if !exists {
 		return -1, nil
@@ -144,7 +187,13 @@ func (c *Client) Incr(key string) (int64, error) {
 
 // IncrBy increments the integer value of a key by the given amount
 func (c *Client) IncrBy(key string, value int64) (int64, error) {
-	current, err := c.Get(key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.incrBy(key, value)
+}
+
+func (c *Client) incrBy(key string, value int64) (int64, error) {
+	current, err := c.get(key)
 	if err != nil {
 		current = "0"
 	}
@@ -155,7 +204,7 @@ func (c *Client) IncrBy(key string, value int64) (int64, error) {
 	}
 	
 	newVal := intVal + value
-	c.Set(key, newVal, 0)
+	c.set(key, newVal, 0)
 	return newVal, nil
 }
 
@@ -173,6 +222,8 @@ func (c *Client) DecrBy(key string, value int64) (int64, error) {
 
 // LPush inserts values at the head of the list
 func (c *Client) LPush(key string, values ...interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.lists[key] == nil {
 		c.lists[key] = []string{}
 	}
@@ -186,6 +237,8 @@ func (c *Client) LPush(key string, values ...interface{}) (int, error) {
 
 // RPush inserts values at the tail of the list
 func (c *Client) RPush(key string, values ...interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.lists[key] == nil {
 		c.lists[key] = []string{}
 	}
@@ -199,6 +252,8 @@ func (c *Client) RPush(key string, values ...interface{}) (int, error) {
 
 // LPop removes and returns the first element of the list
 func (c *Client) LPop(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	list, exists := c.lists[key]
 	if !exists || len(list) == 0 {
 		return "", errors.New("redis: nil")
@@ -211,6 +266,8 @@ func (c *Client) LPop(key string) (string, error) {
 
 // RPop removes and returns the last element of the list
 func (c *Client) RPop(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	list, exists := c.lists[key]
 	if !exists || len(list) == 0 {
 		return "", errors.New("redis: nil")
@@ -223,6 +280,8 @@ func (c *Client) RPop(key string) (string, error) {
 
 // LRange returns a range of elements from the list
 func (c *Client) LRange(key string, start, stop int) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	list, exists := c.lists[key]
 	if !exists {
 		return []string{}, nil
@@ -255,6 +314,8 @@ func (c *Client) LRange(key string, start, stop int) ([]string, error) {
 
 // LLen returns the length of the list
 func (c *Client) LLen(key string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	list, exists := c.lists[key]
 	if !exists {
 		return 0, nil
@@ -266,6 +327,8 @@ func (c *Client) LLen(key string) (int, error) {
 
 // SAdd adds members to a set
 func (c *Client) SAdd(key string, members ...interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.sets[key] == nil {
 		c.sets[key] = make(map[string]bool)
 	}
@@ -284,6 +347,8 @@ func (c *Client) SAdd(key string, members ...interface{}) (int, error) {
 
 // SMembers returns all members of the set
 func (c *Client) SMembers(key string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	set, exists := c.sets[key]
 	if !exists {
 		return []string{}, nil
@@ -299,6 +364,8 @@ func (c *Client) SMembers(key string) ([]string, error) {
 
 // SIsMember checks if a value is a member of the set
 func (c *Client) SIsMember(key string, member interface{}) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	set, exists := c.sets[key]
 	if !exists {
 		return false, nil
@@ -310,6 +377,8 @@ func (c *Client) SIsMember(key string, member interface{}) (bool, error) {
 
 // SRem removes members from a set
 func (c *Client) SRem(key string, members ...interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	set, exists := c.sets[key]
 	if !exists {
 		return 0, nil
@@ -329,6 +398,8 @@ func (c *Client) SRem(key string, members ...interface{}) (int, error) {
 
 // SCard returns the number of members in the set
 func (c *Client) SCard(key string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	set, exists := c.sets[key]
 	if !exists {
 		return 0, nil
@@ -340,6 +411,8 @@ func (c *Client) SCard(key string) (int, error) {
 
 // HSet sets a field in the hash
 func (c *Client) HSet(key, field string, value interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.hashes[key] == nil {
 		c.hashes[key] = make(map[string]string)
 	}
@@ -350,6 +423,8 @@ func (c *Client) HSet(key, field string, value interface{}) error {
 
 // HGet retrieves the value of a hash field
 func (c *Client) HGet(key, field string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	hash, exists := c.hashes[key]
 	if !exists {
 		return "", errors.New("redis: nil")
@@ -365,6 +440,8 @@ func (c *Client) HGet(key, field string) (string, error) {
 
 // HGetAll retrieves all fields and values in a hash
 func (c *Client) HGetAll(key string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	hash, exists := c.hashes[key]
 	if !exists {
 		return make(map[string]string), nil
@@ -380,6 +457,8 @@ func (c *Client) HGetAll(key string) (map[string]string, error) {
 
 // HDel deletes fields from a hash
 func (c *Client) HDel(key string, fields ...string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	hash, exists := c.hashes[key]
 	if !exists {
 		return 0, nil
@@ -398,6 +477,8 @@ func (c *Client) HDel(key string, fields ...string) (int, error) {
 
 // HExists checks if a field exists in the hash
 func (c *Client) HExists(key, field string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	hash, exists := c.hashes[key]
 	if !exists {
 		return false, nil
@@ -409,6 +490,8 @@ func (c *Client) HExists(key, field string) (bool, error) {
 
 // HLen returns the number of fields in the hash
 func (c *Client) HLen(key string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	hash, exists := c.hashes[key]
 	if !exists {
 		return 0, nil
@@ -420,6 +503,8 @@ func (c *Client) HLen(key string) (int, error) {
 
 // ZAdd adds members with scores to a sorted set
 func (c *Client) ZAdd(key string, members ...interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.sortedSets[key] == nil {
 		c.sortedSets[key] = make(map[string]float64)
 	}
@@ -447,6 +532,8 @@ func (c *Client) ZAdd(key string, members ...interface{}) (int, error) {
 
 // ZRange returns a range of members in a sorted set by index
 func (c *Client) ZRange(key string, start, stop int) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	zset, exists := c.sortedSets[key]
 	if !exists {
 		return []string{}, nil
@@ -502,6 +589,8 @@ func (c *Client) ZRange(key string, start, stop int) ([]string, error) {
 
 // ZScore returns the score of a member in a sorted set
 func (c *Client) ZScore(key, member string) (float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	zset, exists := c.sortedSets[key]
 	if !exists {
 		return 0, errors.New("redis: nil")
@@ -517,6 +606,8 @@ func (c *Client) ZScore(key, member string) (float64, error) {
 
 // ZRem removes members from a sorted set
 func (c *Client) ZRem(key string, members ...interface{}) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	zset, exists := c.sortedSets[key]
 	if !exists {
 		return 0, nil
@@ -536,6 +627,8 @@ func (c *Client) ZRem(key string, members ...interface{}) (int, error) {
 
 // ZCard returns the number of members in a sorted set
 func (c *Client) ZCard(key string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	zset, exists := c.sortedSets[key]
 	if !exists {
 		return 0, nil
@@ -547,6 +640,8 @@ func (c *Client) ZCard(key string) (int, error) {
 
 // Keys returns all keys matching the pattern
 func (c *Client) Keys(pattern string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	keys := []string{}
 	
 	// Simplified pattern matching (only supports * wildcard)
@@ -581,6 +676,8 @@ func (c *Client) Keys(pattern string) ([]string, error) {
 
 // FlushDB removes all keys from the current database
 func (c *Client) FlushDB() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.data = make(map[string]string)
 	c.lists = make(map[string][]string)
 	c.sets = make(map[string]map[string]bool)
@@ -592,6 +689,8 @@ func (c *Client) FlushDB() error {
 
 // Ping tests the connection
 func (c *Client) Ping() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return "PONG", nil
 }
 
@@ -3,6 +3,7 @@ package main
 // Developed by PowerShield, as an alternative to Redis (Go client)
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -246,5 +247,44 @@ func main() {
 		fmt.Println("✓ Database flushed successfully")
 	}
 	
+	// Test 25: SetNX
+	fmt.Println("\nTest 25: SetNX")
+	claimed, _ := client.SetNX("lock:report", "worker-1", time.Minute)
+	again, _ := client.SetNX("lock:report", "worker-2", time.Minute)
+	owner, _ := client.Get("lock:report")
+	client.Set("lock:expired", "worker-1", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	reclaimed, _ := client.SetNX("lock:expired", "worker-3", 0)
+	if claimed && !again && owner == "worker-1" && reclaimed {
+		fmt.Printf("✓ Lock held by %s, expired lock reclaimed\n", owner)
+	} else {
+		fmt.Printf("❌ SetNX failed: %v, %v, %v\n", claimed, again, reclaimed)
+	}
+	
+	// Test 26: Concurrent use
+	fmt.Println("\nTest 26: Concurrent Use")
+	var wg sync.WaitGroup
+	var winners, counted sync.Map
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if ok, _ := client.SetNX("lock:race", i, time.Minute); ok {
+				winners.Store(i, true)
+			}
+			n, _ := client.Incr("hits")
+			counted.Store(n, true)
+		}(i)
+	}
+	wg.Wait()
+	won, distinct := 0, 0
+	winners.Range(func(_, _ interface{}) bool { won++; return true })
+	counted.Range(func(_, _ interface{}) bool { distinct++; return true })
+	if won == 1 && distinct == 50 {
+		fmt.Println("✓ One SetNX won the lock and every Incr was counted")
+	} else {
+		fmt.Printf("❌ Concurrent use failed: %d winners, %d counts\n", won, distinct)
+	}
+	
 	fmt.Println("\n=== All Tests Completed ===")
 }
@@ -0,0 +1,2499 @@
+package main
+
+// Developed by PowerShield, as an alternative to Consul and its Go API client
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Health states, as in the api package
+const (
+	HealthAny      = "any"
+	HealthPassing  = "passing"
+	HealthWarning  = "warning"
+	HealthCritical = "critical"
+	HealthMaint    = "maintenance"
+)
+
+// Session behaviors: what happens to a session's locks when it is
+// invalidated
+const (
+	SessionBehaviorRelease = "release"
+	SessionBehaviorDelete  = "delete"
+)
+
+const (
+	serfHealthCheckID         = "serfHealth"
+	nodeMaintCheckID          = "_node_maintenance"
+	serviceMaintCheckIDPrefix = "_service_maintenance:"
+
+	defaultLockDelay = 15 * time.Second
+	defaultWaitTime  = 5 * time.Minute
+	maxWaitTime      = 10 * time.Minute
+	defaultTimeout   = 10 * time.Second
+	// sessionTTLMultiplier is the grace the servers give a session TTL
+	// before invalidating it
+	sessionTTLMultiplier = 2
+)
+
+// ErrSessionExpired is returned by RenewPeriodic when the session is gone
+var ErrSessionExpired = errors.New("session expired")
+
+// StatusError is an error response from the agent
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("Unexpected response code: %d (%s)", e.Code, e.Body)
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return StatusError{Code: 400, Body: fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return StatusError{Code: 404, Body: fmt.Sprintf(format, args...)}
+}
+
+func serverError(format string, args ...interface{}) error {
+	return StatusError{Code: 500, Body: fmt.Sprintf(format, args...)}
+}
+
+// Catalog and health types
+
+// Node is a machine running an agent
+type Node struct {
+	ID              string
+	Node            string
+	Address         string
+	Datacenter      string
+	TaggedAddresses map[string]string
+	Meta            map[string]string
+	CreateIndex     uint64
+	ModifyIndex     uint64
+}
+
+func (n *Node) clone() *Node {
+	c := *n
+	c.TaggedAddresses = cloneMap(n.TaggedAddresses)
+	c.Meta = cloneMap(n.Meta)
+	return &c
+}
+
+// AgentWeights are the weights of a service in DNS answers
+type AgentWeights struct {
+	Passing int
+	Warning int
+}
+
+// AgentService is a service registered with an agent
+type AgentService struct {
+	Kind              string
+	ID                string
+	Service           string
+	Tags              []string
+	Meta              map[string]string
+	Port              int
+	Address           string
+	Weights           AgentWeights
+	EnableTagOverride bool
+	CreateIndex       uint64
+	ModifyIndex       uint64
+	Datacenter        string
+}
+
+func (s *AgentService) clone() *AgentService {
+	c := *s
+	c.Tags = append([]string(nil), s.Tags...)
+	c.Meta = cloneMap(s.Meta)
+	return &c
+}
+
+func (s *AgentService) hasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// CatalogService is one instance of a service, with the node it runs on
+type CatalogService struct {
+	ID                       string
+	Node                     string
+	Address                  string
+	Datacenter               string
+	TaggedAddresses          map[string]string
+	NodeMeta                 map[string]string
+	ServiceID                string
+	ServiceName              string
+	ServiceAddress           string
+	ServiceTags              []string
+	ServiceMeta              map[string]string
+	ServicePort              int
+	ServiceWeights           AgentWeights
+	ServiceEnableTagOverride bool
+	CreateIndex              uint64
+	ModifyIndex              uint64
+}
+
+// CatalogNode is a node with its services
+type CatalogNode struct {
+	Node     *Node
+	Services map[string]*AgentService
+}
+
+// HealthCheck is the state of a check
+type HealthCheck struct {
+	Node        string
+	CheckID     string
+	Name        string
+	Status      string
+	Notes       string
+	Output      string
+	ServiceID   string
+	ServiceName string
+	ServiceTags []string
+	Type        string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+func (c *HealthCheck) clone() *HealthCheck {
+	h := *c
+	h.ServiceTags = append([]string(nil), c.ServiceTags...)
+	return &h
+}
+
+// HealthChecks is a list of checks
+type HealthChecks []*HealthCheck
+
+// AggregatedStatus returns the worst status of the checks: maintenance,
+// then critical, then warning, then passing
+func (c HealthChecks) AggregatedStatus() string {
+	var passing, warning, critical, maint bool
+	for _, check := range c {
+		if check.CheckID == nodeMaintCheckID || strings.HasPrefix(check.CheckID, serviceMaintCheckIDPrefix) {
+			maint = true
+			continue
+		}
+		switch check.Status {
+		case HealthPassing:
+			passing = true
+		case HealthWarning:
+			warning = true
+		case HealthCritical:
+			critical = true
+		default:
+			return ""
+		}
+	}
+	switch {
+	case maint:
+		return HealthMaint
+	case critical:
+		return HealthCritical
+	case warning:
+		return HealthWarning
+	case passing:
+		return HealthPassing
+	}
+	return HealthPassing
+}
+
+// ServiceEntry is an instance of a service with its node and the checks
+// of both
+type ServiceEntry struct {
+	Node    *Node
+	Service *AgentService
+	Checks  HealthChecks
+}
+
+// Agent types
+
+// AgentCheck is a check registered with an agent
+type AgentCheck struct {
+	Node        string
+	CheckID     string
+	Name        string
+	Status      string
+	Notes       string
+	Output      string
+	ServiceID   string
+	ServiceName string
+	Type        string
+}
+
+// AgentServiceCheck defines a check. Exactly one of TTL, HTTP and TCP
+// must be set; HTTP and TCP checks also need an Interval.
+type AgentServiceCheck struct {
+	CheckID  string
+	Name     string
+	Interval string
+	Timeout  string
+	TTL      string
+	HTTP     string
+	Header   map[string][]string
+	Method   string
+	TCP      string
+	// Status is the initial status. It defaults to critical.
+	Status string
+	Notes  string
+	// DeregisterCriticalServiceAfter deregisters the check's service
+	// once the check has been critical this long
+	DeregisterCriticalServiceAfter string
+}
+
+// AgentServiceChecks is a list of check definitions
+type AgentServiceChecks []*AgentServiceCheck
+
+// AgentServiceRegistration registers a service with an agent
+type AgentServiceRegistration struct {
+	Kind              string
+	ID                string
+	Name              string
+	Tags              []string
+	Port              int
+	Address           string
+	EnableTagOverride bool
+	Meta              map[string]string
+	Weights           *AgentWeights
+	Check             *AgentServiceCheck
+	Checks            AgentServiceChecks
+}
+
+// AgentCheckRegistration registers a check with an agent, optionally
+// bound to one of its services
+type AgentCheckRegistration struct {
+	ID        string
+	Name      string
+	Notes     string
+	ServiceID string
+	AgentServiceCheck
+}
+
+// KV and session types
+
+// KVPair is a key with its value and indexes
+type KVPair struct {
+	Key string
+	// CreateIndex is the index the key was created at
+	CreateIndex uint64
+	// ModifyIndex is the index of the key's last change; CAS compares it
+	ModifyIndex uint64
+	// LockIndex counts the times the key's lock was acquired
+	LockIndex uint64
+	Flags     uint64
+	Value     []byte
+	// Session is the ID of the session holding the key's lock, if any
+	Session string
+}
+
+func (p *KVPair) clone() *KVPair {
+	c := *p
+	c.Value = append([]byte(nil), p.Value...)
+	return &c
+}
+
+// KVPairs is a list of pairs
+type KVPairs []*KVPair
+
+// ServiceCheck names a service check a session depends on
+type ServiceCheck struct {
+	ID        string
+	Namespace string
+}
+
+// SessionEntry is a session: a lease on locks, invalidated when it is
+// destroyed, when its TTL runs out or when a check it depends on turns
+// critical
+type SessionEntry struct {
+	CreateIndex uint64
+	ModifyIndex uint64
+	ID          string
+	Name        string
+	Node        string
+	// LockDelay is how long a lock cannot be acquired after the session
+	// holding it is invalidated. 0 means 15 seconds.
+	LockDelay time.Duration
+	Behavior  string
+	TTL       string
+	// Checks and NodeChecks name checks on the session's node. If both
+	// are nil, the session depends on the node's serfHealth check.
+	Checks        []string
+	NodeChecks    []string
+	ServiceChecks []ServiceCheck
+}
+
+func (e *SessionEntry) clone() *SessionEntry {
+	c := *e
+	c.Checks = append([]string(nil), e.Checks...)
+	c.NodeChecks = append([]string(nil), e.NodeChecks...)
+	c.ServiceChecks = append([]ServiceCheck(nil), e.ServiceChecks...)
+	return &c
+}
+
+// checkIDs returns every check the session depends on
+func (e *SessionEntry) checkIDs() []string {
+	ids := append(append([]string(nil), e.Checks...), e.NodeChecks...)
+	for _, sc := range e.ServiceChecks {
+		ids = append(ids, sc.ID)
+	}
+	return ids
+}
+
+// Request options
+
+// QueryOptions configures a read. With WaitIndex set the read is a
+// blocking query: it waits until the result's index passes WaitIndex,
+// or WaitTime elapses.
+type QueryOptions struct {
+	Datacenter        string
+	AllowStale        bool
+	RequireConsistent bool
+	// WaitIndex is the LastIndex of a previous result
+	WaitIndex uint64
+	// WaitTime bounds a blocking query. It defaults to 5 minutes and is
+	// capped at 10.
+	WaitTime time.Duration
+	Token    string
+
+	ctx context.Context
+}
+
+// WithContext returns a copy of o that cancels when ctx is done
+func (o *QueryOptions) WithContext(ctx context.Context) *QueryOptions {
+	o2 := new(QueryOptions)
+	if o != nil {
+		*o2 = *o
+	}
+	o2.ctx = ctx
+	return o2
+}
+
+// Context returns the context of o, or the background context
+func (o *QueryOptions) Context() context.Context {
+	if o != nil && o.ctx != nil {
+		return o.ctx
+	}
+	return context.Background()
+}
+
+// QueryMeta describes a read
+type QueryMeta struct {
+	// LastIndex is the index of the result, the WaitIndex of the next
+	// blocking query
+	LastIndex   uint64
+	LastContact time.Duration
+	KnownLeader bool
+	RequestTime time.Duration
+}
+
+// WriteOptions configures a write
+type WriteOptions struct {
+	Datacenter string
+	Token      string
+
+	ctx context.Context
+}
+
+// WithContext returns a copy of o that cancels when ctx is done
+func (o *WriteOptions) WithContext(ctx context.Context) *WriteOptions {
+	o2 := new(WriteOptions)
+	if o != nil {
+		*o2 = *o
+	}
+	o2.ctx = ctx
+	return o2
+}
+
+// Context returns the context of o, or the background context
+func (o *WriteOptions) Context() context.Context {
+	if o != nil && o.ctx != nil {
+		return o.ctx
+	}
+	return context.Background()
+}
+
+// WriteMeta describes a write
+type WriteMeta struct {
+	RequestTime time.Duration
+}
+
+// Server
+
+var (
+	agentsMu sync.Mutex
+	agents   = make(map[string]*agent)
+	nextPort = 49152
+)
+
+// agent is a node's agent, the endpoint clients talk to
+type agent struct {
+	server *Server
+	node   string
+}
+
+type checkKey struct {
+	node string
+	id   string
+}
+
+type serviceKey struct {
+	node string
+	id   string
+}
+
+// checkState is a registered check with what runs it
+type checkState struct {
+	check *HealthCheck
+	ttl   time.Duration
+	timer *time.Timer
+	// deadline is when a TTL check fails unless updated
+	deadline time.Time
+	// stop ends the goroutine polling an HTTP or TCP check
+	stop            chan struct{}
+	deregisterAfter time.Duration
+	deregisterTimer *time.Timer
+}
+
+type session struct {
+	entry    *SessionEntry
+	ttl      time.Duration
+	timer    *time.Timer
+	deadline time.Time
+}
+
+// Server is an in-memory, single-datacenter Consul cluster. Clients reach
+// it through its agents, one per node.
+type Server struct {
+	// Addr is the address of the first agent, e.g. 127.0.0.1:8500
+	Addr string
+	// NodeName is the node of the first agent
+	NodeName string
+
+	mu         sync.Mutex
+	datacenter string
+	index      uint64
+	// tables holds the index of the last change to each table, which
+	// list and health queries report
+	tables   map[string]uint64
+	changed  chan struct{}
+	nodes    map[string]*Node
+	services map[serviceKey]*AgentService
+	checks   map[checkKey]*checkState
+	kvs      map[string]*KVPair
+	// lockDelays holds, per key, when its lock may be acquired again
+	// after its session was invalidated
+	lockDelays map[string]time.Time
+	sessions   map[string]*session
+	addrs      []string
+	stopped    bool
+}
+
+// NewServer starts an in-memory cluster with one agent on an unused
+// loopback port
+func NewServer() *Server {
+	agentsMu.Lock()
+	port := nextPort
+	nextPort++
+	agentsMu.Unlock()
+	return Listen(fmt.Sprintf("127.0.0.1:%d", port))
+}
+
+// Listen starts an in-memory cluster with one agent at addr, such as
+// "127.0.0.1:8500" or "http://localhost:8500", replacing any agent
+// already there
+func Listen(addr string) *Server {
+	s := &Server{
+		datacenter: "dc1",
+		index:      1,
+		tables:     make(map[string]uint64),
+		changed:    make(chan struct{}),
+		nodes:      make(map[string]*Node),
+		services:   make(map[serviceKey]*AgentService),
+		checks:     make(map[checkKey]*checkState),
+		kvs:        make(map[string]*KVPair),
+		lockDelays: make(map[string]time.Time),
+		sessions:   make(map[string]*session),
+	}
+	s.NodeName = "consul-server-1"
+	s.Addr = s.StartAgent(addr, s.NodeName)
+	return s
+}
+
+// StartAgent joins a node named node to the cluster, with an agent at
+// addr. It returns the agent's normalized address.
+func (s *Server) StartAgent(addr, node string) string {
+	addr = normalizeAddr(addr)
+	host, _, _ := net.SplitHostPort(addr)
+
+	s.mu.Lock()
+	idx := s.bump("nodes", "checks")
+	if _, ok := s.nodes[node]; !ok {
+		s.nodes[node] = &Node{
+			ID:              newUUID(),
+			Node:            node,
+			Address:         host,
+			Datacenter:      s.datacenter,
+			TaggedAddresses: map[string]string{"lan": host, "wan": host},
+			Meta:            map[string]string{"consul-network-segment": ""},
+			CreateIndex:     idx,
+			ModifyIndex:     idx,
+		}
+		s.checks[checkKey{node, serfHealthCheckID}] = &checkState{check: &HealthCheck{
+			Node:        node,
+			CheckID:     serfHealthCheckID,
+			Name:        "Serf Health Status",
+			Status:      HealthPassing,
+			Output:      "Agent alive and reachable",
+			CreateIndex: idx,
+			ModifyIndex: idx,
+		}}
+	}
+	s.addrs = append(s.addrs, addr)
+	s.mu.Unlock()
+
+	agentsMu.Lock()
+	agents[addr] = &agent{server: s, node: node}
+	agentsMu.Unlock()
+	return addr
+}
+
+// normalizeAddr reduces addr to host:port, dropping any scheme and
+// defaulting the port to 8500
+func normalizeAddr(addr string) string {
+	if i := strings.Index(addr, "://"); i >= 0 {
+		addr = addr[i+3:]
+	}
+	addr = strings.TrimSuffix(addr, "/")
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "8500"
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+// Index returns the cluster's current Raft index
+func (s *Server) Index() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index
+}
+
+// Close stops the cluster: its agents become unreachable, its checks
+// stop running and its blocking queries return
+func (s *Server) Close() {
+	agentsMu.Lock()
+	for _, addr := range s.addrs {
+		if a := agents[addr]; a != nil && a.server == s {
+			delete(agents, addr)
+		}
+	}
+	agentsMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for _, cs := range s.checks {
+		cs.halt()
+	}
+	for _, se := range s.sessions {
+		if se.timer != nil {
+			se.timer.Stop()
+		}
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// bump advances the Raft index for a write to tables and wakes the
+// blocking queries
+func (s *Server) bump(tables ...string) uint64 {
+	s.index++
+	for _, t := range tables {
+		s.tables[t] = s.index
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return s.index
+}
+
+// tableIndex returns the latest index of tables, never 0
+func (s *Server) tableIndex(tables ...string) uint64 {
+	idx := uint64(1)
+	for _, t := range tables {
+		if s.tables[t] > idx {
+			idx = s.tables[t]
+		}
+	}
+	return idx
+}
+
+// errStopped is what a request to a closed cluster fails with
+func (s *Server) errStopped() error {
+	return serverError("No cluster leader")
+}
+
+// checkDC fails requests for another datacenter
+func (s *Server) checkDC(dc string) error {
+	if dc != "" && dc != s.datacenter {
+		return serverError("No path to datacenter")
+	}
+	return nil
+}
+
+// query runs a read. With a WaitIndex it runs run again after every
+// write until the index it returns passes WaitIndex, the wait times out
+// or the context is done.
+func (s *Server) query(q *QueryOptions, run func() uint64) (*QueryMeta, error) {
+	start := time.Now()
+	var dc string
+	var waitIndex uint64
+	wait := defaultWaitTime
+	if q != nil {
+		dc, waitIndex = q.Datacenter, q.WaitIndex
+		if q.WaitTime > 0 {
+			wait = q.WaitTime
+		}
+	}
+	if wait > maxWaitTime {
+		wait = maxWaitTime
+	}
+	ctx := q.Context()
+	var timeout <-chan time.Time
+	for {
+		s.mu.Lock()
+		if s.stopped {
+			s.mu.Unlock()
+			return nil, s.errStopped()
+		}
+		if err := s.checkDC(dc); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		idx := run()
+		changed := s.changed
+		s.mu.Unlock()
+
+		meta := &QueryMeta{LastIndex: idx, KnownLeader: true, RequestTime: time.Since(start)}
+		if waitIndex == 0 || idx > waitIndex {
+			return meta, nil
+		}
+		if timeout == nil {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return meta, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// write runs a write
+func (s *Server) write(w *WriteOptions, run func() error) (*WriteMeta, error) {
+	start := time.Now()
+	if err := w.Context().Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, s.errStopped()
+	}
+	if err := s.checkDC(w.Datacenter); err != nil {
+		return nil, err
+	}
+	if err := run(); err != nil {
+		return nil, err
+	}
+	return &WriteMeta{RequestTime: time.Since(start)}, nil
+}
+
+// Services and checks
+
+// registerService adds or replaces a service on node, with its checks
+func (s *Server) registerService(node string, r *AgentServiceRegistration) error {
+	if r.Name == "" {
+		return badRequest("Missing service name")
+	}
+	id := r.ID
+	if id == "" {
+		id = r.Name
+	}
+	defs := append(AgentServiceChecks(nil), r.Checks...)
+	if r.Check != nil {
+		defs = append(AgentServiceChecks{r.Check}, defs...)
+	}
+	for _, d := range defs {
+		if err := validateCheck(d); err != nil {
+			return err
+		}
+	}
+
+	key := serviceKey{node, id}
+	svc := &AgentService{
+		Kind:              r.Kind,
+		ID:                id,
+		Service:           r.Name,
+		Tags:              append([]string(nil), r.Tags...),
+		Meta:              cloneMap(r.Meta),
+		Port:              r.Port,
+		Address:           r.Address,
+		Weights:           AgentWeights{Passing: 1, Warning: 1},
+		EnableTagOverride: r.EnableTagOverride,
+		Datacenter:        s.datacenter,
+	}
+	if svc.Meta == nil {
+		svc.Meta = map[string]string{}
+	}
+	if r.Weights != nil {
+		svc.Weights = *r.Weights
+	}
+
+	// Re-registering replaces the service's checks
+	for k, cs := range s.checks {
+		if k.node == node && cs.check.ServiceID == id {
+			s.removeCheck(k)
+		}
+	}
+	idx := s.bump("services", "checks")
+	svc.CreateIndex, svc.ModifyIndex = idx, idx
+	if old, ok := s.services[key]; ok {
+		svc.CreateIndex = old.CreateIndex
+	}
+	s.services[key] = svc
+	for i, d := range defs {
+		checkID := d.CheckID
+		if checkID == "" {
+			checkID = "service:" + id
+			if len(defs) > 1 {
+				checkID = fmt.Sprintf("service:%s:%d", id, i+1)
+			}
+		}
+		name := d.Name
+		if name == "" {
+			name = fmt.Sprintf("Service '%s' check", r.Name)
+		}
+		s.addCheck(node, checkID, name, d.Notes, svc, d, idx)
+	}
+	return nil
+}
+
+// deregisterService removes a service from node, with its checks
+func (s *Server) deregisterService(node, id string) error {
+	key := serviceKey{node, id}
+	if _, ok := s.services[key]; !ok {
+		return notFound("Unknown service ID %q. Ensure that the service ID is passed, not the service name.", id)
+	}
+	for k, cs := range s.checks {
+		if k.node == node && cs.check.ServiceID == id {
+			s.removeCheck(k)
+		}
+	}
+	delete(s.services, key)
+	s.bump("services", "checks")
+	return nil
+}
+
+// validateCheck checks that a definition has one way to run
+func validateCheck(d *AgentServiceCheck) error {
+	kinds := 0
+	for _, v := range []string{d.TTL, d.HTTP, d.TCP} {
+		if v != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return badRequest("Invalid check: exactly one of TTL, HTTP or TCP must be set")
+	}
+	if d.TTL != "" {
+		if ttl, err := time.ParseDuration(d.TTL); err != nil || ttl <= 0 {
+			return badRequest("Invalid check: TTL must be > 0 for TTL checks")
+		}
+	} else if iv, err := time.ParseDuration(d.Interval); err != nil || iv <= 0 {
+		return badRequest("Invalid check: Interval must be > 0 for HTTP and TCP checks")
+	}
+	for _, v := range []string{d.Timeout, d.DeregisterCriticalServiceAfter} {
+		if v == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(v); err != nil {
+			return badRequest("Invalid check: %v", err)
+		}
+	}
+	switch d.Status {
+	case "", HealthPassing, HealthWarning, HealthCritical:
+	default:
+		return badRequest("Invalid check status: %q", d.Status)
+	}
+	return nil
+}
+
+// addCheck registers a validated check on node, bound to svc if it is
+// not nil, and starts running it
+func (s *Server) addCheck(node, id, name, notes string, svc *AgentService, d *AgentServiceCheck, idx uint64) {
+	k := checkKey{node, id}
+	if _, ok := s.checks[k]; ok {
+		s.removeCheck(k)
+	}
+	status := d.Status
+	if status == "" {
+		status = HealthCritical
+	}
+	hc := &HealthCheck{
+		Node:        node,
+		CheckID:     id,
+		Name:        name,
+		Status:      status,
+		Notes:       notes,
+		CreateIndex: idx,
+		ModifyIndex: idx,
+	}
+	if svc != nil {
+		hc.ServiceID, hc.ServiceName = svc.ID, svc.Service
+		hc.ServiceTags = append([]string(nil), svc.Tags...)
+	}
+	cs := &checkState{check: hc}
+	if d.DeregisterCriticalServiceAfter != "" && svc != nil {
+		cs.deregisterAfter, _ = time.ParseDuration(d.DeregisterCriticalServiceAfter)
+	}
+	s.checks[k] = cs
+
+	switch {
+	case d.TTL != "":
+		hc.Type = "ttl"
+		cs.ttl, _ = time.ParseDuration(d.TTL)
+		cs.deadline = time.Now().Add(cs.ttl)
+		cs.timer = time.AfterFunc(cs.ttl, func() { s.expireTTL(k, cs) })
+	case d.HTTP != "":
+		hc.Type = "http"
+		cs.stop = make(chan struct{})
+		go s.runCheck(k, cs, d, httpProbe(d))
+	case d.TCP != "":
+		hc.Type = "tcp"
+		cs.stop = make(chan struct{})
+		go s.runCheck(k, cs, d, tcpProbe(d))
+	}
+	s.armDeregister(k, cs)
+}
+
+// removeCheck deregisters a check, invalidating the sessions that
+// depend on it
+func (s *Server) removeCheck(k checkKey) {
+	cs, ok := s.checks[k]
+	if !ok {
+		return
+	}
+	cs.halt()
+	delete(s.checks, k)
+	s.invalidateDependents(k)
+}
+
+// halt stops whatever runs the check
+func (cs *checkState) halt() {
+	if cs.timer != nil {
+		cs.timer.Stop()
+	}
+	if cs.deregisterTimer != nil {
+		cs.deregisterTimer.Stop()
+	}
+	if cs.stop != nil {
+		select {
+		case <-cs.stop:
+		default:
+			close(cs.stop)
+		}
+	}
+}
+
+// setStatus records a check result. A critical check invalidates the
+// sessions depending on it.
+func (s *Server) setStatus(k checkKey, status, output string) {
+	cs, ok := s.checks[k]
+	if !ok || (cs.check.Status == status && cs.check.Output == output) {
+		return
+	}
+	cs.check.Status, cs.check.Output = status, output
+	cs.check.ModifyIndex = s.bump("checks")
+	if status == HealthCritical {
+		s.invalidateDependents(k)
+	}
+	s.armDeregister(k, cs)
+}
+
+// armDeregister starts or stops the timer that deregisters the service
+// of a check that stays critical
+func (s *Server) armDeregister(k checkKey, cs *checkState) {
+	if cs.deregisterAfter <= 0 {
+		return
+	}
+	if cs.check.Status != HealthCritical {
+		if cs.deregisterTimer != nil {
+			cs.deregisterTimer.Stop()
+			cs.deregisterTimer = nil
+		}
+		return
+	}
+	if cs.deregisterTimer != nil {
+		return
+	}
+	cs.deregisterTimer = time.AfterFunc(cs.deregisterAfter, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.stopped || s.checks[k] != cs || cs.check.Status != HealthCritical {
+			return
+		}
+		s.deregisterService(k.node, cs.check.ServiceID)
+	})
+}
+
+// expireTTL fails a TTL check that was not updated in time
+func (s *Server) expireTTL(k checkKey, cs *checkState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped || s.checks[k] != cs || time.Now().Before(cs.deadline) {
+		return
+	}
+	s.setStatus(k, HealthCritical, "TTL expired")
+}
+
+// updateTTL sets the status of a TTL check and restarts its TTL
+func (s *Server) updateTTL(node, id, status, output string) error {
+	k := checkKey{node, id}
+	cs, ok := s.checks[k]
+	if !ok {
+		return notFound("Unknown check ID %q. Ensure that the check ID is passed, not the check name.", id)
+	}
+	if cs.check.Type != "ttl" {
+		return serverError("CheckID %q does not have associated TTL", id)
+	}
+	cs.deadline = time.Now().Add(cs.ttl)
+	cs.timer.Reset(cs.ttl)
+	s.setStatus(k, status, output)
+	return nil
+}
+
+// probe runs a check once, returning its status and output
+type probe func() (string, string)
+
+// runCheck runs a probe now and then every interval until the check is
+// halted
+func (s *Server) runCheck(k checkKey, cs *checkState, d *AgentServiceCheck, p probe) {
+	interval, _ := time.ParseDuration(d.Interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, output := p()
+		s.mu.Lock()
+		if s.stopped || s.checks[k] != cs {
+			s.mu.Unlock()
+			return
+		}
+		s.setStatus(k, status, output)
+		s.mu.Unlock()
+		select {
+		case <-ticker.C:
+		case <-cs.stop:
+			return
+		}
+	}
+}
+
+func checkTimeout(d *AgentServiceCheck) time.Duration {
+	if t, err := time.ParseDuration(d.Timeout); err == nil && t > 0 {
+		return t
+	}
+	return defaultTimeout
+}
+
+// httpProbe requests d.HTTP: a 2xx response passes, a 429 warns and
+// anything else is critical
+func httpProbe(d *AgentServiceCheck) probe {
+	client := &http.Client{Timeout: checkTimeout(d)}
+	method := d.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return func() (string, string) {
+		req, err := http.NewRequest(method, d.HTTP, nil)
+		if err != nil {
+			return HealthCritical, err.Error()
+		}
+		for name, values := range d.Header {
+			for _, v := range values {
+				req.Header.Add(name, v)
+			}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return HealthCritical, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		output := fmt.Sprintf("HTTP %s %s: %s Output: %s", method, d.HTTP, resp.Status, body)
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return HealthPassing, output
+		case resp.StatusCode == http.StatusTooManyRequests:
+			return HealthWarning, output
+		}
+		return HealthCritical, output
+	}
+}
+
+// tcpProbe connects to d.TCP
+func tcpProbe(d *AgentServiceCheck) probe {
+	timeout := checkTimeout(d)
+	return func() (string, string) {
+		conn, err := net.DialTimeout("tcp", d.TCP, timeout)
+		if err != nil {
+			return HealthCritical, err.Error()
+		}
+		conn.Close()
+		return HealthPassing, fmt.Sprintf("TCP connect %s: Success", d.TCP)
+	}
+}
+
+// nodeChecks returns the checks of node that belong to no service
+func (s *Server) nodeChecks(node string) HealthChecks {
+	var out HealthChecks
+	for k, cs := range s.checks {
+		if k.node == node && cs.check.ServiceID == "" {
+			out = append(out, cs.check.clone())
+		}
+	}
+	sortChecks(out)
+	return out
+}
+
+// serviceChecks returns the checks of one service instance
+func (s *Server) serviceChecks(node, id string) HealthChecks {
+	var out HealthChecks
+	for k, cs := range s.checks {
+		if k.node == node && cs.check.ServiceID == id {
+			out = append(out, cs.check.clone())
+		}
+	}
+	sortChecks(out)
+	return out
+}
+
+func sortChecks(checks HealthChecks) {
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Node != checks[j].Node {
+			return checks[i].Node < checks[j].Node
+		}
+		return checks[i].CheckID < checks[j].CheckID
+	})
+}
+
+// instances returns the instances of a service carrying tag, sorted by
+// node and ID
+func (s *Server) instances(service, tag string) []serviceKey {
+	var keys []serviceKey
+	for k, svc := range s.services {
+		if svc.Service == service && (tag == "" || svc.hasTag(tag)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].node != keys[j].node {
+			return keys[i].node < keys[j].node
+		}
+		return keys[i].id < keys[j].id
+	})
+	return keys
+}
+
+// KV store and sessions
+
+func validateKey(key string) error {
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("Invalid key. Key must not begin with a '/': %s", key)
+	}
+	return nil
+}
+
+// putKV writes p's value and flags, keeping the key's lock
+func (s *Server) putKV(p *KVPair) {
+	idx := s.bump("kvs")
+	cur, ok := s.kvs[p.Key]
+	if !ok {
+		cur = &KVPair{Key: p.Key, CreateIndex: idx}
+		s.kvs[p.Key] = cur
+	}
+	cur.Value = append([]byte(nil), p.Value...)
+	cur.Flags = p.Flags
+	cur.ModifyIndex = idx
+}
+
+// acquire locks a key for p.Session, writing p's value
+func (s *Server) acquire(p *KVPair) (bool, error) {
+	if _, ok := s.sessions[p.Session]; !ok {
+		return false, serverError("invalid session %q", p.Session)
+	}
+	cur, ok := s.kvs[p.Key]
+	if ok && cur.Session != "" && cur.Session != p.Session {
+		return false, nil
+	}
+	if until, ok := s.lockDelays[p.Key]; ok {
+		if time.Now().Before(until) {
+			return false, nil
+		}
+		delete(s.lockDelays, p.Key)
+	}
+	held := ok && cur.Session == p.Session
+	s.putKV(p)
+	cur = s.kvs[p.Key]
+	if !held {
+		cur.Session = p.Session
+		cur.LockIndex++
+	}
+	return true, nil
+}
+
+// release unlocks a key held by p.Session, writing p's value
+func (s *Server) release(p *KVPair) bool {
+	cur, ok := s.kvs[p.Key]
+	if !ok || cur.Session == "" || cur.Session != p.Session {
+		return false
+	}
+	s.putKV(p)
+	s.kvs[p.Key].Session = ""
+	return true
+}
+
+// createSession validates and stores a session
+func (s *Server) createSession(node string, se *SessionEntry) (string, error) {
+	e := se.clone()
+	if e.Node == "" {
+		e.Node = node
+	}
+	if _, ok := s.nodes[e.Node]; !ok {
+		return "", serverError("Missing node registration")
+	}
+	switch e.Behavior {
+	case "":
+		e.Behavior = SessionBehaviorRelease
+	case SessionBehaviorRelease, SessionBehaviorDelete:
+	default:
+		return "", badRequest("Invalid Behavior setting '%s'", e.Behavior)
+	}
+	if se.Checks == nil && se.NodeChecks == nil {
+		e.Checks = []string{serfHealthCheckID}
+	}
+	if e.LockDelay == 0 {
+		e.LockDelay = defaultLockDelay
+	}
+	var ttl time.Duration
+	if e.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(e.TTL); err != nil || ttl <= 0 {
+			return "", badRequest("Request decode failed: invalid TTL %q", e.TTL)
+		}
+	}
+	for _, id := range e.checkIDs() {
+		cs, ok := s.checks[checkKey{e.Node, id}]
+		if !ok {
+			return "", serverError("Missing check '%s' registration", id)
+		}
+		if cs.check.Status == HealthCritical {
+			return "", serverError("Check '%s' is in critical state", id)
+		}
+	}
+
+	e.ID = newUUID()
+	idx := s.bump("sessions")
+	e.CreateIndex, e.ModifyIndex = idx, idx
+	sess := &session{entry: e, ttl: ttl}
+	s.sessions[e.ID] = sess
+	s.armSession(sess)
+	return e.ID, nil
+}
+
+// armSession (re)starts a session's TTL, with the servers' grace
+func (s *Server) armSession(sess *session) {
+	if sess.ttl <= 0 {
+		return
+	}
+	if sess.timer != nil {
+		sess.timer.Stop()
+	}
+	id := sess.entry.ID
+	grace := sess.ttl * sessionTTLMultiplier
+	sess.deadline = time.Now().Add(grace)
+	sess.timer = time.AfterFunc(grace, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.stopped && s.sessions[id] == sess && !time.Now().Before(sess.deadline) {
+			s.invalidate(id)
+		}
+	})
+}
+
+// invalidate destroys a session, releasing or deleting the keys it
+// locked according to its behavior
+func (s *Server) invalidate(id string) {
+	sess, ok := s.sessions[id]
+	if !ok {
+		return
+	}
+	if sess.timer != nil {
+		sess.timer.Stop()
+	}
+	delete(s.sessions, id)
+	idx := s.bump("sessions", "kvs")
+	until := time.Now().Add(sess.entry.LockDelay)
+	for key, p := range s.kvs {
+		if p.Session != id {
+			continue
+		}
+		if sess.entry.Behavior == SessionBehaviorDelete {
+			delete(s.kvs, key)
+			continue
+		}
+		p.Session = ""
+		p.ModifyIndex = idx
+		s.lockDelays[key] = until
+	}
+}
+
+// invalidateDependents invalidates the sessions depending on a check
+func (s *Server) invalidateDependents(k checkKey) {
+	for id, sess := range s.sessions {
+		if sess.entry.Node != k.node {
+			continue
+		}
+		for _, cid := range sess.entry.checkIDs() {
+			if cid == k.id {
+				s.invalidate(id)
+				break
+			}
+		}
+	}
+}
+
+// Client
+
+// Config configures a Client
+type Config struct {
+	// Address is the agent's host:port. It defaults to 127.0.0.1:8500.
+	Address string
+	// Scheme is http or https; the emulator ignores it
+	Scheme     string
+	Datacenter string
+	Token      string
+	// WaitTime is the default WaitTime of blocking queries
+	WaitTime time.Duration
+}
+
+// DefaultConfig returns the configuration of a client of the local agent
+func DefaultConfig() *Config {
+	return &Config{Address: "127.0.0.1:8500", Scheme: "http"}
+}
+
+// Client talks to an agent of an in-memory cluster, as api.Client does
+// to a Consul agent
+type Client struct {
+	config Config
+}
+
+// NewClient returns a client of the agent at config.Address. As with
+// Consul, the agent is not contacted until the first request.
+func NewClient(config *Config) (*Client, error) {
+	c := *DefaultConfig()
+	if config != nil {
+		if config.Address != "" {
+			c.Address = config.Address
+		}
+		if config.Scheme != "" {
+			c.Scheme = config.Scheme
+		}
+		c.Datacenter, c.Token, c.WaitTime = config.Datacenter, config.Token, config.WaitTime
+	}
+	if c.Scheme != "http" && c.Scheme != "https" {
+		return nil, fmt.Errorf("Unknown protocol scheme: %s", c.Scheme)
+	}
+	c.Address = normalizeAddr(c.Address)
+	return &Client{config: c}, nil
+}
+
+// agent returns the agent the client talks to
+func (c *Client) agent(path string) (*agent, error) {
+	agentsMu.Lock()
+	a := agents[c.config.Address]
+	agentsMu.Unlock()
+	if a == nil {
+		return nil, fmt.Errorf("%s://%s%s: dial tcp %s: connect: connection refused",
+			c.config.Scheme, c.config.Address, path, c.config.Address)
+	}
+	return a, nil
+}
+
+// queryOpts applies the client's defaults to q
+func (c *Client) queryOpts(q *QueryOptions) *QueryOptions {
+	o := new(QueryOptions)
+	if q != nil {
+		*o = *q
+	}
+	if o.Datacenter == "" {
+		o.Datacenter = c.config.Datacenter
+	}
+	if o.WaitIndex != 0 && o.WaitTime == 0 {
+		o.WaitTime = c.config.WaitTime
+	}
+	return o
+}
+
+// writeOpts applies the client's defaults to w
+func (c *Client) writeOpts(w *WriteOptions) *WriteOptions {
+	o := new(WriteOptions)
+	if w != nil {
+		*o = *w
+	}
+	if o.Datacenter == "" {
+		o.Datacenter = c.config.Datacenter
+	}
+	return o
+}
+
+// query runs a read on the client's agent
+func (c *Client) query(path string, q *QueryOptions, run func(a *agent) uint64) (*QueryMeta, error) {
+	a, err := c.agent(path)
+	if err != nil {
+		return nil, err
+	}
+	return a.server.query(c.queryOpts(q), func() uint64 { return run(a) })
+}
+
+// write runs a write on the client's agent
+func (c *Client) write(path string, w *WriteOptions, run func(a *agent) error) (*WriteMeta, error) {
+	a, err := c.agent(path)
+	if err != nil {
+		return nil, err
+	}
+	return a.server.write(c.writeOpts(w), func() error { return run(a) })
+}
+
+// Agent
+
+// Agent is the agent endpoint: the services and checks of the client's
+// node
+type Agent struct {
+	c *Client
+}
+
+// Agent returns the agent endpoint
+func (c *Client) Agent() *Agent {
+	return &Agent{c}
+}
+
+// NodeName returns the name of the agent's node
+func (a *Agent) NodeName() (string, error) {
+	ag, err := a.c.agent("/v1/agent/self")
+	if err != nil {
+		return "", err
+	}
+	return ag.node, nil
+}
+
+// ServiceRegister registers a service and its checks, replacing any
+// service with the same ID
+func (a *Agent) ServiceRegister(r *AgentServiceRegistration) error {
+	_, err := a.c.write("/v1/agent/service/register", nil, func(ag *agent) error {
+		return ag.server.registerService(ag.node, r)
+	})
+	return err
+}
+
+// ServiceDeregister removes a service and its checks
+func (a *Agent) ServiceDeregister(serviceID string) error {
+	_, err := a.c.write("/v1/agent/service/deregister/"+serviceID, nil, func(ag *agent) error {
+		return ag.server.deregisterService(ag.node, serviceID)
+	})
+	return err
+}
+
+// Services returns the agent's services by ID
+func (a *Agent) Services() (map[string]*AgentService, error) {
+	out := make(map[string]*AgentService)
+	_, err := a.c.query("/v1/agent/services", nil, func(ag *agent) uint64 {
+		for k, svc := range ag.server.services {
+			if k.node == ag.node {
+				out[k.id] = svc.clone()
+			}
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Service returns one of the agent's services. With a WaitIndex it
+// blocks until the service changes.
+func (a *Agent) Service(serviceID string, q *QueryOptions) (*AgentService, *QueryMeta, error) {
+	var out *AgentService
+	meta, err := a.c.query("/v1/agent/service/"+serviceID, q, func(ag *agent) uint64 {
+		out = nil
+		if svc, ok := ag.server.services[serviceKey{ag.node, serviceID}]; ok {
+			out = svc.clone()
+			return svc.ModifyIndex
+		}
+		return ag.server.tableIndex("services")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if out == nil {
+		return nil, nil, notFound("unknown service ID: %s", serviceID)
+	}
+	return out, meta, nil
+}
+
+// Checks returns the agent's checks by ID
+func (a *Agent) Checks() (map[string]*AgentCheck, error) {
+	out := make(map[string]*AgentCheck)
+	_, err := a.c.query("/v1/agent/checks", nil, func(ag *agent) uint64 {
+		for k, cs := range ag.server.checks {
+			if k.node != ag.node {
+				continue
+			}
+			hc := cs.check
+			out[k.id] = &AgentCheck{
+				Node:        hc.Node,
+				CheckID:     hc.CheckID,
+				Name:        hc.Name,
+				Status:      hc.Status,
+				Notes:       hc.Notes,
+				Output:      hc.Output,
+				ServiceID:   hc.ServiceID,
+				ServiceName: hc.ServiceName,
+				Type:        hc.Type,
+			}
+		}
+		return 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CheckRegister registers a check, bound to one of the agent's services
+// if ServiceID is set
+func (a *Agent) CheckRegister(r *AgentCheckRegistration) error {
+	_, err := a.c.write("/v1/agent/check/register", nil, func(ag *agent) error {
+		d := r.AgentServiceCheck
+		if err := validateCheck(&d); err != nil {
+			return err
+		}
+		id, name := r.ID, r.Name
+		if id == "" {
+			id = d.CheckID
+		}
+		if name == "" {
+			name = d.Name
+		}
+		if name == "" {
+			return badRequest("Missing check name")
+		}
+		if id == "" {
+			id = name
+		}
+		var svc *AgentService
+		if r.ServiceID != "" {
+			var ok bool
+			if svc, ok = ag.server.services[serviceKey{ag.node, r.ServiceID}]; !ok {
+				return serverError("ServiceID %q does not exist", r.ServiceID)
+			}
+		}
+		notes := r.Notes
+		if notes == "" {
+			notes = d.Notes
+		}
+		ag.server.addCheck(ag.node, id, name, notes, svc, &d, ag.server.bump("checks"))
+		return nil
+	})
+	return err
+}
+
+// CheckDeregister removes a check
+func (a *Agent) CheckDeregister(checkID string) error {
+	_, err := a.c.write("/v1/agent/check/deregister/"+checkID, nil, func(ag *agent) error {
+		k := checkKey{ag.node, checkID}
+		if _, ok := ag.server.checks[k]; !ok {
+			return notFound("Unknown check ID %q. Ensure that the check ID is passed, not the check name.", checkID)
+		}
+		ag.server.removeCheck(k)
+		ag.server.bump("checks")
+		return nil
+	})
+	return err
+}
+
+// UpdateTTL sets the status of a TTL check, "pass", "warn" or "fail" (or
+// "passing", "warning" or "critical"), and restarts its TTL
+func (a *Agent) UpdateTTL(checkID, output, status string) error {
+	switch status {
+	case "pass", HealthPassing:
+		status = HealthPassing
+	case "warn", HealthWarning:
+		status = HealthWarning
+	case "fail", HealthCritical:
+		status = HealthCritical
+	default:
+		return fmt.Errorf("Invalid status: %s", status)
+	}
+	_, err := a.c.write("/v1/agent/check/update/"+checkID, nil, func(ag *agent) error {
+		return ag.server.updateTTL(ag.node, checkID, status, output)
+	})
+	return err
+}
+
+// PassTTL marks a TTL check as passing
+func (a *Agent) PassTTL(checkID, note string) error {
+	return a.UpdateTTL(checkID, note, "pass")
+}
+
+// WarnTTL marks a TTL check as warning
+func (a *Agent) WarnTTL(checkID, note string) error {
+	return a.UpdateTTL(checkID, note, "warn")
+}
+
+// FailTTL marks a TTL check as critical
+func (a *Agent) FailTTL(checkID, note string) error {
+	return a.UpdateTTL(checkID, note, "fail")
+}
+
+// EnableServiceMaintenance puts a service in maintenance mode, which
+// takes it out of passing health queries
+func (a *Agent) EnableServiceMaintenance(serviceID, reason string) error {
+	_, err := a.c.write("/v1/agent/service/maintenance/"+serviceID, nil, func(ag *agent) error {
+		svc, ok := ag.server.services[serviceKey{ag.node, serviceID}]
+		if !ok {
+			return notFound("Unknown service ID %q. Ensure that the service ID is passed, not the service name.", serviceID)
+		}
+		if reason == "" {
+			reason = "Maintenance mode is enabled for this service, but no reason was provided. This is a default message."
+		}
+		ag.server.addMaintCheck(ag.node, serviceMaintCheckIDPrefix+serviceID, "Service Maintenance Mode", reason, svc)
+		return nil
+	})
+	return err
+}
+
+// DisableServiceMaintenance takes a service out of maintenance mode
+func (a *Agent) DisableServiceMaintenance(serviceID string) error {
+	_, err := a.c.write("/v1/agent/service/maintenance/"+serviceID, nil, func(ag *agent) error {
+		if _, ok := ag.server.services[serviceKey{ag.node, serviceID}]; !ok {
+			return notFound("Unknown service ID %q. Ensure that the service ID is passed, not the service name.", serviceID)
+		}
+		ag.server.removeCheck(checkKey{ag.node, serviceMaintCheckIDPrefix + serviceID})
+		ag.server.bump("checks")
+		return nil
+	})
+	return err
+}
+
+// EnableNodeMaintenance puts the agent's node, and so all its services,
+// in maintenance mode
+func (a *Agent) EnableNodeMaintenance(reason string) error {
+	_, err := a.c.write("/v1/agent/maintenance", nil, func(ag *agent) error {
+		if reason == "" {
+			reason = "Maintenance mode is enabled for this node, but no reason was provided. This is a default message."
+		}
+		ag.server.addMaintCheck(ag.node, nodeMaintCheckID, "Node Maintenance Mode", reason, nil)
+		return nil
+	})
+	return err
+}
+
+// DisableNodeMaintenance takes the agent's node out of maintenance mode
+func (a *Agent) DisableNodeMaintenance() error {
+	_, err := a.c.write("/v1/agent/maintenance", nil, func(ag *agent) error {
+		ag.server.removeCheck(checkKey{ag.node, nodeMaintCheckID})
+		ag.server.bump("checks")
+		return nil
+	})
+	return err
+}
+
+// addMaintCheck adds the critical check that marks maintenance mode
+func (s *Server) addMaintCheck(node, id, name, reason string, svc *AgentService) {
+	idx := s.bump("checks")
+	hc := &HealthCheck{
+		Node:        node,
+		CheckID:     id,
+		Name:        name,
+		Status:      HealthCritical,
+		Notes:       reason,
+		Type:        "maintenance",
+		CreateIndex: idx,
+		ModifyIndex: idx,
+	}
+	if svc != nil {
+		hc.ServiceID, hc.ServiceName = svc.ID, svc.Service
+		hc.ServiceTags = append([]string(nil), svc.Tags...)
+	}
+	s.checks[checkKey{node, id}] = &checkState{check: hc}
+	s.invalidateDependents(checkKey{node, id})
+}
+
+// Catalog
+
+// Catalog is the catalog endpoint: the nodes and services of the
+// datacenter
+type Catalog struct {
+	c *Client
+}
+
+// Catalog returns the catalog endpoint
+func (c *Client) Catalog() *Catalog {
+	return &Catalog{c}
+}
+
+// Datacenters lists the known datacenters
+func (c *Catalog) Datacenters() ([]string, error) {
+	var out []string
+	_, err := c.c.query("/v1/catalog/datacenters", nil, func(ag *agent) uint64 {
+		out = []string{ag.server.datacenter}
+		return 0
+	})
+	return out, err
+}
+
+// Nodes lists the nodes, sorted by name
+func (c *Catalog) Nodes(q *QueryOptions) ([]*Node, *QueryMeta, error) {
+	var out []*Node
+	meta, err := c.c.query("/v1/catalog/nodes", q, func(ag *agent) uint64 {
+		out = out[:0]
+		for _, n := range ag.server.nodes {
+			out = append(out, n.clone())
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Node < out[j].Node })
+		return ag.server.tableIndex("nodes")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Services maps the name of every service to the tags of its instances
+func (c *Catalog) Services(q *QueryOptions) (map[string][]string, *QueryMeta, error) {
+	var out map[string][]string
+	meta, err := c.c.query("/v1/catalog/services", q, func(ag *agent) uint64 {
+		out = make(map[string][]string)
+		seen := make(map[string]map[string]bool)
+		for _, svc := range ag.server.services {
+			if seen[svc.Service] == nil {
+				seen[svc.Service] = make(map[string]bool)
+				out[svc.Service] = []string{}
+			}
+			for _, t := range svc.Tags {
+				if !seen[svc.Service][t] {
+					seen[svc.Service][t] = true
+					out[svc.Service] = append(out[svc.Service], t)
+				}
+			}
+		}
+		for _, tags := range out {
+			sort.Strings(tags)
+		}
+		return ag.server.tableIndex("services")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Service lists the instances of a service, only those carrying tag if
+// it is not empty
+func (c *Catalog) Service(service, tag string, q *QueryOptions) ([]*CatalogService, *QueryMeta, error) {
+	var out []*CatalogService
+	meta, err := c.c.query("/v1/catalog/service/"+service, q, func(ag *agent) uint64 {
+		out = nil
+		s := ag.server
+		for _, k := range s.instances(service, tag) {
+			svc, n := s.services[k], s.nodes[k.node]
+			out = append(out, &CatalogService{
+				ID:                       n.ID,
+				Node:                     n.Node,
+				Address:                  n.Address,
+				Datacenter:               n.Datacenter,
+				TaggedAddresses:          cloneMap(n.TaggedAddresses),
+				NodeMeta:                 cloneMap(n.Meta),
+				ServiceID:                svc.ID,
+				ServiceName:              svc.Service,
+				ServiceAddress:           svc.Address,
+				ServiceTags:              append([]string(nil), svc.Tags...),
+				ServiceMeta:              cloneMap(svc.Meta),
+				ServicePort:              svc.Port,
+				ServiceWeights:           svc.Weights,
+				ServiceEnableTagOverride: svc.EnableTagOverride,
+				CreateIndex:              svc.CreateIndex,
+				ModifyIndex:              svc.ModifyIndex,
+			})
+		}
+		return s.tableIndex("services")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Node returns a node and its services, or nil if there is no such node
+func (c *Catalog) Node(node string, q *QueryOptions) (*CatalogNode, *QueryMeta, error) {
+	var out *CatalogNode
+	meta, err := c.c.query("/v1/catalog/node/"+node, q, func(ag *agent) uint64 {
+		out = nil
+		n, ok := ag.server.nodes[node]
+		if ok {
+			out = &CatalogNode{Node: n.clone(), Services: make(map[string]*AgentService)}
+			for k, svc := range ag.server.services {
+				if k.node == node {
+					out.Services[k.id] = svc.clone()
+				}
+			}
+		}
+		return ag.server.tableIndex("nodes", "services")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Health
+
+// Health is the health endpoint: instances and checks by status
+type Health struct {
+	c *Client
+}
+
+// Health returns the health endpoint
+func (c *Client) Health() *Health {
+	return &Health{c}
+}
+
+// Service lists the instances of a service with their checks, only those
+// carrying tag if it is not empty, and only those whose checks all pass
+// if passingOnly is set
+func (h *Health) Service(service, tag string, passingOnly bool, q *QueryOptions) ([]*ServiceEntry, *QueryMeta, error) {
+	var out []*ServiceEntry
+	meta, err := h.c.query("/v1/health/service/"+service, q, func(ag *agent) uint64 {
+		out = nil
+		s := ag.server
+		for _, k := range s.instances(service, tag) {
+			checks := append(s.nodeChecks(k.node), s.serviceChecks(k.node, k.id)...)
+			if passingOnly && checks.AggregatedStatus() != HealthPassing {
+				continue
+			}
+			out = append(out, &ServiceEntry{
+				Node:    s.nodes[k.node].clone(),
+				Service: s.services[k].clone(),
+				Checks:  checks,
+			})
+		}
+		return s.tableIndex("nodes", "services", "checks")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Checks lists the checks of every instance of a service
+func (h *Health) Checks(service string, q *QueryOptions) (HealthChecks, *QueryMeta, error) {
+	var out HealthChecks
+	meta, err := h.c.query("/v1/health/checks/"+service, q, func(ag *agent) uint64 {
+		out = HealthChecks{}
+		for _, cs := range ag.server.checks {
+			if cs.check.ServiceName == service {
+				out = append(out, cs.check.clone())
+			}
+		}
+		sortChecks(out)
+		return ag.server.tableIndex("checks")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Node lists the checks of a node and its services
+func (h *Health) Node(node string, q *QueryOptions) (HealthChecks, *QueryMeta, error) {
+	var out HealthChecks
+	meta, err := h.c.query("/v1/health/node/"+node, q, func(ag *agent) uint64 {
+		out = HealthChecks{}
+		for k, cs := range ag.server.checks {
+			if k.node == node {
+				out = append(out, cs.check.clone())
+			}
+		}
+		sortChecks(out)
+		return ag.server.tableIndex("checks")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// State lists the checks in a state: passing, warning, critical, or any
+func (h *Health) State(state string, q *QueryOptions) (HealthChecks, *QueryMeta, error) {
+	switch state {
+	case HealthAny, HealthPassing, HealthWarning, HealthCritical:
+	default:
+		return nil, nil, fmt.Errorf("Unsupported state: %v", state)
+	}
+	var out HealthChecks
+	meta, err := h.c.query("/v1/health/state/"+state, q, func(ag *agent) uint64 {
+		out = HealthChecks{}
+		for _, cs := range ag.server.checks {
+			if state == HealthAny || cs.check.Status == state {
+				out = append(out, cs.check.clone())
+			}
+		}
+		sortChecks(out)
+		return ag.server.tableIndex("checks")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// KV
+
+// KV is the key-value endpoint
+type KV struct {
+	c *Client
+}
+
+// KV returns the key-value endpoint
+func (c *Client) KV() *KV {
+	return &KV{c}
+}
+
+// Get reads a key. A missing key is returned as nil, without an error.
+// With a WaitIndex it blocks until the key changes.
+func (k *KV) Get(key string, q *QueryOptions) (*KVPair, *QueryMeta, error) {
+	var out *KVPair
+	meta, err := k.c.query("/v1/kv/"+key, q, func(ag *agent) uint64 {
+		out = nil
+		if p, ok := ag.server.kvs[key]; ok {
+			out = p.clone()
+			return p.ModifyIndex
+		}
+		return ag.server.tableIndex("kvs")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// List reads the keys starting with prefix, sorted
+func (k *KV) List(prefix string, q *QueryOptions) (KVPairs, *QueryMeta, error) {
+	var out KVPairs
+	meta, err := k.c.query("/v1/kv/"+prefix+"?recurse", q, func(ag *agent) uint64 {
+		out = nil
+		for key, p := range ag.server.kvs {
+			if strings.HasPrefix(key, prefix) {
+				out = append(out, p.clone())
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+		return ag.server.tableIndex("kvs")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Keys lists the keys starting with prefix, sorted. With a separator,
+// keys are cut after the first separator past the prefix and listed
+// once, like the entries of a directory.
+func (k *KV) Keys(prefix, separator string, q *QueryOptions) ([]string, *QueryMeta, error) {
+	var out []string
+	meta, err := k.c.query("/v1/kv/"+prefix+"?keys", q, func(ag *agent) uint64 {
+		seen := make(map[string]bool)
+		out = nil
+		for key := range ag.server.kvs {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if separator != "" {
+				if i := strings.Index(key[len(prefix):], separator); i >= 0 {
+					key = key[:len(prefix)+i+len(separator)]
+				}
+			}
+			if !seen[key] {
+				seen[key] = true
+				out = append(out, key)
+			}
+		}
+		sort.Strings(out)
+		return ag.server.tableIndex("kvs")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Put writes a key's value and flags
+func (k *KV) Put(p *KVPair, q *WriteOptions) (*WriteMeta, error) {
+	if err := validateKey(p.Key); err != nil {
+		return nil, err
+	}
+	return k.c.write("/v1/kv/"+p.Key, q, func(ag *agent) error {
+		ag.server.putKV(p)
+		return nil
+	})
+}
+
+// CAS writes a key if its ModifyIndex is still p.ModifyIndex. A
+// ModifyIndex of 0 writes the key only if it does not exist.
+func (k *KV) CAS(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
+	if err := validateKey(p.Key); err != nil {
+		return false, nil, err
+	}
+	var ok bool
+	wm, err := k.c.write("/v1/kv/"+p.Key+"?cas", q, func(ag *agent) error {
+		cur, exists := ag.server.kvs[p.Key]
+		if (p.ModifyIndex == 0 && exists) || (p.ModifyIndex != 0 && (!exists || cur.ModifyIndex != p.ModifyIndex)) {
+			return nil
+		}
+		ag.server.putKV(p)
+		ok = true
+		return nil
+	})
+	return ok, wm, err
+}
+
+// Acquire locks a key for p.Session and writes p's value. It returns
+// false if another session holds the lock, or the key is in its lock
+// delay.
+func (k *KV) Acquire(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
+	if err := validateKey(p.Key); err != nil {
+		return false, nil, err
+	}
+	var ok bool
+	wm, err := k.c.write("/v1/kv/"+p.Key+"?acquire", q, func(ag *agent) (err error) {
+		ok, err = ag.server.acquire(p)
+		return err
+	})
+	return ok, wm, err
+}
+
+// Release unlocks a key held by p.Session and writes p's value
+func (k *KV) Release(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
+	if err := validateKey(p.Key); err != nil {
+		return false, nil, err
+	}
+	var ok bool
+	wm, err := k.c.write("/v1/kv/"+p.Key+"?release", q, func(ag *agent) error {
+		ok = ag.server.release(p)
+		return nil
+	})
+	return ok, wm, err
+}
+
+// Delete removes a key
+func (k *KV) Delete(key string, w *WriteOptions) (*WriteMeta, error) {
+	return k.c.write("/v1/kv/"+key, w, func(ag *agent) error {
+		if _, ok := ag.server.kvs[key]; ok {
+			delete(ag.server.kvs, key)
+			ag.server.bump("kvs")
+		}
+		return nil
+	})
+}
+
+// DeleteCAS removes a key if its ModifyIndex is still p.ModifyIndex
+func (k *KV) DeleteCAS(p *KVPair, q *WriteOptions) (bool, *WriteMeta, error) {
+	if err := validateKey(p.Key); err != nil {
+		return false, nil, err
+	}
+	var ok bool
+	wm, err := k.c.write("/v1/kv/"+p.Key+"?cas", q, func(ag *agent) error {
+		cur, exists := ag.server.kvs[p.Key]
+		if !exists || cur.ModifyIndex != p.ModifyIndex {
+			return nil
+		}
+		delete(ag.server.kvs, p.Key)
+		ag.server.bump("kvs")
+		ok = true
+		return nil
+	})
+	return ok, wm, err
+}
+
+// DeleteTree removes every key starting with prefix
+func (k *KV) DeleteTree(prefix string, w *WriteOptions) (*WriteMeta, error) {
+	return k.c.write("/v1/kv/"+prefix+"?recurse", w, func(ag *agent) error {
+		deleted := false
+		for key := range ag.server.kvs {
+			if strings.HasPrefix(key, prefix) {
+				delete(ag.server.kvs, key)
+				deleted = true
+			}
+		}
+		if deleted {
+			ag.server.bump("kvs")
+		}
+		return nil
+	})
+}
+
+// Session
+
+// Session is the session endpoint
+type Session struct {
+	c *Client
+}
+
+// Session returns the session endpoint
+func (c *Client) Session() *Session {
+	return &Session{c}
+}
+
+// Create creates a session on the agent's node, or se.Node. The checks
+// it depends on must exist and not be critical.
+func (s *Session) Create(se *SessionEntry, q *WriteOptions) (string, *WriteMeta, error) {
+	if se == nil {
+		se = &SessionEntry{}
+	}
+	var id string
+	wm, err := s.c.write("/v1/session/create", q, func(ag *agent) (err error) {
+		id, err = ag.server.createSession(ag.node, se)
+		return err
+	})
+	return id, wm, err
+}
+
+// CreateNoChecks creates a session that depends on no check, so only
+// Destroy or its TTL end it
+func (s *Session) CreateNoChecks(se *SessionEntry, q *WriteOptions) (string, *WriteMeta, error) {
+	e := &SessionEntry{}
+	if se != nil {
+		e = se.clone()
+	}
+	e.Checks, e.NodeChecks, e.ServiceChecks = []string{}, []string{}, nil
+	return s.Create(e, q)
+}
+
+// Destroy invalidates a session
+func (s *Session) Destroy(id string, q *WriteOptions) (*WriteMeta, error) {
+	return s.c.write("/v1/session/destroy/"+id, q, func(ag *agent) error {
+		ag.server.invalidate(id)
+		return nil
+	})
+}
+
+// Renew restarts a session's TTL. It returns nil, without an error, if
+// the session is gone.
+func (s *Session) Renew(id string, q *WriteOptions) (*SessionEntry, *WriteMeta, error) {
+	var out *SessionEntry
+	wm, err := s.c.write("/v1/session/renew/"+id, q, func(ag *agent) error {
+		out = nil
+		if sess, ok := ag.server.sessions[id]; ok {
+			ag.server.armSession(sess)
+			out = sess.entry.clone()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, wm, nil
+}
+
+// RenewPeriodic renews a session every half of initialTTL until doneCh
+// is closed, then destroys it. It returns ErrSessionExpired if the
+// session goes away first.
+func (s *Session) RenewPeriodic(initialTTL string, id string, q *WriteOptions, doneCh <-chan struct{}) error {
+	ttl, err := time.ParseDuration(initialTTL)
+	if err != nil {
+		return err
+	}
+	ctx := q.Context()
+	ticker := time.NewTicker(ttl / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			entry, _, err := s.Renew(id, q)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				return ErrSessionExpired
+			}
+		case <-doneCh:
+			_, err := s.Destroy(id, q)
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Info returns a session, or nil if it is gone
+func (s *Session) Info(id string, q *QueryOptions) (*SessionEntry, *QueryMeta, error) {
+	var out *SessionEntry
+	meta, err := s.c.query("/v1/session/info/"+id, q, func(ag *agent) uint64 {
+		out = nil
+		if sess, ok := ag.server.sessions[id]; ok {
+			out = sess.entry.clone()
+		}
+		return ag.server.tableIndex("sessions")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// List lists the sessions, sorted by creation
+func (s *Session) List(q *QueryOptions) ([]*SessionEntry, *QueryMeta, error) {
+	return s.list("/v1/session/list", "", q)
+}
+
+// Node lists the sessions of a node, sorted by creation
+func (s *Session) Node(node string, q *QueryOptions) ([]*SessionEntry, *QueryMeta, error) {
+	return s.list("/v1/session/node/"+node, node, q)
+}
+
+func (s *Session) list(path, node string, q *QueryOptions) ([]*SessionEntry, *QueryMeta, error) {
+	var out []*SessionEntry
+	meta, err := s.c.query(path, q, func(ag *agent) uint64 {
+		out = nil
+		for _, sess := range ag.server.sessions {
+			if node == "" || sess.entry.Node == node {
+				out = append(out, sess.entry.clone())
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].CreateIndex < out[j].CreateIndex })
+		return ag.server.tableIndex("sessions")
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, meta, nil
+}
+
+// Status
+
+// Status is the status endpoint
+type Status struct {
+	c *Client
+}
+
+// Status returns the status endpoint
+func (c *Client) Status() *Status {
+	return &Status{c}
+}
+
+// Leader returns the Raft address of the cluster's leader
+func (s *Status) Leader() (string, error) {
+	var out string
+	_, err := s.c.query("/v1/status/leader", nil, func(ag *agent) uint64 {
+		out = ag.server.raftAddr()
+		return 0
+	})
+	return out, err
+}
+
+// Peers returns the Raft addresses of the cluster's servers
+func (s *Status) Peers() ([]string, error) {
+	var out []string
+	_, err := s.c.query("/v1/status/peers", nil, func(ag *agent) uint64 {
+		out = []string{ag.server.raftAddr()}
+		return 0
+	})
+	return out, err
+}
+
+// raftAddr is the server's address on the Raft port
+func (s *Server) raftAddr() string {
+	return net.JoinHostPort(s.nodes[s.NodeName].Address, "8300")
+}
+
+// Go-kit service discovery
+
+// Logger is a go-kit logger, such as the Go-kit emulator's
+type Logger interface {
+	Log(keyvals ...interface{}) error
+}
+
+// KitClient is the registry client of go-kit's sd/consul. In the Go-kit
+// emulator's instancers the watched "prefix" is a service name: they
+// track the addresses of its healthy instances.
+type KitClient struct {
+	client      *Client
+	ctx         context.Context
+	tags        []string
+	passingOnly bool
+}
+
+// NewKitClient returns a KitClient of client's agent whose entries are
+// the instances carrying every one of tags, and only those passing their
+// checks if passingOnly is set. The client stops watching when ctx is
+// done.
+func NewKitClient(ctx context.Context, client *Client, tags []string, passingOnly bool) *KitClient {
+	return &KitClient{client: client, ctx: ctx, tags: tags, passingOnly: passingOnly}
+}
+
+// Register registers a service with the agent
+func (c *KitClient) Register(r *AgentServiceRegistration) error {
+	return c.client.Agent().ServiceRegister(r)
+}
+
+// Deregister removes a service from the agent
+func (c *KitClient) Deregister(r *AgentServiceRegistration) error {
+	id := r.ID
+	if id == "" {
+		id = r.Name
+	}
+	return c.client.Agent().ServiceDeregister(id)
+}
+
+// Service lists the instances of a service, as Health.Service
+func (c *KitClient) Service(service, tag string, passingOnly bool, q *QueryOptions) ([]*ServiceEntry, *QueryMeta, error) {
+	return c.client.Health().Service(service, tag, passingOnly, q)
+}
+
+// GetEntries returns the host:port of each instance of service
+func (c *KitClient) GetEntries(service string) ([]string, error) {
+	entries, _, err := c.entries(service, nil)
+	return entries, err
+}
+
+// entries runs the health query behind GetEntries and WatchPrefix
+func (c *KitClient) entries(service string, q *QueryOptions) ([]string, uint64, error) {
+	var tag string
+	if len(c.tags) > 0 {
+		tag = c.tags[0]
+	}
+	q = q.WithContext(c.ctx)
+	svcs, meta, err := c.client.Health().Service(service, tag, c.passingOnly, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	var out []string
+	for _, e := range svcs {
+		if !hasAllTags(e.Service, c.tags) {
+			continue
+		}
+		addr := e.Node.Address
+		if e.Service.Address != "" {
+			addr = e.Service.Address
+		}
+		out = append(out, net.JoinHostPort(addr, fmt.Sprint(e.Service.Port)))
+	}
+	return out, meta.LastIndex, nil
+}
+
+func hasAllTags(svc *AgentService, tags []string) bool {
+	for _, t := range tags {
+		if !svc.hasTag(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchPrefix signals ch once, then whenever the instances of service
+// may have changed, until the client's context is done. It runs blocking
+// queries, backing off for a second after an error.
+func (c *KitClient) WatchPrefix(service string, ch chan struct{}) {
+	var index uint64
+	for {
+		_, idx, err := c.entries(service, &QueryOptions{WaitIndex: index})
+		if c.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			select {
+			case <-time.After(time.Second):
+				continue
+			case <-c.ctx.Done():
+				return
+			}
+		}
+		if index != 0 && idx == index {
+			continue
+		}
+		index = idx
+		select {
+		case ch <- struct{}{}:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Registrar registers one service instance, as go-kit's consul.Registrar
+type Registrar struct {
+	client       *KitClient
+	registration *AgentServiceRegistration
+	logger       Logger
+}
+
+// NewRegistrar returns a Registrar for r
+func NewRegistrar(client *KitClient, r *AgentServiceRegistration, logger Logger) *Registrar {
+	return &Registrar{client: client, registration: r, logger: logger}
+}
+
+func (r *Registrar) log(keyvals ...interface{}) {
+	reg := r.registration
+	r.logger.Log(append([]interface{}{"service", reg.Name, "tags", fmt.Sprint(reg.Tags), "address", reg.Address}, keyvals...)...)
+}
+
+// Register registers the service, logging the outcome
+func (r *Registrar) Register() {
+	if err := r.client.Register(r.registration); err != nil {
+		r.log("err", err)
+		return
+	}
+	r.log("action", "register")
+}
+
+// Deregister deregisters the service, logging the outcome
+func (r *Registrar) Deregister() {
+	if err := r.client.Deregister(r.registration); err != nil {
+		r.log("err", err)
+		return
+	}
+	r.log("action", "deregister")
+}
+
+// Viper remote provider
+
+// ErrRemoteKeyNotFound is returned by ViperStore.Get for a missing key
+var ErrRemoteKeyNotFound = errors.New("key not found")
+
+// ViperStore serves configuration documents stored in keys to the Viper
+// emulator's remote provider, as viper's consul provider does. Register
+// it with viper.RegisterRemoteStore("consul", endpoint, store).
+type ViperStore struct {
+	kv *KV
+}
+
+// NewViperStore returns a ViperStore reading through client
+func NewViperStore(client *Client) *ViperStore {
+	return &ViperStore{kv: client.KV()}
+}
+
+// Get returns the document stored under path
+func (r *ViperStore) Get(path string) ([]byte, error) {
+	p, _, err := r.kv.Get(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrRemoteKeyNotFound
+	}
+	return p.Value, nil
+}
+
+// Watch sends every new version of the document under path until stop
+// is closed, using blocking queries
+func (r *ViperStore) Watch(path string, stop <-chan struct{}) <-chan []byte {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		defer cancel()
+		var index uint64
+		if _, meta, err := r.kv.Get(path, nil); err == nil {
+			index = meta.LastIndex
+		}
+		for {
+			p, meta, err := r.kv.Get(path, (&QueryOptions{WaitIndex: index}).WithContext(ctx))
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				select {
+				case <-time.After(time.Second):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			if meta.LastIndex == index {
+				continue
+			}
+			index = meta.LastIndex
+			if p == nil {
+				continue
+			}
+			select {
+			case out <- p.Value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Helpers
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
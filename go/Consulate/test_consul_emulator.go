@@ -0,0 +1,827 @@
+package main
+
+// Developed by PowerShield, as an alternative to Consul and its Go API client
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// newClient returns a client of a fresh cluster's agent
+func newClient() (*Client, *Server) {
+	server := NewServer()
+	client, _ := NewClient(&Config{Address: server.Addr})
+	return client, server
+}
+
+// eventually polls cond for up to a second
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+// statusOf returns the status of one of the agent's checks
+func statusOf(client *Client, checkID string) string {
+	checks, err := client.Agent().Checks()
+	if err != nil || checks[checkID] == nil {
+		return ""
+	}
+	return checks[checkID].Status
+}
+
+// passing returns the IDs of the passing instances of service
+func passing(client *Client, service string) string {
+	entries, _, err := client.Health().Service(service, "", true, nil)
+	if err != nil {
+		return "error"
+	}
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.Service.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Log(keyvals ...interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(keyvals...))
+	return nil
+}
+
+func testKVPutAndGet() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv := client.KV()
+
+	if _, err := kv.Put(&KVPair{Key: "config/db", Value: []byte("postgres://a"), Flags: 42}, nil); err != nil {
+		return false
+	}
+	p, meta, err := kv.Get("config/db", nil)
+	if err != nil || p == nil || string(p.Value) != "postgres://a" || p.Flags != 42 {
+		return false
+	}
+	if p.CreateIndex != p.ModifyIndex || meta.LastIndex != p.ModifyIndex || !meta.KnownLeader {
+		return false
+	}
+
+	// An update keeps the creation index
+	kv.Put(&KVPair{Key: "config/db", Value: []byte("postgres://b")}, nil)
+	p2, _, _ := kv.Get("config/db", nil)
+	if p2.CreateIndex != p.CreateIndex || p2.ModifyIndex <= p.ModifyIndex || p2.Flags != 0 {
+		return false
+	}
+
+	// Missing keys are nil, not errors
+	if missing, _, err := kv.Get("config/none", nil); missing != nil || err != nil {
+		return false
+	}
+	if _, err := kv.Put(&KVPair{Key: "/config/db"}, nil); err == nil || !strings.Contains(err.Error(), "must not begin with a '/'") {
+		return false
+	}
+	kv.Delete("config/db", nil)
+	gone, _, _ := kv.Get("config/db", nil)
+	return gone == nil
+}
+
+func testKVListAndKeys() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv := client.KV()
+
+	for _, key := range []string{"app/db/host", "app/db/port", "app/name", "apple", "other"} {
+		kv.Put(&KVPair{Key: key, Value: []byte(key)}, nil)
+	}
+	pairs, _, err := kv.List("app/", nil)
+	if err != nil || len(pairs) != 3 || pairs[0].Key != "app/db/host" || pairs[2].Key != "app/name" {
+		return false
+	}
+	keys, _, _ := kv.Keys("app", "", nil)
+	if strings.Join(keys, ",") != "app/db/host,app/db/port,app/name,apple" {
+		return false
+	}
+
+	// A separator lists keys like directory entries
+	keys, _, _ = kv.Keys("app/", "/", nil)
+	if strings.Join(keys, ",") != "app/db/,app/name" {
+		return false
+	}
+
+	kv.DeleteTree("app/", nil)
+	keys, _, _ = kv.Keys("", "", nil)
+	return strings.Join(keys, ",") == "apple,other"
+}
+
+func testCheckAndSet() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv := client.KV()
+
+	// ModifyIndex 0 creates only
+	if ok, _, err := kv.CAS(&KVPair{Key: "counter", Value: []byte("1")}, nil); !ok || err != nil {
+		return false
+	}
+	if ok, _, _ := kv.CAS(&KVPair{Key: "counter", Value: []byte("x")}, nil); ok {
+		return false
+	}
+
+	p, _, _ := kv.Get("counter", nil)
+	stale := p.ModifyIndex
+	p.Value = []byte("2")
+	if ok, _, _ := kv.CAS(p, nil); !ok {
+		return false
+	}
+	if ok, _, _ := kv.CAS(&KVPair{Key: "counter", Value: []byte("3"), ModifyIndex: stale}, nil); ok {
+		return false
+	}
+	if cur, _, _ := kv.Get("counter", nil); string(cur.Value) != "2" {
+		return false
+	}
+
+	cur, _, _ := kv.Get("counter", nil)
+	if ok, _, _ := kv.DeleteCAS(&KVPair{Key: "counter", ModifyIndex: stale}, nil); ok {
+		return false
+	}
+	if ok, _, _ := kv.DeleteCAS(cur, nil); !ok {
+		return false
+	}
+	gone, _, _ := kv.Get("counter", nil)
+	return gone == nil
+}
+
+func testServiceRegistration() bool {
+	client, server := newClient()
+	defer server.Close()
+	agent := client.Agent()
+
+	err := agent.ServiceRegister(&AgentServiceRegistration{
+		ID:      "web-1",
+		Name:    "web",
+		Tags:    []string{"v1", "primary"},
+		Port:    8080,
+		Address: "10.0.0.1",
+		Meta:    map[string]string{"version": "1.0"},
+	})
+	if err != nil {
+		return false
+	}
+	// The ID defaults to the name
+	agent.ServiceRegister(&AgentServiceRegistration{Name: "web", Tags: []string{"v2"}, Port: 8081})
+	agent.ServiceRegister(&AgentServiceRegistration{Name: "db", Port: 5432})
+
+	services, _ := agent.Services()
+	if len(services) != 3 || services["web-1"].Meta["version"] != "1.0" || services["web"].Service != "web" {
+		return false
+	}
+
+	catalog := client.Catalog()
+	names, _, err := catalog.Services(nil)
+	if err != nil || len(names) != 2 || strings.Join(names["web"], ",") != "primary,v1,v2" || len(names["db"]) != 0 {
+		return false
+	}
+	instances, _, _ := catalog.Service("web", "v1", nil)
+	if len(instances) != 1 || instances[0].ServiceAddress != "10.0.0.1" || instances[0].Node != server.NodeName || instances[0].Address != "127.0.0.1" {
+		return false
+	}
+
+	// Re-registering replaces the service
+	agent.ServiceRegister(&AgentServiceRegistration{ID: "web-1", Name: "web", Port: 9090})
+	instances, _, _ = catalog.Service("web", "v1", nil)
+	if len(instances) != 0 {
+		return false
+	}
+
+	if err := agent.ServiceDeregister("db"); err != nil {
+		return false
+	}
+	err = agent.ServiceDeregister("db")
+	if se, ok := err.(StatusError); !ok || se.Code != 404 {
+		return false
+	}
+	if err := agent.ServiceRegister(&AgentServiceRegistration{ID: "x"}); err == nil {
+		return false
+	}
+	dcs, _ := catalog.Datacenters()
+	return len(dcs) == 1 && dcs[0] == "dc1"
+}
+
+func testTTLChecks() bool {
+	client, server := newClient()
+	defer server.Close()
+	agent := client.Agent()
+
+	agent.ServiceRegister(&AgentServiceRegistration{
+		ID:    "api-1",
+		Name:  "api",
+		Port:  8080,
+		Check: &AgentServiceCheck{TTL: "100ms"},
+	})
+	agent.ServiceRegister(&AgentServiceRegistration{
+		ID:    "api-2",
+		Name:  "api",
+		Port:  8081,
+		Check: &AgentServiceCheck{TTL: "10s", Status: HealthPassing},
+	})
+
+	// New checks start critical unless given a status
+	if statusOf(client, "service:api-1") != HealthCritical || passing(client, "api") != "api-2" {
+		return false
+	}
+	if err := agent.PassTTL("service:api-1", "ok"); err != nil {
+		return false
+	}
+	if passing(client, "api") != "api-1,api-2" {
+		return false
+	}
+	entries, _, _ := client.Health().Service("api", "", false, nil)
+	if len(entries) != 2 || len(entries[0].Checks) != 2 || entries[0].Checks.AggregatedStatus() != HealthPassing {
+		return false
+	}
+
+	// Warning is not passing
+	agent.WarnTTL("service:api-2", "slow")
+	if passing(client, "api") != "api-1" || statusOf(client, "service:api-2") != HealthWarning {
+		return false
+	}
+
+	// A TTL check that is not updated fails
+	if !eventually(func() bool { return statusOf(client, "service:api-1") == HealthCritical }) {
+		return false
+	}
+	checks, _ := agent.Checks()
+	if checks["service:api-1"].Output != "TTL expired" || checks["service:api-1"].Type != "ttl" {
+		return false
+	}
+	critical, _, _ := client.Health().State(HealthCritical, nil)
+	if len(critical) != 1 || critical[0].ServiceID != "api-1" {
+		return false
+	}
+
+	err := agent.UpdateTTL("service:api-9", "", "pass")
+	if se, ok := err.(StatusError); !ok || se.Code != 404 {
+		return false
+	}
+	if err := agent.UpdateTTL("service:api-1", "", "unknown"); err == nil {
+		return false
+	}
+	return agent.ServiceRegister(&AgentServiceRegistration{Name: "bad", Check: &AgentServiceCheck{}}) != nil
+}
+
+func testHTTPAndTCPChecks() bool {
+	client, server := newClient()
+	defer server.Close()
+	agent := client.Agent()
+
+	var code int32 = http.StatusOK
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&code)))
+		fmt.Fprint(w, "status")
+	}))
+	defer web.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return false
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	agent.ServiceRegister(&AgentServiceRegistration{
+		ID:   "web",
+		Name: "web",
+		Checks: AgentServiceChecks{
+			{HTTP: web.URL + "/health", Interval: "10ms"},
+			{TCP: ln.Addr().String(), Interval: "10ms"},
+		},
+	})
+	if !eventually(func() bool { return passing(client, "web") == "web" }) {
+		return false
+	}
+	checks, _ := agent.Checks()
+	if !strings.Contains(checks["service:web:1"].Output, "200 OK") || checks["service:web:2"].Type != "tcp" {
+		return false
+	}
+
+	// 429 warns, other errors are critical
+	atomic.StoreInt32(&code, http.StatusTooManyRequests)
+	if !eventually(func() bool { return statusOf(client, "service:web:1") == HealthWarning }) {
+		return false
+	}
+	atomic.StoreInt32(&code, http.StatusServiceUnavailable)
+	if !eventually(func() bool { return statusOf(client, "service:web:1") == HealthCritical }) {
+		return false
+	}
+	ln.Close()
+	if !eventually(func() bool { return statusOf(client, "service:web:2") == HealthCritical }) {
+		return false
+	}
+
+	// Checks can be added and removed on their own
+	err = agent.CheckRegister(&AgentCheckRegistration{
+		ID:                "disk",
+		Name:              "Disk space",
+		AgentServiceCheck: AgentServiceCheck{TTL: "1m", Status: HealthPassing},
+	})
+	if err != nil || statusOf(client, "disk") != HealthPassing {
+		return false
+	}
+	if err := agent.CheckDeregister("disk"); err != nil || statusOf(client, "disk") != "" {
+		return false
+	}
+	return agent.CheckRegister(&AgentCheckRegistration{Name: "x", ServiceID: "none", AgentServiceCheck: AgentServiceCheck{TTL: "1m"}}) != nil
+}
+
+func testDeregisterCriticalServices() bool {
+	client, server := newClient()
+	defer server.Close()
+	agent := client.Agent()
+
+	agent.ServiceRegister(&AgentServiceRegistration{
+		Name: "worker",
+		Check: &AgentServiceCheck{
+			TTL:                            "30ms",
+			Status:                         HealthPassing,
+			DeregisterCriticalServiceAfter: "50ms",
+		},
+	})
+
+	// Heartbeats keep it registered
+	for i := 0; i < 6; i++ {
+		time.Sleep(15 * time.Millisecond)
+		agent.PassTTL("service:worker", "")
+	}
+	if services, _ := agent.Services(); services["worker"] == nil {
+		return false
+	}
+
+	// Once critical long enough, it is reaped with its checks
+	return eventually(func() bool {
+		services, _ := agent.Services()
+		return services["worker"] == nil && statusOf(client, "service:worker") == ""
+	})
+}
+
+func testMaintenanceMode() bool {
+	client, server := newClient()
+	defer server.Close()
+	agent := client.Agent()
+
+	agent.ServiceRegister(&AgentServiceRegistration{Name: "cache", Check: &AgentServiceCheck{TTL: "1m", Status: HealthPassing}})
+	if err := agent.EnableServiceMaintenance("cache", "upgrading"); err != nil || passing(client, "cache") != "" {
+		return false
+	}
+	entries, _, _ := client.Health().Service("cache", "", false, nil)
+	if len(entries) != 1 || entries[0].Checks.AggregatedStatus() != HealthMaint {
+		return false
+	}
+	agent.DisableServiceMaintenance("cache")
+	if passing(client, "cache") != "cache" {
+		return false
+	}
+
+	// Node maintenance covers every service of the node
+	agent.EnableNodeMaintenance("")
+	if passing(client, "cache") != "" {
+		return false
+	}
+	checks, _, _ := client.Health().Node(server.NodeName, nil)
+	if len(checks) != 3 {
+		return false
+	}
+	agent.DisableNodeMaintenance()
+	if passing(client, "cache") != "cache" {
+		return false
+	}
+	return agent.EnableServiceMaintenance("none", "") != nil
+}
+
+func testSessionsAndLocks() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv, sessions := client.KV(), client.Session()
+
+	leader, _, err := sessions.Create(&SessionEntry{Name: "leader", LockDelay: time.Millisecond}, nil)
+	if err != nil || leader == "" {
+		return false
+	}
+	follower, _, _ := sessions.CreateNoChecks(&SessionEntry{Name: "follower"}, nil)
+
+	info, _, _ := sessions.Info(leader, nil)
+	if info == nil || info.Behavior != SessionBehaviorRelease || info.Node != server.NodeName || strings.Join(info.Checks, ",") != "serfHealth" {
+		return false
+	}
+
+	if ok, _, err := kv.Acquire(&KVPair{Key: "service/leader", Value: []byte("node-a"), Session: leader}, nil); !ok || err != nil {
+		return false
+	}
+	if ok, _, _ := kv.Acquire(&KVPair{Key: "service/leader", Value: []byte("node-b"), Session: follower}, nil); ok {
+		return false
+	}
+	// The holder may acquire again without bumping the lock index
+	kv.Acquire(&KVPair{Key: "service/leader", Value: []byte("node-a2"), Session: leader}, nil)
+	p, _, _ := kv.Get("service/leader", nil)
+	if p.Session != leader || p.LockIndex != 1 || string(p.Value) != "node-a2" {
+		return false
+	}
+
+	if ok, _, _ := kv.Release(&KVPair{Key: "service/leader", Session: follower}, nil); ok {
+		return false
+	}
+	if ok, _, _ := kv.Release(&KVPair{Key: "service/leader", Session: leader}, nil); !ok {
+		return false
+	}
+	if ok, _, _ := kv.Acquire(&KVPair{Key: "service/leader", Value: []byte("node-b"), Session: follower}, nil); !ok {
+		return false
+	}
+	p, _, _ = kv.Get("service/leader", nil)
+	if p.Session != follower || p.LockIndex != 2 {
+		return false
+	}
+
+	// Destroying the holder releases the lock
+	sessions.Destroy(follower, nil)
+	p, _, _ = kv.Get("service/leader", nil)
+	if p == nil || p.Session != "" {
+		return false
+	}
+	list, _, _ := sessions.List(nil)
+	if len(list) != 1 || list[0].ID != leader {
+		return false
+	}
+	_, _, err = kv.Acquire(&KVPair{Key: "x", Session: follower}, nil)
+	return err != nil && strings.Contains(err.Error(), "invalid session")
+}
+
+func testSessionInvalidation() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv, sessions, agent := client.KV(), client.Session(), client.Agent()
+
+	// A lock of an expired session waits out its lock delay
+	short, _, _ := sessions.CreateNoChecks(&SessionEntry{TTL: "20ms", LockDelay: 100 * time.Millisecond}, nil)
+	kv.Acquire(&KVPair{Key: "lock", Session: short}, nil)
+	if !eventually(func() bool { info, _, _ := sessions.Info(short, nil); return info == nil }) {
+		return false
+	}
+	other, _, _ := sessions.CreateNoChecks(nil, nil)
+	if ok, _, _ := kv.Acquire(&KVPair{Key: "lock", Session: other}, nil); ok {
+		return false
+	}
+	if !eventually(func() bool { ok, _, _ := kv.Acquire(&KVPair{Key: "lock", Session: other}, nil); return ok }) {
+		return false
+	}
+
+	// Renewals keep a session alive
+	renewed, _, _ := sessions.CreateNoChecks(&SessionEntry{TTL: "20ms"}, nil)
+	for i := 0; i < 6; i++ {
+		time.Sleep(15 * time.Millisecond)
+		if entry, _, _ := sessions.Renew(renewed, nil); entry == nil {
+			return false
+		}
+	}
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	go func() { result <- sessions.RenewPeriodic("20ms", renewed, nil, done) }()
+	time.Sleep(80 * time.Millisecond)
+	close(done)
+	if err := <-result; err != nil {
+		return false
+	}
+	if entry, _, _ := sessions.Renew(renewed, nil); entry != nil {
+		return false
+	}
+
+	// A critical check invalidates the sessions depending on it, and a
+	// delete session takes its keys with it
+	agent.CheckRegister(&AgentCheckRegistration{Name: "alive", AgentServiceCheck: AgentServiceCheck{TTL: "1m", Status: HealthPassing}})
+	eph, _, err := sessions.Create(&SessionEntry{NodeChecks: []string{"alive"}, Behavior: SessionBehaviorDelete}, nil)
+	if err != nil {
+		return false
+	}
+	kv.Acquire(&KVPair{Key: "ephemeral/node", Value: []byte("x"), Session: eph}, nil)
+	agent.FailTTL("alive", "down")
+	if info, _, _ := sessions.Info(eph, nil); info != nil {
+		return false
+	}
+	if p, _, _ := kv.Get("ephemeral/node", nil); p != nil {
+		return false
+	}
+	_, _, err = sessions.Create(&SessionEntry{NodeChecks: []string{"alive"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "critical state") {
+		return false
+	}
+	_, _, err = sessions.Create(&SessionEntry{NodeChecks: []string{"missing"}}, nil)
+	return err != nil
+}
+
+func testBlockingQueries() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv := client.KV()
+
+	kv.Put(&KVPair{Key: "feature", Value: []byte("off")}, nil)
+	_, meta, _ := kv.Get("feature", nil)
+
+	type result struct {
+		p    *KVPair
+		meta *QueryMeta
+	}
+	results := make(chan result, 1)
+	go func() {
+		p, m, _ := kv.Get("feature", &QueryOptions{WaitIndex: meta.LastIndex, WaitTime: time.Second})
+		results <- result{p, m}
+	}()
+
+	// Writes to other keys do not wake a single-key query
+	time.Sleep(20 * time.Millisecond)
+	kv.Put(&KVPair{Key: "other", Value: []byte("x")}, nil)
+	select {
+	case <-results:
+		return false
+	case <-time.After(30 * time.Millisecond):
+	}
+	kv.Put(&KVPair{Key: "feature", Value: []byte("on")}, nil)
+	select {
+	case r := <-results:
+		if string(r.p.Value) != "on" || r.meta.LastIndex <= meta.LastIndex {
+			return false
+		}
+		meta = r.meta
+	case <-time.After(time.Second):
+		return false
+	}
+
+	// A query that times out returns the same index
+	start := time.Now()
+	_, timedOut, _ := kv.Get("feature", &QueryOptions{WaitIndex: meta.LastIndex, WaitTime: 30 * time.Millisecond})
+	if timedOut.LastIndex != meta.LastIndex || time.Since(start) < 30*time.Millisecond {
+		return false
+	}
+
+	// Queries stop with their context
+	qctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := kv.Get("feature", (&QueryOptions{WaitIndex: meta.LastIndex}).WithContext(qctx)); err != context.DeadlineExceeded {
+		return false
+	}
+
+	// Health queries wake on check changes
+	client.Agent().ServiceRegister(&AgentServiceRegistration{Name: "api", Check: &AgentServiceCheck{TTL: "1m"}})
+	_, hmeta, _ := client.Health().Service("api", "", true, nil)
+	woke := make(chan int, 1)
+	go func() {
+		entries, _, _ := client.Health().Service("api", "", true, &QueryOptions{WaitIndex: hmeta.LastIndex, WaitTime: time.Second})
+		woke <- len(entries)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	client.Agent().PassTTL("service:api", "")
+	select {
+	case n := <-woke:
+		return n == 1
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func testMultipleAgents() bool {
+	server := NewServer()
+	defer server.Close()
+	addr := server.StartAgent("http://10.0.0.2:8500", "node-2")
+	if addr != "10.0.0.2:8500" {
+		return false
+	}
+	c1, _ := NewClient(&Config{Address: server.Addr})
+	c2, _ := NewClient(&Config{Address: "10.0.0.2"})
+
+	c1.Agent().ServiceRegister(&AgentServiceRegistration{Name: "web", Port: 80})
+	c2.Agent().ServiceRegister(&AgentServiceRegistration{Name: "web", Port: 80})
+	if name, _ := c2.Agent().NodeName(); name != "node-2" {
+		return false
+	}
+
+	// Each agent has its own services; the catalog has them all
+	if services, _ := c2.Agent().Services(); len(services) != 1 {
+		return false
+	}
+	nodes, _, _ := c1.Catalog().Nodes(nil)
+	if len(nodes) != 2 || nodes[1].Node != "node-2" || nodes[1].Address != "10.0.0.2" {
+		return false
+	}
+	entries, _, _ := c1.Health().Service("web", "", true, nil)
+	if len(entries) != 2 || entries[1].Node.Node != "node-2" || entries[1].Checks[0].CheckID != "serfHealth" {
+		return false
+	}
+	node, _, _ := c1.Catalog().Node("node-2", nil)
+	if node == nil || node.Services["web"] == nil {
+		return false
+	}
+	if leader, _ := c2.Status().Leader(); leader != "127.0.0.1:8300" {
+		return false
+	}
+
+	// Other datacenters and missing agents are errors
+	if _, _, err := c1.KV().Get("k", &QueryOptions{Datacenter: "dc2"}); err == nil {
+		return false
+	}
+	c3, err := NewClient(&Config{Address: "10.9.9.9:8500"})
+	if err != nil {
+		return false
+	}
+	if _, _, err := c3.KV().Get("k", nil); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		return false
+	}
+	_, err = NewClient(&Config{Scheme: "ftp"})
+	return err != nil
+}
+
+func testServiceDiscovery() bool {
+	client, server := newClient()
+	defer server.Close()
+	kctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kit := NewKitClient(kctx, client, []string{"v1"}, true)
+	ch := make(chan struct{}, 16)
+	go kit.WatchPrefix("users", ch)
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		return false
+	}
+
+	logger := &recordingLogger{}
+	registrar := NewRegistrar(kit, &AgentServiceRegistration{
+		ID:      "users-1",
+		Name:    "users",
+		Tags:    []string{"v1"},
+		Address: "10.0.0.1",
+		Port:    8080,
+		Check:   &AgentServiceCheck{TTL: "1m", Status: HealthPassing},
+	}, logger)
+	registrar.Register()
+	kit.Register(&AgentServiceRegistration{ID: "users-2", Name: "users", Tags: []string{"v1"}, Port: 8081})
+	kit.Register(&AgentServiceRegistration{ID: "users-3", Name: "users", Tags: []string{"v2"}, Port: 8082})
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		return false
+	}
+
+	// Instances without an address use their node's
+	if !eventually(func() bool {
+		entries, err := kit.GetEntries("users")
+		return err == nil && strings.Join(entries, ",") == "10.0.0.1:8080,127.0.0.1:8081"
+	}) {
+		return false
+	}
+
+	// Failing instances drop out
+	client.Agent().FailTTL("service:users-1", "")
+	if entries, _ := kit.GetEntries("users"); strings.Join(entries, ",") != "127.0.0.1:8081" {
+		return false
+	}
+	client.Agent().PassTTL("service:users-1", "")
+
+	registrar.Deregister()
+	if entries, _ := kit.GetEntries("users"); strings.Join(entries, ",") != "127.0.0.1:8081" {
+		return false
+	}
+	registrar.Deregister()
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return len(logger.lines) == 3 && strings.Contains(logger.lines[0], "register") &&
+		strings.Contains(logger.lines[1], "deregister") && strings.Contains(logger.lines[2], "err")
+}
+
+func testViperStore() bool {
+	client, server := newClient()
+	defer server.Close()
+	store := NewViperStore(client)
+
+	if _, err := store.Get("config/app.json"); err != ErrRemoteKeyNotFound {
+		return false
+	}
+	client.KV().Put(&KVPair{Key: "config/app.json", Value: []byte(`{"port": 8080}`)}, nil)
+	if doc, err := store.Get("config/app.json"); err != nil || string(doc) != `{"port": 8080}` {
+		return false
+	}
+
+	stop := make(chan struct{})
+	updates := store.Watch("config/app.json", stop)
+	time.Sleep(20 * time.Millisecond)
+	client.KV().Put(&KVPair{Key: "config/app.json", Value: []byte(`{"port": 9090}`)}, nil)
+	select {
+	case doc := <-updates:
+		if string(doc) != `{"port": 9090}` {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	close(stop)
+	select {
+	case _, ok := <-updates:
+		return !ok
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func testConcurrentAccess() bool {
+	client, server := newClient()
+	defer server.Close()
+	kv := client.KV()
+	kv.Put(&KVPair{Key: "counter", Value: []byte("0")}, nil)
+
+	// CAS retries make the increments atomic
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				for {
+					p, _, _ := kv.Get("counter", nil)
+					var v int
+					fmt.Sscan(string(p.Value), &v)
+					p.Value = []byte(fmt.Sprint(v + 1))
+					if ok, _, _ := kv.CAS(p, nil); ok {
+						break
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("svc-%d", i)
+			client.Agent().ServiceRegister(&AgentServiceRegistration{ID: id, Name: "svc", Check: &AgentServiceCheck{TTL: "1m"}})
+			client.Agent().PassTTL("service:"+id, "")
+			client.Health().Service("svc", "", true, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	p, _, _ := kv.Get("counter", nil)
+	return string(p.Value) == "80" && passing(client, "svc") == "svc-0,svc-1,svc-2,svc-3"
+}
+
+func main() {
+	fmt.Println("Running Consul Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("KV Put And Get", testKVPutAndGet)
+	runTest("KV List And Keys", testKVListAndKeys)
+	runTest("Check-And-Set", testCheckAndSet)
+	runTest("Service Registration", testServiceRegistration)
+	runTest("TTL Checks", testTTLChecks)
+	runTest("HTTP And TCP Checks", testHTTPAndTCPChecks)
+	runTest("Deregister Critical Services", testDeregisterCriticalServices)
+	runTest("Maintenance Mode", testMaintenanceMode)
+	runTest("Sessions And Locks", testSessionsAndLocks)
+	runTest("Session Invalidation", testSessionInvalidation)
+	runTest("Blocking Queries", testBlockingQueries)
+	runTest("Multiple Agents", testMultipleAgents)
+	runTest("Service Discovery", testServiceDiscovery)
+	runTest("Viper Store", testViperStore)
+	runTest("Concurrent Access", testConcurrentAccess)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1168 @@
+package main
+
+// Developed by PowerShield, as an alternative to robfig/cron
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Jobs and schedules
+
+// Job is the work a cron entry runs
+type Job interface {
+	Run()
+}
+
+// FuncJob makes a func() a Job
+type FuncJob func()
+
+func (f FuncJob) Run() { f() }
+
+// Schedule gives the next activation time after a given time
+type Schedule interface {
+	// Next returns the next activation time, later than t
+	Next(t time.Time) time.Time
+}
+
+// EntryID identifies an entry within a Cron
+type EntryID int
+
+// Entry is a job and its schedule
+type Entry struct {
+	ID       EntryID
+	Schedule Schedule
+	// Next is when the job runs next, zero if the Cron is not running or
+	// the schedule is exhausted
+	Next time.Time
+	// Prev is when the job last ran, zero if it has not
+	Prev time.Time
+	// WrappedJob is Job wrapped by the Cron's chain
+	WrappedJob Job
+	// Job is the job as it was added
+	Job Job
+}
+
+// Valid reports whether the entry exists; Cron.Entry returns an invalid
+// entry for unknown IDs
+func (e Entry) Valid() bool { return e.ID != 0 }
+
+// byTime sorts entries by Next, with zero times last
+type byTime []*Entry
+
+func (s byTime) Len() int      { return len(s) }
+func (s byTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byTime) Less(i, j int) bool {
+	if s[i].Next.IsZero() {
+		return false
+	}
+	if s[j].Next.IsZero() {
+		return true
+	}
+	return s[i].Next.Before(s[j].Next)
+}
+
+// Clocks
+
+// Clock tells the time and makes timers. The default is the system
+// clock; a FakeClock lets tests advance time by hand.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer made by a Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// FakeClock is a Clock that only moves when told to
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{}
+}
+
+// NewFakeClock creates a FakeClock stopped at now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the clock's time
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+// NewTimer makes a timer that fires once the clock reaches now+d
+func (fc *FakeClock) NewTimer(d time.Duration) Timer {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	t := &fakeTimer{clock: fc, deadline: fc.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- fc.now
+		return t
+	}
+	fc.timers = append(fc.timers, t)
+	fc.notify()
+	return t
+}
+
+// Advance moves the clock forward by d and fires the timers that are
+// due. A cron entry due several times within d runs once, as after a
+// jump of the system clock; advance in steps to see every run.
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.set(fc.now.Add(d))
+}
+
+// Set moves the clock to t and fires the timers that are due
+func (fc *FakeClock) Set(t time.Time) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.set(t)
+}
+
+func (fc *FakeClock) set(t time.Time) {
+	fc.now = t
+	pending := fc.timers[:0]
+	for _, timer := range fc.timers {
+		if timer.deadline.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- t
+	}
+	fc.timers = pending
+	fc.notify()
+}
+
+// BlockUntil waits until n timers are pending, i.e. a started Cron is
+// waiting for its next entry
+func (fc *FakeClock) BlockUntil(n int) {
+	for {
+		fc.mu.Lock()
+		pending, changed := len(fc.timers), fc.changed
+		fc.mu.Unlock()
+		if pending == n {
+			return
+		}
+		<-changed
+	}
+}
+
+// notify wakes BlockUntil callers; the lock must be held
+func (fc *FakeClock) notify() {
+	close(fc.changed)
+	fc.changed = make(chan struct{})
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	fc := t.clock
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for i, timer := range fc.timers {
+		if timer == t {
+			fc.timers = append(fc.timers[:i], fc.timers[i+1:]...)
+			fc.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// Logging
+
+// Logger is the logging interface of the Cron and its wrappers
+type Logger interface {
+	// Info logs routine messages about the Cron's operation
+	Info(msg string, keysAndValues ...interface{})
+	// Error logs an error condition
+	Error(err error, msg string, keysAndValues ...interface{})
+}
+
+// DefaultLogger logs errors to stdout
+var DefaultLogger Logger = PrintfLogger(log.New(os.Stdout, "cron: ", log.LstdFlags))
+
+// DiscardLogger logs nothing
+var DiscardLogger Logger = PrintfLogger(log.New(discard{}, "", 0))
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+// PrintfLogger logs errors to a Printf-style logger
+func PrintfLogger(l interface{ Printf(string, ...interface{}) }) Logger {
+	return printfLogger{l, false}
+}
+
+// VerbosePrintfLogger logs errors and info messages to a Printf-style logger
+func VerbosePrintfLogger(l interface{ Printf(string, ...interface{}) }) Logger {
+	return printfLogger{l, true}
+}
+
+type printfLogger struct {
+	logger  interface{ Printf(string, ...interface{}) }
+	logInfo bool
+}
+
+func (pl printfLogger) Info(msg string, keysAndValues ...interface{}) {
+	if pl.logInfo {
+		keysAndValues = formatTimes(keysAndValues)
+		pl.logger.Printf(formatString(len(keysAndValues)), append([]interface{}{msg}, keysAndValues...)...)
+	}
+}
+
+func (pl printfLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	keysAndValues = formatTimes(keysAndValues)
+	pl.logger.Printf(formatString(len(keysAndValues)+2), append([]interface{}{msg, "error", err}, keysAndValues...)...)
+}
+
+// formatString returns "%s, %v=%v, %v=%v" for the message and pairs
+func formatString(numKeysAndValues int) string {
+	var sb strings.Builder
+	sb.WriteString("%s")
+	if numKeysAndValues > 0 {
+		sb.WriteString(", ")
+	}
+	for i := 0; i < numKeysAndValues/2; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("%v=%v")
+	}
+	return sb.String()
+}
+
+// formatTimes renders times as RFC3339
+func formatTimes(keysAndValues []interface{}) []interface{} {
+	var formatted []interface{}
+	for _, arg := range keysAndValues {
+		if t, ok := arg.(time.Time); ok {
+			arg = t.Format(time.RFC3339)
+		}
+		formatted = append(formatted, arg)
+	}
+	return formatted
+}
+
+// Job wrappers
+
+// JobWrapper decorates a Job
+type JobWrapper func(Job) Job
+
+// Chain is a sequence of JobWrappers
+type Chain struct {
+	wrappers []JobWrapper
+}
+
+// NewChain returns a Chain of the given wrappers
+func NewChain(c ...JobWrapper) Chain {
+	return Chain{c}
+}
+
+// Then wraps j in the chain's wrappers; the first wrapper is outermost
+func (c Chain) Then(j Job) Job {
+	for i := range c.wrappers {
+		j = c.wrappers[len(c.wrappers)-i-1](j)
+	}
+	return j
+}
+
+// Recover logs panics in wrapped jobs instead of crashing the program
+func Recover(logger Logger) JobWrapper {
+	return func(j Job) Job {
+		return FuncJob(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					const size = 64 << 10
+					buf := make([]byte, size)
+					buf = buf[:runtime.Stack(buf, false)]
+					err, ok := r.(error)
+					if !ok {
+						err = fmt.Errorf("%v", r)
+					}
+					logger.Error(err, "panic", "stack", "...\n"+string(buf))
+				}
+			}()
+			j.Run()
+		})
+	}
+}
+
+// DelayIfStillRunning serializes runs of a job, delaying a run until the
+// previous one has finished and logging delays over a minute
+func DelayIfStillRunning(logger Logger) JobWrapper {
+	return func(j Job) Job {
+		var mu sync.Mutex
+		return FuncJob(func() {
+			start := time.Now()
+			mu.Lock()
+			defer mu.Unlock()
+			if dur := time.Since(start); dur > time.Minute {
+				logger.Info("delay", "duration", dur)
+			}
+			j.Run()
+		})
+	}
+}
+
+// SkipIfStillRunning skips a run of a job if the previous one is still
+// in progress
+func SkipIfStillRunning(logger Logger) JobWrapper {
+	return func(j Job) Job {
+		var ch = make(chan struct{}, 1)
+		ch <- struct{}{}
+		return FuncJob(func() {
+			select {
+			case v := <-ch:
+				defer func() { ch <- v }()
+				j.Run()
+			default:
+				logger.Info("skip")
+			}
+		})
+	}
+}
+
+// Cron
+
+// Cron runs jobs on their schedules. Entries can be added and removed
+// while it runs.
+type Cron struct {
+	entries   []*Entry
+	chain     Chain
+	stop      chan struct{}
+	add       chan *Entry
+	remove    chan EntryID
+	snapshot  chan chan []Entry
+	running   bool
+	logger    Logger
+	runningMu sync.Mutex
+	location  *time.Location
+	parser    ScheduleParser
+	clock     Clock
+	nextID    EntryID
+	jobWaiter sync.WaitGroup
+}
+
+// ScheduleParser parses specs into Schedules
+type ScheduleParser interface {
+	Parse(spec string) (Schedule, error)
+}
+
+// Option configures a Cron
+type Option func(*Cron)
+
+// WithLocation interprets schedules in loc instead of time.Local
+func WithLocation(loc *time.Location) Option {
+	return func(c *Cron) { c.location = loc }
+}
+
+// WithSeconds accepts specs with a leading seconds field
+func WithSeconds() Option {
+	return WithParser(NewParser(Second | Minute | Hour | Dom | Month | Dow | Descriptor))
+}
+
+// WithParser sets the parser for AddFunc and AddJob specs
+func WithParser(p ScheduleParser) Option {
+	return func(c *Cron) { c.parser = p }
+}
+
+// WithChain wraps every job in the given wrappers
+func WithChain(wrappers ...JobWrapper) Option {
+	return func(c *Cron) { c.chain = NewChain(wrappers...) }
+}
+
+// WithLogger sets the logger for the Cron
+func WithLogger(logger Logger) Option {
+	return func(c *Cron) { c.logger = logger }
+}
+
+// WithClock sets the clock schedules are run against, such as a FakeClock
+func WithClock(clock Clock) Option {
+	return func(c *Cron) { c.clock = clock }
+}
+
+// New creates a Cron that parses standard 5-field specs and descriptors.
+// Jobs are not wrapped by default, so a panicking job crashes the
+// program; use WithChain(Recover(logger)) to log panics instead.
+func New(opts ...Option) *Cron {
+	c := &Cron{
+		chain:    NewChain(),
+		add:      make(chan *Entry),
+		stop:     make(chan struct{}),
+		snapshot: make(chan chan []Entry),
+		remove:   make(chan EntryID),
+		logger:   DefaultLogger,
+		location: time.Local,
+		parser:   standardParser,
+		clock:    realClock{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// AddFunc schedules cmd on spec
+func (c *Cron) AddFunc(spec string, cmd func()) (EntryID, error) {
+	return c.AddJob(spec, FuncJob(cmd))
+}
+
+// AddJob schedules cmd on spec
+func (c *Cron) AddJob(spec string, cmd Job) (EntryID, error) {
+	schedule, err := c.parser.Parse(spec)
+	if err != nil {
+		return 0, err
+	}
+	return c.Schedule(schedule, cmd), nil
+}
+
+// Schedule adds cmd with a Schedule
+func (c *Cron) Schedule(schedule Schedule, cmd Job) EntryID {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+	c.nextID++
+	entry := &Entry{
+		ID:         c.nextID,
+		Schedule:   schedule,
+		WrappedJob: c.chain.Then(cmd),
+		Job:        cmd,
+	}
+	if !c.running {
+		c.entries = append(c.entries, entry)
+	} else {
+		c.add <- entry
+	}
+	return entry.ID
+}
+
+// Entries returns a snapshot of the entries, soonest first
+func (c *Cron) Entries() []Entry {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+	if c.running {
+		replyChan := make(chan []Entry, 1)
+		c.snapshot <- replyChan
+		return <-replyChan
+	}
+	return c.entrySnapshot()
+}
+
+// Location returns the Cron's time zone
+func (c *Cron) Location() *time.Location {
+	return c.location
+}
+
+// Entry returns a snapshot of one entry, or an invalid Entry
+func (c *Cron) Entry(id EntryID) Entry {
+	for _, entry := range c.Entries() {
+		if id == entry.ID {
+			return entry
+		}
+	}
+	return Entry{}
+}
+
+// Remove stops an entry from running in the future
+func (c *Cron) Remove(id EntryID) {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+	if c.running {
+		c.remove <- id
+	} else {
+		c.removeEntry(id)
+	}
+}
+
+// Start runs the scheduler in its own goroutine; it does nothing if the
+// Cron is already running
+func (c *Cron) Start() {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+	if c.running {
+		return
+	}
+	c.running = true
+	go c.run()
+}
+
+// Run runs the scheduler in the calling goroutine until Stop
+func (c *Cron) Run() {
+	c.runningMu.Lock()
+	if c.running {
+		c.runningMu.Unlock()
+		return
+	}
+	c.running = true
+	c.runningMu.Unlock()
+	c.run()
+}
+
+// Stop stops the scheduler without interrupting running jobs. The
+// returned context is done once they have finished.
+func (c *Cron) Stop() context.Context {
+	c.runningMu.Lock()
+	defer c.runningMu.Unlock()
+	if c.running {
+		c.stop <- struct{}{}
+		c.running = false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		c.jobWaiter.Wait()
+		cancel()
+	}()
+	return ctx
+}
+
+// run is the scheduler loop: it sleeps until the soonest entry is due,
+// runs every due entry and reschedules them
+func (c *Cron) run() {
+	c.logger.Info("start")
+
+	now := c.now()
+	for _, entry := range c.entries {
+		entry.Next = entry.Schedule.Next(now)
+		c.logger.Info("schedule", "now", now, "entry", entry.ID, "next", entry.Next)
+	}
+
+	for {
+		sort.Sort(byTime(c.entries))
+
+		var timer Timer
+		if len(c.entries) == 0 || c.entries[0].Next.IsZero() {
+			// Nothing to run: sleep until something is added
+			timer = c.clock.NewTimer(100000 * time.Hour)
+		} else {
+			timer = c.clock.NewTimer(c.entries[0].Next.Sub(now))
+		}
+
+		for {
+			select {
+			case now = <-timer.C():
+				now = now.In(c.location)
+				c.logger.Info("wake", "now", now)
+				for _, e := range c.entries {
+					if e.Next.After(now) || e.Next.IsZero() {
+						break
+					}
+					c.startJob(e.WrappedJob)
+					e.Prev = e.Next
+					e.Next = e.Schedule.Next(now)
+					c.logger.Info("run", "now", now, "entry", e.ID, "next", e.Next)
+				}
+
+			case newEntry := <-c.add:
+				timer.Stop()
+				now = c.now()
+				newEntry.Next = newEntry.Schedule.Next(now)
+				c.entries = append(c.entries, newEntry)
+				c.logger.Info("added", "now", now, "entry", newEntry.ID, "next", newEntry.Next)
+
+			case replyChan := <-c.snapshot:
+				replyChan <- c.entrySnapshot()
+				continue
+
+			case <-c.stop:
+				timer.Stop()
+				c.logger.Info("stop")
+				return
+
+			case id := <-c.remove:
+				timer.Stop()
+				now = c.now()
+				c.removeEntry(id)
+				c.logger.Info("removed", "entry", id)
+			}
+			break
+		}
+	}
+}
+
+// startJob runs j in its own goroutine
+func (c *Cron) startJob(j Job) {
+	c.jobWaiter.Add(1)
+	go func() {
+		defer c.jobWaiter.Done()
+		j.Run()
+	}()
+}
+
+func (c *Cron) now() time.Time {
+	return c.clock.Now().In(c.location)
+}
+
+func (c *Cron) entrySnapshot() []Entry {
+	entries := make([]Entry, len(c.entries))
+	for i, e := range c.entries {
+		entries[i] = *e
+	}
+	return entries
+}
+
+func (c *Cron) removeEntry(id EntryID) {
+	var entries []*Entry
+	for _, e := range c.entries {
+		if e.ID != id {
+			entries = append(entries, e)
+		}
+	}
+	c.entries = entries
+}
+
+// Schedules
+
+// ConstantDelaySchedule runs at a fixed interval, such as "@every 5m"
+type ConstantDelaySchedule struct {
+	Delay time.Duration
+}
+
+// Every returns a schedule that runs every duration, rounded down to
+// whole seconds with a minimum of one second
+func Every(duration time.Duration) ConstantDelaySchedule {
+	if duration < time.Second {
+		duration = time.Second
+	}
+	return ConstantDelaySchedule{Delay: duration - time.Duration(duration.Nanoseconds())%time.Second}
+}
+
+// Next returns t plus the delay, on a whole second
+func (schedule ConstantDelaySchedule) Next(t time.Time) time.Time {
+	return t.Add(schedule.Delay - time.Duration(t.Nanosecond())*time.Nanosecond)
+}
+
+// SpecSchedule is a parsed cron spec. Each field is a bit set of the
+// values it matches, with starBit set for "*" and "?".
+type SpecSchedule struct {
+	Second, Minute, Hour, Dom, Month, Dow uint64
+
+	// Location is the time zone of the spec, from a CRON_TZ= prefix or
+	// the time passed to Next
+	Location *time.Location
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	dom     = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dow = bounds{0, 6, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// starBit marks fields given as "*" or "?"
+const starBit = 1 << 63
+
+// Next returns the next time the schedule matches after t, or the zero
+// time if none is found within five years
+func (s *SpecSchedule) Next(t time.Time) time.Time {
+	// Work in the schedule's time zone, returning the result in t's
+	origLocation := t.Location()
+	loc := s.Location
+	if loc == time.Local {
+		loc = t.Location()
+	}
+	if s.Location != time.Local {
+		t = t.In(s.Location)
+	}
+
+	// Start at the next whole second
+	t = t.Add(1*time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+
+	// added tracks whether a field was advanced, so lower fields are
+	// reset to their first value only once
+	added := false
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.Month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !dayMatches(s, t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// Around a DST change midnight may not exist; move to 1am and
+		// let the hour loop correct it
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.Hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(1 * time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.Minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(1 * time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.Second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(1 * time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLocation)
+}
+
+// dayMatches applies cron's day rule: if both day of month and day of
+// week are restricted, either may match; otherwise both must
+func dayMatches(s *SpecSchedule, t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.Dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.Dow > 0
+	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Parsing
+
+// ParseOption selects the fields a Parser accepts
+type ParseOption int
+
+// Fields and features a Parser can accept
+const (
+	Second         ParseOption = 1 << iota // Seconds field, default 0
+	SecondOptional                         // Optional seconds field, default 0
+	Minute                                 // Minutes field, default 0
+	Hour                                   // Hours field, default 0
+	Dom                                    // Day of month field, default *
+	Month                                  // Month field, default *
+	Dow                                    // Day of week field, default *
+	DowOptional                            // Optional day of week field, default *
+	Descriptor                             // Allow descriptors such as @monthly, @weekly, etc.
+)
+
+var places = []ParseOption{Second, Minute, Hour, Dom, Month, Dow}
+
+var defaults = []string{"0", "0", "0", "*", "*", "*"}
+
+// Parser parses cron specs with a configurable set of fields
+type Parser struct {
+	options ParseOption
+}
+
+// NewParser creates a Parser for the given fields, e.g.
+// NewParser(Second | Minute | Hour | Dom | Month | Dow | Descriptor).
+// It panics if more than one field is optional.
+func NewParser(options ParseOption) Parser {
+	optionals := 0
+	if options&DowOptional > 0 {
+		optionals++
+	}
+	if options&SecondOptional > 0 {
+		optionals++
+	}
+	if optionals > 1 {
+		panic("multiple optionals may not be configured")
+	}
+	return Parser{options}
+}
+
+var standardParser = NewParser(Minute | Hour | Dom | Month | Dow | Descriptor)
+
+// ParseStandard parses a standard 5-field spec ("minute hour dom month
+// dow") or a descriptor
+func ParseStandard(standardSpec string) (Schedule, error) {
+	return standardParser.Parse(standardSpec)
+}
+
+// Parse parses a spec into a Schedule. A spec may start with
+// CRON_TZ=<zone> (or TZ=<zone>) to set its time zone.
+func (p Parser) Parse(spec string) (Schedule, error) {
+	if len(spec) == 0 {
+		return nil, fmt.Errorf("empty spec string")
+	}
+
+	var loc = time.Local
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		var err error
+		i := strings.Index(spec, " ")
+		eq := strings.Index(spec, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("missing spec after time zone: %s", spec)
+		}
+		if loc, err = time.LoadLocation(spec[eq+1 : i]); err != nil {
+			return nil, fmt.Errorf("provided bad location %s: %v", spec[eq+1:i], err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		if p.options&Descriptor == 0 {
+			return nil, fmt.Errorf("parser does not accept descriptors: %v", spec)
+		}
+		return parseDescriptor(spec, loc)
+	}
+
+	fields := strings.Fields(spec)
+	fields, err := normalizeFields(fields, p.options)
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(field string, r bounds) uint64 {
+		if err != nil {
+			return 0
+		}
+		var bits uint64
+		bits, err = getField(field, r)
+		return bits
+	}
+
+	var (
+		second     = field(fields[0], seconds)
+		minute     = field(fields[1], minutes)
+		hour       = field(fields[2], hours)
+		dayofmonth = field(fields[3], dom)
+		month      = field(fields[4], months)
+		dayofweek  = field(fields[5], dow)
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpecSchedule{
+		Second:   second,
+		Minute:   minute,
+		Hour:     hour,
+		Dom:      dayofmonth,
+		Month:    month,
+		Dow:      dayofweek,
+		Location: loc,
+	}, nil
+}
+
+// normalizeFields fills in optional and omitted fields so there are
+// always six, in the order of places
+func normalizeFields(fields []string, options ParseOption) ([]string, error) {
+	optionals := 0
+	if options&SecondOptional > 0 {
+		options |= Second
+		optionals++
+	}
+	if options&DowOptional > 0 {
+		options |= Dow
+		optionals++
+	}
+	if optionals > 1 {
+		return nil, fmt.Errorf("multiple optionals may not be configured")
+	}
+
+	max := 0
+	for _, place := range places {
+		if options&place > 0 {
+			max++
+		}
+	}
+	min := max - optionals
+
+	if count := len(fields); count < min || count > max {
+		if min == max {
+			return nil, fmt.Errorf("expected exactly %d fields, found %d: %s", min, count, fields)
+		}
+		return nil, fmt.Errorf("expected %d to %d fields, found %d: %s", min, max, count, fields)
+	}
+
+	// Fill in the missing optional field
+	if min < max && len(fields) == min {
+		switch {
+		case options&DowOptional > 0:
+			fields = append(fields, defaults[5])
+		case options&SecondOptional > 0:
+			fields = append([]string{defaults[0]}, fields...)
+		default:
+			return nil, fmt.Errorf("unknown optional field")
+		}
+	}
+
+	// Populate the fields the parser does not accept with defaults
+	n := 0
+	expandedFields := make([]string, len(places))
+	copy(expandedFields, defaults)
+	for i, place := range places {
+		if options&place > 0 {
+			expandedFields[i] = fields[n]
+			n++
+		}
+	}
+	return expandedFields, nil
+}
+
+// getField parses a comma-separated list of ranges into a bit set
+func getField(field string, r bounds) (uint64, error) {
+	var bits uint64
+	ranges := strings.FieldsFunc(field, func(r rune) bool { return r == ',' })
+	for _, expr := range ranges {
+		bit, err := getRange(expr, r)
+		if err != nil {
+			return bits, err
+		}
+		bits |= bit
+	}
+	return bits, nil
+}
+
+// getRange parses "*", "?", "n", "a-b" or any of those with "/step"
+func getRange(expr string, r bounds) (uint64, error) {
+	var (
+		start, end, step uint
+		rangeAndStep     = strings.Split(expr, "/")
+		lowAndHigh       = strings.Split(rangeAndStep[0], "-")
+		singleDigit      = len(lowAndHigh) == 1
+		err              error
+	)
+
+	var extra uint64
+	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+		start = r.min
+		end = r.max
+		extra = starBit
+	} else {
+		start, err = parseIntOrName(lowAndHigh[0], r.names)
+		if err != nil {
+			return 0, err
+		}
+		switch len(lowAndHigh) {
+		case 1:
+			end = start
+		case 2:
+			end, err = parseIntOrName(lowAndHigh[1], r.names)
+			if err != nil {
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("too many hyphens: %s", expr)
+		}
+	}
+
+	switch len(rangeAndStep) {
+	case 1:
+		step = 1
+	case 2:
+		step, err = mustParseInt(rangeAndStep[1])
+		if err != nil {
+			return 0, err
+		}
+		// "n/step" means n through the maximum
+		if singleDigit {
+			end = r.max
+		}
+		if step > 1 {
+			extra = 0
+		}
+	default:
+		return 0, fmt.Errorf("too many slashes: %s", expr)
+	}
+
+	if start < r.min {
+		return 0, fmt.Errorf("beginning of range (%d) below minimum (%d): %s", start, r.min, expr)
+	}
+	if end > r.max {
+		return 0, fmt.Errorf("end of range (%d) above maximum (%d): %s", end, r.max, expr)
+	}
+	if start > end {
+		return 0, fmt.Errorf("beginning of range (%d) beyond end of range (%d): %s", start, end, expr)
+	}
+	if step == 0 {
+		return 0, fmt.Errorf("step of range should be a positive number: %s", expr)
+	}
+
+	return getBits(start, end, step) | extra, nil
+}
+
+func parseIntOrName(expr string, names map[string]uint) (uint, error) {
+	if names != nil {
+		if namedInt, ok := names[strings.ToLower(expr)]; ok {
+			return namedInt, nil
+		}
+	}
+	return mustParseInt(expr)
+}
+
+func mustParseInt(expr string) (uint, error) {
+	num, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse int from %s: %s", expr, err)
+	}
+	if num < 0 {
+		return 0, fmt.Errorf("negative number (%d) not allowed: %s", num, expr)
+	}
+	return uint(num), nil
+}
+
+// getBits sets the bits from min to max, every step
+func getBits(min, max, step uint) uint64 {
+	var bits uint64
+	if step == 1 {
+		return ^(math.MaxUint64 << (max + 1)) & (math.MaxUint64 << min)
+	}
+	for i := min; i <= max; i += step {
+		bits |= 1 << i
+	}
+	return bits
+}
+
+func all(r bounds) uint64 {
+	return getBits(r.min, r.max, 1) | starBit
+}
+
+// parseDescriptor parses @yearly, @monthly, @weekly, @daily, @hourly and
+// @every <duration>
+func parseDescriptor(descriptor string, loc *time.Location) (Schedule, error) {
+	switch descriptor {
+	case "@yearly", "@annually":
+		return &SpecSchedule{
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      1 << dom.min,
+			Month:    1 << months.min,
+			Dow:      all(dow),
+			Location: loc,
+		}, nil
+
+	case "@monthly":
+		return &SpecSchedule{
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      1 << dom.min,
+			Month:    all(months),
+			Dow:      all(dow),
+			Location: loc,
+		}, nil
+
+	case "@weekly":
+		return &SpecSchedule{
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      all(dom),
+			Month:    all(months),
+			Dow:      1 << dow.min,
+			Location: loc,
+		}, nil
+
+	case "@daily", "@midnight":
+		return &SpecSchedule{
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      all(dom),
+			Month:    all(months),
+			Dow:      all(dow),
+			Location: loc,
+		}, nil
+
+	case "@hourly":
+		return &SpecSchedule{
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     all(hours),
+			Dom:      all(dom),
+			Month:    all(months),
+			Dow:      all(dow),
+			Location: loc,
+		}, nil
+	}
+
+	const every = "@every "
+	if strings.HasPrefix(descriptor, every) {
+		duration, err := time.ParseDuration(descriptor[len(every):])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse duration %s: %s", descriptor, err)
+		}
+		return Every(duration), nil
+	}
+
+	return nil, fmt.Errorf("unrecognized descriptor: %s", descriptor)
+}
@@ -0,0 +1,794 @@
+package main
+
+// Developed by PowerShield, as an alternative to robfig/cron
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// start is a Monday
+var start = time.Date(2024, time.January, 1, 8, 0, 0, 0, time.UTC)
+
+// nextTimes returns the next n activations of a spec after from
+func nextTimes(spec string, from time.Time, n int) ([]time.Time, error) {
+	schedule, err := ParseStandard(spec)
+	if err != nil {
+		return nil, err
+	}
+	var times []time.Time
+	for i := 0; i < n; i++ {
+		from = schedule.Next(from)
+		times = append(times, from)
+	}
+	return times, nil
+}
+
+// at builds a UTC time in January 2024
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+}
+
+// received waits briefly for a value on ch
+func received(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+// quiet reports whether nothing arrives on ch for a moment
+func quiet(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return false
+	case <-time.After(50 * time.Millisecond):
+		return true
+	}
+}
+
+// panics reports whether fn panics
+func panics(fn func()) (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+	fn()
+	return false
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent loggers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newFakeCron returns a started Cron on a fake clock, waiting for its
+// first entry
+func newFakeCron(opts ...Option) (*Cron, *FakeClock) {
+	clock := NewFakeClock(start)
+	opts = append([]Option{WithClock(clock), WithLocation(time.UTC), WithLogger(DiscardLogger)}, opts...)
+	c := New(opts...)
+	return c, clock
+}
+
+func testStandardSpecs() bool {
+	times, err := nextTimes("30 9 * * 1-5", start, 3)
+	if err != nil {
+		return false
+	}
+	if !times[0].Equal(at(1, 9, 30)) || !times[1].Equal(at(2, 9, 30)) || !times[2].Equal(at(3, 9, 30)) {
+		return false
+	}
+
+	// Steps, lists and ranges
+	times, _ = nextTimes("*/15 8-9 * * *", start, 4)
+	if !times[0].Equal(at(1, 8, 15)) || !times[3].Equal(at(1, 9, 0)) {
+		return false
+	}
+	times, _ = nextTimes("0,45 10 * * *", start, 3)
+	if !times[0].Equal(at(1, 10, 0)) || !times[1].Equal(at(1, 10, 45)) || !times[2].Equal(at(2, 10, 0)) {
+		return false
+	}
+	times, _ = nextTimes("5/20 * * * *", start, 3)
+	if !times[0].Equal(at(1, 8, 5)) || !times[1].Equal(at(1, 8, 25)) || !times[2].Equal(at(1, 8, 45)) {
+		return false
+	}
+
+	// Month and weekday names, in any case
+	times, _ = nextTimes("0 12 * FEB sat", start, 2)
+	if !times[0].Equal(time.Date(2024, time.February, 3, 12, 0, 0, 0, time.UTC)) ||
+		!times[1].Equal(time.Date(2024, time.February, 10, 12, 0, 0, 0, time.UTC)) {
+		return false
+	}
+
+	// Next is strictly after the given time
+	times, _ = nextTimes("0 8 * * *", start, 1)
+	if !times[0].Equal(at(2, 8, 0)) {
+		return false
+	}
+
+	// Impossible dates give up with the zero time
+	times, _ = nextTimes("0 0 30 2 *", start, 1)
+	if !times[0].IsZero() {
+		return false
+	}
+
+	errorCases := map[string]string{
+		"":               "empty spec string",
+		"* * * *":        "expected exactly 5 fields, found 4",
+		"60 * * * *":     "end of range (60) above maximum (59)",
+		"* * 0 * *":      "beginning of range (0) below minimum (1)",
+		"5-2 * * * *":    "beginning of range (5) beyond end of range (2)",
+		"*/0 * * * *":    "step of range should be a positive number",
+		"1-2-3 * * * *":  "too many hyphens",
+		"*/2/3 * * * *":  "too many slashes",
+		"x * * * *":      "failed to parse int from x",
+		"* * * * funday": "failed to parse int from funday",
+		"-1 * * * *":     "failed to parse int from ",
+	}
+	for spec, want := range errorCases {
+		if _, err := ParseStandard(spec); err == nil || !strings.Contains(err.Error(), want) {
+			return false
+		}
+	}
+	return true
+}
+
+func testSecondsAndOptionalFields() bool {
+	secondsParser := NewParser(Second | Minute | Hour | Dom | Month | Dow | Descriptor)
+	schedule, err := secondsParser.Parse("*/30 0 9 * * *")
+	if err != nil {
+		return false
+	}
+	first := schedule.Next(start)
+	if !first.Equal(time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)) {
+		return false
+	}
+	if !schedule.Next(first).Equal(first.Add(30 * time.Second)) {
+		return false
+	}
+	if _, err := secondsParser.Parse("0 9 * * *"); err == nil || !strings.Contains(err.Error(), "expected exactly 6 fields, found 5") {
+		return false
+	}
+
+	// An optional seconds field defaults to 0
+	optional := NewParser(SecondOptional | Minute | Hour | Dom | Month | Dow)
+	withSeconds, err1 := optional.Parse("15 30 9 * * *")
+	withoutSeconds, err2 := optional.Parse("30 9 * * *")
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	if withSeconds.Next(start).Second() != 15 || withoutSeconds.Next(start).Second() != 0 {
+		return false
+	}
+	if _, err := optional.Parse("* * * *"); err == nil || !strings.Contains(err.Error(), "expected 5 to 6 fields, found 4") {
+		return false
+	}
+	if _, err := optional.Parse("@daily"); err == nil || !strings.Contains(err.Error(), "does not accept descriptors") {
+		return false
+	}
+
+	// Fields a parser omits take their defaults: hour 0, every day
+	minuteOnly := NewParser(Minute)
+	schedule, _ = minuteOnly.Parse("45")
+	if !schedule.Next(start).Equal(at(2, 0, 45)) {
+		return false
+	}
+
+	// WithSeconds switches a Cron's parser
+	c := New(WithSeconds(), WithLogger(DiscardLogger))
+	if _, err := c.AddFunc("*/5 * * * * *", func() {}); err != nil {
+		return false
+	}
+	if _, err := New().AddFunc("*/5 * * * * *", func() {}); err == nil {
+		return false
+	}
+
+	// Only one field may be optional
+	return panics(func() { NewParser(SecondOptional | DowOptional) })
+}
+
+func testDescriptors() bool {
+	cases := map[string]time.Time{
+		"@yearly":   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"@annually": time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"@monthly":  time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"@weekly":   at(7, 0, 0),
+		"@daily":    at(2, 0, 0),
+		"@midnight": at(2, 0, 0),
+		"@hourly":   at(1, 9, 0),
+	}
+	for spec, want := range cases {
+		times, err := nextTimes(spec, start, 1)
+		if err != nil || !times[0].Equal(want) {
+			return false
+		}
+	}
+
+	schedule, err := ParseStandard("@every 1m30s")
+	if err != nil {
+		return false
+	}
+	every, ok := schedule.(ConstantDelaySchedule)
+	if !ok || every.Delay != 90*time.Second {
+		return false
+	}
+	// Runs land on whole seconds
+	from := start.Add(500 * time.Millisecond)
+	if !schedule.Next(from).Equal(start.Add(90 * time.Second)) {
+		return false
+	}
+
+	// Delays are rounded down to seconds, with a minimum of one
+	if Every(1500*time.Millisecond).Delay != time.Second || Every(10*time.Millisecond).Delay != time.Second {
+		return false
+	}
+
+	if _, err := ParseStandard("@fortnightly"); err == nil || !strings.Contains(err.Error(), "unrecognized descriptor") {
+		return false
+	}
+	if _, err := ParseStandard("@every often"); err == nil || !strings.Contains(err.Error(), "failed to parse duration") {
+		return false
+	}
+	return true
+}
+
+func testDayMatching() bool {
+	// With both restricted, either day of month or day of week matches:
+	// the 13th, or any Friday
+	times, _ := nextTimes("0 0 13 * 5", start, 3)
+	if !times[0].Equal(at(5, 0, 0)) || !times[1].Equal(at(12, 0, 0)) || !times[2].Equal(at(13, 0, 0)) {
+		return false
+	}
+
+	// With one given as *, both must match: only Friday the 13th
+	times, _ = nextTimes("0 0 13 * *", start, 1)
+	if !times[0].Equal(at(13, 0, 0)) {
+		return false
+	}
+	secondsParser := NewParser(Second | Minute | Hour | Dom | Month | Dow)
+	schedule, _ := secondsParser.Parse("0 0 0 ? * fri")
+	if !schedule.Next(start).Equal(at(5, 0, 0)) {
+		return false
+	}
+
+	// A step of 1 keeps * semantics; any other step restricts
+	times, _ = nextTimes("0 0 */1 * mon", start, 2)
+	if !times[0].Equal(at(8, 0, 0)) || !times[1].Equal(at(15, 0, 0)) {
+		return false
+	}
+	times, _ = nextTimes("0 0 */10 * mon", start, 3)
+	if !times[0].Equal(at(8, 0, 0)) || !times[1].Equal(at(11, 0, 0)) || !times[2].Equal(at(15, 0, 0)) {
+		return false
+	}
+
+	// Month ends are handled, including leap years
+	times, _ = nextTimes("0 0 31 * *", start, 2)
+	if !times[0].Equal(at(31, 0, 0)) || !times[1].Equal(time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)) {
+		return false
+	}
+	times, _ = nextTimes("0 0 29 2 *", start, 2)
+	return times[0].Equal(time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)) &&
+		times[1].Equal(time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC))
+}
+
+func testTimeZones() bool {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return false
+	}
+
+	// CRON_TZ= sets the spec's zone; results come back in the caller's
+	schedule, err := ParseStandard("CRON_TZ=America/New_York 0 9 * * *")
+	if err != nil {
+		return false
+	}
+	next := schedule.Next(start)
+	if next.Location() != time.UTC || !next.Equal(time.Date(2024, time.January, 1, 9, 0, 0, 0, newYork)) {
+		return false
+	}
+	if schedule.(*SpecSchedule).Location.String() != "America/New_York" {
+		return false
+	}
+	if _, err := ParseStandard("TZ=UTC @daily"); err != nil {
+		return false
+	}
+	if _, err := ParseStandard("CRON_TZ=Mars/Olympus 0 9 * * *"); err == nil || !strings.Contains(err.Error(), "provided bad location Mars/Olympus") {
+		return false
+	}
+
+	// Without a prefix, specs follow the time they are evaluated in
+	schedule, _ = ParseStandard("0 9 * * *")
+	inNewYork := schedule.Next(start.In(newYork))
+	if inNewYork.Location() != newYork || inNewYork.Hour() != 9 || inNewYork.Day() != 1 {
+		return false
+	}
+
+	// Daylight saving: 2:30 does not exist on 10 March 2024 in New York
+	schedule, _ = ParseStandard("30 2 * * *")
+	march := time.Date(2024, time.March, 9, 12, 0, 0, 0, newYork)
+	next = schedule.Next(march)
+	if !next.Equal(time.Date(2024, time.March, 11, 2, 30, 0, 0, newYork)) {
+		return false
+	}
+
+	// WithLocation sets the zone the Cron runs in
+	c := New(WithLocation(newYork))
+	return c.Location() == newYork
+}
+
+func testJobRegistration() bool {
+	c := New(WithLogger(DiscardLogger))
+	id1, err1 := c.AddFunc("@hourly", func() {})
+	id2, err2 := c.AddJob("0 9 * * *", FuncJob(func() {}))
+	id3 := c.Schedule(Every(time.Minute), FuncJob(func() {}))
+	if err1 != nil || err2 != nil || id1 != 1 || id2 != 2 || id3 != 3 {
+		return false
+	}
+	if _, err := c.AddFunc("bogus", func() {}); err == nil {
+		return false
+	}
+	if len(c.Entries()) != 3 {
+		return false
+	}
+
+	entry := c.Entry(id2)
+	if !entry.Valid() || entry.ID != id2 || entry.Job == nil || entry.WrappedJob == nil {
+		return false
+	}
+	// Entries are not scheduled until the Cron starts
+	if !entry.Next.IsZero() || !entry.Prev.IsZero() {
+		return false
+	}
+	if c.Entry(99).Valid() {
+		return false
+	}
+
+	c.Remove(id1)
+	c.Remove(99)
+	entries := c.Entries()
+	if len(entries) != 2 || entries[0].ID != id2 || entries[1].ID != id3 {
+		return false
+	}
+
+	// IDs are not reused
+	id4, _ := c.AddFunc("@daily", func() {})
+	return id4 == 4
+}
+
+func testFakeClockScheduling() bool {
+	c, clock := newFakeCron()
+	ran := make(chan struct{}, 10)
+	id, _ := c.AddFunc("*/15 * * * *", func() { ran <- struct{}{} })
+	c.Start()
+	defer c.Stop()
+	clock.BlockUntil(1)
+
+	entry := c.Entry(id)
+	if !entry.Next.Equal(at(1, 8, 15)) || !entry.Prev.IsZero() {
+		return false
+	}
+
+	// Nothing runs before the entry is due
+	clock.Advance(14 * time.Minute)
+	if !quiet(ran) {
+		return false
+	}
+
+	clock.Advance(time.Minute)
+	if !received(ran) {
+		return false
+	}
+	clock.BlockUntil(1)
+	entry = c.Entry(id)
+	if !entry.Prev.Equal(at(1, 8, 15)) || !entry.Next.Equal(at(1, 8, 30)) {
+		return false
+	}
+
+	// Advancing in steps sees every run
+	for i := 0; i < 3; i++ {
+		clock.Advance(15 * time.Minute)
+		if !received(ran) {
+			return false
+		}
+		clock.BlockUntil(1)
+	}
+	entry = c.Entry(id)
+	if !entry.Prev.Equal(at(1, 9, 0)) || !entry.Next.Equal(at(1, 9, 15)) {
+		return false
+	}
+
+	// A single jump past several activations runs the job once
+	clock.Advance(2 * time.Hour)
+	if !received(ran) || !quiet(ran) {
+		return false
+	}
+	clock.BlockUntil(1)
+	return c.Entry(id).Next.Equal(at(1, 11, 15))
+}
+
+func testEveryAndOrdering() bool {
+	c, clock := newFakeCron()
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{}, 10)
+	record := func(name string) func() {
+		return func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			done <- struct{}{}
+		}
+	}
+	fast, _ := c.AddFunc("@every 10s", record("fast"))
+	slow, _ := c.AddFunc("@every 25s", record("slow"))
+	c.Start()
+	defer c.Stop()
+	clock.BlockUntil(1)
+
+	// Entries are listed soonest first
+	entries := c.Entries()
+	if entries[0].ID != fast || entries[1].ID != slow {
+		return false
+	}
+
+	runs := 0
+	for i := 0; i < 5; i++ {
+		clock.Advance(10 * time.Second)
+		expected := 1
+		if i == 2 {
+			// At 30s both are due: fast (every 10s) and slow (due at 25s)
+			expected = 2
+		}
+		for j := 0; j < expected; j++ {
+			if !received(done) {
+				return false
+			}
+			runs++
+		}
+		clock.BlockUntil(1)
+	}
+	if runs != 6 {
+		return false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	fastRuns, slowRuns := 0, 0
+	for _, name := range order {
+		if name == "fast" {
+			fastRuns++
+		} else {
+			slowRuns++
+		}
+	}
+	// The slow entry is rescheduled from the wake time, 30s + 25s
+	return fastRuns == 5 && slowRuns == 1 && c.Entry(slow).Next.Equal(start.Add(55*time.Second))
+}
+
+func testAddAndRemoveWhileRunning() bool {
+	c, clock := newFakeCron()
+	c.Start()
+	defer c.Stop()
+	// With no entries the Cron still waits on a timer
+	clock.BlockUntil(1)
+
+	hourly := make(chan struct{}, 10)
+	minutely := make(chan struct{}, 10)
+	hourlyID, _ := c.AddFunc("@hourly", func() { hourly <- struct{}{} })
+	minutelyID, _ := c.AddFunc("* * * * *", func() { minutely <- struct{}{} })
+
+	// Added entries are scheduled from the current time
+	entries := c.Entries()
+	if len(entries) != 2 || entries[0].ID != minutelyID || !entries[0].Next.Equal(at(1, 8, 1)) {
+		return false
+	}
+	if !c.Entry(hourlyID).Next.Equal(at(1, 9, 0)) {
+		return false
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if !received(minutely) {
+		return false
+	}
+	clock.BlockUntil(1)
+
+	c.Remove(minutelyID)
+	if c.Entry(minutelyID).Valid() || len(c.Entries()) != 1 {
+		return false
+	}
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Minute)
+	if !received(hourly) || !quiet(minutely) {
+		return false
+	}
+	clock.BlockUntil(1)
+
+	// Starting twice is harmless
+	c.Start()
+	return len(c.Entries()) == 1
+}
+
+func testRecoverWrapper() bool {
+	var out syncBuffer
+	logger := PrintfLogger(log.New(&out, "", 0))
+	c, clock := newFakeCron(WithChain(Recover(logger)))
+	ran := make(chan struct{}, 10)
+	c.AddFunc("@every 1m", func() {
+		defer func() { ran <- struct{}{} }()
+		panic("disk full")
+	})
+	c.AddFunc("@every 1m", func() {
+		defer func() { ran <- struct{}{} }()
+		panic(fmt.Errorf("connection refused"))
+	})
+	c.Start()
+	clock.BlockUntil(1)
+
+	// Both run and panic, and the scheduler carries on
+	clock.Advance(time.Minute)
+	if !received(ran) || !received(ran) {
+		return false
+	}
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if !received(ran) || !received(ran) {
+		return false
+	}
+	<-c.Stop().Done()
+
+	logged := out.String()
+	if !strings.Contains(logged, "panic, error=disk full, stack=...") ||
+		!strings.Contains(logged, "error=connection refused") ||
+		strings.Count(logged, "panic, error=") != 4 {
+		return false
+	}
+
+	// Chains apply the first wrapper outermost
+	var calls []string
+	wrap := func(name string) JobWrapper {
+		return func(j Job) Job {
+			return FuncJob(func() {
+				calls = append(calls, name)
+				j.Run()
+			})
+		}
+	}
+	NewChain(wrap("outer"), wrap("inner")).Then(FuncJob(func() { calls = append(calls, "job") })).Run()
+	if strings.Join(calls, ",") != "outer,inner,job" {
+		return false
+	}
+
+	// Only verbose loggers print info messages
+	var verbose bytes.Buffer
+	VerbosePrintfLogger(log.New(&verbose, "", 0)).Info("run", "entry", 1, "next", start)
+	PrintfLogger(log.New(&verbose, "", 0)).Info("hidden")
+	return verbose.String() == "run, entry=1, next=2024-01-01T08:00:00Z\n"
+}
+
+func testSkipAndDelayWrappers() bool {
+	var out syncBuffer
+	logger := VerbosePrintfLogger(log.New(&out, "", 0))
+
+	// SkipIfStillRunning drops runs while one is in progress
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	var runs int32
+	skipping := NewChain(SkipIfStillRunning(logger)).Then(FuncJob(func() {
+		atomic.AddInt32(&runs, 1)
+		started <- struct{}{}
+		<-release
+	}))
+	first := make(chan struct{})
+	go func() { skipping.Run(); close(first) }()
+	if !received(started) {
+		return false
+	}
+	skipping.Run()
+	skipping.Run()
+	close(release)
+	if !received(first) || atomic.LoadInt32(&runs) != 1 || strings.Count(out.String(), "skip") != 2 {
+		return false
+	}
+	// Once finished, the job runs again
+	done := make(chan struct{})
+	go func() { skipping.Run(); close(done) }()
+	if !received(started) || !received(done) || atomic.LoadInt32(&runs) != 2 {
+		return false
+	}
+
+	// DelayIfStillRunning queues runs instead
+	var mu sync.Mutex
+	var active, maxActive, delayedRuns int
+	delaying := NewChain(DelayIfStillRunning(logger)).Then(FuncJob(func() {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		delayedRuns++
+		mu.Unlock()
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delaying.Run()
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	return maxActive == 1 && delayedRuns == 5
+}
+
+func testStopAndRun() bool {
+	c, clock := newFakeCron()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	finished := int32(0)
+	c.AddFunc("@every 1s", func() {
+		started <- struct{}{}
+		<-release
+		atomic.StoreInt32(&finished, 1)
+	})
+	c.Start()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if !received(started) {
+		return false
+	}
+
+	// Stop does not interrupt the running job; its context waits for it
+	ctx := c.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		return false
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		return false
+	}
+
+	// A stopped Cron no longer waits on the clock and can be stopped again
+	clock.BlockUntil(0)
+	<-c.Stop().Done()
+
+	// Run blocks the calling goroutine until Stop
+	ran := make(chan struct{}, 10)
+	c2, clock2 := newFakeCron()
+	c2.AddFunc("@every 1s", func() { ran <- struct{}{} })
+	returned := make(chan struct{})
+	go func() {
+		c2.Run()
+		close(returned)
+	}()
+	clock2.BlockUntil(1)
+	clock2.Advance(time.Second)
+	if !received(ran) {
+		return false
+	}
+	if !quiet(returned) {
+		return false
+	}
+	c2.Stop()
+	if !received(returned) {
+		return false
+	}
+
+	// Stop, add and restart: entries are rescheduled from the new time
+	clock2.Advance(time.Hour)
+	c2.AddFunc("@hourly", func() {})
+	c2.Start()
+	defer c2.Stop()
+	clock2.BlockUntil(1)
+	return len(c2.Entries()) == 2 && c2.Entries()[0].Next.Equal(start.Add(time.Hour+2*time.Second))
+}
+
+func testConcurrentUse() bool {
+	c, clock := newFakeCron()
+	var runs int64
+	c.Start()
+	clock.BlockUntil(1)
+
+	var wg sync.WaitGroup
+	ids := make(chan EntryID, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := c.AddFunc("@every 1s", func() { atomic.AddInt64(&runs, 1) })
+			if err == nil {
+				ids <- id
+			}
+			c.Entries()
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[EntryID]bool{}
+	for id := range ids {
+		seen[id] = true
+	}
+	if len(seen) != 20 {
+		return false
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+	clock.BlockUntil(1)
+
+	for id := range seen {
+		if id%2 == 0 {
+			c.Remove(id)
+		}
+	}
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	<-c.Stop().Done()
+
+	return len(c.Entries()) == 10 && atomic.LoadInt64(&runs) == 70
+}
+
+func main() {
+	fmt.Println("Running Cron Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Standard Specs", testStandardSpecs)
+	runTest("Seconds And Optional Fields", testSecondsAndOptionalFields)
+	runTest("Descriptors", testDescriptors)
+	runTest("Day Matching", testDayMatching)
+	runTest("Time Zones", testTimeZones)
+	runTest("Job Registration", testJobRegistration)
+	runTest("Fake Clock Scheduling", testFakeClockScheduling)
+	runTest("Every And Ordering", testEveryAndOrdering)
+	runTest("Add And Remove While Running", testAddAndRemoveWhileRunning)
+	runTest("Recover Wrapper", testRecoverWrapper)
+	runTest("Skip And Delay Wrappers", testSkipAndDelayWrappers)
+	runTest("Stop And Run", testStopAndRun)
+	runTest("Concurrent Use", testConcurrentUse)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,955 @@
+package main
+
+// Developed by PowerShield, as an alternative to Uber's dig and fx dependency injection
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Parameter and result objects
+
+// In marks a struct as a parameter object when embedded in it: each
+// exported field is a dependency. Fields may be tagged name:"..." for a
+// named value, group:"..." for a value group (a slice), and
+// optional:"true" to receive the zero value when nothing provides them.
+type In struct{}
+
+// Out marks a struct as a result object when embedded in it: each
+// exported field is provided to the container. Fields may be tagged
+// name:"..." or group:"...", where group:"...,flatten" adds the elements
+// of a slice to the group one by one.
+type Out struct{}
+
+var (
+	inType    = reflect.TypeOf(In{})
+	outType   = reflect.TypeOf(Out{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// embeds reports whether t is a struct embedding marker
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIn reports whether o is a parameter object, or the type of one
+func IsIn(o interface{}) bool {
+	return embeds(typeOf(o), inType)
+}
+
+// IsOut reports whether o is a result object, or the type of one
+func IsOut(o interface{}) bool {
+	return embeds(typeOf(o), outType)
+}
+
+func typeOf(o interface{}) reflect.Type {
+	if t, ok := o.(reflect.Type); ok {
+		return t
+	}
+	return reflect.TypeOf(o)
+}
+
+// Errors
+
+// digError is an error raised by the container, wrapping its cause
+type digError struct {
+	msg   string
+	cause error
+}
+
+func (e *digError) Error() string {
+	if e.cause == nil {
+		return e.msg
+	}
+	return e.msg + ": " + e.cause.Error()
+}
+
+func (e *digError) Unwrap() error { return e.cause }
+
+func errf(cause error, format string, args ...interface{}) error {
+	return &digError{msg: fmt.Sprintf(format, args...), cause: cause}
+}
+
+// RootCause returns the error that started a chain of container errors,
+// such as the error a constructor returned
+func RootCause(err error) error {
+	for {
+		de, ok := err.(*digError)
+		if !ok || de.cause == nil {
+			return err
+		}
+		err = de.cause
+	}
+}
+
+// cycleError reports a cycle in the dependency graph
+type cycleError struct {
+	path []cycleEntry
+}
+
+type cycleEntry struct {
+	key key
+	fn  string
+}
+
+func (e *cycleError) Error() string {
+	var b strings.Builder
+	b.WriteString("cycle detected in dependency graph: ")
+	for i, entry := range e.path {
+		if i > 0 {
+			b.WriteString("\n\tdepends on ")
+		}
+		fmt.Fprintf(&b, "%v provided by %s", entry.key, entry.fn)
+	}
+	return b.String()
+}
+
+// IsCycleDetected reports whether err, or an error it wraps, is a
+// dependency cycle
+func IsCycleDetected(err error) bool {
+	var ce *cycleError
+	return errors.As(err, &ce)
+}
+
+// Keys, parameters and results
+
+// key identifies what the container holds: a type, optionally with a
+// name or in a value group
+type key struct {
+	t     reflect.Type
+	name  string
+	group string
+}
+
+func (k key) String() string {
+	switch {
+	case k.name != "":
+		return fmt.Sprintf("%v[name=%q]", k.t, k.name)
+	case k.group != "":
+		return fmt.Sprintf("%v[group=%q]", k.t, k.group)
+	}
+	return k.t.String()
+}
+
+// param is a dependency of a function: a value, a value group, or a
+// parameter object whose fields are params
+type param struct {
+	t        reflect.Type
+	name     string
+	group    string
+	optional bool
+	fields   []fieldParam
+}
+
+type fieldParam struct {
+	index int
+	param param
+}
+
+// key returns the key a value or group param reads; groups are keyed by
+// their element type
+func (p param) key() key {
+	if p.group != "" {
+		return key{t: p.t.Elem(), group: p.group}
+	}
+	return key{t: p.t, name: p.name}
+}
+
+// leaves returns the value and group params, looking inside parameter
+// objects
+func (p param) leaves() []param {
+	if p.fields == nil {
+		return []param{p}
+	}
+	var out []param
+	for _, f := range p.fields {
+		out = append(out, f.param.leaves()...)
+	}
+	return out
+}
+
+// newParam describes a dependency of type t
+func newParam(t reflect.Type) (param, error) {
+	if !embeds(t, inType) {
+		if embeds(t, outType) {
+			return param{}, errf(nil, "cannot depend on result objects: %v embeds dig.Out", t)
+		}
+		return param{t: t}, nil
+	}
+	p := param{t: t, fields: []fieldParam{}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type == inType {
+			continue
+		}
+		if f.PkgPath != "" {
+			return param{}, errf(nil, "bad field %q of %v: unexported fields not allowed in dig.In, did you mean to export %q?", f.Name, t, f.Name)
+		}
+		fp, err := newParam(f.Type)
+		if err != nil {
+			return param{}, errf(err, "bad field %q of %v", f.Name, t)
+		}
+		fp.name = f.Tag.Get("name")
+		fp.group = f.Tag.Get("group")
+		fp.optional = f.Tag.Get("optional") == "true"
+		if fp.name != "" && fp.group != "" {
+			return param{}, errf(nil, "bad field %q of %v: cannot use named values with value groups: name:%q requested with group:%q", f.Name, t, fp.name, fp.group)
+		}
+		if fp.group != "" && f.Type.Kind() != reflect.Slice {
+			return param{}, errf(nil, "bad field %q of %v: value groups may be consumed as slices only: field %q (%v) is not a slice", f.Name, t, f.Name, f.Type)
+		}
+		p.fields = append(p.fields, fieldParam{index: i, param: fp})
+	}
+	return p, nil
+}
+
+// provided is one key a constructor provides, and where its value is
+// in the constructor's results
+type provided struct {
+	key     key
+	path    []int
+	flatten bool
+}
+
+// resultKeys returns what a result of type t provides. Result objects
+// provide their fields.
+func resultKeys(t reflect.Type, path []int, o *provideOptions) ([]provided, error) {
+	if !embeds(t, outType) {
+		if embeds(t, inType) {
+			return nil, errf(nil, "cannot provide parameter objects: %v embeds dig.In", t)
+		}
+		if len(o.as) == 0 {
+			return []provided{{key: key{t: t, name: o.name, group: o.group}, path: path}}, nil
+		}
+		var out []provided
+		for _, iface := range o.as {
+			if !t.Implements(iface) {
+				return nil, errf(nil, "invalid dig.As: %v does not implement %v", t, iface)
+			}
+			out = append(out, provided{key: key{t: iface, name: o.name, group: o.group}, path: path})
+		}
+		return out, nil
+	}
+	if o.name != "" || o.group != "" || len(o.as) > 0 {
+		return nil, errf(nil, "cannot specify a name, group or interfaces for result objects: %v embeds dig.Out", t)
+	}
+	var out []provided
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type == outType {
+			continue
+		}
+		if f.PkgPath != "" {
+			return nil, errf(nil, "bad field %q of %v: unexported fields not allowed in dig.Out, did you mean to export %q?", f.Name, t, f.Name)
+		}
+		fieldPath := append(append([]int(nil), path...), i)
+		if embeds(f.Type, outType) {
+			nested, err := resultKeys(f.Type, fieldPath, &provideOptions{})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, nested...)
+			continue
+		}
+		name := f.Tag.Get("name")
+		group, flags, _ := strings.Cut(f.Tag.Get("group"), ",")
+		if name != "" && group != "" {
+			return nil, errf(nil, "bad field %q of %v: cannot use named values with value groups: name:%q provided with group:%q", f.Name, t, name, group)
+		}
+		p := provided{key: key{t: f.Type, name: name, group: group}, path: fieldPath}
+		if flags == "flatten" {
+			if group == "" || f.Type.Kind() != reflect.Slice {
+				return nil, errf(nil, "bad field %q of %v: flatten can only be applied to slices in value groups", f.Name, t)
+			}
+			p.key.t, p.flatten = f.Type.Elem(), true
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Container
+
+// node is a registered constructor
+type node struct {
+	ctor      reflect.Value
+	desc      string
+	params    []param
+	provides  []provided
+	hasErr    bool
+	transient bool
+	called    bool
+	results   []reflect.Value
+}
+
+// Container holds constructors and the values they built. Like dig's, it
+// is not safe for concurrent use.
+type Container struct {
+	providers    map[key][]*node
+	nodes        []*node
+	deferAcyclic bool
+	verified     bool
+	dryRun       bool
+}
+
+// Option configures a Container
+type Option func(*Container)
+
+// DeferAcyclicVerification defers checking the graph for cycles from
+// each Provide to the first Invoke
+func DeferAcyclicVerification() Option {
+	return func(c *Container) { c.deferAcyclic = true }
+}
+
+// DryRun makes the container check the graph without calling any
+// constructor or invoked function
+func DryRun(dry bool) Option {
+	return func(c *Container) { c.dryRun = dry }
+}
+
+// New returns an empty container
+func New(opts ...Option) *Container {
+	c := &Container{providers: make(map[key][]*node)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ProvideOption configures a constructor
+type ProvideOption func(*provideOptions)
+
+type provideOptions struct {
+	name      string
+	group     string
+	as        []reflect.Type
+	transient bool
+	desc      string
+}
+
+// Name provides the constructor's results as named values
+func Name(name string) ProvideOption {
+	return func(o *provideOptions) { o.name = name }
+}
+
+// Group adds the constructor's results to a value group
+func Group(group string) ProvideOption {
+	return func(o *provideOptions) { o.group = group }
+}
+
+// As provides the constructor's results as each of the interfaces that
+// ifaces point to, e.g. As(new(io.Reader)), instead of as their own type
+func As(ifaces ...interface{}) ProvideOption {
+	return func(o *provideOptions) {
+		for _, i := range ifaces {
+			t := reflect.TypeOf(i)
+			if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+				panic(fmt.Sprintf("invalid dig.As(%v): argument must be a pointer to an interface", t))
+			}
+			o.as = append(o.as, t.Elem())
+		}
+	}
+}
+
+// Transient makes the container call the constructor for every function
+// that depends on it, instead of once. dig has no such option: there
+// every constructor is a singleton.
+func Transient() ProvideOption {
+	return func(o *provideOptions) { o.transient = true }
+}
+
+// describe returns a function's name and location, e.g.
+// "main".NewServer (/src/main.go:12)
+func describe(fn reflect.Value) string {
+	f := runtime.FuncForPC(fn.Pointer())
+	if f == nil {
+		return fn.Type().String()
+	}
+	full := f.Name()
+	dir, base := "", full
+	if i := strings.LastIndex(full, "/"); i >= 0 {
+		dir, base = full[:i+1], full[i+1:]
+	}
+	pkg, name, _ := strings.Cut(base, ".")
+	file, line := f.FileLine(f.Entry())
+	return fmt.Sprintf("%q.%s (%s:%d)", dir+pkg, name, file, line)
+}
+
+// Provide registers a constructor: a function whose parameters are its
+// dependencies and whose results, optionally followed by an error, are
+// what it provides. It is called at most once, when a function invoked
+// later first needs one of its results.
+func (c *Container) Provide(constructor interface{}, opts ...ProvideOption) error {
+	o := &provideOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	fn := reflect.ValueOf(constructor)
+	if fn.Kind() != reflect.Func {
+		return errf(nil, "must provide constructor function, got %v (type %T)", constructor, constructor)
+	}
+	desc := o.desc
+	if desc == "" {
+		desc = describe(fn)
+	}
+	if o.name != "" && o.group != "" {
+		return errf(nil, "cannot provide function %s: cannot use named values with value groups: name:%q provided with group:%q", desc, o.name, o.group)
+	}
+	n, err := c.newNode(fn, desc, o)
+	if err != nil {
+		return errf(err, "cannot provide function %s", desc)
+	}
+	if len(n.provides) == 0 {
+		return errf(nil, "cannot provide function %s: must provide at least one non-error type", desc)
+	}
+	seen := make(map[key]bool)
+	for _, p := range n.provides {
+		if p.key.group != "" {
+			continue
+		}
+		if seen[p.key] {
+			return errf(nil, "cannot provide function %s: cannot provide %v: provided more than once", desc, p.key)
+		}
+		seen[p.key] = true
+		if existing := c.providers[p.key]; len(existing) > 0 {
+			return errf(nil, "cannot provide function %s: cannot provide %v from %v: already provided by %s", desc, p.key, p.path, existing[0].desc)
+		}
+	}
+
+	c.add(n)
+	if !c.deferAcyclic {
+		if cycle := c.findCycle(n); cycle != nil {
+			c.remove(n)
+			return errf(cycle, "cannot provide function %s: this function introduces a cycle", desc)
+		}
+	}
+	c.verified = false
+	return nil
+}
+
+func (c *Container) newNode(fn reflect.Value, desc string, o *provideOptions) (*node, error) {
+	ft := fn.Type()
+	n := &node{ctor: fn, desc: desc, transient: o.transient}
+	for i := 0; i < ft.NumIn(); i++ {
+		if ft.IsVariadic() && i == ft.NumIn()-1 {
+			break
+		}
+		p, err := newParam(ft.In(i))
+		if err != nil {
+			return nil, err
+		}
+		n.params = append(n.params, p)
+	}
+	for i := 0; i < ft.NumOut(); i++ {
+		t := ft.Out(i)
+		if t == errorType {
+			if i != ft.NumOut()-1 {
+				return nil, errf(nil, "only the last result can be an error: %v has an error at position %d", ft, i)
+			}
+			n.hasErr = true
+			continue
+		}
+		keys, err := resultKeys(t, []int{i}, o)
+		if err != nil {
+			return nil, err
+		}
+		n.provides = append(n.provides, keys...)
+	}
+	return n, nil
+}
+
+func (c *Container) add(n *node) {
+	c.nodes = append(c.nodes, n)
+	for _, p := range n.provides {
+		if len(c.providers[p.key]) > 0 && c.providers[p.key][len(c.providers[p.key])-1] == n {
+			continue
+		}
+		c.providers[p.key] = append(c.providers[p.key], n)
+	}
+}
+
+func (c *Container) remove(n *node) {
+	c.nodes = c.nodes[:len(c.nodes)-1]
+	for _, p := range n.provides {
+		list := c.providers[p.key]
+		if len(list) > 0 && list[len(list)-1] == n {
+			c.providers[p.key] = list[:len(list)-1]
+		}
+		if len(c.providers[p.key]) == 0 {
+			delete(c.providers, p.key)
+		}
+	}
+}
+
+// findCycle looks for a path of dependencies from start back to itself
+func (c *Container) findCycle(start *node) *cycleError {
+	visited := make(map[*node]bool)
+	var path []cycleEntry
+	var visit func(n *node, via key) bool
+	visit = func(n *node, via key) bool {
+		path = append(path, cycleEntry{key: via, fn: n.desc})
+		if len(path) > 1 && n == start {
+			return true
+		}
+		if visited[n] {
+			path = path[:len(path)-1]
+			return false
+		}
+		visited[n] = true
+		for _, p := range n.params {
+			for _, leaf := range p.leaves() {
+				for _, dep := range c.providers[leaf.key()] {
+					if visit(dep, leaf.key()) {
+						return true
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(start, start.provides[0].key) {
+		return &cycleError{path: path}
+	}
+	return nil
+}
+
+// verify checks the whole graph for cycles, once after each change
+func (c *Container) verify() error {
+	if c.verified {
+		return nil
+	}
+	for _, n := range c.nodes {
+		if cycle := c.findCycle(n); cycle != nil {
+			return cycle
+		}
+	}
+	c.verified = true
+	return nil
+}
+
+// Invoke calls fn with its dependencies, building them and what they
+// depend on first. If fn returns an error, Invoke returns it.
+func (c *Container) Invoke(fn interface{}) error {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return errf(nil, "can't invoke non-function %v (type %T)", fn, fn)
+	}
+	return c.invoke(fv, describe(fv))
+}
+
+// invoke calls fv, described in errors as desc
+func (c *Container) invoke(fv reflect.Value, desc string) error {
+	ft := fv.Type()
+	var params []param
+	for i := 0; i < ft.NumIn(); i++ {
+		if ft.IsVariadic() && i == ft.NumIn()-1 {
+			break
+		}
+		p, err := newParam(ft.In(i))
+		if err != nil {
+			return errf(err, "cannot invoke function %s", desc)
+		}
+		params = append(params, p)
+	}
+	if err := c.verify(); err != nil {
+		return err
+	}
+	args, err := c.args(desc, params, nil)
+	if err != nil {
+		return err
+	}
+	if c.dryRun {
+		return nil
+	}
+	if ft.IsVariadic() {
+		args = append(args, reflect.Zero(ft.In(ft.NumIn()-1)))
+		return callErr(fv.CallSlice(args))
+	}
+	return callErr(fv.Call(args))
+}
+
+// callErr returns the trailing error of a call's results, if any
+func callErr(results []reflect.Value) error {
+	if len(results) == 0 {
+		return nil
+	}
+	last := results[len(results)-1]
+	if last.Type() == errorType && !last.IsNil() {
+		return last.Interface().(error)
+	}
+	return nil
+}
+
+// args builds the arguments of a function, after checking that nothing
+// it needs directly is missing
+func (c *Container) args(desc string, params []param, stack []*node) ([]reflect.Value, error) {
+	var missing []string
+	for _, p := range params {
+		for _, leaf := range p.leaves() {
+			if leaf.group == "" && !leaf.optional && len(c.providers[leaf.key()]) == 0 {
+				missing = append(missing, leaf.key().String())
+			}
+		}
+	}
+	switch len(missing) {
+	case 0:
+	case 1:
+		return nil, errf(nil, "missing dependencies for function %s: missing type: %s", desc, missing[0])
+	default:
+		return nil, errf(nil, "missing dependencies for function %s: missing types: %s", desc, strings.Join(missing, "; "))
+	}
+
+	args := make([]reflect.Value, len(params))
+	for i, p := range params {
+		v, err := c.build(p, stack)
+		if err != nil {
+			return nil, errf(err, "could not build arguments for function %s", desc)
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+// build returns the value of a param
+func (c *Container) build(p param, stack []*node) (reflect.Value, error) {
+	if p.fields != nil {
+		v := reflect.New(p.t).Elem()
+		for _, f := range p.fields {
+			fv, err := c.build(f.param, stack)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Field(f.index).Set(fv)
+		}
+		return v, nil
+	}
+
+	k := p.key()
+	if p.group != "" {
+		group := reflect.MakeSlice(p.t, 0, len(c.providers[k]))
+		for _, n := range c.providers[k] {
+			results, err := c.call(n, stack)
+			if err != nil {
+				return reflect.Value{}, errf(err, "could not build value group %v", k)
+			}
+			for _, pr := range n.provides {
+				if pr.key != k {
+					continue
+				}
+				v := extract(results, pr.path)
+				if pr.flatten {
+					group = reflect.AppendSlice(group, v)
+				} else {
+					group = reflect.Append(group, v)
+				}
+			}
+		}
+		return group, nil
+	}
+
+	nodes := c.providers[k]
+	if len(nodes) == 0 {
+		return reflect.Zero(p.t), nil
+	}
+	results, err := c.call(nodes[0], stack)
+	if err != nil {
+		return reflect.Value{}, errf(err, "failed to build %v", k)
+	}
+	for _, pr := range nodes[0].provides {
+		if pr.key == k {
+			return extract(results, pr.path), nil
+		}
+	}
+	return reflect.Zero(p.t), nil
+}
+
+// extract follows a result index and field indexes into results
+func extract(results []reflect.Value, path []int) reflect.Value {
+	v := results[path[0]]
+	for _, i := range path[1:] {
+		v = v.Field(i)
+	}
+	return v
+}
+
+// call runs a constructor, or returns its cached results
+func (c *Container) call(n *node, stack []*node) ([]reflect.Value, error) {
+	if n.called && !n.transient {
+		return n.results, nil
+	}
+	for i, s := range stack {
+		if s == n {
+			cycle := &cycleError{}
+			for _, entry := range append(stack[i:], n) {
+				cycle.path = append(cycle.path, cycleEntry{key: entry.provides[0].key, fn: entry.desc})
+			}
+			return nil, cycle
+		}
+	}
+	args, err := c.args(n.desc, n.params, append(stack, n))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []reflect.Value
+	ft := n.ctor.Type()
+	if c.dryRun {
+		for i := 0; i < ft.NumOut(); i++ {
+			results = append(results, reflect.Zero(ft.Out(i)))
+		}
+	} else if ft.IsVariadic() {
+		results = n.ctor.CallSlice(append(args, reflect.Zero(ft.In(ft.NumIn()-1))))
+	} else {
+		results = n.ctor.Call(args)
+	}
+	if n.hasErr {
+		if err := callErr(results); err != nil {
+			return nil, errf(err, "received non-nil error from function %s", n.desc)
+		}
+	}
+	if !n.transient {
+		n.called, n.results = true, results
+	}
+	return results, nil
+}
+
+// String lists what the container provides
+func (c *Container) String() string {
+	var b strings.Builder
+	b.WriteString("nodes: {\n")
+	for _, n := range c.nodes {
+		keys := make([]string, len(n.provides))
+		for i, p := range n.provides {
+			keys[i] = p.key.String()
+		}
+		state := "not called"
+		switch {
+		case n.transient:
+			state = "transient"
+		case n.called:
+			state = "called"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s (%s)\n", strings.Join(keys, ", "), n.desc, state)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// Applications, as in fx
+
+// Hook is a pair of functions run when an App starts and stops
+type Hook struct {
+	OnStart func(context.Context) error
+	OnStop  func(context.Context) error
+}
+
+// Lifecycle collects the hooks of an App's components. Constructors and
+// invoked functions receive it as a dependency.
+type Lifecycle interface {
+	Append(Hook)
+}
+
+type lifecycle struct {
+	hooks   []Hook
+	started int
+}
+
+// invocation is a function an App invokes
+type invocation struct {
+	fn   reflect.Value
+	desc string
+}
+
+func (l *lifecycle) Append(h Hook) {
+	l.hooks = append(l.hooks, h)
+}
+
+// Annotated provides a constructor's results under a name or in a group
+type Annotated struct {
+	Name   string
+	Group  string
+	Target interface{}
+}
+
+// AppOption configures an App
+type AppOption interface {
+	apply(*App)
+}
+
+type appOptionFunc func(*App)
+
+func (f appOptionFunc) apply(a *App) { f(a) }
+
+// Provide registers constructors, or Annotated constructors, with the
+// App's container
+func Provide(constructors ...interface{}) AppOption {
+	return appOptionFunc(func(a *App) {
+		for _, ctor := range constructors {
+			var opts []ProvideOption
+			if an, ok := ctor.(Annotated); ok {
+				ctor = an.Target
+				if an.Name != "" {
+					opts = append(opts, Name(an.Name))
+				}
+				if an.Group != "" {
+					opts = append(opts, Group(an.Group))
+				}
+			}
+			a.fail(a.container.Provide(ctor, opts...))
+		}
+	})
+}
+
+// invoke queues a function for NewApp to invoke
+func (a *App) invoke(fn interface{}) {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		a.fail(errf(nil, "can't invoke non-function %v (type %T)", fn, fn))
+		return
+	}
+	a.invokes = append(a.invokes, invocation{fn: fv, desc: describe(fv)})
+}
+
+// Supply provides values as they are, under their dynamic types
+func Supply(values ...interface{}) AppOption {
+	return appOptionFunc(func(a *App) {
+		for _, v := range values {
+			if v == nil {
+				a.fail(errf(nil, "cannot supply an untyped nil"))
+				continue
+			}
+			rv := reflect.ValueOf(v)
+			ft := reflect.FuncOf(nil, []reflect.Type{rv.Type()}, false)
+			ctor := reflect.MakeFunc(ft, func([]reflect.Value) []reflect.Value { return []reflect.Value{rv} })
+			a.fail(a.container.Provide(ctor.Interface(), func(o *provideOptions) {
+				o.desc = fmt.Sprintf("fx.Supply(%v)", rv.Type())
+			}))
+		}
+	})
+}
+
+// Invoke registers functions to call, in order, once every constructor
+// is registered
+func Invoke(funcs ...interface{}) AppOption {
+	return appOptionFunc(func(a *App) {
+		for _, fn := range funcs {
+			a.invoke(fn)
+		}
+	})
+}
+
+// Populate fills the values that targets point to from the container
+func Populate(targets ...interface{}) AppOption {
+	return appOptionFunc(func(a *App) {
+		for _, target := range targets {
+			tv := reflect.ValueOf(target)
+			if tv.Kind() != reflect.Ptr || tv.IsNil() {
+				a.fail(errf(nil, "fx.Populate: target must be a non-nil pointer, got %T", target))
+				continue
+			}
+			ft := reflect.FuncOf([]reflect.Type{tv.Elem().Type()}, nil, false)
+			fn := reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+				tv.Elem().Set(args[0])
+				return nil
+			})
+			a.invokes = append(a.invokes, invocation{fn: fn, desc: fmt.Sprintf("fx.Populate(%T)", target)})
+		}
+	})
+}
+
+// Options groups options into one
+func Options(opts ...AppOption) AppOption {
+	return appOptionFunc(func(a *App) {
+		for _, opt := range opts {
+			opt.apply(a)
+		}
+	})
+}
+
+// App is an application assembled from constructors, as fx.App
+type App struct {
+	container *Container
+	lifecycle *lifecycle
+	invokes   []invocation
+	err       error
+}
+
+// NewApp registers the constructors of opts, then runs their invoked
+// functions. The first error is kept and returned by Err and Start.
+func NewApp(opts ...AppOption) *App {
+	a := &App{container: New(), lifecycle: &lifecycle{}}
+	a.fail(a.container.Provide(func() Lifecycle { return a.lifecycle }, func(o *provideOptions) {
+		o.desc = "fx.New"
+	}))
+	for _, opt := range opts {
+		opt.apply(a)
+	}
+	for _, inv := range a.invokes {
+		if a.err != nil {
+			break
+		}
+		a.fail(a.container.invoke(inv.fn, inv.desc))
+	}
+	return a
+}
+
+func (a *App) fail(err error) {
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// Err returns the error that stopped the App from being built, or nil
+func (a *App) Err() error {
+	return a.err
+}
+
+// Start runs the OnStart hooks in order. If one fails, the hooks that
+// already ran are stopped in reverse order.
+func (a *App) Start(ctx context.Context) error {
+	if a.err != nil {
+		return a.err
+	}
+	l := a.lifecycle
+	for l.started < len(l.hooks) {
+		h := l.hooks[l.started]
+		if h.OnStart != nil {
+			if err := h.OnStart(ctx); err != nil {
+				if stopErr := a.Stop(ctx); stopErr != nil {
+					return errors.Join(err, stopErr)
+				}
+				return err
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs the OnStop hooks of the started hooks in reverse order,
+// returning their errors together
+func (a *App) Stop(ctx context.Context) error {
+	l := a.lifecycle
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.OnStop != nil {
+			if err := h.OnStop(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
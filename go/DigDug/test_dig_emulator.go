@@ -0,0 +1,556 @@
+package main
+
+// Developed by PowerShield, as an alternative to Uber's dig and fx dependency injection
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+type Config struct {
+	DSN string
+}
+
+type DB struct {
+	Config *Config
+}
+
+type Repo struct {
+	DB *DB
+}
+
+type Server struct {
+	Repo *Repo
+	Name string
+}
+
+func NewConfig() *Config {
+	return &Config{DSN: "postgres://localhost"}
+}
+
+func NewDB(cfg *Config) (*DB, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("empty DSN")
+	}
+	return &DB{Config: cfg}, nil
+}
+
+func NewRepo(db *DB) *Repo {
+	return &Repo{DB: db}
+}
+
+type A struct{}
+type B struct{}
+type C struct{}
+
+func NewA(*B) *A { return &A{} }
+func NewB(*C) *B { return &B{} }
+func NewC(*A) *C { return &C{} }
+
+func testProvideAndInvoke() bool {
+	c := New()
+	calls := 0
+	c.Provide(NewConfig)
+	c.Provide(func(cfg *Config) (*DB, error) {
+		calls++
+		return NewDB(cfg)
+	})
+	c.Provide(NewRepo)
+	if calls != 0 {
+		return false
+	}
+
+	var first, second *Repo
+	if err := c.Invoke(func(r *Repo) { first = r }); err != nil {
+		return false
+	}
+	c.Invoke(func(r *Repo, db *DB) { second = r })
+
+	// Constructors run once, and only when needed
+	if first == nil || first != second || calls != 1 || first.DB.Config.DSN != "postgres://localhost" {
+		return false
+	}
+	return strings.Contains(c.String(), "*main.DB") && strings.Contains(c.String(), "called")
+}
+
+func testConstructorErrors() bool {
+	c := New()
+	boom := errors.New("boom")
+	c.Provide(NewConfig)
+	c.Provide(func(*Config) (*DB, error) { return nil, boom })
+	c.Provide(NewRepo)
+
+	err := c.Invoke(func(*Repo) {})
+	if err == nil || RootCause(err) != boom || !errors.Is(err, boom) {
+		return false
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "could not build arguments for function") || !strings.Contains(msg, "failed to build *main.Repo") ||
+		!strings.Contains(msg, "received non-nil error from function") || !strings.HasSuffix(msg, ": boom") {
+		return false
+	}
+
+	// An invoked function's own error is returned as it is
+	invokeErr := errors.New("invoke failed")
+	return c.Invoke(func(*Config) error { return invokeErr }) == invokeErr
+}
+
+func testMissingDependencies() bool {
+	c := New()
+	c.Provide(NewRepo)
+
+	err := c.Invoke(func(*Repo) {})
+	if err == nil || !strings.Contains(err.Error(), "missing dependencies for function \"main\".NewRepo") ||
+		!strings.Contains(err.Error(), "missing type: *main.DB") {
+		return false
+	}
+	err = c.Invoke(func(*Config, *DB) {})
+	if err == nil || !strings.Contains(err.Error(), "missing types: *main.Config; *main.DB") {
+		return false
+	}
+	// The location of the function is part of its description
+	return strings.Contains(err.Error(), "test_dig_emulator.go:")
+}
+
+func testProvideValidation() bool {
+	c := New()
+	if err := c.Provide("not a function"); err == nil || !strings.Contains(err.Error(), "must provide constructor function") {
+		return false
+	}
+	c.Provide(NewConfig)
+	err := c.Provide(func() *Config { return nil })
+	if err == nil || !strings.Contains(err.Error(), "already provided by \"main\".NewConfig") {
+		return false
+	}
+	if err := c.Provide(func() error { return nil }); err == nil || !strings.Contains(err.Error(), "at least one non-error type") {
+		return false
+	}
+	if err := c.Provide(func() (error, *DB) { return nil, nil }); err == nil {
+		return false
+	}
+	if err := c.Provide(NewRepo, Name("a"), Group("b")); err == nil || !strings.Contains(err.Error(), "cannot use named values with value groups") {
+		return false
+	}
+	type badIn struct {
+		In
+		db *DB
+	}
+	if err := c.Provide(func(badIn) *Server { return nil }); err == nil || !strings.Contains(err.Error(), "unexported fields") {
+		return false
+	}
+	return c.Invoke(func(*Config) {}) == nil
+}
+
+func testNamedValues() bool {
+	c := New()
+	c.Provide(func() *Config { return &Config{DSN: "primary"} }, Name("primary"))
+	c.Provide(func() *Config { return &Config{DSN: "replica"} }, Name("replica"))
+
+	type dbs struct {
+		Out
+		Primary *DB `name:"primary"`
+		Replica *DB `name:"replica"`
+	}
+	c.Provide(func(p struct {
+		In
+		Primary *Config `name:"primary"`
+		Replica *Config `name:"replica"`
+	}) dbs {
+		return dbs{Primary: &DB{Config: p.Primary}, Replica: &DB{Config: p.Replica}}
+	})
+
+	type params struct {
+		In
+		Primary *DB `name:"primary"`
+		Replica *DB `name:"replica"`
+	}
+	var got params
+	if err := c.Invoke(func(p params) { got = p }); err != nil {
+		return false
+	}
+	if got.Primary.Config.DSN != "primary" || got.Replica.Config.DSN != "replica" {
+		return false
+	}
+
+	// The unnamed type is a different key
+	err := c.Invoke(func(*DB) {})
+	return err != nil && strings.Contains(err.Error(), "missing type: *main.DB") &&
+		IsIn(params{}) && !IsIn(got.Primary) && IsOut(dbs{})
+}
+
+type Handler interface {
+	Pattern() string
+}
+
+type handler string
+
+func (h handler) Pattern() string { return string(h) }
+
+func testValueGroups() bool {
+	c := New()
+	c.Provide(func() Handler { return handler("/users") }, Group("routes"))
+	c.Provide(func() Handler { return handler("/orders") }, Group("routes"))
+
+	type routes struct {
+		Out
+		Admin  []Handler `group:"routes,flatten"`
+		Health Handler   `group:"routes"`
+	}
+	c.Provide(func() routes {
+		return routes{Admin: []Handler{handler("/admin/a"), handler("/admin/b")}, Health: handler("/health")}
+	})
+
+	var patterns []string
+	err := c.Invoke(func(p struct {
+		In
+		Routes []Handler `group:"routes"`
+	}) {
+		for _, h := range p.Routes {
+			patterns = append(patterns, h.Pattern())
+		}
+	})
+	if err != nil || strings.Join(patterns, ",") != "/users,/orders,/admin/a,/admin/b,/health" {
+		return false
+	}
+
+	// An empty group is an empty slice, not an error
+	var empty []Handler
+	err = c.Invoke(func(p struct {
+		In
+		Jobs []Handler `group:"jobs"`
+	}) {
+		empty = p.Jobs
+	})
+	if err != nil || empty == nil || len(empty) != 0 {
+		return false
+	}
+	type notSlice struct {
+		In
+		H Handler `group:"routes"`
+	}
+	return c.Invoke(func(notSlice) {}) != nil
+}
+
+func testOptionalDependencies() bool {
+	c := New()
+	c.Provide(NewConfig)
+
+	type params struct {
+		In
+		Config *Config
+		DB     *DB    `optional:"true"`
+		Name   string `name:"service" optional:"true"`
+	}
+	var got params
+	if err := c.Invoke(func(p params) { got = p }); err != nil {
+		return false
+	}
+	if got.Config == nil || got.DB != nil || got.Name != "" {
+		return false
+	}
+	c.Provide(func() string { return "orders" }, Name("service"))
+	c.Invoke(func(p params) { got = p })
+	return got.Name == "orders"
+}
+
+type buffer struct {
+	strings.Builder
+}
+
+func (b *buffer) Close() error { return nil }
+
+func testInterfacesWithAs() bool {
+	c := New()
+	if err := c.Provide(func() *buffer { return &buffer{} }, As(new(io.Writer), new(io.Closer))); err != nil {
+		return false
+	}
+
+	var w io.Writer
+	var cl io.Closer
+	if err := c.Invoke(func(writer io.Writer, closer io.Closer) { w, cl = writer, closer }); err != nil {
+		return false
+	}
+	if w == nil || w != cl.(io.Writer) {
+		return false
+	}
+
+	// The concrete type is not provided
+	if err := c.Invoke(func(*buffer) {}); err == nil {
+		return false
+	}
+	err := c.Provide(NewConfig, As(new(io.Reader)))
+	if err == nil || !strings.Contains(err.Error(), "does not implement io.Reader") {
+		return false
+	}
+
+	// As combines with Name
+	c.Provide(func() *buffer { return &buffer{} }, As(new(io.Writer)), Name("log"))
+	return c.Invoke(func(p struct {
+		In
+		Log io.Writer `name:"log"`
+	}) error {
+		if p.Log == w {
+			return errors.New("same writer")
+		}
+		return nil
+	}) == nil
+}
+
+func testTransientScope() bool {
+	c := New()
+	calls := 0
+	c.Provide(NewConfig)
+	c.Provide(func(cfg *Config) *DB {
+		calls++
+		return &DB{Config: cfg}
+	}, Transient())
+
+	var dbs []*DB
+	c.Provide(func(db *DB) *Repo { return &Repo{DB: db} })
+	c.Invoke(func(db *DB, r *Repo) { dbs = append(dbs, db, r.DB) })
+	c.Invoke(func(db *DB) { dbs = append(dbs, db) })
+
+	// A transient constructor runs for every dependent; its own
+	// dependencies stay singletons
+	if calls != 3 || dbs[0] == dbs[1] || dbs[1] == dbs[2] {
+		return false
+	}
+	if dbs[0].Config != dbs[2].Config {
+		return false
+	}
+	return strings.Contains(c.String(), "transient")
+}
+
+func testCycleDetection() bool {
+	c := New()
+	c.Provide(NewA)
+	c.Provide(NewB)
+	err := c.Provide(NewC)
+	if err == nil || !IsCycleDetected(err) {
+		return false
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "this function introduces a cycle") || !strings.Contains(msg, "cycle detected in dependency graph") {
+		return false
+	}
+
+	// The error shows the path around the cycle
+	path := []string{"*main.C provided by \"main\".NewC", "depends on *main.A provided by \"main\".NewA",
+		"depends on *main.B provided by \"main\".NewB", "depends on *main.C provided by \"main\".NewC"}
+	last := -1
+	for _, step := range path {
+		i := strings.Index(msg[last+1:], step)
+		if i < 0 {
+			return false
+		}
+		last += i + 1
+	}
+
+	// The rejected constructor is not registered
+	err = c.Invoke(func(*A) {})
+	if err == nil || IsCycleDetected(err) || !strings.Contains(err.Error(), "missing type: *main.C") {
+		return false
+	}
+	return !IsCycleDetected(errors.New("x"))
+}
+
+func testDeferredCycleDetection() bool {
+	c := New(DeferAcyclicVerification())
+	for _, ctor := range []interface{}{NewA, NewB, NewC} {
+		if err := c.Provide(ctor); err != nil {
+			return false
+		}
+	}
+	err := c.Invoke(func(*A) {})
+	if !IsCycleDetected(err) {
+		return false
+	}
+
+	// Self-dependency is a cycle too
+	c2 := New()
+	err = c2.Provide(func(*Config) *Config { return nil })
+	return IsCycleDetected(err)
+}
+
+func testDryRun() bool {
+	c := New(DryRun(true))
+	called := false
+	c.Provide(NewConfig)
+	c.Provide(func(*Config) (*DB, error) {
+		called = true
+		return nil, errors.New("never")
+	})
+	c.Provide(NewRepo)
+
+	if err := c.Invoke(func(*Repo) { called = true }); err != nil || called {
+		return false
+	}
+	// Missing dependencies are still reported
+	return c.Invoke(func(*Server) {}) != nil
+}
+
+func testParameterAndResultObjects() bool {
+	c := New()
+
+	type infra struct {
+		Out
+		Config *Config
+		DB     *DB
+	}
+	c.Provide(func() (infra, error) {
+		cfg := NewConfig()
+		return infra{Config: cfg, DB: &DB{Config: cfg}}, nil
+	})
+	c.Provide(func() string { return "api" }, Name("name"))
+
+	type deps struct {
+		In
+		DB *DB
+	}
+	type serverParams struct {
+		In
+		Deps deps
+		Name string `name:"name"`
+	}
+	c.Provide(func(p serverParams) *Server {
+		return &Server{Repo: &Repo{DB: p.Deps.DB}, Name: p.Name}
+	})
+
+	var s *Server
+	var cfg *Config
+	if err := c.Invoke(func(srv *Server, c *Config) { s, cfg = srv, c }); err != nil {
+		return false
+	}
+	if s.Name != "api" || s.Repo.DB.Config != cfg {
+		return false
+	}
+	return c.Provide(func() infra { return infra{} }, Name("x")) != nil
+}
+
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) hook(name string, startErr error) Hook {
+	return Hook{
+		OnStart: func(context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func testApplicationLifecycle() bool {
+	rec := &recorder{}
+	var repo *Repo
+	var primary *Config
+	app := NewApp(
+		Supply(&Config{DSN: "sqlite://"}),
+		Options(
+			Provide(NewDB, NewRepo),
+			Provide(Annotated{Name: "primary", Target: func() *Config { return &Config{DSN: "primary"} }}),
+		),
+		Invoke(func(lc Lifecycle, db *DB) { lc.Append(rec.hook("db", nil)) }),
+		Invoke(func(lc Lifecycle, r *Repo) { lc.Append(rec.hook("repo", nil)) }),
+		Populate(&repo),
+		Invoke(func(p struct {
+			In
+			Config *Config `name:"primary"`
+		}) {
+			primary = p.Config
+		}),
+	)
+	if app.Err() != nil || repo == nil || repo.DB.Config.DSN != "sqlite://" || primary.DSN != "primary" {
+		return false
+	}
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		return false
+	}
+	if err := app.Stop(ctx); err != nil {
+		return false
+	}
+	if strings.Join(rec.events, ",") != "start db,start repo,stop repo,stop db" {
+		return false
+	}
+
+	// A failed start stops what already started
+	rec.events = nil
+	startErr := errors.New("port in use")
+	app = NewApp(Invoke(func(lc Lifecycle) {
+		lc.Append(rec.hook("a", nil))
+		lc.Append(rec.hook("b", startErr))
+		lc.Append(rec.hook("c", nil))
+	}))
+	if err := app.Start(ctx); err != startErr {
+		return false
+	}
+	return strings.Join(rec.events, ",") == "start a,start b,stop a"
+}
+
+func testApplicationErrors() bool {
+	invoked := false
+	app := NewApp(
+		Provide(NewRepo),
+		Invoke(func(*Repo) { invoked = true }),
+		Invoke(func() { invoked = true }),
+	)
+	if app.Err() == nil || invoked || !strings.Contains(app.Err().Error(), "missing type: *main.DB") {
+		return false
+	}
+	if err := app.Start(context.Background()); err != app.Err() {
+		return false
+	}
+
+	app = NewApp(Supply(nil))
+	if app.Err() == nil {
+		return false
+	}
+	var n int
+	app = NewApp(Populate(n))
+	if app.Err() == nil {
+		return false
+	}
+	app = NewApp(Populate(&n))
+	return app.Err() != nil && strings.Contains(app.Err().Error(), "fx.Populate(*int)")
+}
+
+func main() {
+	fmt.Println("Running dig Emulator Tests...")
+	fmt.Println("=============================")
+
+	runTest("Provide And Invoke", testProvideAndInvoke)
+	runTest("Constructor Errors", testConstructorErrors)
+	runTest("Missing Dependencies", testMissingDependencies)
+	runTest("Provide Validation", testProvideValidation)
+	runTest("Named Values", testNamedValues)
+	runTest("Value Groups", testValueGroups)
+	runTest("Optional Dependencies", testOptionalDependencies)
+	runTest("Interfaces With As", testInterfacesWithAs)
+	runTest("Transient Scope", testTransientScope)
+	runTest("Cycle Detection", testCycleDetection)
+	runTest("Deferred Cycle Detection", testDeferredCycleDetection)
+	runTest("Dry Run", testDryRun)
+	runTest("Parameter And Result Objects", testParameterAndResultObjects)
+	runTest("Application Lifecycle", testApplicationLifecycle)
+	runTest("Application Errors", testApplicationErrors)
+
+	fmt.Println("=============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,340 @@
+package main
+
+// Developed by PowerShield, as an alternative to google/uuid and oklog/ulid
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// countingReader returns the bytes 0, 1, 2, ... for predictable IDs
+type countingReader struct{ next byte }
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.next
+		r.next++
+	}
+	return len(p), nil
+}
+
+// fixedClock always returns the same time
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func panics(f func()) (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+	f()
+	return false
+}
+
+func testNewV4() bool {
+	seen := make(map[UUID]bool)
+	for i := 0; i < 1000; i++ {
+		u := New()
+		if u.Version() != 4 || u.Variant() != RFC4122 || seen[u] {
+			return false
+		}
+		seen[u] = true
+	}
+	return Version(4).String() == "VERSION_4" && RFC4122.String() == "RFC4122"
+}
+
+func testStringForms() bool {
+	g := NewGenerator(&countingReader{}, nil)
+	u, err := g.NewV4()
+	if err != nil {
+		return false
+	}
+	s := NewString()
+	return u.String() == "00010203-0405-4607-8809-0a0b0c0d0e0f" &&
+		u.URN() == "urn:uuid:00010203-0405-4607-8809-0a0b0c0d0e0f" &&
+		len(s) == 36 && strings.Count(s, "-") == 4 &&
+		Nil.String() == "00000000-0000-0000-0000-000000000000" &&
+		Max.String() == "ffffffff-ffff-ffff-ffff-ffffffffffff"
+}
+
+func testParse() bool {
+	want := MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	for _, s := range []string{
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+		"urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"{6ba7b810-9dad-11d1-80b4-00c04fd430c8}",
+		"6ba7b8109dad11d180b400c04fd430c8",
+	} {
+		u, err := Parse(s)
+		if err != nil || u != want {
+			return false
+		}
+	}
+	fromBytes, err := FromBytes(want[:])
+	return err == nil && fromBytes == want && want.Version() == 1
+}
+
+func testParseErrors() bool {
+	if err := Validate("6ba7b810-9dad-11d1-80b4"); !IsInvalidLengthError(err) {
+		return false
+	}
+	for _, s := range []string{
+		"6ba7b810x9dad-11d1-80b4-00c04fd430c8",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cg",
+		"urn:uuix:6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		"(6ba7b810-9dad-11d1-80b4-00c04fd430c8)",
+		"6ba7b8109dad11d180b400c04fd430cz",
+	} {
+		if Validate(s) == nil {
+			return false
+		}
+	}
+	_, err := FromBytes([]byte{1, 2, 3})
+	return err != nil && panics(func() { MustParse("nope") }) && Validate(NewString()) == nil
+}
+
+func testNewV7() bool {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := NewGenerator(nil, fixedClock(at))
+	var ids []string
+	for i := 0; i < 10; i++ {
+		u, err := g.NewV7()
+		if err != nil || u.Version() != 7 || u.Variant() != RFC4122 {
+			return false
+		}
+		ids = append(ids, u.String())
+	}
+	// Sorting the strings sorts by creation
+	if !sort.StringsAreSorted(ids) {
+		return false
+	}
+	last := MustParse(ids[9])
+	ts, ok := last.Timestamp()
+	if !ok || !ts.Equal(at) {
+		return false
+	}
+	_, ok = New().Timestamp()
+	return !ok
+}
+
+func testV7SequenceOverflow() bool {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := NewGenerator(nil, fixedClock(at))
+	var prev UUID
+	for i := 0; i < 5000; i++ {
+		u, _ := g.NewV7()
+		if bytes.Compare(u[:], prev[:]) <= 0 {
+			return false
+		}
+		prev = u
+	}
+	// More than 4096 in one millisecond borrow the next millisecond
+	ts, _ := prev.Timestamp()
+	return ts.Equal(at.Add(time.Millisecond))
+}
+
+func testTextAndJSON() bool {
+	type record struct {
+		ID    UUID `json:"id"`
+		Trace ULID `json:"trace"`
+	}
+	in := record{ID: New(), Trace: MakeULID()}
+	data, err := json.Marshal(in)
+	if err != nil || !strings.Contains(string(data), `"id":"`+in.ID.String()+`"`) {
+		return false
+	}
+	var out record
+	if err := json.Unmarshal(data, &out); err != nil || out != in {
+		return false
+	}
+	bin, _ := in.ID.MarshalBinary()
+	var fromBin UUID
+	fromBin.UnmarshalBinary(bin)
+	return fromBin == in.ID && json.Unmarshal([]byte(`{"id":"bad"}`), &out) != nil
+}
+
+func testSQL() bool {
+	u := New()
+	value, err := u.Value()
+	if err != nil || value != u.String() {
+		return false
+	}
+	var scanned UUID
+	if scanned.Scan(u.String()) != nil || scanned != u {
+		return false
+	}
+	if scanned.Scan(u[:]) != nil || scanned != u {
+		return false
+	}
+	if scanned.Scan(nil) != nil || scanned != Nil {
+		return false
+	}
+	var id ULID
+	ulid := MakeULID()
+	if id.Scan(ulid.String()) != nil || id != ulid {
+		return false
+	}
+	return scanned.Scan(42) != nil && id.Scan(42) != nil
+}
+
+func testULIDEncoding() bool {
+	var largest ULID
+	for i := range largest {
+		largest[i] = 0xff
+	}
+	if (ULID{}).String() != "00000000000000000000000000" || largest.String() != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		return false
+	}
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	id := MustNewULID(ULIDTimestamp(at), &countingReader{})
+	s := id.String()
+	parsed, err := ParseULID(strings.ToLower(s))
+	return err == nil && parsed == id && len(s) == EncodedSize && id.Timestamp().Equal(at) &&
+		bytes.Equal(id.Entropy(), []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+}
+
+func testULIDErrors() bool {
+	if ValidateULID("01ARZ3NDEKTSV4RRFFQ69G5FA") != ErrDataSize {
+		return false
+	}
+	if ValidateULID("01ARZ3NDEKTSV4RRFFQ69G5FAU") != ErrInvalidCharacters {
+		return false
+	}
+	if ValidateULID("81ARZ3NDEKTSV4RRFFQ69G5FAV") != ErrOverflow {
+		return false
+	}
+	if _, err := NewULID(1<<48, nil); err != ErrBigTime {
+		return false
+	}
+	return ValidateULID("01ARZ3NDEKTSV4RRFFQ69G5FAV") == nil &&
+		panics(func() { MustParseULID("nope") })
+}
+
+func testULIDMonotonic() bool {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := NewGenerator(nil, fixedClock(at))
+	var ids []ULID
+	for i := 0; i < 100; i++ {
+		id, err := g.NewULID()
+		if err != nil || id.Timestamp() != at {
+			return false
+		}
+		ids = append(ids, id)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i-1].Compare(ids[i]) >= 0 || ids[i-1].String() >= ids[i].String() {
+			return false
+		}
+	}
+
+	// MonotonicEntropy does the same for NewULID
+	entropy := Monotonic(&countingReader{}, 10)
+	ms := ULIDTimestamp(at)
+	a := MustNewULID(ms, entropy)
+	b := MustNewULID(ms, entropy)
+	c := MustNewULID(ms+1, entropy)
+	if a.Compare(b) >= 0 || b.Compare(c) >= 0 {
+		return false
+	}
+	// The last millisecond's random bits can run out
+	full := Monotonic(&countingReader{next: 0xff}, 1)
+	full.last = [10]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	full.ms, full.used = ms, true
+	_, err := NewULID(ms, full)
+	return err == ErrMonotonicOverflow
+}
+
+func testSeededGenerator() bool {
+	sequence := func(g *Generator) string {
+		v4, _ := g.NewV4()
+		v7, _ := g.NewV7()
+		ulid, _ := g.NewULID()
+		return v4.String() + " " + v7.String() + " " + ulid.String()
+	}
+	a := sequence(NewSeededGenerator(42))
+	b := sequence(NewSeededGenerator(42))
+	c := sequence(NewSeededGenerator(7))
+	if a != b || a == c {
+		return false
+	}
+	g := NewSeededGenerator(1)
+	first, _ := g.NewULID()
+	second, _ := g.NewULID()
+	// The seeded clock moves a millisecond per read
+	return first.Timestamp().Equal(SeededStart) && second.Timestamp().Equal(SeededStart.Add(time.Millisecond))
+}
+
+func testSeedDefault() bool {
+	defer Unseed()
+	Seed(99)
+	first := []string{NewString(), MakeULID().String()}
+	Seed(99)
+	second := []string{NewString(), MakeULID().String()}
+	if strings.Join(first, ",") != strings.Join(second, ",") {
+		return false
+	}
+	SetRand(&countingReader{})
+	if NewString() != "00010203-0405-4607-8809-0a0b0c0d0e0f" {
+		return false
+	}
+	Unseed()
+	return NewString() != NewString() && MakeULID().Timestamp().After(SeededStart.Add(time.Hour))
+}
+
+func testConcurrentGeneration() bool {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				v7, _ := NewV7()
+				ids := []string{NewString(), v7.String(), MakeULID().String()}
+				mu.Lock()
+				for _, id := range ids {
+					seen[id] = true
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return len(seen) == 8*200*3
+}
+
+func main() {
+	fmt.Println("Running uuid Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("NewV4", testNewV4)
+	runTest("String Forms", testStringForms)
+	runTest("Parse", testParse)
+	runTest("Parse Errors", testParseErrors)
+	runTest("NewV7", testNewV7)
+	runTest("V7 Sequence Overflow", testV7SequenceOverflow)
+	runTest("Text and JSON", testTextAndJSON)
+	runTest("SQL", testSQL)
+	runTest("ULID Encoding", testULIDEncoding)
+	runTest("ULID Errors", testULIDErrors)
+	runTest("ULID Monotonic", testULIDMonotonic)
+	runTest("Seeded Generator", testSeededGenerator)
+	runTest("Seed Default", testSeedDefault)
+	runTest("Concurrent Generation", testConcurrentGeneration)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
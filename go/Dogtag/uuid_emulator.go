@@ -0,0 +1,717 @@
+package main
+
+// Developed by PowerShield, as an alternative to google/uuid and oklog/ulid
+import (
+	"bytes"
+	crand "crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Generators
+
+// Generator makes UUIDs and ULIDs from a source of random bytes and a
+// clock. It keeps v7 UUIDs and ULIDs made in the same millisecond in
+// order. The package-level functions use a default generator.
+type Generator struct {
+	mu   sync.Mutex
+	rand io.Reader
+	now  func() time.Time
+
+	// lastV7 and v7Seq keep v7 UUIDs monotonic
+	lastV7 int64
+	v7Seq  uint16
+	// lastULID keeps ULIDs from the same millisecond monotonic
+	lastULID ULID
+}
+
+// NewGenerator returns a generator reading random bytes from r, or from
+// crypto/rand if r is nil, and the time from now, or time.Now if nil
+func NewGenerator(r io.Reader, now func() time.Time) *Generator {
+	if r == nil {
+		r = crand.Reader
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &Generator{rand: r, now: now}
+}
+
+// SeededStart is the time a seeded generator's clock starts at
+var SeededStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewSeededGenerator returns a generator that makes the same IDs in the
+// same order for the same seed: random bytes come from math/rand seeded
+// with seed, and the clock starts at SeededStart and moves one millisecond
+// each time it is read. This is an emulator extension for reproducible
+// tests.
+func NewSeededGenerator(seed int64) *Generator {
+	source := rand.New(rand.NewSource(seed))
+	var mu sync.Mutex
+	tick := SeededStart
+	return NewGenerator(source, func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		t := tick
+		tick = tick.Add(time.Millisecond)
+		return t
+	})
+}
+
+var (
+	defaultGenMu sync.Mutex
+	defaultGen   = NewGenerator(nil, nil)
+)
+
+func generator() *Generator {
+	defaultGenMu.Lock()
+	defer defaultGenMu.Unlock()
+	return defaultGen
+}
+
+// SetRand makes the default generator read random bytes from r; nil
+// restores crypto/rand
+func SetRand(r io.Reader) {
+	defaultGenMu.Lock()
+	defer defaultGenMu.Unlock()
+	defaultGen = NewGenerator(r, defaultGen.now)
+}
+
+// Seed makes the package-level functions reproducible, as with
+// NewSeededGenerator. This is an emulator extension.
+func Seed(seed int64) {
+	defaultGenMu.Lock()
+	defer defaultGenMu.Unlock()
+	defaultGen = NewSeededGenerator(seed)
+}
+
+// Unseed restores crypto/rand and the system clock for the package-level
+// functions
+func Unseed() {
+	defaultGenMu.Lock()
+	defer defaultGenMu.Unlock()
+	defaultGen = NewGenerator(nil, nil)
+}
+
+// UUIDs
+
+// UUID is a 128-bit universally unique identifier, RFC 9562
+type UUID [16]byte
+
+var (
+	// Nil is the UUID with all bits zero
+	Nil UUID
+	// Max is the UUID with all bits set
+	Max = UUID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+)
+
+// Version is the version of a UUID, from its 13th hex digit
+type Version byte
+
+// String returns "VERSION_n", or "BAD_VERSION_n" above 15
+func (v Version) String() string {
+	if v > 15 {
+		return fmt.Sprintf("BAD_VERSION_%d", v)
+	}
+	return fmt.Sprintf("VERSION_%d", v)
+}
+
+// Variant is the layout of a UUID, from the top bits of its 17th hex digit
+type Variant byte
+
+// The UUID variants
+const (
+	Invalid   = Variant(iota) // Invalid UUID
+	RFC4122                   // The variant of RFC 4122 and RFC 9562
+	Reserved                  // Reserved for NCS backward compatibility
+	Microsoft                 // Reserved for Microsoft backward compatibility
+	Future                    // Reserved for future definition
+)
+
+// String returns the name of the variant
+func (v Variant) String() string {
+	switch v {
+	case RFC4122:
+		return "RFC4122"
+	case Reserved:
+		return "Reserved"
+	case Microsoft:
+		return "Microsoft"
+	case Future:
+		return "Future"
+	case Invalid:
+		return "Invalid"
+	}
+	return fmt.Sprintf("BadVariant%d", int(v))
+}
+
+// NewV4 returns a random (version 4) UUID
+func (g *Generator) NewV4() (UUID, error) {
+	var u UUID
+	g.mu.Lock()
+	_, err := io.ReadFull(g.rand, u[:])
+	g.mu.Unlock()
+	if err != nil {
+		return Nil, err
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u, nil
+}
+
+// NewV7 returns a time-ordered (version 7) UUID: 48 bits of Unix
+// milliseconds, then a 12-bit sequence that keeps UUIDs from the same
+// millisecond in order, then random bits
+func (g *Generator) NewV7() (UUID, error) {
+	var u UUID
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, err := io.ReadFull(g.rand, u[8:]); err != nil {
+		return Nil, err
+	}
+
+	t := g.now()
+	ms := t.UnixMilli()
+	// The sequence starts from the fraction of the millisecond
+	seq := uint16(int64(t.Nanosecond()%1e6) * 4096 / 1e6)
+	if ms <= g.lastV7 {
+		ms = g.lastV7
+		seq = g.v7Seq + 1
+		if seq > 0xfff {
+			ms++
+			seq = 0
+		}
+	}
+	g.lastV7, g.v7Seq = ms, seq
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	binary.BigEndian.PutUint32(u[2:6], uint32(ms))
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = u[8]&0x3f | 0x80
+	return u, nil
+}
+
+// New returns a random UUID, or panics if random bytes cannot be read
+func New() UUID {
+	return Must(NewRandom())
+}
+
+// NewString returns a random UUID as a string, or panics
+func NewString() string {
+	return New().String()
+}
+
+// NewRandom returns a random (version 4) UUID
+func NewRandom() (UUID, error) {
+	return generator().NewV4()
+}
+
+// NewV7 returns a time-ordered (version 7) UUID
+func NewV7() (UUID, error) {
+	return generator().NewV7()
+}
+
+// Must returns u, or panics if err is not nil
+func Must(u UUID, err error) UUID {
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// invalidLengthError reports a string of the wrong length
+type invalidLengthError struct{ len int }
+
+func (err invalidLengthError) Error() string {
+	return fmt.Sprintf("invalid UUID length: %d", err.len)
+}
+
+// IsInvalidLengthError reports whether err is from a string of the wrong
+// length
+func IsInvalidLengthError(err error) bool {
+	var target invalidLengthError
+	return errors.As(err, &target)
+}
+
+// Parse decodes a UUID in the forms xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx,
+// urn:uuid:xxxxxxxx-..., {xxxxxxxx-...} or 32 hex digits, in either case
+func Parse(s string) (UUID, error) {
+	var u UUID
+	switch len(s) {
+	case 36:
+	case 36 + 9:
+		if !strings.EqualFold(s[:9], "urn:uuid:") {
+			return u, fmt.Errorf("invalid urn prefix: %q", s[:9])
+		}
+		s = s[9:]
+	case 36 + 2:
+		if s[0] != '{' || s[len(s)-1] != '}' {
+			return u, errors.New("invalid bracketed UUID format")
+		}
+		s = s[1 : len(s)-1]
+	case 32:
+		if _, err := hex.Decode(u[:], []byte(s)); err != nil {
+			return Nil, errors.New("invalid UUID format")
+		}
+		return u, nil
+	default:
+		return u, invalidLengthError{len(s)}
+	}
+
+	if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errors.New("invalid UUID format")
+	}
+	digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(digits)); err != nil {
+		return Nil, errors.New("invalid UUID format")
+	}
+	return u, nil
+}
+
+// ParseBytes is Parse for a byte slice
+func ParseBytes(b []byte) (UUID, error) {
+	return Parse(string(b))
+}
+
+// MustParse is Parse that panics on error, for constants
+func MustParse(s string) UUID {
+	u, err := Parse(s)
+	if err != nil {
+		panic(`uuid: Parse(` + s + `): ` + err.Error())
+	}
+	return u
+}
+
+// FromBytes returns the UUID made of the 16 bytes in b
+func FromBytes(b []byte) (UUID, error) {
+	var u UUID
+	if len(b) != 16 {
+		return u, fmt.Errorf("invalid UUID (got %d bytes)", len(b))
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// Validate returns the error Parse would return for s, without making a
+// UUID
+func Validate(s string) error {
+	_, err := Parse(s)
+	return err
+}
+
+// String returns the canonical form, xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// URN returns the form urn:uuid:xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func (u UUID) URN() string {
+	return "urn:uuid:" + u.String()
+}
+
+// Version returns the version of u
+func (u UUID) Version() Version {
+	return Version(u[6] >> 4)
+}
+
+// Variant returns the variant of u
+func (u UUID) Variant() Variant {
+	switch {
+	case (u[8] & 0xc0) == 0x80:
+		return RFC4122
+	case (u[8] & 0xe0) == 0xc0:
+		return Microsoft
+	case (u[8] & 0xe0) == 0xe0:
+		return Future
+	default:
+		return Reserved
+	}
+}
+
+// Timestamp returns the time in a version 7 UUID, to the millisecond, and
+// false for other versions. This is an emulator extension.
+func (u UUID) Timestamp() (time.Time, bool) {
+	if u.Version() != 7 {
+		return time.Time{}, false
+	}
+	ms := int64(u[0])<<40 | int64(u[1])<<32 | int64(binary.BigEndian.Uint32(u[2:6]))
+	return time.UnixMilli(ms).UTC(), true
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(data []byte) error {
+	id, err := ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	*u = id
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (u UUID) MarshalBinary() ([]byte, error) {
+	return u[:], nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (u *UUID) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return fmt.Errorf("invalid UUID (got %d bytes)", len(data))
+	}
+	copy(u[:], data)
+	return nil
+}
+
+// Value implements driver.Valuer, storing the UUID as a string
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Scan implements sql.Scanner for strings and 16-byte slices; nil and the
+// empty string scan as Nil
+func (u *UUID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*u = Nil
+		return nil
+	case string:
+		if src == "" {
+			*u = Nil
+			return nil
+		}
+		id, err := Parse(src)
+		if err != nil {
+			return fmt.Errorf("Scan: %v", err)
+		}
+		*u = id
+	case []byte:
+		if len(src) == 0 {
+			*u = Nil
+			return nil
+		}
+		if len(src) != 16 {
+			return u.Scan(string(src))
+		}
+		copy(u[:], src)
+	default:
+		return fmt.Errorf("Scan: unable to scan type %T into UUID", src)
+	}
+	return nil
+}
+
+// ULIDs
+
+// ULID is a universally unique lexicographically sortable identifier: 48
+// bits of Unix milliseconds and 80 random bits, written as 26 characters
+// of Crockford's base32
+type ULID [16]byte
+
+// EncodedSize is the length of a ULID string
+const EncodedSize = 26
+
+// crockford is Crockford's base32 alphabet, without I, L, O and U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// crockfordValues decodes the alphabet, in both cases; 0xff is invalid
+var crockfordValues = func() [256]byte {
+	var values [256]byte
+	for i := range values {
+		values[i] = 0xff
+	}
+	for i := 0; i < len(crockford); i++ {
+		values[crockford[i]] = byte(i)
+		values[strings.ToLower(crockford[i : i+1])[0]] = byte(i)
+	}
+	return values
+}()
+
+var (
+	// ErrDataSize is returned for ULID strings or bytes of the wrong size
+	ErrDataSize = errors.New("ulid: bad data size when unmarshaling")
+	// ErrInvalidCharacters is returned for characters outside the alphabet
+	ErrInvalidCharacters = errors.New("ulid: bad data characters when unmarshaling")
+	// ErrBigTime is returned for times beyond 48 bits of milliseconds
+	ErrBigTime = errors.New("ulid: time too big")
+	// ErrOverflow is returned for strings above the largest ULID
+	ErrOverflow = errors.New("ulid: overflow when unmarshaling")
+	// ErrMonotonicOverflow is returned when the random bits of a
+	// millisecond run out
+	ErrMonotonicOverflow = errors.New("ulid: monotonic entropy overflow")
+)
+
+// maxTime is the largest time a ULID holds, in Unix milliseconds
+const maxTime = 1<<48 - 1
+
+// ULIDTimestamp converts a time to Unix milliseconds for NewULID
+func ULIDTimestamp(t time.Time) uint64 {
+	return uint64(t.UnixMilli())
+}
+
+// NewULID returns a ULID for the time ms, with random bits read from
+// entropy. A MonotonicEntropy keeps ULIDs of the same millisecond in
+// order; a nil entropy leaves the random bits zero.
+func NewULID(ms uint64, entropy io.Reader) (ULID, error) {
+	var id ULID
+	if err := id.SetTime(ms); err != nil {
+		return id, err
+	}
+	switch e := entropy.(type) {
+	case nil:
+		return id, nil
+	case *MonotonicEntropy:
+		return id, e.MonotonicRead(ms, id[6:])
+	default:
+		_, err := io.ReadFull(e, id[6:])
+		return id, err
+	}
+}
+
+// MustNewULID is NewULID that panics on error
+func MustNewULID(ms uint64, entropy io.Reader) ULID {
+	id, err := NewULID(ms, entropy)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// NewULID returns a ULID for the generator's current time. ULIDs of the
+// same millisecond increment the previous one's random bits.
+func (g *Generator) NewULID() (ULID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := ULIDTimestamp(g.now())
+	if ms <= g.lastULID.Time() && g.lastULID != (ULID{}) {
+		id := g.lastULID
+		if !incrementEntropy(id[6:], 1) {
+			return ULID{}, ErrMonotonicOverflow
+		}
+		g.lastULID = id
+		return id, nil
+	}
+	id, err := NewULID(ms, g.rand)
+	if err != nil {
+		return id, err
+	}
+	g.lastULID = id
+	return id, nil
+}
+
+// MakeULID returns a ULID for the current time from the default
+// generator, or panics if random bytes cannot be read
+func MakeULID() ULID {
+	id, err := generator().NewULID()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// ParseULID decodes a ULID string, in either case
+func ParseULID(s string) (ULID, error) {
+	var id ULID
+	return id, id.UnmarshalText([]byte(s))
+}
+
+// MustParseULID is ParseULID that panics on error
+func MustParseULID(s string) ULID {
+	id, err := ParseULID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// ValidateULID returns the error ParseULID would return for s
+func ValidateULID(s string) error {
+	_, err := ParseULID(s)
+	return err
+}
+
+// String returns the 26-character, upper-case form
+func (id ULID) String() string {
+	var out [EncodedSize]byte
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	for i := EncodedSize - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Time returns the time in Unix milliseconds
+func (id ULID) Time() uint64 {
+	return uint64(id[5]) | uint64(id[4])<<8 | uint64(id[3])<<16 |
+		uint64(id[2])<<24 | uint64(id[1])<<32 | uint64(id[0])<<40
+}
+
+// Timestamp returns the time as a time.Time
+func (id ULID) Timestamp() time.Time {
+	return time.UnixMilli(int64(id.Time())).UTC()
+}
+
+// SetTime sets the time in Unix milliseconds
+func (id *ULID) SetTime(ms uint64) error {
+	if ms > maxTime {
+		return ErrBigTime
+	}
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	return nil
+}
+
+// Entropy returns a copy of the 80 random bits
+func (id ULID) Entropy() []byte {
+	e := make([]byte, 10)
+	copy(e, id[6:])
+	return e
+}
+
+// Compare returns -1, 0 or 1 as id sorts before, with or after other
+func (id ULID) Compare(other ULID) int {
+	return bytes.Compare(id[:], other[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (id ULID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (id *ULID) UnmarshalText(data []byte) error {
+	if len(data) != EncodedSize {
+		return ErrDataSize
+	}
+	var hi, lo uint64
+	for i, c := range data {
+		v := crockfordValues[c]
+		if v == 0xff {
+			return ErrInvalidCharacters
+		}
+		// 26 characters carry 130 bits, so the first may only use 3
+		if i == 0 && v > 7 {
+			return ErrOverflow
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	binary.BigEndian.PutUint64(id[:8], hi)
+	binary.BigEndian.PutUint64(id[8:], lo)
+	return nil
+}
+
+// Value implements driver.Valuer, storing the ULID as a string
+func (id ULID) Value() (driver.Value, error) {
+	return id.String(), nil
+}
+
+// Scan implements sql.Scanner for strings and 16-byte slices
+func (id *ULID) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*id = ULID{}
+		return nil
+	case string:
+		return id.UnmarshalText([]byte(src))
+	case []byte:
+		if len(src) != 16 {
+			return id.UnmarshalText(src)
+		}
+		copy(id[:], src)
+		return nil
+	}
+	return fmt.Errorf("ulid: source value must be a string or byte slice, not %T", src)
+}
+
+// MonotonicEntropy reads random bits for NewULID, incrementing the
+// previous bits instead within the same millisecond so ULIDs stay in order
+type MonotonicEntropy struct {
+	io.Reader
+	inc  uint64
+	ms   uint64
+	last [10]byte
+	used bool
+}
+
+// Monotonic returns a MonotonicEntropy reading from entropy that
+// increments by a random amount from 1 to inc, or up to 2^32-1 if inc is
+// 0. It is not safe for concurrent use.
+func Monotonic(entropy io.Reader, inc uint64) *MonotonicEntropy {
+	if inc == 0 {
+		inc = 1<<32 - 1
+	}
+	return &MonotonicEntropy{Reader: entropy, inc: inc}
+}
+
+// MonotonicRead fills p with the random bits for a ULID of time ms
+func (m *MonotonicEntropy) MonotonicRead(ms uint64, p []byte) error {
+	if m.used && ms == m.ms {
+		step := uint64(1)
+		if m.inc > 1 {
+			var b [8]byte
+			if _, err := io.ReadFull(m.Reader, b[:]); err != nil {
+				return err
+			}
+			step = 1 + binary.BigEndian.Uint64(b[:])%m.inc
+		}
+		if !incrementEntropy(m.last[:], step) {
+			return ErrMonotonicOverflow
+		}
+		copy(p, m.last[:])
+		return nil
+	}
+	if _, err := io.ReadFull(m.Reader, m.last[:]); err != nil {
+		return err
+	}
+	m.ms, m.used = ms, true
+	copy(p, m.last[:])
+	return nil
+}
+
+// incrementEntropy adds step to the 80-bit big-endian number in e,
+// reporting false on overflow
+func incrementEntropy(e []byte, step uint64) bool {
+	lo := binary.BigEndian.Uint64(e[2:])
+	hi := binary.BigEndian.Uint16(e[:2])
+	sum := lo + step
+	if sum < lo {
+		if hi == 0xffff {
+			return false
+		}
+		hi++
+	}
+	binary.BigEndian.PutUint16(e[:2], hi)
+	binary.BigEndian.PutUint64(e[2:], sum)
+	return true
+}
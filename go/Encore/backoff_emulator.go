@@ -0,0 +1,545 @@
+package main
+
+// Developed by PowerShield, as an alternative to cenkalti/backoff and Go retry libraries
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Stop is returned by NextBackOff when no more retries should be made
+const Stop time.Duration = -1
+
+// BackOff computes the waits between retries
+type BackOff interface {
+	// NextBackOff returns the wait before the next retry, or Stop
+	NextBackOff() time.Duration
+	// Reset starts the backoff over
+	Reset()
+}
+
+// ZeroBackOff retries immediately, forever
+type ZeroBackOff struct{}
+
+func (b *ZeroBackOff) Reset() {}
+
+func (b *ZeroBackOff) NextBackOff() time.Duration { return 0 }
+
+// StopBackOff never retries
+type StopBackOff struct{}
+
+func (b *StopBackOff) Reset() {}
+
+func (b *StopBackOff) NextBackOff() time.Duration { return Stop }
+
+// ConstantBackOff waits the same interval before every retry, forever
+type ConstantBackOff struct {
+	Interval time.Duration
+}
+
+func (b *ConstantBackOff) Reset() {}
+
+func (b *ConstantBackOff) NextBackOff() time.Duration { return b.Interval }
+
+// NewConstantBackOff returns a backoff that always waits d
+func NewConstantBackOff(d time.Duration) *ConstantBackOff {
+	return &ConstantBackOff{Interval: d}
+}
+
+// Exponential backoff
+
+// Default values for ExponentialBackOff
+const (
+	DefaultInitialInterval     = 500 * time.Millisecond
+	DefaultRandomizationFactor = 0.5
+	DefaultMultiplier          = 1.5
+	DefaultMaxInterval         = 60 * time.Second
+	DefaultMaxElapsedTime      = 15 * time.Minute
+)
+
+// Clock tells the time to an ExponentialBackOff
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (t systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the Clock of the time package
+var SystemClock = systemClock{}
+
+// ExponentialBackOff multiplies its interval after every retry, up to
+// MaxInterval, and randomizes each wait within RandomizationFactor of
+// it. It stops once MaxElapsedTime has passed since Reset; zero means
+// never.
+//
+// With the defaults the waits are about 0.5s, 0.75s, 1.1s, 1.7s, 2.5s
+// and so on, each within 50% either way.
+type ExponentialBackOff struct {
+	InitialInterval     time.Duration
+	RandomizationFactor float64
+	Multiplier          float64
+	MaxInterval         time.Duration
+	MaxElapsedTime      time.Duration
+	// Stop is what NextBackOff returns when it gives up
+	Stop  time.Duration
+	Clock Clock
+
+	currentInterval time.Duration
+	startTime       time.Time
+}
+
+// ExponentialBackOffOpts configures an ExponentialBackOff
+type ExponentialBackOffOpts func(*ExponentialBackOff)
+
+// NewExponentialBackOff returns a reset ExponentialBackOff with the
+// default values, changed by opts
+func NewExponentialBackOff(opts ...ExponentialBackOffOpts) *ExponentialBackOff {
+	b := &ExponentialBackOff{
+		InitialInterval:     DefaultInitialInterval,
+		RandomizationFactor: DefaultRandomizationFactor,
+		Multiplier:          DefaultMultiplier,
+		MaxInterval:         DefaultMaxInterval,
+		MaxElapsedTime:      DefaultMaxElapsedTime,
+		Stop:                Stop,
+		Clock:               SystemClock,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.Reset()
+	return b
+}
+
+// WithInitialInterval sets the first interval
+func WithInitialInterval(d time.Duration) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.InitialInterval = d }
+}
+
+// WithRandomizationFactor sets how far waits are randomized, from 0 for
+// not at all to 1 for anywhere between zero and twice the interval
+func WithRandomizationFactor(f float64) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.RandomizationFactor = f }
+}
+
+// WithMultiplier sets what the interval is multiplied by after a retry
+func WithMultiplier(m float64) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.Multiplier = m }
+}
+
+// WithMaxInterval caps the interval
+func WithMaxInterval(d time.Duration) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.MaxInterval = d }
+}
+
+// WithMaxElapsedTime sets how long after Reset the backoff gives up
+func WithMaxElapsedTime(d time.Duration) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.MaxElapsedTime = d }
+}
+
+// WithRetryStopDuration sets what NextBackOff returns when it gives up
+func WithRetryStopDuration(d time.Duration) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.Stop = d }
+}
+
+// WithClockProvider sets the clock elapsed time is measured on
+func WithClockProvider(c Clock) ExponentialBackOffOpts {
+	return func(b *ExponentialBackOff) { b.Clock = c }
+}
+
+// Reset goes back to the initial interval and restarts the elapsed time
+func (b *ExponentialBackOff) Reset() {
+	b.currentInterval = b.InitialInterval
+	b.startTime = b.Clock.Now()
+}
+
+// NextBackOff returns a randomized wait around the current interval and
+// grows the interval, or returns Stop once MaxElapsedTime would be
+// exceeded
+func (b *ExponentialBackOff) NextBackOff() time.Duration {
+	elapsed := b.GetElapsedTime()
+	next := randomizedInterval(b.RandomizationFactor, rand.Float64(), b.currentInterval)
+	b.incrementCurrentInterval()
+	if b.MaxElapsedTime != 0 && elapsed+next > b.MaxElapsedTime {
+		return b.Stop
+	}
+	return next
+}
+
+// GetElapsedTime returns the time since Reset
+func (b *ExponentialBackOff) GetElapsedTime() time.Duration {
+	return b.Clock.Now().Sub(b.startTime)
+}
+
+// incrementCurrentInterval multiplies the interval, without overflowing
+// past MaxInterval
+func (b *ExponentialBackOff) incrementCurrentInterval() {
+	if float64(b.currentInterval) >= float64(b.MaxInterval)/b.Multiplier {
+		b.currentInterval = b.MaxInterval
+	} else {
+		b.currentInterval = time.Duration(float64(b.currentInterval) * b.Multiplier)
+	}
+}
+
+// randomizedInterval returns a value in
+// [interval - factor*interval, interval + factor*interval], picked by
+// random in [0, 1)
+func randomizedInterval(factor, random float64, interval time.Duration) time.Duration {
+	if factor == 0 {
+		return interval
+	}
+	delta := factor * float64(interval)
+	min := float64(interval) - delta
+	max := float64(interval) + delta
+	// The +1 makes max itself reachable
+	return time.Duration(min + random*(max-min+1))
+}
+
+// Wrappers
+
+// BackOffContext is a BackOff that stops when its context is done
+type BackOffContext interface {
+	BackOff
+	Context() context.Context
+}
+
+type backOffContext struct {
+	BackOff
+	ctx context.Context
+}
+
+// WithContext returns a copy of b that returns Stop once ctx is done.
+// Retry also stops waiting as soon as ctx is done.
+func WithContext(b BackOff, ctx context.Context) BackOffContext {
+	if ctx == nil {
+		panic("nil context")
+	}
+	if b, ok := b.(*backOffContext); ok {
+		return &backOffContext{BackOff: b.BackOff, ctx: ctx}
+	}
+	return &backOffContext{BackOff: b, ctx: ctx}
+}
+
+func (b *backOffContext) Context() context.Context { return b.ctx }
+
+func (b *backOffContext) NextBackOff() time.Duration {
+	select {
+	case <-b.ctx.Done():
+		return Stop
+	default:
+		return b.BackOff.NextBackOff()
+	}
+}
+
+type backOffTries struct {
+	delegate BackOff
+	maxTries uint64
+	numTries uint64
+}
+
+// WithMaxRetries returns a copy of b that returns Stop after max
+// retries, so zero means no retries at all.
+func WithMaxRetries(b BackOff, max uint64) BackOff {
+	return &backOffTries{delegate: b, maxTries: max}
+}
+
+func (b *backOffTries) NextBackOff() time.Duration {
+	if b.numTries >= b.maxTries {
+		return Stop
+	}
+	b.numTries++
+	return b.delegate.NextBackOff()
+}
+
+func (b *backOffTries) Reset() {
+	b.numTries = 0
+	b.delegate.Reset()
+}
+
+// contextOf finds the context of b, looking through WithMaxRetries
+func contextOf(b BackOff) context.Context {
+	if cb, ok := b.(*backOffContext); ok {
+		return cb.ctx
+	}
+	if tb, ok := b.(*backOffTries); ok {
+		return contextOf(tb.delegate)
+	}
+	return context.Background()
+}
+
+// Permanent errors
+
+// PermanentError stops a retry loop; Retry returns the error it wraps
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+func (e *PermanentError) Is(target error) bool {
+	_, ok := target.(*PermanentError)
+	return ok
+}
+
+// Permanent wraps err so that retrying stops at once. It returns nil
+// for a nil err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Retrying
+
+// Operation is retried until it returns nil or a permanent error
+type Operation func() error
+
+// OperationWithData is an Operation that also returns a result
+type OperationWithData[T any] func() (T, error)
+
+// Notify is called with the error of each failed attempt that will be
+// retried, and the wait before the retry
+type Notify func(error, time.Duration)
+
+// Timer waits between retries; tests can replace the default one
+type Timer interface {
+	Start(duration time.Duration)
+	Stop()
+	C() <-chan time.Time
+}
+
+type defaultTimer struct {
+	timer *time.Timer
+}
+
+func (t *defaultTimer) C() <-chan time.Time { return t.timer.C }
+
+func (t *defaultTimer) Start(duration time.Duration) {
+	if t.timer == nil {
+		t.timer = time.NewTimer(duration)
+	} else {
+		t.timer.Reset(duration)
+	}
+}
+
+func (t *defaultTimer) Stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+// Hooks observe the attempts of a retry loop. Any of them may be nil.
+// They are an emulator extension: backoff only has Notify.
+type Hooks struct {
+	// OnAttempt is called before each attempt, numbered from 1
+	OnAttempt func(attempt int)
+	// OnRetry is called after a failed attempt that will be retried
+	OnRetry func(attempt int, err error, next time.Duration)
+	// OnSuccess is called after the attempt that succeeded
+	OnSuccess func(attempt int, elapsed time.Duration)
+	// OnGiveUp is called with the error the loop returns
+	OnGiveUp func(attempt int, err error, elapsed time.Duration)
+}
+
+// Retry runs o until it succeeds, returns a permanent error, or b says
+// to stop, waiting as b says in between. It returns the last error.
+// b is reset first.
+func Retry(o Operation, b BackOff) error {
+	return RetryNotify(o, b, nil)
+}
+
+// RetryWithData is Retry for an operation returning a result
+func RetryWithData[T any](o OperationWithData[T], b BackOff) (T, error) {
+	return RetryNotifyWithData(o, b, nil)
+}
+
+// RetryNotify is Retry, calling notify before each wait
+func RetryNotify(o Operation, b BackOff, notify Notify) error {
+	return RetryNotifyWithTimer(o, b, notify, nil)
+}
+
+// RetryNotifyWithData is RetryNotify for an operation returning a result
+func RetryNotifyWithData[T any](o OperationWithData[T], b BackOff, notify Notify) (T, error) {
+	return doRetry(o, b, notifyHooks(notify), nil)
+}
+
+// RetryNotifyWithTimer is RetryNotify, waiting on t; a nil t is a real
+// timer
+func RetryNotifyWithTimer(o Operation, b BackOff, notify Notify, t Timer) error {
+	_, err := doRetry(withoutData(o), b, notifyHooks(notify), t)
+	return err
+}
+
+// RetryNotifyWithTimerAndData is RetryNotifyWithTimer for an operation
+// returning a result
+func RetryNotifyWithTimerAndData[T any](o OperationWithData[T], b BackOff, notify Notify, t Timer) (T, error) {
+	return doRetry(o, b, notifyHooks(notify), t)
+}
+
+// RetryWithHooks is Retry, reporting each attempt to hooks
+func RetryWithHooks(o Operation, b BackOff, hooks Hooks) error {
+	_, err := doRetry(withoutData(o), b, hooks, nil)
+	return err
+}
+
+// RetryWithHooksAndData is RetryWithHooks for an operation returning a
+// result
+func RetryWithHooksAndData[T any](o OperationWithData[T], b BackOff, hooks Hooks) (T, error) {
+	return doRetry(o, b, hooks, nil)
+}
+
+func withoutData(o Operation) OperationWithData[struct{}] {
+	return func() (struct{}, error) {
+		return struct{}{}, o()
+	}
+}
+
+func notifyHooks(notify Notify) Hooks {
+	if notify == nil {
+		return Hooks{}
+	}
+	return Hooks{OnRetry: func(attempt int, err error, next time.Duration) {
+		notify(err, next)
+	}}
+}
+
+func doRetry[T any](o OperationWithData[T], b BackOff, hooks Hooks, t Timer) (T, error) {
+	if t == nil {
+		t = &defaultTimer{}
+	}
+	defer t.Stop()
+	ctx := contextOf(b)
+	start := time.Now()
+	b.Reset()
+
+	for attempt := 1; ; attempt++ {
+		if hooks.OnAttempt != nil {
+			hooks.OnAttempt(attempt)
+		}
+		res, err := o()
+		if err == nil {
+			if hooks.OnSuccess != nil {
+				hooks.OnSuccess(attempt, time.Since(start))
+			}
+			return res, nil
+		}
+
+		giveUp := func(err error) (T, error) {
+			if hooks.OnGiveUp != nil {
+				hooks.OnGiveUp(attempt, err, time.Since(start))
+			}
+			return res, err
+		}
+		var permanent *PermanentError
+		if errors.As(err, &permanent) {
+			return giveUp(permanent.Err)
+		}
+		next := b.NextBackOff()
+		if next == Stop {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return giveUp(ctxErr)
+			}
+			return giveUp(err)
+		}
+		if hooks.OnRetry != nil {
+			hooks.OnRetry(attempt, err, next)
+		}
+
+		t.Start(next)
+		select {
+		case <-ctx.Done():
+			return giveUp(ctx.Err())
+		case <-t.C():
+		}
+	}
+}
+
+// Tickers
+
+// Ticker sends the time on C at the intervals of a BackOff, once right
+// away and then after each wait. C is closed when the backoff stops or
+// the ticker is stopped.
+type Ticker struct {
+	C        <-chan time.Time
+	c        chan time.Time
+	b        BackOff
+	ctx      context.Context
+	timer    Timer
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTicker returns a running Ticker following b, which is reset first
+func NewTicker(b BackOff) *Ticker {
+	return NewTickerWithTimer(b, nil)
+}
+
+// NewTickerWithTimer is NewTicker, waiting on timer; a nil timer is a
+// real timer
+func NewTickerWithTimer(b BackOff, timer Timer) *Ticker {
+	if timer == nil {
+		timer = &defaultTimer{}
+	}
+	c := make(chan time.Time)
+	t := &Ticker{
+		C:     c,
+		c:     c,
+		b:     b,
+		ctx:   contextOf(b),
+		timer: timer,
+		stop:  make(chan struct{}),
+	}
+	t.b.Reset()
+	go t.run()
+	return t
+}
+
+// Stop stops the ticker; no more ticks are sent after it returns
+func (t *Ticker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+func (t *Ticker) run() {
+	c := t.c
+	defer close(c)
+	defer t.timer.Stop()
+
+	// The first tick is right away
+	afterC := t.send(time.Now())
+	for {
+		if afterC == nil {
+			return
+		}
+		select {
+		case tick := <-afterC:
+			afterC = t.send(tick)
+		case <-t.stop:
+			return
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// send delivers a tick and returns the channel of the next one, or nil
+// when the backoff stops
+func (t *Ticker) send(tick time.Time) <-chan time.Time {
+	select {
+	case t.c <- tick:
+	case <-t.stop:
+		return nil
+	}
+	next := t.b.NextBackOff()
+	if next == Stop {
+		t.Stop()
+		return nil
+	}
+	t.timer.Start(next)
+	return t.timer.C()
+}
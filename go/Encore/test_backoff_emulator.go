@@ -0,0 +1,366 @@
+package main
+
+// Developed by PowerShield, as an alternative to cenkalti/backoff and Go retry libraries
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// testClock is a Clock moved by hand
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+// testTimer is a Timer that fires at once, recording the waits
+type testTimer struct {
+	mu    sync.Mutex
+	waits []time.Duration
+	c     chan time.Time
+}
+
+func newTestTimer() *testTimer {
+	return &testTimer{c: make(chan time.Time, 1)}
+}
+
+func (t *testTimer) Start(d time.Duration) {
+	t.mu.Lock()
+	t.waits = append(t.waits, d)
+	t.mu.Unlock()
+	t.c <- time.Now()
+}
+
+func (t *testTimer) Stop() {}
+
+func (t *testTimer) C() <-chan time.Time { return t.c }
+
+func (t *testTimer) recorded() []time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]time.Duration(nil), t.waits...)
+}
+
+// failing returns an operation failing n times before succeeding
+func failing(n int, calls *int) Operation {
+	return func() error {
+		*calls++
+		if *calls <= n {
+			return fmt.Errorf("attempt %d failed", *calls)
+		}
+		return nil
+	}
+}
+
+func testExponentialIntervals() bool {
+	b := NewExponentialBackOff(
+		WithInitialInterval(100*time.Millisecond),
+		WithRandomizationFactor(0),
+		WithMultiplier(2),
+		WithMaxInterval(time.Second),
+		WithMaxElapsedTime(0),
+	)
+	expected := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for _, e := range expected {
+		if b.NextBackOff() != e*time.Millisecond {
+			return false
+		}
+	}
+	b.Reset()
+	return b.NextBackOff() == 100*time.Millisecond
+}
+
+func testJitter() bool {
+	b := NewExponentialBackOff(WithInitialInterval(time.Second), WithMultiplier(1))
+	min, max := 500*time.Millisecond, 1500*time.Millisecond
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 100; i++ {
+		next := b.NextBackOff()
+		if next < min || next > max {
+			return false
+		}
+		seen[next] = true
+	}
+	if len(seen) < 10 {
+		return false
+	}
+	// The bounds of the randomization
+	return randomizedInterval(0.5, 0, time.Second) == min &&
+		randomizedInterval(0.5, 0.9999999999, time.Second) == max &&
+		randomizedInterval(0, 0.3, time.Second) == time.Second
+}
+
+func testMaxElapsedTime() bool {
+	clock := &testClock{now: time.Now()}
+	b := NewExponentialBackOff(
+		WithInitialInterval(time.Second),
+		WithRandomizationFactor(0),
+		WithMaxElapsedTime(10*time.Second),
+		WithClockProvider(clock),
+	)
+	if b.NextBackOff() != time.Second {
+		return false
+	}
+	clock.now = clock.now.Add(8 * time.Second)
+	if b.GetElapsedTime() != 8*time.Second {
+		return false
+	}
+	// 8s elapsed plus 1.5s is still within 10s, plus 2.25s is not
+	if b.NextBackOff() != 1500*time.Millisecond {
+		return false
+	}
+	if b.NextBackOff() != Stop {
+		return false
+	}
+	custom := NewExponentialBackOff(WithMaxElapsedTime(time.Nanosecond), WithRetryStopDuration(-2), WithClockProvider(clock))
+	clock.now = clock.now.Add(time.Second)
+	return custom.NextBackOff() == -2
+}
+
+func testSimpleBackOffs() bool {
+	constant := NewConstantBackOff(3 * time.Second)
+	if constant.NextBackOff() != 3*time.Second || constant.NextBackOff() != 3*time.Second {
+		return false
+	}
+	var zero ZeroBackOff
+	var stop StopBackOff
+	return zero.NextBackOff() == 0 && stop.NextBackOff() == Stop
+}
+
+func testRetrySucceeds() bool {
+	calls := 0
+	timer := newTestTimer()
+	err := RetryNotifyWithTimer(failing(3, &calls), NewConstantBackOff(time.Second), nil, timer)
+	if err != nil || calls != 4 {
+		return false
+	}
+	waits := timer.recorded()
+	return len(waits) == 3 && waits[0] == time.Second
+}
+
+func testRetryGivesUp() bool {
+	calls := 0
+	timer := newTestTimer()
+	err := RetryNotifyWithTimer(failing(10, &calls), WithMaxRetries(&ZeroBackOff{}, 2), nil, timer)
+	if err == nil || err.Error() != "attempt 3 failed" || calls != 3 {
+		return false
+	}
+	// No retries at all
+	calls = 0
+	err = Retry(failing(10, &calls), WithMaxRetries(&ZeroBackOff{}, 0))
+	if err == nil || calls != 1 {
+		return false
+	}
+	calls = 0
+	err = Retry(failing(10, &calls), &StopBackOff{})
+	return err != nil && calls == 1
+}
+
+func testMaxRetriesReset() bool {
+	b := WithMaxRetries(&ZeroBackOff{}, 2)
+	if b.NextBackOff() != 0 || b.NextBackOff() != 0 || b.NextBackOff() != Stop {
+		return false
+	}
+	// Each Retry resets the count
+	for i := 0; i < 2; i++ {
+		calls := 0
+		if Retry(failing(2, &calls), b) != nil || calls != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+func testPermanentError() bool {
+	calls := 0
+	cause := errors.New("invalid credentials")
+	err := Retry(func() error {
+		calls++
+		return fmt.Errorf("login: %w", Permanent(cause))
+	}, &ZeroBackOff{})
+	if err != cause || calls != 1 {
+		return false
+	}
+	if Permanent(nil) != nil {
+		return false
+	}
+	var permanent *PermanentError
+	wrapped := Permanent(cause)
+	return errors.As(wrapped, &permanent) && errors.Is(wrapped, cause) &&
+		errors.Is(wrapped, &PermanentError{}) && wrapped.Error() == "invalid credentials"
+}
+
+func testRetryNotify() bool {
+	calls := 0
+	var notified []string
+	timer := newTestTimer()
+	err := RetryNotifyWithTimer(failing(2, &calls), NewConstantBackOff(time.Second), func(err error, wait time.Duration) {
+		notified = append(notified, fmt.Sprintf("%v after %v", err, wait))
+	}, timer)
+	if err != nil || len(notified) != 2 {
+		return false
+	}
+	return notified[0] == "attempt 1 failed after 1s" && notified[1] == "attempt 2 failed after 1s"
+}
+
+func testRetryWithData() bool {
+	calls := 0
+	value, err := RetryWithData(func() (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errors.New("not yet")
+		}
+		return "ready", nil
+	}, &ZeroBackOff{})
+	if err != nil || value != "ready" || calls != 3 {
+		return false
+	}
+
+	// The last result is returned with the error
+	n, err := RetryNotifyWithTimerAndData(func() (int, error) {
+		return 42, errors.New("always")
+	}, WithMaxRetries(&ZeroBackOff{}, 1), nil, newTestTimer())
+	return n == 42 && err != nil && err.Error() == "always"
+}
+
+func testContextCancel() bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Retry(func() error {
+		calls++
+		if calls == 2 {
+			cancel()
+		}
+		return errors.New("unavailable")
+	}, WithContext(&ZeroBackOff{}, ctx))
+	if err != context.Canceled || calls != 2 {
+		return false
+	}
+
+	// A done context interrupts the wait, even through WithMaxRetries
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = Retry(func() error {
+		return errors.New("unavailable")
+	}, WithMaxRetries(WithContext(NewConstantBackOff(time.Hour), ctx), 5))
+	return err == context.DeadlineExceeded && time.Since(start) < time.Second
+}
+
+func testContextBackOff() bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := WithContext(NewConstantBackOff(time.Second), ctx)
+	if b.Context() != ctx || b.NextBackOff() != time.Second {
+		return false
+	}
+	cancel()
+	if b.NextBackOff() != Stop {
+		return false
+	}
+	// Wrapping again replaces the context
+	b = WithContext(b, context.Background())
+	return b.NextBackOff() == time.Second
+}
+
+func testHooks() bool {
+	calls := 0
+	var events []string
+	hooks := Hooks{
+		OnAttempt: func(attempt int) {
+			events = append(events, fmt.Sprintf("attempt %d", attempt))
+		},
+		OnRetry: func(attempt int, err error, next time.Duration) {
+			events = append(events, fmt.Sprintf("retry %d in %v", attempt, next))
+		},
+		OnSuccess: func(attempt int, elapsed time.Duration) {
+			events = append(events, fmt.Sprintf("success %d", attempt))
+		},
+		OnGiveUp: func(attempt int, err error, elapsed time.Duration) {
+			events = append(events, fmt.Sprintf("give up %d: %v", attempt, err))
+		},
+	}
+	if RetryWithHooks(failing(1, &calls), &ZeroBackOff{}, hooks) != nil {
+		return false
+	}
+	expected := "attempt 1,retry 1 in 0s,attempt 2,success 2"
+	if strings.Join(events, ",") != expected {
+		return false
+	}
+
+	events = nil
+	calls = 0
+	if RetryWithHooks(failing(5, &calls), WithMaxRetries(&ZeroBackOff{}, 1), hooks) == nil {
+		return false
+	}
+	expected = "attempt 1,retry 1 in 0s,attempt 2,give up 2: attempt 2 failed"
+	if strings.Join(events, ",") != expected {
+		return false
+	}
+
+	_, err := RetryWithHooksAndData(func() (int, error) { return 0, nil }, &StopBackOff{}, Hooks{})
+	return err == nil
+}
+
+func testTicker() bool {
+	timer := newTestTimer()
+	ticker := NewTickerWithTimer(WithMaxRetries(NewConstantBackOff(time.Minute), 3), timer)
+	ticks := 0
+	for range ticker.C {
+		ticks++
+	}
+	// One tick right away, then one per retry, then C is closed
+	if ticks != 4 || len(timer.recorded()) != 3 {
+		return false
+	}
+
+	ticker = NewTicker(NewConstantBackOff(time.Millisecond))
+	<-ticker.C
+	<-ticker.C
+	ticker.Stop()
+	select {
+	case _, ok := <-ticker.C:
+		for ok {
+			_, ok = <-ticker.C
+		}
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func main() {
+	fmt.Println("Running backoff Emulator Tests...")
+	fmt.Println("=================================")
+
+	runTest("Exponential Intervals", testExponentialIntervals)
+	runTest("Jitter", testJitter)
+	runTest("Max Elapsed Time", testMaxElapsedTime)
+	runTest("Simple BackOffs", testSimpleBackOffs)
+	runTest("Retry Succeeds", testRetrySucceeds)
+	runTest("Retry Gives Up", testRetryGivesUp)
+	runTest("Max Retries Reset", testMaxRetriesReset)
+	runTest("Permanent Error", testPermanentError)
+	runTest("Retry Notify", testRetryNotify)
+	runTest("Retry With Data", testRetryWithData)
+	runTest("Context Cancel", testContextCancel)
+	runTest("Context BackOff", testContextBackOff)
+	runTest("Hooks", testHooks)
+	runTest("Ticker", testTicker)
+
+	fmt.Println("=================================")
+	fmt.Println("All tests completed!")
+}
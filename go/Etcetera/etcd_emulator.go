@@ -0,0 +1,2003 @@
+package main
+
+// Developed by PowerShield, as an alternative to etcd and its clientv3 Go client
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors, as in the rpctypes package and clientv3
+var (
+	ErrEmptyKey             = errors.New("etcdserver: key is not provided")
+	ErrKeyNotFound          = errors.New("etcdserver: key not found")
+	ErrDuplicateKey         = errors.New("etcdserver: duplicate key given in txn request")
+	ErrCompacted            = errors.New("etcdserver: mvcc: required revision has been compacted")
+	ErrFutureRev            = errors.New("etcdserver: mvcc: required revision is a future revision")
+	ErrLeaseNotFound        = errors.New("etcdserver: requested lease not found")
+	ErrStopped              = errors.New("etcdserver: server stopped")
+	ErrNoAvailableEndpoints = errors.New("etcdclient: no available endpoints")
+)
+
+// Keys and events, as in the mvccpb package
+
+// KeyValue is a key and its value at a revision
+type KeyValue struct {
+	Key []byte
+	// CreateRevision is the revision of the key's last creation
+	CreateRevision int64
+	// ModRevision is the revision of the key's last modification
+	ModRevision int64
+	// Version counts the modifications since the key was created
+	Version int64
+	Value   []byte
+	// Lease is the ID of the lease attached to the key, or 0
+	Lease int64
+}
+
+func (kv *KeyValue) clone() *KeyValue {
+	if kv == nil {
+		return nil
+	}
+	c := *kv
+	c.Key = append([]byte(nil), kv.Key...)
+	c.Value = append([]byte(nil), kv.Value...)
+	return &c
+}
+
+// EventType is the kind of change an Event reports
+type EventType int32
+
+// Event types
+const (
+	PUT    EventType = 0
+	DELETE EventType = 1
+)
+
+func (t EventType) String() string {
+	if t == DELETE {
+		return "DELETE"
+	}
+	return "PUT"
+}
+
+// Event is a change to a key. For a DELETE, Kv holds the key and the
+// revision of the deletion.
+type Event struct {
+	Type EventType
+	Kv   *KeyValue
+	// PrevKv is the key before the change, if the watch asked for it
+	PrevKv *KeyValue
+}
+
+// IsCreate reports whether the event created the key
+func (e *Event) IsCreate() bool {
+	return e.Type == PUT && e.Kv.CreateRevision == e.Kv.ModRevision
+}
+
+// IsModify reports whether the event updated an existing key
+func (e *Event) IsModify() bool {
+	return e.Type == PUT && e.Kv.CreateRevision != e.Kv.ModRevision
+}
+
+// revision returns the revision the event happened at
+func (e *Event) revision() int64 {
+	return e.Kv.ModRevision
+}
+
+// Responses
+
+// ResponseHeader identifies the member that answered and the store
+// revision at the time
+type ResponseHeader struct {
+	ClusterId uint64
+	MemberId  uint64
+	Revision  int64
+	RaftTerm  uint64
+}
+
+// GetResponse is the result of a Get
+type GetResponse struct {
+	Header *ResponseHeader
+	Kvs    []*KeyValue
+	// More reports whether the limit cut off some keys
+	More bool
+	// Count is the number of keys in the range, regardless of the limit
+	Count int64
+}
+
+// PutResponse is the result of a Put
+type PutResponse struct {
+	Header *ResponseHeader
+	PrevKv *KeyValue
+}
+
+// DeleteResponse is the result of a Delete
+type DeleteResponse struct {
+	Header  *ResponseHeader
+	Deleted int64
+	PrevKvs []*KeyValue
+}
+
+// TxnResponse is the result of a transaction
+type TxnResponse struct {
+	Header *ResponseHeader
+	// Succeeded reports whether the comparisons held and Then ran
+	Succeeded bool
+	// Responses holds the results of the operations that ran, in order
+	Responses []*ResponseOp
+}
+
+// ResponseOp is the result of one operation in a transaction
+type ResponseOp struct {
+	rangeResp *GetResponse
+	putResp   *PutResponse
+	delResp   *DeleteResponse
+	txnResp   *TxnResponse
+}
+
+// GetResponseRange returns the result of a get, or nil
+func (r *ResponseOp) GetResponseRange() *GetResponse { return r.rangeResp }
+
+// GetResponsePut returns the result of a put, or nil
+func (r *ResponseOp) GetResponsePut() *PutResponse { return r.putResp }
+
+// GetResponseDeleteRange returns the result of a delete, or nil
+func (r *ResponseOp) GetResponseDeleteRange() *DeleteResponse { return r.delResp }
+
+// GetResponseTxn returns the result of a nested transaction, or nil
+func (r *ResponseOp) GetResponseTxn() *TxnResponse { return r.txnResp }
+
+// CompactResponse is the result of a Compact
+type CompactResponse struct {
+	Header *ResponseHeader
+}
+
+// Operations
+
+// SortTarget is the field a Get sorts by
+type SortTarget int
+
+// Sort targets
+const (
+	SortByKey SortTarget = iota
+	SortByVersion
+	SortByCreateRevision
+	SortByModRevision
+	SortByValue
+)
+
+// SortOrder is the direction a Get sorts in
+type SortOrder int
+
+// Sort orders
+const (
+	SortNone SortOrder = iota
+	SortAscend
+	SortDescend
+)
+
+type opType int
+
+const (
+	tRange opType = iota + 1
+	tPut
+	tDeleteRange
+	tTxn
+)
+
+// Op is a Get, Put, Delete or transaction, to run on its own with Do or
+// inside a Txn
+type Op struct {
+	t   opType
+	key []byte
+	end []byte
+
+	// get
+	rev        int64
+	limit      int64
+	sortTarget SortTarget
+	sortOrder  SortOrder
+	keysOnly   bool
+	countOnly  bool
+
+	// put
+	val         []byte
+	leaseID     LeaseID
+	ignoreValue bool
+	ignoreLease bool
+
+	// put, delete and watch
+	prevKV bool
+
+	// watch
+	filterPut     bool
+	filterDelete  bool
+	createdNotify bool
+
+	// txn
+	cmps    []Cmp
+	thenOps []Op
+	elseOps []Op
+}
+
+// OpOption configures an operation
+type OpOption func(*Op)
+
+func (op *Op) applyOpts(opts []OpOption) {
+	for _, opt := range opts {
+		opt(op)
+	}
+}
+
+// OpGet returns a Get operation on key
+func OpGet(key string, opts ...OpOption) Op {
+	op := Op{t: tRange, key: []byte(key)}
+	op.applyOpts(opts)
+	return op
+}
+
+// OpPut returns a Put operation of val under key
+func OpPut(key, val string, opts ...OpOption) Op {
+	op := Op{t: tPut, key: []byte(key), val: []byte(val)}
+	op.applyOpts(opts)
+	return op
+}
+
+// OpDelete returns a Delete operation on key
+func OpDelete(key string, opts ...OpOption) Op {
+	op := Op{t: tDeleteRange, key: []byte(key)}
+	op.applyOpts(opts)
+	return op
+}
+
+// OpTxn returns a nested transaction
+func OpTxn(cmps []Cmp, thenOps []Op, elseOps []Op) Op {
+	return Op{t: tTxn, cmps: cmps, thenOps: thenOps, elseOps: elseOps}
+}
+
+// IsGet reports whether op is a Get
+func (op Op) IsGet() bool { return op.t == tRange }
+
+// IsPut reports whether op is a Put
+func (op Op) IsPut() bool { return op.t == tPut }
+
+// IsDelete reports whether op is a Delete
+func (op Op) IsDelete() bool { return op.t == tDeleteRange }
+
+// IsTxn reports whether op is a transaction
+func (op Op) IsTxn() bool { return op.t == tTxn }
+
+// KeyBytes returns the key op works on
+func (op Op) KeyBytes() []byte { return op.key }
+
+// RangeBytes returns the end of op's range, or nil for a single key
+func (op Op) RangeBytes() []byte { return op.end }
+
+// ValueBytes returns the value a Put writes
+func (op Op) ValueBytes() []byte { return op.val }
+
+// GetPrefixRangeEnd returns the end of the range of keys starting with
+// prefix
+func GetPrefixRangeEnd(prefix string) string {
+	return string(prefixEnd([]byte(prefix)))
+}
+
+// prefixEnd returns the first key after every key starting with prefix,
+// or "\x00" (every key from prefix on) if there is none
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+// WithPrefix makes the operation cover every key starting with its key.
+// An empty key then means every key.
+func WithPrefix() OpOption {
+	return func(op *Op) {
+		if len(op.key) == 0 {
+			op.key, op.end = []byte{0}, []byte{0}
+			return
+		}
+		op.end = prefixEnd(op.key)
+	}
+}
+
+// WithRange makes the operation cover the keys from its key up to, but
+// not including, endKey
+func WithRange(endKey string) OpOption {
+	return func(op *Op) { op.end = []byte(endKey) }
+}
+
+// WithFromKey makes the operation cover every key from its key on
+func WithFromKey() OpOption {
+	return func(op *Op) {
+		if len(op.key) == 0 {
+			op.key = []byte{0}
+		}
+		op.end = []byte{0}
+	}
+}
+
+// WithRev reads at a past revision, or starts a watch at one
+func WithRev(rev int64) OpOption {
+	return func(op *Op) { op.rev = rev }
+}
+
+// WithLimit caps the number of keys a Get returns
+func WithLimit(n int64) OpOption {
+	return func(op *Op) { op.limit = n }
+}
+
+// WithSort sorts the keys a Get returns
+func WithSort(target SortTarget, order SortOrder) OpOption {
+	return func(op *Op) {
+		op.sortTarget, op.sortOrder = target, order
+	}
+}
+
+// WithKeysOnly makes a Get return keys without values
+func WithKeysOnly() OpOption {
+	return func(op *Op) { op.keysOnly = true }
+}
+
+// WithCountOnly makes a Get return only the number of keys
+func WithCountOnly() OpOption {
+	return func(op *Op) { op.countOnly = true }
+}
+
+// WithSerializable allows a Get to be served by any member. The emulator
+// has one member, so it has no effect.
+func WithSerializable() OpOption {
+	return func(op *Op) {}
+}
+
+// WithPrevKV returns the keys as they were before a Put or Delete, or
+// adds them to watch events
+func WithPrevKV() OpOption {
+	return func(op *Op) { op.prevKV = true }
+}
+
+// WithLease attaches a lease to the key a Put writes
+func WithLease(id LeaseID) OpOption {
+	return func(op *Op) { op.leaseID = id }
+}
+
+// WithIgnoreValue makes a Put keep the key's current value, e.g. to
+// change only its lease
+func WithIgnoreValue() OpOption {
+	return func(op *Op) { op.ignoreValue = true }
+}
+
+// WithIgnoreLease makes a Put keep the key's current lease
+func WithIgnoreLease() OpOption {
+	return func(op *Op) { op.ignoreLease = true }
+}
+
+// WithFilterPut drops PUT events from a watch
+func WithFilterPut() OpOption {
+	return func(op *Op) { op.filterPut = true }
+}
+
+// WithFilterDelete drops DELETE events from a watch
+func WithFilterDelete() OpOption {
+	return func(op *Op) { op.filterDelete = true }
+}
+
+// WithCreatedNotify makes a watch send an empty response with Created
+// set once it is registered
+func WithCreatedNotify() OpOption {
+	return func(op *Op) { op.createdNotify = true }
+}
+
+// Comparisons
+
+// CompareTarget is the field of a key a Cmp looks at
+type CompareTarget int
+
+// Compare targets
+const (
+	CompareVersion CompareTarget = iota
+	CompareCreated
+	CompareModified
+	CompareValue
+	CompareLease
+)
+
+// Cmp is a condition on a key, or on every key in a range, for Txn.If
+type Cmp struct {
+	target CompareTarget
+	result string
+	key    []byte
+	end    []byte
+	value  []byte
+	num    int64
+}
+
+// Value compares the key's value
+func Value(key string) Cmp { return Cmp{key: []byte(key), target: CompareValue} }
+
+// Version compares the key's version; 0 means the key does not exist
+func Version(key string) Cmp { return Cmp{key: []byte(key), target: CompareVersion} }
+
+// CreateRevision compares the revision the key was created at
+func CreateRevision(key string) Cmp { return Cmp{key: []byte(key), target: CompareCreated} }
+
+// ModRevision compares the revision the key was last modified at
+func ModRevision(key string) Cmp { return Cmp{key: []byte(key), target: CompareModified} }
+
+// LeaseValue compares the ID of the key's lease
+func LeaseValue(key string) Cmp { return Cmp{key: []byte(key), target: CompareLease} }
+
+// Compare completes cmp with an operator ("=", "!=", "<" or ">") and the
+// value to compare against: a string for Value, an integer otherwise
+func Compare(cmp Cmp, result string, v interface{}) Cmp {
+	switch result {
+	case "=", "!=", "<", ">":
+	default:
+		panic("Unknown result op")
+	}
+	cmp.result = result
+	switch cmp.target {
+	case CompareValue:
+		s, ok := v.(string)
+		if !ok {
+			panic("bad compare value")
+		}
+		cmp.value = []byte(s)
+	case CompareLease:
+		cmp.num = int64(mustLease(v))
+	default:
+		cmp.num = mustInt64(v)
+	}
+	return cmp
+}
+
+func mustInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	}
+	panic("bad value")
+}
+
+func mustLease(v interface{}) LeaseID {
+	if id, ok := v.(LeaseID); ok {
+		return id
+	}
+	return LeaseID(mustInt64(v))
+}
+
+// WithPrefix makes the comparison hold for every key starting with its key
+func (cmp Cmp) WithPrefix() Cmp {
+	cmp.end = prefixEnd(cmp.key)
+	return cmp
+}
+
+// WithRange makes the comparison hold for every key up to end
+func (cmp Cmp) WithRange(end string) Cmp {
+	cmp.end = []byte(end)
+	return cmp
+}
+
+// holds reports whether the comparison holds for kv. A missing key is
+// compared as zeroes, except that value comparisons fail.
+func (cmp Cmp) holds(kv *KeyValue) bool {
+	var c int
+	switch cmp.target {
+	case CompareValue:
+		if kv == nil {
+			return false
+		}
+		c = bytes.Compare(kv.Value, cmp.value)
+	default:
+		var n int64
+		if kv != nil {
+			switch cmp.target {
+			case CompareVersion:
+				n = kv.Version
+			case CompareCreated:
+				n = kv.CreateRevision
+			case CompareModified:
+				n = kv.ModRevision
+			case CompareLease:
+				n = kv.Lease
+			}
+		}
+		switch {
+		case n < cmp.num:
+			c = -1
+		case n > cmp.num:
+			c = 1
+		}
+	}
+	switch cmp.result {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	default:
+		return c > 0
+	}
+}
+
+// Txn is a transaction: if every comparison holds, the Then operations
+// run, otherwise the Else operations, all at one revision
+type Txn interface {
+	If(cs ...Cmp) Txn
+	Then(ops ...Op) Txn
+	Else(ops ...Op) Txn
+	Commit() (*TxnResponse, error)
+}
+
+type txn struct {
+	client  *Client
+	ctx     context.Context
+	cmps    []Cmp
+	thenOps []Op
+	elseOps []Op
+	cif     bool
+	cthen   bool
+	celse   bool
+}
+
+// If adds comparisons. It panics if called twice or after Then or Else.
+func (t *txn) If(cs ...Cmp) Txn {
+	if t.cif {
+		panic("cannot call If twice!")
+	}
+	if t.cthen {
+		panic("cannot call If after Then!")
+	}
+	if t.celse {
+		panic("cannot call If after Else!")
+	}
+	t.cif = true
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+// Then adds the operations to run when the comparisons hold
+func (t *txn) Then(ops ...Op) Txn {
+	if t.cthen {
+		panic("cannot call Then twice!")
+	}
+	if t.celse {
+		panic("cannot call Then after Else!")
+	}
+	t.cthen = true
+	t.thenOps = append(t.thenOps, ops...)
+	return t
+}
+
+// Else adds the operations to run when a comparison fails
+func (t *txn) Else(ops ...Op) Txn {
+	if t.celse {
+		panic("cannot call Else twice!")
+	}
+	t.celse = true
+	t.elseOps = append(t.elseOps, ops...)
+	return t
+}
+
+// Commit runs the transaction
+func (t *txn) Commit() (*TxnResponse, error) {
+	if err := t.client.check(t.ctx); err != nil {
+		return nil, err
+	}
+	resp, err := t.client.server.do(OpTxn(t.cmps, t.thenOps, t.elseOps))
+	if err != nil {
+		return nil, err
+	}
+	return resp.txnResp, nil
+}
+
+// Leases
+
+// LeaseID identifies a lease
+type LeaseID int64
+
+// NoLease is the zero LeaseID
+const NoLease LeaseID = 0
+
+// LeaseGrantResponse is the result of a Grant
+type LeaseGrantResponse struct {
+	*ResponseHeader
+	ID    LeaseID
+	TTL   int64
+	Error string
+}
+
+// LeaseRevokeResponse is the result of a Revoke
+type LeaseRevokeResponse struct {
+	Header *ResponseHeader
+}
+
+// LeaseKeepAliveResponse is the result of renewing a lease
+type LeaseKeepAliveResponse struct {
+	*ResponseHeader
+	ID  LeaseID
+	TTL int64
+}
+
+// LeaseTimeToLiveResponse describes a lease
+type LeaseTimeToLiveResponse struct {
+	*ResponseHeader
+	ID LeaseID
+	// TTL is the number of seconds left, or -1 if the lease has expired
+	TTL int64
+	// GrantedTTL is the TTL the lease was granted or last renewed with
+	GrantedTTL int64
+	// Keys holds the attached keys, if WithAttachedKeys was given
+	Keys [][]byte
+}
+
+// LeaseStatus identifies a live lease
+type LeaseStatus struct {
+	ID LeaseID
+}
+
+// LeaseLeasesResponse lists the live leases
+type LeaseLeasesResponse struct {
+	*ResponseHeader
+	Leases []LeaseStatus
+}
+
+// LeaseOption configures TimeToLive
+type LeaseOption func(*leaseOp)
+
+type leaseOp struct {
+	attachedKeys bool
+}
+
+// WithAttachedKeys makes TimeToLive list the keys attached to the lease
+func WithAttachedKeys() LeaseOption {
+	return func(op *leaseOp) { op.attachedKeys = true }
+}
+
+type lease struct {
+	id     LeaseID
+	ttl    int64
+	expiry time.Time
+	keys   map[string]struct{}
+	timer  *time.Timer
+}
+
+// Watches
+
+// WatchChan receives the responses of a watch. It is closed when the
+// watch's context is done or its client is closed.
+type WatchChan <-chan WatchResponse
+
+// WatchResponse holds the events of one revision, or reports that the
+// watch was created or canceled
+type WatchResponse struct {
+	Header ResponseHeader
+	Events []*Event
+	// CompactRevision is set when the watch started at a compacted
+	// revision; the watch is then canceled
+	CompactRevision int64
+	Canceled        bool
+	Created         bool
+
+	closeErr error
+}
+
+// Err returns the reason the watch was canceled, or nil
+func (wr *WatchResponse) Err() error {
+	switch {
+	case wr.closeErr != nil:
+		return wr.closeErr
+	case wr.CompactRevision != 0:
+		return ErrCompacted
+	case wr.Canceled:
+		return ErrStopped
+	}
+	return nil
+}
+
+// IsProgressNotify reports whether the response carries no events, only
+// the current revision
+func (wr *WatchResponse) IsProgressNotify() bool {
+	return len(wr.Events) == 0 && !wr.Canceled && !wr.Created && wr.CompactRevision == 0 && wr.Header.Revision != 0
+}
+
+// watcher queues responses for one watch, so writers never wait for a
+// slow reader
+type watcher struct {
+	op     Op
+	out    chan WatchResponse
+	mu     sync.Mutex
+	queue  []WatchResponse
+	signal chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// matches reports whether the watch covers key
+func (w *watcher) matches(key []byte) bool {
+	return inRange(key, w.op.key, w.op.end)
+}
+
+// filter returns the events the watch wants, or nil
+func (w *watcher) filter(events []*Event) []*Event {
+	var out []*Event
+	for _, ev := range events {
+		if !w.matches(ev.Kv.Key) {
+			continue
+		}
+		if (ev.Type == PUT && w.op.filterPut) || (ev.Type == DELETE && w.op.filterDelete) {
+			continue
+		}
+		e := &Event{Type: ev.Type, Kv: ev.Kv.clone()}
+		if w.op.prevKV {
+			e.PrevKv = ev.PrevKv.clone()
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func (w *watcher) enqueue(resp WatchResponse) {
+	w.mu.Lock()
+	w.queue = append(w.queue, resp)
+	w.mu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// stop ends the watch, closing its channel once the queue is drained or
+// abandoned
+func (w *watcher) stop() {
+	w.once.Do(func() { close(w.done) })
+}
+
+// run delivers queued responses until the watch stops
+func (w *watcher) run() {
+	defer w.stop()
+	defer close(w.out)
+	for {
+		w.mu.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+		for _, resp := range queue {
+			select {
+			case w.out <- resp:
+			case <-w.done:
+				return
+			}
+			if resp.Canceled {
+				return
+			}
+		}
+		select {
+		case <-w.signal:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Server
+
+var (
+	serversMu    sync.Mutex
+	servers      = make(map[string]*Server)
+	nextPort     = 49152
+	nextMemberID uint64
+)
+
+// Server is an in-memory, single-member etcd cluster. Clients reach it
+// through its endpoint.
+type Server struct {
+	// Endpoint is the server's host:port, e.g. localhost:2379
+	Endpoint string
+
+	mu         sync.Mutex
+	clusterID  uint64
+	memberID   uint64
+	rev        int64
+	compactRev int64
+	kvs        map[string]*KeyValue
+	// history holds the events after the compacted revision, with
+	// PrevKv set, so past revisions can be read and watched
+	history   []*Event
+	leases    map[LeaseID]*lease
+	nextLease LeaseID
+	watchers  map[*watcher]struct{}
+	ttlUnit   time.Duration
+	stopped   bool
+}
+
+// NewServer starts an in-memory server on an unused loopback port
+func NewServer() *Server {
+	serversMu.Lock()
+	port := nextPort
+	nextPort++
+	serversMu.Unlock()
+	return Listen(fmt.Sprintf("127.0.0.1:%d", port))
+}
+
+// Listen starts an in-memory server at endpoint, such as
+// "localhost:2379" or "http://127.0.0.1:2379", replacing any server
+// already there
+func Listen(endpoint string) *Server {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	nextMemberID++
+	s := &Server{
+		Endpoint:  normalizeEndpoint(endpoint),
+		clusterID: 0xcdf818194e3a8c32,
+		memberID:  0x8e9e05c52164694d + nextMemberID,
+		rev:       1,
+		kvs:       make(map[string]*KeyValue),
+		leases:    make(map[LeaseID]*lease),
+		nextLease: 0x694d71ddacfda200,
+		watchers:  make(map[*watcher]struct{}),
+		ttlUnit:   time.Second,
+	}
+	servers[s.Endpoint] = s
+	return s
+}
+
+// normalizeEndpoint reduces endpoint to host:port, dropping any scheme
+// and defaulting the port to 2379
+func normalizeEndpoint(endpoint string) string {
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		endpoint = endpoint[i+3:]
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host, port = endpoint, "2379"
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+func lookupServer(endpoint string) *Server {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	return servers[normalizeEndpoint(endpoint)]
+}
+
+// SetTTLUnit sets how long one second of lease TTL lasts, so tests can
+// expire leases quickly. The default is a second.
+func (s *Server) SetTTLUnit(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttlUnit = d
+}
+
+// Revision returns the current revision of the store
+func (s *Server) Revision() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rev
+}
+
+// Close stops the server: it becomes unreachable, its watches are
+// canceled and its operations fail with ErrStopped
+func (s *Server) Close() {
+	serversMu.Lock()
+	if servers[s.Endpoint] == s {
+		delete(servers, s.Endpoint)
+	}
+	serversMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for _, l := range s.leases {
+		l.timer.Stop()
+	}
+	for w := range s.watchers {
+		w.enqueue(WatchResponse{Header: *s.header(), Canceled: true, closeErr: ErrStopped})
+		delete(s.watchers, w)
+	}
+}
+
+func (s *Server) header() *ResponseHeader {
+	return &ResponseHeader{ClusterId: s.clusterID, MemberId: s.memberID, Revision: s.rev, RaftTerm: 2}
+}
+
+// inRange reports whether key is in [start, end). A nil end means start
+// alone, and "\x00" means every key from start on.
+func inRange(key, start, end []byte) bool {
+	if len(end) == 0 {
+		return bytes.Equal(key, start)
+	}
+	if bytes.Compare(key, start) < 0 {
+		return false
+	}
+	return bytes.Equal(end, []byte{0}) || bytes.Compare(key, end) < 0
+}
+
+// rangeKVs returns the keys in [key, end) at rev, or now if rev is 0,
+// sorted by key
+func (s *Server) rangeKVs(key, end []byte, rev int64) ([]*KeyValue, error) {
+	if err := s.checkRev(rev); err != nil {
+		return nil, err
+	}
+	kvs := s.kvs
+	if rev > 0 && rev < s.rev {
+		kvs = s.stateAt(rev)
+	}
+	var out []*KeyValue
+	if len(end) == 0 {
+		if kv := kvs[string(key)]; kv != nil {
+			out = append(out, kv)
+		}
+		return out, nil
+	}
+	for k, kv := range kvs {
+		if inRange([]byte(k), key, end) {
+			out = append(out, kv)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].Key, out[j].Key) < 0 })
+	return out, nil
+}
+
+// checkRev checks that rev, if set, can still be read
+func (s *Server) checkRev(rev int64) error {
+	switch {
+	case rev > s.rev:
+		return ErrFutureRev
+	case rev > 0 && rev < s.compactRev:
+		return ErrCompacted
+	}
+	return nil
+}
+
+// stateAt rebuilds the store as it was at rev by undoing later events
+func (s *Server) stateAt(rev int64) map[string]*KeyValue {
+	kvs := make(map[string]*KeyValue, len(s.kvs))
+	for k, kv := range s.kvs {
+		kvs[k] = kv
+	}
+	for i := len(s.history) - 1; i >= 0 && s.history[i].revision() > rev; i-- {
+		ev := s.history[i]
+		if ev.PrevKv == nil {
+			delete(kvs, string(ev.Kv.Key))
+		} else {
+			kvs[string(ev.Kv.Key)] = ev.PrevKv
+		}
+	}
+	return kvs
+}
+
+// do runs op as one request: reads see the current revision and writes
+// share the next one
+func (s *Server) do(op Op) (*ResponseOp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrStopped
+	}
+	path := s.txnPath(op)
+	if err := s.validate(op, path); err != nil {
+		return nil, err
+	}
+	var events []*Event
+	resp := s.apply(op, path, s.rev+1, &events)
+	s.commit(events)
+	return resp, nil
+}
+
+// txnPath decides every branch of a transaction up front, against the
+// store as it is before any operation runs
+func (s *Server) txnPath(op Op) []bool {
+	if op.t != tTxn {
+		return nil
+	}
+	ok := s.compare(op.cmps)
+	path := []bool{ok}
+	ops := op.elseOps
+	if ok {
+		ops = op.thenOps
+	}
+	for _, o := range ops {
+		path = append(path, s.txnPath(o)...)
+	}
+	return path
+}
+
+func (s *Server) compare(cmps []Cmp) bool {
+	for _, cmp := range cmps {
+		kvs, _ := s.rangeKVs(cmp.key, cmp.end, 0)
+		if len(kvs) == 0 {
+			if !cmp.holds(nil) {
+				return false
+			}
+			continue
+		}
+		for _, kv := range kvs {
+			if !cmp.holds(kv) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validate checks the operations on path before any of them runs, so a
+// failing transaction changes nothing
+func (s *Server) validate(op Op, path []bool) error {
+	var puts [][]byte
+	var dels []Op
+	var check func(op Op) error
+	check = func(op Op) error {
+		switch op.t {
+		case tPut:
+			if len(op.key) == 0 {
+				return ErrEmptyKey
+			}
+			for _, key := range puts {
+				if bytes.Equal(key, op.key) {
+					return ErrDuplicateKey
+				}
+			}
+			puts = append(puts, op.key)
+			prev := s.kvs[string(op.key)]
+			if (op.ignoreValue || op.ignoreLease) && prev == nil {
+				return ErrKeyNotFound
+			}
+			if !op.ignoreLease && op.leaseID != NoLease && s.leases[op.leaseID] == nil {
+				return ErrLeaseNotFound
+			}
+		case tRange:
+			if len(op.key) == 0 {
+				return ErrEmptyKey
+			}
+			return s.checkRev(op.rev)
+		case tDeleteRange:
+			if len(op.key) == 0 {
+				return ErrEmptyKey
+			}
+			dels = append(dels, op)
+		case tTxn:
+			ok := path[0]
+			path = path[1:]
+			ops := op.elseOps
+			if ok {
+				ops = op.thenOps
+			}
+			for _, o := range ops {
+				if err := check(o); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := check(op); err != nil {
+		return err
+	}
+	for _, del := range dels {
+		for _, key := range puts {
+			if inRange(key, del.key, del.end) {
+				return ErrDuplicateKey
+			}
+		}
+	}
+	return nil
+}
+
+// apply runs a validated op, writing at rev and collecting the events
+func (s *Server) apply(op Op, path []bool, rev int64, events *[]*Event) *ResponseOp {
+	switch op.t {
+	case tRange:
+		return &ResponseOp{rangeResp: s.get(op)}
+	case tPut:
+		return &ResponseOp{putResp: s.put(op, rev, events)}
+	case tDeleteRange:
+		return &ResponseOp{delResp: s.deleteRange(op.key, op.end, op.prevKV, rev, events)}
+	}
+	var walk func(op Op) *TxnResponse
+	walk = func(op Op) *TxnResponse {
+		ok := path[0]
+		path = path[1:]
+		ops := op.elseOps
+		if ok {
+			ops = op.thenOps
+		}
+		resp := &TxnResponse{Succeeded: ok}
+		for _, o := range ops {
+			if o.t == tTxn {
+				resp.Responses = append(resp.Responses, &ResponseOp{txnResp: walk(o)})
+				continue
+			}
+			resp.Responses = append(resp.Responses, s.apply(o, nil, rev, events))
+		}
+		return resp
+	}
+	txnResp := walk(op)
+	var setHeaders func(resp *TxnResponse)
+	setHeaders = func(resp *TxnResponse) {
+		resp.Header = s.headerAfter(*events)
+		for _, r := range resp.Responses {
+			switch {
+			case r.rangeResp != nil:
+				r.rangeResp.Header = resp.Header
+			case r.putResp != nil:
+				r.putResp.Header = resp.Header
+			case r.delResp != nil:
+				r.delResp.Header = resp.Header
+			case r.txnResp != nil:
+				setHeaders(r.txnResp)
+			}
+		}
+	}
+	setHeaders(txnResp)
+	return &ResponseOp{txnResp: txnResp}
+}
+
+// headerAfter returns the header for a request that produced events
+func (s *Server) headerAfter(events []*Event) *ResponseHeader {
+	h := s.header()
+	if len(events) > 0 {
+		h.Revision = events[0].revision()
+	}
+	return h
+}
+
+func (s *Server) get(op Op) *GetResponse {
+	kvs, _ := s.rangeKVs(op.key, op.end, op.rev)
+	resp := &GetResponse{Header: s.header(), Count: int64(len(kvs))}
+	if op.countOnly {
+		return resp
+	}
+	if op.sortOrder != SortNone || op.sortTarget != SortByKey {
+		less := sortLess(op.sortTarget)
+		if op.sortOrder == SortDescend {
+			sort.SliceStable(kvs, func(i, j int) bool { return less(kvs[j], kvs[i]) })
+		} else {
+			sort.SliceStable(kvs, func(i, j int) bool { return less(kvs[i], kvs[j]) })
+		}
+	}
+	if op.limit > 0 && int64(len(kvs)) > op.limit {
+		kvs = kvs[:op.limit]
+		resp.More = true
+	}
+	for _, kv := range kvs {
+		c := kv.clone()
+		if op.keysOnly {
+			c.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, c)
+	}
+	return resp
+}
+
+func sortLess(target SortTarget) func(a, b *KeyValue) bool {
+	switch target {
+	case SortByVersion:
+		return func(a, b *KeyValue) bool { return a.Version < b.Version }
+	case SortByCreateRevision:
+		return func(a, b *KeyValue) bool { return a.CreateRevision < b.CreateRevision }
+	case SortByModRevision:
+		return func(a, b *KeyValue) bool { return a.ModRevision < b.ModRevision }
+	case SortByValue:
+		return func(a, b *KeyValue) bool { return bytes.Compare(a.Value, b.Value) < 0 }
+	}
+	return func(a, b *KeyValue) bool { return bytes.Compare(a.Key, b.Key) < 0 }
+}
+
+func (s *Server) put(op Op, rev int64, events *[]*Event) *PutResponse {
+	key := string(op.key)
+	prev := s.kvs[key]
+	kv := &KeyValue{
+		Key:            append([]byte(nil), op.key...),
+		CreateRevision: rev,
+		ModRevision:    rev,
+		Version:        1,
+		Value:          append([]byte(nil), op.val...),
+		Lease:          int64(op.leaseID),
+	}
+	if prev != nil {
+		kv.CreateRevision = prev.CreateRevision
+		kv.Version = prev.Version + 1
+		if op.ignoreValue {
+			kv.Value = prev.Value
+		}
+		if op.ignoreLease {
+			kv.Lease = prev.Lease
+		}
+		if l := s.leases[LeaseID(prev.Lease)]; l != nil {
+			delete(l.keys, key)
+		}
+	}
+	if l := s.leases[LeaseID(kv.Lease)]; l != nil {
+		l.keys[key] = struct{}{}
+	}
+	s.kvs[key] = kv
+	*events = append(*events, &Event{Type: PUT, Kv: kv, PrevKv: prev})
+
+	resp := &PutResponse{Header: s.header()}
+	resp.Header.Revision = rev
+	if op.prevKV {
+		resp.PrevKv = prev.clone()
+	}
+	return resp
+}
+
+func (s *Server) deleteRange(key, end []byte, prevKV bool, rev int64, events *[]*Event) *DeleteResponse {
+	kvs, _ := s.rangeKVs(key, end, 0)
+	resp := &DeleteResponse{Header: s.header(), Deleted: int64(len(kvs))}
+	for _, kv := range kvs {
+		k := string(kv.Key)
+		if l := s.leases[LeaseID(kv.Lease)]; l != nil {
+			delete(l.keys, k)
+		}
+		delete(s.kvs, k)
+		*events = append(*events, &Event{Type: DELETE, Kv: &KeyValue{Key: kv.Key, ModRevision: rev}, PrevKv: kv})
+		if prevKV {
+			resp.PrevKvs = append(resp.PrevKvs, kv.clone())
+		}
+	}
+	if len(kvs) > 0 {
+		resp.Header.Revision = rev
+	}
+	return resp
+}
+
+// commit records the events of a write, if any, at the next revision and
+// passes them to the watches
+func (s *Server) commit(events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+	s.rev++
+	s.history = append(s.history, events...)
+	for w := range s.watchers {
+		if evs := w.filter(events); len(evs) > 0 {
+			w.enqueue(WatchResponse{Header: *s.header(), Events: evs})
+		}
+	}
+}
+
+func (s *Server) compact(rev int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if rev <= s.compactRev {
+		return ErrCompacted
+	}
+	if rev > s.rev {
+		return ErrFutureRev
+	}
+	s.compactRev = rev
+	i := sort.Search(len(s.history), func(i int) bool { return s.history[i].revision() >= rev })
+	s.history = append([]*Event(nil), s.history[i:]...)
+	return nil
+}
+
+// watch registers a watch for op and replays the events since op.rev
+func (s *Server) watch(op Op) *watcher {
+	w := &watcher{
+		op:     op,
+		out:    make(chan WatchResponse),
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		w.enqueue(WatchResponse{Canceled: true, closeErr: ErrStopped})
+		return w
+	}
+	if op.createdNotify {
+		w.enqueue(WatchResponse{Header: *s.header(), Created: true})
+	}
+	if op.rev > 0 && op.rev < s.compactRev {
+		w.enqueue(WatchResponse{Header: *s.header(), CompactRevision: s.compactRev, Canceled: true})
+		return w
+	}
+	if op.rev > 0 {
+		i := sort.Search(len(s.history), func(i int) bool { return s.history[i].revision() >= op.rev })
+		for i < len(s.history) {
+			j := i
+			for j < len(s.history) && s.history[j].revision() == s.history[i].revision() {
+				j++
+			}
+			if evs := w.filter(s.history[i:j]); len(evs) > 0 {
+				w.enqueue(WatchResponse{Header: *s.header(), Events: evs})
+			}
+			i = j
+		}
+	}
+	s.watchers[w] = struct{}{}
+	return w
+}
+
+func (s *Server) unwatch(w *watcher) {
+	s.mu.Lock()
+	delete(s.watchers, w)
+	s.mu.Unlock()
+	w.stop()
+}
+
+func (s *Server) grant(ttl int64) (*lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrStopped
+	}
+	if ttl < 1 {
+		ttl = 1
+	}
+	s.nextLease++
+	l := &lease{id: s.nextLease, ttl: ttl, keys: make(map[string]struct{})}
+	l.expiry = time.Now().Add(time.Duration(ttl) * s.ttlUnit)
+	id := l.id
+	l.timer = time.AfterFunc(time.Duration(ttl)*s.ttlUnit, func() { s.expire(id) })
+	s.leases[id] = l
+	return l, nil
+}
+
+// expire revokes a lease whose time is up, or re-arms its timer if it
+// was renewed
+func (s *Server) expire(id LeaseID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.leases[id]
+	if l == nil || s.stopped {
+		return
+	}
+	if left := time.Until(l.expiry); left > 0 {
+		l.timer.Reset(left)
+		return
+	}
+	s.revoke(l)
+}
+
+// revoke deletes a lease and its keys, at one revision
+func (s *Server) revoke(l *lease) {
+	l.timer.Stop()
+	delete(s.leases, l.id)
+	keys := make([]string, 0, len(l.keys))
+	for key := range l.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var events []*Event
+	for _, key := range keys {
+		s.deleteRange([]byte(key), nil, false, s.rev+1, &events)
+	}
+	s.commit(events)
+}
+
+// renew restarts a lease's TTL
+func (s *Server) renew(id LeaseID) (*LeaseKeepAliveResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrStopped
+	}
+	l := s.leases[id]
+	if l == nil {
+		return nil, ErrLeaseNotFound
+	}
+	l.expiry = time.Now().Add(time.Duration(l.ttl) * s.ttlUnit)
+	return &LeaseKeepAliveResponse{ResponseHeader: s.header(), ID: id, TTL: l.ttl}, nil
+}
+
+// Client
+
+// Config configures a Client
+type Config struct {
+	// Endpoints lists the servers to try, e.g. "localhost:2379"
+	Endpoints []string
+	// DialTimeout is accepted for compatibility; in-memory dials are
+	// immediate
+	DialTimeout time.Duration
+	// Username and Password are accepted for compatibility; the
+	// emulator has no authentication
+	Username string
+	Password string
+	// Context, if set, closes the client when it is done
+	Context context.Context
+}
+
+// KV is the key-value part of a Client
+type KV interface {
+	Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error)
+	Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error)
+	Delete(ctx context.Context, key string, opts ...OpOption) (*DeleteResponse, error)
+	Compact(ctx context.Context, rev int64) (*CompactResponse, error)
+	Do(ctx context.Context, op Op) (OpResponse, error)
+	Txn(ctx context.Context) Txn
+}
+
+// Lease is the lease part of a Client
+type Lease interface {
+	Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error)
+	Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error)
+	TimeToLive(ctx context.Context, id LeaseID, opts ...LeaseOption) (*LeaseTimeToLiveResponse, error)
+	Leases(ctx context.Context) (*LeaseLeasesResponse, error)
+	KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAliveResponse, error)
+	KeepAliveOnce(ctx context.Context, id LeaseID) (*LeaseKeepAliveResponse, error)
+}
+
+// Watcher is the watch part of a Client
+type Watcher interface {
+	Watch(ctx context.Context, key string, opts ...OpOption) WatchChan
+}
+
+// NewKV returns the key-value part of c
+func NewKV(c *Client) KV { return c }
+
+// NewLease returns the lease part of c
+func NewLease(c *Client) Lease { return c }
+
+// NewWatcher returns the watch part of c
+func NewWatcher(c *Client) Watcher { return c }
+
+// Client talks to an in-memory server, as clientv3.Client does to an
+// etcd cluster
+type Client struct {
+	server    *Server
+	endpoints []string
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// New connects to the first reachable server in cfg.Endpoints
+func New(cfg Config) (*Client, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, ErrNoAvailableEndpoints
+	}
+	var server *Server
+	for _, endpoint := range cfg.Endpoints {
+		if server = lookupServer(endpoint); server != nil {
+			break
+		}
+	}
+	if server == nil {
+		return nil, context.DeadlineExceeded
+	}
+	parent := cfg.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	return &Client{
+		server:    server,
+		endpoints: append([]string(nil), cfg.Endpoints...),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Endpoints returns the endpoints the client was configured with
+func (c *Client) Endpoints() []string {
+	return append([]string(nil), c.endpoints...)
+}
+
+// Ctx returns a context that is canceled when the client is closed
+func (c *Client) Ctx() context.Context {
+	return c.ctx
+}
+
+// Close closes the client, ending its watches and keep-alives
+func (c *Client) Close() error {
+	c.cancel()
+	return nil
+}
+
+// check returns the error an operation fails with before it starts
+func (c *Client) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.ctx.Err() != nil {
+		return context.Canceled
+	}
+	return nil
+}
+
+// Put stores val under key
+func (c *Client) Put(ctx context.Context, key, val string, opts ...OpOption) (*PutResponse, error) {
+	resp, err := c.Do(ctx, OpPut(key, val, opts...))
+	return resp.put, err
+}
+
+// Get reads key, or a range of keys with WithPrefix, WithRange or
+// WithFromKey
+func (c *Client) Get(ctx context.Context, key string, opts ...OpOption) (*GetResponse, error) {
+	resp, err := c.Do(ctx, OpGet(key, opts...))
+	return resp.get, err
+}
+
+// Delete removes key, or a range of keys
+func (c *Client) Delete(ctx context.Context, key string, opts ...OpOption) (*DeleteResponse, error) {
+	resp, err := c.Do(ctx, OpDelete(key, opts...))
+	return resp.del, err
+}
+
+// OpResponse is the result of Do
+type OpResponse struct {
+	put *PutResponse
+	get *GetResponse
+	del *DeleteResponse
+	txn *TxnResponse
+}
+
+// Put returns the result of a put, or nil
+func (r OpResponse) Put() *PutResponse { return r.put }
+
+// Get returns the result of a get, or nil
+func (r OpResponse) Get() *GetResponse { return r.get }
+
+// Del returns the result of a delete, or nil
+func (r OpResponse) Del() *DeleteResponse { return r.del }
+
+// Txn returns the result of a transaction, or nil
+func (r OpResponse) Txn() *TxnResponse { return r.txn }
+
+// Do runs op
+func (c *Client) Do(ctx context.Context, op Op) (OpResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return OpResponse{}, err
+	}
+	resp, err := c.server.do(op)
+	if err != nil {
+		return OpResponse{}, err
+	}
+	return OpResponse{put: resp.putResp, get: resp.rangeResp, del: resp.delResp, txn: resp.txnResp}, nil
+}
+
+// Txn starts a transaction
+func (c *Client) Txn(ctx context.Context) Txn {
+	return &txn{client: c, ctx: ctx}
+}
+
+// Compact discards the history before rev, so it can no longer be read
+// or watched
+func (c *Client) Compact(ctx context.Context, rev int64) (*CompactResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	if err := c.server.compact(rev); err != nil {
+		return nil, err
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	return &CompactResponse{Header: c.server.header()}, nil
+}
+
+// Watch watches key, or a range of keys, for changes from now on, or
+// from WithRev. The channel is closed when ctx is done or the client is
+// closed.
+func (c *Client) Watch(ctx context.Context, key string, opts ...OpOption) WatchChan {
+	op := Op{key: []byte(key)}
+	op.applyOpts(opts)
+	w := c.server.watch(op)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.ctx.Done():
+		case <-w.done:
+		}
+		c.server.unwatch(w)
+	}()
+	return w.out
+}
+
+// Grant creates a lease that expires after ttl seconds unless kept alive
+func (c *Client) Grant(ctx context.Context, ttl int64) (*LeaseGrantResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	l, err := c.server.grant(ttl)
+	if err != nil {
+		return nil, err
+	}
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	return &LeaseGrantResponse{ResponseHeader: c.server.header(), ID: l.id, TTL: l.ttl}, nil
+}
+
+// Revoke ends a lease now, deleting its keys
+func (c *Client) Revoke(ctx context.Context, id LeaseID) (*LeaseRevokeResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrStopped
+	}
+	l := s.leases[id]
+	if l == nil {
+		return nil, ErrLeaseNotFound
+	}
+	s.revoke(l)
+	return &LeaseRevokeResponse{Header: s.header()}, nil
+}
+
+// TimeToLive describes a lease. For an expired or unknown lease TTL is -1.
+func (c *Client) TimeToLive(ctx context.Context, id LeaseID, opts ...LeaseOption) (*LeaseTimeToLiveResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	var op leaseOp
+	for _, opt := range opts {
+		opt(&op)
+	}
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrStopped
+	}
+	resp := &LeaseTimeToLiveResponse{ResponseHeader: s.header(), ID: id, TTL: -1}
+	l := s.leases[id]
+	if l == nil {
+		return resp, nil
+	}
+	resp.GrantedTTL = l.ttl
+	resp.TTL = int64((time.Until(l.expiry) + s.ttlUnit - 1) / s.ttlUnit)
+	if op.attachedKeys {
+		for key := range l.keys {
+			resp.Keys = append(resp.Keys, []byte(key))
+		}
+		sort.Slice(resp.Keys, func(i, j int) bool { return bytes.Compare(resp.Keys[i], resp.Keys[j]) < 0 })
+	}
+	return resp, nil
+}
+
+// Leases lists the live leases
+func (c *Client) Leases(ctx context.Context) (*LeaseLeasesResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return nil, ErrStopped
+	}
+	resp := &LeaseLeasesResponse{ResponseHeader: s.header()}
+	for id := range s.leases {
+		resp.Leases = append(resp.Leases, LeaseStatus{ID: id})
+	}
+	sort.Slice(resp.Leases, func(i, j int) bool { return resp.Leases[i].ID < resp.Leases[j].ID })
+	return resp, nil
+}
+
+// KeepAliveOnce renews a lease once
+func (c *Client) KeepAliveOnce(ctx context.Context, id LeaseID) (*LeaseKeepAliveResponse, error) {
+	if err := c.check(ctx); err != nil {
+		return nil, err
+	}
+	return c.server.renew(id)
+}
+
+// KeepAlive renews a lease every third of its TTL until ctx is done or
+// the client is closed. Each renewal is sent on the returned channel,
+// which is closed when renewing stops, including when the lease is gone.
+// Renewals are dropped while the channel is full.
+func (c *Client) KeepAlive(ctx context.Context, id LeaseID) (<-chan *LeaseKeepAliveResponse, error) {
+	resp, err := c.KeepAliveOnce(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.server.mu.Lock()
+	interval := time.Duration(resp.TTL) * c.server.ttlUnit / 3
+	c.server.mu.Unlock()
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	ch := make(chan *LeaseKeepAliveResponse, 16)
+	ch <- resp
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			resp, err := c.server.renew(id)
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- resp:
+			default:
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Go-kit service discovery
+
+// Errors returned by KitClient, as in go-kit's sd/etcdv3
+var (
+	ErrNoKey   = errors.New("no key provided")
+	ErrNoValue = errors.New("no value provided")
+)
+
+// ClientOptions configures a KitClient
+type ClientOptions struct {
+	DialTimeout time.Duration
+	Username    string
+	Password    string
+}
+
+// Service is a service instance to register: its address under a key,
+// optionally kept alive by a lease
+type Service struct {
+	Key   string
+	Value string
+	TTL   *TTLOption
+}
+
+// TTLOption makes a registration expire after ttl unless renewed every
+// heartbeat
+type TTLOption struct {
+	heartbeat time.Duration
+	ttl       time.Duration
+}
+
+// NewTTLOption returns a TTLOption. heartbeat defaults to 3 seconds, and
+// ttl to three heartbeats if it is not longer than one.
+func NewTTLOption(heartbeat, ttl time.Duration) *TTLOption {
+	if heartbeat <= 0 {
+		heartbeat = 3 * time.Second
+	}
+	if ttl <= heartbeat {
+		ttl = 3 * heartbeat
+	}
+	return &TTLOption{heartbeat: heartbeat, ttl: ttl}
+}
+
+// KitClient is the registry client of go-kit's sd/etcdv3. The Go-kit
+// emulator's instancers watch it to track instances, and Registrar
+// registers services with it.
+type KitClient struct {
+	cli     *Client
+	ctx     context.Context
+	mu      sync.Mutex
+	leaseID LeaseID
+	hbStop  context.CancelFunc
+}
+
+// NewKitClient connects to the first reachable server in machines. The
+// client stops watching and renewing when ctx is done.
+func NewKitClient(ctx context.Context, machines []string, options ClientOptions) (*KitClient, error) {
+	cli, err := New(Config{
+		Endpoints:   machines,
+		DialTimeout: options.DialTimeout,
+		Username:    options.Username,
+		Password:    options.Password,
+		Context:     ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &KitClient{cli: cli, ctx: ctx}, nil
+}
+
+// GetEntries returns the values of the keys starting with prefix
+func (c *KitClient) GetEntries(prefix string) ([]string, error) {
+	resp, err := c.cli.Get(c.ctx, prefix, WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		entries[i] = string(kv.Value)
+	}
+	return entries, nil
+}
+
+// WatchPrefix signals ch once, then after every change under prefix,
+// until the client's context is done
+func (c *KitClient) WatchPrefix(prefix string, ch chan struct{}) {
+	wch := c.cli.Watch(c.ctx, prefix, WithPrefix())
+	select {
+	case ch <- struct{}{}:
+	case <-c.ctx.Done():
+		return
+	}
+	for wr := range wch {
+		if wr.Canceled {
+			return
+		}
+		select {
+		case ch <- struct{}{}:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Register stores the service's value under its key. With a TTL the key
+// is attached to a lease that is kept alive until Deregister.
+func (c *KitClient) Register(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	if s.Value == "" {
+		return ErrNoValue
+	}
+	if s.TTL == nil {
+		_, err := c.cli.Put(c.ctx, s.Key, s.Value)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	grant, err := c.cli.Grant(c.ctx, int64(s.TTL.ttl.Seconds()))
+	if err != nil {
+		return err
+	}
+	if _, err := c.cli.Put(c.ctx, s.Key, s.Value, WithLease(grant.ID)); err != nil {
+		return err
+	}
+	if c.hbStop != nil {
+		c.hbStop()
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	ch, err := c.cli.KeepAlive(ctx, grant.ID)
+	if err != nil {
+		cancel()
+		return err
+	}
+	go func() {
+		for range ch {
+		}
+	}()
+	c.leaseID, c.hbStop = grant.ID, cancel
+	return nil
+}
+
+// Deregister stops renewing the service's lease and deletes its key
+func (c *KitClient) Deregister(s Service) error {
+	if s.Key == "" {
+		return ErrNoKey
+	}
+	c.mu.Lock()
+	if c.hbStop != nil {
+		c.hbStop()
+		c.hbStop = nil
+	}
+	c.mu.Unlock()
+	_, err := c.cli.Delete(c.ctx, s.Key)
+	return err
+}
+
+// LeaseID returns the ID of the lease of the last registration with a TTL
+func (c *KitClient) LeaseID() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(c.leaseID)
+}
+
+// Logger is a go-kit logger, such as the Go-kit emulator's
+type Logger interface {
+	Log(keyvals ...interface{}) error
+}
+
+// Registrar registers one service instance, as go-kit's etcdv3.Registrar
+type Registrar struct {
+	client  *KitClient
+	service Service
+	logger  Logger
+}
+
+// NewRegistrar returns a Registrar for service
+func NewRegistrar(client *KitClient, service Service, logger Logger) *Registrar {
+	return &Registrar{client: client, service: service, logger: logger}
+}
+
+// Register registers the service, logging the outcome
+func (r *Registrar) Register() {
+	if err := r.client.Register(r.service); err != nil {
+		r.logger.Log("key", r.service.Key, "value", r.service.Value, "err", err)
+		return
+	}
+	r.logger.Log("key", r.service.Key, "value", r.service.Value, "action", "register")
+}
+
+// Deregister deregisters the service, logging the outcome
+func (r *Registrar) Deregister() {
+	if err := r.client.Deregister(r.service); err != nil {
+		r.logger.Log("key", r.service.Key, "value", r.service.Value, "err", err)
+		return
+	}
+	r.logger.Log("key", r.service.Key, "value", r.service.Value, "action", "deregister")
+}
+
+// Viper remote provider
+
+// ErrRemoteKeyNotFound is returned by ViperStore.Get for a missing key
+var ErrRemoteKeyNotFound = errors.New("key not found")
+
+// ViperStore serves configuration documents stored in keys to the Viper
+// emulator's remote provider, as viper's etcd3 provider does. Register
+// it with viper.RegisterRemoteStore("etcd3", endpoint, store).
+type ViperStore struct {
+	cli *Client
+}
+
+// NewViperStore returns a ViperStore reading through cli
+func NewViperStore(cli *Client) *ViperStore {
+	return &ViperStore{cli: cli}
+}
+
+// Get returns the document stored under path
+func (r *ViperStore) Get(path string) ([]byte, error) {
+	resp, err := r.cli.Get(r.cli.Ctx(), path)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrRemoteKeyNotFound
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// Watch sends every new version of the document under path until stop
+// is closed or the client is closed
+func (r *ViperStore) Watch(path string, stop <-chan struct{}) <-chan []byte {
+	ctx, cancel := context.WithCancel(r.cli.Ctx())
+	wch := r.cli.Watch(ctx, path, WithFilterDelete())
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		defer cancel()
+		for {
+			select {
+			case wr, ok := <-wch:
+				if !ok || wr.Canceled {
+					return
+				}
+				for _, ev := range wr.Events {
+					select {
+					case out <- ev.Kv.Value:
+					case <-stop:
+						return
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,750 @@
+package main
+
+// Developed by PowerShield, as an alternative to etcd and its clientv3 Go client
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+var ctx = context.Background()
+
+// newClient returns a client on a fresh server
+func newClient() (*Client, *Server) {
+	server := NewServer()
+	client, _ := New(Config{Endpoints: []string{server.Endpoint}})
+	return client, server
+}
+
+func keysOf(resp *GetResponse) string {
+	keys := make([]string, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		keys[i] = string(kv.Key)
+	}
+	return strings.Join(keys, ",")
+}
+
+// next waits briefly for a watch response
+func next(wch WatchChan) (WatchResponse, bool) {
+	select {
+	case wr, ok := <-wch:
+		return wr, ok
+	case <-time.After(time.Second):
+		return WatchResponse{}, false
+	}
+}
+
+// eventually polls cond for up to a second
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Log(keyvals ...interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprint(keyvals...))
+	return nil
+}
+
+func testPutAndGet() bool {
+	client, _ := newClient()
+	defer client.Close()
+
+	put, err := client.Put(ctx, "/config/db", "postgres://a")
+	if err != nil || put.Header.Revision != 2 {
+		return false
+	}
+	put, _ = client.Put(ctx, "/config/db", "postgres://b", WithPrevKV())
+	if string(put.PrevKv.Value) != "postgres://a" || put.Header.Revision != 3 {
+		return false
+	}
+
+	resp, err := client.Get(ctx, "/config/db")
+	if err != nil || resp.Count != 1 {
+		return false
+	}
+	kv := resp.Kvs[0]
+	if string(kv.Value) != "postgres://b" || kv.CreateRevision != 2 || kv.ModRevision != 3 || kv.Version != 2 {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "/config/missing"); len(resp.Kvs) != 0 || resp.Header.Revision != 3 {
+		return false
+	}
+	if _, err := client.Put(ctx, "", "x"); err != ErrEmptyKey {
+		return false
+	}
+
+	// Deleting bumps the revision; deleting nothing does not
+	del, _ := client.Delete(ctx, "/config/db", WithPrevKV())
+	if del.Deleted != 1 || string(del.PrevKvs[0].Value) != "postgres://b" || del.Header.Revision != 4 {
+		return false
+	}
+	del, _ = client.Delete(ctx, "/config/db")
+	if del.Deleted != 0 || del.Header.Revision != 4 {
+		return false
+	}
+
+	// A recreated key starts a new version history
+	client.Put(ctx, "/config/db", "postgres://c")
+	resp, _ = client.Get(ctx, "/config/db")
+	return resp.Kvs[0].Version == 1 && resp.Kvs[0].CreateRevision == 5
+}
+
+func testRangeQueries() bool {
+	client, _ := newClient()
+	defer client.Close()
+	for _, k := range []string{"/svc/b", "/svc/a", "/svc/c", "/svcx", "/other"} {
+		client.Put(ctx, k, "v"+k)
+	}
+
+	if resp, _ := client.Get(ctx, "/svc/", WithPrefix()); keysOf(resp) != "/svc/a,/svc/b,/svc/c" || resp.Count != 3 {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "/svc/a", WithRange("/svc/c")); keysOf(resp) != "/svc/a,/svc/b" {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "/svc/c", WithFromKey()); keysOf(resp) != "/svc/c,/svcx" {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "", WithPrefix()); resp.Count != 5 {
+		return false
+	}
+	if GetPrefixRangeEnd("/svc/") != "/svc0" {
+		return false
+	}
+
+	resp, _ := client.Get(ctx, "/svc/", WithPrefix(), WithLimit(2))
+	if keysOf(resp) != "/svc/a,/svc/b" || !resp.More || resp.Count != 3 {
+		return false
+	}
+	resp, _ = client.Get(ctx, "/svc/", WithPrefix(), WithSort(SortByModRevision, SortDescend))
+	if keysOf(resp) != "/svc/c,/svc/a,/svc/b" {
+		return false
+	}
+	resp, _ = client.Get(ctx, "/svc/", WithPrefix(), WithKeysOnly())
+	if len(resp.Kvs) != 3 || len(resp.Kvs[0].Value) != 0 {
+		return false
+	}
+	resp, _ = client.Get(ctx, "/svc/", WithPrefix(), WithCountOnly())
+	if len(resp.Kvs) != 0 || resp.Count != 3 {
+		return false
+	}
+
+	del, _ := client.Delete(ctx, "/svc/", WithPrefix())
+	if del.Deleted != 3 {
+		return false
+	}
+	resp, _ = client.Get(ctx, "", WithFromKey())
+	return keysOf(resp) == "/other,/svcx"
+}
+
+func testHistoricalReads() bool {
+	client, _ := newClient()
+	defer client.Close()
+	p1, _ := client.Put(ctx, "k", "v1")
+	p2, _ := client.Put(ctx, "k", "v2")
+	client.Delete(ctx, "k")
+	client.Put(ctx, "other", "x")
+
+	if resp, _ := client.Get(ctx, "k", WithRev(p1.Header.Revision)); string(resp.Kvs[0].Value) != "v1" {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "k", WithRev(p2.Header.Revision)); string(resp.Kvs[0].Value) != "v2" || resp.Kvs[0].Version != 2 {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "k"); len(resp.Kvs) != 0 {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "", WithPrefix(), WithRev(p1.Header.Revision)); keysOf(resp) != "k" {
+		return false
+	}
+	if _, err := client.Get(ctx, "k", WithRev(100)); err != ErrFutureRev {
+		return false
+	}
+
+	if _, err := client.Compact(ctx, p2.Header.Revision); err != nil {
+		return false
+	}
+	if _, err := client.Get(ctx, "k", WithRev(p1.Header.Revision)); err != ErrCompacted {
+		return false
+	}
+	if resp, err := client.Get(ctx, "k", WithRev(p2.Header.Revision)); err != nil || string(resp.Kvs[0].Value) != "v2" {
+		return false
+	}
+	_, err := client.Compact(ctx, p1.Header.Revision)
+	return err == ErrCompacted
+}
+
+func testTransactions() bool {
+	client, _ := newClient()
+	defer client.Close()
+
+	// Create-if-absent: only the first claim wins
+	claim := func(owner string) (bool, string) {
+		resp, err := client.Txn(ctx).
+			If(Compare(CreateRevision("/lock"), "=", 0)).
+			Then(OpPut("/lock", owner)).
+			Else(OpGet("/lock")).
+			Commit()
+		if err != nil {
+			return false, ""
+		}
+		if resp.Succeeded {
+			return true, owner
+		}
+		return false, string(resp.Responses[0].GetResponseRange().Kvs[0].Value)
+	}
+	if ok, holder := claim("worker-1"); !ok || holder != "worker-1" {
+		return false
+	}
+	if ok, holder := claim("worker-2"); ok || holder != "worker-1" {
+		return false
+	}
+
+	// Compare-and-swap on the mod revision
+	get, _ := client.Get(ctx, "/lock")
+	rev := get.Kvs[0].ModRevision
+	resp, _ := client.Txn(ctx).
+		If(Compare(ModRevision("/lock"), "=", rev), Compare(Value("/lock"), "=", "worker-1")).
+		Then(OpPut("/lock", "worker-3"), OpPut("/lock-owner-changed", "1")).
+		Commit()
+	if !resp.Succeeded || resp.Header.Revision != rev+1 {
+		return false
+	}
+	if resp.Responses[0].GetResponsePut().Header.Revision != rev+1 {
+		return false
+	}
+	// Both writes share one revision
+	get, _ = client.Get(ctx, "/lock", WithPrefix())
+	if get.Kvs[0].ModRevision != get.Kvs[1].ModRevision {
+		return false
+	}
+	resp, _ = client.Txn(ctx).If(Compare(ModRevision("/lock"), "=", rev)).Then(OpPut("/lock", "late")).Commit()
+	if resp.Succeeded || len(resp.Responses) != 0 {
+		return false
+	}
+
+	// Comparisons over a prefix, and a nested transaction
+	resp, _ = client.Txn(ctx).
+		If(Compare(Version("/lock").WithPrefix(), ">", 0)).
+		Then(OpTxn(
+			[]Cmp{Compare(Value("/lock"), "!=", "worker-3")},
+			[]Op{OpPut("/nested", "then")},
+			[]Op{OpPut("/nested", "else")},
+		)).
+		Commit()
+	if !resp.Succeeded || resp.Responses[0].GetResponseTxn().Succeeded {
+		return false
+	}
+	get, _ = client.Get(ctx, "/nested")
+	if string(get.Kvs[0].Value) != "else" {
+		return false
+	}
+
+	// Do runs a single operation
+	out, err := client.Do(ctx, OpGet("/lock", WithPrefix(), WithCountOnly()))
+	return err == nil && out.Get().Count == 2 && out.Put() == nil
+}
+
+func testTransactionValidation() (ok bool) {
+	client, _ := newClient()
+	defer client.Close()
+	client.Put(ctx, "a", "1")
+	rev := client.server.Revision()
+
+	if _, err := client.Txn(ctx).Then(OpPut("x", "1"), OpPut("x", "2")).Commit(); err != ErrDuplicateKey {
+		return false
+	}
+	if _, err := client.Txn(ctx).Then(OpDelete("p", WithPrefix()), OpPut("p/1", "2")).Commit(); err != ErrDuplicateKey {
+		return false
+	}
+	// A failing operation rolls back the whole transaction
+	if _, err := client.Txn(ctx).Then(OpPut("b", "1"), OpPut("c", "1", WithLease(12345))).Commit(); err != ErrLeaseNotFound {
+		return false
+	}
+	if _, err := client.Put(ctx, "missing", "", WithIgnoreValue()); err != ErrKeyNotFound {
+		return false
+	}
+	if client.server.Revision() != rev {
+		return false
+	}
+	if resp, _ := client.Get(ctx, "b"); len(resp.Kvs) != 0 {
+		return false
+	}
+
+	// Only the branch that runs is checked
+	resp, err := client.Txn(ctx).
+		If(Compare(Value("a"), "=", "1")).
+		Then(OpPut("b", "1")).
+		Else(OpPut("c", "1", WithLease(12345))).
+		Commit()
+	if err != nil || !resp.Succeeded {
+		return false
+	}
+
+	// Calling If after Then is a programming error
+	defer func() { ok = recover() != nil }()
+	client.Txn(ctx).Then().If()
+	return false
+}
+
+func testWatch() bool {
+	client, _ := newClient()
+	defer client.Close()
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wch := client.Watch(wctx, "/jobs/", WithPrefix(), WithPrevKV())
+	client.Put(ctx, "/jobs/1", "queued")
+	client.Put(ctx, "/other", "ignored")
+	client.Put(ctx, "/jobs/1", "running")
+	client.Delete(ctx, "/jobs/1")
+
+	wr, ok := next(wch)
+	if !ok || len(wr.Events) != 1 || !wr.Events[0].IsCreate() || string(wr.Events[0].Kv.Value) != "queued" {
+		return false
+	}
+	wr, _ = next(wch)
+	ev := wr.Events[0]
+	if !ev.IsModify() || string(ev.PrevKv.Value) != "queued" || ev.Kv.Version != 2 {
+		return false
+	}
+	wr, _ = next(wch)
+	ev = wr.Events[0]
+	if ev.Type != DELETE || string(ev.Kv.Key) != "/jobs/1" || string(ev.PrevKv.Value) != "running" || ev.Type.String() != "DELETE" {
+		return false
+	}
+
+	// A transaction's events arrive together
+	client.Txn(ctx).Then(OpPut("/jobs/2", "a"), OpPut("/jobs/3", "b")).Commit()
+	wr, _ = next(wch)
+	if len(wr.Events) != 2 || wr.Events[0].Kv.ModRevision != wr.Events[1].Kv.ModRevision {
+		return false
+	}
+
+	// A single-key watch with a filter
+	sch := client.Watch(wctx, "/jobs/2", WithFilterPut(), WithCreatedNotify())
+	if wr, _ := next(sch); !wr.Created {
+		return false
+	}
+	client.Put(ctx, "/jobs/2", "b")
+	client.Delete(ctx, "/jobs/2")
+	wr, _ = next(sch)
+	return len(wr.Events) == 1 && wr.Events[0].Type == DELETE && wr.Err() == nil
+}
+
+func testWatchFromRevision() bool {
+	client, _ := newClient()
+	defer client.Close()
+	p1, _ := client.Put(ctx, "k", "1")
+	client.Put(ctx, "k", "2")
+	client.Put(ctx, "k", "3")
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := client.Watch(wctx, "k", WithRev(p1.Header.Revision+1))
+	client.Put(ctx, "k", "4")
+	var values []string
+	for len(values) < 3 {
+		wr, ok := next(wch)
+		if !ok {
+			return false
+		}
+		for _, ev := range wr.Events {
+			values = append(values, string(ev.Kv.Value))
+		}
+	}
+	if strings.Join(values, ",") != "2,3,4" {
+		return false
+	}
+
+	// Watching from before the compaction fails
+	client.Compact(ctx, p1.Header.Revision+2)
+	cch := client.Watch(wctx, "k", WithRev(p1.Header.Revision))
+	wr, ok := next(cch)
+	if !ok || !wr.Canceled || wr.CompactRevision != p1.Header.Revision+2 || !errors.Is(wr.Err(), ErrCompacted) {
+		return false
+	}
+	if _, ok := next(cch); ok {
+		return false
+	}
+	return true
+}
+
+func testWatchCancellation() bool {
+	client, server := newClient()
+
+	// Canceling the context closes the channel
+	wctx, cancel := context.WithCancel(ctx)
+	wch := client.Watch(wctx, "k")
+	cancel()
+	if _, ok := next(wch); ok {
+		return false
+	}
+
+	// So does closing the client
+	wch = client.Watch(ctx, "k")
+	client.Close()
+	if _, ok := next(wch); ok {
+		return false
+	}
+	if _, err := client.Get(ctx, "k"); err != context.Canceled {
+		return false
+	}
+
+	// Stopping the server cancels its watches
+	other, _ := New(Config{Endpoints: []string{server.Endpoint}})
+	defer other.Close()
+	wch = other.Watch(ctx, "k")
+	server.Close()
+	wr, ok := next(wch)
+	if !ok || !wr.Canceled || wr.Err() != ErrStopped {
+		return false
+	}
+	_, err := other.Put(ctx, "k", "v")
+	return err == ErrStopped
+}
+
+func testLeases() bool {
+	client, _ := newClient()
+	defer client.Close()
+
+	grant, err := client.Grant(ctx, 60)
+	if err != nil || grant.TTL != 60 || grant.ID == NoLease {
+		return false
+	}
+	client.Put(ctx, "/session/a", "1", WithLease(grant.ID))
+	client.Put(ctx, "/session/b", "2", WithLease(grant.ID))
+	client.Put(ctx, "/session/c", "3")
+
+	ttl, _ := client.TimeToLive(ctx, grant.ID, WithAttachedKeys())
+	if ttl.GrantedTTL != 60 || ttl.TTL < 59 || len(ttl.Keys) != 2 || string(ttl.Keys[0]) != "/session/a" {
+		return false
+	}
+	if get, _ := client.Get(ctx, "/session/a"); get.Kvs[0].Lease != int64(grant.ID) {
+		return false
+	}
+	resp, _ := client.Txn(ctx).If(Compare(LeaseValue("/session/a"), "=", grant.ID)).Commit()
+	if !resp.Succeeded {
+		return false
+	}
+
+	// Moving a key off the lease keeps its value
+	client.Put(ctx, "/session/b", "", WithIgnoreValue())
+	if get, _ := client.Get(ctx, "/session/b"); string(get.Kvs[0].Value) != "2" || get.Kvs[0].Lease != 0 {
+		return false
+	}
+
+	leases, _ := client.Leases(ctx)
+	if len(leases.Leases) != 1 || leases.Leases[0].ID != grant.ID {
+		return false
+	}
+
+	// Revoking deletes the attached keys at one revision
+	rev := client.server.Revision()
+	if _, err := client.Revoke(ctx, grant.ID); err != nil {
+		return false
+	}
+	if get, _ := client.Get(ctx, "/session/", WithPrefix()); keysOf(get) != "/session/b,/session/c" || get.Header.Revision != rev+1 {
+		return false
+	}
+	if ttl, _ := client.TimeToLive(ctx, grant.ID); ttl.TTL != -1 {
+		return false
+	}
+	if _, err := client.Revoke(ctx, grant.ID); err != ErrLeaseNotFound {
+		return false
+	}
+	_, err = client.KeepAliveOnce(ctx, grant.ID)
+	return err == ErrLeaseNotFound
+}
+
+func testLeaseExpiryAndKeepAlive() bool {
+	client, server := newClient()
+	defer client.Close()
+	server.SetTTLUnit(20 * time.Millisecond)
+
+	wch := client.Watch(ctx, "/ephemeral", WithPrefix())
+	short, _ := client.Grant(ctx, 2)
+	client.Put(ctx, "/ephemeral/short", "x", WithLease(short.ID))
+	kept, _ := client.Grant(ctx, 2)
+	client.Put(ctx, "/ephemeral/kept", "y", WithLease(kept.ID))
+
+	kctx, stop := context.WithCancel(ctx)
+	defer stop()
+	ch, err := client.KeepAlive(kctx, kept.ID)
+	if err != nil {
+		return false
+	}
+	if first := <-ch; first.ID != kept.ID || first.TTL != 2 {
+		return false
+	}
+
+	// The expired lease's key is deleted, with a watch event
+	var deleted string
+	for deleted == "" {
+		wr, ok := next(wch)
+		if !ok {
+			return false
+		}
+		for _, ev := range wr.Events {
+			if ev.Type == DELETE {
+				deleted = string(ev.Kv.Key)
+			}
+		}
+	}
+	if deleted != "/ephemeral/short" {
+		return false
+	}
+	time.Sleep(100 * time.Millisecond)
+	if get, _ := client.Get(ctx, "/ephemeral/kept"); len(get.Kvs) != 1 {
+		return false
+	}
+
+	// Once renewing stops, the other lease expires too and the
+	// keep-alive channel closes
+	stop()
+	if !eventually(func() bool {
+		get, _ := client.Get(ctx, "/ephemeral/", WithPrefix())
+		return get.Count == 0
+	}) {
+		return false
+	}
+	for range ch {
+	}
+	return true
+}
+
+func testServersAndClients() bool {
+	server := Listen("http://localhost:23790")
+	defer server.Close()
+	if server.Endpoint != "localhost:23790" {
+		return false
+	}
+
+	// Clients on the same server share its data
+	a, err := New(Config{Endpoints: []string{"unreachable:1", "http://localhost:23790"}, DialTimeout: time.Second})
+	if err != nil {
+		return false
+	}
+	defer a.Close()
+	b, _ := New(Config{Endpoints: []string{"localhost:23790"}})
+	defer b.Close()
+	a.Put(ctx, "shared", "yes")
+	if get, _ := b.Get(ctx, "shared"); string(get.Kvs[0].Value) != "yes" {
+		return false
+	}
+	if get, _ := b.Get(ctx, "shared"); get.Header.ClusterId == 0 || get.Header.MemberId == 0 {
+		return false
+	}
+	if len(a.Endpoints()) != 2 {
+		return false
+	}
+
+	if _, err := New(Config{}); err != ErrNoAvailableEndpoints {
+		return false
+	}
+	if _, err := New(Config{Endpoints: []string{"localhost:1"}}); err != context.DeadlineExceeded {
+		return false
+	}
+
+	// Canceled contexts fail operations
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := a.Get(cctx, "shared"); err != context.Canceled {
+		return false
+	}
+
+	// The interfaces clientv3 code depends on
+	var kv KV = NewKV(a)
+	var lease Lease = NewLease(a)
+	var watcher Watcher = NewWatcher(a)
+	return kv != nil && lease != nil && watcher != nil
+}
+
+func testServiceDiscovery() bool {
+	server := NewServer()
+	defer server.Close()
+	server.SetTTLUnit(20 * time.Millisecond)
+	kctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := NewKitClient(kctx, []string{server.Endpoint}, ClientOptions{DialTimeout: time.Second})
+	if err != nil {
+		return false
+	}
+	ch := make(chan struct{}, 16)
+	go client.WatchPrefix("/services/users/", ch)
+	if _, ok := <-ch; !ok {
+		return false
+	}
+
+	logger := &recordingLogger{}
+	registrar := NewRegistrar(client, Service{
+		Key:   "/services/users/10.0.0.1:8080",
+		Value: "10.0.0.1:8080",
+		TTL:   NewTTLOption(time.Second, 3*time.Second),
+	}, logger)
+	registrar.Register()
+	client.Register(Service{Key: "/services/users/10.0.0.2:8080", Value: "10.0.0.2:8080"})
+
+	// Each change signals the watcher
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	entries, err := client.GetEntries("/services/users/")
+	if err != nil || strings.Join(entries, ",") != "10.0.0.1:8080,10.0.0.2:8080" {
+		return false
+	}
+	if client.LeaseID() == 0 {
+		return false
+	}
+
+	// The registration outlives its TTL while the lease is kept alive
+	time.Sleep(150 * time.Millisecond)
+	if entries, _ := client.GetEntries("/services/users/"); len(entries) != 2 {
+		return false
+	}
+
+	registrar.Deregister()
+	if entries, _ := client.GetEntries("/services/users/"); strings.Join(entries, ",") != "10.0.0.2:8080" {
+		return false
+	}
+	if err := client.Register(Service{Key: "/services/x"}); err != ErrNoValue {
+		return false
+	}
+	if err := client.Register(Service{Value: "x"}); err != ErrNoKey {
+		return false
+	}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return len(logger.lines) == 2 && strings.Contains(logger.lines[0], "register") && strings.Contains(logger.lines[1], "deregister")
+}
+
+func testViperStore() bool {
+	client, _ := newClient()
+	defer client.Close()
+	store := NewViperStore(client)
+
+	if _, err := store.Get("/config/app.json"); err != ErrRemoteKeyNotFound {
+		return false
+	}
+	client.Put(ctx, "/config/app.json", `{"port": 8080}`)
+	data, err := store.Get("/config/app.json")
+	if err != nil || string(data) != `{"port": 8080}` {
+		return false
+	}
+
+	stop := make(chan struct{})
+	updates := store.Watch("/config/app.json", stop)
+	client.Put(ctx, "/config/app.json", `{"port": 9090}`)
+	client.Put(ctx, "/config/other.json", `{}`)
+	select {
+	case data := <-updates:
+		if string(data) != `{"port": 9090}` {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	close(stop)
+	for range updates {
+	}
+	return true
+}
+
+func testConcurrentAccess() bool {
+	client, _ := newClient()
+	defer client.Close()
+	client.Put(ctx, "counter", "0")
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := client.Watch(wctx, "counter")
+
+	// Increment with compare-and-swap retries
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				get, _ := client.Get(ctx, "counter")
+				kv := get.Kvs[0]
+				var n int
+				fmt.Sscan(string(kv.Value), &n)
+				resp, err := client.Txn(ctx).
+					If(Compare(ModRevision("counter"), "=", kv.ModRevision)).
+					Then(OpPut("counter", fmt.Sprint(n+1))).
+					Commit()
+				if err == nil && resp.Succeeded {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	get, _ := client.Get(ctx, "counter")
+	if string(get.Kvs[0].Value) != "20" || get.Kvs[0].Version != 21 {
+		return false
+	}
+
+	// Every increment reached the watch, in order
+	for i := 1; i <= 20; i++ {
+		wr, ok := next(wch)
+		if !ok || string(wr.Events[0].Kv.Value) != fmt.Sprint(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func main() {
+	fmt.Println("Running etcd Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Put And Get", testPutAndGet)
+	runTest("Range Queries", testRangeQueries)
+	runTest("Historical Reads", testHistoricalReads)
+	runTest("Transactions", testTransactions)
+	runTest("Transaction Validation", testTransactionValidation)
+	runTest("Watch", testWatch)
+	runTest("Watch From Revision", testWatchFromRevision)
+	runTest("Watch Cancellation", testWatchCancellation)
+	runTest("Leases", testLeases)
+	runTest("Lease Expiry And KeepAlive", testLeaseExpiryAndKeepAlive)
+	runTest("Servers And Clients", testServersAndClients)
+	runTest("Service Discovery", testServiceDiscovery)
+	runTest("Viper Store", testViperStore)
+	runTest("Concurrent Access", testConcurrentAccess)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
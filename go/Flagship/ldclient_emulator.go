@@ -0,0 +1,1639 @@
+package main
+
+// Developed by PowerShield, as an alternative to LaunchDarkly
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultContextKind is the kind of contexts built without Kind
+const DefaultContextKind = "user"
+
+// Contexts, as in the ldcontext package
+
+// Context is the user, device, organization or other entity a flag is
+// evaluated for
+type Context struct {
+	kind       string
+	key        string
+	name       string
+	anonymous  bool
+	attributes map[string]interface{}
+}
+
+// NewContext returns a user context with a key and no other attributes
+func NewContext(key string) Context {
+	return NewContextBuilder(key).Build()
+}
+
+// ContextBuilder builds a Context with attributes
+type ContextBuilder struct {
+	c Context
+}
+
+// NewContextBuilder returns a builder for a user context with key
+func NewContextBuilder(key string) *ContextBuilder {
+	return &ContextBuilder{c: Context{kind: DefaultContextKind, key: key, attributes: map[string]interface{}{}}}
+}
+
+// Kind sets the context's kind, such as "organization" or "device"
+func (b *ContextBuilder) Kind(kind string) *ContextBuilder {
+	b.c.kind = kind
+	return b
+}
+
+// Name sets the context's name
+func (b *ContextBuilder) Name(name string) *ContextBuilder {
+	b.c.name = name
+	return b
+}
+
+// Anonymous marks the context as anonymous
+func (b *ContextBuilder) Anonymous(anonymous bool) *ContextBuilder {
+	b.c.anonymous = anonymous
+	return b
+}
+
+// SetString sets a string attribute
+func (b *ContextBuilder) SetString(attr, value string) *ContextBuilder {
+	return b.SetValue(attr, value)
+}
+
+// SetInt sets a numeric attribute
+func (b *ContextBuilder) SetInt(attr string, value int) *ContextBuilder {
+	return b.SetValue(attr, float64(value))
+}
+
+// SetFloat64 sets a numeric attribute
+func (b *ContextBuilder) SetFloat64(attr string, value float64) *ContextBuilder {
+	return b.SetValue(attr, value)
+}
+
+// SetBool sets a boolean attribute
+func (b *ContextBuilder) SetBool(attr string, value bool) *ContextBuilder {
+	return b.SetValue(attr, value)
+}
+
+// SetValue sets an attribute to any JSON value, such as a []interface{}
+// of groups
+func (b *ContextBuilder) SetValue(attr string, value interface{}) *ContextBuilder {
+	b.c.attributes[attr] = normalizeValue(value)
+	return b
+}
+
+// Build returns the context
+func (b *ContextBuilder) Build() Context {
+	c := b.c
+	c.attributes = make(map[string]interface{}, len(b.c.attributes))
+	for k, v := range b.c.attributes {
+		c.attributes[k] = v
+	}
+	return c
+}
+
+// Key returns the context's key
+func (c Context) Key() string { return c.key }
+
+// Kind returns the context's kind
+func (c Context) Kind() string { return c.kind }
+
+// Name returns the context's name
+func (c Context) Name() string { return c.name }
+
+// Anonymous reports whether the context is anonymous
+func (c Context) Anonymous() bool { return c.anonymous }
+
+// Err returns why the context can't be evaluated, or nil
+func (c Context) Err() error {
+	if c.key == "" {
+		return errors.New("context key must not be empty")
+	}
+	if c.kind == "kind" || c.kind == "multi" {
+		return fmt.Errorf("%q is not a valid context kind", c.kind)
+	}
+	return nil
+}
+
+// GetValue returns an attribute, including the built-in key, kind, name
+// and anonymous
+func (c Context) GetValue(attr string) (interface{}, bool) {
+	switch attr {
+	case "key":
+		return c.key, true
+	case "kind":
+		return c.kind, true
+	case "name":
+		return c.name, c.name != ""
+	case "anonymous":
+		return c.anonymous, true
+	}
+	v, ok := c.attributes[attr]
+	return v, ok
+}
+
+// normalizeValue turns Go numbers into float64, as JSON decoding does
+func normalizeValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case []string:
+		values := make([]interface{}, len(n))
+		for i, s := range n {
+			values[i] = s
+		}
+		return values
+	}
+	return v
+}
+
+// Flag data, in LaunchDarkly's JSON format
+
+// FeatureFlag is a flag's full configuration
+type FeatureFlag struct {
+	Key           string             `json:"key"`
+	Version       int                `json:"version"`
+	On            bool               `json:"on"`
+	Variations    []interface{}      `json:"variations"`
+	OffVariation  *int               `json:"offVariation,omitempty"`
+	Fallthrough   VariationOrRollout `json:"fallthrough"`
+	Targets       []Target           `json:"targets,omitempty"`
+	Rules         []FlagRule         `json:"rules,omitempty"`
+	Prerequisites []Prerequisite     `json:"prerequisites,omitempty"`
+	Salt          string             `json:"salt,omitempty"`
+}
+
+// Target serves a variation to contexts with the listed keys
+type Target struct {
+	ContextKind string   `json:"contextKind,omitempty"`
+	Values      []string `json:"values"`
+	Variation   int      `json:"variation"`
+}
+
+// FlagRule serves a variation or rollout to contexts matching all its
+// clauses
+type FlagRule struct {
+	ID      string   `json:"id,omitempty"`
+	Clauses []Clause `json:"clauses"`
+	VariationOrRollout
+}
+
+// Clause tests one attribute of a context
+type Clause struct {
+	ContextKind string        `json:"contextKind,omitempty"`
+	Attribute   string        `json:"attribute"`
+	Op          string        `json:"op"`
+	Values      []interface{} `json:"values"`
+	Negate      bool          `json:"negate,omitempty"`
+}
+
+// Clause operators
+const (
+	OpIn                 = "in"
+	OpStartsWith         = "startsWith"
+	OpEndsWith           = "endsWith"
+	OpContains           = "contains"
+	OpMatches            = "matches"
+	OpLessThan           = "lessThan"
+	OpLessThanOrEqual    = "lessThanOrEqual"
+	OpGreaterThan        = "greaterThan"
+	OpGreaterThanOrEqual = "greaterThanOrEqual"
+	OpBefore             = "before"
+	OpAfter              = "after"
+	OpSegmentMatch       = "segmentMatch"
+)
+
+// VariationOrRollout serves one variation, or splits contexts between
+// variations by weight
+type VariationOrRollout struct {
+	Variation *int     `json:"variation,omitempty"`
+	Rollout   *Rollout `json:"rollout,omitempty"`
+}
+
+// Rollout splits contexts between variations. Weights are in thousandths
+// of a percent and should add up to 100000.
+type Rollout struct {
+	Variations []WeightedVariation `json:"variations"`
+	// BucketBy is the attribute contexts are split by; the default is key
+	BucketBy string `json:"bucketBy,omitempty"`
+	// Seed replaces the flag key and salt in the hash, so that several
+	// flags can split contexts the same way
+	Seed        *int   `json:"seed,omitempty"`
+	ContextKind string `json:"contextKind,omitempty"`
+}
+
+// WeightedVariation is one variation's share of a rollout
+type WeightedVariation struct {
+	Variation int `json:"variation"`
+	Weight    int `json:"weight"`
+}
+
+// Prerequisite requires another flag to be on and serving a variation
+type Prerequisite struct {
+	Key       string `json:"key"`
+	Variation int    `json:"variation"`
+}
+
+// Segment is a reusable set of contexts, used by segmentMatch clauses
+type Segment struct {
+	Key      string        `json:"key"`
+	Version  int           `json:"version"`
+	Included []string      `json:"included,omitempty"`
+	Excluded []string      `json:"excluded,omitempty"`
+	Rules    []SegmentRule `json:"rules,omitempty"`
+}
+
+// SegmentRule includes contexts matching all its clauses
+type SegmentRule struct {
+	ID      string   `json:"id,omitempty"`
+	Clauses []Clause `json:"clauses"`
+}
+
+// Int returns a pointer to n, for OffVariation and Variation fields
+func Int(n int) *int { return &n }
+
+// Evaluation results, as in the ldreason package
+
+// Reason kinds
+const (
+	ReasonOff                = "OFF"
+	ReasonFallthrough        = "FALLTHROUGH"
+	ReasonTargetMatch        = "TARGET_MATCH"
+	ReasonRuleMatch          = "RULE_MATCH"
+	ReasonPrerequisiteFailed = "PREREQUISITE_FAILED"
+	ReasonError              = "ERROR"
+)
+
+// Error kinds
+const (
+	ErrorClientNotReady   = "CLIENT_NOT_READY"
+	ErrorFlagNotFound     = "FLAG_NOT_FOUND"
+	ErrorMalformedFlag    = "MALFORMED_FLAG"
+	ErrorUserNotSpecified = "USER_NOT_SPECIFIED"
+	ErrorWrongType        = "WRONG_TYPE"
+)
+
+// EvaluationReason explains why a flag gave its value
+type EvaluationReason struct {
+	Kind            string `json:"kind"`
+	RuleIndex       int    `json:"ruleIndex,omitempty"`
+	RuleID          string `json:"ruleId,omitempty"`
+	PrerequisiteKey string `json:"prerequisiteKey,omitempty"`
+	ErrorKind       string `json:"errorKind,omitempty"`
+	// InExperiment is set when a rollout chose the variation
+	InExperiment bool `json:"inExperiment,omitempty"`
+}
+
+// String returns the reason as, for example, RULE_MATCH(1,beta-testers)
+func (r EvaluationReason) String() string {
+	switch r.Kind {
+	case ReasonRuleMatch:
+		return fmt.Sprintf("%s(%d,%s)", r.Kind, r.RuleIndex, r.RuleID)
+	case ReasonPrerequisiteFailed:
+		return fmt.Sprintf("%s(%s)", r.Kind, r.PrerequisiteKey)
+	case ReasonError:
+		return fmt.Sprintf("%s(%s)", r.Kind, r.ErrorKind)
+	}
+	return r.Kind
+}
+
+// EvaluationDetail is a flag's value, which variation it is, and why
+type EvaluationDetail struct {
+	Value interface{}
+	// VariationIndex is -1 when the default value was returned
+	VariationIndex int
+	Reason         EvaluationReason
+}
+
+// IsDefaultValue reports whether the caller's default value was returned
+func (d EvaluationDetail) IsDefaultValue() bool { return d.VariationIndex < 0 }
+
+// Evaluation
+
+// dataStore holds flags and segments and tells listeners what changed
+type dataStore struct {
+	mu       sync.RWMutex
+	flags    map[string]*FeatureFlag
+	segments map[string]*Segment
+	inited   bool
+	onChange func(keys []string)
+}
+
+// DataSourceUpdates receives flag data from a DataSource
+type DataSourceUpdates interface {
+	// Init replaces all flags and segments
+	Init(flags []FeatureFlag, segments []Segment)
+	// UpsertFlag adds or replaces a flag, if its version is newer
+	UpsertFlag(flag FeatureFlag)
+	// UpsertSegment adds or replaces a segment, if its version is newer
+	UpsertSegment(segment Segment)
+}
+
+func (s *dataStore) Init(flags []FeatureFlag, segments []Segment) {
+	s.mu.Lock()
+	old := s.flags
+	oldSegments := s.segments
+	s.flags = make(map[string]*FeatureFlag, len(flags))
+	for i := range flags {
+		f := flags[i]
+		s.flags[f.Key] = &f
+	}
+	s.segments = make(map[string]*Segment, len(segments))
+	for i := range segments {
+		seg := segments[i]
+		s.segments[seg.Key] = &seg
+	}
+	s.inited = true
+
+	changed := map[string]bool{}
+	for key, f := range s.flags {
+		if !reflect.DeepEqual(old[key], f) {
+			changed[key] = true
+		}
+	}
+	for key := range old {
+		if s.flags[key] == nil {
+			changed[key] = true
+		}
+	}
+	var changedSegments []string
+	for key, seg := range s.segments {
+		if !reflect.DeepEqual(oldSegments[key], seg) {
+			changedSegments = append(changedSegments, key)
+		}
+	}
+	for key := range oldSegments {
+		if s.segments[key] == nil {
+			changedSegments = append(changedSegments, key)
+		}
+	}
+	keys := s.affectedLocked(changed, changedSegments)
+	s.mu.Unlock()
+	s.notify(keys)
+}
+
+func (s *dataStore) UpsertFlag(flag FeatureFlag) {
+	s.mu.Lock()
+	if old := s.flags[flag.Key]; old != nil && old.Version >= flag.Version {
+		s.mu.Unlock()
+		return
+	}
+	if s.flags == nil {
+		s.flags = map[string]*FeatureFlag{}
+	}
+	s.flags[flag.Key] = &flag
+	keys := s.affectedLocked(map[string]bool{flag.Key: true}, nil)
+	s.mu.Unlock()
+	s.notify(keys)
+}
+
+func (s *dataStore) UpsertSegment(segment Segment) {
+	s.mu.Lock()
+	if old := s.segments[segment.Key]; old != nil && old.Version >= segment.Version {
+		s.mu.Unlock()
+		return
+	}
+	if s.segments == nil {
+		s.segments = map[string]*Segment{}
+	}
+	s.segments[segment.Key] = &segment
+	keys := s.affectedLocked(map[string]bool{}, []string{segment.Key})
+	s.mu.Unlock()
+	s.notify(keys)
+}
+
+// affectedLocked adds the flags using changed segments, then the flags
+// with changed flags as prerequisites, until nothing more is added
+func (s *dataStore) affectedLocked(changed map[string]bool, segments []string) []string {
+	for _, f := range s.flags {
+		for _, r := range f.Rules {
+			for _, c := range r.Clauses {
+				if c.Op != OpSegmentMatch {
+					continue
+				}
+				for _, v := range c.Values {
+					for _, seg := range segments {
+						if v == seg {
+							changed[f.Key] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	for added := true; added; {
+		added = false
+		for _, f := range s.flags {
+			if changed[f.Key] {
+				continue
+			}
+			for _, p := range f.Prerequisites {
+				if changed[p.Key] {
+					changed[f.Key] = true
+					added = true
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(changed))
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *dataStore) notify(keys []string) {
+	if len(keys) > 0 && s.onChange != nil {
+		s.onChange(keys)
+	}
+}
+
+func (s *dataStore) flag(key string) *FeatureFlag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.flags[key]
+}
+
+func (s *dataStore) segment(key string) *Segment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.segments[key]
+}
+
+func (s *dataStore) flagKeys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.flags))
+	for key := range s.flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// evaluator evaluates one flag, following prerequisites
+type evaluator struct {
+	store   *dataStore
+	visited map[string]bool
+}
+
+func (e *evaluator) evaluate(f *FeatureFlag, ctx Context) EvaluationDetail {
+	if e.visited[f.Key] {
+		return errorDetail(ErrorMalformedFlag)
+	}
+	e.visited[f.Key] = true
+	defer delete(e.visited, f.Key)
+
+	if !f.On {
+		return e.offDetail(f, EvaluationReason{Kind: ReasonOff})
+	}
+	for _, p := range f.Prerequisites {
+		pf := e.store.flag(p.Key)
+		if pf == nil {
+			return e.offDetail(f, EvaluationReason{Kind: ReasonPrerequisiteFailed, PrerequisiteKey: p.Key})
+		}
+		result := e.evaluate(pf, ctx)
+		if result.Reason.Kind == ReasonError && result.Reason.ErrorKind == ErrorMalformedFlag {
+			return result
+		}
+		if !pf.On || result.VariationIndex != p.Variation {
+			return e.offDetail(f, EvaluationReason{Kind: ReasonPrerequisiteFailed, PrerequisiteKey: p.Key})
+		}
+	}
+	for _, t := range f.Targets {
+		if contextKindOf(t.ContextKind) != ctx.kind {
+			continue
+		}
+		for _, key := range t.Values {
+			if key == ctx.key {
+				return variationDetail(f, t.Variation, EvaluationReason{Kind: ReasonTargetMatch})
+			}
+		}
+	}
+	for i, r := range f.Rules {
+		if e.clausesMatch(r.Clauses, ctx) {
+			return e.serve(f, r.VariationOrRollout, ctx, EvaluationReason{Kind: ReasonRuleMatch, RuleIndex: i, RuleID: r.ID})
+		}
+	}
+	return e.serve(f, f.Fallthrough, ctx, EvaluationReason{Kind: ReasonFallthrough})
+}
+
+func (e *evaluator) offDetail(f *FeatureFlag, reason EvaluationReason) EvaluationDetail {
+	if f.OffVariation == nil {
+		return EvaluationDetail{VariationIndex: -1, Reason: reason}
+	}
+	return variationDetail(f, *f.OffVariation, reason)
+}
+
+func (e *evaluator) serve(f *FeatureFlag, vr VariationOrRollout, ctx Context, reason EvaluationReason) EvaluationDetail {
+	if vr.Variation != nil {
+		return variationDetail(f, *vr.Variation, reason)
+	}
+	if vr.Rollout == nil || len(vr.Rollout.Variations) == 0 {
+		return errorDetail(ErrorMalformedFlag)
+	}
+	reason.InExperiment = true
+	r := vr.Rollout
+	bucket := bucketContext(ctx, f.Key, f.Salt, r)
+	sum := 0.0
+	for _, wv := range r.Variations {
+		sum += float64(wv.Weight) / 100000
+		if bucket < sum {
+			return variationDetail(f, wv.Variation, reason)
+		}
+	}
+	// Weights adding up to less than 100000 leave the rest to the last
+	// variation
+	return variationDetail(f, r.Variations[len(r.Variations)-1].Variation, reason)
+}
+
+// bucketContext returns a number in [0, 1) that is the same every time
+// for a context, flag and salt, so a context stays in its rollout bucket
+func bucketContext(ctx Context, flagKey, salt string, r *Rollout) float64 {
+	if contextKindOf(r.ContextKind) != ctx.kind {
+		return 0
+	}
+	bucketBy := r.BucketBy
+	if bucketBy == "" {
+		bucketBy = "key"
+	}
+	value, ok := ctx.GetValue(bucketBy)
+	if !ok {
+		return 0
+	}
+	var id string
+	switch v := value.(type) {
+	case string:
+		id = v
+	case float64:
+		if v != float64(int64(v)) {
+			return 0
+		}
+		id = strconv.FormatInt(int64(v), 10)
+	default:
+		return 0
+	}
+
+	prefix := flagKey + "." + salt
+	if r.Seed != nil {
+		prefix = strconv.Itoa(*r.Seed)
+	}
+	sum := sha1.Sum([]byte(prefix + "." + id))
+	n, _ := strconv.ParseInt(hex.EncodeToString(sum[:])[:15], 16, 64)
+	return float64(n) / float64(0xFFFFFFFFFFFFFFF)
+}
+
+func variationDetail(f *FeatureFlag, index int, reason EvaluationReason) EvaluationDetail {
+	if index < 0 || index >= len(f.Variations) {
+		return errorDetail(ErrorMalformedFlag)
+	}
+	return EvaluationDetail{Value: f.Variations[index], VariationIndex: index, Reason: reason}
+}
+
+func errorDetail(errorKind string) EvaluationDetail {
+	return EvaluationDetail{VariationIndex: -1, Reason: EvaluationReason{Kind: ReasonError, ErrorKind: errorKind}}
+}
+
+func contextKindOf(kind string) string {
+	if kind == "" {
+		return DefaultContextKind
+	}
+	return kind
+}
+
+func (e *evaluator) clausesMatch(clauses []Clause, ctx Context) bool {
+	for _, c := range clauses {
+		if !e.clauseMatches(c, ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *evaluator) clauseMatches(c Clause, ctx Context) bool {
+	if c.Op == OpSegmentMatch {
+		matched := false
+		for _, v := range c.Values {
+			if key, ok := v.(string); ok && e.segmentMatches(key, ctx) {
+				matched = true
+				break
+			}
+		}
+		return matched != c.Negate
+	}
+	if contextKindOf(c.ContextKind) != ctx.kind && c.Attribute != "kind" {
+		return false
+	}
+	value, ok := ctx.GetValue(c.Attribute)
+	if !ok {
+		return false
+	}
+	values := []interface{}{value}
+	if list, isList := value.([]interface{}); isList {
+		values = list
+	}
+	matched := false
+	for _, v := range values {
+		if matchAny(c.Op, v, c.Values) {
+			matched = true
+			break
+		}
+	}
+	return matched != c.Negate
+}
+
+func (e *evaluator) segmentMatches(key string, ctx Context) bool {
+	seg := e.store.segment(key)
+	if seg == nil {
+		return false
+	}
+	for _, k := range seg.Included {
+		if k == ctx.key {
+			return true
+		}
+	}
+	for _, k := range seg.Excluded {
+		if k == ctx.key {
+			return false
+		}
+	}
+	for _, r := range seg.Rules {
+		if e.clausesMatch(r.Clauses, ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(op string, value interface{}, clauseValues []interface{}) bool {
+	for _, cv := range clauseValues {
+		if matchOp(op, value, cv) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOp(op string, value, clauseValue interface{}) bool {
+	switch op {
+	case OpIn:
+		return reflect.DeepEqual(normalizeValue(value), normalizeValue(clauseValue))
+	case OpStartsWith, OpEndsWith, OpContains, OpMatches:
+		s, ok1 := value.(string)
+		cs, ok2 := clauseValue.(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		switch op {
+		case OpStartsWith:
+			return strings.HasPrefix(s, cs)
+		case OpEndsWith:
+			return strings.HasSuffix(s, cs)
+		case OpContains:
+			return strings.Contains(s, cs)
+		}
+		re, err := regexp.Compile(cs)
+		return err == nil && re.MatchString(s)
+	case OpLessThan, OpLessThanOrEqual, OpGreaterThan, OpGreaterThanOrEqual:
+		n, ok1 := normalizeValue(value).(float64)
+		cn, ok2 := normalizeValue(clauseValue).(float64)
+		if !ok1 || !ok2 {
+			return false
+		}
+		switch op {
+		case OpLessThan:
+			return n < cn
+		case OpLessThanOrEqual:
+			return n <= cn
+		case OpGreaterThan:
+			return n > cn
+		}
+		return n >= cn
+	case OpBefore, OpAfter:
+		t, ok1 := toTime(value)
+		ct, ok2 := toTime(clauseValue)
+		if !ok1 || !ok2 {
+			return false
+		}
+		if op == OpBefore {
+			return t.Before(ct)
+		}
+		return t.After(ct)
+	}
+	return false
+}
+
+// toTime reads a date as milliseconds since the epoch or RFC 3339
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := normalizeValue(v).(type) {
+	case float64:
+		return time.UnixMilli(int64(t)), true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+// Clients, as in the ld package
+
+// DataSource supplies flag data to a client
+type DataSource interface {
+	// Start sends the current data to updates, and later changes
+	Start(updates DataSourceUpdates) error
+	Close() error
+}
+
+// Config configures a client
+type Config struct {
+	// DataSource supplies the flags, such as TestData or a
+	// FileDataSource. Without one, every flag gives its default value.
+	DataSource DataSource
+	// Offline makes every flag give its default value, without errors
+	Offline bool
+}
+
+// ErrInitializationFailed is returned when a client's data source fails
+// to start; the client still works, giving default values
+var ErrInitializationFailed = errors.New("LaunchDarkly client initialization failed")
+
+// ErrClientNotInitialized is returned by evaluations before data arrives
+var ErrClientNotInitialized = errors.New("feature flag evaluation called before LaunchDarkly client initialization completed")
+
+// LDClient evaluates flags for contexts
+type LDClient struct {
+	config  Config
+	store   *dataStore
+	tracker *flagTracker
+}
+
+// MakeCustomClient returns a client started from config. The SDK key is
+// not checked. waitFor is accepted for compatibility; data sources in the
+// emulator start at once.
+func MakeCustomClient(sdkKey string, config Config, waitFor time.Duration) (*LDClient, error) {
+	client := &LDClient{config: config, store: &dataStore{}}
+	client.tracker = newFlagTracker(client)
+	client.store.onChange = client.tracker.flagsChanged
+	if config.Offline || config.DataSource == nil {
+		client.store.Init(nil, nil)
+		return client, nil
+	}
+	if err := config.DataSource.Start(client.store); err != nil {
+		return client, fmt.Errorf("%w: %v", ErrInitializationFailed, err)
+	}
+	return client, nil
+}
+
+// Initialized reports whether the client has flag data
+func (c *LDClient) Initialized() bool {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	return c.store.inited
+}
+
+// IsOffline reports whether the client was configured offline
+func (c *LDClient) IsOffline() bool { return c.config.Offline }
+
+// Close stops the client's data source
+func (c *LDClient) Close() error {
+	c.tracker.close()
+	if c.config.DataSource != nil && !c.config.Offline {
+		return c.config.DataSource.Close()
+	}
+	return nil
+}
+
+// variation evaluates a flag, returning defaultValue on errors and when
+// the result isn't accepted by check
+func (c *LDClient) variation(key string, ctx Context, defaultValue interface{}, check func(interface{}) bool) (EvaluationDetail, error) {
+	if c.config.Offline {
+		return EvaluationDetail{Value: defaultValue, VariationIndex: -1, Reason: EvaluationReason{Kind: ReasonError, ErrorKind: ErrorClientNotReady}}, nil
+	}
+	fail := func(errorKind string, err error) (EvaluationDetail, error) {
+		detail := errorDetail(errorKind)
+		detail.Value = defaultValue
+		return detail, err
+	}
+	if !c.Initialized() {
+		return fail(ErrorClientNotReady, ErrClientNotInitialized)
+	}
+	if err := ctx.Err(); err != nil {
+		return fail(ErrorUserNotSpecified, err)
+	}
+	f := c.store.flag(key)
+	if f == nil {
+		return fail(ErrorFlagNotFound, fmt.Errorf("unknown feature key: %s. Verify that this feature key exists. Returning default value", key))
+	}
+
+	detail := (&evaluator{store: c.store, visited: map[string]bool{}}).evaluate(f, ctx)
+	if detail.Reason.Kind == ReasonError {
+		detail.Value = defaultValue
+		return detail, fmt.Errorf("flag %q could not be evaluated: %s", key, detail.Reason.ErrorKind)
+	}
+	if detail.VariationIndex < 0 {
+		detail.Value = defaultValue
+		return detail, nil
+	}
+	if !check(detail.Value) {
+		return fail(ErrorWrongType, fmt.Errorf("flag %q has a value of the wrong type", key))
+	}
+	return detail, nil
+}
+
+func isBool(v interface{}) bool   { _, ok := v.(bool); return ok }
+func isString(v interface{}) bool { _, ok := v.(string); return ok }
+func isNumber(v interface{}) bool { _, ok := v.(float64); return ok }
+func isAnyValue(interface{}) bool { return true }
+
+// BoolVariation returns a boolean flag's value for ctx
+func (c *LDClient) BoolVariation(key string, ctx Context, defaultVal bool) (bool, error) {
+	value, _, err := c.BoolVariationDetail(key, ctx, defaultVal)
+	return value, err
+}
+
+// BoolVariationDetail is BoolVariation with an explanation
+func (c *LDClient) BoolVariationDetail(key string, ctx Context, defaultVal bool) (bool, EvaluationDetail, error) {
+	detail, err := c.variation(key, ctx, defaultVal, isBool)
+	return detail.Value.(bool), detail, err
+}
+
+// StringVariation returns a string flag's value for ctx
+func (c *LDClient) StringVariation(key string, ctx Context, defaultVal string) (string, error) {
+	value, _, err := c.StringVariationDetail(key, ctx, defaultVal)
+	return value, err
+}
+
+// StringVariationDetail is StringVariation with an explanation
+func (c *LDClient) StringVariationDetail(key string, ctx Context, defaultVal string) (string, EvaluationDetail, error) {
+	detail, err := c.variation(key, ctx, defaultVal, isString)
+	return detail.Value.(string), detail, err
+}
+
+// IntVariation returns a numeric flag's value for ctx, truncated
+func (c *LDClient) IntVariation(key string, ctx Context, defaultVal int) (int, error) {
+	value, _, err := c.IntVariationDetail(key, ctx, defaultVal)
+	return value, err
+}
+
+// IntVariationDetail is IntVariation with an explanation
+func (c *LDClient) IntVariationDetail(key string, ctx Context, defaultVal int) (int, EvaluationDetail, error) {
+	detail, err := c.variation(key, ctx, float64(defaultVal), isNumber)
+	value := int(detail.Value.(float64))
+	detail.Value = value
+	return value, detail, err
+}
+
+// Float64Variation returns a numeric flag's value for ctx
+func (c *LDClient) Float64Variation(key string, ctx Context, defaultVal float64) (float64, error) {
+	value, _, err := c.Float64VariationDetail(key, ctx, defaultVal)
+	return value, err
+}
+
+// Float64VariationDetail is Float64Variation with an explanation
+func (c *LDClient) Float64VariationDetail(key string, ctx Context, defaultVal float64) (float64, EvaluationDetail, error) {
+	detail, err := c.variation(key, ctx, defaultVal, isNumber)
+	return detail.Value.(float64), detail, err
+}
+
+// JSONVariation returns a flag's value of any type for ctx, as decoded
+// from JSON: a map, slice, string, float64, bool or nil
+func (c *LDClient) JSONVariation(key string, ctx Context, defaultVal interface{}) (interface{}, error) {
+	value, _, err := c.JSONVariationDetail(key, ctx, defaultVal)
+	return value, err
+}
+
+// JSONVariationDetail is JSONVariation with an explanation
+func (c *LDClient) JSONVariationDetail(key string, ctx Context, defaultVal interface{}) (interface{}, EvaluationDetail, error) {
+	detail, err := c.variation(key, ctx, defaultVal, isAnyValue)
+	return detail.Value, detail, err
+}
+
+// FeatureFlagsState is every flag's value for one context, as front ends
+// bootstrapped by a server need
+type FeatureFlagsState struct {
+	values  map[string]interface{}
+	details map[string]EvaluationDetail
+	valid   bool
+}
+
+// AllFlagsState evaluates every flag for ctx
+func (c *LDClient) AllFlagsState(ctx Context) FeatureFlagsState {
+	state := FeatureFlagsState{values: map[string]interface{}{}, details: map[string]EvaluationDetail{}}
+	if c.config.Offline || !c.Initialized() || ctx.Err() != nil {
+		return state
+	}
+	state.valid = true
+	for _, key := range c.store.flagKeys() {
+		f := c.store.flag(key)
+		detail := (&evaluator{store: c.store, visited: map[string]bool{}}).evaluate(f, ctx)
+		state.values[key] = detail.Value
+		state.details[key] = detail
+	}
+	return state
+}
+
+// IsValid reports whether the flags could be evaluated
+func (s FeatureFlagsState) IsValid() bool { return s.valid }
+
+// GetFlagValue returns one flag's value, or nil
+func (s FeatureFlagsState) GetFlagValue(key string) interface{} { return s.values[key] }
+
+// GetFlagDetail returns one flag's evaluation detail
+func (s FeatureFlagsState) GetFlagDetail(key string) (EvaluationDetail, bool) {
+	d, ok := s.details[key]
+	return d, ok
+}
+
+// ToValuesMap returns every flag's value by key
+func (s FeatureFlagsState) ToValuesMap() map[string]interface{} {
+	values := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+
+// Change listeners, as in the interfaces package
+
+// FlagChangeEvent reports that a flag's configuration may have changed,
+// including through one of its prerequisites or segments
+type FlagChangeEvent struct {
+	Key string
+}
+
+// FlagValueChangeEvent reports that a flag's value changed for a context
+type FlagValueChangeEvent struct {
+	Key      string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// FlagTracker tells listeners when flags change
+type FlagTracker interface {
+	// OnFlagChange calls fn for each changed flag, after the change, until
+	// the returned function is called
+	OnFlagChange(fn func(FlagChangeEvent)) (remove func())
+	// OnFlagValueChange calls fn when the flag's value for ctx changes
+	OnFlagValueChange(flagKey string, ctx Context, defaultValue interface{}, fn func(FlagValueChangeEvent)) (remove func())
+	// AddFlagChangeListener returns a channel receiving FlagChangeEvents,
+	// as the SDK does. Events queue without limit until read.
+	AddFlagChangeListener() <-chan FlagChangeEvent
+	RemoveFlagChangeListener(listener <-chan FlagChangeEvent)
+	// AddFlagValueChangeListener returns a channel receiving
+	// FlagValueChangeEvents for one flag and context
+	AddFlagValueChangeListener(flagKey string, ctx Context, defaultValue interface{}) <-chan FlagValueChangeEvent
+	RemoveFlagValueChangeListener(listener <-chan FlagValueChangeEvent)
+}
+
+// GetFlagTracker returns the client's flag tracker
+func (c *LDClient) GetFlagTracker() FlagTracker { return c.tracker }
+
+type flagTracker struct {
+	client *LDClient
+
+	mu        sync.Mutex
+	nextID    int
+	listeners map[int]func(FlagChangeEvent)
+	channels  map[interface{}]func()
+}
+
+func newFlagTracker(client *LDClient) *flagTracker {
+	return &flagTracker{client: client, listeners: map[int]func(FlagChangeEvent){}, channels: map[interface{}]func(){}}
+}
+
+func (t *flagTracker) flagsChanged(keys []string) {
+	t.mu.Lock()
+	ids := make([]int, 0, len(t.listeners))
+	for id := range t.listeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	listeners := make([]func(FlagChangeEvent), len(ids))
+	for i, id := range ids {
+		listeners[i] = t.listeners[id]
+	}
+	t.mu.Unlock()
+
+	for _, key := range keys {
+		for _, fn := range listeners {
+			fn(FlagChangeEvent{Key: key})
+		}
+	}
+}
+
+func (t *flagTracker) OnFlagChange(fn func(FlagChangeEvent)) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := t.nextID
+	t.nextID++
+	t.listeners[id] = fn
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.listeners, id)
+	}
+}
+
+func (t *flagTracker) OnFlagValueChange(flagKey string, ctx Context, defaultValue interface{}, fn func(FlagValueChangeEvent)) func() {
+	var mu sync.Mutex
+	current, _ := t.client.JSONVariation(flagKey, ctx, defaultValue)
+	return t.OnFlagChange(func(e FlagChangeEvent) {
+		if e.Key != flagKey {
+			return
+		}
+		value, _ := t.client.JSONVariation(flagKey, ctx, defaultValue)
+		mu.Lock()
+		old := current
+		changed := !reflect.DeepEqual(old, value)
+		current = value
+		mu.Unlock()
+		if changed {
+			fn(FlagValueChangeEvent{Key: flagKey, OldValue: old, NewValue: value})
+		}
+	})
+}
+
+func (t *flagTracker) AddFlagChangeListener() <-chan FlagChangeEvent {
+	ch, push, stop := newEventQueue[FlagChangeEvent]()
+	remove := t.OnFlagChange(push)
+	t.addChannel(ch, func() { remove(); stop() })
+	return ch
+}
+
+func (t *flagTracker) RemoveFlagChangeListener(listener <-chan FlagChangeEvent) {
+	t.removeChannel(listener)
+}
+
+func (t *flagTracker) AddFlagValueChangeListener(flagKey string, ctx Context, defaultValue interface{}) <-chan FlagValueChangeEvent {
+	ch, push, stop := newEventQueue[FlagValueChangeEvent]()
+	remove := t.OnFlagValueChange(flagKey, ctx, defaultValue, push)
+	t.addChannel(ch, func() { remove(); stop() })
+	return ch
+}
+
+func (t *flagTracker) RemoveFlagValueChangeListener(listener <-chan FlagValueChangeEvent) {
+	t.removeChannel(listener)
+}
+
+func (t *flagTracker) addChannel(ch interface{}, stop func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channels[ch] = stop
+}
+
+func (t *flagTracker) removeChannel(ch interface{}) {
+	t.mu.Lock()
+	stop, ok := t.channels[ch]
+	delete(t.channels, ch)
+	t.mu.Unlock()
+	if ok {
+		stop()
+	}
+}
+
+func (t *flagTracker) close() {
+	t.mu.Lock()
+	stops := make([]func(), 0, len(t.channels))
+	for ch, stop := range t.channels {
+		stops = append(stops, stop)
+		delete(t.channels, ch)
+	}
+	t.mu.Unlock()
+	for _, stop := range stops {
+		stop()
+	}
+}
+
+// newEventQueue returns a channel fed by push without blocking the caller,
+// and a stop function closing it once queued events are delivered
+func newEventQueue[E any]() (<-chan E, func(E), func()) {
+	out := make(chan E)
+	var mu sync.Mutex
+	var queue []E
+	stopped := false
+	wake := make(chan struct{}, 1)
+
+	go func() {
+		defer close(out)
+		for {
+			mu.Lock()
+			if len(queue) == 0 {
+				done := stopped
+				mu.Unlock()
+				if done {
+					return
+				}
+				<-wake
+				continue
+			}
+			next := queue[0]
+			queue = queue[1:]
+			mu.Unlock()
+			out <- next
+		}
+	}()
+
+	signal := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	push := func(e E) {
+		mu.Lock()
+		if !stopped {
+			queue = append(queue, e)
+		}
+		mu.Unlock()
+		signal()
+	}
+	stop := func() {
+		mu.Lock()
+		stopped = true
+		mu.Unlock()
+		signal()
+	}
+	return out, push, stop
+}
+
+// Test data, as in the ldtestdata package
+
+// TestData is an in-memory data source whose flags tests set directly.
+// Updates reach every client using it at once.
+type TestData struct {
+	mu       sync.Mutex
+	flags    map[string]FeatureFlag
+	segments map[string]Segment
+	builders map[string]*FlagBuilder
+	clients  []DataSourceUpdates
+}
+
+// NewTestData returns a data source with no flags
+func NewTestData() *TestData {
+	return &TestData{flags: map[string]FeatureFlag{}, segments: map[string]Segment{}, builders: map[string]*FlagBuilder{}}
+}
+
+// Start implements DataSource
+func (td *TestData) Start(updates DataSourceUpdates) error {
+	td.mu.Lock()
+	td.clients = append(td.clients, updates)
+	flags := make([]FeatureFlag, 0, len(td.flags))
+	for _, f := range td.flags {
+		flags = append(flags, f)
+	}
+	segments := make([]Segment, 0, len(td.segments))
+	for _, s := range td.segments {
+		segments = append(segments, s)
+	}
+	td.mu.Unlock()
+	updates.Init(flags, segments)
+	return nil
+}
+
+// Close implements DataSource
+func (td *TestData) Close() error { return nil }
+
+// Flag returns a builder for a flag: a copy of the last one given to
+// Update for key, or a new boolean flag serving true to everyone
+func (td *TestData) Flag(key string) *FlagBuilder {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	if b, ok := td.builders[key]; ok {
+		return b.copy()
+	}
+	return newFlagBuilder(key).BooleanFlag()
+}
+
+// Update stores the flag a builder describes and sends it to clients
+func (td *TestData) Update(b *FlagBuilder) *TestData {
+	td.mu.Lock()
+	f := b.build()
+	f.Version = td.flags[f.Key].Version + 1
+	td.flags[f.Key] = f
+	td.builders[f.Key] = b.copy()
+	clients := append([]DataSourceUpdates(nil), td.clients...)
+	td.mu.Unlock()
+	for _, c := range clients {
+		c.UpsertFlag(f)
+	}
+	return td
+}
+
+// UsePreconfiguredFlag stores a flag in full, such as one with a
+// percentage rollout, and sends it to clients
+func (td *TestData) UsePreconfiguredFlag(f FeatureFlag) *TestData {
+	td.mu.Lock()
+	if f.Version <= td.flags[f.Key].Version {
+		f.Version = td.flags[f.Key].Version + 1
+	}
+	td.flags[f.Key] = f
+	delete(td.builders, f.Key)
+	clients := append([]DataSourceUpdates(nil), td.clients...)
+	td.mu.Unlock()
+	for _, c := range clients {
+		c.UpsertFlag(f)
+	}
+	return td
+}
+
+// UsePreconfiguredSegment stores a segment and sends it to clients
+func (td *TestData) UsePreconfiguredSegment(s Segment) *TestData {
+	td.mu.Lock()
+	if s.Version <= td.segments[s.Key].Version {
+		s.Version = td.segments[s.Key].Version + 1
+	}
+	td.segments[s.Key] = s
+	clients := append([]DataSourceUpdates(nil), td.clients...)
+	td.mu.Unlock()
+	for _, c := range clients {
+		c.UpsertSegment(s)
+	}
+	return td
+}
+
+// Boolean flags have variations true and false, in that order
+const (
+	trueVariation  = 0
+	falseVariation = 1
+)
+
+func boolVariation(value bool) int {
+	if value {
+		return trueVariation
+	}
+	return falseVariation
+}
+
+// FlagBuilder describes a flag for TestData.Update
+type FlagBuilder struct {
+	key                  string
+	on                   bool
+	variations           []interface{}
+	offVariation         int
+	fallthroughVariation int
+	targets              map[string]map[string]int // kind, key, variation
+	rules                []*RuleBuilder
+}
+
+func newFlagBuilder(key string) *FlagBuilder {
+	return &FlagBuilder{key: key, on: true, targets: map[string]map[string]int{}}
+}
+
+func (b *FlagBuilder) copy() *FlagBuilder {
+	c := *b
+	c.variations = append([]interface{}(nil), b.variations...)
+	c.targets = map[string]map[string]int{}
+	for kind, keys := range b.targets {
+		c.targets[kind] = map[string]int{}
+		for k, v := range keys {
+			c.targets[kind][k] = v
+		}
+	}
+	c.rules = nil
+	for _, r := range b.rules {
+		rc := *r
+		rc.clauses = append([]Clause(nil), r.clauses...)
+		rc.flag = &c
+		c.rules = append(c.rules, &rc)
+	}
+	return &c
+}
+
+func (b *FlagBuilder) isBoolean() bool {
+	return len(b.variations) == 2 && b.variations[0] == true && b.variations[1] == false
+}
+
+// BooleanFlag makes the flag boolean, serving true when on and false
+// when off unless told otherwise
+func (b *FlagBuilder) BooleanFlag() *FlagBuilder {
+	if b.isBoolean() {
+		return b
+	}
+	b.variations = []interface{}{true, false}
+	b.fallthroughVariation = trueVariation
+	b.offVariation = falseVariation
+	return b
+}
+
+// Variations sets the flag's possible values
+func (b *FlagBuilder) Variations(values ...interface{}) *FlagBuilder {
+	b.variations = make([]interface{}, len(values))
+	for i, v := range values {
+		b.variations[i] = normalizeValue(v)
+	}
+	return b
+}
+
+// On turns targeting on or off; an off flag serves its off variation
+func (b *FlagBuilder) On(on bool) *FlagBuilder {
+	b.on = on
+	return b
+}
+
+// FallthroughVariation sets what a boolean flag serves contexts no target
+// or rule matched
+func (b *FlagBuilder) FallthroughVariation(value bool) *FlagBuilder {
+	return b.BooleanFlag().FallthroughVariationIndex(boolVariation(value))
+}
+
+// FallthroughVariationIndex sets the variation served to contexts no
+// target or rule matched
+func (b *FlagBuilder) FallthroughVariationIndex(index int) *FlagBuilder {
+	b.fallthroughVariation = index
+	return b
+}
+
+// OffVariation sets what a boolean flag serves when off
+func (b *FlagBuilder) OffVariation(value bool) *FlagBuilder {
+	return b.BooleanFlag().OffVariationIndex(boolVariation(value))
+}
+
+// OffVariationIndex sets the variation served when the flag is off
+func (b *FlagBuilder) OffVariationIndex(index int) *FlagBuilder {
+	b.offVariation = index
+	return b
+}
+
+// VariationForAll makes a boolean flag serve value to everyone
+func (b *FlagBuilder) VariationForAll(value bool) *FlagBuilder {
+	return b.BooleanFlag().VariationIndexForAll(boolVariation(value))
+}
+
+// VariationIndexForAll makes the flag serve one variation to everyone,
+// clearing its targets and rules
+func (b *FlagBuilder) VariationIndexForAll(index int) *FlagBuilder {
+	b.on = true
+	b.targets = map[string]map[string]int{}
+	b.rules = nil
+	b.fallthroughVariation = index
+	return b
+}
+
+// ValueForAll makes the flag serve value, of any type, to everyone
+func (b *FlagBuilder) ValueForAll(value interface{}) *FlagBuilder {
+	return b.Variations(value).VariationIndexForAll(0)
+}
+
+// VariationForUser makes a boolean flag serve value to one user
+func (b *FlagBuilder) VariationForUser(userKey string, value bool) *FlagBuilder {
+	return b.VariationForKey(DefaultContextKind, userKey, value)
+}
+
+// VariationForKey makes a boolean flag serve value to one context
+func (b *FlagBuilder) VariationForKey(kind, key string, value bool) *FlagBuilder {
+	return b.BooleanFlag().VariationIndexForKey(kind, key, boolVariation(value))
+}
+
+// VariationIndexForKey makes the flag serve a variation to one context
+func (b *FlagBuilder) VariationIndexForKey(kind, key string, index int) *FlagBuilder {
+	kind = contextKindOf(kind)
+	if b.targets[kind] == nil {
+		b.targets[kind] = map[string]int{}
+	}
+	b.targets[kind][key] = index
+	return b
+}
+
+// IfMatch starts a rule matching user contexts whose attribute equals
+// one of values
+func (b *FlagBuilder) IfMatch(attr string, values ...interface{}) *RuleBuilder {
+	return b.IfMatchContext(DefaultContextKind, attr, values...)
+}
+
+// IfMatchContext is IfMatch for contexts of another kind
+func (b *FlagBuilder) IfMatchContext(kind, attr string, values ...interface{}) *RuleBuilder {
+	return (&RuleBuilder{flag: b}).AndMatchContext(kind, attr, values...)
+}
+
+// IfNotMatch starts a rule matching user contexts whose attribute equals
+// none of values
+func (b *FlagBuilder) IfNotMatch(attr string, values ...interface{}) *RuleBuilder {
+	return (&RuleBuilder{flag: b}).AndNotMatch(attr, values...)
+}
+
+// ClearRules removes the flag's rules
+func (b *FlagBuilder) ClearRules() *FlagBuilder {
+	b.rules = nil
+	return b
+}
+
+// ClearTargets removes the flag's individual targets
+func (b *FlagBuilder) ClearTargets() *FlagBuilder {
+	b.targets = map[string]map[string]int{}
+	return b
+}
+
+func (b *FlagBuilder) build() FeatureFlag {
+	f := FeatureFlag{
+		Key:          b.key,
+		On:           b.on,
+		Variations:   append([]interface{}(nil), b.variations...),
+		OffVariation: Int(b.offVariation),
+		Fallthrough:  VariationOrRollout{Variation: Int(b.fallthroughVariation)},
+		Salt:         "salt",
+	}
+	kinds := make([]string, 0, len(b.targets))
+	for kind := range b.targets {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		byVariation := map[int][]string{}
+		for key, v := range b.targets[kind] {
+			byVariation[v] = append(byVariation[v], key)
+		}
+		for v := range b.variations {
+			if keys := byVariation[v]; len(keys) > 0 {
+				sort.Strings(keys)
+				f.Targets = append(f.Targets, Target{ContextKind: kind, Values: keys, Variation: v})
+			}
+		}
+	}
+	for i, r := range b.rules {
+		f.Rules = append(f.Rules, FlagRule{
+			ID:                 "rule" + strconv.Itoa(i),
+			Clauses:            append([]Clause(nil), r.clauses...),
+			VariationOrRollout: VariationOrRollout{Variation: Int(r.variation)},
+		})
+	}
+	return f
+}
+
+// RuleBuilder describes a targeting rule for a FlagBuilder
+type RuleBuilder struct {
+	flag      *FlagBuilder
+	clauses   []Clause
+	variation int
+}
+
+// AndMatch adds a clause matching user contexts whose attribute equals
+// one of values
+func (r *RuleBuilder) AndMatch(attr string, values ...interface{}) *RuleBuilder {
+	return r.AndMatchContext(DefaultContextKind, attr, values...)
+}
+
+// AndMatchContext is AndMatch for contexts of another kind
+func (r *RuleBuilder) AndMatchContext(kind, attr string, values ...interface{}) *RuleBuilder {
+	return r.addClause(Clause{ContextKind: kind, Attribute: attr, Op: OpIn, Values: values})
+}
+
+// AndNotMatch adds a clause matching user contexts whose attribute equals
+// none of values
+func (r *RuleBuilder) AndNotMatch(attr string, values ...interface{}) *RuleBuilder {
+	return r.addClause(Clause{ContextKind: DefaultContextKind, Attribute: attr, Op: OpIn, Values: values, Negate: true})
+}
+
+// AndClause adds any clause, such as one using endsWith or lessThan
+func (r *RuleBuilder) AndClause(c Clause) *RuleBuilder {
+	return r.addClause(c)
+}
+
+func (r *RuleBuilder) addClause(c Clause) *RuleBuilder {
+	values := make([]interface{}, len(c.Values))
+	for i, v := range c.Values {
+		values[i] = normalizeValue(v)
+	}
+	c.Values = values
+	r.clauses = append(r.clauses, c)
+	return r
+}
+
+// ThenReturn finishes the rule, making a boolean flag serve value to
+// contexts it matches
+func (r *RuleBuilder) ThenReturn(value bool) *FlagBuilder {
+	r.flag.BooleanFlag()
+	return r.ThenReturnIndex(boolVariation(value))
+}
+
+// ThenReturnIndex finishes the rule, serving a variation to contexts it
+// matches
+func (r *RuleBuilder) ThenReturnIndex(index int) *FlagBuilder {
+	r.variation = index
+	r.flag.rules = append(r.flag.rules, r)
+	return r.flag
+}
+
+// File data, as in the ldfiledata package
+
+// flagFile is the format of flag data files: full flags, simple
+// key-value flags, and segments
+type flagFile struct {
+	Flags      map[string]FeatureFlag `json:"flags"`
+	FlagValues map[string]interface{} `json:"flagValues"`
+	Segments   map[string]Segment     `json:"segments"`
+}
+
+// FileDataSource reads flags from JSON files, as exported from
+// LaunchDarkly or written by hand
+type FileDataSource struct {
+	paths []string
+
+	mu      sync.Mutex
+	clients []DataSourceUpdates
+}
+
+// NewFileDataSource returns a data source reading paths. A key defined
+// in two files is an error.
+func NewFileDataSource(paths ...string) *FileDataSource {
+	return &FileDataSource{paths: paths}
+}
+
+// Start implements DataSource, reading the files
+func (fs *FileDataSource) Start(updates DataSourceUpdates) error {
+	flags, segments, err := fs.load()
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	fs.clients = append(fs.clients, updates)
+	fs.mu.Unlock()
+	updates.Init(flags, segments)
+	return nil
+}
+
+// Close implements DataSource
+func (fs *FileDataSource) Close() error { return nil }
+
+// Reload reads the files again and sends their data to clients, which
+// tell listeners what changed. If a file can't be read, clients keep
+// the data they had.
+func (fs *FileDataSource) Reload() error {
+	flags, segments, err := fs.load()
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	clients := append([]DataSourceUpdates(nil), fs.clients...)
+	fs.mu.Unlock()
+	for _, c := range clients {
+		c.Init(flags, segments)
+	}
+	return nil
+}
+
+func (fs *FileDataSource) load() ([]FeatureFlag, []Segment, error) {
+	flags := map[string]FeatureFlag{}
+	segments := map[string]Segment{}
+	for _, path := range fs.paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var file flagFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for key, f := range file.Flags {
+			if _, dup := flags[key]; dup {
+				return nil, nil, fmt.Errorf("%s: flag %q is defined more than once", path, key)
+			}
+			f.Key = key
+			flags[key] = f
+		}
+		for key, value := range file.FlagValues {
+			if _, dup := flags[key]; dup {
+				return nil, nil, fmt.Errorf("%s: flag %q is defined more than once", path, key)
+			}
+			flags[key] = FeatureFlag{
+				Key:         key,
+				On:          true,
+				Variations:  []interface{}{value},
+				Fallthrough: VariationOrRollout{Variation: Int(0)},
+			}
+		}
+		for key, s := range file.Segments {
+			if _, dup := segments[key]; dup {
+				return nil, nil, fmt.Errorf("%s: segment %q is defined more than once", path, key)
+			}
+			s.Key = key
+			segments[key] = s
+		}
+	}
+
+	flagList := make([]FeatureFlag, 0, len(flags))
+	for _, f := range flags {
+		flagList = append(flagList, f)
+	}
+	segmentList := make([]Segment, 0, len(segments))
+	for _, s := range segments {
+		segmentList = append(segmentList, s)
+	}
+	return flagList, segmentList, nil
+}
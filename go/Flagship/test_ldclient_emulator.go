@@ -0,0 +1,594 @@
+package main
+
+// Developed by PowerShield, as an alternative to LaunchDarkly
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// newTestClient returns a client reading flags from td
+func newTestClient(td *TestData) *LDClient {
+	client, err := MakeCustomClient("sdk-key", Config{DataSource: td}, 5*time.Second)
+	if err != nil {
+		panic(err)
+	}
+	return client
+}
+
+// writeFlagFile writes a flag data file and returns its path
+func writeFlagFile(dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// Test boolean flags both ways
+func testBoolVariation() bool {
+	td := NewTestData()
+	td.Update(td.Flag("new-checkout").VariationForAll(true))
+	client := newTestClient(td)
+	defer client.Close()
+	ctx := NewContext("user-1")
+
+	on, err := client.BoolVariation("new-checkout", ctx, false)
+	if err != nil || !on {
+		return false
+	}
+	td.Update(td.Flag("new-checkout").VariationForAll(false))
+	off, err := client.BoolVariation("new-checkout", ctx, true)
+	if err != nil || off {
+		return false
+	}
+
+	// Turning the flag off serves its off variation
+	td.Update(td.Flag("new-checkout").VariationForAll(true).On(false))
+	value, detail, _ := client.BoolVariationDetail("new-checkout", ctx, true)
+	return !value && detail.Reason.Kind == ReasonOff && detail.VariationIndex == 1
+}
+
+// Test string, number and JSON variations
+func testTypedVariations() bool {
+	td := NewTestData()
+	td.Update(td.Flag("theme").Variations("light", "dark").VariationIndexForAll(1))
+	td.Update(td.Flag("page-size").ValueForAll(25))
+	td.Update(td.Flag("discount").ValueForAll(0.15))
+	td.Update(td.Flag("banner").ValueForAll(map[string]interface{}{"text": "Sale!", "colors": []interface{}{"red", "gold"}}))
+	client := newTestClient(td)
+	ctx := NewContext("user-1")
+
+	theme, err1 := client.StringVariation("theme", ctx, "light")
+	size, err2 := client.IntVariation("page-size", ctx, 10)
+	discount, err3 := client.Float64Variation("discount", ctx, 0)
+	banner, err4 := client.JSONVariation("banner", ctx, nil)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return false
+	}
+	if theme != "dark" || size != 25 || discount != 0.15 {
+		return false
+	}
+	want := map[string]interface{}{"text": "Sale!", "colors": []interface{}{"red", "gold"}}
+	if !reflect.DeepEqual(banner, want) {
+		return false
+	}
+
+	// Asking for the wrong type gives the default
+	wrong, detail, err := client.BoolVariationDetail("theme", ctx, true)
+	return wrong && err != nil && detail.Reason.ErrorKind == ErrorWrongType && detail.IsDefaultValue()
+}
+
+// Test flags that don't exist and invalid contexts
+func testDefaults() bool {
+	td := NewTestData()
+	td.Update(td.Flag("beta").VariationForAll(true))
+	client := newTestClient(td)
+
+	value, detail, err := client.StringVariationDetail("missing", NewContext("user-1"), "fallback")
+	if value != "fallback" || err == nil || detail.Reason.String() != "ERROR(FLAG_NOT_FOUND)" {
+		return false
+	}
+	on, detail, err := client.BoolVariationDetail("beta", NewContext(""), false)
+	if on || err == nil || detail.Reason.ErrorKind != ErrorUserNotSpecified {
+		return false
+	}
+
+	// Offline clients give defaults without errors
+	offline, _ := MakeCustomClient("sdk-key", Config{Offline: true, DataSource: td}, 0)
+	on, err = offline.BoolVariation("beta", NewContext("user-1"), false)
+	return !on && err == nil && offline.IsOffline()
+}
+
+// Test individual targets for users and other context kinds
+func testTargets() bool {
+	td := NewTestData()
+	td.Update(td.Flag("beta").
+		FallthroughVariation(false).
+		VariationForUser("alice", true).
+		VariationForKey("organization", "acme", true))
+	client := newTestClient(td)
+
+	alice, detail, _ := client.BoolVariationDetail("beta", NewContext("alice"), false)
+	bob, _ := client.BoolVariation("beta", NewContext("bob"), true)
+	acme, _ := client.BoolVariation("beta", NewContextBuilder("acme").Kind("organization").Build(), false)
+	// A user named acme is not the organization
+	acmeUser, _ := client.BoolVariation("beta", NewContext("acme"), true)
+	return alice && detail.Reason.Kind == ReasonTargetMatch && !bob && acme && !acmeUser
+}
+
+// Test rules matching context attributes
+func testRules() bool {
+	td := NewTestData()
+	td.Update(td.Flag("export").
+		FallthroughVariation(false).
+		IfMatch("plan", "enterprise").AndNotMatch("country", "FR").ThenReturn(true).
+		IfMatch("groups", "beta-testers").ThenReturn(true))
+	client := newTestClient(td)
+
+	enterprise := NewContextBuilder("u1").SetString("plan", "enterprise").SetString("country", "US").Build()
+	french := NewContextBuilder("u2").SetString("plan", "enterprise").SetString("country", "FR").Build()
+	tester := NewContextBuilder("u3").SetValue("groups", []string{"staff", "beta-testers"}).Build()
+
+	on, detail, _ := client.BoolVariationDetail("export", enterprise, false)
+	if !on || detail.Reason.Kind != ReasonRuleMatch || detail.Reason.RuleIndex != 0 {
+		return false
+	}
+	if off, _ := client.BoolVariation("export", french, true); off {
+		return false
+	}
+	// Any value of a list attribute can match
+	on, detail, _ = client.BoolVariationDetail("export", tester, false)
+	if !on || detail.Reason.String() != "RULE_MATCH(1,rule1)" {
+		return false
+	}
+	off, detail, _ := client.BoolVariationDetail("export", NewContext("u4"), true)
+	return !off && detail.Reason.Kind == ReasonFallthrough
+}
+
+// Test clause operators
+func testOperators() bool {
+	td := NewTestData()
+	flag := func(c Clause) FeatureFlag {
+		return FeatureFlag{
+			Key:          "op",
+			On:           true,
+			Variations:   []interface{}{true, false},
+			OffVariation: Int(1),
+			Fallthrough:  VariationOrRollout{Variation: Int(1)},
+			Rules:        []FlagRule{{Clauses: []Clause{c}, VariationOrRollout: VariationOrRollout{Variation: Int(0)}}},
+		}
+	}
+	client := newTestClient(td)
+	ctx := NewContextBuilder("u1").
+		SetString("email", "ann@corp.example").
+		SetInt("age", 30).
+		SetString("joined", "2024-03-01T00:00:00Z").
+		Build()
+
+	cases := []struct {
+		clause Clause
+		want   bool
+	}{
+		{Clause{Attribute: "email", Op: OpEndsWith, Values: []interface{}{"@corp.example"}}, true},
+		{Clause{Attribute: "email", Op: OpStartsWith, Values: []interface{}{"bob"}}, false},
+		{Clause{Attribute: "email", Op: OpContains, Values: []interface{}{"@corp"}}, true},
+		{Clause{Attribute: "email", Op: OpMatches, Values: []interface{}{`^[a-z]+@corp\.`}}, true},
+		{Clause{Attribute: "age", Op: OpGreaterThanOrEqual, Values: []interface{}{30.0}}, true},
+		{Clause{Attribute: "age", Op: OpLessThan, Values: []interface{}{18.0}}, false},
+		{Clause{Attribute: "joined", Op: OpBefore, Values: []interface{}{"2025-01-01T00:00:00Z"}}, true},
+		{Clause{Attribute: "joined", Op: OpAfter, Values: []interface{}{float64(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())}}, false},
+		{Clause{Attribute: "email", Op: OpEndsWith, Values: []interface{}{"@corp.example"}, Negate: true}, false},
+		{Clause{Attribute: "missing", Op: OpIn, Values: []interface{}{"x"}}, false},
+		{Clause{Attribute: "kind", Op: OpIn, Values: []interface{}{"user"}}, true},
+	}
+	for _, c := range cases {
+		td.UsePreconfiguredFlag(flag(c.clause))
+		if got, _ := client.BoolVariation("op", ctx, false); got != c.want {
+			return false
+		}
+	}
+	return true
+}
+
+// Test that percentage rollouts are stable and split by weight
+func testRollouts() bool {
+	td := NewTestData()
+	td.UsePreconfiguredFlag(FeatureFlag{
+		Key:        "new-search",
+		On:         true,
+		Variations: []interface{}{"new", "old"},
+		Salt:       "a1b2",
+		Fallthrough: VariationOrRollout{Rollout: &Rollout{Variations: []WeightedVariation{
+			{Variation: 0, Weight: 25000},
+			{Variation: 1, Weight: 75000},
+		}}},
+	})
+	client := newTestClient(td)
+
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		ctx := NewContext("user-" + strconv.Itoa(i))
+		first, detail, _ := client.StringVariationDetail("new-search", ctx, "")
+		again, _ := client.StringVariation("new-search", ctx, "")
+		if first != again || !detail.Reason.InExperiment {
+			return false
+		}
+		counts[first]++
+	}
+	// 25% of 2000, give or take
+	if counts["new"] < 400 || counts["new"] > 600 || counts["new"]+counts["old"] != 2000 {
+		return false
+	}
+
+	// Bucketing is by hash, so it doesn't depend on the client
+	other := newTestClient(td)
+	for i := 0; i < 50; i++ {
+		ctx := NewContext("user-" + strconv.Itoa(i))
+		a, _ := client.StringVariation("new-search", ctx, "")
+		b, _ := other.StringVariation("new-search", ctx, "")
+		if a != b {
+			return false
+		}
+	}
+	return true
+}
+
+// Test bucketing by another attribute and with a seed
+func testRolloutBucketBy() bool {
+	rollout := func(key string, seed *int) FeatureFlag {
+		return FeatureFlag{
+			Key:        key,
+			On:         true,
+			Variations: []interface{}{true, false},
+			Salt:       key,
+			Fallthrough: VariationOrRollout{Rollout: &Rollout{
+				BucketBy:   "company",
+				Seed:       seed,
+				Variations: []WeightedVariation{{Variation: 0, Weight: 50000}, {Variation: 1, Weight: 50000}},
+			}},
+		}
+	}
+	td := NewTestData()
+	td.UsePreconfiguredFlag(rollout("a", nil))
+	td.UsePreconfiguredFlag(rollout("b", Int(61)))
+	td.UsePreconfiguredFlag(rollout("c", Int(61)))
+	client := newTestClient(td)
+
+	// Everyone at a company gets the same variation
+	for c := 0; c < 20; c++ {
+		company := "company-" + strconv.Itoa(c)
+		first, _ := client.BoolVariation("a", NewContextBuilder("u1").SetString("company", company).Build(), false)
+		for u := 2; u < 6; u++ {
+			v, _ := client.BoolVariation("a", NewContextBuilder("u"+strconv.Itoa(u)).SetString("company", company).Build(), false)
+			if v != first {
+				return false
+			}
+		}
+	}
+	// Flags with the same seed split contexts the same way
+	for c := 0; c < 50; c++ {
+		ctx := NewContextBuilder("u1").SetString("company", "company-"+strconv.Itoa(c)).Build()
+		b, _ := client.BoolVariation("b", ctx, false)
+		cv, _ := client.BoolVariation("c", ctx, false)
+		if b != cv {
+			return false
+		}
+	}
+	return true
+}
+
+// Test prerequisites
+func testPrerequisites() bool {
+	td := NewTestData()
+	td.Update(td.Flag("payments-v2").VariationForAll(true))
+	td.UsePreconfiguredFlag(FeatureFlag{
+		Key:           "apple-pay",
+		On:            true,
+		Variations:    []interface{}{true, false},
+		OffVariation:  Int(1),
+		Fallthrough:   VariationOrRollout{Variation: Int(0)},
+		Prerequisites: []Prerequisite{{Key: "payments-v2", Variation: 0}},
+	})
+	client := newTestClient(td)
+	ctx := NewContext("user-1")
+
+	if on, _ := client.BoolVariation("apple-pay", ctx, false); !on {
+		return false
+	}
+	td.Update(td.Flag("payments-v2").VariationForAll(false))
+	on, detail, _ := client.BoolVariationDetail("apple-pay", ctx, true)
+	if on || detail.Reason.String() != "PREREQUISITE_FAILED(payments-v2)" {
+		return false
+	}
+
+	// Prerequisite cycles are malformed flags
+	td.UsePreconfiguredFlag(FeatureFlag{Key: "x", On: true, Variations: []interface{}{true},
+		Fallthrough: VariationOrRollout{Variation: Int(0)}, Prerequisites: []Prerequisite{{Key: "y"}}})
+	td.UsePreconfiguredFlag(FeatureFlag{Key: "y", On: true, Variations: []interface{}{true},
+		Fallthrough: VariationOrRollout{Variation: Int(0)}, Prerequisites: []Prerequisite{{Key: "x"}}})
+	_, detail, err := client.BoolVariationDetail("x", ctx, false)
+	return err != nil && detail.Reason.ErrorKind == ErrorMalformedFlag
+}
+
+// Test segments
+func testSegments() bool {
+	td := NewTestData()
+	td.UsePreconfiguredSegment(Segment{
+		Key:      "staff",
+		Included: []string{"ceo"},
+		Excluded: []string{"contractor"},
+		Rules: []SegmentRule{{Clauses: []Clause{
+			{Attribute: "email", Op: OpEndsWith, Values: []interface{}{"@corp.example"}},
+		}}},
+	})
+	td.UsePreconfiguredFlag(FeatureFlag{
+		Key:          "dogfood",
+		On:           true,
+		Variations:   []interface{}{true, false},
+		OffVariation: Int(1),
+		Fallthrough:  VariationOrRollout{Variation: Int(1)},
+		Rules: []FlagRule{{
+			Clauses:            []Clause{{Op: OpSegmentMatch, Values: []interface{}{"staff"}}},
+			VariationOrRollout: VariationOrRollout{Variation: Int(0)},
+		}},
+	})
+	client := newTestClient(td)
+
+	ceo, _ := client.BoolVariation("dogfood", NewContext("ceo"), false)
+	employee, _ := client.BoolVariation("dogfood", NewContextBuilder("e1").SetString("email", "e1@corp.example").Build(), false)
+	contractor, _ := client.BoolVariation("dogfood", NewContextBuilder("contractor").SetString("email", "c@corp.example").Build(), true)
+	customer, _ := client.BoolVariation("dogfood", NewContextBuilder("c1").SetString("email", "c1@gmail.example").Build(), true)
+	return ceo && employee && !contractor && !customer
+}
+
+// Test flags read from files, and reloading them
+func testFileDataSource() bool {
+	dir, err := os.MkdirTemp("", "flagship")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(dir)
+
+	full := writeFlagFile(dir, "flags.json", `{
+		"flags": {
+			"new-ui": {
+				"on": true,
+				"variations": [true, false],
+				"offVariation": 1,
+				"fallthrough": {"variation": 1},
+				"targets": [{"values": ["alice"], "variation": 0}],
+				"rules": [{"id": "admins", "clauses": [{"attribute": "role", "op": "in", "values": ["admin"]}], "variation": 0}]
+			}
+		},
+		"segments": {}
+	}`)
+	simple := writeFlagFile(dir, "values.json", `{"flagValues": {"max-upload-mb": 50, "greeting": "hello"}}`)
+
+	source := NewFileDataSource(full, simple)
+	client, err := MakeCustomClient("sdk-key", Config{DataSource: source}, time.Second)
+	if err != nil || !client.Initialized() {
+		return false
+	}
+	alice, _ := client.BoolVariation("new-ui", NewContext("alice"), false)
+	admin, detail, _ := client.BoolVariationDetail("new-ui", NewContextBuilder("u9").SetString("role", "admin").Build(), false)
+	bob, _ := client.BoolVariation("new-ui", NewContext("bob"), true)
+	upload, _ := client.IntVariation("max-upload-mb", NewContext("bob"), 10)
+	if !alice || !admin || detail.Reason.RuleID != "admins" || bob || upload != 50 {
+		return false
+	}
+
+	writeFlagFile(dir, "values.json", `{"flagValues": {"max-upload-mb": 100, "greeting": "hello"}}`)
+	if err := source.Reload(); err != nil {
+		return false
+	}
+	if upload, _ := client.IntVariation("max-upload-mb", NewContext("bob"), 10); upload != 100 {
+		return false
+	}
+
+	// A broken file keeps the old data
+	writeFlagFile(dir, "values.json", `{"flagValues": `)
+	if err := source.Reload(); err == nil {
+		return false
+	}
+	if upload, _ := client.IntVariation("max-upload-mb", NewContext("bob"), 10); upload != 100 {
+		return false
+	}
+
+	// Keys defined twice fail to start
+	dup := writeFlagFile(dir, "dup.json", `{"flagValues": {"greeting": "hi"}}`)
+	writeFlagFile(dir, "values.json", `{"flagValues": {"greeting": "hello"}}`)
+	failed, err := MakeCustomClient("sdk-key", Config{DataSource: NewFileDataSource(simple, dup)}, time.Second)
+	if !errors.Is(err, ErrInitializationFailed) || failed.Initialized() {
+		return false
+	}
+	greeting, err := failed.StringVariation("greeting", NewContext("bob"), "default")
+	return greeting == "default" && errors.Is(err, ErrClientNotInitialized)
+}
+
+// Test change callbacks, including flags depending on a changed flag
+func testChangeCallbacks() bool {
+	td := NewTestData()
+	td.Update(td.Flag("base").VariationForAll(true))
+	td.UsePreconfiguredFlag(FeatureFlag{
+		Key: "dependent", On: true, Variations: []interface{}{true, false},
+		OffVariation: Int(1), Fallthrough: VariationOrRollout{Variation: Int(0)},
+		Prerequisites: []Prerequisite{{Key: "base", Variation: 0}},
+	})
+	td.Update(td.Flag("other").VariationForAll(true))
+	client := newTestClient(td)
+	tracker := client.GetFlagTracker()
+
+	var changed []string
+	var values []FlagValueChangeEvent
+	removeChange := tracker.OnFlagChange(func(e FlagChangeEvent) { changed = append(changed, e.Key) })
+	removeValue := tracker.OnFlagValueChange("dependent", NewContext("u1"), false, func(e FlagValueChangeEvent) {
+		values = append(values, e)
+	})
+
+	td.Update(td.Flag("base").VariationForAll(false))
+	if !reflect.DeepEqual(changed, []string{"base", "dependent"}) {
+		return false
+	}
+	if len(values) != 1 || values[0].OldValue != true || values[0].NewValue != false {
+		return false
+	}
+
+	// A change that doesn't change the value isn't a value change
+	td.Update(td.Flag("base").VariationForAll(false).On(false))
+	if len(values) != 1 {
+		return false
+	}
+
+	removeChange()
+	removeValue()
+	td.Update(td.Flag("base").VariationForAll(true))
+	return len(changed) == 4 && len(values) == 1
+}
+
+// Test change listener channels
+func testChangeChannels() bool {
+	td := NewTestData()
+	td.Update(td.Flag("theme").Variations("light", "dark").VariationIndexForAll(0))
+	client := newTestClient(td)
+	tracker := client.GetFlagTracker()
+
+	changes := tracker.AddFlagChangeListener()
+	values := tracker.AddFlagValueChangeListener("theme", NewContext("u1"), "light")
+
+	// Updates don't wait for listeners to read
+	td.Update(td.Flag("theme").VariationIndexForAll(1))
+	td.Update(td.Flag("theme").VariationIndexForAll(0))
+	td.Update(td.Flag("beta").VariationForAll(true))
+
+	var keys []string
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-changes:
+			keys = append(keys, e.Key)
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	if !reflect.DeepEqual(keys, []string{"theme", "theme", "beta"}) {
+		return false
+	}
+	first, second := <-values, <-values
+	if first.NewValue != "dark" || second.OldValue != "dark" || second.NewValue != "light" {
+		return false
+	}
+
+	// Removed and closed listeners' channels are closed
+	tracker.RemoveFlagChangeListener(changes)
+	if _, open := <-changes; open {
+		return false
+	}
+	client.Close()
+	_, open := <-values
+	return !open
+}
+
+// Test evaluating all flags, and concurrent use
+func testAllFlagsAndConcurrency() bool {
+	td := NewTestData()
+	td.Update(td.Flag("beta").VariationForAll(true))
+	td.Update(td.Flag("theme").Variations("light", "dark").VariationIndexForAll(1))
+	client := newTestClient(td)
+
+	state := client.AllFlagsState(NewContext("u1"))
+	want := map[string]interface{}{"beta": true, "theme": "dark"}
+	if !state.IsValid() || !reflect.DeepEqual(state.ToValuesMap(), want) {
+		return false
+	}
+	if detail, ok := state.GetFlagDetail("theme"); !ok || detail.VariationIndex != 1 {
+		return false
+	}
+	if client.AllFlagsState(NewContext("")).IsValid() {
+		return false
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				client.BoolVariation("beta", NewContext("u"+strconv.Itoa(i)), false)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				td.Update(td.Flag("beta").VariationForAll(j%2 == 0))
+			}
+		}(i)
+	}
+	wg.Wait()
+	return true
+}
+
+// Test that builders start from the flag's last configuration
+func testFlagBuilderCopies() bool {
+	td := NewTestData()
+	td.Update(td.Flag("beta").FallthroughVariation(false).VariationForUser("alice", true))
+	// Adding a rule keeps alice's target
+	td.Update(td.Flag("beta").IfMatch("plan", "pro").ThenReturn(true))
+	client := newTestClient(td)
+
+	alice, _ := client.BoolVariation("beta", NewContext("alice"), false)
+	pro, _ := client.BoolVariation("beta", NewContextBuilder("p1").SetString("plan", "pro").Build(), false)
+	bob, _ := client.BoolVariation("beta", NewContext("bob"), true)
+	if !alice || !pro || bob {
+		return false
+	}
+
+	// Changing a builder after Update doesn't change the flag
+	b := td.Flag("beta")
+	td.Update(b)
+	b.VariationForAll(true)
+	bob, _ = client.BoolVariation("beta", NewContext("bob"), true)
+	if bob {
+		return false
+	}
+	td.Update(td.Flag("beta").ClearTargets().ClearRules())
+	alice, _ = client.BoolVariation("beta", NewContext("alice"), true)
+	return !alice
+}
+
+func main() {
+	fmt.Println("Running LaunchDarkly Emulator Tests...")
+	fmt.Println("======================================")
+
+	runTest("Bool Variation", testBoolVariation)
+	runTest("Typed Variations", testTypedVariations)
+	runTest("Defaults", testDefaults)
+	runTest("Targets", testTargets)
+	runTest("Rules", testRules)
+	runTest("Operators", testOperators)
+	runTest("Rollouts", testRollouts)
+	runTest("Rollout Bucket By", testRolloutBucketBy)
+	runTest("Prerequisites", testPrerequisites)
+	runTest("Segments", testSegments)
+	runTest("File Data Source", testFileDataSource)
+	runTest("Change Callbacks", testChangeCallbacks)
+	runTest("Change Channels", testChangeChannels)
+	runTest("All Flags and Concurrency", testAllFlagsAndConcurrency)
+	runTest("Flag Builder Copies", testFlagBuilderCopies)
+
+	fmt.Println("======================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1311 @@
+package main
+
+// Developed by PowerShield, as an alternative to asynq
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var timeNow = time.Now
+
+var (
+	// ErrDuplicateTask is returned when a unique task is enqueued while an
+	// identical one is still held
+	ErrDuplicateTask = errors.New("task already exists")
+	// ErrTaskIDConflict is returned when a task ID is already in use in the
+	// queue
+	ErrTaskIDConflict = errors.New("task ID conflicts with another task")
+	// ErrServerClosed is returned by a server that was shut down
+	ErrServerClosed = errors.New("asynq: Server closed")
+	// ErrQueueNotFound is returned by the inspector for unknown queues
+	ErrQueueNotFound = errors.New("queue not found")
+	// ErrTaskNotFound is returned by the inspector for unknown tasks
+	ErrTaskNotFound = errors.New("task not found")
+	// SkipRetry, returned or wrapped by a handler, archives the task
+	// instead of retrying it
+	SkipRetry = errors.New("skip retry for the task")
+)
+
+// Defaults, as asynq has them
+const (
+	DefaultQueueName = "default"
+	defaultMaxRetry  = 25
+	defaultTimeout   = 30 * time.Minute
+)
+
+// Tasks
+
+// Task is a unit of work: a type naming the handler and a payload
+type Task struct {
+	typename string
+	payload  []byte
+	opts     []Option
+}
+
+// NewTask returns a task. Options given here apply whenever it is
+// enqueued, before the options given to Enqueue.
+func NewTask(typename string, payload []byte, opts ...Option) *Task {
+	return &Task{typename: typename, payload: payload, opts: opts}
+}
+
+// Type returns the task's type
+func (t *Task) Type() string { return t.typename }
+
+// Payload returns the task's payload
+func (t *Task) Payload() []byte { return t.payload }
+
+// TaskState is where a task is in its life
+type TaskState int
+
+const (
+	TaskStateActive TaskState = iota + 1
+	TaskStatePending
+	TaskStateScheduled
+	TaskStateRetry
+	TaskStateArchived
+	TaskStateCompleted
+)
+
+var taskStateNames = map[TaskState]string{
+	TaskStateActive:    "active",
+	TaskStatePending:   "pending",
+	TaskStateScheduled: "scheduled",
+	TaskStateRetry:     "retry",
+	TaskStateArchived:  "archived",
+	TaskStateCompleted: "completed",
+}
+
+func (s TaskState) String() string {
+	if name, ok := taskStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("TaskState(%d)", int(s))
+}
+
+func parseTaskState(s string) TaskState {
+	for state, name := range taskStateNames {
+		if name == s {
+			return state
+		}
+	}
+	return 0
+}
+
+// TaskInfo describes an enqueued task
+type TaskInfo struct {
+	ID            string
+	Queue         string
+	Type          string
+	Payload       []byte
+	State         TaskState
+	MaxRetry      int
+	Retried       int
+	LastErr       string
+	LastFailedAt  time.Time
+	Timeout       time.Duration
+	Deadline      time.Time
+	NextProcessAt time.Time
+}
+
+// Options
+
+type optionType int
+
+const (
+	maxRetryOpt optionType = iota
+	queueOpt
+	timeoutOpt
+	deadlineOpt
+	uniqueOpt
+	processAtOpt
+	processInOpt
+	taskIDOpt
+)
+
+// Option changes how a task is enqueued
+type Option interface {
+	String() string
+	Type() optionType
+	Value() interface{}
+}
+
+type option struct {
+	typ   optionType
+	value interface{}
+	str   string
+}
+
+func (o option) String() string     { return o.str }
+func (o option) Type() optionType   { return o.typ }
+func (o option) Value() interface{} { return o.value }
+
+// MaxRetry sets how many times a failed task is retried; the default is 25
+func MaxRetry(n int) Option {
+	if n < 0 {
+		n = 0
+	}
+	return option{maxRetryOpt, n, fmt.Sprintf("MaxRetry(%d)", n)}
+}
+
+// Queue sets the queue the task goes to
+func Queue(name string) Option {
+	return option{queueOpt, name, fmt.Sprintf("Queue(%q)", name)}
+}
+
+// Timeout limits how long one attempt may run; the default is 30 minutes
+func Timeout(d time.Duration) Option {
+	return option{timeoutOpt, d, fmt.Sprintf("Timeout(%v)", d)}
+}
+
+// Deadline sets a time by which every attempt must finish
+func Deadline(t time.Time) Option {
+	return option{deadlineOpt, t, fmt.Sprintf("Deadline(%v)", t.Format(time.UnixDate))}
+}
+
+// Unique rejects tasks with the same type, payload and queue as one
+// enqueued within ttl that hasn't completed yet
+func Unique(ttl time.Duration) Option {
+	return option{uniqueOpt, ttl, fmt.Sprintf("Unique(%v)", ttl)}
+}
+
+// ProcessAt schedules the task for a time
+func ProcessAt(t time.Time) Option {
+	return option{processAtOpt, t, fmt.Sprintf("ProcessAt(%v)", t.Format(time.UnixDate))}
+}
+
+// ProcessIn schedules the task for a while from now
+func ProcessIn(d time.Duration) Option {
+	return option{processInOpt, d, fmt.Sprintf("ProcessIn(%v)", d)}
+}
+
+// TaskID sets the task's ID instead of a random one
+func TaskID(id string) Option {
+	return option{taskIDOpt, id, fmt.Sprintf("TaskID(%q)", id)}
+}
+
+// taskMessage is a task as it is kept in Redis
+type taskMessage struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Payload      []byte `json:"payload"`
+	Queue        string `json:"queue"`
+	MaxRetry     int    `json:"max_retry"`
+	Retried      int    `json:"retried"`
+	ErrorMsg     string `json:"error_msg,omitempty"`
+	LastFailedAt int64  `json:"last_failed_at,omitempty"`
+	Timeout      int64  `json:"timeout"`
+	Deadline     int64  `json:"deadline"`
+	UniqueKey    string `json:"unique_key,omitempty"`
+}
+
+func newTaskID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Redis store
+
+// RedisStore is the Redis commands the queue is kept with. The Redis
+// emulator's Client implements them.
+type RedisStore interface {
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	Get(key string) (string, error)
+	Del(keys ...string) (int, error)
+	Incr(key string) (int64, error)
+	LPush(key string, values ...interface{}) (int, error)
+	RPush(key string, values ...interface{}) (int, error)
+	RPop(key string) (string, error)
+	LRange(key string, start, stop int) ([]string, error)
+	LLen(key string) (int, error)
+	SAdd(key string, members ...interface{}) (int, error)
+	SRem(key string, members ...interface{}) (int, error)
+	SMembers(key string) ([]string, error)
+	HSet(key, field string, value interface{}) error
+	HGet(key, field string) (string, error)
+	ZAdd(key string, members ...interface{}) (int, error)
+	ZRange(key string, start, stop int) ([]string, error)
+	ZScore(key, member string) (float64, error)
+	ZRem(key string, members ...interface{}) (int, error)
+	ZCard(key string) (int, error)
+}
+
+// storeLocks serializes access to each store, since clients, servers and
+// inspectors share one and a queue operation takes several commands
+var (
+	storeLocksMu sync.Mutex
+	storeLocks   = map[RedisStore]*sync.Mutex{}
+)
+
+func lockFor(store RedisStore) *sync.Mutex {
+	storeLocksMu.Lock()
+	defer storeLocksMu.Unlock()
+	if storeLocks[store] == nil {
+		storeLocks[store] = &sync.Mutex{}
+	}
+	return storeLocks[store]
+}
+
+const allQueuesKey = "asynq:queues"
+
+func queueKey(qname, suffix string) string {
+	return fmt.Sprintf("asynq:{%s}:%s", qname, suffix)
+}
+
+func taskKey(qname, id string) string {
+	return queueKey(qname, "t:"+id)
+}
+
+func uniqueKey(qname, typename string, payload []byte) string {
+	return queueKey(qname, fmt.Sprintf("unique:%s:%x", typename, md5.Sum(payload)))
+}
+
+func score(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+func fromScore(s float64) time.Time {
+	return time.UnixMilli(int64(s))
+}
+
+// broker keeps tasks in a store. Its methods take the store's lock.
+type broker struct {
+	store RedisStore
+	mu    *sync.Mutex
+}
+
+func newBroker(store RedisStore) *broker {
+	return &broker{store: store, mu: lockFor(store)}
+}
+
+func (b *broker) readMessage(qname, id string) (*taskMessage, TaskState, error) {
+	raw, err := b.store.HGet(taskKey(qname, id), "msg")
+	if err != nil || raw == "" {
+		return nil, 0, ErrTaskNotFound
+	}
+	var msg taskMessage
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		return nil, 0, err
+	}
+	state, _ := b.store.HGet(taskKey(qname, id), "state")
+	return &msg, parseTaskState(state), nil
+}
+
+func (b *broker) writeMessage(msg *taskMessage, state TaskState) error {
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := b.store.HSet(taskKey(msg.Queue, msg.ID), "msg", string(raw)); err != nil {
+		return err
+	}
+	return b.store.HSet(taskKey(msg.Queue, msg.ID), "state", state.String())
+}
+
+// enqueue adds a task, pending or scheduled for processAt
+func (b *broker) enqueue(msg *taskMessage, processAt time.Time, uniqueTTL time.Duration) (TaskState, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, _, err := b.readMessage(msg.Queue, msg.ID); err == nil {
+		return 0, ErrTaskIDConflict
+	}
+	if uniqueTTL > 0 {
+		msg.UniqueKey = uniqueKey(msg.Queue, msg.Type, msg.Payload)
+		ok, err := b.store.SetNX(msg.UniqueKey, msg.ID, uniqueTTL)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, ErrDuplicateTask
+		}
+	}
+	b.store.SAdd(allQueuesKey, msg.Queue)
+
+	state := TaskStatePending
+	if processAt.After(timeNow()) {
+		state = TaskStateScheduled
+	}
+	if err := b.writeMessage(msg, state); err != nil {
+		return 0, err
+	}
+	if state == TaskStateScheduled {
+		b.store.ZAdd(queueKey(msg.Queue, "scheduled"), score(processAt), msg.ID)
+	} else {
+		b.store.LPush(queueKey(msg.Queue, "pending"), msg.ID)
+	}
+	return state, nil
+}
+
+// dequeue takes the oldest pending task from the first of qnames that
+// has one, marking it active
+func (b *broker) dequeue(qnames []string) *taskMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, qname := range qnames {
+		id, err := b.store.RPop(queueKey(qname, "pending"))
+		if err != nil {
+			continue
+		}
+		msg, _, err := b.readMessage(qname, id)
+		if err != nil {
+			continue
+		}
+		b.store.SAdd(queueKey(qname, "active"), id)
+		b.store.HSet(taskKey(qname, id), "state", TaskStateActive.String())
+		return msg
+	}
+	return nil
+}
+
+// forward moves scheduled and retry tasks that are due to pending. With
+// all, it moves every one of them, due or not.
+func (b *broker) forward(qnames []string, all bool) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := score(timeNow())
+	moved := 0
+	for _, qname := range qnames {
+		for _, set := range []string{"scheduled", "retry"} {
+			key := queueKey(qname, set)
+			ids, _ := b.store.ZRange(key, 0, -1)
+			for _, id := range ids {
+				if at, _ := b.store.ZScore(key, id); !all && at > now {
+					break
+				}
+				b.store.ZRem(key, id)
+				b.store.LPush(queueKey(qname, "pending"), id)
+				b.store.HSet(taskKey(qname, id), "state", TaskStatePending.String())
+				moved++
+			}
+		}
+	}
+	return moved
+}
+
+// done removes a task that succeeded, releasing its unique lock
+func (b *broker) done(msg *taskMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.store.SRem(queueKey(msg.Queue, "active"), msg.ID)
+	b.store.Del(taskKey(msg.Queue, msg.ID))
+	b.store.Incr(queueKey(msg.Queue, "processed"))
+	if msg.UniqueKey != "" {
+		if owner, err := b.store.Get(msg.UniqueKey); err == nil && owner == msg.ID {
+			b.store.Del(msg.UniqueKey)
+		}
+	}
+}
+
+// fail records a failed attempt, scheduling a retry at retryAt or, if
+// retryAt is zero, archiving the task
+func (b *broker) fail(msg *taskMessage, errMsg string, retryAt time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := timeNow()
+	b.store.SRem(queueKey(msg.Queue, "active"), msg.ID)
+	b.store.Incr(queueKey(msg.Queue, "processed"))
+	b.store.Incr(queueKey(msg.Queue, "failed"))
+	msg.ErrorMsg = errMsg
+	msg.LastFailedAt = now.Unix()
+	if retryAt.IsZero() {
+		b.writeMessage(msg, TaskStateArchived)
+		b.store.ZAdd(queueKey(msg.Queue, "archived"), score(now), msg.ID)
+		return
+	}
+	msg.Retried++
+	b.writeMessage(msg, TaskStateRetry)
+	b.store.ZAdd(queueKey(msg.Queue, "retry"), score(retryAt), msg.ID)
+}
+
+// requeue puts an active task back at the front of its queue, for a
+// server shutting down before the task finished
+func (b *broker) requeue(msg *taskMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.store.SRem(queueKey(msg.Queue, "active"), msg.ID)
+	b.store.RPush(queueKey(msg.Queue, "pending"), msg.ID)
+	b.store.HSet(taskKey(msg.Queue, msg.ID), "state", TaskStatePending.String())
+}
+
+func (b *broker) queues() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names, _ := b.store.SMembers(allQueuesKey)
+	sort.Strings(names)
+	return names
+}
+
+// Client
+
+// Client enqueues tasks
+type Client struct {
+	broker *broker
+}
+
+// NewClient returns a client enqueuing tasks in store
+func NewClient(store RedisStore) *Client {
+	return &Client{broker: newBroker(store)}
+}
+
+// Enqueue adds a task to its queue, to be processed now or when scheduled
+func (c *Client) Enqueue(task *Task, opts ...Option) (*TaskInfo, error) {
+	return c.EnqueueContext(context.Background(), task, opts...)
+}
+
+// EnqueueContext is Enqueue with a context
+func (c *Client) EnqueueContext(ctx context.Context, task *Task, opts ...Option) (*TaskInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(task.Type()) == "" {
+		return nil, errors.New("task typename cannot be empty")
+	}
+	msg := &taskMessage{
+		ID:       newTaskID(),
+		Type:     task.Type(),
+		Payload:  task.Payload(),
+		Queue:    DefaultQueueName,
+		MaxRetry: defaultMaxRetry,
+	}
+	timeout := time.Duration(0)
+	var deadline time.Time
+	var uniqueTTL time.Duration
+	processAt := timeNow()
+	for _, opt := range append(append([]Option{}, task.opts...), opts...) {
+		switch opt.Type() {
+		case maxRetryOpt:
+			msg.MaxRetry = opt.Value().(int)
+		case queueOpt:
+			msg.Queue = opt.Value().(string)
+		case timeoutOpt:
+			timeout = opt.Value().(time.Duration)
+		case deadlineOpt:
+			deadline = opt.Value().(time.Time)
+		case uniqueOpt:
+			uniqueTTL = opt.Value().(time.Duration)
+		case processAtOpt:
+			processAt = opt.Value().(time.Time)
+		case processInOpt:
+			processAt = timeNow().Add(opt.Value().(time.Duration))
+		case taskIDOpt:
+			msg.ID = opt.Value().(string)
+		}
+	}
+	if strings.TrimSpace(msg.Queue) == "" {
+		return nil, errors.New("queue name cannot be empty")
+	}
+	if strings.TrimSpace(msg.ID) == "" {
+		return nil, errors.New("task ID cannot be empty")
+	}
+	if uniqueTTL != 0 && uniqueTTL < time.Second {
+		return nil, errors.New("unique TTL cannot be less than 1s")
+	}
+	if timeout == 0 && deadline.IsZero() {
+		timeout = defaultTimeout
+	}
+	msg.Timeout = int64(timeout / time.Second)
+	if !deadline.IsZero() {
+		msg.Deadline = deadline.Unix()
+	}
+
+	state, err := c.broker.enqueue(msg, processAt, uniqueTTL)
+	if err != nil {
+		return nil, err
+	}
+	info := newTaskInfo(msg, state)
+	if state == TaskStateScheduled {
+		info.NextProcessAt = processAt
+	} else {
+		info.NextProcessAt = timeNow()
+	}
+	return info, nil
+}
+
+// Close releases the client
+func (c *Client) Close() error {
+	return nil
+}
+
+func newTaskInfo(msg *taskMessage, state TaskState) *TaskInfo {
+	info := &TaskInfo{
+		ID:       msg.ID,
+		Queue:    msg.Queue,
+		Type:     msg.Type,
+		Payload:  msg.Payload,
+		State:    state,
+		MaxRetry: msg.MaxRetry,
+		Retried:  msg.Retried,
+		LastErr:  msg.ErrorMsg,
+		Timeout:  time.Duration(msg.Timeout) * time.Second,
+	}
+	if msg.LastFailedAt != 0 {
+		info.LastFailedAt = time.Unix(msg.LastFailedAt, 0)
+	}
+	if msg.Deadline != 0 {
+		info.Deadline = time.Unix(msg.Deadline, 0)
+	}
+	return info
+}
+
+// Handlers
+
+// Handler processes tasks. Returning an error retries the task, unless
+// it wraps SkipRetry or the task is out of retries.
+type Handler interface {
+	ProcessTask(ctx context.Context, task *Task) error
+}
+
+// HandlerFunc is a function Handler
+type HandlerFunc func(ctx context.Context, task *Task) error
+
+// ProcessTask calls f
+func (f HandlerFunc) ProcessTask(ctx context.Context, task *Task) error {
+	return f(ctx, task)
+}
+
+// MiddlewareFunc wraps a Handler
+type MiddlewareFunc func(Handler) Handler
+
+// ServeMux routes tasks to handlers by type. A pattern is a task type or
+// a prefix of one, and the longest matching pattern wins.
+type ServeMux struct {
+	mu  sync.RWMutex
+	m   map[string]Handler
+	mws []MiddlewareFunc
+}
+
+// NewServeMux returns an empty ServeMux
+func NewServeMux() *ServeMux {
+	return &ServeMux{m: make(map[string]Handler)}
+}
+
+// Handle registers handler for pattern
+func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	if strings.TrimSpace(pattern) == "" {
+		panic("asynq: invalid pattern")
+	}
+	if handler == nil {
+		panic("asynq: nil handler")
+	}
+	if _, exists := mux.m[pattern]; exists {
+		panic("asynq: multiple registrations for " + pattern)
+	}
+	mux.m[pattern] = handler
+}
+
+// HandleFunc registers a handler function for pattern
+func (mux *ServeMux) HandleFunc(pattern string, handler func(context.Context, *Task) error) {
+	mux.Handle(pattern, HandlerFunc(handler))
+}
+
+// Use adds middleware, applied to every handler in the order given
+func (mux *ServeMux) Use(mws ...MiddlewareFunc) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+	mux.mws = append(mux.mws, mws...)
+}
+
+// Handler returns the handler for a task, or one that fails with a not
+// found error
+func (mux *ServeMux) Handler(task *Task) (Handler, string) {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+	var h Handler
+	pattern := ""
+	for p, handler := range mux.m {
+		if strings.HasPrefix(task.Type(), p) && len(p) > len(pattern) {
+			h, pattern = handler, p
+		}
+	}
+	if h == nil {
+		return NotFoundHandler(), ""
+	}
+	for i := len(mux.mws) - 1; i >= 0; i-- {
+		h = mux.mws[i](h)
+	}
+	return h, pattern
+}
+
+// ProcessTask runs the task's handler
+func (mux *ServeMux) ProcessTask(ctx context.Context, task *Task) error {
+	h, _ := mux.Handler(task)
+	return h.ProcessTask(ctx, task)
+}
+
+// NotFound fails a task no handler is registered for
+func NotFound(ctx context.Context, task *Task) error {
+	return fmt.Errorf("handler not found for task %q", task.Type())
+}
+
+// NotFoundHandler returns NotFound as a Handler
+func NotFoundHandler() Handler {
+	return HandlerFunc(NotFound)
+}
+
+// Task context
+
+type ctxKey int
+
+const metadataCtxKey ctxKey = 0
+
+type taskMetadata struct {
+	id         string
+	maxRetry   int
+	retryCount int
+	qname      string
+}
+
+// GetTaskID returns the ID of the task a handler is running
+func GetTaskID(ctx context.Context) (string, bool) {
+	md, ok := ctx.Value(metadataCtxKey).(taskMetadata)
+	return md.id, ok
+}
+
+// GetRetryCount returns how many times the task has been retried
+func GetRetryCount(ctx context.Context) (int, bool) {
+	md, ok := ctx.Value(metadataCtxKey).(taskMetadata)
+	return md.retryCount, ok
+}
+
+// GetMaxRetry returns how many times the task may be retried
+func GetMaxRetry(ctx context.Context) (int, bool) {
+	md, ok := ctx.Value(metadataCtxKey).(taskMetadata)
+	return md.maxRetry, ok
+}
+
+// GetQueueName returns the queue the task came from
+func GetQueueName(ctx context.Context) (string, bool) {
+	md, ok := ctx.Value(metadataCtxKey).(taskMetadata)
+	return md.qname, ok
+}
+
+// Server
+
+// RetryDelayFunc says how long to wait before retry n of a task
+type RetryDelayFunc func(n int, e error, t *Task) time.Duration
+
+// DefaultRetryDelayFunc backs off exponentially, with jitter: about 15s,
+// 16s, 31s, 96s and so on
+func DefaultRetryDelayFunc(n int, e error, t *Task) time.Duration {
+	s := int(math.Pow(float64(n), 4)) + 15 + mrand.Intn(30)*(n+1)
+	return time.Duration(s) * time.Second
+}
+
+// ErrorHandler is told about every failed attempt
+type ErrorHandler interface {
+	HandleError(ctx context.Context, task *Task, err error)
+}
+
+// ErrorHandlerFunc is a function ErrorHandler
+type ErrorHandlerFunc func(ctx context.Context, task *Task, err error)
+
+// HandleError calls fn
+func (fn ErrorHandlerFunc) HandleError(ctx context.Context, task *Task, err error) {
+	fn(ctx, task, err)
+}
+
+// Config configures a Server
+type Config struct {
+	// Concurrency is how many tasks run at once; the default is the
+	// number of CPUs
+	Concurrency int
+	// Queues are the queues to process and their priorities; the default
+	// is the default queue alone
+	Queues map[string]int
+	// StrictPriority empties higher priority queues first. Otherwise
+	// queues are picked at random, in proportion to their priority.
+	StrictPriority bool
+	// RetryDelayFunc defaults to DefaultRetryDelayFunc
+	RetryDelayFunc RetryDelayFunc
+	// ErrorHandler is told about failed attempts
+	ErrorHandler ErrorHandler
+	// ShutdownTimeout is how long Shutdown waits for active tasks before
+	// putting them back in their queues; the default is 8s
+	ShutdownTimeout time.Duration
+	// TaskCheckInterval is how often idle workers check for tasks; the
+	// default is 1s
+	TaskCheckInterval time.Duration
+}
+
+type serverState int
+
+const (
+	srvStateNew serverState = iota
+	srvStateActive
+	srvStateStopped
+	srvStateClosed
+)
+
+// activeTask is a task a worker is running
+type activeTask struct {
+	msg       *taskMessage
+	cancel    context.CancelFunc
+	abandoned bool
+}
+
+// Server runs tasks from its queues with a pool of workers
+type Server struct {
+	broker *broker
+	config Config
+	queues []string
+
+	mu      sync.Mutex
+	state   serverState
+	handler Handler
+	quit    chan struct{}
+	sema    chan struct{}
+	active  map[string]*activeTask
+	workers sync.WaitGroup
+	loops   sync.WaitGroup
+}
+
+// NewServer returns a server processing tasks in store
+func NewServer(store RedisStore, config Config) *Server {
+	if config.Concurrency < 1 {
+		config.Concurrency = runtime.NumCPU()
+	}
+	if len(config.Queues) == 0 {
+		config.Queues = map[string]int{DefaultQueueName: 1}
+	}
+	if config.RetryDelayFunc == nil {
+		config.RetryDelayFunc = DefaultRetryDelayFunc
+	}
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = 8 * time.Second
+	}
+	if config.TaskCheckInterval == 0 {
+		config.TaskCheckInterval = time.Second
+	}
+	var queues []string
+	for qname, priority := range config.Queues {
+		if priority > 0 {
+			queues = append(queues, qname)
+		}
+	}
+	sort.Slice(queues, func(i, j int) bool {
+		pi, pj := config.Queues[queues[i]], config.Queues[queues[j]]
+		if pi != pj {
+			return pi > pj
+		}
+		return queues[i] < queues[j]
+	})
+	return &Server{
+		broker: newBroker(store),
+		config: config,
+		queues: queues,
+		quit:   make(chan struct{}),
+		sema:   make(chan struct{}, config.Concurrency),
+		active: make(map[string]*activeTask),
+	}
+}
+
+// queueOrder returns the queues to try, highest priority first or, with
+// weighted priorities, in a random order favoring higher priorities
+func (srv *Server) queueOrder() []string {
+	if srv.config.StrictPriority || len(srv.queues) < 2 {
+		return srv.queues
+	}
+	var names []string
+	for _, qname := range srv.queues {
+		for i := 0; i < srv.config.Queues[qname]; i++ {
+			names = append(names, qname)
+		}
+	}
+	mrand.Shuffle(len(names), func(i, j int) { names[i], names[j] = names[j], names[i] })
+	seen := map[string]bool{}
+	var order []string
+	for _, qname := range names {
+		if !seen[qname] {
+			seen[qname] = true
+			order = append(order, qname)
+		}
+	}
+	return order
+}
+
+// Run starts the server and shuts it down on SIGINT or SIGTERM
+func (srv *Server) Run(handler Handler) error {
+	if err := srv.Start(handler); err != nil {
+		return err
+	}
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	<-sigs
+	srv.Shutdown()
+	return nil
+}
+
+// Start starts processing tasks in the background
+func (srv *Server) Start(handler Handler) error {
+	if handler == nil {
+		return errors.New("asynq: server cannot run with nil handler")
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	switch srv.state {
+	case srvStateActive:
+		return errors.New("asynq: the server is already running")
+	case srvStateStopped:
+		return errors.New("asynq: the server is in the stopped state. Waiting for shutdown.")
+	case srvStateClosed:
+		return ErrServerClosed
+	}
+	srv.state = srvStateActive
+	srv.handler = handler
+	srv.loops.Add(2)
+	go srv.forwardLoop()
+	go srv.processLoop()
+	return nil
+}
+
+// Stop stops taking new tasks; running tasks carry on. Call Shutdown
+// afterward.
+func (srv *Server) Stop() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.state != srvStateActive {
+		return
+	}
+	srv.state = srvStateStopped
+	close(srv.quit)
+}
+
+// Shutdown stops taking new tasks and waits up to ShutdownTimeout for
+// running ones. Tasks still running then are cancelled and put back in
+// their queues, to run again.
+func (srv *Server) Shutdown() {
+	srv.mu.Lock()
+	switch srv.state {
+	case srvStateNew, srvStateClosed:
+		srv.state = srvStateClosed
+		srv.mu.Unlock()
+		return
+	case srvStateActive:
+		close(srv.quit)
+	}
+	srv.state = srvStateClosed
+	srv.mu.Unlock()
+
+	srv.loops.Wait()
+	finished := make(chan struct{})
+	go func() {
+		srv.workers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(srv.config.ShutdownTimeout):
+		srv.mu.Lock()
+		for _, t := range srv.active {
+			t.abandoned = true
+			t.cancel()
+			srv.broker.requeue(t.msg)
+		}
+		srv.mu.Unlock()
+	}
+}
+
+func (srv *Server) forwardLoop() {
+	defer srv.loops.Done()
+	ticker := time.NewTicker(srv.config.TaskCheckInterval)
+	defer ticker.Stop()
+	for {
+		srv.broker.forward(srv.queues, false)
+		select {
+		case <-srv.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (srv *Server) processLoop() {
+	defer srv.loops.Done()
+	for {
+		select {
+		case <-srv.quit:
+			return
+		case srv.sema <- struct{}{}:
+		}
+		msg := srv.broker.dequeue(srv.queueOrder())
+		if msg == nil {
+			<-srv.sema
+			select {
+			case <-srv.quit:
+				return
+			case <-time.After(srv.config.TaskCheckInterval):
+			}
+			continue
+		}
+		srv.workers.Add(1)
+		go func() {
+			defer srv.workers.Done()
+			defer func() { <-srv.sema }()
+			srv.exec(srv.handler, msg)
+		}()
+	}
+}
+
+// exec runs one attempt of a task and records how it went
+func (srv *Server) exec(handler Handler, msg *taskMessage) {
+	ctx := context.WithValue(context.Background(), metadataCtxKey, taskMetadata{
+		id:         msg.ID,
+		maxRetry:   msg.MaxRetry,
+		retryCount: msg.Retried,
+		qname:      msg.Queue,
+	})
+	var cancel context.CancelFunc
+	deadline := time.Time{}
+	if msg.Timeout > 0 {
+		deadline = time.Now().Add(time.Duration(msg.Timeout) * time.Second)
+	}
+	if msg.Deadline > 0 {
+		if d := time.Unix(msg.Deadline, 0); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if deadline.IsZero() {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	}
+	defer cancel()
+
+	t := &activeTask{msg: msg, cancel: cancel}
+	srv.mu.Lock()
+	srv.active[msg.ID] = t
+	srv.mu.Unlock()
+
+	task := NewTask(msg.Type, msg.Payload)
+	err := runHandler(ctx, handler, task)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	srv.mu.Lock()
+	delete(srv.active, msg.ID)
+	abandoned := t.abandoned
+	srv.mu.Unlock()
+	if abandoned {
+		return
+	}
+
+	if err == nil {
+		srv.broker.done(msg)
+		return
+	}
+	if srv.config.ErrorHandler != nil {
+		srv.config.ErrorHandler.HandleError(ctx, task, err)
+	}
+	if errors.Is(err, SkipRetry) || msg.Retried >= msg.MaxRetry {
+		srv.broker.fail(msg, err.Error(), time.Time{})
+		return
+	}
+	delay := srv.config.RetryDelayFunc(msg.Retried+1, err, task)
+	srv.broker.fail(msg, err.Error(), timeNow().Add(delay))
+}
+
+// runHandler runs a handler, turning a panic into an error
+func runHandler(ctx context.Context, handler Handler, task *Task) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("panic: %v", x)
+		}
+	}()
+	return handler.ProcessTask(ctx, task)
+}
+
+// Test helpers
+
+// Drain runs every task that is due, one at a time in the calling
+// goroutine, as the server would, until none are left. It returns how
+// many attempts ran. Tasks scheduled for later, including retries, are
+// left; DrainAll runs those too.
+func (srv *Server) Drain(handler Handler) int {
+	return srv.drain(handler, false)
+}
+
+// DrainAll is Drain, running scheduled tasks and retries straight away
+// instead of waiting for them. Failing tasks run until they are out of
+// retries.
+func (srv *Server) DrainAll(handler Handler) int {
+	return srv.drain(handler, true)
+}
+
+func (srv *Server) drain(handler Handler, all bool) int {
+	n := 0
+	for {
+		srv.broker.forward(srv.queues, all)
+		msg := srv.broker.dequeue(srv.queueOrder())
+		if msg == nil {
+			return n
+		}
+		srv.exec(handler, msg)
+		n++
+	}
+}
+
+// Inspector
+
+// QueueInfo counts a queue's tasks
+type QueueInfo struct {
+	Queue     string
+	Size      int
+	Pending   int
+	Active    int
+	Scheduled int
+	Retry     int
+	Archived  int
+	Processed int
+	Failed    int
+}
+
+// Inspector looks at and changes queues, for dashboards, CLIs and tests
+type Inspector struct {
+	broker *broker
+}
+
+// NewInspector returns an inspector for store
+func NewInspector(store RedisStore) *Inspector {
+	return &Inspector{broker: newBroker(store)}
+}
+
+// Queues returns the names of the queues tasks have been enqueued in
+func (i *Inspector) Queues() ([]string, error) {
+	return i.broker.queues(), nil
+}
+
+func (i *Inspector) checkQueue(qname string) error {
+	for _, q := range i.broker.queues() {
+		if q == qname {
+			return nil
+		}
+	}
+	return ErrQueueNotFound
+}
+
+// GetQueueInfo counts a queue's tasks
+func (i *Inspector) GetQueueInfo(qname string) (*QueueInfo, error) {
+	if err := i.checkQueue(qname); err != nil {
+		return nil, err
+	}
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	info := &QueueInfo{Queue: qname}
+	info.Pending, _ = b.store.LLen(queueKey(qname, "pending"))
+	active, _ := b.store.SMembers(queueKey(qname, "active"))
+	info.Active = len(active)
+	info.Scheduled, _ = b.store.ZCard(queueKey(qname, "scheduled"))
+	info.Retry, _ = b.store.ZCard(queueKey(qname, "retry"))
+	info.Archived, _ = b.store.ZCard(queueKey(qname, "archived"))
+	info.Size = info.Pending + info.Active + info.Scheduled + info.Retry + info.Archived
+	processed, _ := b.store.Get(queueKey(qname, "processed"))
+	failed, _ := b.store.Get(queueKey(qname, "failed"))
+	info.Processed, _ = strconv.Atoi(processed)
+	info.Failed, _ = strconv.Atoi(failed)
+	return info, nil
+}
+
+// GetTaskInfo returns a task
+func (i *Inspector) GetTaskInfo(qname, id string) (*TaskInfo, error) {
+	if err := i.checkQueue(qname); err != nil {
+		return nil, err
+	}
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.taskInfo(qname, id)
+}
+
+func (b *broker) taskInfo(qname, id string) (*TaskInfo, error) {
+	msg, state, err := b.readMessage(qname, id)
+	if err != nil {
+		return nil, err
+	}
+	info := newTaskInfo(msg, state)
+	switch state {
+	case TaskStateScheduled, TaskStateRetry:
+		if at, err := b.store.ZScore(queueKey(qname, state.String()), id); err == nil {
+			info.NextProcessAt = fromScore(at)
+		}
+	case TaskStatePending:
+		info.NextProcessAt = timeNow()
+	}
+	return info, nil
+}
+
+func (i *Inspector) listTasks(qname string, state TaskState) ([]*TaskInfo, error) {
+	if err := i.checkQueue(qname); err != nil {
+		return nil, err
+	}
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var ids []string
+	switch state {
+	case TaskStatePending:
+		// The list is pushed on the left and popped on the right; show
+		// the next task first
+		pending, _ := b.store.LRange(queueKey(qname, "pending"), 0, -1)
+		for j := len(pending) - 1; j >= 0; j-- {
+			ids = append(ids, pending[j])
+		}
+	case TaskStateActive:
+		ids, _ = b.store.SMembers(queueKey(qname, "active"))
+		sort.Strings(ids)
+	default:
+		ids, _ = b.store.ZRange(queueKey(qname, state.String()), 0, -1)
+	}
+	tasks := []*TaskInfo{}
+	for _, id := range ids {
+		if info, err := b.taskInfo(qname, id); err == nil {
+			tasks = append(tasks, info)
+		}
+	}
+	return tasks, nil
+}
+
+// ListPendingTasks returns a queue's pending tasks, next first
+func (i *Inspector) ListPendingTasks(qname string) ([]*TaskInfo, error) {
+	return i.listTasks(qname, TaskStatePending)
+}
+
+// ListActiveTasks returns the tasks workers are running
+func (i *Inspector) ListActiveTasks(qname string) ([]*TaskInfo, error) {
+	return i.listTasks(qname, TaskStateActive)
+}
+
+// ListScheduledTasks returns a queue's scheduled tasks, soonest first
+func (i *Inspector) ListScheduledTasks(qname string) ([]*TaskInfo, error) {
+	return i.listTasks(qname, TaskStateScheduled)
+}
+
+// ListRetryTasks returns a queue's tasks waiting to be retried, soonest
+// first
+func (i *Inspector) ListRetryTasks(qname string) ([]*TaskInfo, error) {
+	return i.listTasks(qname, TaskStateRetry)
+}
+
+// ListArchivedTasks returns a queue's tasks that ran out of retries,
+// oldest first
+func (i *Inspector) ListArchivedTasks(qname string) ([]*TaskInfo, error) {
+	return i.listTasks(qname, TaskStateArchived)
+}
+
+// removeFromState takes a task out of the list or set for its state
+func (b *broker) removeFromState(qname, id string, state TaskState) {
+	switch state {
+	case TaskStatePending:
+		key := queueKey(qname, "pending")
+		ids, _ := b.store.LRange(key, 0, -1)
+		b.store.Del(key)
+		for _, other := range ids {
+			if other != id {
+				b.store.RPush(key, other)
+			}
+		}
+	case TaskStateScheduled, TaskStateRetry, TaskStateArchived:
+		b.store.ZRem(queueKey(qname, state.String()), id)
+	}
+}
+
+// DeleteTask deletes a task that isn't running, releasing its unique
+// lock
+func (i *Inspector) DeleteTask(qname, id string) error {
+	if err := i.checkQueue(qname); err != nil {
+		return err
+	}
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg, state, err := b.readMessage(qname, id)
+	if err != nil {
+		return err
+	}
+	if state == TaskStateActive {
+		return fmt.Errorf("cannot delete task in active state: %s", id)
+	}
+	b.removeFromState(qname, id, state)
+	b.store.Del(taskKey(qname, id))
+	if msg.UniqueKey != "" {
+		if owner, err := b.store.Get(msg.UniqueKey); err == nil && owner == id {
+			b.store.Del(msg.UniqueKey)
+		}
+	}
+	return nil
+}
+
+// RunTask makes a scheduled, retry or archived task pending, to run now
+func (i *Inspector) RunTask(qname, id string) error {
+	if err := i.checkQueue(qname); err != nil {
+		return err
+	}
+	b := i.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, state, err := b.readMessage(qname, id)
+	if err != nil {
+		return err
+	}
+	switch state {
+	case TaskStateActive, TaskStatePending:
+		return fmt.Errorf("task is already %s", state)
+	}
+	b.removeFromState(qname, id, state)
+	b.store.LPush(queueKey(qname, "pending"), id)
+	b.store.HSet(taskKey(qname, id), "state", TaskStatePending.String())
+	return nil
+}
+
+func (i *Inspector) runAll(qname string, state TaskState) (int, error) {
+	tasks, err := i.listTasks(qname, state)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, t := range tasks {
+		if i.RunTask(qname, t.ID) == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// RunAllScheduledTasks makes every scheduled task pending
+func (i *Inspector) RunAllScheduledTasks(qname string) (int, error) {
+	return i.runAll(qname, TaskStateScheduled)
+}
+
+// RunAllRetryTasks makes every task waiting for a retry pending
+func (i *Inspector) RunAllRetryTasks(qname string) (int, error) {
+	return i.runAll(qname, TaskStateRetry)
+}
+
+// RunAllArchivedTasks makes every archived task pending, for another
+// round of attempts
+func (i *Inspector) RunAllArchivedTasks(qname string) (int, error) {
+	return i.runAll(qname, TaskStateArchived)
+}
@@ -0,0 +1,823 @@
+package main
+
+// Developed by PowerShield, as an alternative to asynq
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+var errNil = errors.New("redis: nil")
+
+// fakeRedis implements RedisStore in memory, as the Redis emulator's
+// Client does
+type fakeRedis struct {
+	strings map[string]string
+	expires map[string]time.Time
+	lists   map[string][]string
+	sets    map[string]map[string]bool
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		strings: map[string]string{},
+		expires: map[string]time.Time{},
+		lists:   map[string][]string{},
+		sets:    map[string]map[string]bool{},
+		hashes:  map[string]map[string]string{},
+		zsets:   map[string]map[string]float64{},
+	}
+}
+
+func (r *fakeRedis) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if _, err := r.Get(key); err == nil {
+		return false, nil
+	}
+	r.strings[key] = fmt.Sprint(value)
+	delete(r.expires, key)
+	if expiration > 0 {
+		r.expires[key] = time.Now().Add(expiration)
+	}
+	return true, nil
+}
+
+func (r *fakeRedis) Get(key string) (string, error) {
+	if at, ok := r.expires[key]; ok && time.Now().After(at) {
+		delete(r.strings, key)
+		delete(r.expires, key)
+	}
+	v, ok := r.strings[key]
+	if !ok {
+		return "", errNil
+	}
+	return v, nil
+}
+
+func (r *fakeRedis) Del(keys ...string) (int, error) {
+	n := 0
+	for _, key := range keys {
+		if _, ok := r.strings[key]; ok {
+			n++
+		}
+		delete(r.strings, key)
+		delete(r.expires, key)
+		delete(r.lists, key)
+		delete(r.sets, key)
+		delete(r.hashes, key)
+		delete(r.zsets, key)
+	}
+	return n, nil
+}
+
+func (r *fakeRedis) Incr(key string) (int64, error) {
+	n, _ := strconv.ParseInt(r.strings[key], 10, 64)
+	n++
+	r.strings[key] = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+func (r *fakeRedis) LPush(key string, values ...interface{}) (int, error) {
+	for _, v := range values {
+		r.lists[key] = append([]string{fmt.Sprint(v)}, r.lists[key]...)
+	}
+	return len(r.lists[key]), nil
+}
+
+func (r *fakeRedis) RPush(key string, values ...interface{}) (int, error) {
+	for _, v := range values {
+		r.lists[key] = append(r.lists[key], fmt.Sprint(v))
+	}
+	return len(r.lists[key]), nil
+}
+
+func (r *fakeRedis) RPop(key string) (string, error) {
+	list := r.lists[key]
+	if len(list) == 0 {
+		return "", errNil
+	}
+	r.lists[key] = list[:len(list)-1]
+	return list[len(list)-1], nil
+}
+
+func (r *fakeRedis) LRange(key string, start, stop int) ([]string, error) {
+	return append([]string{}, r.lists[key]...), nil
+}
+
+func (r *fakeRedis) LLen(key string) (int, error) {
+	return len(r.lists[key]), nil
+}
+
+func (r *fakeRedis) SAdd(key string, members ...interface{}) (int, error) {
+	if r.sets[key] == nil {
+		r.sets[key] = map[string]bool{}
+	}
+	for _, m := range members {
+		r.sets[key][fmt.Sprint(m)] = true
+	}
+	return len(members), nil
+}
+
+func (r *fakeRedis) SRem(key string, members ...interface{}) (int, error) {
+	for _, m := range members {
+		delete(r.sets[key], fmt.Sprint(m))
+	}
+	return len(members), nil
+}
+
+func (r *fakeRedis) SMembers(key string) ([]string, error) {
+	members := []string{}
+	for m := range r.sets[key] {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+func (r *fakeRedis) HSet(key, field string, value interface{}) error {
+	if r.hashes[key] == nil {
+		r.hashes[key] = map[string]string{}
+	}
+	r.hashes[key][field] = fmt.Sprint(value)
+	return nil
+}
+
+func (r *fakeRedis) HGet(key, field string) (string, error) {
+	v, ok := r.hashes[key][field]
+	if !ok {
+		return "", errNil
+	}
+	return v, nil
+}
+
+func (r *fakeRedis) ZAdd(key string, members ...interface{}) (int, error) {
+	if r.zsets[key] == nil {
+		r.zsets[key] = map[string]float64{}
+	}
+	for i := 0; i+1 < len(members); i += 2 {
+		r.zsets[key][fmt.Sprint(members[i+1])] = members[i].(float64)
+	}
+	return len(members) / 2, nil
+}
+
+func (r *fakeRedis) ZRange(key string, start, stop int) ([]string, error) {
+	zset := r.zsets[key]
+	members := []string{}
+	for m := range zset {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if zset[members[i]] != zset[members[j]] {
+			return zset[members[i]] < zset[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	return members, nil
+}
+
+func (r *fakeRedis) ZScore(key, member string) (float64, error) {
+	s, ok := r.zsets[key][member]
+	if !ok {
+		return 0, errNil
+	}
+	return s, nil
+}
+
+func (r *fakeRedis) ZRem(key string, members ...interface{}) (int, error) {
+	for _, m := range members {
+		delete(r.zsets[key], fmt.Sprint(m))
+	}
+	return len(members), nil
+}
+
+func (r *fakeRedis) ZCard(key string) (int, error) {
+	return len(r.zsets[key]), nil
+}
+
+// setClock fixes timeNow, returning a function that moves it forward
+func setClock() func(time.Duration) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	timeNow = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	return func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+}
+
+// recorder is a handler remembering the payloads it saw, in order
+type recorder struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *recorder) ProcessTask(ctx context.Context, task *Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, string(task.Payload()))
+	return nil
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.seen, ",")
+}
+
+func testEnqueue() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	defer client.Close()
+
+	info, err := client.Enqueue(NewTask("email:welcome", []byte(`{"user_id":42}`)))
+	if err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if info.ID == "" || info.Queue != "default" || info.Type != "email:welcome" ||
+		info.State != TaskStatePending || info.MaxRetry != 25 || info.Timeout != 30*time.Minute ||
+		!info.NextProcessAt.Equal(timeNow()) {
+		fmt.Printf("  Wrong info: %+v\n", info)
+		return false
+	}
+
+	if _, err := client.Enqueue(NewTask("", nil)); err == nil {
+		fmt.Println("  Empty type accepted")
+		return false
+	}
+
+	inspector := NewInspector(store)
+	queues, _ := inspector.Queues()
+	pending, _ := inspector.ListPendingTasks("default")
+	if len(queues) != 1 || queues[0] != "default" || len(pending) != 1 ||
+		pending[0].ID != info.ID || string(pending[0].Payload) != `{"user_id":42}` ||
+		pending[0].State.String() != "pending" {
+		fmt.Println("  Task not stored:", queues, pending)
+		return false
+	}
+	return true
+}
+
+func testOptions() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+
+	deadline := timeNow().Add(time.Hour)
+	task := NewTask("report:generate", nil, Queue("reports"), MaxRetry(3))
+	info, err := client.Enqueue(task, MaxRetry(1), TaskID("report-7"), Timeout(time.Minute), Deadline(deadline))
+	if err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if info.ID != "report-7" || info.Queue != "reports" || info.MaxRetry != 1 ||
+		info.Timeout != time.Minute || !info.Deadline.Equal(deadline) {
+		fmt.Printf("  Options not applied: %+v\n", info)
+		return false
+	}
+
+	if _, err := client.Enqueue(task, TaskID("report-7")); err != ErrTaskIDConflict {
+		fmt.Println("  Expected ID conflict, got:", err)
+		return false
+	}
+	if _, err := client.Enqueue(task, TaskID("report-7"), Queue("other")); err != nil {
+		fmt.Println("  IDs should be per queue:", err)
+		return false
+	}
+	if Queue("reports").String() != `Queue("reports")` || MaxRetry(-2).Value() != 0 {
+		fmt.Println("  Wrong option values")
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.EnqueueContext(ctx, task); err != context.Canceled {
+		fmt.Println("  Cancelled context not honored:", err)
+		return false
+	}
+	return true
+}
+
+func testScheduled() bool {
+	advance := setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	srv := NewServer(store, Config{})
+	rec := &recorder{}
+
+	in, _ := client.Enqueue(NewTask("reminder", []byte("in")), ProcessIn(10*time.Minute))
+	at, _ := client.Enqueue(NewTask("reminder", []byte("at")), ProcessAt(timeNow().Add(5*time.Minute)))
+	client.Enqueue(NewTask("reminder", []byte("now")))
+	if in.State != TaskStateScheduled || !in.NextProcessAt.Equal(timeNow().Add(10*time.Minute)) ||
+		at.State != TaskStateScheduled {
+		fmt.Printf("  Not scheduled: %+v\n", in)
+		return false
+	}
+
+	if n := srv.Drain(rec); n != 1 || rec.String() != "now" {
+		fmt.Println("  Ran early:", n, rec)
+		return false
+	}
+	advance(5 * time.Minute)
+	srv.Drain(rec)
+	advance(5 * time.Minute)
+	srv.Drain(rec)
+	if rec.String() != "now,at,in" {
+		fmt.Println("  Wrong order:", rec)
+		return false
+	}
+
+	client.Enqueue(NewTask("reminder", []byte("later")), ProcessIn(24*time.Hour))
+	if n := srv.DrainAll(rec); n != 1 {
+		fmt.Println("  DrainAll should run scheduled tasks:", n)
+		return false
+	}
+	return true
+}
+
+func testOrdering() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	for i := 1; i <= 5; i++ {
+		client.Enqueue(NewTask("job", []byte(strconv.Itoa(i))))
+	}
+	pending, _ := NewInspector(store).ListPendingTasks("default")
+	if len(pending) != 5 || string(pending[0].Payload) != "1" {
+		fmt.Println("  Pending list not next first")
+		return false
+	}
+
+	rec := &recorder{}
+	if n := NewServer(store, Config{}).Drain(rec); n != 5 || rec.String() != "1,2,3,4,5" {
+		fmt.Println("  Not first in, first out:", n, rec)
+		return false
+	}
+	return true
+}
+
+func testStrictPriority() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	client.Enqueue(NewTask("job", []byte("low")), Queue("low"))
+	client.Enqueue(NewTask("job", []byte("default")))
+	client.Enqueue(NewTask("job", []byte("critical-1")), Queue("critical"))
+	client.Enqueue(NewTask("job", []byte("critical-2")), Queue("critical"))
+	client.Enqueue(NewTask("job", []byte("ignored")), Queue("unlisted"))
+
+	srv := NewServer(store, Config{
+		Queues:         map[string]int{"critical": 6, "default": 3, "low": 1},
+		StrictPriority: true,
+	})
+	rec := &recorder{}
+	srv.Drain(rec)
+	if rec.String() != "critical-1,critical-2,default,low" {
+		fmt.Println("  Wrong order:", rec)
+		return false
+	}
+	return true
+}
+
+func testWeightedPriority() bool {
+	srv := NewServer(newFakeRedis(), Config{
+		Queues: map[string]int{"critical": 6, "default": 3, "low": 1, "off": 0},
+	})
+	first := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		order := srv.queueOrder()
+		if len(order) != 3 {
+			fmt.Println("  Wrong queues:", order)
+			return false
+		}
+		first[order[0]]++
+	}
+	// About 1200, 600 and 200
+	if first["critical"] < 1000 || first["default"] < 450 || first["low"] < 100 || first["low"] > 320 {
+		fmt.Println("  Not weighted:", first)
+		return false
+	}
+	return true
+}
+
+func testRetries() bool {
+	advance := setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	inspector := NewInspector(store)
+	attempts := []int{}
+	handler := HandlerFunc(func(ctx context.Context, task *Task) error {
+		n, _ := GetRetryCount(ctx)
+		attempts = append(attempts, n)
+		if n < 2 {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	})
+	srv := NewServer(store, Config{
+		RetryDelayFunc: func(n int, err error, task *Task) time.Duration {
+			return time.Duration(n) * time.Minute
+		},
+	})
+
+	info, _ := client.Enqueue(NewTask("email", nil))
+	srv.Drain(handler)
+	retry, _ := inspector.ListRetryTasks("default")
+	if len(retry) != 1 || retry[0].Retried != 1 || retry[0].LastErr != "smtp unavailable" ||
+		!retry[0].NextProcessAt.Equal(timeNow().Add(time.Minute)) || retry[0].LastFailedAt.IsZero() {
+		fmt.Println("  Not scheduled for retry:", retry)
+		return false
+	}
+
+	advance(time.Minute)
+	srv.Drain(handler)
+	if got, _ := inspector.GetTaskInfo("default", info.ID); got.State != TaskStateRetry ||
+		!got.NextProcessAt.Equal(timeNow().Add(2*time.Minute)) {
+		fmt.Println("  Backoff not applied:", got)
+		return false
+	}
+	advance(2 * time.Minute)
+	srv.Drain(handler)
+
+	if fmt.Sprint(attempts) != "[0 1 2]" {
+		fmt.Println("  Wrong attempts:", attempts)
+		return false
+	}
+	if _, err := inspector.GetTaskInfo("default", info.ID); err != ErrTaskNotFound {
+		fmt.Println("  Completed task kept:", err)
+		return false
+	}
+	q, _ := inspector.GetQueueInfo("default")
+	if q.Processed != 3 || q.Failed != 2 || q.Size != 0 {
+		fmt.Printf("  Wrong counts: %+v\n", q)
+		return false
+	}
+	return true
+}
+
+func testArchive() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	var handled []string
+	srv := NewServer(store, Config{
+		ErrorHandler: ErrorHandlerFunc(func(ctx context.Context, task *Task, err error) {
+			handled = append(handled, err.Error())
+		}),
+	})
+
+	info, _ := client.Enqueue(NewTask("charge", nil), MaxRetry(2))
+	failing := HandlerFunc(func(ctx context.Context, task *Task) error {
+		return errors.New("card declined")
+	})
+	if n := srv.DrainAll(failing); n != 3 || len(handled) != 3 {
+		fmt.Println("  Wrong attempts:", n, handled)
+		return false
+	}
+	archived, _ := NewInspector(store).ListArchivedTasks("default")
+	if len(archived) != 1 || archived[0].ID != info.ID || archived[0].Retried != 2 ||
+		archived[0].State != TaskStateArchived || archived[0].LastErr != "card declined" {
+		fmt.Println("  Not archived:", archived)
+		return false
+	}
+	return true
+}
+
+func testFailures() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	inspector := NewInspector(store)
+	srv := NewServer(store, Config{})
+
+	skip, _ := client.Enqueue(NewTask("parse", nil))
+	srv.Drain(HandlerFunc(func(ctx context.Context, task *Task) error {
+		return fmt.Errorf("malformed payload: %w", SkipRetry)
+	}))
+	if got, _ := inspector.GetTaskInfo("default", skip.ID); got.State != TaskStateArchived || got.Retried != 0 {
+		fmt.Println("  SkipRetry not archived:", got)
+		return false
+	}
+
+	panics, _ := client.Enqueue(NewTask("parse", nil))
+	srv.Drain(HandlerFunc(func(ctx context.Context, task *Task) error {
+		panic("nil map")
+	}))
+	if got, _ := inspector.GetTaskInfo("default", panics.ID); got.State != TaskStateRetry || got.LastErr != "panic: nil map" {
+		fmt.Println("  Panic not retried:", got)
+		return false
+	}
+
+	late, _ := client.Enqueue(NewTask("parse", nil), Deadline(timeNow().Add(-time.Second)), MaxRetry(0))
+	srv.Drain(HandlerFunc(func(ctx context.Context, task *Task) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	if got, _ := inspector.GetTaskInfo("default", late.ID); got.State != TaskStateArchived ||
+		got.LastErr != context.DeadlineExceeded.Error() {
+		fmt.Println("  Deadline not enforced:", got)
+		return false
+	}
+	return true
+}
+
+func testDefaultRetryDelay() bool {
+	for i := 0; i < 100; i++ {
+		first := DefaultRetryDelayFunc(1, nil, nil)
+		tenth := DefaultRetryDelayFunc(10, nil, nil)
+		if first < 15*time.Second || first > 75*time.Second ||
+			tenth < 10015*time.Second || tenth > 10334*time.Second {
+			fmt.Println("  Out of range:", first, tenth)
+			return false
+		}
+	}
+	return true
+}
+
+func testUnique() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	task := NewTask("sync:account", []byte("42"), Unique(time.Hour))
+
+	first, err := client.Enqueue(task)
+	if err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if _, err := client.Enqueue(task); err != ErrDuplicateTask {
+		fmt.Println("  Expected duplicate, got:", err)
+		return false
+	}
+	if _, err := client.Enqueue(NewTask("sync:account", []byte("43"), Unique(time.Hour))); err != nil {
+		fmt.Println("  Different payload rejected:", err)
+		return false
+	}
+	if _, err := client.Enqueue(task, Queue("other")); err != nil {
+		fmt.Println("  Other queue rejected:", err)
+		return false
+	}
+	if _, err := client.Enqueue(task, Unique(time.Millisecond)); err == nil {
+		fmt.Println("  Short TTL accepted")
+		return false
+	}
+
+	NewServer(store, Config{}).Drain(&recorder{})
+	again, err := client.Enqueue(task)
+	if err != nil {
+		fmt.Println("  Lock not released on completion:", err)
+		return false
+	}
+	if err := NewInspector(store).DeleteTask("default", again.ID); err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if _, err := client.Enqueue(task); err != nil || first.ID == again.ID {
+		fmt.Println("  Lock not released on delete:", err)
+		return false
+	}
+	return true
+}
+
+func testServeMux() (ok bool) {
+	mux := NewServeMux()
+	var calls []string
+	mux.HandleFunc("email:", func(ctx context.Context, task *Task) error {
+		calls = append(calls, "email:*")
+		return nil
+	})
+	mux.HandleFunc("email:welcome", func(ctx context.Context, task *Task) error {
+		calls = append(calls, "email:welcome")
+		return nil
+	})
+	logged := func(name string) MiddlewareFunc {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, task *Task) error {
+				calls = append(calls, name)
+				return next.ProcessTask(ctx, task)
+			})
+		}
+	}
+	mux.Use(logged("outer"), logged("inner"))
+
+	ctx := context.Background()
+	mux.ProcessTask(ctx, NewTask("email:welcome", nil))
+	mux.ProcessTask(ctx, NewTask("email:reset", nil))
+	if strings.Join(calls, " ") != "outer inner email:welcome outer inner email:*" {
+		fmt.Println("  Wrong routing:", calls)
+		return false
+	}
+	if _, pattern := mux.Handler(NewTask("email:reset", nil)); pattern != "email:" {
+		fmt.Println("  Wrong pattern:", pattern)
+		return false
+	}
+	err := mux.ProcessTask(ctx, NewTask("sms:send", nil))
+	if err == nil || err.Error() != `handler not found for task "sms:send"` {
+		fmt.Println("  Expected not found, got:", err)
+		return false
+	}
+
+	defer func() { ok = recover() != nil }()
+	mux.HandleFunc("email:", func(ctx context.Context, task *Task) error { return nil })
+	fmt.Println("  Duplicate pattern accepted")
+	return false
+}
+
+func testConcurrency() bool {
+	timeNow = time.Now
+	store := newFakeRedis()
+	client := NewClient(store)
+	for i := 0; i < 6; i++ {
+		client.Enqueue(NewTask("resize", []byte(strconv.Itoa(i))))
+	}
+
+	var mu sync.Mutex
+	running, peak, done := 0, 0, 0
+	finished := make(chan struct{})
+	handler := HandlerFunc(func(ctx context.Context, task *Task) error {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		done++
+		if done == 6 {
+			close(finished)
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	srv := NewServer(store, Config{Concurrency: 2, TaskCheckInterval: 5 * time.Millisecond})
+	if err := srv.Start(handler); err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if err := srv.Start(handler); err == nil {
+		fmt.Println("  Started twice")
+		return false
+	}
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		fmt.Println("  Tasks not processed")
+		return false
+	}
+	srv.Shutdown()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak != 2 {
+		fmt.Println("  Wrong concurrency:", peak)
+		return false
+	}
+	q, _ := NewInspector(store).GetQueueInfo("default")
+	return q.Processed == 6 && q.Size == 0
+}
+
+func testShutdown() bool {
+	timeNow = time.Now
+	store := newFakeRedis()
+	client := NewClient(store)
+	info, _ := client.Enqueue(NewTask("export", nil))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := HandlerFunc(func(ctx context.Context, task *Task) error {
+		close(started)
+		<-release // ignores ctx, so shutdown gives up on it
+		return nil
+	})
+
+	srv := NewServer(store, Config{ShutdownTimeout: 20 * time.Millisecond, TaskCheckInterval: 5 * time.Millisecond})
+	srv.Start(handler)
+	<-started
+	active, _ := NewInspector(store).ListActiveTasks("default")
+	if len(active) != 1 || active[0].ID != info.ID {
+		fmt.Println("  Task not active:", active)
+		return false
+	}
+
+	srv.Stop()
+	srv.Shutdown()
+	got, _ := NewInspector(store).GetTaskInfo("default", info.ID)
+	if got == nil || got.State != TaskStatePending {
+		fmt.Println("  Task not requeued:", got)
+		return false
+	}
+	if err := srv.Start(handler); err != ErrServerClosed {
+		fmt.Println("  Expected closed, got:", err)
+		return false
+	}
+	return true
+}
+
+func testInspector() bool {
+	setClock()
+	store := newFakeRedis()
+	client := NewClient(store)
+	inspector := NewInspector(store)
+
+	a, _ := client.Enqueue(NewTask("job", []byte("a")))
+	b, _ := client.Enqueue(NewTask("job", []byte("b")))
+	client.Enqueue(NewTask("job", []byte("c")))
+	later, _ := client.Enqueue(NewTask("job", []byte("later")), ProcessIn(time.Hour))
+
+	q, _ := inspector.GetQueueInfo("default")
+	if q.Size != 4 || q.Pending != 3 || q.Scheduled != 1 {
+		fmt.Printf("  Wrong counts: %+v\n", q)
+		return false
+	}
+	if err := inspector.DeleteTask("default", b.ID); err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if err := inspector.RunTask("default", later.ID); err != nil {
+		fmt.Println("  Error:", err)
+		return false
+	}
+	if err := inspector.RunTask("default", a.ID); err == nil {
+		fmt.Println("  Ran a pending task")
+		return false
+	}
+
+	rec := &recorder{}
+	srv := NewServer(store, Config{})
+	srv.Drain(rec)
+	if rec.String() != "a,c,later" {
+		fmt.Println("  Wrong tasks run:", rec)
+		return false
+	}
+
+	client.Enqueue(NewTask("job", []byte("x")), MaxRetry(0), TaskID("x"))
+	client.Enqueue(NewTask("job", []byte("y")), MaxRetry(0), TaskID("y"))
+	srv.Drain(HandlerFunc(func(ctx context.Context, task *Task) error { return errors.New("down") }))
+	if n, _ := inspector.RunAllArchivedTasks("default"); n != 2 {
+		fmt.Println("  Wrong archived count:", n)
+		return false
+	}
+	srv.Drain(rec)
+	if rec.String() != "a,c,later,x,y" {
+		fmt.Println("  Archived tasks not run:", rec)
+		return false
+	}
+
+	if _, err := inspector.GetQueueInfo("missing"); err != ErrQueueNotFound {
+		fmt.Println("  Expected queue not found, got:", err)
+		return false
+	}
+	if err := inspector.DeleteTask("default", "missing"); err != ErrTaskNotFound {
+		fmt.Println("  Expected task not found, got:", err)
+		return false
+	}
+	return true
+}
+
+func main() {
+	fmt.Println("Running asynq Emulator Tests...")
+	fmt.Println("===============================")
+
+	runTest("Enqueue", testEnqueue)
+	runTest("Options", testOptions)
+	runTest("Scheduled", testScheduled)
+	runTest("Ordering", testOrdering)
+	runTest("Strict Priority", testStrictPriority)
+	runTest("Weighted Priority", testWeightedPriority)
+	runTest("Retries", testRetries)
+	runTest("Archive", testArchive)
+	runTest("Failures", testFailures)
+	runTest("Default Retry Delay", testDefaultRetryDelay)
+	runTest("Unique", testUnique)
+	runTest("ServeMux", testServeMux)
+	runTest("Concurrency", testConcurrency)
+	runTest("Shutdown", testShutdown)
+	runTest("Inspector", testInspector)
+
+	fmt.Println("===============================")
+	fmt.Println("All tests completed!")
+}
@@ -2,10 +2,46 @@ package main
 
 // Developed by PowerShield, as an alternative to Gin
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Context represents the context of an HTTP request in Gin
@@ -13,17 +49,64 @@ type Context struct {
 	Request  *Request
 	Response *Response
 	Params   map[string]string
+
+	// Writer writes the response; middleware may replace it with a wrapper
+	Writer    ResponseWriter
+	writermem responseWriter
+
 	handlers []HandlerFunc
 	index    int
+	fullPath string
+
+	// Keys holds values shared between handlers in the chain
+	Keys map[string]interface{}
+	mu   sync.RWMutex
+
+	// Errors collects the errors attached with c.Error
+	Errors errorMsgs
+
+	engine        *Engine
+	writer        http.ResponseWriter // real writer when served via ServeHTTP
+	headerSent    bool
+	bodySent      int // bytes of Response.Body already written to writer
+	chunkStart    int // start of the chunk being collected for Response.Chunks
+	sameSite      http.SameSite
+	formParsed    bool
+	formErr       error
+	form          url.Values
+	postForm      url.Values
+	multipartForm *multipart.Form
 }
 
 // Request represents an HTTP request
 type Request struct {
-	Method  string
-	Path    string
-	Headers map[string]string
-	Body    []byte
-	Query   url.Values
+	Method     string
+	Path       string
+	Headers    map[string]string
+	Body       []byte
+	Query      url.Values
+	RemoteAddr string
+
+	ctx context.Context
+}
+
+// Context returns the request's context, or context.Background if none
+// was set. Servers cancel it when the client disconnects.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of the request using ctx
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("nil context")
+	}
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
 }
 
 // Response represents an HTTP response
@@ -31,6 +114,61 @@ type Response struct {
 	StatusCode int
 	Headers    map[string]string
 	Body       []byte
+
+	// Cookies holds cookies set by the handler; each one is written as its
+	// own Set-Cookie header since Headers holds a single value per name
+	Cookies []*http.Cookie
+
+	// Template records the last HTML template rendered in TestMode
+	Template *RenderedTemplate
+
+	// Chunks records the body written between each flush of a streamed
+	// response, e.g. one entry per c.Stream step
+	Chunks [][]byte
+
+	// Pushes records the HTTP/2 server pushes initiated with c.Push
+	Pushes []PushPromise
+}
+
+// PushPromise describes a resource pushed with c.Push
+type PushPromise struct {
+	Target string
+	Method string
+	Header http.Header
+}
+
+// RenderedTemplate captures an HTML render for assertions in TestMode
+type RenderedTemplate struct {
+	Name   string
+	Data   interface{}
+	Output string
+}
+
+// Gin modes
+const (
+	DebugMode   = "debug"
+	ReleaseMode = "release"
+	TestMode    = "test"
+)
+
+// ginMode is the current mode, shared by all engines as in Gin
+var ginMode = DebugMode
+
+// SetMode sets the Gin mode (DebugMode, ReleaseMode or TestMode)
+func SetMode(value string) {
+	switch value {
+	case DebugMode, ReleaseMode, TestMode:
+		ginMode = value
+	case "":
+		ginMode = DebugMode
+	default:
+		panic("gin mode unknown: " + value + " (available mode: debug release test)")
+	}
+}
+
+// Mode returns the current Gin mode
+func Mode() string {
+	return ginMode
 }
 
 // HandlerFunc defines the handler function type
@@ -38,25 +176,137 @@ type HandlerFunc func(*Context)
 
 // Engine is the core of the Gin framework
 type Engine struct {
-	routes     map[string]map[string][]HandlerFunc // method -> path -> handlers
+	trees      map[string]*node // method -> route tree
 	middleware []HandlerFunc
+
+	// MaxMultipartMemory limits the memory used when parsing multipart forms
+	MaxMultipartMemory int64
+
+	// MaxDecompressedBodySize limits how large Gzip lets a compressed
+	// request body grow; larger ones are rejected with 413
+	MaxDecompressedBodySize int64
+
+	// RedirectTrailingSlash redirects /foo/ to /foo (or the reverse) when
+	// only the other form is registered. Enabled by default.
+	RedirectTrailingSlash bool
+
+	// RedirectFixedPath redirects paths that only match after cleaning and
+	// a case-insensitive lookup, e.g. /FOO/../Bar to /bar
+	RedirectFixedPath bool
+
+	// HandleMethodNotAllowed answers 405 with an Allow header when the path
+	// exists for other methods, instead of 404
+	HandleMethodNotAllowed bool
+
+	// ForwardedByClientIP makes ClientIP read RemoteIPHeaders on requests
+	// from trusted proxies. Enabled by default.
+	ForwardedByClientIP bool
+
+	// RemoteIPHeaders are the headers ClientIP checks, in order
+	RemoteIPHeaders []string
+
+	// TrustedPlatform names a client address header set by the hosting
+	// platform, e.g. PlatformCloudflare, which ClientIP uses as is
+	TrustedPlatform string
+
+	trustedCIDRs []*net.IPNet // nil trusts every proxy
+
+	noRoute  []HandlerFunc
+	noMethod []HandlerFunc
+
+	routes      RoutesInfo
+	namedRoutes map[string]string
+	routeDocs   map[string]*routeDoc // OpenAPI metadata by "METHOD path"
+
+	// OpenAPIInfo describes the API in OpenAPISpec
+	OpenAPIInfo OpenAPIInfo
+
+	secureJSONPrefix string
+
+	htmlTemplate *template.Template
+	htmlLoader   func() *template.Template // reloads templates in DebugMode
+	funcMap      template.FuncMap
+	delims       [2]string
+}
+
+// defaultMultipartMemory is the default MaxMultipartMemory (32 MB)
+const defaultMultipartMemory = 32 << 20
+
+// defaultDecompressedBodySize is the default MaxDecompressedBodySize (32 MB)
+const defaultDecompressedBodySize = 32 << 20
+
+// node is a route tree node holding one path segment. Lookups walk the tree
+// one segment at a time, preferring static children over parameters and
+// parameters over catch-all wildcards.
+type node struct {
+	segment    string
+	children   map[string]*node // static children keyed by segment
+	paramChild *node            // ":name" child, at most one per node
+	wildChild  *node            // "*name" catch-all child, always a leaf
+	handlers   []HandlerFunc    // the route's own handlers
+	group      *RouterGroup     // group the route was registered on, or nil
+	fullPath   string
 }
 
 // RouterGroup is used for grouping routes
 type RouterGroup struct {
-	engine      *Engine
-	prefix      string
-	middleware  []HandlerFunc
+	engine     *Engine
+	prefix     string
+	middleware []HandlerFunc // the group's own middleware
+	parent     *RouterGroup
 }
 
 // H is a shortcut for map[string]interface{}
 type H map[string]interface{}
 
+// MarshalXML renders H as <map><key>value</key>...</map> with sorted keys
+func (h H) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "map"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := e.EncodeElement(h[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+// Content types used by the render methods
+const (
+	MIMEJSON     = "application/json"
+	MIMEHTML     = "text/html"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/yaml"
+	MIMETOML     = "application/toml"
+	MIMEPROTOBUF = "application/x-protobuf"
+	MIMEJSONP    = "application/javascript"
+)
+
+// ProtoMarshaler is implemented by generated protobuf messages
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
 // New creates a new Engine instance
 func New() *Engine {
 	return &Engine{
-		routes:     make(map[string]map[string][]HandlerFunc),
-		middleware: []HandlerFunc{},
+		trees:                 make(map[string]*node),
+		middleware:            []HandlerFunc{},
+		MaxMultipartMemory:      defaultMultipartMemory,
+		MaxDecompressedBodySize: defaultDecompressedBodySize,
+		RedirectTrailingSlash:   true,
+		ForwardedByClientIP:     true,
+		RemoteIPHeaders:         []string{"X-Forwarded-For", "X-Real-IP"},
+		secureJSONPrefix:        "while(1);",
 	}
 }
 
@@ -72,293 +322,6128 @@ func (e *Engine) Use(middleware ...HandlerFunc) {
 	e.middleware = append(e.middleware, middleware...)
 }
 
+// NoRoute sets the handlers run when no route matches (404)
+func (e *Engine) NoRoute(handlers ...HandlerFunc) {
+	e.noRoute = handlers
+}
+
+// NoMethod sets the handlers run for 405 responses when
+// HandleMethodNotAllowed is enabled
+func (e *Engine) NoMethod(handlers ...HandlerFunc) {
+	e.noMethod = handlers
+}
+
 // Group creates a new router group
 func (e *Engine) Group(prefix string, handlers ...HandlerFunc) *RouterGroup {
 	return &RouterGroup{
 		engine:     e,
 		prefix:     prefix,
-		middleware: handlers,
+		middleware: append([]HandlerFunc(nil), handlers...),
 	}
 }
 
 // GET registers a GET route
-func (e *Engine) GET(path string, handlers ...HandlerFunc) {
-	e.addRoute("GET", path, handlers)
+func (e *Engine) GET(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("GET", path, nil, handlers)
 }
 
 // POST registers a POST route
-func (e *Engine) POST(path string, handlers ...HandlerFunc) {
-	e.addRoute("POST", path, handlers)
+func (e *Engine) POST(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("POST", path, nil, handlers)
 }
 
 // PUT registers a PUT route
-func (e *Engine) PUT(path string, handlers ...HandlerFunc) {
-	e.addRoute("PUT", path, handlers)
+func (e *Engine) PUT(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("PUT", path, nil, handlers)
 }
 
 // DELETE registers a DELETE route
-func (e *Engine) DELETE(path string, handlers ...HandlerFunc) {
-	e.addRoute("DELETE", path, handlers)
+func (e *Engine) DELETE(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("DELETE", path, nil, handlers)
 }
 
 // PATCH registers a PATCH route
-func (e *Engine) PATCH(path string, handlers ...HandlerFunc) {
-	e.addRoute("PATCH", path, handlers)
+func (e *Engine) PATCH(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("PATCH", path, nil, handlers)
 }
 
-// addRoute adds a route to the engine
-func (e *Engine) addRoute(method, path string, handlers []HandlerFunc) {
-	if e.routes[method] == nil {
-		e.routes[method] = make(map[string][]HandlerFunc)
-	}
-	// Combine global middleware with route handlers
-	allHandlers := append(e.middleware, handlers...)
-	e.routes[method][path] = allHandlers
+// HEAD registers a HEAD route
+func (e *Engine) HEAD(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("HEAD", path, nil, handlers)
 }
 
-// ServeHTTP simulates handling an HTTP request
-func (e *Engine) ServeHTTP(method, path string, body []byte, headers map[string]string) *Response {
-	// Parse query string from path
-	pathParts := strings.SplitN(path, "?", 2)
-	cleanPath := pathParts[0]
-	queryString := ""
-	if len(pathParts) > 1 {
-		queryString = pathParts[1]
-	}
-
-	queryValues, _ := url.ParseQuery(queryString)
+// OPTIONS registers an OPTIONS route
+func (e *Engine) OPTIONS(path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute("OPTIONS", path, nil, handlers)
+}
 
-	// Create context
-	ctx := &Context{
-		Request: &Request{
-			Method:  method,
-			Path:    cleanPath,
-			Headers: headers,
-			Body:    body,
-			Query:   queryValues,
-		},
-		Response: &Response{
-			StatusCode: 200,
-			Headers:    make(map[string]string),
-			Body:       []byte{},
-		},
-		Params:   make(map[string]string),
-		handlers: []HandlerFunc{},
-		index:    -1,
-	}
+// Handle registers a route for an arbitrary method
+func (e *Engine) Handle(method, path string, handlers ...HandlerFunc) *Route {
+	return e.addRoute(method, path, nil, handlers)
+}
 
-	// Find matching route
-	if routes, ok := e.routes[method]; ok {
-		for routePath, handlers := range routes {
-			params := matchRoute(routePath, cleanPath)
-			if params != nil {
-				ctx.Params = params
-				ctx.handlers = handlers
-				ctx.Next()
-				return ctx.Response
-			}
+// Any registers a route for all common HTTP methods, returning the GET one
+func (e *Engine) Any(path string, handlers ...HandlerFunc) *Route {
+	var route *Route
+	for _, method := range anyMethods {
+		if r := e.addRoute(method, path, nil, handlers); route == nil {
+			route = r
 		}
 	}
+	return route
+}
 
-	// No route found
-	ctx.Response.StatusCode = 404
-	ctx.Response.Body = []byte("404 Not Found")
-	return ctx.Response
+// anyMethods are the methods registered by Any
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "HEAD", "OPTIONS", "DELETE", "CONNECT", "TRACE"}
+
+// Static serves files from a directory on disk under relativePath
+func (e *Engine) Static(relativePath, root string) {
+	e.Group("").Static(relativePath, root)
 }
 
-// matchRoute checks if a route pattern matches a path and extracts parameters
-func matchRoute(pattern, path string) map[string]string {
-	patternParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
+// StaticFS serves files from a http.FileSystem (use http.FS for an fs.FS)
+func (e *Engine) StaticFS(relativePath string, fs http.FileSystem) {
+	e.Group("").StaticFS(relativePath, fs)
+}
 
-	if len(patternParts) != len(pathParts) {
-		return nil
+// StaticFile serves a single file at relativePath
+func (e *Engine) StaticFile(relativePath, filepath string) {
+	e.Group("").StaticFile(relativePath, filepath)
+}
+
+// SetFuncMap sets the functions available to templates loaded afterwards
+func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
+	e.funcMap = funcMap
+}
+
+// Delims sets the template action delimiters for templates loaded afterwards
+func (e *Engine) Delims(left, right string) *Engine {
+	e.delims = [2]string{left, right}
+	return e
+}
+
+// LoadHTMLGlob loads HTML templates matching a glob pattern. In DebugMode the
+// templates are re-read on every render so edits show up without a restart.
+func (e *Engine) LoadHTMLGlob(pattern string) {
+	e.loadHTML(func(t *template.Template) (*template.Template, error) {
+		return t.ParseGlob(pattern)
+	})
+}
+
+// LoadHTMLFiles loads the given HTML template files
+func (e *Engine) LoadHTMLFiles(files ...string) {
+	e.loadHTML(func(t *template.Template) (*template.Template, error) {
+		return t.ParseFiles(files...)
+	})
+}
+
+// loadHTML parses templates with the engine's delimiters and functions
+func (e *Engine) loadHTML(parse func(*template.Template) (*template.Template, error)) {
+	left, right := e.delims[0], e.delims[1]
+	funcMap := e.funcMap
+	loader := func() *template.Template {
+		return template.Must(parse(template.New("").Delims(left, right).Funcs(funcMap)))
 	}
 
-	params := make(map[string]string)
-	for i, part := range patternParts {
-		if strings.HasPrefix(part, ":") {
-			// This is a parameter
-			paramName := part[1:]
-			params[paramName] = pathParts[i]
-		} else if part != pathParts[i] {
-			// Literal part doesn't match
-			return nil
-		}
+	e.htmlTemplate = loader()
+	e.htmlLoader = nil
+	if ginMode == DebugMode {
+		e.htmlLoader = loader
 	}
+}
 
-	return params
+// SetHTMLTemplate sets a pre-parsed template set for c.HTML
+func (e *Engine) SetHTMLTemplate(templ *template.Template) {
+	e.htmlTemplate = templ
+	e.htmlLoader = nil
 }
 
-// RouterGroup methods
+// addRoute adds a route to the engine. Only the route's own handlers are
+// stored; global and group middleware are composed per request, so Use
+// also affects routes registered earlier.
+func (e *Engine) addRoute(method, path string, group *RouterGroup, handlers []HandlerFunc) *Route {
+	if !strings.HasPrefix(path, "/") {
+		panic("path must begin with '/'")
+	}
+	if len(handlers) == 0 {
+		panic("there must be at least one handler")
+	}
 
-// Group creates a sub-group
-func (rg *RouterGroup) Group(prefix string, handlers ...HandlerFunc) *RouterGroup {
-	return &RouterGroup{
-		engine:     rg.engine,
-		prefix:     rg.prefix + prefix,
-		middleware: append(rg.middleware, handlers...),
+	root := e.trees[method]
+	if root == nil {
+		root = &node{}
+		e.trees[method] = root
 	}
+	root.addRoute(path, append([]HandlerFunc(nil), handlers...), group)
+
+	e.routes = append(e.routes, RouteInfo{
+		Method:      method,
+		Path:        path,
+		Handler:     nameOfFunction(handlers[len(handlers)-1]),
+		HandlerFunc: handlers[len(handlers)-1],
+	})
+	return &Route{Method: method, Path: path, engine: e}
 }
 
-// Use adds middleware to the group
-func (rg *RouterGroup) Use(middleware ...HandlerFunc) {
-	rg.middleware = append(rg.middleware, middleware...)
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method      string
+	Path        string
+	Handler     string
+	HandlerFunc HandlerFunc
 }
 
-// GET registers a GET route in the group
-func (rg *RouterGroup) GET(path string, handlers ...HandlerFunc) {
-	rg.handle("GET", path, handlers)
+// RoutesInfo lists registered routes
+type RoutesInfo []RouteInfo
+
+// Routes returns every registered route in registration order
+func (e *Engine) Routes() RoutesInfo {
+	routes := make(RoutesInfo, len(e.routes))
+	copy(routes, e.routes)
+	return routes
 }
 
-// POST registers a POST route in the group
-func (rg *RouterGroup) POST(path string, handlers ...HandlerFunc) {
-	rg.handle("POST", path, handlers)
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
 }
 
-// PUT registers a PUT route in the group
-func (rg *RouterGroup) PUT(path string, handlers ...HandlerFunc) {
-	rg.handle("PUT", path, handlers)
+// Route is a registered route, returned by the registration methods
+type Route struct {
+	Method string
+	Path   string
+	engine *Engine
 }
 
-// DELETE registers a DELETE route in the group
-func (rg *RouterGroup) DELETE(path string, handlers ...HandlerFunc) {
-	rg.handle("DELETE", path, handlers)
+// Name registers the route's path under name for RouteURL. It panics if
+// name already refers to a different path.
+func (r *Route) Name(name string) *Route {
+	if existing, ok := r.engine.namedRoutes[name]; ok && existing != r.Path {
+		panic(fmt.Sprintf("route name '%s' is already used for '%s'", name, existing))
+	}
+	if r.engine.namedRoutes == nil {
+		r.engine.namedRoutes = make(map[string]string)
+	}
+	r.engine.namedRoutes[name] = r.Path
+	return r
 }
 
-// PATCH registers a PATCH route in the group
-func (rg *RouterGroup) PATCH(path string, handlers ...HandlerFunc) {
-	rg.handle("PATCH", path, handlers)
+// RouteURL builds the path of the named route, filling its parameters from
+// key/value pairs, e.g. RouteURL("user", "id", "42"). Pairs that are not
+// route parameters are added as query parameters.
+func (e *Engine) RouteURL(name string, params ...string) (string, error) {
+	pattern, ok := e.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("route %q is not defined", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: params must be key/value pairs", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	segments := splitPath(pattern)
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			value, ok := values[segment[1:]]
+			if !ok || value == "" {
+				return "", fmt.Errorf("route %q: missing parameter %q", name, segment[1:])
+			}
+			segments[i] = url.PathEscape(value)
+			delete(values, segment[1:])
+		case strings.HasPrefix(segment, "*"):
+			value := values[segment[1:]]
+			parts := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+			delete(values, segment[1:])
+		}
+	}
+
+	result := "/" + strings.Join(segments, "/")
+	if len(values) > 0 {
+		query := url.Values{}
+		for key, value := range values {
+			query.Set(key, value)
+		}
+		result += "?" + query.Encode()
+	}
+	return result, nil
 }
 
-// handle registers a route with the group's prefix and middleware
-func (rg *RouterGroup) handle(method, path string, handlers []HandlerFunc) {
-	fullPath := rg.prefix + path
-	allHandlers := append(rg.middleware, handlers...)
-	rg.engine.addRoute(method, fullPath, allHandlers)
+// routeDoc holds the OpenAPI metadata attached to a route
+type routeDoc struct {
+	summary     string
+	description string
+	tags        []string
+	request     reflect.Type
+	responses   map[int]routeResponse
 }
 
-// Context methods
+type routeResponse struct {
+	description string
+	model       reflect.Type
+}
 
-// Next executes the next handler in the chain
-func (c *Context) Next() {
-	c.index++
-	for c.index < len(c.handlers) {
-		c.handlers[c.index](c)
-		c.index++
+func (r *Route) doc() *routeDoc {
+	key := r.Method + " " + r.Path
+	if r.engine.routeDocs == nil {
+		r.engine.routeDocs = make(map[string]*routeDoc)
+	}
+	doc := r.engine.routeDocs[key]
+	if doc == nil {
+		doc = &routeDoc{responses: make(map[int]routeResponse)}
+		r.engine.routeDocs[key] = doc
 	}
+	return doc
 }
 
-// Abort prevents pending handlers from being called
-func (c *Context) Abort() {
-	c.index = len(c.handlers)
+// Summary sets the route's OpenAPI summary
+func (r *Route) Summary(summary string) *Route {
+	r.doc().summary = summary
+	return r
 }
 
-// AbortWithStatus aborts with a status code
-func (c *Context) AbortWithStatus(code int) {
-	c.Response.StatusCode = code
-	c.Abort()
+// Description sets the route's OpenAPI description
+func (r *Route) Description(description string) *Route {
+	r.doc().description = description
+	return r
 }
 
-// Param returns a URL parameter value
-func (c *Context) Param(key string) string {
-	return c.Params[key]
+// Tags adds OpenAPI tags to the route
+func (r *Route) Tags(tags ...string) *Route {
+	doc := r.doc()
+	doc.tags = append(doc.tags, tags...)
+	return r
 }
 
-// Query returns a query parameter value
-func (c *Context) Query(key string) string {
-	return c.Request.Query.Get(key)
+// Request documents the request model: query parameters from its form
+// tags for GET, HEAD and DELETE routes, a JSON body otherwise
+func (r *Route) Request(model interface{}) *Route {
+	r.doc().request = reflect.TypeOf(model)
+	return r
 }
 
-// DefaultQuery returns a query parameter with a default value
-func (c *Context) DefaultQuery(key, defaultValue string) string {
-	value := c.Request.Query.Get(key)
-	if value == "" {
-		return defaultValue
+// Response documents a response; model may be nil for responses without
+// a body
+func (r *Route) Response(code int, description string, model interface{}) *Route {
+	var t reflect.Type
+	if model != nil {
+		t = reflect.TypeOf(model)
 	}
-	return value
+	r.doc().responses[code] = routeResponse{description: description, model: t}
+	return r
 }
 
-// GetHeader returns a request header value
-func (c *Context) GetHeader(key string) string {
-	return c.Request.Headers[key]
+// OpenAPIInfo describes the API in the generated OpenAPI document
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
 }
 
-// Status sets the HTTP status code
-func (c *Context) Status(code int) {
-	c.Response.StatusCode = code
-}
+// OpenAPISpec returns an OpenAPI 3 JSON document for the registered
+// routes. Path parameters are documented as strings, models become
+// component schemas named after their Go types (fields named by json tags,
+// constraints taken from binding tags), and routes without documented
+// responses get a plain "200".
+func (e *Engine) OpenAPISpec() ([]byte, error) {
+	info := map[string]interface{}{"title": e.OpenAPIInfo.Title, "version": e.OpenAPIInfo.Version}
+	if e.OpenAPIInfo.Title == "" {
+		info["title"] = "API"
+	}
+	if e.OpenAPIInfo.Version == "" {
+		info["version"] = "1.0.0"
+	}
+	if e.OpenAPIInfo.Description != "" {
+		info["description"] = e.OpenAPIInfo.Description
+	}
 
-// Header sets a response header
-func (c *Context) Header(key, value string) {
-	c.Response.Headers[key] = value
-}
+	gen := &openAPIGenerator{schemas: make(map[string]interface{})}
+	paths := make(map[string]map[string]interface{})
+	for _, route := range e.routes {
+		method := strings.ToLower(route.Method)
+		switch method {
+		case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		default:
+			continue // not expressible in OpenAPI
+		}
 
-// JSON sends a JSON response
-func (c *Context) JSON(code int, obj interface{}) {
-	c.Response.StatusCode = code
-	c.Response.Headers["Content-Type"] = "application/json"
-	data, err := json.Marshal(obj)
-	if err != nil {
-		c.Response.StatusCode = 500
-		c.Response.Body = []byte(`{"error":"Failed to marshal JSON"}`)
-		return
+		path, names := openAPIPath(route.Path)
+		var parameters []interface{}
+		for _, name := range names {
+			parameters = append(parameters, map[string]interface{}{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		operation := make(map[string]interface{})
+		responses := make(map[string]interface{})
+
+		if doc := e.routeDocs[route.Method+" "+route.Path]; doc != nil {
+			if doc.summary != "" {
+				operation["summary"] = doc.summary
+			}
+			if doc.description != "" {
+				operation["description"] = doc.description
+			}
+			if len(doc.tags) > 0 {
+				operation["tags"] = doc.tags
+			}
+			if doc.request != nil {
+				if method == "get" || method == "head" || method == "delete" {
+					parameters = append(parameters, gen.queryParameters(doc.request)...)
+				} else {
+					operation["requestBody"] = map[string]interface{}{
+						"required": true,
+						"content":  jsonContent(gen.schema(doc.request)),
+					}
+				}
+			}
+			for code, resp := range doc.responses {
+				response := map[string]interface{}{"description": resp.description}
+				if resp.model != nil {
+					response["content"] = jsonContent(gen.schema(resp.model))
+				}
+				responses[strconv.Itoa(code)] = response
+			}
+		}
+		if len(responses) == 0 {
+			responses["200"] = map[string]interface{}{"description": "OK"}
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		operation["responses"] = responses
+
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][method] = operation
+	}
+
+	spec := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(gen.schemas) > 0 {
+		spec["components"] = map[string]interface{}{"schemas": gen.schemas}
 	}
-	c.Response.Body = data
+	return json.MarshalIndent(spec, "", "  ")
 }
 
-// String sends a string response
-func (c *Context) String(code int, format string, values ...interface{}) {
-	c.Response.StatusCode = code
-	c.Response.Headers["Content-Type"] = "text/plain"
-	c.Response.Body = []byte(fmt.Sprintf(format, values...))
+// openAPIPath converts /users/:id and /files/*path to OpenAPI templates,
+// returning the parameter names
+func openAPIPath(path string) (string, []string) {
+	segments := splitPath(path)
+	var names []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return "/" + strings.Join(segments, "/"), names
 }
 
-// Data sends raw data response
-func (c *Context) Data(code int, contentType string, data []byte) {
-	c.Response.StatusCode = code
-	c.Response.Headers["Content-Type"] = contentType
-	c.Response.Body = data
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{MIMEJSON: map[string]interface{}{"schema": schema}}
 }
 
-// BindJSON binds the request body to a struct
-func (c *Context) BindJSON(obj interface{}) error {
-	return json.Unmarshal(c.Request.Body, obj)
+// openAPIGenerator builds schemas, collecting named structs as components
+type openAPIGenerator struct {
+	schemas map[string]interface{}
 }
 
-// Set stores a value in the context
-func (c *Context) Set(key string, value interface{}) {
-	// In a real implementation, this would use a map
-	// For this emulator, we'll keep it simple
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(UUID{})
+)
+
+// schema returns the schema for t, a $ref for named structs
+func (g *openAPIGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case uuidType:
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int32, reflect.Uint32, reflect.Int16, reflect.Uint16, reflect.Int8, reflect.Uint8:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.schemas[t.Name()]; !ok {
+			g.schemas[t.Name()] = nil // placeholder for recursive types
+			g.schemas[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
 }
 
-// Get retrieves a value from the context
-func (c *Context) Get(key string) (interface{}, bool) {
-	// In a real implementation, this would retrieve from a map
-	return nil, false
+// object builds an inline object schema from a struct's JSON fields
+func (g *openAPIGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.addFields(t, properties, &required)
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
 }
 
-// Middleware
+func (g *openAPIGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.addFields(fieldType, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.fieldSchema(field)
+		if bindingRequired(field) {
+			*required = append(*required, name)
+		}
+	}
+}
 
-// Logger returns a logging middleware
-func Logger() HandlerFunc {
-	return func(c *Context) {
-		fmt.Printf("[GIN] %s %s\n", c.Request.Method, c.Request.Path)
-		c.Next()
+// queryParameters documents a struct's form fields as query parameters
+func (g *openAPIGenerator) queryParameters(t reflect.Type) []interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var parameters []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		parameter := map[string]interface{}{"name": name, "in": "query", "schema": g.fieldSchema(field)}
+		if bindingRequired(field) {
+			parameter["required"] = true
+		}
+		parameters = append(parameters, parameter)
 	}
+	return parameters
 }
 
-// Recovery returns a recovery middleware that recovers from panics
-func Recovery() HandlerFunc {
-	return func(c *Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				fmt.Printf("[GIN] Recovery from panic: %v\n", err)
-				c.AbortWithStatus(500)
+// fieldSchema is the schema of a field with its binding constraints
+func (g *openAPIGenerator) fieldSchema(field reflect.StructField) map[string]interface{} {
+	schema := g.schema(field.Type)
+	if _, ref := schema["$ref"]; ref {
+		return schema // siblings of $ref are ignored in OpenAPI 3.0
+	}
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "email":
+			schema["format"] = "email"
+		case "oneof":
+			var options []interface{}
+			for _, option := range strings.Fields(param) {
+				if n, err := strconv.ParseFloat(option, 64); err == nil && schema["type"] != "string" {
+					options = append(options, n)
+				} else {
+					options = append(options, option)
+				}
 			}
-		}()
-		c.Next()
+			schema["enum"] = options
+		case "min", "max", "len", "gt", "gte", "lt", "lte":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			applyLimit(schema, name, limit)
+		}
+	}
+	return schema
+}
+
+// applyLimit maps a min/max style rule to the schema keyword for the
+// field's type: lengths for strings, item counts for arrays, values for
+// numbers
+func applyLimit(schema map[string]interface{}, rule string, limit float64) {
+	var lower, upper string
+	switch schema["type"] {
+	case "string":
+		lower, upper = "minLength", "maxLength"
+	case "array":
+		lower, upper = "minItems", "maxItems"
+	case "object":
+		lower, upper = "minProperties", "maxProperties"
+	case "integer", "number":
+		lower, upper = "minimum", "maximum"
+	default:
+		return
+	}
+	switch rule {
+	case "min", "gte":
+		schema[lower] = limit
+	case "max", "lte":
+		schema[upper] = limit
+	case "len":
+		schema[lower], schema[upper] = limit, limit
+	case "gt", "lt":
+		if lower != "minimum" {
+			return // exclusive bounds only exist for numbers
+		}
+		if rule == "gt" {
+			schema["minimum"], schema["exclusiveMinimum"] = limit, true
+		} else {
+			schema["maximum"], schema["exclusiveMaximum"] = limit, true
+		}
+	}
+}
+
+func bindingRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits a path into segments, dropping the leading slash
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// addRoute registers handlers for a full path, panicking on conflicts
+func (n *node) addRoute(fullPath string, handlers []HandlerFunc, group *RouterGroup) {
+	current := n
+	segments := splitPath(fullPath)
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "*") {
+			if len(segment) == 1 {
+				panic(fmt.Sprintf("wildcards must be named with a non-empty name in path '%s'", fullPath))
+			}
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("catch-all routes are only allowed at the end of the path in path '%s'", fullPath))
+			}
+			if current.wildChild == nil {
+				current.wildChild = &node{segment: segment}
+			} else if current.wildChild.segment != segment {
+				panic(fmt.Sprintf("'%s' in new path '%s' conflicts with existing wildcard '%s'",
+					segment, fullPath, current.wildChild.segment))
+			}
+			current = current.wildChild
+			continue
+		}
+
+		if strings.HasPrefix(segment, ":") {
+			if len(segment) == 1 {
+				panic(fmt.Sprintf("wildcards must be named with a non-empty name in path '%s'", fullPath))
+			}
+			if current.paramChild == nil {
+				current.paramChild = &node{segment: segment}
+			} else if current.paramChild.segment != segment {
+				panic(fmt.Sprintf("'%s' in new path '%s' conflicts with existing wildcard '%s'",
+					segment, fullPath, current.paramChild.segment))
+			}
+			current = current.paramChild
+			continue
+		}
+
+		if current.children == nil {
+			current.children = make(map[string]*node)
+		}
+		child, ok := current.children[segment]
+		if !ok {
+			child = &node{segment: segment}
+			current.children[segment] = child
+		}
+		current = child
+	}
+
+	if current.handlers != nil {
+		panic(fmt.Sprintf("handlers are already registered for path '%s'", fullPath))
+	}
+	current.handlers = handlers
+	current.group = group
+	current.fullPath = fullPath
+}
+
+// chain returns the engine and group middleware followed by the route's
+// handlers, as currently registered
+func (n *node) chain(e *Engine) []HandlerFunc {
+	groupMiddleware := n.group.chain()
+	chain := make([]HandlerFunc, 0, len(e.middleware)+len(groupMiddleware)+len(n.handlers))
+	chain = append(chain, e.middleware...)
+	chain = append(chain, groupMiddleware...)
+	return append(chain, n.handlers...)
+}
+
+// getValue finds the route matching the remaining segments, collecting
+// parameters along the way. Static segments take priority over parameters;
+// if a static branch dead-ends the lookup backtracks to the parameter.
+func (n *node) getValue(segments []string, params map[string]string) *node {
+	if len(segments) == 0 {
+		if n.handlers != nil {
+			return n
+		}
+		return nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if child, ok := n.children[segment]; ok {
+		if found := child.getValue(rest, params); found != nil {
+			return found
+		}
+	}
+
+	if n.paramChild != nil && segment != "" {
+		if found := n.paramChild.getValue(rest, params); found != nil {
+			params[n.paramChild.segment[1:]] = segment
+			return found
+		}
+	}
+
+	// A catch-all consumes the rest of the path, including slashes
+	if n.wildChild != nil && n.wildChild.handlers != nil {
+		params[n.wildChild.segment[1:]] = "/" + strings.Join(segments, "/")
+		return n.wildChild
+	}
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler, so the engine can be mounted on a
+// net/http server or exercised with httptest
+func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body.Close()
+	}
+
+	headers := make(map[string]string, len(r.Header)+1)
+	for key, values := range r.Header {
+		headers[key] = strings.Join(values, ", ")
+	}
+	if r.Host != "" {
+		headers["Host"] = r.Host
+	}
+
+	e.handleRequest(&Request{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Headers:    headers,
+		Body:       body,
+		Query:      r.URL.Query(),
+		RemoteAddr: r.RemoteAddr,
+		ctx:        r.Context(),
+	}, w)
+}
+
+// ServeRequest simulates handling an HTTP request without a server. Tests
+// that need multipart bodies, cookies or assertions can use NewTestRequest.
+func (e *Engine) ServeRequest(method, path string, body []byte, headers map[string]string) *Response {
+	return e.ServeRequestContext(context.Background(), method, path, body, headers)
+}
+
+// ServeRequestContext is ServeRequest with a request context, e.g. one
+// with a deadline for testing timeouts and cancellation
+func (e *Engine) ServeRequestContext(ctx context.Context, method, path string, body []byte, headers map[string]string) *Response {
+	// Parse query string from path
+	pathParts := strings.SplitN(path, "?", 2)
+	cleanPath := pathParts[0]
+	queryString := ""
+	if len(pathParts) > 1 {
+		queryString = pathParts[1]
+	}
+
+	queryValues, _ := url.ParseQuery(queryString)
+
+	return e.handleRequest(&Request{
+		Method:  method,
+		Path:    cleanPath,
+		Headers: headers,
+		Body:    body,
+		Query:   queryValues,
+		ctx:     ctx,
+	}, nil)
+}
+
+// handleRequest routes a request through the matching handler chain. When w
+// is non-nil the buffered response is written to it once the chain is done
+// (or earlier, as streamed handlers flush).
+func (e *Engine) handleRequest(req *Request, w http.ResponseWriter) *Response {
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
+
+	// Create context
+	ctx := &Context{
+		Request: req,
+		Response: &Response{
+			StatusCode: 200,
+			Headers:    make(map[string]string),
+			Body:       []byte{},
+		},
+		Params:   make(map[string]string),
+		handlers: []HandlerFunc{},
+		index:    -1,
+		engine:   e,
+		writer:   w,
+	}
+	ctx.writermem.c = ctx
+	ctx.Writer = &ctx.writermem
+	defer ctx.commit()
+
+	// Find matching route
+	if root, ok := e.trees[req.Method]; ok {
+		if route := root.getValue(splitPath(req.Path), ctx.Params); route != nil {
+			ctx.handlers = route.chain(e)
+			ctx.fullPath = route.fullPath
+			ctx.Next()
+			return ctx.Response
+		}
+	}
+
+	// Try redirecting to a registered variant of the path
+	if root, ok := e.trees[req.Method]; ok && req.Method != "CONNECT" && req.Path != "/" {
+		if location, found := e.findRedirect(root, req.Path); found {
+			code := 301 // permanent redirect for GET
+			if req.Method != "GET" {
+				code = 307 // keep method and body
+			}
+			if len(req.Query) > 0 {
+				location += "?" + req.Query.Encode()
+			}
+			ctx.Redirect(code, location)
+			return ctx.Response
+		}
+	}
+
+	// The path exists, but not for this method
+	if e.HandleMethodNotAllowed {
+		if allowed := e.allowedMethods(req.Path, req.Method); len(allowed) > 0 {
+			ctx.Response.Headers["Allow"] = strings.Join(allowed, ", ")
+			ctx.serveError(405, "405 Method Not Allowed", e.noMethod)
+			return ctx.Response
+		}
+	}
+
+	// No route found
+	ctx.serveError(404, "404 Not Found", e.noRoute)
+	return ctx.Response
+}
+
+// allowedMethods lists the other methods with a route matching path
+func (e *Engine) allowedMethods(path, exclude string) []string {
+	var allowed []string
+	for method, root := range e.trees {
+		if method == exclude {
+			continue
+		}
+		if root.getValue(splitPath(path), map[string]string{}) != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// serveError runs global middleware and the given error handlers with the
+// status preset to code, writing defaultBody if nothing else was written
+func (c *Context) serveError(code int, defaultBody string, handlers []HandlerFunc) {
+	c.Response.StatusCode = code
+	chain := make([]HandlerFunc, 0, len(c.engine.middleware)+len(handlers))
+	chain = append(chain, c.engine.middleware...)
+	chain = append(chain, handlers...)
+	c.handlers = chain
+	c.index = -1
+	c.Next()
+
+	if len(c.Response.Body) == 0 && c.Response.StatusCode == code {
+		c.writeBody(code, []byte(defaultBody))
+	}
+}
+
+// findRedirect looks for a registered path that differs from p only by a
+// trailing slash or, with RedirectFixedPath, by cleaning and letter case
+func (e *Engine) findRedirect(root *node, p string) (string, bool) {
+	toggled := toggleTrailingSlash(p)
+	if e.RedirectTrailingSlash && root.getValue(splitPath(toggled), map[string]string{}) != nil {
+		return toggled, true
+	}
+
+	if e.RedirectFixedPath {
+		cleaned := cleanPath(p)
+		candidates := []string{cleaned}
+		if e.RedirectTrailingSlash {
+			candidates = append(candidates, toggleTrailingSlash(cleaned))
+		}
+		for _, candidate := range candidates {
+			if fixed, ok := root.findCaseInsensitive(splitPath(candidate)); ok {
+				return "/" + strings.Join(fixed, "/"), true
+			}
+		}
+	}
+	return "", false
+}
+
+// toggleTrailingSlash adds or removes a trailing slash
+func toggleTrailingSlash(p string) string {
+	if len(p) > 1 && strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
+}
+
+// cleanPath resolves . and .. elements and duplicate slashes, keeping a
+// trailing slash
+func cleanPath(p string) string {
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// findCaseInsensitive matches segments against the tree ignoring the case of
+// static segments, returning the segments with the registered spelling
+func (n *node) findCaseInsensitive(segments []string) ([]string, bool) {
+	if len(segments) == 0 {
+		return nil, n.handlers != nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if child, ok := n.children[segment]; ok {
+		if fixed, found := child.findCaseInsensitive(rest); found {
+			return append([]string{segment}, fixed...), true
+		}
+	}
+	for key, child := range n.children {
+		if key != segment && strings.EqualFold(key, segment) {
+			if fixed, found := child.findCaseInsensitive(rest); found {
+				return append([]string{key}, fixed...), true
+			}
+		}
+	}
+
+	if n.paramChild != nil && segment != "" {
+		if fixed, found := n.paramChild.findCaseInsensitive(rest); found {
+			return append([]string{segment}, fixed...), true
+		}
+	}
+
+	if n.wildChild != nil && n.wildChild.handlers != nil {
+		return segments, true
+	}
+	return nil, false
+}
+
+// RouterGroup methods
+
+// Group creates a sub-group
+func (rg *RouterGroup) Group(prefix string, handlers ...HandlerFunc) *RouterGroup {
+	return &RouterGroup{
+		engine:     rg.engine,
+		prefix:     rg.prefix + prefix,
+		middleware: append([]HandlerFunc(nil), handlers...),
+		parent:     rg,
+	}
+}
+
+// chain returns the middleware of the group's ancestors and then its own
+func (rg *RouterGroup) chain() []HandlerFunc {
+	if rg == nil {
+		return nil
+	}
+	return append(rg.parent.chain(), rg.middleware...)
+}
+
+// Use adds middleware to the group
+func (rg *RouterGroup) Use(middleware ...HandlerFunc) {
+	rg.middleware = append(rg.middleware, middleware...)
+}
+
+// GET registers a GET route in the group
+func (rg *RouterGroup) GET(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("GET", path, handlers)
+}
+
+// POST registers a POST route in the group
+func (rg *RouterGroup) POST(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("POST", path, handlers)
+}
+
+// PUT registers a PUT route in the group
+func (rg *RouterGroup) PUT(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("PUT", path, handlers)
+}
+
+// DELETE registers a DELETE route in the group
+func (rg *RouterGroup) DELETE(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("DELETE", path, handlers)
+}
+
+// PATCH registers a PATCH route in the group
+func (rg *RouterGroup) PATCH(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("PATCH", path, handlers)
+}
+
+// HEAD registers a HEAD route in the group
+func (rg *RouterGroup) HEAD(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("HEAD", path, handlers)
+}
+
+// OPTIONS registers an OPTIONS route in the group
+func (rg *RouterGroup) OPTIONS(path string, handlers ...HandlerFunc) *Route {
+	return rg.handle("OPTIONS", path, handlers)
+}
+
+// Handle registers a route for an arbitrary method in the group
+func (rg *RouterGroup) Handle(method, path string, handlers ...HandlerFunc) *Route {
+	return rg.handle(method, path, handlers)
+}
+
+// Any registers a route for all common HTTP methods in the group,
+// returning the GET one
+func (rg *RouterGroup) Any(path string, handlers ...HandlerFunc) *Route {
+	var route *Route
+	for _, method := range anyMethods {
+		if r := rg.handle(method, path, handlers); route == nil {
+			route = r
+		}
+	}
+	return route
+}
+
+// handle registers a route with the group's prefix and middleware
+func (rg *RouterGroup) handle(method, path string, handlers []HandlerFunc) *Route {
+	return rg.engine.addRoute(method, rg.prefix+path, rg, handlers)
+}
+
+// Static serves files from a directory on disk under relativePath.
+// Directory listings are disabled and paths cannot escape root.
+func (rg *RouterGroup) Static(relativePath, root string) {
+	rg.StaticFS(relativePath, onlyFilesFS{http.Dir(root)})
+}
+
+// StaticFS serves files from a http.FileSystem under relativePath
+func (rg *RouterGroup) StaticFS(relativePath string, fs http.FileSystem) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("URL parameters can not be used when serving a static folder")
+	}
+	urlPattern := strings.TrimSuffix(relativePath, "/") + "/*filepath"
+	handler := func(c *Context) {
+		c.FileFromFS(c.Param("filepath"), fs)
+	}
+	rg.GET(urlPattern, handler)
+	rg.HEAD(urlPattern, handler)
+}
+
+// StaticFile serves a single file at relativePath
+func (rg *RouterGroup) StaticFile(relativePath, filepath string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("URL parameters can not be used when serving a static file")
+	}
+	handler := func(c *Context) {
+		c.File(filepath)
+	}
+	rg.GET(relativePath, handler)
+	rg.HEAD(relativePath, handler)
+}
+
+// onlyFilesFS hides directories that have no index.html, so static
+// routes never produce directory listings
+type onlyFilesFS struct {
+	fs http.FileSystem
+}
+
+// Open implements http.FileSystem
+func (o onlyFilesFS) Open(name string) (http.File, error) {
+	f, err := o.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if stat, err := f.Stat(); err == nil && stat.IsDir() {
+		index, err := o.fs.Open(strings.TrimSuffix(name, "/") + "/index.html")
+		if err != nil {
+			f.Close()
+			return nil, os.ErrNotExist
+		}
+		index.Close()
+	}
+	return f, nil
+}
+
+// Context methods
+
+// Next executes the next handler in the chain
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.writermem.syncHeader()
+		c.index++
+	}
+}
+
+// Abort prevents pending handlers from being called
+func (c *Context) Abort() {
+	c.index = len(c.handlers)
+}
+
+// AbortWithStatus aborts with a status code
+func (c *Context) AbortWithStatus(code int) {
+	c.Response.StatusCode = code
+	c.Abort()
+}
+
+// AbortWithError aborts with a status code and attaches err to c.Errors
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.AbortWithStatus(code)
+	return c.Error(err)
+}
+
+// Error attaches err to the context, wrapping it as a private *Error
+// unless it already is one. It panics if err is nil.
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("err is nil")
+	}
+	var parsed *Error
+	if !errors.As(err, &parsed) {
+		parsed = &Error{Err: err, Type: ErrorTypePrivate}
+	}
+	c.Errors = append(c.Errors, parsed)
+	return parsed
+}
+
+// Param returns a URL parameter value
+func (c *Context) Param(key string) string {
+	return c.Params[key]
+}
+
+// FullPath returns the matched route pattern, e.g. "/users/:id"
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// Query returns a query parameter value
+func (c *Context) Query(key string) string {
+	return c.Request.Query.Get(key)
+}
+
+// DefaultQuery returns a query parameter with a default value
+func (c *Context) DefaultQuery(key, defaultValue string) string {
+	value := c.Request.Query.Get(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// GetHeader returns a request header value; names are case-insensitive
+func (c *Context) GetHeader(key string) string {
+	if value, ok := c.Request.Headers[key]; ok {
+		return value
+	}
+	for name, value := range c.Request.Headers {
+		if strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Client address headers set by hosting platforms, for TrustedPlatform
+const (
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	PlatformCloudflare      = "CF-Connecting-IP"
+	PlatformFlyIO           = "Fly-Client-IP"
+)
+
+// SetTrustedProxies limits the proxies whose RemoteIPHeaders ClientIP
+// believes to the given IPs and CIDR ranges. Every proxy is trusted until
+// this is called; nil trusts none.
+func (e *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return &net.ParseError{Type: "IP address", Text: proxy}
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			proxy = ip.String() + "/" + strconv.Itoa(bits)
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	e.trustedCIDRs = cidrs
+	return nil
+}
+
+// isTrustedProxy reports whether ip may set RemoteIPHeaders. A nil ip, as
+// for simulated requests without a RemoteAddr, is only trusted while every
+// proxy is.
+func (e *Engine) isTrustedProxy(ip net.IP) bool {
+	if e.trustedCIDRs == nil {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range e.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIPFromHeader walks a forwarding header from the nearest hop back,
+// skipping trusted proxies, and returns the first address that is not one
+func (e *Engine) clientIPFromHeader(header string) (string, bool) {
+	items := strings.Split(header, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		item := strings.TrimSpace(items[i])
+		ip := net.ParseIP(item)
+		if ip == nil {
+			break
+		}
+		if i == 0 || !e.isTrustedProxy(ip) {
+			return item, true
+		}
+	}
+	return "", false
+}
+
+// RemoteIP returns the address of the connection's peer, without port
+func (c *Context) RemoteIP() string {
+	addr := strings.TrimSpace(c.Request.RemoteAddr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// ClientIP returns the client address. The TrustedPlatform header wins if
+// set; RemoteIPHeaders are only believed when the peer is a trusted proxy,
+// so clients cannot spoof them. Otherwise it is RemoteIP.
+func (c *Context) ClientIP() string {
+	e := c.engine
+	if e == nil {
+		return c.RemoteIP()
+	}
+	if e.TrustedPlatform != "" {
+		if ip := strings.TrimSpace(c.GetHeader(e.TrustedPlatform)); ip != "" {
+			return ip
+		}
+	}
+	remoteIP := c.RemoteIP()
+	if e.ForwardedByClientIP && e.isTrustedProxy(net.ParseIP(remoteIP)) {
+		for _, name := range e.RemoteIPHeaders {
+			if ip, ok := e.clientIPFromHeader(c.GetHeader(name)); ok {
+				return ip
+			}
+		}
+	}
+	return remoteIP
+}
+
+// Deadline implements context.Context using the request's context
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.Request == nil {
+		return time.Time{}, false
+	}
+	return c.Request.Context().Deadline()
+}
+
+// Done implements context.Context using the request's context; it is
+// closed when the request is cancelled or times out
+func (c *Context) Done() <-chan struct{} {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Done()
+}
+
+// Err implements context.Context using the request's context
+func (c *Context) Err() error {
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Err()
+}
+
+// Value implements context.Context. String keys are looked up in Keys
+// first; everything else falls back to the request's context.
+func (c *Context) Value(key interface{}) interface{} {
+	if name, ok := key.(string); ok {
+		if value, exists := c.Get(name); exists {
+			return value
+		}
+	}
+	if c.Request == nil {
+		return nil
+	}
+	return c.Request.Context().Value(key)
+}
+
+// Status sets the HTTP status code
+func (c *Context) Status(code int) {
+	c.Response.StatusCode = code
+}
+
+// Header sets a response header
+func (c *Context) Header(key, value string) {
+	c.Response.Headers[key] = value
+}
+
+// Cookie returns the named request cookie, unescaped
+func (c *Context) Cookie(name string) (string, error) {
+	header := c.GetHeader("Cookie")
+	if header == "" {
+		return "", http.ErrNoCookie
+	}
+	req := &http.Request{Header: http.Header{"Cookie": {header}}}
+	cookie, err := req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, _ := url.QueryUnescape(cookie.Value)
+	return value, nil
+}
+
+// SetSameSite sets the SameSite attribute used by subsequent SetCookie calls
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie adds a Set-Cookie header to the response. A negative maxAge
+// deletes the cookie; an empty path defaults to "/".
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	}
+
+	// Replace an earlier cookie with the same name, path and domain
+	for i, existing := range c.Response.Cookies {
+		if existing.Name == name && existing.Path == path && existing.Domain == domain {
+			c.Response.Cookies[i] = cookie
+			return
+		}
+	}
+	c.Response.Cookies = append(c.Response.Cookies, cookie)
+}
+
+// JSON sends a JSON response
+func (c *Context) JSON(code int, obj interface{}) {
+	c.Response.Headers["Content-Type"] = "application/json"
+	data, err := json.Marshal(obj)
+	if err != nil {
+		c.writeBody(500, []byte(`{"error":"Failed to marshal JSON"}`))
+		return
+	}
+	c.writeBody(code, data)
+}
+
+// IndentedJSON sends pretty-printed JSON
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	c.render(code, MIMEJSON, data, err)
+}
+
+// PureJSON sends JSON without escaping HTML characters such as < and >
+func (c *Context) PureJSON(code int, obj interface{}) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(obj)
+	c.render(code, MIMEJSON, bytes.TrimSuffix(buf.Bytes(), []byte("\n")), err)
+}
+
+// SecureJSON sends JSON, prefixing arrays to prevent JSON hijacking
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err == nil && bytes.HasPrefix(data, []byte("[")) {
+		prefix := "while(1);"
+		if c.engine != nil {
+			prefix = c.engine.secureJSONPrefix
+		}
+		data = append([]byte(prefix), data...)
+	}
+	c.render(code, MIMEJSON, data, err)
+}
+
+// JSONP sends JSON wrapped in the function named by the "callback" query
+// parameter, or plain JSON when there is no callback
+func (c *Context) JSONP(code int, obj interface{}) {
+	data, err := json.Marshal(obj)
+	callback := c.Query("callback")
+	if err != nil || callback == "" {
+		c.render(code, MIMEJSON, data, err)
+		return
+	}
+	wrapped := template.JSEscapeString(callback) + "(" + string(data) + ");"
+	c.render(code, MIMEJSONP, []byte(wrapped), nil)
+}
+
+// XML sends an XML response
+func (c *Context) XML(code int, obj interface{}) {
+	data, err := xml.Marshal(obj)
+	c.render(code, MIMEXML, data, err)
+}
+
+// YAML sends a YAML response encoded by the YAML codec
+func (c *Context) YAML(code int, obj interface{}) {
+	data, err := YAML.Marshal(obj)
+	c.render(code, MIMEYAML, data, err)
+}
+
+// TOML sends a TOML response; field names follow `json` tags
+func (c *Context) TOML(code int, obj interface{}) {
+	data, err := marshalTOML(obj)
+	c.render(code, MIMETOML, data, err)
+}
+
+// ProtoBuf sends a protobuf-encoded message. obj must implement
+// ProtoMarshaler or encoding.BinaryMarshaler.
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	var data []byte
+	var err error
+	switch msg := obj.(type) {
+	case ProtoMarshaler:
+		data, err = msg.Marshal()
+	case encoding.BinaryMarshaler:
+		data, err = msg.MarshalBinary()
+	default:
+		err = fmt.Errorf("%T is not a protobuf message", obj)
+	}
+	c.render(code, MIMEPROTOBUF, data, err)
+}
+
+// render writes an encoded body or a 500 if encoding failed
+func (c *Context) render(code int, contentType string, data []byte, err error) {
+	if err != nil {
+		c.Response.Headers["Content-Type"] = MIMEPlain
+		c.writeBody(500, []byte(err.Error()))
+		return
+	}
+	c.Response.Headers["Content-Type"] = contentType
+	c.writeBody(code, data)
+}
+
+// Negotiate holds the data offered to c.Negotiate for each format
+type Negotiate struct {
+	Offered  []string
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	TOMLData interface{}
+	Data     interface{}
+}
+
+// Negotiate renders the offered format that best matches the Accept header,
+// or 406 Not Acceptable when none match
+func (c *Context) Negotiate(code int, config Negotiate) {
+	pick := func(specific interface{}) interface{} {
+		if specific != nil {
+			return specific
+		}
+		return config.Data
+	}
+
+	switch c.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData))
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, pick(config.HTMLData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData))
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAMLData))
+	case MIMETOML:
+		c.TOML(code, pick(config.TOMLData))
+	default:
+		c.AbortWithStatus(406)
+	}
+}
+
+// NegotiateFormat returns the offered content type that best matches the
+// Accept header, honouring q-values; the first offer wins with no header
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		panic("you must provide at least one offer")
+	}
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return offered[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.TrimSpace(fields[0])
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		if q <= bestQ {
+			continue
+		}
+		for _, offer := range offered {
+			if mediaTypeMatches(mediaType, offer) {
+				best, bestQ = offer, q
+				break
+			}
+		}
+	}
+	return best
+}
+
+// mediaTypeMatches reports whether an Accept entry such as "text/*" covers offer
+func mediaTypeMatches(accepted, offer string) bool {
+	if accepted == "*/*" || accepted == offer {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(offer, strings.TrimSuffix(accepted, "*"))
+	}
+	return false
+}
+
+// SecureJsonPrefix sets the prefix written by c.SecureJSON
+func (e *Engine) SecureJsonPrefix(prefix string) *Engine {
+	e.secureJSONPrefix = prefix
+	return e
+}
+
+// normalize converts obj to maps, slices and scalars via JSON so encoders
+// honour `json` tags the same way c.JSON does
+func normalize(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&generic)
+	return generic, err
+}
+
+// YAMLCodec encodes YAML responses and decodes YAML request bodies. The
+// yaml emulator's Codec implements it, as can any YAML library with these
+// methods.
+type YAMLCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// YAML is the YAMLCodec used by c.YAML and the YAML bind methods. The
+// built-in codec writes block-style YAML with field names from `json`
+// tags and cannot decode; set it to the yaml emulator's Codec to honor
+// `yaml` tags and bind YAML bodies.
+var YAML YAMLCodec = defaultYAMLCodec{}
+
+// defaultYAMLCodec is the built-in encoder
+type defaultYAMLCodec struct{}
+
+// Marshal implements YAMLCodec
+func (defaultYAMLCodec) Marshal(v interface{}) ([]byte, error) {
+	return marshalYAML(v)
+}
+
+// Unmarshal implements YAMLCodec; the built-in codec only encodes
+func (defaultYAMLCodec) Unmarshal(data []byte, v interface{}) error {
+	return errors.New("yaml: no YAML decoder; set gin.YAML to a YAMLCodec")
+}
+
+// marshalYAML encodes obj as block-style YAML
+func marshalYAML(obj interface{}) ([]byte, error) {
+	generic, err := normalize(obj)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	switch value := generic.(type) {
+	case map[string]interface{}:
+		writeYAMLMap(&b, value, 0)
+	case []interface{}:
+		writeYAMLList(&b, value, 0)
+	default:
+		b.WriteString(yamlScalar(value) + "\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// writeYAMLMap writes a mapping at the given indent level
+func writeYAMLMap(b *strings.Builder, m map[string]interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, key := range sortedMapKeys(m) {
+		switch value := m[key].(type) {
+		case map[string]interface{}:
+			if len(value) == 0 {
+				fmt.Fprintf(b, "%s%s: {}\n", pad, yamlScalar(key))
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, yamlScalar(key))
+			writeYAMLMap(b, value, indent+1)
+		case []interface{}:
+			if len(value) == 0 {
+				fmt.Fprintf(b, "%s%s: []\n", pad, yamlScalar(key))
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, yamlScalar(key))
+			writeYAMLList(b, value, indent+1)
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", pad, yamlScalar(key), yamlScalar(value))
+		}
+	}
+}
+
+// writeYAMLList writes a sequence at the given indent level
+func writeYAMLList(b *strings.Builder, list []interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, item := range list {
+		switch value := item.(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(b, "%s-\n", pad)
+			writeYAMLMap(b, value, indent+1)
+		case []interface{}:
+			fmt.Fprintf(b, "%s-\n", pad)
+			writeYAMLList(b, value, indent+1)
+		default:
+			fmt.Fprintf(b, "%s- %s\n", pad, yamlScalar(value))
+		}
+	}
+}
+
+// yamlScalar renders a scalar, quoting strings that YAML would misread
+func yamlScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		switch strings.ToLower(v) {
+		case "", "true", "false", "yes", "no", "on", "off", "null", "~":
+			return strconv.Quote(v)
+		}
+		if _, err := strconv.ParseFloat(v, 64); err == nil ||
+			strings.ContainsAny(v, ":#[]{},&*!|>'\"%@`\n") || v != strings.TrimSpace(v) ||
+			strings.HasPrefix(v, "-") || strings.HasPrefix(v, "?") {
+			return strconv.Quote(v)
+		}
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// marshalTOML encodes obj, which must encode to an object, as TOML
+func marshalTOML(obj interface{}) ([]byte, error) {
+	generic, err := normalize(obj)
+	if err != nil {
+		return nil, err
+	}
+	table, ok := generic.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("toml: top-level value must be a table, got %T", obj)
+	}
+	var b strings.Builder
+	writeTOMLTable(&b, table, "")
+	return []byte(strings.TrimPrefix(b.String(), "\n")), nil
+}
+
+// writeTOMLTable writes a table's values, then its sub-tables and arrays of tables
+func writeTOMLTable(b *strings.Builder, m map[string]interface{}, prefix string) {
+	var tables, tableArrays []string
+	for _, key := range sortedMapKeys(m) {
+		switch value := m[key].(type) {
+		case nil:
+			// TOML has no null
+		case map[string]interface{}:
+			tables = append(tables, key)
+		case []interface{}:
+			if isTableArray(value) {
+				tableArrays = append(tableArrays, key)
+				continue
+			}
+			fmt.Fprintf(b, "%s = %s\n", tomlKey(key), tomlValue(value))
+		default:
+			fmt.Fprintf(b, "%s = %s\n", tomlKey(key), tomlValue(value))
+		}
+	}
+
+	for _, key := range tables {
+		name := joinTOMLKey(prefix, key)
+		fmt.Fprintf(b, "\n[%s]\n", name)
+		writeTOMLTable(b, m[key].(map[string]interface{}), name)
+	}
+	for _, key := range tableArrays {
+		name := joinTOMLKey(prefix, key)
+		for _, item := range m[key].([]interface{}) {
+			fmt.Fprintf(b, "\n[[%s]]\n", name)
+			writeTOMLTable(b, item.(map[string]interface{}), name)
+		}
+	}
+}
+
+// isTableArray reports whether every element of list is an object
+func isTableArray(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlValue renders an inline TOML value
+func tomlValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = tomlValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]interface{}:
+		parts := make([]string, 0, len(v))
+		for _, key := range sortedMapKeys(v) {
+			parts = append(parts, tomlKey(key)+" = "+tomlValue(v[key]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// tomlKey quotes keys that are not valid bare keys
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return strconv.Quote(key)
+		}
+	}
+	return key
+}
+
+// joinTOMLKey builds a dotted table name
+func joinTOMLKey(prefix, key string) string {
+	if prefix == "" {
+		return tomlKey(key)
+	}
+	return prefix + "." + tomlKey(key)
+}
+
+// sortedMapKeys returns the keys of m in sorted order
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String sends a string response
+func (c *Context) String(code int, format string, values ...interface{}) {
+	c.Response.Headers["Content-Type"] = "text/plain"
+	c.writeBody(code, []byte(fmt.Sprintf(format, values...)))
+}
+
+// Data sends raw data response
+func (c *Context) Data(code int, contentType string, data []byte) {
+	c.Response.Headers["Content-Type"] = contentType
+	c.writeBody(code, data)
+}
+
+// Redirect sends a redirect to location. code must be a 3xx status (or 201
+// Created); anything else panics, as it is a programming error.
+func (c *Context) Redirect(code int, location string) {
+	if (code < 300 || code > 308) && code != 201 {
+		panic(fmt.Sprintf("Cannot redirect with status code %d", code))
+	}
+	req, err := c.httpRequest()
+	if err != nil {
+		c.AbortWithStatus(500)
+		return
+	}
+	http.Redirect(&responseBuffer{c: c}, req, location, code)
+}
+
+// Stream calls step repeatedly, flushing after each call, until step returns
+// false. It returns true if the request's context was cancelled (client
+// gone or deadline passed) before the stream ended, false otherwise.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	w := streamWriter{c}
+	for {
+		if c.Err() != nil {
+			// The client went away or the request timed out
+			return true
+		}
+		keepOpen := step(w)
+		c.flush()
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
+// SSEvent writes a Server-Sent Event. Strings are sent as-is, other values
+// are JSON-encoded; multi-line data becomes multiple data: lines.
+func (c *Context) SSEvent(name string, message interface{}) {
+	c.SSEventWithID("", name, message)
+}
+
+// SSEventWithID is SSEvent with an event ID, which browsers send back in
+// the Last-Event-ID header when they reconnect
+func (c *Context) SSEventWithID(id, name string, message interface{}) {
+	var data string
+	switch value := message.(type) {
+	case string:
+		data = value
+	case []byte:
+		data = string(value)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			data = fmt.Sprintf("%v", value)
+		} else {
+			data = string(encoded)
+		}
+	}
+
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id:" + id + "\n")
+	}
+	if name != "" {
+		b.WriteString("event:" + name + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data:" + line + "\n")
+	}
+	b.WriteString("\n")
+
+	c.Response.Headers["Content-Type"] = "text/event-stream"
+	c.Response.Headers["Cache-Control"] = "no-cache"
+	c.Writer.WriteString(b.String())
+}
+
+// LastEventID returns the Last-Event-ID header of a reconnecting
+// Server-Sent Events client, so streams can resume after that event
+func (c *Context) LastEventID() string {
+	return c.GetHeader("Last-Event-ID")
+}
+
+// Pusher returns the connection's http.Pusher when serving HTTP/2 through
+// ServeHTTP, or nil when push is unavailable
+func (c *Context) Pusher() http.Pusher {
+	if pusher, ok := c.writer.(http.Pusher); ok {
+		return pusher
+	}
+	return nil
+}
+
+// Push initiates an HTTP/2 server push of target. Simulated requests record
+// the push in Response.Pushes; on a real connection without push support
+// it returns http.ErrNotSupported so handlers can fall back gracefully.
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	promise := newPushPromise(target, opts)
+	if c.writer != nil {
+		pusher := c.Pusher()
+		if pusher == nil {
+			return http.ErrNotSupported
+		}
+		if err := pusher.Push(target, opts); err != nil {
+			return err
+		}
+	}
+	c.Response.Pushes = append(c.Response.Pushes, promise)
+	return nil
+}
+
+func newPushPromise(target string, opts *http.PushOptions) PushPromise {
+	promise := PushPromise{Target: target, Method: "GET", Header: http.Header{}}
+	if opts != nil {
+		if opts.Method != "" {
+			promise.Method = opts.Method
+		}
+		for key, values := range opts.Header {
+			promise.Header[key] = append([]string(nil), values...)
+		}
+	}
+	return promise
+}
+
+// ServerSentEvent is one event parsed by ParseSSE
+type ServerSentEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// ParseSSE splits a text/event-stream body into events, joining multi-line
+// data with newlines and skipping comments
+func ParseSSE(body []byte) []ServerSentEvent {
+	var events []ServerSentEvent
+	var current ServerSentEvent
+	var data []string
+	hasData := false
+	for _, line := range strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n") {
+		if line == "" {
+			if hasData || current.Event != "" || current.ID != "" {
+				current.Data = strings.Join(data, "\n")
+				events = append(events, current)
+			}
+			current, data, hasData = ServerSentEvent{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			current.ID = value
+		case "event":
+			current.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		}
+	}
+	return events
+}
+
+// StreamRecorder is an httptest.ResponseRecorder for streamed responses.
+// It records the body written between flushes and the server pushes a
+// handler makes, and can be disconnected to test cancellation.
+type StreamRecorder struct {
+	*httptest.ResponseRecorder
+
+	// DisablePush makes Push fail like an HTTP/1.x connection
+	DisablePush bool
+
+	mu      sync.Mutex
+	chunks  [][]byte
+	flushed int
+	pushes  []PushPromise
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewStreamRecorder creates a StreamRecorder
+func NewStreamRecorder() *StreamRecorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamRecorder{ResponseRecorder: httptest.NewRecorder(), ctx: ctx, cancel: cancel}
+}
+
+// Request builds a request bound to the recorder, so Disconnect cancels it
+func (r *StreamRecorder) Request(method, target string, body io.Reader) *http.Request {
+	return httptest.NewRequest(method, target, body).WithContext(r.ctx)
+}
+
+// Disconnect simulates the client going away
+func (r *StreamRecorder) Disconnect() {
+	r.cancel()
+}
+
+// Flush implements http.Flusher, recording the body written since the
+// previous flush as a chunk
+func (r *StreamRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body := r.Body.Bytes()
+	if r.flushed < len(body) {
+		r.chunks = append(r.chunks, append([]byte(nil), body[r.flushed:]...))
+		r.flushed = len(body)
+	}
+	r.ResponseRecorder.Flush()
+}
+
+// Push implements http.Pusher, recording the promise
+func (r *StreamRecorder) Push(target string, opts *http.PushOptions) error {
+	if r.DisablePush {
+		return http.ErrNotSupported
+	}
+	promise := newPushPromise(target, opts)
+	r.mu.Lock()
+	r.pushes = append(r.pushes, promise)
+	r.mu.Unlock()
+	return nil
+}
+
+// Chunks returns the body written between each flush
+func (r *StreamRecorder) Chunks() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte(nil), r.chunks...)
+}
+
+// Pushes returns the recorded server pushes
+func (r *StreamRecorder) Pushes() []PushPromise {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PushPromise(nil), r.pushes...)
+}
+
+// Events parses the recorded body as Server-Sent Events
+func (r *StreamRecorder) Events() []ServerSentEvent {
+	return ParseSSE(r.Body.Bytes())
+}
+
+// TestingT is the subset of *testing.T used by TestResponse assertions;
+// the Testify emulator's TestingT satisfies it too
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// TestRequest builds requests for tests fluently:
+//
+//	resp := NewTestRequest("POST", "/upload").
+//		FormField("title", "Report").
+//		File("file", "report.pdf", data).
+//		Do(router)
+type TestRequest struct {
+	method   string
+	path     string
+	query    url.Values
+	header   http.Header
+	cookies  []*http.Cookie
+	body     []byte
+	form     url.Values
+	files    []testFile
+	ctx      context.Context
+	buildErr error
+
+	remoteAddr string
+}
+
+type testFile struct {
+	field    string
+	filename string
+	content  []byte
+}
+
+// NewTestRequest starts a request; path may include a query string
+func NewTestRequest(method, path string) *TestRequest {
+	return &TestRequest{
+		method: method,
+		path:   path,
+		query:  url.Values{},
+		header: http.Header{},
+		form:   url.Values{},
+
+		remoteAddr: "192.0.2.1:1234",
+	}
+}
+
+// Header sets a request header
+func (r *TestRequest) Header(key, value string) *TestRequest {
+	r.header.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter
+func (r *TestRequest) Query(key, value string) *TestRequest {
+	r.query.Add(key, value)
+	return r
+}
+
+// Cookie adds a request cookie
+func (r *TestRequest) Cookie(name, value string) *TestRequest {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// RemoteAddr sets the client address, "192.0.2.1:1234" by default
+func (r *TestRequest) RemoteAddr(addr string) *TestRequest {
+	r.remoteAddr = addr
+	return r
+}
+
+// BasicAuth sets HTTP Basic credentials
+func (r *TestRequest) BasicAuth(user, password string) *TestRequest {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(user, password)
+	return r.Header("Authorization", req.Header.Get("Authorization"))
+}
+
+// BearerToken sets an "Authorization: Bearer" header
+func (r *TestRequest) BearerToken(token string) *TestRequest {
+	return r.Header("Authorization", "Bearer "+token)
+}
+
+// Body sets a raw body and its content type
+func (r *TestRequest) Body(data []byte, contentType string) *TestRequest {
+	r.body = data
+	if contentType != "" {
+		r.header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+// JSON sets a JSON-encoded body
+func (r *TestRequest) JSON(v interface{}) *TestRequest {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.buildErr = err
+	}
+	return r.Body(data, MIMEJSON)
+}
+
+// FormField adds a form field, sent url-encoded, or as a multipart part
+// when files are attached
+func (r *TestRequest) FormField(key, value string) *TestRequest {
+	r.form.Add(key, value)
+	return r
+}
+
+// File attaches a file part, making the body multipart/form-data
+func (r *TestRequest) File(field, filename string, content []byte) *TestRequest {
+	r.files = append(r.files, testFile{field: field, filename: filename, content: content})
+	return r
+}
+
+// WithContext sets the request context
+func (r *TestRequest) WithContext(ctx context.Context) *TestRequest {
+	r.ctx = ctx
+	return r
+}
+
+// Build returns the request as an *http.Request
+func (r *TestRequest) Build() (*http.Request, error) {
+	if r.buildErr != nil {
+		return nil, r.buildErr
+	}
+	header := r.header.Clone()
+	body := r.body
+	switch {
+	case len(r.files) > 0:
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for _, key := range sortedFormKeys(r.form) {
+			for _, value := range r.form[key] {
+				mw.WriteField(key, value)
+			}
+		}
+		for _, f := range r.files {
+			part, err := mw.CreateFormFile(f.field, f.filename)
+			if err != nil {
+				return nil, err
+			}
+			part.Write(f.content)
+		}
+		mw.Close()
+		body = buf.Bytes()
+		header.Set("Content-Type", mw.FormDataContentType())
+	case len(r.form) > 0:
+		body = []byte(r.form.Encode())
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	target := r.path
+	if len(r.query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + r.query.Encode()
+	}
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	req.RemoteAddr = r.remoteAddr
+	return req, nil
+}
+
+func sortedFormKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Do serves the request through e.ServeHTTP and records the response. It
+// panics if the request cannot be built.
+func (r *TestRequest) Do(e *Engine) *TestResponse {
+	req, err := r.Build()
+	if err != nil {
+		panic(err)
+	}
+	rec := NewStreamRecorder()
+	if r.ctx == nil {
+		req = req.WithContext(rec.ctx)
+	}
+	e.ServeHTTP(rec, req)
+	return &TestResponse{StreamRecorder: rec}
+}
+
+// TestResponse is a recorded response with assertion helpers. Each
+// assertion reports failures through t.Errorf and returns whether it held.
+type TestResponse struct {
+	*StreamRecorder
+}
+
+// BodyString returns the body as a string
+func (r *TestResponse) BodyString() string {
+	return r.Body.String()
+}
+
+// DecodeJSON unmarshals the body into v
+func (r *TestResponse) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(r.Body.Bytes(), v)
+}
+
+// Cookie returns the named Set-Cookie cookie, or nil
+func (r *TestResponse) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// AssertStatus checks the status code
+func (r *TestResponse) AssertStatus(t TestingT, code int) bool {
+	if r.Code != code {
+		t.Errorf("expected status %d, got %d (body: %q)", code, r.Code, r.BodyString())
+		return false
+	}
+	return true
+}
+
+// AssertHeader checks a response header value
+func (r *TestResponse) AssertHeader(t TestingT, key, value string) bool {
+	if got := r.Header().Get(key); got != value {
+		t.Errorf("expected header %s: %q, got %q", key, value, got)
+		return false
+	}
+	return true
+}
+
+// AssertBodyContains checks that the body contains substr
+func (r *TestResponse) AssertBodyContains(t TestingT, substr string) bool {
+	if !strings.Contains(r.BodyString(), substr) {
+		t.Errorf("expected body to contain %q, got %q", substr, r.BodyString())
+		return false
+	}
+	return true
+}
+
+// AssertJSON checks that the body is JSON equal to expected, ignoring
+// formatting and key order
+func (r *TestResponse) AssertJSON(t TestingT, expected interface{}) bool {
+	var got interface{}
+	if err := json.Unmarshal(r.Body.Bytes(), &got); err != nil {
+		t.Errorf("expected JSON body, got %q: %v", r.BodyString(), err)
+		return false
+	}
+	want, err := normalize(expected)
+	if err != nil {
+		t.Errorf("cannot encode expected JSON: %v", err)
+		return false
+	}
+	got, _ = normalize(got)
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("expected JSON %s, got %s", wantJSON, gotJSON)
+		return false
+	}
+	return true
+}
+
+// AssertCookie checks that a cookie was set with the given value
+func (r *TestResponse) AssertCookie(t TestingT, name, value string) bool {
+	cookie := r.Cookie(name)
+	if cookie == nil {
+		t.Errorf("expected cookie %q to be set", name)
+		return false
+	}
+	got := cookie.Value
+	if unescaped, err := url.QueryUnescape(got); err == nil {
+		got = unescaped
+	}
+	if got != value {
+		t.Errorf("expected cookie %s=%q, got %q", name, value, got)
+		return false
+	}
+	return true
+}
+
+
+// streamWriter appends writes to the response body
+type streamWriter struct {
+	c *Context
+}
+
+// Write implements io.Writer
+func (sw streamWriter) Write(data []byte) (int, error) {
+	return sw.c.Writer.Write(data)
+}
+
+// flush records the body written since the last flush as a chunk and, when
+// serving a real connection, sends it to the client immediately
+func (c *Context) flush() {
+	if c.chunkStart < len(c.Response.Body) {
+		chunk := append([]byte(nil), c.Response.Body[c.chunkStart:]...)
+		c.Response.Chunks = append(c.Response.Chunks, chunk)
+		c.chunkStart = len(c.Response.Body)
+	}
+	c.commit()
+	if flusher, ok := c.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// commit writes the status, headers and any unsent body to the real writer
+func (c *Context) commit() {
+	c.writermem.syncHeader()
+	if c.writer == nil {
+		return
+	}
+	if !c.headerSent {
+		c.headerSent = true
+		for key, value := range c.Response.Headers {
+			c.writer.Header().Set(key, value)
+		}
+		for _, cookie := range c.Response.Cookies {
+			c.writer.Header().Add("Set-Cookie", cookie.String())
+		}
+		c.writer.WriteHeader(c.Response.StatusCode)
+	}
+	if c.bodySent < len(c.Response.Body) {
+		c.writer.Write(c.Response.Body[c.bodySent:])
+		c.bodySent = len(c.Response.Body)
+	}
+}
+
+// HTML renders the named template with obj as its data
+func (c *Context) HTML(code int, name string, obj interface{}) {
+	var templ *template.Template
+	if c.engine != nil {
+		templ = c.engine.htmlTemplate
+		if c.engine.htmlLoader != nil {
+			templ = c.engine.htmlLoader()
+		}
+	}
+	if templ == nil {
+		c.writeBody(500, []byte("html template not loaded: call LoadHTMLGlob, LoadHTMLFiles or SetHTMLTemplate"))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := templ.ExecuteTemplate(&buf, name, obj); err != nil {
+		c.writeBody(500, []byte(err.Error()))
+		return
+	}
+
+	c.Response.Headers["Content-Type"] = "text/html; charset=utf-8"
+	c.writeBody(code, buf.Bytes())
+	if ginMode == TestMode {
+		c.Response.Template = &RenderedTemplate{Name: name, Data: obj, Output: buf.String()}
+	}
+}
+
+// File serves a file from disk, handling Content-Type detection, Range and
+// conditional requests
+func (c *Context) File(filepath string) {
+	req, err := c.httpRequest()
+	if err != nil {
+		c.AbortWithStatus(500)
+		return
+	}
+	http.ServeFile(&responseBuffer{c: c}, req, filepath)
+}
+
+// FileAttachment serves a file as a download with the given filename
+func (c *Context) FileAttachment(filepath, filename string) {
+	if isASCII(filename) {
+		c.Header("Content-Disposition", `attachment; filename="`+strings.ReplaceAll(filename, `"`, `\"`)+`"`)
+	} else {
+		c.Header("Content-Disposition", `attachment; filename*=UTF-8''`+url.QueryEscape(filename))
+	}
+	c.File(filepath)
+}
+
+// FileFromFS serves the file at filepath from a http.FileSystem
+func (c *Context) FileFromFS(filepath string, fs http.FileSystem) {
+	req, err := c.httpRequest()
+	if err != nil {
+		c.AbortWithStatus(500)
+		return
+	}
+	req.URL.Path = filepath
+
+	f, err := fs.Open(filepath)
+	if err != nil {
+		// Missing files fall through to the NoRoute handlers
+		var noRoute []HandlerFunc
+		if c.engine != nil {
+			noRoute = c.engine.noRoute
+		}
+		c.Response.StatusCode = 404
+		c.handlers = noRoute
+		c.index = -1
+		c.Next()
+		if len(c.Response.Body) == 0 && c.Response.StatusCode == 404 {
+			c.writeBody(404, []byte("404 Not Found"))
+		}
+		return
+	}
+	f.Close()
+
+	http.FileServer(fs).ServeHTTP(&responseBuffer{c: c}, req)
+}
+
+// isASCII reports whether s contains only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// httpRequest builds a net/http request equivalent to the current request
+func (c *Context) httpRequest() (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, c.Request.Path, bytes.NewReader(c.Request.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range c.Request.Headers {
+		req.Header.Set(key, value)
+	}
+	req.URL.RawQuery = c.Request.Query.Encode()
+	return req, nil
+}
+
+// WrapF wraps an http.HandlerFunc as a Gin handler
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return WrapH(f)
+}
+
+// WrapH wraps an http.Handler as a Gin handler. The handler writes
+// through c.Writer and sees the request's headers, query, host, remote
+// address and context, so net/http code such as a WebSocket upgrader can
+// serve Gin routes.
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		req, err := c.httpRequest()
+		if err != nil {
+			c.AbortWithStatus(500)
+			return
+		}
+		req.Host = c.GetHeader("Host")
+		req.RemoteAddr = c.Request.RemoteAddr
+		h.ServeHTTP(c.Writer, req)
+	}
+}
+
+// ResponseWriter is the writer handlers and middleware see as c.Writer.
+// Middleware may wrap it (embedding the original) to intercept writes,
+// e.g. for metrics or caching; the renderers write through c.Writer.
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+
+	// Status returns the response status code
+	Status() int
+	// Size returns the number of body bytes written, or -1 if none
+	Size() int
+	// Written reports whether a status or body has been written
+	Written() bool
+	// WriteHeaderNow marks the header written, sending it to the client
+	// when serving a real connection
+	WriteHeaderNow()
+	// WriteString writes a string body
+	WriteString(s string) (int, error)
+	// Pusher returns the http.Pusher for server push, or nil
+	Pusher() http.Pusher
+}
+
+// responseWriter is the default ResponseWriter, writing into the buffered
+// Response. Its http.Header is kept in sync with Response.Headers, so
+// c.Header and c.Writer.Header() see each other's changes.
+type responseWriter struct {
+	c       *Context
+	header  http.Header
+	synced  http.Header // header contents as of the last sync
+	written bool
+}
+
+// Header implements http.ResponseWriter
+func (w *responseWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	w.syncHeader()
+	return w.header
+}
+
+// syncHeader copies changes made through Header() into Response.Headers
+// (Set-Cookie values go to Response.Cookies), then refreshes Header()
+// from Response.Headers
+func (w *responseWriter) syncHeader() {
+	if w.header == nil {
+		return
+	}
+	response := w.c.Response
+	for key := range w.synced {
+		if _, ok := w.header[key]; !ok {
+			deleteHeader(response.Headers, key)
+		}
+	}
+	for key, values := range w.header {
+		if equalValues(values, w.synced[key]) {
+			continue
+		}
+		if key == "Set-Cookie" {
+			for _, value := range values {
+				if cookie, err := http.ParseSetCookie(value); err == nil {
+					response.Cookies = append(response.Cookies, cookie)
+				}
+			}
+			continue
+		}
+		deleteHeader(response.Headers, key)
+		response.Headers[key] = strings.Join(values, ", ")
+	}
+
+	for key := range w.header {
+		delete(w.header, key)
+	}
+	for key, value := range response.Headers {
+		w.header[http.CanonicalHeaderKey(key)] = []string{value}
+	}
+	w.synced = w.header.Clone()
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteHeader implements http.ResponseWriter. The status can still change
+// until the header is sent to a real connection.
+func (w *responseWriter) WriteHeader(code int) {
+	w.syncHeader()
+	if code > 0 && !w.c.headerSent {
+		w.c.Response.StatusCode = code
+	}
+}
+
+// WriteHeaderNow implements ResponseWriter
+func (w *responseWriter) WriteHeaderNow() {
+	w.syncHeader()
+	w.written = true
+	w.c.commit()
+}
+
+// Write implements http.ResponseWriter, appending to the body
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.syncHeader()
+	w.written = true
+	w.c.Response.Body = append(w.c.Response.Body, data...)
+	return len(data), nil
+}
+
+// WriteString implements ResponseWriter
+func (w *responseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status implements ResponseWriter
+func (w *responseWriter) Status() int {
+	return w.c.Response.StatusCode
+}
+
+// Size implements ResponseWriter
+func (w *responseWriter) Size() int {
+	if !w.Written() {
+		return -1
+	}
+	return len(w.c.Response.Body)
+}
+
+// Written implements ResponseWriter
+func (w *responseWriter) Written() bool {
+	return w.written || len(w.c.Response.Body) > 0 || w.c.headerSent
+}
+
+// Flush implements http.Flusher
+func (w *responseWriter) Flush() {
+	w.c.flush()
+}
+
+// Pusher implements ResponseWriter
+func (w *responseWriter) Pusher() http.Pusher {
+	return w.c.Pusher()
+}
+
+// writeBody replaces the buffered response through c.Writer, so wrapping
+// writers see rendered output
+func (c *Context) writeBody(code int, data []byte) {
+	c.Writer.WriteHeader(code)
+	c.Response.Body = nil
+	c.Writer.Write(data)
+}
+
+// responseBuffer adapts the buffered Response to http.ResponseWriter so
+// net/http helpers such as http.ServeContent can write into it
+type responseBuffer struct {
+	c           *Context
+	header      http.Header
+	wroteHeader bool
+}
+
+// Header implements http.ResponseWriter
+func (rb *responseBuffer) Header() http.Header {
+	if rb.header == nil {
+		rb.header = http.Header{}
+		for key, value := range rb.c.Response.Headers {
+			rb.header.Set(key, value)
+		}
+	}
+	return rb.header
+}
+
+// WriteHeader implements http.ResponseWriter
+func (rb *responseBuffer) WriteHeader(code int) {
+	if rb.wroteHeader {
+		return
+	}
+	rb.wroteHeader = true
+	headers := make(map[string]string, len(rb.Header()))
+	for key, values := range rb.Header() {
+		headers[key] = strings.Join(values, ", ")
+	}
+	rb.c.Response.Headers = headers
+	rb.c.Response.Body = nil
+	rb.c.Writer.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (rb *responseBuffer) Write(data []byte) (int, error) {
+	if !rb.wroteHeader {
+		rb.WriteHeader(200)
+	}
+	return rb.c.Writer.Write(data)
+}
+
+// BindJSON binds the request body to a struct
+func (c *Context) BindJSON(obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.AbortWithError(400, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// ShouldBindJSON decodes a JSON body into obj and validates `binding` tags
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if err := json.Unmarshal(c.Request.Body, obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// BindYAML binds a YAML request body to a struct
+func (c *Context) BindYAML(obj interface{}) error {
+	if err := c.ShouldBindYAML(obj); err != nil {
+		c.AbortWithError(400, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// ShouldBindYAML decodes a YAML body into obj with the YAML codec and
+// validates `binding` tags
+func (c *Context) ShouldBindYAML(obj interface{}) error {
+	if err := YAML.Unmarshal(c.Request.Body, obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindQuery binds query parameters to obj using `form` tags
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	if err := mapForm(obj, c.Request.Query, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindUri binds route parameters to obj using `uri` tags
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for key, value := range c.Params {
+		values[key] = []string{value}
+	}
+	if err := mapForm(obj, values, "uri"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ShouldBindHeader binds request headers to obj using `header` tags
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	values := make(map[string][]string, len(c.Request.Headers))
+	for key, value := range c.Request.Headers {
+		values[http.CanonicalHeaderKey(key)] = []string{value}
+	}
+	if err := mapForm(obj, values, "header"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// ContentType returns the request Content-Type without parameters
+func (c *Context) ContentType() string {
+	contentType := c.GetHeader("Content-Type")
+	if idx := strings.Index(contentType, ";"); idx >= 0 {
+		contentType = contentType[:idx]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// parseForm parses url-encoded and multipart bodies once per request
+func (c *Context) parseForm() error {
+	if c.formParsed {
+		return c.formErr
+	}
+	c.formParsed = true
+
+	req, err := c.httpRequest()
+	if err != nil {
+		c.formErr = err
+		return err
+	}
+
+	if c.ContentType() == "multipart/form-data" {
+		maxMemory := int64(defaultMultipartMemory)
+		if c.engine != nil {
+			maxMemory = c.engine.MaxMultipartMemory
+		}
+		err = req.ParseMultipartForm(maxMemory)
+	} else {
+		err = req.ParseForm()
+	}
+
+	c.form = req.Form
+	c.postForm = req.PostForm
+	c.multipartForm = req.MultipartForm
+	if c.postForm == nil {
+		c.postForm = url.Values{}
+	}
+	c.formErr = err
+	return err
+}
+
+// PostForm returns a value from a url-encoded or multipart form body
+func (c *Context) PostForm(key string) string {
+	value, _ := c.GetPostForm(key)
+	return value
+}
+
+// DefaultPostForm returns a form value or defaultValue if it is missing
+func (c *Context) DefaultPostForm(key, defaultValue string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetPostForm returns a form value and whether it was present
+func (c *Context) GetPostForm(key string) (string, bool) {
+	if values, ok := c.GetPostFormArray(key); ok {
+		return values[0], true
+	}
+	return "", false
+}
+
+// PostFormArray returns all values for a form key
+func (c *Context) PostFormArray(key string) []string {
+	values, _ := c.GetPostFormArray(key)
+	return values
+}
+
+// GetPostFormArray returns all values for a form key and whether it was present
+func (c *Context) GetPostFormArray(key string) ([]string, bool) {
+	c.parseForm()
+	values, ok := c.postForm[key]
+	if !ok || len(values) == 0 {
+		return []string{}, false
+	}
+	return values, true
+}
+
+// MultipartForm returns the parsed multipart form, including uploaded files
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.parseForm(); err != nil {
+		return nil, err
+	}
+	if c.multipartForm == nil {
+		return nil, http.ErrNotMultipart
+	}
+	return c.multipartForm, nil
+}
+
+// FormFile returns the first uploaded file for the given form key
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile writes an uploaded file to dst, creating parent directories
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
+}
+
+// ShouldBind binds the request to obj based on the method and Content-Type:
+// JSON and YAML bodies are decoded as such, everything else is bound from
+// the form (query string plus url-encoded or multipart body) using `form`
+// tags
+func (c *Context) ShouldBind(obj interface{}) error {
+	if c.Request.Method != "GET" {
+		switch c.ContentType() {
+		case MIMEJSON:
+			return c.ShouldBindJSON(obj)
+		case MIMEYAML, "application/x-yaml":
+			return c.ShouldBindYAML(obj)
+		}
+	}
+	if err := c.parseForm(); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	if err := mapForm(obj, c.form, "form"); err != nil {
+		return err
+	}
+	return validate(obj)
+}
+
+// mapForm copies values into the fields of the struct pointed to by obj,
+// matching field names (or the given struct tag) to keys
+func mapForm(obj interface{}, values map[string][]string, tag string) error {
+	ptr := reflect.ValueOf(obj)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binding requires a pointer to a struct, got %T", obj)
+	}
+	return mapStruct(ptr.Elem(), values, tag)
+}
+
+// mapStruct maps values onto each exported field of a struct value
+func mapStruct(value reflect.Value, values map[string][]string, tag string) error {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // unexported
+		}
+
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if idx := strings.Index(name, ","); idx >= 0 {
+			name = name[:idx]
+		}
+
+		fieldValue := value.Field(i)
+		_, convertible := lookupConverter(fieldValue.Type())
+		if name == "" && fieldValue.Kind() == reflect.Struct && !convertible {
+			// Untagged nested and embedded structs share the same values
+			if err := mapStruct(fieldValue, values, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if tag == "header" {
+			name = http.CanonicalHeaderKey(name)
+		}
+
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setFormField(fieldValue, vals, field); err != nil {
+			return fmt.Errorf("binding field %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// BindingConverter parses a query, form, URI or header value into a
+// custom type. The struct field gives access to tags such as time_format.
+type BindingConverter func(value string, field reflect.StructField) (interface{}, error)
+
+var (
+	convertersMu      sync.RWMutex
+	bindingConverters = map[reflect.Type]BindingConverter{}
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// RegisterBindingConverter makes form-style binding parse fields of the
+// type of sample with convert, replacing any earlier converter
+func RegisterBindingConverter(sample interface{}, convert BindingConverter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	bindingConverters[reflect.TypeOf(sample)] = convert
+}
+
+func init() {
+	RegisterBindingConverter(time.Time{}, convertTime)
+	RegisterBindingConverter(time.Duration(0), func(value string, _ reflect.StructField) (interface{}, error) {
+		if value == "" {
+			return time.Duration(0), nil
+		}
+		return time.ParseDuration(value)
+	})
+}
+
+// lookupConverter returns the registered converter for t, falling back to
+// encoding.TextUnmarshaler (as implemented by UUID types)
+func lookupConverter(t reflect.Type) (BindingConverter, bool) {
+	convertersMu.RLock()
+	convert, ok := bindingConverters[t]
+	convertersMu.RUnlock()
+	if ok {
+		return convert, true
+	}
+	if t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(value string, _ reflect.StructField) (interface{}, error) {
+			ptr := reflect.New(t)
+			if value == "" {
+				return ptr.Elem().Interface(), nil
+			}
+			if err := ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+				return nil, err
+			}
+			return ptr.Elem().Interface(), nil
+		}, true
+	}
+	return nil, false
+}
+
+// convertTime parses RFC3339 by default, or the layout in the field's
+// time_format tag ("unix" and "unixnano" take epoch numbers). time_utc
+// and time_location select the location for layouts without a zone.
+func convertTime(value string, field reflect.StructField) (interface{}, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	format := field.Tag.Get("time_format")
+	switch format {
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if format == "unix" {
+			return time.Unix(n, 0), nil
+		}
+		return time.Unix(0, n), nil
+	case "":
+		format = time.RFC3339
+	}
+
+	loc := time.Local
+	if utc, _ := strconv.ParseBool(field.Tag.Get("time_utc")); utc {
+		loc = time.UTC
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, err
+		}
+	}
+	return time.ParseInLocation(format, value, loc)
+}
+
+// UUID is a 128-bit identifier, bound and rendered in its canonical
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx form
+type UUID [16]byte
+
+// ParseUUID parses a UUID in canonical form, with or without hyphens,
+// braces or a urn:uuid: prefix
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	raw := strings.TrimPrefix(strings.ToLower(s), "urn:uuid:")
+	raw = strings.TrimSuffix(strings.TrimPrefix(raw, "{"), "}")
+	if len(raw) == 36 {
+		if raw[8] != '-' || raw[13] != '-' || raw[18] != '-' || raw[23] != '-' {
+			return u, fmt.Errorf("invalid UUID %q", s)
+		}
+		raw = strings.ReplaceAll(raw, "-", "")
+	}
+	if len(raw) != 32 {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(raw)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical form
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(text []byte) error {
+	parsed, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// FieldError describes a single failed `binding` rule
+type FieldError struct {
+	Namespace string // e.g. "Signup.Address.City"
+	Field     string
+	Tag       string // the failed rule, e.g. "required" or "min"
+	Param     string // the rule parameter, e.g. "3" for min=3
+	Value     interface{}
+}
+
+// Error implements the error interface
+func (fe FieldError) Error() string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag",
+		fe.Namespace, fe.Field, fe.Tag)
+}
+
+// ValidationErrors is returned by the bind methods when validation fails
+type ValidationErrors []FieldError
+
+// Error implements the error interface
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, fe := range ve {
+		messages[i] = fe.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// emailPattern is a pragmatic email address check
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
+// StructValidator validates the structs the bind methods fill in
+type StructValidator interface {
+	// ValidateStruct validates obj, ignoring values that are not structs
+	ValidateStruct(obj interface{}) error
+	// Engine returns the underlying validation engine, for registering
+	// custom rules
+	Engine() interface{}
+}
+
+// Validator is the StructValidator used by the bind methods. Replace it
+// to plug in a full validation engine such as the validator emulator's
+// BindingValidator; set it to nil to disable validation.
+var Validator StructValidator = defaultValidator{}
+
+// defaultValidator applies the built-in subset of `binding` rules
+type defaultValidator struct{}
+
+// ValidateStruct implements StructValidator
+func (defaultValidator) ValidateStruct(obj interface{}) error {
+	return validateBindingTags(obj)
+}
+
+// Engine implements StructValidator; the built-in rules have no engine
+func (defaultValidator) Engine() interface{} {
+	return nil
+}
+
+// validate runs the configured Validator on a bound obj
+func validate(obj interface{}) error {
+	if Validator == nil {
+		return nil
+	}
+	return Validator.ValidateStruct(obj)
+}
+
+// validateBindingTags checks `binding` tags on obj, returning
+// ValidationErrors on failure
+func validateBindingTags(obj interface{}) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	validateStruct(value, value.Type().Name(), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct applies the rules of each field, recursing into structs
+func validateStruct(value reflect.Value, namespace string, errs *ValidationErrors) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		fieldValue := value.Field(i)
+		fieldNamespace := namespace + "." + field.Name
+
+		if tag := field.Tag.Get("binding"); tag != "" && tag != "-" {
+			validateField(fieldValue, field.Name, fieldNamespace, tag, errs)
+		}
+
+		nested := fieldValue
+		if nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type() != reflect.TypeOf(time.Time{}) {
+			validateStruct(nested, fieldNamespace, errs)
+		}
+	}
+}
+
+// validateField applies a comma-separated rule list to one field
+func validateField(value reflect.Value, name, namespace, tag string, errs *ValidationErrors) {
+	rules := strings.Split(tag, ",")
+	isZero := value.IsZero()
+	for _, rule := range rules {
+		if rule == "omitempty" && isZero {
+			return
+		}
+	}
+
+	if value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	for _, rule := range rules {
+		ruleName, param := rule, ""
+		if idx := strings.Index(rule, "="); idx >= 0 {
+			ruleName, param = rule[:idx], rule[idx+1:]
+		}
+
+		if !checkRule(value, ruleName, param, isZero) {
+			*errs = append(*errs, FieldError{
+				Namespace: namespace,
+				Field:     name,
+				Tag:       ruleName,
+				Param:     param,
+				Value:     value.Interface(),
+			})
+			// Report only the first failing rule per field
+			return
+		}
+	}
+}
+
+// checkRule reports whether value satisfies a single rule
+func checkRule(value reflect.Value, rule, param string, isZero bool) bool {
+	switch rule {
+	case "required":
+		return !isZero
+	case "omitempty", "":
+		return true
+	case "email":
+		return value.Kind() == reflect.String && emailPattern.MatchString(value.String())
+	case "oneof":
+		actual := fmt.Sprintf("%v", value.Interface())
+		for _, option := range strings.Fields(param) {
+			if actual == option {
+				return true
+			}
+		}
+		return false
+	case "min", "max", "len", "gt", "gte", "lt", "lte":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false
+		}
+		measured, ok := measure(value)
+		if !ok {
+			return false
+		}
+		switch rule {
+		case "min", "gte":
+			return measured >= limit
+		case "max", "lte":
+			return measured <= limit
+		case "len":
+			return measured == limit
+		case "gt":
+			return measured > limit
+		default:
+			return measured < limit
+		}
+	default:
+		panic(fmt.Sprintf("unknown binding rule '%s'", rule))
+	}
+}
+
+// measure returns the length of strings and collections or a number's value
+func measure(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(len([]rune(value.String()))), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// setFormField sets a field from one or more string values
+func setFormField(field reflect.Value, vals []string, sf reflect.StructField) error {
+	if _, ok := lookupConverter(field.Type()); ok {
+		return setFormValue(field, vals[0], sf)
+	}
+	switch field.Kind() {
+	case reflect.Ptr:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFormField(field.Elem(), vals, sf)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setFormValue(slice.Index(i), val, sf); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	default:
+		return setFormValue(field, vals[0], sf)
+	}
+}
+
+// setFormValue converts a single string value to the field's type
+func setFormValue(field reflect.Value, val string, sf reflect.StructField) error {
+	if convert, ok := lookupConverter(field.Type()); ok {
+		converted, err := convert(val, sf)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(converted).Convert(field.Type()))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(val)
+	case reflect.Bool:
+		if val == "" {
+			val = "false"
+		}
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val == "" {
+			val = "0"
+		}
+		i, err := strconv.ParseInt(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val == "" {
+			val = "0"
+		}
+		u, err := strconv.ParseUint(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if val == "" {
+			val = "0"
+		}
+		f, err := strconv.ParseFloat(val, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Ptr:
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFormValue(field.Elem(), val, sf)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Set stores a value in the context for later handlers in the chain
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+// Get retrieves a value from the context
+func (c *Context) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, exists := c.Keys[key]
+	return value, exists
+}
+
+// MustGet retrieves a value from the context, panicking if it is missing
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("Key \"" + key + "\" does not exist")
+}
+
+// GetString returns the value for key as a string, or "" if missing
+func (c *Context) GetString(key string) string {
+	value, _ := c.Get(key)
+	s, _ := value.(string)
+	return s
+}
+
+// GetBool returns the value for key as a bool, or false if missing
+func (c *Context) GetBool(key string) bool {
+	value, _ := c.Get(key)
+	b, _ := value.(bool)
+	return b
+}
+
+// GetInt returns the value for key as an int, or 0 if missing
+func (c *Context) GetInt(key string) int {
+	value, _ := c.Get(key)
+	i, _ := value.(int)
+	return i
+}
+
+// GetInt64 returns the value for key as an int64, or 0 if missing
+func (c *Context) GetInt64(key string) int64 {
+	value, _ := c.Get(key)
+	i, _ := value.(int64)
+	return i
+}
+
+// GetFloat64 returns the value for key as a float64, or 0 if missing
+func (c *Context) GetFloat64(key string) float64 {
+	value, _ := c.Get(key)
+	f, _ := value.(float64)
+	return f
+}
+
+// GetDuration returns the value for key as a time.Duration, or 0 if missing
+func (c *Context) GetDuration(key string) time.Duration {
+	value, _ := c.Get(key)
+	d, _ := value.(time.Duration)
+	return d
+}
+
+// GetStringSlice returns the value for key as a []string, or nil if missing
+func (c *Context) GetStringSlice(key string) []string {
+	value, _ := c.Get(key)
+	ss, _ := value.([]string)
+	return ss
+}
+
+// GetStringMap returns the value for key as a map, or nil if missing
+func (c *Context) GetStringMap(key string) map[string]interface{} {
+	value, _ := c.Get(key)
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+// Middleware
+
+// CORSConfig describes a cross-origin resource sharing policy
+type CORSConfig struct {
+	AllowAllOrigins bool
+	// AllowOrigins lists exact origins, or patterns such as
+	// "https://*.example.com" when AllowWildcard is set
+	AllowOrigins []string
+	// AllowOriginFunc decides origins not listed in AllowOrigins
+	AllowOriginFunc  func(origin string) bool
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+	AllowWildcard    bool
+}
+
+// DefaultCORSConfig allows all origins with the common methods and headers
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowAllOrigins: true,
+		AllowMethods:    []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowHeaders:    []string{"Origin", "Content-Length", "Content-Type"},
+		MaxAge:          12 * time.Hour,
+	}
+}
+
+// Validate reports conflicting or missing origin settings
+func (cfg CORSConfig) Validate() error {
+	if cfg.AllowAllOrigins && (len(cfg.AllowOrigins) > 0 || cfg.AllowOriginFunc != nil) {
+		return errors.New("cors: AllowAllOrigins conflicts with AllowOrigins and AllowOriginFunc")
+	}
+	if !cfg.AllowAllOrigins && len(cfg.AllowOrigins) == 0 && cfg.AllowOriginFunc == nil {
+		return errors.New("cors: no origins allowed, set AllowAllOrigins, AllowOrigins or AllowOriginFunc")
+	}
+	for _, origin := range cfg.AllowOrigins {
+		if origin == "*" {
+			return errors.New("cors: use AllowAllOrigins instead of \"*\" in AllowOrigins")
+		}
+		if strings.Contains(origin, "*") && !cfg.AllowWildcard {
+			return fmt.Errorf("cors: origin %q contains a wildcard but AllowWildcard is not set", origin)
+		}
+		if strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("cors: origin %q may only contain one wildcard", origin)
+		}
+	}
+	return nil
+}
+
+func (cfg CORSConfig) allowOrigin(origin string) bool {
+	if cfg.AllowAllOrigins {
+		return true
+	}
+	for _, allowed := range cfg.AllowOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if cfg.AllowWildcard {
+			if i := strings.Index(allowed, "*"); i >= 0 {
+				prefix, suffix := allowed[:i], allowed[i+1:]
+				if len(origin) >= len(prefix)+len(suffix) &&
+					strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+					return true
+				}
+			}
+		}
+	}
+	return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin)
+}
+
+// CORS returns a middleware applying the given policy. Preflight requests
+// (OPTIONS with Access-Control-Request-Method) are answered with 204 and
+// never reach route handlers; disallowed origins get 403. It panics if the
+// config is invalid.
+func CORS(cfg CORSConfig) HandlerFunc {
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	methods := make([]string, len(cfg.AllowMethods))
+	for i, method := range cfg.AllowMethods {
+		methods[i] = strings.ToUpper(method)
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := strconv.FormatInt(int64(cfg.MaxAge/time.Second), 10)
+
+	return func(c *Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !cfg.allowOrigin(origin) {
+			c.AbortWithStatus(403)
+			return
+		}
+
+		if cfg.AllowAllOrigins && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == "OPTIONS" && c.GetHeader("Access-Control-Request-Method") != "" {
+			if allowMethods != "" {
+				c.Header("Access-Control-Allow-Methods", allowMethods)
+			}
+			if allowHeaders != "" {
+				c.Header("Access-Control-Allow-Headers", allowHeaders)
+			} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				c.Header("Access-Control-Allow-Headers", requested)
+			}
+			if cfg.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(204)
+			return
+		}
+
+		if exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		c.Next()
+	}
+}
+
+// AuthUserKey is the context key holding the authenticated user
+const AuthUserKey = "user"
+
+// Accounts maps user names to passwords for BasicAuth
+type Accounts map[string]string
+
+// BasicAuth returns a middleware requiring HTTP Basic credentials from
+// accounts, using the default realm
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm is BasicAuth with a custom realm. Failures abort with
+// 401 and a WWW-Authenticate challenge; on success the user name is stored
+// under AuthUserKey.
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if len(accounts) == 0 {
+		panic("BasicAuth: empty list of authorized credentials")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+
+	return func(c *Context) {
+		req := &http.Request{Header: http.Header{"Authorization": {c.GetHeader("Authorization")}}}
+		user, password, ok := req.BasicAuth()
+		if ok {
+			expected, found := accounts[user]
+			// Compare even for unknown users to keep timing uniform
+			match := subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+			if found && match {
+				c.Set(AuthUserKey, user)
+				c.Next()
+				return
+			}
+		}
+		c.Header("WWW-Authenticate", challenge)
+		c.AbortWithStatus(401)
+	}
+}
+
+// TokenValidator checks a bearer token, returning the user it belongs to
+type TokenValidator func(token string) (user interface{}, err error)
+
+// BearerAuth returns a middleware requiring an "Authorization: Bearer"
+// token accepted by validate, using the default realm
+func BearerAuth(validate TokenValidator) HandlerFunc {
+	return BearerAuthForRealm(validate, "")
+}
+
+// BearerAuthForRealm is BearerAuth with a custom realm. Missing tokens get a
+// plain challenge and rejected ones an error="invalid_token" challenge, both
+// with 401; on success the validator's user is stored under AuthUserKey.
+func BearerAuthForRealm(validate TokenValidator, realm string) HandlerFunc {
+	if validate == nil {
+		panic("BearerAuth: nil token validator")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Bearer realm=" + strconv.Quote(realm)
+
+	return func(c *Context) {
+		header := c.GetHeader("Authorization")
+		if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") || strings.TrimSpace(header[7:]) == "" {
+			c.Header("WWW-Authenticate", challenge)
+			c.AbortWithStatus(401)
+			return
+		}
+		user, err := validate(strings.TrimSpace(header[7:]))
+		if err != nil {
+			c.Header("WWW-Authenticate", challenge+`, error="invalid_token"`)
+			c.AbortWithStatus(401)
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// BodyLimit returns a middleware rejecting request bodies larger than
+// limit bytes with 413. Place it after Gzip to limit decompressed sizes.
+func BodyLimit(limit int64) HandlerFunc {
+	return func(c *Context) {
+		size := int64(len(c.Request.Body))
+		if declared, err := strconv.ParseInt(c.GetHeader("Content-Length"), 10, 64); err == nil && declared > size {
+			size = declared
+		}
+		if size > limit {
+			c.Abort()
+			c.writeBody(413, []byte("413 Request Entity Too Large"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Compression levels for Gzip
+const (
+	NoCompression      = gzip.NoCompression
+	BestSpeed          = gzip.BestSpeed
+	BestCompression    = gzip.BestCompression
+	DefaultCompression = gzip.DefaultCompression
+)
+
+// Gzip returns a middleware that transparently decompresses gzip and
+// deflate request bodies (400 if corrupt, 413 if they decompress to more
+// than the engine's MaxDecompressedBodySize) and gzips responses for
+// clients sending "Accept-Encoding: gzip". It panics on an invalid level.
+func Gzip(level int) HandlerFunc {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(err)
+	}
+
+	return func(c *Context) {
+		if encoding := strings.ToLower(c.GetHeader("Content-Encoding")); encoding == "gzip" || encoding == "deflate" {
+			body, err := decompressBody(encoding, c.Request.Body, c.engine.MaxDecompressedBodySize)
+			if err == errBodyTooLarge {
+				c.Abort()
+				c.writeBody(413, []byte("413 Request Entity Too Large"))
+				return
+			}
+			if err != nil {
+				c.Abort()
+				c.writeBody(400, []byte("400 Bad Request: "+err.Error()))
+				return
+			}
+			c.Request.Body = body
+			deleteHeader(c.Request.Headers, "Content-Encoding")
+			deleteHeader(c.Request.Headers, "Content-Length")
+		}
+
+		c.Next()
+
+		if !acceptsEncoding(c.GetHeader("Accept-Encoding"), "gzip") || !shouldCompress(c) {
+			return
+		}
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, level)
+		zw.Write(c.Response.Body)
+		zw.Close()
+
+		c.Response.Body = buf.Bytes()
+		deleteHeader(c.Response.Headers, "Content-Length")
+		c.Header("Content-Encoding", "gzip")
+		c.Header("Vary", "Accept-Encoding")
+	}
+}
+
+// errBodyTooLarge reports a request body decompressing past its limit
+var errBodyTooLarge = errors.New("gin: decompressed body too large")
+
+// decompressBody decodes body, reading no more than limit bytes of output
+// so a small compressed body can't expand without bound
+func decompressBody(encoding string, body []byte, limit int64) ([]byte, error) {
+	var r io.ReadCloser
+	if encoding == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	} else {
+		// HTTP deflate is zlib-wrapped (RFC 9110), though some clients
+		// send raw DEFLATE; fall back to it when the zlib header is wrong
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if errors.Is(err, zlib.ErrHeader) {
+			zr, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return out, nil
+}
+
+// shouldCompress skips empty, already encoded and already streamed bodies
+func shouldCompress(c *Context) bool {
+	if len(c.Response.Body) == 0 || c.headerSent {
+		return false
+	}
+	switch c.Response.StatusCode {
+	case 204, 304:
+		return false
+	}
+	for key := range c.Response.Headers {
+		if strings.EqualFold(key, "Content-Encoding") {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding
+func acceptsEncoding(header, coding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+		if !strings.EqualFold(name, coding) && name != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func deleteHeader(headers map[string]string, name string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// ETagConfig configures the ETag middleware
+type ETagConfig struct {
+	// Weak marks generated ETags as weak (W/"..."), for responses whose
+	// encoding may vary, e.g. behind Gzip
+	Weak bool
+}
+
+// ETag returns a middleware adding a strong ETag to successful GET and
+// HEAD responses and answering conditional requests with 304
+func ETag() HandlerFunc {
+	return ETagWithConfig(ETagConfig{})
+}
+
+// ETagWithConfig returns an ETag middleware with the given config.
+//
+// An ETag set by the handler is kept; otherwise one is computed from the
+// body. If-None-Match is matched against it with weak comparison, and
+// If-Modified-Since against a Last-Modified header set by the handler.
+func ETagWithConfig(cfg ETagConfig) HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+
+		method := c.Request.Method
+		if (method != "GET" && method != "HEAD") || c.Response.StatusCode != 200 || c.headerSent {
+			return
+		}
+		c.writermem.syncHeader()
+		etag := headerValue(c.Response.Headers, "ETag")
+		if etag == "" {
+			sum := sha256.Sum256(c.Response.Body)
+			etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			if cfg.Weak {
+				etag = "W/" + etag
+			}
+			c.Header("ETag", etag)
+		}
+
+		if notModified(c.Request.Headers, etag, headerValue(c.Response.Headers, "Last-Modified")) {
+			c.Response.StatusCode = 304
+			c.Response.Body = nil
+			deleteHeader(c.Response.Headers, "Content-Type")
+			deleteHeader(c.Response.Headers, "Content-Length")
+			deleteHeader(c.Response.Headers, "Last-Modified")
+		}
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when it is
+// absent, as in RFC 9110 section 13.2.2
+func notModified(request map[string]string, etag, lastModified string) bool {
+	if inm := headerValue(request, "If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	ims := headerValue(request, "If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && !modified.Truncate(time.Second).After(since)
+}
+
+// CacheConfig describes a Cache-Control header
+type CacheConfig struct {
+	Public         bool
+	Private        bool
+	NoCache        bool
+	NoStore        bool
+	MustRevalidate bool
+	Immutable      bool
+	// MaxAge and SharedMaxAge are sent in whole seconds when positive
+	MaxAge       time.Duration
+	SharedMaxAge time.Duration
+}
+
+// String returns the Cache-Control header value
+func (cfg CacheConfig) String() string {
+	var directives []string
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{cfg.Public, "public"},
+		{cfg.Private, "private"},
+		{cfg.NoCache, "no-cache"},
+		{cfg.NoStore, "no-store"},
+		{cfg.MustRevalidate, "must-revalidate"},
+		{cfg.Immutable, "immutable"},
+	}
+	for _, flag := range flags {
+		if flag.set {
+			directives = append(directives, flag.name)
+		}
+	}
+	if cfg.MaxAge > 0 {
+		directives = append(directives, "max-age="+strconv.Itoa(int(cfg.MaxAge.Seconds())))
+	}
+	if cfg.SharedMaxAge > 0 {
+		directives = append(directives, "s-maxage="+strconv.Itoa(int(cfg.SharedMaxAge.Seconds())))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// CacheControl returns a middleware setting the Cache-Control header, e.g.
+// per route group. Handlers can still override it with c.Header.
+func CacheControl(cfg CacheConfig) HandlerFunc {
+	value := cfg.String()
+	return func(c *Context) {
+		if value != "" {
+			c.Header("Cache-Control", value)
+		}
+		c.Next()
+	}
+}
+
+// PageCachePrefix prefixes the keys under which pages are cached
+const PageCachePrefix = "gincontrib.page.cache"
+
+// CacheStore holds the pages cached by CachePage. The cache emulator's
+// InMemoryStore implements it, as can any store with these methods.
+type CacheStore interface {
+	// Get copies the value under key into value, a pointer, or returns
+	// an error if there is none
+	Get(key string, value interface{}) error
+	// Set stores value under key for expire
+	Set(key string, value interface{}, expire time.Duration) error
+	// Delete removes key
+	Delete(key string) error
+}
+
+// CachedPage is a response stored in a CacheStore
+type CachedPage struct {
+	Status int
+	Header map[string]string
+	Data   []byte
+}
+
+// CachePage wraps handle so its successful responses are cached in store
+// for expire, keyed by path and query
+func CachePage(store CacheStore, expire time.Duration, handle HandlerFunc) HandlerFunc {
+	return cachePage(store, expire, requestURI, handle)
+}
+
+// CachePageWithoutQuery is CachePage keyed by path only
+func CachePageWithoutQuery(store CacheStore, expire time.Duration, handle HandlerFunc) HandlerFunc {
+	return cachePage(store, expire, func(c *Context) string { return c.Request.Path }, handle)
+}
+
+// CacheByRequestURI returns a middleware caching the responses of the
+// rest of the chain, e.g. for a route group
+func CacheByRequestURI(store CacheStore, expire time.Duration) HandlerFunc {
+	return cachePage(store, expire, requestURI, nil)
+}
+
+// cachePage serves cached pages, or runs handle (or the rest of the
+// chain when nil) and caches the response. Only 2xx responses that were
+// not streamed and set no cookies are cached.
+func cachePage(store CacheStore, expire time.Duration, key func(*Context) string, handle HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		cacheKey := pageCacheKey(key(c))
+		var page CachedPage
+		if err := store.Get(cacheKey, &page); err == nil {
+			c.Abort()
+			for name, value := range page.Header {
+				c.Header(name, value)
+			}
+			c.writeBody(page.Status, page.Data)
+			return
+		}
+
+		if handle != nil {
+			handle(c)
+		} else {
+			c.Next()
+		}
+
+		status := c.Response.StatusCode
+		if status < 200 || status >= 300 || c.headerSent || len(c.Response.Cookies) > 0 {
+			return
+		}
+		c.writermem.syncHeader()
+		page = CachedPage{
+			Status: status,
+			Header: make(map[string]string, len(c.Response.Headers)),
+			Data:   append([]byte(nil), c.Response.Body...),
+		}
+		for name, value := range c.Response.Headers {
+			page.Header[name] = value
+		}
+		if err := store.Set(cacheKey, page, expire); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// requestURI returns the path and sorted query of the request
+func requestURI(c *Context) string {
+	if len(c.Request.Query) == 0 {
+		return c.Request.Path
+	}
+	return c.Request.Path + "?" + c.Request.Query.Encode()
+}
+
+// pageCacheKey escapes uri into a key, hashing long ones
+func pageCacheKey(uri string) string {
+	key := url.QueryEscape(uri)
+	if len(key) > 200 {
+		sum := sha1.Sum([]byte(uri))
+		key = hex.EncodeToString(sum[:])
+	}
+	return PageCachePrefix + ":" + key
+}
+
+// ProxyConfig configures ReverseProxy
+type ProxyConfig struct {
+	// Target is the upstream base URL, e.g. "http://users:8080/v2". Its
+	// path is prepended to forwarded paths and its host becomes the Host
+	// header. For an Engine upstream only the path matters.
+	Target string
+	// Engine serves forwarded requests in-process, in place of Transport
+	Engine *Engine
+	// Transport sends forwarded requests; http.DefaultTransport if nil
+	Transport http.RoundTripper
+	// StripPrefix is removed from the request path before forwarding
+	StripPrefix string
+	// Director can rewrite the outgoing request after the defaults above
+	Director func(req *http.Request)
+	// ModifyResponse can rewrite the upstream response; an error gives 502
+	ModifyResponse func(resp *http.Response) error
+}
+
+// hopHeaders are connection-level headers a proxy must not forward
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// ReverseProxy returns a handler forwarding requests upstream and copying
+// back the response. X-Forwarded-For, X-Forwarded-Host and
+// X-Forwarded-Proto are set; upstream failures give 502 Bad Gateway. It
+// panics if Target is not a valid URL.
+func ReverseProxy(cfg ProxyConfig) HandlerFunc {
+	target, err := url.Parse(cfg.Target)
+	if err != nil {
+		panic("ReverseProxy: invalid target: " + err.Error())
+	}
+	return func(c *Context) {
+		c.proxy(cfg, target, strings.TrimPrefix(c.Request.Path, cfg.StripPrefix))
+	}
+}
+
+// ProxyPass forwards the request to target as path, e.g. the remainder
+// captured by a catch-all parameter, and writes the upstream response
+func (c *Context) ProxyPass(target *Engine, path string) {
+	c.proxy(ProxyConfig{Engine: target}, &url.URL{}, path)
+}
+
+func (c *Context) proxy(cfg ProxyConfig, target *url.URL, path string) {
+	fail := func(err error) {
+		c.AbortWithError(502, err)
+		c.writeBody(502, []byte("502 Bad Gateway"))
+	}
+	req, err := c.httpRequest()
+	if err != nil {
+		fail(err)
+		return
+	}
+	req.Host = headerValue(c.Request.Headers, "Host")
+	req.RemoteAddr = c.Request.RemoteAddr
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req.URL.Path = strings.TrimSuffix(target.Path, "/") + path
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	if target.RawQuery != "" {
+		if req.URL.RawQuery == "" {
+			req.URL.RawQuery = target.RawQuery
+		} else {
+			req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+		}
+	}
+
+	if ip, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			ip = prior + ", " + ip
+		}
+		req.Header.Set("X-Forwarded-For", ip)
+	}
+	if req.Header.Get("X-Forwarded-Host") == "" && req.Host != "" {
+		req.Header.Set("X-Forwarded-Host", req.Host)
+	}
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		req.Header.Set("X-Forwarded-Proto", "http")
+	}
+	if target.Host != "" {
+		req.Host = target.Host
+	}
+	if cfg.Director != nil {
+		cfg.Director(req)
+	}
+
+	transport := cfg.Transport
+	if cfg.Engine != nil {
+		transport = engineTransport{cfg.Engine}
+	} else if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err == nil && cfg.ModifyResponse != nil {
+		if err = cfg.ModifyResponse(resp); err != nil {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	for _, name := range hopHeaders {
+		resp.Header.Del(name)
+	}
+	resp.Header.Del("Content-Length")
+	for key, values := range resp.Header {
+		if key == "Set-Cookie" {
+			continue
+		}
+		c.Header(key, strings.Join(values, ", "))
+	}
+	c.Response.Cookies = append(c.Response.Cookies, resp.Cookies()...)
+	c.writeBody(resp.StatusCode, body)
+}
+
+// engineTransport is an http.RoundTripper serving requests with an Engine
+type engineTransport struct {
+	engine *Engine
+}
+
+// RoundTrip implements http.RoundTripper
+func (t engineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.engine.ServeHTTP(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// WebSocket message types, as in RFC 6455
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// WebSocket close codes, as in RFC 6455 section 7.4.1
+const (
+	CloseNormalClosure     = 1000
+	CloseGoingAway         = 1001
+	CloseProtocolError     = 1002
+	CloseUnsupportedData   = 1003
+	CloseNoStatusReceived  = 1005
+	CloseAbnormalClosure   = 1006
+	ClosePolicyViolation   = 1008
+	CloseMessageTooBig     = 1009
+	CloseInternalServerErr = 1011
+)
+
+var (
+	// ErrBadHandshake is returned by DialWebSocket when the handler does
+	// not upgrade the connection
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	// ErrCloseSent is returned when writing after a close message
+	ErrCloseSent = errors.New("websocket: close sent")
+
+	errWebSocketClosed = errors.New("websocket: use of closed connection")
+)
+
+// CloseError is returned by ReadMessage once the peer closes the connection
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsCloseError reports whether err is a *CloseError with one of codes
+func IsCloseError(err error, codes ...int) bool {
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatCloseMessage formats a close message payload for WriteMessage
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	buf[0], buf[1] = byte(code>>8), byte(code)
+	copy(buf[2:], text)
+	return buf
+}
+
+func parseCloseMessage(data []byte) *CloseError {
+	if len(data) < 2 {
+		return &CloseError{Code: CloseNoStatusReceived}
+	}
+	return &CloseError{Code: int(data[0])<<8 | int(data[1]), Text: string(data[2:])}
+}
+
+type wsFrame struct {
+	messageType int
+	data        []byte
+}
+
+// wsPipe carries frames in one direction; closing it drops the transport
+type wsPipe struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	frames []wsFrame
+	closed bool
+}
+
+func newWSPipe() *wsPipe {
+	p := &wsPipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *wsPipe) push(frame wsFrame) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.frames = append(p.frames, frame)
+	p.cond.Signal()
+	return true
+}
+
+// pop waits for the next frame; ok is false once the pipe is closed and
+// drained
+func (p *wsPipe) pop() (frame wsFrame, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.frames) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.frames) == 0 {
+		return wsFrame{}, false
+	}
+	frame = p.frames[0]
+	p.frames = p.frames[1:]
+	return frame, true
+}
+
+func (p *wsPipe) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// WebSocketConn is one end of an in-memory WebSocket connection. As with
+// real connections, one goroutine may read and one may write at a time.
+type WebSocketConn struct {
+	in  *wsPipe
+	out *wsPipe
+
+	mu           sync.Mutex
+	closeSent    bool
+	readErr      error
+	pingHandler  func(appData string) error
+	pongHandler  func(appData string) error
+	closeHandler func(code int, text string) error
+
+	handshake chan *Response // server end: receives the 101 response
+}
+
+// newWebSocketPair returns the client and server ends of a connection
+func newWebSocketPair() (client, server *WebSocketConn) {
+	toServer, toClient := newWSPipe(), newWSPipe()
+	client = &WebSocketConn{in: toClient, out: toServer}
+	server = &WebSocketConn{in: toServer, out: toClient, handshake: make(chan *Response, 1)}
+	return client, server
+}
+
+// WriteMessage sends a message. Writing a CloseMessage starts the closing
+// handshake; later writes fail with ErrCloseSent.
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if len(data) > 125 {
+			return errors.New("websocket: control frame payload too large")
+		}
+	default:
+		return fmt.Errorf("websocket: unknown message type %d", messageType)
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closeSent {
+		return ErrCloseSent
+	}
+	if !ws.out.push(wsFrame{messageType: messageType, data: append([]byte(nil), data...)}) {
+		return errWebSocketClosed
+	}
+	if messageType == CloseMessage {
+		ws.closeSent = true
+	}
+	return nil
+}
+
+// WriteJSON sends v as a JSON text message
+func (ws *WebSocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.WriteMessage(TextMessage, data)
+}
+
+// ReadMessage waits for the next text or binary message. Pings, pongs and
+// close messages are passed to their handlers; once the peer closes,
+// ReadMessage returns a *CloseError, with CloseAbnormalClosure if the
+// connection dropped without a close message.
+func (ws *WebSocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		if ws.readErr != nil {
+			return 0, nil, ws.readErr
+		}
+		frame, ok := ws.in.pop()
+		if !ok {
+			ws.readErr = &CloseError{Code: CloseAbnormalClosure, Text: "unexpected EOF"}
+			continue
+		}
+		switch frame.messageType {
+		case TextMessage, BinaryMessage:
+			return frame.messageType, frame.data, nil
+		case PingMessage:
+			if err := ws.handler(PingMessage)(string(frame.data)); err != nil {
+				return 0, nil, err
+			}
+		case PongMessage:
+			if err := ws.handler(PongMessage)(string(frame.data)); err != nil {
+				return 0, nil, err
+			}
+		case CloseMessage:
+			closeErr := parseCloseMessage(frame.data)
+			ws.mu.Lock()
+			handle := ws.closeHandler
+			ws.mu.Unlock()
+			if handle == nil {
+				handle = func(code int, text string) error {
+					err := ws.WriteMessage(CloseMessage, FormatCloseMessage(code, ""))
+					if err == ErrCloseSent {
+						return nil
+					}
+					return err
+				}
+			}
+			handle(closeErr.Code, closeErr.Text)
+			ws.readErr = closeErr
+		}
+	}
+}
+
+// ReadJSON reads the next message and unmarshals it into v
+func (ws *WebSocketConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (ws *WebSocketConn) handler(messageType int) func(appData string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if messageType == PingMessage {
+		if ws.pingHandler != nil {
+			return ws.pingHandler
+		}
+		return func(appData string) error {
+			err := ws.WriteMessage(PongMessage, []byte(appData))
+			if err == ErrCloseSent {
+				return nil
+			}
+			return err
+		}
+	}
+	if ws.pongHandler != nil {
+		return ws.pongHandler
+	}
+	return func(string) error { return nil }
+}
+
+// SetPingHandler sets the handler for pings; the default replies with a
+// pong carrying the same data
+func (ws *WebSocketConn) SetPingHandler(h func(appData string) error) {
+	ws.mu.Lock()
+	ws.pingHandler = h
+	ws.mu.Unlock()
+}
+
+// SetPongHandler sets the handler for pongs; the default ignores them
+func (ws *WebSocketConn) SetPongHandler(h func(appData string) error) {
+	ws.mu.Lock()
+	ws.pongHandler = h
+	ws.mu.Unlock()
+}
+
+// SetCloseHandler sets the handler for close messages; the default echoes
+// the close code back to complete the closing handshake
+func (ws *WebSocketConn) SetCloseHandler(h func(code int, text string) error) {
+	ws.mu.Lock()
+	ws.closeHandler = h
+	ws.mu.Unlock()
+}
+
+// Close sends a CloseNormalClosure message unless a close message was
+// already sent, then drops the connection
+func (ws *WebSocketConn) Close() error {
+	return ws.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode sends a close message with code and text unless one was
+// already sent, then drops the connection
+func (ws *WebSocketConn) CloseWithCode(code int, text string) error {
+	err := ws.WriteMessage(CloseMessage, FormatCloseMessage(code, text))
+	if err == ErrCloseSent {
+		err = nil
+	}
+	ws.out.close()
+	return err
+}
+
+// webSocketKey is the request-context key holding the server end of a
+// connection opened with DialWebSocket
+type webSocketKey struct{}
+
+// webSocketGUID is appended to Sec-WebSocket-Key to form the accept value
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// IsWebsocket reports whether the request asks for a WebSocket upgrade
+func (c *Context) IsWebsocket() bool {
+	return headerHasToken(c.GetHeader("Connection"), "upgrade") &&
+		strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
+
+func headerHasToken(header, token string) bool {
+	for _, part := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade completes the WebSocket handshake, responding 101 Switching
+// Protocols, and returns the server end of the connection. Connections are
+// in-memory: the client end comes from DialWebSocket. Invalid handshakes
+// get 400, and requests not made through DialWebSocket get 501.
+func Upgrade(c *Context) (*WebSocketConn, error) {
+	if c.Request.Method != "GET" || !c.IsWebsocket() ||
+		c.GetHeader("Sec-WebSocket-Version") != "13" || c.GetHeader("Sec-WebSocket-Key") == "" {
+		c.Abort()
+		c.writeBody(400, []byte("400 Bad Request"))
+		return nil, ErrBadHandshake
+	}
+	ws, _ := c.Request.Context().Value(webSocketKey{}).(*WebSocketConn)
+	if ws == nil {
+		c.Abort()
+		c.writeBody(501, []byte("501 Not Implemented"))
+		return nil, errors.New("websocket: only connections from DialWebSocket can be upgraded")
+	}
+
+	c.Header("Upgrade", "websocket")
+	c.Header("Connection", "Upgrade")
+	c.Header("Sec-WebSocket-Accept", webSocketAccept(c.GetHeader("Sec-WebSocket-Key")))
+	c.Writer.WriteHeader(101)
+	c.writermem.syncHeader()
+
+	snapshot := &Response{StatusCode: c.Response.StatusCode, Headers: make(map[string]string, len(c.Response.Headers))}
+	for key, value := range c.Response.Headers {
+		snapshot.Headers[key] = value
+	}
+	select {
+	case ws.handshake <- snapshot:
+	default:
+		return nil, errors.New("websocket: connection already upgraded")
+	}
+	return ws, nil
+}
+
+// DialWebSocket opens an in-memory WebSocket connection to the handler for
+// path, which runs on its own goroutine and must call Upgrade. It returns
+// the client end and the handshake response; if the handler does not
+// upgrade, the error is ErrBadHandshake and the response is the handler's.
+// The server end is dropped when the handler returns.
+func DialWebSocket(e *Engine, path string, headers map[string]string) (*WebSocketConn, *Response, error) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	requestHeaders := map[string]string{
+		"Connection":            "Upgrade",
+		"Upgrade":               "websocket",
+		"Sec-WebSocket-Version": "13",
+		"Sec-WebSocket-Key":     key,
+	}
+	for name, value := range headers {
+		deleteHeader(requestHeaders, name)
+		requestHeaders[name] = value
+	}
+
+	client, server := newWebSocketPair()
+	ctx := context.WithValue(context.Background(), webSocketKey{}, server)
+	done := make(chan *Response, 1)
+	go func() {
+		defer server.out.close()
+		done <- e.ServeRequestContext(ctx, "GET", path, nil, requestHeaders)
+	}()
+
+	select {
+	case resp := <-server.handshake:
+		return client, resp, nil
+	case resp := <-done:
+		select {
+		case handshake := <-server.handshake:
+			return client, handshake, nil
+		default:
+			return nil, resp, ErrBadHandshake
+		}
+	}
+}
+
+// RateLimitStore holds token buckets shared by rate-limit middleware.
+// The rate emulator's Registry implements it, as can any store with this
+// method.
+type RateLimitStore interface {
+	// Take removes a token from key's bucket, which refills at rate tokens
+	// per second up to burst. When empty it reports how long until the
+	// next token is available.
+	Take(key string, rate float64, burst int) (ok bool, retryAfter time.Duration, err error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket for the time elapsed since it was last used
+// and removes a token if one is available
+func (b *tokenBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait
+}
+
+// MemoryRateStore keeps token buckets in process memory
+type MemoryRateStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// NewMemoryRateStore creates an empty in-memory store
+func NewMemoryRateStore() *MemoryRateStore {
+	return &MemoryRateStore{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateStore) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{}
+		s.buckets[key] = bucket
+	}
+	allowed, wait := bucket.take(s.now(), rate, burst)
+	return allowed, wait, nil
+}
+
+// RedisClient is the subset of the Redis emulator's Client used by the
+// Redis-backed stores
+type RedisClient interface {
+	Get(key string) (string, error)
+	Set(key string, value interface{}, expiration time.Duration) error
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
+	Del(keys ...string) (int, error)
+}
+
+// redisNil is the error the Redis client returns for missing keys
+const redisNil = "redis: nil"
+
+// clientLocks serializes the stores' commands on each client, since rate
+// and session stores may share one and a client isn't necessarily safe
+// for concurrent use
+var (
+	clientLocksMu sync.Mutex
+	clientLocks   = map[RedisClient]*sync.Mutex{}
+)
+
+func lockFor(client RedisClient) *sync.Mutex {
+	clientLocksMu.Lock()
+	defer clientLocksMu.Unlock()
+	mu, ok := clientLocks[client]
+	if !ok {
+		mu = &sync.Mutex{}
+		clientLocks[client] = mu
+	}
+	return mu
+}
+
+// rateLockTTL bounds how long a bucket stays locked by an engine that
+// died while holding it
+const rateLockTTL = time.Second
+
+// RedisRateStore keeps token buckets in Redis so several engines can share
+// limits. Buckets are stored as "tokens:unixnano" under Prefix+key, and
+// each Take holds a SetNX lock on "lock:"+Prefix+key while it reads and
+// writes the bucket, so engines can't both spend the same token.
+type RedisRateStore struct {
+	Prefix string
+
+	mu     sync.Mutex
+	client RedisClient
+	now    func() time.Time
+}
+
+// NewRedisRateStore creates a store backed by client, using the
+// "ratelimit:" key prefix
+func NewRedisRateStore(client RedisClient) *RedisRateStore {
+	return &RedisRateStore{Prefix: "ratelimit:", client: client, now: time.Now}
+}
+
+// Take implements RateLimitStore
+func (s *RedisRateStore) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock(key)
+	if err != nil {
+		return false, 0, err
+	}
+	defer unlock()
+	mu := lockFor(s.client)
+	mu.Lock()
+	defer mu.Unlock()
+
+	bucket := &tokenBucket{}
+	raw, err := s.client.Get(s.Prefix + key)
+	if err != nil && err.Error() != redisNil {
+		return false, 0, err
+	}
+	if err == nil {
+		tokens, last, found := strings.Cut(raw, ":")
+		nanos, perr := strconv.ParseInt(last, 10, 64)
+		if bucket.tokens, err = strconv.ParseFloat(tokens, 64); err != nil || !found || perr != nil {
+			return false, 0, fmt.Errorf("ratelimit: corrupt bucket %q", raw)
+		}
+		bucket.last = time.Unix(0, nanos)
+	}
+
+	allowed, wait := bucket.take(s.now(), rate, burst)
+	// Expire buckets once they would have refilled completely
+	ttl := time.Duration(float64(burst)/rate*float64(time.Second)) + time.Second
+	value := strconv.FormatFloat(bucket.tokens, 'f', -1, 64) + ":" + strconv.FormatInt(bucket.last.UnixNano(), 10)
+	if err := s.client.Set(s.Prefix+key, value, ttl); err != nil {
+		return false, 0, err
+	}
+	return allowed, wait, nil
+}
+
+// lock claims key's bucket, waiting for another engine's lock to be
+// released or to expire. The returned func releases the lock if it is
+// still ours, checking and deleting it in one step.
+func (s *RedisRateStore) lock(key string) (func(), error) {
+	lockKey := "lock:" + s.Prefix + key
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	token := hex.EncodeToString(nonce)
+	mu := lockFor(s.client)
+
+	deadline := time.Now().Add(2 * rateLockTTL)
+	for {
+		mu.Lock()
+		ok, err := s.client.SetNX(lockKey, token, rateLockTTL)
+		mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if owner, err := s.client.Get(lockKey); err == nil && owner == token {
+					s.client.Del(lockKey)
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("ratelimit: bucket %q is locked", key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// RateLimitConfig configures RateLimit
+type RateLimitConfig struct {
+	// Rate is the number of requests per second allowed on average
+	Rate float64
+	// Burst is the bucket size, i.e. how many requests may arrive at once
+	Burst int
+	// KeyFunc picks the bucket for a request; defaults to ClientIP
+	KeyFunc func(c *Context) string
+	// Store holds the buckets; defaults to a new MemoryRateStore
+	Store RateLimitStore
+}
+
+// RateLimit returns a token-bucket rate-limit middleware for use globally,
+// on a group or on a single route. Throttled requests are aborted with 429
+// and a Retry-After header in whole seconds.
+func RateLimit(cfg RateLimitConfig) HandlerFunc {
+	if cfg.Rate <= 0 || cfg.Burst <= 0 {
+		panic("RateLimit: Rate and Burst must be positive")
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(c *Context) string { return c.ClientIP() }
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateStore()
+	}
+
+	return func(c *Context) {
+		allowed, wait, err := cfg.Store.Take(cfg.KeyFunc(c), cfg.Rate, cfg.Burst)
+		if err != nil {
+			c.Abort()
+			c.writeBody(500, []byte(err.Error()))
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.Abort()
+			c.writeBody(429, []byte("429 Too Many Requests"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestIDContextKey is the context key RequestID stores the ID under
+const requestIDContextKey = "X-Request-ID"
+
+// RequestIDConfig configures RequestIDWithConfig
+type RequestIDConfig struct {
+	// Header carries the ID in requests and responses; defaults to
+	// X-Request-ID
+	Header string
+	// Generator makes IDs for requests that arrive without one; defaults
+	// to random version 4 UUIDs. The uuid emulator's NewString fits, as
+	// does any function returning ULID strings.
+	Generator func() string
+	// Handler, if set, is called with each request's ID before the rest
+	// of the chain runs, e.g. to add it to a logger
+	Handler func(c *Context, requestID string)
+}
+
+// RequestID returns a middleware that gives each request an ID, reusing
+// the one in the X-Request-ID header if the client sent it. The ID is
+// echoed in the response header and available through GetRequestID.
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig returns a RequestID middleware with the given config
+func RequestIDWithConfig(cfg RequestIDConfig) HandlerFunc {
+	if cfg.Header == "" {
+		cfg.Header = "X-Request-ID"
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = newRequestID
+	}
+
+	return func(c *Context) {
+		id := c.GetHeader(cfg.Header)
+		if id == "" {
+			id = cfg.Generator()
+		}
+		c.Header(cfg.Header, id)
+		c.Set(requestIDContextKey, id)
+		if cfg.Handler != nil {
+			cfg.Handler(c, id)
+		}
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID RequestID gave the request, or "" if the
+// middleware did not run
+func GetRequestID(c *Context) string {
+	return c.GetString(requestIDContextKey)
+}
+
+// newRequestID returns a random version 4 UUID
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Tracer reads a remote span context from request headers and starts
+// spans. The OpenTelemetry emulator's MiddlewareTracer implements it, as
+// can any tracer with these methods.
+type Tracer interface {
+	// Extract returns ctx with the span context carried in headers
+	Extract(ctx context.Context, headers map[string]string) context.Context
+	// StartSpan starts a span and returns a context holding it, and a
+	// function ending it with more attributes and the request's error
+	StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, func(attributes map[string]interface{}, err error))
+}
+
+// Tracing returns a middleware that wraps each request in a span named
+// after its route, continuing the trace in the request's traceparent
+// header, as otelgin.Middleware does. Handlers reach the span through
+// c.Request.Context(), or c itself. The span ends with the response status
+// and the last error attached with c.Error.
+func Tracing(service string, tracer Tracer) HandlerFunc {
+	return func(c *Context) {
+		ctx := tracer.Extract(c.Request.Context(), c.Request.Headers)
+		route := c.FullPath()
+		name := route
+		if name == "" {
+			name = fmt.Sprintf("HTTP %s route not found", c.Request.Method)
+		}
+		ctx, end := tracer.StartSpan(ctx, name, map[string]interface{}{
+			"http.method":      c.Request.Method,
+			"http.route":       route,
+			"http.target":      c.Request.Path,
+			"http.server_name": service,
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		var err error
+		if last := c.Errors.Last(); last != nil {
+			err = last
+		}
+		end(map[string]interface{}{"http.status_code": c.Writer.Status()}, err)
+	}
+}
+
+// TimeoutConfig configures the Timeout middleware
+type TimeoutConfig struct {
+	// Timeout is the time the rest of the chain has to finish
+	Timeout time.Duration
+	// Response writes the timeout response, with the status preset to
+	// 503; "503 Service Unavailable" is written if it writes no body
+	Response HandlerFunc
+}
+
+// Timeout returns a middleware giving the rest of the chain timeout to
+// finish, responding 503 if it does not or the request is cancelled first
+func Timeout(timeout time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig returns a Timeout middleware with the given config.
+//
+// The remaining handlers run on a copy of the context whose request
+// carries the deadline, so they can watch c.Done(). Their response is
+// copied back if they finish in time; otherwise it is discarded and writes
+// made after the deadline fail with http.ErrHandlerTimeout. Panics are
+// re-raised on the calling goroutine for Recovery to handle.
+func TimeoutWithConfig(cfg TimeoutConfig) HandlerFunc {
+	if cfg.Timeout <= 0 {
+		panic("Timeout: timeout must be positive")
+	}
+
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), cfg.Timeout)
+		defer cancel()
+
+		tc := c.timeoutCopy(ctx)
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			tc.Next()
+			close(done)
+		}()
+
+		c.Abort()
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+		case <-ctx.Done():
+		}
+		// A handler finishing after the deadline may have had writes
+		// rejected, so only a response completed in time is used
+		if ctx.Err() == nil {
+			c.mergeTimeoutCopy(tc)
+			return
+		}
+		c.Response.StatusCode = 503
+		if cfg.Response != nil {
+			cfg.Response(c)
+			c.writermem.syncHeader()
+		}
+		if len(c.Response.Body) == 0 && c.Response.StatusCode == 503 {
+			c.writeBody(503, []byte("503 Service Unavailable"))
+		}
+	}
+}
+
+// timeoutCopy returns a copy of c for running the rest of the chain with
+// ctx, with its own response buffer and keys
+func (c *Context) timeoutCopy(ctx context.Context) *Context {
+	c.writermem.syncHeader()
+	response := *c.Response
+	response.Headers = make(map[string]string, len(c.Response.Headers))
+	for key, value := range c.Response.Headers {
+		response.Headers[key] = value
+	}
+	response.Body = append([]byte(nil), c.Response.Body...)
+	response.Cookies = append([]*http.Cookie(nil), c.Response.Cookies...)
+
+	tc := &Context{
+		Request:       c.Request.WithContext(ctx),
+		Response:      &response,
+		Params:        make(map[string]string, len(c.Params)),
+		handlers:      c.handlers,
+		index:         c.index,
+		fullPath:      c.fullPath,
+		Errors:        append(errorMsgs(nil), c.Errors...),
+		engine:        c.engine,
+		sameSite:      c.sameSite,
+		formParsed:    c.formParsed,
+		formErr:       c.formErr,
+		form:          c.form,
+		postForm:      c.postForm,
+		multipartForm: c.multipartForm,
+	}
+	for key, value := range c.Params {
+		tc.Params[key] = value
+	}
+	c.mu.RLock()
+	if c.Keys != nil {
+		tc.Keys = make(map[string]interface{}, len(c.Keys))
+		for key, value := range c.Keys {
+			tc.Keys[key] = value
+		}
+	}
+	c.mu.RUnlock()
+
+	tc.writermem.c = tc
+	tc.Writer = &timeoutWriter{ResponseWriter: &tc.writermem, ctx: ctx}
+	return tc
+}
+
+// mergeTimeoutCopy copies the response, keys and errors of a finished
+// timeoutCopy back into c, writing the body through c.Writer
+func (c *Context) mergeTimeoutCopy(tc *Context) {
+	tc.writermem.syncHeader()
+	body := tc.Response.Body
+	*c.Response = *tc.Response
+	c.Response.Body = nil
+	c.Keys = tc.Keys
+	c.Errors = tc.Errors
+	if tc.writermem.Written() {
+		c.writeBody(tc.Response.StatusCode, body)
+	} else {
+		c.Writer.WriteHeader(tc.Response.StatusCode)
+	}
+}
+
+// timeoutWriter rejects writes once its handler's deadline has passed
+type timeoutWriter struct {
+	ResponseWriter
+	ctx context.Context
+}
+
+func (w *timeoutWriter) expired() bool {
+	return w.ctx.Err() != nil
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *timeoutWriter) WriteHeader(code int) {
+	if !w.expired() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write implements http.ResponseWriter
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString implements ResponseWriter
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// SessionKey is the context key under which Sessions stores its state
+const SessionKey = "gin-session"
+
+// SessionOptions controls the session cookie. A negative MaxAge deletes the
+// session; zero makes it a browser-session cookie.
+type SessionOptions struct {
+	Path     string
+	Domain   string
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// SessionStore loads and persists sessions
+type SessionStore interface {
+	// Get returns the named session for the request, or a new empty one
+	// if it is missing, expired or fails verification
+	Get(c *Context, name string) (*Session, error)
+	// Save persists the session and writes its cookie
+	Save(c *Context, session *Session) error
+	// Options sets the cookie options for new sessions
+	Options(options SessionOptions)
+}
+
+// Session holds the values of one client's session
+type Session struct {
+	ID      string
+	Values  map[interface{}]interface{}
+	IsNew   bool
+	name    string
+	options SessionOptions
+	store   SessionStore
+	ctx     *Context
+}
+
+// Name returns the cookie name of the session
+func (s *Session) Name() string {
+	return s.name
+}
+
+// Get returns the value stored under key, or nil
+func (s *Session) Get(key interface{}) interface{} {
+	return s.Values[key]
+}
+
+// Set stores a value under key
+func (s *Session) Set(key, value interface{}) {
+	s.Values[key] = value
+}
+
+// Delete removes the value stored under key
+func (s *Session) Delete(key interface{}) {
+	delete(s.Values, key)
+}
+
+// Clear removes all values
+func (s *Session) Clear() {
+	for key := range s.Values {
+		delete(s.Values, key)
+	}
+}
+
+// AddFlash adds a message that is removed when read with Flashes
+func (s *Session) AddFlash(value interface{}) {
+	flashes, _ := s.Values[flashesKey].([]interface{})
+	s.Values[flashesKey] = append(flashes, value)
+}
+
+// Flashes returns and removes all flash messages
+func (s *Session) Flashes() []interface{} {
+	flashes, _ := s.Values[flashesKey].([]interface{})
+	delete(s.Values, flashesKey)
+	return flashes
+}
+
+// Options overrides the cookie options for this session, e.g. MaxAge -1
+// to log out
+func (s *Session) Options(options SessionOptions) {
+	s.options = options
+}
+
+// Save persists the session and sets its cookie on the response
+func (s *Session) Save() error {
+	return s.store.Save(s.ctx, s)
+}
+
+const flashesKey = "_flash"
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Sessions returns a middleware making the named session available to
+// handlers through DefaultSession
+func Sessions(name string, store SessionStore) HandlerFunc {
+	return func(c *Context) {
+		c.Set(SessionKey, &sessionHandle{name: name, store: store})
+		c.Next()
+	}
+}
+
+type sessionHandle struct {
+	name    string
+	store   SessionStore
+	session *Session
+}
+
+// DefaultSession returns the request's session, loading it on first use.
+// It panics if the Sessions middleware is not installed.
+func DefaultSession(c *Context) *Session {
+	handle := c.MustGet(SessionKey).(*sessionHandle)
+	if handle.session == nil {
+		// Unreadable cookies (tampered, expired, rotated keys) start afresh
+		handle.session, _ = handle.store.Get(c, handle.name)
+	}
+	return handle.session
+}
+
+// sessionCodec signs (HMAC-SHA256) and optionally encrypts (AES-GCM)
+// cookie values. Values carry a timestamp so expired cookies are rejected.
+type sessionCodec struct {
+	hashKey []byte
+	block   cipher.AEAD
+}
+
+// newSessionCodecs builds codecs from hash/block key pairs; the first pair
+// encodes and all pairs are tried when decoding, allowing key rotation
+func newSessionCodecs(keyPairs ...[]byte) []sessionCodec {
+	if len(keyPairs) == 0 || len(keyPairs[0]) == 0 {
+		panic("sessions: a hash key is required")
+	}
+	var codecs []sessionCodec
+	for i := 0; i < len(keyPairs); i += 2 {
+		codec := sessionCodec{hashKey: keyPairs[i]}
+		if i+1 < len(keyPairs) && len(keyPairs[i+1]) > 0 {
+			block, err := aes.NewCipher(keyPairs[i+1])
+			if err != nil {
+				panic(fmt.Sprintf("sessions: invalid block key: %v", err))
+			}
+			codec.block, _ = cipher.NewGCM(block)
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs
+}
+
+func (sc sessionCodec) encode(name string, value []byte) (string, error) {
+	if sc.block != nil {
+		nonce := make([]byte, sc.block.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		value = sc.block.Seal(nonce, nonce, value, []byte(name))
+	}
+	payload := base64.RawURLEncoding.EncodeToString(value) + "." + strconv.FormatInt(time.Now().Unix(), 10)
+	return payload + "." + sc.sign(name, payload), nil
+}
+
+func (sc sessionCodec) decode(name, cookie string, maxAge int) ([]byte, error) {
+	i := strings.LastIndex(cookie, ".")
+	if i < 0 || !hmac.Equal([]byte(cookie[i+1:]), []byte(sc.sign(name, cookie[:i]))) {
+		return nil, errors.New("sessions: invalid signature")
+	}
+	encoded, stamp, _ := strings.Cut(cookie[:i], ".")
+	issued, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("sessions: invalid timestamp")
+	}
+	if maxAge > 0 && time.Now().Unix()-issued > int64(maxAge) {
+		return nil, errors.New("sessions: cookie expired")
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if sc.block != nil {
+		size := sc.block.NonceSize()
+		if len(value) < size {
+			return nil, errors.New("sessions: invalid ciphertext")
+		}
+		return sc.block.Open(nil, value[:size], value[size:], []byte(name))
+	}
+	return value, nil
+}
+
+func (sc sessionCodec) sign(name, payload string) string {
+	mac := hmac.New(sha256.New, sc.hashKey)
+	mac.Write([]byte(name + "|" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionBase holds the cookie handling shared by all stores
+type sessionBase struct {
+	codecs  []sessionCodec
+	options SessionOptions
+}
+
+func newSessionBase(keyPairs [][]byte) sessionBase {
+	return sessionBase{
+		codecs:  newSessionCodecs(keyPairs...),
+		options: SessionOptions{Path: "/", MaxAge: 86400 * 30},
+	}
+}
+
+func (b *sessionBase) Options(options SessionOptions) {
+	b.options = options
+}
+
+func (b *sessionBase) newSession(c *Context, name string, store SessionStore) *Session {
+	return &Session{
+		Values:  make(map[interface{}]interface{}),
+		IsNew:   true,
+		name:    name,
+		options: b.options,
+		store:   store,
+		ctx:     c,
+	}
+}
+
+// readCookie returns the decoded value of the named cookie
+func (b *sessionBase) readCookie(c *Context, name string) ([]byte, error) {
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, codec := range b.codecs {
+		if value, err := codec.decode(name, cookie, b.options.MaxAge); err == nil {
+			return value, nil
+		}
+	}
+	return nil, errors.New("sessions: cookie could not be verified")
+}
+
+// writeCookie encodes value into the session cookie; nil deletes it
+func (b *sessionBase) writeCookie(session *Session, value []byte) error {
+	opts := session.options
+	encoded := ""
+	if value != nil {
+		var err error
+		if encoded, err = b.codecs[0].encode(session.name, value); err != nil {
+			return err
+		}
+	}
+	c := session.ctx
+	previous := c.sameSite
+	c.sameSite = opts.SameSite
+	c.SetCookie(session.name, encoded, opts.MaxAge, opts.Path, opts.Domain, opts.Secure, opts.HttpOnly)
+	c.sameSite = previous
+	return nil
+}
+
+func encodeSessionValues(values map[interface{}]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, fmt.Errorf("sessions: %w (register custom types with gob.Register)", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSessionValues(data []byte) (map[interface{}]interface{}, error) {
+	values := make(map[interface{}]interface{})
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+// CookieStore keeps the whole session in the signed (and, with a block
+// key, encrypted) cookie
+type CookieStore struct {
+	sessionBase
+}
+
+// NewCookieStore creates a cookie store from hash/block key pairs. Block
+// keys must be 16, 24 or 32 bytes to select AES-128, -192 or -256.
+func NewCookieStore(keyPairs ...[]byte) *CookieStore {
+	return &CookieStore{newSessionBase(keyPairs)}
+}
+
+// Get implements SessionStore
+func (s *CookieStore) Get(c *Context, name string) (*Session, error) {
+	session := s.newSession(c, name, s)
+	data, err := s.readCookie(c, name)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			return session, nil
+		}
+		return session, err
+	}
+	values, err := decodeSessionValues(data)
+	if err != nil {
+		return session, err
+	}
+	session.Values = values
+	session.IsNew = false
+	return session, nil
+}
+
+// Save implements SessionStore
+func (s *CookieStore) Save(c *Context, session *Session) error {
+	if session.options.MaxAge < 0 {
+		return s.writeCookie(session, nil)
+	}
+	data, err := encodeSessionValues(session.Values)
+	if err != nil {
+		return err
+	}
+	return s.writeCookie(session, data)
+}
+
+// idStore keeps session values server-side under a random ID carried in
+// the signed cookie; load, store and remove access the backend
+type idStore struct {
+	sessionBase
+	load   func(id string) ([]byte, error)
+	store  func(id string, data []byte, maxAge int) error
+	remove func(id string) error
+}
+
+func (s *idStore) get(self SessionStore, c *Context, name string) (*Session, error) {
+	session := s.newSession(c, name, self)
+	id, err := s.readCookie(c, name)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			return session, nil
+		}
+		return session, err
+	}
+	data, err := s.load(string(id))
+	if err != nil || data == nil {
+		return session, err
+	}
+	values, err := decodeSessionValues(data)
+	if err != nil {
+		return session, err
+	}
+	session.ID = string(id)
+	session.Values = values
+	session.IsNew = false
+	return session, nil
+}
+
+func (s *idStore) save(session *Session) error {
+	if session.options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.remove(session.ID); err != nil {
+				return err
+			}
+		}
+		return s.writeCookie(session, nil)
+	}
+	if session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+	data, err := encodeSessionValues(session.Values)
+	if err != nil {
+		return err
+	}
+	if err := s.store(session.ID, data, session.options.MaxAge); err != nil {
+		return err
+	}
+	return s.writeCookie(session, []byte(session.ID))
+}
+
+// MemoryStore keeps session values in process memory
+type MemoryStore struct {
+	idStore
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore creates an in-memory store; the key pairs sign (and
+// optionally encrypt) the session ID cookie
+func NewMemoryStore(keyPairs ...[]byte) *MemoryStore {
+	s := &MemoryStore{sessions: make(map[string]memorySession)}
+	s.idStore = idStore{
+		sessionBase: newSessionBase(keyPairs),
+		load: func(id string) ([]byte, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			entry, ok := s.sessions[id]
+			if !ok || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+				delete(s.sessions, id)
+				return nil, nil
+			}
+			return entry.data, nil
+		},
+		store: func(id string, data []byte, maxAge int) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			entry := memorySession{data: data}
+			if maxAge > 0 {
+				entry.expires = time.Now().Add(time.Duration(maxAge) * time.Second)
+			}
+			s.sessions[id] = entry
+			return nil
+		},
+		remove: func(id string) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.sessions, id)
+			return nil
+		},
+	}
+	return s
+}
+
+// Get implements SessionStore
+func (s *MemoryStore) Get(c *Context, name string) (*Session, error) {
+	return s.get(s, c, name)
+}
+
+// Save implements SessionStore
+func (s *MemoryStore) Save(c *Context, session *Session) error {
+	return s.save(session)
+}
+
+// RedisStore keeps session values in Redis under Prefix+ID, expiring with
+// the session's MaxAge
+type RedisStore struct {
+	idStore
+	Prefix string
+}
+
+// NewRedisStore creates a store backed by client (e.g. the Redis emulator's
+// Client); the key pairs sign (and optionally encrypt) the session ID cookie
+func NewRedisStore(client RedisClient, keyPairs ...[]byte) *RedisStore {
+	s := &RedisStore{Prefix: "session_"}
+	mu := lockFor(client)
+	s.idStore = idStore{
+		sessionBase: newSessionBase(keyPairs),
+		load: func(id string) ([]byte, error) {
+			mu.Lock()
+			raw, err := client.Get(s.Prefix + id)
+			mu.Unlock()
+			if err != nil {
+				if err.Error() == redisNil {
+					return nil, nil
+				}
+				return nil, err
+			}
+			return base64.StdEncoding.DecodeString(raw)
+		},
+		store: func(id string, data []byte, maxAge int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return client.Set(s.Prefix+id, base64.StdEncoding.EncodeToString(data), time.Duration(maxAge)*time.Second)
+		},
+		remove: func(id string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			_, err := client.Del(s.Prefix + id)
+			return err
+		},
+	}
+	return s
+}
+
+// Get implements SessionStore
+func (s *RedisStore) Get(c *Context, name string) (*Session, error) {
+	return s.get(s, c, name)
+}
+
+// Save implements SessionStore
+func (s *RedisStore) Save(c *Context, session *Session) error {
+	return s.save(session)
+}
+
+// ErrorType classifies errors attached with c.Error
+type ErrorType uint64
+
+const (
+	// ErrorTypeBind is used for binding failures from Bind* methods
+	ErrorTypeBind ErrorType = 1 << 63
+	// ErrorTypeRender is used for rendering failures
+	ErrorTypeRender ErrorType = 1 << 62
+	// ErrorTypePrivate errors are logged but not shown to clients
+	ErrorTypePrivate ErrorType = 1 << 0
+	// ErrorTypePublic errors may be shown to clients
+	ErrorTypePublic ErrorType = 1 << 1
+	// ErrorTypeAny matches every type
+	ErrorTypeAny ErrorType = 1<<64 - 1
+)
+
+// Error is an error attached to a Context, with a type and optional metadata
+type Error struct {
+	Err  error
+	Type ErrorType
+	Meta interface{}
+}
+
+// Error implements the error interface
+func (msg *Error) Error() string {
+	return msg.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (msg *Error) Unwrap() error {
+	return msg.Err
+}
+
+// SetType sets the error's type
+func (msg *Error) SetType(flags ErrorType) *Error {
+	msg.Type = flags
+	return msg
+}
+
+// SetMeta attaches metadata to the error
+func (msg *Error) SetMeta(data interface{}) *Error {
+	msg.Meta = data
+	return msg
+}
+
+// IsType reports whether the error has any of the given type flags
+func (msg *Error) IsType(flags ErrorType) bool {
+	return msg.Type&flags > 0
+}
+
+// JSON returns a JSON-friendly view of the error: map metadata is merged
+// with the message under "error", other metadata appears under "meta"
+func (msg *Error) JSON() interface{} {
+	result := H{}
+	if msg.Meta != nil {
+		if meta, ok := msg.Meta.(H); ok {
+			for k, v := range meta {
+				result[k] = v
+			}
+		} else if meta, ok := msg.Meta.(map[string]interface{}); ok {
+			for k, v := range meta {
+				result[k] = v
+			}
+		} else {
+			result["meta"] = msg.Meta
+		}
+	}
+	if _, ok := result["error"]; !ok {
+		result["error"] = msg.Error()
+	}
+	return result
+}
+
+// MarshalJSON implements json.Marshaler
+func (msg *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(msg.JSON())
+}
+
+type errorMsgs []*Error
+
+// ByType returns the errors having any of the given type flags
+func (a errorMsgs) ByType(typ ErrorType) errorMsgs {
+	if typ == ErrorTypeAny {
+		return a
+	}
+	var result errorMsgs
+	for _, msg := range a {
+		if msg.IsType(typ) {
+			result = append(result, msg)
+		}
+	}
+	return result
+}
+
+// Last returns the most recent error, or nil
+func (a errorMsgs) Last() *Error {
+	if len(a) == 0 {
+		return nil
+	}
+	return a[len(a)-1]
+}
+
+// Errors returns the error messages
+func (a errorMsgs) Errors() []string {
+	messages := make([]string, len(a))
+	for i, msg := range a {
+		messages[i] = msg.Error()
+	}
+	return messages
+}
+
+// JSON returns the JSON views of the errors: nil, one object, or a list
+func (a errorMsgs) JSON() interface{} {
+	switch len(a) {
+	case 0:
+		return nil
+	case 1:
+		return a.Last().JSON()
+	}
+	list := make([]interface{}, len(a))
+	for i, msg := range a {
+		list[i] = msg.JSON()
+	}
+	return list
+}
+
+// MarshalJSON implements json.Marshaler
+func (a errorMsgs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.JSON())
+}
+
+// String lists the errors one per line, numbered
+func (a errorMsgs) String() string {
+	var b strings.Builder
+	for i, msg := range a {
+		fmt.Fprintf(&b, "Error #%02d: %s\n", i+1, msg.Err)
+		if msg.Meta != nil {
+			fmt.Fprintf(&b, "     Meta: %v\n", msg.Meta)
+		}
+	}
+	return b.String()
+}
+
+// ErrorHandler returns a middleware rendering c.Errors as JSON when a
+// handler recorded errors without writing a body. Public and bind errors
+// are listed under "errors"; private ones are reported only as a generic
+// message. Statuses below 400 become 400 for bind errors, else 500.
+func ErrorHandler() HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || len(c.Response.Body) > 0 || c.headerSent {
+			return
+		}
+		if c.Response.StatusCode < 400 {
+			if len(c.Errors.ByType(ErrorTypeBind)) > 0 {
+				c.Response.StatusCode = 400
+			} else {
+				c.Response.StatusCode = 500
+			}
+		}
+		visible := c.Errors.ByType(ErrorTypePublic | ErrorTypeBind)
+		if len(visible) == 0 {
+			c.JSON(c.Response.StatusCode, H{"error": http.StatusText(c.Response.StatusCode)})
+			return
+		}
+		list := make([]interface{}, len(visible))
+		for i, msg := range visible {
+			list[i] = msg.JSON()
+		}
+		c.JSON(c.Response.StatusCode, H{"errors": list})
+	}
+}
+
+// DefaultWriter is where Logger writes when no Output is configured
+var DefaultWriter io.Writer = os.Stdout
+
+// LogFormatterParams describes a completed request for a LogFormatter
+type LogFormatterParams struct {
+	Request      *Request
+	TimeStamp    time.Time
+	StatusCode   int
+	Latency      time.Duration
+	ClientIP     string
+	Method       string
+	Path         string
+	BodySize     int
+	Keys         map[string]interface{}
+	ErrorMessage string // private errors recorded with c.Error
+}
+
+// LogFormatter renders one access-log line
+type LogFormatter func(params LogFormatterParams) string
+
+// LoggerConfig configures LoggerWithConfig
+type LoggerConfig struct {
+	// Formatter renders each line; defaults to defaultLogFormatter
+	Formatter LogFormatter
+	// Output receives the lines; defaults to DefaultWriter
+	Output io.Writer
+	// SkipPaths lists request paths that are not logged
+	SkipPaths []string
+	// Skip decides per request whether to skip logging
+	Skip func(c *Context) bool
+}
+
+// defaultLogFormatter produces lines like
+// [GIN] 2006/01/02 - 15:04:05 | 200 |      1.2ms |       127.0.0.1 | GET      "/ping"
+func defaultLogFormatter(params LogFormatterParams) string {
+	latency := params.Latency
+	if latency > time.Minute {
+		latency = latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		params.StatusCode,
+		latency,
+		params.ClientIP,
+		params.Method,
+		params.Path,
+		params.ErrorMessage,
+	)
+}
+
+// Logger returns a logging middleware writing to DefaultWriter
+func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithFormatter returns a Logger using a custom format
+func LoggerWithFormatter(f LogFormatter) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Formatter: f})
+}
+
+// LoggerWithWriter returns a Logger writing to out, skipping notLogged paths
+func LoggerWithWriter(out io.Writer, notLogged ...string) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Output: out, SkipPaths: notLogged})
+}
+
+// LoggerWithConfig returns a logging middleware that writes one line per
+// request after the handlers have run
+func LoggerWithConfig(cfg LoggerConfig) HandlerFunc {
+	formatter := cfg.Formatter
+	if formatter == nil {
+		formatter = defaultLogFormatter
+	}
+	skip := make(map[string]bool, len(cfg.SkipPaths))
+	for _, p := range cfg.SkipPaths {
+		skip[p] = true
+	}
+
+	return func(c *Context) {
+		out := cfg.Output
+		if out == nil {
+			out = DefaultWriter
+		}
+		start := time.Now()
+		requestPath := c.Request.Path
+		if raw := c.Request.Query.Encode(); raw != "" {
+			requestPath += "?" + raw
+		}
+
+		c.Next()
+
+		if skip[c.Request.Path] || (cfg.Skip != nil && cfg.Skip(c)) {
+			return
+		}
+		c.mu.RLock()
+		keys := make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			keys[k] = v
+		}
+		c.mu.RUnlock()
+
+		fmt.Fprint(out, formatter(LogFormatterParams{
+			Request:      c.Request,
+			TimeStamp:    time.Now(),
+			StatusCode:   c.Response.StatusCode,
+			Latency:      time.Since(start),
+			ClientIP:     c.ClientIP(),
+			Method:       c.Request.Method,
+			Path:         requestPath,
+			BodySize:     len(c.Response.Body),
+			Keys:         keys,
+			ErrorMessage: c.Errors.ByType(ErrorTypePrivate).String(),
+		}))
+	}
+}
+
+// DefaultErrorWriter is where Recovery writes when no writer is given
+var DefaultErrorWriter io.Writer = os.Stderr
+
+// RecoveryStackKey is the context key holding the stack trace (a string)
+// of a recovered panic, for use by custom recovery handlers
+const RecoveryStackKey = "gin-recovery-stack"
+
+// RecoveryFunc handles a recovered panic value
+type RecoveryFunc func(c *Context, err interface{})
+
+// Recovery returns a recovery middleware that recovers from panics,
+// logs them with a stack trace to DefaultErrorWriter and responds 500
+func Recovery() HandlerFunc {
+	return RecoveryWithWriter(DefaultErrorWriter)
+}
+
+// CustomRecovery is Recovery with a custom handler in place of the 500
+func CustomRecovery(handle RecoveryFunc) HandlerFunc {
+	return RecoveryWithWriter(DefaultErrorWriter, handle)
+}
+
+// RecoveryWithWriter returns a recovery middleware logging to out (nil
+// disables logging) and calling the optional handler instead of
+// responding 500. Panics caused by the client hanging up (broken pipe,
+// connection reset) abort without a response or handler call.
+func RecoveryWithWriter(out io.Writer, recovery ...RecoveryFunc) HandlerFunc {
+	handle := defaultRecovery
+	if len(recovery) > 0 && recovery[0] != nil {
+		handle = recovery[0]
+	}
+
+	return func(c *Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			stack := string(debug.Stack())
+			brokenPipe := isBrokenPipe(err)
+
+			if out != nil {
+				request := dumpRequest(c.Request)
+				timestamp := time.Now().Format("2006/01/02 - 15:04:05")
+				if brokenPipe {
+					fmt.Fprintf(out, "[Recovery] %s connection error: %v\n%s\n", timestamp, err, request)
+				} else {
+					fmt.Fprintf(out, "[Recovery] %s panic recovered:\n%s\n%v\n%s\n", timestamp, request, err, stack)
+				}
+			}
+
+			if brokenPipe {
+				// The client is gone, so there is nobody to respond to
+				c.Error(err.(error))
+				c.Abort()
+				return
+			}
+			c.Set(RecoveryStackKey, stack)
+			handle(c, err)
+		}()
+		c.Next()
+	}
+}
+
+func defaultRecovery(c *Context, err interface{}) {
+	c.AbortWithStatus(500)
+}
+
+// isBrokenPipe reports whether a panic value is a lost client connection
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	var sysErr *os.SyscallError
+	if errors.As(opErr, &sysErr) {
+		e = sysErr.Err
+	}
+	message := strings.ToLower(e.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
+
+// dumpRequest formats the request line and headers for panic logs,
+// masking credentials
+func dumpRequest(req *Request) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", req.Method, req.Path)
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := req.Headers[name]
+		if strings.EqualFold(name, "Authorization") {
+			value = "*"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, value)
+	}
+	return b.String()
+}
+
+// HostSwitch dispatches requests to engines by Host header, e.g. one
+// engine per service or tenant domain. Patterns are exact host names,
+// "*.example.com" (one subdomain label, available as c.Subdomain()) or
+// "*" for every other host. Ports are ignored.
+type HostSwitch struct {
+	hosts     map[string]*Engine
+	wildcards map[string]*Engine // keyed by suffix, e.g. ".example.com"
+	fallback  *Engine
+}
+
+// NewHostSwitch creates an empty HostSwitch
+func NewHostSwitch() *HostSwitch {
+	return &HostSwitch{
+		hosts:     make(map[string]*Engine),
+		wildcards: make(map[string]*Engine),
+	}
+}
+
+// Handle routes hosts matching pattern to e. It panics if the pattern is
+// invalid or already registered.
+func (hs *HostSwitch) Handle(pattern string, e *Engine) {
+	pattern = normalizeHost(pattern)
+	switch {
+	case pattern == "*":
+		if hs.fallback != nil {
+			panic("HostSwitch: duplicate pattern \"*\"")
+		}
+		hs.fallback = e
+	case strings.HasPrefix(pattern, "*."):
+		suffix := pattern[1:]
+		if strings.Contains(suffix, "*") {
+			panic("HostSwitch: invalid pattern " + strconv.Quote(pattern))
+		}
+		if hs.wildcards[suffix] != nil {
+			panic("HostSwitch: duplicate pattern " + strconv.Quote(pattern))
+		}
+		hs.wildcards[suffix] = e
+	default:
+		if pattern == "" || strings.Contains(pattern, "*") {
+			panic("HostSwitch: invalid pattern " + strconv.Quote(pattern))
+		}
+		if hs.hosts[pattern] != nil {
+			panic("HostSwitch: duplicate pattern " + strconv.Quote(pattern))
+		}
+		hs.hosts[pattern] = e
+	}
+}
+
+// Match returns the engine for host and the subdomain matched by a
+// wildcard, preferring exact hosts, then wildcards, then "*"
+func (hs *HostSwitch) Match(host string) (*Engine, string) {
+	host = normalizeHost(host)
+	if e := hs.hosts[host]; e != nil {
+		return e, ""
+	}
+	if label, suffix, ok := strings.Cut(host, "."); ok && label != "" {
+		if e := hs.wildcards["."+suffix]; e != nil {
+			return e, label
+		}
+	}
+	return hs.fallback, ""
+}
+
+// ServeHTTP implements http.Handler, answering 404 for unknown hosts
+func (hs *HostSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e, subdomain := hs.Match(r.Host)
+	if e == nil {
+		http.Error(w, "404 Not Found", 404)
+		return
+	}
+	e.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), subdomainKey{}, subdomain)))
+}
+
+// ServeRequest simulates a request, dispatched by its Host header
+func (hs *HostSwitch) ServeRequest(method, path string, body []byte, headers map[string]string) *Response {
+	e, subdomain := hs.Match(headerValue(headers, "Host"))
+	if e == nil {
+		return &Response{StatusCode: 404, Headers: map[string]string{}, Body: []byte("404 Not Found")}
+	}
+	ctx := context.WithValue(context.Background(), subdomainKey{}, subdomain)
+	return e.ServeRequestContext(ctx, method, path, body, headers)
+}
+
+// Host returns a middleware restricting a route group to hosts matching
+// one of patterns (as for HostSwitch); other hosts get the 404 handlers
+func Host(patterns ...string) HandlerFunc {
+	// The switch is only used for matching; its engines mark a match
+	hs := NewHostSwitch()
+	for _, pattern := range patterns {
+		hs.Handle(pattern, &Engine{})
+	}
+	return func(c *Context) {
+		e, subdomain := hs.Match(c.GetHeader("Host"))
+		if e == nil {
+			c.Abort()
+			if c.engine != nil && len(c.engine.noRoute) > 0 {
+				c.serveError(404, "404 Not Found", c.engine.noRoute)
+			} else {
+				c.writeBody(404, []byte("404 Not Found"))
+			}
+			return
+		}
+		if subdomain != "" {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), subdomainKey{}, subdomain))
+		}
+		c.Next()
+	}
+}
+
+// subdomainKey is the request-context key for the wildcard subdomain
+type subdomainKey struct{}
+
+// Subdomain returns the subdomain matched by a "*." host pattern in a
+// HostSwitch or Host middleware, or ""
+func (c *Context) Subdomain() string {
+	subdomain, _ := c.Request.Context().Value(subdomainKey{}).(string)
+	return subdomain
+}
+
+// normalizeHost lowercases host and drops any port and trailing dot
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
 	}
+	return strings.TrimSuffix(host, ".")
 }
 
 // Run starts the server (simulated in this emulator)
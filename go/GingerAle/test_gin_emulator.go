@@ -2,8 +2,30 @@ package main
 
 // Developed by PowerShield, as an alternative to Gin
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 // Helper function to run a test
@@ -22,7 +44,7 @@ func testBasicRouting() bool {
 		c.String(200, "Hello World")
 	})
 
-	resp := r.ServeHTTP("GET", "/hello", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/hello", nil, map[string]string{})
 	return resp.StatusCode == 200 && string(resp.Body) == "Hello World"
 }
 
@@ -36,7 +58,7 @@ func testJSONResponse() bool {
 		})
 	})
 
-	resp := r.ServeHTTP("GET", "/json", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/json", nil, map[string]string{})
 	if resp.StatusCode != 200 {
 		return false
 	}
@@ -58,7 +80,7 @@ func testURLParameters() bool {
 		c.JSON(200, H{"userId": id})
 	})
 
-	resp := r.ServeHTTP("GET", "/users/123", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/users/123", nil, map[string]string{})
 	if resp.StatusCode != 200 {
 		return false
 	}
@@ -80,7 +102,7 @@ func testQueryParameters() bool {
 		})
 	})
 
-	resp := r.ServeHTTP("GET", "/search?q=test&limit=20", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/search?q=test&limit=20", nil, map[string]string{})
 	if resp.StatusCode != 200 {
 		return false
 	}
@@ -98,7 +120,7 @@ func testDefaultQueryParameters() bool {
 		c.String(200, limit)
 	})
 
-	resp := r.ServeHTTP("GET", "/search", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/search", nil, map[string]string{})
 	return resp.StatusCode == 200 && string(resp.Body) == "10"
 }
 
@@ -119,7 +141,7 @@ func testPOSTRequest() bool {
 	})
 
 	body := []byte(`{"name":"John Doe"}`)
-	resp := r.ServeHTTP("POST", "/users", body, map[string]string{})
+	resp := r.ServeRequest("POST", "/users", body, map[string]string{})
 	
 	if resp.StatusCode != 201 {
 		return false
@@ -144,7 +166,7 @@ func testMiddleware() bool {
 		c.String(200, "OK")
 	})
 
-	r.ServeHTTP("GET", "/test", nil, map[string]string{})
+	r.ServeRequest("GET", "/test", nil, map[string]string{})
 	return executed
 }
 
@@ -169,7 +191,7 @@ func testMiddlewareOrder() bool {
 		// Handler executes between middleware
 	})
 
-	r.ServeHTTP("GET", "/test", nil, map[string]string{})
+	r.ServeRequest("GET", "/test", nil, map[string]string{})
 	return order == "1234"
 }
 
@@ -187,7 +209,7 @@ func testAbort() bool {
 		c.String(200, "OK")
 	})
 
-	resp := r.ServeHTTP("GET", "/test", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/test", nil, map[string]string{})
 	return resp.StatusCode == 401 && !handlerExecuted
 }
 
@@ -200,7 +222,7 @@ func testRouterGroup() bool {
 		c.String(200, "Users")
 	})
 
-	resp := r.ServeHTTP("GET", "/api/users", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/api/users", nil, map[string]string{})
 	return resp.StatusCode == 200 && string(resp.Body) == "Users"
 }
 
@@ -214,7 +236,7 @@ func testNestedRouterGroups() bool {
 		c.String(200, "V1 Users")
 	})
 
-	resp := r.ServeHTTP("GET", "/api/v1/users", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/api/v1/users", nil, map[string]string{})
 	return resp.StatusCode == 200 && string(resp.Body) == "V1 Users"
 }
 
@@ -232,7 +254,7 @@ func testGroupMiddleware() bool {
 		c.String(200, "OK")
 	})
 
-	r.ServeHTTP("GET", "/api/test", nil, map[string]string{})
+	r.ServeRequest("GET", "/api/test", nil, map[string]string{})
 	return executed
 }
 
@@ -256,10 +278,10 @@ func testMultipleHTTPMethods() bool {
 		c.String(204, "DELETE")
 	})
 
-	getResp := r.ServeHTTP("GET", "/resource", nil, map[string]string{})
-	postResp := r.ServeHTTP("POST", "/resource", nil, map[string]string{})
-	putResp := r.ServeHTTP("PUT", "/resource", nil, map[string]string{})
-	deleteResp := r.ServeHTTP("DELETE", "/resource", nil, map[string]string{})
+	getResp := r.ServeRequest("GET", "/resource", nil, map[string]string{})
+	postResp := r.ServeRequest("POST", "/resource", nil, map[string]string{})
+	putResp := r.ServeRequest("PUT", "/resource", nil, map[string]string{})
+	deleteResp := r.ServeRequest("DELETE", "/resource", nil, map[string]string{})
 
 	return getResp.StatusCode == 200 &&
 		postResp.StatusCode == 201 &&
@@ -274,7 +296,7 @@ func test404Handling() bool {
 		c.String(200, "OK")
 	})
 
-	resp := r.ServeHTTP("GET", "/notfound", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/notfound", nil, map[string]string{})
 	return resp.StatusCode == 404
 }
 
@@ -286,7 +308,7 @@ func testResponseHeaders() bool {
 		c.String(200, "OK")
 	})
 
-	resp := r.ServeHTTP("GET", "/test", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/test", nil, map[string]string{})
 	return resp.Headers["X-Custom-Header"] == "CustomValue"
 }
 
@@ -301,7 +323,7 @@ func testRequestHeaders() bool {
 	headers := map[string]string{
 		"Authorization": "Bearer token123",
 	}
-	resp := r.ServeHTTP("GET", "/test", nil, headers)
+	resp := r.ServeRequest("GET", "/test", nil, headers)
 	return string(resp.Body) == "Bearer token123"
 }
 
@@ -312,7 +334,7 @@ func testDefaultEngine() bool {
 		c.String(200, "OK")
 	})
 
-	resp := r.ServeHTTP("GET", "/test", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/test", nil, map[string]string{})
 	return resp.StatusCode == 200
 }
 
@@ -342,14 +364,14 @@ func testRESTfulAPI() bool {
 	
 	// Create a user
 	createBody := []byte(`{"id":"1","name":"Alice"}`)
-	createResp := r.ServeHTTP("POST", "/users", createBody, map[string]string{})
+	createResp := r.ServeRequest("POST", "/users", createBody, map[string]string{})
 	
 	if createResp.StatusCode != 201 {
 		return false
 	}
 	
 	// Get the user
-	getResp := r.ServeHTTP("GET", "/users/1", nil, map[string]string{})
+	getResp := r.ServeRequest("GET", "/users/1", nil, map[string]string{})
 	if getResp.StatusCode != 200 {
 		return false
 	}
@@ -373,7 +395,7 @@ func testComplexURLParameters() bool {
 		})
 	})
 
-	resp := r.ServeHTTP("GET", "/api/v1/users/123/posts/456", nil, map[string]string{})
+	resp := r.ServeRequest("GET", "/api/v1/users/123/posts/456", nil, map[string]string{})
 	if resp.StatusCode != 200 {
 		return false
 	}
@@ -394,13 +416,2709 @@ func testContentTypeHeaders() bool {
 		c.String(200, "text")
 	})
 
-	jsonResp := r.ServeHTTP("GET", "/json", nil, map[string]string{})
-	textResp := r.ServeHTTP("GET", "/text", nil, map[string]string{})
+	jsonResp := r.ServeRequest("GET", "/json", nil, map[string]string{})
+	textResp := r.ServeRequest("GET", "/text", nil, map[string]string{})
 
 	return jsonResp.Headers["Content-Type"] == "application/json" &&
 		textResp.Headers["Content-Type"] == "text/plain"
 }
 
+// Test Engine as an http.Handler with httptest.ResponseRecorder
+func testHTTPHandler() bool {
+	r := New()
+	r.POST("/users/:id", func(c *Context) {
+		var user map[string]interface{}
+		c.BindJSON(&user)
+		c.Header("X-User-Id", c.Param("id"))
+		c.JSON(201, H{
+			"name":  user["name"],
+			"page":  c.Query("page"),
+			"agent": c.GetHeader("user-agent"),
+		})
+	})
+
+	var handler http.Handler = r
+	req := httptest.NewRequest("POST", "/users/42?page=2", strings.NewReader(`{"name":"Ann"}`))
+	req.Header.Set("User-Agent", "emu-test")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	return rec.Code == 201 &&
+		rec.Header().Get("X-User-Id") == "42" &&
+		rec.Header().Get("Content-Type") == "application/json" &&
+		result["name"] == "Ann" && result["page"] == "2" && result["agent"] == "emu-test"
+}
+
+// Test Engine mounted on a real net/http server
+func testHTTPServer() bool {
+	r := New()
+	r.GET("/ping", func(c *Context) {
+		c.String(200, "pong")
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ping")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	missing, err := http.Get(server.URL + "/missing")
+	if err != nil {
+		return false
+	}
+	missing.Body.Close()
+
+	return resp.StatusCode == 200 && string(body) == "pong" && missing.StatusCode == 404
+}
+
+// Test static routes take priority over parameters
+func testRoutePriority() bool {
+	r := New()
+	r.GET("/users/:id", func(c *Context) {
+		c.String(200, "%s", "param:"+c.Param("id"))
+	})
+	r.GET("/users/me", func(c *Context) {
+		c.String(200, "static")
+	})
+	r.GET("/users/:id/posts", func(c *Context) {
+		c.String(200, "%s", "posts:"+c.Param("id")+":"+c.FullPath())
+	})
+	r.GET("/users/me/settings", func(c *Context) {
+		c.String(200, "settings")
+	})
+
+	// "me" exists as a static node but has no "posts" child, so the lookup
+	// must fall back to the parameter branch
+	return string(r.ServeRequest("GET", "/users/me", nil, nil).Body) == "static" &&
+		string(r.ServeRequest("GET", "/users/42", nil, nil).Body) == "param:42" &&
+		string(r.ServeRequest("GET", "/users/me/settings", nil, nil).Body) == "settings" &&
+		string(r.ServeRequest("GET", "/users/me/posts", nil, nil).Body) == "posts:me:/users/:id/posts" &&
+		r.ServeRequest("GET", "/users/", nil, nil).StatusCode == 404
+}
+
+// Helper to check that route registration panics
+func registrationPanics(register func()) (panicked bool) {
+	defer func() {
+		panicked = recover() != nil
+	}()
+	register()
+	return false
+}
+
+// Test route conflicts are detected at registration time
+func testRouteConflicts() bool {
+	handler := func(c *Context) {}
+
+	duplicate := registrationPanics(func() {
+		r := New()
+		r.GET("/users/:id", handler)
+		r.GET("/users/:id", handler)
+	})
+	paramConflict := registrationPanics(func() {
+		r := New()
+		r.GET("/users/:id", handler)
+		r.GET("/users/:name/posts", handler)
+	})
+	noSlash := registrationPanics(func() {
+		New().GET("users", handler)
+	})
+	otherMethod := registrationPanics(func() {
+		r := New()
+		r.GET("/users/:id", handler)
+		r.POST("/users/:id", handler)
+	})
+
+	return duplicate && paramConflict && noSlash && !otherMethod
+}
+
+// Test catch-all wildcard parameters
+func testWildcardParameters() bool {
+	r := New()
+	r.GET("/static/*filepath", func(c *Context) {
+		c.String(200, "%s", "file:"+c.Param("filepath"))
+	})
+	r.GET("/static/index.html", func(c *Context) {
+		c.String(200, "index")
+	})
+	r.GET("/proxy/:service/*rest", func(c *Context) {
+		c.String(200, "%s", c.Param("service")+c.Param("rest"))
+	})
+
+	return string(r.ServeRequest("GET", "/static/css/site/main.css", nil, nil).Body) == "file:/css/site/main.css" &&
+		string(r.ServeRequest("GET", "/static/", nil, nil).Body) == "file:/" &&
+		string(r.ServeRequest("GET", "/static/index.html", nil, nil).Body) == "index" &&
+		string(r.ServeRequest("GET", "/proxy/users/v1/list", nil, nil).Body) == "users/v1/list" &&
+		r.ServeRequest("GET", "/static", nil, nil).Headers["Location"] == "/static/"
+}
+
+// Test catch-all registration rules
+func testWildcardConflicts() bool {
+	handler := func(c *Context) {}
+
+	notLast := registrationPanics(func() {
+		New().GET("/files/*path/edit", handler)
+	})
+	unnamed := registrationPanics(func() {
+		New().GET("/files/*", handler)
+	})
+	renamed := registrationPanics(func() {
+		r := New()
+		r.GET("/files/*path", handler)
+		r.POST("/files/*path", handler)
+		r.GET("/files/*other", handler)
+	})
+	return notLast && unnamed && renamed
+}
+
+// Test Context Set/Get across the handler chain
+func testContextKeys() bool {
+	r := New()
+	r.Use(func(c *Context) {
+		c.Set("user", "alice")
+		c.Set("admin", true)
+		c.Set("quota", 42)
+		c.Set("roles", []string{"read", "write"})
+		c.Next()
+	})
+
+	var missingPanicked bool
+	r.GET("/me", func(c *Context) {
+		func() {
+			defer func() { missingPanicked = recover() != nil }()
+			c.MustGet("missing")
+		}()
+		_, exists := c.Get("missing")
+		c.JSON(200, H{
+			"user":    c.MustGet("user"),
+			"admin":   c.GetBool("admin"),
+			"quota":   c.GetInt("quota"),
+			"roles":   len(c.GetStringSlice("roles")),
+			"missing": exists,
+			"wrong":   c.GetString("quota"),
+		})
+	})
+
+	resp := r.ServeRequest("GET", "/me", nil, nil)
+	var result map[string]interface{}
+	json.Unmarshal(resp.Body, &result)
+	return missingPanicked &&
+		result["user"] == "alice" &&
+		result["admin"] == true &&
+		result["quota"] == float64(42) &&
+		result["roles"] == float64(2) &&
+		result["missing"] == false &&
+		result["wrong"] == ""
+}
+
+// Test url-encoded form values
+func testPostForm() bool {
+	r := New()
+	r.POST("/form", func(c *Context) {
+		c.JSON(200, H{
+			"name":    c.PostForm("name"),
+			"role":    c.DefaultPostForm("role", "guest"),
+			"tags":    c.PostFormArray("tag"),
+			"missing": c.PostForm("missing"),
+			"query":   c.PostForm("page"),
+		})
+	})
+
+	body := []byte("name=Ann+Lee&tag=a&tag=b")
+	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	resp := r.ServeRequest("POST", "/form?page=2", body, headers)
+
+	var result map[string]interface{}
+	json.Unmarshal(resp.Body, &result)
+	tags, _ := result["tags"].([]interface{})
+	return result["name"] == "Ann Lee" && result["role"] == "guest" &&
+		len(tags) == 2 && result["missing"] == "" && result["query"] == ""
+}
+
+// Test ShouldBind with form bodies, query strings and JSON
+func testShouldBind() bool {
+	type Signup struct {
+		Name  string   `form:"name"`
+		Age   int      `form:"age"`
+		Admin bool     `form:"admin"`
+		Tags  []string `form:"tag"`
+		Page  *int     `form:"page"`
+	}
+
+	r := New()
+	var formResult, queryResult, jsonResult Signup
+	var badErr error
+	r.POST("/signup", func(c *Context) {
+		c.ShouldBind(&formResult)
+	})
+	r.GET("/signup", func(c *Context) {
+		c.ShouldBind(&queryResult)
+	})
+	r.PUT("/signup", func(c *Context) {
+		c.ShouldBind(&jsonResult)
+	})
+	r.PATCH("/signup", func(c *Context) {
+		var bad Signup
+		badErr = c.ShouldBind(&bad)
+	})
+
+	formHeaders := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	r.ServeRequest("POST", "/signup?page=3", []byte("name=Ann&age=30&admin=true&tag=x&tag=y"), formHeaders)
+	r.ServeRequest("GET", "/signup?name=Bob&age=41", nil, nil)
+	r.ServeRequest("PUT", "/signup", []byte(`{"Name":"Cy","Age":5}`), map[string]string{"Content-Type": "application/json"})
+	r.ServeRequest("PATCH", "/signup", []byte("age=old"), formHeaders)
+
+	return formResult.Name == "Ann" && formResult.Age == 30 && formResult.Admin &&
+		len(formResult.Tags) == 2 && formResult.Page != nil && *formResult.Page == 3 &&
+		queryResult.Name == "Bob" && queryResult.Age == 41 &&
+		jsonResult.Name == "Cy" && jsonResult.Age == 5 &&
+		badErr != nil
+}
+
+// Test multipart uploads with FormFile, MultipartForm and SaveUploadedFile
+func testMultipartUpload() bool {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("title", "Report")
+	part, _ := writer.CreateFormFile("file", "report.txt")
+	part.Write([]byte("quarterly numbers"))
+	extra, _ := writer.CreateFormFile("attachments", "a.txt")
+	extra.Write([]byte("a"))
+	extra, _ = writer.CreateFormFile("attachments", "b.txt")
+	extra.Write([]byte("b"))
+	writer.Close()
+
+	dst := "/tmp/gin_emulator_upload/report.txt"
+	defer os.RemoveAll("/tmp/gin_emulator_upload")
+
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.String(400, "%s", err.Error())
+			return
+		}
+		if err := c.SaveUploadedFile(file, dst); err != nil {
+			c.String(500, "%s", err.Error())
+			return
+		}
+		form, _ := c.MultipartForm()
+		_, missingErr := c.FormFile("missing")
+		c.JSON(200, H{
+			"title":       c.PostForm("title"),
+			"filename":    file.Filename,
+			"attachments": len(form.File["attachments"]),
+			"missing":     missingErr == http.ErrMissingFile,
+		})
+	})
+
+	headers := map[string]string{"Content-Type": writer.FormDataContentType()}
+	resp := r.ServeRequest("POST", "/upload", body.Bytes(), headers)
+
+	var result map[string]interface{}
+	json.Unmarshal(resp.Body, &result)
+	saved, _ := os.ReadFile(dst)
+	return resp.StatusCode == 200 &&
+		result["title"] == "Report" && result["filename"] == "report.txt" &&
+		result["attachments"] == float64(2) && result["missing"] == true &&
+		string(saved) == "quarterly numbers"
+}
+
+// Test ShouldBindJSON validation with binding tags
+func testBindingValidation() bool {
+	type Address struct {
+		City string `json:"city" binding:"required"`
+	}
+	type Signup struct {
+		Name    string   `json:"name" binding:"required,min=2,max=10"`
+		Email   string   `json:"email" binding:"required,email"`
+		Age     int      `json:"age" binding:"gte=18,lte=130"`
+		Role    string   `json:"role" binding:"omitempty,oneof=admin user"`
+		Tags    []string `json:"tags" binding:"max=2"`
+		Address Address  `json:"address"`
+	}
+
+	r := New()
+	var validErr, invalidErr error
+	r.POST("/valid", func(c *Context) {
+		var s Signup
+		validErr = c.ShouldBindJSON(&s)
+	})
+	r.POST("/invalid", func(c *Context) {
+		var s Signup
+		invalidErr = c.ShouldBindJSON(&s)
+	})
+
+	r.ServeRequest("POST", "/valid", []byte(`{"name":"Ann","email":"ann@example.com","age":30,"address":{"city":"Oslo"}}`), nil)
+	r.ServeRequest("POST", "/invalid", []byte(`{"name":"A","email":"not-an-email","age":12,"role":"root","tags":["a","b","c"]}`), nil)
+
+	errs, ok := invalidErr.(ValidationErrors)
+	if validErr != nil || !ok || len(errs) != 6 {
+		return false
+	}
+	failed := make(map[string]string)
+	for _, fe := range errs {
+		failed[fe.Namespace] = fe.Tag
+	}
+	return failed["Signup.Name"] == "min" &&
+		failed["Signup.Email"] == "email" &&
+		failed["Signup.Age"] == "gte" &&
+		failed["Signup.Role"] == "oneof" &&
+		failed["Signup.Tags"] == "max" &&
+		failed["Signup.Address.City"] == "required" &&
+		strings.Contains(errs[0].Error(), "failed on the")
+}
+
+// recordingValidator is a StructValidator that rejects every struct
+type recordingValidator struct {
+	seen []interface{}
+}
+
+func (v *recordingValidator) ValidateStruct(obj interface{}) error {
+	v.seen = append(v.seen, obj)
+	return errors.New("rejected by custom validator")
+}
+
+func (v *recordingValidator) Engine() interface{} {
+	return v
+}
+
+// Test replacing and disabling the binding Validator
+func testCustomStructValidator() bool {
+	type Login struct {
+		User string `json:"user" binding:"required"`
+	}
+	original := Validator
+	defer func() { Validator = original }()
+
+	r := New()
+	var bindErr error
+	r.POST("/login", func(c *Context) {
+		var l Login
+		bindErr = c.ShouldBindJSON(&l)
+	})
+
+	custom := &recordingValidator{}
+	Validator = custom
+	r.ServeRequest("POST", "/login", []byte(`{"user":"ann"}`), nil)
+	if bindErr == nil || bindErr.Error() != "rejected by custom validator" || len(custom.seen) != 1 {
+		return false
+	}
+	if engine, ok := Validator.Engine().(*recordingValidator); !ok || engine != custom {
+		return false
+	}
+
+	Validator = nil
+	r.ServeRequest("POST", "/login", []byte(`{}`), nil)
+	if bindErr != nil {
+		return false
+	}
+
+	Validator = original
+	r.ServeRequest("POST", "/login", []byte(`{}`), nil)
+	_, ok := bindErr.(ValidationErrors)
+	return ok && original.Engine() == nil
+}
+
+// Test ShouldBindQuery, ShouldBindUri and ShouldBindHeader
+func testBindingSources() bool {
+	type Paging struct {
+		Page int    `form:"page" binding:"required,min=1"`
+		Sort string `form:"sort"`
+	}
+	type UserURI struct {
+		ID int `uri:"id" binding:"required"`
+	}
+	type Headers struct {
+		RequestID string `header:"x-request-id" binding:"required"`
+		Agent     string `header:"User-Agent"`
+	}
+
+	r := New()
+	var paging Paging
+	var uri UserURI
+	var headers Headers
+	var queryErr, uriErr, headerErr, missingErr error
+	r.GET("/users/:id", func(c *Context) {
+		queryErr = c.ShouldBindQuery(&paging)
+		uriErr = c.ShouldBindUri(&uri)
+		headerErr = c.ShouldBindHeader(&headers)
+		var missing Paging
+		c.Request.Query.Del("page")
+		missingErr = c.ShouldBindQuery(&missing)
+	})
+
+	r.ServeRequest("GET", "/users/7?page=2&sort=name", nil, map[string]string{
+		"X-Request-Id": "abc",
+		"user-agent":   "emu",
+	})
+
+	return queryErr == nil && paging.Page == 2 && paging.Sort == "name" &&
+		uriErr == nil && uri.ID == 7 &&
+		headerErr == nil && headers.RequestID == "abc" && headers.Agent == "emu" &&
+		missingErr != nil
+}
+
+// Test reading and setting cookies
+func testCookies() bool {
+	r := New()
+	r.GET("/login", func(c *Context) {
+		session, err := c.Cookie("session")
+		_, missingErr := c.Cookie("missing")
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie("token", "a b/c", 3600, "", "example.com", true, true)
+		c.SetCookie("theme", "dark", 0, "/app", "", false, false)
+		c.SetCookie("token", "rotated", 60, "/", "example.com", true, true)
+		c.SetCookie("old", "", -1, "/", "", false, false)
+		c.JSON(200, H{"session": session, "ok": err == nil, "missing": missingErr == http.ErrNoCookie})
+	})
+
+	resp := r.ServeRequest("GET", "/login", nil, map[string]string{
+		"Cookie": "theme=light; session=abc%20123",
+	})
+	var result map[string]interface{}
+	json.Unmarshal(resp.Body, &result)
+	if result["session"] != "abc 123" || result["ok"] != true || result["missing"] != true ||
+		len(resp.Cookies) != 3 {
+		return false
+	}
+	token := resp.Cookies[0]
+	if token.Value != "rotated" || token.MaxAge != 60 || !token.HttpOnly || token.SameSite != http.SameSiteStrictMode {
+		return false
+	}
+
+	// Over net/http every cookie becomes its own Set-Cookie header
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/login", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "xyz"})
+	r.ServeHTTP(rec, req)
+	cookies := rec.Result().Cookies()
+	return len(cookies) == 3 &&
+		cookies[0].Name == "token" && cookies[0].Domain == "example.com" && cookies[0].Secure &&
+		cookies[1].Name == "theme" && cookies[1].Path == "/app" &&
+		cookies[2].Name == "old" && cookies[2].MaxAge == -1 &&
+		strings.Contains(rec.Header().Values("Set-Cookie")[0], "SameSite=Strict")
+}
+
+// Test static file serving from disk and fs.FS
+func testStaticFiles() bool {
+	root := "/tmp/gin_emulator_static"
+	os.MkdirAll(root+"/css", 0755)
+	os.MkdirAll(root+"/empty", 0755)
+	defer os.RemoveAll(root)
+	os.WriteFile(root+"/css/site.css", []byte("body { color: red; }"), 0644)
+	os.WriteFile(root+"/index.html", []byte("<h1>home</h1>"), 0644)
+	os.WriteFile("/tmp/gin_emulator_secret.txt", []byte("secret"), 0644)
+	defer os.Remove("/tmp/gin_emulator_secret.txt")
+
+	r := New()
+	r.Static("/assets", root)
+	r.StaticFile("/favicon.css", root+"/css/site.css")
+	r.StaticFS("/embedded", http.FS(os.DirFS(root)))
+
+	css := r.ServeRequest("GET", "/assets/css/site.css", nil, nil)
+	if css.StatusCode != 200 || string(css.Body) != "body { color: red; }" ||
+		!strings.HasPrefix(css.Headers["Content-Type"], "text/css") {
+		return false
+	}
+
+	ranged := r.ServeRequest("GET", "/assets/css/site.css", nil, map[string]string{"Range": "bytes=0-3"})
+	if ranged.StatusCode != 206 || string(ranged.Body) != "body" ||
+		ranged.Headers["Content-Range"] != "bytes 0-3/20" {
+		return false
+	}
+
+	traversal := r.ServeRequest("GET", "/assets/../gin_emulator_secret.txt", nil, nil)
+	listing := r.ServeRequest("GET", "/assets/empty/", nil, nil)
+	missing := r.ServeRequest("GET", "/assets/missing.js", nil, nil)
+	index := r.ServeRequest("GET", "/assets/", nil, nil)
+	single := r.ServeRequest("GET", "/favicon.css", nil, nil)
+	head := r.ServeRequest("HEAD", "/assets/css/site.css", nil, nil)
+	embedded := r.ServeRequest("GET", "/embedded/css/site.css", nil, nil)
+
+	return traversal.StatusCode == 404 && !strings.Contains(string(traversal.Body), "secret") &&
+		listing.StatusCode == 404 &&
+		missing.StatusCode == 404 &&
+		index.StatusCode == 200 && string(index.Body) == "<h1>home</h1>" &&
+		single.StatusCode == 200 && string(single.Body) == "body { color: red; }" &&
+		head.StatusCode == 200 && len(head.Body) == 0 &&
+		embedded.StatusCode == 200 && string(embedded.Body) == "body { color: red; }"
+}
+
+// Test c.File and c.FileAttachment
+func testFileResponses() bool {
+	path := "/tmp/gin_emulator_report.json"
+	os.WriteFile(path, []byte(`{"total": 3}`), 0644)
+	defer os.Remove(path)
+
+	r := New()
+	r.GET("/report", func(c *Context) {
+		c.File(path)
+	})
+	r.GET("/download", func(c *Context) {
+		c.FileAttachment(path, "report 2024.json")
+	})
+	r.GET("/download-utf8", func(c *Context) {
+		c.FileAttachment(path, "résumé.json")
+	})
+
+	report := r.ServeRequest("GET", "/report", nil, nil)
+	download := r.ServeRequest("GET", "/download", nil, nil)
+	utf8 := r.ServeRequest("GET", "/download-utf8", nil, nil)
+	return report.StatusCode == 200 && report.Headers["Content-Type"] == "application/json" &&
+		string(report.Body) == `{"total": 3}` &&
+		download.Headers["Content-Disposition"] == `attachment; filename="report 2024.json"` &&
+		strings.HasPrefix(utf8.Headers["Content-Disposition"], "attachment; filename*=UTF-8''r%C3%A9sum")
+}
+
+// Test HTML template rendering from files
+func testHTMLTemplates() bool {
+	dir := "/tmp/gin_emulator_templates"
+	os.MkdirAll(dir, 0755)
+	defer os.RemoveAll(dir)
+	os.WriteFile(dir+"/index.tmpl", []byte(`<h1>{{ .title | upper }}</h1>`), 0644)
+	os.WriteFile(dir+"/user.tmpl", []byte(`<p>{{ .name }}</p>`), 0644)
+
+	r := New()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	r.LoadHTMLGlob(dir + "/*.tmpl")
+	r.GET("/", func(c *Context) {
+		c.HTML(200, "index.tmpl", H{"title": "home"})
+	})
+	r.GET("/user", func(c *Context) {
+		c.HTML(200, "user.tmpl", H{"name": "<script>"})
+	})
+	r.GET("/missing", func(c *Context) {
+		c.HTML(200, "missing.tmpl", nil)
+	})
+
+	index := r.ServeRequest("GET", "/", nil, nil)
+	user := r.ServeRequest("GET", "/user", nil, nil)
+	missing := r.ServeRequest("GET", "/missing", nil, nil)
+
+	// DebugMode re-reads templates on each render
+	os.WriteFile(dir+"/index.tmpl", []byte(`<h2>{{ .title }}</h2>`), 0644)
+	reloaded := r.ServeRequest("GET", "/", nil, nil)
+
+	return string(index.Body) == "<h1>HOME</h1>" &&
+		index.Headers["Content-Type"] == "text/html; charset=utf-8" &&
+		string(user.Body) == "<p>&lt;script&gt;</p>" &&
+		missing.StatusCode == 500 &&
+		string(reloaded.Body) == "<h2>home</h2>"
+}
+
+// Test SetHTMLTemplate, custom delimiters and TestMode capture
+func testHTMLTestMode() bool {
+	SetMode(TestMode)
+	defer SetMode(DebugMode)
+
+	r := New()
+	r.SetHTMLTemplate(template.Must(template.New("page").Delims("[[", "]]").Parse(`<title>[[ .Title ]]</title>`)))
+	type Page struct{ Title string }
+	r.GET("/page", func(c *Context) {
+		c.HTML(201, "page", Page{Title: "Docs"})
+	})
+
+	resp := r.ServeRequest("GET", "/page", nil, nil)
+	rendered := resp.Template
+	return Mode() == TestMode && resp.StatusCode == 201 &&
+		rendered != nil && rendered.Name == "page" &&
+		rendered.Data.(Page).Title == "Docs" &&
+		rendered.Output == "<title>Docs</title>"
+}
+
+// protoUser is a stand-in for a generated protobuf message
+type protoUser struct {
+	name string
+}
+
+// Marshal implements ProtoMarshaler with a minimal wire encoding
+func (p *protoUser) Marshal() ([]byte, error) {
+	return append([]byte{0x0a, byte(len(p.name))}, p.name...), nil
+}
+
+// Test XML, YAML, TOML and ProtoBuf renderers
+func testRenderFormats() bool {
+	type Server struct {
+		Host  string   `json:"host" xml:"host"`
+		Ports []int    `json:"ports" xml:"port"`
+		Tags  []string `json:"tags" xml:"-"`
+	}
+	server := Server{Host: "localhost", Ports: []int{80, 443}, Tags: []string{"web", "8080"}}
+
+	r := New()
+	r.GET("/xml", func(c *Context) { c.XML(200, server) })
+	r.GET("/xml-h", func(c *Context) { c.XML(200, H{"b": 2, "a": "x"}) })
+	r.GET("/yaml", func(c *Context) { c.YAML(200, H{"server": server, "debug": true}) })
+	r.GET("/toml", func(c *Context) {
+		c.TOML(200, H{"title": "demo", "server": server, "users": []H{{"name": "ann"}}})
+	})
+	r.GET("/proto", func(c *Context) { c.ProtoBuf(200, &protoUser{name: "ann"}) })
+	r.GET("/proto-bad", func(c *Context) { c.ProtoBuf(200, server) })
+
+	xmlResp := r.ServeRequest("GET", "/xml", nil, nil)
+	xmlH := r.ServeRequest("GET", "/xml-h", nil, nil)
+	yamlResp := r.ServeRequest("GET", "/yaml", nil, nil)
+	tomlResp := r.ServeRequest("GET", "/toml", nil, nil)
+	proto := r.ServeRequest("GET", "/proto", nil, nil)
+	protoBad := r.ServeRequest("GET", "/proto-bad", nil, nil)
+
+	expectedYAML := "debug: true\nserver:\n  host: localhost\n  ports:\n    - 80\n    - 443\n  tags:\n    - web\n    - \"8080\"\n"
+	expectedTOML := "title = \"demo\"\n\n[server]\nhost = \"localhost\"\nports = [80, 443]\ntags = [\"web\", \"8080\"]\n\n[[users]]\nname = \"ann\"\n"
+
+	return xmlResp.Headers["Content-Type"] == MIMEXML &&
+		string(xmlResp.Body) == "<Server><host>localhost</host><port>80</port><port>443</port></Server>" &&
+		string(xmlH.Body) == "<map><a>x</a><b>2</b></map>" &&
+		yamlResp.Headers["Content-Type"] == MIMEYAML && string(yamlResp.Body) == expectedYAML &&
+		tomlResp.Headers["Content-Type"] == MIMETOML && string(tomlResp.Body) == expectedTOML &&
+		proto.Headers["Content-Type"] == MIMEPROTOBUF && string(proto.Body) == "\x0a\x03ann" &&
+		protoBad.StatusCode == 500
+}
+
+// Test IndentedJSON, PureJSON, SecureJSON and JSONP
+func testJSONVariants() bool {
+	r := New()
+	r.GET("/indented", func(c *Context) { c.IndentedJSON(200, H{"a": 1}) })
+	r.GET("/pure", func(c *Context) { c.PureJSON(200, H{"html": "<b>hi</b>"}) })
+	r.GET("/escaped", func(c *Context) { c.JSON(200, H{"html": "<b>hi</b>"}) })
+	r.GET("/secure", func(c *Context) { c.SecureJSON(200, []string{"a", "b"}) })
+	r.GET("/secure-object", func(c *Context) { c.SecureJSON(200, H{"a": 1}) })
+	r.GET("/jsonp", func(c *Context) { c.JSONP(200, H{"a": 1}) })
+
+	jsonp := r.ServeRequest("GET", "/jsonp?callback=handle", nil, nil)
+	plain := r.ServeRequest("GET", "/jsonp", nil, nil)
+
+	custom := New()
+	custom.SecureJsonPrefix(")]}',\n")
+	custom.GET("/secure", func(c *Context) { c.SecureJSON(200, []int{1}) })
+
+	return string(r.ServeRequest("GET", "/indented", nil, nil).Body) == "{\n    \"a\": 1\n}" &&
+		string(r.ServeRequest("GET", "/pure", nil, nil).Body) == `{"html":"<b>hi</b>"}` &&
+		string(r.ServeRequest("GET", "/escaped", nil, nil).Body) == `{"html":"\u003cb\u003ehi\u003c/b\u003e"}` &&
+		string(r.ServeRequest("GET", "/secure", nil, nil).Body) == `while(1);["a","b"]` &&
+		string(r.ServeRequest("GET", "/secure-object", nil, nil).Body) == `{"a":1}` &&
+		string(custom.ServeRequest("GET", "/secure", nil, nil).Body) == ")]}',\n[1]" &&
+		string(jsonp.Body) == `handle({"a":1});` && jsonp.Headers["Content-Type"] == MIMEJSONP &&
+		string(plain.Body) == `{"a":1}` && plain.Headers["Content-Type"] == MIMEJSON
+}
+
+// Test content negotiation
+func testNegotiate() bool {
+	r := New()
+	r.GET("/data", func(c *Context) {
+		c.Negotiate(200, Negotiate{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:    H{"a": 1},
+		})
+	})
+
+	accept := func(value string) *Response {
+		return r.ServeRequest("GET", "/data", nil, map[string]string{"Accept": value})
+	}
+	return accept("application/xml").Headers["Content-Type"] == MIMEXML &&
+		accept("application/yaml;q=0.9, application/json;q=0.5").Headers["Content-Type"] == MIMEYAML &&
+		accept("*/*").Headers["Content-Type"] == MIMEJSON &&
+		r.ServeRequest("GET", "/data", nil, nil).Headers["Content-Type"] == MIMEJSON &&
+		accept("image/png").StatusCode == 406
+}
+
+// Test c.Stream records each flushed chunk
+func testStream() bool {
+	r := New()
+	r.GET("/progress", func(c *Context) {
+		step := 0
+		c.Stream(func(w io.Writer) bool {
+			step++
+			fmt.Fprintf(w, "step %d\n", step)
+			return step < 3
+		})
+	})
+
+	resp := r.ServeRequest("GET", "/progress", nil, nil)
+	return len(resp.Chunks) == 3 &&
+		string(resp.Chunks[0]) == "step 1\n" &&
+		string(resp.Chunks[2]) == "step 3\n" &&
+		string(resp.Body) == "step 1\nstep 2\nstep 3\n"
+}
+
+// Test Server-Sent Events encoding
+func testSSEvent() bool {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		messages := []interface{}{"hello", H{"count": 2}, "line1\nline2"}
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			c.SSEvent("message", messages[i])
+			i++
+			return i < len(messages)
+		})
+	})
+
+	resp := r.ServeRequest("GET", "/events", nil, nil)
+	return resp.Headers["Content-Type"] == "text/event-stream" &&
+		resp.Headers["Cache-Control"] == "no-cache" &&
+		len(resp.Chunks) == 3 &&
+		string(resp.Chunks[0]) == "event:message\ndata:hello\n\n" &&
+		string(resp.Chunks[1]) == "event:message\ndata:{\"count\":2}\n\n" &&
+		string(resp.Chunks[2]) == "event:message\ndata:line1\ndata:line2\n\n"
+}
+
+// Test streamed chunks reach a real client before the handler finishes
+func testStreamOverHTTP() bool {
+	release := make(chan struct{})
+	r := New()
+	r.GET("/stream", func(c *Context) {
+		sent := false
+		c.Stream(func(w io.Writer) bool {
+			if !sent {
+				sent = true
+				c.SSEvent("", "first")
+				return true
+			}
+			<-release
+			c.SSEvent("", "second")
+			return false
+		})
+	})
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// The first event must be readable while the handler is still blocked
+	first, _ := reader.ReadString('\n')
+	close(release)
+	rest, _ := io.ReadAll(reader)
+
+	return resp.Header.Get("Content-Type") == "text/event-stream" &&
+		first == "data:first\n" &&
+		string(rest) == "\ndata:second\n\n"
+}
+
+// Test c.Redirect
+func testRedirect() bool {
+	r := New()
+	r.GET("/old", func(c *Context) {
+		c.Redirect(301, "/new")
+	})
+	r.POST("/submit", func(c *Context) {
+		c.Redirect(303, "https://example.com/done")
+	})
+	r.GET("/bad", func(c *Context) {
+		defer func() {
+			if recover() != nil {
+				c.String(500, "invalid redirect")
+			}
+		}()
+		c.Redirect(200, "/nowhere")
+	})
+
+	old := r.ServeRequest("GET", "/old", nil, nil)
+	submit := r.ServeRequest("POST", "/submit", nil, nil)
+	bad := r.ServeRequest("GET", "/bad", nil, nil)
+	return old.StatusCode == 301 && old.Headers["Location"] == "/new" &&
+		strings.Contains(string(old.Body), "Moved Permanently") &&
+		submit.StatusCode == 303 && submit.Headers["Location"] == "https://example.com/done" &&
+		bad.StatusCode == 500 && string(bad.Body) == "invalid redirect"
+}
+
+// Test RedirectTrailingSlash and RedirectFixedPath
+func testRedirectPaths() bool {
+	handler := func(c *Context) { c.String(200, "ok") }
+	r := New()
+	r.GET("/users", handler)
+	r.GET("/docs/", handler)
+	r.POST("/items", handler)
+	r.GET("/Admin/:section", handler)
+
+	users := r.ServeRequest("GET", "/users/?page=2", nil, nil)
+	docs := r.ServeRequest("GET", "/docs", nil, nil)
+	items := r.ServeRequest("POST", "/items/", nil, nil)
+	notFixed := r.ServeRequest("GET", "/ADMIN/Reports", nil, nil)
+
+	r.RedirectFixedPath = true
+	fixed := r.ServeRequest("GET", "/x/../ADMIN/Reports", nil, nil)
+	fixedSlash := r.ServeRequest("GET", "/USERS/", nil, nil)
+
+	r.RedirectTrailingSlash = false
+	disabled := r.ServeRequest("GET", "/docs", nil, nil)
+
+	return users.StatusCode == 301 && users.Headers["Location"] == "/users?page=2" &&
+		docs.StatusCode == 301 && docs.Headers["Location"] == "/docs/" &&
+		items.StatusCode == 307 && items.Headers["Location"] == "/items" &&
+		notFixed.StatusCode == 404 &&
+		fixed.StatusCode == 301 && fixed.Headers["Location"] == "/Admin/Reports" &&
+		fixedSlash.Headers["Location"] == "/users" &&
+		disabled.StatusCode == 404
+}
+
+// Test NoRoute handlers
+func testNoRoute() bool {
+	r := New()
+	middlewareRan := false
+	r.Use(func(c *Context) {
+		middlewareRan = true
+		c.Next()
+	})
+	r.Static("/assets", "/tmp/gin_emulator_no_such_dir")
+	r.GET("/exists", func(c *Context) { c.String(200, "ok") })
+
+	plain := r.ServeRequest("GET", "/missing", nil, nil)
+
+	r.NoRoute(func(c *Context) {
+		c.JSON(404, H{"error": "not found", "path": c.Request.Path})
+	})
+	custom := r.ServeRequest("GET", "/missing", nil, nil)
+	asset := r.ServeRequest("GET", "/assets/app.js", nil, nil)
+
+	var result map[string]interface{}
+	json.Unmarshal(custom.Body, &result)
+	return plain.StatusCode == 404 && string(plain.Body) == "404 Not Found" &&
+		middlewareRan &&
+		custom.StatusCode == 404 && result["path"] == "/missing" &&
+		asset.StatusCode == 404 && strings.Contains(string(asset.Body), "not found")
+}
+
+// Test HandleMethodNotAllowed and NoMethod handlers
+func testMethodNotAllowed() bool {
+	r := New()
+	r.GET("/users/:id", func(c *Context) { c.String(200, "get") })
+	r.PUT("/users/:id", func(c *Context) { c.String(200, "put") })
+
+	disabled := r.ServeRequest("POST", "/users/1", nil, nil)
+
+	r.HandleMethodNotAllowed = true
+	defaultBody := r.ServeRequest("POST", "/users/1", nil, nil)
+	missing := r.ServeRequest("POST", "/other", nil, nil)
+
+	r.NoMethod(func(c *Context) {
+		c.JSON(405, H{"allow": c.Response.Headers["Allow"]})
+	})
+	custom := r.ServeRequest("DELETE", "/users/1", nil, nil)
+
+	var result map[string]interface{}
+	json.Unmarshal(custom.Body, &result)
+	return disabled.StatusCode == 404 &&
+		defaultBody.StatusCode == 405 && defaultBody.Headers["Allow"] == "GET, PUT" &&
+		string(defaultBody.Body) == "405 Method Not Allowed" &&
+		missing.StatusCode == 404 &&
+		custom.StatusCode == 405 && result["allow"] == "GET, PUT"
+}
+
+// Test CORS middleware
+func testCORS() bool {
+	r := New()
+	r.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return origin == "http://localhost:3000" },
+		AllowMethods:     []string{"get", "post"},
+		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		ExposeHeaders:    []string{"X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+		AllowWildcard:    true,
+	}))
+	handlerRan := false
+	r.POST("/api", func(c *Context) {
+		handlerRan = true
+		c.String(200, "ok")
+	})
+
+	preflight := r.ServeRequest("OPTIONS", "/api", nil, map[string]string{
+		"Origin":                        "https://app.example.com",
+		"Access-Control-Request-Method": "POST",
+	})
+	preflightOK := preflight.StatusCode == 204 && !handlerRan &&
+		preflight.Headers["Access-Control-Allow-Origin"] == "https://app.example.com" &&
+		preflight.Headers["Access-Control-Allow-Methods"] == "GET, POST" &&
+		preflight.Headers["Access-Control-Allow-Headers"] == "Authorization, Content-Type" &&
+		preflight.Headers["Access-Control-Allow-Credentials"] == "true" &&
+		preflight.Headers["Access-Control-Max-Age"] == "600" &&
+		len(preflight.Body) == 0
+
+	simple := r.ServeRequest("POST", "/api", nil, map[string]string{"Origin": "https://cdn.example.org"})
+	simpleOK := simple.StatusCode == 200 && handlerRan &&
+		simple.Headers["Access-Control-Allow-Origin"] == "https://cdn.example.org" &&
+		simple.Headers["Access-Control-Expose-Headers"] == "X-Total-Count" &&
+		simple.Headers["Vary"] == "Origin"
+
+	viaFunc := r.ServeRequest("POST", "/api", nil, map[string]string{"Origin": "http://localhost:3000"})
+	denied := r.ServeRequest("POST", "/api", nil, map[string]string{"Origin": "https://evil.com"})
+	sameOrigin := r.ServeRequest("POST", "/api", nil, nil)
+
+	open := New()
+	open.Use(CORS(DefaultCORSConfig()))
+	open.GET("/", func(c *Context) { c.String(200, "ok") })
+	wildcard := open.ServeRequest("GET", "/", nil, map[string]string{"Origin": "https://any.site"})
+
+	invalid := registrationPanics(func() {
+		CORS(CORSConfig{AllowOrigins: []string{"https://*.example.com"}})
+	})
+
+	return preflightOK && simpleOK &&
+		viaFunc.StatusCode == 200 &&
+		denied.StatusCode == 403 && denied.Headers["Access-Control-Allow-Origin"] == "" &&
+		sameOrigin.StatusCode == 200 && sameOrigin.Headers["Access-Control-Allow-Origin"] == "" &&
+		wildcard.Headers["Access-Control-Allow-Origin"] == "*" &&
+		invalid
+}
+
+// Test BasicAuth middleware
+func testBasicAuth() bool {
+	r := New()
+	admin := r.Group("/admin", BasicAuthForRealm(Accounts{"alice": "secret"}, "Admin Area"))
+	admin.GET("/dashboard", func(c *Context) {
+		c.String(200, "%s", "hello "+c.MustGet(AuthUserKey).(string))
+	})
+
+	basic := func(user, pass string) map[string]string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.SetBasicAuth(user, pass)
+		return map[string]string{"Authorization": req.Header.Get("Authorization")}
+	}
+
+	ok := r.ServeRequest("GET", "/admin/dashboard", nil, basic("alice", "secret"))
+	wrong := r.ServeRequest("GET", "/admin/dashboard", nil, basic("alice", "nope"))
+	unknown := r.ServeRequest("GET", "/admin/dashboard", nil, basic("bob", "secret"))
+	missing := r.ServeRequest("GET", "/admin/dashboard", nil, nil)
+
+	return ok.StatusCode == 200 && string(ok.Body) == "hello alice" &&
+		wrong.StatusCode == 401 && unknown.StatusCode == 401 &&
+		missing.StatusCode == 401 &&
+		missing.Headers["WWW-Authenticate"] == `Basic realm="Admin Area"` &&
+		registrationPanics(func() { BasicAuth(Accounts{}) })
+}
+
+// Test bearer token middleware
+func testBearerAuth() bool {
+	r := New()
+	r.Use(BearerAuth(func(token string) (interface{}, error) {
+		if token == "valid-token" {
+			return map[string]string{"id": "42"}, nil
+		}
+		return nil, fmt.Errorf("unknown token")
+	}))
+	r.GET("/me", func(c *Context) {
+		user := c.MustGet(AuthUserKey).(map[string]string)
+		c.String(200, "%s", user["id"])
+	})
+
+	ok := r.ServeRequest("GET", "/me", nil, map[string]string{"Authorization": "Bearer valid-token"})
+	invalid := r.ServeRequest("GET", "/me", nil, map[string]string{"Authorization": "Bearer other"})
+	missing := r.ServeRequest("GET", "/me", nil, map[string]string{"Authorization": "Basic abc"})
+
+	return ok.StatusCode == 200 && string(ok.Body) == "42" &&
+		invalid.StatusCode == 401 &&
+		invalid.Headers["WWW-Authenticate"] == `Bearer realm="Authorization Required", error="invalid_token"` &&
+		missing.StatusCode == 401 &&
+		missing.Headers["WWW-Authenticate"] == `Bearer realm="Authorization Required"`
+}
+
+// Test BodyLimit middleware
+func testBodyLimit() bool {
+	r := New()
+	r.Use(BodyLimit(10))
+	r.POST("/upload", func(c *Context) { c.String(200, "ok") })
+
+	small := r.ServeRequest("POST", "/upload", []byte("tiny"), nil)
+	large := r.ServeRequest("POST", "/upload", []byte("this body is too large"), nil)
+	declared := r.ServeRequest("POST", "/upload", nil, map[string]string{"Content-Length": "1024"})
+
+	return small.StatusCode == 200 &&
+		large.StatusCode == 413 && string(large.Body) == "413 Request Entity Too Large" &&
+		declared.StatusCode == 413
+}
+
+// Test gzip request decompression and response compression
+func testGzip() bool {
+	r := New()
+	r.Use(Gzip(BestSpeed))
+	r.POST("/echo", func(c *Context) {
+		c.Data(200, "text/plain", c.Request.Body)
+	})
+	r.GET("/empty", func(c *Context) { c.Status(204) })
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("hello gzip"))
+	zw.Close()
+	var df bytes.Buffer
+	fw := zlib.NewWriter(&df)
+	fw.Write([]byte("hello deflate"))
+	fw.Close()
+	var raw bytes.Buffer
+	rw, _ := flate.NewWriter(&raw, flate.BestSpeed)
+	rw.Write([]byte("hello raw deflate"))
+	rw.Close()
+
+	compressed := r.ServeRequest("POST", "/echo", gz.Bytes(), map[string]string{
+		"Content-Encoding": "gzip",
+		"Accept-Encoding":  "gzip, deflate",
+	})
+	zr, err := gzip.NewReader(bytes.NewReader(compressed.Body))
+	if err != nil {
+		return false
+	}
+	decoded, _ := io.ReadAll(zr)
+
+	plain := r.ServeRequest("POST", "/echo", df.Bytes(), map[string]string{"Content-Encoding": "deflate"})
+	rawDeflate := r.ServeRequest("POST", "/echo", raw.Bytes(), map[string]string{"Content-Encoding": "deflate"})
+	refused := r.ServeRequest("POST", "/echo", []byte("raw"), map[string]string{"Accept-Encoding": "gzip;q=0"})
+	corrupt := r.ServeRequest("POST", "/echo", []byte("not gzip"), map[string]string{"Content-Encoding": "gzip"})
+	empty := r.ServeRequest("GET", "/empty", nil, map[string]string{"Accept-Encoding": "gzip"})
+
+	// Bodies may not decompress past the engine's limit
+	r.MaxDecompressedBodySize = 1 << 10
+	var bomb, fits bytes.Buffer
+	zw = gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+	zw = gzip.NewWriter(&fits)
+	zw.Write(make([]byte, 1<<10))
+	zw.Close()
+	tooLarge := r.ServeRequest("POST", "/echo", bomb.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+	atLimit := r.ServeRequest("POST", "/echo", fits.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+
+	return tooLarge.StatusCode == 413 && atLimit.StatusCode == 200 && len(atLimit.Body) == 1<<10 &&
+		compressed.Headers["Content-Encoding"] == "gzip" &&
+		compressed.Headers["Vary"] == "Accept-Encoding" &&
+		string(decoded) == "hello gzip" &&
+		string(plain.Body) == "hello deflate" && plain.Headers["Content-Encoding"] == "" &&
+		string(rawDeflate.Body) == "hello raw deflate" &&
+		string(refused.Body) == "raw" &&
+		corrupt.StatusCode == 400 &&
+		empty.StatusCode == 204 && empty.Headers["Content-Encoding"] == "" &&
+		registrationPanics(func() { Gzip(42) })
+}
+
+// fakeRedis is a minimal in-memory RedisClient. Like a client that isn't
+// safe for concurrent use, it does no locking, and SetNX checks the key
+// before setting it; latency delays replies to Get and SetNX, as a
+// network round trip would.
+type fakeRedis struct {
+	data    map[string]string
+	latency time.Duration
+}
+
+func (f *fakeRedis) Get(key string) (string, error) {
+	value, ok := f.data[key]
+	time.Sleep(f.latency)
+	if !ok {
+		return "", fmt.Errorf("redis: nil")
+	}
+	return value, nil
+}
+
+func (f *fakeRedis) Set(key string, value interface{}, expiration time.Duration) error {
+	f.data[key] = fmt.Sprintf("%v", value)
+	return nil
+}
+
+func (f *fakeRedis) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if _, ok := f.data[key]; ok {
+		return false, nil
+	}
+	time.Sleep(f.latency)
+	f.data[key] = fmt.Sprintf("%v", value)
+	return true, nil
+}
+
+func (f *fakeRedis) Del(keys ...string) (int, error) {
+	count := 0
+	for _, key := range keys {
+		if _, ok := f.data[key]; ok {
+			delete(f.data, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Test rate limiting middleware
+func testRateLimit() bool {
+	now := time.Unix(1000, 0)
+	store := NewMemoryRateStore()
+	store.now = func() time.Time { return now }
+
+	r := New()
+	r.GET("/open", func(c *Context) { c.String(200, "ok") })
+	api := r.Group("/api", RateLimit(RateLimitConfig{Rate: 0.5, Burst: 2, Store: store}))
+	api.GET("/items", func(c *Context) { c.String(200, "ok") })
+
+	client := map[string]string{"X-Forwarded-For": "10.0.0.1, 192.168.0.1"}
+	other := map[string]string{"X-Real-IP": "10.0.0.2"}
+
+	first := r.ServeRequest("GET", "/api/items", nil, client)
+	second := r.ServeRequest("GET", "/api/items", nil, client)
+	throttled := r.ServeRequest("GET", "/api/items", nil, client)
+	otherClient := r.ServeRequest("GET", "/api/items", nil, other)
+	unlimited := r.ServeRequest("GET", "/open", nil, client)
+	now = now.Add(2 * time.Second)
+	refilled := r.ServeRequest("GET", "/api/items", nil, client)
+
+	return first.StatusCode == 200 && second.StatusCode == 200 &&
+		throttled.StatusCode == 429 && throttled.Headers["Retry-After"] == "2" &&
+		otherClient.StatusCode == 200 && unlimited.StatusCode == 200 &&
+		refilled.StatusCode == 200 &&
+		registrationPanics(func() { RateLimit(RateLimitConfig{Rate: 1}) })
+}
+
+// Test rate limiting with a shared Redis store and custom keys
+func testRateLimitRedis() bool {
+	redis := &fakeRedis{data: map[string]string{}}
+	limit := RateLimitConfig{
+		Rate:    1,
+		Burst:   1,
+		KeyFunc: func(c *Context) string { return c.GetHeader("X-API-Key") },
+		Store:   NewRedisRateStore(redis),
+	}
+
+	// Two engines sharing one Redis share the same limits
+	a, b := New(), New()
+	a.GET("/", RateLimit(limit), func(c *Context) { c.String(200, "a") })
+	b.GET("/", RateLimit(limit), func(c *Context) { c.String(200, "b") })
+
+	key := map[string]string{"X-API-Key": "team-1"}
+	first := a.ServeRequest("GET", "/", nil, key)
+	second := b.ServeRequest("GET", "/", nil, key)
+	_, stored := redis.data["ratelimit:team-1"]
+
+	redis.data["ratelimit:team-2"] = "garbage"
+	corrupt := a.ServeRequest("GET", "/", nil, map[string]string{"X-API-Key": "team-2"})
+
+	return first.StatusCode == 200 && second.StatusCode == 429 && stored &&
+		corrupt.StatusCode == 500
+}
+
+// Test that stores sharing a Redis client admit no more than the limit
+// between them when taking tokens concurrently
+func testRateLimitRedisShared() bool {
+	redis := &fakeRedis{data: map[string]string{}, latency: time.Millisecond}
+	stores := []*RedisRateStore{NewRedisRateStore(redis), NewRedisRateStore(redis)}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(store *RedisRateStore) {
+			defer wg.Done()
+			ok, _, err := store.Take("team-1", 0.001, 5)
+			if ok && err == nil {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}(stores[i%2])
+	}
+	wg.Wait()
+
+	_, locked := redis.data["lock:ratelimit:team-1"]
+	return admitted == 5 && !locked
+}
+
+// sessionRouter builds login/profile/logout routes on store
+func sessionRouter(store SessionStore) *Engine {
+	r := New()
+	r.Use(Sessions("app", store))
+	r.POST("/login", func(c *Context) {
+		session := DefaultSession(c)
+		session.Set("user", "alice")
+		session.Set("visits", 1)
+		session.AddFlash("welcome")
+		session.Save()
+		c.String(200, "logged in")
+	})
+	r.GET("/profile", func(c *Context) {
+		session := DefaultSession(c)
+		user, _ := session.Get("user").(string)
+		if user == "" {
+			c.String(401, "anonymous")
+			return
+		}
+		visits := session.Get("visits").(int) + 1
+		session.Set("visits", visits)
+		flashes := session.Flashes()
+		session.Save()
+		c.String(200, "%s %d %d", user, visits, len(flashes))
+	})
+	r.POST("/logout", func(c *Context) {
+		session := DefaultSession(c)
+		session.Clear()
+		session.Options(SessionOptions{Path: "/", MaxAge: -1})
+		session.Save()
+		c.String(200, "bye")
+	})
+	return r
+}
+
+// sessionCookie returns a Cookie request header for the response's cookie
+func sessionCookie(resp *Response) map[string]string {
+	for _, cookie := range resp.Cookies {
+		if cookie.Name == "app" {
+			return map[string]string{"Cookie": "app=" + cookie.Value}
+		}
+	}
+	return nil
+}
+
+// Test cookie-backed sessions with signing and encryption
+func testCookieSessions() bool {
+	signed := sessionRouter(NewCookieStore([]byte("hash-key")))
+	login := signed.ServeRequest("POST", "/login", nil, nil)
+	cookie := sessionCookie(login)
+	profile := signed.ServeRequest("GET", "/profile", nil, cookie)
+	again := signed.ServeRequest("GET", "/profile", nil, sessionCookie(profile))
+
+	tampered := map[string]string{"Cookie": cookie["Cookie"][:12] + "x" + cookie["Cookie"][13:]}
+	rejected := signed.ServeRequest("GET", "/profile", nil, tampered)
+	otherKey := sessionRouter(NewCookieStore([]byte("other-key")))
+	foreign := otherKey.ServeRequest("GET", "/profile", nil, cookie)
+
+	// Rotated keys still accept cookies signed with the old key
+	rotated := sessionRouter(NewCookieStore([]byte("new-key"), nil, []byte("hash-key"), nil))
+	rotatedProfile := rotated.ServeRequest("GET", "/profile", nil, cookie)
+
+	encrypted := sessionRouter(NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef0123456789abcdef")))
+	encLogin := encrypted.ServeRequest("POST", "/login", nil, nil)
+	encCookie := sessionCookie(encLogin)
+	encProfile := encrypted.ServeRequest("GET", "/profile", nil, encCookie)
+	plain, _ := base64.RawURLEncoding.DecodeString(strings.SplitN(strings.TrimPrefix(encCookie["Cookie"], "app="), ".", 2)[0])
+
+	return login.StatusCode == 200 && cookie != nil &&
+		string(profile.Body) == "alice 2 1" &&
+		string(again.Body) == "alice 3 0" &&
+		rejected.StatusCode == 401 && foreign.StatusCode == 401 &&
+		string(rotatedProfile.Body) == "alice 2 1" &&
+		string(encProfile.Body) == "alice 2 1" &&
+		!bytes.Contains(plain, []byte("alice")) &&
+		registrationPanics(func() { NewCookieStore([]byte("k"), []byte("short")) })
+}
+
+// Test server-side sessions in memory and Redis
+func testServerSessions() bool {
+	redis := &fakeRedis{data: map[string]string{}}
+	stores := []SessionStore{
+		NewMemoryStore([]byte("hash-key")),
+		NewRedisStore(redis, []byte("hash-key")),
+	}
+	for _, store := range stores {
+		r := sessionRouter(store)
+		login := r.ServeRequest("POST", "/login", nil, nil)
+		cookie := sessionCookie(login)
+		profile := r.ServeRequest("GET", "/profile", nil, cookie)
+		logout := r.ServeRequest("POST", "/logout", nil, cookie)
+		// The old cookie no longer maps to any session data
+		after := r.ServeRequest("GET", "/profile", nil, cookie)
+
+		if string(profile.Body) != "alice 2 1" || logout.Cookies[0].MaxAge >= 0 || after.StatusCode != 401 {
+			return false
+		}
+	}
+
+	r := sessionRouter(NewRedisStore(redis, []byte("hash-key")))
+	r.ServeRequest("POST", "/login", nil, nil)
+	keys := 0
+	for key := range redis.data {
+		if strings.HasPrefix(key, "session_") {
+			keys++
+		}
+	}
+	return keys == 1
+}
+
+// Test LoggerWithConfig
+func testLoggerWithConfig() bool {
+	var out bytes.Buffer
+	var captured LogFormatterParams
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{
+		Output:    &out,
+		SkipPaths: []string{"/health"},
+		Skip:      func(c *Context) bool { return c.GetHeader("X-No-Log") != "" },
+		Formatter: func(p LogFormatterParams) string {
+			captured = p
+			return fmt.Sprintf("%s %s %d %s %v\n", p.Method, p.Path, p.StatusCode, p.ClientIP, p.Keys["user"])
+		},
+	}))
+	r.GET("/items", func(c *Context) {
+		c.Set("user", "alice")
+		c.String(201, "created")
+	})
+	r.GET("/health", func(c *Context) { c.String(200, "ok") })
+
+	r.ServeRequest("GET", "/items?page=2", nil, map[string]string{"X-Real-IP": "10.1.2.3"})
+	r.ServeRequest("GET", "/health", nil, nil)
+	r.ServeRequest("GET", "/items", nil, map[string]string{"X-No-Log": "1"})
+	custom := out.String()
+
+	var plain bytes.Buffer
+	d := New()
+	d.Use(LoggerWithWriter(&plain))
+	d.GET("/ping", func(c *Context) { c.String(200, "pong") })
+	d.ServeRequest("GET", "/ping", nil, nil)
+
+	return custom == "GET /items?page=2 201 10.1.2.3 alice\n" &&
+		captured.BodySize == len("created") && captured.Latency >= 0 &&
+		strings.HasPrefix(plain.String(), "[GIN] ") &&
+		strings.Contains(plain.String(), "| 200 |") &&
+		strings.HasSuffix(plain.String(), "GET     \"/ping\"\n")
+}
+
+// Test Recovery with writers, custom handlers and broken pipes
+func testRecovery() bool {
+	var log bytes.Buffer
+	r := New()
+	r.Use(RecoveryWithWriter(&log))
+	r.GET("/panic", func(c *Context) { panic("boom") })
+	resp := r.ServeRequest("GET", "/panic", nil, map[string]string{"Authorization": "Bearer secret"})
+	logged := log.String()
+
+	var recovered interface{}
+	var stack string
+	custom := New()
+	custom.Use(RecoveryWithWriter(nil, func(c *Context, err interface{}) {
+		recovered = err
+		stack = c.GetString(RecoveryStackKey)
+		c.JSON(503, H{"error": fmt.Sprint(err)})
+	}))
+	custom.GET("/panic", func(c *Context) { panic(fmt.Errorf("db down")) })
+	customResp := custom.ServeRequest("GET", "/panic", nil, nil)
+
+	var pipeLog bytes.Buffer
+	handlerCalled := false
+	pipe := New()
+	pipe.Use(RecoveryWithWriter(&pipeLog, func(c *Context, err interface{}) { handlerCalled = true }))
+	pipe.GET("/stream", func(c *Context) {
+		c.Status(200)
+		panic(&net.OpError{Op: "write", Net: "tcp", Err: &os.SyscallError{Syscall: "write", Err: syscall.EPIPE}})
+	})
+	pipeResp := pipe.ServeRequest("GET", "/stream", nil, nil)
+
+	return resp.StatusCode == 500 &&
+		strings.Contains(logged, "panic recovered") && strings.Contains(logged, "boom") &&
+		strings.Contains(logged, "Authorization: *") && !strings.Contains(logged, "secret") &&
+		strings.Contains(logged, "goroutine ") &&
+		customResp.StatusCode == 503 && fmt.Sprint(recovered) == "db down" &&
+		strings.Contains(stack, "testRecovery") &&
+		!handlerCalled && pipeResp.StatusCode == 200 &&
+		strings.Contains(pipeLog.String(), "connection error") &&
+		!strings.Contains(pipeLog.String(), "goroutine ")
+}
+
+// Test c.Error collection and error types
+func testContextErrors() bool {
+	var errs errorMsgs
+	r := New()
+	r.Use(func(c *Context) {
+		c.Next()
+		errs = c.Errors
+	})
+	r.GET("/", func(c *Context) {
+		c.Error(fmt.Errorf("cache miss"))
+		c.Error(fmt.Errorf("quota exceeded")).SetType(ErrorTypePublic).SetMeta(H{"limit": 10})
+		c.String(200, "ok")
+	})
+	r.ServeRequest("GET", "/", nil, nil)
+
+	wrapped := &Error{Err: fmt.Errorf("typed"), Type: ErrorTypePublic}
+	c := &Context{}
+	same := c.Error(fmt.Errorf("context: %w", wrapped)) == wrapped
+
+	public := errs.ByType(ErrorTypePublic)
+	data, _ := json.Marshal(public)
+	return len(errs) == 2 && errs.Last().Error() == "quota exceeded" &&
+		len(errs.ByType(ErrorTypePrivate)) == 1 &&
+		strings.Join(errs.Errors(), ",") == "cache miss,quota exceeded" &&
+		string(data) == `{"error":"quota exceeded","limit":10}` &&
+		strings.Contains(errs.String(), "Error #01: cache miss") &&
+		same && registrationPanics(func() { c.Error(nil) })
+}
+
+// Test ErrorHandler middleware and error logging
+func testErrorHandler() bool {
+	var logs bytes.Buffer
+	r := New()
+	r.Use(LoggerWithWriter(&logs), ErrorHandler())
+	r.GET("/private", func(c *Context) {
+		c.Error(fmt.Errorf("db password rejected"))
+	})
+	r.GET("/public", func(c *Context) {
+		c.AbortWithError(404, fmt.Errorf("user not found")).SetType(ErrorTypePublic)
+	})
+	r.POST("/bind", func(c *Context) {
+		var body struct {
+			Name string `json:"name" binding:"required"`
+		}
+		c.BindJSON(&body)
+	})
+	r.GET("/handled", func(c *Context) {
+		c.Error(fmt.Errorf("ignored"))
+		c.String(200, "fine")
+	})
+
+	private := r.ServeRequest("GET", "/private", nil, nil)
+	public := r.ServeRequest("GET", "/public", nil, nil)
+	bind := r.ServeRequest("POST", "/bind", []byte(`{}`), nil)
+	handled := r.ServeRequest("GET", "/handled", nil, nil)
+
+	var bindResult map[string][]map[string]interface{}
+	json.Unmarshal(bind.Body, &bindResult)
+	return private.StatusCode == 500 &&
+		string(private.Body) == `{"error":"Internal Server Error"}` &&
+		public.StatusCode == 404 &&
+		string(public.Body) == `{"errors":[{"error":"user not found"}]}` &&
+		bind.StatusCode == 400 && len(bindResult["errors"]) == 1 &&
+		string(handled.Body) == "fine" &&
+		strings.Contains(logs.String(), "Error #01: db password rejected")
+}
+
+func listUsers(c *Context) { c.String(200, "users") }
+
+// Test Routes() introspection
+func testRoutesInfo() bool {
+	r := New()
+	r.Use(Logger())
+	r.GET("/users", listUsers)
+	api := r.Group("/api")
+	api.POST("/items/:id", func(c *Context) {})
+
+	routes := r.Routes()
+	routes[0].Path = "/mutated"
+	routes = r.Routes()
+
+	return len(routes) == 2 &&
+		routes[0].Method == "GET" && routes[0].Path == "/users" &&
+		routes[0].Handler == "main.listUsers" && routes[0].HandlerFunc != nil &&
+		routes[1].Method == "POST" && routes[1].Path == "/api/items/:id" &&
+		strings.HasPrefix(routes[1].Handler, "main.testRoutesInfo.func")
+}
+
+// Test named routes and RouteURL
+func testRouteURL() bool {
+	r := New()
+	r.GET("/users/:id", func(c *Context) {}).Name("user")
+	files := r.Group("/files")
+	files.GET("/*path", func(c *Context) {}).Name("file")
+	r.Any("/search", func(c *Context) {}).Name("search")
+
+	user, err1 := r.RouteURL("user", "id", "42")
+	escaped, _ := r.RouteURL("user", "id", "a b/c")
+	file, _ := r.RouteURL("file", "path", "docs/read me.txt")
+	search, _ := r.RouteURL("search", "q", "gin", "page", "2")
+	_, missing := r.RouteURL("user")
+	_, unknown := r.RouteURL("nope")
+	_, odd := r.RouteURL("user", "id")
+
+	return err1 == nil && user == "/users/42" &&
+		escaped == "/users/a%20b%2Fc" &&
+		file == "/files/docs/read%20me.txt" &&
+		search == "/search?page=2&q=gin" &&
+		missing != nil && unknown != nil && odd != nil &&
+		registrationPanics(func() { r.GET("/other", func(c *Context) {}).Name("user") })
+}
+
+type requestIDKey struct{}
+
+// Test Context as context.Context with deadlines and cancellation
+func testRequestContext() bool {
+	r := New()
+	r.GET("/slow", func(c *Context) {
+		select {
+		case <-time.After(time.Second):
+			c.String(200, "done")
+		case <-c.Done():
+			c.String(503, "%s", c.Err().Error())
+		}
+	})
+	r.GET("/values", func(c *Context) {
+		c.Set("user", "alice")
+		var ctx context.Context = c
+		_, hasDeadline := ctx.Deadline()
+		c.String(200, "%v %v %v", ctx.Value("user"), ctx.Value(requestIDKey{}), hasDeadline)
+	})
+	r.GET("/stream", func(c *Context) {
+		gone := c.Stream(func(w io.Writer) bool {
+			w.Write([]byte("tick\n"))
+			return true
+		})
+		c.Set("gone", gone)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	slow := r.ServeRequestContext(ctx, "GET", "/slow", nil, nil)
+
+	valueCtx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	values := r.ServeRequestContext(valueCtx, "GET", "/values", nil, nil)
+	plain := r.ServeRequest("GET", "/values", nil, nil)
+
+	// A cancelled stream stops instead of looping forever
+	streamCtx, stop := context.WithCancel(context.Background())
+	stop()
+	stream := r.ServeRequestContext(streamCtx, "GET", "/stream", nil, nil)
+
+	req := (&Request{}).WithContext(valueCtx)
+	return slow.StatusCode == 503 && string(slow.Body) == "context deadline exceeded" &&
+		string(values.Body) == "alice req-1 false" &&
+		string(plain.Body) == "alice <nil> false" &&
+		stream.StatusCode == 200 && len(stream.Body) == 0 &&
+		req.Context().Value(requestIDKey{}) == "req-1" &&
+		(&Request{}).Context() == context.Background()
+}
+
+// eventsHandler streams numbered events, resuming after Last-Event-ID
+func eventsHandler(c *Context) {
+	next := 1
+	if last, err := strconv.Atoi(c.LastEventID()); err == nil {
+		next = last + 1
+	}
+	c.Stream(func(w io.Writer) bool {
+		c.SSEventWithID(strconv.Itoa(next), "tick", H{"n": next})
+		next++
+		return next <= 3
+	})
+}
+
+// Test server push and Last-Event-ID resumption under ServeRequest
+func testPushAndResume() bool {
+	r := New()
+	r.GET("/", func(c *Context) {
+		if err := c.Push("/app.css", &http.PushOptions{Header: http.Header{"Accept": {"text/css"}}}); err != nil {
+			c.Header("X-Push", "unsupported")
+		}
+		c.String(200, "page")
+	})
+	r.GET("/events", eventsHandler)
+
+	page := r.ServeRequest("GET", "/", nil, nil)
+	full := ParseSSE(r.ServeRequest("GET", "/events", nil, nil).Body)
+	resumed := ParseSSE(r.ServeRequest("GET", "/events", nil, map[string]string{"Last-Event-ID": "2"}).Body)
+	parsed := ParseSSE([]byte(": comment\nid: 7\ndata: a\ndata: b\n\n"))
+
+	return len(page.Pushes) == 1 && page.Pushes[0].Target == "/app.css" &&
+		page.Pushes[0].Method == "GET" && page.Pushes[0].Header.Get("Accept") == "text/css" &&
+		len(full) == 3 && full[0].ID == "1" && full[0].Event == "tick" && full[0].Data == `{"n":1}` &&
+		len(resumed) == 1 && resumed[0].ID == "3" &&
+		len(parsed) == 1 && parsed[0].ID == "7" && parsed[0].Data == "a\nb"
+}
+
+// Test the StreamRecorder harness over ServeHTTP
+func testStreamRecorder() bool {
+	r := New()
+	r.GET("/", func(c *Context) {
+		if err := c.Push("/app.js", nil); err != nil {
+			c.Header("X-Push", "unsupported")
+		}
+		c.String(200, "page")
+	})
+	r.GET("/events", eventsHandler)
+	ticks := 0
+	r.GET("/forever", func(c *Context) {
+		c.Stream(func(w io.Writer) bool {
+			ticks++
+			w.Write([]byte("."))
+			return true
+		})
+	})
+
+	rec := NewStreamRecorder()
+	r.ServeHTTP(rec, rec.Request("GET", "/", nil))
+	http1 := NewStreamRecorder()
+	http1.DisablePush = true
+	r.ServeHTTP(http1, http1.Request("GET", "/", nil))
+
+	events := NewStreamRecorder()
+	req := events.Request("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	r.ServeHTTP(events, req)
+
+	gone := NewStreamRecorder()
+	gone.Disconnect()
+	r.ServeHTTP(gone, gone.Request("GET", "/forever", nil))
+
+	return len(rec.Pushes()) == 1 && rec.Pushes()[0].Target == "/app.js" &&
+		rec.Header().Get("X-Push") == "" &&
+		http1.Header().Get("X-Push") == "unsupported" && http1.Body.String() == "page" &&
+		len(events.Chunks()) == 2 && len(events.Events()) == 2 &&
+		events.Events()[0].ID == "2" &&
+		events.Header().Get("Content-Type") == "text/event-stream" &&
+		ticks == 0
+}
+
+type money int64
+
+// Test binding custom types from query, form and URI values
+func testCustomTypeBinding() bool {
+	RegisterBindingConverter(money(0), func(value string, _ reflect.StructField) (interface{}, error) {
+		var dollars, cents int64
+		if _, err := fmt.Sscanf(value, "%d.%d", &dollars, &cents); err != nil {
+			return nil, err
+		}
+		return money(dollars*100 + cents), nil
+	})
+
+	type Filter struct {
+		From    time.Time       `form:"from"`
+		Day     time.Time       `form:"day" time_format:"2006-01-02" time_utc:"1"`
+		Epoch   *time.Time      `form:"epoch" time_format:"unix"`
+		Timeout time.Duration   `form:"timeout"`
+		Backoff []time.Duration `form:"backoff"`
+		Price   money           `form:"price"`
+		Since   time.Time       // untagged, so bound by field name
+	}
+	type Path struct {
+		ID UUID `uri:"id" binding:"required"`
+	}
+
+	var filter Filter
+	var path Path
+	var badTime, badUUID error
+	r := New()
+	r.GET("/orders/:id", func(c *Context) {
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.String(400, "%s", err.Error())
+			return
+		}
+		path.ID = UUID{}
+		badUUID = c.ShouldBindUri(&path)
+	})
+	r.GET("/check/:id", func(c *Context) {
+		var f Filter
+		badTime = c.ShouldBindQuery(&f)
+		c.ShouldBindUri(&path)
+	})
+
+	id := "123e4567-e89b-12d3-a456-426614174000"
+	resp := r.ServeRequest("GET", "/orders/"+id+"?from=2024-01-01T10:00:00Z&day=2024-03-05&epoch=1700000000&timeout=1m30s&backoff=1s&backoff=2s&price=12.34&Since=2024-02-02T00:00:00Z", nil, nil)
+	r.ServeRequest("GET", "/check/"+id+"?from=yesterday", nil, nil)
+	parsedID := path.ID
+	_, parseErr := ParseUUID("not-a-uuid")
+	r.ServeRequest("GET", "/orders/nope", nil, nil)
+
+	return resp.StatusCode == 200 &&
+		filter.From.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) &&
+		filter.Day.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) &&
+		filter.Epoch != nil && filter.Epoch.Unix() == 1700000000 &&
+		filter.Timeout == 90*time.Second &&
+		len(filter.Backoff) == 2 && filter.Backoff[1] == 2*time.Second &&
+		filter.Price == 1234 && filter.Since.Year() == 2024 &&
+		badTime != nil &&
+		parsedID.String() == id && parseErr != nil &&
+		badUUID != nil
+}
+
+// countingWriter wraps a ResponseWriter to record what passes through it
+type countingWriter struct {
+	ResponseWriter
+	writes int
+	body   bytes.Buffer
+	status int
+}
+
+func (w *countingWriter) Write(data []byte) (int, error) {
+	w.writes++
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *countingWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Test the ResponseWriter abstraction and wrapping middleware
+func testResponseWriter() bool {
+	var counter *countingWriter
+	var before, after int
+	var writtenBefore bool
+	r := New()
+	r.Use(func(c *Context) {
+		counter = &countingWriter{ResponseWriter: c.Writer}
+		c.Writer = counter
+		before, writtenBefore = c.Writer.Size(), c.Writer.Written()
+		c.Writer.Header().Set("X-Request-Id", "abc")
+		c.Next()
+		after = c.Writer.Size()
+	})
+	r.GET("/json", func(c *Context) {
+		c.Header("X-Handler", "json")
+		c.JSON(201, H{"ok": true})
+	})
+	r.GET("/raw", func(c *Context) {
+		h := c.Writer.Header()
+		h.Set("Content-Type", "text/csv")
+		h.Add("Set-Cookie", "theme=dark; Path=/")
+		h.Del("X-Request-Id")
+		c.Writer.WriteHeader(202)
+		c.Writer.Write([]byte("a,b\n"))
+		c.Writer.Write([]byte("1,2\n"))
+	})
+	r.GET("/visible", func(c *Context) {
+		c.Header("X-From-Context", "1")
+		c.Writer.WriteString(c.Writer.Header().Get("X-From-Context") + c.Writer.Header().Get("X-Request-Id"))
+	})
+
+	jsonResp := r.ServeRequest("GET", "/json", nil, nil)
+	jsonOK := jsonResp.StatusCode == 201 && counter.status == 201 &&
+		counter.body.String() == `{"ok":true}` &&
+		jsonResp.Headers["X-Request-Id"] == "abc" && jsonResp.Headers["X-Handler"] == "json" &&
+		before == -1 && !writtenBefore && after == len(`{"ok":true}`)
+
+	raw := r.ServeRequest("GET", "/raw", nil, nil)
+	rawOK := raw.StatusCode == 202 && string(raw.Body) == "a,b\n1,2\n" && counter.writes == 2 &&
+		raw.Headers["Content-Type"] == "text/csv" && raw.Headers["X-Request-Id"] == "" &&
+		len(raw.Cookies) == 1 && raw.Cookies[0].Name == "theme"
+
+	visible := r.ServeRequest("GET", "/visible", nil, nil)
+
+	// Writes through a wrapper reach real connections too
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/json", nil))
+
+	return jsonOK && rawOK && string(visible.Body) == "1abc" &&
+		rec.Code == 201 && rec.Body.String() == `{"ok":true}` &&
+		rec.Header().Get("X-Request-Id") == "abc"
+}
+
+// Test middleware added after routes are registered
+func testLateMiddleware() bool {
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	handler := func(c *Context) { trace = append(trace, "handler") }
+
+	r := New()
+	r.GET("/early", handler)
+	api := r.Group("/api", mark("api"))
+	api.GET("/items", handler)
+	v1 := api.Group("/v1")
+	v1.GET("/users", handler)
+	admin := api.Group("/admin", mark("admin"))
+	admin.GET("/stats", handler)
+
+	// Registered after the routes above, yet applied to them
+	r.Use(mark("global"))
+	api.Use(mark("api-late"))
+	v1.Use(mark("v1"))
+
+	serve := func(path string) string {
+		trace = nil
+		r.ServeRequest("GET", path, nil, nil)
+		return strings.Join(trace, ",")
+	}
+	missing := serve("/nowhere")
+
+	return serve("/early") == "global,handler" &&
+		serve("/api/items") == "global,api,api-late,handler" &&
+		serve("/api/v1/users") == "global,api,api-late,v1,handler" &&
+		serve("/api/admin/stats") == "global,api,api-late,admin,handler" &&
+		missing == "global"
+}
+
+// recordingT collects assertion failures like a *testing.T
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func testTestRequestBuilder() bool {
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.String(400, "no file")
+			return
+		}
+		token, _ := c.Cookie("token")
+		c.JSON(200, H{
+			"title":    c.PostForm("title"),
+			"filename": file.Filename,
+			"size":     file.Size,
+			"token":    token,
+			"trace":    c.GetHeader("X-Trace"),
+			"page":     c.Query("page"),
+		})
+	})
+	r.POST("/login", func(c *Context) {
+		c.SetCookie("session", "a b", 3600, "/", "", false, true)
+		c.String(200, "hello %s", c.PostForm("user"))
+	})
+	r.POST("/echo", func(c *Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.String(400, "bad json")
+			return
+		}
+		c.JSON(201, body)
+	})
+
+	upload := NewTestRequest("POST", "/upload").
+		Query("page", "2").
+		Header("X-Trace", "abc").
+		Cookie("token", "t1").
+		FormField("title", "Report").
+		File("file", "report.txt", []byte("hello world")).
+		Do(r)
+	login := NewTestRequest("POST", "/login").FormField("user", "ann").Do(r)
+	echo := NewTestRequest("POST", "/echo").JSON(H{"id": 7, "tags": []string{"x"}}).Do(r)
+
+	t := &recordingT{}
+	ok := upload.AssertStatus(t, 200) &&
+		upload.AssertHeader(t, "Content-Type", "application/json") &&
+		upload.AssertJSON(t, H{"title": "Report", "filename": "report.txt", "size": 11, "token": "t1", "trace": "abc", "page": "2"}) &&
+		login.AssertBodyContains(t, "hello ann") &&
+		login.AssertCookie(t, "session", "a b") &&
+		echo.AssertStatus(t, 201) &&
+		echo.AssertJSON(t, map[string]interface{}{"tags": []string{"x"}, "id": 7.0})
+
+	return ok && len(t.errors) == 0
+}
+
+func testTestResponseAssertions() bool {
+	r := New()
+	r.GET("/item", func(c *Context) {
+		c.Header("X-Version", "1")
+		c.JSON(200, H{"name": "widget"})
+	})
+
+	resp := NewTestRequest("GET", "/item").Do(r)
+	var decoded map[string]string
+	if err := resp.DecodeJSON(&decoded); err != nil || decoded["name"] != "widget" {
+		return false
+	}
+
+	t := &recordingT{}
+	failures := []bool{
+		resp.AssertStatus(t, 404),
+		resp.AssertHeader(t, "X-Version", "2"),
+		resp.AssertBodyContains(t, "gadget"),
+		resp.AssertJSON(t, H{"name": "gadget"}),
+		resp.AssertCookie(t, "missing", ""),
+		NewTestRequest("GET", "/missing").Do(r).AssertJSON(t, H{}),
+	}
+	for _, held := range failures {
+		if held {
+			return false
+		}
+	}
+
+	_, err := NewTestRequest("POST", "/item").JSON(make(chan int)).Build()
+	return len(t.errors) == len(failures) &&
+		strings.Contains(t.errors[0], "expected status 404, got 200") &&
+		strings.Contains(t.errors[3], `{"name":"gadget"}`) &&
+		err != nil
+}
+
+func testTimeout() bool {
+	lateWrite := make(chan error, 1)
+	cancelled := make(chan error, 1)
+
+	r := New()
+	r.Use(func(c *Context) {
+		c.Header("X-Request-Id", "42")
+		c.Next()
+		c.Header("X-Handled-By", c.GetString("handler"))
+	})
+	r.GET("/fast", Timeout(time.Second), func(c *Context) {
+		c.Set("handler", "fast")
+		c.Header("X-Fast", "1")
+		c.JSON(201, H{"ok": true})
+	})
+	r.GET("/slow", Timeout(20*time.Millisecond), func(c *Context) {
+		select {
+		case <-c.Done():
+			cancelled <- c.Err()
+		case <-time.After(time.Second):
+			cancelled <- nil
+		}
+		c.Header("X-Late", "1")
+		_, err := c.Writer.Write([]byte("late"))
+		lateWrite <- err
+	})
+	r.GET("/custom", TimeoutWithConfig(TimeoutConfig{
+		Timeout:  10 * time.Millisecond,
+		Response: func(c *Context) { c.JSON(504, H{"error": "upstream timeout"}) },
+	}), func(c *Context) {
+		<-c.Done()
+	})
+
+	fast := r.ServeRequest("GET", "/fast", nil, nil)
+	slow := r.ServeRequest("GET", "/slow", nil, nil)
+	custom := r.ServeRequest("GET", "/custom", nil, nil)
+
+	recovered := New()
+	recovered.Use(RecoveryWithWriter(io.Discard))
+	recovered.GET("/panic", Timeout(time.Second), func(c *Context) { panic("boom") })
+	panicked := recovered.ServeRequest("GET", "/panic", nil, nil)
+
+	return fast.StatusCode == 201 && string(fast.Body) == `{"ok":true}` &&
+		fast.Headers["X-Fast"] == "1" && fast.Headers["X-Request-Id"] == "42" &&
+		fast.Headers["X-Handled-By"] == "fast" &&
+		slow.StatusCode == 503 && string(slow.Body) == "503 Service Unavailable" &&
+		slow.Headers["X-Request-Id"] == "42" &&
+		<-cancelled == context.DeadlineExceeded && <-lateWrite == http.ErrHandlerTimeout &&
+		slow.Headers["X-Late"] == "" &&
+		custom.StatusCode == 504 && strings.Contains(string(custom.Body), "upstream timeout") &&
+		panicked.StatusCode == 500
+}
+
+func testETag() bool {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := New()
+	r.Use(ETag())
+	r.GET("/items", func(c *Context) { c.JSON(200, H{"items": []int{1, 2}}) })
+	r.GET("/doc", func(c *Context) {
+		c.Header("Last-Modified", modified.Format(http.TimeFormat))
+		c.String(200, "document")
+	})
+	r.GET("/versioned", func(c *Context) {
+		c.Header("ETag", `"v7"`)
+		c.String(200, "v7")
+	})
+	r.GET("/missing", func(c *Context) { c.String(404, "nope") })
+	r.POST("/items", func(c *Context) { c.String(201, "created") })
+
+	first := r.ServeRequest("GET", "/items", nil, nil)
+	etag := first.Headers["ETag"]
+	cached := r.ServeRequest("GET", "/items", nil, map[string]string{"If-None-Match": `"other", ` + etag})
+	changed := r.ServeRequest("GET", "/items", nil, map[string]string{"If-None-Match": `"other"`})
+	weakMatch := r.ServeRequest("GET", "/versioned", nil, map[string]string{"If-None-Match": `W/"v7"`})
+	fresh := r.ServeRequest("GET", "/doc", nil, map[string]string{"If-Modified-Since": modified.Add(time.Minute).Format(http.TimeFormat)})
+	stale := r.ServeRequest("GET", "/doc", nil, map[string]string{"If-Modified-Since": modified.Add(-time.Minute).Format(http.TimeFormat)})
+	missing := r.ServeRequest("GET", "/missing", nil, map[string]string{"If-None-Match": "*"})
+	post := r.ServeRequest("POST", "/items", nil, map[string]string{"If-None-Match": "*"})
+
+	weak := New()
+	weak.Use(ETagWithConfig(ETagConfig{Weak: true}))
+	weak.GET("/", func(c *Context) { c.String(200, "hi") })
+	weakResp := weak.ServeRequest("GET", "/", nil, nil)
+
+	return first.StatusCode == 200 && strings.HasPrefix(etag, `"`) && len(etag) == 34 &&
+		cached.StatusCode == 304 && len(cached.Body) == 0 && cached.Headers["ETag"] == etag &&
+		cached.Headers["Content-Type"] == "" &&
+		changed.StatusCode == 200 && string(changed.Body) == `{"items":[1,2]}` &&
+		weakMatch.StatusCode == 304 && weakMatch.Headers["ETag"] == `"v7"` &&
+		fresh.StatusCode == 304 && stale.StatusCode == 200 && string(stale.Body) == "document" &&
+		missing.StatusCode == 404 && post.StatusCode == 201 &&
+		strings.HasPrefix(weakResp.Headers["ETag"], `W/"`)
+}
+
+func testCacheControl() bool {
+	r := New()
+	assets := r.Group("/assets", CacheControl(CacheConfig{Public: true, Immutable: true, MaxAge: 365 * 24 * time.Hour}))
+	assets.GET("/app.js", func(c *Context) { c.String(200, "js") })
+	api := r.Group("/api", CacheControl(CacheConfig{Private: true, NoCache: true, MustRevalidate: true}))
+	api.GET("/me", func(c *Context) { c.JSON(200, H{"id": 1}) })
+	api.GET("/token", func(c *Context) {
+		c.Header("Cache-Control", CacheConfig{NoStore: true}.String())
+		c.String(200, "secret")
+	})
+
+	js := r.ServeRequest("GET", "/assets/app.js", nil, nil)
+	me := r.ServeRequest("GET", "/api/me", nil, nil)
+	token := r.ServeRequest("GET", "/api/token", nil, nil)
+
+	return js.Headers["Cache-Control"] == "public, immutable, max-age=31536000" &&
+		me.Headers["Cache-Control"] == "private, no-cache, must-revalidate" &&
+		token.Headers["Cache-Control"] == "no-store" &&
+		CacheConfig{SharedMaxAge: time.Minute, MaxAge: 90 * time.Second}.String() == "max-age=90, s-maxage=60"
+}
+
+// mapCacheStore is a CacheStore recording what was cached and for how long
+type mapCacheStore struct {
+	mu      sync.Mutex
+	pages   map[string]CachedPage
+	expires map[string]time.Duration
+}
+
+func newMapCacheStore() *mapCacheStore {
+	return &mapCacheStore{pages: map[string]CachedPage{}, expires: map[string]time.Duration{}}
+}
+
+func (s *mapCacheStore) Get(key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, ok := s.pages[key]
+	if !ok {
+		return errors.New("cache miss")
+	}
+	*value.(*CachedPage) = page
+	return nil
+}
+
+func (s *mapCacheStore) Set(key string, value interface{}, expire time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[key] = value.(CachedPage)
+	s.expires[key] = expire
+	return nil
+}
+
+func (s *mapCacheStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pages, key)
+	return nil
+}
+
+func testPageCache() bool {
+	store := newMapCacheStore()
+	calls := 0
+	r := New()
+	r.GET("/report", CachePage(store, time.Minute, func(c *Context) {
+		calls++
+		c.Header("X-Generated", strconv.Itoa(calls))
+		c.JSON(200, H{"calls": calls, "q": c.Query("q")})
+	}))
+	r.GET("/plain", CachePageWithoutQuery(store, time.Hour, func(c *Context) {
+		calls++
+		c.String(200, "plain %d", calls)
+	}))
+	r.GET("/fail", CachePage(store, time.Minute, func(c *Context) {
+		calls++
+		c.String(500, "boom")
+	}))
+	r.GET("/login", CachePage(store, time.Minute, func(c *Context) {
+		calls++
+		c.SetCookie("sid", "abc", 60, "/", "", false, true)
+		c.String(200, "welcome")
+	}))
+	api := r.Group("/api", CacheByRequestURI(store, 30*time.Second))
+	api.GET("/items", func(c *Context) {
+		calls++
+		c.JSON(200, H{"items": calls})
+	})
+
+	first := r.ServeRequest("GET", "/report?q=a", nil, nil)
+	second := r.ServeRequest("GET", "/report?q=a", nil, nil)
+	other := r.ServeRequest("GET", "/report?q=b", nil, nil)
+	if calls != 2 || string(first.Body) != `{"calls":1,"q":"a"}` || string(second.Body) != string(first.Body) ||
+		second.Headers["X-Generated"] != "1" || second.Headers["Content-Type"] != first.Headers["Content-Type"] ||
+		string(other.Body) != `{"calls":2,"q":"b"}` {
+		return false
+	}
+	if store.expires[PageCachePrefix+":"+url.QueryEscape("/report?q=a")] != time.Minute {
+		return false
+	}
+
+	// Keys ignore the query
+	plainA := r.ServeRequest("GET", "/plain?page=1", nil, nil)
+	plainB := r.ServeRequest("GET", "/plain?page=2", nil, nil)
+	if string(plainA.Body) != "plain 3" || string(plainB.Body) != "plain 3" {
+		return false
+	}
+
+	// Errors and responses setting cookies are not cached
+	r.ServeRequest("GET", "/fail", nil, nil)
+	r.ServeRequest("GET", "/fail", nil, nil)
+	r.ServeRequest("GET", "/login", nil, nil)
+	login := r.ServeRequest("GET", "/login", nil, nil)
+	if calls != 7 || len(login.Cookies) != 1 {
+		return false
+	}
+
+	// The middleware form caches the rest of the chain
+	items1 := r.ServeRequest("GET", "/api/items", nil, nil)
+	items2 := r.ServeRequest("GET", "/api/items", nil, nil)
+	if calls != 8 || string(items1.Body) != `{"items":8}` || string(items2.Body) != string(items1.Body) {
+		return false
+	}
+
+	// Deleting the key refreshes the page
+	store.Delete(PageCachePrefix + ":" + url.QueryEscape("/api/items"))
+	items3 := r.ServeRequest("GET", "/api/items", nil, nil)
+	return calls == 9 && string(items3.Body) == `{"items":9}` && len(store.pages) == 4
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func testReverseProxy() bool {
+	users := New()
+	users.GET("/:id", func(c *Context) {
+		c.SetCookie("seen", c.Param("id"), 60, "/", "", false, false)
+		c.Header("X-Service", "users")
+		c.JSON(200, H{
+			"id":     c.Param("id"),
+			"fields": c.Query("fields"),
+			"xff":    c.GetHeader("X-Forwarded-For"),
+			"host":   c.GetHeader("X-Forwarded-Host"),
+		})
+	})
+	orders := New()
+	orders.POST("/v1/orders/:id", func(c *Context) {
+		body := c.Request.Body
+		c.JSON(201, H{
+			"path":   c.FullPath(),
+			"id":     c.Param("id"),
+			"body":   string(body),
+			"tenant": c.GetHeader("X-Tenant"),
+			"conn":   c.GetHeader("Connection"),
+		})
+	})
+
+	gateway := New()
+	gateway.GET("/users/*rest", func(c *Context) { c.ProxyPass(users, c.Param("rest")) })
+	gateway.POST("/orders/*rest", ReverseProxy(ProxyConfig{
+		Target:      "http://orders.internal/v1/orders",
+		Engine:      orders,
+		StripPrefix: "/orders",
+		Director:    func(req *http.Request) { req.Header.Set("X-Tenant", "acme") },
+		ModifyResponse: func(resp *http.Response) error {
+			resp.Header.Set("X-Proxied", "orders")
+			return nil
+		},
+	}))
+	gateway.GET("/down", ReverseProxy(ProxyConfig{
+		Target: "http://down.internal",
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, fmt.Errorf("connection refused")
+		}),
+	}))
+
+	user := NewTestRequest("GET", "/users/7").
+		Query("fields", "name").
+		Header("Host", "api.example.com").
+		Header("X-Forwarded-For", "203.0.113.9").
+		Do(gateway)
+	order := NewTestRequest("POST", "/orders/42").
+		Header("Connection", "close").
+		Body([]byte("qty=2"), "text/plain").
+		Do(gateway)
+	down := gateway.ServeRequest("GET", "/down", nil, nil)
+
+	t := &recordingT{}
+	return user.AssertStatus(t, 200) &&
+		user.AssertHeader(t, "X-Service", "users") &&
+		user.AssertCookie(t, "seen", "7") &&
+		user.AssertJSON(t, H{"id": "7", "fields": "name", "xff": "203.0.113.9, 192.0.2.1", "host": "api.example.com"}) &&
+		order.AssertStatus(t, 201) &&
+		order.AssertHeader(t, "X-Proxied", "orders") &&
+		order.AssertJSON(t, H{"path": "/v1/orders/:id", "id": "42", "body": "qty=2", "tenant": "acme", "conn": ""}) &&
+		down.StatusCode == 502 && string(down.Body) == "502 Bad Gateway"
+}
+
+func testReverseProxyOverHTTP() bool {
+	upstream := New()
+	upstream.GET("/api/ping", func(c *Context) {
+		c.String(200, "pong over %s", c.GetHeader("X-Forwarded-Proto"))
+	})
+	server := httptest.NewServer(upstream)
+	defer server.Close()
+
+	gateway := New()
+	gateway.GET("/ping", ReverseProxy(ProxyConfig{Target: server.URL + "/api"}))
+	resp := gateway.ServeRequest("GET", "/ping", nil, nil)
+
+	return resp.StatusCode == 200 && string(resp.Body) == "pong over http"
+}
+
+func testWebSocket() bool {
+	serverClose := make(chan error, 1)
+	r := New()
+	r.GET("/ws", func(c *Context) {
+		conn, err := Upgrade(c)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteJSON(H{"type": "welcome", "user": c.Query("user")})
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				serverClose <- err
+				return
+			}
+			conn.WriteMessage(messageType, append([]byte("echo: "), data...))
+		}
+	})
+
+	client, resp, err := DialWebSocket(r, "/ws?user=ann", nil)
+	if err != nil || resp.StatusCode != 101 || resp.Headers["Upgrade"] != "websocket" {
+		return false
+	}
+
+	var welcome map[string]string
+	client.ReadJSON(&welcome)
+
+	var pongs []string
+	client.SetPongHandler(func(appData string) error {
+		pongs = append(pongs, appData)
+		return nil
+	})
+	client.WriteMessage(PingMessage, []byte("p1"))
+	client.WriteMessage(TextMessage, []byte("hello"))
+	textType, text, _ := client.ReadMessage()
+	client.WriteMessage(BinaryMessage, []byte{1, 2})
+	binaryType, binary, _ := client.ReadMessage()
+
+	client.CloseWithCode(CloseGoingAway, "bye")
+	serverErr := <-serverClose
+	_, _, clientErr := client.ReadMessage()
+	writeErr := client.WriteMessage(TextMessage, []byte("late"))
+
+	return welcome["type"] == "welcome" && welcome["user"] == "ann" &&
+		textType == TextMessage && string(text) == "echo: hello" &&
+		binaryType == BinaryMessage && bytes.Equal(binary, []byte("echo: \x01\x02")) &&
+		len(pongs) == 1 && pongs[0] == "p1" &&
+		IsCloseError(serverErr, CloseGoingAway) && serverErr.(*CloseError).Text == "bye" &&
+		IsCloseError(clientErr, CloseGoingAway) && writeErr == ErrCloseSent
+}
+
+func testWebSocketHandshake() bool {
+	r := New()
+	r.GET("/strict", func(c *Context) {
+		conn, err := Upgrade(c)
+		if err != nil {
+			return
+		}
+		if _, data, err := conn.ReadMessage(); err == nil && string(data) == "bad" {
+			conn.CloseWithCode(ClosePolicyViolation, "no")
+		}
+	})
+	r.GET("/drop", func(c *Context) { Upgrade(c) })
+	r.GET("/plain", func(c *Context) { c.String(200, "not a socket") })
+
+	strict, resp, _ := DialWebSocket(r, "/strict", map[string]string{"Sec-WebSocket-Key": "dGhlIHNhbXBsZSBub25jZQ=="})
+	strict.WriteMessage(TextMessage, []byte("bad"))
+	_, _, policyErr := strict.ReadMessage()
+
+	drop, _, _ := DialWebSocket(r, "/drop", nil)
+	_, _, dropErr := drop.ReadMessage()
+
+	plainConn, plainResp, plainErr := DialWebSocket(r, "/plain", nil)
+	missingHeaders := r.ServeRequest("GET", "/strict", nil, nil)
+	notDialed := r.ServeRequest("GET", "/strict", nil, map[string]string{
+		"Connection": "keep-alive, Upgrade", "Upgrade": "websocket",
+		"Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": "abc",
+	})
+
+	return resp.Headers["Sec-WebSocket-Accept"] == "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" &&
+		IsCloseError(policyErr, ClosePolicyViolation) && policyErr.(*CloseError).Text == "no" &&
+		IsCloseError(dropErr, CloseAbnormalClosure) &&
+		plainConn == nil && plainErr == ErrBadHandshake && string(plainResp.Body) == "not a socket" &&
+		missingHeaders.StatusCode == 400 && notDialed.StatusCode == 501 &&
+		!IsCloseError(fmt.Errorf("other"), CloseNormalClosure)
+}
+
+func testWrapHandlers() bool {
+	r := New()
+	r.Use(func(c *Context) {
+		c.Header("X-Gin", "yes")
+		c.Next()
+	})
+	r.GET("/hello", WrapF(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Seen", req.Host+"|"+req.RemoteAddr+"|"+req.Header.Get("X-Token"))
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "hello %s", req.URL.Query().Get("name"))
+	}))
+	files := http.StripPrefix("/files", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, req.URL.Path)
+	}))
+	r.GET("/files/*path", WrapH(files))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://api.example/hello?name=gin", nil)
+	req.Header.Set("X-Token", "t1")
+	req.RemoteAddr = "10.0.0.1:1234"
+	r.ServeHTTP(rec, req)
+
+	stripped := r.ServeRequest("GET", "/files/docs/readme.md", nil, nil)
+
+	return rec.Code == http.StatusAccepted && rec.Body.String() == "hello gin" &&
+		rec.Header().Get("X-Seen") == "api.example|10.0.0.1:1234|t1" &&
+		rec.Header().Get("X-Gin") == "yes" &&
+		stripped.StatusCode == 200 && string(stripped.Body) == "/docs/readme.md"
+}
+
+func testTrustedProxies() bool {
+	r := New()
+	r.GET("/ip", func(c *Context) { c.String(200, "%s", c.ClientIP()) })
+	ip := func(remote string, headers map[string]string) string {
+		req := NewTestRequest("GET", "/ip").RemoteAddr(remote)
+		for name, value := range headers {
+			req.Header(name, value)
+		}
+		return req.Do(r).BodyString()
+	}
+
+	// Every proxy is trusted by default
+	trustAll := ip("198.51.100.7:5000", map[string]string{"X-Forwarded-For": "203.0.113.5"})
+
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1"}); err != nil {
+		return false
+	}
+	viaProxy := ip("10.0.0.2:80", map[string]string{"X-Forwarded-For": "203.0.113.5, 10.0.0.9"})
+	spoofed := ip("198.51.100.7:5000", map[string]string{"X-Forwarded-For": "203.0.113.5"})
+	prepended := ip("10.0.0.2:80", map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.5"})
+	realIP := ip("192.168.1.1:80", map[string]string{"X-Real-IP": "203.0.113.6"})
+	garbage := ip("[::1]:80", map[string]string{"X-Forwarded-For": "nonsense", "X-Real-IP": "203.0.113.7"})
+	simulated := r.ServeRequest("GET", "/ip", nil, map[string]string{"X-Forwarded-For": "203.0.113.5"})
+
+	r.TrustedPlatform = PlatformCloudflare
+	platform := ip("198.51.100.7:5000", map[string]string{"CF-Connecting-IP": "203.0.113.8"})
+	r.TrustedPlatform = ""
+	r.ForwardedByClientIP = false
+	disabled := ip("10.0.0.2:80", map[string]string{"X-Forwarded-For": "203.0.113.5"})
+	r.ForwardedByClientIP = true
+	r.SetTrustedProxies(nil)
+	trustNone := ip("10.0.0.2:80", map[string]string{"X-Forwarded-For": "203.0.113.5"})
+
+	return trustAll == "203.0.113.5" && viaProxy == "203.0.113.5" &&
+		spoofed == "198.51.100.7" && prepended == "203.0.113.5" &&
+		realIP == "203.0.113.6" && garbage == "203.0.113.7" &&
+		string(simulated.Body) == "" && platform == "203.0.113.8" &&
+		disabled == "10.0.0.2" && trustNone == "10.0.0.2" &&
+		r.SetTrustedProxies([]string{"10.0.0.300"}) != nil &&
+		r.SetTrustedProxies([]string{"10.0.0.0/40"}) != nil
+}
+
+// Spoofed forwarding headers cannot dodge a rate limit
+func testRateLimitSpoofing() bool {
+	r := New()
+	r.SetTrustedProxies([]string{"10.0.0.1"})
+	r.GET("/login", RateLimit(RateLimitConfig{Rate: 0.1, Burst: 1}), func(c *Context) { c.String(200, "ok") })
+
+	attempt := func(remote, forwardedFor string) int {
+		return NewTestRequest("GET", "/login").RemoteAddr(remote).Header("X-Forwarded-For", forwardedFor).Do(r).Code
+	}
+	first := attempt("198.51.100.7:1000", "1.1.1.1")
+	spoofed := attempt("198.51.100.7:1001", "2.2.2.2")
+	proxiedA := attempt("10.0.0.1:80", "203.0.113.1")
+	proxiedB := attempt("10.0.0.1:80", "203.0.113.2")
+	proxiedAgain := attempt("10.0.0.1:80", "203.0.113.1")
+
+	return first == 200 && spoofed == 429 && proxiedA == 200 && proxiedB == 200 && proxiedAgain == 429
+}
+
+// Test request IDs, generated or passed through from the client
+func testRequestID() bool {
+	r := New()
+	r.Use(RequestID())
+	r.GET("/id", func(c *Context) { c.String(200, "%s", GetRequestID(c)) })
+
+	generated := r.ServeRequest("GET", "/id", nil, nil)
+	id := generated.Headers["X-Request-ID"]
+	again := r.ServeRequest("GET", "/id", nil, nil)
+	passed := r.ServeRequest("GET", "/id", nil, map[string]string{"X-Request-ID": "client-1"})
+	if len(id) != 36 || id[14] != '4' || string(generated.Body) != id ||
+		again.Headers["X-Request-ID"] == id ||
+		passed.Headers["X-Request-ID"] != "client-1" || string(passed.Body) != "client-1" {
+		return false
+	}
+
+	// Custom headers and generators, e.g. the uuid emulator's NewString
+	n := 0
+	var logged []string
+	custom := New()
+	custom.Use(RequestIDWithConfig(RequestIDConfig{
+		Header:    "X-Trace-ID",
+		Generator: func() string { n++; return fmt.Sprintf("trace-%d", n) },
+		Handler:   func(c *Context, id string) { logged = append(logged, id) },
+	}))
+	custom.GET("/id", func(c *Context) { c.String(200, "%s", GetRequestID(c)) })
+	first := custom.ServeRequest("GET", "/id", nil, nil)
+	second := custom.ServeRequest("GET", "/id", nil, nil)
+
+	plain := New()
+	plain.GET("/id", func(c *Context) { c.String(200, "[%s]", GetRequestID(c)) })
+	return first.Headers["X-Trace-ID"] == "trace-1" && string(second.Body) == "trace-2" &&
+		strings.Join(logged, ",") == "trace-1,trace-2" &&
+		string(plain.ServeRequest("GET", "/id", nil, nil).Body) == "[]"
+}
+
+// fakeYAML stands in for the yaml emulator's Codec: it writes "key: value"
+// lines and reads them back through JSON
+type fakeYAML struct{}
+
+func (fakeYAML) Marshal(v interface{}) ([]byte, error) {
+	return []byte(fmt.Sprintf("# fake\nvalue: %v\n", v)), nil
+}
+
+func (fakeYAML) Unmarshal(data []byte, v interface{}) error {
+	fields := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if key, value, ok := strings.Cut(line, ": "); ok {
+			fields[key] = value
+		}
+	}
+	encoded, _ := json.Marshal(fields)
+	return json.Unmarshal(encoded, v)
+}
+
+// Test YAML rendering and binding through a pluggable codec
+func testYAMLCodec() bool {
+	type Order struct {
+		Item string `json:"item" binding:"required"`
+		Note string `json:"note"`
+	}
+	handler := func(c *Context) {
+		var order Order
+		if err := c.ShouldBind(&order); err != nil {
+			c.String(400, "%s", err)
+			return
+		}
+		c.YAML(200, order.Item+"/"+order.Note)
+	}
+	yamlBody := func(r *Engine, body string) *Response {
+		return r.ServeRequest("POST", "/orders", []byte(body), map[string]string{"Content-Type": "application/x-yaml"})
+	}
+
+	// The built-in codec encodes but cannot decode
+	r := New()
+	r.POST("/orders", handler)
+	builtin := yamlBody(r, "item: tea\n")
+	if builtin.StatusCode != 400 || !strings.Contains(string(builtin.Body), "no YAML decoder") {
+		return false
+	}
+
+	YAML = fakeYAML{}
+	defer func() { YAML = defaultYAMLCodec{} }()
+	ok := yamlBody(r, "item: tea\nnote: hot\n")
+	missing := yamlBody(r, "note: cold\n")
+	return ok.StatusCode == 200 && string(ok.Body) == "# fake\nvalue: tea/hot\n" &&
+		ok.Headers["Content-Type"] == MIMEYAML && missing.StatusCode == 400
+}
+
+func testHostSwitch() bool {
+	service := func(name string) *Engine {
+		e := New()
+		e.GET("/whoami", func(c *Context) { c.String(200, "%s:%s", name, c.Subdomain()) })
+		return e
+	}
+	hosts := NewHostSwitch()
+	hosts.Handle("api.example.com", service("api"))
+	hosts.Handle("*.example.com", service("tenant"))
+	hosts.Handle("*.eu.example.com", service("eu"))
+
+	serve := func(host string) *Response {
+		return hosts.ServeRequest("GET", "/whoami", nil, map[string]string{"Host": host})
+	}
+	api := serve("API.example.com:8443")
+	tenant := serve("acme.example.com")
+	eu := serve("acme.eu.example.com.")
+	apex := serve("example.com")
+	deep := serve("a.b.example.com")
+
+	hosts.Handle("*", service("fallback"))
+	fallback := serve("other.org")
+
+	server := httptest.NewServer(hosts)
+	defer server.Close()
+	req, _ := http.NewRequest("GET", server.URL+"/whoami", nil)
+	req.Host = "globex.example.com"
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer httpResp.Body.Close()
+	overHTTP, _ := io.ReadAll(httpResp.Body)
+
+	return string(api.Body) == "api:" && string(tenant.Body) == "tenant:acme" &&
+		string(eu.Body) == "eu:acme" && apex.StatusCode == 404 && deep.StatusCode == 404 &&
+		string(fallback.Body) == "fallback:" && string(overHTTP) == "tenant:globex" &&
+		registrationPanics(func() { hosts.Handle("api.example.com", New()) }) &&
+		registrationPanics(func() { hosts.Handle("a.*.com", New()) })
+}
+
+func testHostMiddleware() bool {
+	r := New()
+	r.NoRoute(func(c *Context) { c.JSON(404, H{"error": "not found"}) })
+	admin := r.Group("/admin", Host("admin.example.com"))
+	admin.GET("/stats", func(c *Context) { c.String(200, "stats") })
+	tenants := r.Group("/app", Host("*.example.com", "localhost"))
+	tenants.GET("/home", func(c *Context) { c.String(200, "home of %q", c.Subdomain()) })
+
+	serve := func(path, host string) *Response {
+		return r.ServeRequest("GET", path, nil, map[string]string{"Host": host})
+	}
+	stats := serve("/admin/stats", "admin.example.com")
+	wrongHost := serve("/admin/stats", "acme.example.com")
+	home := serve("/app/home", "acme.example.com:80")
+	local := serve("/app/home", "localhost")
+
+	return stats.StatusCode == 200 && wrongHost.StatusCode == 404 &&
+		strings.Contains(string(wrongHost.Body), "not found") &&
+		string(home.Body) == `home of "acme"` && string(local.Body) == `home of ""`
+}
+
+type apiAudit struct {
+	Created time.Time `json:"created"`
+}
+
+type apiUser struct {
+	apiAudit
+	ID      int64    `json:"id"`
+	Name    string   `json:"name" binding:"required,min=2,max=50"`
+	Email   string   `json:"email" binding:"required,email"`
+	Role    string   `json:"role,omitempty" binding:"omitempty,oneof=admin member"`
+	Tags    []string `json:"tags" binding:"max=5"`
+	Manager *apiUser `json:"manager,omitempty"`
+	Secret  string   `json:"-"`
+}
+
+type apiUserQuery struct {
+	Page   int    `form:"page" binding:"required,gt=0"`
+	Search string `form:"q"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func testOpenAPISpec() bool {
+	r := New()
+	r.OpenAPIInfo = OpenAPIInfo{Title: "Users", Version: "2.1.0"}
+	api := r.Group("/api")
+	api.GET("/users", func(c *Context) {}).
+		Summary("List users").Tags("users").
+		Request(apiUserQuery{}).
+		Response(200, "The users", []apiUser{})
+	api.POST("/users", func(c *Context) {}).
+		Summary("Create a user").Tags("users").
+		Request(&apiUser{}).
+		Response(201, "Created", apiUser{}).
+		Response(400, "Invalid user", apiError{})
+	api.DELETE("/users/:id", func(c *Context) {}).Response(204, "Deleted", nil)
+	r.GET("/files/*path", func(c *Context) {})
+
+	data, err := r.OpenAPISpec()
+	again, _ := r.OpenAPISpec()
+	if err != nil || !bytes.Equal(data, again) {
+		return false
+	}
+	var spec map[string]interface{}
+	if json.Unmarshal(data, &spec) != nil {
+		return false
+	}
+	get := func(v interface{}, path ...interface{}) interface{} {
+		for _, key := range path {
+			switch k := key.(type) {
+			case string:
+				m, _ := v.(map[string]interface{})
+				v = m[k]
+			case int:
+				a, _ := v.([]interface{})
+				if k >= len(a) {
+					return nil
+				}
+				v = a[k]
+			}
+		}
+		return v
+	}
+
+	list := get(spec, "paths", "/api/users", "get")
+	create := get(spec, "paths", "/api/users", "post")
+	user := get(spec, "components", "schemas", "apiUser")
+	name := get(user, "properties", "name")
+	page := get(list, "parameters", 0)
+
+	return get(spec, "openapi") == "3.0.3" && get(spec, "info", "title") == "Users" &&
+		get(list, "summary") == "List users" && get(list, "tags", 0) == "users" &&
+		get(page, "name") == "page" && get(page, "in") == "query" && get(page, "required") == true &&
+		get(page, "schema", "minimum") == 0.0 && get(page, "schema", "exclusiveMinimum") == true &&
+		get(list, "parameters", 1, "name") == "q" &&
+		get(list, "responses", "200", "content", "application/json", "schema", "items", "$ref") == "#/components/schemas/apiUser" &&
+		get(create, "requestBody", "content", "application/json", "schema", "$ref") == "#/components/schemas/apiUser" &&
+		get(create, "responses", "400", "content", "application/json", "schema", "$ref") == "#/components/schemas/apiError" &&
+		fmt.Sprint(get(user, "required")) == "[name email]" &&
+		get(name, "minLength") == 2.0 && get(name, "maxLength") == 50.0 &&
+		get(user, "properties", "email", "format") == "email" &&
+		fmt.Sprint(get(user, "properties", "role", "enum")) == "[admin member]" &&
+		get(user, "properties", "tags", "maxItems") == 5.0 &&
+		get(user, "properties", "created", "format") == "date-time" &&
+		get(user, "properties", "id", "format") == "int64" &&
+		get(user, "properties", "manager", "$ref") == "#/components/schemas/apiUser" &&
+		get(user, "properties", "Secret") == nil && get(user, "properties", "secret") == nil &&
+		get(spec, "paths", "/api/users/{id}", "delete", "parameters", 0, "in") == "path" &&
+		get(spec, "paths", "/api/users/{id}", "delete", "responses", "204", "content") == nil &&
+		get(spec, "paths", "/files/{path}", "get", "responses", "200", "description") == "OK"
+}
+
+// fakeTracer stands in for the OpenTelemetry emulator's MiddlewareTracer:
+// it reads a "trace" header as the parent and records the spans it ends
+type fakeTracer struct {
+	spans []map[string]interface{}
+}
+
+type traceKey struct{}
+
+func (t *fakeTracer) Extract(ctx context.Context, headers map[string]string) context.Context {
+	if parent := headers["trace"]; parent != "" {
+		return context.WithValue(ctx, traceKey{}, parent)
+	}
+	return ctx
+}
+
+func (t *fakeTracer) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, func(map[string]interface{}, error)) {
+	span := map[string]interface{}{"name": name, "parent": ctx.Value(traceKey{})}
+	for k, v := range attributes {
+		span[k] = v
+	}
+	return context.WithValue(ctx, traceKey{}, name), func(attributes map[string]interface{}, err error) {
+		for k, v := range attributes {
+			span[k] = v
+		}
+		span["error"] = err
+		t.spans = append(t.spans, span)
+	}
+}
+
+// Test the tracing middleware names spans by route and records outcomes
+func testTracing() bool {
+	tracer := &fakeTracer{}
+	r := New()
+	r.Use(Tracing("shop", tracer))
+	r.GET("/users/:id", func(c *Context) {
+		// The span is in the request context, and so in c
+		c.String(200, "%v", c.Value(traceKey{}))
+	})
+	r.GET("/fail", func(c *Context) {
+		c.Error(errors.New("database down"))
+		c.Status(500)
+	})
+
+	ok := r.ServeRequest("GET", "/users/7", nil, map[string]string{"trace": "remote"})
+	r.ServeRequest("GET", "/fail", nil, nil)
+	r.ServeRequest("GET", "/missing", nil, nil)
+	if string(ok.Body) != "/users/:id" || len(tracer.spans) != 3 {
+		return false
+	}
+
+	user, fail, missing := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	return user["name"] == "/users/:id" && user["parent"] == "remote" &&
+		user["http.method"] == "GET" && user["http.target"] == "/users/7" &&
+		user["http.server_name"] == "shop" && user["http.status_code"] == 200 && user["error"] == nil &&
+		fail["parent"] == nil && fail["http.status_code"] == 500 &&
+		fail["error"] != nil && fail["error"].(error).Error() == "database down" &&
+		missing["name"] == "HTTP GET route not found" && missing["http.route"] == "" &&
+		missing["http.status_code"] == 404
+}
+
 func main() {
 	fmt.Println("Running Gin Emulator Tests...")
 	fmt.Println("==============================")
@@ -425,6 +3143,75 @@ func main() {
 	runTest("RESTful API Pattern", testRESTfulAPI)
 	runTest("Complex URL Parameters", testComplexURLParameters)
 	runTest("Content Type Headers", testContentTypeHeaders)
+	runTest("HTTP Handler", testHTTPHandler)
+	runTest("HTTP Server", testHTTPServer)
+	runTest("Route Priority", testRoutePriority)
+	runTest("Route Conflicts", testRouteConflicts)
+	runTest("Wildcard Parameters", testWildcardParameters)
+	runTest("Wildcard Conflicts", testWildcardConflicts)
+	runTest("Context Keys", testContextKeys)
+	runTest("Post Form", testPostForm)
+	runTest("ShouldBind", testShouldBind)
+	runTest("Multipart Upload", testMultipartUpload)
+	runTest("Binding Validation", testBindingValidation)
+	runTest("Custom Struct Validator", testCustomStructValidator)
+	runTest("Binding Sources", testBindingSources)
+	runTest("Cookies", testCookies)
+	runTest("Static Files", testStaticFiles)
+	runTest("File Responses", testFileResponses)
+	runTest("HTML Templates", testHTMLTemplates)
+	runTest("HTML Test Mode", testHTMLTestMode)
+	runTest("Render Formats", testRenderFormats)
+	runTest("JSON Variants", testJSONVariants)
+	runTest("Negotiate", testNegotiate)
+	runTest("Stream", testStream)
+	runTest("SSEvent", testSSEvent)
+	runTest("Stream over HTTP", testStreamOverHTTP)
+	runTest("Redirect", testRedirect)
+	runTest("Redirect Paths", testRedirectPaths)
+	runTest("NoRoute", testNoRoute)
+	runTest("Method Not Allowed", testMethodNotAllowed)
+	runTest("CORS", testCORS)
+	runTest("BasicAuth", testBasicAuth)
+	runTest("BearerAuth", testBearerAuth)
+	runTest("Body Limit", testBodyLimit)
+	runTest("Gzip", testGzip)
+	runTest("Rate Limit", testRateLimit)
+	runTest("Rate Limit Redis", testRateLimitRedis)
+	runTest("Rate Limit Redis Shared", testRateLimitRedisShared)
+	runTest("Cookie Sessions", testCookieSessions)
+	runTest("Server Sessions", testServerSessions)
+	runTest("Logger With Config", testLoggerWithConfig)
+	runTest("Recovery", testRecovery)
+	runTest("Context Errors", testContextErrors)
+	runTest("Error Handler", testErrorHandler)
+	runTest("Routes Info", testRoutesInfo)
+	runTest("Route URL", testRouteURL)
+	runTest("Request Context", testRequestContext)
+	runTest("Push and Resume", testPushAndResume)
+	runTest("Stream Recorder", testStreamRecorder)
+	runTest("Custom Type Binding", testCustomTypeBinding)
+	runTest("Response Writer", testResponseWriter)
+	runTest("Late Middleware", testLateMiddleware)
+	runTest("Test Request Builder", testTestRequestBuilder)
+	runTest("Test Response Assertions", testTestResponseAssertions)
+	runTest("Timeout", testTimeout)
+	runTest("ETag", testETag)
+	runTest("Cache Control", testCacheControl)
+	runTest("Page Cache", testPageCache)
+	runTest("Reverse Proxy", testReverseProxy)
+	runTest("Reverse Proxy over HTTP", testReverseProxyOverHTTP)
+	runTest("WebSocket", testWebSocket)
+	runTest("WebSocket Handshake", testWebSocketHandshake)
+	runTest("Wrap Handlers", testWrapHandlers)
+	runTest("Trusted Proxies", testTrustedProxies)
+	runTest("Rate Limit Spoofing", testRateLimitSpoofing)
+	runTest("Request ID", testRequestID)
+	runTest("YAML Codec", testYAMLCodec)
+	runTest("Host Switch", testHostSwitch)
+	runTest("Host Middleware", testHostMiddleware)
+	runTest("OpenAPI Spec", testOpenAPISpec)
+	runTest("Tracing", testTracing)
 
 	fmt.Println("==============================")
 	fmt.Println("All tests completed!")
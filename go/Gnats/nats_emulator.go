@@ -0,0 +1,3154 @@
+package main
+
+// Developed by PowerShield, as an alternative to NATS
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults shared by the server, connections and subscriptions
+const (
+	DefaultURL                  = "nats://127.0.0.1:4222"
+	DefaultMaxPayload           = 1024 * 1024
+	DefaultSubPendingMsgsLimit  = 512 * 1024
+	DefaultSubPendingBytesLimit = 64 * 1024 * 1024
+	DefaultDrainTimeout         = 30 * time.Second
+	InboxPrefix                 = "_INBOX."
+)
+
+// Errors returned by connections and subscriptions
+var (
+	ErrConnectionClosed   = errors.New("nats: connection closed")
+	ErrConnectionDraining = errors.New("nats: connection draining")
+	ErrDrainTimeout       = errors.New("nats: draining connection timed out")
+	ErrNoServers          = errors.New("nats: no servers available for connection")
+	ErrBadSubject         = errors.New("nats: invalid subject")
+	ErrBadQueueName       = errors.New("nats: invalid queue name")
+	ErrBadSubscription    = errors.New("nats: invalid subscription")
+	ErrTypeSubscription   = errors.New("nats: invalid subscription type")
+	ErrSyncSubRequired    = errors.New("nats: illegal call on an async subscription")
+	ErrInvalidArg         = errors.New("nats: invalid argument")
+	ErrMaxPayload         = errors.New("nats: maximum payload exceeded")
+	ErrMaxMessages        = errors.New("nats: maximum messages delivered")
+	ErrSlowConsumer       = errors.New("nats: slow consumer, messages dropped")
+	ErrTimeout            = errors.New("nats: timeout")
+	ErrNoResponders       = errors.New("nats: no responders available for request")
+	ErrNoDeadlineContext  = errors.New("nats: context requires a deadline")
+	ErrMsgNotBound        = errors.New("nats: message is not bound to subscription/connection")
+	ErrMsgNoReply         = errors.New("nats: message does not have a reply")
+)
+
+// Errors returned by JetStream
+var (
+	ErrStreamNameRequired          = errors.New("nats: stream name is required")
+	ErrInvalidStreamName           = errors.New("nats: invalid stream name")
+	ErrStreamNotFound              = errors.New("nats: stream not found")
+	ErrStreamNameAlreadyInUse      = errors.New("nats: stream name already in use")
+	ErrStreamSubjectsOverlap       = errors.New("nats: subjects overlap with an existing stream")
+	ErrMaxMsgsExceeded             = errors.New("nats: maximum messages exceeded")
+	ErrInvalidConsumerName         = errors.New("nats: invalid consumer name")
+	ErrConsumerNotFound            = errors.New("nats: consumer not found")
+	ErrConsumerNameAlreadyInUse    = errors.New("nats: consumer name already in use")
+	ErrConsumerAlreadyBound        = errors.New("nats: consumer is already bound to a subscription")
+	ErrPullSubscribeToPushConsumer = errors.New("nats: cannot pull subscribe to push based consumer")
+	ErrPullSubscribeRequired       = errors.New("nats: must use pull subscribe to bind to pull based consumer")
+	ErrNoMatchingStream            = errors.New("nats: no stream matches subject")
+	ErrNoStreamResponse            = errors.New("nats: no response from stream")
+	ErrStreamMismatch              = errors.New("nats: expected stream does not match")
+	ErrMsgNotFound                 = errors.New("nats: message not found")
+	ErrNotJSMessage                = errors.New("nats: not a jetstream message")
+	ErrMsgAlreadyAckd              = errors.New("nats: message was already acknowledged")
+)
+
+// Header holds message headers. Unlike http.Header, keys are case
+// sensitive.
+type Header map[string][]string
+
+// Add appends a value to key
+func (h Header) Add(key, value string) {
+	h[key] = append(h[key], value)
+}
+
+// Set replaces the values of key
+func (h Header) Set(key, value string) {
+	h[key] = []string{value}
+}
+
+// Get returns the first value of key
+func (h Header) Get(key string) string {
+	if values := h[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Values returns all values of key
+func (h Header) Values(key string) []string {
+	return h[key]
+}
+
+// Del removes key
+func (h Header) Del(key string) {
+	delete(h, key)
+}
+
+func (h Header) clone() Header {
+	if h == nil {
+		return nil
+	}
+	c := make(Header, len(h))
+	for k, v := range h {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
+// Msg is a message published to or received from a subject
+type Msg struct {
+	Subject string
+	Reply   string
+	Header  Header
+	Data    []byte
+	// Sub is the subscription the message was delivered to
+	Sub *Subscription
+
+	ack *jsAck // set on messages delivered by a JetStream consumer
+}
+
+// NewMsg creates a message for subject with an empty header
+func NewMsg(subject string) *Msg {
+	return &Msg{Subject: subject, Header: make(Header)}
+}
+
+// Size returns the size of the message data
+func (m *Msg) Size() int {
+	return len(m.Data)
+}
+
+// copyFor gives each subscription its own copy of a message
+func (m *Msg) copyFor(sub *Subscription) *Msg {
+	return &Msg{
+		Subject: m.Subject,
+		Reply:   m.Reply,
+		Header:  m.Header.clone(),
+		Data:    append([]byte(nil), m.Data...),
+		Sub:     sub,
+		ack:     m.ack,
+	}
+}
+
+// Respond publishes data to the message's reply subject
+func (m *Msg) Respond(data []byte) error {
+	return m.RespondMsg(&Msg{Data: data})
+}
+
+// RespondMsg publishes msg to the message's reply subject
+func (m *Msg) RespondMsg(msg *Msg) error {
+	if m.Sub == nil || m.Sub.conn == nil {
+		return ErrMsgNotBound
+	}
+	if m.Reply == "" {
+		return ErrMsgNoReply
+	}
+	resp := *msg
+	resp.Subject = m.Reply
+	return m.Sub.conn.PublishMsg(&resp)
+}
+
+// Subjects
+
+// validSubject checks a subject's tokens. Wildcards are allowed only in
+// subscriptions: "*" as a whole token, ">" as the whole last token.
+func validSubject(subject string, wildcards bool) bool {
+	if subject == "" {
+		return false
+	}
+	tokens := strings.Split(subject, ".")
+	for i, t := range tokens {
+		if t == "" || strings.ContainsAny(t, " \t\r\n") {
+			return false
+		}
+		if strings.ContainsAny(t, "*>") {
+			if !wildcards || len(t) > 1 || (t == ">" && i != len(tokens)-1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// subjectMatches reports whether a literal subject matches pattern
+func subjectMatches(pattern, subject string) bool {
+	pt := strings.Split(pattern, ".")
+	st := strings.Split(subject, ".")
+	for i, p := range pt {
+		if p == ">" {
+			return len(st) > i
+		}
+		if i >= len(st) || (p != "*" && p != st[i]) {
+			return false
+		}
+	}
+	return len(pt) == len(st)
+}
+
+// subjectIsSubset reports whether every subject filter matches is also
+// matched by pattern
+func subjectIsSubset(filter, pattern string) bool {
+	ft := strings.Split(filter, ".")
+	pt := strings.Split(pattern, ".")
+	for i, p := range pt {
+		if p == ">" {
+			return len(ft) > i
+		}
+		if i >= len(ft) || ft[i] == ">" {
+			return false
+		}
+		if p != "*" && (ft[i] == "*" || ft[i] != p) {
+			return false
+		}
+	}
+	return len(ft) == len(pt)
+}
+
+// subjectsOverlap reports whether some subject matches both patterns
+func subjectsOverlap(a, b string) bool {
+	at := strings.Split(a, ".")
+	bt := strings.Split(b, ".")
+	for i := 0; i < len(at) && i < len(bt); i++ {
+		if at[i] == ">" || bt[i] == ">" {
+			return true
+		}
+		if at[i] != "*" && bt[i] != "*" && at[i] != bt[i] {
+			return false
+		}
+	}
+	return len(at) == len(bt)
+}
+
+const nuidDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// nuid returns a random base62 identifier of n characters
+func nuid(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = nuidDigits[rand.Intn(len(nuidDigits))]
+	}
+	return string(b)
+}
+
+// NewInbox returns a unique subject for replies
+func NewInbox() string {
+	return InboxPrefix + nuid(22)
+}
+
+// Server
+
+var (
+	serversMu sync.Mutex
+	servers   = make(map[string]*Server)
+)
+
+// Server is an in-memory NATS server. Clients connect to it through any
+// of its URLs, so code under test keeps its usual Connect(url) call.
+type Server struct {
+	// MaxPayload is the largest message data accepted
+	MaxPayload int
+
+	urls    []string
+	mu      sync.Mutex
+	conns   map[*Conn]struct{}
+	subs    map[*Subscription]struct{}
+	streams map[string]*stream
+}
+
+// NewServer starts an in-memory server reachable at urls, replacing any
+// server already registered at them. Without urls it listens on
+// DefaultURL.
+func NewServer(urls ...string) *Server {
+	if len(urls) == 0 {
+		urls = []string{DefaultURL}
+	}
+	s := &Server{
+		MaxPayload: DefaultMaxPayload,
+		urls:       urls,
+		conns:      make(map[*Conn]struct{}),
+		subs:       make(map[*Subscription]struct{}),
+		streams:    make(map[string]*stream),
+	}
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	for _, url := range urls {
+		servers[serverKey(url)] = s
+	}
+	return s
+}
+
+// serverKey normalizes a URL so "nats://host:4222" and "host:4222" match
+func serverKey(url string) string {
+	url = strings.TrimSpace(url)
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	if i := strings.LastIndex(url, "@"); i >= 0 {
+		url = url[i+1:]
+	}
+	return url
+}
+
+// lookupServer finds the server at the first URL that has one
+func lookupServer(urls []string) (*Server, string, error) {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	for _, url := range urls {
+		if s, ok := servers[serverKey(url)]; ok {
+			return s, strings.TrimSpace(url), nil
+		}
+	}
+	return nil, "", ErrNoServers
+}
+
+// ClientURL returns the server's first URL
+func (s *Server) ClientURL() string {
+	return s.urls[0]
+}
+
+// NumClients returns the number of open connections
+func (s *Server) NumClients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// NumSubscriptions returns the number of active subscriptions
+func (s *Server) NumSubscriptions() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint32(len(s.subs))
+}
+
+// Shutdown makes the server unreachable, disconnects its clients and
+// drops its streams
+func (s *Server) Shutdown() {
+	serversMu.Lock()
+	for _, url := range s.urls {
+		if servers[serverKey(url)] == s {
+			delete(servers, serverKey(url))
+		}
+	}
+	serversMu.Unlock()
+
+	s.mu.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for nc := range s.conns {
+		conns = append(conns, nc)
+	}
+	streams := s.streams
+	s.streams = make(map[string]*stream)
+	s.mu.Unlock()
+
+	for _, nc := range conns {
+		nc.close(io.EOF)
+	}
+	for _, st := range streams {
+		st.delete()
+	}
+}
+
+func (s *Server) addConn(nc *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[nc] = struct{}{}
+}
+
+func (s *Server) removeConn(nc *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, nc)
+}
+
+// addSub registers a subscription for routing. New interest may let
+// push consumers deliver, so every stream is woken.
+func (s *Server) addSub(sub *Subscription) {
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	streams := s.streamList()
+	s.mu.Unlock()
+	for _, st := range streams {
+		st.mu.Lock()
+		st.notify()
+		st.mu.Unlock()
+	}
+}
+
+func (s *Server) removeSub(sub *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, sub)
+}
+
+// match returns the subscriptions a message on subject goes to: every
+// plain subscription, and one random member of each queue group
+func (s *Server) match(from *Conn, subject string) []*Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var targets []*Subscription
+	var groups map[string][]*Subscription
+	for sub := range s.subs {
+		if from != nil && sub.conn == from && from.Opts.NoEcho {
+			continue
+		}
+		if !subjectMatches(sub.Subject, subject) {
+			continue
+		}
+		if sub.Queue == "" {
+			targets = append(targets, sub)
+			continue
+		}
+		if groups == nil {
+			groups = make(map[string][]*Subscription)
+		}
+		groups[sub.Queue] = append(groups[sub.Queue], sub)
+	}
+	for _, members := range groups {
+		targets = append(targets, members[rand.Intn(len(members))])
+	}
+	return targets
+}
+
+// hasInterest reports whether any subscription matches subject
+func (s *Server) hasInterest(subject string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if subjectMatches(sub.Subject, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// route delivers a published message to subscribers and stores it in the
+// stream capturing its subject, if any. A stored message with a reply
+// subject is acknowledged there, as the real server does for JetStream
+// publishes. It returns the number of subscriptions reached, counting
+// the stream's acknowledgement.
+func (s *Server) route(from *Conn, m *Msg) (int, *PubAck, error) {
+	delivered := deliver(s.match(from, m.Subject), m)
+
+	s.mu.Lock()
+	st := s.streamFor(m.Subject)
+	s.mu.Unlock()
+	if st == nil {
+		return delivered, nil, nil
+	}
+	ack, err := st.store(m)
+	if m.Reply != "" {
+		var resp []byte
+		if err != nil {
+			resp, _ = json.Marshal(map[string]interface{}{"error": map[string]interface{}{"code": 503, "description": err.Error()}})
+		} else {
+			resp, _ = json.Marshal(ack)
+		}
+		delivered += deliver(s.match(nil, m.Reply), &Msg{Subject: m.Reply, Data: resp})
+	} else {
+		delivered++
+	}
+	return delivered, ack, err
+}
+
+// deliver hands each target its own copy of m
+func deliver(targets []*Subscription, m *Msg) int {
+	n := 0
+	for _, sub := range targets {
+		if sub.deliver(m.copyFor(sub)) {
+			n++
+		}
+	}
+	return n
+}
+
+// Connections
+
+// Status is the state of a connection
+type Status int
+
+const (
+	DISCONNECTED Status = iota
+	CONNECTED
+	CLOSED
+	RECONNECTING
+	CONNECTING
+	DRAINING_SUBS
+	DRAINING_PUBS
+)
+
+func (s Status) String() string {
+	switch s {
+	case DISCONNECTED:
+		return "DISCONNECTED"
+	case CONNECTED:
+		return "CONNECTED"
+	case CLOSED:
+		return "CLOSED"
+	case RECONNECTING:
+		return "RECONNECTING"
+	case CONNECTING:
+		return "CONNECTING"
+	case DRAINING_SUBS:
+		return "DRAINING_SUBS"
+	case DRAINING_PUBS:
+		return "DRAINING_PUBS"
+	}
+	return "unknown status"
+}
+
+// MsgHandler processes messages delivered to an asynchronous subscription
+type MsgHandler func(msg *Msg)
+
+// ConnHandler is called on connection events such as Close
+type ConnHandler func(*Conn)
+
+// ConnErrHandler is called on connection events that carry an error
+type ConnErrHandler func(*Conn, error)
+
+// ErrHandler is called on asynchronous errors such as slow consumers
+type ErrHandler func(*Conn, *Subscription, error)
+
+// Options configure a connection
+type Options struct {
+	Url               string
+	Servers           []string
+	Name              string
+	NoEcho            bool
+	Timeout           time.Duration
+	DrainTimeout      time.Duration
+	ClosedCB          ConnHandler
+	DisconnectedErrCB ConnErrHandler
+	AsyncErrorCB      ErrHandler
+}
+
+// Option sets a connection option
+type Option func(*Options) error
+
+// GetDefaultOptions returns the options Connect starts from
+func GetDefaultOptions() Options {
+	return Options{
+		Url:          DefaultURL,
+		Timeout:      2 * time.Second,
+		DrainTimeout: DefaultDrainTimeout,
+	}
+}
+
+// Name names the connection
+func Name(name string) Option {
+	return func(o *Options) error {
+		o.Name = name
+		return nil
+	}
+}
+
+// NoEcho stops the connection receiving its own messages
+func NoEcho() Option {
+	return func(o *Options) error {
+		o.NoEcho = true
+		return nil
+	}
+}
+
+// Timeout sets the connect timeout
+func Timeout(t time.Duration) Option {
+	return func(o *Options) error {
+		o.Timeout = t
+		return nil
+	}
+}
+
+// DrainTimeout limits how long Drain waits for subscriptions
+func DrainTimeout(t time.Duration) Option {
+	return func(o *Options) error {
+		o.DrainTimeout = t
+		return nil
+	}
+}
+
+// ClosedHandler is called once the connection is closed
+func ClosedHandler(cb ConnHandler) Option {
+	return func(o *Options) error {
+		o.ClosedCB = cb
+		return nil
+	}
+}
+
+// DisconnectErrHandler is called when the server goes away
+func DisconnectErrHandler(cb ConnErrHandler) Option {
+	return func(o *Options) error {
+		o.DisconnectedErrCB = cb
+		return nil
+	}
+}
+
+// ErrorHandler is called on asynchronous errors
+func ErrorHandler(cb ErrHandler) Option {
+	return func(o *Options) error {
+		o.AsyncErrorCB = cb
+		return nil
+	}
+}
+
+// Connect connects to the server at url, a comma separated list of
+// server URLs
+func Connect(url string, options ...Option) (*Conn, error) {
+	opts := GetDefaultOptions()
+	if url != "" {
+		opts.Servers = strings.Split(url, ",")
+	}
+	for _, opt := range options {
+		if err := opt(&opts); err != nil {
+			return nil, err
+		}
+	}
+	return opts.Connect()
+}
+
+// Connect connects with these options
+func (o Options) Connect() (*Conn, error) {
+	urls := o.Servers
+	if len(urls) == 0 {
+		urls = []string{o.Url}
+	}
+	s, url, err := lookupServer(urls)
+	if err != nil {
+		return nil, err
+	}
+	nc := &Conn{
+		Opts:   o,
+		server: s,
+		url:    url,
+		status: CONNECTED,
+		subs:   make(map[*Subscription]struct{}),
+	}
+	s.addConn(nc)
+	return nc, nil
+}
+
+// Conn is a client connection to a server
+type Conn struct {
+	Opts Options
+
+	server *Server
+	url    string
+	mu     sync.Mutex
+	status Status
+	subs   map[*Subscription]struct{}
+}
+
+// Status returns the connection's state
+func (nc *Conn) Status() Status {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.status
+}
+
+// IsClosed reports whether the connection is closed
+func (nc *Conn) IsClosed() bool {
+	return nc.Status() == CLOSED
+}
+
+// IsConnected reports whether the connection is usable
+func (nc *Conn) IsConnected() bool {
+	return nc.Status() == CONNECTED
+}
+
+// IsDraining reports whether the connection is draining
+func (nc *Conn) IsDraining() bool {
+	status := nc.Status()
+	return status == DRAINING_SUBS || status == DRAINING_PUBS
+}
+
+// ConnectedUrl returns the URL the connection was made to
+func (nc *Conn) ConnectedUrl() string {
+	if nc.IsClosed() {
+		return ""
+	}
+	return nc.url
+}
+
+// MaxPayload returns the largest message data the server accepts
+func (nc *Conn) MaxPayload() int64 {
+	return int64(nc.server.MaxPayload)
+}
+
+// NumSubscriptions returns the connection's active subscriptions
+func (nc *Conn) NumSubscriptions() int {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return len(nc.subs)
+}
+
+// Publish publishes data to subject
+func (nc *Conn) Publish(subject string, data []byte) error {
+	return nc.PublishMsg(&Msg{Subject: subject, Data: data})
+}
+
+// PublishRequest publishes data to subject, asking for replies on reply
+func (nc *Conn) PublishRequest(subject, reply string, data []byte) error {
+	return nc.PublishMsg(&Msg{Subject: subject, Reply: reply, Data: data})
+}
+
+// PublishMsg publishes a message with its reply subject and headers
+func (nc *Conn) PublishMsg(m *Msg) error {
+	_, _, err := nc.publish(m)
+	return err
+}
+
+func (nc *Conn) publish(m *Msg) (int, *PubAck, error) {
+	if m == nil || !validSubject(m.Subject, false) {
+		return 0, nil, ErrBadSubject
+	}
+	if m.Reply != "" && !validSubject(m.Reply, false) {
+		return 0, nil, ErrBadSubject
+	}
+	if nc.IsClosed() {
+		return 0, nil, ErrConnectionClosed
+	}
+	if len(m.Data) > nc.server.MaxPayload {
+		return 0, nil, ErrMaxPayload
+	}
+	return nc.server.route(nc, m)
+}
+
+// Flush waits until the server has processed everything published.
+// Publishing is synchronous here, so it only checks the connection.
+func (nc *Conn) Flush() error {
+	if nc.IsClosed() {
+		return ErrConnectionClosed
+	}
+	return nil
+}
+
+// FlushTimeout is Flush with a timeout
+func (nc *Conn) FlushTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return ErrInvalidArg
+	}
+	return nc.Flush()
+}
+
+// Subscribe delivers messages on subject to cb, one at a time
+func (nc *Conn) Subscribe(subject string, cb MsgHandler) (*Subscription, error) {
+	if cb == nil {
+		return nil, ErrBadSubscription
+	}
+	return nc.subscribe(subject, "", cb, nil, asyncSub)
+}
+
+// SubscribeSync subscribes to subject for reading with NextMsg
+func (nc *Conn) SubscribeSync(subject string) (*Subscription, error) {
+	return nc.subscribe(subject, "", nil, nil, syncSub)
+}
+
+// ChanSubscribe delivers messages on subject to ch
+func (nc *Conn) ChanSubscribe(subject string, ch chan *Msg) (*Subscription, error) {
+	if ch == nil {
+		return nil, ErrBadSubscription
+	}
+	return nc.subscribe(subject, "", nil, ch, chanSub)
+}
+
+// QueueSubscribe joins queue group queue on subject. Each message goes
+// to one member of the group.
+func (nc *Conn) QueueSubscribe(subject, queue string, cb MsgHandler) (*Subscription, error) {
+	if cb == nil {
+		return nil, ErrBadSubscription
+	}
+	return nc.subscribe(subject, queue, cb, nil, asyncSub)
+}
+
+// QueueSubscribeSync joins a queue group for reading with NextMsg
+func (nc *Conn) QueueSubscribeSync(subject, queue string) (*Subscription, error) {
+	return nc.subscribe(subject, queue, nil, nil, syncSub)
+}
+
+// ChanQueueSubscribe joins a queue group, delivering to ch
+func (nc *Conn) ChanQueueSubscribe(subject, queue string, ch chan *Msg) (*Subscription, error) {
+	if ch == nil {
+		return nil, ErrBadSubscription
+	}
+	return nc.subscribe(subject, queue, nil, ch, chanSub)
+}
+
+func (nc *Conn) subscribe(subject, queue string, cb MsgHandler, ch chan *Msg, typ subType) (*Subscription, error) {
+	if !validSubject(subject, true) {
+		return nil, ErrBadSubject
+	}
+	if queue != "" && strings.ContainsAny(queue, " \t\r\n") {
+		return nil, ErrBadQueueName
+	}
+	sub := newSubscription(nc, subject, queue, cb, ch, typ)
+	if err := nc.addSub(sub); err != nil {
+		return nil, err
+	}
+	nc.server.addSub(sub)
+	return sub, nil
+}
+
+func (nc *Conn) addSub(sub *Subscription) error {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.status == CLOSED {
+		return ErrConnectionClosed
+	}
+	if nc.status == DRAINING_SUBS || nc.status == DRAINING_PUBS {
+		return ErrConnectionDraining
+	}
+	nc.subs[sub] = struct{}{}
+	return nil
+}
+
+func (nc *Conn) removeSub(sub *Subscription) {
+	nc.mu.Lock()
+	delete(nc.subs, sub)
+	nc.mu.Unlock()
+	nc.server.removeSub(sub)
+}
+
+// asyncError reports an error to the ErrorHandler, if any
+func (nc *Conn) asyncError(sub *Subscription, err error) {
+	if cb := nc.Opts.AsyncErrorCB; cb != nil {
+		go cb(nc, sub, err)
+	}
+}
+
+// NewRespInbox returns a unique subject for a reply
+func (nc *Conn) NewRespInbox() string {
+	return NewInbox()
+}
+
+// Request publishes data to subject and waits for the first reply
+func (nc *Conn) Request(subject string, data []byte, timeout time.Duration) (*Msg, error) {
+	return nc.RequestMsg(&Msg{Subject: subject, Data: data}, timeout)
+}
+
+// RequestMsg is Request for a message with headers
+func (nc *Conn) RequestMsg(msg *Msg, timeout time.Duration) (*Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := nc.request(ctx, msg)
+	if err == context.DeadlineExceeded {
+		return nil, ErrTimeout
+	}
+	return resp, err
+}
+
+// RequestWithContext is Request bounded by ctx, which needs a deadline
+func (nc *Conn) RequestWithContext(ctx context.Context, subject string, data []byte) (*Msg, error) {
+	return nc.RequestMsgWithContext(ctx, &Msg{Subject: subject, Data: data})
+}
+
+// RequestMsgWithContext is RequestMsg bounded by ctx, which needs a
+// deadline
+func (nc *Conn) RequestMsgWithContext(ctx context.Context, msg *Msg) (*Msg, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, ErrNoDeadlineContext
+	}
+	return nc.request(ctx, msg)
+}
+
+func (nc *Conn) request(ctx context.Context, msg *Msg) (*Msg, error) {
+	inbox := nc.NewRespInbox()
+	sub, err := nc.subscribe(inbox, "", nil, nil, syncSub)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+	sub.AutoUnsubscribe(1)
+
+	req := *msg
+	req.Reply = inbox
+	n, _, err := nc.publish(&req)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNoResponders
+	}
+	return sub.NextMsgWithContext(ctx)
+}
+
+// Drain stops new messages reaching the connection's subscriptions, lets
+// their handlers finish what is pending, then closes the connection.
+// It returns at once; ClosedHandler reports when it is done.
+func (nc *Conn) Drain() error {
+	nc.mu.Lock()
+	switch nc.status {
+	case CLOSED:
+		nc.mu.Unlock()
+		return ErrConnectionClosed
+	case DRAINING_SUBS, DRAINING_PUBS:
+		nc.mu.Unlock()
+		return ErrConnectionDraining
+	}
+	nc.status = DRAINING_SUBS
+	subs := nc.subList()
+	nc.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Drain()
+	}
+	go func() {
+		timeout := time.NewTimer(nc.Opts.DrainTimeout)
+		defer timeout.Stop()
+		for _, sub := range subs {
+			if sub.finished == nil {
+				continue
+			}
+			select {
+			case <-sub.finished:
+			case <-timeout.C:
+				nc.asyncError(nil, ErrDrainTimeout)
+				nc.close(nil)
+				return
+			}
+		}
+		nc.mu.Lock()
+		nc.status = DRAINING_PUBS
+		nc.mu.Unlock()
+		nc.close(nil)
+	}()
+	return nil
+}
+
+func (nc *Conn) subList() []*Subscription {
+	subs := make([]*Subscription, 0, len(nc.subs))
+	for sub := range nc.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// Close closes the connection and its subscriptions. Pending messages
+// are dropped.
+func (nc *Conn) Close() {
+	nc.close(nil)
+}
+
+func (nc *Conn) close(disconnectErr error) {
+	nc.mu.Lock()
+	if nc.status == CLOSED {
+		nc.mu.Unlock()
+		return
+	}
+	nc.status = CLOSED
+	subs := nc.subList()
+	nc.subs = make(map[*Subscription]struct{})
+	nc.mu.Unlock()
+
+	for _, sub := range subs {
+		if sub.stop(true) {
+			nc.server.removeSub(sub)
+		}
+		sub.releaseConsumer(false)
+	}
+	nc.server.removeConn(nc)
+
+	disconnected, closed := nc.Opts.DisconnectedErrCB, nc.Opts.ClosedCB
+	if (disconnectErr != nil && disconnected != nil) || closed != nil {
+		go func() {
+			if disconnectErr != nil && disconnected != nil {
+				disconnected(nc, disconnectErr)
+			}
+			if closed != nil {
+				closed(nc)
+			}
+		}()
+	}
+}
+
+// Subscriptions
+
+type subType int
+
+const (
+	asyncSub subType = iota
+	syncSub
+	chanSub
+	pullSub
+)
+
+// Subscription is interest in a subject, asynchronous (with a handler),
+// synchronous (read with NextMsg), channel based or a JetStream pull
+// subscription
+type Subscription struct {
+	Subject string
+	Queue   string
+
+	conn *Conn
+	typ  subType
+	cb   MsgHandler
+	mch  chan *Msg
+	jsi  *jsSub
+
+	mu           sync.Mutex
+	pending      []*Msg
+	pendingBytes int
+	msgsLimit    int
+	bytesLimit   int
+	ready        chan struct{} // signalled when a message is queued
+	done         chan struct{} // closed when no more messages will be queued
+	finished     chan struct{} // closed when an async handler returns for good
+	closed       bool
+	maxReached   bool
+	max          uint64
+	delivered    uint64
+	dropped      int
+	slow         bool
+}
+
+func newSubscription(nc *Conn, subject, queue string, cb MsgHandler, ch chan *Msg, typ subType) *Subscription {
+	sub := &Subscription{
+		Subject:    subject,
+		Queue:      queue,
+		conn:       nc,
+		typ:        typ,
+		cb:         cb,
+		mch:        ch,
+		msgsLimit:  DefaultSubPendingMsgsLimit,
+		bytesLimit: DefaultSubPendingBytesLimit,
+		ready:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if typ == asyncSub {
+		sub.finished = make(chan struct{})
+		go sub.waitForMsgs()
+	}
+	return sub
+}
+
+// waitForMsgs runs an async subscription's handler on each message in turn
+func (sub *Subscription) waitForMsgs() {
+	defer close(sub.finished)
+	for {
+		m, err := sub.next(nil)
+		if err != nil {
+			return
+		}
+		sub.cb(m)
+	}
+}
+
+// deliver queues a message, or drops it if the subscription is too far
+// behind. It reports whether the subscription took the message.
+func (sub *Subscription) deliver(m *Msg) bool {
+	sub.mu.Lock()
+	if sub.closed {
+		sub.mu.Unlock()
+		return false
+	}
+	if sub.typ == chanSub {
+		select {
+		case sub.mch <- m:
+		default:
+			return sub.dropLocked()
+		}
+	} else {
+		if (sub.msgsLimit > 0 && len(sub.pending) >= sub.msgsLimit) ||
+			(sub.bytesLimit > 0 && sub.pendingBytes+len(m.Data) > sub.bytesLimit) {
+			return sub.dropLocked()
+		}
+		sub.pending = append(sub.pending, m)
+		sub.pendingBytes += len(m.Data)
+		sub.signal()
+	}
+	sub.delivered++
+	last := sub.max > 0 && sub.delivered >= sub.max
+	if last {
+		sub.maxReached = true
+		sub.closeLocked(false)
+	}
+	sub.mu.Unlock()
+	if last {
+		sub.conn.removeSub(sub)
+	}
+	return true
+}
+
+// dropLocked records a dropped message and unlocks. The ErrorHandler
+// hears about each run of drops once.
+func (sub *Subscription) dropLocked() bool {
+	sub.dropped++
+	report := !sub.slow
+	sub.slow = true
+	sub.mu.Unlock()
+	if report {
+		sub.conn.asyncError(sub, ErrSlowConsumer)
+	}
+	return false
+}
+
+func (sub *Subscription) signal() {
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next pops the next pending message, waiting until one arrives, the
+// subscription closes or wait fires
+func (sub *Subscription) next(wait <-chan struct{}) (*Msg, error) {
+	for {
+		sub.mu.Lock()
+		if len(sub.pending) > 0 {
+			m := sub.pending[0]
+			sub.pending[0] = nil
+			sub.pending = sub.pending[1:]
+			sub.pendingBytes -= len(m.Data)
+			sub.slow = false
+			if len(sub.pending) > 0 {
+				sub.signal()
+			}
+			sub.mu.Unlock()
+			return m, nil
+		}
+		if sub.closed {
+			err := sub.closedErrLocked()
+			sub.mu.Unlock()
+			return nil, err
+		}
+		sub.mu.Unlock()
+		select {
+		case <-sub.ready:
+		case <-sub.done:
+		case <-wait:
+			return nil, ErrTimeout
+		}
+	}
+}
+
+func (sub *Subscription) closedErrLocked() error {
+	if sub.maxReached {
+		return ErrMaxMessages
+	}
+	if sub.conn.IsClosed() {
+		return ErrConnectionClosed
+	}
+	return ErrBadSubscription
+}
+
+// closeLocked stops the subscription accepting messages, optionally
+// dropping those already pending
+func (sub *Subscription) closeLocked(discard bool) {
+	sub.closed = true
+	if discard {
+		sub.pending = nil
+		sub.pendingBytes = 0
+	}
+	close(sub.done)
+}
+
+// stop closes the subscription, reporting whether it was still open
+func (sub *Subscription) stop(discard bool) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		if discard {
+			sub.pending = nil
+			sub.pendingBytes = 0
+		}
+		return false
+	}
+	sub.closeLocked(discard)
+	return true
+}
+
+// NextMsg returns the next message of a synchronous subscription,
+// waiting up to timeout
+func (sub *Subscription) NextMsg(timeout time.Duration) (*Msg, error) {
+	if err := sub.checkSync(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return sub.next(ctx.Done())
+}
+
+// NextMsgWithContext is NextMsg bounded by ctx
+func (sub *Subscription) NextMsgWithContext(ctx context.Context) (*Msg, error) {
+	if err := sub.checkSync(); err != nil {
+		return nil, err
+	}
+	m, err := sub.next(ctx.Done())
+	if err == ErrTimeout {
+		return nil, ctx.Err()
+	}
+	return m, err
+}
+
+func (sub *Subscription) checkSync() error {
+	if sub == nil || sub.conn == nil {
+		return ErrBadSubscription
+	}
+	if sub.typ != syncSub {
+		return ErrSyncSubRequired
+	}
+	return nil
+}
+
+// IsValid reports whether the subscription is still active
+func (sub *Subscription) IsValid() bool {
+	if sub == nil {
+		return false
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return !sub.closed
+}
+
+// Unsubscribe removes interest in the subject and drops pending
+// messages. A JetStream consumer the subscription created is deleted.
+func (sub *Subscription) Unsubscribe() error {
+	if sub == nil || sub.conn == nil {
+		return ErrBadSubscription
+	}
+	if sub.conn.IsClosed() {
+		return ErrConnectionClosed
+	}
+	if !sub.stop(true) {
+		return ErrBadSubscription
+	}
+	sub.conn.removeSub(sub)
+	sub.releaseConsumer(true)
+	return nil
+}
+
+// Drain removes interest in the subject but lets the handler finish the
+// messages already pending
+func (sub *Subscription) Drain() error {
+	if sub == nil || sub.conn == nil {
+		return ErrBadSubscription
+	}
+	if sub.conn.IsClosed() {
+		return ErrConnectionClosed
+	}
+	if sub.stop(false) {
+		sub.conn.server.removeSub(sub)
+	}
+	go func() {
+		if sub.finished != nil {
+			<-sub.finished
+		}
+		sub.conn.mu.Lock()
+		delete(sub.conn.subs, sub)
+		sub.conn.mu.Unlock()
+		sub.releaseConsumer(true)
+	}()
+	return nil
+}
+
+// AutoUnsubscribe removes the subscription after max messages
+func (sub *Subscription) AutoUnsubscribe(max int) error {
+	if sub == nil || sub.conn == nil || max <= 0 {
+		return ErrBadSubscription
+	}
+	sub.mu.Lock()
+	if sub.closed {
+		sub.mu.Unlock()
+		return ErrBadSubscription
+	}
+	sub.max = uint64(max)
+	reached := sub.delivered >= sub.max
+	if reached {
+		sub.maxReached = true
+		sub.closeLocked(false)
+	}
+	sub.mu.Unlock()
+	if reached {
+		sub.conn.removeSub(sub)
+	}
+	return nil
+}
+
+// Pending returns the number and size of queued messages
+func (sub *Subscription) Pending() (int, int, error) {
+	if sub == nil || sub.conn == nil {
+		return -1, -1, ErrBadSubscription
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed && len(sub.pending) == 0 {
+		return -1, -1, ErrBadSubscription
+	}
+	if sub.typ == chanSub {
+		return len(sub.mch), 0, nil
+	}
+	return len(sub.pending), sub.pendingBytes, nil
+}
+
+// SetPendingLimits sets how many messages and bytes may queue before
+// messages are dropped. -1 removes a limit.
+func (sub *Subscription) SetPendingLimits(msgLimit, bytesLimit int) error {
+	if sub == nil || sub.conn == nil {
+		return ErrBadSubscription
+	}
+	if sub.typ == chanSub {
+		return ErrTypeSubscription
+	}
+	if msgLimit == 0 || bytesLimit == 0 {
+		return ErrInvalidArg
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.msgsLimit, sub.bytesLimit = msgLimit, bytesLimit
+	return nil
+}
+
+// Dropped returns the number of messages dropped for being too slow
+func (sub *Subscription) Dropped() (int, error) {
+	if sub == nil || sub.conn == nil {
+		return -1, ErrBadSubscription
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.dropped, nil
+}
+
+// Delivered returns the number of messages delivered
+func (sub *Subscription) Delivered() (int64, error) {
+	if sub == nil || sub.conn == nil {
+		return -1, ErrBadSubscription
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return int64(sub.delivered), nil
+}
+
+// JetStream
+
+// RetentionPolicy decides when a stream discards messages
+type RetentionPolicy int
+
+const (
+	// LimitsPolicy keeps messages until MaxMsgs or MaxAge is reached
+	LimitsPolicy RetentionPolicy = iota
+	// InterestPolicy is accepted but behaves as LimitsPolicy
+	InterestPolicy
+	// WorkQueuePolicy removes each message once a consumer acks it
+	WorkQueuePolicy
+)
+
+// DiscardPolicy decides what happens when a stream is full
+type DiscardPolicy int
+
+const (
+	DiscardOld DiscardPolicy = iota
+	DiscardNew
+)
+
+// StorageType is accepted for compatibility; streams live in memory
+type StorageType int
+
+const (
+	FileStorage StorageType = iota
+	MemoryStorage
+)
+
+// StreamConfig configures a stream
+type StreamConfig struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Subjects    []string        `json:"subjects,omitempty"`
+	Retention   RetentionPolicy `json:"retention"`
+	MaxMsgs     int64           `json:"max_msgs"`
+	MaxAge      time.Duration   `json:"max_age"`
+	Discard     DiscardPolicy   `json:"discard"`
+	Storage     StorageType     `json:"storage"`
+	Replicas    int             `json:"num_replicas"`
+	// Duplicates is how long a Nats-Msg-Id is remembered
+	Duplicates time.Duration `json:"duplicate_window,omitempty"`
+}
+
+// StreamState describes the messages in a stream
+type StreamState struct {
+	Msgs      uint64 `json:"messages"`
+	Bytes     uint64 `json:"bytes"`
+	FirstSeq  uint64 `json:"first_seq"`
+	LastSeq   uint64 `json:"last_seq"`
+	Consumers int    `json:"consumer_count"`
+}
+
+// StreamInfo describes a stream
+type StreamInfo struct {
+	Config  StreamConfig `json:"config"`
+	Created time.Time    `json:"created"`
+	State   StreamState  `json:"state"`
+}
+
+// PubAck acknowledges a message stored in a stream
+type PubAck struct {
+	Stream    string `json:"stream"`
+	Sequence  uint64 `json:"seq"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+}
+
+// RawStreamMsg is a message read directly from a stream
+type RawStreamMsg struct {
+	Subject  string
+	Sequence uint64
+	Header   Header
+	Data     []byte
+	Time     time.Time
+}
+
+// DeliverPolicy decides where a consumer starts
+type DeliverPolicy int
+
+const (
+	DeliverAllPolicy DeliverPolicy = iota
+	DeliverLastPolicy
+	DeliverNewPolicy
+	DeliverByStartSequencePolicy
+	DeliverByStartTimePolicy
+)
+
+// AckPolicy decides which messages need acknowledging
+type AckPolicy int
+
+const (
+	AckNonePolicy AckPolicy = iota
+	// AckAllPolicy acks a message and every one before it
+	AckAllPolicy
+	AckExplicitPolicy
+)
+
+// ConsumerConfig configures a consumer. A consumer with a DeliverSubject
+// pushes messages to it; without one, messages are pulled with Fetch.
+type ConsumerConfig struct {
+	Durable        string        `json:"durable_name,omitempty"`
+	Description    string        `json:"description,omitempty"`
+	DeliverSubject string        `json:"deliver_subject,omitempty"`
+	DeliverGroup   string        `json:"deliver_group,omitempty"`
+	DeliverPolicy  DeliverPolicy `json:"deliver_policy"`
+	OptStartSeq    uint64        `json:"opt_start_seq,omitempty"`
+	OptStartTime   *time.Time    `json:"opt_start_time,omitempty"`
+	AckPolicy      AckPolicy     `json:"ack_policy"`
+	AckWait        time.Duration `json:"ack_wait,omitempty"`
+	MaxDeliver     int           `json:"max_deliver,omitempty"`
+	FilterSubject  string        `json:"filter_subject,omitempty"`
+	MaxAckPending  int           `json:"max_ack_pending,omitempty"`
+}
+
+// SequenceInfo is a consumer's position in consumer and stream sequences
+type SequenceInfo struct {
+	Consumer uint64 `json:"consumer_seq"`
+	Stream   uint64 `json:"stream_seq"`
+}
+
+// ConsumerInfo describes a consumer
+type ConsumerInfo struct {
+	Stream         string         `json:"stream_name"`
+	Name           string         `json:"name"`
+	Created        time.Time      `json:"created"`
+	Config         ConsumerConfig `json:"config"`
+	Delivered      SequenceInfo   `json:"delivered"`
+	AckFloor       SequenceInfo   `json:"ack_floor"`
+	NumAckPending  int            `json:"num_ack_pending"`
+	NumRedelivered int            `json:"num_redelivered"`
+	NumPending     uint64         `json:"num_pending"`
+}
+
+// SequencePair is a message's consumer and stream sequence
+type SequencePair struct {
+	Consumer uint64 `json:"consumer_seq"`
+	Stream   uint64 `json:"stream_seq"`
+}
+
+// MsgMetadata describes a message delivered by a consumer
+type MsgMetadata struct {
+	Sequence     SequencePair
+	NumDelivered uint64
+	NumPending   uint64
+	Timestamp    time.Time
+	Stream       string
+	Consumer     string
+	Domain       string
+}
+
+type storedMsg struct {
+	seq     uint64
+	subject string
+	header  Header
+	data    []byte
+	time    time.Time
+}
+
+type dedupeEntry struct {
+	seq  uint64
+	time time.Time
+}
+
+// stream stores the messages published to its subjects
+type stream struct {
+	server  *Server
+	mu      sync.Mutex
+	config  StreamConfig
+	created time.Time
+	msgs    []*storedMsg // ascending by seq
+	bytes   uint64
+	lastSeq uint64
+	dedupe  map[string]dedupeEntry
+	// consumers by name
+	consumers map[string]*consumer
+	changed   chan struct{} // closed when messages, acks or interest change
+	deleted   bool
+}
+
+// notify wakes everything waiting on the stream. Callers hold st.mu.
+func (st *stream) notify() {
+	close(st.changed)
+	st.changed = make(chan struct{})
+}
+
+// streamFor returns the stream capturing subject. Callers hold s.mu.
+func (s *Server) streamFor(subject string) *stream {
+	for _, st := range s.streams {
+		for _, pattern := range st.config.Subjects {
+			if subjectMatches(pattern, subject) {
+				return st
+			}
+		}
+	}
+	return nil
+}
+
+// streamList returns the streams in name order. Callers hold s.mu.
+func (s *Server) streamList() []*stream {
+	streams := make([]*stream, 0, len(s.streams))
+	for _, st := range s.streams {
+		streams = append(streams, st)
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].config.Name < streams[j].config.Name })
+	return streams
+}
+
+func (s *Server) stream(name string) (*stream, error) {
+	if name == "" {
+		return nil, ErrStreamNameRequired
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.streams[name]
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
+	return st, nil
+}
+
+// expire drops messages older than MaxAge. Callers hold st.mu.
+func (st *stream) expire(now time.Time) {
+	if st.config.MaxAge <= 0 {
+		return
+	}
+	i := 0
+	for i < len(st.msgs) && now.Sub(st.msgs[i].time) > st.config.MaxAge {
+		st.bytes -= uint64(len(st.msgs[i].data))
+		i++
+	}
+	st.msgs = st.msgs[i:]
+}
+
+// store appends a published message
+func (st *stream) store(m *Msg) (*PubAck, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.deleted {
+		return nil, ErrStreamNotFound
+	}
+	now := time.Now()
+	st.expire(now)
+
+	id := m.Header.Get(MsgIdHdr)
+	if id != "" {
+		for key, entry := range st.dedupe {
+			if now.Sub(entry.time) > st.config.Duplicates {
+				delete(st.dedupe, key)
+			}
+		}
+		if entry, ok := st.dedupe[id]; ok {
+			return &PubAck{Stream: st.config.Name, Sequence: entry.seq, Duplicate: true}, nil
+		}
+	}
+	if st.config.MaxMsgs > 0 && int64(len(st.msgs)) >= st.config.MaxMsgs {
+		if st.config.Discard == DiscardNew {
+			return nil, ErrMaxMsgsExceeded
+		}
+		st.bytes -= uint64(len(st.msgs[0].data))
+		st.msgs = st.msgs[1:]
+	}
+
+	st.lastSeq++
+	st.msgs = append(st.msgs, &storedMsg{
+		seq:     st.lastSeq,
+		subject: m.Subject,
+		header:  m.Header.clone(),
+		data:    append([]byte(nil), m.Data...),
+		time:    now,
+	})
+	st.bytes += uint64(len(m.Data))
+	if id != "" {
+		st.dedupe[id] = dedupeEntry{seq: st.lastSeq, time: now}
+	}
+	st.notify()
+	return &PubAck{Stream: st.config.Name, Sequence: st.lastSeq}, nil
+}
+
+// index returns the position of the first message at or after seq.
+// Callers hold st.mu.
+func (st *stream) index(seq uint64) int {
+	return sort.Search(len(st.msgs), func(i int) bool { return st.msgs[i].seq >= seq })
+}
+
+// find returns the message with sequence seq, if still stored
+func (st *stream) find(seq uint64) *storedMsg {
+	if i := st.index(seq); i < len(st.msgs) && st.msgs[i].seq == seq {
+		return st.msgs[i]
+	}
+	return nil
+}
+
+// next returns the first message at or after seq matching filter
+func (st *stream) next(seq uint64, filter string) *storedMsg {
+	for i := st.index(seq); i < len(st.msgs); i++ {
+		if filter == "" || subjectMatches(filter, st.msgs[i].subject) {
+			return st.msgs[i]
+		}
+	}
+	return nil
+}
+
+// count returns the number of messages at or after seq matching filter
+func (st *stream) count(seq uint64, filter string) uint64 {
+	var n uint64
+	for i := st.index(seq); i < len(st.msgs); i++ {
+		if filter == "" || subjectMatches(filter, st.msgs[i].subject) {
+			n++
+		}
+	}
+	return n
+}
+
+// remove deletes a message, reporting whether it was there
+func (st *stream) remove(seq uint64) bool {
+	i := st.index(seq)
+	if i >= len(st.msgs) || st.msgs[i].seq != seq {
+		return false
+	}
+	st.bytes -= uint64(len(st.msgs[i].data))
+	st.msgs = append(st.msgs[:i:i], st.msgs[i+1:]...)
+	return true
+}
+
+func (st *stream) info() *StreamInfo {
+	st.expire(time.Now())
+	state := StreamState{
+		Msgs:      uint64(len(st.msgs)),
+		Bytes:     st.bytes,
+		FirstSeq:  st.lastSeq + 1,
+		LastSeq:   st.lastSeq,
+		Consumers: len(st.consumers),
+	}
+	if len(st.msgs) > 0 {
+		state.FirstSeq = st.msgs[0].seq
+	}
+	return &StreamInfo{Config: st.config, Created: st.created, State: state}
+}
+
+// delete removes the stream's consumers and wakes their waiters
+func (st *stream) delete() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.deleted = true
+	for name, c := range st.consumers {
+		c.stop()
+		delete(st.consumers, name)
+	}
+	st.notify()
+}
+
+type pendingMsg struct {
+	dseq       uint64
+	deliveries int
+	deadline   time.Time
+}
+
+// consumer tracks delivery and acknowledgement of a stream's messages.
+// Its state is guarded by the stream's lock.
+type consumer struct {
+	stream  *stream
+	name    string
+	config  ConsumerConfig
+	created time.Time
+
+	next     uint64 // next stream sequence to deliver
+	dseq     uint64 // last consumer sequence used
+	pending  map[uint64]*pendingMsg
+	ackFloor SequencePair
+	bound    int // subscriptions using the consumer
+	quit     chan struct{}
+	deleted  bool
+}
+
+// stop marks a deleted consumer. Callers hold st.mu.
+func (c *consumer) stop() {
+	if !c.deleted {
+		c.deleted = true
+		close(c.quit)
+	}
+}
+
+func validConsumerName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, ".*> \t\r\n")
+}
+
+// newConsumer adds a consumer. Callers hold st.mu.
+func (st *stream) newConsumer(cfg ConsumerConfig) (*consumer, error) {
+	if st.deleted {
+		return nil, ErrStreamNotFound
+	}
+	if cfg.Durable != "" && !validConsumerName(cfg.Durable) {
+		return nil, ErrInvalidConsumerName
+	}
+	if cfg.FilterSubject != "" {
+		covered := false
+		for _, pattern := range st.config.Subjects {
+			if subjectIsSubset(cfg.FilterSubject, pattern) {
+				covered = true
+			}
+		}
+		if !covered {
+			return nil, fmt.Errorf("nats: consumer filter subject %q is not a subset of the stream subjects", cfg.FilterSubject)
+		}
+	}
+	cfg = consumerDefaults(cfg)
+	name := cfg.Durable
+	if name == "" {
+		name = nuid(8)
+	}
+
+	c := &consumer{
+		stream:  st,
+		name:    name,
+		config:  cfg,
+		created: time.Now(),
+		next:    st.lastSeq + 1,
+		pending: make(map[uint64]*pendingMsg),
+		quit:    make(chan struct{}),
+	}
+	switch cfg.DeliverPolicy {
+	case DeliverAllPolicy:
+		c.next = 1
+	case DeliverLastPolicy:
+		for i := len(st.msgs) - 1; i >= 0; i-- {
+			if cfg.FilterSubject == "" || subjectMatches(cfg.FilterSubject, st.msgs[i].subject) {
+				c.next = st.msgs[i].seq
+				break
+			}
+		}
+	case DeliverByStartSequencePolicy:
+		if cfg.OptStartSeq == 0 {
+			return nil, errors.New("nats: consumer deliver policy requires a start sequence")
+		}
+		c.next = cfg.OptStartSeq
+	case DeliverByStartTimePolicy:
+		if cfg.OptStartTime == nil {
+			return nil, errors.New("nats: consumer deliver policy requires a start time")
+		}
+		for _, sm := range st.msgs {
+			if !sm.time.Before(*cfg.OptStartTime) {
+				c.next = sm.seq
+				break
+			}
+		}
+	}
+	c.ackFloor = SequencePair{Stream: c.next - 1}
+	st.consumers[name] = c
+	if cfg.DeliverSubject != "" {
+		go c.run()
+	}
+	return c, nil
+}
+
+// consumerDefaults fills in the server's defaults
+func consumerDefaults(cfg ConsumerConfig) ConsumerConfig {
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = 30 * time.Second
+	}
+	if cfg.MaxDeliver == 0 {
+		cfg.MaxDeliver = -1
+	}
+	if cfg.MaxAckPending == 0 && cfg.AckPolicy != AckNonePolicy {
+		cfg.MaxAckPending = 1000
+	}
+	return cfg
+}
+
+// collect picks the messages to deliver now: due redeliveries first,
+// then new messages, up to max (0 for no limit) and MaxAckPending. It
+// also returns when the next redelivery is due. Callers hold st.mu.
+func (c *consumer) collect(now time.Time, max int) ([]*Msg, time.Time) {
+	st := c.stream
+	st.expire(now)
+	var msgs []*Msg
+	full := func() bool { return max > 0 && len(msgs) >= max }
+	numPending := st.count(c.next, c.config.FilterSubject)
+
+	var due []uint64
+	for seq, p := range c.pending {
+		if !p.deadline.After(now) {
+			due = append(due, seq)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+	for _, seq := range due {
+		if full() {
+			break
+		}
+		p := c.pending[seq]
+		sm := st.find(seq)
+		if sm == nil || (c.config.MaxDeliver > 0 && p.deliveries >= c.config.MaxDeliver) {
+			delete(c.pending, seq)
+			continue
+		}
+		c.dseq++
+		p.dseq = c.dseq
+		p.deliveries++
+		p.deadline = now.Add(c.config.AckWait)
+		msgs = append(msgs, c.message(sm, p.deliveries, numPending))
+	}
+
+	for !full() && (c.config.MaxAckPending <= 0 || len(c.pending) < c.config.MaxAckPending) {
+		sm := st.next(c.next, c.config.FilterSubject)
+		if sm == nil {
+			break
+		}
+		c.next = sm.seq + 1
+		c.dseq++
+		numPending--
+		if c.config.AckPolicy != AckNonePolicy {
+			c.pending[sm.seq] = &pendingMsg{dseq: c.dseq, deliveries: 1, deadline: now.Add(c.config.AckWait)}
+		}
+		msgs = append(msgs, c.message(sm, 1, numPending))
+	}
+	c.updateAckFloor()
+
+	var wake time.Time
+	for _, p := range c.pending {
+		if wake.IsZero() || p.deadline.Before(wake) {
+			wake = p.deadline
+		}
+	}
+	return msgs, wake
+}
+
+// message builds the delivery of a stored message
+func (c *consumer) message(sm *storedMsg, deliveries int, numPending uint64) *Msg {
+	meta := MsgMetadata{
+		Sequence:     SequencePair{Consumer: c.dseq, Stream: sm.seq},
+		NumDelivered: uint64(deliveries),
+		NumPending:   numPending,
+		Timestamp:    sm.time,
+		Stream:       c.stream.config.Name,
+		Consumer:     c.name,
+	}
+	return &Msg{
+		Subject: sm.subject,
+		Reply: fmt.Sprintf("$JS.ACK.%s.%s.%d.%d.%d.%d.%d", meta.Stream, meta.Consumer,
+			deliveries, sm.seq, c.dseq, sm.time.UnixNano(), numPending),
+		Header: sm.header.clone(),
+		Data:   append([]byte(nil), sm.data...),
+		ack:    &jsAck{consumer: c, meta: meta},
+	}
+}
+
+// updateAckFloor moves the ack floor below the oldest unacked message.
+// Callers hold st.mu.
+func (c *consumer) updateAckFloor() {
+	floor := SequencePair{Consumer: c.dseq, Stream: c.next - 1}
+	for seq, p := range c.pending {
+		if seq-1 < floor.Stream {
+			floor = SequencePair{Consumer: p.dseq - 1, Stream: seq - 1}
+		}
+	}
+	c.ackFloor = floor
+}
+
+// run pushes messages to the deliver subject while someone listens
+func (c *consumer) run() {
+	st := c.stream
+	server := st.server
+	for {
+		st.mu.Lock()
+		if c.deleted {
+			st.mu.Unlock()
+			return
+		}
+		changed := st.changed
+		st.mu.Unlock()
+
+		var msgs []*Msg
+		var wake time.Time
+		if server.hasInterest(c.config.DeliverSubject) {
+			st.mu.Lock()
+			if !c.deleted {
+				msgs, wake = c.collect(time.Now(), 0)
+			}
+			st.mu.Unlock()
+		}
+		for _, m := range msgs {
+			deliver(server.match(nil, c.config.DeliverSubject), m)
+		}
+		if len(msgs) > 0 {
+			continue
+		}
+
+		var timer <-chan time.Time
+		var t *time.Timer
+		if !wake.IsZero() {
+			t = time.NewTimer(time.Until(wake))
+			timer = t.C
+		}
+		select {
+		case <-changed:
+		case <-timer:
+		case <-c.quit:
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+type ackKind int
+
+const (
+	ackAck ackKind = iota
+	ackNak
+	ackProgress
+	ackTerm
+)
+
+// acknowledge applies an ack, nak, progress or term to a delivery
+func (c *consumer) acknowledge(seq SequencePair, kind ackKind, delay time.Duration) {
+	st := c.stream
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if c.deleted {
+		return
+	}
+	p := c.pending[seq.Stream]
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	switch kind {
+	case ackAck:
+		acked := []uint64{seq.Stream}
+		if c.config.AckPolicy == AckAllPolicy {
+			for s := range c.pending {
+				if s < seq.Stream {
+					acked = append(acked, s)
+				}
+			}
+		}
+		for _, s := range acked {
+			delete(c.pending, s)
+			if st.config.Retention == WorkQueuePolicy {
+				st.remove(s)
+			}
+		}
+	case ackNak:
+		p.deadline = now.Add(delay)
+	case ackProgress:
+		p.deadline = now.Add(c.config.AckWait)
+	case ackTerm:
+		delete(c.pending, seq.Stream)
+	}
+	c.updateAckFloor()
+	st.notify()
+}
+
+func (c *consumer) info() *ConsumerInfo {
+	redelivered := 0
+	for _, p := range c.pending {
+		if p.deliveries > 1 {
+			redelivered++
+		}
+	}
+	return &ConsumerInfo{
+		Stream:         c.stream.config.Name,
+		Name:           c.name,
+		Created:        c.created,
+		Config:         c.config,
+		Delivered:      SequenceInfo{Consumer: c.dseq, Stream: c.next - 1},
+		AckFloor:       SequenceInfo{Consumer: c.ackFloor.Consumer, Stream: c.ackFloor.Stream},
+		NumAckPending:  len(c.pending),
+		NumRedelivered: redelivered,
+		NumPending:     c.stream.count(c.next, c.config.FilterSubject),
+	}
+}
+
+// jsAck is the acknowledgement state of one delivery
+type jsAck struct {
+	consumer *consumer
+	meta     MsgMetadata
+
+	mu    sync.Mutex
+	acked bool
+}
+
+func (a *jsAck) isAcked() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.acked
+}
+
+func (m *Msg) reply(kind ackKind, delay time.Duration) error {
+	if m.ack == nil {
+		return ErrNotJSMessage
+	}
+	m.ack.mu.Lock()
+	if m.ack.acked {
+		m.ack.mu.Unlock()
+		return ErrMsgAlreadyAckd
+	}
+	if kind != ackProgress {
+		m.ack.acked = true
+	}
+	m.ack.mu.Unlock()
+	m.ack.consumer.acknowledge(m.ack.meta.Sequence, kind, delay)
+	return nil
+}
+
+// AckOpt configures an acknowledgement
+type AckOpt interface {
+	configureAck(*ackOpts) error
+}
+
+type ackOpts struct {
+	ctx context.Context
+}
+
+// Ack acknowledges a JetStream message
+func (m *Msg) Ack(opts ...AckOpt) error {
+	return m.reply(ackAck, 0)
+}
+
+// AckSync acknowledges a JetStream message and waits for the server.
+// Acks are applied at once here, so it is the same as Ack.
+func (m *Msg) AckSync(opts ...AckOpt) error {
+	return m.reply(ackAck, 0)
+}
+
+// Nak asks for the message to be redelivered now
+func (m *Msg) Nak(opts ...AckOpt) error {
+	return m.reply(ackNak, 0)
+}
+
+// NakWithDelay asks for the message to be redelivered after delay
+func (m *Msg) NakWithDelay(delay time.Duration, opts ...AckOpt) error {
+	return m.reply(ackNak, delay)
+}
+
+// InProgress resets the ack wait of a message still being worked on
+func (m *Msg) InProgress(opts ...AckOpt) error {
+	return m.reply(ackProgress, 0)
+}
+
+// Term stops the message being redelivered
+func (m *Msg) Term(opts ...AckOpt) error {
+	return m.reply(ackTerm, 0)
+}
+
+// Metadata returns the stream, consumer and delivery details of a
+// JetStream message
+func (m *Msg) Metadata() (*MsgMetadata, error) {
+	if m.ack == nil {
+		return nil, ErrNotJSMessage
+	}
+	meta := m.ack.meta
+	return &meta, nil
+}
+
+// JetStream options
+
+// MsgIdHdr carries the ID a stream uses to drop duplicate publishes
+const MsgIdHdr = "Nats-Msg-Id"
+
+// JSOpt configures a JetStream context
+type JSOpt interface {
+	configureJSContext(*jsOpts) error
+}
+
+type jsOpts struct {
+	wait time.Duration
+}
+
+// PubOpt configures a JetStream publish
+type PubOpt interface {
+	configurePublish(*pubOpts) error
+}
+
+type pubOpts struct {
+	id     string
+	stream string
+}
+
+type pubOptFn func(*pubOpts) error
+
+func (f pubOptFn) configurePublish(o *pubOpts) error { return f(o) }
+
+// MsgId sets the ID used to detect duplicate publishes
+func MsgId(id string) PubOpt {
+	return pubOptFn(func(o *pubOpts) error {
+		o.id = id
+		return nil
+	})
+}
+
+// ExpectStream fails the publish unless it is stored in stream
+func ExpectStream(stream string) PubOpt {
+	return pubOptFn(func(o *pubOpts) error {
+		o.stream = stream
+		return nil
+	})
+}
+
+// PullOpt configures a Fetch
+type PullOpt interface {
+	configurePull(*pullOpts) error
+}
+
+type pullOpts struct {
+	ctx     context.Context
+	maxWait time.Duration
+}
+
+// MaxWait limits how long a Fetch, or every request of a JetStream
+// context, waits
+type MaxWait time.Duration
+
+func (w MaxWait) configureJSContext(o *jsOpts) error {
+	o.wait = time.Duration(w)
+	return nil
+}
+
+func (w MaxWait) configurePull(o *pullOpts) error {
+	o.maxWait = time.Duration(w)
+	return nil
+}
+
+// ContextOpt bounds a Fetch or an acknowledgement by a context
+type ContextOpt struct {
+	context.Context
+}
+
+func (c ContextOpt) configurePull(o *pullOpts) error {
+	o.ctx = c
+	return nil
+}
+
+func (c ContextOpt) configureAck(o *ackOpts) error {
+	o.ctx = c
+	return nil
+}
+
+// Context returns an option carrying ctx
+func Context(ctx context.Context) ContextOpt {
+	return ContextOpt{ctx}
+}
+
+// SubOpt configures a JetStream subscription
+type SubOpt interface {
+	configureSubscribe(*subOpts) error
+}
+
+type subOpts struct {
+	stream    string
+	consumer  string
+	bound     bool
+	manualAck bool
+	cfg       ConsumerConfig
+}
+
+type subOptFn func(*subOpts) error
+
+func (f subOptFn) configureSubscribe(o *subOpts) error { return f(o) }
+
+// Durable names the consumer so it outlives the connection
+func Durable(name string) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		if !validConsumerName(name) {
+			return ErrInvalidConsumerName
+		}
+		o.cfg.Durable = name
+		return nil
+	})
+}
+
+// ManualAck stops messages being acked when the handler returns
+func ManualAck() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.manualAck = true
+		return nil
+	})
+}
+
+// AckWait sets how long a message may go unacked before redelivery
+func AckWait(t time.Duration) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.AckWait = t
+		return nil
+	})
+}
+
+// MaxDeliver limits how often a message is delivered
+func MaxDeliver(n int) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.MaxDeliver = n
+		return nil
+	})
+}
+
+// MaxAckPending limits how many messages may await an ack
+func MaxAckPending(n int) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.MaxAckPending = n
+		return nil
+	})
+}
+
+// DeliverAll starts with the oldest message in the stream
+func DeliverAll() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.DeliverPolicy = DeliverAllPolicy
+		return nil
+	})
+}
+
+// DeliverLast starts with the newest message in the stream
+func DeliverLast() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.DeliverPolicy = DeliverLastPolicy
+		return nil
+	})
+}
+
+// DeliverNew starts with the next message published
+func DeliverNew() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.DeliverPolicy = DeliverNewPolicy
+		return nil
+	})
+}
+
+// StartSequence starts at stream sequence seq
+func StartSequence(seq uint64) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.DeliverPolicy = DeliverByStartSequencePolicy
+		o.cfg.OptStartSeq = seq
+		return nil
+	})
+}
+
+// StartTime starts with the first message stored at or after t
+func StartTime(t time.Time) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.DeliverPolicy = DeliverByStartTimePolicy
+		o.cfg.OptStartTime = &t
+		return nil
+	})
+}
+
+// AckNone delivers messages without tracking acks
+func AckNone() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.AckPolicy = AckNonePolicy
+		return nil
+	})
+}
+
+// AckAll makes acking a message ack every one before it
+func AckAll() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.AckPolicy = AckAllPolicy
+		return nil
+	})
+}
+
+// AckExplicit requires every message to be acked
+func AckExplicit() SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.cfg.AckPolicy = AckExplicitPolicy
+		return nil
+	})
+}
+
+// BindStream subscribes through stream instead of looking it up by
+// subject
+func BindStream(stream string) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.stream = stream
+		return nil
+	})
+}
+
+// Bind attaches to an existing consumer of stream
+func Bind(stream, consumer string) SubOpt {
+	return subOptFn(func(o *subOpts) error {
+		o.stream = stream
+		o.consumer = consumer
+		o.bound = true
+		return nil
+	})
+}
+
+// JetStream contexts
+
+// JetStream publishes to streams and subscribes through consumers
+type JetStream interface {
+	Publish(subject string, data []byte, opts ...PubOpt) (*PubAck, error)
+	PublishMsg(m *Msg, opts ...PubOpt) (*PubAck, error)
+	Subscribe(subject string, cb MsgHandler, opts ...SubOpt) (*Subscription, error)
+	SubscribeSync(subject string, opts ...SubOpt) (*Subscription, error)
+	QueueSubscribe(subject, queue string, cb MsgHandler, opts ...SubOpt) (*Subscription, error)
+	PullSubscribe(subject, durable string, opts ...SubOpt) (*Subscription, error)
+}
+
+// JetStreamManager manages streams and consumers
+type JetStreamManager interface {
+	AddStream(cfg *StreamConfig) (*StreamInfo, error)
+	UpdateStream(cfg *StreamConfig) (*StreamInfo, error)
+	DeleteStream(name string) error
+	StreamInfo(name string) (*StreamInfo, error)
+	PurgeStream(name string) error
+	StreamNames() <-chan string
+	GetMsg(name string, seq uint64) (*RawStreamMsg, error)
+	DeleteMsg(name string, seq uint64) error
+	AddConsumer(stream string, cfg *ConsumerConfig) (*ConsumerInfo, error)
+	DeleteConsumer(stream, consumer string) error
+	ConsumerInfo(stream, consumer string) (*ConsumerInfo, error)
+	ConsumerNames(stream string) <-chan string
+}
+
+// JetStreamContext combines JetStream and JetStreamManager
+type JetStreamContext interface {
+	JetStream
+	JetStreamManager
+}
+
+type js struct {
+	nc   *Conn
+	opts jsOpts
+}
+
+// JetStream returns a JetStream context for the connection
+func (nc *Conn) JetStream(opts ...JSOpt) (JetStreamContext, error) {
+	if nc.IsClosed() {
+		return nil, ErrConnectionClosed
+	}
+	o := jsOpts{wait: 5 * time.Second}
+	for _, opt := range opts {
+		if err := opt.configureJSContext(&o); err != nil {
+			return nil, err
+		}
+	}
+	return &js{nc: nc, opts: o}, nil
+}
+
+// Publish stores data in the stream capturing subject
+func (j *js) Publish(subject string, data []byte, opts ...PubOpt) (*PubAck, error) {
+	return j.PublishMsg(&Msg{Subject: subject, Data: data}, opts...)
+}
+
+// PublishMsg stores a message in the stream capturing its subject
+func (j *js) PublishMsg(m *Msg, opts ...PubOpt) (*PubAck, error) {
+	var o pubOpts
+	for _, opt := range opts {
+		if err := opt.configurePublish(&o); err != nil {
+			return nil, err
+		}
+	}
+	if o.id != "" {
+		msg := *m
+		msg.Header = m.Header.clone()
+		if msg.Header == nil {
+			msg.Header = make(Header)
+		}
+		msg.Header.Set(MsgIdHdr, o.id)
+		m = &msg
+	}
+	if o.stream != "" {
+		j.nc.server.mu.Lock()
+		st := j.nc.server.streamFor(m.Subject)
+		j.nc.server.mu.Unlock()
+		if st == nil || st.config.Name != o.stream {
+			return nil, ErrStreamMismatch
+		}
+	}
+	_, ack, err := j.nc.publish(m)
+	if err != nil {
+		return nil, err
+	}
+	if ack == nil {
+		return nil, ErrNoStreamResponse
+	}
+	return ack, nil
+}
+
+// Subscribe pushes messages on subject to cb through a consumer. Unless
+// ManualAck is given, each message is acked when cb returns.
+func (j *js) Subscribe(subject string, cb MsgHandler, opts ...SubOpt) (*Subscription, error) {
+	if cb == nil {
+		return nil, ErrBadSubscription
+	}
+	return j.subscribe(subject, "", cb, asyncSub, opts)
+}
+
+// SubscribeSync subscribes through a consumer for reading with NextMsg
+func (j *js) SubscribeSync(subject string, opts ...SubOpt) (*Subscription, error) {
+	return j.subscribe(subject, "", nil, syncSub, opts)
+}
+
+// QueueSubscribe shares a durable consumer, named queue unless Durable
+// says otherwise, between the members of a queue group
+func (j *js) QueueSubscribe(subject, queue string, cb MsgHandler, opts ...SubOpt) (*Subscription, error) {
+	if cb == nil {
+		return nil, ErrBadSubscription
+	}
+	if queue == "" || strings.ContainsAny(queue, " \t\r\n") {
+		return nil, ErrBadQueueName
+	}
+	return j.subscribe(subject, queue, cb, asyncSub, opts)
+}
+
+// PullSubscribe creates a subscription whose messages are read with Fetch
+func (j *js) PullSubscribe(subject, durable string, opts ...SubOpt) (*Subscription, error) {
+	if durable != "" {
+		opts = append([]SubOpt{Durable(durable)}, opts...)
+	}
+	return j.subscribe(subject, "", nil, pullSub, opts)
+}
+
+// jsSub binds a subscription to its consumer
+type jsSub struct {
+	js       *js
+	consumer *consumer
+	// created is set when the subscription made the consumer, which
+	// Unsubscribe then deletes
+	created bool
+}
+
+func (j *js) subscribe(subject, queue string, cb MsgHandler, typ subType, opts []SubOpt) (*Subscription, error) {
+	if j.nc.IsClosed() {
+		return nil, ErrConnectionClosed
+	}
+	if !validSubject(subject, true) {
+		return nil, ErrBadSubject
+	}
+	o := subOpts{cfg: ConsumerConfig{AckPolicy: AckExplicitPolicy}}
+	for _, opt := range opts {
+		if err := opt.configureSubscribe(&o); err != nil {
+			return nil, err
+		}
+	}
+	if queue != "" {
+		if o.cfg.Durable == "" {
+			o.cfg.Durable = queue
+		}
+		o.cfg.DeliverGroup = queue
+	}
+	name := o.cfg.Durable
+	if o.bound {
+		name = o.consumer
+	}
+
+	server := j.nc.server
+	streamName := o.stream
+	if streamName == "" {
+		server.mu.Lock()
+		for _, st := range server.streamList() {
+			for _, pattern := range st.config.Subjects {
+				if subjectIsSubset(subject, pattern) {
+					streamName = st.config.Name
+				}
+			}
+		}
+		server.mu.Unlock()
+		if streamName == "" {
+			return nil, ErrNoMatchingStream
+		}
+	}
+	st, err := server.stream(streamName)
+	if err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	c := st.consumers[name]
+	created := false
+	if c == nil {
+		if o.bound {
+			st.mu.Unlock()
+			return nil, ErrConsumerNotFound
+		}
+		cfg := o.cfg
+		cfg.FilterSubject = subject
+		if cfg.FilterSubject == ">" {
+			cfg.FilterSubject = ""
+		}
+		if typ != pullSub {
+			cfg.DeliverSubject = NewInbox()
+		}
+		if c, err = st.newConsumer(cfg); err != nil {
+			st.mu.Unlock()
+			return nil, err
+		}
+		created = true
+	}
+	switch {
+	case typ == pullSub && c.config.DeliverSubject != "":
+		err = ErrPullSubscribeToPushConsumer
+	case typ != pullSub && c.config.DeliverSubject == "":
+		err = ErrPullSubscribeRequired
+	case typ != pullSub && c.config.DeliverGroup != queue:
+		err = fmt.Errorf("nats: cannot create a queue subscription %q for a consumer with a deliver group %q", queue, c.config.DeliverGroup)
+	case typ != pullSub && queue == "" && c.bound > 0:
+		err = ErrConsumerAlreadyBound
+	}
+	if err != nil {
+		st.mu.Unlock()
+		return nil, err
+	}
+	c.bound++
+	deliverSubject, ackPolicy := c.config.DeliverSubject, c.config.AckPolicy
+	st.mu.Unlock()
+
+	jsi := &jsSub{js: j, consumer: c, created: created}
+	if typ == pullSub {
+		sub := newSubscription(j.nc, subject, "", nil, nil, pullSub)
+		sub.jsi = jsi
+		if err := j.nc.addSub(sub); err != nil {
+			sub.releaseConsumer(true)
+			return nil, err
+		}
+		return sub, nil
+	}
+
+	if cb != nil && !o.manualAck && ackPolicy != AckNonePolicy {
+		handler := cb
+		cb = func(m *Msg) {
+			handler(m)
+			if m.ack != nil && !m.ack.isAcked() {
+				m.Ack()
+			}
+		}
+	}
+	sub := newSubscription(j.nc, deliverSubject, queue, cb, nil, typ)
+	sub.jsi = jsi
+	if err := j.nc.addSub(sub); err != nil {
+		sub.stop(true)
+		sub.releaseConsumer(true)
+		return nil, err
+	}
+	j.nc.server.addSub(sub)
+	return sub, nil
+}
+
+// releaseConsumer unbinds a JetStream subscription. The consumer is
+// deleted once unused if it is ephemeral, or if unsubscribing the
+// subscription that created it.
+func (sub *Subscription) releaseConsumer(unsubscribe bool) {
+	if sub.jsi == nil {
+		return
+	}
+	sub.mu.Lock()
+	jsi := sub.jsi
+	sub.jsi = nil
+	sub.mu.Unlock()
+	if jsi == nil {
+		return
+	}
+	c := jsi.consumer
+	st := c.stream
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c.bound--
+	if c.bound == 0 && (c.config.Durable == "" || (unsubscribe && jsi.created)) && st.consumers[c.name] == c {
+		delete(st.consumers, c.name)
+		c.stop()
+		st.notify()
+	}
+}
+
+// Fetch pulls up to batch messages, waiting up to MaxWait (the JetStream
+// context's wait by default) for the first
+func (sub *Subscription) Fetch(batch int, opts ...PullOpt) ([]*Msg, error) {
+	if sub == nil || sub.typ != pullSub {
+		return nil, ErrTypeSubscription
+	}
+	if batch < 1 {
+		return nil, ErrInvalidArg
+	}
+	sub.mu.Lock()
+	jsi := sub.jsi
+	sub.mu.Unlock()
+	if jsi == nil || !sub.IsValid() {
+		return nil, ErrBadSubscription
+	}
+	o := pullOpts{maxWait: jsi.js.opts.wait}
+	for _, opt := range opts {
+		if err := opt.configurePull(&o); err != nil {
+			return nil, err
+		}
+	}
+	ctx := o.ctx
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), o.maxWait)
+		defer cancel()
+	}
+
+	c := jsi.consumer
+	st := c.stream
+	for {
+		st.mu.Lock()
+		if c.deleted {
+			st.mu.Unlock()
+			return nil, ErrConsumerNotFound
+		}
+		changed := st.changed
+		msgs, wake := c.collect(time.Now(), batch)
+		st.mu.Unlock()
+		if len(msgs) > 0 {
+			for _, m := range msgs {
+				m.Sub = sub
+			}
+			return msgs, nil
+		}
+
+		var timer <-chan time.Time
+		var t *time.Timer
+		if !wake.IsZero() {
+			t = time.NewTimer(time.Until(wake))
+			timer = t.C
+		}
+		select {
+		case <-changed:
+		case <-timer:
+		case <-sub.done:
+		case <-ctx.Done():
+		}
+		if t != nil {
+			t.Stop()
+		}
+		if ctx.Err() != nil {
+			if o.ctx != nil {
+				return nil, ctx.Err()
+			}
+			return nil, ErrTimeout
+		}
+		if !sub.IsValid() {
+			return nil, ErrBadSubscription
+		}
+	}
+}
+
+// ConsumerInfo describes the consumer behind a JetStream subscription
+func (sub *Subscription) ConsumerInfo() (*ConsumerInfo, error) {
+	sub.mu.Lock()
+	jsi := sub.jsi
+	sub.mu.Unlock()
+	if jsi == nil {
+		return nil, ErrTypeSubscription
+	}
+	st := jsi.consumer.stream
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if jsi.consumer.deleted {
+		return nil, ErrConsumerNotFound
+	}
+	return jsi.consumer.info(), nil
+}
+
+func validStreamName(name string) bool {
+	return !strings.ContainsAny(name, ".*> \t\r\n")
+}
+
+// streamDefaults fills in the server's defaults
+func streamDefaults(cfg StreamConfig) StreamConfig {
+	if len(cfg.Subjects) == 0 {
+		cfg.Subjects = []string{cfg.Name}
+	}
+	if cfg.Duplicates <= 0 {
+		cfg.Duplicates = 2 * time.Minute
+	}
+	if cfg.MaxMsgs == 0 {
+		cfg.MaxMsgs = -1
+	}
+	if cfg.Replicas == 0 {
+		cfg.Replicas = 1
+	}
+	return cfg
+}
+
+// checkSubjects validates a stream's subjects against the other streams.
+// Callers hold s.mu.
+func (s *Server) checkSubjects(cfg StreamConfig) error {
+	for _, subject := range cfg.Subjects {
+		if !validSubject(subject, true) {
+			return ErrBadSubject
+		}
+	}
+	for name, other := range s.streams {
+		if name == cfg.Name {
+			continue
+		}
+		for _, a := range cfg.Subjects {
+			for _, b := range other.config.Subjects {
+				if subjectsOverlap(a, b) {
+					return ErrStreamSubjectsOverlap
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AddStream creates a stream. Adding an identical stream again succeeds.
+func (j *js) AddStream(cfg *StreamConfig) (*StreamInfo, error) {
+	if cfg == nil || cfg.Name == "" {
+		return nil, ErrStreamNameRequired
+	}
+	if !validStreamName(cfg.Name) {
+		return nil, ErrInvalidStreamName
+	}
+	config := streamDefaults(*cfg)
+	config.Subjects = append([]string(nil), config.Subjects...)
+
+	server := j.nc.server
+	server.mu.Lock()
+	if st, ok := server.streams[config.Name]; ok {
+		server.mu.Unlock()
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		if !reflect.DeepEqual(st.config, config) {
+			return nil, ErrStreamNameAlreadyInUse
+		}
+		return st.info(), nil
+	}
+	if err := server.checkSubjects(config); err != nil {
+		server.mu.Unlock()
+		return nil, err
+	}
+	st := &stream{
+		server:    server,
+		config:    config,
+		created:   time.Now(),
+		dedupe:    make(map[string]dedupeEntry),
+		consumers: make(map[string]*consumer),
+		changed:   make(chan struct{}),
+	}
+	server.streams[config.Name] = st
+	server.mu.Unlock()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.info(), nil
+}
+
+// UpdateStream changes a stream's subjects, limits and description
+func (j *js) UpdateStream(cfg *StreamConfig) (*StreamInfo, error) {
+	if cfg == nil || cfg.Name == "" {
+		return nil, ErrStreamNameRequired
+	}
+	server := j.nc.server
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	st, ok := server.streams[cfg.Name]
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
+	config := streamDefaults(*cfg)
+	config.Subjects = append([]string(nil), config.Subjects...)
+	if err := server.checkSubjects(config); err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if config.Retention != st.config.Retention || config.Storage != st.config.Storage {
+		return nil, errors.New("nats: stream configuration update can not change retention or storage")
+	}
+	st.config = config
+	for st.config.MaxMsgs > 0 && int64(len(st.msgs)) > st.config.MaxMsgs {
+		st.bytes -= uint64(len(st.msgs[0].data))
+		st.msgs = st.msgs[1:]
+	}
+	st.notify()
+	return st.info(), nil
+}
+
+// DeleteStream deletes a stream and its consumers
+func (j *js) DeleteStream(name string) error {
+	st, err := j.nc.server.stream(name)
+	if err != nil {
+		return err
+	}
+	server := j.nc.server
+	server.mu.Lock()
+	delete(server.streams, name)
+	server.mu.Unlock()
+	st.delete()
+	return nil
+}
+
+// StreamInfo describes a stream
+func (j *js) StreamInfo(name string) (*StreamInfo, error) {
+	st, err := j.nc.server.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.info(), nil
+}
+
+// PurgeStream removes every message from a stream, keeping its
+// sequence numbers
+func (j *js) PurgeStream(name string) error {
+	st, err := j.nc.server.stream(name)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.msgs = nil
+	st.bytes = 0
+	st.notify()
+	return nil
+}
+
+// StreamNames lists the streams in name order
+func (j *js) StreamNames() <-chan string {
+	server := j.nc.server
+	server.mu.Lock()
+	streams := server.streamList()
+	server.mu.Unlock()
+	ch := make(chan string, len(streams))
+	for _, st := range streams {
+		ch <- st.config.Name
+	}
+	close(ch)
+	return ch
+}
+
+// GetMsg reads a message from a stream by sequence
+func (j *js) GetMsg(name string, seq uint64) (*RawStreamMsg, error) {
+	st, err := j.nc.server.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.expire(time.Now())
+	sm := st.find(seq)
+	if sm == nil {
+		return nil, ErrMsgNotFound
+	}
+	return &RawStreamMsg{
+		Subject:  sm.subject,
+		Sequence: sm.seq,
+		Header:   sm.header.clone(),
+		Data:     append([]byte(nil), sm.data...),
+		Time:     sm.time,
+	}, nil
+}
+
+// DeleteMsg removes a message from a stream
+func (j *js) DeleteMsg(name string, seq uint64) error {
+	st, err := j.nc.server.stream(name)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.remove(seq) {
+		return ErrMsgNotFound
+	}
+	st.notify()
+	return nil
+}
+
+// AddConsumer creates a consumer. Adding an identical durable consumer
+// again succeeds.
+func (j *js) AddConsumer(stream string, cfg *ConsumerConfig) (*ConsumerInfo, error) {
+	if cfg == nil {
+		return nil, ErrInvalidArg
+	}
+	st, err := j.nc.server.stream(stream)
+	if err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if c, ok := st.consumers[cfg.Durable]; ok && cfg.Durable != "" {
+		if !reflect.DeepEqual(c.config, consumerDefaults(*cfg)) {
+			return nil, ErrConsumerNameAlreadyInUse
+		}
+		return c.info(), nil
+	}
+	c, err := st.newConsumer(*cfg)
+	if err != nil {
+		return nil, err
+	}
+	return c.info(), nil
+}
+
+// DeleteConsumer deletes a consumer
+func (j *js) DeleteConsumer(stream, name string) error {
+	st, err := j.nc.server.stream(stream)
+	if err != nil {
+		return err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c, ok := st.consumers[name]
+	if !ok {
+		return ErrConsumerNotFound
+	}
+	delete(st.consumers, name)
+	c.stop()
+	st.notify()
+	return nil
+}
+
+// ConsumerInfo describes a consumer
+func (j *js) ConsumerInfo(stream, name string) (*ConsumerInfo, error) {
+	st, err := j.nc.server.stream(stream)
+	if err != nil {
+		return nil, err
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	c, ok := st.consumers[name]
+	if !ok {
+		return nil, ErrConsumerNotFound
+	}
+	return c.info(), nil
+}
+
+// ConsumerNames lists a stream's consumers in name order
+func (j *js) ConsumerNames(stream string) <-chan string {
+	var names []string
+	if st, err := j.nc.server.stream(stream); err == nil {
+		st.mu.Lock()
+		for name := range st.consumers {
+			names = append(names, name)
+		}
+		st.mu.Unlock()
+	}
+	sort.Strings(names)
+	ch := make(chan string, len(names))
+	for _, name := range names {
+		ch <- name
+	}
+	close(ch)
+	return ch
+}
+
+// Go-kit transport
+
+// Endpoint has the signature of the Go-kit emulator's Endpoint, so its
+// endpoints and middlewares plug into the Subscriber and Publisher
+type Endpoint func(ctx context.Context, request interface{}) (response interface{}, err error)
+
+// DecodeRequestFunc extracts a request from a NATS message
+type DecodeRequestFunc func(context.Context, *Msg) (request interface{}, err error)
+
+// EncodeResponseFunc publishes a response to the reply subject
+type EncodeResponseFunc func(context.Context, string, *Conn, interface{}) error
+
+// EncodeRequestFunc encodes a request into a NATS message
+type EncodeRequestFunc func(context.Context, *Msg, interface{}) error
+
+// DecodeResponseFunc extracts a response from a reply message
+type DecodeResponseFunc func(context.Context, *Msg) (response interface{}, err error)
+
+// RequestFunc may take information from a message into the context
+type RequestFunc func(context.Context, *Msg) context.Context
+
+// ErrorEncoder publishes an endpoint error to the reply subject
+type ErrorEncoder func(ctx context.Context, err error, reply string, nc *Conn)
+
+// Subscriber serves an endpoint on a subject
+type Subscriber struct {
+	e            Endpoint
+	dec          DecodeRequestFunc
+	enc          EncodeResponseFunc
+	before       []RequestFunc
+	errorEncoder ErrorEncoder
+}
+
+// SubscriberOption configures a Subscriber
+type SubscriberOption func(*Subscriber)
+
+// SubscriberBefore runs functions on the message before decoding
+func SubscriberBefore(before ...RequestFunc) SubscriberOption {
+	return func(s *Subscriber) { s.before = append(s.before, before...) }
+}
+
+// SubscriberErrorEncoder replaces DefaultErrorEncoder
+func SubscriberErrorEncoder(ee ErrorEncoder) SubscriberOption {
+	return func(s *Subscriber) { s.errorEncoder = ee }
+}
+
+// NewSubscriber wraps an endpoint for serving over NATS
+func NewSubscriber(e Endpoint, dec DecodeRequestFunc, enc EncodeResponseFunc, options ...SubscriberOption) *Subscriber {
+	s := &Subscriber{e: e, dec: dec, enc: enc, errorEncoder: DefaultErrorEncoder}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServeMsg returns a handler for Subscribe, QueueSubscribe or a
+// JetStream Subscribe. Requests get their response on the reply
+// subject. Events, messages with no reply subject or from JetStream,
+// only run the endpoint; a JetStream event whose endpoint fails is
+// nak'd for redelivery.
+func (s Subscriber) ServeMsg(nc *Conn) func(msg *Msg) {
+	return func(msg *Msg) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		for _, f := range s.before {
+			ctx = f(ctx, msg)
+		}
+
+		request, err := s.dec(ctx, msg)
+		if err != nil {
+			s.fail(ctx, err, msg, nc)
+			return
+		}
+		response, err := s.e(ctx, request)
+		if err != nil {
+			s.fail(ctx, err, msg, nc)
+			return
+		}
+		if msg.ack != nil || msg.Reply == "" {
+			return
+		}
+		if err := s.enc(ctx, msg.Reply, nc, response); err != nil {
+			s.fail(ctx, err, msg, nc)
+		}
+	}
+}
+
+func (s Subscriber) fail(ctx context.Context, err error, msg *Msg, nc *Conn) {
+	if msg.ack != nil {
+		msg.Nak()
+		return
+	}
+	if msg.Reply != "" {
+		s.errorEncoder(ctx, err, msg.Reply, nc)
+	}
+}
+
+// DefaultErrorEncoder publishes {"error": "..."} to the reply subject
+func DefaultErrorEncoder(_ context.Context, err error, reply string, nc *Conn) {
+	b, _ := json.Marshal(map[string]string{"error": err.Error()})
+	nc.Publish(reply, b)
+}
+
+// EncodeJSONResponse publishes the response as JSON
+func EncodeJSONResponse(_ context.Context, reply string, nc *Conn, response interface{}) error {
+	b, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return nc.Publish(reply, b)
+}
+
+// Publisher calls a remote endpoint with NATS request/reply
+type Publisher struct {
+	publisher *Conn
+	subject   string
+	enc       EncodeRequestFunc
+	dec       DecodeResponseFunc
+	before    []RequestFunc
+	timeout   time.Duration
+}
+
+// PublisherOption configures a Publisher
+type PublisherOption func(*Publisher)
+
+// PublisherBefore runs functions on the request message before sending
+func PublisherBefore(before ...RequestFunc) PublisherOption {
+	return func(p *Publisher) { p.before = append(p.before, before...) }
+}
+
+// PublisherTimeout limits how long a request waits, 10 seconds by default
+func PublisherTimeout(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) { p.timeout = timeout }
+}
+
+// NewPublisher builds a client for the endpoint served on subject
+func NewPublisher(publisher *Conn, subject string, enc EncodeRequestFunc, dec DecodeResponseFunc, options ...PublisherOption) *Publisher {
+	p := &Publisher{publisher: publisher, subject: subject, enc: enc, dec: dec, timeout: 10 * time.Second}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Endpoint returns an endpoint that makes the request
+func (p Publisher) Endpoint() Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+
+		msg := &Msg{Subject: p.subject, Header: make(Header)}
+		if err := p.enc(ctx, msg, request); err != nil {
+			return nil, err
+		}
+		for _, f := range p.before {
+			ctx = f(ctx, msg)
+		}
+		resp, err := p.publisher.RequestMsgWithContext(ctx, msg)
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				return nil, ErrTimeout
+			}
+			return nil, err
+		}
+		return p.dec(ctx, resp)
+	}
+}
+
+// EncodeJSONRequest sets the message data to the request as JSON
+func EncodeJSONRequest(_ context.Context, msg *Msg, request interface{}) error {
+	b, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	msg.Data = b
+	return nil
+}
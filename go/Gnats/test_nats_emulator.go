@@ -0,0 +1,997 @@
+package main
+
+// Developed by PowerShield, as an alternative to NATS
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+// collector gathers the data of messages handed to its handler
+type collector struct {
+	mu     sync.Mutex
+	values []string
+}
+
+func (c *collector) handle(m *Msg) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = append(c.values, string(m.Data))
+}
+
+func (c *collector) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.values...)
+}
+
+func (c *collector) count() int {
+	return len(c.get())
+}
+
+// connect starts a server at url and connects to it
+func connect(url string, options ...Option) (*Server, *Conn) {
+	server := NewServer(url)
+	nc, err := Connect(url, options...)
+	if err != nil {
+		panic(err)
+	}
+	return server, nc
+}
+
+// Test connecting, closing and server bookkeeping
+func testConnectAndClose() bool {
+	if _, err := Connect("nats://nowhere:4222"); err != ErrNoServers {
+		return false
+	}
+
+	server := NewServer("nats://connect:4222")
+	defer server.Shutdown()
+	closed := make(chan string, 1)
+	nc, err := Connect("nats://down:4222, connect:4222", Name("api"), ClosedHandler(func(nc *Conn) {
+		closed <- nc.Opts.Name
+	}))
+	if err != nil || !nc.IsConnected() || nc.ConnectedUrl() != "connect:4222" || nc.Status() != CONNECTED {
+		return false
+	}
+	if nc.MaxPayload() != DefaultMaxPayload || server.NumClients() != 1 {
+		return false
+	}
+	nc.SubscribeSync("updates")
+	nc.SubscribeSync("updates.>")
+	if server.NumSubscriptions() != 2 || nc.NumSubscriptions() != 2 {
+		return false
+	}
+
+	nc.Close()
+	select {
+	case name := <-closed:
+		if name != "api" {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	if !nc.IsClosed() || nc.Status().String() != "CLOSED" || server.NumSubscriptions() != 0 || server.NumClients() != 0 {
+		return false
+	}
+	if nc.Publish("updates", nil) != ErrConnectionClosed || nc.Flush() != ErrConnectionClosed {
+		return false
+	}
+	if _, err := nc.Subscribe("updates", func(*Msg) {}); err != ErrConnectionClosed {
+		return false
+	}
+
+	disconnected := make(chan error, 1)
+	other, _ := Connect("connect:4222", DisconnectErrHandler(func(_ *Conn, err error) { disconnected <- err }))
+	server.Shutdown()
+	select {
+	case err := <-disconnected:
+		if err != io.EOF || !other.IsClosed() {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	_, err = Connect("connect:4222")
+	return err == ErrNoServers
+}
+
+// Test publishing to async and sync subscribers
+func testPublishSubscribe() bool {
+	server, nc := connect("nats://pubsub:4222")
+	defer server.Shutdown()
+
+	got := &collector{}
+	if _, err := nc.Subscribe("greet", got.handle); err != nil {
+		return false
+	}
+	reader, _ := nc.SubscribeSync("greet")
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if err := nc.Publish("greet", []byte(name)); err != nil {
+			return false
+		}
+	}
+	nc.Flush()
+	if !waitFor(func() bool { return got.count() == 3 }) || strings.Join(got.get(), ",") != "alice,bob,carol" {
+		return false
+	}
+	if n, _, _ := reader.Pending(); n != 3 {
+		return false
+	}
+	for _, want := range []string{"alice", "bob", "carol"} {
+		m, err := reader.NextMsg(time.Second)
+		if err != nil || string(m.Data) != want || m.Subject != "greet" || m.Sub != reader {
+			return false
+		}
+	}
+	if _, err := reader.NextMsg(10 * time.Millisecond); err != ErrTimeout {
+		return false
+	}
+
+	msg := NewMsg("greet")
+	msg.Header.Set("Trace-Id", "t-1")
+	msg.Header.Add("Tag", "a")
+	msg.Header.Add("Tag", "b")
+	msg.Data = []byte("dave")
+	nc.PublishMsg(msg)
+	m, _ := reader.NextMsg(time.Second)
+	if m.Header.Get("Trace-Id") != "t-1" || len(m.Header.Values("Tag")) != 2 || m.Header.Get("trace-id") != "" {
+		return false
+	}
+	m.Data[0] = 'X'
+	if !waitFor(func() bool { return got.count() == 4 }) || got.get()[3] != "dave" {
+		return false
+	}
+
+	if nc.Publish("", nil) != ErrBadSubject || nc.Publish("a.*", nil) != ErrBadSubject || nc.Publish("big", make([]byte, DefaultMaxPayload+1)) != ErrMaxPayload {
+		return false
+	}
+	async, _ := nc.Subscribe("async.only", got.handle)
+	if _, err := async.NextMsg(time.Millisecond); err != ErrSyncSubRequired {
+		return false
+	}
+
+	// NoEcho connections do not hear themselves
+	quiet, _ := Connect("pubsub:4222", NoEcho())
+	own, _ := quiet.SubscribeSync("echo")
+	quiet.Publish("echo", []byte("me"))
+	nc.Publish("echo", []byte("other"))
+	m, err := own.NextMsg(time.Second)
+	if err != nil || string(m.Data) != "other" {
+		return false
+	}
+	if reader.Unsubscribe() != nil || reader.IsValid() || reader.Unsubscribe() != ErrBadSubscription {
+		return false
+	}
+	_, err = reader.NextMsg(time.Millisecond)
+	return err == ErrBadSubscription
+}
+
+// Test wildcard subjects
+func testWildcards() bool {
+	server, nc := connect("nats://wildcards:4222")
+	defer server.Shutdown()
+
+	star, _ := nc.SubscribeSync("orders.*.created")
+	tail, _ := nc.SubscribeSync("orders.>")
+	exact, _ := nc.SubscribeSync("orders.eu")
+	for _, subject := range []string{"orders.eu.created", "orders.us.created", "orders.eu", "orders.eu.created.late", "orders", "payments.eu.created"} {
+		nc.Publish(subject, []byte(subject))
+	}
+
+	drain := func(sub *Subscription) []string {
+		var subjects []string
+		for {
+			m, err := sub.NextMsg(10 * time.Millisecond)
+			if err != nil {
+				return subjects
+			}
+			subjects = append(subjects, m.Subject)
+		}
+	}
+	if strings.Join(drain(star), ",") != "orders.eu.created,orders.us.created" {
+		return false
+	}
+	if strings.Join(drain(tail), ",") != "orders.eu.created,orders.us.created,orders.eu,orders.eu.created.late" {
+		return false
+	}
+	if strings.Join(drain(exact), ",") != "orders.eu" {
+		return false
+	}
+
+	for _, bad := range []string{"", "orders.", ".orders", "orders..eu", "orders.>.eu", "orders.e*", "orders eu"} {
+		if _, err := nc.SubscribeSync(bad); err != ErrBadSubject {
+			return false
+		}
+	}
+	if _, err := nc.QueueSubscribeSync("orders", "bad queue"); err != ErrBadQueueName {
+		return false
+	}
+	return subjectMatches("*.>", "a.b") && !subjectMatches("*.>", "a") && subjectIsSubset("a.b", "a.*") && !subjectIsSubset("a.*", "a.b")
+}
+
+// Test that queue groups share messages
+func testQueueGroups() bool {
+	server, nc := connect("nats://queues:4222")
+	defer server.Shutdown()
+
+	workers := []*collector{{}, {}, {}}
+	for _, w := range workers {
+		if _, err := nc.QueueSubscribe("jobs", "workers", w.handle); err != nil {
+			return false
+		}
+	}
+	audit := &collector{}
+	nc.Subscribe("jobs", audit.handle)
+	other := make(chan *Msg, 100)
+	nc.ChanQueueSubscribe("jobs", "billing", other)
+
+	for i := 0; i < 60; i++ {
+		nc.Publish("jobs", []byte(fmt.Sprintf("job-%d", i)))
+	}
+	total := func() int {
+		n := 0
+		for _, w := range workers {
+			n += w.count()
+		}
+		return n
+	}
+	if !waitFor(func() bool { return total() == 60 && audit.count() == 60 }) || len(other) != 60 {
+		return false
+	}
+	seen := make(map[string]bool)
+	for _, w := range workers {
+		if w.count() == 0 {
+			return false
+		}
+		for _, v := range w.get() {
+			if seen[v] {
+				return false
+			}
+			seen[v] = true
+		}
+	}
+	return len(seen) == 60
+}
+
+// Test request/reply
+func testRequestReply() bool {
+	server, nc := connect("nats://rpc:4222")
+	defer server.Shutdown()
+
+	nc.Subscribe("math.double", func(m *Msg) {
+		var n int
+		fmt.Sscan(string(m.Data), &n)
+		m.Respond([]byte(fmt.Sprint(n * 2)))
+	})
+	nc.QueueSubscribe("echo", "echoers", func(m *Msg) {
+		resp := NewMsg("")
+		resp.Header.Set("Echoed-By", "worker")
+		resp.Data = m.Data
+		m.RespondMsg(resp)
+	})
+	nc.Subscribe("slow", func(m *Msg) {
+		time.Sleep(100 * time.Millisecond)
+		m.Respond([]byte("late"))
+	})
+
+	resp, err := nc.Request("math.double", []byte("21"), time.Second)
+	if err != nil || string(resp.Data) != "42" || !strings.HasPrefix(resp.Subject, InboxPrefix) {
+		return false
+	}
+	resp, err = nc.RequestMsg(&Msg{Subject: "echo", Data: []byte("hi")}, time.Second)
+	if err != nil || string(resp.Data) != "hi" || resp.Header.Get("Echoed-By") != "worker" {
+		return false
+	}
+
+	if _, err := nc.Request("slow", nil, 10*time.Millisecond); err != ErrTimeout {
+		return false
+	}
+	if _, err := nc.Request("nobody.home", nil, time.Second); err != ErrNoResponders {
+		return false
+	}
+	if _, err := nc.RequestWithContext(context.Background(), "echo", nil); err != ErrNoDeadlineContext {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := nc.RequestWithContext(ctx, "slow", nil); err != context.DeadlineExceeded {
+		return false
+	}
+
+	// Replies to a manual inbox
+	inbox := nc.NewRespInbox()
+	replies, _ := nc.SubscribeSync(inbox)
+	nc.PublishRequest("math.double", inbox, []byte("5"))
+	if m, err := replies.NextMsg(time.Second); err != nil || string(m.Data) != "10" {
+		return false
+	}
+
+	if (&Msg{Data: []byte("x")}).Respond(nil) != ErrMsgNotBound {
+		return false
+	}
+	noReply, _ := nc.SubscribeSync("plain")
+	nc.Publish("plain", nil)
+	m, _ := noReply.NextMsg(time.Second)
+	return m.Respond(nil) == ErrMsgNoReply
+}
+
+// Test auto-unsubscribe, pending limits and channel subscriptions
+func testSubscriptionLimits() bool {
+	slowErrs := make(chan error, 10)
+	server, nc := connect("nats://limits:4222", ErrorHandler(func(_ *Conn, sub *Subscription, err error) {
+		slowErrs <- err
+	}))
+	defer server.Shutdown()
+
+	sub, _ := nc.SubscribeSync("ticks")
+	sub.AutoUnsubscribe(2)
+	for i := 0; i < 5; i++ {
+		nc.Publish("ticks", []byte{byte(i)})
+	}
+	if n, _ := sub.Delivered(); n != 2 || server.NumSubscriptions() != 0 {
+		return false
+	}
+	sub.NextMsg(time.Second)
+	sub.NextMsg(time.Second)
+	if _, err := sub.NextMsg(time.Millisecond); err != ErrMaxMessages {
+		return false
+	}
+
+	block := make(chan struct{})
+	got := &collector{}
+	slow, _ := nc.Subscribe("flood", func(m *Msg) {
+		<-block
+		got.handle(m)
+	})
+	if slow.SetPendingLimits(0, 10) != ErrInvalidArg || slow.SetPendingLimits(3, -1) != nil {
+		return false
+	}
+	nc.Publish("flood", []byte("0"))
+	if !waitFor(func() bool { n, _, _ := slow.Pending(); return n == 0 }) {
+		return false
+	}
+	for i := 1; i < 10; i++ {
+		nc.Publish("flood", []byte(fmt.Sprint(i)))
+	}
+	// one message in the handler, three pending, six dropped
+	if !waitFor(func() bool { n, _ := slow.Dropped(); return n == 6 }) {
+		return false
+	}
+	select {
+	case err := <-slowErrs:
+		if err != ErrSlowConsumer {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	close(block)
+	if !waitFor(func() bool { return got.count() == 4 }) {
+		return false
+	}
+
+	ch := make(chan *Msg, 2)
+	chanSub, _ := nc.ChanSubscribe("chan", ch)
+	for i := 0; i < 3; i++ {
+		nc.Publish("chan", []byte(fmt.Sprint(i)))
+	}
+	if len(ch) != 2 || string((<-ch).Data) != "0" {
+		return false
+	}
+	if n, _ := chanSub.Dropped(); n != 1 {
+		return false
+	}
+	return chanSub.SetPendingLimits(1, 1) == ErrTypeSubscription
+}
+
+// Test draining a connection
+func testDrain() bool {
+	closed := make(chan struct{})
+	server, nc := connect("nats://drain:4222", ClosedHandler(func(*Conn) { close(closed) }))
+	defer server.Shutdown()
+	publisher, _ := Connect("drain:4222")
+
+	start := make(chan struct{})
+	got := &collector{}
+	nc.Subscribe("work", func(m *Msg) {
+		<-start
+		time.Sleep(time.Millisecond)
+		got.handle(m)
+	})
+	for i := 0; i < 5; i++ {
+		publisher.Publish("work", []byte(fmt.Sprint(i)))
+	}
+
+	if nc.Drain() != nil || !nc.IsDraining() || nc.Drain() != ErrConnectionDraining {
+		return false
+	}
+	// Messages published after Drain are not delivered
+	publisher.Publish("work", []byte("late"))
+	if _, err := nc.SubscribeSync("more"); err != ErrConnectionDraining {
+		return false
+	}
+	close(start)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		return false
+	}
+	if strings.Join(got.get(), ",") != "0,1,2,3,4" || !nc.IsClosed() {
+		return false
+	}
+
+	// Draining one subscription keeps the connection open
+	sub, _ := publisher.Subscribe("single", got.handle)
+	publisher.Publish("single", []byte("last"))
+	sub.Drain()
+	return waitFor(func() bool { return got.count() == 6 && publisher.NumSubscriptions() == 0 }) && publisher.IsConnected()
+}
+
+// Test streams, publish acknowledgements and limits
+func testJetStreamStreams() bool {
+	server, nc := connect("nats://streams:4222")
+	defer server.Shutdown()
+	js, err := nc.JetStream()
+	if err != nil {
+		return false
+	}
+
+	info, err := js.AddStream(&StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}, MaxMsgs: 3})
+	if err != nil || info.Config.Replicas != 1 || info.State.Msgs != 0 {
+		return false
+	}
+	if _, err := js.AddStream(&StreamConfig{Name: "ORDERS", Subjects: []string{"orders.>"}, MaxMsgs: 3}); err != nil {
+		return false
+	}
+	if _, err := js.AddStream(&StreamConfig{Name: "ORDERS", Subjects: []string{"orders.*"}}); err != ErrStreamNameAlreadyInUse {
+		return false
+	}
+	if _, err := js.AddStream(&StreamConfig{Name: "EU", Subjects: []string{"orders.eu.*"}}); err != ErrStreamSubjectsOverlap {
+		return false
+	}
+	if _, err := js.AddStream(&StreamConfig{Name: "bad.name"}); err != ErrInvalidStreamName {
+		return false
+	}
+	if _, err := js.AddStream(&StreamConfig{}); err != ErrStreamNameRequired {
+		return false
+	}
+
+	ack, err := js.Publish("orders.new", []byte("1"))
+	if err != nil || ack.Stream != "ORDERS" || ack.Sequence != 1 {
+		return false
+	}
+	// Core publishes are captured too
+	nc.Publish("orders.new", []byte("2"))
+	// Duplicates are dropped
+	ack, _ = js.Publish("orders.new", []byte("3"), MsgId("order-3"))
+	dup, _ := js.Publish("orders.new", []byte("3 again"), MsgId("order-3"))
+	if !dup.Duplicate || dup.Sequence != ack.Sequence || ack.Sequence != 3 {
+		return false
+	}
+	// A core request gets the PubAck as its reply
+	resp, err := nc.Request("orders.new", []byte("4"), time.Second)
+	var pubAck PubAck
+	if err != nil || json.Unmarshal(resp.Data, &pubAck) != nil || pubAck.Sequence != 4 {
+		return false
+	}
+	if _, err := js.Publish("nowhere", nil); err != ErrNoStreamResponse {
+		return false
+	}
+	if _, err := js.Publish("orders.new", nil, ExpectStream("OTHER")); err != ErrStreamMismatch {
+		return false
+	}
+
+	// MaxMsgs discards the oldest
+	info, _ = js.StreamInfo("ORDERS")
+	if info.State.Msgs != 3 || info.State.FirstSeq != 2 || info.State.LastSeq != 4 {
+		return false
+	}
+	raw, err := js.GetMsg("ORDERS", 3)
+	if err != nil || string(raw.Data) != "3" || raw.Header.Get(MsgIdHdr) != "order-3" {
+		return false
+	}
+	if _, err := js.GetMsg("ORDERS", 1); err != ErrMsgNotFound {
+		return false
+	}
+	if js.DeleteMsg("ORDERS", 3) != nil || js.DeleteMsg("ORDERS", 3) != ErrMsgNotFound {
+		return false
+	}
+	if info, _ = js.StreamInfo("ORDERS"); info.State.Msgs != 2 {
+		return false
+	}
+
+	// DiscardNew rejects once full
+	js.AddStream(&StreamConfig{Name: "AUDIT", MaxMsgs: 1, Discard: DiscardNew})
+	js.Publish("AUDIT", []byte("first"))
+	if _, err := js.Publish("AUDIT", []byte("second")); err != ErrMaxMsgsExceeded {
+		return false
+	}
+
+	var names []string
+	for name := range js.StreamNames() {
+		names = append(names, name)
+	}
+	if strings.Join(names, ",") != "AUDIT,ORDERS" {
+		return false
+	}
+	if js.PurgeStream("ORDERS") != nil {
+		return false
+	}
+	if info, _ = js.StreamInfo("ORDERS"); info.State.Msgs != 0 || info.State.LastSeq != 4 || info.State.FirstSeq != 5 {
+		return false
+	}
+	if js.DeleteStream("ORDERS") != nil || js.DeleteStream("ORDERS") != ErrStreamNotFound {
+		return false
+	}
+	_, err = js.StreamInfo("ORDERS")
+	return err == ErrStreamNotFound
+}
+
+// Test push consumers: auto-ack, durables and deliver policies
+func testJetStreamPushConsumers() bool {
+	server, nc := connect("nats://push:4222")
+	defer server.Shutdown()
+	js, _ := nc.JetStream()
+	js.AddStream(&StreamConfig{Name: "EVENTS", Subjects: []string{"events.*"}})
+	for i := 1; i <= 3; i++ {
+		js.Publish("events.user", []byte(fmt.Sprintf("u%d", i)))
+	}
+	js.Publish("events.billing", []byte("b1"))
+
+	got := &collector{}
+	sub, err := js.Subscribe("events.user", got.handle)
+	if err != nil || !waitFor(func() bool { return got.count() == 3 }) {
+		return false
+	}
+	js.Publish("events.user", []byte("u4"))
+	if !waitFor(func() bool { return got.count() == 4 }) || strings.Join(got.get(), ",") != "u1,u2,u3,u4" {
+		return false
+	}
+	if !waitFor(func() bool { info, _ := sub.ConsumerInfo(); return info.NumAckPending == 0 }) {
+		return false
+	}
+	// Unsubscribing deletes the ephemeral consumer
+	info, _ := sub.ConsumerInfo()
+	sub.Unsubscribe()
+	if _, err := js.ConsumerInfo("EVENTS", info.Name); err != ErrConsumerNotFound {
+		return false
+	}
+
+	// A durable consumer survives the connection and resumes
+	worker, _ := Connect("push:4222")
+	wjs, _ := worker.JetStream()
+	first := &collector{}
+	wjs.Subscribe("events.*", first.handle, Durable("auditor"))
+	if !waitFor(func() bool { return first.count() == 5 }) {
+		return false
+	}
+	if !waitFor(func() bool { info, _ := js.ConsumerInfo("EVENTS", "auditor"); return info.NumAckPending == 0 }) {
+		return false
+	}
+	worker.Close()
+	js.Publish("events.user", []byte("u5"))
+	if info, err := js.ConsumerInfo("EVENTS", "auditor"); err != nil || info.NumPending != 1 || info.Delivered.Stream != 5 {
+		return false
+	}
+	restarted, _ := Connect("push:4222")
+	rjs, _ := restarted.JetStream()
+	second := &collector{}
+	resumed, err := rjs.Subscribe("events.*", second.handle, Durable("auditor"))
+	if err != nil || !waitFor(func() bool { return second.count() == 1 }) || second.get()[0] != "u5" {
+		return false
+	}
+	if _, err := rjs.Subscribe("events.*", second.handle, Durable("auditor")); err != ErrConsumerAlreadyBound {
+		return false
+	}
+	// The subscription did not create the durable, so it stays
+	resumed.Unsubscribe()
+	if _, err := js.ConsumerInfo("EVENTS", "auditor"); err != nil {
+		return false
+	}
+
+	// Deliver policies
+	positions := map[string]SubOpt{
+		"u5":             DeliverLast(),
+		"u2,u3,u4,u5":    StartSequence(2),
+		"":               DeliverNew(),
+		"u1,u2,u3,u4,u5": DeliverAll(),
+	}
+	for want, opt := range positions {
+		reader, err := js.SubscribeSync("events.user", opt)
+		if err != nil {
+			return false
+		}
+		var values []string
+		for {
+			m, err := reader.NextMsg(20 * time.Millisecond)
+			if err != nil {
+				break
+			}
+			values = append(values, string(m.Data))
+			m.Ack()
+		}
+		reader.Unsubscribe()
+		if strings.Join(values, ",") != want {
+			return false
+		}
+	}
+
+	if _, err := js.Subscribe("unknown.subject", got.handle); err != ErrNoMatchingStream {
+		return false
+	}
+	if _, err := js.Subscribe("events.user", got.handle, Bind("EVENTS", "missing")); err != ErrConsumerNotFound {
+		return false
+	}
+	_, err = js.Subscribe("events.user", got.handle, Durable("bad.name"))
+	return err == ErrInvalidConsumerName
+}
+
+// Test acks, naks, redelivery and metadata
+func testJetStreamRedelivery() bool {
+	server, nc := connect("nats://redeliver:4222")
+	defer server.Shutdown()
+	js, _ := nc.JetStream()
+	js.AddStream(&StreamConfig{Name: "TASKS", Subjects: []string{"tasks"}})
+	js.Publish("tasks", []byte("flaky"))
+	js.Publish("tasks", []byte("poison"))
+	js.Publish("tasks", []byte("slow"))
+
+	var mu sync.Mutex
+	attempts := make(map[string][]uint64)
+	sub, err := js.Subscribe("tasks", func(m *Msg) {
+		meta, err := m.Metadata()
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		attempts[string(m.Data)] = append(attempts[string(m.Data)], meta.NumDelivered)
+		mu.Unlock()
+		switch string(m.Data) {
+		case "flaky":
+			if meta.NumDelivered < 3 {
+				m.Nak()
+				return
+			}
+			m.Ack()
+			if m.Ack() != ErrMsgAlreadyAckd {
+				m.Term()
+			}
+		case "poison":
+			m.Term()
+		case "slow":
+			m.InProgress()
+			if meta.NumDelivered == 1 {
+				// never acked: the ack wait expires and it comes back
+				return
+			}
+			m.AckSync()
+		}
+	}, ManualAck(), AckWait(50*time.Millisecond), MaxDeliver(5))
+	if err != nil {
+		return false
+	}
+
+	count := func(key string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(attempts[key])
+	}
+	if !waitFor(func() bool { return count("flaky") == 3 && count("slow") == 2 }) {
+		return false
+	}
+	time.Sleep(120 * time.Millisecond)
+	mu.Lock()
+	flaky := fmt.Sprint(attempts["flaky"])
+	poison, slow := len(attempts["poison"]), len(attempts["slow"])
+	mu.Unlock()
+	if flaky != "[1 2 3]" || poison != 1 || slow != 2 {
+		return false
+	}
+	info, _ := sub.ConsumerInfo()
+	if info.NumAckPending != 0 || info.AckFloor.Stream != 3 || info.Delivered.Consumer != 6 {
+		return false
+	}
+	sub.Unsubscribe()
+
+	// MaxDeliver stops redelivery of messages nobody acks
+	js.AddStream(&StreamConfig{Name: "DEAD", Subjects: []string{"dead"}})
+	js.Publish("dead", []byte("x"))
+	deliveries := 0
+	var dmu sync.Mutex
+	js.Subscribe("dead", func(m *Msg) {
+		dmu.Lock()
+		deliveries++
+		dmu.Unlock()
+		m.Nak()
+	}, ManualAck(), MaxDeliver(3))
+	time.Sleep(50 * time.Millisecond)
+	dmu.Lock()
+	defer dmu.Unlock()
+	if deliveries != 3 {
+		return false
+	}
+
+	plain, _ := nc.SubscribeSync("plain")
+	nc.Publish("plain", nil)
+	m, _ := plain.NextMsg(time.Second)
+	_, err = m.Metadata()
+	return m.Ack() == ErrNotJSMessage && err == ErrNotJSMessage
+}
+
+// Test pull consumers, queue subscriptions and work queues
+func testJetStreamPullAndWorkQueue() bool {
+	server, nc := connect("nats://pull:4222")
+	defer server.Shutdown()
+	js, _ := nc.JetStream(MaxWait(50 * time.Millisecond))
+	js.AddStream(&StreamConfig{Name: "JOBS", Subjects: []string{"jobs.>"}, Retention: WorkQueuePolicy})
+	for i := 0; i < 5; i++ {
+		js.Publish("jobs.resize", []byte(fmt.Sprint(i)))
+	}
+
+	sub, err := js.PullSubscribe("jobs.resize", "resizer")
+	if err != nil {
+		return false
+	}
+	msgs, err := sub.Fetch(3)
+	if err != nil || len(msgs) != 3 || string(msgs[0].Data) != "0" || msgs[0].Sub != sub {
+		return false
+	}
+	for _, m := range msgs {
+		m.Ack()
+	}
+	// Acked messages leave a work queue
+	if info, _ := js.StreamInfo("JOBS"); info.State.Msgs != 2 || info.State.FirstSeq != 4 {
+		return false
+	}
+	msgs, _ = sub.Fetch(10)
+	if len(msgs) != 2 {
+		return false
+	}
+	msgs[0].Ack()
+	msgs[1].Nak()
+	if msgs, _ = sub.Fetch(10); len(msgs) != 1 || string(msgs[0].Data) != "4" {
+		return false
+	}
+	if meta, _ := msgs[0].Metadata(); meta.NumDelivered != 2 {
+		return false
+	}
+	msgs[0].Ack()
+	start := time.Now()
+	if _, err := sub.Fetch(1); err != ErrTimeout || time.Since(start) < 40*time.Millisecond {
+		return false
+	}
+
+	// Fetch wakes up when a message arrives
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		js.Publish("jobs.resize", []byte("late"))
+	}()
+	if msgs, err = sub.Fetch(1, MaxWait(time.Second)); err != nil || string(msgs[0].Data) != "late" {
+		return false
+	}
+	msgs[0].Ack()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sub.Fetch(1, Context(ctx)); err != context.Canceled {
+		return false
+	}
+
+	if _, err := js.Subscribe("jobs.resize", func(*Msg) {}, Durable("resizer")); err != ErrPullSubscribeRequired {
+		return false
+	}
+	if _, err := sub.NextMsg(time.Millisecond); err != ErrSyncSubRequired {
+		return false
+	}
+	sub.Unsubscribe()
+	if _, err := js.ConsumerInfo("JOBS", "resizer"); err != ErrConsumerNotFound {
+		return false
+	}
+
+	// Queue subscribers share a durable consumer
+	workers := []*collector{{}, {}}
+	for _, w := range workers {
+		if _, err := js.QueueSubscribe("jobs.email", "mailers", w.handle); err != nil {
+			return false
+		}
+	}
+	for i := 0; i < 40; i++ {
+		js.Publish("jobs.email", []byte(fmt.Sprint(i)))
+	}
+	if !waitFor(func() bool { return workers[0].count()+workers[1].count() == 40 }) {
+		return false
+	}
+	all := append(workers[0].get(), workers[1].get()...)
+	sort.Strings(all)
+	for i := 1; i < len(all); i++ {
+		if all[i] == all[i-1] {
+			return false
+		}
+	}
+	if !waitFor(func() bool { info, _ := js.StreamInfo("JOBS"); return info.State.Msgs == 0 }) {
+		return false
+	}
+	var consumers []string
+	for name := range js.ConsumerNames("JOBS") {
+		consumers = append(consumers, name)
+	}
+	return strings.Join(consumers, ",") == "mailers"
+}
+
+// Go-kit style service used with the transport
+type uppercaseRequest struct {
+	S string `json:"s"`
+}
+
+type uppercaseResponse struct {
+	V   string `json:"v"`
+	Err string `json:"err,omitempty"`
+}
+
+func makeUppercaseEndpoint() Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(uppercaseRequest)
+		if req.S == "" {
+			return nil, errors.New("empty string")
+		}
+		return uppercaseResponse{V: strings.ToUpper(req.S)}, nil
+	}
+}
+
+func decodeUppercaseRequest(_ context.Context, msg *Msg) (interface{}, error) {
+	var req uppercaseRequest
+	err := json.Unmarshal(msg.Data, &req)
+	return req, err
+}
+
+func decodeUppercaseResponse(_ context.Context, msg *Msg) (interface{}, error) {
+	var resp uppercaseResponse
+	var failure map[string]string
+	if json.Unmarshal(msg.Data, &failure) == nil && failure["error"] != "" {
+		return nil, errors.New(failure["error"])
+	}
+	err := json.Unmarshal(msg.Data, &resp)
+	return resp, err
+}
+
+// Test the Go-kit transport
+func testGoKitTransport() bool {
+	server, nc := connect("nats://gokit:4222")
+	defer server.Shutdown()
+
+	type traceKey struct{}
+	var traced []string
+	var tmu sync.Mutex
+	subscriber := NewSubscriber(makeUppercaseEndpoint(), decodeUppercaseRequest, EncodeJSONResponse,
+		SubscriberBefore(func(ctx context.Context, msg *Msg) context.Context {
+			tmu.Lock()
+			traced = append(traced, msg.Header.Get("Trace-Id"))
+			tmu.Unlock()
+			return context.WithValue(ctx, traceKey{}, msg.Header.Get("Trace-Id"))
+		}))
+	if _, err := nc.QueueSubscribe("svc.uppercase", "svc", subscriber.ServeMsg(nc)); err != nil {
+		return false
+	}
+
+	client, _ := Connect("gokit:4222")
+	uppercase := NewPublisher(client, "svc.uppercase", EncodeJSONRequest, decodeUppercaseResponse,
+		PublisherBefore(func(ctx context.Context, msg *Msg) context.Context {
+			msg.Header.Set("Trace-Id", "abc")
+			return ctx
+		}), PublisherTimeout(time.Second)).Endpoint()
+
+	resp, err := uppercase(context.Background(), uppercaseRequest{S: "hello"})
+	if err != nil || resp.(uppercaseResponse).V != "HELLO" {
+		return false
+	}
+	if _, err := uppercase(context.Background(), uppercaseRequest{}); err == nil || err.Error() != "empty string" {
+		return false
+	}
+	tmu.Lock()
+	if strings.Join(traced, ",") != "abc,abc" {
+		tmu.Unlock()
+		return false
+	}
+	tmu.Unlock()
+
+	// Middleware written against Endpoint wraps the publisher's endpoint
+	calls := 0
+	counting := func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			return next(ctx, request)
+		}
+	}
+	counted := counting(uppercase)
+	counted(context.Background(), uppercaseRequest{S: "a"})
+	if calls != 1 {
+		return false
+	}
+	missing := NewPublisher(client, "svc.missing", EncodeJSONRequest, decodeUppercaseResponse).Endpoint()
+	if _, err := missing(context.Background(), uppercaseRequest{S: "x"}); err != ErrNoResponders {
+		return false
+	}
+
+	// Event-driven: JetStream events run the endpoint, failures are retried
+	js, _ := nc.JetStream()
+	js.AddStream(&StreamConfig{Name: "SIGNUPS", Subjects: []string{"signups"}})
+	var mu sync.Mutex
+	var welcomed []string
+	failOnce := true
+	welcome := func(ctx context.Context, request interface{}) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		name := request.(uppercaseRequest).S
+		if name == "bob" && failOnce {
+			failOnce = false
+			return nil, errors.New("mail server down")
+		}
+		welcomed = append(welcomed, name)
+		return nil, nil
+	}
+	events := NewSubscriber(welcome, decodeUppercaseRequest, EncodeJSONResponse)
+	if _, err := js.Subscribe("signups", events.ServeMsg(nc), Durable("welcomer")); err != nil {
+		return false
+	}
+	js.Publish("signups", []byte(`{"s":"alice"}`))
+	js.Publish("signups", []byte(`{"s":"bob"}`))
+	if !waitFor(func() bool { mu.Lock(); defer mu.Unlock(); return len(welcomed) == 2 }) {
+		return false
+	}
+	if !waitFor(func() bool { info, _ := js.ConsumerInfo("SIGNUPS", "welcomer"); return info.NumAckPending == 0 }) {
+		return false
+	}
+	// Plain events without a reply subject are fire-and-forget
+	nc.Subscribe("notify", events.ServeMsg(nc))
+	nc.Publish("notify", []byte(`{"s":"carol"}`))
+	return waitFor(func() bool { mu.Lock(); defer mu.Unlock(); return len(welcomed) == 3 && welcomed[2] == "carol" })
+}
+
+func main() {
+	fmt.Println("Running NATS Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Connect And Close", testConnectAndClose)
+	runTest("Publish Subscribe", testPublishSubscribe)
+	runTest("Wildcards", testWildcards)
+	runTest("Queue Groups", testQueueGroups)
+	runTest("Request Reply", testRequestReply)
+	runTest("Subscription Limits", testSubscriptionLimits)
+	runTest("Drain", testDrain)
+	runTest("JetStream Streams", testJetStreamStreams)
+	runTest("JetStream Push Consumers", testJetStreamPushConsumers)
+	runTest("JetStream Redelivery", testJetStreamRedelivery)
+	runTest("JetStream Pull And Work Queue", testJetStreamPullAndWorkQueue)
+	runTest("Go-kit Transport", testGoKitTransport)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1475 @@
+package main
+
+// Developed by PowerShield, as an alternative to gorilla/mux
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMethodMismatch is returned when the method in the request does
+	// not match the method defined against the route
+	ErrMethodMismatch = errors.New("method is not allowed")
+
+	// ErrNotFound is returned when no route match is found
+	ErrNotFound = errors.New("no matching route was found")
+
+	// SkipRouter is returned by a WalkFunc to skip the router it was
+	// called for and the routes below it
+	SkipRouter = errors.New("skip this router")
+)
+
+// MiddlewareFunc wraps the handler of a matched route
+type MiddlewareFunc func(http.Handler) http.Handler
+
+// Middleware lets a MiddlewareFunc be used where a middleware is expected
+func (mw MiddlewareFunc) Middleware(handler http.Handler) http.Handler {
+	return mw(handler)
+}
+
+// RouteMatch stores information about a matched route
+type RouteMatch struct {
+	Route   *Route
+	Handler http.Handler
+	Vars    map[string]string
+
+	// MatchErr is set to ErrMethodMismatch when a route matched everything
+	// but the method, or ErrNotFound when nothing matched
+	MatchErr error
+}
+
+// matcher is one condition a request must meet to match a route
+type matcher interface {
+	Match(req *http.Request, match *RouteMatch) bool
+}
+
+// routeConf holds the settings a subrouter's routes inherit from the
+// route the subrouter was created on
+type routeConf struct {
+	strictSlash bool
+	skipClean   bool
+	regexp      routeRegexpGroup
+	matchers    []matcher
+	buildScheme string
+}
+
+func copyRouteConf(conf routeConf) routeConf {
+	c := conf
+	c.regexp.queries = append([]*routeRegexp(nil), conf.regexp.queries...)
+	c.matchers = append([]matcher(nil), conf.matchers...)
+	return c
+}
+
+// Router registers routes to be matched and dispatches a handler. It
+// implements http.Handler, so it can be served directly or through
+// NewTestRequest(...).Do(router).
+type Router struct {
+	// NotFoundHandler is used when no route matches; http.NotFoundHandler
+	// by default
+	NotFoundHandler http.Handler
+
+	// MethodNotAllowedHandler is used when a route matches everything but
+	// the method; a plain 405 by default
+	MethodNotAllowedHandler http.Handler
+
+	routes      []*Route
+	namedRoutes map[string]*Route
+	middlewares []MiddlewareFunc
+
+	routeConf
+}
+
+// NewRouter returns a new router instance
+func NewRouter() *Router {
+	return &Router{namedRoutes: make(map[string]*Route)}
+}
+
+// ServeHTTP dispatches the handler registered in the matched route. Paths
+// are cleaned first: "/a/../b" is redirected to "/b" unless SkipClean is
+// set.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !r.skipClean {
+		p := req.URL.Path
+		if cleaned := cleanPath(p); cleaned != p {
+			u := *req.URL
+			u.Path = cleaned
+			w.Header().Set("Location", u.String())
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+	}
+
+	var match RouteMatch
+	var handler http.Handler
+	if r.Match(req, &match) {
+		handler = match.Handler
+		req = requestWithVars(req, match.Vars)
+		req = requestWithRoute(req, match.Route)
+	}
+	if handler == nil && match.MatchErr == ErrMethodMismatch {
+		handler = methodNotAllowedHandler()
+	}
+	if handler == nil {
+		handler = http.NotFoundHandler()
+	}
+	handler.ServeHTTP(w, req)
+}
+
+// Match reports whether req matches one of the router's routes, filling
+// in match. Middleware is applied to the matched handler here, so a
+// subrouter's middleware runs inside its parent's.
+func (r *Router) Match(req *http.Request, match *RouteMatch) bool {
+	for _, route := range r.routes {
+		if route.Match(req, match) {
+			if match.MatchErr == nil {
+				for i := len(r.middlewares) - 1; i >= 0; i-- {
+					match.Handler = r.middlewares[i].Middleware(match.Handler)
+				}
+			}
+			return true
+		}
+	}
+
+	if match.MatchErr == ErrMethodMismatch {
+		if r.MethodNotAllowedHandler != nil {
+			match.Handler = r.MethodNotAllowedHandler
+			return true
+		}
+		return false
+	}
+
+	if r.NotFoundHandler != nil {
+		match.Handler = r.NotFoundHandler
+		match.MatchErr = ErrNotFound
+		return true
+	}
+	match.MatchErr = ErrNotFound
+	return false
+}
+
+// Get returns a route registered with the given name, or nil
+func (r *Router) Get(name string) *Route {
+	return r.namedRoutes[name]
+}
+
+// StrictSlash makes a route with a trailing slash, like "/path/", also
+// match "/path" and redirect it to "/path/", and the other way round.
+// Subrouters inherit the setting.
+func (r *Router) StrictSlash(value bool) *Router {
+	r.strictSlash = value
+	return r
+}
+
+// SkipClean disables cleaning and redirecting paths like "/a//b"
+func (r *Router) SkipClean(value bool) *Router {
+	r.skipClean = value
+	return r
+}
+
+// Use appends middleware to the chain. Middleware only runs when a route
+// matches, including for method mismatches handled by
+// MethodNotAllowedHandler.
+func (r *Router) Use(mwf ...MiddlewareFunc) {
+	r.middlewares = append(r.middlewares, mwf...)
+}
+
+// NewRoute registers an empty route
+func (r *Router) NewRoute() *Route {
+	route := &Route{routeConf: copyRouteConf(r.routeConf), namedRoutes: r.namedRoutes}
+	r.routes = append(r.routes, route)
+	return route
+}
+
+// Name registers a new route with a name
+func (r *Router) Name(name string) *Route {
+	return r.NewRoute().Name(name)
+}
+
+// Handle registers a new route with a matcher for the URL path
+func (r *Router) Handle(path string, handler http.Handler) *Route {
+	return r.NewRoute().Path(path).Handler(handler)
+}
+
+// HandleFunc registers a new route with a matcher for the URL path
+func (r *Router) HandleFunc(path string, f func(http.ResponseWriter, *http.Request)) *Route {
+	return r.NewRoute().Path(path).HandlerFunc(f)
+}
+
+// Headers registers a new route with a matcher for request header values
+func (r *Router) Headers(pairs ...string) *Route {
+	return r.NewRoute().Headers(pairs...)
+}
+
+// Host registers a new route with a matcher for the URL host
+func (r *Router) Host(tpl string) *Route {
+	return r.NewRoute().Host(tpl)
+}
+
+// MatcherFunc registers a new route with a custom matcher function
+func (r *Router) MatcherFunc(f MatcherFunc) *Route {
+	return r.NewRoute().MatcherFunc(f)
+}
+
+// Methods registers a new route with a matcher for HTTP methods
+func (r *Router) Methods(methods ...string) *Route {
+	return r.NewRoute().Methods(methods...)
+}
+
+// Path registers a new route with a matcher for the URL path
+func (r *Router) Path(tpl string) *Route {
+	return r.NewRoute().Path(tpl)
+}
+
+// PathPrefix registers a new route with a matcher for the URL path prefix
+func (r *Router) PathPrefix(tpl string) *Route {
+	return r.NewRoute().PathPrefix(tpl)
+}
+
+// Queries registers a new route with a matcher for URL query values
+func (r *Router) Queries(pairs ...string) *Route {
+	return r.NewRoute().Queries(pairs...)
+}
+
+// Schemes registers a new route with a matcher for URL schemes
+func (r *Router) Schemes(schemes ...string) *Route {
+	return r.NewRoute().Schemes(schemes...)
+}
+
+// WalkFunc is called for each route visited by Walk. ancestors holds the
+// routes of the subrouters above route.
+type WalkFunc func(route *Route, router *Router, ancestors []*Route) error
+
+// Walk visits the routes in registration order, descending into
+// subrouters. Returning SkipRouter skips the rest of the current router;
+// any other error stops the walk.
+func (r *Router) Walk(walkFn WalkFunc) error {
+	return r.walk(walkFn, []*Route{})
+}
+
+func (r *Router) walk(walkFn WalkFunc, ancestors []*Route) error {
+	for _, t := range r.routes {
+		err := walkFn(t, r, ancestors)
+		if err == SkipRouter {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, sr := range t.matchers {
+			if h, ok := sr.(*Router); ok {
+				ancestors = append(ancestors, t)
+				err := h.walk(walkFn, ancestors)
+				if err != nil {
+					return err
+				}
+				ancestors = ancestors[:len(ancestors)-1]
+			}
+		}
+	}
+	return nil
+}
+
+// Route stores information to match a request and build URLs
+type Route struct {
+	handler   http.Handler
+	buildOnly bool
+	name      string
+	err       error
+
+	// namedRoutes is shared by the router and all its subrouters
+	namedRoutes map[string]*Route
+
+	routeConf
+}
+
+// Match reports whether req matches the route. A route that matches
+// everything but the method records ErrMethodMismatch and returns false,
+// so a later route can still match.
+func (r *Route) Match(req *http.Request, match *RouteMatch) bool {
+	if r.buildOnly || r.err != nil {
+		return false
+	}
+
+	var matchErr error
+	for _, m := range r.matchers {
+		if matched := m.Match(req, match); !matched {
+			if _, ok := m.(methodMatcher); ok {
+				matchErr = ErrMethodMismatch
+				continue
+			}
+			// A subrouter that found nothing isn't a mismatch of this route
+			if match.MatchErr == ErrNotFound {
+				match.MatchErr = nil
+			}
+			return false
+		}
+	}
+
+	if matchErr != nil {
+		match.MatchErr = matchErr
+		return false
+	}
+
+	if match.MatchErr == ErrMethodMismatch && r.handler != nil {
+		// An earlier route only differed in method; this one matches fully
+		match.MatchErr = nil
+		match.Handler = r.handler
+	}
+
+	if match.Route == nil {
+		match.Route = r
+	}
+	if match.Handler == nil {
+		match.Handler = r.handler
+	}
+	if match.Vars == nil {
+		match.Vars = make(map[string]string)
+	}
+	r.regexp.setMatch(req, match, r)
+	return true
+}
+
+// GetError returns an error from building the route, e.g. a bad template
+func (r *Route) GetError() error {
+	return r.err
+}
+
+// BuildOnly makes the route used only for URL building, never matched
+func (r *Route) BuildOnly() *Route {
+	r.buildOnly = true
+	return r
+}
+
+// Handler sets a handler for the route
+func (r *Route) Handler(handler http.Handler) *Route {
+	if r.err == nil {
+		r.handler = handler
+	}
+	return r
+}
+
+// HandlerFunc sets a handler function for the route
+func (r *Route) HandlerFunc(f func(http.ResponseWriter, *http.Request)) *Route {
+	return r.Handler(http.HandlerFunc(f))
+}
+
+// GetHandler returns the handler for the route, if any
+func (r *Route) GetHandler() http.Handler {
+	return r.handler
+}
+
+// Name sets the name for the route, used to build URLs
+func (r *Route) Name(name string) *Route {
+	if r.name != "" {
+		r.err = fmt.Errorf("mux: route already has name %q, can't set %q", r.name, name)
+	}
+	if r.err == nil {
+		r.name = name
+		r.namedRoutes[name] = r
+	}
+	return r
+}
+
+// GetName returns the name for the route, if any
+func (r *Route) GetName() string {
+	return r.name
+}
+
+func (r *Route) addMatcher(m matcher) *Route {
+	if r.err == nil {
+		r.matchers = append(r.matchers, m)
+	}
+	return r
+}
+
+// addRegexpMatcher adds a host, path or query matcher. Paths in
+// subrouters are appended to the parent's path template.
+func (r *Route) addRegexpMatcher(tpl string, typ regexpType) error {
+	if r.err != nil {
+		return r.err
+	}
+	if typ == regexpTypePath || typ == regexpTypePrefix {
+		if len(tpl) > 0 && tpl[0] != '/' {
+			return fmt.Errorf("mux: path must start with a slash, got %q", tpl)
+		}
+		if r.regexp.path != nil {
+			tpl = strings.TrimRight(r.regexp.path.template, "/") + tpl
+		}
+	}
+	rr, err := newRouteRegexp(tpl, typ, r.strictSlash)
+	if err != nil {
+		return err
+	}
+	for _, q := range r.regexp.queries {
+		if err = uniqueVars(rr.varsN, q.varsN); err != nil {
+			return err
+		}
+	}
+	if typ == regexpTypeHost {
+		if r.regexp.path != nil {
+			if err = uniqueVars(rr.varsN, r.regexp.path.varsN); err != nil {
+				return err
+			}
+		}
+		r.regexp.host = rr
+	} else {
+		if r.regexp.host != nil {
+			if err = uniqueVars(rr.varsN, r.regexp.host.varsN); err != nil {
+				return err
+			}
+		}
+		if typ == regexpTypeQuery {
+			r.regexp.queries = append(r.regexp.queries, rr)
+		} else {
+			r.regexp.path = rr
+		}
+	}
+	r.addMatcher(rr)
+	return nil
+}
+
+// Headers adds a matcher for request header values. Pairs are keys and
+// values; an empty value only requires the header to be present.
+//
+//	r.Headers("Content-Type", "application/json", "X-Requested-With", "")
+func (r *Route) Headers(pairs ...string) *Route {
+	if r.err == nil {
+		var headers map[string]string
+		headers, r.err = mapFromPairsToString(pairs...)
+		return r.addMatcher(headerMatcher(headers))
+	}
+	return r
+}
+
+// HeadersRegexp adds a matcher for header values matching regexps
+//
+//	r.HeadersRegexp("Content-Type", "application/(text|json)")
+func (r *Route) HeadersRegexp(pairs ...string) *Route {
+	if r.err == nil {
+		var headers map[string]*regexp.Regexp
+		headers, r.err = mapFromPairsToRegex(pairs...)
+		return r.addMatcher(headerRegexMatcher(headers))
+	}
+	return r
+}
+
+// Host adds a matcher for the URL host. Variables default to the pattern
+// "[^.]+"; a template without a port matches any port.
+//
+//	r.Host("{subdomain:[a-z]+}.example.com")
+func (r *Route) Host(tpl string) *Route {
+	r.err = r.addRegexpMatcher(tpl, regexpTypeHost)
+	return r
+}
+
+// MatcherFunc is a custom function to match requests
+type MatcherFunc func(*http.Request, *RouteMatch) bool
+
+// Match calls the function
+func (m MatcherFunc) Match(r *http.Request, match *RouteMatch) bool {
+	return m(r, match)
+}
+
+// MatcherFunc adds a custom function to match requests
+func (r *Route) MatcherFunc(f MatcherFunc) *Route {
+	return r.addMatcher(f)
+}
+
+// Methods adds a matcher for HTTP methods
+func (r *Route) Methods(methods ...string) *Route {
+	for k, v := range methods {
+		methods[k] = strings.ToUpper(v)
+	}
+	return r.addMatcher(methodMatcher(methods))
+}
+
+// Path adds a matcher for the URL path. Variables are written as
+// {name} or {name:pattern}, with "[^/]+" as the default pattern.
+//
+//	r.Path("/articles/{category}/{id:[0-9]+}")
+func (r *Route) Path(tpl string) *Route {
+	r.err = r.addRegexpMatcher(tpl, regexpTypePath)
+	return r
+}
+
+// PathPrefix adds a matcher for the URL path prefix. It matches whole
+// strings, so "/foo" also matches "/foobar"; end it with a slash to match
+// only below a directory.
+func (r *Route) PathPrefix(tpl string) *Route {
+	r.err = r.addRegexpMatcher(tpl, regexpTypePrefix)
+	return r
+}
+
+// Queries adds a matcher for URL query values. Pairs are keys and
+// values; values may contain variables, and an empty value only requires
+// the key to be present.
+//
+//	r.Queries("page", "{page:[0-9]+}", "filter", "")
+func (r *Route) Queries(pairs ...string) *Route {
+	length := len(pairs)
+	if length%2 != 0 {
+		r.err = fmt.Errorf("mux: number of parameters must be multiple of 2, got %v", pairs)
+		return r
+	}
+	for i := 0; i < length; i += 2 {
+		if r.err = r.addRegexpMatcher(pairs[i]+"="+pairs[i+1], regexpTypeQuery); r.err != nil {
+			return r
+		}
+	}
+	return r
+}
+
+// Schemes adds a matcher for URL schemes. The first scheme is also used
+// when building URLs.
+func (r *Route) Schemes(schemes ...string) *Route {
+	for k, v := range schemes {
+		schemes[k] = strings.ToLower(v)
+	}
+	if len(schemes) > 0 {
+		r.buildScheme = schemes[0]
+	}
+	return r.addMatcher(schemeMatcher(schemes))
+}
+
+// Subrouter creates a router for the route. Its routes only match when
+// this route does, and inherit its host, path prefix and other matchers.
+//
+//	s := r.Host("api.example.com").PathPrefix("/v1").Subrouter()
+//	s.HandleFunc("/users/{id}", getUser) // matches api.example.com/v1/users/42
+func (r *Route) Subrouter() *Router {
+	router := &Router{routeConf: copyRouteConf(r.routeConf), namedRoutes: r.namedRoutes}
+	r.addMatcher(router)
+	return router
+}
+
+// URL builds a URL for the route from variable name/value pairs
+//
+//	r.HandleFunc("/articles/{category}/{id:[0-9]+}", handler).Name("article")
+//	url, err := r.Get("article").URL("category", "technology", "id", "42")
+//	// "/articles/technology/42"
+func (r *Route) URL(pairs ...string) (*url.URL, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	values, err := r.prepareVars(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	var scheme, host, p string
+	queries := make([]string, 0, len(r.regexp.queries))
+	if r.regexp.host != nil {
+		if host, err = r.regexp.host.url(values); err != nil {
+			return nil, err
+		}
+		scheme = "http"
+		if r.buildScheme != "" {
+			scheme = r.buildScheme
+		}
+	}
+	if r.regexp.path != nil {
+		if p, err = r.regexp.path.url(values); err != nil {
+			return nil, err
+		}
+	}
+	for _, q := range r.regexp.queries {
+		var query string
+		if query, err = q.url(values); err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     p,
+		RawQuery: strings.Join(queries, "&"),
+	}, nil
+}
+
+// URLHost builds the host part of the URL for a route with a Host matcher
+func (r *Route) URLHost(pairs ...string) (*url.URL, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.regexp.host == nil {
+		return nil, errors.New("mux: route doesn't have a host")
+	}
+	values, err := r.prepareVars(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	host, err := r.regexp.host.url(values)
+	if err != nil {
+		return nil, err
+	}
+	u := &url.URL{Scheme: "http", Host: host}
+	if r.buildScheme != "" {
+		u.Scheme = r.buildScheme
+	}
+	return u, nil
+}
+
+// URLPath builds the path part of the URL for a route with a Path matcher
+func (r *Route) URLPath(pairs ...string) (*url.URL, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.regexp.path == nil {
+		return nil, errors.New("mux: route doesn't have a path")
+	}
+	values, err := r.prepareVars(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	p, err := r.regexp.path.url(values)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Path: p}, nil
+}
+
+// GetPathTemplate returns the path template used to build the route
+func (r *Route) GetPathTemplate() (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.regexp.path == nil {
+		return "", errors.New("mux: route doesn't have a path")
+	}
+	return r.regexp.path.template, nil
+}
+
+// GetPathRegexp returns the expanded regular expression used to match
+// the route path
+func (r *Route) GetPathRegexp() (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.regexp.path == nil {
+		return "", errors.New("mux: route does not have a path")
+	}
+	return r.regexp.path.regexp.String(), nil
+}
+
+// GetHostTemplate returns the host template used to build the route
+func (r *Route) GetHostTemplate() (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	if r.regexp.host == nil {
+		return "", errors.New("mux: route doesn't have a host")
+	}
+	return r.regexp.host.template, nil
+}
+
+// GetQueriesTemplates returns the query templates, as "key=value"
+func (r *Route) GetQueriesTemplates() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.regexp.queries == nil {
+		return nil, errors.New("mux: route doesn't have queries")
+	}
+	queries := make([]string, 0, len(r.regexp.queries))
+	for _, query := range r.regexp.queries {
+		queries = append(queries, query.template)
+	}
+	return queries, nil
+}
+
+// GetMethods returns the methods the route matches
+func (r *Route) GetMethods() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	for _, m := range r.matchers {
+		if methods, ok := m.(methodMatcher); ok {
+			return []string(methods), nil
+		}
+	}
+	return nil, errors.New("mux: route doesn't have methods")
+}
+
+// prepareVars turns name/value pairs into a map
+func (r *Route) prepareVars(pairs ...string) (map[string]string, error) {
+	return mapFromPairsToString(pairs...)
+}
+
+// regexpType says which part of the request a routeRegexp matches
+type regexpType int
+
+const (
+	regexpTypePath regexpType = iota
+	regexpTypeHost
+	regexpTypePrefix
+	regexpTypeQuery
+)
+
+// routeRegexp matches one template: a host, a path, a path prefix or a
+// "key=value" query pair
+type routeRegexp struct {
+	template         string
+	regexpType       regexpType
+	strictSlash      bool
+	regexp           *regexp.Regexp
+	reverse          string
+	varsN            []string
+	varsR            []*regexp.Regexp
+	wildcardHostPort bool
+}
+
+// newRouteRegexp parses a template like "/articles/{category}/{id:[0-9]+}"
+// into a regexp with one group per variable and a reverse format string
+// for building URLs
+func newRouteRegexp(tpl string, typ regexpType, strictSlash bool) (*routeRegexp, error) {
+	idxs, err := braceIndices(tpl)
+	if err != nil {
+		return nil, err
+	}
+	template := tpl
+	defaultPattern := "[^/]+"
+	if typ == regexpTypeQuery {
+		defaultPattern = ".*"
+	} else if typ == regexpTypeHost {
+		defaultPattern = "[^.]+"
+	}
+	if typ != regexpTypePath {
+		strictSlash = false
+	}
+	endSlash := false
+	if strictSlash && strings.HasSuffix(tpl, "/") {
+		tpl = tpl[:len(tpl)-1]
+		endSlash = true
+	}
+
+	varsN := make([]string, len(idxs)/2)
+	varsR := make([]*regexp.Regexp, len(idxs)/2)
+	var pattern, reverse strings.Builder
+	pattern.WriteByte('^')
+	var end int
+	for i := 0; i < len(idxs); i += 2 {
+		raw := tpl[end:idxs[i]]
+		end = idxs[i+1]
+		parts := strings.SplitN(tpl[idxs[i]+1:end-1], ":", 2)
+		name := parts[0]
+		patt := defaultPattern
+		if len(parts) == 2 {
+			patt = parts[1]
+		}
+		if name == "" || patt == "" {
+			return nil, fmt.Errorf("mux: missing name or pattern in %q", tpl[idxs[i]:end])
+		}
+		fmt.Fprintf(&pattern, "%s(?P<%s>%s)", regexp.QuoteMeta(raw), varGroupName(i/2), patt)
+		fmt.Fprintf(&reverse, "%s%%s", strings.ReplaceAll(raw, "%", "%%"))
+		varsN[i/2] = name
+		if varsR[i/2], err = regexp.Compile(fmt.Sprintf("^%s$", patt)); err != nil {
+			return nil, err
+		}
+	}
+	raw := tpl[end:]
+	pattern.WriteString(regexp.QuoteMeta(raw))
+	if strictSlash {
+		pattern.WriteString("[/]?")
+	}
+	if typ == regexpTypeQuery {
+		// "key=" matches any value, including none
+		if queryVal := strings.SplitN(template, "=", 2)[1]; queryVal == "" {
+			pattern.WriteString(defaultPattern)
+		}
+	}
+	if typ != regexpTypePrefix {
+		pattern.WriteByte('$')
+	}
+	reverse.WriteString(strings.ReplaceAll(raw, "%", "%%"))
+	if endSlash {
+		reverse.WriteByte('/')
+	}
+
+	reg, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+	if reg.NumSubexp() != len(idxs)/2 {
+		return nil, fmt.Errorf("mux: route %s contains capture groups in its regexp. "+
+			"Only non-capturing groups are accepted: e.g. (?:pattern) instead of (pattern)", template)
+	}
+
+	return &routeRegexp{
+		template:         template,
+		regexpType:       typ,
+		strictSlash:      strictSlash,
+		regexp:           reg,
+		reverse:          reverse.String(),
+		varsN:            varsN,
+		varsR:            varsR,
+		wildcardHostPort: typ == regexpTypeHost && !hasPort(template, idxs),
+	}, nil
+}
+
+// Match matches the regexp against the URL host, path or query
+func (r *routeRegexp) Match(req *http.Request, match *RouteMatch) bool {
+	if r.regexpType == regexpTypeHost {
+		host := getHost(req)
+		if r.wildcardHostPort {
+			if i := strings.Index(host, ":"); i != -1 {
+				host = host[:i]
+			}
+		}
+		return r.regexp.MatchString(host)
+	}
+	if r.regexpType == regexpTypeQuery {
+		return r.matchQueryString(req)
+	}
+	return r.regexp.MatchString(req.URL.Path)
+}
+
+// url builds a URL part from variable values, checking each against its
+// pattern
+func (r *routeRegexp) url(values map[string]string) (string, error) {
+	urlValues := make([]interface{}, len(r.varsN))
+	for k, v := range r.varsN {
+		value, ok := values[v]
+		if !ok {
+			return "", fmt.Errorf("mux: missing route variable %q", v)
+		}
+		if !r.varsR[k].MatchString(value) {
+			return "", fmt.Errorf("mux: variable %q doesn't match, expected %q", value, r.varsR[k].String())
+		}
+		if r.regexpType == regexpTypeQuery {
+			value = url.QueryEscape(value)
+		}
+		urlValues[k] = value
+	}
+	return fmt.Sprintf(r.reverse, urlValues...), nil
+}
+
+// getURLQuery returns the first "key=value" pair in the query string for
+// this regexp's key, or "" if the key is missing
+func (r *routeRegexp) getURLQuery(req *http.Request) string {
+	if r.regexpType != regexpTypeQuery {
+		return ""
+	}
+	templateKey := strings.SplitN(r.template, "=", 2)[0]
+	val, ok := findFirstQueryKey(req.URL.RawQuery, templateKey)
+	if ok {
+		return templateKey + "=" + val
+	}
+	return ""
+}
+
+func (r *routeRegexp) matchQueryString(req *http.Request) bool {
+	return r.regexp.MatchString(r.getURLQuery(req))
+}
+
+// findFirstQueryKey returns the first unescaped value for key in rawQuery
+func findFirstQueryKey(rawQuery, key string) (string, bool) {
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v := pair, ""
+		if i := strings.Index(pair, "="); i >= 0 {
+			k, v = pair[:i], pair[i+1:]
+		}
+		if unescaped, err := url.QueryUnescape(k); err == nil && unescaped == key {
+			value, err := url.QueryUnescape(v)
+			if err != nil {
+				return "", false
+			}
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// routeRegexpGroup groups the route's regexps, which subrouter routes copy
+type routeRegexpGroup struct {
+	host    *routeRegexp
+	path    *routeRegexp
+	queries []*routeRegexp
+}
+
+// setMatch extracts the variables from the request and, with strict
+// slashes, sets a redirect when the trailing slash differs
+func (v routeRegexpGroup) setMatch(req *http.Request, m *RouteMatch, r *Route) {
+	if v.host != nil {
+		host := getHost(req)
+		if v.host.wildcardHostPort {
+			if i := strings.Index(host, ":"); i != -1 {
+				host = host[:i]
+			}
+		}
+		extractVars(host, v.host.regexp.FindStringSubmatchIndex(host), v.host.varsN, m.Vars)
+	}
+	p := req.URL.Path
+	if v.path != nil {
+		matches := v.path.regexp.FindStringSubmatchIndex(p)
+		if len(matches) > 0 {
+			extractVars(p, matches, v.path.varsN, m.Vars)
+			if v.path.strictSlash {
+				p1 := strings.HasSuffix(p, "/")
+				p2 := strings.HasSuffix(v.path.template, "/")
+				if p1 != p2 {
+					u := *req.URL
+					if p1 {
+						u.Path = u.Path[:len(u.Path)-1]
+					} else {
+						u.Path += "/"
+					}
+					m.Handler = http.RedirectHandler(u.String(), http.StatusMovedPermanently)
+				}
+			}
+		}
+	}
+	for _, q := range v.queries {
+		queryURL := q.getURLQuery(req)
+		matches := q.regexp.FindStringSubmatchIndex(queryURL)
+		if len(matches) > 0 {
+			extractVars(queryURL, matches, q.varsN, m.Vars)
+		}
+	}
+}
+
+func extractVars(input string, matches []int, names []string, output map[string]string) {
+	for i, name := range names {
+		if 2*i+3 < len(matches) && matches[2*i+2] >= 0 {
+			output[name] = input[matches[2*i+2]:matches[2*i+3]]
+		}
+	}
+}
+
+// getHost returns the host from an absolute URL, or the Host header
+func getHost(r *http.Request) string {
+	if r.URL.IsAbs() {
+		return r.URL.Host
+	}
+	return r.Host
+}
+
+// braceIndices returns the first and last index of each top-level {...}
+// in s, so patterns may contain braces like {id:[0-9]{3}}
+func braceIndices(s string) ([]int, error) {
+	var level, idx int
+	var idxs []int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			if level++; level == 1 {
+				idx = i
+			}
+		case '}':
+			if level--; level == 0 {
+				idxs = append(idxs, idx, i+1)
+			} else if level < 0 {
+				return nil, fmt.Errorf("mux: unbalanced braces in %q", s)
+			}
+		}
+	}
+	if level != 0 {
+		return nil, fmt.Errorf("mux: unbalanced braces in %q", s)
+	}
+	return idxs, nil
+}
+
+// hasPort reports whether a host template has a port, ignoring colons
+// inside variables like {name:pattern}
+func hasPort(tpl string, idxs []int) bool {
+	end := 0
+	for i := 0; i < len(idxs); i += 2 {
+		if strings.Contains(tpl[end:idxs[i]], ":") {
+			return true
+		}
+		end = idxs[i+1]
+	}
+	return strings.Contains(tpl[end:], ":")
+}
+
+// varGroupName names the regexp group for the idx'th variable
+func varGroupName(idx int) string {
+	return "v" + strconv.Itoa(idx)
+}
+
+// uniqueVars returns an error if two slices share a variable name
+func uniqueVars(s1, s2 []string) error {
+	for _, v1 := range s1 {
+		for _, v2 := range s2 {
+			if v1 == v2 {
+				return fmt.Errorf("mux: duplicated route variable %q", v2)
+			}
+		}
+	}
+	return nil
+}
+
+// headerMatcher matches exact header values
+type headerMatcher map[string]string
+
+func (m headerMatcher) Match(r *http.Request, match *RouteMatch) bool {
+	for k, v := range m {
+		values := r.Header.Values(k)
+		if len(values) == 0 {
+			return false
+		}
+		if v == "" {
+			continue
+		}
+		found := false
+		for _, value := range values {
+			if value == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// headerRegexMatcher matches header values against regexps
+type headerRegexMatcher map[string]*regexp.Regexp
+
+func (m headerRegexMatcher) Match(r *http.Request, match *RouteMatch) bool {
+	for k, re := range m {
+		values := r.Header.Values(k)
+		if len(values) == 0 {
+			return false
+		}
+		if re == nil {
+			continue
+		}
+		found := false
+		for _, value := range values {
+			if re.MatchString(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// methodMatcher matches the request method
+type methodMatcher []string
+
+func (m methodMatcher) Match(r *http.Request, match *RouteMatch) bool {
+	return matchInArray(m, r.Method)
+}
+
+// schemeMatcher matches the URL scheme, falling back to the connection
+// for server requests, which have no scheme in their URL
+type schemeMatcher []string
+
+func (m schemeMatcher) Match(r *http.Request, match *RouteMatch) bool {
+	scheme := r.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	}
+	return matchInArray(m, scheme)
+}
+
+func matchInArray(arr []string, value string) bool {
+	for _, v := range arr {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPairs(pairs ...string) (int, error) {
+	length := len(pairs)
+	if length%2 != 0 {
+		return length, fmt.Errorf("mux: number of parameters must be multiple of 2, got %v", pairs)
+	}
+	return length, nil
+}
+
+func mapFromPairsToString(pairs ...string) (map[string]string, error) {
+	length, err := checkPairs(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, length/2)
+	for i := 0; i < length; i += 2 {
+		m[pairs[i]] = pairs[i+1]
+	}
+	return m, nil
+}
+
+func mapFromPairsToRegex(pairs ...string) (map[string]*regexp.Regexp, error) {
+	length, err := checkPairs(pairs...)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]*regexp.Regexp, length/2)
+	for i := 0; i < length; i += 2 {
+		var re *regexp.Regexp
+		if pairs[i+1] != "" {
+			if re, err = regexp.Compile(pairs[i+1]); err != nil {
+				return nil, err
+			}
+		}
+		m[pairs[i]] = re
+	}
+	return m, nil
+}
+
+// cleanPath returns the canonical path for p, keeping a trailing slash
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
+
+func methodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+}
+
+// contextKey is the type of the request context keys set by the router
+type contextKey int
+
+const (
+	varsKey contextKey = iota
+	routeKey
+)
+
+// Vars returns the route variables for the current request, if any
+func Vars(r *http.Request) map[string]string {
+	if rv := r.Context().Value(varsKey); rv != nil {
+		return rv.(map[string]string)
+	}
+	return nil
+}
+
+// CurrentRoute returns the matched route for the current request, if any
+func CurrentRoute(r *http.Request) *Route {
+	if rv := r.Context().Value(routeKey); rv != nil {
+		return rv.(*Route)
+	}
+	return nil
+}
+
+// SetURLVars sets the URL variables for r, so handlers can be tested
+// without a router
+func SetURLVars(r *http.Request, val map[string]string) *http.Request {
+	return requestWithVars(r, val)
+}
+
+func requestWithVars(r *http.Request, vars map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), varsKey, vars))
+}
+
+func requestWithRoute(r *http.Request, route *Route) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), routeKey, route))
+}
+
+// CORSMethodMiddleware sets the Access-Control-Allow-Methods header to
+// the methods of all routes matching the request path, not just the one
+// that matched
+func CORSMethodMiddleware(r *Router) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			allMethods, err := getAllMethodsForRoute(r, req)
+			if err == nil {
+				for _, v := range allMethods {
+					if v == http.MethodOptions {
+						w.Header().Set("Access-Control-Allow-Methods", strings.Join(allMethods, ","))
+					}
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// getAllMethodsForRoute returns the methods of the routes that match
+// req ignoring the method
+func getAllMethodsForRoute(r *Router, req *http.Request) ([]string, error) {
+	var allMethods []string
+	for _, route := range r.routes {
+		var match RouteMatch
+		if route.Match(req, &match) || match.MatchErr == ErrMethodMismatch {
+			methods, err := route.GetMethods()
+			if err != nil {
+				return nil, err
+			}
+			allMethods = append(allMethods, methods...)
+		}
+	}
+	return allMethods, nil
+}
+
+// TestingT is the subset of *testing.T used by TestResponse assertions;
+// the Testify emulator's TestingT satisfies it too
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// TestRequest builds requests for tests fluently. It is the same harness
+// as the Gin emulator's, serving any http.Handler:
+//
+//	resp := NewTestRequest("GET", "https://api.example.com/v1/users/42").
+//		Header("Accept", "application/json").
+//		Do(router)
+type TestRequest struct {
+	method   string
+	path     string
+	query    url.Values
+	header   http.Header
+	cookies  []*http.Cookie
+	body     []byte
+	form     url.Values
+	ctx      context.Context
+	buildErr error
+
+	remoteAddr string
+}
+
+// NewTestRequest starts a request; target may be a path with a query
+// string or an absolute URL, which sets the host and scheme
+func NewTestRequest(method, target string) *TestRequest {
+	return &TestRequest{
+		method: method,
+		path:   target,
+		query:  url.Values{},
+		header: http.Header{},
+		form:   url.Values{},
+
+		remoteAddr: "192.0.2.1:1234",
+	}
+}
+
+// Header sets a request header
+func (r *TestRequest) Header(key, value string) *TestRequest {
+	r.header.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter
+func (r *TestRequest) Query(key, value string) *TestRequest {
+	r.query.Add(key, value)
+	return r
+}
+
+// Cookie adds a request cookie
+func (r *TestRequest) Cookie(name, value string) *TestRequest {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// RemoteAddr sets the client address, "192.0.2.1:1234" by default
+func (r *TestRequest) RemoteAddr(addr string) *TestRequest {
+	r.remoteAddr = addr
+	return r
+}
+
+// BasicAuth sets HTTP Basic credentials
+func (r *TestRequest) BasicAuth(user, password string) *TestRequest {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(user, password)
+	return r.Header("Authorization", req.Header.Get("Authorization"))
+}
+
+// BearerToken sets an "Authorization: Bearer" header
+func (r *TestRequest) BearerToken(token string) *TestRequest {
+	return r.Header("Authorization", "Bearer "+token)
+}
+
+// Body sets a raw body and its content type
+func (r *TestRequest) Body(data []byte, contentType string) *TestRequest {
+	r.body = data
+	if contentType != "" {
+		r.header.Set("Content-Type", contentType)
+	}
+	return r
+}
+
+// JSON sets a JSON-encoded body
+func (r *TestRequest) JSON(v interface{}) *TestRequest {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.buildErr = err
+	}
+	return r.Body(data, "application/json")
+}
+
+// FormField adds a url-encoded form field
+func (r *TestRequest) FormField(key, value string) *TestRequest {
+	r.form.Add(key, value)
+	return r
+}
+
+// WithContext sets the request context
+func (r *TestRequest) WithContext(ctx context.Context) *TestRequest {
+	r.ctx = ctx
+	return r
+}
+
+// Build returns the request as an *http.Request
+func (r *TestRequest) Build() (*http.Request, error) {
+	if r.buildErr != nil {
+		return nil, r.buildErr
+	}
+	header := r.header.Clone()
+	body := r.body
+	if len(r.form) > 0 {
+		body = []byte(r.form.Encode())
+		header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	target := r.path
+	if len(r.query) > 0 {
+		separator := "?"
+		if strings.Contains(target, "?") {
+			separator = "&"
+		}
+		target += separator + r.query.Encode()
+	}
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	req.RemoteAddr = r.remoteAddr
+	if req.Host == "" {
+		req.Host = "example.com"
+	}
+	return req, nil
+}
+
+// Do serves the request through h and records the response. It panics
+// if the request cannot be built.
+func (r *TestRequest) Do(h http.Handler) *TestResponse {
+	req, err := r.Build()
+	if err != nil {
+		panic(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return &TestResponse{ResponseRecorder: rec}
+}
+
+// TestResponse is a recorded response with assertion helpers. Each
+// assertion reports failures through t.Errorf and returns whether it held.
+type TestResponse struct {
+	*httptest.ResponseRecorder
+}
+
+// BodyString returns the body as a string
+func (r *TestResponse) BodyString() string {
+	return r.Body.String()
+}
+
+// DecodeJSON unmarshals the body into v
+func (r *TestResponse) DecodeJSON(v interface{}) error {
+	return json.Unmarshal(r.Body.Bytes(), v)
+}
+
+// Cookie returns the named Set-Cookie cookie, or nil
+func (r *TestResponse) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// AssertStatus checks the status code
+func (r *TestResponse) AssertStatus(t TestingT, code int) bool {
+	if r.Code != code {
+		t.Errorf("expected status %d, got %d (body: %q)", code, r.Code, r.BodyString())
+		return false
+	}
+	return true
+}
+
+// AssertHeader checks a response header value
+func (r *TestResponse) AssertHeader(t TestingT, key, value string) bool {
+	if got := r.Header().Get(key); got != value {
+		t.Errorf("expected header %s: %q, got %q", key, value, got)
+		return false
+	}
+	return true
+}
+
+// AssertBodyContains checks that the body contains substr
+func (r *TestResponse) AssertBodyContains(t TestingT, substr string) bool {
+	if !strings.Contains(r.BodyString(), substr) {
+		t.Errorf("expected body to contain %q, got %q", substr, r.BodyString())
+		return false
+	}
+	return true
+}
+
+// AssertJSON checks that the body is JSON equal to expected, ignoring
+// formatting and key order
+func (r *TestResponse) AssertJSON(t TestingT, expected interface{}) bool {
+	var got interface{}
+	if err := json.Unmarshal(r.Body.Bytes(), &got); err != nil {
+		t.Errorf("expected JSON body, got %q: %v", r.BodyString(), err)
+		return false
+	}
+	want, err := normalize(expected)
+	if err != nil {
+		t.Errorf("cannot encode expected JSON: %v", err)
+		return false
+	}
+	got, _ = normalize(got)
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("expected JSON %s, got %s", wantJSON, gotJSON)
+		return false
+	}
+	return true
+}
+
+// normalize round-trips obj through JSON so values compare by content
+func normalize(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&generic)
+	return generic, err
+}
@@ -0,0 +1,418 @@
+package main
+
+// Developed by PowerShield, as an alternative to gorilla/mux
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// recordingT collects assertion failures
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// echoVars writes the route variables as sorted key=value pairs
+func echoVars(w http.ResponseWriter, r *http.Request) {
+	vars := Vars(r)
+	var parts []string
+	for _, key := range []string{"subdomain", "category", "id", "page", "name", "path"} {
+		if value, ok := vars[key]; ok {
+			parts = append(parts, key+"="+value)
+		}
+	}
+	fmt.Fprint(w, strings.Join(parts, " "))
+}
+
+func writeString(s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, s)
+	}
+}
+
+// Test path variables with and without patterns
+func testPathVariables() bool {
+	r := NewRouter()
+	r.HandleFunc("/articles/{category}/{id:[0-9]+}", echoVars)
+	r.HandleFunc("/codes/{id:[A-Z]{3}}", echoVars)
+
+	resp := NewTestRequest("GET", "/articles/tech/42").Do(r)
+	if resp.Code != 200 || resp.BodyString() != "category=tech id=42" {
+		return false
+	}
+	if NewTestRequest("GET", "/articles/tech/abc").Do(r).Code != 404 {
+		return false
+	}
+	resp = NewTestRequest("GET", "/codes/ABC").Do(r)
+	return resp.BodyString() == "id=ABC" && NewTestRequest("GET", "/codes/ABCD").Do(r).Code == 404
+}
+
+// Test method matching and 405 responses
+func testMethods() bool {
+	r := NewRouter()
+	r.HandleFunc("/users", writeString("list")).Methods("GET")
+	r.HandleFunc("/users", writeString("create")).Methods("post")
+
+	if NewTestRequest("GET", "/users").Do(r).BodyString() != "list" {
+		return false
+	}
+	if NewTestRequest("POST", "/users").Do(r).BodyString() != "create" {
+		return false
+	}
+	if NewTestRequest("DELETE", "/users").Do(r).Code != http.StatusMethodNotAllowed {
+		return false
+	}
+
+	r.MethodNotAllowedHandler = writeString("custom 405")
+	r.NotFoundHandler = writeString("custom 404")
+	return NewTestRequest("DELETE", "/users").Do(r).BodyString() == "custom 405" &&
+		NewTestRequest("GET", "/missing").Do(r).BodyString() == "custom 404"
+}
+
+// Test host, scheme, header and query matchers
+func testMatchers() bool {
+	r := NewRouter()
+	r.Host("{subdomain:[a-z]+}.example.com").Path("/").HandlerFunc(echoVars)
+	r.HandleFunc("/secure", writeString("https")).Schemes("https")
+	r.HandleFunc("/api", writeString("json")).Headers("Content-Type", "application/json")
+	r.HandleFunc("/api", writeString("xml")).HeadersRegexp("Content-Type", "^(application|text)/xml$")
+	r.HandleFunc("/search", echoVars).Queries("page", "{page:[0-9]+}", "name", "")
+	r.HandleFunc("/internal", writeString("internal")).MatcherFunc(func(req *http.Request, m *RouteMatch) bool {
+		return strings.HasPrefix(req.RemoteAddr, "10.")
+	})
+
+	if NewTestRequest("GET", "http://shop.example.com:8080/").Do(r).BodyString() != "subdomain=shop" {
+		return false
+	}
+	if NewTestRequest("GET", "http://Shop1.example.com/").Do(r).Code != 404 {
+		return false
+	}
+	if NewTestRequest("GET", "https://example.com/secure").Do(r).BodyString() != "https" ||
+		NewTestRequest("GET", "http://example.com/secure").Do(r).Code != 404 {
+		return false
+	}
+	if NewTestRequest("POST", "/api").Header("Content-Type", "application/json").Do(r).BodyString() != "json" ||
+		NewTestRequest("POST", "/api").Header("Content-Type", "text/xml").Do(r).BodyString() != "xml" ||
+		NewTestRequest("POST", "/api").Do(r).Code != 404 {
+		return false
+	}
+	if NewTestRequest("GET", "/search").Query("page", "3").Query("name", "").Do(r).BodyString() != "page=3" ||
+		NewTestRequest("GET", "/search?page=x&name=a").Do(r).Code != 404 ||
+		NewTestRequest("GET", "/search?page=1").Do(r).Code != 404 {
+		return false
+	}
+	return NewTestRequest("GET", "/internal").RemoteAddr("10.0.0.5:80").Do(r).BodyString() == "internal" &&
+		NewTestRequest("GET", "/internal").Do(r).Code == 404
+}
+
+// Test subrouters inherit host and path prefix
+func testSubrouters() bool {
+	r := NewRouter()
+	api := r.Host("api.example.com").PathPrefix("/v1").Subrouter()
+	api.HandleFunc("/users/{id}", echoVars).Methods("GET")
+	users := api.PathPrefix("/admin").Subrouter()
+	users.HandleFunc("/{name}", echoVars)
+	r.PathPrefix("/static/").HandlerFunc(writeString("static"))
+
+	resp := NewTestRequest("GET", "http://api.example.com/v1/users/42").Do(r)
+	if resp.BodyString() != "id=42" {
+		return false
+	}
+	if NewTestRequest("GET", "http://www.example.com/v1/users/42").Do(r).Code != 404 {
+		return false
+	}
+	if NewTestRequest("POST", "http://api.example.com/v1/users/42").Do(r).Code != 405 {
+		return false
+	}
+	if NewTestRequest("GET", "http://api.example.com/v1/admin/root").Do(r).BodyString() != "name=root" {
+		return false
+	}
+	return NewTestRequest("GET", "/static/css/site.css").Do(r).BodyString() == "static" &&
+		NewTestRequest("GET", "/staticfile").Do(r).Code == 404
+}
+
+// Test named routes and URL building
+func testURLBuilding() bool {
+	r := NewRouter()
+	r.HandleFunc("/articles/{category}/{id:[0-9]+}", echoVars).Name("article")
+	r.Host("{subdomain}.example.com").Path("/users/{id}").Queries("tab", "{tab}").
+		Schemes("https").Name("profile")
+	s := r.PathPrefix("/v2").Subrouter()
+	s.HandleFunc("/items/{id}", echoVars).Name("item")
+
+	u, err := r.Get("article").URL("category", "tech", "id", "42")
+	if err != nil || u.String() != "/articles/tech/42" {
+		return false
+	}
+	u, err = r.Get("profile").URL("subdomain", "eu", "id", "7", "tab", "a b")
+	if err != nil || u.String() != "https://eu.example.com/users/7?tab=a+b" {
+		return false
+	}
+	u, err = r.Get("item").URLPath("id", "x")
+	if err != nil || u.Path != "/v2/items/x" {
+		return false
+	}
+	host, err := r.Get("profile").URLHost("subdomain", "us")
+	if err != nil || host.String() != "https://us.example.com" {
+		return false
+	}
+
+	if _, err := r.Get("article").URL("category", "tech", "id", "abc"); err == nil || !strings.Contains(err.Error(), "doesn't match") {
+		return false
+	}
+	if _, err := r.Get("article").URL("category", "tech"); err == nil || !strings.Contains(err.Error(), `missing route variable "id"`) {
+		return false
+	}
+	if _, err := r.Get("article").URL("odd"); err == nil {
+		return false
+	}
+	return r.Get("missing") == nil
+}
+
+// Test route introspection helpers
+func testRouteTemplates() bool {
+	r := NewRouter()
+	route := r.Host("{sub}.example.com").Path("/a/{id:[0-9]+}").Queries("q", "{q}").Methods("get", "put")
+
+	tpl, err := route.GetPathTemplate()
+	if err != nil || tpl != "/a/{id:[0-9]+}" {
+		return false
+	}
+	re, err := route.GetPathRegexp()
+	if err != nil || re != "^/a/(?P<v0>[0-9]+)$" {
+		return false
+	}
+	host, _ := route.GetHostTemplate()
+	queries, _ := route.GetQueriesTemplates()
+	methods, _ := route.GetMethods()
+	if host != "{sub}.example.com" || strings.Join(queries, ",") != "q={q}" || strings.Join(methods, ",") != "GET,PUT" {
+		return false
+	}
+	if _, err := r.NewRoute().GetMethods(); err == nil {
+		return false
+	}
+	_, err = r.NewRoute().GetPathTemplate()
+	return err != nil
+}
+
+// Test template errors are reported on the route
+func testRouteErrors() bool {
+	r := NewRouter()
+	if r.Path("/a/{id").GetError() == nil {
+		return false
+	}
+	if err := r.Path("/a/{id:(x|y)}").GetError(); err == nil || !strings.Contains(err.Error(), "capture groups") {
+		return false
+	}
+	if r.Host("{id}.example.com").Path("/a/{id}").GetError() == nil || r.Path("no-slash").GetError() == nil {
+		return false
+	}
+	if r.Queries("a").GetError() == nil || r.Headers("a").GetError() == nil {
+		return false
+	}
+	if r.Path("/x").Name("one").Name("two").GetError() == nil {
+		return false
+	}
+	// Routes with errors never match
+	bad := r.Path("/b/{").HandlerFunc(writeString("bad"))
+	return bad.GetHandler() == nil && NewTestRequest("GET", "/b/{").Do(r).Code == 404
+}
+
+// Test middleware ordering and scoping
+func testMiddleware() bool {
+	var calls []string
+	tag := func(name string) MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	r := NewRouter()
+	r.Use(tag("root"))
+	r.HandleFunc("/", writeString("home"))
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(tag("admin-1"), tag("admin-2"))
+	admin.HandleFunc("/panel", writeString("panel"))
+
+	NewTestRequest("GET", "/").Do(r)
+	if strings.Join(calls, ",") != "root" {
+		return false
+	}
+	calls = nil
+	NewTestRequest("GET", "/admin/panel").Do(r)
+	if strings.Join(calls, ",") != "root,admin-1,admin-2" {
+		return false
+	}
+	// Middleware doesn't run when nothing matches
+	calls = nil
+	NewTestRequest("GET", "/nowhere").Do(r)
+	return len(calls) == 0
+}
+
+// Test strict slash redirects and path cleaning
+func testStrictSlashAndClean() bool {
+	r := NewRouter().StrictSlash(true)
+	r.HandleFunc("/dir/", writeString("dir"))
+	r.HandleFunc("/file", writeString("file"))
+
+	resp := NewTestRequest("GET", "/dir").Do(r)
+	if resp.Code != 301 || resp.Header().Get("Location") != "/dir/" {
+		return false
+	}
+	resp = NewTestRequest("GET", "/file/").Do(r)
+	if resp.Code != 301 || resp.Header().Get("Location") != "/file" {
+		return false
+	}
+	resp = NewTestRequest("GET", "/a/../dir/").Do(r)
+	if resp.Code != 301 || resp.Header().Get("Location") != "/dir/" {
+		return false
+	}
+
+	plain := NewRouter()
+	plain.HandleFunc("/dir/", writeString("dir"))
+	return NewTestRequest("GET", "/dir").Do(plain).Code == 404 &&
+		NewTestRequest("GET", "/dir/").Do(plain).BodyString() == "dir"
+}
+
+// Test walking routes, including subrouters
+func testWalk() bool {
+	r := NewRouter()
+	r.HandleFunc("/", writeString("home"))
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/users", writeString("users"))
+	api.HandleFunc("/orders", writeString("orders"))
+	r.HandleFunc("/about", writeString("about"))
+
+	var visited []string
+	err := r.Walk(func(route *Route, router *Router, ancestors []*Route) error {
+		tpl, _ := route.GetPathTemplate()
+		visited = append(visited, fmt.Sprintf("%s(%d)", tpl, len(ancestors)))
+		return nil
+	})
+	if err != nil || strings.Join(visited, " ") != "/(0) /api(0) /api/users(1) /api/orders(1) /about(0)" {
+		return false
+	}
+
+	visited = nil
+	r.Walk(func(route *Route, router *Router, ancestors []*Route) error {
+		tpl, _ := route.GetPathTemplate()
+		visited = append(visited, tpl)
+		if tpl == "/api" {
+			return SkipRouter
+		}
+		return nil
+	})
+	if strings.Join(visited, " ") != "/ /api /about" {
+		return false
+	}
+
+	stop := errors.New("stop")
+	return r.Walk(func(*Route, *Router, []*Route) error { return stop }) == stop
+}
+
+// Test CurrentRoute, SetURLVars and CORS method headers
+func testRequestHelpers() bool {
+	r := NewRouter()
+	r.HandleFunc("/named/{id}", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, CurrentRoute(req).GetName())
+	}).Name("named")
+	if NewTestRequest("GET", "/named/1").Do(r).BodyString() != "named" {
+		return false
+	}
+
+	// Handlers can be tested without a router
+	req, _ := NewTestRequest("GET", "/whatever").Build()
+	req = SetURLVars(req, map[string]string{"id": "9"})
+	if Vars(req)["id"] != "9" || CurrentRoute(req) != nil {
+		return false
+	}
+
+	cors := NewRouter()
+	cors.HandleFunc("/items", writeString("ok")).Methods("GET", "PUT", "OPTIONS")
+	cors.HandleFunc("/items", writeString("ok")).Methods("PATCH")
+	cors.Use(CORSMethodMiddleware(cors))
+	resp := NewTestRequest("OPTIONS", "/items").Do(cors)
+	return resp.Header().Get("Access-Control-Allow-Methods") == "GET,PUT,OPTIONS,PATCH"
+}
+
+// Test the request/response harness shared with the Gin emulator
+func testHarness() bool {
+	r := NewRouter()
+	r.HandleFunc("/echo", func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		if req.Header.Get("Content-Type") == "application/json" {
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		cookie, _ := req.Cookie("session")
+		user, _, _ := req.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		http.SetCookie(w, &http.Cookie{Name: "seen", Value: "yes"})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"body": body, "session": cookie.Value, "user": user, "q": req.URL.Query().Get("q"),
+		})
+	}).Methods("POST")
+
+	resp := NewTestRequest("POST", "/echo").
+		JSON(map[string]int{"n": 1}).
+		Cookie("session", "abc").
+		BasicAuth("alice", "secret").
+		Query("q", "find me").
+		Do(r)
+
+	t := &recordingT{}
+	resp.AssertStatus(t, 200)
+	resp.AssertHeader(t, "Content-Type", "application/json")
+	resp.AssertJSON(t, map[string]interface{}{
+		"body": map[string]int{"n": 1}, "session": "abc", "user": "alice", "q": "find me",
+	})
+	resp.AssertBodyContains(t, `"user":"alice"`)
+	if len(t.errors) != 0 || resp.Cookie("seen") == nil {
+		return false
+	}
+
+	resp.AssertStatus(t, 404)
+	resp.AssertJSON(t, map[string]string{})
+	return len(t.errors) == 2
+}
+
+func main() {
+	fmt.Println("Running gorilla/mux Emulator Tests...")
+	fmt.Println("=====================================")
+
+	runTest("Path Variables", testPathVariables)
+	runTest("Methods", testMethods)
+	runTest("Matchers", testMatchers)
+	runTest("Subrouters", testSubrouters)
+	runTest("URL Building", testURLBuilding)
+	runTest("Route Templates", testRouteTemplates)
+	runTest("Route Errors", testRouteErrors)
+	runTest("Middleware", testMiddleware)
+	runTest("Strict Slash And Clean", testStrictSlashAndClean)
+	runTest("Walk", testWalk)
+	runTest("Request Helpers", testRequestHelpers)
+	runTest("Harness", testHarness)
+
+	fmt.Println("=====================================")
+	fmt.Println("All tests completed!")
+}
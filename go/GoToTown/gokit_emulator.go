@@ -6,7 +6,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Endpoint represents a single RPC method
@@ -118,28 +124,60 @@ func (l *SimpleLogger) Log(keyvals ...interface{}) error {
 	return nil
 }
 
-// CircuitBreakerMiddleware implements circuit breaker pattern
-func CircuitBreakerMiddleware(maxFailures int) Middleware {
-	failures := 0
-	
+// Breaker runs requests while a dependency is healthy. The gobreaker
+// emulator's CircuitBreaker implements it, as can any breaker with this
+// method.
+type Breaker interface {
+	Execute(req func() (interface{}, error)) (interface{}, error)
+}
+
+// Gobreaker runs each request through cb, which fails requests itself
+// while open, as circuitbreaker.Gobreaker does
+func Gobreaker(cb Breaker) Middleware {
 	return func(next Endpoint) Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			if failures >= maxFailures {
-				return nil, errors.New("circuit breaker is open")
-			}
-			
-			response, err := next(ctx, request)
-			if err != nil {
-				failures++
-				return nil, err
-			}
-			
-			failures = 0
-			return response, nil
+			return cb.Execute(func() (interface{}, error) {
+				return next(ctx, request)
+			})
 		}
 	}
 }
 
+// CircuitBreakerMiddleware implements circuit breaker pattern: after
+// maxFailures consecutive failures it fails every request. Use Gobreaker
+// with a CircuitBreaker from the gobreaker emulator for a breaker that
+// recovers.
+func CircuitBreakerMiddleware(maxFailures int) Middleware {
+	return Gobreaker(&consecutiveBreaker{maxFailures: maxFailures})
+}
+
+// consecutiveBreaker is a Breaker that opens for good after maxFailures
+// consecutive failures
+type consecutiveBreaker struct {
+	mu          sync.Mutex
+	maxFailures int
+	failures    int
+}
+
+func (b *consecutiveBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	b.mu.Lock()
+	open := b.failures >= b.maxFailures
+	b.mu.Unlock()
+	if open {
+		return nil, errors.New("circuit breaker is open")
+	}
+	
+	response, err := req()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.failures++
+		return nil, err
+	}
+	b.failures = 0
+	return response, nil
+}
+
 // RateLimitMiddleware implements rate limiting
 func RateLimitMiddleware(maxRequests int) Middleware {
 	requests := 0
@@ -158,6 +196,48 @@ func RateLimitMiddleware(maxRequests int) Middleware {
 	}
 }
 
+// ErrLimited is returned by an erroring limiter that refuses a request
+var ErrLimited = errors.New("rate limit exceeded")
+
+// Allower decides whether a request may go ahead now. The rate
+// emulator's Limiter implements it, as can any limiter with this method.
+type Allower interface {
+	Allow() bool
+}
+
+// NewErroringLimiter fails requests with ErrLimited when limit does not
+// allow them, as ratelimit.NewErroringLimiter does
+func NewErroringLimiter(limit Allower) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if !limit.Allow() {
+				return nil, ErrLimited
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// Waiter blocks until a request may go ahead. The rate emulator's
+// Limiter implements it, as can any limiter with this method.
+type Waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// NewDelayingLimiter holds requests back until limit lets them through,
+// failing them if the request context ends first, as
+// ratelimit.NewDelayingLimiter does
+func NewDelayingLimiter(limit Waiter) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			if err := limit.Wait(ctx); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
 // TimeoutMiddleware adds timeout to endpoints
 func TimeoutMiddleware() Middleware {
 	return func(next Endpoint) Endpoint {
@@ -169,6 +249,77 @@ func TimeoutMiddleware() Middleware {
 	}
 }
 
+// ErrBulkheadFull is returned when a bulkhead has no room for a request
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// BulkheadExecutor runs tasks on a bounded set of workers, refusing
+// tasks it has no room for. The errgroup emulator's Pool implements it,
+// as can any executor with this method.
+type BulkheadExecutor interface {
+	// TrySubmit queues task if there is room, and reports whether it did
+	TrySubmit(task func()) bool
+}
+
+// Bulkhead runs each request on executor, so that an endpoint can use no
+// more than the executor's workers and queue. Requests it refuses fail
+// with ErrBulkheadFull, and a caller whose context is done stops waiting
+// while the request finishes in the background.
+func Bulkhead(executor BulkheadExecutor) Middleware {
+	type result struct {
+		response interface{}
+		err      error
+	}
+	
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			done := make(chan result, 1)
+			ok := executor.TrySubmit(func() {
+				response, err := next(ctx, request)
+				done <- result{response, err}
+			})
+			if !ok {
+				return nil, ErrBulkheadFull
+			}
+			
+			select {
+			case r := <-done:
+				return r.response, r.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// Tracer starts a span for a request and returns a context holding it,
+// and a function ending it with more attributes and the request's error.
+// The OpenTelemetry emulator's MiddlewareTracer implements it, as can any
+// tracer with this method.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, func(attributes map[string]interface{}, err error))
+}
+
+// TraceEndpoint wraps each request in a span named operationName, as
+// opentelemetry.TraceEndpoint does. The endpoint's error, or the error of
+// a response implementing Failer, is recorded on the span.
+func TraceEndpoint(tracer Tracer, operationName string) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			ctx, end := tracer.StartSpan(ctx, operationName, map[string]interface{}{
+				"gokit.endpoint": operationName,
+			})
+			response, err := next(ctx, request)
+			
+			spanErr := err
+			if f, ok := response.(Failer); ok && spanErr == nil {
+				spanErr = f.Failed()
+			}
+			end(map[string]interface{}{"gokit.failed": spanErr != nil}, spanErr)
+			return response, err
+		}
+	}
+}
+
 // JSONEncoder encodes responses as JSON
 func JSONEncoder(ctx context.Context, w interface{}, response interface{}) error {
 	data, err := json.Marshal(response)
@@ -330,6 +481,456 @@ func NewEndpointSet(svc StringService, middlewares ...Middleware) EndpointSet {
 	}
 }
 
+// Service discovery, as in the sd package
+
+// Event is a change in the instances of a service, or an error reaching
+// the registry
+type Event struct {
+	Instances []string
+	Err       error
+}
+
+// Instancer tracks the instances of a service and sends every change to
+// the registered channels
+type Instancer interface {
+	Register(chan<- Event)
+	Deregister(chan<- Event)
+	Stop()
+}
+
+// Registrar registers a service instance with a registry
+type Registrar interface {
+	Register()
+	Deregister()
+}
+
+// FixedInstancer yields a fixed set of instances
+type FixedInstancer []string
+
+// Register sends the instances to ch
+func (d FixedInstancer) Register(ch chan<- Event) { ch <- Event{Instances: d} }
+
+// Deregister does nothing; the instances never change
+func (d FixedInstancer) Deregister(ch chan<- Event) {}
+
+// Stop does nothing
+func (d FixedInstancer) Stop() {}
+
+// instanceCache holds the latest Event and passes changes on
+type instanceCache struct {
+	mtx      sync.RWMutex
+	state    Event
+	registry map[chan<- Event]struct{}
+}
+
+func newInstanceCache() *instanceCache {
+	return &instanceCache{registry: make(map[chan<- Event]struct{})}
+}
+
+// Update records event and notifies the registered channels, unless
+// nothing changed
+func (c *instanceCache) Update(event Event) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	sort.Strings(event.Instances)
+	if event.Err == nil && c.state.Err == nil && equalStrings(event.Instances, c.state.Instances) {
+		return
+	}
+	c.state = event
+	for ch := range c.registry {
+		ch <- event
+	}
+}
+
+func (c *instanceCache) Register(ch chan<- Event) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.registry[ch] = struct{}{}
+	ch <- c.state
+}
+
+func (c *instanceCache) Deregister(ch chan<- Event) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.registry, ch)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RegistryClient is the part of a registry client an Instancer watches.
+// The etcd and Consul emulators' KitClients implement it, as can any
+// client with these methods. For Consul the prefix is a service name.
+type RegistryClient interface {
+	// GetEntries returns the instances stored under prefix
+	GetEntries(prefix string) ([]string, error)
+	// WatchPrefix signals ch whenever the entries under prefix change
+	WatchPrefix(prefix string, ch chan struct{})
+}
+
+// RegistryInstancer yields the instances stored under a prefix in a
+// registry, as etcdv3.Instancer does
+type RegistryInstancer struct {
+	cache  *instanceCache
+	client RegistryClient
+	prefix string
+	logger Logger
+	quitc  chan struct{}
+	once   sync.Once
+}
+
+// NewInstancer reads the instances under prefix and keeps them up to date
+// until Stop is called
+func NewInstancer(c RegistryClient, prefix string, logger Logger) (*RegistryInstancer, error) {
+	s := &RegistryInstancer{
+		cache:  newInstanceCache(),
+		client: c,
+		prefix: prefix,
+		logger: logger,
+		quitc:  make(chan struct{}),
+	}
+	instances, err := s.client.GetEntries(s.prefix)
+	if err == nil {
+		logger.Log("prefix", s.prefix, "instances", len(instances))
+	} else {
+		logger.Log("prefix", s.prefix, "err", err)
+	}
+	s.cache.Update(Event{Instances: instances, Err: err})
+	go s.loop()
+	return s, nil
+}
+
+func (s *RegistryInstancer) loop() {
+	ch := make(chan struct{})
+	go s.client.WatchPrefix(s.prefix, ch)
+	for {
+		select {
+		case <-ch:
+			instances, err := s.client.GetEntries(s.prefix)
+			if err != nil {
+				s.logger.Log("msg", "failed to retrieve entries", "err", err)
+			}
+			s.cache.Update(Event{Instances: instances, Err: err})
+		case <-s.quitc:
+			return
+		}
+	}
+}
+
+// Register sends the current instances to ch, then every change
+func (s *RegistryInstancer) Register(ch chan<- Event) { s.cache.Register(ch) }
+
+// Deregister stops sending changes to ch
+func (s *RegistryInstancer) Deregister(ch chan<- Event) { s.cache.Deregister(ch) }
+
+// Stop stops watching the registry
+func (s *RegistryInstancer) Stop() {
+	s.once.Do(func() { close(s.quitc) })
+}
+
+// Factory turns an instance address into an endpoint, and something to
+// close when the instance goes away
+type Factory func(instance string) (Endpoint, io.Closer, error)
+
+// Endpointer yields the endpoints of the current instances of a service
+type Endpointer interface {
+	Endpoints() ([]Endpoint, error)
+}
+
+// FixedEndpointer yields a fixed set of endpoints
+type FixedEndpointer []Endpoint
+
+// Endpoints returns the endpoints
+func (s FixedEndpointer) Endpoints() ([]Endpoint, error) { return s, nil }
+
+// EndpointerOption configures NewEndpointer
+type EndpointerOption func(*endpointerOptions)
+
+type endpointerOptions struct {
+	invalidateOnError bool
+	invalidateTimeout time.Duration
+}
+
+// InvalidateOnError drops the endpoints once the instancer has reported
+// an error for timeout. By default endpoints are kept through errors.
+func InvalidateOnError(timeout time.Duration) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		if timeout >= 0 {
+			opts.invalidateOnError = true
+			opts.invalidateTimeout = timeout
+		}
+	}
+}
+
+type endpointCloser struct {
+	Endpoint
+	io.Closer
+}
+
+// DefaultEndpointer builds endpoints for the instances an Instancer
+// reports, reusing them while their instance stays
+type DefaultEndpointer struct {
+	instancer Instancer
+	ch        chan Event
+	factory   Factory
+	logger    Logger
+	options   endpointerOptions
+
+	mtx                sync.RWMutex
+	cache              map[string]endpointCloser
+	endpoints          []Endpoint
+	err                error
+	invalidateDeadline time.Time
+}
+
+// NewEndpointer returns an Endpointer following src, building endpoints
+// with f
+func NewEndpointer(src Instancer, f Factory, logger Logger, options ...EndpointerOption) *DefaultEndpointer {
+	var opts endpointerOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	de := &DefaultEndpointer{
+		instancer: src,
+		ch:        make(chan Event),
+		factory:   f,
+		logger:    logger,
+		options:   opts,
+		cache:     make(map[string]endpointCloser),
+	}
+	go de.receive()
+	src.Register(de.ch)
+	return de
+}
+
+func (de *DefaultEndpointer) receive() {
+	for event := range de.ch {
+		de.update(event)
+	}
+}
+
+func (de *DefaultEndpointer) update(event Event) {
+	de.mtx.Lock()
+	defer de.mtx.Unlock()
+	if event.Err == nil {
+		de.updateCache(event.Instances)
+		de.err = nil
+		return
+	}
+	de.logger.Log("err", event.Err)
+	if !de.options.invalidateOnError {
+		return
+	}
+	if de.err != nil {
+		// Already invalidating; keep the first deadline
+		return
+	}
+	de.err = event.Err
+	de.invalidateDeadline = time.Now().Add(de.options.invalidateTimeout)
+}
+
+// updateCache builds endpoints for new instances and closes those of
+// instances that are gone
+func (de *DefaultEndpointer) updateCache(instances []string) {
+	sort.Strings(instances)
+	cache := make(map[string]endpointCloser, len(instances))
+	for _, instance := range instances {
+		if sc, ok := de.cache[instance]; ok {
+			cache[instance] = sc
+			delete(de.cache, instance)
+			continue
+		}
+		endpoint, closer, err := de.factory(instance)
+		if err != nil {
+			de.logger.Log("instance", instance, "err", err)
+			continue
+		}
+		cache[instance] = endpointCloser{endpoint, closer}
+	}
+	for _, sc := range de.cache {
+		if sc.Closer != nil {
+			sc.Closer.Close()
+		}
+	}
+	endpoints := make([]Endpoint, 0, len(cache))
+	for _, instance := range instances {
+		if sc, ok := cache[instance]; ok {
+			endpoints = append(endpoints, sc.Endpoint)
+		}
+	}
+	de.cache = cache
+	de.endpoints = endpoints
+}
+
+// Endpoints returns the current endpoints, or the instancer's error once
+// it has invalidated them
+func (de *DefaultEndpointer) Endpoints() ([]Endpoint, error) {
+	de.mtx.RLock()
+	if de.err == nil || time.Now().Before(de.invalidateDeadline) {
+		defer de.mtx.RUnlock()
+		return de.endpoints, nil
+	}
+	de.mtx.RUnlock()
+
+	de.mtx.Lock()
+	defer de.mtx.Unlock()
+	if de.err == nil {
+		return de.endpoints, nil
+	}
+	de.updateCache(nil)
+	return nil, de.err
+}
+
+// Close stops following the instancer
+func (de *DefaultEndpointer) Close() {
+	de.instancer.Deregister(de.ch)
+	close(de.ch)
+}
+
+// Load balancing, as in the sd/lb package
+
+// ErrNoEndpoints is returned when a balancer has no endpoints to choose from
+var ErrNoEndpoints = errors.New("no endpoints available")
+
+// Balancer picks an endpoint for each request
+type Balancer interface {
+	Endpoint() (Endpoint, error)
+}
+
+type roundRobin struct {
+	s Endpointer
+	c uint64
+}
+
+// NewRoundRobin returns a Balancer that cycles through the endpoints
+func NewRoundRobin(s Endpointer) Balancer {
+	return &roundRobin{s: s}
+}
+
+func (rr *roundRobin) Endpoint() (Endpoint, error) {
+	endpoints, err := rr.s.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	old := atomic.AddUint64(&rr.c, 1) - 1
+	return endpoints[old%uint64(len(endpoints))], nil
+}
+
+type random struct {
+	s   Endpointer
+	mtx sync.Mutex
+	r   *rand.Rand
+}
+
+// NewRandom returns a Balancer that picks endpoints at random
+func NewRandom(s Endpointer, seed int64) Balancer {
+	return &random{s: s, r: rand.New(rand.NewSource(seed))}
+}
+
+func (r *random) Endpoint() (Endpoint, error) {
+	endpoints, err := r.s.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return endpoints[r.r.Intn(len(endpoints))], nil
+}
+
+// RetryError is returned by a retrying endpoint that gave up. Final is
+// the last error and RawErrors holds every error in order.
+type RetryError struct {
+	RawErrors []error
+	Final     error
+}
+
+func (e RetryError) Error() string {
+	var suffix string
+	if len(e.RawErrors) > 1 {
+		a := make([]string, len(e.RawErrors)-1)
+		for i := 0; i < len(e.RawErrors)-1; i++ {
+			a[i] = e.RawErrors[i].Error()
+		}
+		suffix = fmt.Sprintf(" (previously: %s)", strings.Join(a, "; "))
+	}
+	return fmt.Sprintf("%v%s", e.Final, suffix)
+}
+
+// Callback decides, after the nth failed attempt, whether to keep trying.
+// A non-nil replacement becomes the error reported.
+type Callback func(n int, received error) (keepTrying bool, replacement error)
+
+// Retry returns an endpoint that sends each request to endpoints from b
+// until one succeeds, max attempts fail or timeout passes
+func Retry(max int, timeout time.Duration, b Balancer) Endpoint {
+	return RetryWithCallback(timeout, b, func(n int, err error) (bool, error) {
+		return n < max, nil
+	})
+}
+
+// RetryWithCallback is Retry with cb deciding when to stop
+func RetryWithCallback(timeout time.Duration, b Balancer, cb Callback) Endpoint {
+	if cb == nil {
+		cb = func(int, error) (bool, error) { return true, nil }
+	}
+	if b == nil {
+		panic("nil Balancer")
+	}
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		newctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		responses := make(chan interface{}, 1)
+		errs := make(chan error, 1)
+		var final RetryError
+		for i := 1; ; i++ {
+			go func() {
+				e, err := b.Endpoint()
+				if err != nil {
+					errs <- err
+					return
+				}
+				response, err := e(newctx, request)
+				if err != nil {
+					errs <- err
+					return
+				}
+				responses <- response
+			}()
+			select {
+			case <-newctx.Done():
+				return nil, newctx.Err()
+			case response := <-responses:
+				return response, nil
+			case err := <-errs:
+				final.RawErrors = append(final.RawErrors, err)
+				keepTrying, replacement := cb(i, err)
+				if replacement != nil {
+					err = replacement
+				}
+				if !keepTrying {
+					final.Final = err
+					return nil, final
+				}
+			}
+		}
+	}
+}
+
 func main() {
 	fmt.Println("Go-kit Microservices Toolkit Emulator")
 	fmt.Println("======================================")
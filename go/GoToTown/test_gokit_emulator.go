@@ -5,6 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Simple test framework
@@ -39,6 +43,182 @@ func PrintResults() {
 	fmt.Printf("\nPassed: %d/%d\n", passed, len(tests))
 }
 
+// fakeRegistry is a RegistryClient over an in-memory list of instances
+type fakeRegistry struct {
+	mu        sync.Mutex
+	instances []string
+	err       error
+	changed   chan struct{}
+}
+
+func newFakeRegistry(instances ...string) *fakeRegistry {
+	return &fakeRegistry{instances: instances, changed: make(chan struct{}, 16)}
+}
+
+func (r *fakeRegistry) GetEntries(prefix string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]string(nil), r.instances...), nil
+}
+
+func (r *fakeRegistry) WatchPrefix(prefix string, ch chan struct{}) {
+	ch <- struct{}{}
+	for range r.changed {
+		ch <- struct{}{}
+	}
+}
+
+func (r *fakeRegistry) set(instances []string, err error) {
+	r.mu.Lock()
+	r.instances, r.err = instances, err
+	r.mu.Unlock()
+	r.changed <- struct{}{}
+}
+
+// fakeExecutor is a BulkheadExecutor running up to slots tasks at once
+type fakeExecutor struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+}
+
+func (e *fakeExecutor) TrySubmit(task func()) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running >= e.slots {
+		return false
+	}
+	e.running++
+	go func() {
+		task()
+		e.mu.Lock()
+		e.running--
+		e.mu.Unlock()
+	}()
+	return true
+}
+
+func (e *fakeExecutor) used() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.running
+}
+
+func (e *fakeExecutor) setSlots(n int) {
+	e.mu.Lock()
+	e.slots = n
+	e.mu.Unlock()
+}
+
+// fakeLimiter lets through a fixed number of requests
+type fakeLimiter struct {
+	mu   sync.Mutex
+	left int
+}
+
+func (l *fakeLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.left == 0 {
+		return false
+	}
+	l.left--
+	return true
+}
+
+func (l *fakeLimiter) Wait(ctx context.Context) error {
+	for !l.Allow() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// fakeBreaker is a Breaker that can be opened by hand
+type fakeBreaker struct {
+	open  bool
+	calls int
+}
+
+func (b *fakeBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	if b.open {
+		return nil, errors.New("circuit breaker is open")
+	}
+	b.calls++
+	return req()
+}
+
+// fakeTracer records the spans a Tracer starts and ends
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []fakeSpan
+}
+
+type fakeSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+}
+
+type spanKey struct{}
+
+func (t *fakeTracer) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, func(map[string]interface{}, error)) {
+	span := fakeSpan{name: name, attributes: map[string]interface{}{}}
+	for k, v := range attributes {
+		span.attributes[k] = v
+	}
+	return context.WithValue(ctx, spanKey{}, name), func(attributes map[string]interface{}, err error) {
+		for k, v := range attributes {
+			span.attributes[k] = v
+		}
+		span.err = err
+		t.mu.Lock()
+		t.spans = append(t.spans, span)
+		t.mu.Unlock()
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// instanceFactory makes endpoints that answer with their instance
+func instanceFactory(closed *[]string, mu *sync.Mutex) Factory {
+	return func(instance string) (Endpoint, io.Closer, error) {
+		endpoint := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return instance, nil
+		}
+		closer := closerFunc(func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			*closed = append(*closed, instance)
+			return nil
+		})
+		return endpoint, closer, nil
+	}
+}
+
+// waitForEndpoints polls an Endpointer until it has n endpoints
+func waitForEndpoints(e Endpointer, n int) ([]Endpoint, error) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		endpoints, err := e.Endpoints()
+		if err == nil && len(endpoints) == n {
+			return endpoints, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("expected %d endpoints, got %d (err %v)", n, len(endpoints), err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 func runTests() {
 	fmt.Println("Running Go-kit Emulator Tests")
 	fmt.Println("==============================\n")
@@ -450,5 +630,312 @@ func runTests() {
 		return nil
 	})
 	
+	// Test 21: Fixed instancer with a round-robin balancer
+	TestRunner("Fixed Instancer Round Robin", func() error {
+		var mu sync.Mutex
+		var closed []string
+		endpointer := NewEndpointer(FixedInstancer{"b:80", "a:80", "c:80"}, instanceFactory(&closed, &mu), &SimpleLogger{})
+		defer endpointer.Close()
+		if _, err := waitForEndpoints(endpointer, 3); err != nil {
+			return err
+		}
+		
+		balancer := NewRoundRobin(endpointer)
+		ctx := context.Background()
+		var got []string
+		for i := 0; i < 4; i++ {
+			e, err := balancer.Endpoint()
+			if err != nil {
+				return err
+			}
+			resp, _ := e(ctx, nil)
+			got = append(got, resp.(string))
+		}
+		if strings.Join(got, ",") != "a:80,b:80,c:80,a:80" {
+			return fmt.Errorf("unexpected rotation %v", got)
+		}
+		
+		if _, err := NewRoundRobin(FixedEndpointer{}).Endpoint(); err != ErrNoEndpoints {
+			return fmt.Errorf("expected ErrNoEndpoints, got %v", err)
+		}
+		return nil
+	})
+	
+	// Test 22: Registry instancer follows registry changes
+	TestRunner("Registry Instancer", func() error {
+		registry := newFakeRegistry("10.0.0.1:8080")
+		instancer, err := NewInstancer(registry, "/services/users/", &SimpleLogger{})
+		if err != nil {
+			return err
+		}
+		defer instancer.Stop()
+		
+		var mu sync.Mutex
+		var closed []string
+		endpointer := NewEndpointer(instancer, instanceFactory(&closed, &mu), &SimpleLogger{})
+		defer endpointer.Close()
+		if _, err := waitForEndpoints(endpointer, 1); err != nil {
+			return err
+		}
+		
+		registry.set([]string{"10.0.0.1:8080", "10.0.0.2:8080"}, nil)
+		if _, err := waitForEndpoints(endpointer, 2); err != nil {
+			return err
+		}
+		
+		// A removed instance's endpoint is closed
+		registry.set([]string{"10.0.0.2:8080"}, nil)
+		endpoints, err := waitForEndpoints(endpointer, 1)
+		if err != nil {
+			return err
+		}
+		resp, _ := endpoints[0](context.Background(), nil)
+		mu.Lock()
+		defer mu.Unlock()
+		if resp != "10.0.0.2:8080" || len(closed) != 1 || closed[0] != "10.0.0.1:8080" {
+			return fmt.Errorf("unexpected endpoint %v, closed %v", resp, closed)
+		}
+		return nil
+	})
+	
+	// Test 23: Registry errors keep endpoints unless invalidated
+	TestRunner("Invalidate On Error", func() error {
+		registry := newFakeRegistry("10.0.0.1:8080")
+		instancer, _ := NewInstancer(registry, "/services/users/", &SimpleLogger{})
+		defer instancer.Stop()
+		
+		var mu sync.Mutex
+		var closed []string
+		keeping := NewEndpointer(instancer, instanceFactory(&closed, &mu), &SimpleLogger{})
+		defer keeping.Close()
+		invalidating := NewEndpointer(instancer, instanceFactory(&closed, &mu), &SimpleLogger{}, InvalidateOnError(0))
+		defer invalidating.Close()
+		if _, err := waitForEndpoints(invalidating, 1); err != nil {
+			return err
+		}
+		
+		outage := errors.New("registry unreachable")
+		registry.set(nil, outage)
+		deadline := time.Now().Add(time.Second)
+		for {
+			if _, err := invalidating.Endpoints(); err == outage {
+				break
+			}
+			if time.Now().After(deadline) {
+				return errors.New("endpoints were not invalidated")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if endpoints, err := keeping.Endpoints(); err != nil || len(endpoints) != 1 {
+			return fmt.Errorf("expected the endpoint to be kept, got %d (err %v)", len(endpoints), err)
+		}
+		
+		// Recovery restores the endpoints
+		registry.set([]string{"10.0.0.3:8080"}, nil)
+		_, err := waitForEndpoints(invalidating, 1)
+		return err
+	})
+	
+	// Test 24: Random balancer
+	TestRunner("Random Balancer", func() error {
+		seen := make(map[string]bool)
+		var endpoints FixedEndpointer
+		for _, name := range []string{"a", "b", "c"} {
+			name := name
+			endpoints = append(endpoints, func(ctx context.Context, request interface{}) (interface{}, error) {
+				return name, nil
+			})
+		}
+		balancer := NewRandom(endpoints, 42)
+		for i := 0; i < 100; i++ {
+			e, err := balancer.Endpoint()
+			if err != nil {
+				return err
+			}
+			resp, _ := e(context.Background(), nil)
+			seen[resp.(string)] = true
+		}
+		if len(seen) != 3 {
+			return fmt.Errorf("expected all endpoints to be picked, got %v", seen)
+		}
+		if _, err := NewRandom(FixedEndpointer{}, 1).Endpoint(); err != ErrNoEndpoints {
+			return fmt.Errorf("expected ErrNoEndpoints, got %v", err)
+		}
+		return nil
+	})
+	
+	// Test 25: Retry across endpoints
+	TestRunner("Retry", func() error {
+		failing := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return nil, errors.New("connection refused")
+		}
+		working := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return "ok", nil
+		}
+		ctx := context.Background()
+		
+		balancer := NewRoundRobin(FixedEndpointer{failing, failing, working})
+		resp, err := Retry(3, time.Second, balancer)(ctx, nil)
+		if err != nil || resp != "ok" {
+			return fmt.Errorf("expected ok, got %v (err %v)", resp, err)
+		}
+		
+		// Giving up reports every error
+		_, err = Retry(2, time.Second, NewRoundRobin(FixedEndpointer{failing}))(ctx, nil)
+		retryErr, ok := err.(RetryError)
+		if !ok || len(retryErr.RawErrors) != 2 || err.Error() != "connection refused (previously: connection refused)" {
+			return fmt.Errorf("unexpected error %v", err)
+		}
+		
+		// A callback can stop early and replace the error
+		stop := errors.New("not retryable")
+		_, err = RetryWithCallback(time.Second, NewRoundRobin(FixedEndpointer{failing}), func(n int, err error) (bool, error) {
+			return false, stop
+		})(ctx, nil)
+		if retryErr, ok := err.(RetryError); !ok || retryErr.Final != stop {
+			return fmt.Errorf("unexpected error %v", err)
+		}
+		
+		// The timeout bounds all attempts
+		slow := func(ctx context.Context, request interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		_, err = Retry(10, 20*time.Millisecond, NewRoundRobin(FixedEndpointer{slow}))(ctx, nil)
+		if err != context.DeadlineExceeded {
+			return fmt.Errorf("expected deadline exceeded, got %v", err)
+		}
+		return nil
+	})
+	
+	// Test 26: Bulkhead bounds concurrent requests
+	TestRunner("Bulkhead", func() error {
+		executor := &fakeExecutor{slots: 1}
+		release := make(chan struct{})
+		blocking := func(ctx context.Context, request interface{}) (interface{}, error) {
+			<-release
+			return request, nil
+		}
+		endpoint := Bulkhead(executor)(blocking)
+		ctx := context.Background()
+		
+		first := make(chan interface{})
+		go func() {
+			resp, _ := endpoint(ctx, "first")
+			first <- resp
+		}()
+		for executor.used() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if _, err := endpoint(ctx, "second"); err != ErrBulkheadFull {
+			return fmt.Errorf("expected ErrBulkheadFull, got %v", err)
+		}
+		
+		// A caller that gives up does not wait for the request
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		executor.setSlots(2)
+		if _, err := endpoint(timeoutCtx, "third"); err != context.DeadlineExceeded {
+			return fmt.Errorf("expected deadline exceeded, got %v", err)
+		}
+		close(release)
+		if resp := <-first; resp != "first" {
+			return fmt.Errorf("expected first, got %v", resp)
+		}
+		return nil
+	})
+	
+	// Test 27: Erroring and delaying limiters
+	TestRunner("Rate Limiters", func() error {
+		echo := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return request, nil
+		}
+		ctx := context.Background()
+		
+		erroring := NewErroringLimiter(&fakeLimiter{left: 2})(echo)
+		for i := 0; i < 2; i++ {
+			if resp, err := erroring(ctx, i); err != nil || resp != i {
+				return fmt.Errorf("request %d: expected %d, got %v (err %v)", i, i, resp, err)
+			}
+		}
+		if _, err := erroring(ctx, 2); err != ErrLimited {
+			return fmt.Errorf("expected ErrLimited, got %v", err)
+		}
+		
+		// A delaying limiter waits, until the request context ends
+		limiter := &fakeLimiter{}
+		delaying := NewDelayingLimiter(limiter)(echo)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			limiter.mu.Lock()
+			limiter.left = 1
+			limiter.mu.Unlock()
+		}()
+		if resp, err := delaying(ctx, "held"); err != nil || resp != "held" {
+			return fmt.Errorf("expected held, got %v (err %v)", resp, err)
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := delaying(timeoutCtx, "dropped"); err != context.DeadlineExceeded {
+			return fmt.Errorf("expected deadline exceeded, got %v", err)
+		}
+		return nil
+	})
+	
+	// Test 28: Gobreaker middleware delegates to the breaker
+	TestRunner("Gobreaker", func() error {
+		calls := 0
+		echo := func(ctx context.Context, request interface{}) (interface{}, error) {
+			calls++
+			return request, nil
+		}
+		breaker := &fakeBreaker{}
+		endpoint := Gobreaker(breaker)(echo)
+		ctx := context.Background()
+		
+		if resp, err := endpoint(ctx, "ping"); err != nil || resp != "ping" {
+			return fmt.Errorf("expected ping, got %v (err %v)", resp, err)
+		}
+		breaker.open = true
+		if _, err := endpoint(ctx, "ping"); err == nil || err.Error() != "circuit breaker is open" {
+			return fmt.Errorf("expected open breaker error, got %v", err)
+		}
+		if calls != 1 || breaker.calls != 1 {
+			return fmt.Errorf("expected 1 call through the breaker, got %d", calls)
+		}
+		return nil
+	})
+	
+	// Test 29: Tracing records a span per request
+	TestRunner("Trace Endpoint", func() error {
+		tracer := &fakeTracer{}
+		endpoint := TraceEndpoint(tracer, "uppercase")(func(ctx context.Context, request interface{}) (interface{}, error) {
+			if ctx.Value(spanKey{}) != "uppercase" {
+				return nil, errors.New("span missing from context")
+			}
+			return MakeUppercaseEndpoint(NewStringService())(ctx, request)
+		})
+		ctx := context.Background()
+		
+		if resp, err := endpoint(ctx, UppercaseRequest{S: "hi"}); err != nil || resp.(UppercaseResponse).V != "HI" {
+			return fmt.Errorf("expected HI, got %v (err %v)", resp, err)
+		}
+		// An empty string fails in the response, not the endpoint
+		if _, err := endpoint(ctx, UppercaseRequest{S: ""}); err != nil {
+			return fmt.Errorf("expected failure in the response, got %v", err)
+		}
+		if len(tracer.spans) != 2 {
+			return fmt.Errorf("expected 2 spans, got %d", len(tracer.spans))
+		}
+		ok, failed := tracer.spans[0], tracer.spans[1]
+		if ok.name != "uppercase" || ok.err != nil || ok.attributes["gokit.endpoint"] != "uppercase" || ok.attributes["gokit.failed"] != false {
+			return fmt.Errorf("unexpected span %+v", ok)
+		}
+		if failed.err == nil || failed.err.Error() != "empty string" || failed.attributes["gokit.failed"] != true {
+			return fmt.Errorf("expected the Failer error on the span, got %+v", failed)
+		}
+		return nil
+	})
+	
 	PrintResults()
 }
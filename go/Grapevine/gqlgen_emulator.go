@@ -0,0 +1,2484 @@
+package main
+
+// Developed by PowerShield, as an alternative to gqlgen
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Errors, as in the gqlerror package
+
+// Location is a position in a GraphQL document, counting from 1
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error: a message, where in the document it came
+// from and, for field errors, the path of the field in the response
+type Error struct {
+	Err        error                  `json:"-"`
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Errorf returns an Error with a formatted message, which resolvers can
+// return to set extensions
+func Errorf(format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// Error returns the message with its location and path, as
+// input:3:5: user.friends[0].name message
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("input:")
+	if len(e.Locations) > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Locations[0].Line, e.Locations[0].Column)
+	}
+	if len(e.Path) > 0 {
+		b.WriteString(" " + pathString(e.Path))
+	}
+	b.WriteString(" " + e.Message)
+	return b.String()
+}
+
+// Unwrap returns the error a resolver returned, if any
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrorList is the errors of one response
+type ErrorList []*Error
+
+// Error joins the errors' messages
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func pathString(path []interface{}) string {
+	var b strings.Builder
+	for i, p := range path {
+		switch p := p.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", p)
+		default:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprint(&b, p)
+		}
+	}
+	return b.String()
+}
+
+func errorAt(loc Location, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// Lexing
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "<EOF>"
+	case tokString:
+		return strconv.Quote(t.value)
+	}
+	return t.value
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// lex splits a document into tokens, skipping whitespace, commas and
+// comments
+func lex(src string) ([]token, *Error) {
+	var toks []token
+	line, lineStart := 1, 0
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		loc := Location{Line: line, Column: i - lineStart + 1}
+		switch {
+		case c == '\n':
+			line++
+			lineStart = i + 1
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			toks = append(toks, token{tokPunct, "...", loc})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+			toks = append(toks, token{tokPunct, string(c), loc})
+			i++
+		case isNameStart(c):
+			j := i + 1
+			for j < len(src) && (isNameStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, token{tokName, src[i:j], loc})
+			i = j
+		case c == '-' || isDigit(c):
+			j, kind := i, tokInt
+			if src[j] == '-' {
+				j++
+			}
+			start := j
+			for j < len(src) && isDigit(src[j]) {
+				j++
+			}
+			if j == start || (src[start] == '0' && j-start > 1) {
+				return nil, errorAt(loc, "Invalid number %q", src[i:j])
+			}
+			if j < len(src) && src[j] == '.' {
+				kind = tokFloat
+				j++
+				digits := j
+				for j < len(src) && isDigit(src[j]) {
+					j++
+				}
+				if j == digits {
+					return nil, errorAt(loc, "Invalid number %q", src[i:j])
+				}
+			}
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				kind = tokFloat
+				j++
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				digits := j
+				for j < len(src) && isDigit(src[j]) {
+					j++
+				}
+				if j == digits {
+					return nil, errorAt(loc, "Invalid number %q", src[i:j])
+				}
+			}
+			toks = append(toks, token{kind, src[i:j], loc})
+			i = j
+		case strings.HasPrefix(src[i:], `"""`):
+			end := i + 3
+			for end < len(src) && !strings.HasPrefix(src[end:], `"""`) {
+				if strings.HasPrefix(src[end:], `\"""`) {
+					end += 4
+					continue
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, errorAt(loc, "Unterminated string")
+			}
+			raw := src[i+3 : end]
+			toks = append(toks, token{tokString, blockStringValue(strings.ReplaceAll(raw, `\"""`, `"""`)), loc})
+			for j := i; j < end; j++ {
+				if src[j] == '\n' {
+					line++
+					lineStart = j + 1
+				}
+			}
+			i = end + 3
+		case c == '"':
+			value, n, err := lexString(src[i:])
+			if err != "" {
+				return nil, errorAt(loc, "%s", err)
+			}
+			toks = append(toks, token{tokString, value, loc})
+			i += n
+		default:
+			return nil, errorAt(loc, "Unexpected character %q", c)
+		}
+	}
+	toks = append(toks, token{kind: tokEOF, loc: Location{Line: line, Column: i - lineStart + 1}})
+	return toks, nil
+}
+
+// lexString reads a quoted string, returning its value and length
+func lexString(src string) (string, int, string) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, ""
+		case '\n', '\r':
+			return "", 0, "Unterminated string"
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, "Unterminated string"
+			}
+			switch src[i] {
+			case '"', '\\', '/':
+				b.WriteByte(src[i])
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, "Invalid unicode escape"
+				}
+				r, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, "Invalid unicode escape"
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", 0, fmt.Sprintf("Invalid escape \\%c", src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, "Unterminated string"
+}
+
+// blockStringValue removes a block string's common indentation and its
+// leading and trailing blank lines
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, l := range lines[1:] {
+		trimmed := strings.TrimLeft(l, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(l) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Parsing, shared by schemas and queries
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// parseError aborts parsing; parseWith recovers it
+type parseError struct{ err *Error }
+
+func parseWith(src string, parse func(p *parser)) (err error) {
+	toks, lexErr := lex(src)
+	if lexErr != nil {
+		return lexErr
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			err = pe.err
+		}
+	}()
+	parse(&parser{toks: toks})
+	return nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) fail(t token, format string, args ...interface{}) {
+	panic(parseError{errorAt(t.loc, format, args...)})
+}
+
+func (p *parser) is(punct string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.value == punct
+}
+
+func (p *parser) isKeyword(name string) bool {
+	t := p.peek()
+	return t.kind == tokName && t.value == name
+}
+
+func (p *parser) skip(punct string) bool {
+	if p.is(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) token {
+	t := p.next()
+	if t.kind != tokPunct || t.value != punct {
+		p.fail(t, "Expected %s, found %s", punct, t)
+	}
+	return t
+}
+
+func (p *parser) expectName() token {
+	t := p.next()
+	if t.kind != tokName {
+		p.fail(t, "Expected Name, found %s", t)
+	}
+	return t
+}
+
+func (p *parser) expectKeyword(name string) {
+	if t := p.next(); t.kind != tokName || t.value != name {
+		p.fail(t, "Expected %q, found %s", name, t)
+	}
+}
+
+// typeRef is a type as written: a name or a list, maybe non-null
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// named returns the name of the type, inside any lists
+func (t *typeRef) named() string {
+	for t.elem != nil {
+		t = t.elem
+	}
+	return t.name
+}
+
+func (t *typeRef) nullable() *typeRef {
+	c := *t
+	c.nonNull = false
+	return &c
+}
+
+func (p *parser) parseType() *typeRef {
+	var t *typeRef
+	if p.skip("[") {
+		t = &typeRef{elem: p.parseType()}
+		p.expect("]")
+	} else {
+		t = &typeRef{name: p.expectName().value}
+	}
+	t.nonNull = p.skip("!")
+	return t
+}
+
+type valueKind int
+
+const (
+	valVariable valueKind = iota
+	valInt
+	valFloat
+	valString
+	valBool
+	valNull
+	valEnum
+	valList
+	valObject
+)
+
+// valueNode is a value as written in a document
+type valueNode struct {
+	kind   valueKind
+	raw    string
+	list   []*valueNode
+	fields []*objectField
+	loc    Location
+}
+
+type objectField struct {
+	name  string
+	value *valueNode
+}
+
+func (v *valueNode) String() string {
+	switch v.kind {
+	case valVariable:
+		return "$" + v.raw
+	case valString:
+		return strconv.Quote(v.raw)
+	case valList:
+		items := make([]string, len(v.list))
+		for i, item := range v.list {
+			items[i] = item.String()
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case valObject:
+		fields := make([]string, len(v.fields))
+		for i, f := range v.fields {
+			fields[i] = f.name + ": " + f.value.String()
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	return v.raw
+}
+
+func (p *parser) parseValue(constant bool) *valueNode {
+	t := p.peek()
+	v := &valueNode{raw: t.value, loc: t.loc}
+	switch {
+	case p.skip("$"):
+		if constant {
+			p.fail(t, "Unexpected $")
+		}
+		v.kind = valVariable
+		v.raw = p.expectName().value
+		return v
+	case p.skip("["):
+		v.kind = valList
+		for !p.skip("]") {
+			v.list = append(v.list, p.parseValue(constant))
+		}
+		return v
+	case p.skip("{"):
+		v.kind = valObject
+		for !p.skip("}") {
+			name := p.expectName().value
+			p.expect(":")
+			v.fields = append(v.fields, &objectField{name: name, value: p.parseValue(constant)})
+		}
+		return v
+	case t.kind == tokInt:
+		v.kind = valInt
+	case t.kind == tokFloat:
+		v.kind = valFloat
+	case t.kind == tokString:
+		v.kind = valString
+	case t.kind == tokName:
+		switch t.value {
+		case "true", "false":
+			v.kind = valBool
+		case "null":
+			v.kind = valNull
+		default:
+			v.kind = valEnum
+		}
+	default:
+		p.fail(t, "Unexpected %s", t)
+	}
+	p.next()
+	return v
+}
+
+type argument struct {
+	name  string
+	value *valueNode
+	loc   Location
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	if !p.skip("(") {
+		return nil
+	}
+	var args []*argument
+	for {
+		name := p.expectName()
+		p.expect(":")
+		args = append(args, &argument{name: name.value, value: p.parseValue(constant), loc: name.loc})
+		if p.skip(")") {
+			return args
+		}
+	}
+}
+
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+func (p *parser) parseDirectives(constant bool) []*directive {
+	var dirs []*directive
+	for p.is("@") {
+		at := p.next()
+		dirs = append(dirs, &directive{name: p.expectName().value, args: p.parseArguments(constant), loc: at.loc})
+	}
+	return dirs
+}
+
+func findArgument(args []*argument, name string) *argument {
+	for _, a := range args {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Schemas
+
+type typeKind int
+
+const (
+	kindScalar typeKind = iota
+	kindObject
+	kindInterface
+	kindUnion
+	kindEnum
+	kindInputObject
+)
+
+var kindNames = map[typeKind]string{
+	kindScalar:      "scalar",
+	kindObject:      "type",
+	kindInterface:   "interface",
+	kindUnion:       "union",
+	kindEnum:        "enum",
+	kindInputObject: "input",
+}
+
+type typeDef struct {
+	kind        typeKind
+	name        string
+	description string
+	fields      []*fieldDef
+	fieldMap    map[string]*fieldDef
+	interfaces  []string
+	members     []string
+	enumValues  []string
+	loc         Location
+}
+
+// fieldDef is a field of an object or interface, an argument, or a field
+// of an input object; only the first has args, only the others have a
+// default value
+type fieldDef struct {
+	name         string
+	description  string
+	typ          *typeRef
+	args         []*fieldDef
+	defaultValue *valueNode
+	loc          Location
+}
+
+func (d *typeDef) addField(f *fieldDef) {
+	if d.fieldMap[f.name] != nil {
+		panic(parseError{errorAt(f.loc, "Field %s.%s can only be defined once.", d.name, f.name)})
+	}
+	d.fields = append(d.fields, f)
+	d.fieldMap[f.name] = f
+}
+
+func (d *typeDef) isComposite() bool {
+	return d.kind == kindObject || d.kind == kindInterface || d.kind == kindUnion
+}
+
+func (d *typeDef) isInput() bool {
+	return d.kind == kindScalar || d.kind == kindEnum || d.kind == kindInputObject
+}
+
+// ResolverFunc resolves a field. obj is the value of the object the field
+// is on, nil for the root types, and args holds the field's arguments
+// coerced to their types: int, float64, string, bool, []interface{} and
+// map[string]interface{}, with defaults applied.
+type ResolverFunc func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error)
+
+// TypeResolverFunc returns the name of the object type of a value of an
+// interface or union type
+type TypeResolverFunc func(obj interface{}) string
+
+// Schema is a parsed schema with the resolvers registered for its fields.
+// Fields without resolvers read the map key, struct field or method of
+// their object with the field's name.
+type Schema struct {
+	types        map[string]*typeDef
+	queryType    string
+	mutationType string
+
+	mu            sync.RWMutex
+	resolvers     map[string]ResolverFunc
+	typeResolvers map[string]TypeResolverFunc
+}
+
+var builtinScalars = []string{"Int", "Float", "String", "Boolean", "ID"}
+
+// ParseSchema parses schema definition language, split across any number
+// of sources, as gqlgen reads every .graphqls file. Object, interface,
+// union, enum, input and scalar types are supported, and extend type adds
+// fields to a type from another source.
+func ParseSchema(sources ...string) (*Schema, error) {
+	s := &Schema{
+		types:         map[string]*typeDef{},
+		resolvers:     map[string]ResolverFunc{},
+		typeResolvers: map[string]TypeResolverFunc{},
+	}
+	for _, name := range builtinScalars {
+		s.types[name] = &typeDef{kind: kindScalar, name: name}
+	}
+	var extensions []*typeDef
+	for _, src := range sources {
+		err := parseWith(src, func(p *parser) {
+			for p.peek().kind != tokEOF {
+				if def := s.parseDefinition(p); def != nil {
+					extensions = append(extensions, def)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, ext := range extensions {
+		if err := s.extend(ext); err != nil {
+			return nil, err
+		}
+	}
+	if s.queryType == "" {
+		s.queryType = "Query"
+	}
+	if s.mutationType == "" && s.types["Mutation"] != nil {
+		s.mutationType = "Mutation"
+	}
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustParseSchema is ParseSchema, panicking on errors
+func MustParseSchema(sources ...string) *Schema {
+	s, err := ParseSchema(sources...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseDefinition parses one definition, returning it if it extends a
+// type, which is applied once every source is read
+func (s *Schema) parseDefinition(p *parser) *typeDef {
+	description := ""
+	if p.peek().kind == tokString {
+		description = p.next().value
+	}
+	extend := false
+	if p.isKeyword("extend") {
+		p.next()
+		extend = true
+	}
+	kw := p.expectName()
+	if kw.value == "schema" {
+		p.parseDirectives(true)
+		p.expect("{")
+		for !p.skip("}") {
+			op := p.expectName()
+			p.expect(":")
+			name := p.expectName().value
+			switch op.value {
+			case "query":
+				s.queryType = name
+			case "mutation":
+				s.mutationType = name
+			case "subscription":
+			default:
+				p.fail(op, "Unexpected %s", op)
+			}
+		}
+		return nil
+	}
+	if kw.value == "directive" {
+		p.expect("@")
+		p.expectName()
+		if p.is("(") {
+			p.parseInputValueDefs("(", ")")
+		}
+		if p.isKeyword("repeatable") {
+			p.next()
+		}
+		p.expectKeyword("on")
+		p.skip("|")
+		for {
+			p.expectName()
+			if !p.skip("|") {
+				return nil
+			}
+		}
+	}
+
+	name := p.expectName()
+	def := &typeDef{name: name.value, description: description, fieldMap: map[string]*fieldDef{}, loc: name.loc}
+	switch kw.value {
+	case "scalar":
+		def.kind = kindScalar
+		p.parseDirectives(true)
+	case "type", "interface":
+		def.kind = kindObject
+		if kw.value == "interface" {
+			def.kind = kindInterface
+		}
+		if p.isKeyword("implements") {
+			p.next()
+			p.skip("&")
+			for {
+				def.interfaces = append(def.interfaces, p.expectName().value)
+				if !p.skip("&") {
+					break
+				}
+			}
+		}
+		p.parseDirectives(true)
+		if p.is("{") {
+			for _, f := range p.parseFieldDefs() {
+				def.addField(f)
+			}
+		}
+	case "union":
+		def.kind = kindUnion
+		p.parseDirectives(true)
+		if p.skip("=") {
+			p.skip("|")
+			for {
+				def.members = append(def.members, p.expectName().value)
+				if !p.skip("|") {
+					break
+				}
+			}
+		}
+	case "enum":
+		def.kind = kindEnum
+		p.parseDirectives(true)
+		p.expect("{")
+		for !p.skip("}") {
+			if p.peek().kind == tokString {
+				p.next()
+			}
+			v := p.expectName()
+			if v.value == "true" || v.value == "false" || v.value == "null" {
+				p.fail(v, "Enum value %s is reserved", v.value)
+			}
+			def.enumValues = append(def.enumValues, v.value)
+			p.parseDirectives(true)
+		}
+	case "input":
+		def.kind = kindInputObject
+		p.parseDirectives(true)
+		if p.is("{") {
+			for _, f := range p.parseInputValueDefs("{", "}") {
+				def.addField(f)
+			}
+		}
+	default:
+		p.fail(kw, "Unexpected Name %q", kw.value)
+	}
+
+	if extend {
+		return def
+	}
+	if existing := s.types[def.name]; existing != nil {
+		p.fail(name, "Cannot redeclare type %s.", def.name)
+	}
+	s.types[def.name] = def
+	return nil
+}
+
+func (p *parser) parseFieldDefs() []*fieldDef {
+	p.expect("{")
+	var fields []*fieldDef
+	for !p.skip("}") {
+		f := &fieldDef{}
+		if p.peek().kind == tokString {
+			f.description = p.next().value
+		}
+		name := p.expectName()
+		f.name, f.loc = name.value, name.loc
+		if p.is("(") {
+			f.args = p.parseInputValueDefs("(", ")")
+		}
+		p.expect(":")
+		f.typ = p.parseType()
+		p.parseDirectives(true)
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func (p *parser) parseInputValueDefs(open, close string) []*fieldDef {
+	p.expect(open)
+	var values []*fieldDef
+	for !p.skip(close) {
+		f := &fieldDef{}
+		if p.peek().kind == tokString {
+			f.description = p.next().value
+		}
+		name := p.expectName()
+		f.name, f.loc = name.value, name.loc
+		p.expect(":")
+		f.typ = p.parseType()
+		if p.skip("=") {
+			f.defaultValue = p.parseValue(true)
+		}
+		p.parseDirectives(true)
+		values = append(values, f)
+	}
+	return values
+}
+
+// extend adds an extension's fields, interfaces, members or values to
+// the type it extends
+func (s *Schema) extend(ext *typeDef) error {
+	def := s.types[ext.name]
+	if def == nil {
+		return errorAt(ext.loc, "Cannot extend type %s because it does not exist.", ext.name)
+	}
+	if def.kind != ext.kind {
+		return errorAt(ext.loc, "Cannot extend %s %s as %s.", kindNames[def.kind], def.name, kindNames[ext.kind])
+	}
+	for _, f := range ext.fields {
+		if def.fieldMap[f.name] != nil {
+			return errorAt(f.loc, "Field %s.%s can only be defined once.", def.name, f.name)
+		}
+		def.fields = append(def.fields, f)
+		def.fieldMap[f.name] = f
+	}
+	def.interfaces = append(def.interfaces, ext.interfaces...)
+	def.members = append(def.members, ext.members...)
+	def.enumValues = append(def.enumValues, ext.enumValues...)
+	return nil
+}
+
+// check reports references to undefined types and types used where they
+// can't be
+func (s *Schema) check() error {
+	root := s.types[s.queryType]
+	if root == nil || root.kind != kindObject {
+		return &Error{Message: fmt.Sprintf("Schema does not define the %s type.", s.queryType)}
+	}
+	if s.mutationType != "" {
+		if m := s.types[s.mutationType]; m == nil || m.kind != kindObject {
+			return &Error{Message: fmt.Sprintf("Schema does not define the %s type.", s.mutationType)}
+		}
+	}
+	lookup := func(t *typeRef, loc Location) (*typeDef, error) {
+		def := s.types[t.named()]
+		if def == nil {
+			return nil, errorAt(loc, "Undefined type %s.", t.named())
+		}
+		return def, nil
+	}
+	for _, def := range s.types {
+		for _, f := range def.fields {
+			ft, err := lookup(f.typ, f.loc)
+			if err != nil {
+				return err
+			}
+			if def.kind == kindInputObject && !ft.isInput() {
+				return errorAt(f.loc, "%s.%s must be an input type, not %s.", def.name, f.name, ft.name)
+			}
+			if def.kind != kindInputObject && ft.kind == kindInputObject {
+				return errorAt(f.loc, "%s.%s must be an output type, not input %s.", def.name, f.name, ft.name)
+			}
+			for _, a := range f.args {
+				at, err := lookup(a.typ, a.loc)
+				if err != nil {
+					return err
+				}
+				if !at.isInput() {
+					return errorAt(a.loc, "Argument %s.%s(%s:) must be an input type, not %s.", def.name, f.name, a.name, at.name)
+				}
+			}
+		}
+		for _, name := range def.interfaces {
+			iface := s.types[name]
+			if iface == nil || iface.kind != kindInterface {
+				return errorAt(def.loc, "%s must only implement interfaces, and %s is not one.", def.name, name)
+			}
+			for _, f := range iface.fields {
+				if def.fieldMap[f.name] == nil {
+					return errorAt(def.loc, "Interface field %s.%s expected but %s does not provide it.", name, f.name, def.name)
+				}
+			}
+		}
+		for _, name := range def.members {
+			if m := s.types[name]; m == nil || m.kind != kindObject {
+				return errorAt(def.loc, "Union %s can only include object types, and %s is not one.", def.name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve registers the resolver for a field. It panics if the schema has
+// no such field, since a mistyped name would never be called.
+func (s *Schema) Resolve(typeName, fieldName string, fn ResolverFunc) *Schema {
+	def := s.types[typeName]
+	if def == nil || (def.kind != kindObject && def.kind != kindInterface) || def.fieldMap[fieldName] == nil {
+		panic(fmt.Sprintf("graphql: schema has no field %s.%s", typeName, fieldName))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolvers[typeName+"."+fieldName] = fn
+	return s
+}
+
+// ResolveType registers how to tell which object type a value of an
+// interface or union type is. Without one, a map's "__typename" key or
+// the name of a struct's type is used.
+func (s *Schema) ResolveType(abstractType string, fn TypeResolverFunc) *Schema {
+	def := s.types[abstractType]
+	if def == nil || (def.kind != kindInterface && def.kind != kindUnion) {
+		panic(fmt.Sprintf("graphql: schema has no interface or union %s", abstractType))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typeResolvers[abstractType] = fn
+	return s
+}
+
+func (s *Schema) resolver(obj *typeDef, field string) ResolverFunc {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if fn := s.resolvers[obj.name+"."+field]; fn != nil {
+		return fn
+	}
+	// A resolver registered on an interface serves its implementations
+	for _, name := range obj.interfaces {
+		if fn := s.resolvers[name+"."+field]; fn != nil {
+			return fn
+		}
+	}
+	return nil
+}
+
+// possibleType reports whether obj is, implements or is a member of the
+// named type
+func (s *Schema) possibleType(name string, obj *typeDef) bool {
+	if name == obj.name {
+		return true
+	}
+	def := s.types[name]
+	if def == nil {
+		return false
+	}
+	switch def.kind {
+	case kindInterface:
+		for _, i := range obj.interfaces {
+			if i == name {
+				return true
+			}
+		}
+	case kindUnion:
+		for _, m := range def.members {
+			if m == obj.name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Schema) concreteType(def *typeDef, value interface{}) (*typeDef, error) {
+	s.mu.RLock()
+	fn := s.typeResolvers[def.name]
+	s.mu.RUnlock()
+
+	name := ""
+	switch {
+	case fn != nil:
+		name = fn(value)
+	default:
+		if m, ok := value.(map[string]interface{}); ok {
+			name, _ = m["__typename"].(string)
+		} else {
+			t := reflect.TypeOf(value)
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			name = t.Name()
+		}
+	}
+	obj := s.types[name]
+	if obj == nil || obj.kind != kindObject || !s.possibleType(def.name, obj) {
+		return nil, fmt.Errorf("could not resolve %T to an object type of %s", value, def.name)
+	}
+	return obj, nil
+}
+
+// Coercing input
+
+// literalValue coerces a value written in a document to typ. With nil
+// vars, as when validating, variables are accepted as they are checked
+// when their values are known.
+func (s *Schema) literalValue(typ *typeRef, v *valueNode, vars map[string]interface{}) (interface{}, error) {
+	if v.kind == valVariable {
+		if vars == nil {
+			return nil, nil
+		}
+		value := vars[v.raw]
+		if value == nil && typ.nonNull {
+			return nil, fmt.Errorf("Expected value of type %q, found null variable $%s.", typ, v.raw)
+		}
+		return value, nil
+	}
+	if v.kind == valNull {
+		if typ.nonNull {
+			return nil, fmt.Errorf("Expected value of type %q, found null.", typ)
+		}
+		return nil, nil
+	}
+	if typ.elem != nil {
+		items := v.list
+		if v.kind != valList {
+			items = []*valueNode{v}
+		}
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			value, err := s.literalValue(typ.elem, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	def := s.types[typ.name]
+	invalid := fmt.Errorf("Expected value of type %q, found %s.", typ, v)
+	switch def.kind {
+	case kindEnum:
+		if v.kind == valEnum && def.hasEnumValue(v.raw) {
+			return v.raw, nil
+		}
+		return nil, invalid
+	case kindInputObject:
+		if v.kind != valObject {
+			return nil, invalid
+		}
+		fields := map[string]*valueNode{}
+		for _, f := range v.fields {
+			if def.fieldMap[f.name] == nil {
+				return nil, fmt.Errorf("Field %q is not defined by type %q.", f.name, def.name)
+			}
+			fields[f.name] = f.value
+		}
+		obj := map[string]interface{}{}
+		for _, fd := range def.fields {
+			node, ok := fields[fd.name]
+			if ok && node.kind == valVariable && vars != nil {
+				if _, provided := vars[node.raw]; !provided {
+					ok = false
+				}
+			}
+			if !ok {
+				if err := s.defaultInto(obj, fd, typ); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			value, err := s.literalValue(fd.typ, node, vars)
+			if err != nil {
+				return nil, err
+			}
+			obj[fd.name] = value
+		}
+		return obj, nil
+	}
+
+	switch def.name {
+	case "Int":
+		if v.kind == valInt {
+			n, err := strconv.ParseInt(v.raw, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value: %s", v.raw)
+			}
+			return int(n), nil
+		}
+	case "Float":
+		if v.kind == valInt || v.kind == valFloat {
+			return strconv.ParseFloat(v.raw, 64)
+		}
+	case "String":
+		if v.kind == valString {
+			return v.raw, nil
+		}
+	case "Boolean":
+		if v.kind == valBool {
+			return v.raw == "true", nil
+		}
+	case "ID":
+		if v.kind == valString || v.kind == valInt {
+			return v.raw, nil
+		}
+	default:
+		// Custom scalars take any literal, as Go values
+		return s.plainValue(v, vars), nil
+	}
+	return nil, invalid
+}
+
+// defaultInto sets an absent input field or argument to its default,
+// failing if it is required
+func (s *Schema) defaultInto(values map[string]interface{}, fd *fieldDef, parent fmt.Stringer) error {
+	if fd.defaultValue != nil {
+		value, err := s.literalValue(fd.typ, fd.defaultValue, map[string]interface{}{})
+		if err != nil {
+			return err
+		}
+		values[fd.name] = value
+		return nil
+	}
+	if fd.typ.nonNull {
+		return fmt.Errorf("Field %s.%s of required type %s was not provided.", parent, fd.name, fd.typ)
+	}
+	return nil
+}
+
+// plainValue converts a literal to a Go value without a type
+func (s *Schema) plainValue(v *valueNode, vars map[string]interface{}) interface{} {
+	switch v.kind {
+	case valVariable:
+		return vars[v.raw]
+	case valInt:
+		n, _ := strconv.ParseInt(v.raw, 10, 64)
+		return n
+	case valFloat:
+		f, _ := strconv.ParseFloat(v.raw, 64)
+		return f
+	case valBool:
+		return v.raw == "true"
+	case valNull:
+		return nil
+	case valList:
+		list := make([]interface{}, len(v.list))
+		for i, item := range v.list {
+			list[i] = s.plainValue(item, vars)
+		}
+		return list
+	case valObject:
+		obj := map[string]interface{}{}
+		for _, f := range v.fields {
+			obj[f.name] = s.plainValue(f.value, vars)
+		}
+		return obj
+	}
+	return v.raw
+}
+
+func (d *typeDef) hasEnumValue(value string) bool {
+	for _, v := range d.enumValues {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// coerceInput coerces a variable's value, as decoded from JSON or given
+// in Go, to typ
+func (s *Schema) coerceInput(typ *typeRef, value interface{}) (interface{}, error) {
+	if value == nil {
+		if typ.nonNull {
+			return nil, fmt.Errorf("must not be null")
+		}
+		return nil, nil
+	}
+	if typ.elem != nil {
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			item, err := s.coerceInput(typ.elem, value)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			item, err := s.coerceInput(typ.elem, rv.Index(i).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("[%d] %v", i, err)
+			}
+			list[i] = item
+		}
+		return list, nil
+	}
+
+	def := s.types[typ.name]
+	rv := reflect.ValueOf(value)
+	switch def.kind {
+	case kindEnum:
+		if rv.Kind() == reflect.String && def.hasEnumValue(rv.String()) {
+			return rv.String(), nil
+		}
+		return nil, fmt.Errorf("%v is not a valid %s", value, def.name)
+	case kindInputObject:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v is not an object", value)
+		}
+		for key := range m {
+			if def.fieldMap[key] == nil {
+				return nil, fmt.Errorf("unknown field %s", key)
+			}
+		}
+		obj := map[string]interface{}{}
+		for _, fd := range def.fields {
+			v, ok := m[fd.name]
+			if !ok {
+				if err := s.defaultInto(obj, fd, typ); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			coerced, err := s.coerceInput(fd.typ, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s %v", fd.name, err)
+			}
+			obj[fd.name] = coerced
+		}
+		return obj, nil
+	}
+
+	switch def.name {
+	case "Int":
+		if n, ok := integerValue(rv); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int(n), nil
+		}
+	case "Float":
+		if f, ok := numberValue(rv); ok {
+			return f, nil
+		}
+	case "String":
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+	case "Boolean":
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+	case "ID":
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+		if n, ok := integerValue(rv); ok {
+			return strconv.FormatInt(n, 10), nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", value, def.name)
+}
+
+func integerValue(rv reflect.Value) (int64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return int64(f), true
+		}
+	}
+	return 0, false
+}
+
+func numberValue(rv reflect.Value) (float64, bool) {
+	if n, ok := integerValue(rv); ok && rv.Kind() != reflect.Float32 && rv.Kind() != reflect.Float64 {
+		return float64(n), true
+	}
+	if rv.Kind() == reflect.Float32 || rv.Kind() == reflect.Float64 {
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// serialize turns a resolved value into a scalar or enum of the response
+func (s *Schema) serialize(def *typeDef, value interface{}) (interface{}, error) {
+	if stringer, ok := value.(fmt.Stringer); ok && (def.kind == kindEnum || def.name == "String" || def.name == "ID") {
+		value = stringer.String()
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if def.kind == kindEnum {
+		if rv.Kind() == reflect.String && def.hasEnumValue(rv.String()) {
+			return rv.String(), nil
+		}
+		return nil, fmt.Errorf("%v is not a valid %s", value, def.name)
+	}
+	switch def.name {
+	case "Int":
+		if n, ok := integerValue(rv); ok {
+			return n, nil
+		}
+	case "Float":
+		if f, ok := numberValue(rv); ok {
+			return f, nil
+		}
+	case "String":
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+	case "Boolean":
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+	case "ID":
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+		if n, ok := integerValue(rv); ok {
+			return strconv.FormatInt(n, 10), nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", value, def.name)
+}
+
+// Queries
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragmentDef
+}
+
+type operation struct {
+	kind       string
+	name       string
+	vars       []*varDef
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type varDef struct {
+	name         string
+	typ          *typeRef
+	defaultValue *valueNode
+	loc          Location
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type fragmentDef struct {
+	name          string
+	typeCondition string
+	selections    []selection
+	loc           Location
+}
+
+func parseQuery(src string) (*document, error) {
+	doc := &document{fragments: map[string]*fragmentDef{}}
+	err := parseWith(src, func(p *parser) {
+		if p.peek().kind == tokEOF {
+			p.fail(p.peek(), "Unexpected <EOF>")
+		}
+		for p.peek().kind != tokEOF {
+			t := p.peek()
+			switch {
+			case p.is("{"):
+				doc.operations = append(doc.operations, &operation{kind: "query", selections: p.parseSelectionSet(), loc: t.loc})
+			case p.isKeyword("query"), p.isKeyword("mutation"), p.isKeyword("subscription"):
+				op := &operation{kind: p.next().value, loc: t.loc}
+				if p.peek().kind == tokName {
+					op.name = p.next().value
+				}
+				if p.skip("(") {
+					for !p.skip(")") {
+						p.expect("$")
+						name := p.expectName()
+						p.expect(":")
+						v := &varDef{name: name.value, typ: p.parseType(), loc: name.loc}
+						if p.skip("=") {
+							v.defaultValue = p.parseValue(true)
+						}
+						p.parseDirectives(true)
+						op.vars = append(op.vars, v)
+					}
+				}
+				op.directives = p.parseDirectives(false)
+				op.selections = p.parseSelectionSet()
+				doc.operations = append(doc.operations, op)
+			case p.isKeyword("fragment"):
+				p.next()
+				name := p.expectName()
+				if name.value == "on" {
+					p.fail(name, "Unexpected Name \"on\"")
+				}
+				p.expectKeyword("on")
+				frag := &fragmentDef{name: name.value, typeCondition: p.expectName().value, loc: name.loc}
+				p.parseDirectives(false)
+				frag.selections = p.parseSelectionSet()
+				if doc.fragments[frag.name] != nil {
+					p.fail(name, "There can be only one fragment named %q.", frag.name)
+				}
+				doc.fragments[frag.name] = frag
+			default:
+				p.fail(t, "Unexpected %s", t)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (p *parser) parseSelectionSet() []selection {
+	p.expect("{")
+	var sels []selection
+	for {
+		sels = append(sels, p.parseSelection())
+		if p.skip("}") {
+			return sels
+		}
+	}
+}
+
+func (p *parser) parseSelection() selection {
+	t := p.peek()
+	if p.skip("...") {
+		if p.isKeyword("on") {
+			p.next()
+			frag := &inlineFragment{typeCondition: p.expectName().value, loc: t.loc}
+			frag.directives = p.parseDirectives(false)
+			frag.selections = p.parseSelectionSet()
+			return frag
+		}
+		if p.is("@") || p.is("{") {
+			frag := &inlineFragment{loc: t.loc}
+			frag.directives = p.parseDirectives(false)
+			frag.selections = p.parseSelectionSet()
+			return frag
+		}
+		return &fragmentSpread{name: p.expectName().value, directives: p.parseDirectives(false), loc: t.loc}
+	}
+	name := p.expectName()
+	f := &field{name: name.value, loc: name.loc}
+	if p.skip(":") {
+		f.alias = f.name
+		f.name = p.expectName().value
+	}
+	f.args = p.parseArguments(false)
+	f.directives = p.parseDirectives(false)
+	if p.is("{") {
+		f.selections = p.parseSelectionSet()
+	}
+	return f
+}
+
+// Validation
+
+type validator struct {
+	schema *Schema
+	doc    *document
+	op     *operation
+	vars   map[string]*varDef
+	errors ErrorList
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, errorAt(loc, format, args...))
+}
+
+// validate checks a document against the schema, before anything runs
+func (s *Schema) validate(doc *document) ErrorList {
+	v := &validator{schema: s, doc: doc}
+	names := map[string]bool{}
+	for _, op := range doc.operations {
+		if op.name == "" && len(doc.operations) > 1 {
+			v.errorf(op.loc, "This anonymous operation must be the only defined operation.")
+		}
+		if op.name != "" && names[op.name] {
+			v.errorf(op.loc, "There can be only one operation named %q.", op.name)
+		}
+		names[op.name] = true
+		v.validateOperation(op)
+	}
+	return v.errors
+}
+
+func (v *validator) validateOperation(op *operation) {
+	v.op = op
+	v.vars = map[string]*varDef{}
+	for _, vd := range op.vars {
+		if v.vars[vd.name] != nil {
+			v.errorf(vd.loc, "There can be only one variable named \"$%s\".", vd.name)
+		}
+		v.vars[vd.name] = vd
+		def := v.schema.types[vd.typ.named()]
+		if def == nil {
+			v.errorf(vd.loc, "Unknown type %q.", vd.typ.named())
+			continue
+		}
+		if !def.isInput() {
+			v.errorf(vd.loc, "Variable \"$%s\" cannot be non-input type %q.", vd.name, vd.typ)
+			continue
+		}
+		if vd.defaultValue != nil {
+			if _, err := v.schema.literalValue(vd.typ, vd.defaultValue, map[string]interface{}{}); err != nil {
+				v.errorf(vd.defaultValue.loc, "%v", err)
+			}
+		}
+	}
+	v.validateDirectives(op.directives)
+
+	rootName := v.schema.queryType
+	switch op.kind {
+	case "mutation":
+		rootName = v.schema.mutationType
+	case "subscription":
+		rootName = ""
+	}
+	root := v.schema.types[rootName]
+	if root == nil {
+		v.errorf(op.loc, "Schema does not support operation type %q", op.kind)
+		return
+	}
+	v.validateSelections(root, op.selections, map[string]bool{})
+}
+
+func (v *validator) validateSelections(parent *typeDef, sels []selection, spreading map[string]bool) {
+	for _, sel := range sels {
+		switch s := sel.(type) {
+		case *field:
+			v.validateField(parent, s, spreading)
+		case *fragmentSpread:
+			v.validateDirectives(s.directives)
+			frag := v.doc.fragments[s.name]
+			if frag == nil {
+				v.errorf(s.loc, "Unknown fragment %q.", s.name)
+				continue
+			}
+			if spreading[s.name] {
+				v.errorf(s.loc, "Cannot spread fragment %q within itself.", s.name)
+				continue
+			}
+			cond := v.typeCondition(frag.typeCondition, frag.loc)
+			if cond == nil {
+				continue
+			}
+			spreading[s.name] = true
+			v.validateSelections(cond, frag.selections, spreading)
+			delete(spreading, s.name)
+		case *inlineFragment:
+			v.validateDirectives(s.directives)
+			cond := parent
+			if s.typeCondition != "" {
+				if cond = v.typeCondition(s.typeCondition, s.loc); cond == nil {
+					continue
+				}
+			}
+			v.validateSelections(cond, s.selections, spreading)
+		}
+	}
+}
+
+func (v *validator) typeCondition(name string, loc Location) *typeDef {
+	def := v.schema.types[name]
+	if def == nil {
+		v.errorf(loc, "Unknown type %q.", name)
+		return nil
+	}
+	if !def.isComposite() {
+		v.errorf(loc, "Fragment cannot condition on non composite type %q.", name)
+		return nil
+	}
+	return def
+}
+
+func (v *validator) validateField(parent *typeDef, f *field, spreading map[string]bool) {
+	v.validateDirectives(f.directives)
+	if f.name == "__typename" {
+		if f.selections != nil {
+			v.errorf(f.loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+		}
+		return
+	}
+	fd := parent.fieldMap[f.name]
+	if fd == nil || parent.kind == kindUnion {
+		v.errorf(f.loc, "Cannot query field %q on type %q.", f.name, parent.name)
+		return
+	}
+	v.validateArguments(fmt.Sprintf("%s.%s", parent.name, f.name), fd.args, f.args, f.loc)
+
+	def := v.schema.types[fd.typ.named()]
+	switch {
+	case def.isComposite() && f.selections == nil:
+		v.errorf(f.loc, "Field %q of type %q must have a selection of subfields. Did you mean \"%s { ... }\"?", f.name, fd.typ, f.name)
+	case !def.isComposite() && f.selections != nil:
+		v.errorf(f.loc, "Field %q must not have a selection since type %q has no subfields.", f.name, fd.typ)
+	case f.selections != nil:
+		v.validateSelections(def, f.selections, spreading)
+	}
+}
+
+func (v *validator) validateArguments(owner string, defs []*fieldDef, args []*argument, loc Location) {
+	for _, a := range args {
+		v.validateVariables(a.value)
+		var def *fieldDef
+		for _, d := range defs {
+			if d.name == a.name {
+				def = d
+			}
+		}
+		if def == nil {
+			v.errorf(a.loc, "Unknown argument %q on %s.", a.name, owner)
+			continue
+		}
+		if _, err := v.schema.literalValue(def.typ, a.value, nil); err != nil {
+			v.errorf(a.value.loc, "%v", err)
+		}
+	}
+	for _, d := range defs {
+		if d.typ.nonNull && d.defaultValue == nil && findArgument(args, d.name) == nil {
+			v.errorf(loc, "Argument %q of required type %q was not provided.", d.name, d.typ)
+		}
+	}
+}
+
+// validateVariables checks that the variables a value uses are defined
+func (v *validator) validateVariables(value *valueNode) {
+	switch value.kind {
+	case valVariable:
+		if v.vars[value.raw] == nil {
+			v.errorf(value.loc, "Variable \"$%s\" is not defined.", value.raw)
+		}
+	case valList:
+		for _, item := range value.list {
+			v.validateVariables(item)
+		}
+	case valObject:
+		for _, f := range value.fields {
+			v.validateVariables(f.value)
+		}
+	}
+}
+
+var ifArgument = []*fieldDef{{name: "if", typ: &typeRef{name: "Boolean", nonNull: true}}}
+
+func (v *validator) validateDirectives(dirs []*directive) {
+	for _, d := range dirs {
+		if d.name != "skip" && d.name != "include" {
+			v.errorf(d.loc, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		v.validateArguments("directive \"@"+d.name+"\"", ifArgument, d.args, d.loc)
+	}
+}
+
+// Execution, as in the graphql package
+
+// FieldContext describes the field being resolved
+type FieldContext struct {
+	// Object is the name of the type the field is on
+	Object string
+	// Field is the field's name, and Alias its key in the response
+	Field string
+	Alias string
+	// Args are the field's coerced arguments
+	Args map[string]interface{}
+	// Path is the field's path in the response
+	Path   []interface{}
+	Parent *FieldContext
+
+	field *field
+}
+
+type fieldContextKey struct{}
+type executorKey struct{}
+
+// GetFieldContext returns the field being resolved, or nil outside a
+// resolver
+func GetFieldContext(ctx context.Context) *FieldContext {
+	fc, _ := ctx.Value(fieldContextKey{}).(*FieldContext)
+	return fc
+}
+
+// AddError adds an error at the current field's path without failing the
+// field, so the resolver can still return a value
+func AddError(ctx context.Context, err error) {
+	e, _ := ctx.Value(executorKey{}).(*executor)
+	fc := GetFieldContext(ctx)
+	if e == nil || fc == nil {
+		return
+	}
+	e.fieldError(ctx, err, fc.field, fc.Path)
+}
+
+// AddErrorf is AddError with a formatted message
+func AddErrorf(ctx context.Context, format string, args ...interface{}) {
+	AddError(ctx, fmt.Errorf(format, args...))
+}
+
+// ErrorPresenterFunc turns an error a resolver returned into the error
+// in the response
+type ErrorPresenterFunc func(ctx context.Context, err error) *Error
+
+// RecoverFunc turns a resolver's panic into an error
+type RecoverFunc func(ctx context.Context, err interface{}) error
+
+// DefaultErrorPresenter uses the message of any error, and an *Error,
+// found with errors.As, as it is
+func DefaultErrorPresenter(ctx context.Context, err error) *Error {
+	var gqlErr *Error
+	if errors.As(err, &gqlErr) {
+		presented := *gqlErr
+		if presented.Err == nil && gqlErr != err {
+			presented.Err = err
+		}
+		return &presented
+	}
+	return &Error{Err: err, Message: err.Error()}
+}
+
+// DefaultRecover hides what panicked from clients
+func DefaultRecover(ctx context.Context, err interface{}) error {
+	return errors.New("internal system error")
+}
+
+// errNullField is reported for a null value of a non-null field
+var errNullField = errors.New("the requested element is null which the schema does not allow")
+
+// orderedMap is a response object, keeping its fields in the order they
+// were selected
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+type executor struct {
+	server *Server
+	schema *Schema
+	doc    *document
+	vars   map[string]interface{}
+
+	mu     sync.Mutex
+	errors ErrorList
+}
+
+func (e *executor) fieldError(ctx context.Context, err error, f *field, path []interface{}) {
+	presented := e.server.errorPresenter(ctx, err)
+	if presented == nil {
+		return
+	}
+	if presented.Path == nil {
+		presented.Path = append([]interface{}(nil), path...)
+	}
+	if presented.Locations == nil && f != nil {
+		presented.Locations = []Location{f.loc}
+	}
+	e.mu.Lock()
+	e.errors = append(e.errors, presented)
+	e.mu.Unlock()
+}
+
+// fieldGroup is the fields selected under one response key, merged
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+func (e *executor) collectFields(obj *typeDef, sels []selection, visited map[string]bool, groups []*fieldGroup) []*fieldGroup {
+	for _, sel := range sels {
+		switch s := sel.(type) {
+		case *field:
+			if !e.included(s.directives) {
+				continue
+			}
+			key := s.responseKey()
+			found := false
+			for _, g := range groups {
+				if g.key == key {
+					g.fields = append(g.fields, s)
+					found = true
+				}
+			}
+			if !found {
+				groups = append(groups, &fieldGroup{key: key, fields: []*field{s}})
+			}
+		case *fragmentSpread:
+			if !e.included(s.directives) || visited[s.name] {
+				continue
+			}
+			visited[s.name] = true
+			frag := e.doc.fragments[s.name]
+			if e.schema.possibleType(frag.typeCondition, obj) {
+				groups = e.collectFields(obj, frag.selections, visited, groups)
+			}
+		case *inlineFragment:
+			if !e.included(s.directives) {
+				continue
+			}
+			if s.typeCondition == "" || e.schema.possibleType(s.typeCondition, obj) {
+				groups = e.collectFields(obj, s.selections, visited, groups)
+			}
+		}
+	}
+	return groups
+}
+
+// included applies @skip and @include
+func (e *executor) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		arg := findArgument(d.args, "if")
+		if arg == nil {
+			continue
+		}
+		value, _ := e.schema.literalValue(&typeRef{name: "Boolean", nonNull: true}, arg.value, e.vars)
+		on, _ := value.(bool)
+		if (d.name == "skip" && on) || (d.name == "include" && !on) {
+			return false
+		}
+	}
+	return true
+}
+
+// executeSelections resolves an object's selected fields. It returns
+// false when a non-null field was null, making the object null.
+func (e *executor) executeSelections(ctx context.Context, obj *typeDef, source interface{}, sels []selection, path []interface{}) (*orderedMap, bool) {
+	result := &orderedMap{values: map[string]interface{}{}}
+	for _, g := range e.collectFields(obj, sels, map[string]bool{}, nil) {
+		value, ok := e.executeField(ctx, obj, source, g, append(path[:len(path):len(path)], g.key))
+		if !ok {
+			return nil, false
+		}
+		result.set(g.key, value)
+	}
+	return result, true
+}
+
+func (e *executor) executeField(ctx context.Context, obj *typeDef, source interface{}, g *fieldGroup, path []interface{}) (interface{}, bool) {
+	f := g.fields[0]
+	if f.name == "__typename" {
+		return obj.name, true
+	}
+	fd := obj.fieldMap[f.name]
+	fc := &FieldContext{Object: obj.name, Field: f.name, Alias: g.key, Path: path, Parent: GetFieldContext(ctx), field: f}
+	ctx = context.WithValue(ctx, fieldContextKey{}, fc)
+
+	args, err := e.coerceArguments(fd, f)
+	if err != nil {
+		e.fieldError(ctx, err, f, path)
+		return nil, !fd.typ.nonNull
+	}
+	fc.Args = args
+	value, err := e.resolve(ctx, obj, fd, source, args)
+	if err != nil {
+		e.fieldError(ctx, err, f, path)
+		return nil, !fd.typ.nonNull
+	}
+	return e.complete(ctx, fd.typ, g.fields, value, path)
+}
+
+func (e *executor) coerceArguments(fd *fieldDef, f *field) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for _, d := range fd.args {
+		a := findArgument(f.args, d.name)
+		if a != nil && a.value.kind == valVariable {
+			if _, provided := e.vars[a.value.raw]; !provided {
+				a = nil
+			}
+		}
+		if a == nil {
+			if d.defaultValue != nil {
+				value, err := e.schema.literalValue(d.typ, d.defaultValue, map[string]interface{}{})
+				if err != nil {
+					return nil, err
+				}
+				args[d.name] = value
+			} else if d.typ.nonNull {
+				return nil, fmt.Errorf("argument %q of required type %q was not provided", d.name, d.typ)
+			}
+			continue
+		}
+		value, err := e.schema.literalValue(d.typ, a.value, e.vars)
+		if err != nil {
+			return nil, err
+		}
+		args[d.name] = value
+	}
+	return args, nil
+}
+
+func (e *executor) resolve(ctx context.Context, obj *typeDef, fd *fieldDef, source interface{}, args map[string]interface{}) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = e.server.recoverFunc(ctx, r)
+		}
+	}()
+	if fn := e.schema.resolver(obj, fd.name); fn != nil {
+		return fn(ctx, source, args)
+	}
+	return defaultResolve(ctx, source, fd.name)
+}
+
+// defaultResolve reads a field from a map key, a method or a struct
+// field, matching the name case-insensitively or by json tag
+func defaultResolve(ctx context.Context, source interface{}, name string) (interface{}, error) {
+	if source == nil {
+		return nil, nil
+	}
+	if m, ok := source.(map[string]interface{}); ok {
+		return m[name], nil
+	}
+	rv := reflect.ValueOf(source)
+	for i := 0; i < rv.NumMethod(); i++ {
+		method := rv.Type().Method(i)
+		if !strings.EqualFold(method.Name, name) {
+			continue
+		}
+		fn := rv.Method(i)
+		var in []reflect.Value
+		switch fn.Type().NumIn() {
+		case 0:
+		case 1:
+			in = []reflect.Value{reflect.ValueOf(ctx)}
+		default:
+			return nil, fmt.Errorf("method %s of %T takes arguments; register a resolver", method.Name, source)
+		}
+		out := fn.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+		return out[0].Interface(), nil
+	}
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !v.IsValid() {
+				return nil, nil
+			}
+			return v.Interface(), nil
+		}
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+			if tag == name || (tag == "" && strings.EqualFold(sf.Name, name)) {
+				return rv.Field(i).Interface(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no resolver for field %q, and %T has no field or method of that name", name, source)
+}
+
+// complete turns a resolved value into the response value for typ. It
+// returns false when the value is null but typ is non-null.
+func (e *executor) complete(ctx context.Context, typ *typeRef, fields []*field, value interface{}, path []interface{}) (interface{}, bool) {
+	if typ.nonNull {
+		completed, ok := e.complete(ctx, typ.nullable(), fields, value, path)
+		if !ok {
+			return nil, false
+		}
+		if completed == nil {
+			// Errors completing the value are already reported
+			if isNil(value) {
+				e.fieldError(ctx, errNullField, fields[0], path)
+			}
+			return nil, false
+		}
+		return completed, true
+	}
+	if isNil(value) {
+		return nil, true
+	}
+
+	if typ.elem != nil {
+		rv := reflect.ValueOf(value)
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(ctx, fmt.Errorf("%T is not a list", value), fields[0], path)
+			return nil, true
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			item, ok := e.complete(ctx, typ.elem, fields, rv.Index(i).Interface(), append(path[:len(path):len(path)], i))
+			if !ok {
+				return nil, true
+			}
+			list[i] = item
+		}
+		return list, true
+	}
+
+	def := e.schema.types[typ.name]
+	switch def.kind {
+	case kindScalar, kindEnum:
+		serialized, err := e.schema.serialize(def, value)
+		if err != nil {
+			e.fieldError(ctx, err, fields[0], path)
+			return nil, true
+		}
+		return serialized, true
+	case kindInterface, kindUnion:
+		concrete, err := e.schema.concreteType(def, value)
+		if err != nil {
+			e.fieldError(ctx, err, fields[0], path)
+			return nil, true
+		}
+		def = concrete
+	}
+	var sels []selection
+	for _, f := range fields {
+		sels = append(sels, f.selections...)
+	}
+	result, ok := e.executeSelections(ctx, def, value, sels, path)
+	if !ok {
+		return nil, true
+	}
+	return result, true
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// Serving
+
+// RawParams is a request: a document, which of its operations to run and
+// the values of its variables
+type RawParams struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is null when the request
+// could not be run.
+type Response struct {
+	Errors     ErrorList              `json:"errors,omitempty"`
+	Data       json.RawMessage        `json:"data"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Server runs requests against a schema, over HTTP or directly with Exec
+type Server struct {
+	schema         *Schema
+	errorPresenter ErrorPresenterFunc
+	recoverFunc    RecoverFunc
+}
+
+// NewServer returns a server for schema, as handler.NewDefaultServer
+// does for a generated schema
+func NewServer(schema *Schema) *Server {
+	return &Server{schema: schema, errorPresenter: DefaultErrorPresenter, recoverFunc: DefaultRecover}
+}
+
+// SetErrorPresenter sets how resolver errors appear in responses
+func (s *Server) SetErrorPresenter(f ErrorPresenterFunc) { s.errorPresenter = f }
+
+// SetRecoverFunc sets how resolver panics become errors
+func (s *Server) SetRecoverFunc(f RecoverFunc) { s.recoverFunc = f }
+
+// Exec runs a request
+func (s *Server) Exec(ctx context.Context, params RawParams) *Response {
+	resp, _ := s.exec(ctx, params, false)
+	return resp
+}
+
+// exec runs a request, returning the HTTP status for it: 200 once it
+// runs, even if fields fail, and 422 if it can't
+func (s *Server) exec(ctx context.Context, params RawParams, queryOnly bool) (*Response, int) {
+	fail := func(status int, errs ...*Error) (*Response, int) {
+		return &Response{Errors: errs}, status
+	}
+	doc, err := parseQuery(params.Query)
+	if err != nil {
+		return fail(http.StatusUnprocessableEntity, err.(*Error))
+	}
+	if errs := s.schema.validate(doc); len(errs) > 0 {
+		return fail(http.StatusUnprocessableEntity, errs...)
+	}
+
+	var op *operation
+	for _, o := range doc.operations {
+		if o.name == params.OperationName || (params.OperationName == "" && len(doc.operations) == 1) {
+			op = o
+		}
+	}
+	if op == nil {
+		if params.OperationName == "" {
+			return fail(http.StatusUnprocessableEntity, &Error{Message: "An operation name is required when the document contains multiple operations."})
+		}
+		return fail(http.StatusUnprocessableEntity, &Error{Message: fmt.Sprintf("Unknown operation named %q.", params.OperationName)})
+	}
+	if queryOnly && op.kind != "query" {
+		return fail(http.StatusNotAcceptable, &Error{Message: "GET requests only allow query operations"})
+	}
+
+	vars, varErrs := s.coerceVariables(op, params.Variables)
+	if len(varErrs) > 0 {
+		return fail(http.StatusUnprocessableEntity, varErrs...)
+	}
+
+	e := &executor{server: s, schema: s.schema, doc: doc, vars: vars}
+	ctx = context.WithValue(ctx, executorKey{}, e)
+	root := s.schema.types[s.schema.queryType]
+	if op.kind == "mutation" {
+		root = s.schema.types[s.schema.mutationType]
+	}
+	data, _ := e.executeSelections(ctx, root, nil, op.selections, nil)
+	resp := &Response{Errors: e.errors, Data: json.RawMessage("null")}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fail(http.StatusInternalServerError, &Error{Err: err, Message: err.Error()})
+		}
+		resp.Data = raw
+	}
+	return resp, http.StatusOK
+}
+
+func (s *Server) coerceVariables(op *operation, raw map[string]interface{}) (map[string]interface{}, ErrorList) {
+	vars := map[string]interface{}{}
+	var errs ErrorList
+	for _, vd := range op.vars {
+		value, provided := raw[vd.name]
+		if !provided {
+			if vd.defaultValue != nil {
+				vars[vd.name], _ = s.schema.literalValue(vd.typ, vd.defaultValue, map[string]interface{}{})
+			} else if vd.typ.nonNull {
+				errs = append(errs, errorAt(vd.loc, "Variable \"$%s\" of required type %q was not provided.", vd.name, vd.typ))
+			}
+			continue
+		}
+		coerced, err := s.schema.coerceInput(vd.typ, value)
+		if err != nil {
+			errs = append(errs, errorAt(vd.loc, "Variable \"$%s\" got invalid value: %v", vd.name, err))
+			continue
+		}
+		vars[vd.name] = coerced
+	}
+	return vars, errs
+}
+
+// ServeHTTP serves GraphQL over HTTP: POST with a JSON body or an
+// application/graphql document, or GET with query, operationName and
+// variables parameters, which may only run queries
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params RawParams
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		params.Query = q.Get("query")
+		params.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &params.Variables); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "variables could not be decoded")
+				return
+			}
+		}
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "body could not be read")
+			return
+		}
+		mediaType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+		switch mediaType {
+		case "application/json", "":
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			if err := dec.Decode(&params); err != nil {
+				writeJSONError(w, http.StatusBadRequest, "json request body could not be decoded: "+err.Error())
+				return
+			}
+			params.Variables = decodeNumbers(params.Variables).(map[string]interface{})
+		case "application/graphql":
+			params.Query = string(body)
+		default:
+			writeJSONError(w, http.StatusUnsupportedMediaType, "transport not supported")
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "transport not supported")
+		return
+	}
+
+	resp, status := s.exec(r.Context(), params, r.Method == http.MethodGet)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// decodeNumbers turns json.Numbers into int64 when integral, so large IDs
+// survive, and float64 otherwise
+func decodeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = decodeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = decodeNumbers(item)
+		}
+	}
+	return v
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&Response{Errors: ErrorList{{Message: message}}, Data: json.RawMessage("null")})
+}
+
+// Test client, as in the client package
+
+// Client posts requests to a handler in memory, for tests
+type Client struct {
+	h    http.Handler
+	opts []Option
+}
+
+// Request is the HTTP request a Client sends, which options change
+type Request struct {
+	RawParams
+	Path   string
+	Header http.Header
+}
+
+// Option changes a request
+type Option func(r *Request)
+
+// NewClient returns a client for h, applying opts to every request
+func NewClient(h http.Handler, opts ...Option) *Client {
+	return &Client{h: h, opts: opts}
+}
+
+// Var sets a variable
+func Var(name string, value interface{}) Option {
+	return func(r *Request) {
+		if r.Variables == nil {
+			r.Variables = map[string]interface{}{}
+		}
+		r.Variables[name] = value
+	}
+}
+
+// Operation sets the operation to run
+func Operation(name string) Option {
+	return func(r *Request) { r.OperationName = name }
+}
+
+// AddHeader adds a request header
+func AddHeader(key, value string) Option {
+	return func(r *Request) { r.Header.Add(key, value) }
+}
+
+// Path sets the request path; the default is /query
+func Path(path string) Option {
+	return func(r *Request) { r.Path = path }
+}
+
+// RawJSONError is the errors of a response, as JSON
+type RawJSONError struct {
+	json.RawMessage
+}
+
+func (e RawJSONError) Error() string { return string(e.RawMessage) }
+
+// MustPost is Post, panicking on errors
+func (c *Client) MustPost(query string, response interface{}, options ...Option) {
+	if err := c.Post(query, response, options...); err != nil {
+		panic(err)
+	}
+}
+
+// Post runs query and decodes the response's data into response. If the
+// response has errors, the data is still decoded and the errors are
+// returned as a RawJSONError.
+func (c *Client) Post(query string, response interface{}, options ...Option) error {
+	resp, err := c.RawPost(query, options...)
+	if err != nil {
+		return err
+	}
+	if len(resp.Data) > 0 && response != nil {
+		if err := json.Unmarshal(resp.Data, response); err != nil {
+			return err
+		}
+	}
+	if len(resp.Errors) > 0 {
+		raw, _ := json.Marshal(resp.Errors)
+		return RawJSONError{raw}
+	}
+	return nil
+}
+
+// RawPost runs query and returns the whole response
+func (c *Client) RawPost(query string, options ...Option) (*Response, error) {
+	req := &Request{RawParams: RawParams{Query: query}, Path: "/query", Header: http.Header{}}
+	for _, opt := range append(append([]Option(nil), c.opts...), options...) {
+		opt(req)
+	}
+	body, err := json.Marshal(req.RawParams)
+	if err != nil {
+		return nil, err
+	}
+	r := httptest.NewRequest(http.MethodPost, req.Path, bytes.NewReader(body))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	c.h.ServeHTTP(w, r)
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("http %d: %s", w.Code, w.Body.String())
+	}
+	return &resp, nil
+}
@@ -0,0 +1,681 @@
+package main
+
+// Developed by PowerShield, as an alternative to gqlgen
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+const blogSchema = `
+"""
+The blog's API
+"""
+type Query {
+	user(id: ID!): User
+	users(role: Role, first: Int = 10): [User!]!
+	search(text: String!): [SearchResult!]!
+	node(id: ID!): Node
+	version: String
+}
+
+type Mutation {
+	createPost(input: NewPost!): Post!
+}
+
+interface Node {
+	id: ID!
+}
+
+# Users write posts
+type User implements Node {
+	id: ID!
+	name: String!
+	email: String
+	role: Role!
+	posts(limit: Int): [Post!]!
+}
+
+type Post implements Node {
+	id: ID!
+	title: String!
+	author: User!
+	tags: [String!]
+	published: Time
+}
+
+union SearchResult = User | Post
+
+enum Role {
+	ADMIN
+	EDITOR
+	READER
+}
+
+input NewPost {
+	title: String!
+	authorId: ID!
+	tags: [String!] = []
+}
+
+scalar Time
+`
+
+// User and Post are the blog's data; fields without resolvers read them
+type User struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Email *string `json:"email"`
+	Role  string  `json:"role"`
+}
+
+type Post struct {
+	ID        string
+	Title     string
+	AuthorID  string
+	Tags      []string
+	Published time.Time
+}
+
+type blog struct {
+	mu    sync.Mutex
+	users []*User
+	posts []*Post
+}
+
+func newBlog() *blog {
+	ann := "ann@example.com"
+	return &blog{
+		users: []*User{
+			{ID: "1", Name: "Ann", Email: &ann, Role: "ADMIN"},
+			{ID: "2", Name: "Ben", Role: "EDITOR"},
+			{ID: "3", Name: "Cat", Role: "READER"},
+		},
+		posts: []*Post{
+			{ID: "p1", Title: "Hello GraphQL", AuthorID: "1", Tags: []string{"graphql"}},
+			{ID: "p2", Title: "Resolvers", AuthorID: "1"},
+			{ID: "p3", Title: "Schemas", AuthorID: "2"},
+		},
+	}
+}
+
+func (b *blog) user(id string) *User {
+	for _, u := range b.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+// newBlogServer returns a server for the blog schema with its resolvers
+func newBlogServer() (*Server, *blog) {
+	b := newBlog()
+	schema := MustParseSchema(blogSchema)
+	schema.Resolve("Query", "user", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return b.user(args["id"].(string)), nil
+	})
+	schema.Resolve("Query", "users", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		var users []*User
+		for _, u := range b.users {
+			if role, ok := args["role"].(string); ok && u.Role != role {
+				continue
+			}
+			if len(users) < args["first"].(int) {
+				users = append(users, u)
+			}
+		}
+		return users, nil
+	})
+	schema.Resolve("Query", "search", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		var results []interface{}
+		text := strings.ToLower(args["text"].(string))
+		for _, u := range b.users {
+			if strings.Contains(strings.ToLower(u.Name), text) {
+				results = append(results, u)
+			}
+		}
+		for _, p := range b.posts {
+			if strings.Contains(strings.ToLower(p.Title), text) {
+				results = append(results, p)
+			}
+		}
+		return results, nil
+	})
+	schema.Resolve("Query", "node", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		id := args["id"].(string)
+		if u := b.user(id); u != nil {
+			return u, nil
+		}
+		for _, p := range b.posts {
+			if p.ID == id {
+				return p, nil
+			}
+		}
+		return nil, nil
+	})
+	schema.Resolve("User", "posts", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		var posts []*Post
+		for _, p := range b.posts {
+			if p.AuthorID == obj.(*User).ID {
+				posts = append(posts, p)
+			}
+		}
+		if limit, ok := args["limit"].(int); ok && limit < len(posts) {
+			posts = posts[:limit]
+		}
+		return posts, nil
+	})
+	schema.Resolve("Post", "author", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return b.user(obj.(*Post).AuthorID), nil
+	})
+	schema.Resolve("Mutation", "createPost", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		input := args["input"].(map[string]interface{})
+		if b.user(input["authorId"].(string)) == nil {
+			return nil, Errorf("no user %s", input["authorId"])
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		post := &Post{ID: fmt.Sprintf("p%d", len(b.posts)+1), Title: input["title"].(string), AuthorID: input["authorId"].(string)}
+		for _, tag := range input["tags"].([]interface{}) {
+			post.Tags = append(post.Tags, tag.(string))
+		}
+		b.posts = append(b.posts, post)
+		return post, nil
+	})
+	return NewServer(schema), b
+}
+
+// exec runs a query and returns its data as JSON, and its errors
+func exec(srv *Server, query string, vars map[string]interface{}) (string, ErrorList) {
+	resp := srv.Exec(context.Background(), RawParams{Query: query, Variables: vars})
+	return string(resp.Data), resp.Errors
+}
+
+// Test parsing schemas, and schema errors
+func testSchemaParsing() bool {
+	s, err := ParseSchema(blogSchema, `
+		extend type Query {
+			"The newest posts"
+			latest(count: Int = 3): [Post!]!
+		}
+		directive @auth(role: Role!) on FIELD_DEFINITION
+	`)
+	if err != nil {
+		return false
+	}
+	if s.types["Query"].fieldMap["latest"] == nil || s.mutationType != "Mutation" || len(s.types["Role"].enumValues) != 3 {
+		return false
+	}
+	if s.types["Query"].fieldMap["latest"].description != "The newest posts" {
+		return false
+	}
+
+	bad := map[string]string{
+		`type Query { user: Account }`:                         "Undefined type Account.",
+		`type Query { a: Int } type Query { b: Int }`:          "Cannot redeclare type Query.",
+		`type Mutation { a: Int }`:                             "Schema does not define the Query type.",
+		`type Query { a(x: Q): Int } type Q { b: Int }`:        "must be an input type",
+		`type Query { a: Int, a: String }`:                     "can only be defined once",
+		`type Query { a: Int } extend type Missing { b: Int }`: "does not exist",
+		`type Query { a: Int `:                                 "Expected Name, found <EOF>",
+	}
+	for sdl, want := range bad {
+		_, err := ParseSchema(sdl)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			return false
+		}
+	}
+	return true
+}
+
+// Test selecting fields, in order, with aliases
+func testFieldSelection() bool {
+	srv, _ := newBlogServer()
+	data, errs := exec(srv, `{
+		version
+		ann: user(id: "1") { name id role email }
+		ben: user(id: 2) { name email }
+		nobody: user(id: "99") { name }
+	}`, nil)
+	want := `{"version":null,"ann":{"name":"Ann","id":"1","role":"ADMIN","email":"ann@example.com"},"ben":{"name":"Ben","email":null},"nobody":null}`
+	return len(errs) == 0 && data == want
+}
+
+// Test arguments, variables and default values
+func testArgumentsAndVariables() bool {
+	srv, _ := newBlogServer()
+	query := `query Users($role: Role, $first: Int = 2) {
+		users(role: $role, first: $first) { name }
+	}`
+	all, errs1 := exec(srv, query, nil)
+	editors, errs2 := exec(srv, query, map[string]interface{}{"role": "EDITOR"})
+	one, errs3 := exec(srv, query, map[string]interface{}{"first": 1.0})
+	// A literal argument, and the schema's default
+	readers, errs4 := exec(srv, `{ users(role: READER) { name } }`, nil)
+	if len(errs1)+len(errs2)+len(errs3)+len(errs4) > 0 {
+		return false
+	}
+	return all == `{"users":[{"name":"Ann"},{"name":"Ben"}]}` &&
+		editors == `{"users":[{"name":"Ben"}]}` &&
+		one == `{"users":[{"name":"Ann"}]}` &&
+		readers == `{"users":[{"name":"Cat"}]}`
+}
+
+type account struct {
+	Handle string
+	Tags   map[string]string
+}
+
+func (a account) DisplayName() string { return "@" + a.Handle }
+
+func (a *account) Followers(ctx context.Context) (int, error) {
+	if a.Handle == "private" {
+		return 0, errors.New("followers are hidden")
+	}
+	return 42, nil
+}
+
+// Test resolving fields from maps, struct fields and methods
+func testDefaultResolvers() bool {
+	schema := MustParseSchema(`
+		type Query { account: Account, settings: Settings, private: Account }
+		type Account { handle: String!, displayName: String!, followers: Int }
+		type Settings { theme: String, fontSize: Int }
+	`)
+	schema.Resolve("Query", "account", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return &account{Handle: "ann"}, nil
+	})
+	schema.Resolve("Query", "private", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return &account{Handle: "private"}, nil
+	})
+	schema.Resolve("Query", "settings", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"theme": "dark", "fontSize": 14.0}, nil
+	})
+	data, errs := exec(NewServer(schema), `{
+		account { handle displayName followers }
+		settings { theme fontSize }
+		private { handle followers }
+	}`, nil)
+	want := `{"account":{"handle":"ann","displayName":"@ann","followers":42},"settings":{"theme":"dark","fontSize":14},"private":{"handle":"private","followers":null}}`
+	return data == want && len(errs) == 1 && errs[0].Message == "followers are hidden" &&
+		reflect.DeepEqual(errs[0].Path, []interface{}{"private", "followers"})
+}
+
+// Test field errors, and nulls reaching a nullable parent
+func testFieldErrors() bool {
+	schema := MustParseSchema(`
+		type Query { profile: Profile, count: Int, names: [String!] }
+		type Profile { name: String!, bio: String }
+	`)
+	schema.Resolve("Query", "profile", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{"bio": "hi"}, nil
+	})
+	schema.Resolve("Query", "count", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("count unavailable")
+	})
+	schema.Resolve("Query", "names", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return []interface{}{"a", 7, "c"}, nil
+	})
+	data, errs := exec(NewServer(schema), `{
+		count
+		profile { bio name }
+		names
+	}`, nil)
+	if data != `{"count":null,"profile":null,"names":null}` || len(errs) != 3 {
+		return false
+	}
+	if errs[0].Message != "count unavailable" || errs[0].Locations[0] != (Location{Line: 2, Column: 3}) {
+		return false
+	}
+	if errs[1].Message != "the requested element is null which the schema does not allow" ||
+		!reflect.DeepEqual(errs[1].Path, []interface{}{"profile", "name"}) {
+		return false
+	}
+	// The bad list item's path has its index
+	return reflect.DeepEqual(errs[2].Path, []interface{}{"names", 1}) &&
+		errs[2].Error() == "input:4:3: names[1] 7 is not a valid String"
+}
+
+// Test error extensions, presenters and AddError
+func testErrorFormatting() bool {
+	errForbidden := errors.New("forbidden")
+	schema := MustParseSchema(`type Query { secret: String, coupon: String, warning: String }`)
+	schema.Resolve("Query", "secret", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("reading secret: %w", errForbidden)
+	})
+	schema.Resolve("Query", "coupon", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		err := Errorf("coupon expired")
+		err.Extensions = map[string]interface{}{"code": "EXPIRED"}
+		return nil, err
+	})
+	schema.Resolve("Query", "warning", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		AddErrorf(ctx, "deprecated, use %s", "notice")
+		return "still works", nil
+	})
+	srv := NewServer(schema)
+	srv.SetErrorPresenter(func(ctx context.Context, err error) *Error {
+		presented := DefaultErrorPresenter(ctx, err)
+		if errors.Is(err, errForbidden) {
+			presented.Message = "forbidden"
+			presented.Extensions = map[string]interface{}{"code": "FORBIDDEN"}
+		}
+		return presented
+	})
+
+	resp := srv.Exec(context.Background(), RawParams{Query: `{ secret coupon warning }`})
+	raw, _ := json.Marshal(resp)
+	want := `{"errors":[` +
+		`{"message":"forbidden","locations":[{"line":1,"column":3}],"path":["secret"],"extensions":{"code":"FORBIDDEN"}},` +
+		`{"message":"coupon expired","locations":[{"line":1,"column":10}],"path":["coupon"],"extensions":{"code":"EXPIRED"}},` +
+		`{"message":"deprecated, use notice","locations":[{"line":1,"column":17}],"path":["warning"]}],` +
+		`"data":{"secret":null,"coupon":null,"warning":"still works"}}`
+	return string(raw) == want
+}
+
+// Test that resolver panics become errors
+func testPanics() bool {
+	schema := MustParseSchema(`type Query { boom: String, fine: String }`)
+	schema.Resolve("Query", "boom", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		var m map[string]string
+		m["x"] = "y"
+		return nil, nil
+	})
+	schema.Resolve("Query", "fine", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	srv := NewServer(schema)
+	data, errs := exec(srv, `{ boom fine }`, nil)
+	if data != `{"boom":null,"fine":"ok"}` || len(errs) != 1 || errs[0].Message != "internal system error" {
+		return false
+	}
+
+	srv.SetRecoverFunc(func(ctx context.Context, err interface{}) error {
+		return fmt.Errorf("panic in %s: %v", GetFieldContext(ctx).Field, err)
+	})
+	_, errs = exec(srv, `{ boom }`, nil)
+	return len(errs) == 1 && strings.HasPrefix(errs[0].Message, "panic in boom: assignment to entry in nil map")
+}
+
+// Test fragments, interfaces and unions
+func testFragments() bool {
+	srv, _ := newBlogServer()
+	data, errs := exec(srv, `
+		query {
+			search(text: "re") {
+				__typename
+				... on User { name }
+				... on Post { title author { ...userFields } }
+			}
+			node(id: "p3") { id ... on Post { title } }
+		}
+		fragment userFields on User { name role }
+	`, nil)
+	want := `{"search":[{"__typename":"Post","title":"Resolvers","author":{"name":"Ann","role":"ADMIN"}}],"node":{"id":"p3","title":"Schemas"}}`
+	if len(errs) != 0 || data != want {
+		return false
+	}
+
+	// A type resolver for values that aren't named after their type
+	schema := MustParseSchema(`
+		type Query { pets: [Pet!]! }
+		union Pet = Dog | Cat
+		type Dog { name: String!, barks: Boolean! }
+		type Cat { name: String!, lives: Int! }
+	`)
+	schema.Resolve("Query", "pets", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return []map[string]interface{}{{"name": "Rex", "barks": true}, {"name": "Tom", "lives": 9}}, nil
+	})
+	schema.ResolveType("Pet", func(obj interface{}) string {
+		if _, ok := obj.(map[string]interface{})["barks"]; ok {
+			return "Dog"
+		}
+		return "Cat"
+	})
+	data, errs = exec(NewServer(schema), `{ pets { __typename ... on Dog { name barks } ... on Cat { name lives } } }`, nil)
+	return len(errs) == 0 && data == `{"pets":[{"__typename":"Dog","name":"Rex","barks":true},{"__typename":"Cat","name":"Tom","lives":9}]}`
+}
+
+// Test @skip and @include
+func testDirectives() bool {
+	srv, _ := newBlogServer()
+	query := `query ($withEmail: Boolean!, $brief: Boolean = false) {
+		user(id: "1") {
+			name
+			email @include(if: $withEmail)
+			posts @skip(if: $brief) { title }
+			... on User @include(if: $withEmail) { role }
+		}
+	}`
+	full, errs1 := exec(srv, query, map[string]interface{}{"withEmail": true})
+	brief, errs2 := exec(srv, query, map[string]interface{}{"withEmail": false, "brief": true})
+	if len(errs1)+len(errs2) > 0 {
+		return false
+	}
+	return full == `{"user":{"name":"Ann","email":"ann@example.com","posts":[{"title":"Hello GraphQL"},{"title":"Resolvers"}],"role":"ADMIN"}}` &&
+		brief == `{"user":{"name":"Ann"}}`
+}
+
+// Test mutations with input objects
+func testMutations() bool {
+	srv, b := newBlogServer()
+	data, errs := exec(srv, `mutation Create($input: NewPost!) {
+		first: createPost(input: $input) { id title tags author { name } }
+		second: createPost(input: {title: "Literal", authorId: "2", tags: "go"}) { id tags }
+	}`, map[string]interface{}{"input": map[string]interface{}{"title": "Variables", "authorId": "1"}})
+	want := `{"first":{"id":"p4","title":"Variables","tags":null,"author":{"name":"Ann"}},"second":{"id":"p5","tags":["go"]}}`
+	if len(errs) != 0 || data != want || len(b.posts) != 5 {
+		return false
+	}
+
+	// A failed non-null root field makes data null
+	data, errs = exec(srv, `mutation { createPost(input: {title: "x", authorId: "42"}) { id } }`, nil)
+	if data != "null" || len(errs) != 1 || errs[0].Message != "no user 42" {
+		return false
+	}
+	// A required input field is missing
+	_, errs = exec(srv, `mutation ($input: NewPost!) { createPost(input: $input) { id } }`,
+		map[string]interface{}{"input": map[string]interface{}{"title": "x"}})
+	return len(errs) == 1 && strings.Contains(errs[0].Message, "NewPost!.authorId of required type ID! was not provided")
+}
+
+// Test validation errors
+func testValidation() bool {
+	srv, _ := newBlogServer()
+	cases := map[string]string{
+		`{ user(id: "1") { nickname } }`:                         `Cannot query field "nickname" on type "User".`,
+		`{ user { name } }`:                                      `Argument "id" of required type "ID!" was not provided.`,
+		`{ user(id: "1", full: true) { name } }`:                 `Unknown argument "full" on Query.user.`,
+		`{ user(id: "1") }`:                                      `Field "user" of type "User" must have a selection of subfields. Did you mean "user { ... }"?`,
+		`{ version { length } }`:                                 `Field "version" must not have a selection since type "String" has no subfields.`,
+		`{ user(id: $id) { name } }`:                             `Variable "$id" is not defined.`,
+		`{ user(id: "1") { ...missing } }`:                       `Unknown fragment "missing".`,
+		`{ users(role: OWNER) { name } }`:                        `Expected value of type "Role", found OWNER.`,
+		`{ users(first: "ten") { name } }`:                       `Expected value of type "Int", found "ten".`,
+		`{ search(text: "a") { name } }`:                         `Cannot query field "name" on type "SearchResult".`,
+		`{ version @cached }`:                                    `Unknown directive "@cached".`,
+		`subscription { version }`:                               `Schema does not support operation type "subscription"`,
+		`{ user(id: "1") { name } `:                              `Expected Name, found <EOF>`,
+		`fragment a on User { ...a } { user(id: "1") { ...a } }`: `Cannot spread fragment "a" within itself.`,
+	}
+	for query, want := range cases {
+		resp := srv.Exec(context.Background(), RawParams{Query: query})
+		if len(resp.Errors) == 0 || resp.Errors[0].Message != want || string(resp.Data) != "" || len(resp.Errors[0].Locations) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Test variable errors and choosing an operation
+func testOperationsAndVariables() bool {
+	srv, _ := newBlogServer()
+	doc := `
+		query Ann { user(id: "1") { name } }
+		query ById($id: ID!) { user(id: $id) { name } }
+	`
+	ctx := context.Background()
+	ann := srv.Exec(ctx, RawParams{Query: doc, OperationName: "Ann"})
+	ben := srv.Exec(ctx, RawParams{Query: doc, OperationName: "ById", Variables: map[string]interface{}{"id": 2}})
+	if string(ann.Data) != `{"user":{"name":"Ann"}}` || string(ben.Data) != `{"user":{"name":"Ben"}}` {
+		return false
+	}
+
+	errorOf := func(params RawParams) string {
+		resp := srv.Exec(ctx, params)
+		if len(resp.Errors) == 0 {
+			return ""
+		}
+		return resp.Errors[0].Message
+	}
+	return errorOf(RawParams{Query: doc}) == "An operation name is required when the document contains multiple operations." &&
+		errorOf(RawParams{Query: doc, OperationName: "Cat"}) == `Unknown operation named "Cat".` &&
+		errorOf(RawParams{Query: doc, OperationName: "ById"}) == `Variable "$id" of required type "ID!" was not provided.` &&
+		errorOf(RawParams{Query: doc, OperationName: "ById", Variables: map[string]interface{}{"id": true}}) == `Variable "$id" got invalid value: true is not a valid ID` &&
+		errorOf(RawParams{Query: `query ($n: Int) { users(first: $n) { name } }`, Variables: map[string]interface{}{"n": 1.5}}) == `Variable "$n" got invalid value: 1.5 is not a valid Int`
+}
+
+// Test serving over HTTP
+func testHTTPHandler() bool {
+	srv, _ := newBlogServer()
+	do := func(method, target, contentType, body string) (int, string) {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	code, body := do("POST", "/query", "application/json", `{"query":"query($id: ID!){ user(id: $id) { name } }","variables":{"id":"3"}}`)
+	if code != 200 || body != `{"data":{"user":{"name":"Cat"}}}` {
+		return false
+	}
+	get := "/query?" + url.Values{"query": {`{ user(id: "2") { name } }`}}.Encode()
+	if code, body = do("GET", get, "", ""); code != 200 || body != `{"data":{"user":{"name":"Ben"}}}` {
+		return false
+	}
+	if code, _ = do("POST", "/query", "application/graphql", `{ version }`); code != 200 {
+		return false
+	}
+
+	mutation := "/query?" + url.Values{"query": {`mutation { createPost(input: {title: "x", authorId: "1"}) { id } }`}}.Encode()
+	if code, body = do("GET", mutation, "", ""); code != http.StatusNotAcceptable || !strings.Contains(body, "GET requests only allow query operations") {
+		return false
+	}
+	if code, _ = do("POST", "/query", "application/json", `{"query":`); code != http.StatusBadRequest {
+		return false
+	}
+	if code, body = do("POST", "/query", "application/json", `{"query":"{ nope }"}`); code != http.StatusUnprocessableEntity || !strings.Contains(body, `"data":null`) {
+		return false
+	}
+	code, _ = do("PUT", "/query", "application/json", `{"query":"{ version }"}`)
+	return code == http.StatusMethodNotAllowed
+}
+
+// Test the client and field contexts
+func testClient() bool {
+	schema := MustParseSchema(`
+		type Query { viewer: Viewer! }
+		type Viewer { token: String, greeting(name: String!): String! }
+	`)
+	schema.Resolve("Query", "viewer", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{}, nil
+	})
+	schema.Resolve("Viewer", "greeting", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		fc := GetFieldContext(ctx)
+		return fmt.Sprintf("hello %s at %s (%s under %s)", args["name"], pathString(fc.Path), fc.Alias, fc.Parent.Field), nil
+	})
+	schema.Resolve("Viewer", "token", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("not logged in")
+	})
+
+	c := NewClient(NewServer(schema))
+	var resp struct {
+		Viewer struct {
+			Hi string
+		}
+	}
+	c.MustPost(`query Greet($n: String!) { viewer { hi: greeting(name: $n) } }`, &resp, Var("n", "Ann"), Operation("Greet"))
+	if resp.Viewer.Hi != "hello Ann at viewer.hi (hi under viewer)" {
+		return false
+	}
+
+	var partial struct {
+		Viewer struct {
+			Token *string
+		}
+	}
+	err := c.Post(`{ viewer { token } }`, &partial)
+	var rawErr RawJSONError
+	return errors.As(err, &rawErr) && strings.Contains(err.Error(), `"path":["viewer","token"]`) && partial.Viewer.Token == nil
+}
+
+// Test serializing scalars and enums
+func testScalarsAndEnums() bool {
+	type role string
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	schema := MustParseSchema(`
+		type Query { stats: Stats!, at(when: Time!): Time! }
+		type Stats { count: Int!, ratio: Float!, id: ID!, role: Role!, badRole: Role, published: Time!, big: Int! }
+		enum Role { ADMIN READER }
+		scalar Time
+	`)
+	schema.Resolve("Query", "stats", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{
+			"count": int32(3), "ratio": 1, "id": 1234, "role": role("ADMIN"),
+			"badRole": "OWNER", "published": published, "big": 3.0,
+		}, nil
+	})
+	schema.Resolve("Query", "at", func(ctx context.Context, obj interface{}, args map[string]interface{}) (interface{}, error) {
+		return args["when"], nil
+	})
+	data, errs := exec(NewServer(schema), `{
+		stats { count ratio id role badRole published big }
+		at(when: "2024-05-01")
+	}`, nil)
+	want := `{"stats":{"count":3,"ratio":1,"id":"1234","role":"ADMIN","badRole":null,"published":"2024-05-01T12:00:00Z","big":3},"at":"2024-05-01"}`
+	return data == want && len(errs) == 1 && errs[0].Message == "OWNER is not a valid Role"
+}
+
+func main() {
+	fmt.Println("Running gqlgen Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Schema Parsing", testSchemaParsing)
+	runTest("Field Selection", testFieldSelection)
+	runTest("Arguments and Variables", testArgumentsAndVariables)
+	runTest("Default Resolvers", testDefaultResolvers)
+	runTest("Field Errors", testFieldErrors)
+	runTest("Error Formatting", testErrorFormatting)
+	runTest("Panics", testPanics)
+	runTest("Fragments", testFragments)
+	runTest("Directives", testDirectives)
+	runTest("Mutations", testMutations)
+	runTest("Validation", testValidation)
+	runTest("Operations and Variables", testOperationsAndVariables)
+	runTest("HTTP Handler", testHTTPHandler)
+	runTest("Client", testClient)
+	runTest("Scalars and Enums", testScalarsAndEnums)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1058 @@
+package main
+
+// Developed by PowerShield, as an alternative to jwt-go
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned while signing, parsing and validating tokens. Parse
+// errors wrap one of the ErrToken errors, so check them with errors.Is.
+var (
+	ErrInvalidKey                = errors.New("key is invalid")
+	ErrInvalidKeyType            = errors.New("key is of invalid type")
+	ErrHashUnavailable           = errors.New("the requested hash function is unavailable")
+	ErrSignatureInvalid          = errors.New("signature is invalid")
+	ErrTokenMalformed            = errors.New("token is malformed")
+	ErrTokenUnverifiable         = errors.New("token is unverifiable")
+	ErrTokenSignatureInvalid     = errors.New("token signature is invalid")
+	ErrTokenRequiredClaimMissing = errors.New("token is missing required claim")
+	ErrTokenInvalidAudience      = errors.New("token has invalid audience")
+	ErrTokenExpired              = errors.New("token is expired")
+	ErrTokenUsedBeforeIssued     = errors.New("token used before issued")
+	ErrTokenInvalidIssuer        = errors.New("token has invalid issuer")
+	ErrTokenInvalidSubject       = errors.New("token has invalid subject")
+	ErrTokenNotValidYet          = errors.New("token is not valid yet")
+	ErrTokenInvalidId            = errors.New("token has invalid id")
+	ErrTokenInvalidClaims        = errors.New("token has invalid claims")
+	ErrInvalidType               = errors.New("invalid type for claim")
+)
+
+// newError wraps err with an optional message and further causes, giving
+// messages like "token has invalid claims: token is expired"
+func newError(message string, err error, more ...error) error {
+	format := "%w"
+	args := []interface{}{err}
+	if message != "" {
+		format = "%w: %s"
+		args = append(args, message)
+	}
+	for _, e := range more {
+		format += ": %w"
+		args = append(args, e)
+	}
+	return fmt.Errorf(format, args...)
+}
+
+// Claim types
+
+// TimePrecision is the precision NumericDate keeps
+var TimePrecision = time.Second
+
+// MarshalSingleStringAsArray encodes a one-element ClaimStrings as an
+// array; set it to false to encode a plain string
+var MarshalSingleStringAsArray = true
+
+// NumericDate is a JSON number of seconds since the epoch
+type NumericDate struct {
+	time.Time
+}
+
+// NewNumericDate truncates t to TimePrecision
+func NewNumericDate(t time.Time) *NumericDate {
+	return &NumericDate{t.Truncate(TimePrecision)}
+}
+
+func newNumericDateFromSeconds(f float64) *NumericDate {
+	round, frac := math.Modf(f)
+	return NewNumericDate(time.Unix(int64(round), int64(frac*1e9)))
+}
+
+// MarshalJSON encodes the date as seconds, with a fraction if
+// TimePrecision is finer than a second
+func (date NumericDate) MarshalJSON() ([]byte, error) {
+	var prec int
+	if TimePrecision < time.Second {
+		prec = int(math.Log10(float64(time.Second / TimePrecision)))
+	}
+	truncated := date.Truncate(TimePrecision)
+	seconds := strconv.FormatInt(truncated.Unix(), 10)
+	if prec == 0 {
+		return []byte(seconds), nil
+	}
+	nanos := strconv.FormatInt(int64(truncated.Nanosecond())+int64(time.Second), 10)
+	return []byte(seconds + "." + nanos[1:1+prec]), nil
+}
+
+// UnmarshalJSON decodes a number of seconds
+func (date *NumericDate) UnmarshalJSON(b []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(b, &number); err != nil {
+		return fmt.Errorf("could not parse NumericData: %w", err)
+	}
+	f, err := number.Float64()
+	if err != nil {
+		return fmt.Errorf("could not convert json number value to float: %w", err)
+	}
+	*date = *newNumericDateFromSeconds(f)
+	return nil
+}
+
+// ClaimStrings is a claim holding one string or an array of strings,
+// such as "aud"
+type ClaimStrings []string
+
+// UnmarshalJSON accepts a string or an array of strings
+func (s *ClaimStrings) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	var aud []string
+	switch v := value.(type) {
+	case string:
+		aud = append(aud, v)
+	case []string:
+		aud = ClaimStrings(v)
+	case []interface{}:
+		for _, vv := range v {
+			vs, ok := vv.(string)
+			if !ok {
+				return ErrInvalidType
+			}
+			aud = append(aud, vs)
+		}
+	case nil:
+		return nil
+	default:
+		return ErrInvalidType
+	}
+	*s = aud
+	return nil
+}
+
+// MarshalJSON encodes a single value as a string when
+// MarshalSingleStringAsArray is false
+func (s ClaimStrings) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 && !MarshalSingleStringAsArray {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+// Claims is the payload of a token. The getters return nil or "" for
+// claims that are absent, and an error for claims of the wrong type.
+type Claims interface {
+	GetExpirationTime() (*NumericDate, error)
+	GetIssuedAt() (*NumericDate, error)
+	GetNotBefore() (*NumericDate, error)
+	GetIssuer() (string, error)
+	GetSubject() (string, error)
+	GetAudience() (ClaimStrings, error)
+}
+
+// ClaimsValidator is implemented by custom claims with checks of their
+// own. Validate runs after the registered claims are checked.
+type ClaimsValidator interface {
+	Claims
+	Validate() error
+}
+
+// RegisteredClaims are the claims registered in RFC 7519. Embed it in a
+// struct to add custom claims.
+type RegisteredClaims struct {
+	Issuer    string       `json:"iss,omitempty"`
+	Subject   string       `json:"sub,omitempty"`
+	Audience  ClaimStrings `json:"aud,omitempty"`
+	ExpiresAt *NumericDate `json:"exp,omitempty"`
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+	IssuedAt  *NumericDate `json:"iat,omitempty"`
+	ID        string       `json:"jti,omitempty"`
+}
+
+func (c RegisteredClaims) GetExpirationTime() (*NumericDate, error) { return c.ExpiresAt, nil }
+func (c RegisteredClaims) GetNotBefore() (*NumericDate, error)      { return c.NotBefore, nil }
+func (c RegisteredClaims) GetIssuedAt() (*NumericDate, error)       { return c.IssuedAt, nil }
+func (c RegisteredClaims) GetAudience() (ClaimStrings, error)       { return c.Audience, nil }
+func (c RegisteredClaims) GetIssuer() (string, error)               { return c.Issuer, nil }
+func (c RegisteredClaims) GetSubject() (string, error)              { return c.Subject, nil }
+
+// MapClaims holds claims as decoded JSON. Numbers are float64, or
+// json.Number with WithJSONNumber.
+type MapClaims map[string]interface{}
+
+func (m MapClaims) GetExpirationTime() (*NumericDate, error) { return m.parseNumericDate("exp") }
+func (m MapClaims) GetNotBefore() (*NumericDate, error)      { return m.parseNumericDate("nbf") }
+func (m MapClaims) GetIssuedAt() (*NumericDate, error)       { return m.parseNumericDate("iat") }
+func (m MapClaims) GetAudience() (ClaimStrings, error)       { return m.parseClaimsString("aud") }
+func (m MapClaims) GetIssuer() (string, error)               { return m.parseString("iss") }
+func (m MapClaims) GetSubject() (string, error)              { return m.parseString("sub") }
+
+func (m MapClaims) parseNumericDate(key string) (*NumericDate, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	switch exp := v.(type) {
+	case float64:
+		if exp == 0 {
+			return nil, nil
+		}
+		return newNumericDateFromSeconds(exp), nil
+	case json.Number:
+		f, _ := exp.Float64()
+		return newNumericDateFromSeconds(f), nil
+	}
+	return nil, newError(fmt.Sprintf("%s is invalid", key), ErrInvalidType)
+}
+
+func (m MapClaims) parseClaimsString(key string) (ClaimStrings, error) {
+	var cs []string
+	switch v := m[key].(type) {
+	case string:
+		cs = append(cs, v)
+	case []string:
+		cs = v
+	case []interface{}:
+		for _, a := range v {
+			vs, ok := a.(string)
+			if !ok {
+				return nil, newError(fmt.Sprintf("%s is invalid", key), ErrInvalidType)
+			}
+			cs = append(cs, vs)
+		}
+	case nil:
+	default:
+		return nil, newError(fmt.Sprintf("%s is invalid", key), ErrInvalidType)
+	}
+	return cs, nil
+}
+
+func (m MapClaims) parseString(key string) (string, error) {
+	raw, ok := m[key]
+	if !ok {
+		return "", nil
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return "", newError(fmt.Sprintf("%s is invalid", key), ErrInvalidType)
+	}
+	return s, nil
+}
+
+// Signing methods
+
+// SigningMethod signs and verifies the "header.payload" signing string
+type SigningMethod interface {
+	Verify(signingString string, sig []byte, key interface{}) error
+	Sign(signingString string, key interface{}) ([]byte, error)
+	Alg() string
+}
+
+var (
+	signingMethodsMu sync.RWMutex
+	signingMethods   = make(map[string]func() SigningMethod)
+)
+
+// RegisterSigningMethod makes a signing method available for its "alg"
+func RegisterSigningMethod(alg string, f func() SigningMethod) {
+	signingMethodsMu.Lock()
+	defer signingMethodsMu.Unlock()
+	signingMethods[alg] = f
+}
+
+// GetSigningMethod returns the signing method for alg, or nil
+func GetSigningMethod(alg string) SigningMethod {
+	signingMethodsMu.RLock()
+	defer signingMethodsMu.RUnlock()
+	if f, ok := signingMethods[alg]; ok {
+		return f()
+	}
+	return nil
+}
+
+// GetAlgorithms lists the registered algorithms
+func GetAlgorithms() []string {
+	signingMethodsMu.RLock()
+	defer signingMethodsMu.RUnlock()
+	algs := make([]string, 0, len(signingMethods))
+	for alg := range signingMethods {
+		algs = append(algs, alg)
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// SigningMethodHMAC signs with a shared secret. Keys are []byte.
+type SigningMethodHMAC struct {
+	Name string
+	Hash crypto.Hash
+}
+
+// HMAC signing methods
+var (
+	SigningMethodHS256 = &SigningMethodHMAC{"HS256", crypto.SHA256}
+	SigningMethodHS384 = &SigningMethodHMAC{"HS384", crypto.SHA384}
+	SigningMethodHS512 = &SigningMethodHMAC{"HS512", crypto.SHA512}
+)
+
+func (m *SigningMethodHMAC) Alg() string {
+	return m.Name
+}
+
+// Sign computes the HMAC of signingString with a []byte key
+func (m *SigningMethodHMAC) Sign(signingString string, key interface{}) ([]byte, error) {
+	keyBytes, ok := key.([]byte)
+	if !ok {
+		return nil, newError("HMAC sign expects []byte", ErrInvalidKeyType)
+	}
+	if len(keyBytes) == 0 {
+		return nil, ErrInvalidKey
+	}
+	if !m.Hash.Available() {
+		return nil, ErrHashUnavailable
+	}
+	hasher := hmac.New(m.Hash.New, keyBytes)
+	hasher.Write([]byte(signingString))
+	return hasher.Sum(nil), nil
+}
+
+// Verify checks an HMAC signature in constant time
+func (m *SigningMethodHMAC) Verify(signingString string, sig []byte, key interface{}) error {
+	keyBytes, ok := key.([]byte)
+	if !ok {
+		return newError("HMAC verify expects []byte", ErrInvalidKeyType)
+	}
+	if !m.Hash.Available() {
+		return ErrHashUnavailable
+	}
+	hasher := hmac.New(m.Hash.New, keyBytes)
+	hasher.Write([]byte(signingString))
+	if !hmac.Equal(sig, hasher.Sum(nil)) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// SigningMethodRSA signs with RSASSA-PKCS1-v1_5. Sign takes an
+// *rsa.PrivateKey and Verify an *rsa.PublicKey.
+type SigningMethodRSA struct {
+	Name string
+	Hash crypto.Hash
+}
+
+// RSA signing methods
+var (
+	SigningMethodRS256 = &SigningMethodRSA{"RS256", crypto.SHA256}
+	SigningMethodRS384 = &SigningMethodRSA{"RS384", crypto.SHA384}
+	SigningMethodRS512 = &SigningMethodRSA{"RS512", crypto.SHA512}
+)
+
+func (m *SigningMethodRSA) Alg() string {
+	return m.Name
+}
+
+// Sign signs signingString with an *rsa.PrivateKey
+func (m *SigningMethodRSA) Sign(signingString string, key interface{}) ([]byte, error) {
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, newError("RSA sign expects *rsa.PrivateKey", ErrInvalidKeyType)
+	}
+	if !m.Hash.Available() {
+		return nil, ErrHashUnavailable
+	}
+	hasher := m.Hash.New()
+	hasher.Write([]byte(signingString))
+	return rsa.SignPKCS1v15(rand.Reader, rsaKey, m.Hash, hasher.Sum(nil))
+}
+
+// Verify checks a signature with an *rsa.PublicKey
+func (m *SigningMethodRSA) Verify(signingString string, sig []byte, key interface{}) error {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return newError("RSA verify expects *rsa.PublicKey", ErrInvalidKeyType)
+	}
+	if !m.Hash.Available() {
+		return ErrHashUnavailable
+	}
+	hasher := m.Hash.New()
+	hasher.Write([]byte(signingString))
+	return rsa.VerifyPKCS1v15(rsaKey, m.Hash, hasher.Sum(nil), sig)
+}
+
+type unsafeNoneMagicConstant string
+
+// UnsafeAllowNoneSignatureType is the only key SigningMethodNone accepts,
+// so unsigned tokens are never accepted by accident
+const UnsafeAllowNoneSignatureType unsafeNoneMagicConstant = "none signing method allowed"
+
+// NoneSignatureTypeDisallowedError is returned for "none" tokens
+// verified with any other key
+var NoneSignatureTypeDisallowedError = newError("'none' signature type is not allowed", ErrTokenUnverifiable)
+
+type signingMethodNone struct{}
+
+// SigningMethodNone produces unsigned tokens
+var SigningMethodNone = &signingMethodNone{}
+
+func (m *signingMethodNone) Alg() string {
+	return "none"
+}
+
+func (m *signingMethodNone) Verify(signingString string, sig []byte, key interface{}) error {
+	if _, ok := key.(unsafeNoneMagicConstant); !ok {
+		return NoneSignatureTypeDisallowedError
+	}
+	if len(sig) != 0 {
+		return newError("'none' signing method with non-empty signature", ErrTokenUnverifiable)
+	}
+	return nil
+}
+
+func (m *signingMethodNone) Sign(signingString string, key interface{}) ([]byte, error) {
+	if _, ok := key.(unsafeNoneMagicConstant); !ok {
+		return nil, NoneSignatureTypeDisallowedError
+	}
+	return []byte{}, nil
+}
+
+func init() {
+	for _, m := range []SigningMethod{SigningMethodHS256, SigningMethodHS384, SigningMethodHS512,
+		SigningMethodRS256, SigningMethodRS384, SigningMethodRS512, SigningMethodNone} {
+		method := m
+		RegisterSigningMethod(method.Alg(), func() SigningMethod { return method })
+	}
+}
+
+// RSA keys
+
+// Errors from parsing PEM keys
+var (
+	ErrKeyMustBePEMEncoded = errors.New("invalid key: Key must be a PEM encoded PKCS1 or PKCS8 key")
+	ErrNotRSAPrivateKey    = errors.New("key is not a valid RSA private key")
+	ErrNotRSAPublicKey     = errors.New("key is not a valid RSA public key")
+)
+
+// ParseRSAPrivateKeyFromPEM parses a PKCS1 or PKCS8 private key
+func ParseRSAPrivateKeyFromPEM(key []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+	if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return parsed, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pkey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAPrivateKey
+	}
+	return pkey, nil
+}
+
+// ParseRSAPublicKeyFromPEM parses a PKIX or PKCS1 public key, or the key
+// of a certificate
+func ParseRSAPublicKeyFromPEM(key []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, ErrKeyMustBePEMEncoded
+	}
+	var parsed interface{}
+	var err error
+	if parsed, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		if cert, certErr := x509.ParseCertificate(block.Bytes); certErr == nil {
+			parsed = cert.PublicKey
+		} else if parsed, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	pkey, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrNotRSAPublicKey
+	}
+	return pkey, nil
+}
+
+// Tokens
+
+// Token is a JWT being created or one that was parsed
+type Token struct {
+	// Raw is the token string, set when parsing
+	Raw       string
+	Method    SigningMethod
+	Header    map[string]interface{}
+	Claims    Claims
+	Signature []byte
+	// Valid is set when a parsed token was verified and validated
+	Valid bool
+}
+
+// Keyfunc returns the key to verify a token with. It can read the
+// token's header (such as "kid") and claims, which are not yet verified.
+type Keyfunc func(*Token) (interface{}, error)
+
+// New creates a token with empty MapClaims
+func New(method SigningMethod) *Token {
+	return NewWithClaims(method, MapClaims{})
+}
+
+// NewWithClaims creates a token for signing
+func NewWithClaims(method SigningMethod, claims Claims) *Token {
+	return &Token{
+		Header: map[string]interface{}{
+			"typ": "JWT",
+			"alg": method.Alg(),
+		},
+		Claims: claims,
+		Method: method,
+	}
+}
+
+// SignedString returns the complete, signed token
+func (t *Token) SignedString(key interface{}) (string, error) {
+	sstr, err := t.SigningString()
+	if err != nil {
+		return "", err
+	}
+	sig, err := t.Method.Sign(sstr, key)
+	if err != nil {
+		return "", err
+	}
+	return sstr + "." + t.EncodeSegment(sig), nil
+}
+
+// SigningString returns the encoded header and claims that are signed
+func (t *Token) SigningString() (string, error) {
+	h, err := json.Marshal(t.Header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(t.Claims)
+	if err != nil {
+		return "", err
+	}
+	return t.EncodeSegment(h) + "." + t.EncodeSegment(c), nil
+}
+
+// EncodeSegment encodes with unpadded base64url
+func (*Token) EncodeSegment(seg []byte) string {
+	return base64.RawURLEncoding.EncodeToString(seg)
+}
+
+// Validation
+
+// Validator checks the registered claims of a token. The parser creates
+// one from its options; it can also be used on its own.
+type Validator struct {
+	leeway       time.Duration
+	timeFunc     func() time.Time
+	verifyIat    bool
+	requireExp   bool
+	expectedAud  []string
+	expectAllAud bool
+	expectedIss  string
+	expectedSub  string
+}
+
+// NewValidator creates a Validator from the parser's validation options
+func NewValidator(opts ...ParserOption) *Validator {
+	p := NewParser(opts...)
+	return p.validator
+}
+
+// Validate checks exp, nbf and, when asked for, iat, aud, iss and sub,
+// then runs the claims' own Validate. All failures are returned joined.
+func (v *Validator) Validate(claims Claims) error {
+	now := time.Now()
+	if v.timeFunc != nil {
+		now = v.timeFunc()
+	}
+
+	var errs []error
+	if err := v.verifyExpiresAt(claims, now, v.requireExp); err != nil {
+		errs = append(errs, err)
+	}
+	if err := v.verifyNotBefore(claims, now); err != nil {
+		errs = append(errs, err)
+	}
+	if v.verifyIat {
+		if err := v.verifyIssuedAt(claims, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(v.expectedAud) > 0 {
+		if err := v.verifyAudience(claims); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if v.expectedIss != "" {
+		if err := v.verifyString(claims.GetIssuer, "iss", v.expectedIss, ErrTokenInvalidIssuer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if v.expectedSub != "" {
+		if err := v.verifyString(claims.GetSubject, "sub", v.expectedSub, ErrTokenInvalidSubject); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cv, ok := claims.(ClaimsValidator); ok {
+		if err := cv.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func requiredClaimMissing(claim string) error {
+	return newError(fmt.Sprintf("%s claim is required", claim), ErrTokenRequiredClaimMissing)
+}
+
+func (v *Validator) verifyExpiresAt(claims Claims, now time.Time, required bool) error {
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return err
+	}
+	if exp == nil {
+		if required {
+			return requiredClaimMissing("exp")
+		}
+		return nil
+	}
+	if !now.Before(exp.Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+func (v *Validator) verifyNotBefore(claims Claims, now time.Time) error {
+	nbf, err := claims.GetNotBefore()
+	if err != nil || nbf == nil {
+		return err
+	}
+	if now.Add(v.leeway).Before(nbf.Time) {
+		return ErrTokenNotValidYet
+	}
+	return nil
+}
+
+func (v *Validator) verifyIssuedAt(claims Claims, now time.Time) error {
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return err
+	}
+	if now.Add(v.leeway).Before(iat.Time) {
+		return ErrTokenUsedBeforeIssued
+	}
+	return nil
+}
+
+func (v *Validator) verifyAudience(claims Claims) error {
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return err
+	}
+	if len(aud) == 0 {
+		return requiredClaimMissing("aud")
+	}
+	matched := 0
+	for _, expected := range v.expectedAud {
+		for _, a := range aud {
+			if a == expected {
+				matched++
+				break
+			}
+		}
+	}
+	if matched == 0 || (v.expectAllAud && matched != len(v.expectedAud)) {
+		return ErrTokenInvalidAudience
+	}
+	return nil
+}
+
+func (v *Validator) verifyString(get func() (string, error), claim, expected string, invalid error) error {
+	value, err := get()
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return requiredClaimMissing(claim)
+	}
+	if value != expected {
+		return invalid
+	}
+	return nil
+}
+
+// Parsing
+
+// Parser parses and verifies tokens
+type Parser struct {
+	validMethods         []string
+	useJSONNumber        bool
+	skipClaimsValidation bool
+	decodeStrict         bool
+	decodePaddingAllowed bool
+	validator            *Validator
+}
+
+// ParserOption configures a Parser or Validator
+type ParserOption func(*Parser)
+
+// WithValidMethods accepts only tokens signed with these algorithms.
+// Without it, a Keyfunc should check token.Method itself.
+func WithValidMethods(methods []string) ParserOption {
+	return func(p *Parser) { p.validMethods = methods }
+}
+
+// WithJSONNumber decodes numbers in MapClaims as json.Number
+func WithJSONNumber() ParserOption {
+	return func(p *Parser) { p.useJSONNumber = true }
+}
+
+// WithoutClaimsValidation skips validating the claims
+func WithoutClaimsValidation() ParserOption {
+	return func(p *Parser) { p.skipClaimsValidation = true }
+}
+
+// WithLeeway allows for clock skew when checking exp, nbf and iat
+func WithLeeway(leeway time.Duration) ParserOption {
+	return func(p *Parser) { p.validator.leeway = leeway }
+}
+
+// WithTimeFunc sets the clock claims are checked against
+func WithTimeFunc(f func() time.Time) ParserOption {
+	return func(p *Parser) { p.validator.timeFunc = f }
+}
+
+// WithIssuedAt rejects tokens issued in the future
+func WithIssuedAt() ParserOption {
+	return func(p *Parser) { p.validator.verifyIat = true }
+}
+
+// WithExpirationRequired rejects tokens without exp
+func WithExpirationRequired() ParserOption {
+	return func(p *Parser) { p.validator.requireExp = true }
+}
+
+// WithAudience requires aud to contain one of these audiences
+func WithAudience(aud ...string) ParserOption {
+	return func(p *Parser) { p.validator.expectedAud = aud }
+}
+
+// WithAllAudiences requires aud to contain all of these audiences
+func WithAllAudiences(aud ...string) ParserOption {
+	return func(p *Parser) {
+		p.validator.expectedAud = aud
+		p.validator.expectAllAud = true
+	}
+}
+
+// WithIssuer requires iss to be iss
+func WithIssuer(iss string) ParserOption {
+	return func(p *Parser) { p.validator.expectedIss = iss }
+}
+
+// WithSubject requires sub to be sub
+func WithSubject(sub string) ParserOption {
+	return func(p *Parser) { p.validator.expectedSub = sub }
+}
+
+// WithPaddingAllowed accepts base64 segments with "=" padding
+func WithPaddingAllowed() ParserOption {
+	return func(p *Parser) { p.decodePaddingAllowed = true }
+}
+
+// WithStrictDecoding rejects base64 segments with non-zero padding bits
+func WithStrictDecoding() ParserOption {
+	return func(p *Parser) { p.decodeStrict = true }
+}
+
+// NewParser creates a parser
+func NewParser(options ...ParserOption) *Parser {
+	p := &Parser{validator: &Validator{}}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Parse parses, verifies and validates a token into MapClaims
+func (p *Parser) Parse(tokenString string, keyFunc Keyfunc) (*Token, error) {
+	return p.ParseWithClaims(tokenString, MapClaims{}, keyFunc)
+}
+
+// ParseWithClaims parses into claims, a pointer to a struct or MapClaims.
+// The signature is verified with the key from keyFunc before the claims
+// are validated.
+func (p *Parser) ParseWithClaims(tokenString string, claims Claims, keyFunc Keyfunc) (*Token, error) {
+	token, parts, err := p.ParseUnverified(tokenString, claims)
+	if err != nil {
+		return token, err
+	}
+
+	if p.validMethods != nil {
+		alg := token.Method.Alg()
+		valid := false
+		for _, m := range p.validMethods {
+			if m == alg {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return token, newError(fmt.Sprintf("signing method %v is invalid", alg), ErrTokenSignatureInvalid)
+		}
+	}
+
+	token.Signature, err = p.DecodeSegment(parts[2])
+	if err != nil {
+		return token, newError("could not base64 decode signature", ErrTokenMalformed, err)
+	}
+	if keyFunc == nil {
+		return token, newError("no keyfunc was provided", ErrTokenUnverifiable)
+	}
+	key, err := keyFunc(token)
+	if err != nil {
+		return token, newError("error while executing keyfunc", ErrTokenUnverifiable, err)
+	}
+	if err := token.Method.Verify(strings.Join(parts[0:2], "."), token.Signature, key); err != nil {
+		return token, newError("", ErrTokenSignatureInvalid, err)
+	}
+
+	if !p.skipClaimsValidation {
+		if err := p.validator.Validate(token.Claims); err != nil {
+			return token, newError("", ErrTokenInvalidClaims, err)
+		}
+	}
+	token.Valid = true
+	return token, nil
+}
+
+// ParseUnverified decodes a token without verifying its signature. Only
+// use it when the signature was checked elsewhere.
+func (p *Parser) ParseUnverified(tokenString string, claims Claims) (*Token, []string, error) {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return nil, parts, newError("token contains an invalid number of segments", ErrTokenMalformed)
+	}
+	token := &Token{Raw: tokenString}
+
+	headerBytes, err := p.DecodeSegment(parts[0])
+	if err != nil {
+		return token, parts, newError("could not base64 decode header", ErrTokenMalformed, err)
+	}
+	if err := json.Unmarshal(headerBytes, &token.Header); err != nil {
+		return token, parts, newError("could not JSON decode header", ErrTokenMalformed, err)
+	}
+
+	token.Claims = claims
+	claimBytes, err := p.DecodeSegment(parts[1])
+	if err != nil {
+		return token, parts, newError("could not base64 decode claim", ErrTokenMalformed, err)
+	}
+	dec := json.NewDecoder(bytes.NewBuffer(claimBytes))
+	if p.useJSONNumber {
+		dec.UseNumber()
+	}
+	if c, ok := token.Claims.(MapClaims); ok {
+		err = dec.Decode(&c)
+	} else {
+		err = dec.Decode(&claims)
+	}
+	if err != nil {
+		return token, parts, newError("could not JSON decode claim", ErrTokenMalformed, err)
+	}
+
+	alg, ok := token.Header["alg"].(string)
+	if !ok {
+		return token, parts, newError("signing method (alg) is unspecified", ErrTokenUnverifiable)
+	}
+	if token.Method = GetSigningMethod(alg); token.Method == nil {
+		return token, parts, newError("signing method (alg) is unavailable", ErrTokenUnverifiable)
+	}
+	return token, parts, nil
+}
+
+// DecodeSegment decodes unpadded base64url, honoring the padding and
+// strictness options
+func (p *Parser) DecodeSegment(seg string) ([]byte, error) {
+	encoding := base64.RawURLEncoding
+	if p.decodePaddingAllowed {
+		if l := len(seg) % 4; l > 0 {
+			seg += strings.Repeat("=", 4-l)
+		}
+		encoding = base64.URLEncoding
+	}
+	if p.decodeStrict {
+		encoding = encoding.Strict()
+	}
+	return encoding.DecodeString(seg)
+}
+
+// Parse parses a token into MapClaims with a new parser
+func Parse(tokenString string, keyFunc Keyfunc, options ...ParserOption) (*Token, error) {
+	return NewParser(options...).Parse(tokenString, keyFunc)
+}
+
+// ParseWithClaims parses a token into claims with a new parser
+func ParseWithClaims(tokenString string, claims Claims, keyFunc Keyfunc, options ...ParserOption) (*Token, error) {
+	return NewParser(options...).ParseWithClaims(tokenString, claims, keyFunc)
+}
+
+// Gin bearer authentication
+
+// BearerValidator adapts the parser to the Gin emulator's TokenValidator,
+// so BearerAuth(BearerValidator(...)) accepts valid tokens and stores
+// their claims under AuthUserKey. newClaims makes the claims each token
+// is parsed into; nil means MapClaims.
+func BearerValidator(keyFunc Keyfunc, newClaims func() Claims, options ...ParserOption) func(token string) (interface{}, error) {
+	parser := NewParser(options...)
+	return func(tokenString string) (interface{}, error) {
+		var claims Claims = MapClaims{}
+		if newClaims != nil {
+			claims = newClaims()
+		}
+		token, err := parser.ParseWithClaims(tokenString, claims, keyFunc)
+		if err != nil {
+			return nil, err
+		}
+		return token.Claims, nil
+	}
+}
+
+// Go-kit middleware
+
+// Endpoint has the signature of the Go-kit emulator's Endpoint
+type Endpoint func(ctx context.Context, request interface{}) (response interface{}, err error)
+
+// Middleware has the signature of the Go-kit emulator's Middleware
+type Middleware func(Endpoint) Endpoint
+
+type contextKey string
+
+// Context keys used by the Go-kit middleware
+const (
+	// JWTContextKey holds the raw token string
+	JWTContextKey contextKey = "JWTToken"
+	// JWTClaimsContextKey holds the claims of a parsed token
+	JWTClaimsContextKey contextKey = "JWTClaims"
+)
+
+// Errors returned by the Go-kit middleware, besides the parser's own
+var (
+	ErrTokenContextMissing     = errors.New("token up for parsing was not passed through the context")
+	ErrUnexpectedSigningMethod = errors.New("unexpected signing method")
+)
+
+// ClaimsFactory makes the claims a token is parsed into
+type ClaimsFactory func() Claims
+
+// MapClaimsFactory parses into MapClaims
+func MapClaimsFactory() Claims {
+	return MapClaims{}
+}
+
+// RegisteredClaimsFactory parses into RegisteredClaims
+func RegisteredClaimsFactory() Claims {
+	return &RegisteredClaims{}
+}
+
+// NewSigner returns a client-side middleware that signs claims and puts
+// the token in the context under JWTContextKey, for ContextToHTTP or a
+// transport to send. kid, if set, goes in the token header.
+func NewSigner(kid string, key interface{}, method SigningMethod, claims Claims) Middleware {
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			token := NewWithClaims(method, claims)
+			if kid != "" {
+				token.Header["kid"] = kid
+			}
+			tokenString, err := token.SignedString(key)
+			if err != nil {
+				return nil, err
+			}
+			ctx = context.WithValue(ctx, JWTContextKey, tokenString)
+			return next(ctx, request)
+		}
+	}
+}
+
+// NewParserMiddleware returns a server-side middleware that parses the
+// token under JWTContextKey, rejects tokens not signed with method, and
+// passes the claims on under JWTClaimsContextKey. It is Go-kit's
+// jwt.NewParser; the name NewParser belongs to the token parser here.
+func NewParserMiddleware(keyFunc Keyfunc, method SigningMethod, newClaims ClaimsFactory, options ...ParserOption) Middleware {
+	parser := NewParser(options...)
+	return func(next Endpoint) Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			tokenString, ok := ctx.Value(JWTContextKey).(string)
+			if !ok {
+				return nil, ErrTokenContextMissing
+			}
+			token, err := parser.ParseWithClaims(tokenString, newClaims(), func(token *Token) (interface{}, error) {
+				if token.Method.Alg() != method.Alg() {
+					return nil, ErrUnexpectedSigningMethod
+				}
+				return keyFunc(token)
+			})
+			if err != nil {
+				return nil, err
+			}
+			ctx = context.WithValue(ctx, JWTClaimsContextKey, token.Claims)
+			return next(ctx, request)
+		}
+	}
+}
+
+// HTTPToContext moves a bearer token from the Authorization header into
+// the context, for use as a server before-function
+func HTTPToContext() func(context.Context, *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		token, ok := extractTokenFromAuthHeader(r.Header.Get("Authorization"))
+		if !ok {
+			return ctx
+		}
+		return context.WithValue(ctx, JWTContextKey, token)
+	}
+}
+
+// ContextToHTTP sets the Authorization header from the token in the
+// context, for use as a client before-function
+func ContextToHTTP() func(context.Context, *http.Request) context.Context {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		if token, ok := ctx.Value(JWTContextKey).(string); ok {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return ctx
+	}
+}
+
+func extractTokenFromAuthHeader(val string) (string, bool) {
+	authHeaderParts := strings.Split(val, " ")
+	if len(authHeaderParts) != 2 || !strings.EqualFold(authHeaderParts[0], "bearer") {
+		return "", false
+	}
+	return authHeaderParts[1], true
+}
@@ -0,0 +1,2193 @@
+package main
+
+// Developed by PowerShield, as an alternative to Sarama
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Special offsets for ConsumePartition, GetOffset and
+// Config.Consumer.Offsets.Initial
+const (
+	// OffsetNewest is the offset of the next message to be produced
+	OffsetNewest int64 = -1
+	// OffsetOldest is the oldest offset still available on the broker
+	OffsetOldest int64 = -2
+)
+
+// Errors returned by clients, producers and consumers
+var (
+	ErrOutOfBrokers        = errors.New("kafka: client has run out of available brokers to talk to")
+	ErrClosedClient        = errors.New("kafka: tried to use a client that was closed")
+	ErrShuttingDown        = errors.New("kafka: message received by producer in process of shutting down")
+	ErrInvalidPartition    = errors.New("kafka: partitioner returned an invalid partition index")
+	ErrClosedConsumerGroup = errors.New("kafka: tried to use a consumer group that was closed")
+)
+
+// KError is an error code returned by the broker
+type KError int16
+
+// Broker error codes
+const (
+	ErrNoError                  KError = 0
+	ErrOffsetOutOfRange         KError = 1
+	ErrUnknownTopicOrPartition  KError = 3
+	ErrMessageSizeTooLarge      KError = 10
+	ErrInvalidTopic             KError = 17
+	ErrRebalanceInProgress      KError = 27
+	ErrTopicAlreadyExists       KError = 36
+	ErrInvalidPartitions        KError = 37
+	ErrInvalidReplicationFactor KError = 38
+	ErrNonEmptyGroup            KError = 68
+	ErrGroupIDNotFound          KError = 69
+)
+
+func (err KError) Error() string {
+	switch err {
+	case ErrNoError:
+		return "kafka server: Not an error, why are you printing me?"
+	case ErrOffsetOutOfRange:
+		return "kafka server: The requested offset is outside the range of offsets maintained by the server for the given topic/partition"
+	case ErrUnknownTopicOrPartition:
+		return "kafka server: Request was for a topic or partition that does not exist on this broker"
+	case ErrMessageSizeTooLarge:
+		return "kafka server: Message was too large, server rejected it to avoid allocation error"
+	case ErrInvalidTopic:
+		return "kafka server: The request attempted to perform an operation on an invalid topic"
+	case ErrRebalanceInProgress:
+		return "kafka server: The broker is rebalancing the consumer group"
+	case ErrTopicAlreadyExists:
+		return "kafka server: Topic with this name already exists"
+	case ErrInvalidPartitions:
+		return "kafka server: Number of partitions is invalid"
+	case ErrInvalidReplicationFactor:
+		return "kafka server: Replication-factor is invalid"
+	case ErrNonEmptyGroup:
+		return "kafka server: The group is not empty"
+	case ErrGroupIDNotFound:
+		return "kafka server: The group id does not exist"
+	}
+	return fmt.Sprintf("Unknown error, how did this happen? Error code = %d", int16(err))
+}
+
+// ConfigurationError is returned for an invalid Config
+type ConfigurationError string
+
+func (err ConfigurationError) Error() string {
+	return "kafka: invalid configuration (" + string(err) + ")"
+}
+
+// RequiredAcks is how many replicas must acknowledge a produced message
+type RequiredAcks int16
+
+const (
+	NoResponse   RequiredAcks = 0
+	WaitForLocal RequiredAcks = 1
+	WaitForAll   RequiredAcks = -1
+)
+
+// Config holds the settings for clients, producers and consumers
+type Config struct {
+	// ClientID names the client in member IDs
+	ClientID string
+
+	// ChannelBufferSize is the buffer size of the channels producers and
+	// consumers hand messages over on
+	ChannelBufferSize int
+
+	Producer struct {
+		// MaxMessageBytes is the largest key plus value accepted
+		MaxMessageBytes int
+		RequiredAcks    RequiredAcks
+		// Partitioner picks the partition of messages without one
+		Partitioner PartitionerConstructor
+
+		// Return selects what the async producer reports on its
+		// Successes and Errors channels. A sync producer needs both.
+		Return struct {
+			Successes bool
+			Errors    bool
+		}
+	}
+
+	Consumer struct {
+		Return struct {
+			// Errors sends consumer errors to the Errors channel
+			Errors bool
+		}
+
+		Offsets struct {
+			// Initial is where to start without a committed offset:
+			// OffsetNewest or OffsetOldest
+			Initial int64
+
+			// AutoCommit commits marked offsets every Interval and when a
+			// session ends. Without it only Commit commits.
+			AutoCommit struct {
+				Enable   bool
+				Interval time.Duration
+			}
+		}
+
+		Group struct {
+			Rebalance struct {
+				// GroupStrategies lists the assignment strategies in order
+				// of preference; the group uses its leader's first one
+				GroupStrategies []BalanceStrategy
+			}
+		}
+	}
+}
+
+// NewConfig returns a Config with Sarama's defaults
+func NewConfig() *Config {
+	c := &Config{}
+	c.ClientID = "sarama"
+	c.ChannelBufferSize = 256
+	c.Producer.MaxMessageBytes = 1000000
+	c.Producer.RequiredAcks = WaitForLocal
+	c.Producer.Partitioner = NewHashPartitioner
+	c.Producer.Return.Errors = true
+	c.Consumer.Offsets.Initial = OffsetNewest
+	c.Consumer.Offsets.AutoCommit.Enable = true
+	c.Consumer.Offsets.AutoCommit.Interval = time.Second
+	c.Consumer.Group.Rebalance.GroupStrategies = []BalanceStrategy{BalanceStrategyRange}
+	return c
+}
+
+// Validate checks the config for invalid values
+func (c *Config) Validate() error {
+	switch {
+	case c.ClientID == "":
+		return ConfigurationError("ClientID is invalid")
+	case c.ChannelBufferSize < 0:
+		return ConfigurationError("ChannelBufferSize must be >= 0")
+	case c.Producer.MaxMessageBytes <= 0:
+		return ConfigurationError("Producer.MaxMessageBytes must be > 0")
+	case c.Producer.Partitioner == nil:
+		return ConfigurationError("Producer.Partitioner must not be nil")
+	case c.Consumer.Offsets.Initial != OffsetNewest && c.Consumer.Offsets.Initial != OffsetOldest:
+		return ConfigurationError("Consumer.Offsets.Initial must be OffsetOldest or OffsetNewest")
+	case c.Consumer.Offsets.AutoCommit.Interval < 0:
+		return ConfigurationError("Consumer.Offsets.AutoCommit.Interval must be >= 0")
+	case len(c.Consumer.Group.Rebalance.GroupStrategies) == 0:
+		return ConfigurationError("Consumer.Group.Rebalance.GroupStrategies must not be empty")
+	}
+	return nil
+}
+
+// Encoder is a message key or value
+type Encoder interface {
+	Encode() ([]byte, error)
+	Length() int
+}
+
+// StringEncoder encodes a string
+type StringEncoder string
+
+func (s StringEncoder) Encode() ([]byte, error) { return []byte(s), nil }
+func (s StringEncoder) Length() int             { return len(s) }
+
+// ByteEncoder encodes a byte slice
+type ByteEncoder []byte
+
+func (b ByteEncoder) Encode() ([]byte, error) { return b, nil }
+func (b ByteEncoder) Length() int             { return len(b) }
+
+// RecordHeader is a message header
+type RecordHeader struct {
+	Key   []byte
+	Value []byte
+}
+
+// ProducerMessage is a message to produce. Partition, Offset and
+// Timestamp are filled in once it has been written.
+type ProducerMessage struct {
+	Topic     string
+	Key       Encoder
+	Value     Encoder
+	Headers   []RecordHeader
+	Metadata  interface{} // passed back untouched on Successes and Errors
+	Offset    int64
+	Partition int32
+	Timestamp time.Time
+}
+
+// ConsumerMessage is a message read from a partition
+type ConsumerMessage struct {
+	Headers   []*RecordHeader
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Topic     string
+	Partition int32
+	Offset    int64
+}
+
+func (m *ConsumerMessage) copy() *ConsumerMessage {
+	c := *m
+	c.Key = cloneBytes(m.Key)
+	c.Value = cloneBytes(m.Value)
+	c.Headers = make([]*RecordHeader, len(m.Headers))
+	for i, h := range m.Headers {
+		c.Headers[i] = &RecordHeader{Key: cloneBytes(h.Key), Value: cloneBytes(h.Value)}
+	}
+	return &c
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+// ProducerError is a message that failed to be produced
+type ProducerError struct {
+	Msg *ProducerMessage
+	Err error
+}
+
+func (pe ProducerError) Error() string {
+	return fmt.Sprintf("kafka: Failed to produce message to topic %s: %s", pe.Msg.Topic, pe.Err)
+}
+
+func (pe ProducerError) Unwrap() error {
+	return pe.Err
+}
+
+// ProducerErrors is returned by SendMessages and by an async producer's
+// Close
+type ProducerErrors []*ProducerError
+
+func (pe ProducerErrors) Error() string {
+	return fmt.Sprintf("kafka: Failed to deliver %d messages.", len(pe))
+}
+
+// ConsumerError is an error met while consuming a partition
+type ConsumerError struct {
+	Topic     string
+	Partition int32
+	Err       error
+}
+
+func (ce ConsumerError) Error() string {
+	return fmt.Sprintf("kafka: error while consuming %s/%d: %s", ce.Topic, ce.Partition, ce.Err)
+}
+
+func (ce ConsumerError) Unwrap() error {
+	return ce.Err
+}
+
+// ConsumerErrors is returned by a partition consumer's Close
+type ConsumerErrors []*ConsumerError
+
+func (ce ConsumerErrors) Error() string {
+	return fmt.Sprintf("kafka: %d errors while consuming", len(ce))
+}
+
+// Partitioners
+
+// Partitioner picks the partition for a message
+type Partitioner interface {
+	Partition(message *ProducerMessage, numPartitions int32) (int32, error)
+	// RequiresConsistency reports whether a key always maps to the same
+	// partition
+	RequiresConsistency() bool
+}
+
+// PartitionerConstructor creates the partitioner for a topic
+type PartitionerConstructor func(topic string) Partitioner
+
+type manualPartitioner struct{}
+
+// NewManualPartitioner uses the message's Partition field
+func NewManualPartitioner(topic string) Partitioner {
+	return manualPartitioner{}
+}
+
+func (manualPartitioner) Partition(message *ProducerMessage, numPartitions int32) (int32, error) {
+	return message.Partition, nil
+}
+
+func (manualPartitioner) RequiresConsistency() bool { return true }
+
+type randomPartitioner struct {
+	mu      sync.Mutex
+	present *rand.Rand
+}
+
+// NewRandomPartitioner picks a random partition for each message
+func NewRandomPartitioner(topic string) Partitioner {
+	return &randomPartitioner{present: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (p *randomPartitioner) Partition(message *ProducerMessage, numPartitions int32) (int32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int32(p.present.Intn(int(numPartitions))), nil
+}
+
+func (p *randomPartitioner) RequiresConsistency() bool { return false }
+
+type roundRobinPartitioner struct {
+	mu        sync.Mutex
+	partition int32
+}
+
+// NewRoundRobinPartitioner cycles through the partitions in order
+func NewRoundRobinPartitioner(topic string) Partitioner {
+	return &roundRobinPartitioner{}
+}
+
+func (p *roundRobinPartitioner) Partition(message *ProducerMessage, numPartitions int32) (int32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.partition >= numPartitions {
+		p.partition = 0
+	}
+	partition := p.partition
+	p.partition++
+	return partition, nil
+}
+
+func (p *roundRobinPartitioner) RequiresConsistency() bool { return false }
+
+type hashPartitioner struct {
+	random Partitioner
+}
+
+// NewHashPartitioner sends messages with the same key to the same
+// partition, using FNV-1a like Sarama; messages without a key go to a
+// random partition
+func NewHashPartitioner(topic string) Partitioner {
+	return &hashPartitioner{random: NewRandomPartitioner(topic)}
+}
+
+func (p *hashPartitioner) Partition(message *ProducerMessage, numPartitions int32) (int32, error) {
+	if message.Key == nil {
+		return p.random.Partition(message, numPartitions)
+	}
+	key, err := message.Key.Encode()
+	if err != nil {
+		return -1, err
+	}
+	hasher := fnv.New32a()
+	hasher.Write(key)
+	partition := int32(hasher.Sum32()) % numPartitions
+	if partition < 0 {
+		partition = -partition
+	}
+	return partition, nil
+}
+
+func (p *hashPartitioner) RequiresConsistency() bool { return true }
+
+// Cluster
+
+var (
+	clustersMu sync.Mutex
+	clusters   = make(map[string]*Cluster)
+)
+
+// Cluster is an in-memory Kafka cluster. Clients connect to it through
+// any of its addresses, so code under test keeps its usual
+// NewSyncProducer(addrs, config) calls.
+type Cluster struct {
+	// AutoCreateTopics creates unknown topics on first produce, with
+	// DefaultPartitions partitions
+	AutoCreateTopics  bool
+	DefaultPartitions int32
+
+	addrs  []string
+	mu     sync.Mutex
+	topics map[string]*topicLog
+	groups map[string]*group
+}
+
+type topicLog struct {
+	partitions []*partitionLog
+}
+
+type partitionLog struct {
+	records []*ConsumerMessage
+	notify  chan struct{} // closed when a record is appended
+}
+
+func newPartitionLog() *partitionLog {
+	return &partitionLog{notify: make(chan struct{})}
+}
+
+// NewCluster starts an in-memory cluster reachable at addrs, replacing
+// any cluster already registered at them. Without addrs it listens on
+// "localhost:9092".
+func NewCluster(addrs ...string) *Cluster {
+	if len(addrs) == 0 {
+		addrs = []string{"localhost:9092"}
+	}
+	c := &Cluster{
+		AutoCreateTopics:  true,
+		DefaultPartitions: 1,
+		addrs:             addrs,
+		topics:            make(map[string]*topicLog),
+		groups:            make(map[string]*group),
+	}
+	clustersMu.Lock()
+	defer clustersMu.Unlock()
+	for _, addr := range addrs {
+		clusters[addr] = c
+	}
+	return c
+}
+
+// lookupCluster finds the cluster at the first address that has one
+func lookupCluster(addrs []string) (*Cluster, error) {
+	clustersMu.Lock()
+	defer clustersMu.Unlock()
+	for _, addr := range addrs {
+		if c, ok := clusters[addr]; ok {
+			return c, nil
+		}
+	}
+	return nil, ErrOutOfBrokers
+}
+
+// Addrs returns the cluster's addresses
+func (c *Cluster) Addrs() []string {
+	return append([]string(nil), c.addrs...)
+}
+
+// Close makes the cluster unreachable to new clients
+func (c *Cluster) Close() {
+	clustersMu.Lock()
+	defer clustersMu.Unlock()
+	for _, addr := range c.addrs {
+		if clusters[addr] == c {
+			delete(clusters, addr)
+		}
+	}
+}
+
+func validTopicName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > 249 {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateTopic creates a topic with the given number of partitions
+func (c *Cluster) CreateTopic(name string, partitions int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.createTopic(name, partitions)
+}
+
+func (c *Cluster) createTopic(name string, partitions int32) error {
+	if !validTopicName(name) {
+		return ErrInvalidTopic
+	}
+	if partitions <= 0 {
+		return ErrInvalidPartitions
+	}
+	if _, ok := c.topics[name]; ok {
+		return ErrTopicAlreadyExists
+	}
+	t := &topicLog{partitions: make([]*partitionLog, partitions)}
+	for i := range t.partitions {
+		t.partitions[i] = newPartitionLog()
+	}
+	c.topics[name] = t
+	c.topicChanged(name)
+	return nil
+}
+
+// DeleteTopic deletes a topic and its messages
+func (c *Cluster) DeleteTopic(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.topics[name]
+	if !ok {
+		return ErrUnknownTopicOrPartition
+	}
+	delete(c.topics, name)
+	// Wake readers so they notice the topic is gone
+	for _, p := range t.partitions {
+		close(p.notify)
+		p.notify = make(chan struct{})
+	}
+	c.topicChanged(name)
+	return nil
+}
+
+// CreatePartitions grows a topic to count partitions
+func (c *Cluster) CreatePartitions(name string, count int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.topics[name]
+	if !ok {
+		return ErrUnknownTopicOrPartition
+	}
+	if int(count) <= len(t.partitions) {
+		return ErrInvalidPartitions
+	}
+	for len(t.partitions) < int(count) {
+		t.partitions = append(t.partitions, newPartitionLog())
+	}
+	c.topicChanged(name)
+	return nil
+}
+
+// topicChanged rebalances the groups subscribed to a topic
+func (c *Cluster) topicChanged(name string) {
+	for _, g := range c.groups {
+		if g.subscribes(name) {
+			g.rebalance()
+		}
+	}
+}
+
+// Topics returns the topic names in order
+func (c *Cluster) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, 0, len(c.topics))
+	for name := range c.topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Partitions returns a topic's partition IDs
+func (c *Cluster) Partitions(topic string) ([]int32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.topics[topic]
+	if !ok {
+		return nil, ErrUnknownTopicOrPartition
+	}
+	return partitionIDs(len(t.partitions)), nil
+}
+
+func partitionIDs(n int) []int32 {
+	ids := make([]int32, n)
+	for i := range ids {
+		ids[i] = int32(i)
+	}
+	return ids
+}
+
+func (c *Cluster) partition(topic string, partition int32) (*partitionLog, error) {
+	t, ok := c.topics[topic]
+	if !ok || partition < 0 || int(partition) >= len(t.partitions) {
+		return nil, ErrUnknownTopicOrPartition
+	}
+	return t.partitions[partition], nil
+}
+
+// HighWaterMark returns the offset the next message in a partition gets
+func (c *Cluster) HighWaterMark(topic string, partition int32) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, err := c.partition(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(p.records)), nil
+}
+
+// Messages returns copies of the messages in a partition, for assertions
+func (c *Cluster) Messages(topic string, partition int32) []*ConsumerMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, err := c.partition(topic, partition)
+	if err != nil {
+		return nil
+	}
+	messages := make([]*ConsumerMessage, len(p.records))
+	for i, m := range p.records {
+		messages[i] = m.copy()
+	}
+	return messages
+}
+
+// CommittedOffset returns a group's committed offset for a partition, or
+// -1 if it has none
+func (c *Cluster) CommittedOffset(groupID, topic string, partition int32) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g, ok := c.groups[groupID]; ok {
+		if entry, ok := g.offsets[topic][partition]; ok {
+			return entry.offset
+		}
+	}
+	return -1
+}
+
+// Lag returns how many messages in a topic a group hasn't committed yet
+func (c *Cluster) Lag(groupID, topic string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.topics[topic]
+	if !ok {
+		return 0
+	}
+	var lag int64
+	for i, p := range t.partitions {
+		committed := int64(0)
+		if g, ok := c.groups[groupID]; ok {
+			if entry, ok := g.offsets[topic][int32(i)]; ok {
+				committed = entry.offset
+			}
+		}
+		if hwm := int64(len(p.records)); hwm > committed {
+			lag += hwm - committed
+		}
+	}
+	return lag
+}
+
+// append writes a message to a partition and returns its offset
+func (c *Cluster) append(topic string, partition int32, msg *ConsumerMessage) (int64, error) {
+	p, err := c.partition(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	msg.Offset = int64(len(p.records))
+	p.records = append(p.records, msg)
+	close(p.notify)
+	p.notify = make(chan struct{})
+	return msg.Offset, nil
+}
+
+// fetch returns the message at offset, or a channel closed when the
+// partition changes if there is none yet
+func (c *Cluster) fetch(topic string, partition int32, offset int64) (*ConsumerMessage, <-chan struct{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, err := c.partition(topic, partition)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset < int64(len(p.records)) {
+		return p.records[offset].copy(), nil, nil
+	}
+	return nil, p.notify, nil
+}
+
+// resolveOffset turns OffsetNewest and OffsetOldest into a real offset
+// and checks it is in range
+func (c *Cluster) resolveOffset(topic string, partition int32, offset int64) (int64, error) {
+	p, err := c.partition(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	hwm := int64(len(p.records))
+	switch offset {
+	case OffsetNewest:
+		return hwm, nil
+	case OffsetOldest:
+		return 0, nil
+	}
+	if offset < 0 || offset > hwm {
+		return 0, ErrOffsetOutOfRange
+	}
+	return offset, nil
+}
+
+// Client
+
+// Client holds a connection to a cluster. Producers, consumers and admins
+// can share one client.
+type Client interface {
+	Config() *Config
+	Topics() ([]string, error)
+	Partitions(topic string) ([]int32, error)
+	// GetOffset returns the offset of the first message at or after a
+	// time in milliseconds, or the OffsetNewest/OffsetOldest offsets
+	GetOffset(topic string, partitionID int32, time int64) (int64, error)
+	RefreshMetadata(topics ...string) error
+	Close() error
+	Closed() bool
+}
+
+type client struct {
+	conf    *Config
+	cluster *Cluster
+	closed  int32
+}
+
+// NewClient connects to the cluster at one of addrs
+func NewClient(addrs []string, conf *Config) (Client, error) {
+	return newClient(addrs, conf)
+}
+
+func newClient(addrs []string, conf *Config) (*client, error) {
+	if conf == nil {
+		conf = NewConfig()
+	}
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	cluster, err := lookupCluster(addrs)
+	if err != nil {
+		return nil, err
+	}
+	return &client{conf: conf, cluster: cluster}, nil
+}
+
+// asClient unwraps a Client created by NewClient
+func asClient(c Client) (*client, error) {
+	cl, ok := c.(*client)
+	if !ok {
+		return nil, ConfigurationError("client was not created by NewClient")
+	}
+	if cl.Closed() {
+		return nil, ErrClosedClient
+	}
+	return cl, nil
+}
+
+func (c *client) Config() *Config {
+	return c.conf
+}
+
+func (c *client) Topics() ([]string, error) {
+	if c.Closed() {
+		return nil, ErrClosedClient
+	}
+	return c.cluster.Topics(), nil
+}
+
+func (c *client) Partitions(topic string) ([]int32, error) {
+	if c.Closed() {
+		return nil, ErrClosedClient
+	}
+	return c.cluster.Partitions(topic)
+}
+
+func (c *client) GetOffset(topic string, partitionID int32, t int64) (int64, error) {
+	if c.Closed() {
+		return 0, ErrClosedClient
+	}
+	c.cluster.mu.Lock()
+	defer c.cluster.mu.Unlock()
+	p, err := c.cluster.partition(topic, partitionID)
+	if err != nil {
+		return 0, err
+	}
+	switch t {
+	case OffsetNewest:
+		return int64(len(p.records)), nil
+	case OffsetOldest:
+		return 0, nil
+	}
+	at := time.Unix(0, t*int64(time.Millisecond))
+	for _, m := range p.records {
+		if !m.Timestamp.Before(at) {
+			return m.Offset, nil
+		}
+	}
+	return int64(len(p.records)), nil
+}
+
+func (c *client) RefreshMetadata(topics ...string) error {
+	if c.Closed() {
+		return ErrClosedClient
+	}
+	for _, topic := range topics {
+		if _, err := c.cluster.Partitions(topic); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return ErrClosedClient
+	}
+	return nil
+}
+
+func (c *client) Closed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
+
+// Producers
+
+// producer writes messages to the cluster for both producer types
+type producer struct {
+	client    *client
+	ownClient bool
+	mu        sync.Mutex
+	byTopic   map[string]Partitioner
+}
+
+func newProducer(c *client, ownClient bool) *producer {
+	return &producer{client: c, ownClient: ownClient, byTopic: make(map[string]Partitioner)}
+}
+
+func (p *producer) partitioner(topic string) Partitioner {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	partitioner, ok := p.byTopic[topic]
+	if !ok {
+		partitioner = p.client.conf.Producer.Partitioner(topic)
+		p.byTopic[topic] = partitioner
+	}
+	return partitioner
+}
+
+func encode(e Encoder) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return e.Encode()
+}
+
+// produce writes msg and fills in its partition, offset and timestamp
+func (p *producer) produce(msg *ProducerMessage) error {
+	if p.client.Closed() {
+		return ErrClosedClient
+	}
+	key, err := encode(msg.Key)
+	if err != nil {
+		return err
+	}
+	value, err := encode(msg.Value)
+	if err != nil {
+		return err
+	}
+	size := len(key) + len(value)
+	for _, h := range msg.Headers {
+		size += len(h.Key) + len(h.Value)
+	}
+	if size > p.client.conf.Producer.MaxMessageBytes {
+		return ErrMessageSizeTooLarge
+	}
+
+	cluster := p.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	t, ok := cluster.topics[msg.Topic]
+	if !ok {
+		if !cluster.AutoCreateTopics {
+			return ErrUnknownTopicOrPartition
+		}
+		if err := cluster.createTopic(msg.Topic, cluster.DefaultPartitions); err != nil {
+			return err
+		}
+		t = cluster.topics[msg.Topic]
+	}
+
+	partition, err := p.partitioner(msg.Topic).Partition(msg, int32(len(t.partitions)))
+	if err != nil {
+		return err
+	}
+	if partition < 0 || int(partition) >= len(t.partitions) {
+		return ErrInvalidPartition
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	record := &ConsumerMessage{
+		Timestamp: msg.Timestamp,
+		Key:       cloneBytes(key),
+		Value:     cloneBytes(value),
+		Topic:     msg.Topic,
+		Partition: partition,
+	}
+	for _, h := range msg.Headers {
+		record.Headers = append(record.Headers, &RecordHeader{Key: cloneBytes(h.Key), Value: cloneBytes(h.Value)})
+	}
+	offset, err := cluster.append(msg.Topic, partition, record)
+	if err != nil {
+		return err
+	}
+	msg.Partition, msg.Offset = partition, offset
+	return nil
+}
+
+func (p *producer) close() error {
+	if p.ownClient {
+		return p.client.Close()
+	}
+	return nil
+}
+
+// SyncProducer produces messages and waits until they are written
+type SyncProducer interface {
+	SendMessage(msg *ProducerMessage) (partition int32, offset int64, err error)
+	// SendMessages produces every message and returns ProducerErrors for
+	// the ones that failed
+	SendMessages(msgs []*ProducerMessage) error
+	Close() error
+}
+
+type syncProducer struct {
+	*producer
+	closed int32
+}
+
+// NewSyncProducer creates a SyncProducer with its own client
+func NewSyncProducer(addrs []string, conf *Config) (SyncProducer, error) {
+	if err := verifySyncProducerConfig(conf); err != nil {
+		return nil, err
+	}
+	c, err := newClient(addrs, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &syncProducer{producer: newProducer(c, true)}, nil
+}
+
+// NewSyncProducerFromClient creates a SyncProducer on an existing client,
+// which the producer's Close leaves open
+func NewSyncProducerFromClient(c Client) (SyncProducer, error) {
+	cl, err := asClient(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySyncProducerConfig(cl.conf); err != nil {
+		return nil, err
+	}
+	return &syncProducer{producer: newProducer(cl, false)}, nil
+}
+
+func verifySyncProducerConfig(conf *Config) error {
+	if conf == nil {
+		return nil
+	}
+	if !conf.Producer.Return.Errors {
+		return ConfigurationError("Producer.Return.Errors must be true to be used in a SyncProducer")
+	}
+	if !conf.Producer.Return.Successes {
+		return ConfigurationError("Producer.Return.Successes must be true to be used in a SyncProducer")
+	}
+	return nil
+}
+
+func (sp *syncProducer) SendMessage(msg *ProducerMessage) (int32, int64, error) {
+	if atomic.LoadInt32(&sp.closed) == 1 {
+		return -1, -1, ErrShuttingDown
+	}
+	if err := sp.produce(msg); err != nil {
+		return -1, -1, err
+	}
+	return msg.Partition, msg.Offset, nil
+}
+
+func (sp *syncProducer) SendMessages(msgs []*ProducerMessage) error {
+	var errs ProducerErrors
+	for _, msg := range msgs {
+		if _, _, err := sp.SendMessage(msg); err != nil {
+			errs = append(errs, &ProducerError{Msg: msg, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (sp *syncProducer) Close() error {
+	if !atomic.CompareAndSwapInt32(&sp.closed, 0, 1) {
+		return nil
+	}
+	return sp.close()
+}
+
+// AsyncProducer produces messages sent on Input in the background and
+// reports them on Successes and Errors. Both channels must be read, or
+// the producer blocks.
+type AsyncProducer interface {
+	AsyncClose()
+	// Close flushes pending messages and returns ProducerErrors for any
+	// errors not yet read from Errors
+	Close() error
+	Input() chan<- *ProducerMessage
+	Successes() <-chan *ProducerMessage
+	Errors() <-chan *ProducerError
+}
+
+type asyncProducer struct {
+	*producer
+	input     chan *ProducerMessage
+	successes chan *ProducerMessage
+	errors    chan *ProducerError
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewAsyncProducer creates an AsyncProducer with its own client
+func NewAsyncProducer(addrs []string, conf *Config) (AsyncProducer, error) {
+	c, err := newClient(addrs, conf)
+	if err != nil {
+		return nil, err
+	}
+	return newAsyncProducer(c, true), nil
+}
+
+// NewAsyncProducerFromClient creates an AsyncProducer on an existing
+// client, which the producer's Close leaves open
+func NewAsyncProducerFromClient(c Client) (AsyncProducer, error) {
+	cl, err := asClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return newAsyncProducer(cl, false), nil
+}
+
+func newAsyncProducer(c *client, ownClient bool) *asyncProducer {
+	size := c.conf.ChannelBufferSize
+	ap := &asyncProducer{
+		producer:  newProducer(c, ownClient),
+		input:     make(chan *ProducerMessage, size),
+		successes: make(chan *ProducerMessage, size),
+		errors:    make(chan *ProducerError, size),
+		done:      make(chan struct{}),
+	}
+	go ap.dispatch()
+	return ap
+}
+
+func (ap *asyncProducer) dispatch() {
+	defer close(ap.done)
+	defer close(ap.errors)
+	defer close(ap.successes)
+	ret := ap.client.conf.Producer.Return
+	for msg := range ap.input {
+		if err := ap.produce(msg); err != nil {
+			if ret.Errors {
+				ap.errors <- &ProducerError{Msg: msg, Err: err}
+			}
+			continue
+		}
+		if ret.Successes {
+			ap.successes <- msg
+		}
+	}
+}
+
+func (ap *asyncProducer) Input() chan<- *ProducerMessage     { return ap.input }
+func (ap *asyncProducer) Successes() <-chan *ProducerMessage { return ap.successes }
+func (ap *asyncProducer) Errors() <-chan *ProducerError      { return ap.errors }
+
+func (ap *asyncProducer) AsyncClose() {
+	ap.closeOnce.Do(func() {
+		close(ap.input)
+	})
+}
+
+func (ap *asyncProducer) Close() error {
+	ap.AsyncClose()
+	go func() {
+		for range ap.successes {
+		}
+	}()
+	var errs ProducerErrors
+	for pe := range ap.errors {
+		errs = append(errs, pe)
+	}
+	<-ap.done
+	if err := ap.close(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Consumer
+
+// Consumer reads partitions directly, without a consumer group
+type Consumer interface {
+	Topics() ([]string, error)
+	Partitions(topic string) ([]int32, error)
+	// ConsumePartition starts reading a partition at offset, which may be
+	// OffsetNewest or OffsetOldest
+	ConsumePartition(topic string, partition int32, offset int64) (PartitionConsumer, error)
+	HighWaterMarks() map[string]map[int32]int64
+	Close() error
+}
+
+// PartitionConsumer delivers one partition's messages in order
+type PartitionConsumer interface {
+	AsyncClose()
+	Close() error
+	Messages() <-chan *ConsumerMessage
+	Errors() <-chan *ConsumerError
+	HighWaterMarkOffset() int64
+}
+
+type consumer struct {
+	client    *client
+	ownClient bool
+	mu        sync.Mutex
+	children  map[string]map[int32]*partitionConsumer
+}
+
+// NewConsumer creates a Consumer with its own client
+func NewConsumer(addrs []string, conf *Config) (Consumer, error) {
+	c, err := newClient(addrs, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &consumer{client: c, ownClient: true, children: make(map[string]map[int32]*partitionConsumer)}, nil
+}
+
+// NewConsumerFromClient creates a Consumer on an existing client, which
+// the consumer's Close leaves open
+func NewConsumerFromClient(c Client) (Consumer, error) {
+	cl, err := asClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return &consumer{client: cl, children: make(map[string]map[int32]*partitionConsumer)}, nil
+}
+
+func (c *consumer) Topics() ([]string, error) {
+	return c.client.Topics()
+}
+
+func (c *consumer) Partitions(topic string) ([]int32, error) {
+	return c.client.Partitions(topic)
+}
+
+func (c *consumer) ConsumePartition(topic string, partition int32, offset int64) (PartitionConsumer, error) {
+	if c.client.Closed() {
+		return nil, ErrClosedClient
+	}
+	c.client.cluster.mu.Lock()
+	start, err := c.client.cluster.resolveOffset(topic, partition, offset)
+	c.client.cluster.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.children[topic][partition] != nil {
+		return nil, ConfigurationError("That topic/partition is already being consumed")
+	}
+	size := c.client.conf.ChannelBufferSize
+	pc := &partitionConsumer{
+		parent:    c,
+		topic:     topic,
+		partition: partition,
+		offset:    start,
+		messages:  make(chan *ConsumerMessage, size),
+		errors:    make(chan *ConsumerError, size),
+		dying:     make(chan struct{}),
+	}
+	if c.children[topic] == nil {
+		c.children[topic] = make(map[int32]*partitionConsumer)
+	}
+	c.children[topic][partition] = pc
+	go pc.run()
+	return pc, nil
+}
+
+func (c *consumer) HighWaterMarks() map[string]map[int32]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	marks := make(map[string]map[int32]int64)
+	for topic, children := range c.children {
+		marks[topic] = make(map[int32]int64)
+		for partition, pc := range children {
+			marks[topic][partition] = pc.HighWaterMarkOffset()
+		}
+	}
+	return marks
+}
+
+func (c *consumer) Close() error {
+	c.mu.Lock()
+	var children []*partitionConsumer
+	for _, byPartition := range c.children {
+		for _, pc := range byPartition {
+			children = append(children, pc)
+		}
+	}
+	c.mu.Unlock()
+	for _, pc := range children {
+		pc.Close()
+	}
+	if c.ownClient {
+		return c.client.Close()
+	}
+	return nil
+}
+
+type partitionConsumer struct {
+	parent    *consumer
+	topic     string
+	partition int32
+	offset    int64
+	messages  chan *ConsumerMessage
+	errors    chan *ConsumerError
+	dying     chan struct{}
+	closeOnce sync.Once
+}
+
+func (pc *partitionConsumer) run() {
+	defer close(pc.errors)
+	defer close(pc.messages)
+	cluster := pc.parent.client.cluster
+	for {
+		msg, wait, err := cluster.fetch(pc.topic, pc.partition, pc.offset)
+		if err != nil {
+			if pc.parent.client.conf.Consumer.Return.Errors {
+				select {
+				case pc.errors <- &ConsumerError{Topic: pc.topic, Partition: pc.partition, Err: err}:
+				case <-pc.dying:
+				}
+			}
+			return
+		}
+		if msg != nil {
+			select {
+			case pc.messages <- msg:
+				pc.offset++
+			case <-pc.dying:
+				return
+			}
+			continue
+		}
+		select {
+		case <-wait:
+		case <-pc.dying:
+			return
+		}
+	}
+}
+
+func (pc *partitionConsumer) Messages() <-chan *ConsumerMessage { return pc.messages }
+func (pc *partitionConsumer) Errors() <-chan *ConsumerError     { return pc.errors }
+
+func (pc *partitionConsumer) HighWaterMarkOffset() int64 {
+	hwm, _ := pc.parent.client.cluster.HighWaterMark(pc.topic, pc.partition)
+	return hwm
+}
+
+func (pc *partitionConsumer) AsyncClose() {
+	pc.closeOnce.Do(func() {
+		close(pc.dying)
+		pc.parent.mu.Lock()
+		delete(pc.parent.children[pc.topic], pc.partition)
+		pc.parent.mu.Unlock()
+	})
+}
+
+func (pc *partitionConsumer) Close() error {
+	pc.AsyncClose()
+	for range pc.messages {
+	}
+	var errs ConsumerErrors
+	for err := range pc.errors {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Consumer groups
+
+// ConsumerGroupMemberMetadata is what a member tells the group when it
+// joins
+type ConsumerGroupMemberMetadata struct {
+	Version  int16
+	Topics   []string
+	UserData []byte
+}
+
+// BalanceStrategyPlan assigns partitions by member ID and topic
+type BalanceStrategyPlan map[string]map[string][]int32
+
+// Add assigns partitions of a topic to a member
+func (p BalanceStrategyPlan) Add(memberID, topic string, partitions ...int32) {
+	if len(partitions) == 0 {
+		return
+	}
+	if p[memberID] == nil {
+		p[memberID] = make(map[string][]int32)
+	}
+	p[memberID][topic] = append(p[memberID][topic], partitions...)
+}
+
+// BalanceStrategy assigns a group's partitions to its members
+type BalanceStrategy interface {
+	Name() string
+	Plan(members map[string]ConsumerGroupMemberMetadata, topics map[string][]int32) (BalanceStrategyPlan, error)
+}
+
+// BalanceStrategyRange gives each member a contiguous range of every
+// topic's partitions:
+//
+//	M1: {T: [0, 1, 2]}, M2: {T: [3, 4, 5]}
+var BalanceStrategyRange BalanceStrategy = &rangeStrategy{}
+
+// BalanceStrategyRoundRobin deals out all partitions of all topics in
+// turn:
+//
+//	M1: {T1: [0, 2], T2: [1]}, M2: {T1: [1], T2: [0, 2]}
+var BalanceStrategyRoundRobin BalanceStrategy = &roundRobinStrategy{}
+
+type rangeStrategy struct{}
+
+func (*rangeStrategy) Name() string { return "range" }
+
+func (*rangeStrategy) Plan(members map[string]ConsumerGroupMemberMetadata, topics map[string][]int32) (BalanceStrategyPlan, error) {
+	plan := make(BalanceStrategyPlan)
+	for topic, partitions := range topics {
+		subscribers := subscribersOf(members, topic)
+		if len(subscribers) == 0 {
+			continue
+		}
+		sorted := sortedPartitions(partitions)
+		n, extra := len(sorted)/len(subscribers), len(sorted)%len(subscribers)
+		start := 0
+		for i, memberID := range subscribers {
+			count := n
+			if i < extra {
+				count++
+			}
+			plan.Add(memberID, topic, sorted[start:start+count]...)
+			start += count
+		}
+	}
+	return plan, nil
+}
+
+type roundRobinStrategy struct{}
+
+func (*roundRobinStrategy) Name() string { return "roundrobin" }
+
+func (*roundRobinStrategy) Plan(members map[string]ConsumerGroupMemberMetadata, topics map[string][]int32) (BalanceStrategyPlan, error) {
+	plan := make(BalanceStrategyPlan)
+	memberIDs := make([]string, 0, len(members))
+	for memberID := range members {
+		memberIDs = append(memberIDs, memberID)
+	}
+	sort.Strings(memberIDs)
+	names := make([]string, 0, len(topics))
+	for topic := range topics {
+		names = append(names, topic)
+	}
+	sort.Strings(names)
+
+	next := 0
+	for _, topic := range names {
+		for _, partition := range sortedPartitions(topics[topic]) {
+			// The next member in turn that subscribes to the topic
+			for tries := 0; tries < len(memberIDs); tries++ {
+				memberID := memberIDs[next%len(memberIDs)]
+				next++
+				if containsString(members[memberID].Topics, topic) {
+					plan.Add(memberID, topic, partition)
+					break
+				}
+			}
+		}
+	}
+	return plan, nil
+}
+
+func subscribersOf(members map[string]ConsumerGroupMemberMetadata, topic string) []string {
+	var ids []string
+	for memberID, meta := range members {
+		if containsString(meta.Topics, topic) {
+			ids = append(ids, memberID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedPartitions(partitions []int32) []int32 {
+	sorted := append([]int32(nil), partitions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// group is the coordinator's state for a consumer group
+type group struct {
+	id         string
+	generation int32
+	members    map[string]*groupMember
+	order      []string // member IDs in join order; the first leads
+	plan       BalanceStrategyPlan
+	offsets    map[string]map[int32]offsetEntry
+	leaving    map[*consumerGroupSession]bool // sessions of departed members, until released
+	changed    chan struct{}                  // closed on any membership or session change
+}
+
+type groupMember struct {
+	topics   []string
+	strategy BalanceStrategy
+	session  *consumerGroupSession
+}
+
+type offsetEntry struct {
+	offset   int64
+	metadata string
+}
+
+func (c *Cluster) group(id string) *group {
+	g, ok := c.groups[id]
+	if !ok {
+		g = &group{
+			id:      id,
+			members: make(map[string]*groupMember),
+			offsets: make(map[string]map[int32]offsetEntry),
+			leaving: make(map[*consumerGroupSession]bool),
+			changed: make(chan struct{}),
+		}
+		c.groups[id] = g
+	}
+	return g
+}
+
+func (g *group) notify() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+func (g *group) subscribes(topic string) bool {
+	for _, m := range g.members {
+		if containsString(m.topics, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebalance starts a new generation. Every running session is cancelled,
+// and members get new claims when they call Consume again.
+func (g *group) rebalance() {
+	g.generation++
+	g.plan = nil
+	for _, m := range g.members {
+		if m.session != nil {
+			m.session.cancel()
+		}
+	}
+	g.notify()
+}
+
+// settling reports whether a session from an older generation is still
+// running, including those of members that have left. New sessions wait
+// for those to end and commit, so no partition is claimed twice.
+func (g *group) settling() bool {
+	if len(g.leaving) > 0 {
+		return true
+	}
+	for _, m := range g.members {
+		if m.session != nil && m.session.generation != g.generation {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *group) leave(memberID string) {
+	m, ok := g.members[memberID]
+	if !ok {
+		return
+	}
+	if m.session != nil {
+		m.session.cancel()
+		g.leaving[m.session] = true
+	}
+	delete(g.members, memberID)
+	for i, id := range g.order {
+		if id == memberID {
+			g.order = append(g.order[:i], g.order[i+1:]...)
+			break
+		}
+	}
+	g.rebalance()
+}
+
+// makePlan assigns the partitions with the leader's strategy
+func (g *group) makePlan(c *Cluster) error {
+	members := make(map[string]ConsumerGroupMemberMetadata, len(g.members))
+	topics := make(map[string][]int32)
+	for id, m := range g.members {
+		members[id] = ConsumerGroupMemberMetadata{Topics: append([]string(nil), m.topics...)}
+		for _, topic := range m.topics {
+			if t, ok := c.topics[topic]; ok {
+				topics[topic] = partitionIDs(len(t.partitions))
+			}
+		}
+	}
+	plan, err := g.members[g.order[0]].strategy.Plan(members, topics)
+	if err != nil {
+		return err
+	}
+	g.plan = plan
+	return nil
+}
+
+// ConsumerGroupHandler processes the claims of a session:
+//
+//   - Setup runs when a session starts, before ConsumeClaim
+//   - ConsumeClaim runs in its own goroutine for each claimed partition,
+//     and must return when the claim's Messages channel is closed
+//   - Cleanup runs after every ConsumeClaim has returned
+type ConsumerGroupHandler interface {
+	Setup(ConsumerGroupSession) error
+	Cleanup(ConsumerGroupSession) error
+	ConsumeClaim(ConsumerGroupSession, ConsumerGroupClaim) error
+}
+
+// ConsumerGroupSession is one generation of a member's claims
+type ConsumerGroupSession interface {
+	Claims() map[string][]int32
+	MemberID() string
+	GenerationID() int32
+	// MarkOffset marks offset as the next one to consume; marks only
+	// move forward
+	MarkOffset(topic string, partition int32, offset int64, metadata string)
+	// Commit commits the marked offsets now
+	Commit()
+	// ResetOffset moves a mark back
+	ResetOffset(topic string, partition int32, offset int64, metadata string)
+	// MarkMessage marks a message as consumed
+	MarkMessage(msg *ConsumerMessage, metadata string)
+	Context() context.Context
+}
+
+// ConsumerGroupClaim is a partition claimed by a session
+type ConsumerGroupClaim interface {
+	Topic() string
+	Partition() int32
+	InitialOffset() int64
+	HighWaterMarkOffset() int64
+	Messages() <-chan *ConsumerMessage
+}
+
+// ConsumerGroup consumes topics as a member of a group. Partitions are
+// shared among the members and rebalanced when members join or leave.
+type ConsumerGroup interface {
+	// Consume joins the group and runs a session with the member's
+	// claims. It returns when the session ends: when ctx is done, on a
+	// rebalance or when every ConsumeClaim has returned. Call it in a
+	// loop to keep consuming across rebalances.
+	Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error
+	Errors() <-chan error
+	Close() error
+}
+
+var memberSeq int64
+
+type consumerGroup struct {
+	client    *client
+	ownClient bool
+	groupID   string
+	memberID  string
+	errors    chan error
+	closed    chan struct{}
+	closeOnce sync.Once
+	running   sync.WaitGroup
+	mu        sync.Mutex
+	isClosed  bool
+}
+
+// NewConsumerGroup creates a ConsumerGroup with its own client
+func NewConsumerGroup(addrs []string, groupID string, conf *Config) (ConsumerGroup, error) {
+	c, err := newClient(addrs, conf)
+	if err != nil {
+		return nil, err
+	}
+	return newConsumerGroup(c, true, groupID), nil
+}
+
+// NewConsumerGroupFromClient creates a ConsumerGroup on an existing
+// client, which the group's Close leaves open
+func NewConsumerGroupFromClient(groupID string, c Client) (ConsumerGroup, error) {
+	cl, err := asClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return newConsumerGroup(cl, false, groupID), nil
+}
+
+func newConsumerGroup(c *client, ownClient bool, groupID string) *consumerGroup {
+	return &consumerGroup{
+		client:    c,
+		ownClient: ownClient,
+		groupID:   groupID,
+		memberID:  fmt.Sprintf("%s-%d", c.conf.ClientID, atomic.AddInt64(&memberSeq, 1)),
+		errors:    make(chan error, c.conf.ChannelBufferSize),
+		closed:    make(chan struct{}),
+	}
+}
+
+func (cg *consumerGroup) Errors() <-chan error {
+	return cg.errors
+}
+
+func (cg *consumerGroup) handleError(err error) {
+	if !cg.client.conf.Consumer.Return.Errors {
+		return
+	}
+	select {
+	case cg.errors <- err:
+	case <-cg.closed:
+	}
+}
+
+func (cg *consumerGroup) Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error {
+	if len(topics) == 0 {
+		return ConfigurationError("no topics provided")
+	}
+	cg.mu.Lock()
+	if cg.isClosed {
+		cg.mu.Unlock()
+		return ErrClosedConsumerGroup
+	}
+	cg.running.Add(1)
+	cg.mu.Unlock()
+	defer cg.running.Done()
+
+	sess, err := cg.join(ctx, topics)
+	if err != nil || sess == nil {
+		return err
+	}
+	return sess.run(handler)
+}
+
+// join registers the member, waits for the group to settle and starts a
+// session with the member's claims. It returns a nil session if ctx is
+// done first.
+func (cg *consumerGroup) join(ctx context.Context, topics []string) (*consumerGroupSession, error) {
+	cluster := cg.client.cluster
+	sorted := append([]string(nil), topics...)
+	sort.Strings(sorted)
+
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	select {
+	case <-cg.closed:
+		// Close has already left the group
+		return nil, ErrClosedConsumerGroup
+	default:
+	}
+	g := cluster.group(cg.groupID)
+	m, ok := g.members[cg.memberID]
+	if !ok {
+		m = &groupMember{}
+		g.members[cg.memberID] = m
+		g.order = append(g.order, cg.memberID)
+	}
+	m.strategy = cg.client.conf.Consumer.Group.Rebalance.GroupStrategies[0]
+	if !ok || strings.Join(m.topics, ",") != strings.Join(sorted, ",") {
+		m.topics = sorted
+		g.rebalance()
+	}
+
+	for g.settling() {
+		wait := g.changed
+		cluster.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			cluster.mu.Lock()
+			return nil, nil
+		case <-cg.closed:
+			cluster.mu.Lock()
+			return nil, ErrClosedConsumerGroup
+		}
+		cluster.mu.Lock()
+	}
+	if _, ok := g.members[cg.memberID]; !ok {
+		return nil, ErrClosedConsumerGroup
+	}
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	if g.plan == nil {
+		if err := g.makePlan(cluster); err != nil {
+			return nil, err
+		}
+	}
+
+	sessCtx, cancel := context.WithCancel(ctx)
+	sess := &consumerGroupSession{
+		parent:     cg,
+		group:      g,
+		generation: g.generation,
+		ctx:        sessCtx,
+		cancel:     cancel,
+		marks:      make(map[string]map[int32]*offsetMark),
+	}
+	initial := cg.client.conf.Consumer.Offsets.Initial
+	for topic, partitions := range g.plan[cg.memberID] {
+		sess.marks[topic] = make(map[int32]*offsetMark)
+		for _, partition := range sortedPartitions(partitions) {
+			offset := initial
+			if entry, ok := g.offsets[topic][partition]; ok {
+				offset = entry.offset
+			}
+			start, err := cluster.resolveOffset(topic, partition, offset)
+			if err == ErrOffsetOutOfRange {
+				start, err = cluster.resolveOffset(topic, partition, initial)
+			}
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			sess.marks[topic][partition] = &offsetMark{offset: start}
+			sess.claims = append(sess.claims, &consumerGroupClaim{
+				sess:      sess,
+				topic:     topic,
+				partition: partition,
+				initial:   start,
+				messages:  make(chan *ConsumerMessage, cg.client.conf.ChannelBufferSize),
+			})
+		}
+	}
+	m.session = sess
+	g.notify()
+	return sess, nil
+}
+
+func (cg *consumerGroup) Close() error {
+	var err error
+	cg.closeOnce.Do(func() {
+		cg.mu.Lock()
+		cg.isClosed = true
+		cg.mu.Unlock()
+		close(cg.closed)
+
+		cluster := cg.client.cluster
+		cluster.mu.Lock()
+		if g, ok := cluster.groups[cg.groupID]; ok {
+			g.leave(cg.memberID)
+		}
+		cluster.mu.Unlock()
+
+		cg.running.Wait()
+		close(cg.errors)
+		if cg.ownClient {
+			err = cg.client.Close()
+		}
+	})
+	return err
+}
+
+type offsetMark struct {
+	offset   int64
+	metadata string
+	dirty    bool
+}
+
+type consumerGroupSession struct {
+	parent     *consumerGroup
+	group      *group
+	generation int32
+	ctx        context.Context
+	cancel     context.CancelFunc
+	claims     []*consumerGroupClaim
+
+	mu       sync.Mutex
+	marks    map[string]map[int32]*offsetMark
+	released bool // guarded by the cluster's lock
+}
+
+func (s *consumerGroupSession) Claims() map[string][]int32 {
+	claims := make(map[string][]int32)
+	for _, claim := range s.claims {
+		claims[claim.topic] = append(claims[claim.topic], claim.partition)
+	}
+	return claims
+}
+
+func (s *consumerGroupSession) MemberID() string         { return s.parent.memberID }
+func (s *consumerGroupSession) GenerationID() int32      { return s.generation }
+func (s *consumerGroupSession) Context() context.Context { return s.ctx }
+
+func (s *consumerGroupSession) mark(topic string, partition int32, offset int64, metadata string, forward bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.marks[topic][partition]
+	if !ok {
+		return
+	}
+	if (forward && offset > m.offset) || (!forward && offset < m.offset) {
+		m.offset, m.metadata, m.dirty = offset, metadata, true
+	}
+}
+
+func (s *consumerGroupSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mark(topic, partition, offset, metadata, true)
+}
+
+func (s *consumerGroupSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mark(topic, partition, offset, metadata, false)
+}
+
+func (s *consumerGroupSession) MarkMessage(msg *ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func (s *consumerGroupSession) Commit() {
+	cluster := s.parent.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if s.released && s.generation != s.group.generation {
+		// The partitions may belong to a newer session by now
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for topic, partitions := range s.marks {
+		for partition, m := range partitions {
+			if !m.dirty {
+				continue
+			}
+			if s.group.offsets[topic] == nil {
+				s.group.offsets[topic] = make(map[int32]offsetEntry)
+			}
+			s.group.offsets[topic][partition] = offsetEntry{offset: m.offset, metadata: m.metadata}
+			m.dirty = false
+		}
+	}
+}
+
+// run drives the session until it ends, then commits and releases it
+func (s *consumerGroupSession) run(handler ConsumerGroupHandler) error {
+	defer s.release()
+	if err := handler.Setup(s); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, claim := range s.claims {
+		wg.Add(1)
+		go claim.feed()
+		go func(claim *consumerGroupClaim) {
+			defer wg.Done()
+			if err := handler.ConsumeClaim(s, claim); err != nil {
+				s.parent.handleError(err)
+			}
+		}(claim)
+	}
+	if len(s.claims) > 0 {
+		// The session ends once every claim is done
+		go func() {
+			wg.Wait()
+			s.cancel()
+		}()
+	}
+
+	autoCommit := s.parent.client.conf.Consumer.Offsets.AutoCommit
+	if autoCommit.Enable && autoCommit.Interval > 0 {
+		go func() {
+			ticker := time.NewTicker(autoCommit.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.Commit()
+				case <-s.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	<-s.ctx.Done()
+	wg.Wait()
+	err := handler.Cleanup(s)
+	if autoCommit.Enable {
+		s.Commit()
+	}
+	return err
+}
+
+func (s *consumerGroupSession) release() {
+	s.cancel()
+	cluster := s.parent.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	s.released = true
+	if m, ok := s.group.members[s.parent.memberID]; ok && m.session == s {
+		m.session = nil
+	}
+	delete(s.group.leaving, s)
+	s.group.notify()
+}
+
+type consumerGroupClaim struct {
+	sess      *consumerGroupSession
+	topic     string
+	partition int32
+	initial   int64
+	messages  chan *ConsumerMessage
+}
+
+func (c *consumerGroupClaim) Topic() string                     { return c.topic }
+func (c *consumerGroupClaim) Partition() int32                  { return c.partition }
+func (c *consumerGroupClaim) InitialOffset() int64              { return c.initial }
+func (c *consumerGroupClaim) Messages() <-chan *ConsumerMessage { return c.messages }
+
+func (c *consumerGroupClaim) HighWaterMarkOffset() int64 {
+	hwm, _ := c.sess.parent.client.cluster.HighWaterMark(c.topic, c.partition)
+	return hwm
+}
+
+// feed delivers the partition's messages until the session ends
+func (c *consumerGroupClaim) feed() {
+	defer close(c.messages)
+	cluster := c.sess.parent.client.cluster
+	done := c.sess.ctx.Done()
+	offset := c.initial
+	for {
+		msg, wait, err := cluster.fetch(c.topic, c.partition, offset)
+		if err != nil {
+			c.sess.parent.handleError(&ConsumerError{Topic: c.topic, Partition: c.partition, Err: err})
+			return
+		}
+		if msg != nil {
+			select {
+			case c.messages <- msg:
+				offset++
+			case <-done:
+				return
+			}
+			continue
+		}
+		select {
+		case <-wait:
+		case <-done:
+			return
+		}
+	}
+}
+
+// Admin
+
+// TopicDetail describes a topic to create
+type TopicDetail struct {
+	NumPartitions     int32
+	ReplicationFactor int16
+	ConfigEntries     map[string]*string
+}
+
+// OffsetFetchResponse holds a group's committed offsets
+type OffsetFetchResponse struct {
+	Blocks map[string]map[int32]*OffsetFetchResponseBlock
+}
+
+// OffsetFetchResponseBlock is one partition's committed offset, -1 if
+// there is none
+type OffsetFetchResponseBlock struct {
+	Offset   int64
+	Metadata string
+	Err      KError
+}
+
+// GetBlock returns a partition's block, or nil
+func (r *OffsetFetchResponse) GetBlock(topic string, partition int32) *OffsetFetchResponseBlock {
+	return r.Blocks[topic][partition]
+}
+
+// ClusterAdmin manages topics and consumer groups
+type ClusterAdmin interface {
+	CreateTopic(topic string, detail *TopicDetail, validateOnly bool) error
+	ListTopics() (map[string]TopicDetail, error)
+	DeleteTopic(topic string) error
+	CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error
+	ListConsumerGroups() (map[string]string, error)
+	ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*OffsetFetchResponse, error)
+	DeleteConsumerGroup(group string) error
+	Close() error
+}
+
+type clusterAdmin struct {
+	client    *client
+	ownClient bool
+}
+
+// NewClusterAdmin creates a ClusterAdmin with its own client
+func NewClusterAdmin(addrs []string, conf *Config) (ClusterAdmin, error) {
+	c, err := newClient(addrs, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterAdmin{client: c, ownClient: true}, nil
+}
+
+// NewClusterAdminFromClient creates a ClusterAdmin on an existing client
+func NewClusterAdminFromClient(c Client) (ClusterAdmin, error) {
+	cl, err := asClient(c)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterAdmin{client: cl}, nil
+}
+
+func (ca *clusterAdmin) CreateTopic(topic string, detail *TopicDetail, validateOnly bool) error {
+	if ca.client.Closed() {
+		return ErrClosedClient
+	}
+	if detail == nil {
+		return errors.New("you must specify topic details")
+	}
+	if detail.ReplicationFactor == 0 || detail.ReplicationFactor < -1 {
+		return ErrInvalidReplicationFactor
+	}
+	cluster := ca.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if validateOnly {
+		if !validTopicName(topic) {
+			return ErrInvalidTopic
+		}
+		if detail.NumPartitions <= 0 {
+			return ErrInvalidPartitions
+		}
+		if _, ok := cluster.topics[topic]; ok {
+			return ErrTopicAlreadyExists
+		}
+		return nil
+	}
+	return cluster.createTopic(topic, detail.NumPartitions)
+}
+
+func (ca *clusterAdmin) ListTopics() (map[string]TopicDetail, error) {
+	if ca.client.Closed() {
+		return nil, ErrClosedClient
+	}
+	cluster := ca.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	topics := make(map[string]TopicDetail, len(cluster.topics))
+	for name, t := range cluster.topics {
+		topics[name] = TopicDetail{NumPartitions: int32(len(t.partitions)), ReplicationFactor: 1}
+	}
+	return topics, nil
+}
+
+func (ca *clusterAdmin) DeleteTopic(topic string) error {
+	if ca.client.Closed() {
+		return ErrClosedClient
+	}
+	return ca.client.cluster.DeleteTopic(topic)
+}
+
+func (ca *clusterAdmin) CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error {
+	if ca.client.Closed() {
+		return ErrClosedClient
+	}
+	if validateOnly {
+		partitions, err := ca.client.cluster.Partitions(topic)
+		if err != nil {
+			return err
+		}
+		if int(count) <= len(partitions) {
+			return ErrInvalidPartitions
+		}
+		return nil
+	}
+	return ca.client.cluster.CreatePartitions(topic, count)
+}
+
+func (ca *clusterAdmin) ListConsumerGroups() (map[string]string, error) {
+	if ca.client.Closed() {
+		return nil, ErrClosedClient
+	}
+	cluster := ca.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	groups := make(map[string]string, len(cluster.groups))
+	for id := range cluster.groups {
+		groups[id] = "consumer"
+	}
+	return groups, nil
+}
+
+func (ca *clusterAdmin) ListConsumerGroupOffsets(groupID string, topicPartitions map[string][]int32) (*OffsetFetchResponse, error) {
+	if ca.client.Closed() {
+		return nil, ErrClosedClient
+	}
+	cluster := ca.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	resp := &OffsetFetchResponse{Blocks: make(map[string]map[int32]*OffsetFetchResponseBlock)}
+	g := cluster.groups[groupID]
+	if topicPartitions == nil && g != nil {
+		topicPartitions = make(map[string][]int32)
+		for topic, partitions := range g.offsets {
+			for partition := range partitions {
+				topicPartitions[topic] = append(topicPartitions[topic], partition)
+			}
+		}
+	}
+	for topic, partitions := range topicPartitions {
+		resp.Blocks[topic] = make(map[int32]*OffsetFetchResponseBlock)
+		for _, partition := range partitions {
+			block := &OffsetFetchResponseBlock{Offset: -1}
+			if g != nil {
+				if entry, ok := g.offsets[topic][partition]; ok {
+					block.Offset, block.Metadata = entry.offset, entry.metadata
+				}
+			}
+			resp.Blocks[topic][partition] = block
+		}
+	}
+	return resp, nil
+}
+
+func (ca *clusterAdmin) DeleteConsumerGroup(groupID string) error {
+	if ca.client.Closed() {
+		return ErrClosedClient
+	}
+	cluster := ca.client.cluster
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	g, ok := cluster.groups[groupID]
+	if !ok {
+		return ErrGroupIDNotFound
+	}
+	if len(g.members) > 0 {
+		return ErrNonEmptyGroup
+	}
+	delete(cluster.groups, groupID)
+	return nil
+}
+
+func (ca *clusterAdmin) Close() error {
+	if ca.ownClient {
+		return ca.client.Close()
+	}
+	return nil
+}
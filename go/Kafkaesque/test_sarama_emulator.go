@@ -0,0 +1,1002 @@
+package main
+
+// Developed by PowerShield, as an alternative to Sarama
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func syncConfig() *Config {
+	conf := NewConfig()
+	conf.Producer.Return.Successes = true
+	return conf
+}
+
+// produce sends string values to a topic, failing loudly on errors
+func produce(addr, topic string, values ...string) {
+	producer, err := NewSyncProducer([]string{addr}, syncConfig())
+	if err != nil {
+		panic(err)
+	}
+	defer producer.Close()
+	for _, v := range values {
+		if _, _, err := producer.SendMessage(&ProducerMessage{Topic: topic, Key: StringEncoder(v), Value: StringEncoder(v)}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// recordingHandler collects consumed values and the claims of each session
+type recordingHandler struct {
+	mu       sync.Mutex
+	values   []string
+	claims   []map[string][]int32
+	noMark   bool
+	slow     time.Duration // how long Cleanup takes
+	setups   int
+	cleanups int
+}
+
+func (h *recordingHandler) Setup(s ConsumerGroupSession) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.setups++
+	h.claims = append(h.claims, s.Claims())
+	return nil
+}
+
+func (h *recordingHandler) Cleanup(s ConsumerGroupSession) error {
+	time.Sleep(h.slow)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cleanups++
+	return nil
+}
+
+func (h *recordingHandler) ConsumeClaim(s ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		h.mu.Lock()
+		h.values = append(h.values, string(msg.Value))
+		h.mu.Unlock()
+		if !h.noMark {
+			s.MarkMessage(msg, "")
+		}
+	}
+	return nil
+}
+
+func (h *recordingHandler) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.values)
+}
+
+func (h *recordingHandler) sorted() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	values := append([]string(nil), h.values...)
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func (h *recordingHandler) lastClaims() map[string][]int32 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.claims) == 0 {
+		return nil
+	}
+	return h.claims[len(h.claims)-1]
+}
+
+// consumeLoop calls Consume until ctx is done, like a service would
+func consumeLoop(ctx context.Context, group ConsumerGroup, topics []string, handler ConsumerGroupHandler, done *sync.WaitGroup) {
+	done.Add(1)
+	go func() {
+		defer done.Done()
+		for ctx.Err() == nil {
+			if err := group.Consume(ctx, topics, handler); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func claimCount(claims map[string][]int32) int {
+	n := 0
+	for _, partitions := range claims {
+		n += len(partitions)
+	}
+	return n
+}
+
+// Test topics, partitions and offsets on the cluster and client
+func testTopicsAndPartitions() bool {
+	cluster := NewCluster("topics:9092")
+	defer cluster.Close()
+
+	if cluster.CreateTopic("orders", 3) != nil || cluster.CreateTopic("orders", 3) != ErrTopicAlreadyExists {
+		return false
+	}
+	if cluster.CreateTopic("bad name", 1) != ErrInvalidTopic || cluster.CreateTopic("empty", 0) != ErrInvalidPartitions {
+		return false
+	}
+
+	client, err := NewClient([]string{"nowhere:1", "topics:9092"}, nil)
+	if err != nil {
+		return false
+	}
+	defer client.Close()
+	topics, _ := client.Topics()
+	partitions, _ := client.Partitions("orders")
+	if strings.Join(topics, ",") != "orders" || len(partitions) != 3 || partitions[2] != 2 {
+		return false
+	}
+	if _, err := client.Partitions("missing"); err != ErrUnknownTopicOrPartition {
+		return false
+	}
+
+	produce("topics:9092", "events", "a", "b", "c")
+	messages := cluster.Messages("events", 0)
+	if len(messages) != 3 || messages[2].Offset != 2 || string(messages[1].Value) != "b" {
+		return false
+	}
+	newest, _ := client.GetOffset("events", 0, OffsetNewest)
+	oldest, _ := client.GetOffset("events", 0, OffsetOldest)
+	byTime, _ := client.GetOffset("events", 0, messages[1].Timestamp.UnixNano()/int64(time.Millisecond))
+	if newest != 3 || oldest != 0 || byTime > 1 {
+		return false
+	}
+
+	if _, err := NewClient([]string{"nowhere:1"}, nil); err != ErrOutOfBrokers {
+		return false
+	}
+	client.Close()
+	if _, err := client.Topics(); err != ErrClosedClient {
+		return false
+	}
+	cluster.Close()
+	_, err = NewClient([]string{"topics:9092"}, nil)
+	return err == ErrOutOfBrokers
+}
+
+// Test the sync producer
+func testSyncProducer() bool {
+	cluster := NewCluster("sync:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("payments", 2)
+
+	if _, err := NewSyncProducer([]string{"sync:9092"}, NewConfig()); err == nil ||
+		!strings.Contains(err.Error(), "Producer.Return.Successes must be true") {
+		return false
+	}
+
+	conf := syncConfig()
+	conf.Producer.Partitioner = NewManualPartitioner
+	conf.Producer.MaxMessageBytes = 12
+	producer, err := NewSyncProducer([]string{"sync:9092"}, conf)
+	if err != nil {
+		return false
+	}
+
+	msg := &ProducerMessage{
+		Topic:     "payments",
+		Partition: 1,
+		Key:       StringEncoder("p-1"),
+		Value:     ByteEncoder("42"),
+		Headers:   []RecordHeader{{Key: []byte("trace"), Value: []byte("t1")}},
+	}
+	partition, offset, err := producer.SendMessage(msg)
+	if err != nil || partition != 1 || offset != 0 || msg.Offset != 0 || msg.Timestamp.IsZero() {
+		return false
+	}
+	partition, offset, _ = producer.SendMessage(&ProducerMessage{Topic: "payments", Partition: 1, Value: StringEncoder("7")})
+	if partition != 1 || offset != 1 {
+		return false
+	}
+	stored := cluster.Messages("payments", 1)[0]
+	if string(stored.Key) != "p-1" || string(stored.Headers[0].Value) != "t1" {
+		return false
+	}
+
+	err = producer.SendMessages([]*ProducerMessage{
+		{Topic: "payments", Partition: 0, Value: StringEncoder("ok")},
+		{Topic: "payments", Partition: 5, Value: StringEncoder("bad")},
+		{Topic: "payments", Partition: 0, Value: StringEncoder("far too large")},
+	})
+	var errs ProducerErrors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Err != ErrInvalidPartition || errs[1].Err != ErrMessageSizeTooLarge {
+		return false
+	}
+	if len(cluster.Messages("payments", 0)) != 1 {
+		return false
+	}
+
+	producer.Close()
+	_, _, err = producer.SendMessage(&ProducerMessage{Topic: "payments", Value: StringEncoder("late")})
+	return err == ErrShuttingDown
+}
+
+// Test the built-in partitioners
+func testPartitioners() bool {
+	hash := NewHashPartitioner("t")
+	first, _ := hash.Partition(&ProducerMessage{Key: StringEncoder("user-42")}, 8)
+	for i := 0; i < 10; i++ {
+		if p, _ := hash.Partition(&ProducerMessage{Key: StringEncoder("user-42")}, 8); p != first {
+			return false
+		}
+	}
+	hasher := fnv.New32a()
+	hasher.Write([]byte("user-42"))
+	expected := int32(hasher.Sum32()) % 8
+	if expected < 0 {
+		expected = -expected
+	}
+	if first != expected || !hash.RequiresConsistency() {
+		return false
+	}
+	for i := 0; i < 20; i++ {
+		if p, _ := hash.Partition(&ProducerMessage{}, 3); p < 0 || p > 2 {
+			return false
+		}
+	}
+
+	rr := NewRoundRobinPartitioner("t")
+	var order []int32
+	for i := 0; i < 5; i++ {
+		p, _ := rr.Partition(&ProducerMessage{}, 3)
+		order = append(order, p)
+	}
+	if fmt.Sprint(order) != "[0 1 2 0 1]" || rr.RequiresConsistency() {
+		return false
+	}
+
+	random := NewRandomPartitioner("t")
+	seen := map[int32]bool{}
+	for i := 0; i < 200; i++ {
+		p, _ := random.Partition(&ProducerMessage{}, 4)
+		seen[p] = true
+	}
+	if len(seen) != 4 {
+		return false
+	}
+
+	// Keys keep their partition through a producer
+	cluster := NewCluster("partitioners:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("users", 4)
+	producer, _ := NewSyncProducer([]string{"partitioners:9092"}, syncConfig())
+	defer producer.Close()
+	var partitions []int32
+	for i := 0; i < 3; i++ {
+		p, _, _ := producer.SendMessage(&ProducerMessage{Topic: "users", Key: StringEncoder("alice"), Value: StringEncoder("x")})
+		partitions = append(partitions, p)
+	}
+	return partitions[0] == partitions[1] && partitions[1] == partitions[2]
+}
+
+// Test the async producer
+func testAsyncProducer() bool {
+	cluster := NewCluster("async:9092")
+	defer cluster.Close()
+	cluster.AutoCreateTopics = false
+	cluster.CreateTopic("clicks", 2)
+
+	conf := NewConfig()
+	conf.Producer.Return.Successes = true
+	conf.Producer.Partitioner = NewRoundRobinPartitioner
+	producer, err := NewAsyncProducer([]string{"async:9092"}, conf)
+	if err != nil {
+		return false
+	}
+	for i := 0; i < 4; i++ {
+		producer.Input() <- &ProducerMessage{Topic: "clicks", Value: StringEncoder(fmt.Sprint(i)), Metadata: i}
+	}
+	producer.Input() <- &ProducerMessage{Topic: "unknown", Value: StringEncoder("x"), Metadata: "lost"}
+
+	var metadata []interface{}
+	for i := 0; i < 4; i++ {
+		select {
+		case msg := <-producer.Successes():
+			metadata = append(metadata, msg.Metadata)
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	if fmt.Sprint(metadata) != "[0 1 2 3]" {
+		return false
+	}
+	select {
+	case pe := <-producer.Errors():
+		if pe.Msg.Metadata != "lost" || pe.Err != ErrUnknownTopicOrPartition {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	if len(cluster.Messages("clicks", 0)) != 2 || len(cluster.Messages("clicks", 1)) != 2 {
+		return false
+	}
+
+	// Close flushes and returns the errors nobody read
+	producer2, _ := NewAsyncProducer([]string{"async:9092"}, NewConfig())
+	producer2.Input() <- &ProducerMessage{Topic: "clicks", Value: StringEncoder("kept")}
+	producer2.Input() <- &ProducerMessage{Topic: "nope", Value: StringEncoder("dropped")}
+	err = producer2.Close()
+	var errs ProducerErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Msg.Topic != "nope" {
+		return false
+	}
+	if !strings.Contains(errs[0].Error(), "Failed to produce message to topic nope") || !errors.Is(errs[0], ErrUnknownTopicOrPartition) {
+		return false
+	}
+	return producer.Close() == nil
+}
+
+// Test reading partitions directly
+func testPartitionConsumer() bool {
+	cluster := NewCluster("consumer:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("logs", 1)
+	produce("consumer:9092", "logs", "one", "two")
+
+	consumer, err := NewConsumer([]string{"consumer:9092"}, nil)
+	if err != nil {
+		return false
+	}
+	defer consumer.Close()
+
+	oldest, err := consumer.ConsumePartition("logs", 0, OffsetOldest)
+	if err != nil {
+		return false
+	}
+	if _, err := consumer.ConsumePartition("logs", 0, OffsetNewest); err == nil {
+		return false
+	}
+	var got []string
+	for len(got) < 2 {
+		select {
+		case msg := <-oldest.Messages():
+			got = append(got, fmt.Sprintf("%d:%s", msg.Offset, msg.Value))
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	// Messages produced later arrive too
+	produce("consumer:9092", "logs", "three")
+	select {
+	case msg := <-oldest.Messages():
+		got = append(got, fmt.Sprintf("%d:%s", msg.Offset, msg.Value))
+	case <-time.After(time.Second):
+		return false
+	}
+	if strings.Join(got, ",") != "0:one,1:two,2:three" || oldest.HighWaterMarkOffset() != 3 {
+		return false
+	}
+	if oldest.Close() != nil {
+		return false
+	}
+
+	// Newest skips what is already there; explicit offsets are checked
+	newest, _ := consumer.ConsumePartition("logs", 0, OffsetNewest)
+	produce("consumer:9092", "logs", "four")
+	select {
+	case msg := <-newest.Messages():
+		if string(msg.Value) != "four" || msg.Offset != 3 {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	newest.Close()
+	if _, err := consumer.ConsumePartition("logs", 0, 10); err != ErrOffsetOutOfRange {
+		return false
+	}
+	if _, err := consumer.ConsumePartition("logs", 3, OffsetOldest); err != ErrUnknownTopicOrPartition {
+		return false
+	}
+	pc, err := consumer.ConsumePartition("logs", 0, 1)
+	if err != nil {
+		return false
+	}
+	msg := <-pc.Messages()
+	return string(msg.Value) == "two" && consumer.HighWaterMarks()["logs"][0] == 4
+}
+
+// Test a single consumer group member consuming and committing
+func testConsumerGroup() bool {
+	cluster := NewCluster("group:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("jobs", 3)
+
+	conf := NewConfig()
+	conf.Consumer.Offsets.Initial = OffsetOldest
+	conf.Producer.Partitioner = NewRoundRobinPartitioner
+	conf.Producer.Return.Successes = true
+	producer, _ := NewSyncProducer([]string{"group:9092"}, conf)
+	defer producer.Close()
+	for _, v := range []string{"a", "b", "c", "d", "e", "f"} {
+		producer.SendMessage(&ProducerMessage{Topic: "jobs", Value: StringEncoder(v)})
+	}
+
+	group, err := NewConsumerGroup([]string{"group:9092"}, "workers", conf)
+	if err != nil {
+		return false
+	}
+	handler := &recordingHandler{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var done sync.WaitGroup
+	consumeLoop(ctx, group, []string{"jobs"}, handler, &done)
+
+	if !waitFor(func() bool { return handler.count() == 6 }) || handler.sorted() != "a,b,c,d,e,f" {
+		return false
+	}
+	if claimCount(handler.lastClaims()) != 3 {
+		return false
+	}
+	cancel()
+	done.Wait()
+	if handler.setups != 1 || handler.cleanups != 1 {
+		return false
+	}
+	// The session committed its marks when it ended
+	for p := int32(0); p < 3; p++ {
+		if cluster.CommittedOffset("workers", "jobs", p) != 2 {
+			return false
+		}
+	}
+	group.Close()
+
+	// A new member resumes after the committed offsets
+	producer.SendMessage(&ProducerMessage{Topic: "jobs", Value: StringEncoder("g")})
+	group2, _ := NewConsumerGroup([]string{"group:9092"}, "workers", conf)
+	defer group2.Close()
+	handler2 := &recordingHandler{}
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	consumeLoop(ctx2, group2, []string{"jobs"}, handler2, &done)
+	ok := waitFor(func() bool { return handler2.count() == 1 })
+	time.Sleep(10 * time.Millisecond)
+	cancel2()
+	done.Wait()
+	return ok && handler2.sorted() == "g" && cluster.Lag("workers", "jobs") == 0
+}
+
+// Test rebalancing when members join and leave
+func testRebalancing() bool {
+	cluster := NewCluster("rebalance:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("tasks", 4)
+
+	conf := NewConfig()
+	conf.Consumer.Offsets.Initial = OffsetOldest
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var done sync.WaitGroup
+
+	first, _ := NewConsumerGroup([]string{"rebalance:9092"}, "pool", conf)
+	h1 := &recordingHandler{}
+	consumeLoop(ctx, first, []string{"tasks"}, h1, &done)
+	if !waitFor(func() bool { return claimCount(h1.lastClaims()) == 4 }) {
+		return false
+	}
+
+	second, _ := NewConsumerGroup([]string{"rebalance:9092"}, "pool", conf)
+	h2 := &recordingHandler{}
+	consumeLoop(ctx, second, []string{"tasks"}, h2, &done)
+	split := func() bool {
+		c1, c2 := h1.lastClaims(), h2.lastClaims()
+		return claimCount(c1) == 2 && claimCount(c2) == 2 && fmt.Sprint(c1["tasks"]) != fmt.Sprint(c2["tasks"])
+	}
+	if !waitFor(split) {
+		return false
+	}
+
+	// Every message is consumed exactly once across the members
+	var values []string
+	for i := 0; i < 20; i++ {
+		values = append(values, fmt.Sprintf("m%02d", i))
+	}
+	produce("rebalance:9092", "tasks", values...)
+	if !waitFor(func() bool { return h1.count()+h2.count() == 20 }) {
+		return false
+	}
+	if h1.count() == 0 || h2.count() == 0 {
+		return false
+	}
+
+	// When a member leaves, the other takes over its partitions
+	second.Close()
+	if !waitFor(func() bool { return claimCount(h1.lastClaims()) == 4 }) {
+		return false
+	}
+	produce("rebalance:9092", "tasks", "late-1", "late-2", "late-3")
+	if !waitFor(func() bool { return h1.count()+h2.count() == 23 }) {
+		return false
+	}
+
+	// New partitions trigger a rebalance too
+	cluster.CreatePartitions("tasks", 6)
+	ok := waitFor(func() bool { return claimCount(h1.lastClaims()) == 6 })
+	cancel()
+	done.Wait()
+	first.Close()
+	return ok
+}
+
+// Test that a leaving member's messages aren't consumed again
+func testLeaveExactlyOnce() bool {
+	cluster := NewCluster("leave:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("orders", 4)
+
+	conf := NewConfig()
+	conf.Consumer.Offsets.Initial = OffsetOldest
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var done sync.WaitGroup
+
+	first, _ := NewConsumerGroup([]string{"leave:9092"}, "billing", conf)
+	second, _ := NewConsumerGroup([]string{"leave:9092"}, "billing", conf)
+	h1 := &recordingHandler{}
+	// The leaving member is still cleaning up, with uncommitted marks,
+	// when the group rebalances
+	h2 := &recordingHandler{slow: 20 * time.Millisecond}
+	consumeLoop(ctx, first, []string{"orders"}, h1, &done)
+	consumeLoop(ctx, second, []string{"orders"}, h2, &done)
+	if !waitFor(func() bool { return claimCount(h1.lastClaims()) == 2 && claimCount(h2.lastClaims()) == 2 }) {
+		return false
+	}
+	var values []string
+	for i := 0; i < 20; i++ {
+		values = append(values, fmt.Sprintf("o%02d", i))
+	}
+	produce("leave:9092", "orders", values...)
+	if !waitFor(func() bool { return h1.count()+h2.count() == 20 }) {
+		return false
+	}
+
+	second.Close()
+	if !waitFor(func() bool { return claimCount(h1.lastClaims()) == 4 }) {
+		return false
+	}
+	produce("leave:9092", "orders", "o20", "o21")
+	ok := waitFor(func() bool { return h1.count()+h2.count() == 22 })
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	done.Wait()
+	first.Close()
+
+	seen := make(map[string]bool)
+	for _, v := range strings.Split(h1.sorted()+","+h2.sorted(), ",") {
+		if seen[v] {
+			return false
+		}
+		seen[v] = true
+	}
+	return ok && len(seen) == 22 && cluster.Lag("billing", "orders") == 0
+}
+
+// Test the range and round-robin strategies
+func testBalanceStrategies() bool {
+	members := map[string]ConsumerGroupMemberMetadata{
+		"m1": {Topics: []string{"t1", "t2"}},
+		"m2": {Topics: []string{"t1", "t2"}},
+		"m3": {Topics: []string{"t1"}},
+	}
+	topics := map[string][]int32{"t1": {0, 1, 2, 3, 4}, "t2": {0, 1, 2}}
+
+	plan, err := BalanceStrategyRange.Plan(members, topics)
+	if err != nil || BalanceStrategyRange.Name() != "range" {
+		return false
+	}
+	if fmt.Sprint(plan["m1"]["t1"], plan["m2"]["t1"], plan["m3"]["t1"]) != "[0 1] [2 3] [4]" {
+		return false
+	}
+	if fmt.Sprint(plan["m1"]["t2"], plan["m2"]["t2"]) != "[0 1] [2]" || plan["m3"]["t2"] != nil {
+		return false
+	}
+
+	plan, err = BalanceStrategyRoundRobin.Plan(members, topics)
+	if err != nil || BalanceStrategyRoundRobin.Name() != "roundrobin" {
+		return false
+	}
+	// t1: 0→m1 1→m2 2→m3 3→m1 4→m2, t2 (m3 skipped): 0→m1 1→m2 2→m1
+	if fmt.Sprint(plan["m1"]["t1"], plan["m2"]["t1"], plan["m3"]["t1"]) != "[0 3] [1 4] [2]" {
+		return false
+	}
+	if fmt.Sprint(plan["m1"]["t2"], plan["m2"]["t2"]) != "[0 2] [1]" {
+		return false
+	}
+
+	// The group uses its strategy end to end
+	cluster := NewCluster("strategies:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("a", 2)
+	cluster.CreateTopic("b", 2)
+	conf := NewConfig()
+	conf.Consumer.Group.Rebalance.GroupStrategies = []BalanceStrategy{BalanceStrategyRoundRobin}
+	ctx, cancel := context.WithCancel(context.Background())
+	var done sync.WaitGroup
+	g1, _ := NewConsumerGroup([]string{"strategies:9092"}, "rr", conf)
+	g2, _ := NewConsumerGroup([]string{"strategies:9092"}, "rr", conf)
+	h1, h2 := &recordingHandler{}, &recordingHandler{}
+	consumeLoop(ctx, g1, []string{"a", "b"}, h1, &done)
+	consumeLoop(ctx, g2, []string{"a", "b"}, h2, &done)
+	ok := waitFor(func() bool {
+		c1, c2 := h1.lastClaims(), h2.lastClaims()
+		return len(c1["a"]) == 1 && len(c1["b"]) == 1 && len(c2["a"]) == 1 && len(c2["b"]) == 1
+	})
+	cancel()
+	done.Wait()
+	g1.Close()
+	g2.Close()
+	return ok
+}
+
+// markingHandler marks the first n messages of each claim and commits
+// manually when asked
+type markingHandler struct {
+	commit bool
+	seen   chan *ConsumerMessage
+}
+
+func (h *markingHandler) Setup(ConsumerGroupSession) error   { return nil }
+func (h *markingHandler) Cleanup(ConsumerGroupSession) error { return nil }
+
+func (h *markingHandler) ConsumeClaim(s ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		s.MarkMessage(msg, "processed")
+		if h.commit {
+			s.Commit()
+		}
+		h.seen <- msg
+	}
+	return nil
+}
+
+// Test offset commits with and without auto-commit
+func testOffsetCommits() bool {
+	cluster := NewCluster("offsets:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("audit", 1)
+	produce("offsets:9092", "audit", "x", "y", "z")
+
+	conf := NewConfig()
+	conf.Consumer.Offsets.Initial = OffsetOldest
+	conf.Consumer.Offsets.AutoCommit.Enable = false
+
+	run := func(handler *markingHandler, n int) bool {
+		group, _ := NewConsumerGroup([]string{"offsets:9092"}, "auditors", conf)
+		defer group.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		var done sync.WaitGroup
+		consumeLoop(ctx, group, []string{"audit"}, handler, &done)
+		for i := 0; i < n; i++ {
+			select {
+			case <-handler.seen:
+			case <-time.After(time.Second):
+				cancel()
+				return false
+			}
+		}
+		cancel()
+		done.Wait()
+		return true
+	}
+
+	// Without auto-commit, marks are lost unless committed
+	if !run(&markingHandler{seen: make(chan *ConsumerMessage, 10)}, 3) || cluster.CommittedOffset("auditors", "audit", 0) != -1 {
+		return false
+	}
+	if !run(&markingHandler{seen: make(chan *ConsumerMessage, 10), commit: true}, 3) || cluster.CommittedOffset("auditors", "audit", 0) != 3 {
+		return false
+	}
+
+	// Marks only move forward; ResetOffset moves them back
+	group, _ := NewConsumerGroup([]string{"offsets:9092"}, "rewinders", conf)
+	defer group.Close()
+	sessions := make(chan ConsumerGroupSession, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go group.Consume(ctx, []string{"audit"}, &sessionHandler{sessions: sessions})
+	var sess ConsumerGroupSession
+	select {
+	case sess = <-sessions:
+	case <-time.After(time.Second):
+		return false
+	}
+	sess.MarkOffset("audit", 0, 2, "")
+	sess.MarkOffset("audit", 0, 1, "")
+	sess.Commit()
+	forward := cluster.CommittedOffset("rewinders", "audit", 0)
+	sess.ResetOffset("audit", 0, 1, "replay")
+	sess.Commit()
+	cancel()
+	if forward != 2 || cluster.CommittedOffset("rewinders", "audit", 0) != 1 {
+		return false
+	}
+
+	// Newest starts at the end when nothing is committed
+	conf.Consumer.Offsets.Initial = OffsetNewest
+	handler := &markingHandler{seen: make(chan *ConsumerMessage, 10)}
+	fresh, _ := NewConsumerGroup([]string{"offsets:9092"}, "latest", conf)
+	defer fresh.Close()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	claims := make(chan ConsumerGroupSession, 1)
+	go fresh.Consume(ctx2, []string{"audit"}, &sessionHandler{sessions: claims, inner: handler})
+	<-claims
+	produce("offsets:9092", "audit", "new")
+	select {
+	case msg := <-handler.seen:
+		return string(msg.Value) == "new" && msg.Offset == 3
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+// sessionHandler hands its session to the test once set up
+type sessionHandler struct {
+	sessions chan ConsumerGroupSession
+	inner    ConsumerGroupHandler
+}
+
+func (h *sessionHandler) Setup(s ConsumerGroupSession) error {
+	h.sessions <- s
+	return nil
+}
+
+func (h *sessionHandler) Cleanup(ConsumerGroupSession) error { return nil }
+
+func (h *sessionHandler) ConsumeClaim(s ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	if h.inner != nil {
+		return h.inner.ConsumeClaim(s, claim)
+	}
+	<-s.Context().Done()
+	return nil
+}
+
+// Test the cluster admin
+func testClusterAdmin() bool {
+	cluster := NewCluster("admin:9092")
+	defer cluster.Close()
+	admin, err := NewClusterAdmin([]string{"admin:9092"}, nil)
+	if err != nil {
+		return false
+	}
+	defer admin.Close()
+
+	if admin.CreateTopic("metrics", &TopicDetail{NumPartitions: 2, ReplicationFactor: 1}, true) != nil {
+		return false
+	}
+	if topics, _ := admin.ListTopics(); len(topics) != 0 {
+		return false
+	}
+	if admin.CreateTopic("metrics", &TopicDetail{NumPartitions: 2, ReplicationFactor: 1}, false) != nil {
+		return false
+	}
+	if admin.CreateTopic("metrics", &TopicDetail{NumPartitions: 2, ReplicationFactor: 1}, false) != ErrTopicAlreadyExists ||
+		admin.CreateTopic("other", &TopicDetail{NumPartitions: 1}, false) != ErrInvalidReplicationFactor {
+		return false
+	}
+	if admin.CreatePartitions("metrics", 2, nil, false) != ErrInvalidPartitions || admin.CreatePartitions("metrics", 5, nil, false) != nil {
+		return false
+	}
+	topics, _ := admin.ListTopics()
+	if topics["metrics"].NumPartitions != 5 {
+		return false
+	}
+
+	produce("admin:9092", "metrics", "cpu")
+	conf := NewConfig()
+	conf.Consumer.Offsets.Initial = OffsetOldest
+	group, _ := NewConsumerGroup([]string{"admin:9092"}, "dashboards", conf)
+	handler := &recordingHandler{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var done sync.WaitGroup
+	consumeLoop(ctx, group, []string{"metrics"}, handler, &done)
+	if !waitFor(func() bool { return handler.count() == 1 }) {
+		return false
+	}
+	if admin.DeleteConsumerGroup("dashboards") != ErrNonEmptyGroup {
+		return false
+	}
+	cancel()
+	done.Wait()
+	group.Close()
+
+	groups, _ := admin.ListConsumerGroups()
+	if groups["dashboards"] != "consumer" {
+		return false
+	}
+	offsets, _ := admin.ListConsumerGroupOffsets("dashboards", nil)
+	var committed int64
+	for _, block := range offsets.Blocks["metrics"] {
+		committed += block.Offset
+	}
+	if committed != 1 {
+		return false
+	}
+	specific, _ := admin.ListConsumerGroupOffsets("dashboards", map[string][]int32{"other": {0}})
+	if specific.GetBlock("other", 0).Offset != -1 {
+		return false
+	}
+	if admin.DeleteConsumerGroup("dashboards") != nil || admin.DeleteConsumerGroup("dashboards") != ErrGroupIDNotFound {
+		return false
+	}
+	if admin.DeleteTopic("metrics") != nil || admin.DeleteTopic("metrics") != ErrUnknownTopicOrPartition {
+		return false
+	}
+	return len(cluster.Topics()) == 0
+}
+
+// errorHandler fails every claim
+type errorHandler struct{}
+
+func (errorHandler) Setup(ConsumerGroupSession) error   { return nil }
+func (errorHandler) Cleanup(ConsumerGroupSession) error { return nil }
+func (errorHandler) ConsumeClaim(ConsumerGroupSession, ConsumerGroupClaim) error {
+	return errors.New("handler failed")
+}
+
+// Test configuration and runtime errors
+func testErrors() bool {
+	cluster := NewCluster("errors:9092")
+	defer cluster.Close()
+	cluster.CreateTopic("t", 1)
+
+	conf := NewConfig()
+	conf.ClientID = ""
+	if _, err := NewClient([]string{"errors:9092"}, conf); err == nil || err.Error() != "kafka: invalid configuration (ClientID is invalid)" {
+		return false
+	}
+	conf = NewConfig()
+	conf.Consumer.Offsets.Initial = 5
+	if err := conf.Validate(); err == nil {
+		return false
+	}
+	if ErrUnknownTopicOrPartition.Error() != "kafka server: Request was for a topic or partition that does not exist on this broker" {
+		return false
+	}
+
+	// Handler errors reach the group's Errors channel, and a session
+	// whose claims are all done ends
+	conf = NewConfig()
+	conf.Consumer.Return.Errors = true
+	group, _ := NewConsumerGroup([]string{"errors:9092"}, "failing", conf)
+	result := make(chan error, 1)
+	go func() { result <- group.Consume(context.Background(), []string{"t"}, errorHandler{}) }()
+	select {
+	case err := <-group.Errors():
+		if err.Error() != "handler failed" {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	if group.Consume(context.Background(), nil, errorHandler{}) == nil {
+		return false
+	}
+	group.Close()
+	if group.Consume(context.Background(), []string{"t"}, errorHandler{}) != ErrClosedConsumerGroup {
+		return false
+	}
+
+	// Deleting a topic stops partition consumers with an error
+	conf = NewConfig()
+	conf.Consumer.Return.Errors = true
+	consumer, _ := NewConsumer([]string{"errors:9092"}, conf)
+	pc, _ := consumer.ConsumePartition("t", 0, OffsetOldest)
+	cluster.DeleteTopic("t")
+	select {
+	case err := <-pc.Errors():
+		if !errors.Is(err, ErrUnknownTopicOrPartition) {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	consumer.Close()
+
+	// With auto-creation off, unknown topics are rejected
+	cluster.AutoCreateTopics = false
+	producer, _ := NewSyncProducer([]string{"errors:9092"}, syncConfig())
+	defer producer.Close()
+	_, _, err := producer.SendMessage(&ProducerMessage{Topic: "t", Value: StringEncoder("v")})
+	return err == ErrUnknownTopicOrPartition
+}
+
+// Test sharing one client between producers, consumers and admins
+func testSharedClient() bool {
+	cluster := NewCluster("shared:9092")
+	defer cluster.Close()
+	client, err := NewClient([]string{"shared:9092"}, syncConfig())
+	if err != nil {
+		return false
+	}
+
+	admin, _ := NewClusterAdminFromClient(client)
+	admin.CreateTopic("shared", &TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false)
+	producer, err := NewSyncProducerFromClient(client)
+	if err != nil {
+		return false
+	}
+	producer.SendMessage(&ProducerMessage{Topic: "shared", Value: StringEncoder("hello")})
+	producer.Close()
+	admin.Close()
+
+	// Closing a producer made from a client leaves the client open
+	if client.Closed() {
+		return false
+	}
+	consumer, err := NewConsumerFromClient(client)
+	if err != nil {
+		return false
+	}
+	pc, _ := consumer.ConsumePartition("shared", 0, OffsetOldest)
+	msg := <-pc.Messages()
+	consumer.Close()
+	client.Close()
+	if _, err := NewSyncProducerFromClient(client); err != ErrClosedClient {
+		return false
+	}
+	return string(msg.Value) == "hello" && client.Config().ClientID == "sarama"
+}
+
+func main() {
+	fmt.Println("Running Sarama Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Topics And Partitions", testTopicsAndPartitions)
+	runTest("Sync Producer", testSyncProducer)
+	runTest("Partitioners", testPartitioners)
+	runTest("Async Producer", testAsyncProducer)
+	runTest("Partition Consumer", testPartitionConsumer)
+	runTest("Consumer Group", testConsumerGroup)
+	runTest("Rebalancing", testRebalancing)
+	runTest("Leave Exactly Once", testLeaveExactlyOnce)
+	runTest("Balance Strategies", testBalanceStrategies)
+	runTest("Offset Commits", testOffsetCommits)
+	runTest("Cluster Admin", testClusterAdmin)
+	runTest("Errors", testErrors)
+	runTest("Shared Client", testSharedClient)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
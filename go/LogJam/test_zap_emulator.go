@@ -0,0 +1,304 @@
+package main
+
+// Developed by PowerShield, as an alternative to Zap
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// fixedClock always returns the same time, advanced by hand
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
+var testTime = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+// newBufferLogger returns a JSON logger writing to a buffer at a fixed time
+func newBufferLogger(level LevelEnabler, options ...Option) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	core := NewCore(NewJSONEncoder(NewProductionEncoderConfig()), &buf, level)
+	options = append([]Option{WithClock(&fixedClock{now: testTime})}, options...)
+	return New(core, options...), &buf
+}
+
+// Test level names, parsing and enabling
+func testLevels() bool {
+	if InfoLevel.String() != "info" || DPanicLevel.CapitalString() != "DPANIC" {
+		return false
+	}
+	lvl, err := ParseLevel("WARN")
+	if err != nil || lvl != WarnLevel {
+		return false
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		return false
+	}
+	return WarnLevel.Enabled(ErrorLevel) && !WarnLevel.Enabled(InfoLevel)
+}
+
+// Test JSON output with typed fields
+func testJSONEncoder() bool {
+	logger, buf := newBufferLogger(DebugLevel)
+	logger.Info("request handled",
+		String("method", "GET"),
+		Int("status", 200),
+		Float64("ratio", 0.5),
+		Bool("cached", true),
+		Duration("latency", 1500*time.Millisecond),
+		Error(errors.New("slow upstream")),
+		Error(nil),
+		Any("tags", []string{"a", "b"}),
+	)
+	expected := `{"level":"info","ts":"2024-03-01T12:30:00Z","msg":"request handled",` +
+		`"method":"GET","status":200,"ratio":0.5,"cached":true,"latency":"1.5s",` +
+		`"error":"slow upstream","tags":["a","b"]}` + "\n"
+	return buf.String() == expected
+}
+
+// Test console output
+func testConsoleEncoder() bool {
+	var buf bytes.Buffer
+	core := NewCore(NewConsoleEncoder(NewDevelopmentEncoderConfig()), &buf, DebugLevel)
+	logger := New(core, WithClock(&fixedClock{now: testTime})).Named("api")
+	logger.Warn("disk almost full", Int("percent", 91))
+	logger.Debug("no fields")
+	expected := "2024-03-01T12:30:00.000Z\tWARN\tapi\tdisk almost full\t{\"percent\":91}\n" +
+		"2024-03-01T12:30:00.000Z\tDEBUG\tapi\tno fields\n"
+	return buf.String() == expected
+}
+
+// Test level filtering, including an atomic level
+func testLevelFiltering() bool {
+	level := NewAtomicLevelAt(WarnLevel)
+	logger, buf := newBufferLogger(level)
+	logger.Info("dropped")
+	logger.Warn("kept")
+	level.SetLevel(DebugLevel)
+	logger.Debug("now kept")
+	if strings.Count(buf.String(), "\n") != 2 || strings.Contains(buf.String(), "dropped") {
+		return false
+	}
+	return logger.Level() == DebugLevel && logger.WithOptions(IncreaseLevel(ErrorLevel)).Level() == ErrorLevel
+}
+
+// Test child loggers with fields and names
+func testWithAndNamed() bool {
+	core, logs := NewObserver(DebugLevel)
+	logger := New(core).Named("app").With(String("service", "billing"))
+	child := logger.Named("db").With(Int("shard", 3))
+	child.Info("connected")
+	logger.Info("started")
+
+	entries := logs.All()
+	if len(entries) != 2 || entries[0].LoggerName != "app.db" || entries[1].LoggerName != "app" {
+		return false
+	}
+	ctx := entries[0].ContextMap()
+	if ctx["service"] != "billing" || ctx["shard"] != int64(3) {
+		return false
+	}
+	return len(entries[1].Context) == 1 && New(core, Fields(Bool("x", true))).Core() != core
+}
+
+// Test the observer's filters
+func testObserver() bool {
+	core, logs := NewObserver(InfoLevel)
+	logger := New(core)
+	logger.Debug("ignored")
+	logger.Info("user created", String("user", "alice"))
+	logger.Info("user created", String("user", "bob"))
+	logger.Error("user deleted", String("user", "alice"))
+
+	if logs.Len() != 3 || logs.FilterMessage("user created").Len() != 2 {
+		return false
+	}
+	if logs.FilterField(String("user", "alice")).Len() != 2 || logs.FilterLevelExact(ErrorLevel).Len() != 1 {
+		return false
+	}
+	if logs.FilterMessageSnippet("deleted").Len() != 1 || logs.FilterFieldKey("missing").Len() != 0 {
+		return false
+	}
+	untimed := logs.AllUntimed()
+	if !untimed[0].Time.IsZero() || logs.All()[0].Time.IsZero() {
+		return false
+	}
+	taken := logs.TakeAll()
+	return len(taken) == 3 && logs.Len() == 0
+}
+
+// Test sampling repeated messages
+func testSampling() bool {
+	clock := &fixedClock{now: testTime}
+	core, logs := NewObserver(DebugLevel)
+	logger := New(NewSampler(core, time.Second, 2, 3), WithClock(clock))
+
+	for i := 0; i < 10; i++ {
+		logger.Info("tick", Int("i", i))
+	}
+	logger.Info("other")
+	// First 2, then every 3rd after that: 0, 1, 4, 7
+	var kept []string
+	for _, e := range logs.FilterMessage("tick").All() {
+		kept = append(kept, fmt.Sprint(e.ContextMap()["i"]))
+	}
+	if strings.Join(kept, ",") != "0,1,4,7" || logs.FilterMessage("other").Len() != 1 {
+		return false
+	}
+
+	// A new tick starts counting again
+	clock.now = clock.now.Add(time.Second)
+	logger.With(String("k", "v")).Info("tick")
+	return logs.FilterMessage("tick").Len() == 5
+}
+
+// Test hooks and tee cores
+func testHooksAndTee() bool {
+	var mu sync.Mutex
+	counts := map[Level]int{}
+	jsonCore := NewCore(NewJSONEncoder(NewProductionEncoderConfig()), &bytes.Buffer{}, DebugLevel)
+	errCore, errLogs := NewObserver(ErrorLevel)
+	logger := New(NewTee(jsonCore, errCore), Hooks(func(e Entry) error {
+		mu.Lock()
+		counts[e.Level]++
+		mu.Unlock()
+		return nil
+	}))
+
+	logger.Info("a")
+	logger.Error("b")
+	logger.Error("c")
+	if counts[InfoLevel] != 1 || counts[ErrorLevel] != 2 || errLogs.Len() != 2 {
+		return false
+	}
+
+	// Hook errors are reported on the error output
+	var errOut bytes.Buffer
+	observed, _ := NewObserver(DebugLevel)
+	failing := New(observed, Hooks(func(Entry) error { return errors.New("hook failed") }), ErrorOutput(&errOut))
+	failing.Info("x")
+	NewNop().Error("dropped")
+	return strings.Contains(errOut.String(), "write error: hook failed")
+}
+
+// Test the sugared logger
+func testSugaredLogger() bool {
+	core, logs := NewObserver(DebugLevel)
+	sugar := New(core).Sugar().With("request_id", "r-1")
+	sugar.Infof("processed %d items", 3)
+	sugar.Warnw("retrying", "attempt", 2, Duration("backoff", time.Second), "orphan")
+	sugar.Error("failed: ", errors.New("boom"))
+
+	entries := logs.All()
+	if len(entries) != 3 || entries[0].Message != "processed 3 items" || entries[2].Message != "failed: boom" {
+		return false
+	}
+	ctx := entries[1].ContextMap()
+	return ctx["request_id"] == "r-1" && ctx["attempt"] == int64(2) &&
+		ctx["backoff"] == time.Second && ctx["!BADKEY"] == "orphan" &&
+		sugar.Desugar().Core() != nil
+}
+
+// Test callers point at the logging call site
+func testCaller() bool {
+	core, logs := NewObserver(DebugLevel)
+	logger := New(core, AddCaller())
+	logger.Info("plain")
+	logger.Sugar().Infow("sugared")
+	entries := logs.All()
+	for _, e := range entries {
+		if !e.Caller.Defined || !strings.HasSuffix(e.Caller.File, "test_zap_emulator.go") {
+			return false
+		}
+	}
+	return len(entries) == 2 && strings.HasPrefix(entries[0].Caller.TrimmedPath(), "LogJam/test_zap_emulator.go:")
+}
+
+// Test Panic, DPanic and Fatal
+func testPanicAndFatal() bool {
+	core, logs := NewObserver(DebugLevel)
+	panics := func(fn func()) (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		fn()
+		return
+	}
+
+	exited := false
+	logger := New(core, OnFatal(func() { exited = true }))
+	if !panics(func() { logger.Panic("bad state") }) || panics(func() { logger.DPanic("odd") }) {
+		return false
+	}
+	if !panics(func() { logger.WithOptions(Development()).DPanic("odd") }) {
+		return false
+	}
+	logger.Fatal("giving up")
+	return exited && logs.Len() == 4 && logs.FilterLevelExact(FatalLevel).Len() == 1
+}
+
+// Test the global loggers
+func testGlobals() bool {
+	core, logs := NewObserver(DebugLevel)
+	L().Info("before")
+	restore := ReplaceGlobals(New(core))
+	L().Info("global")
+	S().Infow("sugared global", "k", "v")
+	restore()
+	L().Info("after")
+	return logs.Len() == 2
+}
+
+// Test custom encoder keys; empty keys leave elements out
+func testEncoderKeys() bool {
+	var buf bytes.Buffer
+	cfg := NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	cfg.MessageKey = "message"
+	cfg.LevelKey = "severity"
+	cfg.CapitalLevel = true
+	logger := New(NewCore(NewJSONEncoder(cfg), &buf, DebugLevel)).Named("jobs")
+	logger.Debug("hello", String("who", "world"))
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		return false
+	}
+	return decoded["message"] == "hello" && decoded["severity"] == "DEBUG" &&
+		decoded["logger"] == "jobs" && decoded["who"] == "world" && len(decoded) == 4
+}
+
+func main() {
+	fmt.Println("Running Zap Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Levels", testLevels)
+	runTest("JSON Encoder", testJSONEncoder)
+	runTest("Console Encoder", testConsoleEncoder)
+	runTest("Level Filtering", testLevelFiltering)
+	runTest("With And Named", testWithAndNamed)
+	runTest("Observer", testObserver)
+	runTest("Sampling", testSampling)
+	runTest("Hooks And Tee", testHooksAndTee)
+	runTest("Sugared Logger", testSugaredLogger)
+	runTest("Caller", testCaller)
+	runTest("Panic And Fatal", testPanicAndFatal)
+	runTest("Globals", testGlobals)
+	runTest("Encoder Keys", testEncoderKeys)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
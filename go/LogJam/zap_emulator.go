@@ -0,0 +1,1301 @@
+package main
+
+// Developed by PowerShield, as an alternative to Zap
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Level is a logging priority; higher levels are more important
+type Level int8
+
+const (
+	// DebugLevel logs are verbose and usually disabled in production
+	DebugLevel Level = iota - 1
+	// InfoLevel is the default logging priority
+	InfoLevel
+	// WarnLevel logs are more important than Info but don't need review
+	WarnLevel
+	// ErrorLevel logs are high-priority
+	ErrorLevel
+	// DPanicLevel logs are errors that panic in development
+	DPanicLevel
+	// PanicLevel logs a message, then panics
+	PanicLevel
+	// FatalLevel logs a message, then calls the fatal hook (os.Exit(1))
+	FatalLevel
+)
+
+// String returns the lower-case name of the level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	case DPanicLevel:
+		return "dpanic"
+	case PanicLevel:
+		return "panic"
+	case FatalLevel:
+		return "fatal"
+	}
+	return fmt.Sprintf("Level(%d)", l)
+}
+
+// CapitalString returns the upper-case name of the level
+func (l Level) CapitalString() string {
+	return strings.ToUpper(l.String())
+}
+
+// Enabled reports whether logs at lvl are written by a logger at l
+func (l Level) Enabled(lvl Level) bool {
+	return lvl >= l
+}
+
+// ParseLevel parses a level name such as "info" or "WARN"
+func ParseLevel(text string) (Level, error) {
+	for l := DebugLevel; l <= FatalLevel; l++ {
+		if strings.EqualFold(text, l.String()) {
+			return l, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unrecognized level: %q", text)
+}
+
+// LevelEnabler decides whether a level is logged
+type LevelEnabler interface {
+	Enabled(Level) bool
+}
+
+// LevelEnablerFunc adapts a function to LevelEnabler
+type LevelEnablerFunc func(Level) bool
+
+// Enabled calls f
+func (f LevelEnablerFunc) Enabled(lvl Level) bool {
+	return f(lvl)
+}
+
+// AtomicLevel is a level that can be changed safely while logging, e.g.
+// from an admin endpoint
+type AtomicLevel struct {
+	l *int32
+}
+
+// NewAtomicLevel returns an AtomicLevel at InfoLevel
+func NewAtomicLevel() AtomicLevel {
+	return NewAtomicLevelAt(InfoLevel)
+}
+
+// NewAtomicLevelAt returns an AtomicLevel at l
+func NewAtomicLevelAt(l Level) AtomicLevel {
+	v := int32(l)
+	return AtomicLevel{l: &v}
+}
+
+// Level returns the current level
+func (a AtomicLevel) Level() Level {
+	return Level(atomic.LoadInt32(a.l))
+}
+
+// SetLevel changes the level of every logger using a
+func (a AtomicLevel) SetLevel(l Level) {
+	atomic.StoreInt32(a.l, int32(l))
+}
+
+// Enabled reports whether lvl is at or above the current level
+func (a AtomicLevel) Enabled(lvl Level) bool {
+	return a.Level().Enabled(lvl)
+}
+
+// String returns the name of the current level
+func (a AtomicLevel) String() string {
+	return a.Level().String()
+}
+
+// FieldType tells encoders how to read a Field
+type FieldType uint8
+
+const (
+	UnknownType FieldType = iota
+	StringType
+	Int64Type
+	Float64Type
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	StringerType
+	ReflectType
+	SkipType
+)
+
+// Field is a strongly typed key/value pair added to a log entry
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface interface{}
+}
+
+// String constructs a field with a string value
+func String(key, val string) Field {
+	return Field{Key: key, Type: StringType, String: val}
+}
+
+// Int constructs a field with an int value
+func Int(key string, val int) Field {
+	return Int64(key, int64(val))
+}
+
+// Int64 constructs a field with an int64 value
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Type: Int64Type, Integer: val}
+}
+
+// Float64 constructs a field with a float64 value
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Type: Float64Type, Interface: val}
+}
+
+// Bool constructs a field with a bool value
+func Bool(key string, val bool) Field {
+	var n int64
+	if val {
+		n = 1
+	}
+	return Field{Key: key, Type: BoolType, Integer: n}
+}
+
+// Duration constructs a field with a time.Duration value
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Integer: int64(val)}
+}
+
+// Time constructs a field with a time.Time value
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, Type: TimeType, Interface: val}
+}
+
+// Error constructs a field named "error" holding err; a nil err is
+// skipped
+func Error(err error) Field {
+	return NamedError("error", err)
+}
+
+// NamedError constructs a field holding err under key; a nil err is
+// skipped
+func NamedError(key string, err error) Field {
+	if err == nil {
+		return Skip()
+	}
+	return Field{Key: key, Type: ErrorType, Interface: err}
+}
+
+// Stringer constructs a field holding val.String()
+func Stringer(key string, val fmt.Stringer) Field {
+	return Field{Key: key, Type: StringerType, Interface: val}
+}
+
+// Any constructs a field for any value, picking the typed constructor
+// when there is one and encoding other values as JSON
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case int32:
+		return Int64(key, int64(v))
+	case float64:
+		return Float64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		return NamedError(key, v)
+	case fmt.Stringer:
+		return Stringer(key, v)
+	}
+	return Field{Key: key, Type: ReflectType, Interface: value}
+}
+
+// Skip constructs a field that encoders leave out
+func Skip() Field {
+	return Field{Type: SkipType}
+}
+
+// Value returns the field's value as a Go value: errors and Stringers
+// become their strings
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case Int64Type:
+		return f.Integer
+	case BoolType:
+		return f.Integer == 1
+	case DurationType:
+		return time.Duration(f.Integer)
+	case ErrorType:
+		return f.Interface.(error).Error()
+	case StringerType:
+		return f.Interface.(fmt.Stringer).String()
+	}
+	return f.Interface
+}
+
+// Equals reports whether two fields have the same key, type and value
+func (f Field) Equals(other Field) bool {
+	if f.Key != other.Key || f.Type != other.Type {
+		return false
+	}
+	if f.Type == ReflectType {
+		a, errA := json.Marshal(f.Interface)
+		b, errB := json.Marshal(other.Interface)
+		return errA == nil && errB == nil && bytes.Equal(a, b)
+	}
+	return f.Value() == other.Value()
+}
+
+// EntryCaller is the place in the code a log entry came from
+type EntryCaller struct {
+	Defined bool
+	File    string
+	Line    int
+}
+
+// TrimmedPath returns the caller as dir/file.go:line
+func (c EntryCaller) TrimmedPath() string {
+	if !c.Defined {
+		return "undefined"
+	}
+	dir, file := filepath.Split(c.File)
+	return fmt.Sprintf("%s/%s:%d", filepath.Base(dir), file, c.Line)
+}
+
+// Entry is a log message before its fields are encoded
+type Entry struct {
+	Level      Level
+	Time       time.Time
+	LoggerName string
+	Message    string
+	Caller     EntryCaller
+}
+
+// EncoderConfig names the keys encoders write; an empty key leaves the
+// element out
+type EncoderConfig struct {
+	MessageKey string
+	LevelKey   string
+	TimeKey    string
+	NameKey    string
+	CallerKey  string
+	// TimeLayout is the time.Format layout for the entry time and Time
+	// fields
+	TimeLayout string
+	// CapitalLevel writes "INFO" instead of "info"
+	CapitalLevel bool
+}
+
+// NewProductionEncoderConfig returns the config used by NewProduction
+func NewProductionEncoderConfig() EncoderConfig {
+	return EncoderConfig{
+		MessageKey: "msg",
+		LevelKey:   "level",
+		TimeKey:    "ts",
+		NameKey:    "logger",
+		CallerKey:  "caller",
+		TimeLayout: time.RFC3339Nano,
+	}
+}
+
+// NewDevelopmentEncoderConfig returns the config used by NewDevelopment
+func NewDevelopmentEncoderConfig() EncoderConfig {
+	return EncoderConfig{
+		MessageKey:   "M",
+		LevelKey:     "L",
+		TimeKey:      "T",
+		NameKey:      "N",
+		CallerKey:    "C",
+		TimeLayout:   "2006-01-02T15:04:05.000Z0700",
+		CapitalLevel: true,
+	}
+}
+
+// Encoder turns an entry and its fields into one line of output
+type Encoder interface {
+	EncodeEntry(ent Entry, fields []Field) ([]byte, error)
+}
+
+type jsonEncoder struct {
+	cfg EncoderConfig
+}
+
+// NewJSONEncoder returns an encoder writing one JSON object per entry,
+// with the entry's elements first and the fields after them in order
+func NewJSONEncoder(cfg EncoderConfig) Encoder {
+	return &jsonEncoder{cfg: cfg}
+}
+
+// EncodeEntry encodes ent and fields as a JSON object and a newline
+func (e *jsonEncoder) EncodeEntry(ent Entry, fields []Field) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	add := func(key string, value interface{}) error {
+		if key == "" {
+			return nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("field %q: %v", key, err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(data)
+		return nil
+	}
+
+	add(e.cfg.LevelKey, e.cfg.levelString(ent.Level))
+	if !ent.Time.IsZero() {
+		add(e.cfg.TimeKey, ent.Time.Format(e.cfg.TimeLayout))
+	}
+	if ent.LoggerName != "" {
+		add(e.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		add(e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	add(e.cfg.MessageKey, ent.Message)
+	for _, f := range fields {
+		if f.Type == SkipType {
+			continue
+		}
+		if err := add(f.Key, e.cfg.fieldValue(f)); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+type consoleEncoder struct {
+	cfg EncoderConfig
+}
+
+// NewConsoleEncoder returns an encoder for people: the entry's elements
+// separated by tabs, then the fields as a JSON object
+func NewConsoleEncoder(cfg EncoderConfig) Encoder {
+	return &consoleEncoder{cfg: cfg}
+}
+
+// EncodeEntry encodes ent and fields as a tab-separated line
+func (e *consoleEncoder) EncodeEntry(ent Entry, fields []Field) ([]byte, error) {
+	var parts []string
+	if e.cfg.TimeKey != "" && !ent.Time.IsZero() {
+		parts = append(parts, ent.Time.Format(e.cfg.TimeLayout))
+	}
+	if e.cfg.LevelKey != "" {
+		parts = append(parts, e.cfg.levelString(ent.Level))
+	}
+	if e.cfg.NameKey != "" && ent.LoggerName != "" {
+		parts = append(parts, ent.LoggerName)
+	}
+	if e.cfg.CallerKey != "" && ent.Caller.Defined {
+		parts = append(parts, ent.Caller.TrimmedPath())
+	}
+	if e.cfg.MessageKey != "" {
+		parts = append(parts, ent.Message)
+	}
+
+	context := EncoderConfig{TimeLayout: e.cfg.TimeLayout}
+	data, err := NewJSONEncoder(context).EncodeEntry(Entry{}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if obj := strings.TrimSpace(string(data)); obj != "{}" {
+		parts = append(parts, obj)
+	}
+	return []byte(strings.Join(parts, "\t") + "\n"), nil
+}
+
+// levelString formats a level as configured
+func (cfg EncoderConfig) levelString(l Level) string {
+	if cfg.CapitalLevel {
+		return l.CapitalString()
+	}
+	return l.String()
+}
+
+// fieldValue returns the value to encode for f: durations as strings
+// such as "1.5s", times in TimeLayout
+func (cfg EncoderConfig) fieldValue(f Field) interface{} {
+	switch f.Type {
+	case DurationType:
+		return time.Duration(f.Integer).String()
+	case TimeType:
+		return f.Interface.(time.Time).Format(cfg.TimeLayout)
+	}
+	return f.Value()
+}
+
+// Core is where entries are filtered, encoded and written; cores can be
+// wrapped to add sampling, hooks or fan-out
+type Core interface {
+	LevelEnabler
+	// With returns a core that adds fields to every entry
+	With(fields []Field) Core
+	// Write writes an entry the caller has checked is enabled
+	Write(ent Entry, fields []Field) error
+	// Sync flushes buffered output
+	Sync() error
+}
+
+type ioCore struct {
+	LevelEnabler
+	enc    Encoder
+	out    io.Writer
+	mu     *sync.Mutex
+	fields []Field
+}
+
+// NewCore returns a core writing entries enabled by enab to out with enc.
+// Writes are serialized, so out needn't be safe for concurrent use.
+func NewCore(enc Encoder, out io.Writer, enab LevelEnabler) Core {
+	return &ioCore{LevelEnabler: enab, enc: enc, out: out, mu: &sync.Mutex{}}
+}
+
+// With returns a copy of the core with fields added
+func (c *ioCore) With(fields []Field) Core {
+	clone := *c
+	clone.fields = append(append([]Field(nil), c.fields...), fields...)
+	return &clone
+}
+
+// Write encodes and writes an entry
+func (c *ioCore) Write(ent Entry, fields []Field) error {
+	data, err := c.enc.EncodeEntry(ent, append(append([]Field(nil), c.fields...), fields...))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.out.Write(data)
+	return err
+}
+
+// Sync calls Sync on the writer if it has one, as *os.File does
+func (c *ioCore) Sync() error {
+	if s, ok := c.out.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+type nopCore struct{}
+
+// NewNopCore returns a core that drops everything
+func NewNopCore() Core {
+	return nopCore{}
+}
+
+func (nopCore) Enabled(Level) bool         { return false }
+func (n nopCore) With([]Field) Core        { return n }
+func (nopCore) Write(Entry, []Field) error { return nil }
+func (nopCore) Sync() error                { return nil }
+
+type multiCore []Core
+
+// NewTee returns a core writing each entry to every core that enables its
+// level, e.g. JSON to a file and errors to the console
+func NewTee(cores ...Core) Core {
+	return multiCore(cores)
+}
+
+// Enabled reports whether any core enables lvl
+func (mc multiCore) Enabled(lvl Level) bool {
+	for _, c := range mc {
+		if c.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+// With adds fields to every core
+func (mc multiCore) With(fields []Field) Core {
+	clone := make(multiCore, len(mc))
+	for i, c := range mc {
+		clone[i] = c.With(fields)
+	}
+	return clone
+}
+
+// Write writes to the cores enabling ent.Level, returning their errors
+func (mc multiCore) Write(ent Entry, fields []Field) error {
+	var errs []error
+	for _, c := range mc {
+		if c.Enabled(ent.Level) {
+			if err := c.Write(ent, fields); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sync syncs every core
+func (mc multiCore) Sync() error {
+	var errs []error
+	for _, c := range mc {
+		if err := c.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type samplerCounter struct {
+	resetAt time.Time
+	n       int
+}
+
+type sampler struct {
+	Core
+	tick       time.Duration
+	first      int
+	thereafter int
+	mu         *sync.Mutex
+	counts     map[string]*samplerCounter
+}
+
+// NewSampler returns a core that caps repeated entries: within each tick,
+// the first entries with a given level and message are written, then
+// only every thereafter-th one (none if thereafter is 0). Ticks follow
+// the entries' times, so a logger with WithClock samples
+// deterministically.
+func NewSampler(core Core, tick time.Duration, first, thereafter int) Core {
+	return &sampler{
+		Core:       core,
+		tick:       tick,
+		first:      first,
+		thereafter: thereafter,
+		mu:         &sync.Mutex{},
+		counts:     make(map[string]*samplerCounter),
+	}
+}
+
+// With adds fields to the wrapped core; counts are shared with s
+func (s *sampler) With(fields []Field) Core {
+	clone := *s
+	clone.Core = s.Core.With(fields)
+	return &clone
+}
+
+// Write writes the entry unless it is sampled away
+func (s *sampler) Write(ent Entry, fields []Field) error {
+	if !s.sample(ent) {
+		return nil
+	}
+	return s.Core.Write(ent, fields)
+}
+
+// sample counts ent and reports whether it should be written
+func (s *sampler) sample(ent Entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := ent.Level.String() + "\x00" + ent.Message
+	counter, ok := s.counts[key]
+	if !ok {
+		counter = &samplerCounter{}
+		s.counts[key] = counter
+	}
+	if !ent.Time.Before(counter.resetAt) {
+		counter.resetAt = ent.Time.Add(s.tick)
+		counter.n = 0
+	}
+	counter.n++
+	if counter.n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (counter.n-s.first)%s.thereafter == 0
+}
+
+type hookedCore struct {
+	Core
+	hooks []func(Entry) error
+}
+
+// RegisterHooks returns a core that calls hooks with every entry written
+// to core, e.g. to count errors; hook errors are returned from Write
+func RegisterHooks(core Core, hooks ...func(Entry) error) Core {
+	return &hookedCore{Core: core, hooks: hooks}
+}
+
+// With adds fields to the wrapped core
+func (h *hookedCore) With(fields []Field) Core {
+	return &hookedCore{Core: h.Core.With(fields), hooks: h.hooks}
+}
+
+// Write writes the entry, then runs the hooks
+func (h *hookedCore) Write(ent Entry, fields []Field) error {
+	errs := []error{h.Core.Write(ent, fields)}
+	for _, hook := range h.hooks {
+		errs = append(errs, hook(ent))
+	}
+	return errors.Join(errs...)
+}
+
+// LoggedEntry is an entry recorded by an observer core
+type LoggedEntry struct {
+	Entry
+	Context []Field
+}
+
+// ContextMap returns the entry's fields as a map of their values
+func (e LoggedEntry) ContextMap() map[string]interface{} {
+	m := make(map[string]interface{}, len(e.Context))
+	for _, f := range e.Context {
+		if f.Type != SkipType {
+			m[f.Key] = f.Value()
+		}
+	}
+	return m
+}
+
+// ObservedLogs is the in-memory record of an observer core, for asserting
+// on logs in tests
+type ObservedLogs struct {
+	mu   sync.RWMutex
+	logs []LoggedEntry
+}
+
+type observerCore struct {
+	LevelEnabler
+	logs   *ObservedLogs
+	fields []Field
+}
+
+// NewObserver returns a core that records entries enabled by enab,
+// and the logs it records them in
+func NewObserver(enab LevelEnabler) (Core, *ObservedLogs) {
+	logs := &ObservedLogs{}
+	return &observerCore{LevelEnabler: enab, logs: logs}, logs
+}
+
+// With returns a copy of the core with fields added
+func (o *observerCore) With(fields []Field) Core {
+	return &observerCore{
+		LevelEnabler: o.LevelEnabler,
+		logs:         o.logs,
+		fields:       append(append([]Field(nil), o.fields...), fields...),
+	}
+}
+
+// Write records the entry
+func (o *observerCore) Write(ent Entry, fields []Field) error {
+	all := append(append([]Field(nil), o.fields...), fields...)
+	o.logs.add(LoggedEntry{Entry: ent, Context: all})
+	return nil
+}
+
+// Sync does nothing
+func (o *observerCore) Sync() error {
+	return nil
+}
+
+func (o *ObservedLogs) add(e LoggedEntry) {
+	o.mu.Lock()
+	o.logs = append(o.logs, e)
+	o.mu.Unlock()
+}
+
+// Len returns the number of recorded entries
+func (o *ObservedLogs) Len() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return len(o.logs)
+}
+
+// All returns a copy of the recorded entries
+func (o *ObservedLogs) All() []LoggedEntry {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return append([]LoggedEntry(nil), o.logs...)
+}
+
+// AllUntimed returns the recorded entries with their times zeroed, for
+// comparing whole entries
+func (o *ObservedLogs) AllUntimed() []LoggedEntry {
+	entries := o.All()
+	for i := range entries {
+		entries[i].Time = time.Time{}
+	}
+	return entries
+}
+
+// TakeAll returns the recorded entries and clears them
+func (o *ObservedLogs) TakeAll() []LoggedEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := o.logs
+	o.logs = nil
+	return entries
+}
+
+// Filter returns the entries for which keep returns true
+func (o *ObservedLogs) Filter(keep func(LoggedEntry) bool) *ObservedLogs {
+	filtered := &ObservedLogs{}
+	for _, e := range o.All() {
+		if keep(e) {
+			filtered.logs = append(filtered.logs, e)
+		}
+	}
+	return filtered
+}
+
+// FilterMessage returns the entries with exactly this message
+func (o *ObservedLogs) FilterMessage(msg string) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool { return e.Message == msg })
+}
+
+// FilterMessageSnippet returns the entries whose message contains snippet
+func (o *ObservedLogs) FilterMessageSnippet(snippet string) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool { return strings.Contains(e.Message, snippet) })
+}
+
+// FilterLevelExact returns the entries at exactly this level
+func (o *ObservedLogs) FilterLevelExact(level Level) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool { return e.Level == level })
+}
+
+// FilterField returns the entries with a field equal to field
+func (o *ObservedLogs) FilterField(field Field) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool {
+		for _, f := range e.Context {
+			if f.Equals(field) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterFieldKey returns the entries with a field named key
+func (o *ObservedLogs) FilterFieldKey(key string) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool {
+		for _, f := range e.Context {
+			if f.Key == key {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Clock tells the logger the time of each entry
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Logger is a fast, strongly typed structured logger. Loggers are safe
+// for concurrent use; With and Named return new loggers sharing the core.
+type Logger struct {
+	core        Core
+	name        string
+	addCaller   bool
+	callerSkip  int
+	development bool
+	clock       Clock
+	onFatal     func()
+	errorOutput io.Writer
+}
+
+// Option configures a Logger
+type Option func(*Logger)
+
+// New returns a logger writing to core; a nil core gives a no-op logger
+func New(core Core, options ...Option) *Logger {
+	if core == nil {
+		core = NewNopCore()
+	}
+	l := &Logger{
+		core:        core,
+		clock:       systemClock{},
+		onFatal:     func() { os.Exit(1) },
+		errorOutput: os.Stderr,
+	}
+	return l.WithOptions(options...)
+}
+
+// NewNop returns a logger that never writes anything
+func NewNop() *Logger {
+	return New(nil)
+}
+
+// NewProduction returns a logger writing JSON at InfoLevel and above to
+// stderr, with callers, sampling the same message to 100 per second
+// after the first 100
+func NewProduction(options ...Option) (*Logger, error) {
+	core := NewCore(NewJSONEncoder(NewProductionEncoderConfig()), os.Stderr, InfoLevel)
+	core = NewSampler(core, time.Second, 100, 100)
+	return New(core, append([]Option{AddCaller()}, options...)...), nil
+}
+
+// NewDevelopment returns a logger writing console lines at DebugLevel and
+// above to stderr, with callers; DPanic logs panic
+func NewDevelopment(options ...Option) (*Logger, error) {
+	core := NewCore(NewConsoleEncoder(NewDevelopmentEncoderConfig()), os.Stderr, DebugLevel)
+	return New(core, append([]Option{AddCaller(), Development()}, options...)...), nil
+}
+
+// NewExample returns a logger writing JSON at DebugLevel and above to
+// stdout without times or callers, for examples and tests with stable
+// output
+func NewExample(options ...Option) *Logger {
+	cfg := NewProductionEncoderConfig()
+	cfg.TimeKey = ""
+	core := NewCore(NewJSONEncoder(cfg), os.Stdout, DebugLevel)
+	return New(core, options...)
+}
+
+// Fields adds fields to every entry of the logger
+func Fields(fields ...Field) Option {
+	return func(l *Logger) {
+		l.core = l.core.With(fields)
+	}
+}
+
+// Hooks calls hooks with every entry the logger writes
+func Hooks(hooks ...func(Entry) error) Option {
+	return func(l *Logger) {
+		l.core = RegisterHooks(l.core, hooks...)
+	}
+}
+
+// WrapCore replaces the logger's core with f(core)
+func WrapCore(f func(Core) Core) Option {
+	return func(l *Logger) {
+		l.core = f(l.core)
+	}
+}
+
+// AddCaller records the file and line that logged each entry
+func AddCaller() Option {
+	return func(l *Logger) {
+		l.addCaller = true
+	}
+}
+
+// AddCallerSkip skips skip more stack frames when finding the caller,
+// for helpers that wrap the logger
+func AddCallerSkip(skip int) Option {
+	return func(l *Logger) {
+		l.callerSkip += skip
+	}
+}
+
+// Development makes DPanic logs panic
+func Development() Option {
+	return func(l *Logger) {
+		l.development = true
+	}
+}
+
+// WithClock sets the clock entries are timed with
+func WithClock(clock Clock) Option {
+	return func(l *Logger) {
+		l.clock = clock
+	}
+}
+
+// OnFatal replaces os.Exit(1), run after Fatal logs, e.g. so tests can
+// check Fatal paths
+func OnFatal(action func()) Option {
+	return func(l *Logger) {
+		l.onFatal = action
+	}
+}
+
+// ErrorOutput sets where the logger reports its own errors, such as
+// failed writes (default os.Stderr)
+func ErrorOutput(w io.Writer) Option {
+	return func(l *Logger) {
+		l.errorOutput = w
+	}
+}
+
+// IncreaseLevel raises the logger's minimum level; it can't lower it
+func IncreaseLevel(lvl LevelEnabler) Option {
+	return func(l *Logger) {
+		l.core = &levelFilterCore{Core: l.core, level: lvl}
+	}
+}
+
+type levelFilterCore struct {
+	Core
+	level LevelEnabler
+}
+
+func (c *levelFilterCore) Enabled(lvl Level) bool {
+	return c.level.Enabled(lvl) && c.Core.Enabled(lvl)
+}
+
+func (c *levelFilterCore) With(fields []Field) Core {
+	return &levelFilterCore{Core: c.Core.With(fields), level: c.level}
+}
+
+// WithOptions returns a copy of the logger with options applied
+func (l *Logger) WithOptions(options ...Option) *Logger {
+	clone := *l
+	for _, opt := range options {
+		opt(&clone)
+	}
+	return &clone
+}
+
+// With returns a child logger adding fields to every entry
+func (l *Logger) With(fields ...Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	clone := *l
+	clone.core = l.core.With(fields)
+	return &clone
+}
+
+// Named returns a child logger with name appended to the logger's name,
+// separated by a period
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	clone := *l
+	if l.name == "" {
+		clone.name = name
+	} else {
+		clone.name = l.name + "." + name
+	}
+	return &clone
+}
+
+// Name returns the logger's name
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Core returns the logger's core
+func (l *Logger) Core() Core {
+	return l.core
+}
+
+// Level returns the lowest level the logger writes, or FatalLevel+1 if
+// it writes none
+func (l *Logger) Level() Level {
+	for lvl := DebugLevel; lvl <= FatalLevel; lvl++ {
+		if l.core.Enabled(lvl) {
+			return lvl
+		}
+	}
+	return FatalLevel + 1
+}
+
+// Sync flushes buffered output; call it before the program exits
+func (l *Logger) Sync() error {
+	return l.core.Sync()
+}
+
+// Debug logs a message at DebugLevel
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(DebugLevel, msg, fields)
+}
+
+// Info logs a message at InfoLevel
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(InfoLevel, msg, fields)
+}
+
+// Warn logs a message at WarnLevel
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.log(WarnLevel, msg, fields)
+}
+
+// Error logs a message at ErrorLevel
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(ErrorLevel, msg, fields)
+}
+
+// DPanic logs a message at DPanicLevel, then panics if the logger is in
+// development mode
+func (l *Logger) DPanic(msg string, fields ...Field) {
+	l.log(DPanicLevel, msg, fields)
+}
+
+// Panic logs a message at PanicLevel, then panics
+func (l *Logger) Panic(msg string, fields ...Field) {
+	l.log(PanicLevel, msg, fields)
+}
+
+// Fatal logs a message at FatalLevel, then runs the fatal hook, which
+// calls os.Exit(1) unless replaced with OnFatal
+func (l *Logger) Fatal(msg string, fields ...Field) {
+	l.log(FatalLevel, msg, fields)
+}
+
+// Log logs a message at lvl
+func (l *Logger) Log(lvl Level, msg string, fields ...Field) {
+	l.log(lvl, msg, fields)
+}
+
+// log writes the entry if its level is enabled, then panics or exits for
+// the levels that do so even when disabled. It must be called directly
+// from the exported logging method, which the caller lookup relies on.
+func (l *Logger) log(lvl Level, msg string, fields []Field) {
+	if l.core.Enabled(lvl) {
+		ent := Entry{
+			Level:      lvl,
+			Time:       l.clock.Now(),
+			LoggerName: l.name,
+			Message:    msg,
+		}
+		if l.addCaller {
+			_, file, line, ok := runtime.Caller(2 + l.callerSkip)
+			ent.Caller = EntryCaller{Defined: ok, File: file, Line: line}
+		}
+		if err := l.core.Write(ent, fields); err != nil {
+			fmt.Fprintf(l.errorOutput, "%v write error: %v\n", ent.Time, err)
+		}
+	}
+
+	switch {
+	case lvl == PanicLevel, lvl == DPanicLevel && l.development:
+		panic(msg)
+	case lvl == FatalLevel:
+		l.onFatal()
+	}
+}
+
+// SugaredLogger wraps a Logger with a slower, loosely typed API:
+// printf-style methods and methods taking alternating keys and values
+type SugaredLogger struct {
+	base *Logger
+}
+
+// Sugar wraps the logger in a SugaredLogger
+func (l *Logger) Sugar() *SugaredLogger {
+	return &SugaredLogger{base: l.WithOptions(AddCallerSkip(1))}
+}
+
+// Desugar returns the underlying Logger
+func (s *SugaredLogger) Desugar() *Logger {
+	return s.base.WithOptions(AddCallerSkip(-1))
+}
+
+// With returns a child logger adding loosely typed key/value pairs; see
+// Infow for how they are read
+func (s *SugaredLogger) With(args ...interface{}) *SugaredLogger {
+	return &SugaredLogger{base: s.base.With(sweetenFields(args)...)}
+}
+
+// Named returns a child logger with name appended to the logger's name
+func (s *SugaredLogger) Named(name string) *SugaredLogger {
+	return &SugaredLogger{base: s.base.Named(name)}
+}
+
+// Sync flushes buffered output
+func (s *SugaredLogger) Sync() error {
+	return s.base.Sync()
+}
+
+// Debug logs its arguments, formatted like fmt.Sprint, at DebugLevel
+func (s *SugaredLogger) Debug(args ...interface{}) { s.log(DebugLevel, "", args, nil) }
+
+// Info logs its arguments, formatted like fmt.Sprint, at InfoLevel
+func (s *SugaredLogger) Info(args ...interface{}) { s.log(InfoLevel, "", args, nil) }
+
+// Warn logs its arguments, formatted like fmt.Sprint, at WarnLevel
+func (s *SugaredLogger) Warn(args ...interface{}) { s.log(WarnLevel, "", args, nil) }
+
+// Error logs its arguments, formatted like fmt.Sprint, at ErrorLevel
+func (s *SugaredLogger) Error(args ...interface{}) { s.log(ErrorLevel, "", args, nil) }
+
+// DPanic logs its arguments at DPanicLevel, panicking in development
+func (s *SugaredLogger) DPanic(args ...interface{}) { s.log(DPanicLevel, "", args, nil) }
+
+// Panic logs its arguments at PanicLevel, then panics
+func (s *SugaredLogger) Panic(args ...interface{}) { s.log(PanicLevel, "", args, nil) }
+
+// Fatal logs its arguments at FatalLevel, then runs the fatal hook
+func (s *SugaredLogger) Fatal(args ...interface{}) { s.log(FatalLevel, "", args, nil) }
+
+// Debugf logs a printf-style message at DebugLevel
+func (s *SugaredLogger) Debugf(template string, args ...interface{}) {
+	s.log(DebugLevel, template, args, nil)
+}
+
+// Infof logs a printf-style message at InfoLevel
+func (s *SugaredLogger) Infof(template string, args ...interface{}) {
+	s.log(InfoLevel, template, args, nil)
+}
+
+// Warnf logs a printf-style message at WarnLevel
+func (s *SugaredLogger) Warnf(template string, args ...interface{}) {
+	s.log(WarnLevel, template, args, nil)
+}
+
+// Errorf logs a printf-style message at ErrorLevel
+func (s *SugaredLogger) Errorf(template string, args ...interface{}) {
+	s.log(ErrorLevel, template, args, nil)
+}
+
+// DPanicf logs a printf-style message at DPanicLevel
+func (s *SugaredLogger) DPanicf(template string, args ...interface{}) {
+	s.log(DPanicLevel, template, args, nil)
+}
+
+// Panicf logs a printf-style message at PanicLevel, then panics
+func (s *SugaredLogger) Panicf(template string, args ...interface{}) {
+	s.log(PanicLevel, template, args, nil)
+}
+
+// Fatalf logs a printf-style message at FatalLevel, then runs the fatal
+// hook
+func (s *SugaredLogger) Fatalf(template string, args ...interface{}) {
+	s.log(FatalLevel, template, args, nil)
+}
+
+// Debugw logs a message with key/value pairs at DebugLevel
+func (s *SugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	s.log(DebugLevel, msg, nil, keysAndValues)
+}
+
+// Infow logs a message at InfoLevel with context given as alternating
+// keys and values, e.g. Infow("fetched", "url", url, "attempt", 3).
+// Field values are used as they are. A key that isn't a string, or a
+// final key without a value, is logged under "!BADKEY".
+func (s *SugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	s.log(InfoLevel, msg, nil, keysAndValues)
+}
+
+// Warnw logs a message with key/value pairs at WarnLevel
+func (s *SugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	s.log(WarnLevel, msg, nil, keysAndValues)
+}
+
+// Errorw logs a message with key/value pairs at ErrorLevel
+func (s *SugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	s.log(ErrorLevel, msg, nil, keysAndValues)
+}
+
+// DPanicw logs a message with key/value pairs at DPanicLevel
+func (s *SugaredLogger) DPanicw(msg string, keysAndValues ...interface{}) {
+	s.log(DPanicLevel, msg, nil, keysAndValues)
+}
+
+// Panicw logs a message with key/value pairs at PanicLevel, then panics
+func (s *SugaredLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	s.log(PanicLevel, msg, nil, keysAndValues)
+}
+
+// Fatalw logs a message with key/value pairs at FatalLevel, then runs
+// the fatal hook
+func (s *SugaredLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	s.log(FatalLevel, msg, nil, keysAndValues)
+}
+
+// log formats the message and hands it to the base logger; like
+// Logger.log it must be called directly from the exported method
+func (s *SugaredLogger) log(lvl Level, template string, fmtArgs []interface{}, context []interface{}) {
+	if lvl < DPanicLevel && !s.base.core.Enabled(lvl) {
+		return
+	}
+	msg := template
+	if template == "" && len(fmtArgs) > 0 {
+		msg = fmt.Sprint(fmtArgs...)
+	} else if template != "" && len(fmtArgs) > 0 {
+		msg = fmt.Sprintf(template, fmtArgs...)
+	}
+	s.base.log(lvl, msg, sweetenFields(context))
+}
+
+// sweetenFields turns alternating keys and values into fields
+func sweetenFields(args []interface{}) []Field {
+	fields := make([]Field, 0, len(args)/2)
+	for i := 0; i < len(args); i++ {
+		if f, ok := args[i].(Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		key, ok := args[i].(string)
+		if !ok || i == len(args)-1 {
+			fields = append(fields, Any("!BADKEY", args[i]))
+			continue
+		}
+		fields = append(fields, Any(key, args[i+1]))
+		i++
+	}
+	return fields
+}
+
+var (
+	globalMu     sync.RWMutex
+	globalLogger = NewNop()
+	globalSugar  = globalLogger.Sugar()
+)
+
+// L returns the global Logger, a no-op logger until ReplaceGlobals
+func L() *Logger {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalLogger
+}
+
+// S returns the global SugaredLogger
+func S() *SugaredLogger {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalSugar
+}
+
+// ReplaceGlobals replaces the global loggers and returns a function
+// restoring the previous ones
+func ReplaceGlobals(logger *Logger) func() {
+	globalMu.Lock()
+	prev := globalLogger
+	globalLogger = logger
+	globalSugar = logger.Sugar()
+	globalMu.Unlock()
+	return func() { ReplaceGlobals(prev) }
+}
@@ -0,0 +1,445 @@
+package main
+
+// Developed by PowerShield, as an alternative to fsnotify
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Op describes a set of file operations
+type Op uint32
+
+// The operations an Event can report
+const (
+	// Create is a new file or directory
+	Create Op = 1 << iota
+	// Write is a change to the contents of a file
+	Write
+	// Remove is a file or directory that went away
+	Remove
+	// Rename is a file or directory moved to another name; the new name
+	// is reported as a Create
+	Rename
+	// Chmod is a change to the permission bits
+	Chmod
+)
+
+var opNames = []struct {
+	op   Op
+	name string
+}{
+	{Create, "CREATE"},
+	{Write, "WRITE"},
+	{Remove, "REMOVE"},
+	{Rename, "RENAME"},
+	{Chmod, "CHMOD"},
+}
+
+// String returns the names of the operations in o joined with "|", such
+// as "CREATE|WRITE"
+func (o Op) String() string {
+	var names []string
+	for _, n := range opNames {
+		if o.Has(n.op) {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "[no events]"
+	}
+	return strings.Join(names, "|")
+}
+
+// Has reports whether o includes h
+func (o Op) Has(h Op) bool {
+	return o&h != 0
+}
+
+// Event is an operation on a file or directory
+type Event struct {
+	// Name is the path of the file or directory, as given to Add or
+	// joined onto it
+	Name string
+	// Op is the set of operations that happened
+	Op Op
+}
+
+// Has reports whether the event includes op
+func (e Event) Has(op Op) bool {
+	return e.Op.Has(op)
+}
+
+// String returns the operations and the quoted name
+func (e Event) String() string {
+	return fmt.Sprintf("%-13s %q", e.Op.String(), e.Name)
+}
+
+var (
+	// ErrNonExistentWatch is returned by Remove for a path that is not
+	// watched
+	ErrNonExistentWatch = errors.New("fsnotify: can't remove non-existent watch")
+	// ErrClosed is returned when the watcher is used after Close
+	ErrClosed = errors.New("fsnotify: watcher already closed")
+)
+
+// DefaultPollInterval is how often NewWatcher's watchers look for changes
+const DefaultPollInterval = 100 * time.Millisecond
+
+// recursiveSuffix marks a path given to Add as watched with everything
+// below it
+const recursiveSuffix = string(filepath.Separator) + "..."
+
+// watch is a path given to Add, with what was there at the last poll
+type watch struct {
+	path      string
+	recursive bool
+	files     map[string]os.FileInfo
+}
+
+// Watcher reports changes to files and directories on its Events
+// channel. Watching a directory reports changes to the files directly
+// in it; adding "dir/..." reports changes anywhere below dir, including
+// in directories created later. Changes are found by comparing what is
+// on disk every poll interval, so several writes within one interval
+// are one event.
+type Watcher struct {
+	// Events receives each change. It must be read, or polling stops.
+	Events chan Event
+	// Errors receives errors met while polling
+	Errors chan error
+
+	interval time.Duration
+
+	mu      sync.Mutex
+	watches map[string]*watch
+	notify  func(name string, op uint32)
+	onError func(err error)
+	closed  bool
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// NewWatcher returns a watcher that polls every DefaultPollInterval
+func NewWatcher() (*Watcher, error) {
+	return NewPollingWatcher(DefaultPollInterval)
+}
+
+// NewBufferedWatcher returns a watcher whose Events channel holds up to
+// sz events
+func NewBufferedWatcher(sz uint) (*Watcher, error) {
+	return newWatcher(DefaultPollInterval, sz)
+}
+
+// NewPollingWatcher returns a watcher that polls every interval
+func NewPollingWatcher(interval time.Duration) (*Watcher, error) {
+	return newWatcher(interval, 0)
+}
+
+func newWatcher(interval time.Duration, sz uint) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("fsnotify: poll interval must be positive, got %v", interval)
+	}
+	w := &Watcher{
+		Events:   make(chan Event, sz),
+		Errors:   make(chan error),
+		interval: interval,
+		watches:  make(map[string]*watch),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Add starts watching the named file or directory. A name ending in
+// "/..." watches the directory before it recursively. Adding a path
+// again changes nothing.
+func (w *Watcher) Add(name string) error {
+	path, recursive := splitRecursive(name)
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if recursive && !info.IsDir() {
+		return fmt.Errorf("fsnotify: %s: not a directory", path)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if existing, ok := w.watches[path]; ok {
+		if recursive && !existing.recursive {
+			existing.recursive = true
+			existing.files, _ = scan(path, true)
+		}
+		return nil
+	}
+	files, err := scan(path, recursive)
+	if err != nil {
+		return err
+	}
+	w.watches[path] = &watch{path: path, recursive: recursive, files: files}
+	return nil
+}
+
+// Remove stops watching the named path, which must have been added. The
+// "/..." suffix is optional.
+func (w *Watcher) Remove(name string) error {
+	path, _ := splitRecursive(name)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	if _, ok := w.watches[path]; !ok {
+		return fmt.Errorf("%w: %s", ErrNonExistentWatch, path)
+	}
+	delete(w.watches, path)
+	return nil
+}
+
+// WatchList returns the watched paths in order, with "/..." after the
+// recursive ones
+func (w *Watcher) WatchList() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	list := make([]string, 0, len(w.watches))
+	for path, wt := range w.watches {
+		if wt.recursive {
+			path += recursiveSuffix
+		}
+		list = append(list, path)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Notify makes the watcher call events with the name and Op bits of each
+// change, and errors with each error, instead of sending them on the
+// Events and Errors channels. The functions run on the polling
+// goroutine. This is an emulator extension.
+func (w *Watcher) Notify(events func(name string, op uint32), errs func(err error)) {
+	w.mu.Lock()
+	w.notify = events
+	w.onError = errs
+	w.mu.Unlock()
+}
+
+// Close stops watching and closes the Events and Errors channels once
+// polling has stopped. It is safe to call more than once, but not from a
+// function given to Notify.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.watches = nil
+	w.mu.Unlock()
+
+	close(w.done)
+	<-w.stopped
+	close(w.Events)
+	close(w.Errors)
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if !w.poll() {
+				return
+			}
+		}
+	}
+}
+
+// poll compares every watch with what is on disk now and delivers the
+// differences. It returns false once the watcher is closed.
+func (w *Watcher) poll() bool {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return false
+	}
+	var events []Event
+	var errs []error
+	seen := make(map[Event]bool)
+	paths := make([]string, 0, len(w.watches))
+	for path := range w.watches {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		wt := w.watches[path]
+		files, err := scan(wt.path, wt.recursive)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		for _, e := range diff(wt.files, files) {
+			if !seen[e] {
+				seen[e] = true
+				events = append(events, e)
+			}
+		}
+		wt.files = files
+		// A watched path that went away is no longer watched
+		if _, ok := files[wt.path]; !ok {
+			delete(w.watches, path)
+		}
+	}
+	notify, onError := w.notify, w.onError
+	w.mu.Unlock()
+
+	for _, err := range errs {
+		if onError != nil {
+			onError(err)
+			continue
+		}
+		select {
+		case w.Errors <- err:
+		case <-w.done:
+			return false
+		}
+	}
+	for _, e := range events {
+		if notify != nil {
+			notify(e.Name, uint32(e.Op))
+			continue
+		}
+		select {
+		case w.Events <- e:
+		case <-w.done:
+			return false
+		}
+	}
+	return true
+}
+
+// scan returns what is at path: the path itself and, for a directory,
+// its entries, or everything below it if recursive. A missing path gives
+// an empty map.
+func scan(path string, recursive bool) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	info, err := os.Lstat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return files, nil
+		}
+		return files, err
+	}
+	files[path] = info
+	if !info.IsDir() {
+		return files, nil
+	}
+	if !recursive {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return files, err
+		}
+		for _, entry := range entries {
+			name := filepath.Join(path, entry.Name())
+			if info, err := os.Lstat(name); err == nil {
+				files[name] = info
+			}
+		}
+		return files, nil
+	}
+	err = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Entries removed during the walk are reported next time
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if info, err := d.Info(); err == nil {
+			files[name] = info
+		}
+		return nil
+	})
+	return files, err
+}
+
+// diff returns the events that turn before into after. A removed path
+// that is the same file as a created one is reported as renamed.
+func diff(before, after map[string]os.FileInfo) []Event {
+	var created, removed []string
+	var events []Event
+	for name, info := range after {
+		old, ok := before[name]
+		if !ok {
+			created = append(created, name)
+			continue
+		}
+		var op Op
+		if !info.IsDir() && (!info.ModTime().Equal(old.ModTime()) || info.Size() != old.Size()) {
+			op |= Write
+		}
+		if info.Mode() != old.Mode() {
+			op |= Chmod
+		}
+		if op != 0 {
+			events = append(events, Event{Name: name, Op: op})
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(created)
+	sort.Strings(removed)
+
+	for _, name := range removed {
+		op := Remove
+		for _, newName := range created {
+			if os.SameFile(before[name], after[newName]) {
+				op = Rename
+				break
+			}
+		}
+		events = append(events, Event{Name: name, Op: op})
+	}
+	for _, name := range created {
+		events = append(events, Event{Name: name, Op: Create})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
+	return events
+}
+
+// splitRecursive separates a trailing "/..." from name
+func splitRecursive(name string) (string, bool) {
+	name = filepath.Clean(name)
+	if name == "..." {
+		return ".", true
+	}
+	if strings.HasSuffix(name, recursiveSuffix) {
+		return strings.TrimSuffix(name, recursiveSuffix), true
+	}
+	return name, false
+}
@@ -0,0 +1,292 @@
+package main
+
+// Developed by PowerShield, as an alternative to fsnotify
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// newTestWatcher returns a watcher that never polls by itself, for tests
+// that call poll directly, and a directory to watch
+func newTestWatcher() (*Watcher, string) {
+	w, _ := newWatcher(time.Hour, 64)
+	dir, _ := os.MkdirTemp("", "fsnotify-test")
+	return w, dir
+}
+
+// pollEvents polls once and returns the events as "OP name" strings,
+// with names relative to dir
+func pollEvents(w *Watcher, dir string) string {
+	w.poll()
+	var got []string
+	for {
+		select {
+		case e := <-w.Events:
+			rel, _ := filepath.Rel(dir, e.Name)
+			got = append(got, e.Op.String()+" "+filepath.ToSlash(rel))
+		default:
+			return strings.Join(got, ", ")
+		}
+	}
+}
+
+// touch writes data to a file and moves its modification time along, so
+// writes are seen however coarse the file system's clock is
+func touch(path, data string) {
+	os.WriteFile(path, []byte(data), 0644)
+	later := time.Now().Add(time.Duration(len(data)+1) * time.Second)
+	os.Chtimes(path, later, later)
+}
+
+func testOpStrings() bool {
+	return Create.String() == "CREATE" && (Write|Chmod).String() == "WRITE|CHMOD" &&
+		(Remove|Rename).String() == "REMOVE|RENAME" && Op(0).String() == "[no events]"
+}
+
+func testEventHasAndString() bool {
+	e := Event{Name: "/tmp/app.yaml", Op: Write | Chmod}
+	return e.Has(Write) && e.Has(Chmod) && !e.Has(Create) &&
+		e.String() == `WRITE|CHMOD   "/tmp/app.yaml"`
+}
+
+func testCreateAndWrite() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	existing := filepath.Join(dir, "existing.txt")
+	touch(existing, "one")
+	if err := w.Add(dir); err != nil {
+		return false
+	}
+	if pollEvents(w, dir) != "" {
+		return false
+	}
+	touch(filepath.Join(dir, "new.txt"), "hello")
+	touch(existing, "two!")
+	return pollEvents(w, dir) == "WRITE existing.txt, CREATE new.txt" && pollEvents(w, dir) == ""
+}
+
+func testRemove() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	path := filepath.Join(dir, "old.log")
+	touch(path, "x")
+	w.Add(dir)
+	os.Remove(path)
+	return pollEvents(w, dir) == "REMOVE old.log"
+}
+
+func testRename() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	touch(filepath.Join(dir, "draft.md"), "text")
+	w.Add(dir)
+	os.Rename(filepath.Join(dir, "draft.md"), filepath.Join(dir, "final.md"))
+	return pollEvents(w, dir) == "RENAME draft.md, CREATE final.md"
+}
+
+func testChmod() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	path := filepath.Join(dir, "run.sh")
+	touch(path, "echo hi")
+	w.Add(dir)
+	os.Chmod(path, 0755)
+	return pollEvents(w, dir) == "CHMOD run.sh"
+}
+
+func testWatchFile() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	config := filepath.Join(dir, "config.json")
+	touch(config, "{}")
+	w.Add(config)
+	// Neighbours of a watched file are not reported
+	touch(filepath.Join(dir, "other.json"), "{}")
+	touch(config, `{"a": 1}`)
+	return pollEvents(w, dir) == "WRITE config.json"
+}
+
+func testNotRecursiveByDefault() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	w.Add(dir)
+	touch(filepath.Join(sub, "deep.txt"), "x")
+	if pollEvents(w, dir) != "" {
+		return false
+	}
+	os.Mkdir(filepath.Join(dir, "other"), 0755)
+	return pollEvents(w, dir) == "CREATE other"
+}
+
+func testRecursive() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	sub := filepath.Join(dir, "a", "b")
+	os.MkdirAll(sub, 0755)
+	if err := w.Add(filepath.Join(dir, "...")); err != nil {
+		return false
+	}
+	touch(filepath.Join(sub, "deep.txt"), "x")
+	if pollEvents(w, dir) != "CREATE a/b/deep.txt" {
+		return false
+	}
+	// Directories created later are watched too
+	os.Mkdir(filepath.Join(dir, "c"), 0755)
+	if pollEvents(w, dir) != "CREATE c" {
+		return false
+	}
+	touch(filepath.Join(dir, "c", "late.txt"), "y")
+	return pollEvents(w, dir) == "CREATE c/late.txt"
+}
+
+func testRemoveWatch() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	w.Add(dir)
+	w.Add(filepath.Join(sub, "..."))
+	w.Add(dir)
+	if strings.Join(w.WatchList(), ",") != dir+","+sub+string(filepath.Separator)+"..." {
+		return false
+	}
+	if err := w.Remove(sub); err != nil {
+		return false
+	}
+	touch(filepath.Join(sub, "ignored.txt"), "x")
+	if pollEvents(w, dir) != "" {
+		return false
+	}
+	err := w.Remove(sub)
+	return errors.Is(err, ErrNonExistentWatch) && len(w.WatchList()) == 1
+}
+
+func testAddErrors() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	if err := w.Add(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	file := filepath.Join(dir, "file.txt")
+	touch(file, "x")
+	if err := w.Add(filepath.Join(file, "...")); err == nil {
+		return false
+	}
+	w.Close()
+	return w.Add(dir) == ErrClosed && w.WatchList() == nil && w.Remove(dir) == nil
+}
+
+func testWatchedPathRemoved() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	touch(filepath.Join(sub, "f.txt"), "x")
+	w.Add(sub)
+	os.RemoveAll(sub)
+	if pollEvents(w, dir) != "REMOVE sub, REMOVE sub/f.txt" {
+		return false
+	}
+	// The watch goes with the path, and a new directory there is not seen
+	os.Mkdir(sub, 0755)
+	return len(w.WatchList()) == 0 && pollEvents(w, dir) == ""
+}
+
+func testPollingInterval() bool {
+	if _, err := NewPollingWatcher(0); err == nil {
+		return false
+	}
+	w, err := NewPollingWatcher(10 * time.Millisecond)
+	if err != nil {
+		return false
+	}
+	defer w.Close()
+	dir, _ := os.MkdirTemp("", "fsnotify-test")
+	defer os.RemoveAll(dir)
+	w.Add(dir)
+	path := filepath.Join(dir, "live.txt")
+	touch(path, "x")
+	select {
+	case e := <-w.Events:
+		return e == Event{Name: path, Op: Create}
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+func testNotify() bool {
+	w, dir := newTestWatcher()
+	defer os.RemoveAll(dir)
+	defer w.Close()
+	var got []string
+	w.Notify(func(name string, op uint32) {
+		got = append(got, Op(op).String()+" "+filepath.Base(name))
+	}, func(err error) {})
+	w.Add(dir)
+	touch(filepath.Join(dir, "a.txt"), "x")
+	if pollEvents(w, dir) != "" {
+		return false
+	}
+	return strings.Join(got, ",") == "CREATE a.txt"
+}
+
+func testClose() bool {
+	w, err := NewWatcher()
+	if err != nil {
+		return false
+	}
+	if w.Close() != nil || w.Close() != nil {
+		return false
+	}
+	_, eventsOpen := <-w.Events
+	_, errorsOpen := <-w.Errors
+	return !eventsOpen && !errorsOpen
+}
+
+func main() {
+	fmt.Println("Running fsnotify Emulator Tests...")
+	fmt.Println("==================================")
+
+	runTest("Op Strings", testOpStrings)
+	runTest("Event Has and String", testEventHasAndString)
+	runTest("Create and Write", testCreateAndWrite)
+	runTest("Remove", testRemove)
+	runTest("Rename", testRename)
+	runTest("Chmod", testChmod)
+	runTest("Watch File", testWatchFile)
+	runTest("Not Recursive by Default", testNotRecursiveByDefault)
+	runTest("Recursive", testRecursive)
+	runTest("Remove Watch", testRemoveWatch)
+	runTest("Add Errors", testAddErrors)
+	runTest("Watched Path Removed", testWatchedPathRemoved)
+	runTest("Polling Interval", testPollingInterval)
+	runTest("Notify", testNotify)
+	runTest("Close", testClose)
+
+	fmt.Println("==================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,693 @@
+package main
+
+// Developed by PowerShield, as an alternative to Masterminds/semver
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidSemVer is returned for strings that aren't versions
+	ErrInvalidSemVer = errors.New("Invalid Semantic Version")
+	// ErrEmptyString is returned for empty versions
+	ErrEmptyString = errors.New("Version string empty")
+	// ErrInvalidCharacters is returned for prereleases and metadata with
+	// characters other than letters, digits and hyphens
+	ErrInvalidCharacters = errors.New("Invalid characters in version")
+	// ErrSegmentStartsZero is returned for numbers with leading zeros
+	ErrSegmentStartsZero = errors.New("Version segment starts with 0")
+	// ErrInvalidMetadata is returned for invalid build metadata
+	ErrInvalidMetadata = errors.New("Invalid Metadata string")
+	// ErrInvalidPrerelease is returned for invalid prereleases
+	ErrInvalidPrerelease = errors.New("Invalid Prerelease string")
+)
+
+// versionRegex accepts a leading v and missing minor and patch numbers
+var versionRegex = regexp.MustCompile(`^v?([0-9]+)(\.[0-9]+)?(\.[0-9]+)?` +
+	`(-([0-9A-Za-z\-]+(\.[0-9A-Za-z\-]+)*))?` +
+	`(\+([0-9A-Za-z\-]+(\.[0-9A-Za-z\-]+)*))?$`)
+
+var identifierRegex = regexp.MustCompile(`^[0-9A-Za-z\-]+$`)
+
+// Version is a semantic version: major.minor.patch, an optional
+// prerelease and optional build metadata
+type Version struct {
+	major, minor, patch uint64
+	pre                 string
+	metadata            string
+	original            string
+}
+
+// NewVersion parses a version, allowing a leading "v" and missing minor
+// and patch numbers, as tags often have them
+func NewVersion(v string) (*Version, error) {
+	if v == "" {
+		return nil, ErrEmptyString
+	}
+	m := versionRegex.FindStringSubmatch(v)
+	if m == nil {
+		return nil, ErrInvalidSemVer
+	}
+	sv := &Version{metadata: m[8], pre: m[5], original: v}
+	var err error
+	if sv.major, err = strconv.ParseUint(m[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("Error parsing version segment: %s", err)
+	}
+	if m[2] != "" {
+		if sv.minor, err = strconv.ParseUint(m[2][1:], 10, 64); err != nil {
+			return nil, fmt.Errorf("Error parsing version segment: %s", err)
+		}
+	}
+	if m[3] != "" {
+		if sv.patch, err = strconv.ParseUint(m[3][1:], 10, 64); err != nil {
+			return nil, fmt.Errorf("Error parsing version segment: %s", err)
+		}
+	}
+	if sv.pre != "" {
+		if err := validatePrerelease(sv.pre); err != nil {
+			return nil, err
+		}
+	}
+	if sv.metadata != "" {
+		if err := validateMetadata(sv.metadata); err != nil {
+			return nil, err
+		}
+	}
+	return sv, nil
+}
+
+// StrictNewVersion parses a version exactly as the specification has
+// it: three numbers without leading zeros and no "v"
+func StrictNewVersion(v string) (*Version, error) {
+	if v == "" {
+		return nil, ErrEmptyString
+	}
+	core := v
+	if i := strings.IndexAny(core, "-+"); i >= 0 {
+		core = core[:i]
+	}
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidSemVer
+	}
+	for _, p := range parts {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return nil, ErrInvalidSemVer
+		}
+		if len(p) > 1 && p[0] == '0' {
+			return nil, ErrSegmentStartsZero
+		}
+	}
+	return NewVersion(v)
+}
+
+// MustParse parses a version, panicking if it is invalid
+func MustParse(v string) *Version {
+	sv, err := NewVersion(v)
+	if err != nil {
+		panic(err)
+	}
+	return sv
+}
+
+// New returns a version from its parts
+func New(major, minor, patch uint64, pre, metadata string) *Version {
+	v := &Version{major: major, minor: minor, patch: patch, pre: pre, metadata: metadata}
+	v.original = v.String()
+	return v
+}
+
+func validatePrerelease(p string) error {
+	for _, id := range strings.Split(p, ".") {
+		if !identifierRegex.MatchString(id) {
+			return ErrInvalidPrerelease
+		}
+		if isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return ErrSegmentStartsZero
+		}
+	}
+	return nil
+}
+
+func validateMetadata(m string) error {
+	for _, id := range strings.Split(m, ".") {
+		if !identifierRegex.MatchString(id) {
+			return ErrInvalidMetadata
+		}
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// Major returns the major version
+func (v *Version) Major() uint64 { return v.major }
+
+// Minor returns the minor version
+func (v *Version) Minor() uint64 { return v.minor }
+
+// Patch returns the patch version
+func (v *Version) Patch() uint64 { return v.patch }
+
+// Prerelease returns the prerelease, such as "beta.1"
+func (v *Version) Prerelease() string { return v.pre }
+
+// Metadata returns the build metadata, such as "build.42"
+func (v *Version) Metadata() string { return v.metadata }
+
+// Original returns the string the version was parsed from
+func (v *Version) Original() string { return v.original }
+
+// String returns the version in full, without a "v"
+func (v *Version) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d.%d.%d", v.major, v.minor, v.patch)
+	if v.pre != "" {
+		buf.WriteString("-" + v.pre)
+	}
+	if v.metadata != "" {
+		buf.WriteString("+" + v.metadata)
+	}
+	return buf.String()
+}
+
+// IncPatch returns the next patch version. A prerelease's next patch
+// version is its release.
+func (v Version) IncPatch() Version {
+	vNext := v
+	if v.pre != "" {
+		vNext.metadata = ""
+		vNext.pre = ""
+	} else {
+		vNext.metadata = ""
+		vNext.pre = ""
+		vNext.patch = v.patch + 1
+	}
+	vNext.original = vNext.String()
+	return vNext
+}
+
+// IncMinor returns the next minor version
+func (v Version) IncMinor() Version {
+	vNext := v
+	vNext.metadata = ""
+	vNext.pre = ""
+	vNext.patch = 0
+	vNext.minor = v.minor + 1
+	vNext.original = vNext.String()
+	return vNext
+}
+
+// IncMajor returns the next major version
+func (v Version) IncMajor() Version {
+	vNext := v
+	vNext.metadata = ""
+	vNext.pre = ""
+	vNext.patch = 0
+	vNext.minor = 0
+	vNext.major = v.major + 1
+	vNext.original = vNext.String()
+	return vNext
+}
+
+// SetPrerelease returns the version with another prerelease
+func (v Version) SetPrerelease(prerelease string) (Version, error) {
+	vNext := v
+	if prerelease != "" {
+		if err := validatePrerelease(prerelease); err != nil {
+			return vNext, err
+		}
+	}
+	vNext.pre = prerelease
+	vNext.original = vNext.String()
+	return vNext, nil
+}
+
+// SetMetadata returns the version with other build metadata
+func (v Version) SetMetadata(metadata string) (Version, error) {
+	vNext := v
+	if metadata != "" {
+		if err := validateMetadata(metadata); err != nil {
+			return vNext, err
+		}
+	}
+	vNext.metadata = metadata
+	vNext.original = vNext.String()
+	return vNext, nil
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than
+// o. Build metadata doesn't count.
+func (v *Version) Compare(o *Version) int {
+	if d := compareSegment(v.major, o.major); d != 0 {
+		return d
+	}
+	if d := compareSegment(v.minor, o.minor); d != 0 {
+		return d
+	}
+	if d := compareSegment(v.patch, o.patch); d != 0 {
+		return d
+	}
+	return comparePrerelease(v.pre, o.pre)
+}
+
+// LessThan reports whether v is lower than o
+func (v *Version) LessThan(o *Version) bool { return v.Compare(o) < 0 }
+
+// GreaterThan reports whether v is higher than o
+func (v *Version) GreaterThan(o *Version) bool { return v.Compare(o) > 0 }
+
+// Equal reports whether v and o are the same version, ignoring metadata
+func (v *Version) Equal(o *Version) bool { return v.Compare(o) == 0 }
+
+func compareSegment(v, o uint64) int {
+	switch {
+	case v < o:
+		return -1
+	case v > o:
+		return 1
+	}
+	return 0
+}
+
+// comparePrerelease orders prereleases: a release is higher than its
+// prereleases, numeric identifiers are lower than others and compared as
+// numbers, and a shorter list of otherwise equal identifiers is lower
+func comparePrerelease(v, o string) int {
+	if v == o {
+		return 0
+	}
+	if v == "" {
+		return 1
+	}
+	if o == "" {
+		return -1
+	}
+	vs, os := strings.Split(v, "."), strings.Split(o, ".")
+	for i := 0; i < len(vs) && i < len(os); i++ {
+		if d := comparePrePart(vs[i], os[i]); d != 0 {
+			return d
+		}
+	}
+	switch {
+	case len(vs) < len(os):
+		return -1
+	case len(vs) > len(os):
+		return 1
+	}
+	return 0
+}
+
+func comparePrePart(s, o string) int {
+	if s == o {
+		return 0
+	}
+	sNum, oNum := isNumeric(s), isNumeric(o)
+	switch {
+	case sNum && oNum:
+		si, _ := strconv.ParseUint(s, 10, 64)
+		oi, _ := strconv.ParseUint(o, 10, 64)
+		return compareSegment(si, oi)
+	case sNum:
+		return -1
+	case oNum:
+		return 1
+	case s < o:
+		return -1
+	}
+	return 1
+}
+
+// MarshalJSON writes the version as a string
+func (v Version) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON reads a version string
+func (v *Version) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	temp, err := NewVersion(s)
+	if err != nil {
+		return err
+	}
+	*v = *temp
+	return nil
+}
+
+// MarshalText writes the version, for encodings such as YAML and TOML
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText reads a version
+func (v *Version) UnmarshalText(text []byte) error {
+	temp, err := NewVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = *temp
+	return nil
+}
+
+// Collection sorts versions with sort.Sort
+type Collection []*Version
+
+func (c Collection) Len() int           { return len(c) }
+func (c Collection) Less(i, j int) bool { return c[i].LessThan(c[j]) }
+func (c Collection) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// Constraints
+
+// wildcard says which parts of a constraint's version are wild, such as
+// the patch of "1.2.x" or "1.2"
+type wildcard int
+
+const (
+	wildNone wildcard = iota
+	wildPatch
+	wildMinor
+	wildMajor
+)
+
+// constraint is one comparison, such as ">=1.2.3" or "^2"
+type constraint struct {
+	op    string
+	con   *Version
+	wild  wildcard
+	orig  string
+	input string
+}
+
+// Constraints is a set of comparisons: groups separated by "||", any of
+// which may match, each made of comparisons which must all match
+type Constraints struct {
+	constraints [][]*constraint
+}
+
+var (
+	hyphenRange  = regexp.MustCompile(`(v?[0-9xX*][0-9A-Za-z.+\-*]*)\s+-\s+(v?[0-9xX*][0-9A-Za-z.+\-*]*)`)
+	opSpace      = regexp.MustCompile(`(>=|=>|<=|=<|!=|~>|~|\^|>|<|=)\s+`)
+	constraintRe = regexp.MustCompile(`^(>=|=>|<=|=<|!=|~>|~|\^|>|<|=)?` +
+		`v?([0-9]+|[xX*])(\.([0-9]+|[xX*]))?(\.([0-9]+|[xX*]))?` +
+		`(-([0-9A-Za-z\-]+(\.[0-9A-Za-z\-]+)*))?` +
+		`(\+([0-9A-Za-z\-]+(\.[0-9A-Za-z\-]+)*))?$`)
+)
+
+// NewConstraint parses constraints such as ">= 1.2, < 2", "^1.4",
+// "~2.1.0", "1.2.x", "1.2 - 1.4.5" or "^1 || ^2". Comparisons in a group
+// are separated by commas or spaces.
+func NewConstraint(c string) (*Constraints, error) {
+	c = strings.TrimSpace(c)
+	if c == "" {
+		return nil, fmt.Errorf("improper constraint: %s", c)
+	}
+	cs := &Constraints{}
+	for _, group := range strings.Split(c, "||") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			return nil, fmt.Errorf("improper constraint: %s", c)
+		}
+		group = hyphenRange.ReplaceAllString(group, ">=$1 <=$2")
+		group = opSpace.ReplaceAllString(group, "$1")
+		var and []*constraint
+		for _, part := range strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			pc, err := parseConstraint(part)
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, pc)
+		}
+		cs.constraints = append(cs.constraints, and)
+	}
+	return cs, nil
+}
+
+func isX(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
+
+func parseConstraint(c string) (*constraint, error) {
+	m := constraintRe.FindStringSubmatch(c)
+	if m == nil {
+		return nil, fmt.Errorf("improper constraint: %s", c)
+	}
+	op := m[1]
+	switch op {
+	case "=>":
+		op = ">="
+	case "=<":
+		op = "<="
+	case "~>":
+		op = "~"
+	case "=":
+		op = ""
+	}
+
+	// Missing parts are wild, as are x, X and *; parts after a wild part
+	// are wild too
+	major, minor, patch := m[2], m[4], m[6]
+	wild := wildNone
+	switch {
+	case isX(major):
+		wild = wildMajor
+	case minor == "" || isX(minor):
+		wild = wildMinor
+	case patch == "" || isX(patch):
+		wild = wildPatch
+	}
+	if wild != wildNone && m[7] != "" {
+		return nil, fmt.Errorf("improper constraint: %s", c)
+	}
+	zero := func(s string, w wildcard) string {
+		if wild >= w {
+			return "0"
+		}
+		return s
+	}
+	ver := zero(major, wildMajor) + "." + zero(minor, wildMinor) + "." + zero(patch, wildPatch) + m[7] + m[10]
+	con, err := NewVersion(ver)
+	if err != nil {
+		return nil, err
+	}
+	return &constraint{op: op, con: con, wild: wild, orig: strings.TrimLeft(c[len(m[1]):], "v"), input: c}, nil
+}
+
+// upper returns the lowest version above a wild constraint's range, such
+// as 1.3.0 for 1.2.x; nil means there is none
+func (c *constraint) upper() *Version {
+	switch c.wild {
+	case wildPatch:
+		return New(c.con.major, c.con.minor+1, 0, "", "")
+	case wildMinor:
+		return New(c.con.major+1, 0, 0, "", "")
+	}
+	return nil
+}
+
+// within reports whether v is in a wild constraint's range, or is its
+// version
+func (c *constraint) within(v *Version) bool {
+	if c.wild == wildMajor {
+		return true
+	}
+	if c.wild == wildNone {
+		return v.Equal(c.con)
+	}
+	return !v.LessThan(c.con) && v.LessThan(c.upper())
+}
+
+// check reports whether v satisfies the comparison. Prereleases only
+// satisfy groups that mention a prerelease.
+func (c *constraint) check(v *Version, includePre bool) bool {
+	if v.pre != "" && !includePre {
+		return false
+	}
+	switch c.op {
+	case "":
+		return c.within(v)
+	case "!=":
+		return !c.within(v)
+	case ">":
+		if c.wild == wildMajor {
+			return false
+		}
+		if c.wild != wildNone {
+			return !v.LessThan(c.upper())
+		}
+		return v.GreaterThan(c.con)
+	case ">=":
+		return !v.LessThan(c.con)
+	case "<":
+		if c.wild == wildMajor {
+			return false
+		}
+		return v.LessThan(c.con)
+	case "<=":
+		if c.wild == wildMajor {
+			return true
+		}
+		if c.wild != wildNone {
+			return v.LessThan(c.upper())
+		}
+		return !v.GreaterThan(c.con)
+	case "~":
+		if v.LessThan(c.con) {
+			return false
+		}
+		switch c.wild {
+		case wildMajor:
+			return true
+		case wildMinor:
+			return v.major == c.con.major
+		}
+		return v.major == c.con.major && v.minor == c.con.minor
+	case "^":
+		if v.LessThan(c.con) {
+			return false
+		}
+		switch {
+		case c.wild == wildMajor:
+			return true
+		case c.con.major > 0 || c.wild == wildMinor:
+			return v.major == c.con.major
+		case c.con.minor > 0 || c.wild == wildPatch:
+			return v.major == 0 && v.minor == c.con.minor
+		}
+		return v.major == 0 && v.minor == 0 && v.patch == c.con.patch
+	}
+	return false
+}
+
+// message explains why v fails the comparison
+func (c *constraint) message(v *Version) string {
+	var format string
+	switch c.op {
+	case "":
+		format = "%s is not equal to %s"
+	case "!=":
+		format = "%s is equal to %s"
+	case ">":
+		format = "%s is less than or equal to %s"
+	case ">=":
+		format = "%s is less than %s"
+	case "<":
+		format = "%s is greater than or equal to %s"
+	case "<=":
+		format = "%s is greater than %s"
+	case "~":
+		format = "%s does not have same major and minor version as %s"
+	case "^":
+		format = "%s does not have same major version as %s"
+	}
+	return fmt.Sprintf(format, v, c.orig)
+}
+
+func groupIncludesPre(group []*constraint) bool {
+	for _, c := range group {
+		if c.con.pre != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reports whether v satisfies the constraints
+func (cs Constraints) Check(v *Version) bool {
+	if v == nil {
+		return false
+	}
+	for _, group := range cs.constraints {
+		includePre := groupIncludesPre(group)
+		ok := true
+		for _, c := range group {
+			if !c.check(v, includePre) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate is Check, with the reasons v fails each group
+func (cs Constraints) Validate(v *Version) (bool, []error) {
+	var errs []error
+	for _, group := range cs.constraints {
+		includePre := groupIncludesPre(group)
+		ok := true
+		for _, c := range group {
+			if v.pre != "" && !includePre {
+				errs = append(errs, fmt.Errorf("%s is a prerelease version and the constraint is only looking for release versions", v))
+				ok = false
+				break
+			}
+			if !c.check(v, includePre) {
+				errs = append(errs, errors.New(c.message(v)))
+				ok = false
+			}
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, errs
+}
+
+// String returns the constraints, with hyphen ranges spelled out
+func (cs Constraints) String() string {
+	groups := make([]string, len(cs.constraints))
+	for i, group := range cs.constraints {
+		parts := make([]string, len(group))
+		for j, c := range group {
+			parts[j] = c.input
+		}
+		groups[i] = strings.Join(parts, " ")
+	}
+	return strings.Join(groups, " || ")
+}
+
+// MarshalText writes the constraints
+func (cs Constraints) MarshalText() ([]byte, error) {
+	return []byte(cs.String()), nil
+}
+
+// UnmarshalText reads constraints
+func (cs *Constraints) UnmarshalText(text []byte) error {
+	temp, err := NewConstraint(string(text))
+	if err != nil {
+		return err
+	}
+	*cs = *temp
+	return nil
+}
+
+// Helpers for release tooling
+
+// Latest returns the highest version satisfying cs, or nil. Versions that
+// don't parse, such as other tags, are skipped.
+func (cs Constraints) Latest(versions []string) *Version {
+	var best *Version
+	for _, s := range versions {
+		v, err := NewVersion(s)
+		if err != nil || !cs.Check(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best = v
+		}
+	}
+	return best
+}
@@ -0,0 +1,372 @@
+package main
+
+// Developed by PowerShield, as an alternative to Masterminds/semver
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// checks reports whether each version satisfies the constraint as
+// expected
+func checks(constraint string, want map[string]bool) bool {
+	c, err := NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	for v, expected := range want {
+		if c.Check(MustParse(v)) != expected {
+			fmt.Printf("  %s against %q: expected %v\n", v, constraint, expected)
+			return false
+		}
+	}
+	return true
+}
+
+func testParsing() bool {
+	v, err := NewVersion("1.2.3-beta.1+build.345")
+	if err != nil {
+		return false
+	}
+	if v.Major() != 1 || v.Minor() != 2 || v.Patch() != 3 ||
+		v.Prerelease() != "beta.1" || v.Metadata() != "build.345" ||
+		v.String() != "1.2.3-beta.1+build.345" {
+		return false
+	}
+
+	// Tags may have a v and missing parts; String fills them in
+	v, err = NewVersion("v2.1")
+	if err != nil || v.String() != "2.1.0" || v.Original() != "v2.1" {
+		return false
+	}
+	v, err = NewVersion("3")
+	if err != nil || v.String() != "3.0.0" {
+		return false
+	}
+	return New(1, 0, 0, "rc.1", "").String() == "1.0.0-rc.1"
+}
+
+func testInvalidVersions() (ok bool) {
+	for _, s := range []string{"1.2.3.4", "1.2.beta", "one", "1.2.3-", "1.2.3+", "1.2.3-be$ta"} {
+		if _, err := NewVersion(s); err == nil {
+			return false
+		}
+	}
+	if _, err := NewVersion(""); err != ErrEmptyString {
+		return false
+	}
+	if _, err := NewVersion("1.2.3-beta.01"); err != ErrSegmentStartsZero {
+		return false
+	}
+	if _, err := NewVersion("1.2.3+build.01"); err != nil {
+		return false
+	}
+
+	defer func() { ok = recover() != nil }()
+	MustParse("not-a-version")
+	return false
+}
+
+func testStrictParsing() bool {
+	if _, err := StrictNewVersion("1.2.3-alpha+001"); err != nil {
+		return false
+	}
+	for s, want := range map[string]error{
+		"v1.2.3": ErrInvalidSemVer,
+		"1.2":    ErrInvalidSemVer,
+		"01.2.3": ErrSegmentStartsZero,
+		"1.02.3": ErrSegmentStartsZero,
+	} {
+		if _, err := StrictNewVersion(s); err != want {
+			return false
+		}
+	}
+	return true
+}
+
+func testComparison() bool {
+	a, b := MustParse("1.2.3"), MustParse("1.10.0")
+	if !a.LessThan(b) || !b.GreaterThan(a) || a.Compare(b) != -1 || b.Compare(a) != 1 {
+		return false
+	}
+
+	// Metadata doesn't count; a v doesn't either
+	if !MustParse("1.2.3+build.1").Equal(MustParse("v1.2.3+build.2")) {
+		return false
+	}
+
+	// A release is higher than its prereleases
+	return MustParse("1.0.0-rc.1").LessThan(MustParse("1.0.0")) &&
+		MustParse("1.0.0").GreaterThan(MustParse("1.0.0-rc.1"))
+}
+
+func testPrereleasePrecedence() bool {
+	// The order from the semver specification
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		if !MustParse(ordered[i]).LessThan(MustParse(ordered[i+1])) {
+			fmt.Printf("  %s should be lower than %s\n", ordered[i], ordered[i+1])
+			return false
+		}
+	}
+	return true
+}
+
+func testSorting() bool {
+	raw := []string{"1.2.3", "1.0", "1.3", "2.0.0", "0.4.2", "1.0.0-rc.1", "1.2.3-beta"}
+	vs := make([]*Version, len(raw))
+	for i, r := range raw {
+		vs[i] = MustParse(r)
+	}
+	sort.Sort(Collection(vs))
+	var got []string
+	for _, v := range vs {
+		got = append(got, v.Original())
+	}
+	if strings.Join(got, " ") != "0.4.2 1.0.0-rc.1 1.0 1.2.3-beta 1.2.3 1.3 2.0.0" {
+		return false
+	}
+
+	// Descending, for "newest first" lists
+	sort.Sort(sort.Reverse(Collection(vs)))
+	return vs[0].Original() == "2.0.0" && vs[len(vs)-1].Original() == "0.4.2"
+}
+
+func testIncrementing() bool {
+	v := MustParse("1.2.3-beta.1+build")
+	if p := v.IncPatch(); p.String() != "1.2.3" {
+		return false
+	}
+	if p := MustParse("1.2.3").IncPatch(); p.String() != "1.2.4" {
+		return false
+	}
+	if m := v.IncMinor(); m.String() != "1.3.0" {
+		return false
+	}
+	if m := v.IncMajor(); m.String() != "2.0.0" || m.Original() != "2.0.0" {
+		return false
+	}
+
+	next := MustParse("1.2.3").IncMinor()
+	rc, err := next.SetPrerelease("rc.1")
+	if err != nil || rc.String() != "1.3.0-rc.1" {
+		return false
+	}
+	built, err := rc.SetMetadata("sha.5114f85")
+	if err != nil || built.String() != "1.3.0-rc.1+sha.5114f85" {
+		return false
+	}
+	if _, err := rc.SetPrerelease("rc..1"); err != ErrInvalidPrerelease {
+		return false
+	}
+	if _, err := rc.SetMetadata("a+b"); err != ErrInvalidMetadata {
+		return false
+	}
+	// The original is unchanged
+	return v.String() == "1.2.3-beta.1+build"
+}
+
+func testComparisonConstraints() bool {
+	return checks(">= 1.2, < 2", map[string]bool{
+		"1.2.0": true, "1.9.9": true, "1.1.9": false, "2.0.0": false,
+	}) && checks("!=1.4.0", map[string]bool{
+		"1.4.0": false, "1.4.1": true,
+	}) && checks("=1.4.0", map[string]bool{
+		"1.4.0": true, "1.4.1": false,
+	}) && checks("> 1.2.3 <= 1.3.0", map[string]bool{
+		"1.2.3": false, "1.2.4": true, "1.3.0": true, "1.3.1": false,
+	}) && checks("=>1.0, =<1.1", map[string]bool{
+		"1.0.0": true, "1.1.9": true, "1.2.0": false,
+	})
+}
+
+func testCaretAndTilde() bool {
+	return checks("^1.2.3", map[string]bool{
+		"1.2.3": true, "1.9.0": true, "2.0.0": false, "1.2.2": false,
+	}) && checks("^0.2.3", map[string]bool{
+		"0.2.9": true, "0.3.0": false,
+	}) && checks("^0.0.3", map[string]bool{
+		"0.0.3": true, "0.0.4": false,
+	}) && checks("^0", map[string]bool{
+		"0.9.0": true, "1.0.0": false,
+	}) && checks("~1.2.3", map[string]bool{
+		"1.2.9": true, "1.3.0": false, "1.2.2": false,
+	}) && checks("~1", map[string]bool{
+		"1.9.0": true, "2.0.0": false,
+	}) && checks("~> 2.1", map[string]bool{
+		"2.1.5": true, "2.2.0": false,
+	})
+}
+
+func testWildcardsAndRanges() bool {
+	return checks("1.2.x", map[string]bool{
+		"1.2.0": true, "1.2.99": true, "1.3.0": false,
+	}) && checks("*", map[string]bool{
+		"0.0.1": true, "42.0.0": true,
+	}) && checks("1.2", map[string]bool{
+		"1.2.7": true, "1.3.0": false,
+	}) && checks(">1.2.x", map[string]bool{
+		"1.2.9": false, "1.3.0": true,
+	}) && checks("<=2.x", map[string]bool{
+		"2.9.9": true, "3.0.0": false,
+	}) && checks("!=1.x", map[string]bool{
+		"1.5.0": false, "2.0.0": true,
+	}) && checks("1.2 - 1.4.5", map[string]bool{
+		"1.2.0": true, "1.4.5": true, "1.4.6": false, "1.1.9": false,
+	})
+}
+
+func testOrConstraints() bool {
+	c, err := NewConstraint("^1.2 || >= 3.0, < 3.5 || 5.x")
+	if err != nil {
+		return false
+	}
+	for v, want := range map[string]bool{
+		"1.4.0": true, "2.0.0": false, "3.4.9": true, "3.5.0": false, "5.1.0": true,
+	} {
+		if c.Check(MustParse(v)) != want {
+			return false
+		}
+	}
+	if c.String() != "^1.2 || >=3.0 <3.5 || 5.x" {
+		return false
+	}
+	if c.Check(nil) {
+		return false
+	}
+
+	for _, bad := range []string{"", ">>1.2", "1.2 ||", "^1.2.3 foo", "1.x-beta"} {
+		if _, err := NewConstraint(bad); err == nil {
+			fmt.Printf("  %q should not parse\n", bad)
+			return false
+		}
+	}
+	return true
+}
+
+func testPrereleaseConstraints() bool {
+	// Constraints without prereleases only match releases
+	if !checks(">=1.0.0", map[string]bool{
+		"1.5.0": true, "1.5.0-beta": false, "2.0.0-rc.1": false,
+	}) {
+		return false
+	}
+	// A prerelease in a group lets prereleases match that group
+	if !checks(">=1.2.3-alpha, <1.3", map[string]bool{
+		"1.2.3-alpha": true, "1.2.3-beta.2": true, "1.2.5-rc.1": true,
+		"1.2.3-0": false, "1.4.0-rc.1": false,
+	}) {
+		return false
+	}
+	// But not other groups
+	return checks("^2.0.0-rc.1 || ^1.0", map[string]bool{
+		"2.0.0-rc.2": true, "1.9.0-beta": false, "1.9.0": true,
+	})
+}
+
+func testValidate() bool {
+	c, _ := NewConstraint("<= 1.4, ~1.3")
+	ok, errs := c.Validate(MustParse("1.5.2"))
+	if ok || len(errs) != 2 {
+		return false
+	}
+	if errs[0].Error() != "1.5.2 is greater than 1.4" ||
+		errs[1].Error() != "1.5.2 does not have same major and minor version as 1.3" {
+		return false
+	}
+
+	ok, errs = c.Validate(MustParse("1.3.4-beta"))
+	if ok || len(errs) != 1 || !strings.Contains(errs[0].Error(), "is a prerelease version") {
+		return false
+	}
+
+	ok, errs = c.Validate(MustParse("1.3.4"))
+	return ok && errs == nil
+}
+
+func testSerialization() bool {
+	type release struct {
+		Version  *Version    `json:"version"`
+		Requires Constraints `json:"requires"`
+	}
+	var r release
+	if err := json.Unmarshal([]byte(`{"version":"v1.4.0-rc.2","requires":">= 1.2, < 2"}`), &r); err != nil {
+		return false
+	}
+	if r.Version.String() != "1.4.0-rc.2" || !r.Requires.Check(MustParse("1.5.0")) {
+		return false
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r); err != nil || out.String() != `{"version":"1.4.0-rc.2","requires":">=1.2 <2"}`+"\n" {
+		return false
+	}
+
+	var v Version
+	if err := json.Unmarshal([]byte(`"1.2.3.4"`), &v); !errors.Is(err, ErrInvalidSemVer) {
+		return false
+	}
+	return v.UnmarshalText([]byte("2.0.1")) == nil && v.String() == "2.0.1"
+}
+
+func testReleaseTooling() bool {
+	// A release command picking the newest tag within a range, skipping
+	// tags that aren't versions
+	tags := []string{"v1.2.0", "v1.3.1", "v1.10.0", "v2.0.0-rc.1", "nightly", "v0.9.0"}
+	c, _ := NewConstraint("^1")
+	latest := c.Latest(tags)
+	if latest == nil || latest.Original() != "v1.10.0" {
+		return false
+	}
+	next := latest.IncMinor()
+	if "v"+next.String() != "v1.11.0" {
+		return false
+	}
+
+	c, _ = NewConstraint(">= 2.0.0-0")
+	if latest := c.Latest(tags); latest == nil || latest.String() != "2.0.0-rc.1" {
+		return false
+	}
+	c, _ = NewConstraint("^3")
+	return c.Latest(tags) == nil
+}
+
+func main() {
+	fmt.Println("Running semver Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Parsing", testParsing)
+	runTest("Invalid Versions", testInvalidVersions)
+	runTest("Strict Parsing", testStrictParsing)
+	runTest("Comparison", testComparison)
+	runTest("Prerelease Precedence", testPrereleasePrecedence)
+	runTest("Sorting", testSorting)
+	runTest("Incrementing", testIncrementing)
+	runTest("Comparison Constraints", testComparisonConstraints)
+	runTest("Caret and Tilde", testCaretAndTilde)
+	runTest("Wildcards and Ranges", testWildcardsAndRanges)
+	runTest("Or Constraints", testOrConstraints)
+	runTest("Prerelease Constraints", testPrereleaseConstraints)
+	runTest("Validate", testValidate)
+	runTest("Serialization", testSerialization)
+	runTest("Release Tooling", testReleaseTooling)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
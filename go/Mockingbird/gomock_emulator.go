@@ -0,0 +1,986 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang/mock
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TestReporter is the part of *testing.T a Controller reports to
+type TestReporter interface {
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// TestHelper is a TestReporter that can mark helper functions
+type TestHelper interface {
+	TestReporter
+	Helper()
+}
+
+// cleanuper is implemented by *testing.T, so NewController can register
+// Finish to run when the test ends
+type cleanuper interface {
+	Cleanup(func())
+}
+
+type nopTestHelper struct {
+	TestReporter
+}
+
+func (nopTestHelper) Helper() {}
+
+// Controller holds the expected calls of one test. Mocks forward each call
+// to it, and it checks the call against the expectations.
+type Controller struct {
+	T TestHelper
+
+	mu       sync.Mutex
+	expected map[callSetKey][]*Call
+	used     map[callSetKey][]*Call
+	finished bool
+}
+
+type callSetKey struct {
+	receiver interface{}
+	method   string
+}
+
+// NewController returns a Controller that reports to t. When t is a
+// *testing.T, Finish runs automatically when the test ends.
+func NewController(t TestReporter) *Controller {
+	h, ok := t.(TestHelper)
+	if !ok {
+		h = nopTestHelper{t}
+	}
+	ctrl := &Controller{
+		T:        h,
+		expected: make(map[callSetKey][]*Call),
+		used:     make(map[callSetKey][]*Call),
+	}
+	if c, ok := t.(cleanuper); ok {
+		c.Cleanup(func() {
+			ctrl.T.Helper()
+			ctrl.finish(true)
+		})
+	}
+	return ctrl
+}
+
+// RecordCall expects method to be called on receiver with args, looking up
+// the method's signature on the receiver
+func (ctrl *Controller) RecordCall(receiver interface{}, method string, args ...interface{}) *Call {
+	ctrl.T.Helper()
+
+	recv := reflect.ValueOf(receiver)
+	for i := 0; i < recv.Type().NumMethod(); i++ {
+		if recv.Type().Method(i).Name == method {
+			return ctrl.RecordCallWithMethodType(receiver, method, recv.Method(i).Type(), args...)
+		}
+	}
+	ctrl.T.Fatalf("gomock: failed finding method %s on %T", method, receiver)
+	panic("unreachable")
+}
+
+// RecordCallWithMethodType expects method, whose signature is methodType,
+// to be called on receiver with args
+func (ctrl *Controller) RecordCallWithMethodType(receiver interface{}, method string, methodType reflect.Type, args ...interface{}) *Call {
+	ctrl.T.Helper()
+
+	call := newCall(ctrl.T, receiver, method, methodType, callerInfo(2), args...)
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	key := callSetKey{receiver, method}
+	ctrl.expected[key] = append(ctrl.expected[key], call)
+	return call
+}
+
+// Call is called by a mock. It finds the expected call that matches,
+// runs its actions and returns its return values. A call nothing expects
+// fails the test.
+func (ctrl *Controller) Call(receiver interface{}, method string, args ...interface{}) []interface{} {
+	ctrl.T.Helper()
+
+	actions := func() []func([]interface{}) []interface{} {
+		ctrl.mu.Lock()
+		defer ctrl.mu.Unlock()
+
+		expected, err := ctrl.findMatch(receiver, method, args)
+		if err != nil {
+			ctrl.T.Fatalf("Unexpected call to %s.%v(%v) at %s because: %s",
+				receiverName(receiver), method, formatArgs(args), callerInfo(3), err)
+			panic("unreachable")
+		}
+
+		// Once a call is made, the calls it had to wait for can no
+		// longer be made
+		for _, preReq := range expected.dropPrereqs() {
+			ctrl.remove(preReq)
+		}
+		actions := expected.call()
+		if expected.exhausted() {
+			ctrl.remove(expected)
+		}
+		return actions
+	}()
+
+	var rets []interface{}
+	for _, action := range actions {
+		if r := action(args); r != nil {
+			rets = r
+		}
+	}
+	return rets
+}
+
+// findMatch returns the first expected call that accepts args, or an
+// error describing why each candidate did not
+func (ctrl *Controller) findMatch(receiver interface{}, method string, args []interface{}) (*Call, error) {
+	key := callSetKey{receiver, method}
+
+	var errs bytes.Buffer
+	for _, call := range ctrl.expected[key] {
+		if err := call.matches(args); err != nil {
+			fmt.Fprintf(&errs, "\n%v", err)
+			continue
+		}
+		return call, nil
+	}
+
+	// Search the used up calls too, for a more useful message
+	for _, call := range ctrl.used[key] {
+		if err := call.matchArgs(args); err != nil {
+			fmt.Fprintf(&errs, "\n%v", err)
+		} else if call.exhausted() {
+			fmt.Fprintf(&errs, "\nexpected call at %s has already been called the max number of times", call.origin)
+		} else {
+			fmt.Fprintf(&errs, "\nexpected call at %s can no longer be made, as a call ordered after it has been", call.origin)
+		}
+	}
+
+	if len(ctrl.expected[key])+len(ctrl.used[key]) == 0 {
+		fmt.Fprintf(&errs, "there are no expected calls of the method %q for that receiver", method)
+	}
+	return nil, fmt.Errorf("%s", errs.String())
+}
+
+// remove moves call from the expected calls to the used ones
+func (ctrl *Controller) remove(call *Call) {
+	key := callSetKey{call.receiver, call.method}
+	calls := ctrl.expected[key]
+	for i, c := range calls {
+		if c == call {
+			ctrl.expected[key] = append(calls[:i:i], calls[i+1:]...)
+			ctrl.used[key] = append(ctrl.used[key], call)
+			return
+		}
+	}
+}
+
+// Satisfied reports whether every expected call has been made at least
+// its minimum number of times
+func (ctrl *Controller) Satisfied() bool {
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+	return len(ctrl.failures()) == 0
+}
+
+func (ctrl *Controller) failures() []*Call {
+	var failures []*Call
+	for _, calls := range ctrl.expected {
+		for _, call := range calls {
+			if !call.satisfied() {
+				failures = append(failures, call)
+			}
+		}
+	}
+	// Report in the order the calls were recorded
+	sort.Slice(failures, func(i, j int) bool { return failures[i].seq < failures[j].seq })
+	return failures
+}
+
+// Finish checks that every expected call was made. It is only needed when
+// the Controller's TestReporter cannot register cleanups; calling it more
+// than once has no further effect.
+func (ctrl *Controller) Finish() {
+	ctrl.T.Helper()
+	ctrl.finish(false)
+}
+
+func (ctrl *Controller) finish(cleanup bool) {
+	ctrl.T.Helper()
+
+	ctrl.mu.Lock()
+	defer ctrl.mu.Unlock()
+
+	if ctrl.finished {
+		return
+	}
+	ctrl.finished = true
+
+	failures := ctrl.failures()
+	for _, call := range failures {
+		ctrl.T.Errorf("missing call(s) to %v", call)
+	}
+	if len(failures) != 0 {
+		if !cleanup {
+			ctrl.T.Fatalf("aborting test due to missing call(s)")
+			return
+		}
+		ctrl.T.Errorf("aborting test due to missing call(s)")
+	}
+}
+
+// callerInfo returns the file and line skip frames up the stack
+func callerInfo(skip int) string {
+	if _, file, line, ok := runtime.Caller(skip + 1); ok {
+		return filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+	return "unknown file"
+}
+
+// receiverName names the type a call was made on in messages
+func receiverName(receiver interface{}) string {
+	if m, ok := receiver.(*Mock); ok {
+		return m.iface.String()
+	}
+	return fmt.Sprintf("%T", receiver)
+}
+
+func formatArgs(args []interface{}) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = fmt.Sprintf("%v", arg)
+	}
+	return strings.Join(parts, ", ")
+}
+
+const (
+	// unlimited stands in for no upper bound on a call's count
+	unlimited = 1e8
+)
+
+// callSeq numbers calls so missing calls are reported in the order they
+// were recorded
+var (
+	callSeq   int
+	callSeqMu sync.Mutex
+)
+
+// Call is one expected call: its arguments, how many times it may be
+// made, what it returns and which calls must come first
+type Call struct {
+	t TestHelper
+
+	receiver   interface{}
+	method     string
+	methodType reflect.Type
+	args       []Matcher
+	origin     string
+	seq        int
+
+	preReqs []*Call
+
+	minCalls, maxCalls int
+	numCalls           int
+
+	actions []func([]interface{}) []interface{}
+}
+
+func newCall(t TestHelper, receiver interface{}, method string, methodType reflect.Type, origin string, args ...interface{}) *Call {
+	t.Helper()
+
+	// Arguments that are not matchers must be equal
+	matchers := make([]Matcher, len(args))
+	for i, arg := range args {
+		if m, ok := arg.(Matcher); ok {
+			matchers[i] = m
+		} else if arg == nil {
+			// Eq(nil) would only match an untyped nil
+			matchers[i] = Nil()
+		} else {
+			matchers[i] = Eq(arg)
+		}
+	}
+
+	callSeqMu.Lock()
+	callSeq++
+	seq := callSeq
+	callSeqMu.Unlock()
+
+	// Until Return is called, the call returns zero values
+	actions := []func([]interface{}) []interface{}{func([]interface{}) []interface{} {
+		rets := make([]interface{}, methodType.NumOut())
+		for i := range rets {
+			rets[i] = reflect.Zero(methodType.Out(i)).Interface()
+		}
+		return rets
+	}}
+	return &Call{
+		t:          t,
+		receiver:   receiver,
+		method:     method,
+		methodType: methodType,
+		args:       matchers,
+		origin:     origin,
+		seq:        seq,
+		minCalls:   1,
+		maxCalls:   1,
+		actions:    actions,
+	}
+}
+
+// AnyTimes allows the call to be made any number of times, including none
+func (c *Call) AnyTimes() *Call {
+	c.minCalls, c.maxCalls = 0, unlimited
+	return c
+}
+
+// MinTimes requires the call to be made at least n times. Unless MaxTimes
+// was set, there is no upper bound.
+func (c *Call) MinTimes(n int) *Call {
+	c.minCalls = n
+	if c.maxCalls == 1 {
+		c.maxCalls = unlimited
+	}
+	return c
+}
+
+// MaxTimes allows the call to be made at most n times. Unless MinTimes was
+// set, it may not be made at all.
+func (c *Call) MaxTimes(n int) *Call {
+	c.maxCalls = n
+	if c.minCalls == 1 {
+		c.minCalls = 0
+	}
+	return c
+}
+
+// Times requires the call to be made exactly n times
+func (c *Call) Times(n int) *Call {
+	c.minCalls, c.maxCalls = n, n
+	return c
+}
+
+// Return sets the values the call returns. They must fit the method's
+// results; nil fits any result that can be nil.
+func (c *Call) Return(rets ...interface{}) *Call {
+	c.t.Helper()
+
+	mt := c.methodType
+	if len(rets) != mt.NumOut() {
+		c.t.Fatalf("wrong number of arguments to Return for %s.%v: got %d, want %d [%s]",
+			receiverName(c.receiver), c.method, len(rets), mt.NumOut(), c.origin)
+	}
+	for i, ret := range rets {
+		want := mt.Out(i)
+		if ret == nil {
+			switch want.Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+				continue
+			}
+			c.t.Fatalf("argument %d to Return for %s.%v is nil, but %v is not nillable [%s]",
+				i, receiverName(c.receiver), c.method, want, c.origin)
+		} else if got := reflect.TypeOf(ret); !got.AssignableTo(want) {
+			c.t.Fatalf("wrong type of argument %d to Return for %s.%v: %v is not assignable to %v [%s]",
+				i, receiverName(c.receiver), c.method, got, want, c.origin)
+		}
+	}
+
+	c.addAction(func([]interface{}) []interface{} {
+		return rets
+	})
+	return c
+}
+
+// Do runs f with the call's arguments when the call is made. f's results
+// are ignored.
+func (c *Call) Do(f interface{}) *Call {
+	c.t.Helper()
+	fn := c.checkFunc("Do", f)
+	c.addAction(func(args []interface{}) []interface{} {
+		c.t.Helper()
+		fn.Call(c.funcArgs("Do", fn, args))
+		return nil
+	})
+	return c
+}
+
+// DoAndReturn runs f with the call's arguments when the call is made, and
+// returns f's results
+func (c *Call) DoAndReturn(f interface{}) *Call {
+	c.t.Helper()
+	fn := c.checkFunc("DoAndReturn", f)
+	if fn.Type().NumOut() != c.methodType.NumOut() {
+		c.t.Fatalf("wrong number of results from the function passed to DoAndReturn for %s.%v: got %d, want %d [%s]",
+			receiverName(c.receiver), c.method, fn.Type().NumOut(), c.methodType.NumOut(), c.origin)
+	}
+	c.addAction(func(args []interface{}) []interface{} {
+		c.t.Helper()
+		out := fn.Call(c.funcArgs("DoAndReturn", fn, args))
+		rets := make([]interface{}, len(out))
+		for i, v := range out {
+			rets[i] = v.Interface()
+		}
+		return rets
+	})
+	return c
+}
+
+func (c *Call) checkFunc(name string, f interface{}) reflect.Value {
+	c.t.Helper()
+	fn := reflect.ValueOf(f)
+	if fn.Kind() != reflect.Func {
+		c.t.Fatalf("argument to %s for %s.%v is a %T, not a function [%s]",
+			name, receiverName(c.receiver), c.method, f, c.origin)
+	}
+	return fn
+}
+
+// funcArgs converts the call's arguments for a Do or DoAndReturn function
+func (c *Call) funcArgs(name string, fn reflect.Value, args []interface{}) []reflect.Value {
+	c.t.Helper()
+	ft := fn.Type()
+	if ft.IsVariadic() && len(args) < ft.NumIn()-1 || !ft.IsVariadic() && len(args) != ft.NumIn() {
+		c.t.Fatalf("wrong number of arguments to the function passed to %s for %s.%v: got %d, want %d [%s]",
+			name, receiverName(c.receiver), c.method, len(args), ft.NumIn(), c.origin)
+	}
+	vals := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var want reflect.Type
+		if ft.IsVariadic() && i >= ft.NumIn()-1 {
+			want = ft.In(ft.NumIn() - 1).Elem()
+		} else {
+			want = ft.In(i)
+		}
+		if arg == nil {
+			vals[i] = reflect.Zero(want)
+		} else {
+			vals[i] = reflect.ValueOf(arg)
+		}
+	}
+	return vals
+}
+
+// SetArg sets the value the n'th argument points to when the call is
+// made, for methods that fill in a pointer or slice they are given
+func (c *Call) SetArg(n int, value interface{}) *Call {
+	c.t.Helper()
+
+	if n < 0 || n >= c.methodType.NumIn() {
+		c.t.Fatalf("SetArg(%d, ...) for %s.%v: the method has %d arguments [%s]",
+			n, receiverName(c.receiver), c.method, c.methodType.NumIn(), c.origin)
+	}
+	switch kind := c.methodType.In(n).Kind(); kind {
+	case reflect.Ptr, reflect.Interface, reflect.Slice:
+	default:
+		c.t.Fatalf("SetArg(%d, ...) for %s.%v: argument %d is a %v, not a pointer or slice [%s]",
+			n, receiverName(c.receiver), c.method, n, kind, c.origin)
+	}
+
+	c.addAction(func(args []interface{}) []interface{} {
+		v := reflect.ValueOf(value)
+		switch arg := reflect.ValueOf(args[n]); arg.Kind() {
+		case reflect.Slice:
+			reflect.Copy(arg, v)
+		default:
+			arg.Elem().Set(v)
+		}
+		return nil
+	})
+	return c
+}
+
+func (c *Call) addAction(action func([]interface{}) []interface{}) {
+	c.actions = append(c.actions, action)
+}
+
+// After requires preReq to have been made before this call can be
+func (c *Call) After(preReq *Call) *Call {
+	c.t.Helper()
+	if c.isPreReq(preReq) {
+		c.t.Fatalf("Loop in call order: %v is a prerequisite to %v (possibly indirectly).", c, preReq)
+	}
+	c.preReqs = append(c.preReqs, preReq)
+	return c
+}
+
+// isPreReq reports whether c must come before other, directly or not
+func (c *Call) isPreReq(other *Call) bool {
+	for _, preReq := range other.preReqs {
+		if c == preReq || c.isPreReq(preReq) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropPrereqs forgets the call's prerequisites, which are satisfied once
+// it is made, and returns them
+func (c *Call) dropPrereqs() []*Call {
+	preReqs := c.preReqs
+	c.preReqs = nil
+	return preReqs
+}
+
+func (c *Call) satisfied() bool {
+	return c.numCalls >= c.minCalls
+}
+
+func (c *Call) exhausted() bool {
+	return c.numCalls >= c.maxCalls
+}
+
+func (c *Call) call() []func([]interface{}) []interface{} {
+	c.numCalls++
+	return c.actions
+}
+
+// matches returns an error if args do not fit the call, its prerequisites
+// have not been made, or it has been made as often as it may be
+func (c *Call) matches(args []interface{}) error {
+	if err := c.matchArgs(args); err != nil {
+		return err
+	}
+	for _, preReq := range c.preReqs {
+		if !preReq.satisfied() {
+			return fmt.Errorf("expected call at %s doesn't have a prerequisite call satisfied:\n%v\nshould be called before:\n%v",
+				c.origin, preReq, c)
+		}
+	}
+	if c.exhausted() {
+		return fmt.Errorf("expected call at %s has already been called the max number of times", c.origin)
+	}
+	return nil
+}
+
+func (c *Call) matchArgs(args []interface{}) error {
+	mismatch := func(i int, m Matcher, got interface{}) error {
+		return fmt.Errorf("expected call at %s doesn't match the argument at index %d.\nGot: %v\nWant: %v",
+			c.origin, i, got, m)
+	}
+
+	if len(c.args) == len(args) {
+		var err error
+		for i, m := range c.args {
+			if !m.Matches(args[i]) {
+				err = mismatch(i, m, args[i])
+				break
+			}
+		}
+		if err == nil || !c.methodType.IsVariadic() {
+			return err
+		}
+	}
+
+	// The last matcher of a variadic method may match the variadic
+	// arguments as one slice
+	if c.methodType.IsVariadic() && len(c.args) == c.methodType.NumIn() && len(args) >= len(c.args)-1 {
+		last := len(c.args) - 1
+		for i, m := range c.args[:last] {
+			if !m.Matches(args[i]) {
+				return mismatch(i, m, args[i])
+			}
+		}
+		vargs := reflect.MakeSlice(c.methodType.In(last), 0, len(args)-last)
+		for _, arg := range args[last:] {
+			if arg == nil {
+				vargs = reflect.Append(vargs, reflect.Zero(vargs.Type().Elem()))
+			} else {
+				vargs = reflect.Append(vargs, reflect.ValueOf(arg))
+			}
+		}
+		if !c.args[last].Matches(vargs.Interface()) {
+			return mismatch(last, c.args[last], vargs.Interface())
+		}
+		return nil
+	}
+
+	return fmt.Errorf("expected call at %s has the wrong number of arguments. Got: %d, want: %d",
+		c.origin, len(args), len(c.args))
+}
+
+func (c *Call) String() string {
+	args := make([]string, len(c.args))
+	for i, m := range c.args {
+		args[i] = m.String()
+	}
+	return fmt.Sprintf("%s.%v(%s) %s", receiverName(c.receiver), c.method, strings.Join(args, ", "), c.origin)
+}
+
+// InOrder requires the calls to be made in the order given. Each argument
+// is a *Call or a struct that embeds one.
+func InOrder(calls ...interface{}) {
+	ordered := make([]*Call, len(calls))
+	for i, arg := range calls {
+		call := getCall(arg)
+		if call == nil {
+			panic(fmt.Sprintf("invalid argument at position %d of type %T, InOrder expects *Call or a struct embedding one", i, arg))
+		}
+		ordered[i] = call
+	}
+	for i := 1; i < len(ordered); i++ {
+		ordered[i].After(ordered[i-1])
+	}
+}
+
+func getCall(arg interface{}) *Call {
+	if call, ok := arg.(*Call); ok {
+		return call
+	}
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.CanInterface() {
+			if call, ok := f.Interface().(*Call); ok {
+				return call
+			}
+		}
+	}
+	return nil
+}
+
+// Matcher decides whether an argument is acceptable
+type Matcher interface {
+	// Matches reports whether x is acceptable
+	Matches(x interface{}) bool
+	// String describes what the matcher accepts
+	String() string
+}
+
+type anyMatcher struct{}
+
+func (anyMatcher) Matches(interface{}) bool { return true }
+func (anyMatcher) String() string           { return "is anything" }
+
+// Any matches any argument
+func Any() Matcher { return anyMatcher{} }
+
+type eqMatcher struct {
+	x interface{}
+}
+
+func (e eqMatcher) Matches(x interface{}) bool {
+	if e.x == nil || x == nil {
+		return reflect.DeepEqual(e.x, x)
+	}
+	// Compare as the argument's type, so a value matches an interface
+	// argument holding it
+	want, got := reflect.ValueOf(e.x), reflect.ValueOf(x)
+	if !want.Type().AssignableTo(got.Type()) {
+		return false
+	}
+	return reflect.DeepEqual(want.Convert(got.Type()).Interface(), x)
+}
+
+func (e eqMatcher) String() string { return fmt.Sprintf("is equal to %v (%T)", e.x, e.x) }
+
+// Eq matches an argument deeply equal to x
+func Eq(x interface{}) Matcher { return eqMatcher{x} }
+
+type nilMatcher struct{}
+
+func (nilMatcher) Matches(x interface{}) bool {
+	if x == nil {
+		return true
+	}
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (nilMatcher) String() string { return "is nil" }
+
+// Nil matches nil, including typed nil pointers, maps and slices
+func Nil() Matcher { return nilMatcher{} }
+
+type notMatcher struct {
+	m Matcher
+}
+
+func (n notMatcher) Matches(x interface{}) bool { return !n.m.Matches(x) }
+func (n notMatcher) String() string             { return "not(" + n.m.String() + ")" }
+
+// Not matches what x does not. x is a Matcher or a value for Eq.
+func Not(x interface{}) Matcher {
+	if m, ok := x.(Matcher); ok {
+		return notMatcher{m}
+	}
+	return notMatcher{Eq(x)}
+}
+
+type lenMatcher struct {
+	i int
+}
+
+func (m lenMatcher) Matches(x interface{}) bool {
+	v := reflect.ValueOf(x)
+	switch v.Kind() {
+	case reflect.Array, reflect.Chan, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == m.i
+	}
+	return false
+}
+
+func (m lenMatcher) String() string { return fmt.Sprintf("has length %d", m.i) }
+
+// Len matches an array, channel, map, slice or string of length i
+func Len(i int) Matcher { return lenMatcher{i} }
+
+type assignableToTypeOfMatcher struct {
+	targetType reflect.Type
+}
+
+func (m assignableToTypeOfMatcher) Matches(x interface{}) bool {
+	return x != nil && reflect.TypeOf(x).AssignableTo(m.targetType)
+}
+
+func (m assignableToTypeOfMatcher) String() string {
+	return "is assignable to " + m.targetType.String()
+}
+
+// AssignableToTypeOf matches an argument assignable to x's type. For an
+// interface, pass a nil pointer to it: AssignableToTypeOf((*error)(nil)).
+func AssignableToTypeOf(x interface{}) Matcher {
+	if t, ok := x.(reflect.Type); ok {
+		return assignableToTypeOfMatcher{t}
+	}
+	t := reflect.TypeOf(x)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface && reflect.ValueOf(x).IsNil() {
+		t = t.Elem()
+	}
+	return assignableToTypeOfMatcher{t}
+}
+
+type allMatcher struct {
+	matchers []Matcher
+}
+
+func (am allMatcher) Matches(x interface{}) bool {
+	for _, m := range am.matchers {
+		if !m.Matches(x) {
+			return false
+		}
+	}
+	return true
+}
+
+func (am allMatcher) String() string {
+	parts := make([]string, len(am.matchers))
+	for i, m := range am.matchers {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// All matches what every one of ms does
+func All(ms ...Matcher) Matcher { return allMatcher{ms} }
+
+type condMatcher struct {
+	fn func(x interface{}) bool
+}
+
+func (c condMatcher) Matches(x interface{}) bool { return c.fn(x) }
+func (condMatcher) String() string               { return "adheres to a custom condition" }
+
+// Cond matches an argument fn accepts
+func Cond(fn func(x interface{}) bool) Matcher { return condMatcher{fn} }
+
+type regexMatcher struct {
+	regex *regexp.Regexp
+}
+
+func (m regexMatcher) Matches(x interface{}) bool {
+	switch t := x.(type) {
+	case string:
+		return m.regex.MatchString(t)
+	case []byte:
+		return m.regex.Match(t)
+	}
+	return false
+}
+
+func (m regexMatcher) String() string { return "matches regex " + m.regex.String() }
+
+// Regex matches a string or []byte argument the expression matches. It
+// panics if regexStr does not compile.
+func Regex(regexStr string) Matcher { return regexMatcher{regexp.MustCompile(regexStr)} }
+
+type inAnyOrderMatcher struct {
+	x interface{}
+}
+
+func (m inAnyOrderMatcher) Matches(x interface{}) bool {
+	given, want := reflect.ValueOf(x), reflect.ValueOf(m.x)
+	for _, v := range []reflect.Value{given, want} {
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return false
+		}
+	}
+	if given.Len() != want.Len() {
+		return false
+	}
+	used := make([]bool, want.Len())
+outer:
+	for i := 0; i < given.Len(); i++ {
+		for j := 0; j < want.Len(); j++ {
+			if !used[j] && Eq(want.Index(j).Interface()).Matches(given.Index(i).Interface()) {
+				used[j] = true
+				continue outer
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (m inAnyOrderMatcher) String() string {
+	return fmt.Sprintf("has the same elements as %v", m.x)
+}
+
+// InAnyOrder matches a slice or array with the same elements as x, in any
+// order
+func InAnyOrder(x interface{}) Matcher { return inAnyOrderMatcher{x} }
+
+// Mock is a mock of an interface built by reflection, for tests that would
+// otherwise run mockgen. Expectations are recorded with EXPECT().Call and
+// checked against the interface's method set; a thin type that implements
+// the interface forwards its methods to Invoke, or is filled in with Bind.
+type Mock struct {
+	ctrl     *Controller
+	iface    reflect.Type
+	recorder *MockRecorder
+}
+
+// MockRecorder records expected calls on a Mock
+type MockRecorder struct {
+	mock *Mock
+}
+
+// NewMock returns a mock of the interface iface points to, as in
+// NewMock(ctrl, (*Store)(nil))
+func NewMock(ctrl *Controller, iface interface{}) *Mock {
+	ctrl.T.Helper()
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		ctrl.T.Fatalf("gomock: NewMock needs a nil pointer to an interface, got %T", iface)
+		panic("unreachable")
+	}
+	mock := &Mock{ctrl: ctrl, iface: t.Elem()}
+	mock.recorder = &MockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns the recorder for expected calls
+func (m *Mock) EXPECT() *MockRecorder {
+	return m.recorder
+}
+
+// methodType returns the signature of the interface method, failing the
+// test if there is none
+func (m *Mock) methodType(method string) reflect.Type {
+	m.ctrl.T.Helper()
+	meth, ok := m.iface.MethodByName(method)
+	if !ok {
+		m.ctrl.T.Fatalf("gomock: %v has no method %s", m.iface, method)
+		panic("unreachable")
+	}
+	return meth.Type
+}
+
+// Call expects method to be called with args
+func (mr *MockRecorder) Call(method string, args ...interface{}) *Call {
+	m := mr.mock
+	m.ctrl.T.Helper()
+
+	mt := m.methodType(method)
+	if mt.IsVariadic() && len(args) < mt.NumIn()-1 || !mt.IsVariadic() && len(args) != mt.NumIn() {
+		m.ctrl.T.Fatalf("gomock: wrong number of arguments to %v.%s: got %d, want %d",
+			m.iface, method, len(args), mt.NumIn())
+	}
+	return m.ctrl.RecordCallWithMethodType(m, method, mt, args...)
+}
+
+// Invoke makes a call of method on the mock and returns its results.
+// Variadic arguments are passed one by one, as the method receives them.
+func (m *Mock) Invoke(method string, args ...interface{}) []interface{} {
+	m.ctrl.T.Helper()
+	m.methodType(method)
+	return m.ctrl.Call(m, method, args...)
+}
+
+// Func returns a function with method's signature that calls Invoke
+func (m *Mock) Func(method string) interface{} {
+	m.ctrl.T.Helper()
+	mt := m.methodType(method)
+	return reflect.MakeFunc(mt, func(in []reflect.Value) []reflect.Value {
+		var args []interface{}
+		for i, v := range in {
+			if mt.IsVariadic() && i == len(in)-1 {
+				for j := 0; j < v.Len(); j++ {
+					args = append(args, v.Index(j).Interface())
+				}
+				break
+			}
+			args = append(args, v.Interface())
+		}
+
+		rets := m.ctrl.Call(m, method, args...)
+		out := make([]reflect.Value, mt.NumOut())
+		for i := range out {
+			if i < len(rets) && rets[i] != nil {
+				out[i] = reflect.ValueOf(rets[i]).Convert(mt.Out(i))
+			} else {
+				out[i] = reflect.Zero(mt.Out(i))
+			}
+		}
+		return out
+	}).Interface()
+}
+
+// Bind sets each function field of the struct fns points to whose name is
+// a method of the interface, optionally with a "Func" suffix, to Func of
+// that method. The struct's methods can then call its fields to implement
+// the interface.
+func (m *Mock) Bind(fns interface{}) {
+	m.ctrl.T.Helper()
+
+	v := reflect.ValueOf(fns)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		m.ctrl.T.Fatalf("gomock: Bind needs a pointer to a struct, got %T", fns)
+		return
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type.Kind() != reflect.Func || field.PkgPath != "" {
+			continue
+		}
+		name := strings.TrimSuffix(field.Name, "Func")
+		meth, ok := m.iface.MethodByName(name)
+		if !ok {
+			continue
+		}
+		if meth.Type != field.Type {
+			m.ctrl.T.Fatalf("gomock: field %s is a %v, but %v.%s is a %v",
+				field.Name, field.Type, m.iface, name, meth.Type)
+			return
+		}
+		v.Field(i).Set(reflect.ValueOf(m.Func(name)))
+	}
+}
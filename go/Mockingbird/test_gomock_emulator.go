@@ -0,0 +1,540 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang/mock
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// fakeT records what a Controller reports. Fatalf stops the caller by
+// panicking, as *testing.T stops the test goroutine.
+type fakeT struct {
+	mu       sync.Mutex
+	errors   []string
+	fatals   []string
+	cleanups []func()
+}
+
+type fatalSignal struct{}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.mu.Lock()
+	t.fatals = append(t.fatals, fmt.Sprintf(format, args...))
+	t.mu.Unlock()
+	panic(fatalSignal{})
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+// runCleanups runs registered cleanups, as the testing package does at
+// the end of a test
+func (t *fakeT) runCleanups() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+// plainT is a TestReporter with neither Helper nor Cleanup
+type plainT struct {
+	t *fakeT
+}
+
+func (p plainT) Errorf(format string, args ...interface{}) { p.t.Errorf(format, args...) }
+func (p plainT) Fatalf(format string, args ...interface{}) { p.t.Fatalf(format, args...) }
+
+// fatal runs fn and returns the Fatalf message it stopped with, if any
+func fatal(t *fakeT, fn func()) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(fatalSignal); !ok {
+				panic(r)
+			}
+			msg = t.fatals[len(t.fatals)-1]
+		}
+	}()
+	fn()
+	return ""
+}
+
+type Record struct {
+	Name string
+	Size int
+}
+
+type Store interface {
+	Get(key string) (string, error)
+	Put(key, value string) error
+	Keys(prefix string, limit ...int) []string
+	Load(key string, into *Record) error
+}
+
+// MockStore is written the way mockgen writes mocks
+type MockStore struct {
+	ctrl     *Controller
+	recorder *MockStoreMockRecorder
+}
+
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+func NewMockStore(ctrl *Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+func (m *MockStore) Get(key string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", key)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (mr *MockStoreMockRecorder) Get(key interface{}) *Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStore)(nil).Get), key)
+}
+
+func (m *MockStore) Put(key, value string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", key, value)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockStoreMockRecorder) Put(key, value interface{}) *Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockStore)(nil).Put), key, value)
+}
+
+func (m *MockStore) Keys(prefix string, limit ...int) []string {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{prefix}
+	for _, a := range limit {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Keys", varargs...)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+func (mr *MockStoreMockRecorder) Keys(prefix interface{}, limit ...interface{}) *Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{prefix}, limit...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Keys", reflect.TypeOf((*MockStore)(nil).Keys), varargs...)
+}
+
+func (m *MockStore) Load(key string, into *Record) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", key, into)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockStoreMockRecorder) Load(key, into interface{}) *Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockStore)(nil).Load), key, into)
+}
+
+// storeFuncs implements Store through function fields, for Mock.Bind
+type storeFuncs struct {
+	GetFunc  func(key string) (string, error)
+	PutFunc  func(key, value string) error
+	KeysFunc func(prefix string, limit ...int) []string
+	Load     func(key string, into *Record) error
+}
+
+func (s *storeFuncs) Get(key string) (string, error)       { return s.GetFunc(key) }
+func (s *storeFuncs) Put(key, value string) error          { return s.PutFunc(key, value) }
+func (s *storeFuncs) Keys(p string, limit ...int) []string { return s.KeysFunc(p, limit...) }
+
+func testExpectedCalls() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	store.EXPECT().Get("a").Return("1", nil)
+	store.EXPECT().Get("b").Return("", errors.New("not found"))
+	store.EXPECT().Put("a", "2")
+
+	v, err := store.Get("a")
+	_, errB := store.Get("b")
+	// A call with no Return gives zero values
+	errPut := store.Put("a", "2")
+	ctrl.Finish()
+	return v == "1" && err == nil && errB != nil && errB.Error() == "not found" &&
+		errPut == nil && ctrl.Satisfied() && len(t.errors) == 0 && len(t.fatals) == 0
+}
+
+func testUnexpectedCalls() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	noExpectation := fatal(t, func() { store.Get("a") })
+
+	store.EXPECT().Put("a", "1")
+	mismatch := fatal(t, func() { store.Put("a", "2") })
+
+	// Expectations belong to one mock
+	other := NewMockStore(ctrl)
+	otherReceiver := fatal(t, func() { other.Put("a", "1") })
+
+	return strings.HasPrefix(noExpectation, "Unexpected call to *main.MockStore.Get(a) at ") &&
+		strings.Contains(noExpectation, `there are no expected calls of the method "Get" for that receiver`) &&
+		strings.Contains(mismatch, "doesn't match the argument at index 1.\nGot: 2\nWant: is equal to 1 (string)") &&
+		strings.Contains(otherReceiver, "there are no expected calls")
+}
+
+func testCallCounts() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	store.EXPECT().Get("times").Return("x", nil).Times(2)
+	store.EXPECT().Get("any").AnyTimes()
+	store.EXPECT().Get("min").MinTimes(2)
+	store.EXPECT().Get("max").MaxTimes(1)
+
+	store.Get("times")
+	store.Get("times")
+	exhausted := fatal(t, func() { store.Get("times") })
+	for i := 0; i < 5; i++ {
+		store.Get("min")
+	}
+	if !ctrl.Satisfied() {
+		return false
+	}
+	store.Get("max")
+	tooMany := fatal(t, func() { store.Get("max") })
+
+	return strings.Contains(exhausted, "has already been called the max number of times") &&
+		strings.Contains(exhausted, "doesn't match the argument at index 0.\nGot: times\nWant: is equal to any (string)") &&
+		strings.Contains(tooMany, "max number of times")
+}
+
+func testFinish() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	store.EXPECT().Put("a", "1")
+	store.EXPECT().Get("b").MinTimes(2)
+	store.Get("b")
+
+	msg := fatal(t, ctrl.Finish)
+	// Finish only reports once
+	ctrl.Finish()
+
+	return !ctrl.Satisfied() && msg == "aborting test due to missing call(s)" && len(t.errors) == 2 &&
+		strings.HasPrefix(t.errors[0], "missing call(s) to *main.MockStore.Put(is equal to a (string), is equal to 1 (string)) test_gomock_emulator.go:") &&
+		strings.HasPrefix(t.errors[1], "missing call(s) to *main.MockStore.Get(")
+}
+
+func testCleanup() bool {
+	// A TestReporter with Cleanup finishes the Controller itself, with
+	// Errorf instead of Fatalf
+	t := &fakeT{}
+	ctrl := NewController(t)
+	NewMockStore(ctrl).EXPECT().Put("a", "1")
+	if len(t.cleanups) != 1 {
+		return false
+	}
+	t.runCleanups()
+	cleaned := len(t.fatals) == 0 && len(t.errors) == 2 && t.errors[1] == "aborting test due to missing call(s)"
+
+	// Without Cleanup, Finish has to be called
+	plain := &fakeT{}
+	ctrl = NewController(plainT{plain})
+	NewMockStore(ctrl).EXPECT().Put("a", "1")
+	msg := fatal(plain, ctrl.Finish)
+	return cleaned && len(plain.cleanups) == 0 && msg == "aborting test due to missing call(s)"
+}
+
+func testMatchers() bool {
+	var nilRecord *Record
+	var nilSlice []int
+	checks := []struct {
+		m    Matcher
+		x    interface{}
+		want bool
+	}{
+		{Any(), nil, true},
+		{Any(), 42, true},
+		{Eq(5), 5, true},
+		{Eq(5), int64(5), false},
+		{Eq([]string{"a"}), []string{"a"}, true},
+		{Eq(nil), nil, true},
+		{Nil(), nil, true},
+		{Nil(), nilRecord, true},
+		{Nil(), nilSlice, true},
+		{Nil(), 0, false},
+		{Not(5), 6, true},
+		{Not(Nil()), &Record{}, true},
+		{Len(2), "ab", true},
+		{Len(2), map[int]int{1: 1}, false},
+		{Len(0), 7, false},
+		{AssignableToTypeOf(""), "s", true},
+		{AssignableToTypeOf(0), "s", false},
+		{AssignableToTypeOf((*error)(nil)), errors.New("x"), true},
+		{All(Not(Nil()), Len(1)), []int{1}, true},
+		{All(Not(Nil()), Len(1)), []int{}, false},
+		{Cond(func(x interface{}) bool { return x.(int) > 3 }), 4, true},
+		{Regex("^ab+$"), "abbb", true},
+		{Regex("^ab+$"), []byte("ac"), false},
+		{Regex("a"), 1, false},
+		{InAnyOrder([]int{1, 2, 2}), []int{2, 1, 2}, true},
+		{InAnyOrder([]int{1, 2, 2}), []int{2, 1, 1}, false},
+	}
+	for _, c := range checks {
+		if c.m.Matches(c.x) != c.want {
+			return false
+		}
+	}
+	return Eq(5).String() == "is equal to 5 (int)" &&
+		Not(Eq(5)).String() == "not(is equal to 5 (int))" &&
+		All(Any(), Len(1)).String() == "is anything; has length 1" &&
+		AssignableToTypeOf((*error)(nil)).String() == "is assignable to error"
+}
+
+func testOrder() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	first := store.EXPECT().Get("config")
+	second := store.EXPECT().Put("config", Any())
+	third := store.EXPECT().Get("done").AnyTimes()
+	InOrder(first, second, third)
+	loop := fatal(t, func() { first.After(third) })
+
+	early := fatal(t, func() { store.Put("config", "x") })
+	store.Get("config")
+	store.Put("config", "x")
+	store.Get("done")
+	// Once a later call is made, the earlier ones are used up
+	late := fatal(t, func() { store.Get("config") })
+	store.EXPECT().Put("done", "x").After(third)
+	store.Put("done", "x")
+	dropped := fatal(t, func() { store.Get("done") })
+
+	invalid := func() (ok bool) {
+		defer func() { ok = recover() != nil }()
+		InOrder(first, "not a call")
+		return false
+	}()
+
+	return strings.Contains(early, "doesn't have a prerequisite call satisfied") &&
+		strings.Contains(late, "has already been called the max number of times") &&
+		strings.Contains(dropped, "can no longer be made, as a call ordered after it has been") &&
+		strings.HasPrefix(loop, "Loop in call order") && invalid && ctrl.Satisfied()
+}
+
+func testActions() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	var saved []string
+	store.EXPECT().Put(Any(), Any()).Do(func(key, value string) {
+		saved = append(saved, key+"="+value)
+	}).Times(2)
+	store.EXPECT().Get(Any()).DoAndReturn(func(key string) (string, error) {
+		return strings.ToUpper(key), nil
+	})
+	store.EXPECT().Load("r1", Any()).SetArg(1, Record{Name: "r1", Size: 3})
+
+	store.Put("a", "1")
+	store.Put("b", "2")
+	upper, _ := store.Get("hello")
+	var rec Record
+	err := store.Load("r1", &rec)
+
+	badSetArg := fatal(t, func() { store.EXPECT().Get("x").SetArg(0, "y") })
+	badDo := fatal(t, func() { store.EXPECT().Get("x").Do("not a func") })
+
+	return reflect.DeepEqual(saved, []string{"a=1", "b=2"}) && upper == "HELLO" &&
+		err == nil && rec == Record{Name: "r1", Size: 3} &&
+		strings.Contains(badSetArg, "not a pointer or slice") &&
+		strings.Contains(badDo, "not a function")
+}
+
+func testReturnChecks() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	count := fatal(t, func() { store.EXPECT().Get("a").Return("1") })
+	kind := fatal(t, func() { store.EXPECT().Get("a").Return(1, nil) })
+	notNillable := fatal(t, func() { store.EXPECT().Get("a").Return(nil, nil) })
+	nillable := fatal(t, func() { store.EXPECT().Keys("a").Return(nil) })
+	iface := fatal(t, func() { store.EXPECT().Put("a", "b").Return(errors.New("x")) })
+
+	return strings.HasPrefix(count, "wrong number of arguments to Return for *main.MockStore.Get: got 1, want 2") &&
+		strings.Contains(kind, "int is not assignable to string") &&
+		strings.Contains(notNillable, "string is not nillable") &&
+		nillable == "" && iface == ""
+}
+
+func testVariadic() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	store.EXPECT().Keys("a", 1, 2).Return([]string{"one-by-one"})
+	store.EXPECT().Keys("b", Len(3)).Return([]string{"as a slice"})
+	store.EXPECT().Keys("c").Return([]string{"none"})
+
+	byOne := store.Keys("a", 1, 2)
+	bySlice := store.Keys("b", 1, 2, 3)
+	none := store.Keys("c")
+	wrong := fatal(t, func() { store.Keys("b", 1) })
+
+	return byOne[0] == "one-by-one" && bySlice[0] == "as a slice" && none[0] == "none" &&
+		strings.Contains(wrong, "doesn't match the argument at index 1")
+}
+
+func testReflectionMock() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	mock := NewMock(ctrl, (*Store)(nil))
+
+	mock.EXPECT().Call("Get", "a").Return("1", nil)
+	mock.EXPECT().Call("Put", "a", Any()).Return(errors.New("read only"))
+
+	got := mock.Invoke("Get", "a")
+	put := mock.Invoke("Put", "a", "2")
+	unexpected := fatal(t, func() { mock.Invoke("Get", "z") })
+
+	noMethod := fatal(t, func() { mock.EXPECT().Call("Delete", "a") })
+	wrongArgs := fatal(t, func() { mock.EXPECT().Call("Put", "a") })
+	notIface := fatal(t, func() { NewMock(ctrl, Record{}) })
+
+	return got[0] == "1" && got[1] == nil && put[0].(error).Error() == "read only" &&
+		strings.HasPrefix(unexpected, "Unexpected call to main.Store.Get(z)") &&
+		noMethod == "gomock: main.Store has no method Delete" &&
+		wrongArgs == "gomock: wrong number of arguments to main.Store.Put: got 1, want 2" &&
+		strings.Contains(notIface, "needs a nil pointer to an interface") && ctrl.Satisfied()
+}
+
+func testBind() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	mock := NewMock(ctrl, (*Store)(nil))
+
+	fns := &storeFuncs{}
+	mock.Bind(fns)
+	var rec Record
+	var store Store = &storeFuncsWithLoad{fns}
+
+	mock.EXPECT().Call("Get", "a").Return("1", nil)
+	mock.EXPECT().Call("Keys", "p", 10).Return([]string{"p1"})
+	mock.EXPECT().Call("Put", "a", "b")
+	mock.EXPECT().Call("Load", "r", Any()).SetArg(1, Record{Name: "r"})
+
+	v, err := store.Get("a")
+	keys := store.Keys("p", 10)
+	putErr := store.Put("a", "b")
+	store.Load("r", &rec)
+
+	mismatch := fatal(t, func() {
+		mock.Bind(&struct{ GetFunc func(int) string }{})
+	})
+
+	return v == "1" && err == nil && reflect.DeepEqual(keys, []string{"p1"}) && putErr == nil &&
+		rec.Name == "r" && ctrl.Satisfied() &&
+		strings.Contains(mismatch, "field GetFunc is a func(int) string")
+}
+
+// storeFuncsWithLoad finishes implementing Store with the unsuffixed Load
+// field
+type storeFuncsWithLoad struct {
+	*storeFuncs
+}
+
+func (s *storeFuncsWithLoad) Load(key string, into *Record) error {
+	return s.storeFuncs.Load(key, into)
+}
+
+func testRecordCall() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	// RecordCall finds the method's signature on the receiver
+	ctrl.RecordCall(store, "Get", "a").Return("1", nil)
+	v, _ := store.Get("a")
+	missing := fatal(t, func() { ctrl.RecordCall(store, "Delete", "a") })
+	return v == "1" && missing == "gomock: failed finding method Delete on *main.MockStore"
+}
+
+func testConcurrentCalls() bool {
+	t := &fakeT{}
+	ctrl := NewController(t)
+	store := NewMockStore(ctrl)
+
+	store.EXPECT().Get(Any()).Return("v", nil).Times(50)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store.Get(fmt.Sprint(i))
+		}(i)
+	}
+	wg.Wait()
+	ctrl.Finish()
+	return ctrl.Satisfied() && len(t.errors) == 0 && len(t.fatals) == 0
+}
+
+func main() {
+	fmt.Println("Running gomock Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Expected Calls", testExpectedCalls)
+	runTest("Unexpected Calls", testUnexpectedCalls)
+	runTest("Call Counts", testCallCounts)
+	runTest("Finish", testFinish)
+	runTest("Cleanup", testCleanup)
+	runTest("Matchers", testMatchers)
+	runTest("Order", testOrder)
+	runTest("Actions", testActions)
+	runTest("Return Checks", testReturnChecks)
+	runTest("Variadic", testVariadic)
+	runTest("Reflection Mock", testReflectionMock)
+	runTest("Bind", testBind)
+	runTest("Record Call", testRecordCall)
+	runTest("Concurrent Calls", testConcurrentCalls)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
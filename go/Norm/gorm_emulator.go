@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 )
@@ -20,6 +21,21 @@ type DB struct {
 	order     string
 	Error     error
 	RowsAffected int64
+
+	// Query cache, shared by the DBs chained from UseCache
+	cache    QueryCache
+	cacheTTL time.Duration
+	versions map[string]int64
+
+	// Open transaction, shared by the DBs chained from Begin
+	tx *transaction
+}
+
+// QueryCache stores query results. The cache emulator's Cache implements
+// it, so db.UseCache(New(time.Minute, time.Minute), time.Minute) works.
+type QueryCache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}, d time.Duration)
 }
 
 type whereClause struct {
@@ -35,6 +51,13 @@ type Model struct {
 	DeletedAt *time.Time `gorm:"index"`
 }
 
+// BeforeCreateInterface is implemented by models that prepare themselves
+// before Create inserts them, such as by setting a UUID primary key with
+// the uuid emulator's NewString. An error stops the insert.
+type BeforeCreateInterface interface {
+	BeforeCreate(tx *DB) error
+}
+
 // Open creates a new database connection
 func Open(dialect string, connectionString string) (*DB, error) {
 	if dialect == "" || connectionString == "" {
@@ -42,12 +65,23 @@ func Open(dialect string, connectionString string) (*DB, error) {
 	}
 	
 	return &DB{
-		records: make(map[string][]map[string]interface{}),
-		limit:   -1,
-		offset:  0,
+		records:  make(map[string][]map[string]interface{}),
+		limit:    -1,
+		offset:   0,
+		versions: make(map[string]int64),
 	}, nil
 }
 
+// UseCache returns a DB whose First, Find and Count results are cached
+// for ttl. Writes to a table through any DB from the same Open make its
+// cached results stale.
+func (db *DB) UseCache(cache QueryCache, ttl time.Duration) *DB {
+	newDB := db.clone()
+	newDB.cache = cache
+	newDB.cacheTTL = ttl
+	return newDB
+}
+
 // Table specifies the table to operate on
 func (db *DB) Table(name string) *DB {
 	newDB := db.clone()
@@ -86,7 +120,7 @@ func (db *DB) First(dest interface{}) *DB {
 		return newDB
 	}
 	
-	filtered := db.applyFilters(records)
+	filtered := db.cachedFilters(tableName, "first", records)
 	if len(filtered) == 0 {
 		newDB.Error = errors.New("record not found")
 		return newDB
@@ -111,7 +145,7 @@ func (db *DB) Find(dest interface{}) *DB {
 		return newDB
 	}
 	
-	filtered := db.applyFilters(records)
+	filtered := db.cachedFilters(tableName, "find", records)
 	
 	destValue := reflect.ValueOf(dest)
 	if destValue.Kind() != reflect.Ptr {
@@ -145,6 +179,13 @@ func (db *DB) Create(value interface{}) *DB {
 		tableName = getTableName(value)
 	}
 	
+	if hook, ok := value.(BeforeCreateInterface); ok {
+		if err := hook.BeforeCreate(newDB); err != nil {
+			newDB.Error = err
+			return newDB
+		}
+	}
+	
 	record := structToMap(value)
 	
 	// Set timestamps if they exist
@@ -162,6 +203,7 @@ func (db *DB) Create(value interface{}) *DB {
 	}
 	
 	newDB.records[tableName] = append(db.records[tableName], record)
+	db.touch(tableName)
 	mapToStruct(record, value)
 	
 	newDB.RowsAffected = 1
@@ -179,7 +221,7 @@ func (db *DB) Save(value interface{}) *DB {
 	record := structToMap(value)
 	id := record["ID"]
 	
-	if id == nil || id == uint(0) {
+	if id == nil || reflect.ValueOf(id).IsZero() {
 		return db.Create(value)
 	}
 	
@@ -192,6 +234,7 @@ func (db *DB) Save(value interface{}) *DB {
 				record["CreatedAt"] = createdAt
 			}
 			newDB.records[tableName][i] = record
+			db.touch(tableName)
 			found = true
 			newDB.RowsAffected = 1
 			break
@@ -230,6 +273,7 @@ func (db *DB) Updates(values map[string]interface{}) *DB {
 			newDB.records[tableName][idx][k] = v
 		}
 	}
+	db.touch(tableName)
 	
 	newDB.RowsAffected = int64(len(filtered))
 	return newDB
@@ -255,6 +299,7 @@ func (db *DB) Delete(value interface{}) *DB {
 	for _, idx := range filtered {
 		newDB.records[tableName][idx]["DeletedAt"] = &now
 	}
+	db.touch(tableName)
 	
 	newDB.RowsAffected = int64(len(filtered))
 	return newDB
@@ -300,7 +345,7 @@ func (db *DB) Count(count *int64) *DB {
 		return newDB
 	}
 	
-	filtered := db.applyFilters(records)
+	filtered := db.cachedFilters(tableName, "count", records)
 	*count = int64(len(filtered))
 	return newDB
 }
@@ -312,31 +357,101 @@ func (db *DB) Raw(sql string, values ...interface{}) *DB {
 	return newDB
 }
 
-// Exec executes raw SQL that doesn't return rows
+// Exec executes raw SQL that doesn't return rows. CREATE TABLE and DROP
+// TABLE statements change the tables; others are simulated.
 func (db *DB) Exec(sql string, values ...interface{}) *DB {
 	newDB := db.clone()
-	// Simulated execution
+	newDB.Error = db.ExecSQL(sql)
 	newDB.RowsAffected = 1
 	return newDB
 }
 
+// ErrInvalidTransaction is returned when committing or rolling back a DB
+// that is not an open transaction
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+// transaction is the state shared by the DBs chained from Begin. Writes
+// go to a copy of the tables, so nothing outside the transaction sees
+// them until Commit copies the touched tables back.
+type transaction struct {
+	parent  map[string][]map[string]interface{}
+	touched map[string]bool
+	done    bool
+}
+
 // Begin starts a transaction
 func (db *DB) Begin() *DB {
 	newDB := db.clone()
-	// Transaction simulation
+	newDB.records = copyTables(db.records)
+	newDB.tx = &transaction{parent: db.records, touched: map[string]bool{}}
+	// Results read inside the transaction must not be cached for others
+	newDB.cache = nil
 	return newDB
 }
 
-// Commit commits a transaction
+// Commit commits a transaction. Tables it wrote replace the tables
+// outside it, so the last transaction to commit a table wins.
 func (db *DB) Commit() *DB {
+	if db.tx == nil || db.tx.done {
+		db.Error = ErrInvalidTransaction
+		return db
+	}
+	db.tx.done = true
+	for tableName := range db.tx.touched {
+		if records, exists := db.records[tableName]; exists {
+			db.tx.parent[tableName] = records
+		} else {
+			delete(db.tx.parent, tableName)
+		}
+		db.touch(tableName)
+	}
 	return db
 }
 
-// Rollback rolls back a transaction
+// Rollback rolls back a transaction, discarding its writes
 func (db *DB) Rollback() *DB {
+	if db.tx == nil || db.tx.done {
+		db.Error = ErrInvalidTransaction
+		return db
+	}
+	db.tx.done = true
 	return db
 }
 
+// Transaction runs fc in a transaction, committing it if fc returns nil
+// and rolling it back if fc returns an error or panics
+func (db *DB) Transaction(fc func(tx *DB) error) (err error) {
+	tx := db.Begin()
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			tx.Rollback()
+		}
+	}()
+	if err = fc(tx); err == nil {
+		err = tx.Commit().Error
+	}
+	panicked = false
+	return err
+}
+
+// copyTables copies tables and their rows, so updates in place don't
+// reach the originals
+func copyTables(tables map[string][]map[string]interface{}) map[string][]map[string]interface{} {
+	copied := make(map[string][]map[string]interface{}, len(tables))
+	for tableName, records := range tables {
+		rows := make([]map[string]interface{}, len(records))
+		for i, record := range records {
+			rows[i] = make(map[string]interface{}, len(record))
+			for k, v := range record {
+				rows[i][k] = v
+			}
+		}
+		copied[tableName] = rows
+	}
+	return copied
+}
+
 // Helper functions
 
 func (db *DB) clone() *DB {
@@ -347,6 +462,38 @@ func (db *DB) clone() *DB {
 		limit:     db.limit,
 		offset:    db.offset,
 		order:     db.order,
+		cache:     db.cache,
+		cacheTTL:  db.cacheTTL,
+		versions:  db.versions,
+		tx:        db.tx,
+	}
+}
+
+// cachedFilters is applyFilters through the query cache, if one is in
+// use. Keys include the table's version, so writes make them stale.
+func (db *DB) cachedFilters(tableName, op string, records []map[string]interface{}) []map[string]interface{} {
+	if db.cache == nil {
+		return db.applyFilters(records)
+	}
+	key := fmt.Sprintf("gorm:%s:%d:%s:%v:%d:%d:%s", tableName, db.versions[tableName], op, db.where, db.limit, db.offset, db.order)
+	if cached, ok := db.cache.Get(key); ok {
+		if filtered, ok := cached.([]map[string]interface{}); ok {
+			return filtered
+		}
+	}
+	filtered := db.applyFilters(records)
+	db.cache.Set(key, filtered, db.cacheTTL)
+	return filtered
+}
+
+// touch marks a table's cached query results stale, and as written by
+// the transaction, if there is one
+func (db *DB) touch(tableName string) {
+	if db.tx != nil && !db.tx.done {
+		db.tx.touched[tableName] = true
+	}
+	if db.versions != nil {
+		db.versions[tableName]++
 	}
 }
 
@@ -498,7 +645,126 @@ func (db *DB) AutoMigrate(models ...interface{}) error {
 		tableName := getTableName(model)
 		if _, exists := db.records[tableName]; !exists {
 			db.records[tableName] = []map[string]interface{}{}
+			db.touch(tableName)
 		}
 	}
 	return nil
 }
+
+// The methods below give schema tools, such as the migrate emulator, access
+// to tables by name without going through a model
+
+// ExecSQL runs semicolon-separated statements. CREATE TABLE [IF NOT EXISTS]
+// and DROP TABLE [IF EXISTS] create and drop tables; other statements are
+// accepted without effect.
+func (db *DB) ExecSQL(sql string) error {
+	for _, statement := range splitStatements(sql) {
+		fields := strings.Fields(statement)
+		if len(fields) < 3 || !strings.EqualFold(fields[1], "TABLE") {
+			continue
+		}
+		verb := strings.ToUpper(fields[0])
+		if verb != "CREATE" && verb != "DROP" {
+			continue
+		}
+		
+		rest := fields[2:]
+		ifClause := false
+		for len(rest) > 1 && (strings.EqualFold(rest[0], "IF") || strings.EqualFold(rest[0], "NOT") || strings.EqualFold(rest[0], "EXISTS")) {
+			ifClause = true
+			rest = rest[1:]
+		}
+		tableName := strings.Trim(strings.SplitN(rest[0], "(", 2)[0], "`\"'")
+		
+		_, exists := db.records[tableName]
+		switch {
+		case verb == "CREATE" && exists && !ifClause:
+			return fmt.Errorf("table %s already exists", tableName)
+		case verb == "CREATE" && !exists:
+			db.records[tableName] = []map[string]interface{}{}
+		case verb == "DROP" && !exists && !ifClause:
+			return fmt.Errorf("no such table: %s", tableName)
+		case verb == "DROP" && exists:
+			delete(db.records, tableName)
+		}
+		db.touch(tableName)
+	}
+	return nil
+}
+
+// TableNames returns the names of the tables, sorted
+func (db *DB) TableNames() []string {
+	names := make([]string, 0, len(db.records))
+	for name := range db.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TableRows returns copies of a table's rows, including soft deleted ones,
+// and whether the table exists
+func (db *DB) TableRows(tableName string) ([]map[string]interface{}, bool) {
+	records, exists := db.records[tableName]
+	if !exists {
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		rows[i] = make(map[string]interface{}, len(record))
+		for k, v := range record {
+			rows[i][k] = v
+		}
+	}
+	return rows, true
+}
+
+// SetTableRows replaces a table's rows, creating the table if needed
+func (db *DB) SetTableRows(tableName string, rows []map[string]interface{}) {
+	records := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		records[i] = make(map[string]interface{}, len(row))
+		for k, v := range row {
+			records[i][k] = v
+		}
+	}
+	db.records[tableName] = records
+	db.touch(tableName)
+}
+
+// splitStatements splits SQL on semicolons outside quotes, dropping
+// comment lines and empty statements
+func splitStatements(sql string) []string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	sql = strings.Join(lines, "\n")
+	
+	var statements []string
+	var current strings.Builder
+	var quote rune
+	for _, r := range sql {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ';':
+			if s := strings.TrimSpace(current.String()); s != "" {
+				statements = append(statements, s)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		statements = append(statements, s)
+	}
+	return statements
+}
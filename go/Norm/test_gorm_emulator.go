@@ -2,7 +2,9 @@ package main
 
 // Developed by PowerShield, as an alternative to GORM
 import (
+	"errors"
 	"fmt"
+	"time"
 )
 
 // Test models
@@ -20,6 +22,42 @@ type Product struct {
 	Stock int
 }
 
+// Order has a string key set by its BeforeCreate hook, as a model would
+// with the uuid emulator's NewString
+type Order struct {
+	ID    string
+	Total float64
+}
+
+var nextOrderID int
+
+func (o *Order) BeforeCreate(tx *DB) error {
+	if o.Total < 0 {
+		return fmt.Errorf("order total %v is negative", o.Total)
+	}
+	nextOrderID++
+	o.ID = fmt.Sprintf("order-%d", nextOrderID)
+	return nil
+}
+
+// countingCache is a QueryCache that counts hits
+type countingCache struct {
+	items map[string]interface{}
+	hits  int
+}
+
+func (c *countingCache) Get(key string) (interface{}, bool) {
+	value, ok := c.items[key]
+	if ok {
+		c.hits++
+	}
+	return value, ok
+}
+
+func (c *countingCache) Set(key string, value interface{}, d time.Duration) {
+	c.items[key] = value
+}
+
 func main() {
 	fmt.Println("=== GORM Emulator Test Suite ===\n")
 	
@@ -229,5 +267,91 @@ func main() {
 		fmt.Println("❌ Table() method failed")
 	}
 	
+	// Test 21: Query cache
+	fmt.Println("\nTest 21: Query Cache")
+	cache := &countingCache{items: map[string]interface{}{}}
+	cached := db.UseCache(cache, time.Minute)
+	var firstRead, secondRead []User
+	cached.Find(&firstRead)
+	cached.Find(&secondRead)
+	hitsBeforeWrite := cache.hits
+	cached.Create(&User{Name: "Eve", Email: "eve@example.com", Age: 28})
+	var afterWrite []User
+	cached.Find(&afterWrite)
+	if hitsBeforeWrite == 1 && len(secondRead) == len(firstRead) && len(afterWrite) == len(firstRead)+1 && cache.hits == 1 {
+		fmt.Printf("✓ Cached %d users, refreshed after write: %d users\n", len(secondRead), len(afterWrite))
+	} else {
+		fmt.Printf("❌ Query cache returned %d then %d users with %d hits\n", len(secondRead), len(afterWrite), cache.hits)
+	}
+	
+	// Test 22: BeforeCreate hook
+	fmt.Println("\nTest 22: BeforeCreate Hook")
+	order := Order{Total: 42.5}
+	created := db.Create(&order)
+	rejected := db.Create(&Order{Total: -1})
+	var foundOrder Order
+	db.Where("id = ?", order.ID).First(&foundOrder)
+	order.Total = 50
+	saved := db.Save(&order)
+	if created.Error == nil && order.ID == "order-1" && foundOrder.Total == 42.5 && rejected.Error != nil && saved.Error == nil && saved.RowsAffected == 1 {
+		fmt.Printf("✓ BeforeCreate set ID %s and rejected: %v\n", order.ID, rejected.Error)
+	} else {
+		fmt.Printf("❌ BeforeCreate hook failed: ID=%q, rejected=%v\n", order.ID, rejected.Error)
+	}
+	
+	// Test 23: Schema statements and table access
+	fmt.Println("\nTest 23: Schema Statements")
+	schemaErr := db.ExecSQL(`
+		-- accounts; holds balances
+		CREATE TABLE IF NOT EXISTS accounts (id INT, note TEXT DEFAULT 'a;b');
+		CREATE TABLE "ledgers" (id INT);
+	`)
+	duplicateErr := db.ExecSQL("CREATE TABLE accounts (id INT)")
+	db.SetTableRows("accounts", []map[string]interface{}{{"ID": uint(1), "Balance": 10}})
+	accountRows, accountsExist := db.TableRows("accounts")
+	accountRows[0]["Balance"] = 99
+	fresh, _ := db.TableRows("accounts")
+	dropped := db.Exec("DROP TABLE ledgers; DROP TABLE IF EXISTS missing")
+	missingErr := db.ExecSQL("DROP TABLE ledgers")
+	_, ledgersExist := db.TableRows("ledgers")
+	names := db.TableNames()
+	if schemaErr == nil && duplicateErr != nil && accountsExist && fresh[0]["Balance"] == 10 && dropped.Error == nil && missingErr != nil && !ledgersExist && len(names) > 0 && names[0] == "accounts" {
+		fmt.Printf("✓ Created and dropped tables, now: %v\n", names)
+	} else {
+		fmt.Printf("❌ Schema statements failed: %v, %v, %v, tables %v\n", schemaErr, duplicateErr, missingErr, names)
+	}
+	
+	// Test 24: Transactions
+	fmt.Println("\nTest 24: Transactions")
+	var before, during, afterRollback, afterCommit int64
+	db.Model(&User{}).Count(&before)
+	rolledBack := db.Begin()
+	rolledBack.Create(&User{Name: "Frank", Email: "frank@example.com", Age: 33})
+	rolledBack.Model(&User{}).Where("name = ?", "Alice").Update("Age", 99)
+	db.Model(&User{}).Count(&during)
+	rolledBack.Rollback()
+	var alice User
+	db.Where("name = ?", "Alice").First(&alice)
+	db.Model(&User{}).Count(&afterRollback)
+	txErr := db.Transaction(func(tx *DB) error {
+		if err := tx.Create(&User{Name: "Grace", Email: "grace@example.com", Age: 36}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Product{Name: "TX1", Price: 5}).Error
+	})
+	failErr := db.Transaction(func(tx *DB) error {
+		tx.Create(&User{Name: "Heidi", Email: "heidi@example.com", Age: 41})
+		return errors.New("payment declined")
+	})
+	db.Model(&User{}).Count(&afterCommit)
+	var heidi User
+	heidiMissing := db.Where("name = ?", "Heidi").First(&heidi).Error != nil
+	doubleCommit := rolledBack.Commit().Error
+	if during == before && afterRollback == before && alice.Age != 99 && txErr == nil && failErr != nil && afterCommit == before+1 && heidiMissing && doubleCommit == ErrInvalidTransaction {
+		fmt.Printf("✓ Rolled back and committed: %d users, then %d\n", before, afterCommit)
+	} else {
+		fmt.Printf("❌ Transactions failed: %d, %d, %d, %d users, errors %v, %v, %v\n", before, during, afterRollback, afterCommit, txErr, failErr, doubleCommit)
+	}
+	
 	fmt.Println("\n=== All Tests Completed ===")
 }
@@ -0,0 +1,740 @@
+package main
+
+// Developed by PowerShield, as an alternative to Watermill
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var timeNow = time.Now
+
+// Messages
+
+// Metadata holds a message's headers
+type Metadata map[string]string
+
+// Get returns a header, or "" if it is not set
+func (m Metadata) Get(key string) string {
+	return m[key]
+}
+
+// Set sets a header
+func (m Metadata) Set(key, value string) {
+	m[key] = value
+}
+
+// Payload is a message's body
+type Payload []byte
+
+type ackType int
+
+const (
+	noAckSent ackType = iota
+	ackSent
+	nackSent
+)
+
+// Message is one event. A subscriber receives its own copy and must Ack
+// it once handled, or Nack it to have it delivered again.
+type Message struct {
+	UUID     string
+	Metadata Metadata
+	Payload  Payload
+
+	ack     chan struct{}
+	noAck   chan struct{}
+	ackMu   sync.Mutex
+	ackSent ackType
+	ctx     context.Context
+}
+
+// NewMessage returns a message with an ID and a payload
+func NewMessage(uuid string, payload Payload) *Message {
+	return &Message{
+		UUID:     uuid,
+		Metadata: make(Metadata),
+		Payload:  payload,
+		ack:      make(chan struct{}),
+		noAck:    make(chan struct{}),
+	}
+}
+
+// Ack marks the message handled. It returns false if the message was
+// nacked already.
+func (m *Message) Ack() bool {
+	m.ackMu.Lock()
+	defer m.ackMu.Unlock()
+	if m.ackSent == nackSent {
+		return false
+	}
+	if m.ackSent == noAckSent {
+		m.ackSent = ackSent
+		close(m.ack)
+	}
+	return true
+}
+
+// Nack marks the message failed, so it is delivered again. It returns
+// false if the message was acked already.
+func (m *Message) Nack() bool {
+	m.ackMu.Lock()
+	defer m.ackMu.Unlock()
+	if m.ackSent == ackSent {
+		return false
+	}
+	if m.ackSent == noAckSent {
+		m.ackSent = nackSent
+		close(m.noAck)
+	}
+	return true
+}
+
+// Acked is closed when the message is acked
+func (m *Message) Acked() <-chan struct{} {
+	return m.ack
+}
+
+// Nacked is closed when the message is nacked
+func (m *Message) Nacked() <-chan struct{} {
+	return m.noAck
+}
+
+// Context returns the message's context, which is the subscription's
+func (m *Message) Context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+// SetContext sets the message's context
+func (m *Message) SetContext(ctx context.Context) {
+	m.ctx = ctx
+}
+
+// Copy returns a copy of the message that is neither acked nor nacked
+func (m *Message) Copy() *Message {
+	msg := NewMessage(m.UUID, m.Payload)
+	for k, v := range m.Metadata {
+		msg.Metadata.Set(k, v)
+	}
+	return msg
+}
+
+// NewUUID returns a random UUID for a message
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Publisher publishes messages to topics
+type Publisher interface {
+	Publish(topic string, messages ...*Message) error
+	Close() error
+}
+
+// Subscriber delivers a topic's messages on a channel, which is closed
+// when ctx is done or the subscriber is closed
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic string) (<-chan *Message, error)
+	Close() error
+}
+
+// Metadata the bus sets on the messages it delivers
+const (
+	// DeliveryCountKey is the delivery attempt, starting at 1
+	DeliveryCountKey = "delivery_count"
+	// DeadLetterTopicKey is the topic a dead letter was published to
+	DeadLetterTopicKey = "dead_letter_topic"
+	// DeadLetterReasonKey says why a message was given up on
+	DeadLetterReasonKey = "dead_letter_reason"
+)
+
+// DeliveryCount returns how many times a message has been delivered,
+// counting this time
+func DeliveryCount(msg *Message) int {
+	n, _ := strconv.Atoi(msg.Metadata.Get(DeliveryCountKey))
+	return n
+}
+
+// Event bus
+
+// ErrClosed is returned by a closed bus
+var ErrClosed = errors.New("event bus is closed")
+
+// DefaultMaxDeliveries is how many times a message is delivered before
+// it becomes a dead letter, unless Config says otherwise
+const DefaultMaxDeliveries = 5
+
+// DefaultDeadLetterSuffix is added to a topic to name its dead letter
+// topic
+const DefaultDeadLetterSuffix = ".dead_letter"
+
+// Config configures an EventBus
+type Config struct {
+	// OutputChannelBuffer is the size of subscription channels
+	OutputChannelBuffer int64
+	// Persistent keeps published messages for subscribers that come later
+	Persistent bool
+	// MaxDeliveries is how many deliveries a message gets before it is a
+	// dead letter: 0 means DefaultMaxDeliveries and -1 means no limit
+	MaxDeliveries int
+	// RedeliveryDelay is the wait before delivering a nacked message again
+	RedeliveryDelay time.Duration
+	// AckTimeout is how long a subscriber has to ack or nack a message
+	// before it is delivered again; 0 means no limit
+	AckTimeout time.Duration
+	// DeadLetterSuffix names dead letter topics; "" means
+	// DefaultDeadLetterSuffix
+	DeadLetterSuffix string
+}
+
+// Stats counts what the bus has done
+type Stats struct {
+	Published    int
+	Delivered    int
+	Acked        int
+	Nacked       int
+	TimedOut     int
+	Redelivered  int
+	DeadLettered int
+	// Pending is the messages queued or waiting for an ack
+	Pending int
+}
+
+// EventBus is an in-memory Publisher and Subscriber with at-least-once
+// delivery. Each subscription has its own queue, delivered in order, one
+// message at a time: a message is redelivered until it is acked, and
+// after MaxDeliveries it is published to the topic's dead letter topic.
+type EventBus struct {
+	config Config
+
+	mu          sync.Mutex
+	subscribers map[string][]*subscription
+	persisted   map[string][]*Message
+	deadLetters map[string][]*Message
+	stats       Stats
+	closed      bool
+	closing     chan struct{}
+	wg          sync.WaitGroup
+}
+
+// pending is a message waiting in a subscription's queue
+type pending struct {
+	msg        *Message
+	deliveries int
+	notBefore  time.Time
+}
+
+// subscription is one subscriber's queue
+type subscription struct {
+	bus    *EventBus
+	ctx    context.Context
+	topic  string
+	out    chan *Message
+	queue  []*pending
+	notify chan struct{}
+}
+
+// NewEventBus returns an empty bus
+func NewEventBus(config Config) *EventBus {
+	if config.MaxDeliveries == 0 {
+		config.MaxDeliveries = DefaultMaxDeliveries
+	}
+	if config.DeadLetterSuffix == "" {
+		config.DeadLetterSuffix = DefaultDeadLetterSuffix
+	}
+	return &EventBus{
+		config:      config,
+		subscribers: make(map[string][]*subscription),
+		persisted:   make(map[string][]*Message),
+		deadLetters: make(map[string][]*Message),
+		closing:     make(chan struct{}),
+	}
+}
+
+// Publish queues the messages for every subscription to topic. Without
+// subscriptions, and unless the bus is persistent, they are dropped.
+func (b *EventBus) Publish(topic string, messages ...*Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.stats.Published += len(messages)
+	b.publishLocked(topic, messages)
+	return nil
+}
+
+func (b *EventBus) publishLocked(topic string, messages []*Message) {
+	for _, msg := range messages {
+		if b.config.Persistent {
+			b.persisted[topic] = append(b.persisted[topic], msg.Copy())
+		}
+		for _, s := range b.subscribers[topic] {
+			s.enqueue(&pending{msg: msg.Copy()})
+		}
+	}
+}
+
+// Subscribe returns a channel of topic's messages. A persistent bus
+// delivers the messages published before too.
+func (b *EventBus) Subscribe(ctx context.Context, topic string) (<-chan *Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	s := &subscription{
+		bus:    b,
+		ctx:    ctx,
+		topic:  topic,
+		out:    make(chan *Message, b.config.OutputChannelBuffer),
+		notify: make(chan struct{}, 1),
+	}
+	for _, msg := range b.persisted[topic] {
+		s.enqueue(&pending{msg: msg.Copy()})
+	}
+	b.subscribers[topic] = append(b.subscribers[topic], s)
+	b.wg.Add(1)
+	go s.run()
+	return s.out, nil
+}
+
+// Close stops delivery and closes every subscription's channel
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.closing)
+	b.mu.Unlock()
+	b.wg.Wait()
+	return nil
+}
+
+// DeadLetters returns the messages given up on for topic, with
+// DeadLetterTopicKey and DeadLetterReasonKey set
+func (b *EventBus) DeadLetters(topic string) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	letters := make([]*Message, len(b.deadLetters[topic]))
+	for i, msg := range b.deadLetters[topic] {
+		letters[i] = msg.Copy()
+	}
+	return letters
+}
+
+// Stats returns the bus's counters
+func (b *EventBus) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	for _, subs := range b.subscribers {
+		for _, s := range subs {
+			stats.Pending += len(s.queue)
+		}
+	}
+	return stats
+}
+
+// WaitIdle waits until no messages are queued or waiting for an ack, and
+// reports whether that happened within timeout
+func (b *EventBus) WaitIdle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if b.Stats().Pending == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// deadLetterLocked gives up on a message, publishing it to the dead
+// letter topic
+func (b *EventBus) deadLetterLocked(topic string, p *pending, reason string) {
+	msg := p.msg.Copy()
+	msg.Metadata.Set(DeliveryCountKey, strconv.Itoa(p.deliveries))
+	msg.Metadata.Set(DeadLetterTopicKey, topic)
+	msg.Metadata.Set(DeadLetterReasonKey, reason)
+	b.deadLetters[topic] = append(b.deadLetters[topic], msg)
+	b.stats.DeadLettered++
+	b.publishLocked(topic+b.config.DeadLetterSuffix, []*Message{msg})
+}
+
+// enqueue adds a message to the back of the queue. The bus's lock is
+// held.
+func (s *subscription) enqueue(p *pending) {
+	s.queue = append(s.queue, p)
+	s.wake()
+}
+
+func (s *subscription) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for the message at the front of the queue to be due
+func (s *subscription) next() (*pending, bool) {
+	for {
+		s.bus.mu.Lock()
+		var timer *time.Timer
+		var due <-chan time.Time
+		if len(s.queue) > 0 {
+			p := s.queue[0]
+			delay := p.notBefore.Sub(timeNow())
+			if delay <= 0 {
+				s.bus.mu.Unlock()
+				return p, true
+			}
+			timer = time.NewTimer(delay)
+			due = timer.C
+		}
+		s.bus.mu.Unlock()
+
+		ok := true
+		select {
+		case <-s.notify:
+		case <-due:
+		case <-s.ctx.Done():
+			ok = false
+		case <-s.bus.closing:
+			ok = false
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+}
+
+// run delivers the queue, one message at a time
+func (s *subscription) run() {
+	defer s.bus.wg.Done()
+	defer s.remove()
+	defer close(s.out)
+
+	for {
+		p, ok := s.next()
+		if !ok {
+			return
+		}
+		p.deliveries++
+		msg := p.msg.Copy()
+		msg.Metadata.Set(DeliveryCountKey, strconv.Itoa(p.deliveries))
+		msg.SetContext(s.ctx)
+
+		select {
+		case s.out <- msg:
+		case <-s.ctx.Done():
+			return
+		case <-s.bus.closing:
+			return
+		}
+		s.bus.mu.Lock()
+		s.bus.stats.Delivered++
+		if p.deliveries > 1 {
+			s.bus.stats.Redelivered++
+		}
+		s.bus.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if s.bus.config.AckTimeout > 0 {
+			timer = time.NewTimer(s.bus.config.AckTimeout)
+			timeout = timer.C
+		}
+		var outcome string
+		select {
+		case <-msg.Acked():
+			outcome = "acked"
+		case <-msg.Nacked():
+			outcome = "nacked"
+		case <-timeout:
+			outcome = "ack timeout"
+		case <-s.ctx.Done():
+		case <-s.bus.closing:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		switch outcome {
+		case "acked":
+			s.done()
+		case "nacked":
+			s.retry(p, outcome, func(stats *Stats) { stats.Nacked++ })
+		case "ack timeout":
+			s.retry(p, outcome, func(stats *Stats) { stats.TimedOut++ })
+		default:
+			return
+		}
+	}
+}
+
+// done removes the front message once it is acked
+func (s *subscription) done() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.stats.Acked++
+	s.queue = s.queue[1:]
+}
+
+// retry leaves a failed message at the front of the queue, to be
+// delivered again after the redelivery delay, or gives up on it
+func (s *subscription) retry(p *pending, reason string, count func(*Stats)) {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	count(&s.bus.stats)
+	max := s.bus.config.MaxDeliveries
+	if max > 0 && p.deliveries >= max {
+		s.queue = s.queue[1:]
+		s.bus.deadLetterLocked(s.topic, p, reason)
+		return
+	}
+	p.notBefore = timeNow().Add(s.bus.config.RedeliveryDelay)
+}
+
+// remove drops the subscription from the bus, with whatever it had
+// queued
+func (s *subscription) remove() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	subs := s.bus.subscribers[s.topic]
+	for i, other := range subs {
+		if other == s {
+			s.bus.subscribers[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	s.queue = nil
+}
+
+// Handlers
+
+// HandlerFunc handles a message. Returning nil acks it; returning an
+// error or panicking nacks it.
+type HandlerFunc func(msg *Message) error
+
+// Handle subscribes to topic and runs handler on each message until ctx
+// is done
+func Handle(ctx context.Context, sub Subscriber, topic string, handler HandlerFunc) error {
+	messages, err := sub.Subscribe(ctx, topic)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for msg := range messages {
+			if err := safeHandle(handler, msg); err != nil {
+				msg.Nack()
+			} else {
+				msg.Ack()
+			}
+		}
+	}()
+	return nil
+}
+
+func safeHandle(handler HandlerFunc, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(msg)
+}
+
+// Transactional outbox
+
+// OutboxStore is the database an outbox table is kept in. The GORM
+// emulator's DB implements it, and a DB from its Begin writes the table
+// in the transaction.
+type OutboxStore interface {
+	TableRows(table string) ([]map[string]interface{}, bool)
+	SetTableRows(table string, rows []map[string]interface{})
+}
+
+// DefaultOutboxTable is the outbox table, unless configured otherwise
+const DefaultOutboxTable = "outbox_events"
+
+// OutboxConfig configures an OutboxPublisher
+type OutboxConfig struct {
+	Table string
+}
+
+// OutboxPublisher is a Publisher that writes messages to an outbox table
+// instead of a bus. Given a transaction, the messages are saved if and
+// only if the transaction's other writes are; a Relay publishes them
+// afterward.
+type OutboxPublisher struct {
+	store OutboxStore
+	table string
+}
+
+// NewOutboxPublisher returns a publisher writing to store
+func NewOutboxPublisher(store OutboxStore, config OutboxConfig) *OutboxPublisher {
+	if config.Table == "" {
+		config.Table = DefaultOutboxTable
+	}
+	return &OutboxPublisher{store: store, table: config.Table}
+}
+
+// Publish adds the messages to the outbox, in order
+func (p *OutboxPublisher) Publish(topic string, messages ...*Message) error {
+	rows, _ := p.store.TableRows(p.table)
+	var nextID uint
+	for _, row := range rows {
+		if id, ok := row["ID"].(uint); ok && id > nextID {
+			nextID = id
+		}
+	}
+	for _, msg := range messages {
+		metadata, err := json.Marshal(msg.Metadata)
+		if err != nil {
+			return err
+		}
+		nextID++
+		rows = append(rows, map[string]interface{}{
+			"ID":        nextID,
+			"UUID":      msg.UUID,
+			"Topic":     topic,
+			"Payload":   string(msg.Payload),
+			"Metadata":  string(metadata),
+			"CreatedAt": timeNow(),
+		})
+	}
+	p.store.SetTableRows(p.table, rows)
+	return nil
+}
+
+// Close does nothing; the store belongs to the caller
+func (p *OutboxPublisher) Close() error {
+	return nil
+}
+
+// RelayConfig configures a Relay
+type RelayConfig struct {
+	// Table is the outbox table; "" means DefaultOutboxTable
+	Table string
+	// Interval is how often Run checks the outbox; 0 means 100ms
+	Interval time.Duration
+	// BatchSize limits how many messages one check relays; 0 means all
+	BatchSize int
+}
+
+// Relay moves messages from an outbox table to a publisher. A message is
+// removed from the outbox only after it is published, so a failure
+// between the two publishes it again: consumers see each message at least
+// once, and may see it twice.
+type Relay struct {
+	store     OutboxStore
+	publisher Publisher
+	config    RelayConfig
+	mu        sync.Mutex
+}
+
+// NewRelay returns a relay from store's outbox to publisher
+func NewRelay(store OutboxStore, publisher Publisher, config RelayConfig) *Relay {
+	if config.Table == "" {
+		config.Table = DefaultOutboxTable
+	}
+	if config.Interval == 0 {
+		config.Interval = 100 * time.Millisecond
+	}
+	return &Relay{store: store, publisher: publisher, config: config}
+}
+
+// RelayPending publishes the outbox's messages in the order they were
+// written and removes them, returning how many were relayed. It stops at
+// the first failure, leaving that message and the rest for next time.
+func (r *Relay) RelayPending() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rows, _ := r.store.TableRows(r.config.Table)
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, _ := rows[i]["ID"].(uint)
+		b, _ := rows[j]["ID"].(uint)
+		return a < b
+	})
+	if r.config.BatchSize > 0 && len(rows) > r.config.BatchSize {
+		rows = rows[:r.config.BatchSize]
+	}
+
+	relayed := map[interface{}]bool{}
+	var relayErr error
+	for _, row := range rows {
+		msg, err := outboxMessage(row)
+		if err == nil {
+			topic, _ := row["Topic"].(string)
+			err = r.publisher.Publish(topic, msg)
+		}
+		if err != nil {
+			relayErr = fmt.Errorf("relaying outbox message %v: %w", row["UUID"], err)
+			break
+		}
+		relayed[row["ID"]] = true
+	}
+
+	// Read the table again, so rows written since are kept
+	if len(relayed) > 0 {
+		current, _ := r.store.TableRows(r.config.Table)
+		remaining := current[:0]
+		for _, row := range current {
+			if !relayed[row["ID"]] {
+				remaining = append(remaining, row)
+			}
+		}
+		r.store.SetTableRows(r.config.Table, remaining)
+	}
+	return len(relayed), relayErr
+}
+
+// Run relays the outbox every Interval until ctx is done. Failures are
+// retried at the next interval.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		r.RelayPending()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// outboxMessage rebuilds a message from an outbox row
+func outboxMessage(row map[string]interface{}) (*Message, error) {
+	uuid, _ := row["UUID"].(string)
+	payload, _ := row["Payload"].(string)
+	msg := NewMessage(uuid, Payload(payload))
+	if metadata, _ := row["Metadata"].(string); metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &msg.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,642 @@
+package main
+
+// Developed by PowerShield, as an alternative to Watermill
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// receive waits briefly for a message, returning nil if none comes
+func receive(messages <-chan *Message) *Message {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(time.Second):
+		return nil
+	}
+}
+
+// quiet reports whether no message comes for a short while
+func quiet(messages <-chan *Message) bool {
+	select {
+	case <-messages:
+		return false
+	case <-time.After(30 * time.Millisecond):
+		return true
+	}
+}
+
+// fakeDB keeps tables in memory the way the GORM emulator does,
+// including its transactions: Begin copies the tables, and Commit writes
+// back the ones the transaction wrote
+type fakeDB struct {
+	mu      *sync.Mutex
+	tables  map[string][]map[string]interface{}
+	parent  *fakeDB
+	touched map[string]bool
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{mu: &sync.Mutex{}, tables: map[string][]map[string]interface{}{}}
+}
+
+func (db *fakeDB) TableRows(table string) ([]map[string]interface{}, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	rows, ok := db.tables[table]
+	return append([]map[string]interface{}{}, rows...), ok
+}
+
+func (db *fakeDB) SetTableRows(table string, rows []map[string]interface{}) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.tables[table] = append([]map[string]interface{}{}, rows...)
+	if db.touched != nil {
+		db.touched[table] = true
+	}
+}
+
+func (db *fakeDB) Begin() *fakeDB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tx := &fakeDB{mu: db.mu, tables: map[string][]map[string]interface{}{}, parent: db, touched: map[string]bool{}}
+	for name, rows := range db.tables {
+		tx.tables[name] = append([]map[string]interface{}{}, rows...)
+	}
+	return tx
+}
+
+func (db *fakeDB) Commit() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for name := range db.touched {
+		db.parent.tables[name] = db.tables[name]
+	}
+}
+
+func (db *fakeDB) Transaction(fc func(tx *fakeDB) error) error {
+	tx := db.Begin()
+	if err := fc(tx); err != nil {
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// flakyPublisher fails to publish failOn the first failures times, and
+// records what it publishes
+type flakyPublisher struct {
+	mu        sync.Mutex
+	failOn    string
+	failures  int
+	published []string
+}
+
+func (p *flakyPublisher) Publish(topic string, messages ...*Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, msg := range messages {
+		if string(msg.Payload) == p.failOn && p.failures > 0 {
+			p.failures--
+			return errors.New("broker unavailable")
+		}
+		p.published = append(p.published, topic+":"+string(msg.Payload))
+	}
+	return nil
+}
+
+func (p *flakyPublisher) Close() error { return nil }
+
+func testMessages() bool {
+	msg := NewMessage("id-1", Payload(`{"order":1}`))
+	msg.Metadata.Set("correlation_id", "c-9")
+	if msg.Metadata.Get("correlation_id") != "c-9" || msg.Metadata.Get("missing") != "" {
+		return false
+	}
+
+	// Ack and Nack are exclusive, and repeating one is fine
+	if !msg.Ack() || !msg.Ack() || msg.Nack() {
+		return false
+	}
+	select {
+	case <-msg.Acked():
+	default:
+		return false
+	}
+
+	// Copies start unacked, with their own metadata
+	c := msg.Copy()
+	c.Metadata.Set("correlation_id", "other")
+	if !c.Nack() || c.Ack() || msg.Metadata.Get("correlation_id") != "c-9" || c.UUID != "id-1" {
+		return false
+	}
+	if msg.Context() == nil {
+		return false
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	return uuid.MatchString(NewUUID()) && NewUUID() != NewUUID()
+}
+
+func testPublishSubscribe() bool {
+	bus := NewEventBus(Config{})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := bus.Subscribe(ctx, "orders.created")
+	if err != nil {
+		return false
+	}
+	msg := NewMessage(NewUUID(), Payload("order-1"))
+	msg.Metadata.Set("tenant", "acme")
+	if err := bus.Publish("orders.created", msg); err != nil {
+		return false
+	}
+
+	got := receive(messages)
+	if got == nil || string(got.Payload) != "order-1" || got.UUID != msg.UUID ||
+		got.Metadata.Get("tenant") != "acme" || DeliveryCount(got) != 1 || got.Context() != ctx {
+		return false
+	}
+	got.Ack()
+	if !bus.WaitIdle(time.Second) {
+		return false
+	}
+	stats := bus.Stats()
+	return stats.Published == 1 && stats.Delivered == 1 && stats.Acked == 1 && stats.Pending == 0
+}
+
+func testFanOut() bool {
+	bus := NewEventBus(Config{})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	billing, _ := bus.Subscribe(ctx, "orders.created")
+	shipping, _ := bus.Subscribe(ctx, "orders.created")
+	bus.Publish("orders.created", NewMessage("m1", Payload("order-1")))
+
+	// Each subscription has its own copy; billing failing doesn't make
+	// shipping see the message again
+	b := receive(billing)
+	s := receive(shipping)
+	if b == nil || s == nil || b == s {
+		return false
+	}
+	s.Ack()
+	b.Nack()
+	again := receive(billing)
+	if again == nil || again.UUID != "m1" || DeliveryCount(again) != 2 {
+		return false
+	}
+	again.Ack()
+	return quiet(shipping) && bus.WaitIdle(time.Second) && bus.Stats().Delivered == 3
+}
+
+func testTopics() bool {
+	bus := NewEventBus(Config{})
+	defer bus.Close()
+
+	// Without subscribers, messages are dropped
+	bus.Publish("orders.created", NewMessage("early", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	orders, _ := bus.Subscribe(ctx, "orders.created")
+	refunds, _ := bus.Subscribe(ctx, "orders.refunded")
+	bus.Publish("orders.refunded", NewMessage("r1", nil))
+
+	got := receive(refunds)
+	if got == nil || got.UUID != "r1" {
+		return false
+	}
+	got.Ack()
+	return quiet(orders) && bus.Stats().Published == 2
+}
+
+func testOrdering() bool {
+	bus := NewEventBus(Config{})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := bus.Subscribe(ctx, "ledger")
+	bus.Publish("ledger", NewMessage("1", nil), NewMessage("2", nil), NewMessage("3", nil))
+
+	// One message at a time: the next waits for the ack
+	first := receive(messages)
+	if first == nil || first.UUID != "1" || !quiet(messages) || bus.Stats().Pending != 3 {
+		return false
+	}
+	first.Ack()
+
+	// A nacked message is delivered again before the ones behind it
+	second := receive(messages)
+	second.Nack()
+	second = receive(messages)
+	if second == nil || second.UUID != "2" || DeliveryCount(second) != 2 {
+		return false
+	}
+	second.Ack()
+	third := receive(messages)
+	third.Ack()
+	return third.UUID == "3" && bus.WaitIdle(time.Second)
+}
+
+func testRedeliveryDelay() bool {
+	bus := NewEventBus(Config{RedeliveryDelay: 50 * time.Millisecond})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := bus.Subscribe(ctx, "emails")
+	bus.Publish("emails", NewMessage("welcome", nil))
+
+	first := receive(messages)
+	nacked := time.Now()
+	first.Nack()
+	second := receive(messages)
+	if second == nil || time.Since(nacked) < 50*time.Millisecond {
+		return false
+	}
+	second.Ack()
+	bus.WaitIdle(time.Second)
+	stats := bus.Stats()
+	return stats.Nacked == 1 && stats.Redelivered == 1 && stats.Acked == 1
+}
+
+func testAckTimeout() bool {
+	bus := NewEventBus(Config{AckTimeout: 20 * time.Millisecond})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := bus.Subscribe(ctx, "thumbnails")
+	bus.Publish("thumbnails", NewMessage("img-1", nil))
+
+	// A consumer that crashed mid-way never acks; the message comes back
+	first := receive(messages)
+	second := receive(messages)
+	if first == nil || second == nil || DeliveryCount(second) != 2 {
+		return false
+	}
+	second.Ack()
+	// Acking the abandoned copy late changes nothing
+	first.Ack()
+	bus.WaitIdle(time.Second)
+	stats := bus.Stats()
+	return stats.TimedOut == 1 && stats.Acked == 1 && stats.Delivered == 2
+}
+
+func testDeadLetters() bool {
+	bus := NewEventBus(Config{MaxDeliveries: 3})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := bus.Subscribe(ctx, "payments")
+	dlq, _ := bus.Subscribe(ctx, "payments"+DefaultDeadLetterSuffix)
+
+	poison := NewMessage("p1", Payload("not json"))
+	bus.Publish("payments", poison, NewMessage("p2", Payload("{}")))
+	for i := 1; i <= 3; i++ {
+		msg := receive(messages)
+		if msg == nil || msg.UUID != "p1" || DeliveryCount(msg) != i {
+			return false
+		}
+		msg.Nack()
+	}
+
+	// The queue moves on, and the dead letter says where it came from
+	next := receive(messages)
+	if next == nil || next.UUID != "p2" {
+		return false
+	}
+	next.Ack()
+	letter := receive(dlq)
+	if letter == nil || letter.UUID != "p1" || letter.Metadata.Get(DeadLetterTopicKey) != "payments" ||
+		letter.Metadata.Get(DeadLetterReasonKey) != "nacked" || string(letter.Payload) != "not json" {
+		return false
+	}
+	letter.Ack()
+	bus.WaitIdle(time.Second)
+
+	letters := bus.DeadLetters("payments")
+	stats := bus.Stats()
+	if len(letters) != 1 || letters[0].Metadata.Get(DeliveryCountKey) != "3" ||
+		stats.DeadLettered != 1 || stats.Published != 2 || len(bus.DeadLetters("other")) != 0 {
+		return false
+	}
+
+	// Without a limit, messages are retried for as long as it takes
+	unlimited := NewEventBus(Config{MaxDeliveries: -1})
+	defer unlimited.Close()
+	retries, _ := unlimited.Subscribe(ctx, "sync")
+	unlimited.Publish("sync", NewMessage("s1", nil))
+	for i := 0; i < DefaultMaxDeliveries+2; i++ {
+		receive(retries).Nack()
+	}
+	last := receive(retries)
+	if last == nil || DeliveryCount(last) != DefaultMaxDeliveries+3 {
+		return false
+	}
+	last.Ack()
+	return unlimited.WaitIdle(time.Second) && len(unlimited.DeadLetters("sync")) == 0
+}
+
+func testPersistent() bool {
+	bus := NewEventBus(Config{Persistent: true})
+	defer bus.Close()
+	bus.Publish("audit", NewMessage("a1", nil), NewMessage("a2", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	late, _ := bus.Subscribe(ctx, "audit")
+	a1 := receive(late)
+	a1.Ack()
+	a2 := receive(late)
+	a2.Ack()
+	if a1 == nil || a2 == nil || a1.UUID != "a1" || a2.UUID != "a2" {
+		return false
+	}
+
+	// Each late subscriber gets the history again
+	later, _ := bus.Subscribe(ctx, "audit")
+	again := receive(later)
+	if again == nil || again.UUID != "a1" {
+		return false
+	}
+	again.Ack()
+	receive(later).Ack()
+	return bus.WaitIdle(time.Second)
+}
+
+func testHandlers() bool {
+	bus := NewEventBus(Config{MaxDeliveries: 4})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	handled := make(chan string, 10)
+	err := Handle(ctx, bus, "invoices", func(msg *Message) error {
+		mu.Lock()
+		attempts[msg.UUID]++
+		n := attempts[msg.UUID]
+		mu.Unlock()
+		switch {
+		case msg.UUID == "flaky" && n < 3:
+			return errors.New("timeout talking to the mail server")
+		case msg.UUID == "crash" && n == 1:
+			panic("nil map")
+		}
+		handled <- msg.UUID
+		return nil
+	})
+	if err != nil {
+		return false
+	}
+	bus.Publish("invoices", NewMessage("flaky", nil), NewMessage("crash", nil), NewMessage("fine", nil))
+
+	var order []string
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-handled:
+			order = append(order, id)
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	bus.WaitIdle(time.Second)
+	mu.Lock()
+	defer mu.Unlock()
+	return fmt.Sprint(order) == "[flaky crash fine]" && attempts["flaky"] == 3 &&
+		attempts["crash"] == 2 && bus.Stats().Nacked == 3
+}
+
+func testClose() bool {
+	bus := NewEventBus(Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled, _ := bus.Subscribe(ctx, "t")
+	open, _ := bus.Subscribe(context.Background(), "t")
+
+	// Cancelling the context ends that subscription only
+	cancel()
+	select {
+	case _, ok := <-cancelled:
+		if ok {
+			return false
+		}
+	case <-time.After(time.Second):
+		return false
+	}
+	bus.Publish("t", NewMessage("m", nil))
+	receive(open).Ack()
+
+	bus.Close()
+	if _, ok := <-open; ok {
+		return false
+	}
+	if err := bus.Publish("t", NewMessage("late", nil)); err != ErrClosed {
+		return false
+	}
+	_, err := bus.Subscribe(context.Background(), "t")
+	return err == ErrClosed && bus.Close() == nil
+}
+
+func testOutboxTransactions() bool {
+	db := newFakeDB()
+
+	// The order and its event are written together...
+	err := db.Transaction(func(tx *fakeDB) error {
+		tx.SetTableRows("orders", []map[string]interface{}{{"ID": uint(1), "Total": 42.5}})
+		msg := NewMessage("evt-1", Payload(`{"order_id":1}`))
+		msg.Metadata.Set("type", "OrderCreated")
+		return NewOutboxPublisher(tx, OutboxConfig{}).Publish("orders.created", msg)
+	})
+	if err != nil {
+		return false
+	}
+
+	// ...or not at all
+	failed := db.Transaction(func(tx *fakeDB) error {
+		tx.SetTableRows("orders", nil)
+		NewOutboxPublisher(tx, OutboxConfig{}).Publish("orders.cancelled", NewMessage("evt-2", nil))
+		return errors.New("insufficient stock")
+	})
+	if failed == nil {
+		return false
+	}
+
+	rows, ok := db.TableRows(DefaultOutboxTable)
+	if !ok || len(rows) != 1 || rows[0]["UUID"] != "evt-1" || rows[0]["Topic"] != "orders.created" ||
+		rows[0]["Payload"] != `{"order_id":1}` || rows[0]["ID"] != uint(1) {
+		return false
+	}
+	orders, _ := db.TableRows("orders")
+	if len(orders) != 1 {
+		return false
+	}
+
+	// A custom table
+	NewOutboxPublisher(db, OutboxConfig{Table: "events"}).Publish("x", NewMessage("e", nil))
+	events, _ := db.TableRows("events")
+	return len(events) == 1
+}
+
+func testRelay() bool {
+	db := newFakeDB()
+	outbox := NewOutboxPublisher(db, OutboxConfig{})
+	first := NewMessage("e1", Payload("one"))
+	first.Metadata.Set("type", "OrderCreated")
+	outbox.Publish("orders", first, NewMessage("e2", Payload("two")))
+	outbox.Publish("orders", NewMessage("e3", Payload("three")))
+
+	bus := NewEventBus(Config{})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := bus.Subscribe(ctx, "orders")
+
+	relay := NewRelay(db, bus, RelayConfig{BatchSize: 2})
+	n, err := relay.RelayPending()
+	if n != 2 || err != nil {
+		return false
+	}
+	rows, _ := db.TableRows(DefaultOutboxTable)
+	if len(rows) != 1 || rows[0]["UUID"] != "e3" {
+		return false
+	}
+	if n, _ := relay.RelayPending(); n != 1 {
+		return false
+	}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		msg := receive(messages)
+		if msg == nil {
+			return false
+		}
+		if msg.UUID == "e1" && msg.Metadata.Get("type") != "OrderCreated" {
+			return false
+		}
+		got = append(got, string(msg.Payload))
+		msg.Ack()
+	}
+	rows, _ = db.TableRows(DefaultOutboxTable)
+	return fmt.Sprint(got) == "[one two three]" && len(rows) == 0
+}
+
+func testRelayFailures() bool {
+	db := newFakeDB()
+	outbox := NewOutboxPublisher(db, OutboxConfig{})
+	outbox.Publish("orders", NewMessage("e1", Payload("one")), NewMessage("e2", Payload("two")))
+
+	// The broker fails on the second message: the first is relayed and
+	// removed, the second stays for next time
+	broker := &flakyPublisher{failOn: "two", failures: 1}
+	relay := NewRelay(db, broker, RelayConfig{})
+	n, err := relay.RelayPending()
+	if n != 1 || err == nil {
+		return false
+	}
+	rows, _ := db.TableRows(DefaultOutboxTable)
+	if len(rows) != 1 || rows[0]["UUID"] != "e2" {
+		return false
+	}
+
+	n, err = relay.RelayPending()
+	if n != 1 || err != nil || fmt.Sprint(broker.published) != "[orders:one orders:two]" {
+		return false
+	}
+	rows, _ = db.TableRows(DefaultOutboxTable)
+	if len(rows) != 0 {
+		return false
+	}
+
+	// IDs keep increasing while rows are waiting
+	outbox.Publish("orders", NewMessage("e3", nil))
+	outbox.Publish("orders", NewMessage("e4", nil))
+	rows, _ = db.TableRows(DefaultOutboxTable)
+	return len(rows) == 2 && rows[1]["ID"] == uint(2)
+}
+
+func testEndToEnd() bool {
+	db := newFakeDB()
+	bus := NewEventBus(Config{MaxDeliveries: 3})
+	defer bus.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	relay := NewRelay(db, bus, RelayConfig{Interval: 5 * time.Millisecond})
+	go relay.Run(ctx)
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	done := make(chan struct{}, 10)
+	Handle(ctx, bus, "orders.created", func(msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[msg.UUID]++
+		// The first delivery fails after doing its work; consumers
+		// dedupe by UUID, because delivery is at least once
+		if DeliveryCount(msg) == 1 {
+			return errors.New("lost connection before ack")
+		}
+		done <- struct{}{}
+		return nil
+	})
+
+	for i := 1; i <= 2; i++ {
+		id := fmt.Sprintf("order-%d", i)
+		db.Transaction(func(tx *fakeDB) error {
+			rows, _ := tx.TableRows("orders")
+			tx.SetTableRows("orders", append(rows, map[string]interface{}{"ID": id}))
+			return NewOutboxPublisher(tx, OutboxConfig{}).Publish("orders.created", NewMessage(id, Payload(id)))
+		})
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rows, _ := db.TableRows(DefaultOutboxTable)
+	return seen["order-1"] == 2 && seen["order-2"] == 2 && len(rows) == 0
+}
+
+func main() {
+	fmt.Println("Running eventbus Emulator Tests...")
+	fmt.Println("==================================")
+
+	runTest("Messages", testMessages)
+	runTest("Publish and Subscribe", testPublishSubscribe)
+	runTest("Fan Out", testFanOut)
+	runTest("Topics", testTopics)
+	runTest("Ordering", testOrdering)
+	runTest("Redelivery Delay", testRedeliveryDelay)
+	runTest("Ack Timeout", testAckTimeout)
+	runTest("Dead Letters", testDeadLetters)
+	runTest("Persistent", testPersistent)
+	runTest("Handlers", testHandlers)
+	runTest("Close", testClose)
+	runTest("Outbox Transactions", testOutboxTransactions)
+	runTest("Relay", testRelay)
+	runTest("Relay Failures", testRelayFailures)
+	runTest("End to End", testEndToEnd)
+
+	fmt.Println("==================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1961 @@
+package main
+
+// Developed by PowerShield, as an alternative to OpenTelemetry
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attributes, as in the attribute package
+
+// Type is the type of an attribute value
+type Type int
+
+// Attribute value types
+const (
+	INVALID Type = iota
+	BOOL
+	INT64
+	FLOAT64
+	STRING
+	STRINGSLICE
+)
+
+// Key is an attribute name
+type Key string
+
+// Value is an attribute value of one of the supported types
+type Value struct {
+	vtype Type
+	value interface{}
+}
+
+// KeyValue is one attribute
+type KeyValue struct {
+	Key   Key
+	Value Value
+}
+
+// String returns a string attribute
+func String(k, v string) KeyValue { return Key(k).String(v) }
+
+// Int returns an integer attribute
+func Int(k string, v int) KeyValue { return Key(k).Int(v) }
+
+// Int64 returns an integer attribute
+func Int64(k string, v int64) KeyValue { return Key(k).Int64(v) }
+
+// Float64 returns a floating point attribute
+func Float64(k string, v float64) KeyValue { return Key(k).Float64(v) }
+
+// Bool returns a boolean attribute
+func Bool(k string, v bool) KeyValue { return Key(k).Bool(v) }
+
+// StringSlice returns a string slice attribute
+func StringSlice(k string, v []string) KeyValue { return Key(k).StringSlice(v) }
+
+// String returns a string attribute named k
+func (k Key) String(v string) KeyValue {
+	return KeyValue{Key: k, Value: Value{vtype: STRING, value: v}}
+}
+
+// Int returns an integer attribute named k
+func (k Key) Int(v int) KeyValue { return k.Int64(int64(v)) }
+
+// Int64 returns an integer attribute named k
+func (k Key) Int64(v int64) KeyValue {
+	return KeyValue{Key: k, Value: Value{vtype: INT64, value: v}}
+}
+
+// Float64 returns a floating point attribute named k
+func (k Key) Float64(v float64) KeyValue {
+	return KeyValue{Key: k, Value: Value{vtype: FLOAT64, value: v}}
+}
+
+// Bool returns a boolean attribute named k
+func (k Key) Bool(v bool) KeyValue {
+	return KeyValue{Key: k, Value: Value{vtype: BOOL, value: v}}
+}
+
+// StringSlice returns a string slice attribute named k
+func (k Key) StringSlice(v []string) KeyValue {
+	return KeyValue{Key: k, Value: Value{vtype: STRINGSLICE, value: append([]string(nil), v...)}}
+}
+
+// Valid reports whether the attribute has a name and a value
+func (kv KeyValue) Valid() bool {
+	return kv.Key != "" && kv.Value.vtype != INVALID
+}
+
+// Type returns the type of the value
+func (v Value) Type() Type { return v.vtype }
+
+// AsString returns a string value, or "" for other types
+func (v Value) AsString() string { s, _ := v.value.(string); return s }
+
+// AsInt64 returns an integer value, or 0 for other types
+func (v Value) AsInt64() int64 { n, _ := v.value.(int64); return n }
+
+// AsFloat64 returns a floating point value, or 0 for other types
+func (v Value) AsFloat64() float64 { f, _ := v.value.(float64); return f }
+
+// AsBool returns a boolean value, or false for other types
+func (v Value) AsBool() bool { b, _ := v.value.(bool); return b }
+
+// AsStringSlice returns a string slice value, or nil for other types
+func (v Value) AsStringSlice() []string { s, _ := v.value.([]string); return s }
+
+// AsInterface returns the value as a string, int64, float64, bool or
+// []string
+func (v Value) AsInterface() interface{} { return v.value }
+
+// Emit returns the value as a string
+func (v Value) Emit() string {
+	switch v.vtype {
+	case STRINGSLICE:
+		return "[" + strings.Join(v.AsStringSlice(), " ") + "]"
+	case INVALID:
+		return "INVALID"
+	}
+	return fmt.Sprint(v.value)
+}
+
+// Set is an immutable set of attributes, one per key, sorted by key
+type Set struct {
+	kvs []KeyValue
+}
+
+// NewSet returns a set of kvs. Invalid attributes are dropped, and the
+// last value given for a key wins.
+func NewSet(kvs ...KeyValue) Set {
+	byKey := map[Key]KeyValue{}
+	for _, kv := range kvs {
+		if kv.Valid() {
+			byKey[kv.Key] = kv
+		}
+	}
+	set := Set{kvs: make([]KeyValue, 0, len(byKey))}
+	for _, kv := range byKey {
+		set.kvs = append(set.kvs, kv)
+	}
+	sort.Slice(set.kvs, func(i, j int) bool { return set.kvs[i].Key < set.kvs[j].Key })
+	return set
+}
+
+// Len returns the number of attributes
+func (s Set) Len() int { return len(s.kvs) }
+
+// ToSlice returns the attributes, sorted by key
+func (s Set) ToSlice() []KeyValue { return append([]KeyValue(nil), s.kvs...) }
+
+// Value returns the value for k, and whether the set has it
+func (s Set) Value(k Key) (Value, bool) {
+	for _, kv := range s.kvs {
+		if kv.Key == k {
+			return kv.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// Encoded returns the attributes as k1=v1,k2=v2, which identifies the set
+func (s Set) Encoded() string {
+	parts := make([]string, len(s.kvs))
+	for i, kv := range s.kvs {
+		parts[i] = string(kv.Key) + "=" + kv.Value.Emit()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Resources
+
+// Resource describes the entity producing telemetry, such as a service
+type Resource struct {
+	attrs Set
+}
+
+// NewResource returns a resource with the given attributes
+func NewResource(attrs ...KeyValue) *Resource {
+	return &Resource{attrs: NewSet(attrs...)}
+}
+
+// Attributes returns the resource's attributes
+func (r *Resource) Attributes() []KeyValue {
+	if r == nil {
+		return nil
+	}
+	return r.attrs.ToSlice()
+}
+
+// Set returns the resource's attributes as a Set
+func (r *Resource) Set() Set {
+	if r == nil {
+		return Set{}
+	}
+	return r.attrs
+}
+
+// ServiceName returns the service.name attribute
+func ServiceName(name string) KeyValue { return String("service.name", name) }
+
+// defaultResource names the service "unknown_service", as the SDK does
+func defaultResource() *Resource {
+	return NewResource(ServiceName("unknown_service"), String("telemetry.sdk.name", "opentelemetry"), String("telemetry.sdk.language", "go"))
+}
+
+// Scope identifies the library that produced telemetry
+type Scope struct {
+	Name      string
+	Version   string
+	SchemaURL string
+}
+
+// Trace identifiers, as in the trace package
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// IsValid reports whether the ID is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// String returns the ID as 32 hex digits
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// String returns the ID as 16 hex digits
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// TraceIDFromHex parses 32 lowercase hex digits
+func TraceIDFromHex(h string) (TraceID, error) {
+	var t TraceID
+	if err := decodeHexID(h, t[:]); err != nil {
+		return TraceID{}, err
+	}
+	if !t.IsValid() {
+		return TraceID{}, errors.New("trace-id can't be all zero")
+	}
+	return t, nil
+}
+
+// SpanIDFromHex parses 16 lowercase hex digits
+func SpanIDFromHex(h string) (SpanID, error) {
+	var s SpanID
+	if err := decodeHexID(h, s[:]); err != nil {
+		return SpanID{}, err
+	}
+	if !s.IsValid() {
+		return SpanID{}, errors.New("span-id can't be all zero")
+	}
+	return s, nil
+}
+
+func decodeHexID(h string, dst []byte) error {
+	if len(h) != 2*len(dst) || strings.ToLower(h) != h {
+		return fmt.Errorf("invalid hex id length or case: %q", h)
+	}
+	if _, err := hex.Decode(dst, []byte(h)); err != nil {
+		return fmt.Errorf("invalid hex id: %q", h)
+	}
+	return nil
+}
+
+// TraceFlags are the flags propagated with a span context
+type TraceFlags byte
+
+// FlagsSampled marks a span context whose trace is being recorded
+const FlagsSampled = TraceFlags(0x01)
+
+// IsSampled reports whether the sampled flag is set
+func (f TraceFlags) IsSampled() bool { return f&FlagsSampled == FlagsSampled }
+
+// WithSampled returns the flags with the sampled flag set or cleared
+func (f TraceFlags) WithSampled(sampled bool) TraceFlags {
+	if sampled {
+		return f | FlagsSampled
+	}
+	return f &^ FlagsSampled
+}
+
+// String returns the flags as 2 hex digits
+func (f TraceFlags) String() string { return hex.EncodeToString([]byte{byte(f)}) }
+
+// SpanContextConfig holds the fields of a new SpanContext
+type SpanContextConfig struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags TraceFlags
+	TraceState string
+	Remote     bool
+}
+
+// SpanContext is the part of a span propagated to other services
+type SpanContext struct {
+	traceID    TraceID
+	spanID     SpanID
+	traceFlags TraceFlags
+	traceState string
+	remote     bool
+}
+
+// NewSpanContext returns a span context from config
+func NewSpanContext(config SpanContextConfig) SpanContext {
+	return SpanContext{
+		traceID:    config.TraceID,
+		spanID:     config.SpanID,
+		traceFlags: config.TraceFlags,
+		traceState: config.TraceState,
+		remote:     config.Remote,
+	}
+}
+
+// TraceID returns the trace ID
+func (sc SpanContext) TraceID() TraceID { return sc.traceID }
+
+// SpanID returns the span ID
+func (sc SpanContext) SpanID() SpanID { return sc.spanID }
+
+// TraceFlags returns the flags
+func (sc SpanContext) TraceFlags() TraceFlags { return sc.traceFlags }
+
+// TraceState returns the vendor-specific tracestate header value
+func (sc SpanContext) TraceState() string { return sc.traceState }
+
+// IsSampled reports whether the trace is being recorded
+func (sc SpanContext) IsSampled() bool { return sc.traceFlags.IsSampled() }
+
+// IsRemote reports whether the span context was extracted from a carrier
+func (sc SpanContext) IsRemote() bool { return sc.remote }
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool { return sc.traceID.IsValid() && sc.spanID.IsValid() }
+
+// WithRemote returns a copy marked as remote or local
+func (sc SpanContext) WithRemote(remote bool) SpanContext {
+	sc.remote = remote
+	return sc
+}
+
+// Equal reports whether two span contexts are the same
+func (sc SpanContext) Equal(other SpanContext) bool { return sc == other }
+
+// Spans, as in the trace package
+
+// SpanKind describes a span's role in a trace
+type SpanKind int
+
+// Span kinds
+const (
+	SpanKindUnspecified SpanKind = iota
+	SpanKindInternal
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// String returns the kind's name
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindInternal:
+		return "internal"
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	}
+	return "unspecified"
+}
+
+// Code is a span's status code, as in the codes package
+type Code uint32
+
+// Status codes; Ok overrides Error, which overrides Unset
+const (
+	Unset Code = iota
+	Error
+	Ok
+)
+
+// String returns the code's name
+func (c Code) String() string {
+	switch c {
+	case Error:
+		return "Error"
+	case Ok:
+		return "Ok"
+	}
+	return "Unset"
+}
+
+// Status is a span's status. Description is only kept for Error.
+type Status struct {
+	Code        Code
+	Description string
+}
+
+// Event is something that happened during a span
+type Event struct {
+	Name       string
+	Attributes []KeyValue
+	Time       time.Time
+}
+
+// Link connects a span to a span in another trace, such as the spans of
+// the messages a batch consumer handles
+type Link struct {
+	SpanContext SpanContext
+	Attributes  []KeyValue
+}
+
+// Span is one operation in a trace
+type Span interface {
+	// End completes the span; calls after the first are ignored
+	End(options ...SpanOption)
+	AddEvent(name string, options ...SpanOption)
+	// IsRecording reports whether the span records what happens to it
+	IsRecording() bool
+	// RecordError adds an "exception" event for err. It does not change
+	// the status; call SetStatus too.
+	RecordError(err error, options ...SpanOption)
+	SpanContext() SpanContext
+	SetStatus(code Code, description string)
+	SetName(name string)
+	SetAttributes(kv ...KeyValue)
+}
+
+// Tracer starts spans
+type Tracer interface {
+	// Start creates a span, a child of the span in ctx unless WithNewRoot
+	// is given, and returns a context holding it
+	Start(ctx context.Context, spanName string, opts ...SpanOption) (context.Context, Span)
+}
+
+// SpanOption configures a span when it starts, ends, or records an event
+type SpanOption interface {
+	applySpan(*spanConfig)
+}
+
+type spanConfig struct {
+	attributes []KeyValue
+	timestamp  time.Time
+	links      []Link
+	kind       SpanKind
+	newRoot    bool
+}
+
+type spanOptionFunc func(*spanConfig)
+
+func (f spanOptionFunc) applySpan(c *spanConfig) { f(c) }
+
+func newSpanConfig(options []SpanOption) spanConfig {
+	var c spanConfig
+	for _, o := range options {
+		o.applySpan(&c)
+	}
+	return c
+}
+
+// AttributeOption adds attributes to a span, an event or a measurement
+type AttributeOption []KeyValue
+
+// WithAttributes adds attributes to a span, an event or a measurement
+func WithAttributes(attributes ...KeyValue) AttributeOption {
+	return AttributeOption(attributes)
+}
+
+func (o AttributeOption) applySpan(c *spanConfig) {
+	c.attributes = append(c.attributes, o...)
+}
+
+func (o AttributeOption) applyMeasurement(c *measurementConfig) {
+	c.attributes = append(c.attributes, o...)
+}
+
+// WithTimestamp sets when a span starts or ends, or an event happens
+func WithTimestamp(t time.Time) SpanOption {
+	return spanOptionFunc(func(c *spanConfig) { c.timestamp = t })
+}
+
+// WithSpanKind sets a new span's kind; the default is internal
+func WithSpanKind(kind SpanKind) SpanOption {
+	return spanOptionFunc(func(c *spanConfig) { c.kind = kind })
+}
+
+// WithLinks links a new span to spans in other traces
+func WithLinks(links ...Link) SpanOption {
+	return spanOptionFunc(func(c *spanConfig) { c.links = append(c.links, links...) })
+}
+
+// WithNewRoot starts a new trace, ignoring any span in the context
+func WithNewRoot() SpanOption {
+	return spanOptionFunc(func(c *spanConfig) { c.newRoot = true })
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of parent holding span
+func ContextWithSpan(parent context.Context, span Span) context.Context {
+	return context.WithValue(parent, spanContextKey{}, span)
+}
+
+// ContextWithSpanContext returns a copy of parent holding a non-recording
+// span with sc, making it the parent of spans started from the context
+func ContextWithSpanContext(parent context.Context, sc SpanContext) context.Context {
+	return ContextWithSpan(parent, nonRecordingSpan{sc: sc})
+}
+
+// ContextWithRemoteSpanContext is ContextWithSpanContext with sc marked as
+// remote, as propagators do
+func ContextWithRemoteSpanContext(parent context.Context, sc SpanContext) context.Context {
+	return ContextWithSpanContext(parent, sc.WithRemote(true))
+}
+
+// SpanFromContext returns the span in ctx, or a span that does nothing
+func SpanFromContext(ctx context.Context) Span {
+	if ctx != nil {
+		if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+			return span
+		}
+	}
+	return nonRecordingSpan{}
+}
+
+// SpanContextFromContext returns the span context of the span in ctx
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	return SpanFromContext(ctx).SpanContext()
+}
+
+// nonRecordingSpan carries a span context without recording anything, for
+// remote parents and spans the sampler dropped
+type nonRecordingSpan struct {
+	sc SpanContext
+}
+
+func (nonRecordingSpan) End(...SpanOption)                {}
+func (nonRecordingSpan) AddEvent(string, ...SpanOption)   {}
+func (nonRecordingSpan) IsRecording() bool                { return false }
+func (nonRecordingSpan) RecordError(error, ...SpanOption) {}
+func (s nonRecordingSpan) SpanContext() SpanContext       { return s.sc }
+func (nonRecordingSpan) SetStatus(Code, string)           {}
+func (nonRecordingSpan) SetName(string)                   {}
+func (nonRecordingSpan) SetAttributes(...KeyValue)        {}
+
+// Sampling, as in the SDK's trace package
+
+// SamplingDecision is whether a span is recorded and exported
+type SamplingDecision uint8
+
+// Sampling decisions
+const (
+	Drop SamplingDecision = iota
+	RecordOnly
+	RecordAndSample
+)
+
+// SamplingParameters describe a span about to start
+type SamplingParameters struct {
+	ParentContext context.Context
+	TraceID       TraceID
+	Name          string
+	Kind          SpanKind
+	Attributes    []KeyValue
+}
+
+// SamplingResult is a sampler's decision, with attributes to add
+type SamplingResult struct {
+	Decision   SamplingDecision
+	Attributes []KeyValue
+}
+
+// Sampler decides which spans are recorded
+type Sampler interface {
+	ShouldSample(parameters SamplingParameters) SamplingResult
+	Description() string
+}
+
+type samplerFunc struct {
+	description string
+	sample      func(SamplingParameters) SamplingDecision
+}
+
+func (s samplerFunc) ShouldSample(p SamplingParameters) SamplingResult {
+	return SamplingResult{Decision: s.sample(p)}
+}
+
+func (s samplerFunc) Description() string { return s.description }
+
+// AlwaysSample records and exports every span
+func AlwaysSample() Sampler {
+	return samplerFunc{"AlwaysOnSampler", func(SamplingParameters) SamplingDecision { return RecordAndSample }}
+}
+
+// NeverSample records no spans; their contexts are still propagated
+func NeverSample() Sampler {
+	return samplerFunc{"AlwaysOffSampler", func(SamplingParameters) SamplingDecision { return Drop }}
+}
+
+// TraceIDRatioBased samples a fraction of traces, deciding from the trace
+// ID so every service sampling at the same rate keeps the same traces
+func TraceIDRatioBased(fraction float64) Sampler {
+	if fraction >= 1 {
+		return AlwaysSample()
+	}
+	if fraction <= 0 {
+		fraction = 0
+	}
+	upperBound := uint64(fraction * (1 << 63))
+	return samplerFunc{fmt.Sprintf("TraceIDRatioBased{%g}", fraction), func(p SamplingParameters) SamplingDecision {
+		x := binary.BigEndian.Uint64(p.TraceID[8:16]) >> 1
+		if x < upperBound {
+			return RecordAndSample
+		}
+		return Drop
+	}}
+}
+
+// ParentBased follows the parent's sampled flag, and asks root for spans
+// without a parent
+func ParentBased(root Sampler) Sampler {
+	return samplerFunc{fmt.Sprintf("ParentBased{root:%s}", root.Description()), func(p SamplingParameters) SamplingDecision {
+		parent := SpanContextFromContext(p.ParentContext)
+		if !parent.IsValid() {
+			return root.ShouldSample(p).Decision
+		}
+		if parent.IsSampled() {
+			return RecordAndSample
+		}
+		return Drop
+	}}
+}
+
+// Tracer providers, as in the SDK's trace package
+
+// SpanStub is an ended span, as exporters receive it
+type SpanStub struct {
+	Name                 string
+	SpanContext          SpanContext
+	Parent               SpanContext
+	SpanKind             SpanKind
+	StartTime            time.Time
+	EndTime              time.Time
+	Attributes           []KeyValue
+	Events               []Event
+	Links                []Link
+	Status               Status
+	ChildSpanCount       int
+	Resource             *Resource
+	InstrumentationScope Scope
+}
+
+// Attribute returns the value of the span attribute named k
+func (s SpanStub) Attribute(k string) (Value, bool) {
+	for i := len(s.Attributes) - 1; i >= 0; i-- {
+		if s.Attributes[i].Key == Key(k) {
+			return s.Attributes[i].Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// SpanStubs is a list of ended spans
+type SpanStubs []SpanStub
+
+// SpanExporter sends ended spans somewhere
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []SpanStub) error
+	Shutdown(ctx context.Context) error
+}
+
+// InMemoryExporter keeps exported spans for tests to inspect
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans SpanStubs
+}
+
+// NewInMemoryExporter returns an empty exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans implements SpanExporter
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []SpanStub) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Shutdown implements SpanExporter, forgetting the spans
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error {
+	e.Reset()
+	return nil
+}
+
+// GetSpans returns the spans exported so far, in the order they ended
+func (e *InMemoryExporter) GetSpans() SpanStubs {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append(SpanStubs(nil), e.spans...)
+}
+
+// Reset forgets the spans exported so far
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// spanProcessor receives spans as they end
+type spanProcessor interface {
+	onEnd(span SpanStub)
+	forceFlush(ctx context.Context) error
+	shutdown(ctx context.Context) error
+}
+
+// simpleProcessor exports each span as it ends
+type simpleProcessor struct {
+	exporter SpanExporter
+}
+
+func (p *simpleProcessor) onEnd(span SpanStub) {
+	p.exporter.ExportSpans(context.Background(), []SpanStub{span})
+}
+
+func (p *simpleProcessor) forceFlush(ctx context.Context) error { return nil }
+
+func (p *simpleProcessor) shutdown(ctx context.Context) error { return p.exporter.Shutdown(ctx) }
+
+// DefaultMaxExportBatchSize is how many spans a batcher sends at once
+const DefaultMaxExportBatchSize = 512
+
+// BatchOption configures WithBatcher
+type BatchOption func(*batchProcessor)
+
+// WithMaxExportBatchSize sets how many ended spans are queued before they
+// are exported together
+func WithMaxExportBatchSize(size int) BatchOption {
+	return func(p *batchProcessor) { p.maxBatch = size }
+}
+
+// batchProcessor queues ended spans and exports them together
+type batchProcessor struct {
+	exporter SpanExporter
+	maxBatch int
+
+	mu    sync.Mutex
+	queue []SpanStub
+}
+
+func (p *batchProcessor) onEnd(span SpanStub) {
+	p.mu.Lock()
+	p.queue = append(p.queue, span)
+	full := len(p.queue) >= p.maxBatch
+	p.mu.Unlock()
+	if full {
+		p.forceFlush(context.Background())
+	}
+}
+
+func (p *batchProcessor) forceFlush(ctx context.Context) error {
+	p.mu.Lock()
+	queue := p.queue
+	p.queue = nil
+	p.mu.Unlock()
+	if len(queue) == 0 {
+		return nil
+	}
+	return p.exporter.ExportSpans(ctx, queue)
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	if err := p.forceFlush(ctx); err != nil {
+		return err
+	}
+	return p.exporter.Shutdown(ctx)
+}
+
+// TracerProviderOption configures NewTracerProvider
+type TracerProviderOption interface {
+	applyTracerProvider(*TracerProvider)
+}
+
+type tracerProviderOptionFunc func(*TracerProvider)
+
+func (f tracerProviderOptionFunc) applyTracerProvider(tp *TracerProvider) { f(tp) }
+
+// WithSyncer exports each span to exporter as it ends. It suits tests;
+// use WithBatcher in production.
+func WithSyncer(exporter SpanExporter) TracerProviderOption {
+	return tracerProviderOptionFunc(func(tp *TracerProvider) {
+		tp.processors = append(tp.processors, &simpleProcessor{exporter: exporter})
+	})
+}
+
+// WithBatcher queues ended spans and exports them to exporter together
+func WithBatcher(exporter SpanExporter, options ...BatchOption) TracerProviderOption {
+	return tracerProviderOptionFunc(func(tp *TracerProvider) {
+		p := &batchProcessor{exporter: exporter, maxBatch: DefaultMaxExportBatchSize}
+		for _, o := range options {
+			o(p)
+		}
+		if p.maxBatch < 1 {
+			p.maxBatch = 1
+		}
+		tp.processors = append(tp.processors, p)
+	})
+}
+
+// WithSampler sets the sampler; the default is ParentBased(AlwaysSample())
+func WithSampler(sampler Sampler) TracerProviderOption {
+	return tracerProviderOptionFunc(func(tp *TracerProvider) { tp.sampler = sampler })
+}
+
+// ResourceOption sets the resource of a tracer or meter provider
+type ResourceOption struct {
+	resource *Resource
+}
+
+// WithResource describes the service the provider's telemetry comes from
+func WithResource(r *Resource) ResourceOption {
+	return ResourceOption{resource: r}
+}
+
+func (o ResourceOption) applyTracerProvider(tp *TracerProvider) { tp.resource = o.resource }
+
+func (o ResourceOption) applyMeterProvider(mp *MeterProvider) { mp.resource = o.resource }
+
+// TracerProvider creates tracers whose spans go to its exporters
+type TracerProvider struct {
+	mu         sync.Mutex
+	processors []spanProcessor
+	sampler    Sampler
+	resource   *Resource
+	tracers    map[Scope]*tracer
+	shutdown   bool
+}
+
+// NewTracerProvider returns a provider configured by options
+func NewTracerProvider(options ...TracerProviderOption) *TracerProvider {
+	tp := &TracerProvider{
+		sampler:  ParentBased(AlwaysSample()),
+		resource: defaultResource(),
+		tracers:  map[Scope]*tracer{},
+	}
+	for _, o := range options {
+		o.applyTracerProvider(tp)
+	}
+	return tp
+}
+
+// TracerOption configures a Tracer
+type TracerOption func(*Scope)
+
+// WithInstrumentationVersion sets the version of the instrumented library
+func WithInstrumentationVersion(version string) TracerOption {
+	return func(s *Scope) { s.Version = version }
+}
+
+// Tracer returns the tracer for the named library, creating it if needed
+func (tp *TracerProvider) Tracer(name string, options ...TracerOption) Tracer {
+	scope := Scope{Name: name}
+	for _, o := range options {
+		o(&scope)
+	}
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	t, ok := tp.tracers[scope]
+	if !ok {
+		t = &tracer{provider: tp, scope: scope}
+		tp.tracers[scope] = t
+	}
+	return t
+}
+
+// ForceFlush exports any queued spans
+func (tp *TracerProvider) ForceFlush(ctx context.Context) error {
+	for _, p := range tp.spanProcessors() {
+		if err := p.forceFlush(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown flushes queued spans and shuts the exporters down. Spans that
+// end afterwards are dropped.
+func (tp *TracerProvider) Shutdown(ctx context.Context) error {
+	tp.mu.Lock()
+	if tp.shutdown {
+		tp.mu.Unlock()
+		return nil
+	}
+	tp.shutdown = true
+	processors := tp.processors
+	tp.mu.Unlock()
+
+	var errs []error
+	for _, p := range processors {
+		if err := p.shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (tp *TracerProvider) spanProcessors() []spanProcessor {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	if tp.shutdown {
+		return nil
+	}
+	return tp.processors
+}
+
+type tracer struct {
+	provider *TracerProvider
+	scope    Scope
+}
+
+// Start implements Tracer
+func (t *tracer) Start(ctx context.Context, name string, options ...SpanOption) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	config := newSpanConfig(options)
+	if config.kind == SpanKindUnspecified {
+		config.kind = SpanKindInternal
+	}
+
+	parentCtx := ctx
+	if config.newRoot {
+		parentCtx = ContextWithSpan(ctx, nonRecordingSpan{})
+	}
+	parentSpan := SpanFromContext(parentCtx)
+	parent := parentSpan.SpanContext()
+
+	sc := SpanContextConfig{SpanID: newSpanID()}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID()
+		sc.TraceState = parent.TraceState()
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	result := t.provider.sampler.ShouldSample(SamplingParameters{
+		ParentContext: parentCtx,
+		TraceID:       sc.TraceID,
+		Name:          name,
+		Kind:          config.kind,
+		Attributes:    config.attributes,
+	})
+	sc.TraceFlags = parent.TraceFlags().WithSampled(result.Decision == RecordAndSample)
+	spanContext := NewSpanContext(sc)
+
+	if result.Decision == Drop {
+		return ContextWithSpan(ctx, nonRecordingSpan{sc: spanContext}), nonRecordingSpan{sc: spanContext}
+	}
+
+	start := config.timestamp
+	if start.IsZero() {
+		start = time.Now()
+	}
+	s := &recordingSpan{
+		tracer:      t,
+		name:        name,
+		spanContext: spanContext,
+		parent:      parent,
+		kind:        config.kind,
+		start:       start,
+		links:       config.links,
+	}
+	s.SetAttributes(config.attributes...)
+	s.SetAttributes(result.Attributes...)
+	if p, ok := parentSpan.(*recordingSpan); ok {
+		p.addChild()
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// recordingSpan is a span the SDK records and exports when it ends
+type recordingSpan struct {
+	tracer      *tracer
+	spanContext SpanContext
+	parent      SpanContext
+	kind        SpanKind
+	start       time.Time
+	links       []Link
+
+	mu         sync.Mutex
+	name       string
+	end        time.Time
+	attributes []KeyValue
+	events     []Event
+	status     Status
+	children   int
+	ended      bool
+}
+
+func (s *recordingSpan) End(options ...SpanOption) {
+	config := newSpanConfig(options)
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = config.timestamp
+	if s.end.IsZero() {
+		s.end = time.Now()
+	}
+	stub := s.snapshot()
+	s.mu.Unlock()
+
+	if !s.spanContext.IsSampled() {
+		return
+	}
+	for _, p := range s.tracer.provider.spanProcessors() {
+		p.onEnd(stub)
+	}
+}
+
+func (s *recordingSpan) snapshot() SpanStub {
+	return SpanStub{
+		Name:                 s.name,
+		SpanContext:          s.spanContext,
+		Parent:               s.parent,
+		SpanKind:             s.kind,
+		StartTime:            s.start,
+		EndTime:              s.end,
+		Attributes:           append([]KeyValue(nil), s.attributes...),
+		Events:               append([]Event(nil), s.events...),
+		Links:                append([]Link(nil), s.links...),
+		Status:               s.status,
+		ChildSpanCount:       s.children,
+		Resource:             s.tracer.provider.resource,
+		InstrumentationScope: s.tracer.scope,
+	}
+}
+
+func (s *recordingSpan) AddEvent(name string, options ...SpanOption) {
+	config := newSpanConfig(options)
+	if config.timestamp.IsZero() {
+		config.timestamp = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.events = append(s.events, Event{Name: name, Attributes: config.attributes, Time: config.timestamp})
+}
+
+func (s *recordingSpan) IsRecording() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+func (s *recordingSpan) RecordError(err error, options ...SpanOption) {
+	if err == nil {
+		return
+	}
+	options = append([]SpanOption{WithAttributes(
+		String("exception.type", fmt.Sprintf("%T", err)),
+		String("exception.message", err.Error()),
+	)}, options...)
+	s.AddEvent("exception", options...)
+}
+
+func (s *recordingSpan) SpanContext() SpanContext { return s.spanContext }
+
+func (s *recordingSpan) SetStatus(code Code, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended || code < s.status.Code {
+		return
+	}
+	if code != Error {
+		description = ""
+	}
+	s.status = Status{Code: code, Description: description}
+}
+
+func (s *recordingSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.name = name
+	}
+}
+
+// SetAttributes adds attributes, replacing earlier values for the same keys
+func (s *recordingSpan) SetAttributes(kv ...KeyValue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	for _, attr := range kv {
+		if !attr.Valid() {
+			continue
+		}
+		replaced := false
+		for i := range s.attributes {
+			if s.attributes[i].Key == attr.Key {
+				s.attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.attributes = append(s.attributes, attr)
+		}
+	}
+}
+
+func (s *recordingSpan) addChild() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.children++
+}
+
+func newTraceID() TraceID {
+	var t TraceID
+	for !t.IsValid() {
+		rand.Read(t[:])
+	}
+	return t
+}
+
+func newSpanID() SpanID {
+	var s SpanID
+	for !s.IsValid() {
+		rand.Read(s[:])
+	}
+	return s
+}
+
+// Propagation, as in the propagation package
+
+// TextMapCarrier holds propagated fields, such as HTTP headers
+type TextMapCarrier interface {
+	Get(key string) string
+	Set(key, value string)
+	Keys() []string
+}
+
+// MapCarrier is a TextMapCarrier over a map
+type MapCarrier map[string]string
+
+// Get returns the value for key
+func (c MapCarrier) Get(key string) string { return c[key] }
+
+// Set stores value under key
+func (c MapCarrier) Set(key, value string) { c[key] = value }
+
+// Keys returns the keys, sorted
+func (c MapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HeaderCarrier is a TextMapCarrier over HTTP headers
+type HeaderCarrier http.Header
+
+// Get returns the first value of the header key
+func (c HeaderCarrier) Get(key string) string { return http.Header(c).Get(key) }
+
+// Set replaces the header key
+func (c HeaderCarrier) Set(key, value string) { http.Header(c).Set(key, value) }
+
+// Keys returns the header names, sorted
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TextMapPropagator writes a context's span context into a carrier and
+// reads it back on the other side
+type TextMapPropagator interface {
+	Inject(ctx context.Context, carrier TextMapCarrier)
+	Extract(ctx context.Context, carrier TextMapCarrier) context.Context
+	Fields() []string
+}
+
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+var traceparentRegex = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// TraceContext propagates span contexts in W3C traceparent and tracestate
+// headers
+type TraceContext struct{}
+
+// Inject writes traceparent, and tracestate if there is one, for the span
+// in ctx. Nothing is written for an invalid span context.
+func (TraceContext) Inject(ctx context.Context, carrier TextMapCarrier) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	if ts := sc.TraceState(); ts != "" {
+		carrier.Set(tracestateHeader, ts)
+	}
+	carrier.Set(traceparentHeader, fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags()&FlagsSampled))
+}
+
+// Extract returns ctx with the remote span context in carrier, or ctx
+// unchanged if its traceparent is missing or malformed
+func (tc TraceContext) Extract(ctx context.Context, carrier TextMapCarrier) context.Context {
+	sc, ok := tc.extract(carrier)
+	if !ok {
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+func (TraceContext) extract(carrier TextMapCarrier) (SpanContext, bool) {
+	header := strings.TrimSpace(carrier.Get(traceparentHeader))
+	match := traceparentRegex.FindStringSubmatch(header)
+	if match == nil {
+		return SpanContext{}, false
+	}
+	version, flags := match[1], match[4]
+	// Version ff is invalid; version 00 has no trailing fields, while later
+	// versions may add some
+	if version == "ff" || (version == "00" && match[5] != "") {
+		return SpanContext{}, false
+	}
+	traceID, err := TraceIDFromHex(match[2])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	spanID, err := SpanIDFromHex(match[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	flagBits, _ := strconv.ParseUint(flags, 16, 8)
+	return NewSpanContext(SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: TraceFlags(flagBits) & FlagsSampled,
+		TraceState: strings.TrimSpace(carrier.Get(tracestateHeader)),
+		Remote:     true,
+	}), true
+}
+
+// Fields returns the headers TraceContext uses
+func (TraceContext) Fields() []string {
+	return []string{traceparentHeader, tracestateHeader}
+}
+
+type compositePropagator []TextMapPropagator
+
+// NewCompositeTextMapPropagator runs several propagators in order
+func NewCompositeTextMapPropagator(propagators ...TextMapPropagator) TextMapPropagator {
+	return compositePropagator(propagators)
+}
+
+func (p compositePropagator) Inject(ctx context.Context, carrier TextMapCarrier) {
+	for _, each := range p {
+		each.Inject(ctx, carrier)
+	}
+}
+
+func (p compositePropagator) Extract(ctx context.Context, carrier TextMapCarrier) context.Context {
+	for _, each := range p {
+		ctx = each.Extract(ctx, carrier)
+	}
+	return ctx
+}
+
+func (p compositePropagator) Fields() []string {
+	var fields []string
+	for _, each := range p {
+		fields = append(fields, each.Fields()...)
+	}
+	return fields
+}
+
+// Global providers, as in the otel package
+
+var (
+	globalMu             sync.RWMutex
+	globalTracerProvider                   = NewTracerProvider(WithSampler(NeverSample()))
+	globalMeterProvider                    = NewMeterProvider()
+	globalPropagator     TextMapPropagator = NewCompositeTextMapPropagator()
+)
+
+// SetTracerProvider makes tp the provider GetTracerProvider returns. Until
+// it is called, the global provider records nothing.
+func SetTracerProvider(tp *TracerProvider) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalTracerProvider = tp
+}
+
+// GetTracerProvider returns the global tracer provider
+func GetTracerProvider() *TracerProvider {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalTracerProvider
+}
+
+// SetMeterProvider makes mp the provider GetMeterProvider returns
+func SetMeterProvider(mp *MeterProvider) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalMeterProvider = mp
+}
+
+// GetMeterProvider returns the global meter provider
+func GetMeterProvider() *MeterProvider {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalMeterProvider
+}
+
+// SetTextMapPropagator makes p the propagator GetTextMapPropagator returns.
+// Until it is called, the global propagator propagates nothing.
+func SetTextMapPropagator(p TextMapPropagator) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalPropagator = p
+}
+
+// GetTextMapPropagator returns the global propagator
+func GetTextMapPropagator() TextMapPropagator {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalPropagator
+}
+
+// Metrics, as in the metric package and the SDK's metric package
+
+// InstrumentOption configures an instrument
+type InstrumentOption func(*instrumentConfig)
+
+type instrumentConfig struct {
+	description string
+	unit        string
+	bounds      []float64
+}
+
+// WithDescription describes what an instrument measures
+func WithDescription(description string) InstrumentOption {
+	return func(c *instrumentConfig) { c.description = description }
+}
+
+// WithUnit sets an instrument's unit, such as "ms" or "By"
+func WithUnit(unit string) InstrumentOption {
+	return func(c *instrumentConfig) { c.unit = unit }
+}
+
+// WithExplicitBucketBoundaries sets a histogram's bucket boundaries
+func WithExplicitBucketBoundaries(bounds ...float64) InstrumentOption {
+	return func(c *instrumentConfig) {
+		c.bounds = append([]float64(nil), bounds...)
+		sort.Float64s(c.bounds)
+	}
+}
+
+// MeasurementOption configures one measurement
+type MeasurementOption interface {
+	applyMeasurement(*measurementConfig)
+}
+
+type measurementConfig struct {
+	attributes []KeyValue
+}
+
+// Int64Counter records increasing integer values, such as requests served
+type Int64Counter interface {
+	Add(ctx context.Context, incr int64, options ...MeasurementOption)
+}
+
+// Float64Counter records increasing floating point values
+type Float64Counter interface {
+	Add(ctx context.Context, incr float64, options ...MeasurementOption)
+}
+
+// Int64UpDownCounter records integer values that go up and down, such as
+// queue lengths
+type Int64UpDownCounter interface {
+	Add(ctx context.Context, incr int64, options ...MeasurementOption)
+}
+
+// Int64Histogram records the distribution of integer values
+type Int64Histogram interface {
+	Record(ctx context.Context, value int64, options ...MeasurementOption)
+}
+
+// Float64Histogram records the distribution of floating point values,
+// such as request durations
+type Float64Histogram interface {
+	Record(ctx context.Context, value float64, options ...MeasurementOption)
+}
+
+// Meter creates instruments
+type Meter interface {
+	Int64Counter(name string, options ...InstrumentOption) (Int64Counter, error)
+	Float64Counter(name string, options ...InstrumentOption) (Float64Counter, error)
+	Int64UpDownCounter(name string, options ...InstrumentOption) (Int64UpDownCounter, error)
+	Int64Histogram(name string, options ...InstrumentOption) (Int64Histogram, error)
+	Float64Histogram(name string, options ...InstrumentOption) (Float64Histogram, error)
+}
+
+// DefaultHistogramBoundaries are the bucket boundaries of histograms
+// created without WithExplicitBucketBoundaries
+var DefaultHistogramBoundaries = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+var instrumentNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_./-]{0,254}$`)
+
+// MeterProviderOption configures NewMeterProvider
+type MeterProviderOption interface {
+	applyMeterProvider(*MeterProvider)
+}
+
+type meterProviderOptionFunc func(*MeterProvider)
+
+func (f meterProviderOptionFunc) applyMeterProvider(mp *MeterProvider) { f(mp) }
+
+// WithReader registers a reader that collects the provider's metrics
+func WithReader(r *ManualReader) MeterProviderOption {
+	return meterProviderOptionFunc(func(mp *MeterProvider) {
+		mp.readers = append(mp.readers, r)
+	})
+}
+
+// MeterProvider creates meters whose measurements its readers collect
+type MeterProvider struct {
+	mu       sync.Mutex
+	resource *Resource
+	readers  []*ManualReader
+	meters   []*meter
+}
+
+// NewMeterProvider returns a provider configured by options
+func NewMeterProvider(options ...MeterProviderOption) *MeterProvider {
+	mp := &MeterProvider{resource: defaultResource()}
+	for _, o := range options {
+		o.applyMeterProvider(mp)
+	}
+	for _, r := range mp.readers {
+		r.register(mp)
+	}
+	return mp
+}
+
+// Meter returns the meter for the named library, creating it if needed
+func (mp *MeterProvider) Meter(name string, options ...TracerOption) Meter {
+	scope := Scope{Name: name}
+	for _, o := range options {
+		o(&scope)
+	}
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	for _, m := range mp.meters {
+		if m.scope == scope {
+			return m
+		}
+	}
+	m := &meter{scope: scope}
+	mp.meters = append(mp.meters, m)
+	return m
+}
+
+// Shutdown shuts down the provider's readers
+func (mp *MeterProvider) Shutdown(ctx context.Context) error {
+	for _, r := range mp.readers {
+		r.Shutdown(ctx)
+	}
+	return nil
+}
+
+// meter holds instruments in creation order; asking for an instrument
+// twice returns the same one
+type meter struct {
+	scope Scope
+
+	mu          sync.Mutex
+	instruments []*instrument
+}
+
+func (m *meter) instrument(name string, kind instrumentKind, integer bool, options []InstrumentOption) (*instrument, error) {
+	var config instrumentConfig
+	for _, o := range options {
+		o(&config)
+	}
+	var err error
+	if !instrumentNameRegex.MatchString(name) {
+		err = fmt.Errorf("invalid instrument name: %q", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, inst := range m.instruments {
+		if inst.name == name && inst.kind == kind && inst.integer == integer {
+			return inst, err
+		}
+	}
+	if config.bounds == nil {
+		config.bounds = DefaultHistogramBoundaries
+	}
+	inst := &instrument{
+		name:    name,
+		kind:    kind,
+		integer: integer,
+		config:  config,
+		points:  map[string]*point{},
+		start:   time.Now(),
+	}
+	m.instruments = append(m.instruments, inst)
+	return inst, err
+}
+
+func (m *meter) Int64Counter(name string, options ...InstrumentOption) (Int64Counter, error) {
+	inst, err := m.instrument(name, counterKind, true, options)
+	return int64Instrument{inst}, err
+}
+
+func (m *meter) Float64Counter(name string, options ...InstrumentOption) (Float64Counter, error) {
+	inst, err := m.instrument(name, counterKind, false, options)
+	return float64Instrument{inst}, err
+}
+
+func (m *meter) Int64UpDownCounter(name string, options ...InstrumentOption) (Int64UpDownCounter, error) {
+	inst, err := m.instrument(name, upDownCounterKind, true, options)
+	return int64Instrument{inst}, err
+}
+
+func (m *meter) Int64Histogram(name string, options ...InstrumentOption) (Int64Histogram, error) {
+	inst, err := m.instrument(name, histogramKind, true, options)
+	return int64Instrument{inst}, err
+}
+
+func (m *meter) Float64Histogram(name string, options ...InstrumentOption) (Float64Histogram, error) {
+	inst, err := m.instrument(name, histogramKind, false, options)
+	return float64Instrument{inst}, err
+}
+
+type instrumentKind int
+
+const (
+	counterKind instrumentKind = iota
+	upDownCounterKind
+	histogramKind
+)
+
+// instrument aggregates measurements by attribute set, cumulatively
+type instrument struct {
+	name    string
+	kind    instrumentKind
+	integer bool
+	config  instrumentConfig
+	start   time.Time
+
+	mu     sync.Mutex
+	points map[string]*point
+}
+
+type point struct {
+	attributes   Set
+	sum          float64
+	count        uint64
+	min, max     float64
+	bucketCounts []uint64
+}
+
+func (inst *instrument) measure(value float64, options []MeasurementOption) {
+	// Counters only go up; the SDK drops negative increments
+	if inst.kind == counterKind && value < 0 || math.IsNaN(value) {
+		return
+	}
+	var config measurementConfig
+	for _, o := range options {
+		o.applyMeasurement(&config)
+	}
+	attributes := NewSet(config.attributes...)
+	key := attributes.Encoded()
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	p, ok := inst.points[key]
+	if !ok {
+		p = &point{attributes: attributes, min: value, max: value}
+		if inst.kind == histogramKind {
+			p.bucketCounts = make([]uint64, len(inst.config.bounds)+1)
+		}
+		inst.points[key] = p
+	}
+	p.sum += value
+	p.count++
+	p.min = math.Min(p.min, value)
+	p.max = math.Max(p.max, value)
+	if inst.kind == histogramKind {
+		// Buckets are (bound[i-1], bound[i]], with a last one above them all
+		p.bucketCounts[sort.SearchFloat64s(inst.config.bounds, value)]++
+	}
+}
+
+type int64Instrument struct{ *instrument }
+
+func (i int64Instrument) Add(ctx context.Context, incr int64, options ...MeasurementOption) {
+	i.measure(float64(incr), options)
+}
+
+func (i int64Instrument) Record(ctx context.Context, value int64, options ...MeasurementOption) {
+	i.measure(float64(value), options)
+}
+
+type float64Instrument struct{ *instrument }
+
+func (i float64Instrument) Add(ctx context.Context, incr float64, options ...MeasurementOption) {
+	i.measure(incr, options)
+}
+
+func (i float64Instrument) Record(ctx context.Context, value float64, options ...MeasurementOption) {
+	i.measure(value, options)
+}
+
+// Collected metrics, as in the metricdata package
+
+// Number is the value type of a metric
+type Number interface {
+	int64 | float64
+}
+
+// Temporality is whether values are totals since the start or changes
+// since the last collection
+type Temporality uint8
+
+// Temporalities; this SDK collects cumulative values
+const (
+	undefinedTemporality Temporality = iota
+	CumulativeTemporality
+	DeltaTemporality
+)
+
+// ResourceMetrics is everything a reader collects
+type ResourceMetrics struct {
+	Resource     *Resource
+	ScopeMetrics []ScopeMetrics
+}
+
+// ScopeMetrics is the metrics of one meter
+type ScopeMetrics struct {
+	Scope   Scope
+	Metrics []Metrics
+}
+
+// Metrics is one instrument's collected data
+type Metrics struct {
+	Name        string
+	Description string
+	Unit        string
+	// Data is a Sum[int64], Sum[float64], Histogram[int64] or
+	// Histogram[float64]
+	Data Aggregation
+}
+
+// Aggregation is the collected data of an instrument
+type Aggregation interface {
+	privateAggregation()
+}
+
+// DataPoint is a sum for one set of attributes
+type DataPoint[N Number] struct {
+	Attributes Set
+	StartTime  time.Time
+	Time       time.Time
+	Value      N
+}
+
+// Sum is the data of a counter or up-down counter
+type Sum[N Number] struct {
+	DataPoints  []DataPoint[N]
+	Temporality Temporality
+	IsMonotonic bool
+}
+
+func (Sum[N]) privateAggregation() {}
+
+// Extrema is a minimum or maximum, which may not be defined
+type Extrema[N Number] struct {
+	value N
+	valid bool
+}
+
+// NewExtrema returns a defined extremum
+func NewExtrema[N Number](value N) Extrema[N] {
+	return Extrema[N]{value: value, valid: true}
+}
+
+// Value returns the extremum and whether it is defined
+func (e Extrema[N]) Value() (N, bool) { return e.value, e.valid }
+
+// HistogramDataPoint is a distribution for one set of attributes
+type HistogramDataPoint[N Number] struct {
+	Attributes   Set
+	StartTime    time.Time
+	Time         time.Time
+	Count        uint64
+	Bounds       []float64
+	BucketCounts []uint64
+	Min          Extrema[N]
+	Max          Extrema[N]
+	Sum          N
+}
+
+// Histogram is the data of a histogram
+type Histogram[N Number] struct {
+	DataPoints  []HistogramDataPoint[N]
+	Temporality Temporality
+}
+
+func (Histogram[N]) privateAggregation() {}
+
+// ErrReaderNotRegistered is returned by a reader no provider uses
+var ErrReaderNotRegistered = errors.New("reader is not registered")
+
+// ErrReaderShutdown is returned by a reader after Shutdown
+var ErrReaderShutdown = errors.New("reader is shutdown")
+
+// ManualReader collects metrics when asked, which suits tests
+type ManualReader struct {
+	mu       sync.Mutex
+	provider *MeterProvider
+	shutdown bool
+}
+
+// NewManualReader returns a reader to pass to WithReader
+func NewManualReader() *ManualReader {
+	return &ManualReader{}
+}
+
+func (r *ManualReader) register(mp *MeterProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.provider = mp
+}
+
+// Shutdown stops the reader collecting
+func (r *ManualReader) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shutdown = true
+	return nil
+}
+
+// Collect fills rm with the provider's current metrics
+func (r *ManualReader) Collect(ctx context.Context, rm *ResourceMetrics) error {
+	if rm == nil {
+		return errors.New("nil ResourceMetrics")
+	}
+	r.mu.Lock()
+	mp, shutdown := r.provider, r.shutdown
+	r.mu.Unlock()
+	if shutdown {
+		return ErrReaderShutdown
+	}
+	if mp == nil {
+		return ErrReaderNotRegistered
+	}
+
+	now := time.Now()
+	mp.mu.Lock()
+	meters := append([]*meter(nil), mp.meters...)
+	mp.mu.Unlock()
+
+	*rm = ResourceMetrics{Resource: mp.resource}
+	for _, m := range meters {
+		m.mu.Lock()
+		instruments := append([]*instrument(nil), m.instruments...)
+		m.mu.Unlock()
+
+		sm := ScopeMetrics{Scope: m.scope}
+		for _, inst := range instruments {
+			sm.Metrics = append(sm.Metrics, inst.collect(now))
+		}
+		rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+	}
+	return nil
+}
+
+func (inst *instrument) collect(now time.Time) Metrics {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	keys := make([]string, 0, len(inst.points))
+	for k := range inst.points {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	points := make([]*point, len(keys))
+	for i, k := range keys {
+		points[i] = inst.points[k]
+	}
+
+	metrics := Metrics{Name: inst.name, Description: inst.config.description, Unit: inst.config.unit}
+	if inst.integer {
+		metrics.Data = aggregate[int64](inst, points, now)
+	} else {
+		metrics.Data = aggregate[float64](inst, points, now)
+	}
+	return metrics
+}
+
+func aggregate[N Number](inst *instrument, points []*point, now time.Time) Aggregation {
+	if inst.kind == histogramKind {
+		h := Histogram[N]{Temporality: CumulativeTemporality}
+		for _, p := range points {
+			h.DataPoints = append(h.DataPoints, HistogramDataPoint[N]{
+				Attributes:   p.attributes,
+				StartTime:    inst.start,
+				Time:         now,
+				Count:        p.count,
+				Bounds:       append([]float64(nil), inst.config.bounds...),
+				BucketCounts: append([]uint64(nil), p.bucketCounts...),
+				Min:          NewExtrema(N(p.min)),
+				Max:          NewExtrema(N(p.max)),
+				Sum:          N(p.sum),
+			})
+		}
+		return h
+	}
+	s := Sum[N]{Temporality: CumulativeTemporality, IsMonotonic: inst.kind == counterKind}
+	for _, p := range points {
+		s.DataPoints = append(s.DataPoints, DataPoint[N]{
+			Attributes: p.attributes,
+			StartTime:  inst.start,
+			Time:       now,
+			Value:      N(p.sum),
+		})
+	}
+	return s
+}
+
+// Middleware instrumentation
+
+// MiddlewareTracer adapts a Tracer and a propagator to the plain
+// signatures the Gin emulator's Tracing and the Go-kit emulator's
+// TraceEndpoint middlewares take, which need no types from this package
+type MiddlewareTracer struct {
+	Tracer     Tracer
+	Propagator TextMapPropagator
+	// Kind is the kind of the spans started; the default is server
+	Kind SpanKind
+}
+
+// NewMiddlewareTracer returns a MiddlewareTracer. A nil propagator means
+// the global one.
+func NewMiddlewareTracer(tracer Tracer, propagator TextMapPropagator) *MiddlewareTracer {
+	return &MiddlewareTracer{Tracer: tracer, Propagator: propagator, Kind: SpanKindServer}
+}
+
+func (t *MiddlewareTracer) propagator() TextMapPropagator {
+	if t.Propagator != nil {
+		return t.Propagator
+	}
+	return GetTextMapPropagator()
+}
+
+// Extract returns ctx with the remote span context in headers, whose names
+// are matched case-insensitively
+func (t *MiddlewareTracer) Extract(ctx context.Context, headers map[string]string) context.Context {
+	carrier := MapCarrier{}
+	for k, v := range headers {
+		carrier[strings.ToLower(k)] = v
+	}
+	return t.propagator().Extract(ctx, carrier)
+}
+
+// Inject writes the span context in ctx into headers, for an outgoing
+// request
+func (t *MiddlewareTracer) Inject(ctx context.Context, headers map[string]string) {
+	t.propagator().Inject(ctx, MapCarrier(headers))
+}
+
+// StartSpan starts a span with attributes and returns a context holding
+// it, and a function ending it. The function adds more attributes and
+// records err, if any, as the span's error; an http.status_code of 500 or
+// more is an error too.
+func (t *MiddlewareTracer) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, func(attributes map[string]interface{}, err error)) {
+	kind := t.Kind
+	if kind == SpanKindUnspecified {
+		kind = SpanKindServer
+	}
+	ctx, span := t.Tracer.Start(ctx, name, WithSpanKind(kind), WithAttributes(toKeyValues(attributes)...))
+	return ctx, func(attributes map[string]interface{}, err error) {
+		span.SetAttributes(toKeyValues(attributes)...)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(Error, err.Error())
+		} else if code, ok := attributes["http.status_code"].(int); ok && code >= 500 {
+			span.SetStatus(Error, http.StatusText(code))
+		}
+		span.End()
+	}
+}
+
+// toKeyValues converts plain values to attributes, sorted by key. Types
+// without an attribute type become strings.
+func toKeyValues(attributes map[string]interface{}) []KeyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]KeyValue, 0, len(keys))
+	for _, k := range keys {
+		switch v := attributes[k].(type) {
+		case string:
+			kvs = append(kvs, String(k, v))
+		case int:
+			kvs = append(kvs, Int(k, v))
+		case int64:
+			kvs = append(kvs, Int64(k, v))
+		case float64:
+			kvs = append(kvs, Float64(k, v))
+		case bool:
+			kvs = append(kvs, Bool(k, v))
+		case []string:
+			kvs = append(kvs, StringSlice(k, v))
+		default:
+			kvs = append(kvs, String(k, fmt.Sprint(v)))
+		}
+	}
+	return kvs
+}
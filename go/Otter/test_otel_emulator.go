@@ -0,0 +1,421 @@
+package main
+
+// Developed by PowerShield, as an alternative to OpenTelemetry
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// newTestTracer returns a tracer whose spans go to an in-memory exporter
+func newTestTracer(options ...TracerProviderOption) (Tracer, *InMemoryExporter, *TracerProvider) {
+	exporter := NewInMemoryExporter()
+	tp := NewTracerProvider(append([]TracerProviderOption{WithSyncer(exporter)}, options...)...)
+	return tp.Tracer("test"), exporter, tp
+}
+
+func testSpanHierarchy() bool {
+	tracer, exporter, _ := newTestTracer()
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "child" || spans[1].Name != "parent" {
+		return false
+	}
+	c, p := spans[0], spans[1]
+	return c.SpanContext.TraceID() == p.SpanContext.TraceID() &&
+		c.Parent.SpanID() == p.SpanContext.SpanID() &&
+		!p.Parent.IsValid() && p.ChildSpanCount == 1 &&
+		c.SpanKind == SpanKindInternal && c.InstrumentationScope.Name == "test" &&
+		!c.EndTime.Before(c.StartTime) && SpanFromContext(ctx) == parent
+}
+
+func testSpanAttributesAndEvents() bool {
+	tracer, exporter, _ := newTestTracer()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, span := tracer.Start(context.Background(), "checkout",
+		WithAttributes(String("user", "ann"), Int("items", 2)),
+		WithSpanKind(SpanKindServer), WithTimestamp(start))
+	span.SetAttributes(Int("items", 3), Bool("paid", true), StringSlice("tags", []string{"a", "b"}), KeyValue{})
+	span.AddEvent("charged", WithAttributes(Float64("amount", 9.5)))
+	span.SetName("checkout.pay")
+	span.End(WithTimestamp(start.Add(time.Second)))
+	span.SetAttributes(String("late", "ignored"))
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		return false
+	}
+	s := spans[0]
+	items, _ := s.Attribute("items")
+	tags, _ := s.Attribute("tags")
+	_, late := s.Attribute("late")
+	return s.Name == "checkout.pay" && s.SpanKind == SpanKindServer &&
+		len(s.Attributes) == 4 && items.AsInt64() == 3 && items.Type() == INT64 &&
+		reflect.DeepEqual(tags.AsStringSlice(), []string{"a", "b"}) && !late &&
+		len(s.Events) == 1 && s.Events[0].Name == "charged" &&
+		s.Events[0].Attributes[0].Value.AsFloat64() == 9.5 &&
+		s.EndTime.Sub(s.StartTime) == time.Second && !span.IsRecording()
+}
+
+func testSpanStatus() bool {
+	tracer, exporter, _ := newTestTracer()
+	_, failed := tracer.Start(context.Background(), "failed")
+	failed.RecordError(errors.New("timeout"))
+	failed.RecordError(nil)
+	failed.SetStatus(Error, "timeout")
+	failed.SetStatus(Unset, "")
+	failed.End()
+
+	_, recovered := tracer.Start(context.Background(), "recovered")
+	recovered.SetStatus(Ok, "ignored description")
+	recovered.SetStatus(Error, "too late")
+	recovered.End()
+
+	spans := exporter.GetSpans()
+	f, r := spans[0], spans[1]
+	message := f.Events[0].Attributes[1].Value.AsString()
+	return len(f.Events) == 1 && f.Events[0].Name == "exception" && message == "timeout" &&
+		f.Status == Status{Code: Error, Description: "timeout"} &&
+		r.Status == Status{Code: Ok} && r.Status.Code.String() == "Ok"
+}
+
+func testSamplers() bool {
+	tracer, exporter, _ := newTestTracer(WithSampler(NeverSample()))
+	ctx, dropped := tracer.Start(context.Background(), "dropped")
+	_, child := tracer.Start(ctx, "child")
+	dropped.End()
+	child.End()
+	// Dropped spans still carry IDs, so the trace continues downstream
+	if len(exporter.GetSpans()) != 0 || dropped.IsRecording() || !dropped.SpanContext().IsValid() ||
+		dropped.SpanContext().IsSampled() || child.SpanContext().TraceID() != dropped.SpanContext().TraceID() {
+		return false
+	}
+
+	// The default sampler follows a remote parent's decision
+	tracer, exporter, _ = newTestTracer()
+	unsampled := NewSpanContext(SpanContextConfig{TraceID: TraceID{1}, SpanID: SpanID{1}})
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), unsampled), "follows")
+	span.End()
+	if len(exporter.GetSpans()) != 0 || span.IsRecording() {
+		return false
+	}
+
+	// A ratio sampler keeps about that fraction of traces
+	tracer, exporter, _ = newTestTracer(WithSampler(TraceIDRatioBased(0.25)))
+	for i := 0; i < 400; i++ {
+		_, s := tracer.Start(context.Background(), "sampled")
+		s.End()
+	}
+	kept := len(exporter.GetSpans())
+	return kept > 50 && kept < 150 && TraceIDRatioBased(2).Description() == "AlwaysOnSampler"
+}
+
+func testWithNewRoot() bool {
+	tracer, exporter, _ := newTestTracer()
+	ctx, parent := tracer.Start(context.Background(), "request")
+	_, root := tracer.Start(ctx, "background", WithNewRoot(), WithLinks(Link{SpanContext: parent.SpanContext()}))
+	root.End()
+	parent.End()
+
+	s := exporter.GetSpans()[0]
+	return s.Name == "background" && !s.Parent.IsValid() &&
+		s.SpanContext.TraceID() != parent.SpanContext().TraceID() &&
+		len(s.Links) == 1 && s.Links[0].SpanContext.Equal(parent.SpanContext())
+}
+
+func testTraceContextInject() bool {
+	tracer, _, _ := newTestTracer()
+	ctx, span := tracer.Start(context.Background(), "client")
+	defer span.End()
+
+	carrier := MapCarrier{}
+	TraceContext{}.Inject(ctx, carrier)
+	sc := span.SpanContext()
+	want := fmt.Sprintf("00-%s-%s-01", sc.TraceID(), sc.SpanID())
+
+	// Nothing is written without a span
+	empty := MapCarrier{}
+	TraceContext{}.Inject(context.Background(), empty)
+	return carrier.Get("traceparent") == want && len(carrier.Keys()) == 1 && len(empty) == 0
+}
+
+func testTraceContextExtract() bool {
+	header := http.Header{}
+	carrier := HeaderCarrier(header)
+	carrier.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	carrier.Set("Tracestate", "congo=t61rcWkgMzE")
+	ctx := TraceContext{}.Extract(context.Background(), carrier)
+	sc := SpanContextFromContext(ctx)
+	if sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID().String() != "00f067aa0ba902b7" ||
+		!sc.IsSampled() || !sc.IsRemote() || sc.TraceState() != "congo=t61rcWkgMzE" {
+		return false
+	}
+
+	// Malformed headers leave the context alone
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if SpanContextFromContext(TraceContext{}.Extract(context.Background(), MapCarrier{"traceparent": bad})).IsValid() {
+			return false
+		}
+	}
+	// Later versions may add fields
+	future := MapCarrier{"traceparent": "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"}
+	fsc := SpanContextFromContext(TraceContext{}.Extract(context.Background(), future))
+	return fsc.IsValid() && !fsc.IsSampled()
+}
+
+func testPropagationRoundtrip() bool {
+	client, clientSpans, _ := newTestTracer()
+	server, serverSpans, _ := newTestTracer()
+	propagator := NewCompositeTextMapPropagator(TraceContext{})
+
+	ctx, call := client.Start(context.Background(), "GET /orders", WithSpanKind(SpanKindClient))
+	headers := http.Header{}
+	propagator.Inject(ctx, HeaderCarrier(headers))
+
+	remote := propagator.Extract(context.Background(), HeaderCarrier(headers))
+	_, handle := server.Start(remote, "/orders", WithSpanKind(SpanKindServer))
+	handle.End()
+	call.End()
+
+	c, s := clientSpans.GetSpans()[0], serverSpans.GetSpans()[0]
+	return s.SpanContext.TraceID() == c.SpanContext.TraceID() &&
+		s.Parent.SpanID() == c.SpanContext.SpanID() && s.Parent.IsRemote() &&
+		reflect.DeepEqual(propagator.Fields(), []string{"traceparent", "tracestate"})
+}
+
+func testBatcherAndShutdown() bool {
+	exporter := NewInMemoryExporter()
+	tp := NewTracerProvider(WithBatcher(exporter, WithMaxExportBatchSize(3)))
+	tracer := tp.Tracer("batch")
+	for i := 0; i < 4; i++ {
+		_, span := tracer.Start(context.Background(), fmt.Sprintf("span-%d", i))
+		span.End()
+	}
+	// Three spans filled a batch; the fourth waits for a flush
+	if len(exporter.GetSpans()) != 3 {
+		return false
+	}
+	tp.ForceFlush(context.Background())
+	if len(exporter.GetSpans()) != 4 {
+		return false
+	}
+
+	kept := NewInMemoryExporter()
+	tp = NewTracerProvider(WithSyncer(kept))
+	_, span := tp.Tracer("late").Start(context.Background(), "late")
+	if err := tp.Shutdown(context.Background()); err != nil {
+		return false
+	}
+	span.End()
+	return len(kept.GetSpans()) == 0 && tp.Shutdown(context.Background()) == nil
+}
+
+func testConcurrentSpans() bool {
+	tracer, exporter, _ := newTestTracer()
+	ctx, parent := tracer.Start(context.Background(), "fan-out")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, span := tracer.Start(ctx, "worker", WithAttributes(Int("n", i)))
+			parent.AddEvent("started")
+			span.End()
+		}(i)
+	}
+	wg.Wait()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	last := spans[len(spans)-1]
+	return len(spans) == 21 && last.Name == "fan-out" && last.ChildSpanCount == 20 && len(last.Events) == 20
+}
+
+func testGlobals() bool {
+	// Until configured, the globals record and propagate nothing
+	_, span := GetTracerProvider().Tracer("global").Start(context.Background(), "noop")
+	carrier := MapCarrier{}
+	GetTextMapPropagator().Inject(ContextWithSpan(context.Background(), span), carrier)
+	if span.IsRecording() || len(carrier) != 0 {
+		return false
+	}
+
+	exporter := NewInMemoryExporter()
+	tp := NewTracerProvider(WithSyncer(exporter), WithResource(NewResource(ServiceName("billing"))))
+	SetTracerProvider(tp)
+	SetTextMapPropagator(TraceContext{})
+	defer SetTracerProvider(NewTracerProvider(WithSampler(NeverSample())))
+	defer SetTextMapPropagator(NewCompositeTextMapPropagator())
+
+	ctx, span := GetTracerProvider().Tracer("global").Start(context.Background(), "recorded")
+	GetTextMapPropagator().Inject(ctx, carrier)
+	span.End()
+	service, _ := exporter.GetSpans()[0].Resource.Set().Value("service.name")
+	return carrier.Get("traceparent") != "" && service.AsString() == "billing" &&
+		tp.Tracer("global") == tp.Tracer("global")
+}
+
+func testCounters() bool {
+	reader := NewManualReader()
+	mp := NewMeterProvider(WithReader(reader), WithResource(NewResource(ServiceName("api"))))
+	meter := mp.Meter("http")
+	requests, err := meter.Int64Counter("http.requests", WithDescription("Requests served"), WithUnit("{request}"))
+	if err != nil {
+		return false
+	}
+	ctx := context.Background()
+	requests.Add(ctx, 1, WithAttributes(String("route", "/a")))
+	requests.Add(ctx, 2, WithAttributes(String("route", "/a")))
+	requests.Add(ctx, 5, WithAttributes(String("route", "/b")))
+	requests.Add(ctx, -4, WithAttributes(String("route", "/b")))
+
+	inflight, _ := meter.Int64UpDownCounter("http.inflight")
+	inflight.Add(ctx, 3)
+	inflight.Add(ctx, -2)
+	bytes, _ := meter.Float64Counter("http.bytes")
+	bytes.Add(ctx, 1.5)
+
+	var rm ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil || len(rm.ScopeMetrics) != 1 {
+		return false
+	}
+	service, _ := rm.Resource.Set().Value("service.name")
+	metrics := rm.ScopeMetrics[0].Metrics
+	sum, ok := metrics[0].Data.(Sum[int64])
+	if !ok || service.AsString() != "api" || metrics[0].Description != "Requests served" ||
+		metrics[0].Unit != "{request}" || len(sum.DataPoints) != 2 || !sum.IsMonotonic {
+		return false
+	}
+	a, b := sum.DataPoints[0], sum.DataPoints[1]
+	route, _ := a.Attributes.Value("route")
+	updown := metrics[1].Data.(Sum[int64])
+	floats := metrics[2].Data.(Sum[float64])
+	return route.AsString() == "/a" && a.Value == 3 && b.Value == 5 &&
+		sum.Temporality == CumulativeTemporality &&
+		!updown.IsMonotonic && updown.DataPoints[0].Value == 1 &&
+		floats.DataPoints[0].Value == 1.5
+}
+
+func testHistograms() bool {
+	reader := NewManualReader()
+	meter := NewMeterProvider(WithReader(reader)).Meter("http")
+	latency, _ := meter.Float64Histogram("http.duration", WithUnit("ms"), WithExplicitBucketBoundaries(100, 10, 50))
+	ctx := context.Background()
+	for _, v := range []float64{3, 10, 42, 75, 900} {
+		latency.Record(ctx, v)
+	}
+	sizes, _ := meter.Int64Histogram("http.size")
+	sizes.Record(ctx, 7)
+
+	var rm ResourceMetrics
+	reader.Collect(ctx, &rm)
+	metrics := rm.ScopeMetrics[0].Metrics
+	h := metrics[0].Data.(Histogram[float64]).DataPoints[0]
+	min, _ := h.Min.Value()
+	max, _ := h.Max.Value()
+	sized := metrics[1].Data.(Histogram[int64]).DataPoints[0]
+	return reflect.DeepEqual(h.Bounds, []float64{10, 50, 100}) &&
+		reflect.DeepEqual(h.BucketCounts, []uint64{2, 1, 1, 1}) &&
+		h.Count == 5 && h.Sum == 1030 && min == 3 && max == 900 &&
+		reflect.DeepEqual(sized.Bounds, DefaultHistogramBoundaries) && sized.BucketCounts[2] == 1
+}
+
+func testMeterErrors() bool {
+	reader := NewManualReader()
+	var rm ResourceMetrics
+	if reader.Collect(context.Background(), &rm) != ErrReaderNotRegistered {
+		return false
+	}
+	mp := NewMeterProvider(WithReader(reader))
+	meter := mp.Meter("m")
+	if _, err := meter.Int64Counter("1bad name"); err == nil {
+		return false
+	}
+	// Asking twice returns the same instrument
+	first, _ := meter.Int64Counter("hits")
+	second, _ := meter.Int64Counter("hits")
+	first.Add(context.Background(), 1)
+	second.Add(context.Background(), 1)
+	reader.Collect(context.Background(), &rm)
+	hits := rm.ScopeMetrics[0].Metrics[1].Data.(Sum[int64]).DataPoints[0].Value
+
+	mp.Shutdown(context.Background())
+	return hits == 2 && len(rm.ScopeMetrics[0].Metrics) == 2 &&
+		reader.Collect(context.Background(), &rm) == ErrReaderShutdown && mp.Meter("m") == meter
+}
+
+func testMiddlewareTracer() bool {
+	tracer, exporter, _ := newTestTracer()
+	mt := NewMiddlewareTracer(tracer, TraceContext{})
+
+	ctx := mt.Extract(context.Background(), map[string]string{
+		"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	ctx, end := mt.StartSpan(ctx, "/orders/:id", map[string]interface{}{"http.method": "GET", "retries": int64(2)})
+	outgoing := map[string]string{}
+	mt.Inject(ctx, outgoing)
+	end(map[string]interface{}{"http.status_code": 503}, nil)
+
+	_, endFailed := mt.StartSpan(context.Background(), "charge", nil)
+	endFailed(nil, errors.New("card declined"))
+
+	spans := exporter.GetSpans()
+	served, failed := spans[0], spans[1]
+	method, _ := served.Attribute("http.method")
+	code, _ := served.Attribute("http.status_code")
+	return served.SpanContext.TraceID().String() == "4bf92f3577b34da6a3ce929d0e0e4736" &&
+		served.SpanKind == SpanKindServer && method.AsString() == "GET" && code.AsInt64() == 503 &&
+		served.Status == Status{Code: Error, Description: "Service Unavailable"} &&
+		outgoing["traceparent"] == fmt.Sprintf("00-%s-%s-01", served.SpanContext.TraceID(), served.SpanContext.SpanID()) &&
+		failed.Status.Description == "card declined" && failed.Events[0].Name == "exception"
+}
+
+func main() {
+	fmt.Println("Running OpenTelemetry Emulator Tests...")
+	fmt.Println("=======================================")
+
+	runTest("Span Hierarchy", testSpanHierarchy)
+	runTest("Span Attributes and Events", testSpanAttributesAndEvents)
+	runTest("Span Status", testSpanStatus)
+	runTest("Samplers", testSamplers)
+	runTest("New Root Spans", testWithNewRoot)
+	runTest("TraceContext Inject", testTraceContextInject)
+	runTest("TraceContext Extract", testTraceContextExtract)
+	runTest("Propagation Roundtrip", testPropagationRoundtrip)
+	runTest("Batcher and Shutdown", testBatcherAndShutdown)
+	runTest("Concurrent Spans", testConcurrentSpans)
+	runTest("Globals", testGlobals)
+	runTest("Counters", testCounters)
+	runTest("Histograms", testHistograms)
+	runTest("Meter Errors", testMeterErrors)
+	runTest("Middleware Tracer", testMiddlewareTracer)
+
+	fmt.Println("=======================================")
+	fmt.Println("All tests completed!")
+}
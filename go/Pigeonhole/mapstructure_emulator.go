@@ -0,0 +1,1158 @@
+package main
+
+// Developed by PowerShield, as an alternative to mitchellh/mapstructure
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeHookFunc is a DecodeHookFuncType, DecodeHookFuncKind or
+// DecodeHookFuncValue. Hooks run before every value is decoded and may
+// replace the input, which is how strings become durations or slices.
+type DecodeHookFunc interface{}
+
+// DecodeHookFuncType is a hook that sees the source and target types
+type DecodeHookFuncType func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error)
+
+// DecodeHookFuncKind is a hook that sees the source and target kinds
+type DecodeHookFuncKind func(from reflect.Kind, to reflect.Kind, data interface{}) (interface{}, error)
+
+// DecodeHookFuncValue is a hook that sees the source value and the value
+// being decoded into
+type DecodeHookFuncValue func(from reflect.Value, to reflect.Value) (interface{}, error)
+
+// DecoderConfig configures a Decoder
+type DecoderConfig struct {
+	// DecodeHook runs before each value is decoded
+	DecodeHook DecodeHookFunc
+
+	// ErrorUnused makes keys with no matching struct field an error
+	ErrorUnused bool
+
+	// ErrorUnset makes struct fields with no matching key an error
+	ErrorUnset bool
+
+	// ZeroFields replaces maps, slices and pointers instead of merging
+	// into them, and zeroes fields whose input is nil
+	ZeroFields bool
+
+	// WeaklyTypedInput converts between strings, numbers and bools, turns
+	// single values into one-element slices and merges a slice of maps
+	// into one map
+	WeaklyTypedInput bool
+
+	// Squash flattens every embedded struct as if it were tagged squash
+	Squash bool
+
+	// Metadata, if set, records the keys that were decoded, unused and
+	// unset
+	Metadata *Metadata
+
+	// Result is a pointer to the value to decode into
+	Result interface{}
+
+	// TagName is the struct tag to read, "mapstructure" by default
+	TagName string
+
+	// IgnoreUntaggedFields skips struct fields without a tag
+	IgnoreUntaggedFields bool
+
+	// MatchName reports whether a map key names a struct field. The
+	// default ignores case.
+	MatchName func(mapKey, fieldName string) bool
+}
+
+// Metadata describes what a decode did
+type Metadata struct {
+	// Keys are the names of the values that were decoded
+	Keys []string
+
+	// Unused are input keys with no matching struct field
+	Unused []string
+
+	// Unset are struct fields with no matching input key
+	Unset []string
+}
+
+// Error collects every problem found while decoding
+type Error struct {
+	Errors []string
+}
+
+func (e *Error) Error() string {
+	points := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		points[i] = "* " + err
+	}
+	sort.Strings(points)
+	return fmt.Sprintf("%d error(s) decoding:\n\n%s", len(e.Errors), strings.Join(points, "\n"))
+}
+
+// WrappedErrors returns the collected errors as error values
+func (e *Error) WrappedErrors() []error {
+	if e == nil {
+		return nil
+	}
+	result := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		result[i] = errors.New(err)
+	}
+	return result
+}
+
+func appendErrors(errs []string, err error) []string {
+	if e, ok := err.(*Error); ok {
+		return append(errs, e.Errors...)
+	}
+	return append(errs, err.Error())
+}
+
+// Decoder decodes values with a fixed configuration
+type Decoder struct {
+	config *DecoderConfig
+}
+
+// NewDecoder returns a decoder for config. config.Result must be a
+// non-nil pointer.
+func NewDecoder(config *DecoderConfig) (*Decoder, error) {
+	val := reflect.ValueOf(config.Result)
+	if val.Kind() != reflect.Ptr {
+		return nil, errors.New("result must be a pointer")
+	}
+	if val.IsNil() || !val.Elem().CanAddr() {
+		return nil, errors.New("result must be addressable (a pointer)")
+	}
+	if config.Metadata != nil {
+		if config.Metadata.Keys == nil {
+			config.Metadata.Keys = make([]string, 0)
+		}
+		if config.Metadata.Unused == nil {
+			config.Metadata.Unused = make([]string, 0)
+		}
+		if config.Metadata.Unset == nil {
+			config.Metadata.Unset = make([]string, 0)
+		}
+	}
+	if config.TagName == "" {
+		config.TagName = "mapstructure"
+	}
+	if config.MatchName == nil {
+		config.MatchName = strings.EqualFold
+	}
+	return &Decoder{config: config}, nil
+}
+
+// Decode decodes input into the configured Result
+func (d *Decoder) Decode(input interface{}) error {
+	return d.decode("", input, reflect.ValueOf(d.config.Result).Elem())
+}
+
+// Decode decodes input, usually a map[string]interface{}, into output,
+// which must be a pointer
+func Decode(input interface{}, output interface{}) error {
+	return decodeWith(&DecoderConfig{Result: output}, input)
+}
+
+// WeakDecode is Decode with WeaklyTypedInput
+func WeakDecode(input, output interface{}) error {
+	return decodeWith(&DecoderConfig{Result: output, WeaklyTypedInput: true}, input)
+}
+
+// DecodeMetadata is Decode that also fills metadata
+func DecodeMetadata(input interface{}, output interface{}, metadata *Metadata) error {
+	return decodeWith(&DecoderConfig{Result: output, Metadata: metadata}, input)
+}
+
+// WeakDecodeMetadata is WeakDecode that also fills metadata
+func WeakDecodeMetadata(input interface{}, output interface{}, metadata *Metadata) error {
+	return decodeWith(&DecoderConfig{Result: output, Metadata: metadata, WeaklyTypedInput: true}, input)
+}
+
+func decodeWith(config *DecoderConfig, input interface{}) error {
+	decoder, err := NewDecoder(config)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
+
+// ConfigDecoder decodes with a copy of Config, for emulators that take a
+// decoder through an interface, such as the Viper emulator's StructDecoder
+type ConfigDecoder struct {
+	Config DecoderConfig
+}
+
+// NewConfigDecoder returns the ConfigDecoder viper uses for Unmarshal:
+// weakly typed, with strings parsed into durations and split into slices
+// at commas. Extra hooks run first, so a hook for a slice type such as
+// net.IP sees the string before it is split.
+func NewConfigDecoder(hooks ...DecodeHookFunc) ConfigDecoder {
+	hooks = append(hooks, StringToTimeDurationHookFunc(), StringToSliceHookFunc(","))
+	return ConfigDecoder{Config: DecoderConfig{
+		DecodeHook:       ComposeDecodeHookFunc(hooks...),
+		WeaklyTypedInput: true,
+	}}
+}
+
+// Decode decodes input into output, which must be a pointer
+func (c ConfigDecoder) Decode(input interface{}, output interface{}) error {
+	config := c.Config
+	config.Result = output
+	return decodeWith(&config, input)
+}
+
+func (d *Decoder) decode(name string, input interface{}, outVal reflect.Value) error {
+	var inputVal reflect.Value
+	if input != nil {
+		inputVal = reflect.ValueOf(input)
+		// A typed nil pointer counts as no input
+		if inputVal.Kind() == reflect.Ptr && inputVal.IsNil() {
+			input = nil
+		}
+	}
+	if input == nil {
+		// Nothing to decode: leave the value alone unless asked to zero it
+		if d.config.ZeroFields {
+			outVal.Set(reflect.Zero(outVal.Type()))
+			d.addKey(name)
+		}
+		return nil
+	}
+
+	if d.config.DecodeHook != nil {
+		var err error
+		input, err = DecodeHookExec(d.config.DecodeHook, inputVal, outVal)
+		if err != nil {
+			return fmt.Errorf("error decoding '%s': %s", name, err)
+		}
+		if input == nil {
+			return nil
+		}
+	}
+
+	var err error
+	addKey := true
+	switch getKind(outVal) {
+	case reflect.Bool:
+		err = d.decodeBool(name, input, outVal)
+	case reflect.Interface:
+		err = d.decodeInterface(name, input, outVal)
+	case reflect.String:
+		err = d.decodeString(name, input, outVal)
+	case reflect.Int:
+		err = d.decodeInt(name, input, outVal)
+	case reflect.Uint:
+		err = d.decodeUint(name, input, outVal)
+	case reflect.Float32:
+		err = d.decodeFloat(name, input, outVal)
+	case reflect.Struct:
+		err = d.decodeStruct(name, input, outVal)
+	case reflect.Map:
+		err = d.decodeMap(name, input, outVal)
+	case reflect.Ptr:
+		// The pointed-to value records the key
+		addKey = false
+		err = d.decodePtr(name, input, outVal)
+	case reflect.Slice:
+		err = d.decodeSlice(name, input, outVal)
+	case reflect.Array:
+		err = d.decodeArray(name, input, outVal)
+	case reflect.Func, reflect.Chan:
+		err = d.decodeAssign(name, input, outVal)
+	default:
+		return fmt.Errorf("%s: unsupported type: %s", name, outVal.Kind())
+	}
+	if addKey {
+		d.addKey(name)
+	}
+	return err
+}
+
+func (d *Decoder) addKey(name string) {
+	if d.config.Metadata != nil && name != "" {
+		d.config.Metadata.Keys = append(d.config.Metadata.Keys, name)
+	}
+}
+
+// getKind folds the sized numeric kinds into Int, Uint and Float32
+func getKind(val reflect.Value) reflect.Kind {
+	kind := val.Kind()
+	switch {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		return reflect.Int
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		return reflect.Uint
+	case kind == reflect.Float32 || kind == reflect.Float64:
+		return reflect.Float32
+	default:
+		return kind
+	}
+}
+
+func unconvertible(name string, val reflect.Value, dataVal reflect.Value, data interface{}) error {
+	return fmt.Errorf("'%s' expected type '%s', got unconvertible type '%s', value: '%v'",
+		name, val.Type(), dataVal.Type(), data)
+}
+
+func isJSONNumber(dataVal reflect.Value) bool {
+	return dataVal.Type() == reflect.TypeOf(json.Number(""))
+}
+
+func (d *Decoder) decodeInterface(name string, data interface{}, val reflect.Value) error {
+	if elem := val.Elem(); elem.IsValid() && elem.Kind() == reflect.Ptr && !elem.IsNil() {
+		// Decode into what the interface already points at
+		return d.decode(name, data, elem)
+	}
+	return d.decodeAssign(name, data, val)
+}
+
+// decodeAssign sets val to data if the types allow it
+func (d *Decoder) decodeAssign(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.ValueOf(data)
+	// *T goes into T by value
+	if dataVal.Kind() == reflect.Ptr && dataVal.Type().Elem() == val.Type() {
+		dataVal = dataVal.Elem()
+	}
+	if !dataVal.Type().AssignableTo(val.Type()) {
+		return fmt.Errorf("'%s' expected type '%s', got '%s'", name, val.Type(), dataVal.Type())
+	}
+	val.Set(dataVal)
+	return nil
+}
+
+func (d *Decoder) decodeString(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := getKind(dataVal)
+	weak := d.config.WeaklyTypedInput
+
+	switch {
+	case dataKind == reflect.String:
+		val.SetString(dataVal.String())
+	case dataKind == reflect.Bool && weak:
+		if dataVal.Bool() {
+			val.SetString("1")
+		} else {
+			val.SetString("0")
+		}
+	case dataKind == reflect.Int && weak:
+		val.SetString(strconv.FormatInt(dataVal.Int(), 10))
+	case dataKind == reflect.Uint && weak:
+		val.SetString(strconv.FormatUint(dataVal.Uint(), 10))
+	case dataKind == reflect.Float32 && weak:
+		val.SetString(strconv.FormatFloat(dataVal.Float(), 'f', -1, 64))
+	case (dataKind == reflect.Slice || dataKind == reflect.Array) && weak &&
+		dataVal.Type().Elem().Kind() == reflect.Uint8:
+		bytes := make([]byte, dataVal.Len())
+		reflect.Copy(reflect.ValueOf(bytes), dataVal)
+		val.SetString(string(bytes))
+	default:
+		return unconvertible(name, val, dataVal, data)
+	}
+	return nil
+}
+
+func (d *Decoder) decodeInt(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := getKind(dataVal)
+	weak := d.config.WeaklyTypedInput
+
+	var i int64
+	switch {
+	case isJSONNumber(dataVal):
+		n, err := dataVal.Interface().(json.Number).Int64()
+		if err != nil {
+			return fmt.Errorf("error decoding json.Number into %s: %s", name, err)
+		}
+		i = n
+	case dataKind == reflect.Int:
+		i = dataVal.Int()
+	case dataKind == reflect.Uint:
+		u := dataVal.Uint()
+		if u > 1<<63-1 {
+			return fmt.Errorf("'%s' value %d overflows %s", name, u, val.Type())
+		}
+		i = int64(u)
+	case dataKind == reflect.Float32:
+		i = int64(dataVal.Float())
+	case dataKind == reflect.Bool && weak:
+		if dataVal.Bool() {
+			i = 1
+		}
+	case dataKind == reflect.String && weak:
+		str := dataVal.String()
+		if str == "" {
+			str = "0"
+		}
+		n, err := strconv.ParseInt(str, 0, val.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' as int: %s", name, err)
+		}
+		i = n
+	default:
+		return unconvertible(name, val, dataVal, data)
+	}
+	if val.OverflowInt(i) {
+		return fmt.Errorf("'%s' value %d overflows %s", name, i, val.Type())
+	}
+	val.SetInt(i)
+	return nil
+}
+
+func (d *Decoder) decodeUint(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := getKind(dataVal)
+	weak := d.config.WeaklyTypedInput
+
+	var u uint64
+	switch {
+	case isJSONNumber(dataVal):
+		n, err := strconv.ParseUint(dataVal.String(), 0, 64)
+		if err != nil {
+			return fmt.Errorf("error decoding json.Number into %s: %s", name, err)
+		}
+		u = n
+	case dataKind == reflect.Int:
+		i := dataVal.Int()
+		if i < 0 && !weak {
+			return fmt.Errorf("cannot parse '%s', %d overflows uint", name, i)
+		}
+		u = uint64(i)
+	case dataKind == reflect.Uint:
+		u = dataVal.Uint()
+	case dataKind == reflect.Float32:
+		f := dataVal.Float()
+		if f < 0 && !weak {
+			return fmt.Errorf("cannot parse '%s', %f overflows uint", name, f)
+		}
+		u = uint64(f)
+	case dataKind == reflect.Bool && weak:
+		if dataVal.Bool() {
+			u = 1
+		}
+	case dataKind == reflect.String && weak:
+		str := dataVal.String()
+		if str == "" {
+			str = "0"
+		}
+		n, err := strconv.ParseUint(str, 0, val.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' as uint: %s", name, err)
+		}
+		u = n
+	default:
+		return unconvertible(name, val, dataVal, data)
+	}
+	if val.OverflowUint(u) {
+		return fmt.Errorf("'%s' value %d overflows %s", name, u, val.Type())
+	}
+	val.SetUint(u)
+	return nil
+}
+
+func (d *Decoder) decodeBool(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := getKind(dataVal)
+	weak := d.config.WeaklyTypedInput
+
+	switch {
+	case dataKind == reflect.Bool:
+		val.SetBool(dataVal.Bool())
+	case dataKind == reflect.Int && weak:
+		val.SetBool(dataVal.Int() != 0)
+	case dataKind == reflect.Uint && weak:
+		val.SetBool(dataVal.Uint() != 0)
+	case dataKind == reflect.Float32 && weak:
+		val.SetBool(dataVal.Float() != 0)
+	case dataKind == reflect.String && weak:
+		if dataVal.String() == "" {
+			val.SetBool(false)
+			break
+		}
+		b, err := strconv.ParseBool(dataVal.String())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' as bool: %s", name, err)
+		}
+		val.SetBool(b)
+	default:
+		return unconvertible(name, val, dataVal, data)
+	}
+	return nil
+}
+
+func (d *Decoder) decodeFloat(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := getKind(dataVal)
+	weak := d.config.WeaklyTypedInput
+
+	switch {
+	case isJSONNumber(dataVal):
+		f, err := dataVal.Interface().(json.Number).Float64()
+		if err != nil {
+			return fmt.Errorf("error decoding json.Number into %s: %s", name, err)
+		}
+		val.SetFloat(f)
+	case dataKind == reflect.Int:
+		val.SetFloat(float64(dataVal.Int()))
+	case dataKind == reflect.Uint:
+		val.SetFloat(float64(dataVal.Uint()))
+	case dataKind == reflect.Float32:
+		val.SetFloat(dataVal.Float())
+	case dataKind == reflect.Bool && weak:
+		if dataVal.Bool() {
+			val.SetFloat(1)
+		} else {
+			val.SetFloat(0)
+		}
+	case dataKind == reflect.String && weak:
+		str := dataVal.String()
+		if str == "" {
+			str = "0"
+		}
+		f, err := strconv.ParseFloat(str, val.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cannot parse '%s' as float: %s", name, err)
+		}
+		val.SetFloat(f)
+	default:
+		return unconvertible(name, val, dataVal, data)
+	}
+	return nil
+}
+
+func (d *Decoder) decodePtr(name string, data interface{}, val reflect.Value) error {
+	// A nil map, slice or interface clears the pointer
+	switch dataVal := reflect.Indirect(reflect.ValueOf(data)); dataVal.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Slice:
+		if dataVal.IsNil() {
+			if !val.IsNil() && val.CanSet() {
+				val.Set(reflect.Zero(val.Type()))
+			}
+			return nil
+		}
+	}
+
+	// Decode through a pointer that is already set, or a new one
+	if !val.CanSet() || !val.IsNil() && !d.config.ZeroFields {
+		return d.decode(name, data, val.Elem())
+	}
+	target := reflect.New(val.Type().Elem())
+	if err := d.decode(name, data, target.Elem()); err != nil {
+		return err
+	}
+	val.Set(target)
+	return nil
+}
+
+// sortedMapKeys returns a map's keys in a stable order, so errors and
+// metadata come out the same every time
+func sortedMapKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+func (d *Decoder) decodeMap(name string, data interface{}, val reflect.Value) error {
+	valMap := val
+	if valMap.IsNil() || d.config.ZeroFields {
+		valMap = reflect.MakeMap(val.Type())
+	}
+
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	switch dataVal.Kind() {
+	case reflect.Map:
+		return d.decodeMapFromMap(name, dataVal, val, valMap)
+	case reflect.Struct:
+		return d.decodeMapFromStruct(name, dataVal, val, valMap)
+	case reflect.Slice, reflect.Array:
+		if d.config.WeaklyTypedInput {
+			return d.decodeMapFromSlice(name, dataVal, val, valMap)
+		}
+	}
+	return fmt.Errorf("'%s' expected a map, got '%s'", name, dataVal.Kind())
+}
+
+// decodeMapFromSlice merges each map in a slice into one, for weak input
+func (d *Decoder) decodeMapFromSlice(name string, dataVal reflect.Value, val, valMap reflect.Value) error {
+	if dataVal.Len() == 0 {
+		val.Set(valMap)
+		return nil
+	}
+	for i := 0; i < dataVal.Len(); i++ {
+		fieldName := name + "[" + strconv.Itoa(i) + "]"
+		if err := d.decode(fieldName, dataVal.Index(i).Interface(), val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeMapFromMap(name string, dataVal reflect.Value, val, valMap reflect.Value) error {
+	keyType := val.Type().Key()
+	elemType := val.Type().Elem()
+
+	if dataVal.Len() == 0 {
+		if dataVal.IsNil() {
+			if !val.IsNil() {
+				val.Set(reflect.Zero(val.Type()))
+			}
+		} else {
+			val.Set(valMap)
+		}
+		return nil
+	}
+
+	var errs []string
+	for _, k := range sortedMapKeys(dataVal) {
+		fieldName := fmt.Sprintf("%s[%v]", name, k.Interface())
+
+		key := reflect.New(keyType).Elem()
+		if err := d.decode(fieldName, k.Interface(), key); err != nil {
+			errs = appendErrors(errs, err)
+			continue
+		}
+		elem := reflect.New(elemType).Elem()
+		if err := d.decode(fieldName, dataVal.MapIndex(k).Interface(), elem); err != nil {
+			errs = appendErrors(errs, err)
+			continue
+		}
+		valMap.SetMapIndex(key, elem)
+	}
+	val.Set(valMap)
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+	return nil
+}
+
+// decodeMapFromStruct turns a struct into a map keyed by field name,
+// honouring the same tags as decoding the other way
+func (d *Decoder) decodeMapFromStruct(name string, dataVal reflect.Value, val, valMap reflect.Value) error {
+	if kind := val.Type().Key().Kind(); kind != reflect.String && kind != reflect.Interface {
+		return fmt.Errorf("'%s' needs a map with string keys, has '%s' keys", name, kind)
+	}
+	elemType := val.Type().Elem()
+	dataType := dataVal.Type()
+
+	for i := 0; i < dataType.NumField(); i++ {
+		field := dataType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fieldVal := dataVal.Field(i)
+
+		tagValue := field.Tag.Get(d.config.TagName)
+		if d.config.IgnoreUntaggedFields && tagValue == "" {
+			continue
+		}
+		keyName := field.Name
+		tagParts := strings.Split(tagValue, ",")
+		if tagParts[0] == "-" {
+			continue
+		}
+		if tagParts[0] != "" {
+			keyName = tagParts[0]
+		}
+		squash := d.config.Squash && field.Anonymous
+		omitempty := false
+		for _, option := range tagParts[1:] {
+			switch option {
+			case "squash":
+				squash = true
+			case "omitempty":
+				omitempty = true
+			}
+		}
+		if omitempty && fieldVal.IsZero() {
+			continue
+		}
+		if squash && fieldVal.Kind() == reflect.Ptr && !fieldVal.IsNil() {
+			fieldVal = fieldVal.Elem()
+		}
+
+		if fieldVal.Kind() == reflect.Struct && (squash || fieldVal.Type() != elemType) {
+			// Nested structs become nested maps of the same type
+			nested := reflect.New(val.Type()).Elem()
+			if err := d.decodeMapFromStruct(keyName, fieldVal, nested, reflect.MakeMap(val.Type())); err != nil {
+				return err
+			}
+			if squash {
+				for _, k := range nested.MapKeys() {
+					valMap.SetMapIndex(k, nested.MapIndex(k))
+				}
+			} else {
+				valMap.SetMapIndex(reflect.ValueOf(keyName).Convert(val.Type().Key()), nested)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		// Nil pointers stay nil
+		elem := reflect.New(elemType).Elem()
+		if !(fieldVal.Kind() == reflect.Ptr && fieldVal.IsNil()) {
+			if err := d.decode(keyName, fieldVal.Interface(), elem); err != nil {
+				return err
+			}
+		}
+		valMap.SetMapIndex(reflect.ValueOf(keyName).Convert(val.Type().Key()), elem)
+	}
+	val.Set(valMap)
+	return nil
+}
+
+func (d *Decoder) decodeSlice(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := dataVal.Kind()
+	elemType := val.Type().Elem()
+
+	if dataKind != reflect.Array && dataKind != reflect.Slice {
+		if !d.config.WeaklyTypedInput {
+			return fmt.Errorf("'%s': source data must be an array or slice, got %s", name, dataKind)
+		}
+		switch {
+		case dataKind == reflect.Map && dataVal.Len() == 0:
+			// An empty map is an empty list
+			val.Set(reflect.MakeSlice(val.Type(), 0, 0))
+			return nil
+		case dataKind == reflect.String && elemType.Kind() == reflect.Uint8:
+			return d.decodeSlice(name, []byte(dataVal.String()), val)
+		default:
+			// Anything else becomes a one-element slice
+			return d.decodeSlice(name, []interface{}{data}, val)
+		}
+	}
+	// A nil slice leaves the target alone: empty is not nil
+	if dataKind == reflect.Slice && dataVal.IsNil() {
+		return nil
+	}
+
+	valSlice := val
+	if valSlice.IsNil() || d.config.ZeroFields {
+		valSlice = reflect.MakeSlice(val.Type(), dataVal.Len(), dataVal.Len())
+	} else if valSlice.Len() > dataVal.Len() {
+		valSlice = valSlice.Slice(0, dataVal.Len())
+	}
+
+	var errs []string
+	for i := 0; i < dataVal.Len(); i++ {
+		for valSlice.Len() <= i {
+			valSlice = reflect.Append(valSlice, reflect.Zero(elemType))
+		}
+		fieldName := name + "[" + strconv.Itoa(i) + "]"
+		if err := d.decode(fieldName, dataVal.Index(i).Interface(), valSlice.Index(i)); err != nil {
+			errs = appendErrors(errs, err)
+		}
+	}
+	val.Set(valSlice)
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeArray(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+	dataKind := dataVal.Kind()
+
+	if dataKind != reflect.Array && dataKind != reflect.Slice {
+		if !d.config.WeaklyTypedInput {
+			return fmt.Errorf("'%s': source data must be an array or slice, got %s", name, dataKind)
+		}
+		if dataKind == reflect.Map && dataVal.Len() == 0 {
+			val.Set(reflect.Zero(val.Type()))
+			return nil
+		}
+		return d.decodeArray(name, []interface{}{data}, val)
+	}
+	if dataVal.Len() > val.Len() {
+		return fmt.Errorf("'%s': expected source data to have length less or equal to %d, got %d",
+			name, val.Len(), dataVal.Len())
+	}
+
+	valArray := val
+	if d.config.ZeroFields {
+		valArray = reflect.New(val.Type()).Elem()
+	}
+	var errs []string
+	for i := 0; i < dataVal.Len(); i++ {
+		fieldName := name + "[" + strconv.Itoa(i) + "]"
+		if err := d.decode(fieldName, dataVal.Index(i).Interface(), valArray.Index(i)); err != nil {
+			errs = appendErrors(errs, err)
+		}
+	}
+	val.Set(valArray)
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+	return nil
+}
+
+func (d *Decoder) decodeStruct(name string, data interface{}, val reflect.Value) error {
+	dataVal := reflect.Indirect(reflect.ValueOf(data))
+
+	// The same type is copied as is, which covers types like time.Time
+	if dataVal.Type() == val.Type() {
+		val.Set(dataVal)
+		return nil
+	}
+
+	switch dataVal.Kind() {
+	case reflect.Map:
+		return d.decodeStructFromMap(name, dataVal, val)
+	case reflect.Struct:
+		// Struct to struct goes through a map
+		m := reflect.ValueOf(&map[string]interface{}{}).Elem()
+		if err := d.decodeMapFromStruct(name, dataVal, m, reflect.MakeMap(m.Type())); err != nil {
+			return err
+		}
+		return d.decodeStructFromMap(name, m, val)
+	default:
+		return fmt.Errorf("'%s' expected a map, got '%s'", name, dataVal.Kind())
+	}
+}
+
+// structField is a settable field found while flattening squashed structs
+type structField struct {
+	field reflect.StructField
+	val   reflect.Value
+}
+
+func (d *Decoder) decodeStructFromMap(name string, dataVal, val reflect.Value) error {
+	if kind := dataVal.Type().Key().Kind(); kind != reflect.String && kind != reflect.Interface {
+		return fmt.Errorf("'%s' needs a map with string keys, has '%s' keys", name, kind)
+	}
+
+	dataKeys := sortedMapKeys(dataVal)
+	unused := make(map[interface{}]bool, len(dataKeys))
+	for _, k := range dataKeys {
+		unused[k.Interface()] = true
+	}
+	var unset []string
+	var errs []string
+
+	// Squashed embedded structs add their fields to the parent's
+	structs := []reflect.Value{val}
+	var fields []structField
+	var remain *structField
+	for len(structs) > 0 {
+		structVal := structs[0]
+		structs = structs[1:]
+		structType := structVal.Type()
+
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			fieldVal := structVal.Field(i)
+			if field.Anonymous && fieldVal.Kind() == reflect.Ptr && fieldVal.Elem().Kind() == reflect.Struct {
+				// Embedded struct pointers that are set act as embedded structs
+				fieldVal = fieldVal.Elem()
+			}
+
+			tagValue := field.Tag.Get(d.config.TagName)
+			if d.config.IgnoreUntaggedFields && tagValue == "" || field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			squash := d.config.Squash && fieldVal.Kind() == reflect.Struct && field.Anonymous
+			isRemain := false
+			for _, option := range strings.Split(tagValue, ",")[1:] {
+				switch option {
+				case "squash":
+					squash = true
+				case "remain":
+					isRemain = true
+				}
+			}
+
+			switch {
+			case squash && fieldVal.Kind() != reflect.Struct:
+				errs = append(errs, fmt.Sprintf("%s: unsupported type for squash: %s", field.Name, fieldVal.Kind()))
+			case squash:
+				structs = append(structs, fieldVal)
+			case isRemain:
+				remain = &structField{field, fieldVal}
+			default:
+				fields = append(fields, structField{field, fieldVal})
+			}
+		}
+	}
+
+	for _, f := range fields {
+		fieldName := f.field.Name
+		if tag := strings.SplitN(f.field.Tag.Get(d.config.TagName), ",", 2)[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			fieldName = tag
+		}
+
+		rawKey := reflect.ValueOf(fieldName)
+		rawVal := reflect.Value{}
+		if dataVal.Type().Key().Kind() == reflect.String {
+			rawVal = dataVal.MapIndex(rawKey.Convert(dataVal.Type().Key()))
+			rawKey = rawKey.Convert(dataVal.Type().Key())
+		}
+		if !rawVal.IsValid() {
+			// Fall back to MatchName, which ignores case by default
+			for _, k := range dataKeys {
+				mapKey, ok := k.Interface().(string)
+				if !ok && k.Kind() == reflect.String {
+					mapKey, ok = k.String(), true
+				}
+				if ok && d.config.MatchName(mapKey, fieldName) {
+					rawKey, rawVal = k, dataVal.MapIndex(k)
+					break
+				}
+			}
+		}
+		if !rawVal.IsValid() {
+			unset = append(unset, fieldName)
+			continue
+		}
+		// Unexported fields can't be set
+		if !f.val.CanSet() {
+			continue
+		}
+		delete(unused, rawKey.Interface())
+
+		if name != "" {
+			fieldName = name + "." + fieldName
+		}
+		if err := d.decode(fieldName, rawVal.Interface(), f.val); err != nil {
+			errs = appendErrors(errs, err)
+		}
+	}
+
+	var unusedKeys []string
+	for _, k := range dataKeys {
+		if unused[k.Interface()] {
+			unusedKeys = append(unusedKeys, fmt.Sprint(k.Interface()))
+		}
+	}
+
+	// The remain field collects every key no other field took
+	if remain != nil && len(unusedKeys) > 0 {
+		rest := make(map[interface{}]interface{}, len(unusedKeys))
+		for _, k := range dataKeys {
+			if unused[k.Interface()] {
+				rest[k.Interface()] = dataVal.MapIndex(k).Interface()
+			}
+		}
+		if err := d.decodeMap(name, rest, remain.val); err != nil {
+			errs = appendErrors(errs, err)
+		}
+		unusedKeys = nil
+	}
+
+	if d.config.ErrorUnused && len(unusedKeys) > 0 {
+		errs = append(errs, fmt.Sprintf("'%s' has invalid keys: %s", name, strings.Join(unusedKeys, ", ")))
+	}
+	if d.config.ErrorUnset && len(unset) > 0 {
+		sort.Strings(unset)
+		errs = append(errs, fmt.Sprintf("'%s' has unset fields: %s", name, strings.Join(unset, ", ")))
+	}
+	if len(errs) > 0 {
+		return &Error{Errors: errs}
+	}
+
+	if d.config.Metadata != nil {
+		for _, key := range unusedKeys {
+			if name != "" {
+				key = name + "." + key
+			}
+			d.config.Metadata.Unused = append(d.config.Metadata.Unused, key)
+		}
+		for _, key := range unset {
+			if name != "" {
+				key = name + "." + key
+			}
+			d.config.Metadata.Unset = append(d.config.Metadata.Unset, key)
+		}
+	}
+	return nil
+}
+
+// typedDecodeHook converts a plain func to the hook type it matches
+func typedDecodeHook(h DecodeHookFunc) DecodeHookFunc {
+	v := reflect.ValueOf(h)
+	if !v.IsValid() {
+		return nil
+	}
+	for _, hookType := range []reflect.Type{
+		reflect.TypeOf(DecodeHookFuncType(nil)),
+		reflect.TypeOf(DecodeHookFuncKind(nil)),
+		reflect.TypeOf(DecodeHookFuncValue(nil)),
+	} {
+		if v.Type().ConvertibleTo(hookType) {
+			return v.Convert(hookType).Interface()
+		}
+	}
+	return nil
+}
+
+// DecodeHookExec runs a hook of any of the three kinds
+func DecodeHookExec(raw DecodeHookFunc, from reflect.Value, to reflect.Value) (interface{}, error) {
+	switch f := typedDecodeHook(raw).(type) {
+	case DecodeHookFuncType:
+		return f(from.Type(), to.Type(), from.Interface())
+	case DecodeHookFuncKind:
+		return f(from.Kind(), to.Kind(), from.Interface())
+	case DecodeHookFuncValue:
+		return f(from, to)
+	default:
+		return nil, errors.New("invalid decode hook signature")
+	}
+}
+
+// ComposeDecodeHookFunc runs hooks in order, each seeing the previous
+// one's result
+func ComposeDecodeHookFunc(fs ...DecodeHookFunc) DecodeHookFunc {
+	return func(from reflect.Value, to reflect.Value) (interface{}, error) {
+		data := from.Interface()
+		for _, f := range fs {
+			var err error
+			data, err = DecodeHookExec(f, from, to)
+			if err != nil {
+				return nil, err
+			}
+			if data == nil {
+				return nil, nil
+			}
+			from = reflect.ValueOf(data)
+		}
+		return data, nil
+	}
+}
+
+// OrComposeDecodeHookFunc returns the result of the first hook that
+// succeeds, or all their errors
+func OrComposeDecodeHookFunc(ff ...DecodeHookFunc) DecodeHookFunc {
+	return func(from reflect.Value, to reflect.Value) (interface{}, error) {
+		var messages []string
+		for _, f := range ff {
+			data, err := DecodeHookExec(f, from, to)
+			if err == nil {
+				return data, nil
+			}
+			messages = append(messages, err.Error())
+		}
+		return nil, errors.New(strings.Join(messages, "\n"))
+	}
+}
+
+// StringToSliceHookFunc splits strings at sep when decoding into slices
+func StringToSliceHookFunc(sep string) DecodeHookFunc {
+	return func(from reflect.Kind, to reflect.Kind, data interface{}) (interface{}, error) {
+		if from != reflect.String || to != reflect.Slice {
+			return data, nil
+		}
+		raw := reflect.ValueOf(data).String()
+		if raw == "" {
+			return []string{}, nil
+		}
+		return strings.Split(raw, sep), nil
+	}
+}
+
+// StringToTimeDurationHookFunc parses strings like "1m30s" into
+// time.Duration
+func StringToTimeDurationHookFunc() DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(time.Duration(0)) {
+			return data, nil
+		}
+		return time.ParseDuration(reflect.ValueOf(data).String())
+	}
+}
+
+// StringToTimeHookFunc parses strings into time.Time with layout
+func StringToTimeHookFunc(layout string) DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+		return time.Parse(layout, reflect.ValueOf(data).String())
+	}
+}
+
+// StringToIPHookFunc parses strings into net.IP
+func StringToIPHookFunc() DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(net.IP{}) {
+			return data, nil
+		}
+		ip := net.ParseIP(reflect.ValueOf(data).String())
+		if ip == nil {
+			return net.IP{}, fmt.Errorf("failed parsing ip %v", data)
+		}
+		return ip, nil
+	}
+}
+
+// StringToIPNetHookFunc parses CIDR strings into net.IPNet
+func StringToIPNetHookFunc() DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(net.IPNet{}) {
+			return data, nil
+		}
+		_, network, err := net.ParseCIDR(reflect.ValueOf(data).String())
+		if err != nil {
+			return nil, err
+		}
+		return *network, nil
+	}
+}
+
+// TextUnmarshallerHookFunc decodes strings with the target type's
+// UnmarshalText method, if it has one
+func TextUnmarshallerHookFunc() DecodeHookFunc {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String {
+			return data, nil
+		}
+		result := reflect.New(to)
+		unmarshaler, ok := result.Interface().(encoding.TextUnmarshaler)
+		if !ok {
+			return data, nil
+		}
+		if err := unmarshaler.UnmarshalText([]byte(reflect.ValueOf(data).String())); err != nil {
+			return nil, err
+		}
+		return result.Elem().Interface(), nil
+	}
+}
+
+// WeaklyTypedHook converts bools, numbers and byte slices to strings when
+// decoding into a string
+func WeaklyTypedHook(from reflect.Kind, to reflect.Kind, data interface{}) (interface{}, error) {
+	if to != reflect.String {
+		return data, nil
+	}
+	dataVal := reflect.ValueOf(data)
+	switch getKind(dataVal) {
+	case reflect.Bool:
+		if dataVal.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int:
+		return strconv.FormatInt(dataVal.Int(), 10), nil
+	case reflect.Uint:
+		return strconv.FormatUint(dataVal.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(dataVal.Float(), 'f', -1, 64), nil
+	case reflect.Slice:
+		if dataVal.Type().Elem().Kind() == reflect.Uint8 {
+			return string(dataVal.Bytes()), nil
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,504 @@
+package main
+
+// Developed by PowerShield, as an alternative to mitchellh/mapstructure
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+type Address struct {
+	Street string
+	City   string `mapstructure:"city"`
+}
+
+type Person struct {
+	Name    string
+	Age     int
+	Emails  []string
+	Address Address
+	Home    *Address
+	Tags    map[string]int
+	Skip    string `mapstructure:"-"`
+	private string
+}
+
+// Level decodes from its name with UnmarshalText
+type Level int
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+func testDecodeStruct() bool {
+	input := map[string]interface{}{
+		"name":    "Mitchell",
+		"AGE":     91,
+		"emails":  []interface{}{"one@example.com", "two@example.com"},
+		"address": map[string]interface{}{"street": "Main St", "CITY": "Springfield"},
+		"home":    map[string]string{"city": "Shelbyville"},
+		"tags":    map[string]interface{}{"a": 1, "b": int8(2)},
+		"skip":    "ignored",
+		"private": "hidden",
+	}
+	var p Person
+	if err := Decode(input, &p); err != nil {
+		return false
+	}
+	return p.Name == "Mitchell" && p.Age == 91 && len(p.Emails) == 2 && p.Emails[1] == "two@example.com" &&
+		p.Address.Street == "Main St" && p.Address.City == "Springfield" &&
+		p.Home != nil && p.Home.City == "Shelbyville" &&
+		reflect.DeepEqual(p.Tags, map[string]int{"a": 1, "b": 2}) && p.Skip == "" && p.private == ""
+}
+
+func testDecodeErrors() bool {
+	input := map[string]interface{}{
+		"name":    123,
+		"age":     "old",
+		"emails":  "not a list",
+		"address": map[string]interface{}{"city": true},
+	}
+	var p Person
+	err := Decode(input, &p)
+	derr, ok := err.(*Error)
+	if !ok || len(derr.Errors) != 4 || len(derr.WrappedErrors()) != 4 {
+		return false
+	}
+	msg := err.Error()
+	var n int8
+	overflow := Decode(300, &n)
+	var u uint
+	negative := Decode(-1, &u)
+	return strings.HasPrefix(msg, "4 error(s) decoding:\n\n* ") &&
+		strings.Contains(msg, "'Name' expected type 'string', got unconvertible type 'int', value: '123'") &&
+		strings.Contains(msg, "'Age' expected type 'int', got unconvertible type 'string', value: 'old'") &&
+		strings.Contains(msg, "'Emails': source data must be an array or slice, got string") &&
+		strings.Contains(msg, "'Address.city' expected type 'string', got unconvertible type 'bool'") &&
+		overflow != nil && strings.Contains(overflow.Error(), "overflows int8") &&
+		negative != nil && Decode("x", &struct{}{}) != nil
+}
+
+func testWeakTyping() bool {
+	var out struct {
+		Port    int
+		Ratio   float64
+		Enabled bool
+		Off     bool
+		Name    string
+		Count   uint
+		Hosts   []string
+		Label   string
+		Empty   []int
+		Merged  map[string]int
+	}
+	input := map[string]interface{}{
+		"port":    "8080",
+		"ratio":   "0.5",
+		"enabled": "true",
+		"off":     0,
+		"name":    42,
+		"count":   true,
+		"hosts":   "only-one",
+		"label":   []byte("bytes"),
+		"empty":   map[string]interface{}{},
+		"merged":  []interface{}{map[string]int{"a": 1}, map[string]int{"b": 2}},
+	}
+	if err := WeakDecode(input, &out); err != nil {
+		return false
+	}
+	if Decode(map[string]interface{}{"port": "8080"}, &out) == nil {
+		return false
+	}
+	return out.Port == 8080 && out.Ratio == 0.5 && out.Enabled && !out.Off && out.Name == "42" && out.Count == 1 &&
+		reflect.DeepEqual(out.Hosts, []string{"only-one"}) && out.Label == "bytes" &&
+		out.Empty != nil && len(out.Empty) == 0 && reflect.DeepEqual(out.Merged, map[string]int{"a": 1, "b": 2}) &&
+		WeakDecode(map[string]interface{}{"port": "eighty"}, &out) != nil
+}
+
+func testEmbeddedSquash() bool {
+	type Base struct {
+		ID      string
+		Created string
+	}
+	type Squashed struct {
+		Base `mapstructure:",squash"`
+		Name string
+	}
+	type Nested struct {
+		Base
+		Name string
+	}
+	input := map[string]interface{}{"id": "42", "created": "today", "name": "widget", "base": map[string]interface{}{"id": "7"}}
+
+	var squashed Squashed
+	var nested Nested
+	var byConfig Nested
+	if Decode(input, &squashed) != nil || Decode(input, &nested) != nil {
+		return false
+	}
+	if decodeWith(&DecoderConfig{Result: &byConfig, Squash: true}, input) != nil {
+		return false
+	}
+	var bad struct {
+		Name string `mapstructure:",squash"`
+	}
+	return squashed.ID == "42" && squashed.Created == "today" && squashed.Name == "widget" &&
+		nested.ID == "7" && nested.Created == "" && byConfig.ID == "42" &&
+		Decode(input, &bad) != nil
+}
+
+func testRemainFields() bool {
+	type Plugin struct {
+		Name  string
+		Type  string
+		Extra map[string]interface{} `mapstructure:",remain"`
+	}
+	input := map[string]interface{}{"name": "cache", "type": "redis", "host": "localhost", "ttl": 30}
+	var p Plugin
+	config := &DecoderConfig{Result: &p, ErrorUnused: true}
+	if decodeWith(config, input) != nil {
+		return false
+	}
+	var none Plugin
+	Decode(map[string]interface{}{"name": "x"}, &none)
+	return p.Name == "cache" && reflect.DeepEqual(p.Extra, map[string]interface{}{"host": "localhost", "ttl": 30}) &&
+		none.Extra == nil
+}
+
+func testUnusedAndUnset() bool {
+	input := map[string]interface{}{
+		"name":     "Ann",
+		"nickname": "A",
+		"address":  map[string]interface{}{"city": "Paris", "zip": "75001"},
+	}
+	var md Metadata
+	var p Person
+	if err := DecodeMetadata(input, &p, &md); err != nil {
+		return false
+	}
+	if !reflect.DeepEqual(md.Unused, []string{"Address.zip", "nickname"}) ||
+		!reflect.DeepEqual(md.Unset, []string{"Address.Street", "Age", "Emails", "Home", "Tags"}) ||
+		!reflect.DeepEqual(md.Keys, []string{"Name", "Address.city", "Address"}) {
+		return false
+	}
+
+	err := decodeWith(&DecoderConfig{Result: &Person{}, ErrorUnused: true}, input)
+	if err == nil || !strings.Contains(err.Error(), "'' has invalid keys: nickname") ||
+		!strings.Contains(err.Error(), "'Address' has invalid keys: zip") {
+		return false
+	}
+	var small struct{ A, B string }
+	err = decodeWith(&DecoderConfig{Result: &small, ErrorUnset: true}, map[string]interface{}{"a": "1"})
+	return err != nil && strings.Contains(err.Error(), "'' has unset fields: B")
+}
+
+func testDecodeHooks() bool {
+	var out struct {
+		Timeout time.Duration
+		Hosts   []string
+		Started time.Time
+		IP      net.IP
+		Network net.IPNet
+		Level   Level
+		Levels  []Level
+	}
+	input := map[string]interface{}{
+		"timeout": "1m30s",
+		"hosts":   "a,b,c",
+		"started": "2024-05-01",
+		"ip":      "10.0.0.1",
+		"network": "10.0.0.0/8",
+		"level":   "info",
+		"levels":  []string{"debug", "info"},
+	}
+	config := &DecoderConfig{
+		Result: &out,
+		DecodeHook: ComposeDecodeHookFunc(
+			// IP is a slice, so its hook goes before the slice hook
+			StringToIPHookFunc(),
+			StringToTimeDurationHookFunc(),
+			StringToSliceHookFunc(","),
+			StringToTimeHookFunc("2006-01-02"),
+			StringToIPNetHookFunc(),
+			TextUnmarshallerHookFunc(),
+		),
+	}
+	if err := decodeWith(config, input); err != nil {
+		return false
+	}
+	return out.Timeout == 90*time.Second && reflect.DeepEqual(out.Hosts, []string{"a", "b", "c"}) &&
+		out.Started.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) && out.IP.Equal(net.IPv4(10, 0, 0, 1)) &&
+		out.Network.String() == "10.0.0.0/8" && out.Level == 1 && reflect.DeepEqual(out.Levels, []Level{0, 1})
+}
+
+func testHookKinds() bool {
+	// Plain funcs of each shape work without conversion
+	upper := func(from reflect.Kind, to reflect.Kind, data interface{}) (interface{}, error) {
+		if from == reflect.String {
+			return strings.ToUpper(data.(string)), nil
+		}
+		return data, nil
+	}
+	var seen []string
+	record := func(from reflect.Value, to reflect.Value) (interface{}, error) {
+		seen = append(seen, to.Type().String())
+		return from.Interface(), nil
+	}
+	var out struct{ Name string }
+	if decodeWith(&DecoderConfig{Result: &out, DecodeHook: ComposeDecodeHookFunc(upper, record)}, map[string]interface{}{"name": "x"}) != nil ||
+		out.Name != "X" || !reflect.DeepEqual(seen, []string{"struct { Name string }", "string"}) {
+		return false
+	}
+
+	// Hook errors name the field being decoded
+	var timed struct{ Wait time.Duration }
+	err := decodeWith(&DecoderConfig{Result: &timed, DecodeHook: StringToTimeDurationHookFunc()}, map[string]interface{}{"wait": "soon"})
+	if err == nil || !strings.Contains(err.Error(), "error decoding 'Wait'") {
+		return false
+	}
+
+	// OrCompose takes the first hook that succeeds
+	either := OrComposeDecodeHookFunc(StringToTimeHookFunc(time.RFC3339), StringToTimeHookFunc("2006-01-02"))
+	var when struct{ At time.Time }
+	if decodeWith(&DecoderConfig{Result: &when, DecodeHook: either}, map[string]interface{}{"at": "2024-05-01"}) != nil ||
+		when.At.Year() != 2024 {
+		return false
+	}
+	bad := decodeWith(&DecoderConfig{Result: &out, DecodeHook: 42}, map[string]interface{}{"name": "x"})
+	weak, _ := WeaklyTypedHook(reflect.Bool, reflect.String, true)
+	return bad != nil && strings.Contains(bad.Error(), "invalid decode hook signature") && weak == "1"
+}
+
+func testStructToMap() bool {
+	type Meta struct {
+		Owner string `mapstructure:"owner"`
+	}
+	type Service struct {
+		Meta    `mapstructure:",squash"`
+		Name    string            `mapstructure:"name"`
+		Port    int               `mapstructure:"port,omitempty"`
+		Secret  string            `mapstructure:"-"`
+		Limits  Address           `mapstructure:"limits"`
+		Backup  *Address          `mapstructure:"backup"`
+		Labels  map[string]string `mapstructure:"labels,omitempty"`
+		private int
+	}
+	in := Service{Meta: Meta{Owner: "ops"}, Name: "api", Secret: "s3cret", Limits: Address{Street: "x", City: "y"}, private: 1}
+	out := map[string]interface{}{}
+	if err := Decode(in, &out); err != nil {
+		return false
+	}
+	want := map[string]interface{}{
+		"owner":  "ops",
+		"name":   "api",
+		"limits": map[string]interface{}{"Street": "x", "city": "y"},
+		"backup": nil,
+	}
+	if !reflect.DeepEqual(out, want) {
+		return false
+	}
+	// Struct to struct goes through the same map
+	var copied struct {
+		Owner  string
+		Name   string
+		Limits Address
+	}
+	return Decode(in, &copied) == nil && copied.Owner == "ops" && copied.Limits.City == "y"
+}
+
+func testJSONPayloads() bool {
+	payloads := []string{
+		`{"type": "click", "x": 10, "y": 20}`,
+		`{"type": "key", "code": 9007199254740993, "shift": true}`,
+	}
+	type Click struct{ X, Y int }
+	type Key struct {
+		Code  int64
+		Shift bool
+	}
+	var events []interface{}
+	for _, payload := range payloads {
+		dec := json.NewDecoder(bytes.NewReader([]byte(payload)))
+		dec.UseNumber()
+		var raw map[string]interface{}
+		if err := dec.Decode(&raw); err != nil {
+			return false
+		}
+		// Pick the type from a field, then decode the rest into it
+		var err error
+		switch raw["type"] {
+		case "click":
+			var c Click
+			err = Decode(raw, &c)
+			events = append(events, c)
+		case "key":
+			var k Key
+			err = Decode(raw, &k)
+			events = append(events, k)
+		}
+		if err != nil {
+			return false
+		}
+	}
+	var ratio struct{ R float64 }
+	var name struct{ N string }
+	num := json.Number("0.25")
+	return reflect.DeepEqual(events, []interface{}{Click{10, 20}, Key{9007199254740993, true}}) &&
+		Decode(map[string]interface{}{"r": num}, &ratio) == nil && ratio.R == 0.25 &&
+		Decode(map[string]interface{}{"n": num}, &name) == nil && name.N == "0.25"
+}
+
+func testCustomTags() bool {
+	type User struct {
+		ID       int    `json:"user_id"`
+		Name     string `json:"display_name"`
+		Internal string
+	}
+	input := map[string]interface{}{"user_id": 7, "display_name": "Ann", "internal": "x"}
+	var u User
+	config := &DecoderConfig{Result: &u, TagName: "json", IgnoreUntaggedFields: true}
+	if decodeWith(config, input) != nil || u.ID != 7 || u.Name != "Ann" || u.Internal != "" {
+		return false
+	}
+	// MatchName can make matching exact, or looser
+	var exact struct{ Name string }
+	decodeWith(&DecoderConfig{Result: &exact, MatchName: func(k, f string) bool { return k == f }}, map[string]interface{}{"name": "lower"})
+	var dashed struct{ MaxConns int }
+	loose := func(k, f string) bool { return strings.EqualFold(strings.ReplaceAll(k, "-", ""), f) }
+	decodeWith(&DecoderConfig{Result: &dashed, MatchName: loose}, map[string]interface{}{"max-conns": 5})
+	return exact.Name == "" && dashed.MaxConns == 5
+}
+
+func testZeroFields() bool {
+	p := Person{Name: "old", Tags: map[string]int{"keep": 1}, Emails: []string{"a", "b", "c"}, Home: &Address{Street: "Elm"}}
+	input := map[string]interface{}{"tags": map[string]int{"new": 2}, "emails": []string{"z"}, "home": map[string]string{"city": "X"}}
+	merged := p
+	merged.Tags = map[string]int{"keep": 1}
+	merged.Home = &Address{Street: "Elm"}
+	if Decode(input, &merged) != nil {
+		return false
+	}
+	if merged.Name != "old" || len(merged.Tags) != 2 || !reflect.DeepEqual(merged.Emails, []string{"z"}) ||
+		merged.Home.Street != "Elm" || merged.Home.City != "X" {
+		return false
+	}
+
+	zeroed := p
+	zeroed.Tags = map[string]int{"keep": 1}
+	input["name"] = nil
+	if decodeWith(&DecoderConfig{Result: &zeroed, ZeroFields: true}, input) != nil {
+		return false
+	}
+	return zeroed.Name == "" && reflect.DeepEqual(zeroed.Tags, map[string]int{"new": 2}) &&
+		zeroed.Home.Street == "" && zeroed.Home.City == "X"
+}
+
+func testArraysAndInterfaces() bool {
+	var out struct {
+		Pair  [2]string
+		Any   interface{}
+		Items []interface{}
+		Ptrs  []*Address
+	}
+	input := map[string]interface{}{
+		"pair":  []string{"a", "b"},
+		"any":   map[string]interface{}{"k": 1},
+		"items": []interface{}{1, "two", nil},
+		"ptrs":  []interface{}{map[string]interface{}{"city": "A"}, nil},
+	}
+	if err := Decode(input, &out); err != nil {
+		return false
+	}
+	if out.Pair != [2]string{"a", "b"} || !reflect.DeepEqual(out.Any, map[string]interface{}{"k": 1}) ||
+		!reflect.DeepEqual(out.Items, []interface{}{1, "two", nil}) || out.Ptrs[0].City != "A" || out.Ptrs[1] != nil {
+		return false
+	}
+	// An interface holding a pointer is decoded through
+	target := &Address{}
+	var holder interface{} = target
+	if Decode(map[string]interface{}{"city": "B"}, &holder) != nil || target.City != "B" {
+		return false
+	}
+	var tooLong [1]int
+	return Decode([]int{1, 2}, &tooLong) != nil && Decode(1, Address{}) != nil && Decode(1, nil) != nil
+}
+
+func testConfigDecoder() bool {
+	// The Viper emulator takes a decoder through this interface
+	var decoder interface {
+		Decode(input interface{}, output interface{}) error
+	} = NewConfigDecoder(StringToIPHookFunc())
+
+	type Server struct {
+		Host    string        `mapstructure:"host"`
+		Port    int           `mapstructure:"port"`
+		Timeout time.Duration `mapstructure:"timeout"`
+		Origins []string      `mapstructure:"origins"`
+		Bind    net.IP        `mapstructure:"bind"`
+	}
+	settings := map[string]interface{}{
+		"server": map[string]interface{}{
+			"host":    "localhost",
+			"port":    "8080",
+			"timeout": "5s",
+			"origins": "a.com,b.com",
+			"bind":    "127.0.0.1",
+		},
+	}
+	var cfg struct {
+		Server Server `mapstructure:"server"`
+	}
+	if err := decoder.Decode(settings, &cfg); err != nil {
+		return false
+	}
+	s := cfg.Server
+	// Each call gets its own copy of the configuration
+	var again struct{ Server Server }
+	return s.Host == "localhost" && s.Port == 8080 && s.Timeout == 5*time.Second &&
+		reflect.DeepEqual(s.Origins, []string{"a.com", "b.com"}) && s.Bind.Equal(net.IPv4(127, 0, 0, 1)) &&
+		decoder.Decode(settings, &again) == nil && again.Server.Port == 8080
+}
+
+func main() {
+	fmt.Println("Running mapstructure Emulator Tests...")
+	fmt.Println("======================================")
+
+	runTest("Decode Struct", testDecodeStruct)
+	runTest("Decode Errors", testDecodeErrors)
+	runTest("Weak Typing", testWeakTyping)
+	runTest("Embedded Squash", testEmbeddedSquash)
+	runTest("Remain Fields", testRemainFields)
+	runTest("Unused and Unset", testUnusedAndUnset)
+	runTest("Decode Hooks", testDecodeHooks)
+	runTest("Hook Kinds", testHookKinds)
+	runTest("Struct to Map", testStructToMap)
+	runTest("JSON Payloads", testJSONPayloads)
+	runTest("Custom Tags", testCustomTags)
+	runTest("Zero Fields", testZeroFields)
+	runTest("Arrays and Interfaces", testArraysAndInterfaces)
+	runTest("Config Decoder", testConfigDecoder)
+
+	fmt.Println("======================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,860 @@
+package main
+
+// Developed by PowerShield, as an alternative to gomail
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Encoding is a Content-Transfer-Encoding for message bodies
+type Encoding string
+
+// Body encodings
+const (
+	// QuotedPrintable keeps mostly-ASCII text readable; the default
+	QuotedPrintable Encoding = "quoted-printable"
+	// Base64 suits text in scripts other than Latin
+	Base64 Encoding = "base64"
+	// Unencoded sends bodies as 8-bit text, which most servers accept
+	Unencoded Encoding = "8bit"
+)
+
+// now returns the time used for Date headers; tests may replace it
+var now = time.Now
+
+// addressHeaders are the headers holding addresses, which SetHeader
+// formats and Send reads recipients from
+var addressHeaders = map[string]bool{
+	"From": true, "Sender": true, "Reply-To": true, "To": true, "Cc": true, "Bcc": true,
+}
+
+// Message is an email being composed
+type Message struct {
+	header      map[string][]string
+	parts       []*part
+	attachments []*file
+	embedded    []*file
+	charset     string
+	encoding    Encoding
+}
+
+type part struct {
+	contentType string
+	body        []byte
+	encoding    Encoding
+}
+
+type file struct {
+	name     string
+	header   map[string][]string
+	copyFunc func(w io.Writer) error
+}
+
+// MessageSetting configures a Message
+type MessageSetting func(m *Message)
+
+// SetCharset sets the charset of the message's text; the default is UTF-8
+func SetCharset(charset string) MessageSetting {
+	return func(m *Message) { m.charset = charset }
+}
+
+// SetEncoding sets the default encoding of the message's bodies
+func SetEncoding(enc Encoding) MessageSetting {
+	return func(m *Message) { m.encoding = enc }
+}
+
+// NewMessage returns an empty message
+func NewMessage(settings ...MessageSetting) *Message {
+	m := &Message{header: map[string][]string{}, charset: "UTF-8", encoding: QuotedPrintable}
+	for _, s := range settings {
+		s(m)
+	}
+	return m
+}
+
+// Reset clears the message so it can be reused, keeping its settings
+func (m *Message) Reset() {
+	m.header = map[string][]string{}
+	m.parts = nil
+	m.attachments = nil
+	m.embedded = nil
+}
+
+// SetHeader sets a header. Addresses in From, To, Cc, Bcc, Reply-To and
+// Sender are reformatted, encoding non-ASCII names; other values are
+// encoded if they are not ASCII.
+func (m *Message) SetHeader(field string, value ...string) {
+	values := make([]string, len(value))
+	for i, v := range value {
+		if addressHeaders[field] {
+			if addr, err := mail.ParseAddress(v); err == nil {
+				values[i] = m.FormatAddress(addr.Address, addr.Name)
+				continue
+			}
+		}
+		values[i] = m.encodeHeader(v)
+	}
+	m.header[field] = values
+}
+
+// SetHeaders sets several headers at once
+func (m *Message) SetHeaders(h map[string][]string) {
+	for field, values := range h {
+		m.SetHeader(field, values...)
+	}
+}
+
+// SetAddressHeader sets an address header to one address with a name
+func (m *Message) SetAddressHeader(field, address, name string) {
+	m.header[field] = []string{m.FormatAddress(address, name)}
+}
+
+// FormatAddress formats an address and a name as "Name" <address>,
+// encoding the name if it is not ASCII
+func (m *Message) FormatAddress(address, name string) string {
+	if name == "" {
+		return address
+	}
+	return (&mail.Address{Name: name, Address: address}).String()
+}
+
+// SetDateHeader sets a header to a date
+func (m *Message) SetDateHeader(field string, date time.Time) {
+	m.header[field] = []string{m.FormatDate(date)}
+}
+
+// FormatDate formats a date as RFC 5322 requires
+func (m *Message) FormatDate(date time.Time) string {
+	return date.Format(time.RFC1123Z)
+}
+
+// GetHeader returns the values of a header
+func (m *Message) GetHeader(field string) []string {
+	return m.header[field]
+}
+
+func (m *Message) encodeHeader(v string) string {
+	return mime.QEncoding.Encode(m.charset, v)
+}
+
+// PartSetting configures a body
+type PartSetting func(p *part)
+
+// SetPartEncoding sets one body's encoding
+func SetPartEncoding(enc Encoding) PartSetting {
+	return func(p *part) { p.encoding = enc }
+}
+
+// SetBody replaces the bodies with one of contentType, such as
+// "text/plain" or "text/html"
+func (m *Message) SetBody(contentType, body string, settings ...PartSetting) {
+	m.parts = nil
+	m.AddAlternative(contentType, body, settings...)
+}
+
+// AddAlternative adds another form of the body, such as HTML for a plain
+// text message. Mail clients show the last form they understand, so add
+// the plainest first.
+func (m *Message) AddAlternative(contentType, body string, settings ...PartSetting) {
+	p := &part{contentType: contentType, body: []byte(body), encoding: m.encoding}
+	for _, s := range settings {
+		s(p)
+	}
+	m.parts = append(m.parts, p)
+}
+
+// SetBodyWriter replaces the bodies with one written by f. If f fails,
+// the bodies are left as they were.
+func (m *Message) SetBodyWriter(contentType string, f func(io.Writer) error, settings ...PartSetting) error {
+	var buf bytes.Buffer
+	if err := f(&buf); err != nil {
+		return err
+	}
+	m.SetBody(contentType, buf.String(), settings...)
+	return nil
+}
+
+// AddAlternativeWriter adds a form of the body written by f
+func (m *Message) AddAlternativeWriter(contentType string, f func(io.Writer) error, settings ...PartSetting) error {
+	var buf bytes.Buffer
+	if err := f(&buf); err != nil {
+		return err
+	}
+	m.AddAlternative(contentType, buf.String(), settings...)
+	return nil
+}
+
+// Template renders a body. The *Template types of html/template and
+// text/template implement it.
+type Template interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// SetBodyTemplate replaces the bodies with tmpl rendered with data. Use
+// html/template for HTML bodies, so that data is escaped.
+func (m *Message) SetBodyTemplate(contentType string, tmpl Template, data interface{}, settings ...PartSetting) error {
+	return m.SetBodyWriter(contentType, func(w io.Writer) error { return tmpl.Execute(w, data) }, settings...)
+}
+
+// AddAlternativeTemplate adds a form of the body rendered from tmpl
+func (m *Message) AddAlternativeTemplate(contentType string, tmpl Template, data interface{}, settings ...PartSetting) error {
+	return m.AddAlternativeWriter(contentType, func(w io.Writer) error { return tmpl.Execute(w, data) }, settings...)
+}
+
+// FileSetting configures an attached or embedded file
+type FileSetting func(f *file)
+
+// Rename sets the file's name in the message, instead of its base name
+func Rename(name string) FileSetting {
+	return func(f *file) { f.name = name }
+}
+
+// SetHeader sets headers of the file's part, such as Content-Type
+func SetHeader(h map[string][]string) FileSetting {
+	return func(f *file) {
+		for field, values := range h {
+			f.header[field] = values
+		}
+	}
+}
+
+// SetCopyFunc sets how the file's contents are written, instead of
+// reading the named file when the message is written
+func SetCopyFunc(copyFunc func(w io.Writer) error) FileSetting {
+	return func(f *file) { f.copyFunc = copyFunc }
+}
+
+// Attach attaches a file, read when the message is written
+func (m *Message) Attach(filename string, settings ...FileSetting) {
+	m.attachments = append(m.attachments, newFile(filename, settings))
+}
+
+// Embed adds an inline file, which HTML bodies show with
+// <img src="cid:name">
+func (m *Message) Embed(filename string, settings ...FileSetting) {
+	m.embedded = append(m.embedded, newFile(filename, settings))
+}
+
+func newFile(filename string, settings []FileSetting) *file {
+	f := &file{
+		name:   filepath.Base(filename),
+		header: map[string][]string{},
+		copyFunc: func(w io.Writer) error {
+			h, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			defer h.Close()
+			_, err = io.Copy(w, h)
+			return err
+		},
+	}
+	for _, s := range settings {
+		s(f)
+	}
+	return f
+}
+
+// entity is a MIME header and its encoded body
+type entity struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// WriteTo writes the message in MIME format. Bcc is left out, since every
+// recipient would see it.
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	root, err := m.rootEntity()
+	if err != nil {
+		return 0, err
+	}
+
+	header := map[string][]string{}
+	for field, values := range m.header {
+		if field != "Bcc" {
+			header[field] = values
+		}
+	}
+	if _, ok := header["Mime-Version"]; !ok {
+		header["Mime-Version"] = []string{"1.0"}
+	}
+	if _, ok := header["Date"]; !ok {
+		header["Date"] = []string{m.FormatDate(now())}
+	}
+	for field, values := range root.header {
+		header[field] = values
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, header)
+	buf.Write(root.body)
+	return buf.WriteTo(w)
+}
+
+// rootEntity nests the bodies in multipart/alternative, then with
+// embedded files in multipart/related, then with attachments in
+// multipart/mixed, leaving out levels a message doesn't need
+func (m *Message) rootEntity() (entity, error) {
+	var bodies []entity
+	for _, p := range m.parts {
+		bodies = append(bodies, m.partEntity(p))
+	}
+	var root entity
+	switch len(bodies) {
+	case 0:
+		root = m.partEntity(&part{contentType: "text/plain", encoding: m.encoding})
+	case 1:
+		root = bodies[0]
+	default:
+		root = multipartEntity("alternative", bodies)
+	}
+
+	if len(m.embedded) > 0 {
+		related := []entity{root}
+		for _, f := range m.embedded {
+			e, err := fileEntity(f, "inline")
+			if err != nil {
+				return entity{}, err
+			}
+			related = append(related, e)
+		}
+		root = multipartEntity("related", related)
+	}
+	if len(m.attachments) > 0 {
+		mixed := []entity{root}
+		for _, f := range m.attachments {
+			e, err := fileEntity(f, "attachment")
+			if err != nil {
+				return entity{}, err
+			}
+			mixed = append(mixed, e)
+		}
+		root = multipartEntity("mixed", mixed)
+	}
+	return root, nil
+}
+
+func (m *Message) partEntity(p *part) entity {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", p.contentType+"; charset="+m.charset)
+	header.Set("Content-Transfer-Encoding", string(p.encoding))
+	return entity{header: header, body: encodeBody(p.body, p.encoding)}
+}
+
+func fileEntity(f *file, disposition string) (entity, error) {
+	var data bytes.Buffer
+	if err := f.copyFunc(&data); err != nil {
+		return entity{}, fmt.Errorf("gomail: could not read %s: %w", f.name, err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(f.name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": f.name}))
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": f.name}))
+	header.Set("Content-Transfer-Encoding", string(Base64))
+	if disposition == "inline" {
+		header.Set("Content-ID", "<"+f.name+">")
+	}
+	for field, values := range f.header {
+		header[textproto.CanonicalMIMEHeaderKey(field)] = values
+	}
+	return entity{header: header, body: encodeBody(data.Bytes(), Base64)}, nil
+}
+
+func multipartEntity(subtype string, children []entity) entity {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, child := range children {
+		pw, _ := w.CreatePart(child.header)
+		pw.Write(child.body)
+	}
+	w.Close()
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "multipart/"+subtype+"; boundary="+w.Boundary())
+	return entity{header: header, body: body.Bytes()}
+}
+
+// encodeBody encodes a body with CRLF line endings
+func encodeBody(body []byte, enc Encoding) []byte {
+	var buf bytes.Buffer
+	switch enc {
+	case Base64:
+		encoded := base64.StdEncoding.EncodeToString(body)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded)
+	case QuotedPrintable:
+		w := quotedprintable.NewWriter(&buf)
+		w.Write(body)
+		w.Close()
+	default:
+		text := strings.ReplaceAll(string(body), "\r\n", "\n")
+		buf.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	}
+	return buf.Bytes()
+}
+
+// writeHeader writes header fields in a fixed order: the usual envelope
+// fields first, then the rest sorted
+func writeHeader(buf *bytes.Buffer, header map[string][]string) {
+	order := []string{"Mime-Version", "Date", "From", "Sender", "Reply-To", "To", "Cc", "Subject"}
+	written := map[string]bool{}
+	write := func(field string) {
+		values, ok := header[field]
+		if !ok || written[field] {
+			return
+		}
+		written[field] = true
+		if addressHeaders[field] {
+			buf.WriteString(field + ": " + strings.Join(values, ", ") + "\r\n")
+			return
+		}
+		for _, v := range values {
+			buf.WriteString(field + ": " + v + "\r\n")
+		}
+	}
+	for _, field := range order {
+		write(field)
+	}
+	var rest []string
+	for field := range header {
+		rest = append(rest, field)
+	}
+	sort.Strings(rest)
+	for _, field := range rest {
+		write(field)
+	}
+	buf.WriteString("\r\n")
+}
+
+// Sending
+
+// Sender sends a message to the SMTP envelope's recipients
+type Sender interface {
+	Send(from string, to []string, msg io.WriterTo) error
+}
+
+// SendCloser is a Sender holding a connection open until Close
+type SendCloser interface {
+	Sender
+	Close() error
+}
+
+// SendFunc is a function implementing Sender
+type SendFunc func(from string, to []string, msg io.WriterTo) error
+
+// Send calls f
+func (f SendFunc) Send(from string, to []string, msg io.WriterTo) error {
+	return f(from, to, msg)
+}
+
+// Send sends each message through s. The envelope sender is the Sender
+// header, or else From; recipients are To, Cc and Bcc.
+func Send(s Sender, msg ...*Message) error {
+	for i, m := range msg {
+		if err := send(s, m); err != nil {
+			return fmt.Errorf("gomail: could not send email %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func send(s Sender, m *Message) error {
+	from, err := m.getFrom()
+	if err != nil {
+		return err
+	}
+	to, err := m.getRecipients()
+	if err != nil {
+		return err
+	}
+	return s.Send(from, to, m)
+}
+
+func (m *Message) getFrom() (string, error) {
+	from := m.header["Sender"]
+	if len(from) == 0 {
+		from = m.header["From"]
+		if len(from) == 0 {
+			return "", errors.New(`gomail: invalid message, "From" field is absent`)
+		}
+	}
+	return parseAddress(from[0])
+}
+
+func (m *Message) getRecipients() ([]string, error) {
+	var to []string
+	seen := map[string]bool{}
+	for _, field := range []string{"To", "Cc", "Bcc"} {
+		for _, v := range m.header[field] {
+			addr, err := parseAddress(v)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[strings.ToLower(addr)] {
+				seen[strings.ToLower(addr)] = true
+				to = append(to, addr)
+			}
+		}
+	}
+	if len(to) == 0 {
+		return nil, errors.New("gomail: invalid message, no recipients")
+	}
+	return to, nil
+}
+
+func parseAddress(field string) (string, error) {
+	addr, err := mail.ParseAddress(field)
+	if err != nil {
+		return "", fmt.Errorf("gomail: invalid address %q: %w", field, err)
+	}
+	return addr.Address, nil
+}
+
+// Dialer connects to an SMTP server. In the emulator the server is an
+// Outbox: Dialer.Outbox if set, otherwise DefaultOutbox.
+type Dialer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// SSL connects over TLS from the start, as port 465 expects
+	SSL bool
+	// LocalName is the name sent in HELO; the default is localhost
+	LocalName string
+	// Outbox receives the messages sent through this dialer
+	Outbox *Outbox
+}
+
+// NewDialer returns a dialer. SSL is enabled for port 465.
+func NewDialer(host string, port int, username, password string) *Dialer {
+	return &Dialer{Host: host, Port: port, Username: username, Password: password, SSL: port == 465}
+}
+
+// Dial opens a connection, authenticating if the server requires it
+func (d *Dialer) Dial() (SendCloser, error) {
+	if d.Host == "" {
+		return nil, fmt.Errorf("gomail: dial tcp :%d: missing address", d.Port)
+	}
+	outbox := d.Outbox
+	if outbox == nil {
+		outbox = DefaultOutbox
+	}
+	if err := outbox.authenticate(d.Username, d.Password); err != nil {
+		return nil, err
+	}
+	return &smtpSender{dialer: d, outbox: outbox}, nil
+}
+
+// DialAndSend opens a connection, sends the messages and closes it
+func (d *Dialer) DialAndSend(m ...*Message) error {
+	s, err := d.Dial()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return Send(s, m...)
+}
+
+type smtpSender struct {
+	dialer *Dialer
+	outbox *Outbox
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *smtpSender) Send(from string, to []string, msg io.WriterTo) error {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return errors.New("gomail: connection closed")
+	}
+	return s.outbox.Send(from, to, msg)
+}
+
+func (s *smtpSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Outboxes
+
+// SMTPError is an SMTP server's refusal
+type SMTPError struct {
+	Code    int
+	Message string
+}
+
+func (e *SMTPError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Outbox is an in-memory SMTP server that keeps what it is sent for tests
+// to inspect. It is a Sender itself, so Send(outbox, m) works without a
+// Dialer.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []*SentMessage
+	username string
+	password string
+	rejected map[string]bool
+}
+
+// DefaultOutbox receives the messages of dialers without their own Outbox
+var DefaultOutbox = NewOutbox()
+
+// NewOutbox returns an empty outbox that accepts everything
+func NewOutbox() *Outbox {
+	return &Outbox{rejected: map[string]bool{}}
+}
+
+// SetAuth makes the outbox require a username and password from dialers
+func (o *Outbox) SetAuth(username, password string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.username, o.password = username, password
+}
+
+// Reject makes the outbox refuse messages to the given addresses
+func (o *Outbox) Reject(address ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, a := range address {
+		o.rejected[strings.ToLower(a)] = true
+	}
+}
+
+func (o *Outbox) authenticate(username, password string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.username == "" || (username == o.username && password == o.password) {
+		return nil
+	}
+	return &SMTPError{Code: 535, Message: "5.7.8 Authentication credentials invalid"}
+}
+
+// Send implements Sender, keeping the message as it would be transmitted
+func (o *Outbox) Send(from string, to []string, msg io.WriterTo) error {
+	var raw bytes.Buffer
+	if _, err := msg.WriteTo(&raw); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, addr := range to {
+		if o.rejected[strings.ToLower(addr)] {
+			return &SMTPError{Code: 550, Message: "5.1.1 <" + addr + ">: Recipient address rejected"}
+		}
+	}
+	o.messages = append(o.messages, &SentMessage{
+		From: from,
+		To:   append([]string(nil), to...),
+		Raw:  raw.Bytes(),
+		Time: now(),
+	})
+	return nil
+}
+
+// Messages returns the messages received, oldest first
+func (o *Outbox) Messages() []*SentMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*SentMessage(nil), o.messages...)
+}
+
+// Len returns the number of messages received
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.messages)
+}
+
+// Last returns the newest message, or nil
+func (o *Outbox) Last() *SentMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.messages) == 0 {
+		return nil
+	}
+	return o.messages[len(o.messages)-1]
+}
+
+// To returns the messages delivered to address, including as Bcc
+func (o *Outbox) To(address string) []*SentMessage {
+	var result []*SentMessage
+	for _, m := range o.Messages() {
+		for _, to := range m.To {
+			if strings.EqualFold(to, address) {
+				result = append(result, m)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Reset forgets the messages received
+func (o *Outbox) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = nil
+}
+
+// SentMessage is a message an Outbox received
+type SentMessage struct {
+	// From and To are the SMTP envelope, which includes Bcc recipients
+	From string
+	To   []string
+	// Raw is the message as transmitted
+	Raw  []byte
+	Time time.Time
+}
+
+// Part is a leaf part of a received message, decoded
+type Part struct {
+	ContentType string
+	// Filename is set for attached and embedded files
+	Filename string
+	// Inline is set for embedded files
+	Inline    bool
+	ContentID string
+	Body      []byte
+}
+
+// Header returns the first value of a header, decoded
+func (s *SentMessage) Header(field string) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(s.Raw))
+	if err != nil {
+		return ""
+	}
+	value := msg.Header.Get(field)
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(value); err == nil {
+		return decoded
+	}
+	return value
+}
+
+// Subject returns the Subject header, decoded
+func (s *SentMessage) Subject() string {
+	return s.Header("Subject")
+}
+
+// Parts returns the message's leaf parts, in order
+func (s *SentMessage) Parts() ([]Part, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(s.Raw))
+	if err != nil {
+		return nil, err
+	}
+	return readParts(textproto.MIMEHeader(msg.Header), msg.Body)
+}
+
+func readParts(header textproto.MIMEHeader, body io.Reader) ([]Part, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var parts []Part
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextRawPart()
+			if err == io.EOF {
+				return parts, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			children, err := readParts(p.Header, p)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, children...)
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case string(Base64):
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case string(QuotedPrintable):
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	p := Part{ContentType: mediaType, Body: data}
+	if disposition, dparams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		p.Filename = dparams["filename"]
+		p.Inline = disposition == "inline"
+	}
+	p.ContentID = strings.Trim(header.Get("Content-ID"), "<>")
+	return []Part{p}, nil
+}
+
+// body returns the first body of the given type that isn't a file
+func (s *SentMessage) body(contentType string) string {
+	parts, _ := s.Parts()
+	for _, p := range parts {
+		if p.ContentType == contentType && p.Filename == "" {
+			return string(p.Body)
+		}
+	}
+	return ""
+}
+
+// Text returns the text/plain body, or ""
+func (s *SentMessage) Text() string {
+	return s.body("text/plain")
+}
+
+// HTML returns the text/html body, or ""
+func (s *SentMessage) HTML() string {
+	return s.body("text/html")
+}
+
+// Attachments returns the attached files, without embedded ones
+func (s *SentMessage) Attachments() []Part {
+	parts, _ := s.Parts()
+	var files []Part
+	for _, p := range parts {
+		if p.Filename != "" && !p.Inline {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// Embedded returns the inline files
+func (s *SentMessage) Embedded() []Part {
+	parts, _ := s.Parts()
+	var files []Part
+	for _, p := range parts {
+		if p.Inline {
+			files = append(files, p)
+		}
+	}
+	return files
+}
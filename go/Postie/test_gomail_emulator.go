@@ -0,0 +1,342 @@
+package main
+
+// Developed by PowerShield, as an alternative to gomail
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// newTestMessage returns a plain message from alice to bob
+func newTestMessage() *Message {
+	m := NewMessage()
+	m.SetHeader("From", "alice@example.com")
+	m.SetHeader("To", "bob@example.com")
+	m.SetHeader("Subject", "Hello")
+	m.SetBody("text/plain", "Hi Bob!")
+	return m
+}
+
+// sendTo sends m through an outbox and returns what it received
+func sendTo(m *Message) (*SentMessage, error) {
+	outbox := NewOutbox()
+	if err := Send(outbox, m); err != nil {
+		return nil, err
+	}
+	return outbox.Last(), nil
+}
+
+func testPlainMessage() bool {
+	now = func() time.Time { return time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	sent, err := sendTo(newTestMessage())
+	if err != nil {
+		return false
+	}
+	raw := string(sent.Raw)
+	return strings.HasPrefix(raw, "Mime-Version: 1.0\r\nDate: Fri, 01 Mar 2024 09:30:00 +0000\r\nFrom: alice@example.com\r\n") &&
+		strings.Contains(raw, "Content-Type: text/plain; charset=UTF-8\r\n") &&
+		strings.Contains(raw, "Content-Transfer-Encoding: quoted-printable\r\n") &&
+		sent.Subject() == "Hello" && sent.Text() == "Hi Bob!" && sent.HTML() == "" &&
+		sent.From == "alice@example.com" && sent.Header("To") == "bob@example.com"
+}
+
+func testRecipients() bool {
+	m := newTestMessage()
+	m.SetHeader("To", "bob@example.com", "Carol <carol@example.com>")
+	m.SetHeader("Cc", "dave@example.com", "BOB@example.com")
+	m.SetHeader("Bcc", "eve@example.com")
+	sent, err := sendTo(m)
+	if err != nil {
+		return false
+	}
+	// Bcc recipients get the message but aren't named in it, and
+	// duplicates are sent once
+	return strings.Join(sent.To, ",") == "bob@example.com,carol@example.com,dave@example.com,eve@example.com" &&
+		!strings.Contains(string(sent.Raw), "eve@example.com") &&
+		sent.Header("To") == `bob@example.com, "Carol" <carol@example.com>` &&
+		sent.Header("Cc") == "dave@example.com, BOB@example.com"
+}
+
+func testHeaders() bool {
+	m := newTestMessage()
+	m.SetAddressHeader("From", "jorg@example.com", "Jörg Müller")
+	m.SetHeader("Subject", "Grüße aus Köln")
+	m.SetHeaders(map[string][]string{"Reply-To": {"support@example.com"}, "X-Priority": {"1"}})
+	m.SetDateHeader("X-Sent", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	m.SetHeader("X-Injected", "ok\r\nBcc: mallory@example.com")
+
+	sent, err := sendTo(m)
+	if err != nil {
+		return false
+	}
+	raw := string(sent.Raw)
+	return strings.Contains(raw, "Subject: =?UTF-8?q?Gr=C3=BC=C3=9Fe_aus_K=C3=B6ln?=\r\n") &&
+		sent.Subject() == "Grüße aus Köln" && sent.Header("From") == "Jörg Müller <jorg@example.com>" &&
+		sent.From == "jorg@example.com" && sent.Header("Reply-To") == "support@example.com" &&
+		sent.Header("X-Priority") == "1" && sent.Header("X-Sent") == "Tue, 02 Jan 2024 03:04:05 +0000" &&
+		!strings.Contains(raw, "\r\nBcc:") && len(m.GetHeader("X-Priority")) == 1
+}
+
+func testAlternatives() bool {
+	m := newTestMessage()
+	m.SetBody("text/plain", "Hi Bob!")
+	m.AddAlternative("text/html", "<p>Hi <b>Bob</b>!</p>")
+	sent, err := sendTo(m)
+	if err != nil {
+		return false
+	}
+	parts, _ := sent.Parts()
+	return strings.Contains(string(sent.Raw), "Content-Type: multipart/alternative; boundary=") &&
+		len(parts) == 2 && parts[0].ContentType == "text/plain" && parts[1].ContentType == "text/html" &&
+		sent.Text() == "Hi Bob!" && sent.HTML() == "<p>Hi <b>Bob</b>!</p>"
+}
+
+func testEncodings() bool {
+	long := strings.Repeat("Grüße ", 30) + "\nline two"
+	check := func(settings []MessageSetting, part []PartSetting, want string) bool {
+		m := NewMessage(settings...)
+		m.SetHeader("From", "alice@example.com")
+		m.SetHeader("To", "bob@example.com")
+		m.SetBody("text/plain", long, part...)
+		sent, err := sendTo(m)
+		if err != nil {
+			return false
+		}
+		lines := strings.Split(string(sent.Raw), "\r\n")
+		for _, line := range lines {
+			if len(line) > 998 {
+				return false
+			}
+		}
+		return strings.Contains(string(sent.Raw), "Content-Transfer-Encoding: "+want+"\r\n") &&
+			strings.ReplaceAll(sent.Text(), "\r\n", "\n") == long
+	}
+	latin1 := NewMessage(SetCharset("ISO-8859-1"))
+	latin1.SetBody("text/plain", "x")
+	var buf bytes.Buffer
+	latin1.WriteTo(&buf)
+	return check(nil, nil, "quoted-printable") &&
+		check([]MessageSetting{SetEncoding(Base64)}, nil, "base64") &&
+		check(nil, []PartSetting{SetPartEncoding(Unencoded)}, "8bit") &&
+		strings.Contains(buf.String(), "charset=ISO-8859-1")
+}
+
+func testAttachments() bool {
+	dir, _ := os.MkdirTemp("", "postie")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.json")
+	os.WriteFile(path, []byte(`{"total":9.99}`), 0644)
+	pdf := bytes.Repeat([]byte{0x25, 0x50, 0x44, 0x46, 0x00, 0xff}, 40)
+
+	m := newTestMessage()
+	m.Attach(path)
+	m.Attach("invoice", Rename("invoice-42.pdf"), SetCopyFunc(func(w io.Writer) error {
+		_, err := w.Write(pdf)
+		return err
+	}), SetHeader(map[string][]string{"Content-Description": {"Your invoice"}}))
+	sent, err := sendTo(m)
+	if err != nil {
+		return false
+	}
+	files := sent.Attachments()
+	return strings.Contains(string(sent.Raw), "multipart/mixed") &&
+		strings.Contains(string(sent.Raw), "Content-Description: Your invoice") &&
+		len(files) == 2 && files[0].Filename == "report.json" && files[0].ContentType == "application/json" &&
+		string(files[0].Body) == `{"total":9.99}` &&
+		files[1].Filename == "invoice-42.pdf" && files[1].ContentType == "application/pdf" &&
+		bytes.Equal(files[1].Body, pdf) && sent.Text() == "Hi Bob!"
+}
+
+func testEmbedded() bool {
+	logo := []byte("\x89PNG fake image")
+	m := newTestMessage()
+	m.SetBody("text/plain", "See the logo")
+	m.AddAlternative("text/html", `<img src="cid:logo.png">`)
+	m.Embed("/assets/logo.png", SetCopyFunc(func(w io.Writer) error {
+		_, err := w.Write(logo)
+		return err
+	}))
+	m.Attach("terms.txt", SetCopyFunc(func(w io.Writer) error {
+		_, err := io.WriteString(w, "terms")
+		return err
+	}))
+	sent, err := sendTo(m)
+	if err != nil {
+		return false
+	}
+	// mixed holds related, which holds alternative and the image
+	raw := string(sent.Raw)
+	mixed := strings.Index(raw, "multipart/mixed")
+	related := strings.Index(raw, "multipart/related")
+	alternative := strings.Index(raw, "multipart/alternative")
+	embedded := sent.Embedded()
+	return mixed >= 0 && mixed < related && related < alternative &&
+		len(embedded) == 1 && embedded[0].ContentID == "logo.png" && bytes.Equal(embedded[0].Body, logo) &&
+		len(sent.Attachments()) == 1 && sent.HTML() == `<img src="cid:logo.png">`
+}
+
+func testMissingAttachment() bool {
+	m := newTestMessage()
+	m.Attach("/does/not/exist.pdf")
+	outbox := NewOutbox()
+	err := Send(outbox, m)
+	return err != nil && strings.Contains(err.Error(), "exist.pdf") &&
+		errors.Is(err, os.ErrNotExist) && outbox.Len() == 0
+}
+
+func testTemplates() bool {
+	type Order struct {
+		Name  string
+		Total float64
+	}
+	html := htmltemplate.Must(htmltemplate.New("order").Parse(`<p>Thanks, {{.Name}}! Total: ${{printf "%.2f" .Total}}</p>`))
+	text := texttemplate.Must(texttemplate.New("order").Parse(`Thanks, {{.Name}}! Total: ${{printf "%.2f" .Total}}`))
+	data := Order{Name: "<Bob & Co>", Total: 12.5}
+
+	m := newTestMessage()
+	if m.SetBodyTemplate("text/plain", text, data) != nil || m.AddAlternativeTemplate("text/html", html, data) != nil {
+		return false
+	}
+	// A failing template leaves the bodies alone
+	broken := texttemplate.Must(texttemplate.New("broken").Parse(`{{.Missing.Field}}`))
+	if m.SetBodyTemplate("text/plain", broken, data) == nil {
+		return false
+	}
+	sent, err := sendTo(m)
+	if err != nil {
+		return false
+	}
+	return sent.Text() == "Thanks, <Bob & Co>! Total: $12.50" &&
+		sent.HTML() == "<p>Thanks, &lt;Bob &amp; Co&gt;! Total: $12.50</p>"
+}
+
+func testSendErrors() bool {
+	outbox := NewOutbox()
+	noFrom := NewMessage()
+	noFrom.SetHeader("To", "bob@example.com")
+	noTo := NewMessage()
+	noTo.SetHeader("From", "alice@example.com")
+	badTo := newTestMessage()
+	badTo.SetHeader("To", "not an address")
+
+	err := Send(outbox, newTestMessage(), noFrom)
+	return err != nil && strings.HasPrefix(err.Error(), "gomail: could not send email 2:") &&
+		strings.Contains(err.Error(), `"From" field is absent`) &&
+		Send(outbox, noTo) != nil && Send(outbox, badTo) != nil && outbox.Len() == 1
+}
+
+func testDialer() bool {
+	outbox := NewOutbox()
+	outbox.SetAuth("app", "secret")
+	d := NewDialer("smtp.example.com", 587, "app", "secret")
+	d.Outbox = outbox
+
+	first, second := newTestMessage(), newTestMessage()
+	second.SetHeader("To", "carol@example.com")
+	if err := d.DialAndSend(first, second); err != nil || outbox.Len() != 2 {
+		return false
+	}
+
+	wrong := NewDialer("smtp.example.com", 465, "app", "guess")
+	wrong.Outbox = outbox
+	_, authErr := wrong.Dial()
+	var smtpErr *SMTPError
+	if !errors.As(authErr, &smtpErr) || smtpErr.Code != 535 || !wrong.SSL || d.SSL {
+		return false
+	}
+
+	// A closed connection sends nothing
+	s, _ := d.Dial()
+	s.Close()
+	if Send(s, newTestMessage()) == nil {
+		return false
+	}
+	_, noHost := (&Dialer{Port: 25}).Dial()
+	return noHost != nil && len(outbox.To("carol@example.com")) == 1 && len(outbox.To("BOB@example.com")) == 1
+}
+
+func testDefaultOutbox() bool {
+	defer DefaultOutbox.Reset()
+	DefaultOutbox.Reset()
+	if err := NewDialer("localhost", 25, "", "").DialAndSend(newTestMessage()); err != nil {
+		return false
+	}
+	return DefaultOutbox.Len() == 1 && DefaultOutbox.Last().Subject() == "Hello"
+}
+
+func testRejectedRecipient() bool {
+	outbox := NewOutbox()
+	outbox.Reject("Blocked@example.com")
+	m := newTestMessage()
+	m.SetHeader("Cc", "blocked@example.com")
+	err := Send(outbox, m)
+	var smtpErr *SMTPError
+	return errors.As(err, &smtpErr) && smtpErr.Code == 550 &&
+		strings.Contains(err.Error(), "<blocked@example.com>") && outbox.Len() == 0
+}
+
+func testSendFuncAndReset() bool {
+	var envelopes []string
+	sender := SendFunc(func(from string, to []string, msg io.WriterTo) error {
+		envelopes = append(envelopes, from+"->"+strings.Join(to, ","))
+		return nil
+	})
+	m := newTestMessage()
+	m.SetHeader("Sender", "bounces@example.com")
+	if err := Send(sender, m); err != nil {
+		return false
+	}
+
+	m.Reset()
+	m.SetHeader("From", "alice@example.com")
+	m.SetHeader("To", "bob@example.com")
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	outbox := NewOutbox()
+	Send(outbox, newTestMessage())
+	outbox.Reset()
+	return len(envelopes) == 1 && envelopes[0] == "bounces@example.com->bob@example.com" &&
+		!strings.Contains(buf.String(), "Subject") && strings.Contains(buf.String(), "text/plain") &&
+		outbox.Len() == 0 && outbox.Last() == nil
+}
+
+func main() {
+	fmt.Println("Running gomail Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Plain Message", testPlainMessage)
+	runTest("Recipients", testRecipients)
+	runTest("Headers", testHeaders)
+	runTest("Alternatives", testAlternatives)
+	runTest("Encodings", testEncodings)
+	runTest("Attachments", testAttachments)
+	runTest("Embedded Files", testEmbedded)
+	runTest("Missing Attachment", testMissingAttachment)
+	runTest("Templates", testTemplates)
+	runTest("Send Errors", testSendErrors)
+	runTest("Dialer", testDialer)
+	runTest("Default Outbox", testDefaultOutbox)
+	runTest("Rejected Recipient", testRejectedRecipient)
+	runTest("SendFunc and Reset", testSendFuncAndReset)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
@@ -6,6 +6,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // MockT implements TestingT for testing
@@ -28,6 +29,22 @@ func (m *MockT) Failed() bool {
 	return m.failed
 }
 
+// flatYAML parses "key: value" lines, standing in for the yaml emulator
+type flatYAML struct{}
+
+func (flatYAML) Unmarshal(data []byte, v interface{}) error {
+	doc := map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("line %q has no ':'", line)
+		}
+		doc[strings.TrimSpace(parts[0])] = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+	}
+	*v.(*interface{}) = doc
+	return nil
+}
+
 // Test runner
 func main() {
 	fmt.Println("Running Testify Emulator Tests...\n")
@@ -491,6 +508,76 @@ func main() {
 		failed++
 	}
 	
+	// Test 26: JSONEq
+	fmt.Println("\nTest Group: JSONEq")
+	t26 := &MockT{}
+	assert26 := New(t26)
+	if assert26.JSONEq(`{"hello": "world", "n": [1, 2]}`, `{"n":[1,2],"hello":"world"}`) && !t26.Failed() {
+		fmt.Println("✓ JSONEq ignores key order and whitespace")
+		passed++
+	} else {
+		fmt.Println("✗ JSONEq ignores key order and whitespace")
+		failed++
+	}
+	
+	t26b := &MockT{}
+	if !JSONEq(t26b, `{"n": 1}`, `{"n": 2}`) && !JSONEq(t26b, `{"n": 1}`, `{n: 1}`) && len(t26b.Errors) == 2 &&
+		strings.Contains(t26b.Errors[1], "needs to be valid json") {
+		fmt.Println("✓ JSONEq detects different and invalid JSON")
+		passed++
+	} else {
+		fmt.Println("✗ JSONEq detects different and invalid JSON")
+		failed++
+	}
+	
+	// Test 27: YAMLEq
+	fmt.Println("\nTest Group: YAMLEq")
+	t27 := &MockT{}
+	if !YAMLEq(t27, "a: 1", "a: 1") && strings.Contains(t27.Errors[0], "no YAML codec registered") {
+		fmt.Println("✓ YAMLEq fails without a YAML parser")
+		passed++
+	} else {
+		fmt.Println("✗ YAMLEq fails without a YAML parser")
+		failed++
+	}
+	
+	YAML = flatYAML{}
+	t27b := &MockT{}
+	assert27b := New(t27b)
+	if assert27b.YAMLEq("name: api\nport: 80", "port: '80'\nname: \"api\"") && !t27b.Failed() &&
+		!assert27b.YAMLEq("name: api", "name: web") && !assert27b.YAMLEq("name: api", "- api") {
+		fmt.Println("✓ YAMLEq compares parsed documents")
+		passed++
+	} else {
+		fmt.Println("✗ YAMLEq compares parsed documents")
+		failed++
+	}
+	YAML = nil
+	
+	// Test 28: Comparer
+	fmt.Println("\nTest Group: Comparer")
+	var comparer interface {
+		ObjectsAreEqual(expected, actual interface{}) bool
+		IsNil(object interface{}) bool
+		IsEmpty(object interface{}) bool
+		Len(object interface{}) int
+		Contains(haystack, needle interface{}) bool
+		Compare(e1, e2 interface{}) (int, bool)
+	} = Comparer{}
+	var nilMap map[string]int
+	order, ordered := comparer.Compare("a", "b")
+	_, mixed := comparer.Compare(1, "b")
+	if comparer.ObjectsAreEqual([]int{1}, []int{1}) && !comparer.ObjectsAreEqual(1, int64(1)) &&
+		comparer.IsNil(nilMap) && !comparer.IsNil(0) && comparer.IsEmpty("") && !comparer.IsEmpty([]int{0}) &&
+		comparer.Len(map[int]int{1: 1, 2: 2}) == 2 && comparer.Contains([]string{"a", "b"}, "b") &&
+		order == -1 && ordered && !mixed {
+		fmt.Println("✓ Comparer exposes the comparison helpers")
+		passed++
+	} else {
+		fmt.Println("✗ Comparer exposes the comparison helpers")
+		failed++
+	}
+	
 	// Final results
 	fmt.Println("\n" + "==================================================")
 	fmt.Printf("Test Results: %d passed, %d failed\n", passed, failed)
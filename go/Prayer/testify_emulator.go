@@ -18,6 +18,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -223,6 +224,45 @@ func (a *Assertions) Less(e1, e2 interface{}, msgAndArgs ...interface{}) bool {
 	return true
 }
 
+// JSONEq asserts that two JSON strings are equivalent, ignoring key
+// order and whitespace
+func (a *Assertions) JSONEq(expected, actual string, msgAndArgs ...interface{}) bool {
+	var expectedJSON, actualJSON interface{}
+	if err := json.Unmarshal([]byte(expected), &expectedJSON); err != nil {
+		return a.fail(fmt.Sprintf("Expected value ('%s') is not valid json.\nJSON parsing error: '%s'", expected, err.Error()), msgAndArgs...)
+	}
+	if err := json.Unmarshal([]byte(actual), &actualJSON); err != nil {
+		return a.fail(fmt.Sprintf("Input ('%s') needs to be valid json.\nJSON parsing error: '%s'", actual, err.Error()), msgAndArgs...)
+	}
+	return a.Equal(expectedJSON, actualJSON, msgAndArgs...)
+}
+
+// YAMLUnmarshaler parses YAML documents for YAMLEq. The yaml emulator's
+// Codec implements it, as can any YAML library with this method.
+type YAMLUnmarshaler interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// YAML is the parser YAMLEq uses. It is nil until one is set.
+var YAML YAMLUnmarshaler
+
+// YAMLEq asserts that two YAML strings are equivalent, ignoring key
+// order, layout and quoting. It needs YAML to be set, and fails every
+// comparison until it is.
+func (a *Assertions) YAMLEq(expected, actual string, msgAndArgs ...interface{}) bool {
+	if YAML == nil {
+		return a.fail("YAMLEq: no YAML codec registered; set YAML to a YAMLUnmarshaler such as the yaml emulator's Codec", msgAndArgs...)
+	}
+	var expectedYAML, actualYAML interface{}
+	if err := YAML.Unmarshal([]byte(expected), &expectedYAML); err != nil {
+		return a.fail(fmt.Sprintf("Expected value ('%s') is not valid yaml.\nYAML parsing error: '%s'", expected, err.Error()), msgAndArgs...)
+	}
+	if err := YAML.Unmarshal([]byte(actual), &actualYAML); err != nil {
+		return a.fail(fmt.Sprintf("Input ('%s') needs to be valid yaml.\nYAML error: '%s'", actual, err.Error()), msgAndArgs...)
+	}
+	return a.Equal(expectedYAML, actualYAML, msgAndArgs...)
+}
+
 // fail reports a failure
 func (a *Assertions) fail(message string, msgAndArgs ...interface{}) bool {
 	if len(msgAndArgs) > 0 {
@@ -368,6 +408,43 @@ func compare(e1, e2 interface{}) (int, bool) {
 	return 0, false
 }
 
+// Comparer exposes the helpers the assertions are built on, for emulators
+// that take comparisons through an interface, such as the ginkgo
+// emulator's Comparer
+type Comparer struct{}
+
+// ObjectsAreEqual reports whether expected and actual are deeply equal
+func (Comparer) ObjectsAreEqual(expected, actual interface{}) bool {
+	return objectsAreEqual(expected, actual)
+}
+
+// IsNil reports whether object is nil or a nil pointer, map, slice, etc.
+func (Comparer) IsNil(object interface{}) bool {
+	return isNil(object)
+}
+
+// IsEmpty reports whether object is nil, has no elements, or points to
+// something empty
+func (Comparer) IsEmpty(object interface{}) bool {
+	return isEmpty(object)
+}
+
+// Len returns the number of elements in object, or 0 if it has no length
+func (Comparer) Len(object interface{}) int {
+	return getLength(object)
+}
+
+// Contains reports whether a string contains a substring, or a slice,
+// array or map contains an element
+func (Comparer) Contains(haystack, needle interface{}) bool {
+	return contains(haystack, needle)
+}
+
+// Compare orders two numbers or strings of the same kind
+func (Comparer) Compare(e1, e2 interface{}) (int, bool) {
+	return compare(e1, e2)
+}
+
 // Mock provides a simple mock object
 type Mock struct {
 	Calls       []Call
@@ -551,3 +628,13 @@ func NotEmpty(t TestingT, object interface{}, msgAndArgs ...interface{}) bool {
 	return New(t).NotEmpty(object, msgAndArgs...)
 }
 
+// JSONEq is a convenience function
+func JSONEq(t TestingT, expected, actual string, msgAndArgs ...interface{}) bool {
+	return New(t).JSONEq(expected, actual, msgAndArgs...)
+}
+
+// YAMLEq is a convenience function; like Assertions.YAMLEq, it needs YAML
+// to be set
+func YAMLEq(t TestingT, expected, actual string, msgAndArgs ...interface{}) bool {
+	return New(t).YAMLEq(expected, actual, msgAndArgs...)
+}
@@ -0,0 +1,692 @@
+package main
+
+// Developed by PowerShield, as an alternative to testing/quick
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Generator is implemented by types that make their own random values
+type Generator interface {
+	// Generate returns a random value of the type. size bounds the length
+	// of strings, slices and maps inside it.
+	Generate(rand *rand.Rand, size int) reflect.Value
+}
+
+// Shrinker is implemented by types that know smaller versions of a value,
+// for reporting the simplest input a property fails on
+type Shrinker interface {
+	// Shrink returns candidates simpler than the receiver, simplest first
+	Shrink() []reflect.Value
+}
+
+// TestingT is the part of *testing.T ForAll reports to. The testify
+// emulator's TestingT has the same methods.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	FailNow()
+	Failed() bool
+}
+
+const (
+	// defaultMaxCount is how many inputs a property is checked with
+	defaultMaxCount = 100
+	// complexSize bounds the length of generated strings, slices and maps
+	complexSize = 50
+	// defaultMaxShrinks bounds the steps taken to simplify a failing input
+	defaultMaxShrinks = 1000
+)
+
+// Config controls a check
+type Config struct {
+	// MaxCount is how many inputs to try. If zero, MaxCountScale scales
+	// the default of 100.
+	MaxCount      int
+	MaxCountScale float64
+	// Rand is the source of randomness. If nil, one is seeded from the
+	// clock, and the seed is reported with any failure.
+	Rand *rand.Rand
+	// Values fills in the arguments for one run. If nil, Value is used
+	// for each argument.
+	Values func([]reflect.Value, *rand.Rand)
+	// MaxShrinks bounds the steps taken to simplify a failing input.
+	// If zero, it is 1000; if negative, failing inputs are not shrunk.
+	MaxShrinks int
+}
+
+var defaultConfig Config
+
+func (c *Config) getRand() (*rand.Rand, int64) {
+	if c.Rand != nil {
+		return c.Rand, 0
+	}
+	seed := time.Now().UnixNano()
+	return rand.New(rand.NewSource(seed)), seed
+}
+
+func (c *Config) getMaxCount() int {
+	switch {
+	case c.MaxCount > 0:
+		return c.MaxCount
+	case c.MaxCountScale != 0:
+		return int(c.MaxCountScale * defaultMaxCount)
+	}
+	return defaultMaxCount
+}
+
+func (c *Config) getMaxShrinks() int {
+	switch {
+	case c.MaxShrinks < 0:
+		return 0
+	case c.MaxShrinks > 0:
+		return c.MaxShrinks
+	}
+	return defaultMaxShrinks
+}
+
+// SetupError is returned when a property cannot be checked at all, such as
+// when it is not a function or its arguments cannot be generated
+type SetupError string
+
+func (s SetupError) Error() string { return string(s) }
+
+// CheckError describes the input a property failed on. In is the
+// simplest failing input shrinking found, and Original the one first
+// generated.
+type CheckError struct {
+	Count    int
+	In       []interface{}
+	Original []interface{}
+	Shrinks  int
+	// Seed reproduces the run when the Config had no Rand
+	Seed int64
+	// Err is the error the property returned, and Panic what it panicked
+	// with, if either
+	Err   error
+	Panic interface{}
+}
+
+func (s *CheckError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d: failed on input %s", s.Count, toString(s.In))
+	if s.Shrinks > 0 {
+		fmt.Fprintf(&b, " (shrunk from %s in %d steps)", toString(s.Original), s.Shrinks)
+	}
+	if s.Err != nil {
+		fmt.Fprintf(&b, ": %v", s.Err)
+	}
+	if s.Panic != nil {
+		fmt.Fprintf(&b, ": panic: %v", s.Panic)
+	}
+	if s.Seed != 0 {
+		fmt.Fprintf(&b, " [seed %d]", s.Seed)
+	}
+	return b.String()
+}
+
+// CheckEqualError is a CheckError where two functions gave different
+// results
+type CheckEqualError struct {
+	CheckError
+	Out1 []interface{}
+	Out2 []interface{}
+}
+
+func (s *CheckEqualError) Error() string {
+	return fmt.Sprintf("#%d: failed on input %s. Output 1: %s. Output 2: %s",
+		s.Count, toString(s.In), toString(s.Out1), toString(s.Out2))
+}
+
+func toString(interfaces []interface{}) string {
+	s := make([]string, len(interfaces))
+	for i, v := range interfaces {
+		s[i] = fmt.Sprintf("%#v", v)
+	}
+	return strings.Join(s, ", ")
+}
+
+func toInterfaces(values []reflect.Value) []interface{} {
+	ret := make([]interface{}, len(values))
+	for i, v := range values {
+		ret[i] = v.Interface()
+	}
+	return ret
+}
+
+// Value returns a random value of type t, or false if t's values cannot be
+// generated, as for interfaces, channels and functions
+func Value(t reflect.Type, rand *rand.Rand) (value reflect.Value, ok bool) {
+	return sizedValue(t, rand, complexSize)
+}
+
+func sizedValue(t reflect.Type, rand *rand.Rand, size int) (value reflect.Value, ok bool) {
+	if m, ok := reflect.Zero(t).Interface().(Generator); ok {
+		return m.Generate(rand, size), true
+	}
+
+	v := reflect.New(t).Elem()
+	switch concrete := t; concrete.Kind() {
+	case reflect.Bool:
+		v.SetBool(rand.Int()&1 == 0)
+	case reflect.Float32:
+		v.SetFloat(float64(randFloat32(rand)))
+	case reflect.Float64:
+		v.SetFloat(randFloat64(rand))
+	case reflect.Complex64:
+		v.SetComplex(complex(float64(randFloat32(rand)), float64(randFloat32(rand))))
+	case reflect.Complex128:
+		v.SetComplex(complex(randFloat64(rand), randFloat64(rand)))
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		v.SetInt(randInt64(rand))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint, reflect.Uintptr:
+		v.SetUint(uint64(randInt64(rand)))
+	case reflect.String:
+		numChars := rand.Intn(complexSize)
+		codePoints := make([]rune, numChars)
+		for i := range codePoints {
+			codePoints[i] = rune(rand.Intn(0x10ffff))
+		}
+		v.SetString(string(codePoints))
+	case reflect.Map:
+		numElems := rand.Intn(size)
+		v.Set(reflect.MakeMap(concrete))
+		for i := 0; i < numElems; i++ {
+			key, ok1 := sizedValue(concrete.Key(), rand, size)
+			value, ok2 := sizedValue(concrete.Elem(), rand, size)
+			if !ok1 || !ok2 {
+				return reflect.Value{}, false
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Ptr:
+		if rand.Intn(size) == 0 {
+			v.Set(reflect.Zero(concrete)) // nil pointer
+		} else {
+			elem, ok := sizedValue(concrete.Elem(), rand, size)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v.Set(reflect.New(concrete.Elem()))
+			v.Elem().Set(elem)
+		}
+	case reflect.Slice:
+		numElems := rand.Intn(size)
+		sizeLeft := size - numElems
+		v.Set(reflect.MakeSlice(concrete, numElems, numElems))
+		for i := 0; i < numElems; i++ {
+			elem, ok := sizedValue(concrete.Elem(), rand, sizeLeft)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v.Index(i).Set(elem)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elem, ok := sizedValue(concrete.Elem(), rand, size)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v.Index(i).Set(elem)
+		}
+	case reflect.Struct:
+		// Unexported fields cannot be set and keep their zero value
+		for i := 0; i < v.NumField(); i++ {
+			if concrete.Field(i).PkgPath != "" {
+				continue
+			}
+			elem, ok := sizedValue(concrete.Field(i).Type, rand, size)
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v.Field(i).Set(elem)
+		}
+	default:
+		return reflect.Value{}, false
+	}
+
+	return v, true
+}
+
+// randInt64 returns a random int64 of any magnitude
+func randInt64(rand *rand.Rand) int64 {
+	return int64(rand.Uint64())
+}
+
+// randFloat32 returns a random float32 of any sign and magnitude
+func randFloat32(rand *rand.Rand) float32 {
+	f := rand.Float64() * math.MaxFloat32
+	if rand.Int()&1 == 1 {
+		f = -f
+	}
+	return float32(f)
+}
+
+func randFloat64(rand *rand.Rand) float64 {
+	f := rand.Float64() * math.MaxFloat64
+	if rand.Int()&1 == 1 {
+		f = -f
+	}
+	return f
+}
+
+// Check tries f, a function returning bool or error, with random
+// arguments. It returns nil if f returned true or nil every time, a
+// *CheckError with the simplest failing input it found, or a SetupError.
+func Check(f interface{}, config *Config) error {
+	if config == nil {
+		config = &defaultConfig
+	}
+
+	fVal, fType, ok := functionAndType(f)
+	if !ok {
+		return SetupError("argument is not a function")
+	}
+	if fType.NumOut() != 1 || fType.Out(0).Kind() != reflect.Bool && fType.Out(0) != errorType {
+		return SetupError("function does not return one bool or error")
+	}
+
+	rand, seed := config.getRand()
+	arguments := make([]reflect.Value, fType.NumIn())
+	maxCount := config.getMaxCount()
+
+	for i := 0; i < maxCount; i++ {
+		if err := arbitraryValues(arguments, fType, config, rand); err != nil {
+			return err
+		}
+		if outcome := runProperty(fVal, arguments); !outcome.passed() {
+			in := append([]reflect.Value(nil), arguments...)
+			shrunk, last, steps := shrinkInputs(in, outcome, config.getMaxShrinks(), func(args []reflect.Value) propertyOutcome {
+				return runProperty(fVal, args)
+			})
+			return &CheckError{
+				Count:    i + 1,
+				In:       toInterfaces(shrunk),
+				Original: toInterfaces(in),
+				Shrinks:  steps,
+				Seed:     seed,
+				Err:      last.err,
+				Panic:    last.panicked,
+			}
+		}
+	}
+	return nil
+}
+
+// CheckEqual tries f and g, functions of the same type, with random
+// arguments and returns a *CheckEqualError if their results ever differ
+func CheckEqual(f, g interface{}, config *Config) error {
+	if config == nil {
+		config = &defaultConfig
+	}
+
+	x, xType, ok := functionAndType(f)
+	if !ok {
+		return SetupError("f is not a function")
+	}
+	y, yType, ok := functionAndType(g)
+	if !ok {
+		return SetupError("g is not a function")
+	}
+	if xType != yType {
+		return SetupError("functions have different types")
+	}
+
+	rand, seed := config.getRand()
+	arguments := make([]reflect.Value, xType.NumIn())
+	maxCount := config.getMaxCount()
+
+	equal := func(args []reflect.Value) propertyOutcome {
+		xOut, xPanic := call(x, args)
+		yOut, yPanic := call(y, args)
+		if xPanic != nil || yPanic != nil {
+			return propertyOutcome{panicked: firstNonNil(xPanic, yPanic)}
+		}
+		return propertyOutcome{ok: reflect.DeepEqual(toInterfaces(xOut), toInterfaces(yOut))}
+	}
+
+	for i := 0; i < maxCount; i++ {
+		if err := arbitraryValues(arguments, xType, config, rand); err != nil {
+			return err
+		}
+		if outcome := equal(arguments); !outcome.passed() {
+			in := append([]reflect.Value(nil), arguments...)
+			shrunk, last, steps := shrinkInputs(in, outcome, config.getMaxShrinks(), equal)
+			xOut, _ := call(x, shrunk)
+			yOut, _ := call(y, shrunk)
+			return &CheckEqualError{
+				CheckError: CheckError{
+					Count:    i + 1,
+					In:       toInterfaces(shrunk),
+					Original: toInterfaces(in),
+					Shrinks:  steps,
+					Seed:     seed,
+					Panic:    last.panicked,
+				},
+				Out1: toInterfaces(xOut),
+				Out2: toInterfaces(yOut),
+			}
+		}
+	}
+	return nil
+}
+
+// ForAll checks the property f like Check and reports a failure to t. It
+// returns whether the property held.
+func ForAll(t TestingT, f interface{}, config *Config) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	err := Check(f, config)
+	if err == nil {
+		return true
+	}
+	if _, ok := err.(SetupError); ok {
+		t.Errorf("property could not be checked: %v", err)
+	} else {
+		t.Errorf("property failed: %v", err)
+	}
+	return false
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func functionAndType(f interface{}) (v reflect.Value, t reflect.Type, ok bool) {
+	v = reflect.ValueOf(f)
+	ok = v.Kind() == reflect.Func
+	if !ok {
+		return
+	}
+	t = v.Type()
+	return
+}
+
+// arbitraryValues fills args with random values for f's arguments
+func arbitraryValues(args []reflect.Value, f reflect.Type, config *Config, rand *rand.Rand) error {
+	if config.Values != nil {
+		config.Values(args, rand)
+		return nil
+	}
+	for j := 0; j < len(args); j++ {
+		var ok bool
+		args[j], ok = Value(f.In(j), rand)
+		if !ok {
+			return SetupError(fmt.Sprintf("cannot create arbitrary value of type %s for argument %d", f.In(j), j))
+		}
+	}
+	return nil
+}
+
+// propertyOutcome is the result of one run of a property
+type propertyOutcome struct {
+	ok       bool
+	err      error
+	panicked interface{}
+}
+
+func (o propertyOutcome) passed() bool {
+	return o.ok && o.err == nil && o.panicked == nil
+}
+
+func runProperty(f reflect.Value, args []reflect.Value) propertyOutcome {
+	out, panicked := call(f, args)
+	if panicked != nil {
+		return propertyOutcome{panicked: panicked}
+	}
+	if out[0].Kind() == reflect.Bool {
+		return propertyOutcome{ok: out[0].Bool()}
+	}
+	if err, _ := out[0].Interface().(error); err != nil {
+		return propertyOutcome{err: err}
+	}
+	return propertyOutcome{ok: true}
+}
+
+// call runs f, returning what it panicked with rather than panicking
+func call(f reflect.Value, args []reflect.Value) (out []reflect.Value, panicked interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = r
+		}
+	}()
+	return f.Call(args), nil
+}
+
+func firstNonNil(values ...interface{}) interface{} {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// shrinkInputs repeatedly replaces one argument with a simpler candidate
+// that still fails, until none does or maxShrinks steps are taken. It
+// returns the simplest failing arguments, how they failed, and the steps.
+func shrinkInputs(args []reflect.Value, failure propertyOutcome, maxShrinks int, run func([]reflect.Value) propertyOutcome) ([]reflect.Value, propertyOutcome, int) {
+	current := append([]reflect.Value(nil), args...)
+	steps := 0
+	for steps < maxShrinks {
+		improved := false
+	search:
+		for i := range current {
+			for _, candidate := range shrinkValue(current[i]) {
+				trial := append([]reflect.Value(nil), current...)
+				trial[i] = candidate
+				if outcome := run(trial); !outcome.passed() {
+					current, failure = trial, outcome
+					steps++
+					improved = true
+					break search
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return current, failure, steps
+}
+
+var (
+	generatorType = reflect.TypeOf((*Generator)(nil)).Elem()
+	shrinkerType  = reflect.TypeOf((*Shrinker)(nil)).Elem()
+)
+
+// shrinkValue returns values simpler than v, simplest first. Types that
+// generate their own values are only shrunk if they also implement
+// Shrinker, since simpler values may break their invariants.
+func shrinkValue(v reflect.Value) []reflect.Value {
+	t := v.Type()
+	if t.Implements(shrinkerType) {
+		return v.Interface().(Shrinker).Shrink()
+	}
+	if t.Implements(generatorType) {
+		return nil
+	}
+
+	var candidates []reflect.Value
+	add := func(set func(reflect.Value)) {
+		c := reflect.New(t).Elem()
+		set(c)
+		if !reflect.DeepEqual(c.Interface(), v.Interface()) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			add(func(c reflect.Value) { c.SetBool(false) })
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		for _, n := range shrinkInt(v.Int()) {
+			n := n
+			add(func(c reflect.Value) { c.SetInt(n) })
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if x := v.Uint(); x != 0 {
+			for _, n := range []uint64{0, x / 2, x - 1} {
+				n := n
+				add(func(c reflect.Value) { c.SetUint(n) })
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if x := v.Float(); x != 0 && !math.IsNaN(x) {
+			for _, f := range []float64{0, math.Trunc(x), x / 2} {
+				f := f
+				add(func(c reflect.Value) { c.SetFloat(f) })
+			}
+		}
+	case reflect.String:
+		runes := []rune(v.String())
+		for _, r := range shrinkLength(len(runes)) {
+			r := r
+			add(func(c reflect.Value) { c.SetString(string(r.apply(runes))) })
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			break
+		}
+		for _, r := range shrinkLength(v.Len()) {
+			r := r
+			add(func(c reflect.Value) { c.Set(r.applySlice(v)) })
+		}
+		candidates = append(candidates, shrinkElements(v)...)
+	case reflect.Array:
+		candidates = append(candidates, shrinkElements(v)...)
+	case reflect.Map:
+		if v.Len() == 0 {
+			break
+		}
+		add(func(c reflect.Value) { c.Set(reflect.MakeMap(t)) })
+		for _, key := range v.MapKeys() {
+			key := key
+			add(func(c reflect.Value) {
+				c.Set(copyMap(v))
+				c.SetMapIndex(key, reflect.Value{})
+			})
+		}
+		for _, key := range v.MapKeys() {
+			for _, smaller := range shrinkValue(v.MapIndex(key)) {
+				c := copyMap(v)
+				c.SetMapIndex(key, smaller)
+				candidates = append(candidates, c)
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			break
+		}
+		add(func(c reflect.Value) {})
+		for _, smaller := range shrinkValue(v.Elem()) {
+			c := reflect.New(t.Elem())
+			c.Elem().Set(smaller)
+			candidates = append(candidates, c)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			for _, smaller := range shrinkValue(v.Field(i)) {
+				c := reflect.New(t).Elem()
+				c.Set(v)
+				c.Field(i).Set(smaller)
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// shrinkInt moves an integer towards zero: zero itself, half, one step
+// closer, and its absolute value
+func shrinkInt(x int64) []int64 {
+	if x == 0 {
+		return nil
+	}
+	step := int64(1)
+	if x < 0 {
+		step = -1
+	}
+	candidates := []int64{0, x / 2, x - step}
+	if x < 0 && x != math.MinInt64 {
+		candidates = append(candidates, -x)
+	}
+	return candidates
+}
+
+// lengthShrink keeps the elements [start, end) except the one at skip
+type lengthShrink struct {
+	start, end, skip int
+}
+
+// shrinkLength returns ways to shorten a sequence of n elements: empty,
+// each half, and each element removed. Removals are bounded so long
+// sequences stay quick to shrink.
+func shrinkLength(n int) []lengthShrink {
+	if n == 0 {
+		return nil
+	}
+	shrinks := []lengthShrink{{0, 0, -1}}
+	if n > 1 {
+		shrinks = append(shrinks, lengthShrink{0, n / 2, -1}, lengthShrink{n / 2, n, -1})
+	}
+	for i := 0; i < n && i < 32; i++ {
+		shrinks = append(shrinks, lengthShrink{0, n, i})
+	}
+	return shrinks
+}
+
+func (r lengthShrink) apply(runes []rune) []rune {
+	out := make([]rune, 0, r.end-r.start)
+	for i := r.start; i < r.end; i++ {
+		if i != r.skip {
+			out = append(out, runes[i])
+		}
+	}
+	return out
+}
+
+func (r lengthShrink) applySlice(v reflect.Value) reflect.Value {
+	out := reflect.MakeSlice(v.Type(), 0, r.end-r.start)
+	for i := r.start; i < r.end; i++ {
+		if i != r.skip {
+			out = reflect.Append(out, v.Index(i))
+		}
+	}
+	return out
+}
+
+// shrinkElements simplifies one element of a slice or array at a time
+func shrinkElements(v reflect.Value) []reflect.Value {
+	var candidates []reflect.Value
+	for i := 0; i < v.Len(); i++ {
+		for _, smaller := range shrinkValue(v.Index(i)) {
+			var c reflect.Value
+			if v.Kind() == reflect.Slice {
+				c = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(c, v)
+			} else {
+				c = reflect.New(v.Type()).Elem()
+				c.Set(v)
+			}
+			c.Index(i).Set(smaller)
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+func copyMap(v reflect.Value) reflect.Value {
+	c := reflect.MakeMap(v.Type())
+	for _, key := range v.MapKeys() {
+		c.SetMapIndex(key, v.MapIndex(key))
+	}
+	return c
+}
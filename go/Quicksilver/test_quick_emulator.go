@@ -0,0 +1,396 @@
+package main
+
+// Developed by PowerShield, as an alternative to testing/quick
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// fakeT records what ForAll reports
+type fakeT struct {
+	errors []string
+	failed bool
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+	t.failed = true
+}
+
+func (t *fakeT) FailNow()     { t.failed = true }
+func (t *fakeT) Failed() bool { return t.failed }
+
+func seeded() *Config {
+	return &Config{Rand: rand.New(rand.NewSource(42))}
+}
+
+type Point struct {
+	X, Y   int
+	Label  string
+	hidden int
+}
+
+// Even generates only even numbers and cannot be shrunk safely
+type Even int
+
+func (Even) Generate(rand *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Even(rand.Intn(1000) * 2))
+}
+
+// Digit generates and shrinks within 0-9
+type Digit int
+
+func (Digit) Generate(rand *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Digit(rand.Intn(10)))
+}
+
+func (d Digit) Shrink() []reflect.Value {
+	if d == 0 {
+		return nil
+	}
+	return []reflect.Value{reflect.ValueOf(d - 1)}
+}
+
+// encode joins key and value for storage; it forgets to escape the
+// separator, so keys containing it do not survive a roundtrip
+func encode(key, value string) string {
+	return key + "=" + value
+}
+
+func decode(s string) (string, string) {
+	i := strings.Index(s, "=")
+	return s[:i], s[i+1:]
+}
+
+func testPassingProperty() bool {
+	reverse := func(s []int) []int {
+		out := make([]int, len(s))
+		for i, v := range s {
+			out[len(s)-1-i] = v
+		}
+		return out
+	}
+	err := Check(func(s []int) bool {
+		return reflect.DeepEqual(reverse(reverse(s)), s)
+	}, seeded())
+	return err == nil
+}
+
+func testRunCounts() bool {
+	runs := 0
+	Check(func(int) bool { runs++; return true }, nil)
+	if runs != 100 {
+		return false
+	}
+	runs = 0
+	Check(func(int) bool { runs++; return true }, &Config{MaxCount: 7})
+	if runs != 7 {
+		return false
+	}
+	runs = 0
+	Check(func(int) bool { runs++; return true }, &Config{MaxCountScale: 0.5})
+	return runs == 50
+}
+
+func testShrinkInt() bool {
+	err := Check(func(n int) bool { return n < 100 }, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok {
+		return false
+	}
+	// The simplest failing input is exactly the boundary
+	return reflect.DeepEqual(ce.In, []interface{}{100}) && ce.Shrinks > 0 &&
+		!reflect.DeepEqual(ce.Original, ce.In) && strings.Contains(ce.Error(), "shrunk from")
+}
+
+func testShrinkSlice() bool {
+	err := Check(func(s []uint8) bool {
+		for _, v := range s {
+			if v >= 10 {
+				return false
+			}
+		}
+		return true
+	}, seeded())
+	ce, ok := err.(*CheckError)
+	return ok && reflect.DeepEqual(ce.In, []interface{}{[]uint8{10}})
+}
+
+func testShrinkString() bool {
+	err := Check(func(s string) bool { return len([]rune(s)) < 3 }, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok {
+		return false
+	}
+	s := ce.In[0].(string)
+	// Three characters, each shrunk as far as a string goes
+	return len([]rune(s)) == 3
+}
+
+func testShrinkStruct() bool {
+	err := Check(func(p Point) bool { return p.X <= p.Y }, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok {
+		return false
+	}
+	// Either (1, 0) or (0, -1), depending on which field shrinks first
+	p := ce.In[0].(Point)
+	return p.X-p.Y == 1 && p.X*p.Y == 0 && p.Label == "" && p.hidden == 0
+}
+
+func testShrinkMapAndPointer() bool {
+	err := Check(func(m map[string]int) bool { return len(m) < 2 }, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok || len(ce.In[0].(map[string]int)) != 2 {
+		return false
+	}
+	for _, v := range ce.In[0].(map[string]int) {
+		if v != 0 {
+			return false
+		}
+	}
+
+	err = Check(func(p *int8) bool { return p == nil || *p > -5 }, seeded())
+	ce, ok = err.(*CheckError)
+	return ok && *ce.In[0].(*int8) == -5
+}
+
+func testErrorsAndPanics() bool {
+	err := Check(func(n uint16) error {
+		if n > 500 {
+			return fmt.Errorf("%d is too large", n)
+		}
+		return nil
+	}, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok || ce.In[0] != uint16(501) || ce.Err == nil || ce.Err.Error() != "501 is too large" {
+		return false
+	}
+
+	err = Check(func(s []int) bool { return s[0] == s[0] }, seeded())
+	ce, ok = err.(*CheckError)
+	return ok && ce.Panic != nil && reflect.DeepEqual(ce.In, []interface{}{[]int{}}) &&
+		strings.Contains(ce.Error(), "panic:")
+}
+
+func testCustomGenerators() bool {
+	seen := map[Even]bool{}
+	err := Check(func(e Even) bool {
+		seen[e] = true
+		return e%2 == 0
+	}, seeded())
+	if err != nil || len(seen) < 10 {
+		return false
+	}
+
+	// Generators without Shrink keep their original failing value
+	err = Check(func(e Even) bool { return e < 10 }, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok || ce.Shrinks != 0 || ce.In[0].(Even) < 10 {
+		return false
+	}
+
+	// Shrinkers decide their own simpler values
+	err = Check(func(d Digit) bool { return d < 4 }, seeded())
+	ce, ok = err.(*CheckError)
+	return ok && ce.In[0] == Digit(4)
+}
+
+func testValuesAndSeeds() bool {
+	var got []int
+	config := &Config{
+		MaxCount: 3,
+		Values: func(args []reflect.Value, r *rand.Rand) {
+			args[0] = reflect.ValueOf(len(got) * 10)
+		},
+	}
+	Check(func(n int) bool { got = append(got, n); return true }, config)
+	if !reflect.DeepEqual(got, []int{0, 10, 20}) {
+		return false
+	}
+
+	// Without a Rand, the seed is reported so a failure can be replayed
+	err := Check(func(n int64) bool { return n > 1<<62 }, nil)
+	ce, ok := err.(*CheckError)
+	if !ok || ce.Seed == 0 || !strings.Contains(ce.Error(), "[seed ") {
+		return false
+	}
+	replay := Check(func(n int64) bool { return n > 1<<62 }, &Config{Rand: rand.New(rand.NewSource(ce.Seed))})
+	again, ok := replay.(*CheckError)
+	return ok && reflect.DeepEqual(again.Original, ce.Original)
+}
+
+func testMaxShrinks() bool {
+	err := Check(func(n int) bool { return n < 100 }, &Config{Rand: rand.New(rand.NewSource(42)), MaxShrinks: -1})
+	ce, ok := err.(*CheckError)
+	if !ok || ce.Shrinks != 0 || !reflect.DeepEqual(ce.In, ce.Original) {
+		return false
+	}
+	err = Check(func(n int) bool { return n < 100 }, &Config{Rand: rand.New(rand.NewSource(42)), MaxShrinks: 2})
+	ce, ok = err.(*CheckError)
+	return ok && ce.Shrinks == 2
+}
+
+func testCheckEqual() bool {
+	sorted := func(s []int) []int {
+		out := append([]int(nil), s...)
+		sort.Ints(out)
+		return out
+	}
+	insertion := func(s []int) []int {
+		out := append([]int(nil), s...)
+		for i := 1; i < len(out); i++ {
+			for j := i; j > 0 && out[j] < out[j-1]; j-- {
+				out[j], out[j-1] = out[j-1], out[j]
+			}
+		}
+		return out
+	}
+	if err := CheckEqual(sorted, insertion, seeded()); err != nil {
+		return false
+	}
+
+	// A sort that drops duplicates differs on the smallest repeated pair
+	dedupe := func(s []int) []int {
+		out := sorted(s)
+		for i := 1; i < len(out); i++ {
+			if out[i] == out[i-1] {
+				out = append(out[:i], out[i+1:]...)
+				i--
+			}
+		}
+		return out
+	}
+	err := CheckEqual(sorted, dedupe, &Config{
+		Rand: rand.New(rand.NewSource(1)),
+		Values: func(args []reflect.Value, r *rand.Rand) {
+			s := make([]int, r.Intn(20))
+			for i := range s {
+				s[i] = r.Intn(5)
+			}
+			args[0] = reflect.ValueOf(s)
+		},
+	})
+	ce, ok := err.(*CheckEqualError)
+	return ok && reflect.DeepEqual(ce.In, []interface{}{[]int{0, 0}}) &&
+		reflect.DeepEqual(ce.Out1, []interface{}{[]int{0, 0}}) &&
+		reflect.DeepEqual(ce.Out2, []interface{}{[]int{0}})
+}
+
+func testSetupErrors() bool {
+	if _, ok := Check(42, nil).(SetupError); !ok {
+		return false
+	}
+	if _, ok := Check(func(int) int { return 0 }, nil).(SetupError); !ok {
+		return false
+	}
+	if _, ok := Check(func(chan int) bool { return true }, nil).(SetupError); !ok {
+		return false
+	}
+	if _, ok := CheckEqual(func(int) int { return 0 }, func(string) int { return 0 }, nil).(SetupError); !ok {
+		return false
+	}
+	var fn func(error) bool
+	_, ok := Value(reflect.TypeOf(fn).In(0), rand.New(rand.NewSource(1)))
+	return !ok
+}
+
+func testForAllRoundtrip() bool {
+	t := &fakeT{}
+	ok := ForAll(t, func(key, value string) bool {
+		k, v := decode(encode(key, value))
+		return k == key && v == value
+	}, &Config{
+		Rand: rand.New(rand.NewSource(42)),
+		// Random code points rarely hit the separator, so draw from a
+		// small alphabet that includes it
+		Values: func(args []reflect.Value, r *rand.Rand) {
+			for i := range args {
+				b := make([]byte, r.Intn(8))
+				for j := range b {
+					b[j] = "ab="[r.Intn(3)]
+				}
+				args[i] = reflect.ValueOf(string(b))
+			}
+		},
+	})
+	if ok || !t.Failed() || len(t.errors) != 1 {
+		return false
+	}
+	// The counterexample shrinks to a one-character key holding the separator
+	if !strings.Contains(t.errors[0], `failed on input "=", ""`) {
+		return false
+	}
+
+	passing := &fakeT{}
+	if !ForAll(passing, func(a, b int32) bool { return int64(a)+int64(b) == int64(b)+int64(a) }, nil) || passing.Failed() {
+		return false
+	}
+
+	bad := &fakeT{}
+	return !ForAll(bad, "not a function", nil) && strings.HasPrefix(bad.errors[0], "property could not be checked")
+}
+
+func testValueGeneration() bool {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 50; i++ {
+		v, ok := Value(reflect.TypeOf(Point{}), r)
+		if !ok || v.Interface().(Point).hidden != 0 {
+			return false
+		}
+		a, ok := Value(reflect.TypeOf([3]bool{}), r)
+		if !ok || a.Len() != 3 {
+			return false
+		}
+		s, ok := Value(reflect.TypeOf(""), r)
+		if !ok || len([]rune(s.String())) >= complexSize {
+			return false
+		}
+	}
+	// Named types keep their type through generation and shrinking
+	type Celsius float64
+	err := Check(func(c Celsius) bool { return c < 0.5 }, seeded())
+	ce, ok := err.(*CheckError)
+	if !ok {
+		return false
+	}
+	_, named := ce.In[0].(Celsius)
+	return named && errors.As(err, new(*CheckError))
+}
+
+func main() {
+	fmt.Println("Running testing/quick Emulator Tests...")
+	fmt.Println("=======================================")
+
+	runTest("Passing Property", testPassingProperty)
+	runTest("Run Counts", testRunCounts)
+	runTest("Shrink Int", testShrinkInt)
+	runTest("Shrink Slice", testShrinkSlice)
+	runTest("Shrink String", testShrinkString)
+	runTest("Shrink Struct", testShrinkStruct)
+	runTest("Shrink Map and Pointer", testShrinkMapAndPointer)
+	runTest("Errors and Panics", testErrorsAndPanics)
+	runTest("Custom Generators", testCustomGenerators)
+	runTest("Values and Seeds", testValuesAndSeeds)
+	runTest("Max Shrinks", testMaxShrinks)
+	runTest("CheckEqual", testCheckEqual)
+	runTest("Setup Errors", testSetupErrors)
+	runTest("ForAll Roundtrip", testForAllRoundtrip)
+	runTest("Value Generation", testValueGeneration)
+
+	fmt.Println("=======================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1609 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-resty and httpmock
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the emulated resty version
+const Version = "2.10.0"
+
+var (
+	hdrUserAgentKey     = http.CanonicalHeaderKey("User-Agent")
+	hdrAcceptKey        = http.CanonicalHeaderKey("Accept")
+	hdrContentTypeKey   = http.CanonicalHeaderKey("Content-Type")
+	hdrAuthorizationKey = http.CanonicalHeaderKey("Authorization")
+
+	plainTextType   = "text/plain; charset=utf-8"
+	jsonContentType = "application/json"
+	formContentType = "application/x-www-form-urlencoded"
+
+	jsonCheck = regexp.MustCompile(`(?i:(application|text)/(.*json.*)(;|$))`)
+	xmlCheck  = regexp.MustCompile(`(?i:(application|text)/(.*xml.*)(;|$))`)
+
+	defaultUserAgent = "go-resty/" + Version + " (https://github.com/go-resty/resty)"
+)
+
+// Client
+
+// RequestMiddleware runs before a request is sent and may change it
+type RequestMiddleware func(*Client, *Request) error
+
+// ResponseMiddleware runs after a response is read
+type ResponseMiddleware func(*Client, *Response) error
+
+// PreRequestHook runs on the final *http.Request just before it is sent
+type PreRequestHook func(*Client, *http.Request) error
+
+// ErrorHook runs when a request fails, after all retries
+type ErrorHook func(*Request, error)
+
+// RetryConditionFunc decides whether a request is retried
+type RetryConditionFunc func(*Response, error) bool
+
+// OnRetryFunc runs before each retry
+type OnRetryFunc func(*Response, error)
+
+// BackOff computes the waits between retries; a negative wait stops
+// retrying. The backoff emulator's ExponentialBackOff implements it, as
+// can any backoff with these methods.
+type BackOff interface {
+	NextBackOff() time.Duration
+	Reset()
+}
+
+// User holds basic auth credentials
+type User struct {
+	Username, Password string
+}
+
+// Client sends requests built with R. Settings on the client apply to
+// all its requests unless a request overrides them.
+type Client struct {
+	BaseURL    string
+	QueryParam url.Values
+	FormData   url.Values
+	PathParams map[string]string
+	Header     http.Header
+	UserInfo   *User
+	Token      string
+	AuthScheme string
+	Cookies    []*http.Cookie
+	Error      reflect.Type
+
+	RetryCount       int
+	RetryWaitTime    time.Duration
+	RetryMaxWaitTime time.Duration
+	RetryConditions  []RetryConditionFunc
+	RetryHooks       []OnRetryFunc
+	// RetryBackOff makes the backoff of each request, replacing the
+	// RetryWaitTime and RetryMaxWaitTime waits
+	RetryBackOff func() BackOff
+
+	JSONMarshal   func(v interface{}) ([]byte, error)
+	JSONUnmarshal func(data []byte, v interface{}) error
+	XMLMarshal    func(v interface{}) ([]byte, error)
+	XMLUnmarshal  func(data []byte, v interface{}) error
+
+	httpClient    *http.Client
+	beforeRequest []RequestMiddleware
+	afterResponse []ResponseMiddleware
+	preReqHook    PreRequestHook
+	errorHooks    []ErrorHook
+}
+
+// New creates a client with its own http.Client
+func New() *Client {
+	return NewWithClient(&http.Client{})
+}
+
+// NewWithClient creates a client sending requests through hc
+func NewWithClient(hc *http.Client) *Client {
+	return &Client{
+		QueryParam:       url.Values{},
+		FormData:         url.Values{},
+		PathParams:       make(map[string]string),
+		Header:           http.Header{},
+		RetryWaitTime:    100 * time.Millisecond,
+		RetryMaxWaitTime: 2 * time.Second,
+		JSONMarshal:      json.Marshal,
+		JSONUnmarshal:    json.Unmarshal,
+		XMLMarshal:       xml.Marshal,
+		XMLUnmarshal:     xml.Unmarshal,
+		httpClient:       hc,
+	}
+}
+
+// GetClient returns the underlying http.Client, e.g. to install a mock
+// transport with ActivateNonDefault
+func (c *Client) GetClient() *http.Client {
+	return c.httpClient
+}
+
+// SetBaseURL sets the URL prepended to relative request URLs
+func (c *Client) SetBaseURL(url string) *Client {
+	c.BaseURL = strings.TrimRight(url, "/")
+	return c
+}
+
+// SetHeader sets a header sent with every request
+func (c *Client) SetHeader(header, value string) *Client {
+	c.Header.Set(header, value)
+	return c
+}
+
+// SetHeaders sets several headers sent with every request
+func (c *Client) SetHeaders(headers map[string]string) *Client {
+	for h, v := range headers {
+		c.Header.Set(h, v)
+	}
+	return c
+}
+
+// SetQueryParam sets a query parameter sent with every request
+func (c *Client) SetQueryParam(param, value string) *Client {
+	c.QueryParam.Set(param, value)
+	return c
+}
+
+// SetQueryParams sets several query parameters sent with every request
+func (c *Client) SetQueryParams(params map[string]string) *Client {
+	for p, v := range params {
+		c.SetQueryParam(p, v)
+	}
+	return c
+}
+
+// SetFormData sets form fields sent with every POST, PUT and PATCH
+// request without a body
+func (c *Client) SetFormData(data map[string]string) *Client {
+	for k, v := range data {
+		c.FormData.Set(k, v)
+	}
+	return c
+}
+
+// SetPathParams sets values for {name} placeholders in request URLs
+func (c *Client) SetPathParams(params map[string]string) *Client {
+	for p, v := range params {
+		c.PathParams[p] = v
+	}
+	return c
+}
+
+// SetBasicAuth sets basic auth credentials for every request
+func (c *Client) SetBasicAuth(username, password string) *Client {
+	c.UserInfo = &User{Username: username, Password: password}
+	return c
+}
+
+// SetAuthToken sets the token sent as "Authorization: Bearer <token>"
+func (c *Client) SetAuthToken(token string) *Client {
+	c.Token = token
+	return c
+}
+
+// SetAuthScheme replaces "Bearer" in the Authorization header
+func (c *Client) SetAuthScheme(scheme string) *Client {
+	c.AuthScheme = scheme
+	return c
+}
+
+// SetCookie adds a cookie sent with every request
+func (c *Client) SetCookie(hc *http.Cookie) *Client {
+	c.Cookies = append(c.Cookies, hc)
+	return c
+}
+
+// SetError registers the type that error responses (4xx and 5xx) are
+// unmarshaled into when a request does not set its own
+func (c *Client) SetError(err interface{}) *Client {
+	c.Error = typeOf(err)
+	return c
+}
+
+// SetTimeout sets the time limit for each attempt, including reading the
+// response body
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	c.httpClient.Timeout = timeout
+	return c
+}
+
+// SetTransport sets the http.RoundTripper, e.g. a *MockTransport
+func (c *Client) SetTransport(transport http.RoundTripper) *Client {
+	if transport != nil {
+		c.httpClient.Transport = transport
+	}
+	return c
+}
+
+// SetRedirectPolicy sets the http.Client's CheckRedirect function
+func (c *Client) SetRedirectPolicy(policy func(req *http.Request, via []*http.Request) error) *Client {
+	c.httpClient.CheckRedirect = policy
+	return c
+}
+
+// SetRetryCount sets how many times failed requests are retried
+func (c *Client) SetRetryCount(count int) *Client {
+	c.RetryCount = count
+	return c
+}
+
+// SetRetryWaitTime sets the shortest wait between attempts
+func (c *Client) SetRetryWaitTime(waitTime time.Duration) *Client {
+	c.RetryWaitTime = waitTime
+	return c
+}
+
+// SetRetryMaxWaitTime caps the wait between attempts
+func (c *Client) SetRetryMaxWaitTime(maxWaitTime time.Duration) *Client {
+	c.RetryMaxWaitTime = maxWaitTime
+	return c
+}
+
+// SetRetryBackOff makes each request wait between retries as a backoff
+// from newBackOff says, until it returns a negative wait. This is an
+// emulator extension for backoffs shared with other clients.
+func (c *Client) SetRetryBackOff(newBackOff func() BackOff) *Client {
+	c.RetryBackOff = newBackOff
+	return c
+}
+
+// AddRetryCondition adds a condition for retrying. Without conditions,
+// only transport errors are retried; with them, a request is retried when
+// any condition returns true.
+func (c *Client) AddRetryCondition(condition RetryConditionFunc) *Client {
+	c.RetryConditions = append(c.RetryConditions, condition)
+	return c
+}
+
+// AddRetryAfterErrorCondition retries responses with 4xx and 5xx status
+func (c *Client) AddRetryAfterErrorCondition() *Client {
+	return c.AddRetryCondition(func(r *Response, err error) bool {
+		return r != nil && r.IsError()
+	})
+}
+
+// AddRetryHook adds a function called before each retry
+func (c *Client) AddRetryHook(hook OnRetryFunc) *Client {
+	c.RetryHooks = append(c.RetryHooks, hook)
+	return c
+}
+
+// OnBeforeRequest adds a middleware run before each attempt, in order.
+// An error stops the request without retrying.
+func (c *Client) OnBeforeRequest(m RequestMiddleware) *Client {
+	c.beforeRequest = append(c.beforeRequest, m)
+	return c
+}
+
+// OnAfterResponse adds a middleware run after each response is read, in
+// order. An error is returned from the request without retrying.
+func (c *Client) OnAfterResponse(m ResponseMiddleware) *Client {
+	c.afterResponse = append(c.afterResponse, m)
+	return c
+}
+
+// SetPreRequestHook sets the hook run on the *http.Request before sending
+func (c *Client) SetPreRequestHook(h PreRequestHook) *Client {
+	c.preReqHook = h
+	return c
+}
+
+// OnError adds a hook run when a request finally fails
+func (c *Client) OnError(h ErrorHook) *Client {
+	c.errorHooks = append(c.errorHooks, h)
+	return c
+}
+
+// R creates a request
+func (c *Client) R() *Request {
+	return &Request{
+		QueryParam: url.Values{},
+		FormData:   url.Values{},
+		PathParams: make(map[string]string),
+		Header:     http.Header{},
+		client:     c,
+	}
+}
+
+// typeOf returns the type of v, dereferencing pointers
+func typeOf(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// getPointer returns v if it is a pointer, or a pointer to a new value of
+// its type
+func getPointer(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if reflect.TypeOf(v).Kind() == reflect.Ptr {
+		return v
+	}
+	return reflect.New(typeOf(v)).Interface()
+}
+
+// Request
+
+// Request is a request built with Client.R
+type Request struct {
+	URL        string
+	Method     string
+	Token      string
+	AuthScheme string
+	QueryParam url.Values
+	FormData   url.Values
+	PathParams map[string]string
+	Header     http.Header
+	UserInfo   *User
+	Cookies    []*http.Cookie
+	Body       interface{}
+	Result     interface{}
+	Error      interface{}
+
+	// Time is when the current attempt was sent
+	Time time.Time
+	// Attempt counts attempts, starting at 1
+	Attempt int
+	// RawRequest is the *http.Request of the current attempt
+	RawRequest *http.Request
+
+	client           *Client
+	ctx              context.Context
+	bodyBytes        []byte
+	bodyRead         bool
+	forceContentType string
+	retryConditions  []RetryConditionFunc
+}
+
+// Context returns the request's context, or context.Background
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// SetContext sets the context for the request, cancelling it and its
+// retries when ctx is done
+func (r *Request) SetContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// SetHeader sets a request header, overriding the client's
+func (r *Request) SetHeader(header, value string) *Request {
+	r.Header.Set(header, value)
+	return r
+}
+
+// SetHeaders sets several request headers
+func (r *Request) SetHeaders(headers map[string]string) *Request {
+	for h, v := range headers {
+		r.Header.Set(h, v)
+	}
+	return r
+}
+
+// SetQueryParam sets a query parameter, overriding the client's
+func (r *Request) SetQueryParam(param, value string) *Request {
+	r.QueryParam.Set(param, value)
+	return r
+}
+
+// SetQueryParams sets several query parameters
+func (r *Request) SetQueryParams(params map[string]string) *Request {
+	for p, v := range params {
+		r.SetQueryParam(p, v)
+	}
+	return r
+}
+
+// SetQueryParamsFromValues adds query parameters from url.Values
+func (r *Request) SetQueryParamsFromValues(params url.Values) *Request {
+	for p, v := range params {
+		for _, pv := range v {
+			r.QueryParam.Add(p, pv)
+		}
+	}
+	return r
+}
+
+// SetQueryString parses query, e.g. "page=2&sort=name", into parameters
+func (r *Request) SetQueryString(query string) *Request {
+	params, err := url.ParseQuery(strings.TrimSpace(query))
+	if err == nil {
+		r.SetQueryParamsFromValues(params)
+	}
+	return r
+}
+
+// SetPathParam sets the value for {param} in the URL; it is escaped
+func (r *Request) SetPathParam(param, value string) *Request {
+	r.PathParams[param] = value
+	return r
+}
+
+// SetPathParams sets several path parameters
+func (r *Request) SetPathParams(params map[string]string) *Request {
+	for p, v := range params {
+		r.SetPathParam(p, v)
+	}
+	return r
+}
+
+// SetFormData sets url-encoded form fields, used when there is no body
+func (r *Request) SetFormData(data map[string]string) *Request {
+	for k, v := range data {
+		r.FormData.Set(k, v)
+	}
+	return r
+}
+
+// SetBody sets the request body. Strings, byte slices and io.Readers are
+// sent as they are; other values are marshaled as JSON, or as XML when
+// the Content-Type header is XML.
+func (r *Request) SetBody(body interface{}) *Request {
+	r.Body = body
+	return r
+}
+
+// SetResult sets the value successful (2xx) JSON or XML responses are
+// unmarshaled into. A non-pointer value registers its type.
+func (r *Request) SetResult(res interface{}) *Request {
+	r.Result = getPointer(res)
+	return r
+}
+
+// SetError sets the value error (4xx and 5xx) JSON or XML responses are
+// unmarshaled into, overriding Client.SetError
+func (r *Request) SetError(err interface{}) *Request {
+	r.Error = getPointer(err)
+	return r
+}
+
+// ForceContentType parses the response as contentType whatever its
+// Content-Type header says
+func (r *Request) ForceContentType(contentType string) *Request {
+	r.forceContentType = contentType
+	return r
+}
+
+// SetBasicAuth sets basic auth credentials, overriding the client's
+func (r *Request) SetBasicAuth(username, password string) *Request {
+	r.UserInfo = &User{Username: username, Password: password}
+	return r
+}
+
+// SetAuthToken sets the bearer token, overriding the client's
+func (r *Request) SetAuthToken(token string) *Request {
+	r.Token = token
+	return r
+}
+
+// SetAuthScheme replaces "Bearer" in the Authorization header
+func (r *Request) SetAuthScheme(scheme string) *Request {
+	r.AuthScheme = scheme
+	return r
+}
+
+// SetCookie adds a cookie to the request
+func (r *Request) SetCookie(hc *http.Cookie) *Request {
+	r.Cookies = append(r.Cookies, hc)
+	return r
+}
+
+// AddRetryCondition adds a retry condition for this request only
+func (r *Request) AddRetryCondition(condition RetryConditionFunc) *Request {
+	r.retryConditions = append(r.retryConditions, condition)
+	return r
+}
+
+// Get sends a GET request to url
+func (r *Request) Get(url string) (*Response, error) {
+	return r.Execute(http.MethodGet, url)
+}
+
+// Head sends a HEAD request to url
+func (r *Request) Head(url string) (*Response, error) {
+	return r.Execute(http.MethodHead, url)
+}
+
+// Post sends a POST request to url
+func (r *Request) Post(url string) (*Response, error) {
+	return r.Execute(http.MethodPost, url)
+}
+
+// Put sends a PUT request to url
+func (r *Request) Put(url string) (*Response, error) {
+	return r.Execute(http.MethodPut, url)
+}
+
+// Patch sends a PATCH request to url
+func (r *Request) Patch(url string) (*Response, error) {
+	return r.Execute(http.MethodPatch, url)
+}
+
+// Delete sends a DELETE request to url
+func (r *Request) Delete(url string) (*Response, error) {
+	return r.Execute(http.MethodDelete, url)
+}
+
+// Options sends an OPTIONS request to url
+func (r *Request) Options(url string) (*Response, error) {
+	return r.Execute(http.MethodOptions, url)
+}
+
+// noRetryErr marks errors from middleware, which are never retried
+type noRetryErr struct {
+	err error
+}
+
+func (e *noRetryErr) Error() string { return e.err.Error() }
+
+func unwrapNoRetryErr(err error) error {
+	if e, ok := err.(*noRetryErr); ok {
+		return e.err
+	}
+	return err
+}
+
+// Execute sends the request with method to url, retrying as configured.
+// The response is returned with transport errors when one was received.
+func (r *Request) Execute(method, url string) (*Response, error) {
+	c := r.client
+	r.Method = method
+	r.URL = url
+
+	var resp *Response
+	var err error
+	var backOff BackOff
+	if c.RetryBackOff != nil {
+		backOff = c.RetryBackOff()
+		backOff.Reset()
+	}
+	for r.Attempt = 1; ; r.Attempt++ {
+		resp, err = c.execute(r)
+		if _, ok := err.(*noRetryErr); ok || r.Attempt > c.RetryCount {
+			break
+		}
+		if ctxErr := r.Context().Err(); ctxErr != nil {
+			// The context ended the retries, not the last failure
+			if err != nil && r.Attempt > 1 {
+				err = ctxErr
+			}
+			break
+		}
+		if !r.needsRetry(resp, err) {
+			break
+		}
+		wait := c.backoff(r.Attempt)
+		if backOff != nil {
+			if wait = backOff.NextBackOff(); wait < 0 {
+				break
+			}
+		}
+		for _, hook := range c.RetryHooks {
+			hook(resp, err)
+		}
+		if ctxErr := r.wait(wait); ctxErr != nil {
+			err = ctxErr
+			break
+		}
+	}
+
+	err = unwrapNoRetryErr(err)
+	if err != nil {
+		for _, hook := range c.errorHooks {
+			hook(r, err)
+		}
+	}
+	return resp, err
+}
+
+// needsRetry applies the retry conditions; without any, only transport
+// errors are retried
+func (r *Request) needsRetry(resp *Response, err error) bool {
+	conditions := append(append([]RetryConditionFunc(nil), r.client.RetryConditions...), r.retryConditions...)
+	if len(conditions) == 0 {
+		return err != nil
+	}
+	for _, condition := range conditions {
+		if condition(resp, err) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait sleeps for d unless the request's context is done first
+func (r *Request) wait(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+// backoff returns the wait before the retry after attempt: exponential
+// with jitter, between RetryWaitTime and RetryMaxWaitTime
+func (c *Client) backoff(attempt int) time.Duration {
+	min, max := c.RetryWaitTime, c.RetryMaxWaitTime
+	if max < min {
+		max = min
+	}
+	temp := math.Min(float64(max), float64(min)*math.Exp2(float64(attempt)))
+	half := int64(temp / 2)
+	wait := time.Duration(half)
+	if half > 0 {
+		wait += time.Duration(rand.Int63n(half))
+	}
+	if wait < min {
+		wait = min
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
+
+// execute makes one attempt
+func (c *Client) execute(r *Request) (*Response, error) {
+	for _, m := range c.beforeRequest {
+		if err := m(c, r); err != nil {
+			return nil, &noRetryErr{err}
+		}
+	}
+	if err := c.buildRequest(r); err != nil {
+		return nil, &noRetryErr{err}
+	}
+	if c.preReqHook != nil {
+		if err := c.preReqHook(c, r.RawRequest); err != nil {
+			return nil, &noRetryErr{err}
+		}
+	}
+
+	r.Time = time.Now()
+	resp, err := c.httpClient.Do(r.RawRequest)
+	response := &Response{Request: r, RawResponse: resp}
+	if err != nil {
+		response.receivedAt = time.Now()
+		return response, err
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	response.receivedAt = time.Now()
+	if err != nil {
+		return response, err
+	}
+	response.body = body
+	response.size = int64(len(body))
+
+	if err := c.parseResponseBody(response); err != nil {
+		return response, &noRetryErr{err}
+	}
+	for _, m := range c.afterResponse {
+		if err := m(c, response); err != nil {
+			return response, &noRetryErr{err}
+		}
+	}
+	return response, nil
+}
+
+// buildRequest creates RawRequest from the request and client settings
+func (c *Client) buildRequest(r *Request) error {
+	rawURL, err := c.requestURL(r)
+	if err != nil {
+		return err
+	}
+
+	body, contentType, err := c.requestBody(r)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, rawURL, reader)
+	if err != nil {
+		return err
+	}
+
+	for key, values := range c.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	for key, values := range r.Header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if req.Header.Get(hdrUserAgentKey) == "" {
+		req.Header.Set(hdrUserAgentKey, defaultUserAgent)
+	}
+	if contentType != "" && req.Header.Get(hdrContentTypeKey) == "" {
+		req.Header.Set(hdrContentTypeKey, contentType)
+	}
+	if req.Header.Get(hdrAcceptKey) == "" && (r.Result != nil || r.Error != nil || c.Error != nil) {
+		req.Header.Set(hdrAcceptKey, jsonContentType)
+	}
+
+	userInfo := c.UserInfo
+	if r.UserInfo != nil {
+		userInfo = r.UserInfo
+	}
+	if userInfo != nil {
+		req.SetBasicAuth(userInfo.Username, userInfo.Password)
+	}
+	token, scheme := c.Token, c.AuthScheme
+	if r.Token != "" {
+		token = r.Token
+	}
+	if r.AuthScheme != "" {
+		scheme = r.AuthScheme
+	}
+	if token != "" {
+		if scheme == "" {
+			scheme = "Bearer"
+		}
+		req.Header.Set(hdrAuthorizationKey, scheme+" "+token)
+	}
+
+	for _, cookie := range c.Cookies {
+		req.AddCookie(cookie)
+	}
+	for _, cookie := range r.Cookies {
+		req.AddCookie(cookie)
+	}
+
+	r.RawRequest = req
+	return nil
+}
+
+// requestURL fills in path parameters, prepends the base URL to relative
+// URLs and adds query parameters
+func (c *Client) requestURL(r *Request) (string, error) {
+	rawURL := r.URL
+	for p, v := range r.PathParams {
+		rawURL = strings.ReplaceAll(rawURL, "{"+p+"}", url.PathEscape(v))
+	}
+	for p, v := range c.PathParams {
+		rawURL = strings.ReplaceAll(rawURL, "{"+p+"}", url.PathEscape(v))
+	}
+
+	reqURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if !reqURL.IsAbs() {
+		rawURL = reqURL.String()
+		if len(rawURL) > 0 && rawURL[0] != '/' {
+			rawURL = "/" + rawURL
+		}
+		if reqURL, err = url.Parse(c.BaseURL + rawURL); err != nil {
+			return "", err
+		}
+	}
+
+	query := url.Values{}
+	for k, v := range c.QueryParam {
+		if _, ok := r.QueryParam[k]; ok {
+			continue
+		}
+		query[k] = append([]string(nil), v...)
+	}
+	for k, v := range r.QueryParam {
+		query[k] = append([]string(nil), v...)
+	}
+	if len(query) > 0 {
+		if reqURL.RawQuery == "" {
+			reqURL.RawQuery = query.Encode()
+		} else {
+			reqURL.RawQuery = reqURL.RawQuery + "&" + query.Encode()
+		}
+	}
+	return reqURL.String(), nil
+}
+
+// requestBody encodes the body and returns its default content type.
+// Readers are read once, so retries resend the same bytes.
+func (c *Client) requestBody(r *Request) ([]byte, string, error) {
+	if r.Body == nil {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			return nil, "", nil
+		}
+		form := url.Values{}
+		for k, v := range c.FormData {
+			form[k] = append([]string(nil), v...)
+		}
+		for k, v := range r.FormData {
+			form[k] = append([]string(nil), v...)
+		}
+		if len(form) == 0 {
+			return nil, "", nil
+		}
+		return []byte(form.Encode()), formContentType, nil
+	}
+
+	if r.bodyRead {
+		return r.bodyBytes, "", nil
+	}
+	contentType := r.Header.Get(hdrContentTypeKey)
+	if contentType == "" {
+		contentType = c.Header.Get(hdrContentTypeKey)
+	}
+
+	var body []byte
+	var err error
+	switch b := r.Body.(type) {
+	case string:
+		body = []byte(b)
+	case []byte:
+		body = b
+	case io.Reader:
+		body, err = io.ReadAll(b)
+	default:
+		if xmlCheck.MatchString(contentType) {
+			body, err = c.XMLMarshal(b)
+		} else {
+			body, err = c.JSONMarshal(b)
+			if contentType == "" {
+				contentType = jsonContentType
+			}
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+		if strings.HasPrefix(contentType, "text/plain") {
+			contentType = plainTextType
+		}
+	}
+	r.bodyBytes, r.bodyRead = body, true
+	return body, contentType, nil
+}
+
+// parseResponseBody unmarshals JSON and XML responses into the request's
+// Result or Error
+func (c *Client) parseResponseBody(res *Response) error {
+	if len(res.body) == 0 {
+		return nil
+	}
+	r := res.Request
+	contentType := r.forceContentType
+	if contentType == "" {
+		contentType = res.Header().Get(hdrContentTypeKey)
+	}
+	unmarshal := c.JSONUnmarshal
+	switch {
+	case jsonCheck.MatchString(contentType):
+	case xmlCheck.MatchString(contentType):
+		unmarshal = c.XMLUnmarshal
+	default:
+		return nil
+	}
+
+	if res.IsSuccess() && r.Result != nil {
+		return unmarshal(res.body, r.Result)
+	}
+	if res.IsError() {
+		if r.Error == nil && c.Error != nil {
+			r.Error = reflect.New(c.Error).Interface()
+		}
+		if r.Error != nil {
+			return unmarshal(res.body, r.Error)
+		}
+	}
+	return nil
+}
+
+// Response
+
+// Response is the result of a request
+type Response struct {
+	Request     *Request
+	RawResponse *http.Response
+
+	body       []byte
+	size       int64
+	receivedAt time.Time
+}
+
+// Body returns the response body
+func (r *Response) Body() []byte {
+	if r.RawResponse == nil {
+		return []byte{}
+	}
+	return r.body
+}
+
+// String returns the body as a trimmed string
+func (r *Response) String() string {
+	return strings.TrimSpace(string(r.Body()))
+}
+
+// StatusCode returns the HTTP status code, or 0 if there was no response
+func (r *Response) StatusCode() int {
+	if r.RawResponse == nil {
+		return 0
+	}
+	return r.RawResponse.StatusCode
+}
+
+// Status returns the status line, e.g. "200 OK"
+func (r *Response) Status() string {
+	if r.RawResponse == nil {
+		return ""
+	}
+	return r.RawResponse.Status
+}
+
+// Proto returns the protocol, e.g. "HTTP/1.1"
+func (r *Response) Proto() string {
+	if r.RawResponse == nil {
+		return ""
+	}
+	return r.RawResponse.Proto
+}
+
+// Header returns the response headers
+func (r *Response) Header() http.Header {
+	if r.RawResponse == nil {
+		return http.Header{}
+	}
+	return r.RawResponse.Header
+}
+
+// Cookies returns the cookies set by the response
+func (r *Response) Cookies() []*http.Cookie {
+	if r.RawResponse == nil {
+		return nil
+	}
+	return r.RawResponse.Cookies()
+}
+
+// Result returns the value a successful response was unmarshaled into
+func (r *Response) Result() interface{} {
+	return r.Request.Result
+}
+
+// Error returns the value an error response was unmarshaled into
+func (r *Response) Error() interface{} {
+	return r.Request.Error
+}
+
+// Time returns how long the last attempt took
+func (r *Response) Time() time.Duration {
+	return r.receivedAt.Sub(r.Request.Time)
+}
+
+// ReceivedAt returns when the response was received
+func (r *Response) ReceivedAt() time.Time {
+	return r.receivedAt
+}
+
+// Size returns the body size in bytes
+func (r *Response) Size() int64 {
+	return r.size
+}
+
+// IsSuccess reports whether the status is 2xx
+func (r *Response) IsSuccess() bool {
+	return r.StatusCode() > 199 && r.StatusCode() < 300
+}
+
+// IsError reports whether the status is 4xx or 5xx
+func (r *Response) IsError() bool {
+	return r.StatusCode() > 399
+}
+
+// Mock transport
+
+// ErrNoResponderFound is returned for requests no responder matches
+var ErrNoResponderFound = errors.New("no responder found")
+
+// Responder produces the response to a mocked request
+type Responder func(*http.Request) (*http.Response, error)
+
+// NewStringResponse creates a response with status and body
+func NewStringResponse(status int, body string) *http.Response {
+	return NewBytesResponse(status, []byte(body))
+}
+
+// NewBytesResponse creates a response with status and body
+func NewBytesResponse(status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// NewJsonResponse creates a response with status and body encoded as
+// JSON, with an application/json Content-Type
+func NewJsonResponse(status int, body interface{}) (*http.Response, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	resp := NewBytesResponse(status, encoded)
+	resp.Header.Set(hdrContentTypeKey, jsonContentType)
+	return resp, nil
+}
+
+// ResponderFromResponse returns a Responder answering with copies of resp
+func ResponderFromResponse(resp *http.Response) Responder {
+	var body []byte
+	if resp.Body != nil {
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		res := *resp
+		res.Header = resp.Header.Clone()
+		res.Body = io.NopCloser(bytes.NewReader(body))
+		res.Request = req
+		return &res, nil
+	}
+}
+
+// NewStringResponder answers with status and body
+func NewStringResponder(status int, body string) Responder {
+	return ResponderFromResponse(NewStringResponse(status, body))
+}
+
+// NewBytesResponder answers with status and body
+func NewBytesResponder(status int, body []byte) Responder {
+	return ResponderFromResponse(NewBytesResponse(status, body))
+}
+
+// NewJsonResponder answers with status and body encoded as JSON
+func NewJsonResponder(status int, body interface{}) (Responder, error) {
+	resp, err := NewJsonResponse(status, body)
+	if err != nil {
+		return nil, err
+	}
+	return ResponderFromResponse(resp), nil
+}
+
+// NewJsonResponderOrPanic is NewJsonResponder, panicking on error
+func NewJsonResponderOrPanic(status int, body interface{}) Responder {
+	responder, err := NewJsonResponder(status, body)
+	if err != nil {
+		panic(err)
+	}
+	return responder
+}
+
+// NewErrorResponder fails every request with err, as a network error
+// would
+func NewErrorResponder(err error) Responder {
+	return func(*http.Request) (*http.Response, error) {
+		return nil, err
+	}
+}
+
+// ConnectionFailure is a Responder failing with ErrNoResponderFound
+var ConnectionFailure = NewErrorResponder(ErrNoResponderFound)
+
+// Times returns a Responder answering the first n requests with r and
+// failing later ones
+func (r Responder) Times(n int) Responder {
+	var mu sync.Mutex
+	calls := 0
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+		if call > n {
+			return nil, fmt.Errorf("responder for %s %s called more than %d times", req.Method, req.URL, n)
+		}
+		return r(req)
+	}
+}
+
+// Once is Times(1)
+func (r Responder) Once() Responder {
+	return r.Times(1)
+}
+
+// thenKey carries a *bool through the request context that a chained
+// Responder sets when it still has answers left before its last one
+type thenKey struct{}
+
+// Then returns a Responder answering the first request with r and later
+// ones with next. Chains run each step once in order and repeat the last:
+// a.Then(b).Then(c) answers with a, b, c, c, ...
+func (r Responder) Then(next Responder) Responder {
+	var mu sync.Mutex
+	used := false
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		step := next
+		if !used {
+			step = r
+		}
+		mu.Unlock()
+
+		more := false
+		resp, err := step(req.WithContext(context.WithValue(req.Context(), thenKey{}, &more)))
+
+		mu.Lock()
+		if !used && !more {
+			used = true
+			more = true
+		}
+		mu.Unlock()
+		if outer, ok := req.Context().Value(thenKey{}).(*bool); ok {
+			*outer = more
+		}
+		return resp, err
+	}
+}
+
+// Delay returns a Responder answering after d, or failing with the
+// request's context error if it is cancelled first
+func (r Responder) Delay(d time.Duration) Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return r(req)
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// Matcher restricts a responder to requests it accepts
+type Matcher struct {
+	name string
+	fn   func(*http.Request) bool
+}
+
+// NewMatcher creates a Matcher; name is added to its call count key
+func NewMatcher(name string, fn func(req *http.Request) bool) Matcher {
+	return Matcher{name: name, fn: fn}
+}
+
+// Name returns the matcher's name
+func (m Matcher) Name() string {
+	return m.name
+}
+
+// Check reports whether req matches; a zero Matcher matches everything
+func (m Matcher) Check(req *http.Request) bool {
+	return m.fn == nil || m.fn(req)
+}
+
+// And matches requests matching m and all of ms
+func (m Matcher) And(ms ...Matcher) Matcher {
+	names := []string{m.name}
+	for _, other := range ms {
+		names = append(names, other.name)
+	}
+	return NewMatcher(strings.Join(names, " && "), func(req *http.Request) bool {
+		if !m.Check(req) {
+			return false
+		}
+		for _, other := range ms {
+			if !other.Check(req) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or matches requests matching m or any of ms
+func (m Matcher) Or(ms ...Matcher) Matcher {
+	names := []string{m.name}
+	for _, other := range ms {
+		names = append(names, other.name)
+	}
+	return NewMatcher(strings.Join(names, " || "), func(req *http.Request) bool {
+		if m.Check(req) {
+			return true
+		}
+		for _, other := range ms {
+			if other.Check(req) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// requestBody returns the body of a request being matched, leaving it
+// readable
+func requestBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, _ := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// BodyContainsString matches requests whose body contains s
+func BodyContainsString(s string) Matcher {
+	return NewMatcher("BodyContainsString("+s+")", func(req *http.Request) bool {
+		return bytes.Contains(requestBody(req), []byte(s))
+	})
+}
+
+// BodyContainsBytes matches requests whose body contains b
+func BodyContainsBytes(b []byte) Matcher {
+	return NewMatcher(fmt.Sprintf("BodyContainsBytes(%q)", b), func(req *http.Request) bool {
+		return bytes.Contains(requestBody(req), b)
+	})
+}
+
+// HeaderIs matches requests whose key header is value
+func HeaderIs(key, value string) Matcher {
+	return NewMatcher("HeaderIs("+key+", "+value+")", func(req *http.Request) bool {
+		return req.Header.Get(key) == value
+	})
+}
+
+// HeaderContains matches requests whose key header contains value
+func HeaderContains(key, value string) Matcher {
+	return NewMatcher("HeaderContains("+key+", "+value+")", func(req *http.Request) bool {
+		return strings.Contains(req.Header.Get(key), value)
+	})
+}
+
+// HeaderExists matches requests with a key header
+func HeaderExists(key string) Matcher {
+	return NewMatcher("HeaderExists("+key+")", func(req *http.Request) bool {
+		_, ok := req.Header[http.CanonicalHeaderKey(key)]
+		return ok
+	})
+}
+
+// route is a registered responder
+type route struct {
+	key       string
+	method    string
+	url       string         // scheme://host/path, or /path for any host
+	re        *regexp.Regexp // set for =~ routes
+	query     url.Values     // nil matches any query
+	matcher   Matcher
+	responder Responder
+}
+
+// matches reports whether req is for the route, ignoring its matcher
+func (rt *route) matches(req *http.Request) bool {
+	if rt.method != req.Method {
+		return false
+	}
+	if rt.re != nil {
+		withoutQuery := *req.URL
+		withoutQuery.RawQuery = ""
+		return rt.re.MatchString(req.URL.String()) || rt.re.MatchString(withoutQuery.String())
+	}
+
+	target := strings.ToLower(req.URL.Scheme) + "://" + strings.ToLower(req.URL.Host) + req.URL.Path
+	if strings.HasPrefix(rt.url, "/") {
+		target = req.URL.Path
+	}
+	if target != rt.url {
+		return false
+	}
+	return rt.query == nil || sortedQuery(rt.query) == sortedQuery(req.URL.Query())
+}
+
+// sortedQuery encodes query with keys and values sorted
+func sortedQuery(query url.Values) string {
+	sorted := url.Values{}
+	for k, v := range query {
+		values := append([]string(nil), v...)
+		sort.Strings(values)
+		sorted[k] = values
+	}
+	return sorted.Encode()
+}
+
+// MockTransport is an http.RoundTripper answering requests with
+// registered responders instead of a network. Requests no responder
+// matches get the no-responder, by default an error wrapping
+// ErrNoResponderFound.
+type MockTransport struct {
+	mu          sync.RWMutex
+	routes      []*route
+	noResponder Responder
+	callCount   map[string]int
+	totalCount  int
+}
+
+// NewMockTransport creates a MockTransport without responders
+func NewMockTransport() *MockTransport {
+	return &MockTransport{callCount: make(map[string]int)}
+}
+
+// RoundTrip implements http.RoundTripper
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := requestBody(req)
+	reset := func() {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+
+	m.mu.RLock()
+	routes := m.routes
+	noResponder := m.noResponder
+	m.mu.RUnlock()
+
+	var found *route
+	// Exact URLs before regexps, and matchers before plain routes
+	for _, pass := range []func(*route) bool{
+		func(rt *route) bool { return rt.re == nil && rt.matcher.fn != nil },
+		func(rt *route) bool { return rt.re == nil && rt.matcher.fn == nil },
+		func(rt *route) bool { return rt.re != nil },
+	} {
+		for _, rt := range routes {
+			if !pass(rt) || !rt.matches(req) {
+				continue
+			}
+			reset()
+			if rt.matcher.Check(req) {
+				found = rt
+				break
+			}
+		}
+		if found != nil {
+			break
+		}
+	}
+
+	m.mu.Lock()
+	m.totalCount++
+	responder := noResponder
+	if found != nil {
+		m.callCount[found.key]++
+		responder = found.responder
+	} else {
+		m.callCount["NO_RESPONDER"]++
+	}
+	m.mu.Unlock()
+
+	if responder == nil {
+		return nil, fmt.Errorf("%w for %s %s", ErrNoResponderFound, req.Method, req.URL)
+	}
+	reset()
+	resp, err := responder(req)
+	if resp != nil && resp.Request == nil {
+		resp.Request = req
+	}
+	return resp, err
+}
+
+// RegisterResponder answers requests for method and url with responder.
+// url may be absolute, a path matching any host, or "=~" followed by a
+// regexp. A query string in url must match the request's exactly, in any
+// order; without one any query matches. Registering the same method and
+// url again replaces the responder, and a nil responder removes it.
+func (m *MockTransport) RegisterResponder(method, url string, responder Responder) {
+	m.RegisterMatcherResponder(method, url, Matcher{}, responder)
+}
+
+// RegisterResponderWithQuery is RegisterResponder with the query given
+// as a string, url.Values or map[string]string
+func (m *MockTransport) RegisterResponderWithQuery(method, path string, query interface{}, responder Responder) {
+	var values url.Values
+	switch q := query.(type) {
+	case string:
+		values, _ = url.ParseQuery(strings.TrimPrefix(q, "?"))
+	case url.Values:
+		values = q
+	case map[string]string:
+		values = url.Values{}
+		for k, v := range q {
+			values.Set(k, v)
+		}
+	default:
+		panic(fmt.Sprintf("RegisterResponderWithQuery: unsupported query type %T", query))
+	}
+	target := path
+	if encoded := values.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+	m.RegisterResponder(method, target, responder)
+}
+
+// RegisterRegexpResponder answers requests for method whose URL matches
+// re
+func (m *MockTransport) RegisterRegexpResponder(method string, re *regexp.Regexp, responder Responder) {
+	m.register(&route{key: method + " =~" + re.String(), method: method, re: re, responder: responder})
+}
+
+// RegisterMatcherResponder is RegisterResponder for requests that also
+// match matcher, e.g. BodyContainsString. Matcher routes are tried
+// before plain ones for the same URL.
+func (m *MockTransport) RegisterMatcherResponder(method, rawURL string, matcher Matcher, responder Responder) {
+	key := method + " " + rawURL
+	if matcher.name != "" {
+		key += " <" + matcher.name + ">"
+	}
+	rt := &route{key: key, method: method, matcher: matcher, responder: responder}
+	if strings.HasPrefix(rawURL, "=~") {
+		rt.re = regexp.MustCompile(rawURL[2:])
+		m.register(rt)
+		return
+	}
+
+	if i := strings.Index(rawURL, "?"); i >= 0 {
+		rt.query, _ = url.ParseQuery(rawURL[i+1:])
+		rawURL = rawURL[:i]
+	}
+	if !strings.HasPrefix(rawURL, "/") {
+		if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" {
+			rawURL = strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + u.Path
+		}
+	}
+	rt.url = rawURL
+	m.register(rt)
+}
+
+func (m *MockTransport) register(rt *route) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := make([]*route, 0, len(m.routes)+1)
+	replaced := false
+	for _, existing := range m.routes {
+		if existing.key != rt.key {
+			routes = append(routes, existing)
+			continue
+		}
+		if rt.responder != nil && !replaced {
+			routes = append(routes, rt)
+			replaced = true
+		}
+	}
+	if rt.responder == nil {
+		delete(m.callCount, rt.key)
+	} else {
+		if !replaced {
+			routes = append(routes, rt)
+		}
+		if _, ok := m.callCount[rt.key]; !ok {
+			m.callCount[rt.key] = 0
+		}
+	}
+	m.routes = routes
+}
+
+// RegisterNoResponder sets the responder for requests no route matches
+func (m *MockTransport) RegisterNoResponder(responder Responder) {
+	m.mu.Lock()
+	m.noResponder = responder
+	m.mu.Unlock()
+}
+
+// GetCallCountInfo returns the number of calls per registered route, keyed
+// by "METHOD url"; unmatched requests count under "NO_RESPONDER"
+func (m *MockTransport) GetCallCountInfo() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	info := make(map[string]int, len(m.callCount))
+	for k, v := range m.callCount {
+		info[k] = v
+	}
+	return info
+}
+
+// GetTotalCallCount returns the number of requests made
+func (m *MockTransport) GetTotalCallCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.totalCount
+}
+
+// ZeroCallCounters resets the call counts, keeping the responders
+func (m *MockTransport) ZeroCallCounters() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k := range m.callCount {
+		m.callCount[k] = 0
+	}
+	delete(m.callCount, "NO_RESPONDER")
+	m.totalCount = 0
+}
+
+// Reset removes all responders and call counts
+func (m *MockTransport) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = nil
+	m.noResponder = nil
+	m.callCount = make(map[string]int)
+	m.totalCount = 0
+}
+
+// DefaultTransport is the MockTransport used by the package functions
+var DefaultTransport = NewMockTransport()
+
+// InitialTransport is http.DefaultTransport before Activate
+var InitialTransport = http.DefaultTransport
+
+// Activate replaces http.DefaultTransport with DefaultTransport, mocking
+// clients that use it, such as http.Get
+func Activate() {
+	http.DefaultTransport = DefaultTransport
+}
+
+// ActivateNonDefault mocks client, e.g. the http.Client of a resty
+// client from GetClient
+func ActivateNonDefault(client *http.Client) {
+	client.Transport = DefaultTransport
+}
+
+// Deactivate restores http.DefaultTransport
+func Deactivate() {
+	http.DefaultTransport = InitialTransport
+}
+
+// DeactivateAndReset is Deactivate followed by Reset
+func DeactivateAndReset() {
+	Deactivate()
+	Reset()
+}
+
+// RegisterResponder registers a responder on DefaultTransport
+func RegisterResponder(method, url string, responder Responder) {
+	DefaultTransport.RegisterResponder(method, url, responder)
+}
+
+// RegisterNoResponder sets DefaultTransport's no-responder
+func RegisterNoResponder(responder Responder) {
+	DefaultTransport.RegisterNoResponder(responder)
+}
+
+// GetCallCountInfo returns DefaultTransport's call counts
+func GetCallCountInfo() map[string]int {
+	return DefaultTransport.GetCallCountInfo()
+}
+
+// GetTotalCallCount returns DefaultTransport's number of requests
+func GetTotalCallCount() int {
+	return DefaultTransport.GetTotalCallCount()
+}
+
+// Reset removes DefaultTransport's responders and call counts
+func Reset() {
+	DefaultTransport.Reset()
+}
@@ -0,0 +1,624 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-resty and httpmock
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// newMockedClient returns a client for https://api.example.com answered
+// by a fresh mock transport
+func newMockedClient() (*Client, *MockTransport) {
+	mock := NewMockTransport()
+	client := New().SetBaseURL("https://api.example.com/").SetTransport(mock)
+	return client, mock
+}
+
+// capture answers with 200 and records each request
+type capture struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func (c *capture) responder(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.bodies = append(c.bodies, string(body))
+	c.mu.Unlock()
+	return NewStringResponse(200, "ok"), nil
+}
+
+func (c *capture) last() (*http.Request, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) == 0 {
+		return nil, ""
+	}
+	return c.requests[len(c.requests)-1], c.bodies[len(c.bodies)-1]
+}
+
+func testGetRequest() bool {
+	client, mock := newMockedClient()
+	client.SetHeader("X-Client", "emu").SetQueryParam("lang", "en").SetQueryParam("page", "1")
+	var seen *http.Request
+	mock.RegisterResponder("GET", "https://api.example.com/users/ann%20lee", func(req *http.Request) (*http.Response, error) {
+		seen = req
+		resp := NewStringResponse(200, "  hello  ")
+		resp.Header.Set("X-Request-Id", "r1")
+		return resp, nil
+	})
+
+	resp, err := client.R().
+		SetPathParam("name", "ann lee").
+		SetQueryParam("page", "2").
+		SetHeader("X-Request", "yes").
+		Get("users/{name}")
+	if err != nil || seen == nil {
+		return false
+	}
+	query := seen.URL.Query()
+	return resp.StatusCode() == 200 && resp.Status() == "200 OK" && resp.String() == "hello" &&
+		string(resp.Body()) == "  hello  " && resp.Size() == 9 && resp.IsSuccess() && !resp.IsError() &&
+		resp.Header().Get("X-Request-Id") == "r1" && resp.Time() >= 0 && !resp.ReceivedAt().IsZero() &&
+		query.Get("page") == "2" && query.Get("lang") == "en" &&
+		seen.Header.Get("X-Client") == "emu" && seen.Header.Get("X-Request") == "yes" &&
+		strings.HasPrefix(seen.Header.Get("User-Agent"), "go-resty/") &&
+		resp.Request.Attempt == 1 && resp.Request.RawRequest == seen
+}
+
+func testJSONResult() bool {
+	client, mock := newMockedClient()
+	var created *capture = &capture{}
+	mock.RegisterResponder("POST", "/users", func(req *http.Request) (*http.Response, error) {
+		created.responder(req)
+		return NewJsonResponse(201, user{ID: 7, Name: "ann"})
+	})
+	mock.RegisterResponder("GET", "/users", NewJsonResponderOrPanic(200, []user{{1, "bob"}, {2, "cy"}}))
+
+	resp, err := client.R().SetBody(user{Name: "ann"}).SetResult(&user{}).Post("/users")
+	if err != nil || resp.StatusCode() != 201 {
+		return false
+	}
+	got, ok := resp.Result().(*user)
+	req, body := created.last()
+	if !ok || got.ID != 7 || got.Name != "ann" ||
+		req.Header.Get("Content-Type") != "application/json" || req.Header.Get("Accept") != "application/json" ||
+		body != `{"id":0,"name":"ann"}` {
+		return false
+	}
+
+	// A non-pointer result registers the type
+	resp, err = client.R().SetResult([]user{}).Get("/users")
+	list, ok := resp.Result().(*[]user)
+	return err == nil && ok && len(*list) == 2 && (*list)[1].Name == "cy"
+}
+
+func testErrorResult() bool {
+	client, mock := newMockedClient()
+	client.SetError(&apiError{})
+	mock.RegisterResponder("GET", "/missing", NewJsonResponderOrPanic(404, apiError{Code: "not_found", Message: "no such user"}))
+	mock.RegisterResponder("GET", "/teapot", NewStringResponder(418, "short and stout"))
+
+	resp, err := client.R().SetResult(&user{}).Get("/missing")
+	if err != nil || !resp.IsError() || resp.IsSuccess() {
+		return false
+	}
+	apiErr, ok := resp.Error().(*apiError)
+	if !ok || apiErr.Code != "not_found" || resp.Result().(*user).ID != 0 {
+		return false
+	}
+
+	// Request error types override the client's; non-JSON bodies are not
+	// parsed
+	type other struct {
+		Message string `json:"message"`
+	}
+	resp, _ = client.R().SetError(other{}).Get("/missing")
+	if o, ok := resp.Error().(*other); !ok || o.Message != "no such user" {
+		return false
+	}
+	resp, err = client.R().Get("/teapot")
+	if err != nil || resp.String() != "short and stout" || resp.Error() != nil {
+		return false
+	}
+
+	// ForceContentType parses bodies without a JSON Content-Type
+	mock.RegisterResponder("GET", "/raw", NewStringResponder(200, `{"id":3}`))
+	resp, _ = client.R().SetResult(&user{}).ForceContentType("application/json").Get("/raw")
+	return resp.Result().(*user).ID == 3
+}
+
+func testRequestBodies() bool {
+	client, mock := newMockedClient()
+	rec := &capture{}
+	mock.RegisterResponder("POST", "/echo", rec.responder)
+	mock.RegisterResponder("PUT", "/echo", rec.responder)
+
+	client.R().SetFormData(map[string]string{"name": "ann", "role": "admin"}).Post("/echo")
+	req, body := rec.last()
+	if req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || body != "name=ann&role=admin" {
+		return false
+	}
+
+	client.R().SetBody("plain text").Post("/echo")
+	req, body = rec.last()
+	if req.Header.Get("Content-Type") != "text/plain; charset=utf-8" || body != "plain text" {
+		return false
+	}
+
+	client.R().SetBody(strings.NewReader("from a reader")).SetHeader("Content-Type", "application/octet-stream").Put("/echo")
+	req, body = rec.last()
+	if req.Header.Get("Content-Type") != "application/octet-stream" || body != "from a reader" {
+		return false
+	}
+
+	type item struct {
+		XMLName xml.Name `xml:"item"`
+		Name    string   `xml:"name"`
+	}
+	client.R().SetHeader("Content-Type", "application/xml").SetBody(item{Name: "pen"}).Post("/echo")
+	_, body = rec.last()
+	if body != "<item><name>pen</name></item>" {
+		return false
+	}
+
+	client.R().SetBody(map[string]int{"n": 1}).Post("/echo")
+	req, body = rec.last()
+	return body == `{"n":1}` && req.Header.Get("Content-Type") == "application/json"
+}
+
+func testAuthAndCookies() bool {
+	client, mock := newMockedClient()
+	rec := &capture{}
+	mock.RegisterResponder("GET", "/me", rec.responder)
+
+	client.SetAuthToken("client-token").SetCookie(&http.Cookie{Name: "region", Value: "eu"})
+	client.R().Get("/me")
+	req, _ := rec.last()
+	if req.Header.Get("Authorization") != "Bearer client-token" {
+		return false
+	}
+	if c, err := req.Cookie("region"); err != nil || c.Value != "eu" {
+		return false
+	}
+
+	client.R().SetAuthToken("req-token").SetAuthScheme("Token").SetCookie(&http.Cookie{Name: "sid", Value: "1"}).Get("/me")
+	req, _ = rec.last()
+	if req.Header.Get("Authorization") != "Token req-token" || len(req.Cookies()) != 2 {
+		return false
+	}
+
+	basic := New().SetTransport(mock).SetBaseURL("https://api.example.com").SetBasicAuth("ann", "s3cret")
+	basic.R().Get("/me")
+	req, _ = rec.last()
+	username, password, ok := req.BasicAuth()
+	return ok && username == "ann" && password == "s3cret"
+}
+
+func testRetries() bool {
+	client, mock := newMockedClient()
+	client.SetRetryCount(3).SetRetryWaitTime(time.Millisecond).SetRetryMaxWaitTime(5 * time.Millisecond)
+	retries := 0
+	client.AddRetryHook(func(resp *Response, err error) { retries++ })
+
+	flaky := NewErrorResponder(errors.New("connection reset")).
+		Then(NewErrorResponder(errors.New("connection reset"))).
+		Then(NewStringResponder(200, "finally"))
+	mock.RegisterResponder("GET", "/flaky", flaky)
+
+	resp, err := client.R().Get("/flaky")
+	if err != nil || resp.String() != "finally" || resp.Request.Attempt != 3 || retries != 2 {
+		return false
+	}
+
+	// Retries stop after RetryCount and return the last error
+	mock.RegisterResponder("GET", "/down", NewErrorResponder(errors.New("connection refused")))
+	resp, err = client.R().Get("/down")
+	if err == nil || !strings.Contains(err.Error(), "connection refused") || resp.Request.Attempt != 4 {
+		return false
+	}
+
+	// Error statuses are not retried without a condition
+	mock.RegisterResponder("GET", "/busy", NewStringResponder(503, "busy"))
+	resp, err = client.R().Get("/busy")
+	if err != nil || resp.StatusCode() != 503 || resp.Request.Attempt != 1 {
+		return false
+	}
+
+	// The wait grows exponentially between the bounds
+	c := New().SetRetryWaitTime(100 * time.Millisecond).SetRetryMaxWaitTime(time.Second)
+	for attempt := 1; attempt < 8; attempt++ {
+		if wait := c.backoff(attempt); wait < 100*time.Millisecond || wait > time.Second {
+			return false
+		}
+	}
+	return c.backoff(10) >= 500*time.Millisecond
+}
+
+func testRetryConditions() bool {
+	client, mock := newMockedClient()
+	client.SetRetryCount(2).SetRetryWaitTime(time.Millisecond).SetRetryMaxWaitTime(time.Millisecond)
+	client.AddRetryAfterErrorCondition()
+
+	mock.RegisterResponder("GET", "/busy", NewStringResponder(503, "busy").Then(NewStringResponder(200, "ready")))
+	resp, err := client.R().Get("/busy")
+	if err != nil || resp.String() != "ready" || resp.Request.Attempt != 2 {
+		return false
+	}
+
+	// When retries run out, the last response is returned without error
+	mock.RegisterResponder("GET", "/broken", NewStringResponder(500, "broken"))
+	resp, err = client.R().Get("/broken")
+	if err != nil || resp.StatusCode() != 500 || resp.Request.Attempt != 3 {
+		return false
+	}
+
+	// Request conditions add to the client's
+	plain, plainMock := newMockedClient()
+	plain.SetRetryCount(5).SetRetryWaitTime(time.Millisecond).SetRetryMaxWaitTime(time.Millisecond)
+	plainMock.RegisterResponder("GET", "/pending", NewStringResponder(202, "pending").
+		Then(NewStringResponder(202, "pending")).
+		Then(NewStringResponder(200, "done")))
+	resp, err = plain.R().AddRetryCondition(func(r *Response, err error) bool {
+		return r != nil && r.StatusCode() == 202
+	}).Get("/pending")
+	return err == nil && resp.String() == "done" && resp.Request.Attempt == 3
+}
+
+// countingBackOff is a BackOff waiting a millisecond up to max times
+type countingBackOff struct {
+	max, calls, resets int
+}
+
+func (b *countingBackOff) NextBackOff() time.Duration {
+	if b.calls == b.max {
+		return -1
+	}
+	b.calls++
+	return time.Millisecond
+}
+
+func (b *countingBackOff) Reset() {
+	b.calls = 0
+	b.resets++
+}
+
+func testRetryBackOff() bool {
+	client, mock := newMockedClient()
+	backOff := &countingBackOff{max: 2}
+	client.SetRetryCount(10).SetRetryBackOff(func() BackOff { return backOff })
+	retries := 0
+	client.AddRetryHook(func(resp *Response, err error) { retries++ })
+
+	// The backoff stops retrying before RetryCount does
+	mock.RegisterResponder("GET", "/down", NewErrorResponder(errors.New("connection refused")))
+	resp, err := client.R().Get("/down")
+	if err == nil || resp.Request.Attempt != 3 || retries != 2 {
+		return false
+	}
+
+	// Each request starts with a reset backoff
+	mock.RegisterResponder("GET", "/flaky", NewErrorResponder(errors.New("connection reset")).
+		Then(NewStringResponder(200, "up")))
+	resp, err = client.R().Get("/flaky")
+	return err == nil && resp.String() == "up" && backOff.resets == 2 && backOff.calls == 1
+}
+
+func testTimeoutsAndContext() bool {
+	client, mock := newMockedClient()
+	mock.RegisterResponder("GET", "/slow", NewStringResponder(200, "late").Delay(200*time.Millisecond))
+	mock.RegisterResponder("GET", "/fast", NewStringResponder(200, "fast").Delay(time.Millisecond))
+
+	client.SetTimeout(20 * time.Millisecond)
+	start := time.Now()
+	_, err := client.R().Get("/slow")
+	if err == nil || time.Since(start) > 150*time.Millisecond {
+		return false
+	}
+	if resp, err := client.R().Get("/fast"); err != nil || resp.String() != "fast" {
+		return false
+	}
+
+	// Cancelling the context stops retries
+	retrying, retryMock := newMockedClient()
+	retrying.SetRetryCount(100).SetRetryWaitTime(10 * time.Millisecond).SetRetryMaxWaitTime(10 * time.Millisecond)
+	retryMock.RegisterResponder("GET", "/down", NewErrorResponder(errors.New("connection refused")))
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = retrying.R().SetContext(ctx).Get("/down")
+	return errors.Is(err, context.DeadlineExceeded) && time.Since(start) < time.Second &&
+		retryMock.GetTotalCallCount() < 10
+}
+
+func testMiddlewares() bool {
+	client, mock := newMockedClient()
+	client.SetRetryCount(3).SetRetryWaitTime(time.Millisecond)
+	rec := &capture{}
+	mock.RegisterResponder("GET", "/items", rec.responder)
+
+	var order []string
+	var failures []error
+	client.OnBeforeRequest(func(c *Client, r *Request) error {
+		order = append(order, "before")
+		r.SetHeader("X-Trace", "t-1")
+		if r.QueryParam.Get("deny") != "" {
+			return errors.New("denied by middleware")
+		}
+		return nil
+	})
+	client.SetPreRequestHook(func(c *Client, req *http.Request) error {
+		order = append(order, "pre:"+req.URL.Path)
+		return nil
+	})
+	client.OnAfterResponse(func(c *Client, resp *Response) error {
+		order = append(order, fmt.Sprintf("after:%d", resp.StatusCode()))
+		if resp.Header().Get("X-Fail") != "" {
+			return errors.New("rejected by middleware")
+		}
+		return nil
+	})
+	client.OnError(func(r *Request, err error) {
+		failures = append(failures, err)
+	})
+
+	if _, err := client.R().Get("/items"); err != nil {
+		return false
+	}
+	req, _ := rec.last()
+	if req.Header.Get("X-Trace") != "t-1" || strings.Join(order, ",") != "before,pre:/items,after:200" {
+		return false
+	}
+
+	// Middleware errors are returned without retrying
+	_, err := client.R().SetQueryParam("deny", "1").Get("/items")
+	if err == nil || err.Error() != "denied by middleware" || mock.GetTotalCallCount() != 1 {
+		return false
+	}
+	mock.RegisterResponder("GET", "/fail", func(req *http.Request) (*http.Response, error) {
+		resp := NewStringResponse(200, "ok")
+		resp.Header.Set("X-Fail", "1")
+		return resp, nil
+	})
+	resp, err := client.R().Get("/fail")
+	return err != nil && resp != nil && resp.StatusCode() == 200 && mock.GetTotalCallCount() == 2 &&
+		len(failures) == 2
+}
+
+func testMockMatching() bool {
+	client, mock := newMockedClient()
+	mock.RegisterResponder("GET", "https://API.example.com/users", NewStringResponder(200, "absolute"))
+	mock.RegisterResponder("GET", "/health", NewStringResponder(200, "any host"))
+	mock.RegisterResponder("GET", "/search?q=go&page=1", NewStringResponder(200, "exact query"))
+	mock.RegisterResponder("GET", "/search", NewStringResponder(200, "any query"))
+	mock.RegisterResponderWithQuery("GET", "/list", map[string]string{"sort": "name"}, NewStringResponder(200, "sorted"))
+	mock.RegisterRegexpResponder("GET", regexp.MustCompile(`/users/\d+$`), NewStringResponder(200, "by id"))
+	mock.RegisterResponder("DELETE", `=~^https://api\.example\.com/users/\d+`, NewStringResponder(204, ""))
+	mock.RegisterMatcherResponder("POST", "/users", BodyContainsString(`"admin"`).And(HeaderExists("X-Admin-Key")), NewStringResponder(201, "admin"))
+	mock.RegisterMatcherResponder("POST", "/users", HeaderIs("X-Kind", "bot").Or(HeaderContains("User-Agent", "crawler")), NewStringResponder(202, "bot"))
+	mock.RegisterResponder("POST", "/users", NewStringResponder(201, "user"))
+
+	get := func(url string) string {
+		resp, err := client.R().Get(url)
+		if err != nil {
+			return err.Error()
+		}
+		return resp.String()
+	}
+	other := New().SetTransport(mock)
+	otherResp, _ := other.R().Get("http://status.internal/health")
+
+	if get("/users") != "absolute" || otherResp.String() != "any host" ||
+		get("/search?page=1&q=go") != "exact query" || get("/search?q=rust") != "any query" ||
+		get("/list?sort=name") != "sorted" || !strings.Contains(get("/list?sort=date"), "no responder found") ||
+		get("/users/42") != "by id" {
+		return false
+	}
+	if resp, _ := client.R().Delete("/users/42"); resp.StatusCode() != 204 {
+		return false
+	}
+
+	admin, _ := client.R().SetHeader("X-Admin-Key", "k").SetBody(map[string]string{"role": "admin"}).Post("/users")
+	notAdmin, _ := client.R().SetBody(map[string]string{"role": "admin"}).Post("/users")
+	bot, _ := client.R().SetHeader("User-Agent", "crawler/1.0").SetBody("{}").Post("/users")
+	if admin.String() != "admin" || notAdmin.String() != "user" || bot.String() != "bot" {
+		return false
+	}
+
+	// Registering again replaces a responder; nil removes it
+	mock.RegisterResponder("GET", "/health", NewStringResponder(200, "replaced"))
+	otherResp, _ = other.R().Get("http://status.internal/health")
+	mock.RegisterResponder("GET", "/search", nil)
+	return otherResp.String() == "replaced" && strings.Contains(get("/search?q=rust"), "no responder found")
+}
+
+func testCallCounts() bool {
+	client, mock := newMockedClient()
+	mock.RegisterResponder("GET", "/a", NewStringResponder(200, "a"))
+	mock.RegisterMatcherResponder("POST", "/b", HeaderIs("X-Kind", "b"), NewStringResponder(200, "b"))
+
+	client.R().Get("/a")
+	client.R().Get("/a")
+	client.R().SetHeader("X-Kind", "b").Post("/b")
+	_, err := client.R().Get("/nowhere")
+
+	info := mock.GetCallCountInfo()
+	if info["GET /a"] != 2 || info["POST /b <HeaderIs(X-Kind, b)>"] != 1 || info["NO_RESPONDER"] != 1 ||
+		mock.GetTotalCallCount() != 4 || !errors.Is(err, ErrNoResponderFound) {
+		return false
+	}
+
+	mock.ZeroCallCounters()
+	info = mock.GetCallCountInfo()
+	if info["GET /a"] != 0 || len(info) != 2 || mock.GetTotalCallCount() != 0 {
+		return false
+	}
+	if resp, err := client.R().Get("/a"); err != nil || resp.String() != "a" {
+		return false
+	}
+
+	mock.Reset()
+	_, err = client.R().Get("/a")
+	return errors.Is(err, ErrNoResponderFound) && len(mock.GetCallCountInfo()) == 1
+}
+
+func testResponderHelpers() bool {
+	client, mock := newMockedClient()
+	mock.RegisterResponder("GET", "/once", NewStringResponder(200, "first").Once())
+	mock.RegisterResponder("GET", "/twice", NewStringResponder(200, "again").Times(2))
+	mock.RegisterResponder("GET", "/err", NewErrorResponder(errors.New("tls: handshake failure")))
+
+	resp := NewStringResponse(200, "shared")
+	resp.Header.Set("X-Shared", "yes")
+	mock.RegisterResponder("GET", "/shared", ResponderFromResponse(resp))
+
+	first, err1 := client.R().Get("/once")
+	_, err2 := client.R().Get("/once")
+	if err1 != nil || first.String() != "first" || err2 == nil {
+		return false
+	}
+	_, a := client.R().Get("/twice")
+	_, b := client.R().Get("/twice")
+	_, c := client.R().Get("/twice")
+	if a != nil || b != nil || c == nil {
+		return false
+	}
+
+	s1, _ := client.R().Get("/shared")
+	s2, _ := client.R().Get("/shared")
+	if s1.String() != "shared" || s2.String() != "shared" || s2.Header().Get("X-Shared") != "yes" {
+		return false
+	}
+
+	if _, err := client.R().Get("/err"); err == nil || !strings.Contains(err.Error(), "tls: handshake failure") {
+		return false
+	}
+
+	mock.RegisterNoResponder(NewStringResponder(404, "fallback"))
+	fallback, err := client.R().Get("/anything")
+	if err != nil || fallback.StatusCode() != 404 || fallback.String() != "fallback" {
+		return false
+	}
+	mock.RegisterNoResponder(ConnectionFailure)
+	_, err = client.R().Get("/anything")
+	if !errors.Is(err, ErrNoResponderFound) {
+		return false
+	}
+
+	_, jsonErr := NewJsonResponder(200, func() {})
+	return jsonErr != nil
+}
+
+func testActivation() bool {
+	defer DeactivateAndReset()
+	Activate()
+	RegisterResponder("GET", "https://example.com/ping", NewStringResponder(200, "pong"))
+
+	// Code using http.DefaultTransport is mocked
+	resp, err := http.Get("https://example.com/ping")
+	if err != nil {
+		return false
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		return false
+	}
+
+	client := New()
+	ActivateNonDefault(client.GetClient())
+	r, err := client.R().Get("https://example.com/ping")
+	if err != nil || r.String() != "pong" || GetTotalCallCount() != 2 || GetCallCountInfo()["GET https://example.com/ping"] != 2 {
+		return false
+	}
+
+	RegisterNoResponder(NewStringResponder(418, "teapot"))
+	r, _ = client.R().Get("https://example.com/other")
+	Deactivate()
+	return r.StatusCode() == 418 && http.DefaultTransport == InitialTransport
+}
+
+func testConcurrentRequests() bool {
+	client, mock := newMockedClient()
+	client.SetRetryCount(1).SetRetryWaitTime(time.Millisecond)
+	mock.RegisterRegexpResponder("GET", regexp.MustCompile(`/items/\d+$`), func(req *http.Request) (*http.Response, error) {
+		id := strings.TrimPrefix(req.URL.Path, "/items/")
+		return NewJsonResponse(200, map[string]string{"id": id})
+	})
+
+	const n = 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ok := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := map[string]string{}
+			resp, err := client.R().SetResult(&result).SetPathParam("id", fmt.Sprint(i)).Get("/items/{id}")
+			if err == nil && resp.IsSuccess() && result["id"] == fmt.Sprint(i) {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return ok == n && mock.GetTotalCallCount() == n
+}
+
+func main() {
+	fmt.Println("Running Resty Emulator Tests...")
+	fmt.Println("===============================")
+
+	runTest("GET Request", testGetRequest)
+	runTest("JSON Result", testJSONResult)
+	runTest("Error Result", testErrorResult)
+	runTest("Request Bodies", testRequestBodies)
+	runTest("Auth And Cookies", testAuthAndCookies)
+	runTest("Retries", testRetries)
+	runTest("Retry Conditions", testRetryConditions)
+	runTest("Retry BackOff", testRetryBackOff)
+	runTest("Timeouts And Context", testTimeoutsAndContext)
+	runTest("Middlewares", testMiddlewares)
+	runTest("Mock Matching", testMockMatching)
+	runTest("Call Counts", testCallCounts)
+	runTest("Responder Helpers", testResponderHelpers)
+	runTest("Activation", testActivation)
+	runTest("Concurrent Requests", testConcurrentRequests)
+
+	fmt.Println("===============================")
+	fmt.Println("All tests completed!")
+}
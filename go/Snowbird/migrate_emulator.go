@@ -0,0 +1,574 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang-migrate
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NilVersion is the version of a database no migration has been applied to
+const NilVersion = -1
+
+// DefaultMigrationsTable is where a StoreDriver records the version
+const DefaultMigrationsTable = "schema_migrations"
+
+var (
+	ErrNoChange       = errors.New("no change")
+	ErrNilVersion     = errors.New("no migration")
+	ErrInvalidVersion = errors.New("version must be >= -1")
+	ErrLocked         = errors.New("can't acquire lock")
+	ErrLockNotHeld    = errors.New("can't unlock, as not currently locked")
+)
+
+// ErrDirty is returned when the last migration failed part way. Fix the
+// database by hand, then Force the version it is now at.
+type ErrDirty struct {
+	Version int
+}
+
+func (e ErrDirty) Error() string {
+	return fmt.Sprintf("Dirty database version %v. Fix and force version.", e.Version)
+}
+
+// ErrShortLimit is returned by Steps when fewer migrations than asked for
+// were available. The ones that were available have been applied.
+type ErrShortLimit struct {
+	Short uint
+}
+
+func (e ErrShortLimit) Error() string {
+	return fmt.Sprintf("limit %v short", e.Short)
+}
+
+// MigrationError is returned when a migration fails. The database is left
+// dirty at the migration's target version.
+type MigrationError struct {
+	Migration string
+	Err       error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migration %s failed: %v", e.Migration, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error { return e.Err }
+
+// Logger receives progress messages
+type Logger interface {
+	Printf(format string, v ...interface{})
+	Verbose() bool
+}
+
+// Migration is one versioned change. Each direction is SQL run by the
+// Driver, a Go function, or neither, in which case only the version moves.
+type Migration struct {
+	Version    uint
+	Identifier string
+	Up         string
+	Down       string
+	UpFunc     func() error
+	DownFunc   func() error
+
+	hasUp, hasDown bool
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%d/%s", m.Version, m.Identifier)
+}
+
+// Source lists the migrations available
+type Source interface {
+	Migrations() ([]*Migration, error)
+}
+
+// Driver is the database migrations run against. It records the current
+// version and whether the migration that set it failed part way.
+type Driver interface {
+	Lock() error
+	Unlock() error
+	Run(migration string) error
+	SetVersion(version int, dirty bool) error
+	Version() (version int, dirty bool, err error)
+	Drop() error
+}
+
+// File sources
+
+// fileRegex matches migration files named like 1_create_users.up.sql
+var fileRegex = regexp.MustCompile(`^([0-9]+)_(.*)\.(down|up)\.(.*)$`)
+
+// FileSource reads migrations from {version}_{title}.up.{ext} and
+// {version}_{title}.down.{ext} files. Either file of a pair may be missing.
+type FileSource struct {
+	fsys fs.FS
+	path string
+}
+
+// NewFileSource reads migrations from a directory
+func NewFileSource(dir string) *FileSource {
+	return &FileSource{fsys: os.DirFS(dir), path: "."}
+}
+
+// NewIOFS reads migrations from path in fsys, such as an embed.FS
+func NewIOFS(fsys fs.FS, path string) (*FileSource, error) {
+	if _, err := fs.ReadDir(fsys, path); err != nil {
+		return nil, err
+	}
+	return &FileSource{fsys: fsys, path: path}, nil
+}
+
+// Migrations parses the file names and reads each file
+func (s *FileSource) Migrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(s.fsys, s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileRegex.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version in %s: %v", entry.Name(), err)
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Identifier: match[2]}
+			byVersion[uint(version)] = m
+		}
+		if (match[3] == "up" && m.hasUp) || (match[3] == "down" && m.hasDown) {
+			return nil, fmt.Errorf("duplicate migration file: %s", entry.Name())
+		}
+
+		body, err := fs.ReadFile(s.fsys, joinPath(s.path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.Up, m.hasUp = string(body), true
+		} else {
+			m.Down, m.hasDown = string(body), true
+		}
+	}
+	return sortMigrations(byVersion), nil
+}
+
+func joinPath(dir, name string) string {
+	if dir == "." || dir == "" {
+		return name
+	}
+	return strings.TrimSuffix(dir, "/") + "/" + name
+}
+
+// Go sources
+
+// GoSource holds migrations written as Go functions
+type GoSource struct {
+	migrations map[uint]*Migration
+}
+
+// NewGoSource returns an empty GoSource
+func NewGoSource() *GoSource {
+	return &GoSource{migrations: map[uint]*Migration{}}
+}
+
+// Register adds a migration. Either function may be nil.
+func (s *GoSource) Register(version uint, identifier string, up, down func() error) error {
+	if _, exists := s.migrations[version]; exists {
+		return fmt.Errorf("duplicate migration version: %d", version)
+	}
+	s.migrations[version] = &Migration{
+		Version:    version,
+		Identifier: identifier,
+		UpFunc:     up,
+		DownFunc:   down,
+	}
+	return nil
+}
+
+// Migrations returns the registered migrations in version order
+func (s *GoSource) Migrations() ([]*Migration, error) {
+	return sortMigrations(s.migrations), nil
+}
+
+func sortMigrations(byVersion map[uint]*Migration) []*Migration {
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// Store drivers
+
+// Store is a database a StoreDriver keeps its version table in and runs
+// SQL against. The GORM emulator's DB implements it, as can any store with
+// these methods.
+type Store interface {
+	ExecSQL(sql string) error
+	TableNames() []string
+	TableRows(table string) ([]map[string]interface{}, bool)
+	SetTableRows(table string, rows []map[string]interface{})
+}
+
+// Config configures a StoreDriver
+type Config struct {
+	// MigrationsTable defaults to schema_migrations
+	MigrationsTable string
+}
+
+// StoreDriver is a Driver over a Store. The version is the one row of the
+// migrations table, with version and dirty columns.
+type StoreDriver struct {
+	store  Store
+	config Config
+	mu     sync.Mutex
+	locked bool
+}
+
+// WithInstance returns a Driver for store, creating the migrations table
+// if it does not exist
+func WithInstance(store Store, config *Config) (*StoreDriver, error) {
+	if store == nil {
+		return nil, errors.New("no store")
+	}
+	d := &StoreDriver{store: store}
+	if config != nil {
+		d.config = *config
+	}
+	if d.config.MigrationsTable == "" {
+		d.config.MigrationsTable = DefaultMigrationsTable
+	}
+	if err := d.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *StoreDriver) ensureVersionTable() error {
+	return d.store.ExecSQL(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint not null primary key, dirty boolean not null)", d.config.MigrationsTable))
+}
+
+// Lock takes the driver's lock, failing with ErrLocked if it is held
+func (d *StoreDriver) Lock() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.locked {
+		return ErrLocked
+	}
+	d.locked = true
+	return nil
+}
+
+// Unlock releases the driver's lock
+func (d *StoreDriver) Unlock() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.locked {
+		return ErrLockNotHeld
+	}
+	d.locked = false
+	return nil
+}
+
+// Run runs a migration's SQL against the store
+func (d *StoreDriver) Run(migration string) error {
+	return d.store.ExecSQL(migration)
+}
+
+// SetVersion replaces the recorded version. NilVersion empties the table.
+func (d *StoreDriver) SetVersion(version int, dirty bool) error {
+	if version < NilVersion {
+		return ErrInvalidVersion
+	}
+	var rows []map[string]interface{}
+	if version >= 0 || dirty {
+		rows = []map[string]interface{}{{"version": version, "dirty": dirty}}
+	}
+	d.store.SetTableRows(d.config.MigrationsTable, rows)
+	return nil
+}
+
+// Version returns the recorded version, or NilVersion if there is none
+func (d *StoreDriver) Version() (version int, dirty bool, err error) {
+	rows, ok := d.store.TableRows(d.config.MigrationsTable)
+	if !ok || len(rows) == 0 {
+		return NilVersion, false, nil
+	}
+	version, ok = rows[0]["version"].(int)
+	if !ok {
+		return 0, false, fmt.Errorf("%s has an invalid version: %v", d.config.MigrationsTable, rows[0]["version"])
+	}
+	dirty, _ = rows[0]["dirty"].(bool)
+	return version, dirty, nil
+}
+
+// Drop drops every table in the store, including the migrations table
+func (d *StoreDriver) Drop() error {
+	for _, table := range d.store.TableNames() {
+		if err := d.store.ExecSQL("DROP TABLE IF EXISTS " + table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrate
+
+// Migrate applies migrations from a Source to a Driver
+type Migrate struct {
+	// Log, if set, is told about each migration applied
+	Log Logger
+
+	source     Source
+	db         Driver
+	migrations []*Migration
+}
+
+// New reads migrations from sourceURL, a file:// URL of a directory, and
+// applies them to db
+func New(sourceURL string, db Driver) (*Migrate, error) {
+	if !strings.HasPrefix(sourceURL, "file://") {
+		return nil, fmt.Errorf("unsupported source URL: %s", sourceURL)
+	}
+	return NewWithSourceInstance(NewFileSource(strings.TrimPrefix(sourceURL, "file://")), db)
+}
+
+// NewWithSourceInstance applies the migrations from source to db
+func NewWithSourceInstance(source Source, db Driver) (*Migrate, error) {
+	if source == nil || db == nil {
+		return nil, errors.New("source and database are required")
+	}
+	migrations, err := source.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrate{source: source, db: db, migrations: migrations}, nil
+}
+
+// Version returns the database's version and whether it is dirty, or
+// ErrNilVersion if no migration has been applied
+func (m *Migrate) Version() (version uint, dirty bool, err error) {
+	v, d, err := m.db.Version()
+	if err != nil {
+		return 0, false, err
+	}
+	if v == NilVersion {
+		return 0, false, ErrNilVersion
+	}
+	return uint(v), d, nil
+}
+
+// Up applies every migration after the current version
+func (m *Migrate) Up() error {
+	return m.run(func(current int) ([]step, error) {
+		return m.upSteps(current, -1), nil
+	})
+}
+
+// Down reverts every migration applied
+func (m *Migrate) Down() error {
+	return m.run(func(current int) ([]step, error) {
+		return m.downSteps(current, -1), nil
+	})
+}
+
+// Steps applies n migrations up if n is positive, or reverts -n if it is
+// negative
+func (m *Migrate) Steps(n int) error {
+	if n == 0 {
+		return ErrNoChange
+	}
+	return m.run(func(current int) ([]step, error) {
+		var steps []step
+		if n > 0 {
+			steps = m.upSteps(current, n)
+		} else {
+			steps = m.downSteps(current, -n)
+		}
+		if short := abs(n) - len(steps); short > 0 {
+			return steps, ErrShortLimit{Short: uint(short)}
+		}
+		return steps, nil
+	})
+}
+
+// Migrate moves the database up or down to version
+func (m *Migrate) Migrate(version uint) error {
+	if m.find(int(version)) < 0 {
+		return fmt.Errorf("no migration found for version %d: %w", version, os.ErrNotExist)
+	}
+	return m.run(func(current int) ([]step, error) {
+		if int(version) >= current {
+			return m.upSteps(current, m.find(int(version))-m.find(current)), nil
+		}
+		return m.downSteps(current, m.find(current)-m.find(int(version))), nil
+	})
+}
+
+// Force records version as clean without running anything, after a dirty
+// database has been fixed by hand. NilVersion forgets every migration.
+func (m *Migrate) Force(version int) error {
+	if version < NilVersion {
+		return ErrInvalidVersion
+	}
+	if err := m.db.Lock(); err != nil {
+		return err
+	}
+	defer m.db.Unlock()
+	return m.db.SetVersion(version, false)
+}
+
+// Drop drops everything in the database
+func (m *Migrate) Drop() error {
+	if err := m.db.Lock(); err != nil {
+		return err
+	}
+	defer m.db.Unlock()
+	return m.db.Drop()
+}
+
+// step is one migration to run, and the version it leaves the database at
+type step struct {
+	migration *Migration
+	up        bool
+	target    int
+}
+
+// run locks the database, plans steps from its version and applies them.
+// A planning error such as ErrShortLimit is returned after the planned
+// steps have been applied.
+func (m *Migrate) run(plan func(current int) ([]step, error)) error {
+	if err := m.db.Lock(); err != nil {
+		return err
+	}
+	defer m.db.Unlock()
+
+	current, dirty, err := m.db.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirty{Version: current}
+	}
+	if current != NilVersion && m.find(current) < 0 {
+		return fmt.Errorf("no migration found for version %d: %w", current, os.ErrNotExist)
+	}
+
+	steps, planErr := plan(current)
+	if len(steps) == 0 && planErr == nil {
+		return ErrNoChange
+	}
+	for _, s := range steps {
+		if err := m.apply(s); err != nil {
+			return err
+		}
+	}
+	return planErr
+}
+
+// apply marks the target version dirty, runs the migration and marks it
+// clean. A failure leaves it dirty.
+func (m *Migrate) apply(s step) error {
+	direction, body, fn := "down", s.migration.Down, s.migration.DownFunc
+	if s.up {
+		direction, body, fn = "up", s.migration.Up, s.migration.UpFunc
+	}
+	name := fmt.Sprintf("%d_%s.%s", s.migration.Version, s.migration.Identifier, direction)
+
+	if err := m.db.SetVersion(s.target, true); err != nil {
+		return err
+	}
+	var err error
+	switch {
+	case fn != nil:
+		err = fn()
+	case strings.TrimSpace(body) != "":
+		err = m.db.Run(body)
+	}
+	if err != nil {
+		return &MigrationError{Migration: name, Err: err}
+	}
+	if err := m.db.SetVersion(s.target, false); err != nil {
+		return err
+	}
+	m.logf("%s\n", name)
+	return nil
+}
+
+func (m *Migrate) logf(format string, v ...interface{}) {
+	if m.Log != nil && m.Log.Verbose() {
+		m.Log.Printf(format, v...)
+	}
+}
+
+// find returns the index of version in the sorted migrations, or -1
+func (m *Migrate) find(version int) int {
+	for i, mig := range m.migrations {
+		if int(mig.Version) == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// upSteps plans up to limit migrations after current, or all if limit
+// is negative
+func (m *Migrate) upSteps(current, limit int) []step {
+	var steps []step
+	for _, mig := range m.migrations {
+		if int(mig.Version) <= current {
+			continue
+		}
+		if limit >= 0 && len(steps) == limit {
+			break
+		}
+		steps = append(steps, step{migration: mig, up: true, target: int(mig.Version)})
+	}
+	return steps
+}
+
+// downSteps plans reverting up to limit migrations from current, or all
+// if limit is negative
+func (m *Migrate) downSteps(current, limit int) []step {
+	var steps []step
+	for i := m.find(current); i >= 0; i-- {
+		if limit >= 0 && len(steps) == limit {
+			break
+		}
+		target := NilVersion
+		if i > 0 {
+			target = int(m.migrations[i-1].Version)
+		}
+		steps = append(steps, step{migration: m.migrations[i], up: false, target: target})
+	}
+	return steps
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Close is a no-op kept for compatibility; sources and drivers hold no
+// connections of their own
+func (m *Migrate) Close() (sourceErr, databaseErr error) {
+	return nil, nil
+}
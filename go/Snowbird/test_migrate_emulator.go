@@ -0,0 +1,506 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang-migrate
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing/fstest"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// fakeStore keeps tables in memory the way the GORM emulator does. It
+// creates and drops tables, and fails statements containing FAIL.
+type fakeStore struct {
+	mu     sync.Mutex
+	tables map[string][]map[string]interface{}
+	ran    []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{tables: map[string][]map[string]interface{}{}}
+}
+
+func (s *fakeStore) ExecSQL(sql string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, statement := range strings.Split(sql, ";") {
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			continue
+		}
+		s.ran = append(s.ran, strings.Join(fields, " "))
+		if strings.Contains(statement, "FAIL") {
+			return errors.New("syntax error at or near FAIL")
+		}
+		if len(fields) < 3 || strings.ToUpper(fields[1]) != "TABLE" {
+			continue
+		}
+		name := fields[len(fields)-1]
+		if strings.ToUpper(fields[0]) == "CREATE" {
+			for i, f := range fields {
+				if strings.Contains(f, "(") {
+					name = strings.SplitN(f, "(", 2)[0]
+					if name == "" {
+						name = fields[i-1]
+					}
+					break
+				}
+			}
+			if _, exists := s.tables[name]; !exists {
+				s.tables[name] = []map[string]interface{}{}
+			}
+		} else if strings.ToUpper(fields[0]) == "DROP" {
+			delete(s.tables, name)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) TableNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *fakeStore) TableRows(table string) ([]map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, ok := s.tables[table]
+	return rows, ok
+}
+
+func (s *fakeStore) SetTableRows(table string, rows []map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables[table] = rows
+}
+
+func (s *fakeStore) has(table string) bool {
+	_, ok := s.TableRows(table)
+	return ok
+}
+
+// fakeLog records verbose messages
+type fakeLog struct {
+	lines []string
+}
+
+func (l *fakeLog) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l *fakeLog) Verbose() bool { return true }
+
+// schemaFS has four migrations; only the first two have down files
+func schemaFS() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/1_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/1_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"migrations/2_create_orders.up.sql":   {Data: []byte("CREATE TABLE orders (id INT);\nCREATE TABLE order_items (id INT);")},
+		"migrations/2_create_orders.down.sql": {Data: []byte("DROP TABLE order_items; DROP TABLE orders;")},
+		"migrations/10_index_orders.up.sql":   {Data: []byte("CREATE INDEX orders_by_user ON orders (user_id);")},
+		"migrations/README.md":                {Data: []byte("not a migration")},
+		"migrations/archive/3_old.up.sql":     {Data: []byte("CREATE TABLE old (id INT);")},
+		"other/1_elsewhere.up.sql":            {Data: []byte("CREATE TABLE elsewhere (id INT);")},
+		"migrations/5_seed.up.txt.bak":        {Data: nil},
+	}
+}
+
+func newMigrate(store *fakeStore) (*Migrate, error) {
+	source, err := NewIOFS(schemaFS(), "migrations")
+	if err != nil {
+		return nil, err
+	}
+	driver, err := WithInstance(store, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithSourceInstance(source, driver)
+}
+
+func testFileSource() bool {
+	source, err := NewIOFS(schemaFS(), "migrations")
+	if err != nil {
+		return false
+	}
+	migrations, err := source.Migrations()
+	if err != nil || len(migrations) != 4 {
+		return false
+	}
+	var versions []uint
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	// 5_seed.up.txt.bak matches the pattern with extension txt.bak
+	return reflect.DeepEqual(versions, []uint{1, 2, 5, 10}) &&
+		migrations[0].Identifier == "create_users" && migrations[0].Down == "DROP TABLE users;" &&
+		migrations[3].Down == "" && migrations[3].String() == "10/index_orders"
+}
+
+func testSourceErrors() bool {
+	duplicate := fstest.MapFS{
+		"m/1_a.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		"m/1_b.up.sql": {Data: []byte("CREATE TABLE b (id INT);")},
+	}
+	source, _ := NewIOFS(duplicate, "m")
+	if _, err := source.Migrations(); err == nil || !strings.Contains(err.Error(), "duplicate migration file") {
+		return false
+	}
+	if _, err := NewIOFS(duplicate, "missing"); err == nil {
+		return false
+	}
+	if _, err := New("postgres://localhost/db", nil); err == nil {
+		return false
+	}
+	_, err := NewWithSourceInstance(NewGoSource(), nil)
+	return err != nil
+}
+
+func testUp() bool {
+	store := newFakeStore()
+	m, err := newMigrate(store)
+	if err != nil {
+		return false
+	}
+	if _, _, err := m.Version(); err != ErrNilVersion {
+		return false
+	}
+	if err := m.Up(); err != nil {
+		return false
+	}
+	version, dirty, err := m.Version()
+	if err != nil || version != 10 || dirty {
+		return false
+	}
+	if !store.has("users") || !store.has("orders") || !store.has("order_items") {
+		return false
+	}
+	return m.Up() == ErrNoChange
+}
+
+func testDown() bool {
+	store := newFakeStore()
+	m, _ := newMigrate(store)
+	m.Up()
+	if err := m.Down(); err != nil {
+		return false
+	}
+	if _, _, err := m.Version(); err != ErrNilVersion {
+		return false
+	}
+	if store.has("users") || store.has("orders") || store.has("order_items") {
+		return false
+	}
+	return m.Down() == ErrNoChange && store.has(DefaultMigrationsTable)
+}
+
+func testSteps() bool {
+	store := newFakeStore()
+	m, _ := newMigrate(store)
+	if err := m.Steps(2); err != nil {
+		return false
+	}
+	if v, _, _ := m.Version(); v != 2 || store.has("users") != true {
+		return false
+	}
+	if err := m.Steps(-1); err != nil {
+		return false
+	}
+	if v, _, _ := m.Version(); v != 1 || store.has("orders") {
+		return false
+	}
+
+	// Asking for more than there are applies what there is
+	err := m.Steps(5)
+	short, ok := err.(ErrShortLimit)
+	if !ok || short.Short != 2 || err.Error() != "limit 2 short" {
+		return false
+	}
+	if v, _, _ := m.Version(); v != 10 {
+		return false
+	}
+	err = m.Steps(-6)
+	short, ok = err.(ErrShortLimit)
+	if !ok || short.Short != 2 {
+		return false
+	}
+	_, _, err = m.Version()
+	return err == ErrNilVersion && m.Steps(0) == ErrNoChange
+}
+
+func testMigrateToVersion() bool {
+	store := newFakeStore()
+	m, _ := newMigrate(store)
+	if err := m.Migrate(5); err != nil {
+		return false
+	}
+	if v, _, _ := m.Version(); v != 5 || !store.has("orders") {
+		return false
+	}
+	if err := m.Migrate(1); err != nil {
+		return false
+	}
+	if v, _, _ := m.Version(); v != 1 || store.has("orders") || !store.has("users") {
+		return false
+	}
+	if m.Migrate(1) != ErrNoChange {
+		return false
+	}
+	err := m.Migrate(7)
+	return errors.Is(err, os.ErrNotExist) && strings.Contains(err.Error(), "version 7")
+}
+
+func testDirtyState() bool {
+	store := newFakeStore()
+	source := fstest.MapFS{
+		"m/1_users.up.sql":  {Data: []byte("CREATE TABLE users (id INT);")},
+		"m/2_broken.up.sql": {Data: []byte("CREATE TABLE half (id INT); FAIL HERE;")},
+		"m/3_later.up.sql":  {Data: []byte("CREATE TABLE later (id INT);")},
+	}
+	fsSource, _ := NewIOFS(source, "m")
+	driver, _ := WithInstance(store, nil)
+	m, _ := NewWithSourceInstance(fsSource, driver)
+
+	err := m.Up()
+	var migrationErr *MigrationError
+	if !errors.As(err, &migrationErr) || migrationErr.Migration != "2_broken.up" || !strings.Contains(err.Error(), "syntax error") {
+		return false
+	}
+	version, dirty, _ := m.Version()
+	if version != 2 || !dirty || !store.has("half") || store.has("later") {
+		return false
+	}
+
+	// Nothing runs until the database is fixed and forced
+	err = m.Up()
+	if err != (ErrDirty{Version: 2}) || err.Error() != "Dirty database version 2. Fix and force version." {
+		return false
+	}
+	if _, ok := m.Steps(-1).(ErrDirty); !ok {
+		return false
+	}
+	if err := m.Force(1); err != nil {
+		return false
+	}
+	version, dirty, _ = m.Version()
+	return version == 1 && !dirty
+}
+
+func testForce() bool {
+	store := newFakeStore()
+	m, _ := newMigrate(store)
+	m.Up()
+	if m.Force(-2) != ErrInvalidVersion {
+		return false
+	}
+	if err := m.Force(NilVersion); err != nil {
+		return false
+	}
+	if _, _, err := m.Version(); err != ErrNilVersion {
+		return false
+	}
+	// Forcing runs nothing; Up carries on after the forced version
+	if err := m.Force(2); err != nil {
+		return false
+	}
+	return m.Up() == nil && len(store.ran) > 0 && store.ran[len(store.ran)-1] == "CREATE INDEX orders_by_user ON orders (user_id)"
+}
+
+func testGoMigrations() bool {
+	var applied []string
+	source := NewGoSource()
+	source.Register(2, "backfill", func() error {
+		applied = append(applied, "up 2")
+		return nil
+	}, func() error {
+		applied = append(applied, "down 2")
+		return nil
+	})
+	source.Register(1, "init", func() error {
+		applied = append(applied, "up 1")
+		return nil
+	}, nil)
+	source.Register(3, "explode", func() error {
+		return errors.New("backfill failed")
+	}, nil)
+	if source.Register(1, "again", nil, nil) == nil {
+		return false
+	}
+
+	driver, _ := WithInstance(newFakeStore(), nil)
+	m, _ := NewWithSourceInstance(source, driver)
+	err := m.Up()
+	var migrationErr *MigrationError
+	if !errors.As(err, &migrationErr) || migrationErr.Migration != "3_explode.up" {
+		return false
+	}
+	m.Force(2)
+	// 1 has no down function, so reverting it only moves the version
+	if err := m.Down(); err != nil {
+		return false
+	}
+	_, _, err = m.Version()
+	return err == ErrNilVersion && reflect.DeepEqual(applied, []string{"up 1", "up 2", "down 2"})
+}
+
+func testStoreDriver() bool {
+	store := newFakeStore()
+	driver, err := WithInstance(store, &Config{MigrationsTable: "schema_versions"})
+	if err != nil || !store.has("schema_versions") || store.has(DefaultMigrationsTable) {
+		return false
+	}
+	if v, dirty, err := driver.Version(); v != NilVersion || dirty || err != nil {
+		return false
+	}
+	driver.SetVersion(3, true)
+	rows, _ := store.TableRows("schema_versions")
+	if len(rows) != 1 || rows[0]["version"] != 3 || rows[0]["dirty"] != true {
+		return false
+	}
+	driver.SetVersion(4, false)
+	if v, dirty, _ := driver.Version(); v != 4 || dirty {
+		return false
+	}
+	if driver.SetVersion(-5, false) != ErrInvalidVersion {
+		return false
+	}
+	store.SetTableRows("schema_versions", []map[string]interface{}{{"version": "four"}})
+	if _, _, err := driver.Version(); err == nil {
+		return false
+	}
+	_, err = WithInstance(nil, nil)
+	return err != nil
+}
+
+func testLocking() bool {
+	store := newFakeStore()
+	driver, _ := WithInstance(store, nil)
+	if driver.Unlock() != ErrLockNotHeld {
+		return false
+	}
+	driver.Lock()
+	source, _ := NewIOFS(schemaFS(), "migrations")
+	m, _ := NewWithSourceInstance(source, driver)
+	if m.Up() != ErrLocked || m.Force(1) != ErrLocked {
+		return false
+	}
+	driver.Unlock()
+
+	// Concurrent runs either apply everything once or find the lock held
+	var wg sync.WaitGroup
+	results := make([]error, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = m.Up()
+		}(i)
+	}
+	wg.Wait()
+	applied := 0
+	for _, err := range results {
+		switch err {
+		case nil:
+			applied++
+		case ErrLocked, ErrNoChange:
+		default:
+			return false
+		}
+	}
+	v, _, _ := m.Version()
+	return applied == 1 && v == 10
+}
+
+func testDrop() bool {
+	store := newFakeStore()
+	store.ExecSQL("CREATE TABLE unmanaged (id INT)")
+	m, _ := newMigrate(store)
+	m.Up()
+	if err := m.Drop(); err != nil {
+		return false
+	}
+	if len(store.TableNames()) != 0 {
+		return false
+	}
+	// The version is gone with its table, so Up starts again
+	_, _, err := m.Version()
+	return err == ErrNilVersion && m.Up() == nil && store.has("users")
+}
+
+func testFileURL() bool {
+	dir, err := os.MkdirTemp("", "migrations")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(dir)
+	os.WriteFile(filepath.Join(dir, "1_accounts.up.sql"), []byte("CREATE TABLE accounts (id INT);"), 0644)
+	os.WriteFile(filepath.Join(dir, "1_accounts.down.sql"), []byte("DROP TABLE accounts;"), 0644)
+	os.WriteFile(filepath.Join(dir, "2_audit.up.sql"), []byte("CREATE TABLE audit (id INT);"), 0644)
+
+	store := newFakeStore()
+	driver, _ := WithInstance(store, nil)
+	m, err := New("file://"+dir, driver)
+	if err != nil {
+		return false
+	}
+	log := &fakeLog{}
+	m.Log = log
+	if err := m.Up(); err != nil || !store.has("accounts") || !store.has("audit") {
+		return false
+	}
+	if _, err := New("file://"+filepath.Join(dir, "missing"), driver); err == nil {
+		return false
+	}
+	sourceErr, databaseErr := m.Close()
+	return reflect.DeepEqual(log.lines, []string{"1_accounts.up", "2_audit.up"}) && sourceErr == nil && databaseErr == nil
+}
+
+func testUnknownCurrentVersion() bool {
+	store := newFakeStore()
+	m, _ := newMigrate(store)
+	m.Force(4)
+	err := m.Up()
+	return errors.Is(err, os.ErrNotExist) && strings.Contains(err.Error(), "version 4")
+}
+
+func main() {
+	fmt.Println("Running golang-migrate Emulator Tests...")
+	fmt.Println("========================================")
+
+	runTest("File Source", testFileSource)
+	runTest("Source Errors", testSourceErrors)
+	runTest("Up", testUp)
+	runTest("Down", testDown)
+	runTest("Steps", testSteps)
+	runTest("Migrate To Version", testMigrateToVersion)
+	runTest("Dirty State", testDirtyState)
+	runTest("Force", testForce)
+	runTest("Go Migrations", testGoMigrations)
+	runTest("Store Driver", testStoreDriver)
+	runTest("Locking", testLocking)
+	runTest("Drop", testDrop)
+	runTest("File URL", testFileURL)
+	runTest("Unknown Current Version", testUnknownCurrentVersion)
+
+	fmt.Println("========================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,678 @@
+package main
+
+// Developed by PowerShield, as an alternative to gorilla/websocket
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+var upgrader = Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+// echo upgrades and echoes messages until the client closes
+func echo(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			return
+		}
+	}
+}
+
+// startEcho serves echo at /ws and dials it
+func startEcho() (*Server, *Conn, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", echo)
+	s := NewServer(mux)
+	conn, _, err := DefaultDialer.Dial(s.URL+"/ws", nil)
+	if err != nil {
+		s.Close()
+		return nil, nil, err
+	}
+	return s, conn, nil
+}
+
+// eventually polls cond for up to a second
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func testEcho() bool {
+	s, conn, err := startEcho()
+	if err != nil {
+		return false
+	}
+	defer s.Close()
+	defer conn.Close()
+
+	if err := conn.WriteMessage(TextMessage, []byte("hello")); err != nil {
+		return false
+	}
+	if err := conn.WriteMessage(BinaryMessage, []byte{0, 1, 2}); err != nil {
+		return false
+	}
+	mt1, data1, err1 := conn.ReadMessage()
+	mt2, data2, err2 := conn.ReadMessage()
+	return err1 == nil && err2 == nil &&
+		mt1 == TextMessage && string(data1) == "hello" &&
+		mt2 == BinaryMessage && len(data2) == 3 && data2[2] == 2 &&
+		conn.LocalAddr().Network() == "tcp" && conn.RemoteAddr().String() == strings.TrimPrefix(s.URL, "ws://")
+}
+
+func testJSON() bool {
+	type chat struct {
+		User string `json:"user"`
+		Text string `json:"text"`
+	}
+	s, conn, err := startEcho()
+	if err != nil {
+		return false
+	}
+	defer s.Close()
+	defer conn.Close()
+
+	if err := conn.WriteJSON(chat{User: "ann", Text: "hi"}); err != nil {
+		return false
+	}
+	var got chat
+	if err := conn.ReadJSON(&got); err != nil || got.User != "ann" || got.Text != "hi" {
+		return false
+	}
+
+	// WriteJSON uses a json.Encoder, so the message ends in a newline
+	conn.WriteJSON(map[string]int{"n": 1})
+	_, raw, err := conn.ReadMessage()
+	if err != nil || string(raw) != "{\"n\":1}\n" {
+		return false
+	}
+
+	conn.WriteMessage(TextMessage, []byte("{"))
+	return conn.ReadJSON(&got) != nil
+}
+
+func testNextReaderWriter() bool {
+	s, conn, err := startEcho()
+	if err != nil {
+		return false
+	}
+	defer s.Close()
+	defer conn.Close()
+
+	w, err := conn.NextWriter(TextMessage)
+	if err != nil {
+		return false
+	}
+	fmt.Fprintf(w, "part %d, ", 1)
+	fmt.Fprintf(w, "part %d", 2)
+	if w.Close() != nil {
+		return false
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		return false
+	}
+
+	messageType, r, err := conn.NextReader()
+	if err != nil || messageType != TextMessage {
+		return false
+	}
+	buf := make([]byte, 64)
+	n, _ := r.Read(buf)
+	if string(buf[:n]) != "part 1, part 2" {
+		return false
+	}
+
+	// Starting a message closes the previous writer, sending it
+	first, _ := conn.NextWriter(BinaryMessage)
+	first.Write([]byte("one"))
+	second, _ := conn.NextWriter(TextMessage)
+	second.Write([]byte("two"))
+	second.Close()
+	_, a, _ := conn.ReadMessage()
+	_, b, _ := conn.ReadMessage()
+	if string(a) != "one" || string(b) != "two" {
+		return false
+	}
+
+	_, err = conn.NextWriter(PingMessage)
+	return err != nil
+}
+
+func testPingPong() bool {
+	s, conn, err := startEcho()
+	if err != nil {
+		return false
+	}
+	defer s.Close()
+	defer conn.Close()
+
+	pongs := make(chan string, 1)
+	conn.SetPongHandler(func(appData string) error {
+		pongs <- appData
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		return nil
+	})
+	if err := conn.WriteControl(PingMessage, []byte("beat"), time.Now().Add(time.Second)); err != nil {
+		return false
+	}
+	conn.WriteMessage(TextMessage, []byte("after"))
+
+	// The pong is handled while waiting for the next data message
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != "after" || <-pongs != "beat" {
+		return false
+	}
+
+	if conn.WriteControl(TextMessage, nil, time.Time{}) == nil {
+		return false
+	}
+	if conn.WriteControl(PingMessage, make([]byte, 126), time.Time{}) == nil {
+		return false
+	}
+
+	// A custom ping handler replaces the pong reply
+	client, server := newConnPair(ephemeralAddr(), ephemeralAddr())
+	custom := make(chan string, 1)
+	client.SetPingHandler(func(appData string) error {
+		custom <- appData
+		return nil
+	})
+	server.WriteMessage(PingMessage, []byte("x"))
+	server.WriteMessage(TextMessage, []byte("y"))
+	client.ReadMessage()
+	server.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, _, err := server.ReadMessage(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		return false
+	}
+	return <-custom == "x"
+}
+
+func testCloseHandshake() bool {
+	closed := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		closed <- err
+	})
+	s := NewServer(mux)
+	defer s.Close()
+
+	conn, _, err := DefaultDialer.Dial(s.URL+"/ws", nil)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(CloseMessage, FormatCloseMessage(CloseGoingAway, "bye")); err != nil {
+		return false
+	}
+	if conn.WriteMessage(TextMessage, []byte("late")) != ErrCloseSent {
+		return false
+	}
+
+	// The server sees the close, and its default handler echoes the code
+	serverErr := <-closed
+	var closeErr *CloseError
+	if !errors.As(serverErr, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Text != "bye" {
+		return false
+	}
+	if serverErr.Error() != "websocket: close 1001 (going away): bye" {
+		return false
+	}
+	_, _, err = conn.ReadMessage()
+	if !IsCloseError(err, CloseGoingAway) || IsUnexpectedCloseError(err, CloseGoingAway, CloseNormalClosure) {
+		return false
+	}
+
+	// Later reads return the same error
+	_, _, again := conn.ReadMessage()
+	return again == err && len(FormatCloseMessage(CloseNoStatusReceived, "x")) == 0
+}
+
+func testAbnormalClosure() bool {
+	ready := make(chan *Conn, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		ready <- conn
+	})
+	s := NewServer(mux)
+	defer s.Close()
+
+	conn, _, err := DefaultDialer.Dial(s.URL+"/ws", nil)
+	if err != nil {
+		return false
+	}
+	server := <-ready
+
+	// The handler returned, but the connection stays open
+	server.WriteMessage(TextMessage, []byte("last words"))
+	server.Close()
+	if server.Close() == nil {
+		return false
+	}
+
+	_, data, err := conn.ReadMessage()
+	if err != nil || string(data) != "last words" {
+		return false
+	}
+	_, _, err = conn.ReadMessage()
+	if !IsUnexpectedCloseError(err, CloseGoingAway, CloseNormalClosure) || !IsCloseError(err, CloseAbnormalClosure) {
+		return false
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("anyone?")); err == nil {
+		return false
+	}
+
+	conn.Close()
+	_, _, err = server.ReadMessage()
+	return errors.Is(err, net.ErrClosed)
+}
+
+func testReadDeadline() bool {
+	s, conn, err := startEcho()
+	if err != nil {
+		return false
+	}
+	defer s.Close()
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	start := time.Now()
+	_, _, err = conn.ReadMessage()
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() || time.Since(start) < 15*time.Millisecond {
+		return false
+	}
+
+	// After a timeout the connection is broken for reading
+	conn.SetReadDeadline(time.Time{})
+	conn.WriteMessage(TextMessage, []byte("too late"))
+	_, _, again := conn.ReadMessage()
+	return again == err
+}
+
+func testWriteDeadline() bool {
+	s, conn, err := startEcho()
+	if err != nil {
+		return false
+	}
+	defer s.Close()
+	defer conn.Close()
+
+	if err := conn.WriteControl(PingMessage, nil, time.Now().Add(-time.Second)); err == nil {
+		return false
+	} else if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		return false
+	}
+	// A control write timing out does not break the connection
+	if err := conn.WriteMessage(TextMessage, []byte("ok")); err != nil {
+		return false
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(-time.Millisecond))
+	err = conn.WriteMessage(TextMessage, []byte("late"))
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		return false
+	}
+	conn.SetWriteDeadline(time.Time{})
+	return conn.WriteMessage(TextMessage, []byte("still broken")) == err
+}
+
+func testReadLimit() bool {
+	result := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(8)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				result <- err
+				return
+			}
+		}
+	})
+	s := NewServer(mux)
+	defer s.Close()
+
+	conn, _, err := DefaultDialer.Dial(s.URL+"/ws", nil)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	conn.WriteMessage(TextMessage, []byte("short"))
+	conn.WriteMessage(TextMessage, []byte("far too long"))
+
+	if <-result != ErrReadLimit {
+		return false
+	}
+	_, _, err = conn.ReadMessage()
+	return IsCloseError(err, CloseMessageTooBig) && err.Error() == "websocket: close 1009 (message too big)"
+}
+
+func testHandshakeErrors() bool {
+	strict := Upgrader{CheckOrigin: func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "https://app.example"
+	}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", echo)
+	mux.HandleFunc("/strict", func(w http.ResponseWriter, r *http.Request) {
+		strict.Upgrade(w, r, nil)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("no upgrade here"))
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	s := NewServer(mux)
+	defer s.Close()
+
+	_, resp, err := DefaultDialer.Dial(s.URL+"/plain", nil)
+	if err != ErrBadHandshake || resp == nil || resp.StatusCode != http.StatusTeapot {
+		return false
+	}
+
+	// Same-origin check by default; CheckOrigin overrides it
+	origin := http.Header{"Origin": {"https://evil.example"}}
+	if _, resp, err := DefaultDialer.Dial(s.URL+"/ws", origin); err != ErrBadHandshake || resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if _, resp, err := DefaultDialer.Dial(s.URL+"/strict", origin); err != ErrBadHandshake || resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	conn, _, err := DefaultDialer.Dial(s.URL+"/strict", http.Header{"Origin": {"https://app.example"}})
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	if _, _, err := DefaultDialer.Dial(s.URL+"/ws", http.Header{"Sec-Websocket-Key": {"x"}}); err == nil ||
+		!strings.Contains(err.Error(), "duplicate header") {
+		return false
+	}
+	if _, _, err := DefaultDialer.Dial("http"+strings.TrimPrefix(s.URL, "ws")+"/ws", nil); err == nil {
+		return false
+	}
+	if _, _, err := DefaultDialer.Dial("ws://nowhere.invalid:9/ws", nil); err == nil ||
+		!strings.Contains(err.Error(), "connection refused") {
+		return false
+	}
+	if _, _, err := DefaultDialer.Dial(s.URL+"/panic", nil); err == nil {
+		return false
+	}
+
+	// Plain requests cannot be upgraded
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/ws", nil)
+	if _, err := upgrader.Upgrade(rec, req, nil); err == nil || rec.Code != http.StatusBadRequest {
+		return false
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", generateChallengeKey())
+	rec = httptest.NewRecorder()
+	_, err = upgrader.Upgrade(rec, req, nil)
+	var he HandshakeError
+	if !errors.As(err, &he) || rec.Code != http.StatusInternalServerError || !IsWebSocketUpgrade(req) {
+		return false
+	}
+	req.Method = "POST"
+	rec = httptest.NewRecorder()
+	upgrader.Upgrade(rec, req, nil)
+	return rec.Code == http.StatusMethodNotAllowed && rec.Header().Get("Sec-Websocket-Version") == "13"
+}
+
+func testSubprotocolsAndHeaders() bool {
+	chat := Upgrader{Subprotocols: []string{"chat.v2", "chat.v1"}}
+	seen := make(chan *http.Request, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		seen <- r
+		header := http.Header{}
+		header.Add("Set-Cookie", "session=abc; Path=/")
+		conn, err := chat.Upgrade(w, r, header)
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(TextMessage, []byte(conn.Subprotocol()))
+		conn.Close()
+	})
+	s := NewServer(mux)
+	defer s.Close()
+
+	jar, _ := cookiejar.New(nil)
+	dialer := Dialer{Subprotocols: []string{"chat.v1", "chat.v2"}, Jar: jar}
+	conn, resp, err := dialer.Dial(s.URL+"/ws?room=go", http.Header{"X-Token": {"t1"}})
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	r := <-seen
+	if r.URL.Query().Get("room") != "go" || r.Header.Get("X-Token") != "t1" ||
+		r.RemoteAddr != conn.LocalAddr().String() || !IsWebSocketUpgrade(r) {
+		return false
+	}
+	if got := Subprotocols(r); len(got) != 2 || got[0] != "chat.v1" {
+		return false
+	}
+
+	// The server's preference wins among protocols both sides support
+	_, data, _ := conn.ReadMessage()
+	if conn.Subprotocol() != "chat.v2" || string(data) != "chat.v2" || resp.StatusCode != http.StatusSwitchingProtocols {
+		return false
+	}
+	u, _ := url.Parse("http" + strings.TrimPrefix(s.URL, "ws"))
+	cookies := jar.Cookies(u)
+	return len(cookies) == 1 && cookies[0].Value == "abc"
+}
+
+func testServerLifecycle() bool {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", echo)
+	s := Listen(":8080", mux)
+	if s.URL != "ws://localhost:8080" {
+		s.Close()
+		return false
+	}
+
+	conn, _, err := DefaultDialer.Dial("ws://LOCALHOST:8080/ws", nil)
+	if err != nil {
+		s.Close()
+		return false
+	}
+	other, _, err := DefaultDialer.Dial(s.URL+"/ws", nil)
+	if err != nil {
+		s.Close()
+		return false
+	}
+	if !eventually(func() bool { return s.NumConns() == 2 }) {
+		return false
+	}
+
+	// A clean close frees the connection on the server
+	other.WriteMessage(CloseMessage, FormatCloseMessage(CloseNormalClosure, ""))
+	other.ReadMessage()
+	other.Close()
+	if !eventually(func() bool { return s.NumConns() == 1 }) {
+		return false
+	}
+
+	s.Close()
+	_, _, err = conn.ReadMessage()
+	if !IsCloseError(err, CloseAbnormalClosure) || s.NumConns() != 0 {
+		return false
+	}
+	_, _, err = DefaultDialer.Dial(s.URL+"/ws", nil)
+	return err != nil
+}
+
+func testHandshakeTimeout() bool {
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		upgrader.Upgrade(w, r, nil)
+	})
+	s := NewServer(mux)
+	defer s.Close()
+	defer close(release)
+
+	dialer := Dialer{HandshakeTimeout: 20 * time.Millisecond}
+	_, _, err := dialer.Dial(s.URL+"/slow", nil)
+	if err == nil {
+		return false
+	}
+	var none *Dialer
+	conn, _, err := none.Dial(s.URL+"/missing", nil)
+	return conn == nil && err == ErrBadHandshake
+}
+
+func testBroadcastHub() bool {
+	// A chat hub: every message from one client goes to all clients
+	var mu sync.Mutex
+	clients := make(map[*Conn]bool)
+	broadcast := func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		for c := range clients {
+			c.WriteMessage(TextMessage, data)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hub", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		clients[conn] = true
+		mu.Unlock()
+		go func() {
+			defer func() {
+				mu.Lock()
+				delete(clients, conn)
+				mu.Unlock()
+				conn.Close()
+			}()
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				broadcast(data)
+			}
+		}()
+	})
+	s := NewServer(mux)
+	defer s.Close()
+
+	const n = 5
+	conns := make([]*Conn, n)
+	for i := range conns {
+		conn, _, err := DefaultDialer.Dial(s.URL+"/hub", nil)
+		if err != nil {
+			return false
+		}
+		conns[i] = conn
+	}
+	if !eventually(func() bool { return s.NumConns() == n }) {
+		return false
+	}
+
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn *Conn) {
+			defer wg.Done()
+			conn.WriteMessage(TextMessage, []byte(fmt.Sprintf("from %d", i)))
+		}(i, conn)
+	}
+	wg.Wait()
+
+	ok := true
+	for _, conn := range conns {
+		got := make(map[string]bool)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		for len(got) < n {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				ok = false
+				break
+			}
+			got[string(data)] = true
+		}
+		conn.Close()
+	}
+	return ok && eventually(func() bool { return s.NumConns() == 0 })
+}
+
+func main() {
+	fmt.Println("Running WebSocket Emulator Tests...")
+	fmt.Println("===================================")
+
+	runTest("Echo", testEcho)
+	runTest("JSON", testJSON)
+	runTest("Next Reader And Writer", testNextReaderWriter)
+	runTest("Ping Pong", testPingPong)
+	runTest("Close Handshake", testCloseHandshake)
+	runTest("Abnormal Closure", testAbnormalClosure)
+	runTest("Read Deadline", testReadDeadline)
+	runTest("Write Deadline", testWriteDeadline)
+	runTest("Read Limit", testReadLimit)
+	runTest("Handshake Errors", testHandshakeErrors)
+	runTest("Subprotocols And Headers", testSubprotocolsAndHeaders)
+	runTest("Server Lifecycle", testServerLifecycle)
+	runTest("Handshake Timeout", testHandshakeTimeout)
+	runTest("Broadcast Hub", testBroadcastHub)
+
+	fmt.Println("===================================")
+	fmt.Println("All tests completed!")
+}
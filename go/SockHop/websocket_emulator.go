@@ -0,0 +1,1214 @@
+package main
+
+// Developed by PowerShield, as an alternative to gorilla/websocket
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Message types, as in RFC 6455
+const (
+	// TextMessage denotes a text data message. The text message payload is
+	// interpreted as UTF-8 encoded text data.
+	TextMessage = 1
+	// BinaryMessage denotes a binary data message
+	BinaryMessage = 2
+	// CloseMessage denotes a close control message. The optional payload
+	// holds a close code and text; use FormatCloseMessage to build it.
+	CloseMessage = 8
+	// PingMessage denotes a ping control message
+	PingMessage = 9
+	// PongMessage denotes a pong control message
+	PongMessage = 10
+)
+
+// Close codes, as in RFC 6455 section 11.7
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseServiceRestart          = 1012
+	CloseTryAgainLater           = 1013
+	CloseTLSHandshake            = 1015
+)
+
+// maxControlFramePayloadSize is the largest ping, pong or close payload
+const maxControlFramePayloadSize = 125
+
+// writeWait is the deadline default handlers use for their replies
+const writeWait = time.Second
+
+// noFrame is the message type returned with read errors
+const noFrame = -1
+
+var (
+	// ErrBadHandshake is returned by Dial when the server's response is
+	// not a valid upgrade. The response is returned with the error.
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	// ErrCloseSent is returned when writing after a close message was sent
+	ErrCloseSent = errors.New("websocket: close sent")
+	// ErrReadLimit is returned when reading a message larger than the
+	// limit set with SetReadLimit
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+
+	errBadWriteOpCode      = errors.New("websocket: bad write message type")
+	errWriteClosed         = errors.New("websocket: write closed")
+	errInvalidControlFrame = errors.New("websocket: invalid control frame")
+	errMalformedURL        = errors.New("malformed ws or wss URL")
+	errWriteTimeout        = &netError{msg: "websocket: write timeout", timeout: true, temporary: true}
+)
+
+// netError is a net.Error for timeouts raised by the connection itself
+type netError struct {
+	msg       string
+	temporary bool
+	timeout   bool
+}
+
+func (e *netError) Error() string   { return e.msg }
+func (e *netError) Temporary() bool { return e.temporary }
+func (e *netError) Timeout() bool   { return e.timeout }
+
+// CloseError is returned by reads once the peer closes the connection
+type CloseError struct {
+	// Code is defined in RFC 6455, section 11.7
+	Code int
+	// Text is the optional text payload
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	s := []byte("websocket: close ")
+	s = strconv.AppendInt(s, int64(e.Code), 10)
+	switch e.Code {
+	case CloseNormalClosure:
+		s = append(s, " (normal)"...)
+	case CloseGoingAway:
+		s = append(s, " (going away)"...)
+	case CloseProtocolError:
+		s = append(s, " (protocol error)"...)
+	case CloseUnsupportedData:
+		s = append(s, " (unsupported data)"...)
+	case CloseNoStatusReceived:
+		s = append(s, " (no status)"...)
+	case CloseAbnormalClosure:
+		s = append(s, " (abnormal closure)"...)
+	case CloseInvalidFramePayloadData:
+		s = append(s, " (invalid payload data)"...)
+	case ClosePolicyViolation:
+		s = append(s, " (policy violation)"...)
+	case CloseMessageTooBig:
+		s = append(s, " (message too big)"...)
+	case CloseMandatoryExtension:
+		s = append(s, " (mandatory extension missing)"...)
+	case CloseInternalServerErr:
+		s = append(s, " (internal server error)"...)
+	case CloseTLSHandshake:
+		s = append(s, " (TLS handshake error)"...)
+	}
+	if e.Text != "" {
+		s = append(s, ": "...)
+		s = append(s, e.Text...)
+	}
+	return string(s)
+}
+
+// IsCloseError reports whether err is a *CloseError with one of codes
+func IsCloseError(err error, codes ...int) bool {
+	var e *CloseError
+	if errors.As(err, &e) {
+		for _, code := range codes {
+			if e.Code == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsUnexpectedCloseError reports whether err is a *CloseError with a code
+// not in expectedCodes
+func IsUnexpectedCloseError(err error, expectedCodes ...int) bool {
+	var e *CloseError
+	if errors.As(err, &e) {
+		for _, code := range expectedCodes {
+			if e.Code == code {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// FormatCloseMessage formats closeCode and text as a close message
+// payload. CloseNoStatusReceived gives an empty payload.
+func FormatCloseMessage(closeCode int, text string) []byte {
+	if closeCode == CloseNoStatusReceived {
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(closeCode))
+	copy(buf[2:], text)
+	return buf
+}
+
+// HandshakeError describes an error with the handshake from the peer
+type HandshakeError struct {
+	message string
+}
+
+func (e HandshakeError) Error() string { return e.message }
+
+func isControl(messageType int) bool {
+	return messageType == CloseMessage || messageType == PingMessage || messageType == PongMessage
+}
+
+func isData(messageType int) bool {
+	return messageType == TextMessage || messageType == BinaryMessage
+}
+
+// Transport
+
+type frame struct {
+	messageType int
+	data        []byte
+}
+
+// pipe carries frames in one direction. Closing it drops the transport:
+// the reader drains what was sent and then sees EOF.
+type pipe struct {
+	mu     sync.Mutex
+	frames []frame
+	closed bool
+	ready  chan struct{} // signalled when a frame arrives or the pipe closes
+}
+
+func newPipe() *pipe {
+	return &pipe{ready: make(chan struct{}, 1)}
+}
+
+func (p *pipe) signal() {
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (p *pipe) push(f frame) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.frames = append(p.frames, f)
+	p.signal()
+	return true
+}
+
+// pop waits for the next frame until deadline or until done is closed.
+// Errors are io.EOF, os.ErrDeadlineExceeded and net.ErrClosed.
+func (p *pipe) pop(deadline time.Time, done <-chan struct{}) (frame, error) {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return frame{}, os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		select {
+		case <-done:
+			return frame{}, net.ErrClosed
+		default:
+		}
+		p.mu.Lock()
+		if len(p.frames) > 0 {
+			f := p.frames[0]
+			p.frames = p.frames[1:]
+			p.mu.Unlock()
+			return f, nil
+		}
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			return frame{}, io.EOF
+		}
+		select {
+		case <-p.ready:
+		case <-done:
+			return frame{}, net.ErrClosed
+		case <-timeout:
+			return frame{}, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+func (p *pipe) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.signal()
+	p.mu.Unlock()
+}
+
+// memAddr is the address of one end of an in-memory connection
+type memAddr string
+
+func (a memAddr) Network() string { return "tcp" }
+func (a memAddr) String() string  { return string(a) }
+
+// nextPort numbers the ephemeral ports of clients and test servers
+var nextPort uint32 = 49151
+
+func ephemeralAddr() memAddr {
+	return memAddr("127.0.0.1:" + strconv.Itoa(int(atomic.AddUint32(&nextPort, 1))))
+}
+
+// Conn
+
+// Conn is a WebSocket connection. Connections are in-memory: clients come
+// from Dialer and servers from Upgrader.
+//
+// A Conn supports one concurrent reader and one concurrent writer. Close,
+// WriteControl and the Set*Deadline methods may be called concurrently
+// with all other methods.
+type Conn struct {
+	isServer    bool
+	subprotocol string
+	localAddr   net.Addr
+	remoteAddr  net.Addr
+
+	in        *pipe
+	out       *pipe
+	closed    chan struct{}
+	closeOnce sync.Once
+	onClose   func()
+
+	// Writer state
+	writeMu       sync.Mutex
+	writeDeadline time.Time
+	writeErr      error
+	writer        *messageWriter
+
+	// Reader state; readErr is only touched by the reading goroutine
+	mu           sync.Mutex
+	readDeadline time.Time
+	readLimit    int64
+	readErr      error
+	handlePing   func(appData string) error
+	handlePong   func(appData string) error
+	handleClose  func(code int, text string) error
+}
+
+// newConnPair connects a client at clientAddr to a server at serverAddr
+func newConnPair(clientAddr, serverAddr net.Addr) (client, server *Conn) {
+	toServer, toClient := newPipe(), newPipe()
+	client = newConn(false, toClient, toServer, clientAddr, serverAddr)
+	server = newConn(true, toServer, toClient, serverAddr, clientAddr)
+	return client, server
+}
+
+func newConn(isServer bool, in, out *pipe, localAddr, remoteAddr net.Addr) *Conn {
+	c := &Conn{
+		isServer:   isServer,
+		localAddr:  localAddr,
+		remoteAddr: remoteAddr,
+		in:         in,
+		out:        out,
+		closed:     make(chan struct{}),
+	}
+	c.SetPingHandler(nil)
+	c.SetPongHandler(nil)
+	c.SetCloseHandler(nil)
+	return c
+}
+
+// Subprotocol returns the negotiated protocol for the connection
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// LocalAddr returns the local network address
+func (c *Conn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+// RemoteAddr returns the remote network address
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// Close drops the connection without sending or waiting for a close
+// message; the peer reads a CloseError with CloseAbnormalClosure. To close
+// cleanly, write a CloseMessage first and wait for the peer's reply.
+func (c *Conn) Close() error {
+	err := c.opError("close", net.ErrClosed)
+	c.closeOnce.Do(func() {
+		err = nil
+		close(c.closed)
+		c.out.close()
+		c.in.close()
+		if c.onClose != nil {
+			c.onClose()
+		}
+	})
+	return err
+}
+
+func (c *Conn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "tcp", Source: c.localAddr, Addr: c.remoteAddr, Err: err}
+}
+
+func (c *Conn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Write methods
+
+// send queues a frame for the peer, failing if deadline has passed. The
+// caller holds writeMu.
+func (c *Conn) send(messageType int, data []byte, deadline time.Time) error {
+	if c.isClosed() {
+		return c.opError("write", net.ErrClosed)
+	}
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return c.opError("write", os.ErrDeadlineExceeded)
+	}
+	if !c.out.push(frame{messageType: messageType, data: append([]byte(nil), data...)}) {
+		return c.opError("write", syscall.EPIPE)
+	}
+	return nil
+}
+
+// writeData sends a data message; any failure breaks the writer
+func (c *Conn) writeData(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	if err := c.send(messageType, data, c.writeDeadline); err != nil {
+		c.writeErr = err
+		return err
+	}
+	return nil
+}
+
+// WriteControl writes a close, ping or pong message with the given
+// deadline. The payload is at most 125 bytes.
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if !isControl(messageType) {
+		return errBadWriteOpCode
+	}
+	if len(data) > maxControlFramePayloadSize {
+		return errInvalidControlFrame
+	}
+	if !deadline.IsZero() && time.Until(deadline) < 0 {
+		return errWriteTimeout
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+	if err := c.send(messageType, data, time.Time{}); err != nil {
+		c.writeErr = err
+		return err
+	}
+	if messageType == CloseMessage {
+		c.writeErr = ErrCloseSent
+	}
+	return nil
+}
+
+// NextWriter returns a writer for the next text or binary message. The
+// message is sent when the writer is closed; calling NextWriter again
+// closes the previous writer.
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if c.writer != nil {
+		c.writer.Close()
+		c.writer = nil
+	}
+	if !isData(messageType) {
+		return nil, errBadWriteOpCode
+	}
+	c.writeMu.Lock()
+	err := c.writeErr
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	c.writer = &messageWriter{c: c, messageType: messageType}
+	return c.writer, nil
+}
+
+// messageWriter buffers a message until Close
+type messageWriter struct {
+	c           *Conn
+	messageType int
+	buf         []byte
+	closed      bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errWriteClosed
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+func (w *messageWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return errWriteClosed
+	}
+	w.closed = true
+	if w.c.writer == w {
+		w.c.writer = nil
+	}
+	return w.c.writeData(w.messageType, w.buf)
+}
+
+// WriteMessage is a helper for NextWriter, Write and Close. Control
+// messages are sent with WriteControl and the write deadline.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if isControl(messageType) {
+		c.writeMu.Lock()
+		deadline := c.writeDeadline
+		c.writeMu.Unlock()
+		return c.WriteControl(messageType, data, deadline)
+	}
+	w, err := c.NextWriter(messageType)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// WriteJSON writes the JSON encoding of v as a text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	w, err := c.NextWriter(TextMessage)
+	if err != nil {
+		return err
+	}
+	err1 := json.NewEncoder(w).Encode(v)
+	err2 := w.Close()
+	if err1 != nil {
+		return err1
+	}
+	return err2
+}
+
+// SetWriteDeadline sets the write deadline. After a write has timed out
+// the connection is corrupt and all future writes return an error. A
+// zero value means writes do not time out.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeMu.Lock()
+	c.writeDeadline = t
+	c.writeMu.Unlock()
+	return nil
+}
+
+// Read methods
+
+// NextReader returns the next data message. Control messages are passed
+// to their handlers while waiting for it. After a read error, including a
+// *CloseError once the peer closes, all later reads return the same error.
+func (c *Conn) NextReader() (messageType int, r io.Reader, err error) {
+	for c.readErr == nil {
+		c.mu.Lock()
+		deadline, limit := c.readDeadline, c.readLimit
+		c.mu.Unlock()
+
+		f, err := c.in.pop(deadline, c.closed)
+		if err != nil {
+			c.readErr = c.readError(err)
+			break
+		}
+
+		switch f.messageType {
+		case TextMessage, BinaryMessage:
+			if limit > 0 && int64(len(f.data)) > limit {
+				c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""), time.Now().Add(writeWait))
+				c.readErr = ErrReadLimit
+				break
+			}
+			return f.messageType, bytes.NewReader(f.data), nil
+		case PingMessage:
+			if err := c.PingHandler()(string(f.data)); err != nil {
+				c.readErr = err
+			}
+		case PongMessage:
+			if err := c.PongHandler()(string(f.data)); err != nil {
+				c.readErr = err
+			}
+		case CloseMessage:
+			closeCode, closeText := CloseNoStatusReceived, ""
+			if len(f.data) >= 2 {
+				closeCode = int(binary.BigEndian.Uint16(f.data))
+				closeText = string(f.data[2:])
+			}
+			if err := c.CloseHandler()(closeCode, closeText); err != nil {
+				c.readErr = err
+			} else {
+				c.readErr = &CloseError{Code: closeCode, Text: closeText}
+			}
+		}
+	}
+	return noFrame, nil, c.readErr
+}
+
+// readError converts a transport error to the error reads return
+func (c *Conn) readError(err error) error {
+	if err == io.EOF {
+		return &CloseError{Code: CloseAbnormalClosure, Text: io.ErrUnexpectedEOF.Error()}
+	}
+	return c.opError("read", err)
+}
+
+// ReadMessage is a helper for NextReader that reads the whole message
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	var r io.Reader
+	messageType, r, err = c.NextReader()
+	if err != nil {
+		return messageType, nil, err
+	}
+	p, err = io.ReadAll(r)
+	return messageType, p, err
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, r, err := c.NextReader()
+	if err != nil {
+		return err
+	}
+	err = json.NewDecoder(r).Decode(v)
+	if err == io.EOF {
+		// One value is expected in the message.
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// SetReadDeadline sets the read deadline. After a read has timed out the
+// connection is corrupt and all future reads return an error. A zero
+// value means reads do not time out. Handlers may extend the deadline,
+// e.g. on each pong.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+// SetReadLimit sets the largest message read from the peer. Larger
+// messages make the connection send CloseMessageTooBig and return
+// ErrReadLimit.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.mu.Lock()
+	c.readLimit = limit
+	c.mu.Unlock()
+}
+
+// CloseHandler returns the current close handler
+func (c *Conn) CloseHandler() func(code int, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handleClose
+}
+
+// SetCloseHandler sets the handler for close messages. The default, used
+// when h is nil, replies with a close message carrying the same code.
+// After the handler returns, reads return a *CloseError.
+func (c *Conn) SetCloseHandler(h func(code int, text string) error) {
+	if h == nil {
+		h = func(code int, text string) error {
+			message := FormatCloseMessage(code, "")
+			c.WriteControl(CloseMessage, message, time.Now().Add(writeWait))
+			return nil
+		}
+	}
+	c.mu.Lock()
+	c.handleClose = h
+	c.mu.Unlock()
+}
+
+// PingHandler returns the current ping handler
+func (c *Conn) PingHandler() func(appData string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handlePing
+}
+
+// SetPingHandler sets the handler for pings. The default, used when h is
+// nil, replies with a pong carrying the same data.
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(message string) error {
+			err := c.WriteControl(PongMessage, []byte(message), time.Now().Add(writeWait))
+			if err == ErrCloseSent {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				return nil
+			}
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.handlePing = h
+	c.mu.Unlock()
+}
+
+// PongHandler returns the current pong handler
+func (c *Conn) PongHandler() func(appData string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.handlePong
+}
+
+// SetPongHandler sets the handler for pongs. The default, used when h is
+// nil, does nothing.
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	if h == nil {
+		h = func(string) error { return nil }
+	}
+	c.mu.Lock()
+	c.handlePong = h
+	c.mu.Unlock()
+}
+
+// Handshake
+
+// keyGUID is appended to Sec-WebSocket-Key to form the accept value
+var keyGUID = []byte("258EAFA5-E914-47DA-95CA-C5AB0DC85B11")
+
+func computeAcceptKey(challengeKey string) string {
+	h := sha1.New()
+	h.Write([]byte(challengeKey))
+	h.Write(keyGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func generateChallengeKey() string {
+	p := make([]byte, 16)
+	rand.Read(p)
+	return base64.StdEncoding.EncodeToString(p)
+}
+
+func isValidChallengeKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	return err == nil && len(decoded) == 16
+}
+
+// tokenListContainsValue reports whether the comma-separated header name
+// contains value, ignoring case
+func tokenListContainsValue(header http.Header, name, value string) bool {
+	for _, s := range header[http.CanonicalHeaderKey(name)] {
+		for _, token := range strings.Split(s, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsWebSocketUpgrade reports whether the client requested an upgrade to
+// the WebSocket protocol
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return tokenListContainsValue(r.Header, "Connection", "upgrade") &&
+		tokenListContainsValue(r.Header, "Upgrade", "websocket")
+}
+
+// Subprotocols returns the subprotocols requested by the client in the
+// Sec-Websocket-Protocol header
+func Subprotocols(r *http.Request) []string {
+	h := strings.TrimSpace(r.Header.Get("Sec-Websocket-Protocol"))
+	if h == "" {
+		return nil
+	}
+	protocols := strings.Split(h, ",")
+	for i := range protocols {
+		protocols[i] = strings.TrimSpace(protocols[i])
+	}
+	return protocols
+}
+
+// checkSameOrigin allows requests without an Origin header and requests
+// whose Origin host matches the Host header
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header["Origin"]
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(origin[0])
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// pendingKey is the request-context key holding the server end of a
+// connection being dialed
+type pendingKey struct{}
+
+// pendingConn is a dialed connection waiting for the handler to upgrade
+type pendingConn struct {
+	conn      *Conn
+	server    *Server
+	handshake chan *http.Response
+}
+
+// Upgrader upgrades HTTP requests to WebSocket connections. It is safe to
+// call its methods concurrently.
+type Upgrader struct {
+	// HandshakeTimeout is accepted for compatibility; in-memory
+	// handshakes complete immediately
+	HandshakeTimeout time.Duration
+
+	// ReadBufferSize and WriteBufferSize are accepted for compatibility;
+	// messages are never fragmented
+	ReadBufferSize, WriteBufferSize int
+
+	// Subprotocols lists the server's supported protocols in order of
+	// preference. If set, the first one the client also requested is
+	// selected; otherwise the Sec-Websocket-Protocol response header is
+	// used.
+	Subprotocols []string
+
+	// Error writes handshake errors. If nil, http.Error is used.
+	Error func(w http.ResponseWriter, r *http.Request, status int, reason error)
+
+	// CheckOrigin returns true if the request Origin header is
+	// acceptable. If nil, requests are allowed when there is no Origin
+	// header or its host matches the Host header.
+	CheckOrigin func(r *http.Request) bool
+}
+
+func (u *Upgrader) returnError(w http.ResponseWriter, r *http.Request, status int, reason string) (*Conn, error) {
+	err := HandshakeError{reason}
+	if u.Error != nil {
+		u.Error(w, r, status, err)
+	} else {
+		w.Header().Set("Sec-Websocket-Version", "13")
+		http.Error(w, http.StatusText(status), status)
+	}
+	return nil, err
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request, responseHeader http.Header) string {
+	if u.Subprotocols != nil {
+		clientProtocols := Subprotocols(r)
+		for _, serverProtocol := range u.Subprotocols {
+			for _, clientProtocol := range clientProtocols {
+				if clientProtocol == serverProtocol {
+					return clientProtocol
+				}
+			}
+		}
+	} else if responseHeader != nil {
+		return responseHeader.Get("Sec-Websocket-Protocol")
+	}
+	return ""
+}
+
+// Upgrade upgrades the request to a WebSocket connection. responseHeader
+// is included in the 101 response, e.g. for Set-Cookie; headers set on w
+// are not. On failure Upgrade replies with an HTTP error.
+//
+// Only requests made by a Dialer can be upgraded: others fail with 500 as
+// a ResponseWriter without http.Hijacker would.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	const badHandshake = "websocket: the client is not using the websocket protocol: "
+
+	if !tokenListContainsValue(r.Header, "Connection", "upgrade") {
+		return u.returnError(w, r, http.StatusBadRequest, badHandshake+"'upgrade' token not found in 'Connection' header")
+	}
+	if !tokenListContainsValue(r.Header, "Upgrade", "websocket") {
+		return u.returnError(w, r, http.StatusBadRequest, badHandshake+"'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Method != http.MethodGet {
+		return u.returnError(w, r, http.StatusMethodNotAllowed, badHandshake+"request method is not GET")
+	}
+	if !tokenListContainsValue(r.Header, "Sec-Websocket-Version", "13") {
+		return u.returnError(w, r, http.StatusBadRequest, "websocket: unsupported version: 13 not found in 'Sec-Websocket-Version' header")
+	}
+	if _, ok := responseHeader["Sec-Websocket-Extensions"]; ok {
+		return u.returnError(w, r, http.StatusInternalServerError, "websocket: application specific 'Sec-WebSocket-Extensions' headers are unsupported")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return u.returnError(w, r, http.StatusForbidden, "websocket: request origin not allowed by Upgrader.CheckOrigin")
+	}
+
+	challengeKey := r.Header.Get("Sec-Websocket-Key")
+	if !isValidChallengeKey(challengeKey) {
+		return u.returnError(w, r, http.StatusBadRequest, "websocket: not a websocket handshake: 'Sec-WebSocket-Key' header must be Base64 encoded value of 16-byte in length")
+	}
+
+	subprotocol := u.selectSubprotocol(r, responseHeader)
+
+	pending, _ := r.Context().Value(pendingKey{}).(*pendingConn)
+	if pending == nil {
+		return u.returnError(w, r, http.StatusInternalServerError, "websocket: response does not implement http.Hijacker")
+	}
+
+	header := http.Header{}
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", computeAcceptKey(challengeKey))
+	if subprotocol != "" {
+		header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	for key, values := range responseHeader {
+		if http.CanonicalHeaderKey(key) == "Sec-Websocket-Protocol" {
+			continue
+		}
+		header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+	resp := &http.Response{
+		Status:     "101 Switching Protocols",
+		StatusCode: http.StatusSwitchingProtocols,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       http.NoBody,
+	}
+
+	pending.conn.subprotocol = subprotocol
+	pending.server.track(pending.conn)
+	select {
+	case pending.handshake <- resp:
+	default:
+		return u.returnError(w, r, http.StatusInternalServerError, "http: connection has been hijacked")
+	}
+	return pending.conn, nil
+}
+
+// Servers
+
+var (
+	serversMu sync.Mutex
+	servers   = make(map[string]*Server)
+)
+
+// Server serves an http.Handler in memory, so Dialer reaches its
+// WebSocket endpoints by URL without a network. Any handler works: a
+// ServeMux, a mux.Router or a Gin engine.
+type Server struct {
+	// URL is the base URL of the server, e.g. ws://127.0.0.1:49152
+	URL string
+
+	handler http.Handler
+	addr    memAddr
+	mu      sync.Mutex
+	conns   map[*Conn]struct{}
+}
+
+// NewServer starts an in-memory server for handler on an unused loopback
+// port, as httptest.NewServer does
+func NewServer(handler http.Handler) *Server {
+	return Listen(ephemeralAddr().String(), handler)
+}
+
+// Listen starts an in-memory server for handler at addr, such as
+// "localhost:8080" or ":8080", replacing any server already there
+func Listen(addr string, handler http.Handler) *Server {
+	key := hostPort(addr, "ws")
+	s := &Server{
+		URL:     "ws://" + key,
+		handler: handler,
+		addr:    memAddr(key),
+		conns:   make(map[*Conn]struct{}),
+	}
+	serversMu.Lock()
+	servers[key] = s
+	serversMu.Unlock()
+	return s
+}
+
+// hostPort normalizes addr to host:port, defaulting the host to localhost
+// and the port to the scheme's
+func hostPort(addr, scheme string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, "80"
+		if scheme == "wss" || scheme == "https" {
+			port = "443"
+		}
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+func lookupServer(key string) *Server {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	return servers[key]
+}
+
+// NumConns returns the number of open connections to the server
+func (s *Server) NumConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Close makes the server unreachable and drops its open connections
+func (s *Server) Close() {
+	key := s.addr.String()
+	serversMu.Lock()
+	if servers[key] == s {
+		delete(servers, key)
+	}
+	serversMu.Unlock()
+
+	s.mu.Lock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// track counts an upgraded connection until it is closed
+func (s *Server) track(conn *Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !conn.isClosed() {
+		s.conns[conn] = struct{}{}
+	}
+}
+
+func (s *Server) untrack(conn *Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+// Dialer
+
+// Dialer contains options for connecting to a WebSocket server
+type Dialer struct {
+	// HandshakeTimeout is the time allowed for the handler to upgrade
+	HandshakeTimeout time.Duration
+
+	// ReadBufferSize and WriteBufferSize are accepted for compatibility;
+	// messages are never fragmented
+	ReadBufferSize, WriteBufferSize int
+
+	// Subprotocols lists the client's requested subprotocols
+	Subprotocols []string
+
+	// Jar receives cookies set by the handshake response and supplies
+	// cookies for the request. If nil, cookies are not sent or kept.
+	Jar http.CookieJar
+}
+
+// DefaultDialer is a dialer with all fields set to their defaults
+var DefaultDialer = &Dialer{
+	HandshakeTimeout: 45 * time.Second,
+}
+
+var nilDialer = *DefaultDialer
+
+// Dial is DialContext with a background context
+func (d *Dialer) Dial(urlStr string, requestHeader http.Header) (*Conn, *http.Response, error) {
+	return d.DialContext(context.Background(), urlStr, requestHeader)
+}
+
+// DialContext connects to the server at urlStr, a ws:// or wss:// URL of
+// a Server, and performs the opening handshake. The server's handler runs
+// on its own goroutine; the connection stays open after it returns.
+//
+// If the handler does not upgrade, the error is ErrBadHandshake and the
+// response holds the handler's status, headers and body.
+func (d *Dialer) DialContext(ctx context.Context, urlStr string, requestHeader http.Header) (*Conn, *http.Response, error) {
+	if d == nil {
+		d = &nilDialer
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return nil, nil, errMalformedURL
+	}
+	if u.User != nil {
+		// User name and password are not allowed in websocket URIs.
+		return nil, nil, errMalformedURL
+	}
+
+	challengeKey := generateChallengeKey()
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	if d.Jar != nil {
+		for _, cookie := range d.Jar.Cookies(u) {
+			req.AddCookie(cookie)
+		}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", challengeKey)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if len(d.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(d.Subprotocols, ", "))
+	}
+	for key, values := range requestHeader {
+		switch key = http.CanonicalHeaderKey(key); {
+		case key == "Host":
+			if len(values) > 0 {
+				req.Host = values[0]
+			}
+		case key == "Upgrade" || key == "Connection" || key == "Sec-Websocket-Key" ||
+			key == "Sec-Websocket-Version" || key == "Sec-Websocket-Extensions" ||
+			(key == "Sec-Websocket-Protocol" && len(d.Subprotocols) > 0):
+			return nil, nil, errors.New("websocket: duplicate header not allowed: " + key)
+		default:
+			req.Header[key] = append([]string(nil), values...)
+		}
+	}
+
+	if d.HandshakeTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.HandshakeTimeout)
+		defer cancel()
+	}
+
+	hostport := hostPort(u.Host, u.Scheme)
+	server := lookupServer(hostport)
+	if server == nil {
+		return nil, nil, fmt.Errorf("dial tcp %s: connect: connection refused", hostport)
+	}
+
+	clientConn, serverConn := newConnPair(ephemeralAddr(), server.addr)
+	serverConn.onClose = func() { server.untrack(serverConn) }
+	pending := &pendingConn{conn: serverConn, server: server, handshake: make(chan *http.Response, 1)}
+	serverReq := req.Clone(context.WithValue(context.Background(), pendingKey{}, pending))
+	serverReq.RemoteAddr = clientConn.localAddr.String()
+	serverReq.RequestURI = u.RequestURI()
+	serverReq.URL = &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+	serverReq.Body = http.NoBody
+
+	rec := httptest.NewRecorder()
+	done := make(chan bool, 1)
+	go func() {
+		panicked := true
+		defer func() {
+			// As net/http does, a panicking handler only loses its
+			// connection
+			recover()
+			done <- panicked
+		}()
+		server.handler.ServeHTTP(rec, serverReq)
+		panicked = false
+	}()
+
+	var resp *http.Response
+	select {
+	case resp = <-pending.handshake:
+	case panicked := <-done:
+		select {
+		case resp = <-pending.handshake:
+		default:
+			if panicked {
+				return nil, nil, io.ErrUnexpectedEOF
+			}
+			resp = rec.Result()
+			resp.Request = req
+			return nil, resp, ErrBadHandshake
+		}
+	case <-ctx.Done():
+		serverConn.Close()
+		return nil, nil, ctx.Err()
+	}
+	resp.Request = req
+
+	if d.Jar != nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			d.Jar.SetCookies(u, cookies)
+		}
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!tokenListContainsValue(resp.Header, "Upgrade", "websocket") ||
+		!tokenListContainsValue(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-Websocket-Accept") != computeAcceptKey(challengeKey) {
+		serverConn.Close()
+		return nil, resp, ErrBadHandshake
+	}
+
+	clientConn.subprotocol = resp.Header.Get("Sec-Websocket-Protocol")
+	return clientConn, resp, nil
+}
@@ -0,0 +1,482 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang.org/x/time/rate
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a rate of events per second
+type Limit float64
+
+// Inf is the infinite rate limit; it allows all events, even with a
+// zero burst
+const Inf = Limit(math.MaxFloat64)
+
+// InfDuration is the delay of a reservation that can never be met
+const InfDuration = time.Duration(math.MaxInt64)
+
+// Every converts the minimum time between events to a Limit
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+// durationFromTokens is how long it takes to accumulate tokens
+func (limit Limit) durationFromTokens(tokens float64) time.Duration {
+	if limit <= 0 {
+		return InfDuration
+	}
+	duration := (tokens / float64(limit)) * float64(time.Second)
+	// Cap the duration to the maximum representable int64 value
+	if duration > float64(math.MaxInt64) {
+		return InfDuration
+	}
+	return time.Duration(duration)
+}
+
+// tokensFromDuration is how many tokens accumulate in d
+func (limit Limit) tokensFromDuration(d time.Duration) float64 {
+	if limit <= 0 {
+		return 0
+	}
+	return d.Seconds() * float64(limit)
+}
+
+// Limiter controls how often events may happen with a token bucket. The
+// bucket holds up to burst tokens, starts full and refills at limit
+// tokens per second; each event takes one. A Limiter is safe for
+// concurrent use.
+type Limiter struct {
+	mu     sync.Mutex
+	limit  Limit
+	burst  int
+	tokens float64
+	// last is when tokens was last updated
+	last time.Time
+	// lastEvent is the latest time of a rate-limited event, past or future
+	lastEvent time.Time
+}
+
+// NewLimiter returns a Limiter allowing events at rate r, with bursts of
+// at most b events
+func NewLimiter(r Limit, b int) *Limiter {
+	return &Limiter{
+		limit:  r,
+		burst:  b,
+		tokens: float64(b),
+	}
+}
+
+// Limit returns the maximum overall event rate
+func (lim *Limiter) Limit() Limit {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.limit
+}
+
+// Burst returns the maximum number of events at once
+func (lim *Limiter) Burst() int {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return lim.burst
+}
+
+// TokensAt returns the number of tokens available at time t; it is
+// negative while reservations wait for tokens
+func (lim *Limiter) TokensAt(t time.Time) float64 {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	_, tokens := lim.advance(t)
+	return tokens
+}
+
+// Tokens returns the number of tokens available now
+func (lim *Limiter) Tokens() float64 {
+	return lim.TokensAt(time.Now())
+}
+
+// Allow reports whether an event may happen now, taking a token if so
+func (lim *Limiter) Allow() bool {
+	return lim.AllowN(time.Now(), 1)
+}
+
+// AllowN reports whether n events may happen at time t, taking n tokens
+// if so. Use it to drop or skip events exceeding the rate.
+func (lim *Limiter) AllowN(t time.Time, n int) bool {
+	return lim.reserveN(t, n, 0).ok
+}
+
+// Reserve is ReserveN(time.Now(), 1)
+func (lim *Limiter) Reserve() *Reservation {
+	return lim.ReserveN(time.Now(), 1)
+}
+
+// ReserveN takes n tokens, possibly from the future, and returns a
+// Reservation saying how long to wait before the n events may happen.
+// The reservation is not OK if n exceeds the burst. Use it to wait and
+// slow down, or to Cancel if the wait is too long.
+func (lim *Limiter) ReserveN(t time.Time, n int) *Reservation {
+	r := lim.reserveN(t, n, InfDuration)
+	return &r
+}
+
+// Wait is WaitN(ctx, 1)
+func (lim *Limiter) Wait(ctx context.Context) error {
+	return lim.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events may happen. It fails if n exceeds the
+// burst, if ctx is done first, or if the wait would outlast ctx's
+// deadline.
+func (lim *Limiter) WaitN(ctx context.Context, n int) error {
+	lim.mu.Lock()
+	burst := lim.burst
+	limit := lim.limit
+	lim.mu.Unlock()
+
+	if n > burst && limit != Inf {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst %d", n, burst)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	now := time.Now()
+	waitLimit := InfDuration
+	if deadline, ok := ctx.Deadline(); ok {
+		waitLimit = deadline.Sub(now)
+	}
+	r := lim.reserveN(now, n, waitLimit)
+	if !r.ok {
+		return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", n)
+	}
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give back the tokens, so that other events can use them
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// SetLimit is SetLimitAt(time.Now(), newLimit)
+func (lim *Limiter) SetLimit(newLimit Limit) {
+	lim.SetLimitAt(time.Now(), newLimit)
+}
+
+// SetLimitAt changes the limit from time t on. Reservations already
+// made keep their delays.
+func (lim *Limiter) SetLimitAt(t time.Time, newLimit Limit) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	t, tokens := lim.advance(t)
+	lim.last = t
+	lim.tokens = tokens
+	lim.limit = newLimit
+}
+
+// SetBurst is SetBurstAt(time.Now(), newBurst)
+func (lim *Limiter) SetBurst(newBurst int) {
+	lim.SetBurstAt(time.Now(), newBurst)
+}
+
+// SetBurstAt changes the burst from time t on
+func (lim *Limiter) SetBurstAt(t time.Time, newBurst int) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	t, tokens := lim.advance(t)
+	lim.last = t
+	lim.tokens = tokens
+	lim.burst = newBurst
+}
+
+// reserveN takes n tokens at time t if they are available within
+// maxFutureReserve
+func (lim *Limiter) reserveN(t time.Time, n int, maxFutureReserve time.Duration) Reservation {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+
+	if lim.limit == Inf {
+		return Reservation{ok: true, lim: lim, tokens: n, timeToAct: t}
+	}
+	if lim.limit == 0 {
+		// Without refills the burst is all there will ever be
+		ok := lim.burst >= n
+		if ok {
+			lim.burst -= n
+		}
+		return Reservation{ok: ok, lim: lim, tokens: lim.burst, timeToAct: t}
+	}
+
+	t, tokens := lim.advance(t)
+	tokens -= float64(n)
+	var waitDuration time.Duration
+	if tokens < 0 {
+		waitDuration = lim.limit.durationFromTokens(-tokens)
+	}
+	ok := n <= lim.burst && waitDuration <= maxFutureReserve
+
+	r := Reservation{ok: ok, lim: lim, limit: lim.limit}
+	if ok {
+		r.tokens = n
+		r.timeToAct = t.Add(waitDuration)
+		lim.last = t
+		lim.tokens = tokens
+		lim.lastEvent = r.timeToAct
+	}
+	return r
+}
+
+// advance returns the tokens at time t, refilled since lim.last; the
+// caller holds lim.mu. A t before lim.last is treated as lim.last.
+func (lim *Limiter) advance(t time.Time) (time.Time, float64) {
+	last := lim.last
+	if t.Before(last) {
+		last = t
+	}
+	elapsed := t.Sub(last)
+	tokens := lim.tokens + lim.limit.tokensFromDuration(elapsed)
+	if burst := float64(lim.burst); tokens > burst {
+		tokens = burst
+	}
+	return t, tokens
+}
+
+// Reservation holds tokens taken from a Limiter for events that may
+// happen after a delay
+type Reservation struct {
+	ok        bool
+	lim       *Limiter
+	tokens    int
+	timeToAct time.Time
+	// limit is the limiter's limit at reservation time
+	limit Limit
+}
+
+// OK reports whether the limiter can grant the tokens within the
+// maximum wait. Cancel does not need to be called when it is false.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay is DelayFrom(time.Now())
+func (r *Reservation) Delay() time.Duration {
+	return r.DelayFrom(time.Now())
+}
+
+// DelayFrom returns how long after t the reserved events may happen.
+// It returns InfDuration for a reservation that is not OK.
+func (r *Reservation) DelayFrom(t time.Time) time.Duration {
+	if !r.ok {
+		return InfDuration
+	}
+	delay := r.timeToAct.Sub(t)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Cancel is CancelAt(time.Now())
+func (r *Reservation) Cancel() {
+	r.CancelAt(time.Now())
+}
+
+// CancelAt says the reserved events will not happen, and gives back as
+// many tokens as possible without penalizing reservations made since
+func (r *Reservation) CancelAt(t time.Time) {
+	if !r.ok {
+		return
+	}
+
+	r.lim.mu.Lock()
+	defer r.lim.mu.Unlock()
+
+	if r.lim.limit == Inf || r.tokens == 0 || r.timeToAct.Before(t) {
+		return
+	}
+
+	// Tokens reserved after this one cannot be given back
+	restoreTokens := float64(r.tokens) - r.limit.tokensFromDuration(r.lim.lastEvent.Sub(r.timeToAct))
+	if restoreTokens <= 0 {
+		return
+	}
+	t, tokens := r.lim.advance(t)
+	tokens += restoreTokens
+	if burst := float64(r.lim.burst); tokens > burst {
+		tokens = burst
+	}
+	r.lim.last = t
+	r.lim.tokens = tokens
+	if r.timeToAct == r.lim.lastEvent {
+		prevEvent := r.timeToAct.Add(r.limit.durationFromTokens(float64(-r.tokens)))
+		if !prevEvent.Before(t) {
+			r.lim.lastEvent = prevEvent
+		}
+	}
+}
+
+// Keyed limiters
+
+// Registry keeps a Limiter per key, such as a client IP, user or API
+// key, each made on first use. Limiters idle for the TTL are removed, so
+// that one-off keys do not pile up; a removed key starts over with a
+// full bucket. The registry is an emulator extension.
+type Registry struct {
+	mu        sync.Mutex
+	limit     Limit
+	burst     int
+	ttl       time.Duration
+	entries   map[string]*registryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type registryEntry struct {
+	limiter  *Limiter
+	lastUsed time.Time
+}
+
+// NewRegistry returns an empty registry of limiters allowing r events
+// per second in bursts of b. A ttl of zero keeps limiters forever; it
+// should be at least the time a bucket takes to refill, b/r seconds.
+func NewRegistry(r Limit, b int, ttl time.Duration) *Registry {
+	return &Registry{
+		limit:   r,
+		burst:   b,
+		ttl:     ttl,
+		entries: make(map[string]*registryEntry),
+		now:     time.Now,
+	}
+}
+
+// Get returns the limiter of key, making it if needed
+func (reg *Registry) Get(key string) *Limiter {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.get(key, reg.now(), reg.limit, reg.burst)
+}
+
+// get finds key's limiter or makes it with limit r and burst b, and
+// sweeps idle ones once per TTL; the caller holds reg.mu
+func (reg *Registry) get(key string, now time.Time, r Limit, b int) *Limiter {
+	if reg.ttl > 0 && now.Sub(reg.lastSweep) >= reg.ttl {
+		reg.sweep(now)
+	}
+	entry, ok := reg.entries[key]
+	if !ok {
+		entry = &registryEntry{limiter: NewLimiter(r, b)}
+		reg.entries[key] = entry
+	}
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+// sweep removes limiters idle for the TTL; the caller holds reg.mu
+func (reg *Registry) sweep(now time.Time) int {
+	removed := 0
+	for key, entry := range reg.entries {
+		if now.Sub(entry.lastUsed) >= reg.ttl {
+			delete(reg.entries, key)
+			removed++
+		}
+	}
+	reg.lastSweep = now
+	return removed
+}
+
+// Allow reports whether an event for key may happen now
+func (reg *Registry) Allow(key string) bool {
+	return reg.Get(key).Allow()
+}
+
+// Wait blocks until an event for key may happen, as Limiter.Wait does
+func (reg *Registry) Wait(ctx context.Context, key string) error {
+	return reg.Get(key).Wait(ctx)
+}
+
+// Take implements the Gin emulator's RateLimitStore: it takes a token
+// from key's limiter, made or changed with rate and burst, and otherwise
+// reports how long until a token is available
+func (reg *Registry) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	reg.mu.Lock()
+	now := reg.now()
+	lim := reg.get(key, now, Limit(rate), burst)
+	reg.mu.Unlock()
+
+	if lim.Limit() != Limit(rate) {
+		lim.SetLimitAt(now, Limit(rate))
+	}
+	if lim.Burst() != burst {
+		lim.SetBurstAt(now, burst)
+	}
+	r := lim.ReserveN(now, 1)
+	if !r.OK() {
+		return false, InfDuration, nil
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, nil
+}
+
+// SetLimit changes the limit of every limiter, present and future
+func (reg *Registry) SetLimit(newLimit Limit) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.limit = newLimit
+	for _, entry := range reg.entries {
+		entry.limiter.SetLimit(newLimit)
+	}
+}
+
+// SetBurst changes the burst of every limiter, present and future
+func (reg *Registry) SetBurst(newBurst int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.burst = newBurst
+	for _, entry := range reg.entries {
+		entry.limiter.SetBurst(newBurst)
+	}
+}
+
+// Delete removes key's limiter
+func (reg *Registry) Delete(key string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.entries, key)
+}
+
+// Cleanup removes limiters idle for the TTL now, instead of on a later
+// Get, and returns how many it removed
+func (reg *Registry) Cleanup() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.ttl <= 0 {
+		return 0
+	}
+	return reg.sweep(reg.now())
+}
+
+// Len returns the number of limiters held
+func (reg *Registry) Len() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return len(reg.entries)
+}
@@ -0,0 +1,327 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang.org/x/time/rate
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// at returns the time ms milliseconds after t0
+func at(ms int) time.Time {
+	return t0.Add(time.Duration(ms) * time.Millisecond)
+}
+
+func testEvery() bool {
+	return Every(100*time.Millisecond) == 10 && Every(0) == Inf &&
+		Every(2*time.Second) == 0.5
+}
+
+func testAllowBurst() bool {
+	lim := NewLimiter(10, 3)
+	// The bucket starts full
+	for i := 0; i < 3; i++ {
+		if !lim.AllowN(t0, 1) {
+			return false
+		}
+	}
+	if lim.AllowN(t0, 1) {
+		return false
+	}
+	// One token every 100ms
+	if lim.AllowN(at(50), 1) || !lim.AllowN(at(100), 1) || lim.AllowN(at(150), 1) {
+		return false
+	}
+	// Refills stop at the burst
+	if !lim.AllowN(at(10000), 3) || lim.AllowN(at(10000), 1) {
+		return false
+	}
+	// More than the burst is never allowed
+	return !lim.AllowN(at(20000), 4)
+}
+
+func testTokens() bool {
+	lim := NewLimiter(2, 4)
+	if lim.TokensAt(t0) != 4 {
+		return false
+	}
+	lim.AllowN(t0, 3)
+	if lim.TokensAt(t0) != 1 || lim.TokensAt(at(500)) != 2 || lim.TokensAt(at(5000)) != 4 {
+		return false
+	}
+	// A time before the last update counts as no time passing
+	lim.AllowN(at(1000), 1)
+	return lim.TokensAt(at(500)) == 2 && lim.Limit() == 2 && lim.Burst() == 4
+}
+
+func testReserve() bool {
+	lim := NewLimiter(10, 2)
+	r1 := lim.ReserveN(t0, 2)
+	if !r1.OK() || r1.DelayFrom(t0) != 0 {
+		return false
+	}
+	// The next token is 100ms away, the one after 200ms
+	r2 := lim.ReserveN(t0, 1)
+	r3 := lim.ReserveN(t0, 1)
+	if r2.DelayFrom(t0) != 100*time.Millisecond || r3.DelayFrom(t0) != 200*time.Millisecond {
+		return false
+	}
+	if r3.DelayFrom(at(150)) != 50*time.Millisecond || r3.DelayFrom(at(300)) != 0 {
+		return false
+	}
+	// Tokens are owed, not available
+	if lim.TokensAt(t0) != -2 {
+		return false
+	}
+	// More than the burst cannot be reserved
+	r4 := lim.ReserveN(t0, 3)
+	return !r4.OK() && r4.DelayFrom(t0) == InfDuration
+}
+
+func testCancel() bool {
+	lim := NewLimiter(10, 2)
+	lim.ReserveN(t0, 2)
+	r := lim.ReserveN(t0, 1)
+	if r.DelayFrom(t0) != 100*time.Millisecond {
+		return false
+	}
+	// Cancelling the latest reservation gives its token back
+	r.CancelAt(t0)
+	if lim.TokensAt(t0) != 0 {
+		return false
+	}
+	next := lim.ReserveN(t0, 1)
+	if next.DelayFrom(t0) != 100*time.Millisecond {
+		return false
+	}
+
+	// A reservation followed by others only gives back what they did not
+	// rely on
+	lim = NewLimiter(10, 2)
+	lim.ReserveN(t0, 2)
+	earlier := lim.ReserveN(t0, 1)
+	lim.ReserveN(t0, 1)
+	earlier.CancelAt(t0)
+	if lim.TokensAt(t0) != -2 {
+		return false
+	}
+
+	// Reservations in the past cannot be cancelled
+	lim = NewLimiter(10, 1)
+	past := lim.ReserveN(t0, 1)
+	past.CancelAt(at(100))
+	return lim.TokensAt(at(100)) == 1
+}
+
+func testWait() bool {
+	lim := NewLimiter(Every(10*time.Millisecond), 1)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := lim.Wait(ctx); err != nil {
+			return false
+		}
+	}
+	// The first is free, the next three wait about 10ms each
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond || elapsed > time.Second {
+		return false
+	}
+	if err := lim.WaitN(ctx, 2); err == nil || err.Error() != "rate: Wait(n=2) exceeds limiter's burst 1" {
+		return false
+	}
+	return true
+}
+
+func testWaitContext() bool {
+	lim := NewLimiter(Every(time.Hour), 1)
+	lim.Allow()
+
+	// A deadline too close is reported without waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := lim.Wait(ctx)
+	if err == nil || !strings.Contains(err.Error(), "would exceed context deadline") {
+		return false
+	}
+
+	// A canceled wait gives its token back
+	lim = NewLimiter(Every(50*time.Millisecond), 1)
+	lim.Allow()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel2()
+	}()
+	if err := lim.Wait(ctx2); err != context.Canceled {
+		return false
+	}
+	if lim.Tokens() < 0 {
+		return false
+	}
+	return lim.Wait(ctx2) == context.Canceled
+}
+
+func testInfAndZero() bool {
+	inf := NewLimiter(Inf, 0)
+	for i := 0; i < 1000; i++ {
+		if !inf.Allow() {
+			return false
+		}
+	}
+	if inf.Wait(context.Background()) != nil || inf.WaitN(context.Background(), 50) != nil {
+		return false
+	}
+	// A zero limit allows the burst once, then never again
+	zero := NewLimiter(0, 2)
+	if !zero.AllowN(t0, 1) || !zero.AllowN(at(1000000), 1) || zero.AllowN(at(2000000), 1) {
+		return false
+	}
+	return NewLimiter(0, 0).ReserveN(t0, 1).OK() == false
+}
+
+func testSetLimitAndBurst() bool {
+	lim := NewLimiter(1, 1)
+	lim.AllowN(t0, 1)
+	// Tokens earned under the old limit are kept
+	lim.SetLimitAt(at(500), 10)
+	if lim.TokensAt(at(500)) != 0.5 {
+		return false
+	}
+	if !lim.AllowN(at(550), 1) {
+		return false
+	}
+	lim.SetBurstAt(at(550), 5)
+	if !lim.AllowN(at(1050), 5) || lim.Burst() != 5 || lim.Limit() != 10 {
+		return false
+	}
+	// Shrinking the burst caps the bucket
+	lim.SetBurstAt(at(9000), 2)
+	return lim.TokensAt(at(9000)) == 2 && !lim.AllowN(at(9000), 3)
+}
+
+func testConcurrentAllow() bool {
+	lim := NewLimiter(Every(time.Hour), 50)
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if lim.Allow() {
+					atomic.AddInt32(&allowed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return allowed == 50
+}
+
+func testRegistry() bool {
+	reg := NewRegistry(1, 2, 0)
+	if !reg.Allow("alice") || !reg.Allow("alice") || reg.Allow("alice") {
+		return false
+	}
+	// Keys have their own buckets
+	if !reg.Allow("bob") || reg.Len() != 2 {
+		return false
+	}
+	if reg.Get("alice") != reg.Get("alice") {
+		return false
+	}
+	reg.Delete("alice")
+	return reg.Allow("alice") && reg.Len() == 2
+}
+
+func testRegistryExpiry() bool {
+	now := t0
+	reg := NewRegistry(1, 1, time.Minute)
+	reg.now = func() time.Time { return now }
+	reg.Get("a")
+	now = now.Add(30 * time.Second)
+	reg.Get("b")
+	now = now.Add(40 * time.Second)
+	// "a" has been idle 70s, "b" only 40s
+	if reg.Cleanup() != 1 || reg.Len() != 1 {
+		return false
+	}
+	// Idle limiters are also swept as keys are used
+	now = now.Add(2 * time.Minute)
+	reg.Get("c")
+	if reg.Len() != 1 {
+		return false
+	}
+	return NewRegistry(1, 1, 0).Cleanup() == 0
+}
+
+func testRegistrySetLimit() bool {
+	reg := NewRegistry(1, 1, 0)
+	existing := reg.Get("old")
+	reg.SetLimit(5)
+	reg.SetBurst(3)
+	fresh := reg.Get("new")
+	return existing.Limit() == 5 && existing.Burst() == 3 &&
+		fresh.Limit() == 5 && fresh.Burst() == 3
+}
+
+func testRegistryTake() bool {
+	now := t0
+	reg := NewRegistry(1, 1, 0)
+	reg.now = func() time.Time { return now }
+
+	// Take uses the rate and burst it is given, as a Gin store does
+	for i := 0; i < 2; i++ {
+		if ok, _, err := reg.Take("10.0.0.1", 0.5, 2); !ok || err != nil {
+			return false
+		}
+	}
+	ok, wait, err := reg.Take("10.0.0.1", 0.5, 2)
+	if ok || err != nil || wait != 2*time.Second {
+		return false
+	}
+	// A refused request does not use up the next token
+	now = now.Add(2 * time.Second)
+	if ok, _, _ := reg.Take("10.0.0.1", 0.5, 2); !ok {
+		return false
+	}
+	lim := reg.Get("10.0.0.1")
+	return lim.Limit() == 0.5 && lim.Burst() == 2
+}
+
+func main() {
+	fmt.Println("Running rate Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Every", testEvery)
+	runTest("Allow Burst", testAllowBurst)
+	runTest("Tokens", testTokens)
+	runTest("Reserve", testReserve)
+	runTest("Cancel", testCancel)
+	runTest("Wait", testWait)
+	runTest("Wait Context", testWaitContext)
+	runTest("Inf And Zero", testInfAndZero)
+	runTest("SetLimit And SetBurst", testSetLimitAndBurst)
+	runTest("Concurrent Allow", testConcurrentAllow)
+	runTest("Registry", testRegistry)
+	runTest("Registry Expiry", testRegistryExpiry)
+	runTest("Registry SetLimit", testRegistrySetLimit)
+	runTest("Registry Take", testRegistryTake)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,2983 @@
+package main
+
+// Developed by PowerShield, as an alternative to sqlx
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Bind types for placeholders, as returned by BindType
+const (
+	UNKNOWN = iota
+	QUESTION
+	DOLLAR
+	NAMED
+	AT
+)
+
+// NameMapper maps struct field names to column names when a field has no
+// db tag. Like sqlx, it lowercases the name.
+var NameMapper = strings.ToLower
+
+// BindType returns the placeholder style for a driver name
+func BindType(driverName string) int {
+	switch driverName {
+	case "postgres", "pgx", "pq-timeouts", "cloudsqlpostgres", "ql", "nrpostgres", "cockroach":
+		return DOLLAR
+	case "mysql", "sqlite3", "sqlite", "nrmysql", "nrsqlite3":
+		return QUESTION
+	case "oci8", "ora", "goracle", "godror":
+		return NAMED
+	case "sqlserver", "azuresql":
+		return AT
+	}
+	return UNKNOWN
+}
+
+// Rebind turns a query using "?" placeholders into the bind type's style
+//
+//	Rebind(DOLLAR, "SELECT * FROM users WHERE id = ? AND age > ?")
+//	// "SELECT * FROM users WHERE id = $1 AND age > $2"
+func Rebind(bindType int, query string) string {
+	switch bindType {
+	case QUESTION, UNKNOWN:
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			inString = !inString
+		}
+		if c != '?' || inString {
+			b.WriteByte(c)
+			continue
+		}
+		n++
+		switch bindType {
+		case DOLLAR:
+			b.WriteString("$" + strconv.Itoa(n))
+		case NAMED:
+			b.WriteString(":arg" + strconv.Itoa(n))
+		case AT:
+			b.WriteString("@p" + strconv.Itoa(n))
+		}
+	}
+	return b.String()
+}
+
+// In expands slice arguments into one placeholder per element, for
+// queries like "SELECT * FROM users WHERE id IN (?)". The query must use
+// "?" placeholders; call Rebind afterwards for other styles.
+func In(query string, args ...interface{}) (string, []interface{}, error) {
+	var b strings.Builder
+	var newArgs []interface{}
+	argIndex := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '\'' {
+			inString = !inString
+		}
+		if c != '?' || inString {
+			b.WriteByte(c)
+			continue
+		}
+		if argIndex >= len(args) {
+			return "", nil, errors.New("number of bindVars exceeds arguments")
+		}
+		arg := args[argIndex]
+		argIndex++
+		v := reflect.ValueOf(arg)
+		if _, ok := arg.(driver.Valuer); ok || arg == nil || !isExpandable(v) {
+			b.WriteByte('?')
+			newArgs = append(newArgs, arg)
+			continue
+		}
+		if v.Len() == 0 {
+			return "", nil, errors.New("empty slice passed to 'in' query")
+		}
+		for j := 0; j < v.Len(); j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('?')
+			newArgs = append(newArgs, v.Index(j).Interface())
+		}
+	}
+	if argIndex < len(args) {
+		return "", nil, errors.New("number of bindVars less than number arguments")
+	}
+	return b.String(), newArgs, nil
+}
+
+// isExpandable reports whether v is a slice or array other than []byte
+func isExpandable(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	return v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// Named compiles a query with :name parameters into one with "?"
+// placeholders and the matching arguments from a struct or map
+func Named(query string, arg interface{}) (string, []interface{}, error) {
+	return bindNamed(QUESTION, query, arg, newMapper(NameMapper))
+}
+
+// table holds the rows of one in-memory table. Rows are maps from column
+// name to value, the same layout as the GORM emulator's records, so
+// fixtures can be shared between the two.
+type table struct {
+	columns  []string
+	declared bool // columns come from CREATE TABLE and are enforced
+	rows     []map[string]interface{}
+}
+
+func (t *table) column(name string) (string, bool) {
+	for _, c := range t.columns {
+		if c == name {
+			return c, true
+		}
+	}
+	key := normalizeColumn(name)
+	for _, c := range t.columns {
+		if normalizeColumn(c) == key {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+func (t *table) addColumn(name string) {
+	if _, ok := t.column(name); !ok {
+		t.columns = append(t.columns, name)
+	}
+}
+
+func (t *table) copy() *table {
+	c := &table{columns: append([]string(nil), t.columns...), declared: t.declared}
+	c.rows = make([]map[string]interface{}, len(t.rows))
+	for i, row := range t.rows {
+		c.rows[i] = copyRow(row)
+	}
+	return c
+}
+
+func copyRow(row map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		c[k] = v
+	}
+	return c
+}
+
+// store is an in-memory database
+type store struct {
+	mu     sync.RWMutex
+	tables map[string]*table
+}
+
+func newStore() *store {
+	return &store{tables: make(map[string]*table)}
+}
+
+func (s *store) snapshot() *store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := newStore()
+	for name, t := range s.tables {
+		c.tables[name] = t.copy()
+	}
+	return c
+}
+
+// DB wraps an in-memory database with sqlx's extensions: struct scanning,
+// named parameters and Get/Select helpers
+type DB struct {
+	*conn
+	driverName string
+}
+
+// Open opens a new, empty in-memory database. The driver name only picks
+// the placeholder style: "postgres" uses $1, "mysql" and "sqlite3" use ?.
+func Open(driverName, dataSourceName string) (*DB, error) {
+	if driverName == "" {
+		return nil, errors.New("sql: unknown driver \"\" (forgotten import?)")
+	}
+	db := &DB{driverName: driverName}
+	db.conn = &conn{
+		store:    newStore(),
+		bindType: BindType(driverName),
+		mapper:   newMapper(NameMapper),
+		closed:   new(bool),
+	}
+	db.conn.check = db.conn.checkOpen
+	return db, nil
+}
+
+// MustOpen is Open but panics on error
+func MustOpen(driverName, dataSourceName string) *DB {
+	db, err := Open(driverName, dataSourceName)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Connect opens a database and verifies it with Ping
+func Connect(driverName, dataSourceName string) (*DB, error) {
+	db, err := Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// MustConnect is Connect but panics on error
+func MustConnect(driverName, dataSourceName string) *DB {
+	db, err := Connect(driverName, dataSourceName)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// DriverName returns the driver name passed to Open
+func (db *DB) DriverName() string {
+	return db.driverName
+}
+
+// Ping checks the database is still open
+func (db *DB) Ping() error {
+	return db.checkOpen()
+}
+
+// Close closes the database; later calls fail
+func (db *DB) Close() error {
+	*db.closed = true
+	return nil
+}
+
+// MapperFunc sets the function mapping field names without a db tag to
+// column names
+func (db *DB) MapperFunc(mf func(string) string) {
+	db.mapper = newMapper(mf)
+}
+
+// Unsafe returns a version of the DB that ignores result columns with no
+// matching struct field instead of failing
+func (db *DB) Unsafe() *DB {
+	c := *db.conn
+	c.unsafe = true
+	return &DB{conn: &c, driverName: db.driverName}
+}
+
+// LoadFixtures inserts rows into tables, creating the tables as needed.
+// Rows use the same layout as the GORM emulator's records, a map from
+// column name to value per row, and columns are matched ignoring case and
+// underscores, so a GORM record's "CreatedAt" is the "created_at" column.
+func (db *DB) LoadFixtures(fixtures map[string][]map[string]interface{}) error {
+	if err := db.checkOpen(); err != nil {
+		return err
+	}
+	db.store.mu.Lock()
+	defer db.store.mu.Unlock()
+	names := make([]string, 0, len(fixtures))
+	for name := range fixtures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := strings.ToLower(name)
+		t, ok := db.store.tables[key]
+		if !ok {
+			t = &table{}
+			db.store.tables[key] = t
+		}
+		for _, fixture := range fixtures[name] {
+			row := make(map[string]interface{}, len(fixture))
+			columns := make([]string, 0, len(fixture))
+			for column := range fixture {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			for _, column := range columns {
+				value, err := driverValue(fixture[column])
+				if err != nil {
+					return fmt.Errorf("fixture %s.%s: %v", name, column, err)
+				}
+				if existing, ok := t.column(column); ok {
+					column = existing
+				} else if t.declared {
+					return fmt.Errorf("table %s has no column named %s", key, column)
+				} else {
+					t.addColumn(column)
+				}
+				row[column] = value
+			}
+			t.rows = append(t.rows, row)
+		}
+	}
+	return nil
+}
+
+// Table returns a copy of a table's rows, for assertions in tests
+func (db *DB) Table(name string) []map[string]interface{} {
+	db.store.mu.RLock()
+	defer db.store.mu.RUnlock()
+	t, ok := db.store.tables[strings.ToLower(name)]
+	if !ok {
+		return nil
+	}
+	return t.copy().rows
+}
+
+// Beginx starts a transaction. The transaction works on a snapshot of the
+// database that replaces it on Commit.
+func (db *DB) Beginx() (*Tx, error) {
+	return db.BeginTxx(context.Background(), nil)
+}
+
+// BeginTxx starts a transaction; opts are accepted for compatibility
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if err := db.checkOpen(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tx := &Tx{db: db, done: new(bool)}
+	c := *db.conn
+	c.store = db.store.snapshot()
+	c.check = func() error {
+		if *tx.done {
+			return sql.ErrTxDone
+		}
+		return db.checkOpen()
+	}
+	tx.conn = &c
+	return tx, nil
+}
+
+// MustBegin is Beginx but panics on error
+func (db *DB) MustBegin() *Tx {
+	tx, err := db.Beginx()
+	if err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// Tx is a transaction with sqlx's extensions
+type Tx struct {
+	*conn
+	db   *DB
+	done *bool
+}
+
+// Commit makes the transaction's changes visible to the database. With
+// concurrent transactions the last commit wins.
+func (tx *Tx) Commit() error {
+	if err := tx.check(); err != nil {
+		return err
+	}
+	*tx.done = true
+	tx.db.store.mu.Lock()
+	defer tx.db.store.mu.Unlock()
+	tx.store.mu.RLock()
+	defer tx.store.mu.RUnlock()
+	tx.db.store.tables = tx.store.tables
+	return nil
+}
+
+// Rollback discards the transaction's changes
+func (tx *Tx) Rollback() error {
+	if *tx.done {
+		return sql.ErrTxDone
+	}
+	*tx.done = true
+	return nil
+}
+
+// DriverName returns the database's driver name
+func (tx *Tx) DriverName() string {
+	return tx.db.driverName
+}
+
+// Unsafe returns a version of the transaction that ignores result columns
+// with no matching struct field
+func (tx *Tx) Unsafe() *Tx {
+	c := *tx.conn
+	c.unsafe = true
+	return &Tx{conn: &c, db: tx.db, done: tx.done}
+}
+
+// Stmtx returns a version of a prepared statement running in the
+// transaction
+func (tx *Tx) Stmtx(stmt *Stmt) *Stmt {
+	return &Stmt{conn: tx.conn, query: stmt.query, parsed: stmt.parsed}
+}
+
+// NamedStmt returns a version of a prepared named statement running in
+// the transaction
+func (tx *Tx) NamedStmt(stmt *NamedStmt) *NamedStmt {
+	return &NamedStmt{Stmt: tx.Stmtx(stmt.Stmt), QueryString: stmt.QueryString, Params: stmt.Params}
+}
+
+// conn runs queries against a store. DB and Tx embed it, so both have the
+// same query methods.
+type conn struct {
+	store    *store
+	bindType int
+	mapper   *mapper
+	unsafe   bool
+	closed   *bool
+	check    func() error
+}
+
+func (c *conn) checkOpen() error {
+	if *c.closed {
+		return errors.New("sql: database is closed")
+	}
+	return nil
+}
+
+// Rebind turns a query using "?" placeholders into the driver's style
+func (c *conn) Rebind(query string) string {
+	return Rebind(c.bindType, query)
+}
+
+// BindNamed compiles a named query into the driver's placeholder style
+func (c *conn) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return bindNamed(c.bindType, query, arg, c.mapper)
+}
+
+// Exec runs a query that doesn't return rows
+func (c *conn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext is Exec with a context
+func (c *conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := parseStatement(query)
+	if err != nil {
+		return nil, err
+	}
+	_, res, err := c.run(ctx, stmt, args)
+	return res, err
+}
+
+// MustExec is Exec but panics on error
+func (c *conn) MustExec(query string, args ...interface{}) sql.Result {
+	res, err := c.Exec(query, args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Query runs a query that returns rows
+func (c *conn) Query(query string, args ...interface{}) (*Rows, error) {
+	return c.QueryxContext(context.Background(), query, args...)
+}
+
+// Queryx runs a query that returns rows, which can be scanned into structs
+func (c *conn) Queryx(query string, args ...interface{}) (*Rows, error) {
+	return c.QueryxContext(context.Background(), query, args...)
+}
+
+// QueryxContext is Queryx with a context
+func (c *conn) QueryxContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	stmt, err := parseStatement(query)
+	if err != nil {
+		return nil, err
+	}
+	rows, _, err := c.run(ctx, stmt, args)
+	return rows, err
+}
+
+// QueryRowx runs a query expected to return at most one row. Errors are
+// deferred until the row is scanned.
+func (c *conn) QueryRowx(query string, args ...interface{}) *Row {
+	return c.QueryRowxContext(context.Background(), query, args...)
+}
+
+// QueryRowxContext is QueryRowx with a context
+func (c *conn) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := c.QueryxContext(ctx, query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+// Get scans a single row into dest: a struct, or a scannable value for a
+// one-column result. It returns sql.ErrNoRows if there is no row.
+func (c *conn) Get(dest interface{}, query string, args ...interface{}) error {
+	return c.GetContext(context.Background(), dest, query, args...)
+}
+
+// GetContext is Get with a context
+func (c *conn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return c.QueryRowxContext(ctx, query, args...).scanAny(dest)
+}
+
+// Select scans all rows into dest, a pointer to a slice of structs,
+// pointers to structs or scannable values
+func (c *conn) Select(dest interface{}, query string, args ...interface{}) error {
+	return c.SelectContext(context.Background(), dest, query, args...)
+}
+
+// SelectContext is Select with a context
+func (c *conn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanAll(rows, dest)
+}
+
+// NamedExec runs a query with :name parameters bound from a struct or a
+// map. A slice of structs or maps inserts one row per element:
+//
+//	db.NamedExec(`INSERT INTO users (name, age) VALUES (:name, :age)`, users)
+func (c *conn) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return c.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext is NamedExec with a context
+func (c *conn) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	q, args, err := bindNamed(c.bindType, query, arg, c.mapper)
+	if err != nil {
+		return nil, err
+	}
+	return c.ExecContext(ctx, q, args...)
+}
+
+// NamedQuery runs a query with :name parameters bound from a struct or map
+func (c *conn) NamedQuery(query string, arg interface{}) (*Rows, error) {
+	return c.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext is NamedQuery with a context
+func (c *conn) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*Rows, error) {
+	q, args, err := bindNamed(c.bindType, query, arg, c.mapper)
+	if err != nil {
+		return nil, err
+	}
+	return c.QueryxContext(ctx, q, args...)
+}
+
+// Preparex parses a query once for repeated execution
+func (c *conn) Preparex(query string) (*Stmt, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	stmt, err := parseStatement(query)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{conn: c, query: query, parsed: stmt}, nil
+}
+
+// PrepareNamed prepares a query with :name parameters
+func (c *conn) PrepareNamed(query string) (*NamedStmt, error) {
+	q, names, err := compileNamedQuery(query, c.bindType)
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := c.Preparex(q)
+	if err != nil {
+		return nil, err
+	}
+	return &NamedStmt{Stmt: stmt, QueryString: q, Params: names}, nil
+}
+
+// run executes a parsed statement with its arguments
+func (c *conn) run(ctx context.Context, stmt statement, args []interface{}) (*Rows, sql.Result, error) {
+	if err := c.check(); err != nil {
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := driverValue(arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sql: converting argument $%d type: %v", i+1, err)
+		}
+		values[i] = v
+	}
+	if n := stmt.numArgs(); n != len(values) {
+		return nil, nil, fmt.Errorf("sql: expected %d arguments, got %d", n, len(values))
+	}
+	ex := &execution{store: c.store, args: values}
+	rows, res, err := ex.run(stmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows.mapper, rows.unsafe = c.mapper, c.unsafe
+	return rows, res, nil
+}
+
+// Stmt is a prepared statement
+type Stmt struct {
+	conn   *conn
+	query  string
+	parsed statement
+}
+
+// Exec runs the statement
+func (s *Stmt) Exec(args ...interface{}) (sql.Result, error) {
+	_, res, err := s.conn.run(context.Background(), s.parsed, args)
+	return res, err
+}
+
+// MustExec is Exec but panics on error
+func (s *Stmt) MustExec(args ...interface{}) sql.Result {
+	res, err := s.Exec(args...)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Queryx runs the statement and returns its rows
+func (s *Stmt) Queryx(args ...interface{}) (*Rows, error) {
+	rows, _, err := s.conn.run(context.Background(), s.parsed, args)
+	return rows, err
+}
+
+// QueryRowx runs the statement expecting at most one row
+func (s *Stmt) QueryRowx(args ...interface{}) *Row {
+	rows, err := s.Queryx(args...)
+	return &Row{rows: rows, err: err}
+}
+
+// Get scans a single row into dest
+func (s *Stmt) Get(dest interface{}, args ...interface{}) error {
+	return s.QueryRowx(args...).scanAny(dest)
+}
+
+// Select scans all rows into dest
+func (s *Stmt) Select(dest interface{}, args ...interface{}) error {
+	rows, err := s.Queryx(args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanAll(rows, dest)
+}
+
+// Close releases the statement
+func (s *Stmt) Close() error {
+	return nil
+}
+
+// NamedStmt is a prepared statement with :name parameters
+type NamedStmt struct {
+	*Stmt
+
+	// QueryString is the compiled query and Params the parameter names in
+	// placeholder order
+	QueryString string
+	Params      []string
+}
+
+func (n *NamedStmt) bind(arg interface{}) ([]interface{}, error) {
+	return bindArgs(n.Params, arg, n.conn.mapper)
+}
+
+// Exec runs the statement with parameters bound from arg
+func (n *NamedStmt) Exec(arg interface{}) (sql.Result, error) {
+	args, err := n.bind(arg)
+	if err != nil {
+		return nil, err
+	}
+	return n.Stmt.Exec(args...)
+}
+
+// MustExec is Exec but panics on error
+func (n *NamedStmt) MustExec(arg interface{}) sql.Result {
+	res, err := n.Exec(arg)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// Queryx runs the statement with parameters bound from arg
+func (n *NamedStmt) Queryx(arg interface{}) (*Rows, error) {
+	args, err := n.bind(arg)
+	if err != nil {
+		return nil, err
+	}
+	return n.Stmt.Queryx(args...)
+}
+
+// QueryRowx runs the statement expecting at most one row
+func (n *NamedStmt) QueryRowx(arg interface{}) *Row {
+	rows, err := n.Queryx(arg)
+	return &Row{rows: rows, err: err}
+}
+
+// Get scans a single row into dest
+func (n *NamedStmt) Get(dest interface{}, arg interface{}) error {
+	return n.QueryRowx(arg).scanAny(dest)
+}
+
+// Select scans all rows into dest
+func (n *NamedStmt) Select(dest interface{}, arg interface{}) error {
+	rows, err := n.Queryx(arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanAll(rows, dest)
+}
+
+// Queryer is implemented by DB and Tx
+type Queryer interface {
+	Query(query string, args ...interface{}) (*Rows, error)
+	Queryx(query string, args ...interface{}) (*Rows, error)
+	QueryRowx(query string, args ...interface{}) *Row
+}
+
+// Execer is implemented by DB and Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Ext combines Queryer and Execer with named query binding
+type Ext interface {
+	Queryer
+	Execer
+	BindNamed(query string, arg interface{}) (string, []interface{}, error)
+}
+
+// Get scans a single row from q into dest, for code that accepts either a
+// DB or a Tx
+func Get(q Queryer, dest interface{}, query string, args ...interface{}) error {
+	return q.QueryRowx(query, args...).scanAny(dest)
+}
+
+// Select scans all rows from q into dest
+func Select(q Queryer, dest interface{}, query string, args ...interface{}) error {
+	rows, err := q.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return scanAll(rows, dest)
+}
+
+// NamedExec runs a named query on e
+func NamedExec(e Ext, query string, arg interface{}) (sql.Result, error) {
+	q, args, err := e.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return e.Exec(q, args...)
+}
+
+// result implements sql.Result
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// Rows is a result set that can be scanned into structs, maps or slices
+type Rows struct {
+	columns []string
+	data    [][]interface{}
+	pos     int
+	closed  bool
+	err     error
+	mapper  *mapper
+	unsafe  bool
+}
+
+// Next advances to the next row
+func (r *Rows) Next() bool {
+	if r.closed || r.pos >= len(r.data) {
+		r.closed = true
+		return false
+	}
+	r.pos++
+	return true
+}
+
+// Columns returns the column names
+func (r *Rows) Columns() ([]string, error) {
+	if r.closed {
+		return nil, errors.New("sql: Rows are closed")
+	}
+	return append([]string(nil), r.columns...), nil
+}
+
+// Close closes the rows
+func (r *Rows) Close() error {
+	r.closed = true
+	return nil
+}
+
+// Err returns the error, if any, met during iteration
+func (r *Rows) Err() error {
+	return r.err
+}
+
+func (r *Rows) current() ([]interface{}, error) {
+	if r.closed {
+		return nil, errors.New("sql: Rows are closed")
+	}
+	if r.pos == 0 {
+		return nil, errors.New("sql: Scan called without calling Next")
+	}
+	return r.data[r.pos-1], nil
+}
+
+// Scan copies the columns of the current row into dest
+func (r *Rows) Scan(dest ...interface{}) error {
+	row, err := r.current()
+	if err != nil {
+		return err
+	}
+	if len(dest) != len(r.columns) {
+		return fmt.Errorf("sql: expected %d destination arguments in Scan, not %d", len(r.columns), len(dest))
+	}
+	for i, d := range dest {
+		dv := reflect.ValueOf(d)
+		if dv.Kind() != reflect.Ptr || dv.IsNil() {
+			return errors.New("sql: destination not a pointer")
+		}
+		if err := convertAssign(dv.Elem(), row[i]); err != nil {
+			return fmt.Errorf("sql: Scan error on column index %d, name %q: %v", i, r.columns[i], err)
+		}
+	}
+	return nil
+}
+
+// StructScan copies the current row into a struct, matching columns to
+// fields by db tag or mapped field name
+func (r *Rows) StructScan(dest interface{}) error {
+	row, err := r.current()
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("must pass a pointer, not a value, to StructScan destination")
+	}
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("expected struct destination, got %s", v.Kind())
+	}
+	for i, column := range r.columns {
+		index, ok := r.mapper.lookup(v.Type(), column)
+		if !ok {
+			if r.unsafe {
+				continue
+			}
+			return fmt.Errorf("missing destination name %s in %T", column, dest)
+		}
+		if err := convertAssign(fieldByIndex(v, index), row[i]); err != nil {
+			return fmt.Errorf("sql: Scan error on column index %d, name %q: %v", i, column, err)
+		}
+	}
+	return nil
+}
+
+// MapScan copies the current row into dest, keyed by column
+func (r *Rows) MapScan(dest map[string]interface{}) error {
+	row, err := r.current()
+	if err != nil {
+		return err
+	}
+	for i, column := range r.columns {
+		dest[column] = row[i]
+	}
+	return nil
+}
+
+// SliceScan returns the current row's values
+func (r *Rows) SliceScan() ([]interface{}, error) {
+	row, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return append([]interface{}(nil), row...), nil
+}
+
+// Row is the result of QueryRowx
+type Row struct {
+	rows *Rows
+	err  error
+}
+
+// Err returns the query error, if any
+func (r *Row) Err() error {
+	return r.err
+}
+
+// Columns returns the column names
+func (r *Row) Columns() ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]string(nil), r.rows.columns...), nil
+}
+
+func (r *Row) next() error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.rows.Next() {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Scan copies the row's columns into dest, or returns sql.ErrNoRows
+func (r *Row) Scan(dest ...interface{}) error {
+	if err := r.next(); err != nil {
+		return err
+	}
+	defer r.rows.Close()
+	return r.rows.Scan(dest...)
+}
+
+// StructScan copies the row into a struct, or returns sql.ErrNoRows
+func (r *Row) StructScan(dest interface{}) error {
+	if err := r.next(); err != nil {
+		return err
+	}
+	defer r.rows.Close()
+	return r.rows.StructScan(dest)
+}
+
+// MapScan copies the row into dest, or returns sql.ErrNoRows
+func (r *Row) MapScan(dest map[string]interface{}) error {
+	if err := r.next(); err != nil {
+		return err
+	}
+	defer r.rows.Close()
+	return r.rows.MapScan(dest)
+}
+
+// scanAny scans into a struct with StructScan, or into a scannable value
+// with Scan when the result has one column
+func (r *Row) scanAny(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("must pass a pointer, not a value, to StructScan destination")
+	}
+	if v.IsNil() {
+		return errors.New("nil pointer passed to StructScan destination")
+	}
+	if err := r.next(); err != nil {
+		return err
+	}
+	defer r.rows.Close()
+	if isScannable(v.Elem().Type()) {
+		if len(r.rows.columns) > 1 {
+			return fmt.Errorf("scannable dest type %s with >1 columns (%d) in result", v.Elem().Kind(), len(r.rows.columns))
+		}
+		return r.rows.Scan(dest)
+	}
+	return r.rows.StructScan(dest)
+}
+
+// scanAll scans every row into a pointer to a slice
+func scanAll(rows *Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("must pass a pointer, not a value, to StructScan destination")
+	}
+	if v.IsNil() {
+		return errors.New("nil pointer passed to StructScan destination")
+	}
+	direct := v.Elem()
+	if direct.Kind() != reflect.Slice {
+		return fmt.Errorf("expected slice but got %s", direct.Kind())
+	}
+	slice := direct.Type()
+	base := slice.Elem()
+	isPtr := base.Kind() == reflect.Ptr
+	if isPtr {
+		base = base.Elem()
+	}
+	scannable := isScannable(base)
+	if scannable && len(rows.columns) > 1 {
+		return fmt.Errorf("non-struct dest type %s with >1 columns (%d)", base.Kind(), len(rows.columns))
+	}
+	direct.SetLen(0)
+	for rows.Next() {
+		vp := reflect.New(base)
+		var err error
+		if scannable {
+			err = rows.Scan(vp.Interface())
+		} else {
+			err = rows.StructScan(vp.Interface())
+		}
+		if err != nil {
+			return err
+		}
+		if isPtr {
+			direct.Set(reflect.Append(direct, vp))
+		} else {
+			direct.Set(reflect.Append(direct, reflect.Indirect(vp)))
+		}
+	}
+	return rows.Err()
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// isScannable reports whether values of t are scanned whole rather than
+// field by field: anything but a struct, plus structs that implement
+// sql.Scanner or have no exported fields, like time.Time
+func isScannable(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	if t == timeType {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// mapper maps struct fields to column names
+type mapper struct {
+	nameFunc func(string) string
+	mu       sync.Mutex
+	cache    map[reflect.Type]map[string][]int
+}
+
+func newMapper(nameFunc func(string) string) *mapper {
+	return &mapper{nameFunc: nameFunc, cache: make(map[reflect.Type]map[string][]int)}
+}
+
+// fields returns the field index paths by column name. Embedded structs
+// are flattened; a db tag of "-" skips a field.
+func (m *mapper) fields(t reflect.Type) map[string][]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if fields, ok := m.cache[t]; ok {
+		return fields
+	}
+	fields := make(map[string][]int)
+	m.collect(t, nil, fields)
+	m.cache[t] = fields
+	return fields
+}
+
+func (m *mapper) collect(t reflect.Type, index []int, fields map[string][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		path := append(append([]int(nil), index...), i)
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			m.collect(f.Type, path, fields)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = m.nameFunc(f.Name)
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = path
+		}
+	}
+}
+
+// lookup finds the field for a column, falling back to matching without
+// case and underscores
+func (m *mapper) lookup(t reflect.Type, column string) ([]int, bool) {
+	fields := m.fields(t)
+	if index, ok := fields[column]; ok {
+		return index, true
+	}
+	key := normalizeColumn(column)
+	for name, index := range fields {
+		if normalizeColumn(name) == key {
+			return index, true
+		}
+	}
+	return nil, false
+}
+
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		v = v.Field(i)
+	}
+	return v
+}
+
+// normalizeColumn lowercases a name and drops underscores, so "CreatedAt"
+// and "created_at" name the same column
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// compileNamedQuery turns :name parameters into placeholders for the bind
+// type, returning the names in order. "::" is a literal colon, as in
+// Postgres casts, and colons inside string literals are left alone.
+func compileNamedQuery(query string, bindType int) (string, []string, error) {
+	var b strings.Builder
+	var names []string
+	inString := false
+	runes := []rune(query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\'' {
+			inString = !inString
+		}
+		if r != ':' || inString {
+			b.WriteRune(r)
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == ':' {
+			b.WriteRune(':')
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && isNameRune(runes[j]) {
+			j++
+		}
+		if j == i+1 {
+			return "", nil, fmt.Errorf("unexpected `:` while reading named param at %d", i)
+		}
+		names = append(names, string(runes[i+1:j]))
+		switch bindType {
+		case DOLLAR:
+			b.WriteString("$" + strconv.Itoa(len(names)))
+		case AT:
+			b.WriteString("@p" + strconv.Itoa(len(names)))
+		case NAMED:
+			b.WriteString(":" + string(runes[i+1:j]))
+		default:
+			b.WriteByte('?')
+		}
+		i = j - 1
+	}
+	return b.String(), names, nil
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+// bindNamed compiles a named query and binds its arguments. A slice arg
+// repeats the query's VALUES (...) group once per element.
+func bindNamed(bindType int, query string, arg interface{}, m *mapper) (string, []interface{}, error) {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !isExpandable(v) {
+		q, names, err := compileNamedQuery(query, bindType)
+		if err != nil {
+			return "", nil, err
+		}
+		args, err := bindArgs(names, arg, m)
+		return q, args, err
+	}
+
+	if v.Len() == 0 {
+		return "", nil, errors.New("length of array is 0: cannot bind an empty slice")
+	}
+	start, end, err := valuesGroup(query)
+	if err != nil {
+		return "", nil, err
+	}
+	_, groupNames, err := compileNamedQuery(query[start:end], bindType)
+	if err != nil {
+		return "", nil, err
+	}
+	groups := make([]string, v.Len())
+	for i := range groups {
+		groups[i] = query[start:end]
+	}
+	expanded := query[:start] + strings.Join(groups, ", ") + query[end:]
+	q, names, err := compileNamedQuery(expanded, bindType)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(names) != len(groupNames)*v.Len() {
+		return "", nil, errors.New("named parameters outside VALUES (...) can't be bound from a slice")
+	}
+	var args []interface{}
+	for i := 0; i < v.Len(); i++ {
+		elemArgs, err := bindArgs(groupNames, v.Index(i).Interface(), m)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, elemArgs...)
+	}
+	return q, args, nil
+}
+
+// valuesGroup finds the parenthesized group after VALUES
+func valuesGroup(query string) (int, int, error) {
+	upper := strings.ToUpper(query)
+	i := strings.Index(upper, "VALUES")
+	if i < 0 {
+		return 0, 0, errors.New("a slice can only be bound to an INSERT ... VALUES (...) query")
+	}
+	start := strings.Index(query[i:], "(")
+	if start < 0 {
+		return 0, 0, errors.New("a slice can only be bound to an INSERT ... VALUES (...) query")
+	}
+	start += i
+	depth := 0
+	for j := start; j < len(query); j++ {
+		switch query[j] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return start, j + 1, nil
+			}
+		}
+	}
+	return 0, 0, errors.New("unbalanced parentheses after VALUES")
+}
+
+// bindArgs looks up each name in a map or struct
+func bindArgs(names []string, arg interface{}, m *mapper) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(names))
+	if mp, ok := arg.(map[string]interface{}); ok {
+		for _, name := range names {
+			value, ok := mp[name]
+			if !ok {
+				return nil, fmt.Errorf("could not find name %s in %#v", name, arg)
+			}
+			args = append(args, value)
+		}
+		return args, nil
+	}
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		if len(names) == 0 {
+			return args, nil
+		}
+		return nil, fmt.Errorf("unsupported type %T for named parameters", arg)
+	}
+	for _, name := range names {
+		index, ok := m.lookup(v.Type(), name)
+		if !ok {
+			return nil, fmt.Errorf("could not find name %s in %#v", name, arg)
+		}
+		args = append(args, fieldByIndex(v, index).Interface())
+	}
+	return args, nil
+}
+
+// driverValue converts an argument to one of the values a driver stores:
+// nil, int64, float64, bool, string, []byte or time.Time
+func driverValue(arg interface{}) (interface{}, error) {
+	if arg == nil {
+		return nil, nil
+	}
+	if valuer, ok := arg.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		return driverValue(v)
+	}
+	switch v := arg.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		return append([]byte(nil), v...), nil
+	}
+	rv := reflect.ValueOf(arg)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return driverValue(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T, a %s", arg, rv.Kind())
+}
+
+// convertAssign stores a driver value in dest, converting between
+// numbers, strings, booleans and times like database/sql does
+func convertAssign(dest reflect.Value, src interface{}) error {
+	if dest.CanAddr() {
+		if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+			return scanner.Scan(src)
+		}
+	}
+	if dest.Kind() == reflect.Ptr {
+		if src == nil {
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		elem := reflect.New(dest.Type().Elem())
+		if err := convertAssign(elem.Elem(), src); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	}
+	if dest.Kind() == reflect.Interface {
+		if src == nil {
+			dest.Set(reflect.Zero(dest.Type()))
+		} else {
+			dest.Set(reflect.ValueOf(src))
+		}
+		return nil
+	}
+	if src == nil {
+		return fmt.Errorf("converting NULL to %s is unsupported", dest.Kind())
+	}
+
+	sv := reflect.ValueOf(src)
+	if dest.Type() == timeType {
+		switch s := src.(type) {
+		case time.Time:
+			dest.Set(sv)
+			return nil
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return fmt.Errorf("converting %q to time.Time: %v", s, err)
+			}
+			dest.Set(reflect.ValueOf(t))
+			return nil
+		}
+		return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %s", src, dest.Type())
+	}
+	if b, ok := src.([]byte); ok && dest.Kind() == reflect.Slice && dest.Type().Elem().Kind() == reflect.Uint8 {
+		dest.SetBytes(append([]byte(nil), b...))
+		return nil
+	}
+
+	text := asString(src)
+	switch dest.Kind() {
+	case reflect.String:
+		dest.SetString(text)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := src.(float64); ok && f == float64(int64(f)) {
+			text = strconv.FormatInt(int64(f), 10)
+		}
+		i, err := strconv.ParseInt(text, 10, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, text, dest.Kind(), numError(err))
+		}
+		dest.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(text, 10, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, text, dest.Kind(), numError(err))
+		}
+		dest.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, text, dest.Kind(), numError(err))
+		}
+		dest.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("converting driver.Value type %T (%q) to a %s: %v", src, text, dest.Kind(), numError(err))
+		}
+		dest.SetBool(b)
+		return nil
+	}
+	if sv.Type().AssignableTo(dest.Type()) {
+		dest.Set(sv)
+		return nil
+	}
+	return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %s", src, dest.Type())
+}
+
+func numError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err
+	}
+	return err
+}
+
+// asString formats a driver value as text
+func asString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case int64:
+		return strconv.FormatInt(s, 10)
+	case float64:
+		return strconv.FormatFloat(s, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(s)
+	case time.Time:
+		return s.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// Statements
+
+type statement interface {
+	numArgs() int
+}
+
+type selectItem struct {
+	star  bool
+	count bool
+	expr  operand
+	name  string
+}
+
+type orderItem struct {
+	expr operand
+	desc bool
+}
+
+type selectStmt struct {
+	table   string
+	items   []selectItem
+	where   condition
+	orderBy []orderItem
+	limit   operand
+	offset  operand
+	args    int
+	refs    []string
+}
+
+type insertStmt struct {
+	table   string
+	columns []string
+	rows    [][]operand
+	args    int
+}
+
+type setClause struct {
+	column string
+	expr   operand
+}
+
+type updateStmt struct {
+	table string
+	sets  []setClause
+	where condition
+	args  int
+	refs  []string
+}
+
+type deleteStmt struct {
+	table string
+	where condition
+	args  int
+	refs  []string
+}
+
+type createStmt struct {
+	table       string
+	columns     []string
+	ifNotExists bool
+}
+
+type dropStmt struct {
+	table    string
+	ifExists bool
+}
+
+func (s *selectStmt) numArgs() int { return s.args }
+func (s *insertStmt) numArgs() int { return s.args }
+func (s *updateStmt) numArgs() int { return s.args }
+func (s *deleteStmt) numArgs() int { return s.args }
+func (s *createStmt) numArgs() int { return 0 }
+func (s *dropStmt) numArgs() int   { return 0 }
+
+// Expressions
+
+type operand interface {
+	value(row map[string]interface{}, args []interface{}) interface{}
+}
+
+type columnRef struct{ name string }
+
+type literal struct{ v interface{} }
+
+type paramRef struct{ index int }
+
+type arithmetic struct {
+	op          string
+	left, right operand
+}
+
+func (c columnRef) value(row map[string]interface{}, args []interface{}) interface{} {
+	v, _ := lookupColumn(row, c.name)
+	return v
+}
+
+func (l literal) value(row map[string]interface{}, args []interface{}) interface{} {
+	return l.v
+}
+
+func (p paramRef) value(row map[string]interface{}, args []interface{}) interface{} {
+	return args[p.index]
+}
+
+func (a arithmetic) value(row map[string]interface{}, args []interface{}) interface{} {
+	l, r := a.left.value(row, args), a.right.value(row, args)
+	if l == nil || r == nil {
+		return nil
+	}
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	if lok && rok {
+		switch a.op {
+		case "+":
+			return li + ri
+		case "-":
+			return li - ri
+		case "*":
+			return li * ri
+		case "/":
+			if ri == 0 {
+				return nil
+			}
+			return li / ri
+		}
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return nil
+	}
+	switch a.op {
+	case "+":
+		return lf + rf
+	case "-":
+		return lf - rf
+	case "*":
+		return lf * rf
+	}
+	if rf == 0 {
+		return nil
+	}
+	return lf / rf
+}
+
+// lookupColumn finds a column in a row, exactly or ignoring case and
+// underscores
+func lookupColumn(row map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := row[name]; ok {
+		return v, true
+	}
+	key := normalizeColumn(name)
+	for k, v := range row {
+		if normalizeColumn(k) == key {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type condition interface {
+	match(row map[string]interface{}, args []interface{}) bool
+}
+
+type andCond struct{ left, right condition }
+
+type orCond struct{ left, right condition }
+
+type notCond struct{ cond condition }
+
+type compareCond struct {
+	op          string
+	left, right operand
+}
+
+type nullCond struct {
+	expr operand
+	not  bool
+}
+
+type inCond struct {
+	expr operand
+	list []operand
+	not  bool
+}
+
+type likeCond struct {
+	expr, pattern   operand
+	not, ignoreCase bool
+}
+
+type betweenCond struct {
+	expr, low, high operand
+	not             bool
+}
+
+type truthCond struct{ expr operand }
+
+func (c andCond) match(row map[string]interface{}, args []interface{}) bool {
+	return c.left.match(row, args) && c.right.match(row, args)
+}
+
+func (c orCond) match(row map[string]interface{}, args []interface{}) bool {
+	return c.left.match(row, args) || c.right.match(row, args)
+}
+
+func (c notCond) match(row map[string]interface{}, args []interface{}) bool {
+	return !c.cond.match(row, args)
+}
+
+func (c compareCond) match(row map[string]interface{}, args []interface{}) bool {
+	l, r := c.left.value(row, args), c.right.value(row, args)
+	if l == nil || r == nil {
+		return false
+	}
+	cmp := compareValues(l, r)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+func (c nullCond) match(row map[string]interface{}, args []interface{}) bool {
+	return (c.expr.value(row, args) == nil) != c.not
+}
+
+func (c inCond) match(row map[string]interface{}, args []interface{}) bool {
+	v := c.expr.value(row, args)
+	if v == nil {
+		return false
+	}
+	for _, item := range c.list {
+		if iv := item.value(row, args); iv != nil && compareValues(v, iv) == 0 {
+			return !c.not
+		}
+	}
+	return c.not
+}
+
+func (c likeCond) match(row map[string]interface{}, args []interface{}) bool {
+	v, p := c.expr.value(row, args), c.pattern.value(row, args)
+	if v == nil || p == nil {
+		return false
+	}
+	s, pattern := asString(v), asString(p)
+	if c.ignoreCase {
+		s, pattern = strings.ToLower(s), strings.ToLower(pattern)
+	}
+	return likeMatch(s, pattern) != c.not
+}
+
+func (c betweenCond) match(row map[string]interface{}, args []interface{}) bool {
+	v, lo, hi := c.expr.value(row, args), c.low.value(row, args), c.high.value(row, args)
+	if v == nil || lo == nil || hi == nil {
+		return false
+	}
+	in := compareValues(v, lo) >= 0 && compareValues(v, hi) <= 0
+	return in != c.not
+}
+
+func (c truthCond) match(row map[string]interface{}, args []interface{}) bool {
+	switch v := c.expr.value(row, args).(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	}
+	return false
+}
+
+// likeMatch matches s against a LIKE pattern with % and _ wildcards
+func likeMatch(s, pattern string) bool {
+	sr, pr := []rune(s), []rune(pattern)
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		for j < len(pr) {
+			switch pr[j] {
+			case '%':
+				for k := i; k <= len(sr); k++ {
+					if match(k, j+1) {
+						return true
+					}
+				}
+				return false
+			case '_':
+				if i >= len(sr) {
+					return false
+				}
+			default:
+				if i >= len(sr) || sr[i] != pr[j] {
+					return false
+				}
+			}
+			i++
+			j++
+		}
+		return i == len(sr)
+	}
+	return match(0, 0)
+}
+
+// compareValues orders two non-NULL values. Numbers compare numerically,
+// also against numeric strings, times chronologically, and anything else
+// by its text.
+func compareValues(a, b interface{}) int {
+	if ai, ok := a.(int64); ok {
+		if bi, ok := b.(int64); ok {
+			return compareInts(ai, bi)
+		}
+	}
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	at, aok := toTime(a)
+	bt, bok := toTime(b)
+	if aok && bok {
+		switch {
+		case at.Before(bt):
+			return -1
+		case at.After(bt):
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(asString(a), asString(b))
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+// evaluateCondition reports whether a record matches a WHERE condition
+// with "?" placeholders, using the same record layout and condition
+// syntax as the GORM emulator's Where
+func evaluateCondition(record map[string]interface{}, condition string, args []interface{}) (bool, error) {
+	p, err := newParser(condition)
+	if err != nil {
+		return false, err
+	}
+	cond, err := p.parseCondition()
+	if err != nil {
+		return false, err
+	}
+	if !p.at(tokEOF) {
+		return false, p.errorf("unexpected %q", p.peek().text)
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if values[i], err = driverValue(arg); err != nil {
+			return false, err
+		}
+	}
+	if p.args != len(values) {
+		return false, fmt.Errorf("sql: expected %d arguments, got %d", p.args, len(values))
+	}
+	return cond.match(record, values), nil
+}
+
+// Execution
+
+type execution struct {
+	store *store
+	args  []interface{}
+}
+
+func (ex *execution) table(name string) (*table, error) {
+	t, ok := ex.store.tables[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("no such table: %s", name)
+	}
+	return t, nil
+}
+
+// checkColumns rejects references to columns a table doesn't have
+func checkColumns(t *table, refs []string, aliases map[string]bool) error {
+	if len(t.columns) == 0 {
+		return nil
+	}
+	for _, ref := range refs {
+		if aliases[ref] {
+			continue
+		}
+		if _, ok := t.column(ref); !ok {
+			return fmt.Errorf("no such column: %s", ref)
+		}
+	}
+	return nil
+}
+
+func (ex *execution) run(stmt statement) (*Rows, sql.Result, error) {
+	switch s := stmt.(type) {
+	case *selectStmt:
+		ex.store.mu.RLock()
+		defer ex.store.mu.RUnlock()
+		rows, err := ex.selectRows(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, result{}, nil
+	}
+
+	ex.store.mu.Lock()
+	defer ex.store.mu.Unlock()
+	var res result
+	var err error
+	switch s := stmt.(type) {
+	case *insertStmt:
+		res, err = ex.insert(s)
+	case *updateStmt:
+		res, err = ex.update(s)
+	case *deleteStmt:
+		res, err = ex.delete(s)
+	case *createStmt:
+		err = ex.create(s)
+	case *dropStmt:
+		err = ex.drop(s)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Rows{closed: true}, res, nil
+}
+
+func (ex *execution) selectRows(s *selectStmt) (*Rows, error) {
+	t, err := ex.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]bool)
+	for _, item := range s.items {
+		if item.name != "" {
+			aliases[item.name] = true
+		}
+	}
+	if err := checkColumns(t, s.refs, aliases); err != nil {
+		return nil, err
+	}
+
+	var matched []map[string]interface{}
+	for _, row := range t.rows {
+		if s.where == nil || s.where.match(row, ex.args) {
+			matched = append(matched, row)
+		}
+	}
+
+	// Columns and values of each result row
+	var columns []string
+	for _, item := range s.items {
+		if item.star {
+			columns = append(columns, t.columns...)
+		} else {
+			columns = append(columns, item.name)
+		}
+	}
+	project := func(row map[string]interface{}) []interface{} {
+		values := make([]interface{}, 0, len(columns))
+		for _, item := range s.items {
+			switch {
+			case item.star:
+				for _, c := range t.columns {
+					values = append(values, row[c])
+				}
+			case item.count:
+				values = append(values, int64(len(matched)))
+			default:
+				values = append(values, item.expr.value(row, ex.args))
+			}
+		}
+		return values
+	}
+
+	for _, item := range s.items {
+		if item.count {
+			// Aggregates collapse the result to one row
+			row := map[string]interface{}{}
+			if len(matched) > 0 {
+				row = matched[0]
+			}
+			return &Rows{columns: columns, data: [][]interface{}{project(row)}}, nil
+		}
+	}
+
+	if len(s.orderBy) > 0 {
+		sorted := append([]map[string]interface{}(nil), matched...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			for _, o := range s.orderBy {
+				a := orderValue(o.expr, s.items, sorted[i], ex.args)
+				b := orderValue(o.expr, s.items, sorted[j], ex.args)
+				cmp := compareNullable(a, b)
+				if cmp == 0 {
+					continue
+				}
+				if o.desc {
+					return cmp > 0
+				}
+				return cmp < 0
+			}
+			return false
+		})
+		matched = sorted
+	}
+
+	if s.offset != nil {
+		n, err := ex.count(s.offset, "OFFSET")
+		if err != nil {
+			return nil, err
+		}
+		if n >= len(matched) {
+			matched = nil
+		} else {
+			matched = matched[n:]
+		}
+	}
+	if s.limit != nil {
+		n, err := ex.count(s.limit, "LIMIT")
+		if err != nil {
+			return nil, err
+		}
+		if n >= 0 && n < len(matched) {
+			matched = matched[:n]
+		}
+	}
+
+	data := make([][]interface{}, len(matched))
+	for i, row := range matched {
+		data[i] = project(row)
+	}
+	return &Rows{columns: columns, data: data}, nil
+}
+
+// orderValue evaluates an ORDER BY item, which may name a select alias
+func orderValue(expr operand, items []selectItem, row map[string]interface{}, args []interface{}) interface{} {
+	if c, ok := expr.(columnRef); ok {
+		if _, found := lookupColumn(row, c.name); !found {
+			for _, item := range items {
+				if item.name == c.name && item.expr != nil {
+					return item.expr.value(row, args)
+				}
+			}
+		}
+	}
+	return expr.value(row, args)
+}
+
+// compareNullable orders values with NULLs first
+func compareNullable(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return compareValues(a, b)
+}
+
+func (ex *execution) count(expr operand, clause string) (int, error) {
+	n, ok := expr.value(nil, ex.args).(int64)
+	if !ok {
+		return 0, fmt.Errorf("%s must be an integer", clause)
+	}
+	return int(n), nil
+}
+
+func (ex *execution) insert(s *insertStmt) (result, error) {
+	t, err := ex.table(s.table)
+	if err != nil {
+		return result{}, err
+	}
+	columns := make([]string, len(s.columns))
+	for i, name := range s.columns {
+		if existing, ok := t.column(name); ok {
+			columns[i] = existing
+		} else if t.declared {
+			return result{}, fmt.Errorf("table %s has no column named %s", s.table, name)
+		} else {
+			columns[i] = name
+		}
+	}
+
+	var res result
+	added := make([]map[string]interface{}, 0, len(s.rows))
+	for _, values := range s.rows {
+		if len(values) != len(columns) {
+			return result{}, fmt.Errorf("%d values for %d columns", len(values), len(columns))
+		}
+		row := make(map[string]interface{}, len(columns))
+		if t.declared {
+			for _, c := range t.columns {
+				row[c] = nil
+			}
+		}
+		for i, c := range columns {
+			row[c] = values[i].value(nil, ex.args)
+		}
+		if idColumn, ok := t.column("id"); ok && row[idColumn] == nil {
+			row[idColumn] = nextID(t, added, idColumn)
+		}
+		if idColumn, ok := t.column("id"); ok {
+			if id, ok := row[idColumn].(int64); ok {
+				res.lastInsertID = id
+			}
+		}
+		added = append(added, row)
+	}
+	for _, c := range columns {
+		t.addColumn(c)
+	}
+	t.rows = append(t.rows, added...)
+	res.rowsAffected = int64(len(added))
+	return res, nil
+}
+
+// nextID returns one more than the largest integer id in the table
+func nextID(t *table, added []map[string]interface{}, idColumn string) int64 {
+	var max int64
+	for _, rows := range [][]map[string]interface{}{t.rows, added} {
+		for _, row := range rows {
+			if id, ok := row[idColumn].(int64); ok && id > max {
+				max = id
+			}
+		}
+	}
+	return max + 1
+}
+
+func (ex *execution) update(s *updateStmt) (result, error) {
+	t, err := ex.table(s.table)
+	if err != nil {
+		return result{}, err
+	}
+	if err := checkColumns(t, s.refs, nil); err != nil {
+		return result{}, err
+	}
+	var res result
+	for _, row := range t.rows {
+		if s.where != nil && !s.where.match(row, ex.args) {
+			continue
+		}
+		// Evaluate every SET expression against the old row first
+		values := make([]interface{}, len(s.sets))
+		for i, set := range s.sets {
+			values[i] = set.expr.value(row, ex.args)
+		}
+		for i, set := range s.sets {
+			column, ok := t.column(set.column)
+			if !ok {
+				column = set.column
+			}
+			row[column] = values[i]
+		}
+		res.rowsAffected++
+	}
+	return res, nil
+}
+
+func (ex *execution) delete(s *deleteStmt) (result, error) {
+	t, err := ex.table(s.table)
+	if err != nil {
+		return result{}, err
+	}
+	if err := checkColumns(t, s.refs, nil); err != nil {
+		return result{}, err
+	}
+	var res result
+	kept := t.rows[:0]
+	for _, row := range t.rows {
+		if s.where == nil || s.where.match(row, ex.args) {
+			res.rowsAffected++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	t.rows = kept
+	return res, nil
+}
+
+func (ex *execution) create(s *createStmt) error {
+	key := strings.ToLower(s.table)
+	if _, ok := ex.store.tables[key]; ok {
+		if s.ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("table %s already exists", s.table)
+	}
+	ex.store.tables[key] = &table{columns: s.columns, declared: true}
+	return nil
+}
+
+func (ex *execution) drop(s *dropStmt) error {
+	key := strings.ToLower(s.table)
+	if _, ok := ex.store.tables[key]; !ok {
+		if s.ifExists {
+			return nil
+		}
+		return fmt.Errorf("no such table: %s", s.table)
+	}
+	delete(ex.store.tables, key)
+	return nil
+}
+
+// Parsing
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokParam
+	tokSymbol
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool // a "quoted" identifier, never a keyword
+}
+
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '\'':
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(query) {
+					return nil, errors.New("unterminated string literal")
+				}
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						b.WriteByte('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(query[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String()})
+		case c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return nil, errors.New("unterminated quoted identifier")
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: query[i+1 : i+1+end], quoted: true})
+			i += end + 2
+		case c == '?':
+			tokens = append(tokens, token{kind: tokParam, text: "?"})
+			i++
+		case (c == '$' || c == '@') && i+1 < len(query):
+			j := i + 1
+			if c == '@' && j < len(query) && query[j] == 'p' {
+				j++
+			}
+			start := j
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j == start {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, token{kind: tokParam, text: query[i:j]})
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i
+			for j < len(query) && (query[j] >= '0' && query[j] <= '9' || query[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: query[i:j]})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(query) && (query[j] == '_' || query[j] == '.' || unicode.IsLetter(rune(query[j])) || query[j] >= '0' && query[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: query[i:j]})
+			i = j
+		default:
+			if i+1 < len(query) {
+				switch two := query[i : i+2]; two {
+				case "<=", ">=", "<>", "!=":
+					tokens = append(tokens, token{kind: tokSymbol, text: two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("=<>(),*+-/;", rune(c)) {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, token{kind: tokSymbol, text: string(c)})
+			i++
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+type parser struct {
+	query  string
+	tokens []token
+	pos    int
+	args   int // number of arguments referenced
+	next   int // index of the next "?" placeholder
+	refs   []string
+}
+
+func newParser(query string) (*parser, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, fmt.Errorf("syntax error in %q: %v", query, err)
+	}
+	return &parser{query: query, tokens: tokens}, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error in %q: %s", p.query, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) at(kind tokenKind) bool {
+	return p.peek().kind == kind
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && !t.quoted && strings.EqualFold(t.text, word)
+}
+
+func (p *parser) acceptKeyword(words ...string) bool {
+	save := p.pos
+	for _, word := range words {
+		if !p.isKeyword(word) {
+			p.pos = save
+			return false
+		}
+		p.advance()
+	}
+	return true
+}
+
+func (p *parser) expectKeyword(words ...string) error {
+	if !p.acceptKeyword(words...) {
+		return p.errorf("expected %s near %q", strings.Join(words, " "), p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if t := p.peek(); t.kind == tokSymbol && t.text == symbol {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.errorf("expected %q near %q", symbol, p.peek().text)
+	}
+	return nil
+}
+
+var reservedWords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"ORDER": true, "BY": true, "LIMIT": true, "OFFSET": true, "AS": true, "IN": true,
+	"IS": true, "NULL": true, "LIKE": true, "ILIKE": true, "BETWEEN": true, "SET": true,
+	"VALUES": true, "INTO": true, "ASC": true, "DESC": true,
+}
+
+func (p *parser) identifier() (string, error) {
+	t := p.peek()
+	if t.kind != tokIdent || (!t.quoted && reservedWords[strings.ToUpper(t.text)]) {
+		return "", p.errorf("expected a name near %q", t.text)
+	}
+	p.advance()
+	return t.text, nil
+}
+
+// column parses a possibly qualified column name and returns its last
+// part, since queries only read one table
+func (p *parser) column() (string, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return "", err
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name, nil
+}
+
+// tableName parses a table name with an optional alias
+func (p *parser) tableName() (string, error) {
+	name, err := p.identifier()
+	if err != nil {
+		return "", err
+	}
+	if p.acceptKeyword("AS") {
+		if _, err := p.identifier(); err != nil {
+			return "", err
+		}
+	} else if t := p.peek(); t.kind == tokIdent && !reservedWords[strings.ToUpper(t.text)] {
+		p.advance()
+	}
+	return name, nil
+}
+
+// parseStatement parses one SQL statement
+func parseStatement(query string) (statement, error) {
+	p, err := newParser(query)
+	if err != nil {
+		return nil, err
+	}
+	var stmt statement
+	switch {
+	case p.acceptKeyword("SELECT"):
+		stmt, err = p.parseSelect()
+	case p.acceptKeyword("INSERT", "INTO"):
+		stmt, err = p.parseInsert()
+	case p.acceptKeyword("UPDATE"):
+		stmt, err = p.parseUpdate()
+	case p.acceptKeyword("DELETE", "FROM"):
+		stmt, err = p.parseDelete()
+	case p.acceptKeyword("CREATE", "TABLE"):
+		stmt, err = p.parseCreate()
+	case p.acceptKeyword("DROP", "TABLE"):
+		stmt, err = p.parseDrop()
+	default:
+		return nil, p.errorf("unsupported statement")
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.acceptSymbol(";")
+	if !p.at(tokEOF) {
+		return nil, p.errorf("unexpected %q", p.peek().text)
+	}
+	return stmt, nil
+}
+
+func (p *parser) parseSelect() (*selectStmt, error) {
+	s := &selectStmt{}
+	for {
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		s.items = append(s.items, item)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	var err error
+	if s.table, err = p.tableName(); err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("WHERE") {
+		if s.where, err = p.parseCondition(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER", "BY") {
+		for {
+			expr, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			item := orderItem{expr: expr}
+			if p.acceptKeyword("DESC") {
+				item.desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			s.orderBy = append(s.orderBy, item)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if s.limit, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("OFFSET") {
+		if s.offset, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+	s.args, s.refs = p.args, p.refs
+	return s, nil
+}
+
+func (p *parser) parseSelectItem() (selectItem, error) {
+	if p.acceptSymbol("*") {
+		return selectItem{star: true}, nil
+	}
+	start := p.pos
+	var item selectItem
+	if p.isKeyword("COUNT") && p.tokens[p.pos+1].text == "(" {
+		p.advance()
+		p.advance()
+		if err := p.expectSymbol("*"); err != nil {
+			return item, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return item, err
+		}
+		item.count = true
+	} else {
+		expr, err := p.parseOperand()
+		if err != nil {
+			return item, err
+		}
+		item.expr = expr
+	}
+	// The result column is named by its alias, its column name, or the
+	// expression's text
+	if p.acceptKeyword("AS") {
+		name, err := p.identifier()
+		if err != nil {
+			return item, err
+		}
+		item.name = name
+	} else if c, ok := item.expr.(columnRef); ok && p.pos == start+1 {
+		item.name = c.name
+	} else {
+		var parts []string
+		for _, t := range p.tokens[start:p.pos] {
+			parts = append(parts, t.text)
+		}
+		item.name = strings.Join(parts, "")
+	}
+	return item, nil
+}
+
+func (p *parser) parseInsert() (*insertStmt, error) {
+	s := &insertStmt{}
+	var err error
+	if s.table, err = p.identifier(); err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	for {
+		column, err := p.column()
+		if err != nil {
+			return nil, err
+		}
+		s.columns = append(s.columns, column)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("VALUES"); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		var values []operand
+		for {
+			v, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		if len(values) != len(s.columns) {
+			return nil, p.errorf("%d values for %d columns", len(values), len(s.columns))
+		}
+		s.rows = append(s.rows, values)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	s.args = p.args
+	return s, nil
+}
+
+func (p *parser) parseUpdate() (*updateStmt, error) {
+	s := &updateStmt{}
+	var err error
+	if s.table, err = p.tableName(); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("SET"); err != nil {
+		return nil, err
+	}
+	for {
+		column, err := p.column()
+		if err != nil {
+			return nil, err
+		}
+		p.refs = append(p.refs, column)
+		if err := p.expectSymbol("="); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		s.sets = append(s.sets, setClause{column: column, expr: expr})
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if p.acceptKeyword("WHERE") {
+		if s.where, err = p.parseCondition(); err != nil {
+			return nil, err
+		}
+	}
+	s.args, s.refs = p.args, p.refs
+	return s, nil
+}
+
+func (p *parser) parseDelete() (*deleteStmt, error) {
+	s := &deleteStmt{}
+	var err error
+	if s.table, err = p.tableName(); err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("WHERE") {
+		if s.where, err = p.parseCondition(); err != nil {
+			return nil, err
+		}
+	}
+	s.args, s.refs = p.args, p.refs
+	return s, nil
+}
+
+var constraintWords = map[string]bool{
+	"PRIMARY": true, "UNIQUE": true, "FOREIGN": true, "CONSTRAINT": true, "CHECK": true, "KEY": true, "INDEX": true,
+}
+
+// parseCreate reads the column names of CREATE TABLE; types and
+// constraints are skipped
+func (p *parser) parseCreate() (*createStmt, error) {
+	s := &createStmt{}
+	s.ifNotExists = p.acceptKeyword("IF", "NOT", "EXISTS")
+	var err error
+	if s.table, err = p.identifier(); err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokIdent {
+			return nil, p.errorf("expected a column definition near %q", t.text)
+		}
+		if t.quoted || !constraintWords[strings.ToUpper(t.text)] {
+			s.columns = append(s.columns, t.text)
+		}
+		// Skip to the end of the definition
+		depth := 0
+		for {
+			t := p.peek()
+			if t.kind == tokEOF {
+				return nil, p.errorf("unterminated column list")
+			}
+			if t.kind == tokSymbol {
+				if t.text == "(" {
+					depth++
+				} else if t.text == ")" {
+					if depth == 0 {
+						break
+					}
+					depth--
+				} else if t.text == "," && depth == 0 {
+					break
+				}
+			}
+			p.advance()
+		}
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (p *parser) parseDrop() (*dropStmt, error) {
+	s := &dropStmt{}
+	s.ifExists = p.acceptKeyword("IF", "EXISTS")
+	var err error
+	s.table, err = p.identifier()
+	return s, err
+}
+
+// parseCondition parses OR of ANDs of predicates
+func (p *parser) parseCondition() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCond{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andCond{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.acceptKeyword("NOT") {
+		cond, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCond{cond}, nil
+	}
+	return p.parsePredicate()
+}
+
+func (p *parser) parsePredicate() (condition, error) {
+	if p.acceptSymbol("(") {
+		cond, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokSymbol {
+		switch t.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=":
+			p.advance()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return compareCond{op: t.text, left: left, right: right}, nil
+		}
+	}
+	if p.acceptKeyword("IS") {
+		not := p.acceptKeyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		return nullCond{expr: left, not: not}, nil
+	}
+	not := p.acceptKeyword("NOT")
+	switch {
+	case p.acceptKeyword("IN"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		var list []operand
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return inCond{expr: left, list: list, not: not}, nil
+	case p.isKeyword("LIKE") || p.isKeyword("ILIKE"):
+		ignoreCase := p.isKeyword("ILIKE")
+		p.advance()
+		pattern, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return likeCond{expr: left, pattern: pattern, not: not, ignoreCase: ignoreCase}, nil
+	case p.acceptKeyword("BETWEEN"):
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCond{expr: left, low: low, high: high, not: not}, nil
+	}
+	if not {
+		return nil, p.errorf("expected IN, LIKE or BETWEEN after NOT near %q", p.peek().text)
+	}
+	return truthCond{left}, nil
+}
+
+// parseOperand parses a value with + - * / applied left to right
+func (p *parser) parseOperand() (operand, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tokSymbol || !strings.Contains("+-*/", t.text) || len(t.text) != 1 {
+			return left, nil
+		}
+		p.advance()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = arithmetic{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parsePrimary() (operand, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.advance()
+		return numberLiteral(p, t.text, false)
+	case tokString:
+		p.advance()
+		return literal{t.text}, nil
+	case tokParam:
+		p.advance()
+		index := p.next
+		if t.text == "?" {
+			p.next++
+		} else {
+			digits := strings.TrimLeft(t.text, "$@p")
+			n, _ := strconv.Atoi(digits)
+			if n < 1 {
+				return nil, p.errorf("bad placeholder %s", t.text)
+			}
+			index = n - 1
+		}
+		if index+1 > p.args {
+			p.args = index + 1
+		}
+		return paramRef{index}, nil
+	case tokSymbol:
+		if t.text == "-" && p.tokens[p.pos+1].kind == tokNumber {
+			p.advance()
+			return numberLiteral(p, p.advance().text, true)
+		}
+	case tokIdent:
+		if !t.quoted {
+			switch strings.ToUpper(t.text) {
+			case "NULL":
+				p.advance()
+				return literal{nil}, nil
+			case "TRUE":
+				p.advance()
+				return literal{true}, nil
+			case "FALSE":
+				p.advance()
+				return literal{false}, nil
+			}
+		}
+		name, err := p.column()
+		if err != nil {
+			return nil, err
+		}
+		p.refs = append(p.refs, name)
+		return columnRef{name}, nil
+	}
+	return nil, p.errorf("expected a value near %q", t.text)
+}
+
+func numberLiteral(p *parser, text string, negative bool) (operand, error) {
+	if negative {
+		text = "-" + text
+	}
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return literal{i}, nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, p.errorf("bad number %s", text)
+	}
+	return literal{f}, nil
+}
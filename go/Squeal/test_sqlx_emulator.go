@@ -0,0 +1,617 @@
+package main
+
+// Developed by PowerShield, as an alternative to sqlx
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+type User struct {
+	ID        int64          `db:"id"`
+	Name      string         `db:"name"`
+	Email     sql.NullString `db:"email"`
+	Age       int            `db:"age"`
+	Active    bool           `db:"active"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+const schema = `CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	email TEXT,
+	age INTEGER,
+	active BOOLEAN,
+	created_at TIMESTAMP
+)`
+
+var epoch = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newUsersDB opens a database with a users table and three users
+func newUsersDB(driverName string) *DB {
+	db := MustConnect(driverName, ":memory:")
+	db.MustExec(schema)
+	insert := db.Rebind(`INSERT INTO users (name, email, age, active, created_at) VALUES (?, ?, ?, ?, ?)`)
+	db.MustExec(insert, "alice", "alice@example.com", 30, true, epoch)
+	db.MustExec(insert, "bob", nil, 25, false, epoch.Add(time.Hour))
+	db.MustExec(insert, "carol", "carol@example.com", 35, true, epoch.Add(2*time.Hour))
+	return db
+}
+
+// Test Exec, Get and Select
+func testExecGetSelect() bool {
+	db := newUsersDB("sqlite3")
+
+	res, err := db.Exec(`INSERT INTO users (name, age, active, created_at) VALUES (?, ?, ?, ?)`, "dave", 41, true, epoch)
+	if err != nil {
+		return false
+	}
+	id, _ := res.LastInsertId()
+	affected, _ := res.RowsAffected()
+	if id != 4 || affected != 1 {
+		return false
+	}
+
+	var user User
+	if err := db.Get(&user, `SELECT * FROM users WHERE name = ?`, "alice"); err != nil {
+		return false
+	}
+	if user.ID != 1 || user.Age != 30 || !user.Active || user.Email.String != "alice@example.com" || !user.CreatedAt.Equal(epoch) {
+		return false
+	}
+
+	var users []User
+	if err := db.Select(&users, `SELECT * FROM users WHERE active = ? ORDER BY age DESC`, true); err != nil {
+		return false
+	}
+	if len(users) != 3 || users[0].Name != "dave" || users[2].Name != "alice" {
+		return false
+	}
+
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM users WHERE age > ?`, 26); err != nil || count != 3 {
+		return false
+	}
+	var names []string
+	if err := db.Select(&names, `SELECT name FROM users ORDER BY name LIMIT 2 OFFSET 1`); err != nil {
+		return false
+	}
+	if strings.Join(names, ",") != "bob,carol" {
+		return false
+	}
+
+	res, err = db.Exec(`UPDATE users SET age = age + 1 WHERE age < ?`, 31)
+	if n, _ := res.RowsAffected(); err != nil || n != 2 {
+		return false
+	}
+	res, err = db.Exec(`DELETE FROM users WHERE name = 'dave'`)
+	if n, _ := res.RowsAffected(); err != nil || n != 1 {
+		return false
+	}
+
+	err = db.Get(&user, `SELECT * FROM users WHERE id = ?`, 99)
+	return err == sql.ErrNoRows
+}
+
+type Base struct {
+	ID        int64     `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Profile struct {
+	Base
+	Name     string
+	Nickname *string `db:"email"`
+	Ignored  string  `db:"-"`
+}
+
+// Test StructScan with db tags, embedded structs and mapped names
+func testStructScan() bool {
+	db := newUsersDB("sqlite3")
+
+	rows, err := db.Queryx(`SELECT id, name, email, created_at FROM users ORDER BY id`)
+	if err != nil {
+		return false
+	}
+	defer rows.Close()
+	var profiles []Profile
+	for rows.Next() {
+		var p Profile
+		if err := rows.StructScan(&p); err != nil {
+			return false
+		}
+		profiles = append(profiles, p)
+	}
+	if rows.Err() != nil || len(profiles) != 3 {
+		return false
+	}
+	if profiles[0].ID != 1 || profiles[0].Name != "alice" || *profiles[0].Nickname != "alice@example.com" {
+		return false
+	}
+	if profiles[1].Nickname != nil || !profiles[2].CreatedAt.Equal(epoch.Add(2*time.Hour)) {
+		return false
+	}
+
+	// Aliases pick the destination field
+	var p Profile
+	err = db.QueryRowx(`SELECT name AS name, email AS email FROM users WHERE id = ?`, 2).StructScan(&p)
+	if err != nil || p.Name != "bob" || p.Nickname != nil {
+		return false
+	}
+
+	// A custom mapper for untagged fields
+	type Row struct {
+		UserName string
+	}
+	db.MapperFunc(strings.ToUpper)
+	var r Row
+	if err := db.Get(&r, `SELECT name AS USERNAME FROM users WHERE id = 3`); err != nil || r.UserName != "carol" {
+		return false
+	}
+
+	var pointers []*User
+	db.MapperFunc(strings.ToLower)
+	return db.Select(&pointers, `SELECT * FROM users WHERE id <= 2`) == nil && len(pointers) == 2 && pointers[1].Name == "bob"
+}
+
+// Test named queries from structs and maps, and batch inserts
+func testNamedQueries() bool {
+	db := newUsersDB("postgres")
+
+	u := User{Name: "dave", Age: 41, Active: true, CreatedAt: epoch}
+	if _, err := db.NamedExec(`INSERT INTO users (name, age, active, created_at) VALUES (:name, :age, :active, :created_at)`, u); err != nil {
+		return false
+	}
+	_, err := db.NamedExec(`UPDATE users SET email = :email WHERE name = :name`,
+		map[string]interface{}{"name": "dave", "email": "dave@example.com"})
+	if err != nil {
+		return false
+	}
+
+	rows, err := db.NamedQuery(`SELECT * FROM users WHERE age >= :min ORDER BY age`, map[string]interface{}{"min": 35})
+	if err != nil {
+		return false
+	}
+	var found []string
+	for rows.Next() {
+		var user User
+		if rows.StructScan(&user) != nil {
+			return false
+		}
+		found = append(found, user.Name+":"+user.Email.String)
+	}
+	if strings.Join(found, ",") != "carol:carol@example.com,dave:dave@example.com" {
+		return false
+	}
+
+	batch := []User{{Name: "erin", Age: 22}, {Name: "frank", Age: 23}}
+	res, err := db.NamedExec(`INSERT INTO users (name, age) VALUES (:name, :age)`, batch)
+	if n, _ := res.RowsAffected(); err != nil || n != 2 {
+		return false
+	}
+	var ages []int
+	if err := db.Select(&ages, `SELECT age FROM users WHERE name IN ($1, $2) ORDER BY age`, "erin", "frank"); err != nil {
+		return false
+	}
+	if len(ages) != 2 || ages[0] != 22 || ages[1] != 23 {
+		return false
+	}
+
+	q, args, err := Named(`SELECT * FROM users WHERE name = :name AND created_at::date > '12:00'`, map[string]interface{}{"name": "x"})
+	if err != nil || q != `SELECT * FROM users WHERE name = ? AND created_at:date > '12:00'` || len(args) != 1 {
+		return false
+	}
+
+	q, _, err = db.BindNamed(`SELECT * FROM users WHERE id = :id AND name = :name`, User{ID: 1, Name: "alice"})
+	if err != nil || q != `SELECT * FROM users WHERE id = $1 AND name = $2` {
+		return false
+	}
+
+	_, err = db.NamedExec(`UPDATE users SET age = :age WHERE name = :name`, map[string]interface{}{"name": "x"})
+	return err != nil && strings.Contains(err.Error(), "could not find name age")
+}
+
+// Test prepared named statements
+func testPrepareNamed() bool {
+	db := newUsersDB("sqlite3")
+
+	stmt, err := db.PrepareNamed(`SELECT * FROM users WHERE active = :active AND age > :age ORDER BY id`)
+	if err != nil {
+		return false
+	}
+	defer stmt.Close()
+	if len(stmt.Params) != 2 || stmt.Params[0] != "active" || stmt.QueryString != `SELECT * FROM users WHERE active = ? AND age > ? ORDER BY id` {
+		return false
+	}
+
+	var users []User
+	if err := stmt.Select(&users, map[string]interface{}{"active": true, "age": 20}); err != nil || len(users) != 2 {
+		return false
+	}
+	var user User
+	if err := stmt.Get(&user, map[string]interface{}{"active": false, "age": 0}); err != nil || user.Name != "bob" {
+		return false
+	}
+	if err := stmt.Get(&user, map[string]interface{}{"active": true, "age": 99}); err != sql.ErrNoRows {
+		return false
+	}
+
+	insert, err := db.PrepareNamed(`INSERT INTO users (name, age) VALUES (:name, :age)`)
+	if err != nil {
+		return false
+	}
+	for _, u := range []User{{Name: "dave", Age: 40}, {Name: "erin", Age: 50}} {
+		if _, err := insert.Exec(&u); err != nil {
+			return false
+		}
+	}
+	var count int
+	return db.Get(&count, `SELECT COUNT(*) FROM users`) == nil && count == 5
+}
+
+// Test prepared statements with positional arguments
+func testPreparex() bool {
+	db := newUsersDB("postgres")
+
+	stmt, err := db.Preparex(`SELECT name FROM users WHERE age BETWEEN $1 AND $2 ORDER BY age`)
+	if err != nil {
+		return false
+	}
+	var names []string
+	if err := stmt.Select(&names, 20, 32); err != nil || strings.Join(names, ",") != "bob,alice" {
+		return false
+	}
+	var name string
+	if err := stmt.Get(&name, 33, 40); err != nil || name != "carol" {
+		return false
+	}
+
+	// The same argument can be referenced twice
+	if err := db.Get(&name, `SELECT name FROM users WHERE age > $1 OR id = $1`, 33); err != nil || name != "carol" {
+		return false
+	}
+
+	update, err := db.Preparex(`UPDATE users SET active = $1 WHERE id = $2`)
+	if err != nil {
+		return false
+	}
+	update.MustExec(true, 2)
+	var active bool
+	if err := db.Get(&active, `SELECT active FROM users WHERE id = $1`, 2); err != nil || !active {
+		return false
+	}
+
+	_, err = stmt.Queryx(20)
+	return err != nil && err.Error() == "sql: expected 2 arguments, got 1"
+}
+
+// Test committing and rolling back transactions
+func testTransactions() bool {
+	db := newUsersDB("sqlite3")
+
+	tx := db.MustBegin()
+	tx.MustExec(`INSERT INTO users (name, age) VALUES (?, ?)`, "dave", 41)
+	if _, err := tx.NamedExec(`UPDATE users SET age = :age WHERE name = :name`, User{Name: "alice", Age: 31}); err != nil {
+		return false
+	}
+	var inTx, outside int
+	tx.Get(&inTx, `SELECT COUNT(*) FROM users`)
+	db.Get(&outside, `SELECT COUNT(*) FROM users`)
+	if inTx != 4 || outside != 3 {
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		return false
+	}
+	var age int
+	if err := db.Get(&age, `SELECT age FROM users WHERE name = 'alice'`); err != nil || age != 31 {
+		return false
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false
+	}
+	tx.MustExec(`DELETE FROM users`)
+	stmt, _ := db.Preparex(`SELECT COUNT(*) FROM users`)
+	var count int
+	if err := tx.Stmtx(stmt).Get(&count); err != nil || count != 0 {
+		return false
+	}
+	if err := tx.Rollback(); err != nil {
+		return false
+	}
+	if err := db.Get(&count, `SELECT COUNT(*) FROM users`); err != nil || count != 4 {
+		return false
+	}
+
+	// A finished transaction can't be used again
+	if _, err := tx.Exec(`DELETE FROM users`); err != sql.ErrTxDone {
+		return false
+	}
+	if tx.Commit() != sql.ErrTxDone || tx.Rollback() != sql.ErrTxDone {
+		return false
+	}
+
+	// The package functions take either a DB or a Tx
+	tx = db.MustBegin()
+	defer tx.Rollback()
+	var names []string
+	if err := Select(tx, &names, `SELECT name FROM users WHERE id < ? ORDER BY id`, 3); err != nil {
+		return false
+	}
+	_, err = NamedExec(db, `UPDATE users SET age = :age WHERE id = :id`, map[string]interface{}{"age": 1, "id": 1})
+	return err == nil && strings.Join(names, ",") == "alice,bob"
+}
+
+// Test In and Rebind
+func testInAndRebind() bool {
+	db := newUsersDB("postgres")
+
+	query, args, err := In(`SELECT * FROM users WHERE id IN (?) AND active = ? ORDER BY id`, []int{1, 3}, true)
+	if err != nil || query != `SELECT * FROM users WHERE id IN (?, ?) AND active = ? ORDER BY id` || len(args) != 3 {
+		return false
+	}
+	query = db.Rebind(query)
+	if query != `SELECT * FROM users WHERE id IN ($1, $2) AND active = $3 ORDER BY id` {
+		return false
+	}
+	var users []User
+	if err := db.Select(&users, query, args...); err != nil || len(users) != 2 || users[1].Name != "carol" {
+		return false
+	}
+
+	if _, _, err := In(`SELECT * FROM users WHERE id IN (?)`, []int{}); err == nil {
+		return false
+	}
+	// []byte is a single value, not a list
+	if q, args, _ := In(`SELECT ?`, []byte("ab")); q != `SELECT ?` || len(args) != 1 {
+		return false
+	}
+
+	if Rebind(AT, `a = ? AND b = '?'`) != `a = @p1 AND b = '?'` || Rebind(NAMED, `a = ?`) != `a = :arg1` {
+		return false
+	}
+	return BindType("postgres") == DOLLAR && BindType("mysql") == QUESTION && BindType("sqlserver") == AT &&
+		BindType("unknown") == UNKNOWN
+}
+
+// Test loading GORM-shaped fixtures
+func testFixtures() bool {
+	db := MustOpen("sqlite3", ":memory:")
+	deleted := epoch.Add(time.Hour)
+	// The same records the GORM emulator keeps for its "users" table
+	err := db.LoadFixtures(map[string][]map[string]interface{}{
+		"users": {
+			{"ID": uint(1), "Name": "alice", "Age": 30, "CreatedAt": epoch, "DeletedAt": nil},
+			{"ID": uint(2), "Name": "bob", "Age": 25, "CreatedAt": epoch, "DeletedAt": &deleted},
+		},
+	})
+	if err != nil {
+		return false
+	}
+
+	var users []User
+	err = db.Unsafe().Select(&users, `SELECT * FROM users WHERE deleted_at IS NULL`)
+	if err != nil || len(users) != 1 || users[0].ID != 1 || users[0].Name != "alice" || !users[0].CreatedAt.Equal(epoch) {
+		return false
+	}
+
+	// Inserted rows use the fixture's columns
+	db.MustExec(`INSERT INTO users (name, age) VALUES (?, ?)`, "carol", 35)
+	table := db.Table("users")
+	if len(table) != 3 || table[2]["Name"] != "carol" || table[2]["ID"] != int64(3) {
+		return false
+	}
+
+	// evaluateCondition takes the GORM emulator's Where conditions
+	match, err := evaluateCondition(table[0], "name = ? AND age > ?", []interface{}{"alice", 18})
+	if err != nil || !match {
+		return false
+	}
+	match, err = evaluateCondition(table[1], "deleted_at IS NULL", nil)
+	if err != nil || match {
+		return false
+	}
+
+	// Declared tables reject unknown fixture columns
+	strict := newUsersDB("sqlite3")
+	err = strict.LoadFixtures(map[string][]map[string]interface{}{"users": {{"Nickname": "al"}}})
+	return err != nil && strings.Contains(err.Error(), "no column named Nickname")
+}
+
+// Test WHERE operators
+func testWhereOperators() bool {
+	db := newUsersDB("sqlite3")
+
+	cases := map[string][]interface{}{
+		`SELECT name FROM users WHERE age >= ? AND age <= ? ORDER BY id`:         {"alice,carol", 30, 35},
+		`SELECT name FROM users WHERE age <> 30 ORDER BY id`:                     {"bob,carol"},
+		`SELECT name FROM users WHERE email IS NULL`:                             {"bob"},
+		`SELECT name FROM users WHERE email IS NOT NULL ORDER BY name DESC`:      {"carol,alice"},
+		`SELECT name FROM users WHERE name NOT IN ('alice', 'bob')`:              {"carol"},
+		`SELECT name FROM users WHERE email LIKE '%@example.com' ORDER BY id`:    {"alice,carol"},
+		`SELECT name FROM users WHERE name ILIKE 'B_B'`:                          {"bob"},
+		`SELECT name FROM users WHERE NOT active`:                                {"bob"},
+		`SELECT name FROM users WHERE (age < 26 OR age > 34) AND NOT (id = 3)`:   {"bob"},
+		`SELECT name FROM users WHERE age NOT BETWEEN 26 AND 34 ORDER BY id`:     {"bob,carol"},
+		`SELECT name FROM users WHERE created_at > ? ORDER BY created_at DESC`:   {"carol,bob", epoch},
+		`SELECT u.name FROM users u WHERE u.age * 2 = 60`:                        {"alice"},
+		`SELECT name FROM users WHERE name = 'o''brien' OR age = -1 OR id = 2.0`: {"bob"},
+	}
+	for query, expected := range cases {
+		var names []string
+		if err := db.Select(&names, query, expected[1:]...); err != nil {
+			fmt.Println("   ", query, err)
+			return false
+		}
+		if strings.Join(names, ",") != expected[0] {
+			fmt.Println("   ", query, names)
+			return false
+		}
+	}
+	return true
+}
+
+// Test error reporting
+func testErrors() bool {
+	db := newUsersDB("sqlite3")
+	var user User
+
+	checks := []struct {
+		err  error
+		want string
+	}{
+		{db.Get(&user, `SELECT * FROM accounts`), "no such table: accounts"},
+		{db.Get(&user, `SELECT * FROM users WHERE nickname = ?`, "x"), "no such column: nickname"},
+		{func() error {
+			_, err := db.Exec(`INSERT INTO users (nickname) VALUES (?)`, "x")
+			return err
+		}(), "table users has no column named nickname"},
+		{db.Get(&user, `SELECT * FROM users WHERE id = ?`), "sql: expected 1 arguments, got 0"},
+		{db.Get(&user, `SELEC * FROM users`), "syntax error"},
+		{db.Get(&user, `SELECT * FROM users WHERE id = ?`, struct{}{}), "unsupported type struct {}, a struct"},
+		{db.Get(user, `SELECT * FROM users`), "must pass a pointer"},
+		{db.Get(&user, `SELECT id, name, email AS contact FROM users`), "missing destination name contact"},
+		{db.Select(&[]User{}, `SELECT * FROM users`, 1), "sql: expected 0 arguments, got 1"},
+		{func() error {
+			var age int
+			return db.Get(&age, `SELECT email FROM users WHERE id = 2`)
+		}(), "converting NULL to int is unsupported"},
+		{func() error {
+			var n int
+			return db.Get(&n, `SELECT id, name FROM users`)
+		}(), "scannable dest type int with >1 columns"},
+	}
+	for _, c := range checks {
+		if c.err == nil || !strings.Contains(c.err.Error(), c.want) {
+			fmt.Println("    expected", c.want, "got", c.err)
+			return false
+		}
+	}
+
+	db.Close()
+	if err := db.Ping(); err == nil || db.Get(&user, `SELECT * FROM users`) == nil {
+		return false
+	}
+	if _, err := Open("", ""); err == nil {
+		return false
+	}
+	return db.Ping().Error() == "sql: database is closed"
+}
+
+// Test MapScan, SliceScan, Scan and Unsafe
+func testScanning() bool {
+	db := newUsersDB("sqlite3")
+
+	row := map[string]interface{}{}
+	if err := db.QueryRowx(`SELECT id, name, email FROM users WHERE id = 2`).MapScan(row); err != nil {
+		return false
+	}
+	if row["id"] != int64(2) || row["name"] != "bob" || row["email"] != nil {
+		return false
+	}
+
+	rows, err := db.Queryx(`SELECT name, age FROM users ORDER BY id`)
+	if err != nil {
+		return false
+	}
+	columns, _ := rows.Columns()
+	if strings.Join(columns, ",") != "name,age" || !rows.Next() {
+		return false
+	}
+	values, err := rows.SliceScan()
+	if err != nil || values[0] != "alice" || values[1] != int64(30) {
+		return false
+	}
+	var name string
+	var age float64
+	if !rows.Next() || rows.Scan(&name, &age) != nil || name != "bob" || age != 25 {
+		return false
+	}
+	var ageText string
+	if !rows.Next() || rows.Scan(&name, &ageText) != nil || ageText != "35" {
+		return false
+	}
+	if rows.Next() {
+		return false
+	}
+
+	// Unsafe skips columns without a field
+	type Partial struct {
+		Name string `db:"name"`
+	}
+	var partials []Partial
+	if err := db.Select(&partials, `SELECT * FROM users`); err == nil {
+		return false
+	}
+	if err := db.Unsafe().Select(&partials, `SELECT * FROM users ORDER BY id DESC`); err != nil || partials[0].Name != "carol" {
+		return false
+	}
+
+	var email sql.NullString
+	var created time.Time
+	if err := db.QueryRowx(`SELECT email, created_at FROM users WHERE id = 2`).Scan(&email, &created); err != nil {
+		return false
+	}
+	return !email.Valid && created.Equal(epoch.Add(time.Hour))
+}
+
+// Test CREATE and DROP TABLE
+func testSchema() bool {
+	db := MustConnect("mysql", "user:pass@/app")
+	db.MustExec("CREATE TABLE IF NOT EXISTS orders (id INT AUTO_INCREMENT, total DECIMAL(10, 2), PRIMARY KEY (id))")
+	db.MustExec("CREATE TABLE IF NOT EXISTS orders (id INT)")
+	if _, err := db.Exec("CREATE TABLE orders (id INT)"); err == nil {
+		return false
+	}
+	db.MustExec("INSERT INTO orders (total) VALUES (?), (?)", 9.5, 20.25)
+	var totals []float64
+	if err := db.Select(&totals, "SELECT total FROM orders ORDER BY total DESC"); err != nil || len(totals) != 2 || totals[0] != 20.25 {
+		return false
+	}
+	var columns []string
+	rows, _ := db.Queryx("SELECT * FROM orders")
+	columns, _ = rows.Columns()
+	if strings.Join(columns, ",") != "id,total" {
+		return false
+	}
+
+	db.MustExec("DROP TABLE orders")
+	db.MustExec("DROP TABLE IF EXISTS orders")
+	if _, err := db.Exec("DROP TABLE orders"); err == nil {
+		return false
+	}
+	return db.DriverName() == "mysql" && db.Table("orders") == nil
+}
+
+func main() {
+	fmt.Println("Running sqlx Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Exec, Get And Select", testExecGetSelect)
+	runTest("StructScan", testStructScan)
+	runTest("Named Queries", testNamedQueries)
+	runTest("PrepareNamed", testPrepareNamed)
+	runTest("Preparex", testPreparex)
+	runTest("Transactions", testTransactions)
+	runTest("In And Rebind", testInAndRebind)
+	runTest("Fixtures", testFixtures)
+	runTest("Where Operators", testWhereOperators)
+	runTest("Errors", testErrors)
+	runTest("Scanning", testScanning)
+	runTest("Schema", testSchema)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,404 @@
+package main
+
+// Developed by PowerShield, as an alternative to joho/godotenv
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Load reads the env files, ".env" if none are named, and sets each
+// variable that is not already in the environment. When files repeat a
+// key, the first one wins.
+func Load(filenames ...string) error {
+	return loadFiles(filenames, false)
+}
+
+// Overload is Load, except it replaces variables that are already set and
+// later files win
+func Overload(filenames ...string) error {
+	return loadFiles(filenames, true)
+}
+
+func loadFiles(filenames []string, overload bool) error {
+	for _, filename := range defaultFilenames(filenames) {
+		envMap, err := readFile(filename)
+		if err != nil {
+			return err
+		}
+		for key, value := range envMap {
+			if _, set := os.LookupEnv(key); set && !overload {
+				continue
+			}
+			if err := os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Read parses the env files, ".env" if none are named, into a map without
+// touching the environment. Later files win.
+func Read(filenames ...string) (map[string]string, error) {
+	envMap := make(map[string]string)
+	for _, filename := range defaultFilenames(filenames) {
+		fileMap, err := readFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileMap {
+			envMap[key] = value
+		}
+	}
+	return envMap, nil
+}
+
+func defaultFilenames(filenames []string) []string {
+	if len(filenames) == 0 {
+		return []string{".env"}
+	}
+	return filenames
+}
+
+func readFile(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// Parse reads an env file from r
+func Parse(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalBytes(data)
+}
+
+// Unmarshal parses the contents of an env file
+func Unmarshal(str string) (map[string]string, error) {
+	return UnmarshalBytes([]byte(str))
+}
+
+// UnmarshalBytes parses the contents of an env file
+func UnmarshalBytes(src []byte) (map[string]string, error) {
+	src = bytes.TrimPrefix(src, []byte("\ufeff"))
+	p := &parser{
+		src:  strings.ReplaceAll(string(src), "\r\n", "\n"),
+		line: 1,
+		vars: make(map[string]string),
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.vars, nil
+}
+
+// parser reads KEY=value statements. Values can refer to variables set
+// earlier in the same file, so they are collected as they are parsed.
+type parser struct {
+	src  string
+	pos  int
+	line int
+	vars map[string]string
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("dotenv: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) advance() {
+	if p.src[p.pos] == '\n' {
+		p.line++
+	}
+	p.pos++
+}
+
+func (p *parser) skipSpaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.advance()
+	}
+}
+
+// skipComment skips a comment to the end of its line
+func (p *parser) skipComment() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) parse() error {
+	for {
+		// Blank lines and comments between statements
+		for c := p.peek(); c == ' ' || c == '\t' || c == '\n' || c == '#'; c = p.peek() {
+			if c == '#' {
+				p.skipComment()
+				continue
+			}
+			p.advance()
+		}
+		if p.pos >= len(p.src) {
+			return nil
+		}
+
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		p.vars[key] = value
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parseKey reads "[export ]KEY" and the "=" or ":" after it
+func (p *parser) parseKey() (string, error) {
+	if rest := p.src[p.pos:]; strings.HasPrefix(rest, "export ") || strings.HasPrefix(rest, "export\t") {
+		p.pos += len("export")
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for c := p.peek(); isNameChar(c) || c == '.' || c == '-'; c = p.peek() {
+		p.advance()
+	}
+	key := p.src[start:p.pos]
+	p.skipSpaces()
+
+	switch c := p.peek(); {
+	case c == '=' || c == ':':
+		if key == "" {
+			return "", p.errorf("zero length variable name")
+		}
+		p.advance()
+		p.skipSpaces()
+		return key, nil
+	case c == 0 || c == '\n':
+		return "", p.errorf("expected '=' after %q", key)
+	default:
+		return "", p.errorf("unexpected character %q in variable name", c)
+	}
+}
+
+func (p *parser) parseValue() (string, error) {
+	switch quote := p.peek(); quote {
+	case '\'', '"':
+		startLine := p.line
+		p.advance()
+		start := p.pos
+		for {
+			if p.pos >= len(p.src) {
+				p.line = startLine
+				return "", p.errorf("unterminated quoted value")
+			}
+			c := p.src[p.pos]
+			if c == quote {
+				break
+			}
+			if c == '\\' && quote == '"' && p.pos+1 < len(p.src) {
+				p.advance()
+			}
+			p.advance()
+		}
+		raw := p.src[start:p.pos]
+		p.advance()
+
+		// Only a comment may follow the closing quote
+		p.skipSpaces()
+		switch p.peek() {
+		case '#':
+			p.skipComment()
+		case 0, '\n':
+		default:
+			return "", p.errorf("unexpected character %q after quoted value", p.peek())
+		}
+		if quote == '\'' {
+			return raw, nil
+		}
+		return p.expand(raw, true), nil
+	default:
+		start := p.pos
+		p.skipComment()
+		raw := p.src[start:p.pos]
+		// " #" starts an inline comment in an unquoted value
+		for i := 0; i < len(raw); i++ {
+			if raw[i] == '#' && i > 0 && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+				raw = raw[:i]
+				break
+			}
+		}
+		return p.expand(strings.TrimSpace(raw), false), nil
+	}
+}
+
+// expand replaces $NAME and ${NAME} with variables set earlier in the
+// file, or else from the environment. Double-quoted values also get
+// backslash escapes; unquoted values only "\$".
+func (p *parser) expand(s string, escapes bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (escapes || s[i+1] == '$'):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(p.lookup(s[i+2 : i+2+end]))
+			i += end + 2
+		case c == '$' && i+1 < len(s) && isNameChar(s[i+1]) && !(s[i+1] >= '0' && s[i+1] <= '9'):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			b.WriteString(p.lookup(s[i+1 : j]))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (p *parser) lookup(name string) string {
+	if value, ok := p.vars[name]; ok {
+		return value
+	}
+	return os.Getenv(name)
+}
+
+// Marshal writes envMap as an env file, sorted by key. Integers are
+// written bare and everything else double-quoted.
+func Marshal(envMap map[string]string) (string, error) {
+	lines := make([]string, 0, len(envMap))
+	for key, value := range envMap {
+		if _, err := strconv.Atoi(value); err == nil {
+			lines = append(lines, key+"="+value)
+		} else {
+			lines = append(lines, key+`="`+doubleQuoteEscape(value)+`"`)
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// doubleQuoteEscape escapes what would otherwise end or change a
+// double-quoted value
+func doubleQuoteEscape(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"\n", `\n`,
+		"\r", `\r`,
+		`"`, `\"`,
+		`$`, `\$`,
+		"`", "\\`",
+		`!`, `\!`,
+	).Replace(value)
+}
+
+// Write writes envMap to filename as an env file
+func Write(envMap map[string]string, filename string) error {
+	content, err := Marshal(envMap)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content + "\n"); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Codec reads and writes env files for emulators that take a format
+// through an interface, such as the Viper emulator's Codec. Like Viper's
+// env format, keys are lower-cased when decoding, and nested maps are
+// flattened into upper-cased KEY_NAME keys when encoding.
+type Codec struct{}
+
+// Decode parses an env file into v
+func (Codec) Decode(data []byte, v map[string]interface{}) error {
+	envMap, err := UnmarshalBytes(data)
+	if err != nil {
+		return err
+	}
+	for key, value := range envMap {
+		v[strings.ToLower(key)] = value
+	}
+	return nil
+}
+
+// Encode writes v as an env file
+func (Codec) Encode(v map[string]interface{}) ([]byte, error) {
+	envMap := make(map[string]string)
+	flattenEnv(v, "", envMap)
+	content, err := Marshal(envMap)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content + "\n"), nil
+}
+
+// flattenEnv joins nested keys with "_" and upper-cases them. Lists are
+// joined with commas.
+func flattenEnv(m map[string]interface{}, prefix string, out map[string]string) {
+	for key, value := range m {
+		key = strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			flattenEnv(typed, key, out)
+		case []interface{}:
+			parts := make([]string, len(typed))
+			for i, item := range typed {
+				parts[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(parts, ",")
+		case []string:
+			out[key] = strings.Join(typed, ",")
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(typed)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+// Developed by PowerShield, as an alternative to joho/godotenv
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// writeFiles creates files in a new temporary directory and returns it
+func writeFiles(files map[string]string) string {
+	dir, err := os.MkdirTemp("", "dotenv")
+	if err != nil {
+		panic(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			panic(err)
+		}
+	}
+	return dir
+}
+
+// unsetAll clears variables a test set in the process environment
+func unsetAll(keys ...string) {
+	for _, key := range keys {
+		os.Unsetenv(key)
+	}
+}
+
+func testParseBasics() bool {
+	env, err := Unmarshal(`
+# database settings
+DB_HOST=localhost
+DB_PORT = 5432
+  DB_USER=admin
+export DB_NAME=app
+EMPTY=
+LOG_LEVEL: debug
+app.name=widget
+`)
+	want := map[string]string{
+		"DB_HOST":   "localhost",
+		"DB_PORT":   "5432",
+		"DB_USER":   "admin",
+		"DB_NAME":   "app",
+		"EMPTY":     "",
+		"LOG_LEVEL": "debug",
+		"app.name":  "widget",
+	}
+	return err == nil && reflect.DeepEqual(env, want)
+}
+
+func testQuoting() bool {
+	env, err := Unmarshal(`SINGLE='literal \n $HOME'
+DOUBLE="tab\there \"quoted\" back\\slash"
+MULTI="first
+second"
+SINGLE_MULTI='a
+b'
+COMMENTED=value # a comment
+HASH=abc#def
+QUOTED_HASH="value # not a comment" # but this is
+SPACED_SINGLE = '  padded  '
+`)
+	return err == nil &&
+		env["SINGLE"] == `literal \n $HOME` &&
+		env["DOUBLE"] == "tab\there \"quoted\" back\\slash" &&
+		env["MULTI"] == "first\nsecond" &&
+		env["SINGLE_MULTI"] == "a\nb" &&
+		env["COMMENTED"] == "value" &&
+		env["HASH"] == "abc#def" &&
+		env["QUOTED_HASH"] == "value # not a comment" &&
+		env["SPACED_SINGLE"] == "  padded  "
+}
+
+func testExpansion() bool {
+	os.Setenv("DOTENV_TEST_USER", "root")
+	defer unsetAll("DOTENV_TEST_USER")
+
+	env, err := Unmarshal(`HOST=db.local
+PORT=5432
+URL=postgres://${DOTENV_TEST_USER}@$HOST:${PORT}/app
+QUOTED="$HOST/${PORT}"
+LITERAL='$HOST'
+ESCAPED=cost \$5
+PRICE=$5
+MISSING=[$DOTENV_TEST_UNSET]
+UNCLOSED=${HOST
+`)
+	return err == nil &&
+		env["URL"] == "postgres://root@db.local:5432/app" &&
+		env["QUOTED"] == "db.local/5432" &&
+		env["LITERAL"] == "$HOST" &&
+		env["ESCAPED"] == "cost $5" &&
+		env["PRICE"] == "$5" &&
+		env["MISSING"] == "[]" &&
+		env["UNCLOSED"] == "${HOST"
+}
+
+func testParseErrors() bool {
+	cases := map[string]string{
+		"A=1\nB=\"open\n":    "line 2: unterminated quoted value",
+		"A=1\nJUST_A_KEY\n":  "line 2: expected '=' after \"JUST_A_KEY\"",
+		"MY KEY=1\n":         "line 1: unexpected character 'K' in variable name",
+		"A=1\n\n=2\n":        "line 3: zero length variable name",
+		"A='x' trailing\n":   "line 1: unexpected character 't' after quoted value",
+		"A=\"a\nb\" c\n":     "line 2: unexpected character 'c' after quoted value",
+		"OK=1\nBAD$NAME=2\n": "line 2: unexpected character '$' in variable name",
+	}
+	for src, want := range cases {
+		_, err := Unmarshal(src)
+		if err == nil || !strings.HasPrefix(err.Error(), "dotenv: ") || !strings.Contains(err.Error(), want) {
+			return false
+		}
+	}
+	return true
+}
+
+func testLineEndings() bool {
+	env, err := UnmarshalBytes([]byte("\ufeffA=1\r\nB=\"two\r\nlines\"\r\n"))
+	if err != nil || env["A"] != "1" || env["B"] != "two\nlines" {
+		return false
+	}
+	fromReader, err := Parse(strings.NewReader("C=3"))
+	return err == nil && fromReader["C"] == "3"
+}
+
+func testRead() bool {
+	dir := writeFiles(map[string]string{
+		".env":       "SHARED=base\nONLY_BASE=1\n",
+		".env.local": "SHARED=local\n",
+	})
+	defer os.RemoveAll(dir)
+
+	env, err := Read(filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local"))
+	if err != nil || !reflect.DeepEqual(env, map[string]string{"SHARED": "local", "ONLY_BASE": "1"}) {
+		return false
+	}
+	if _, err := Read(filepath.Join(dir, "missing.env")); !os.IsNotExist(err) {
+		return false
+	}
+
+	// With no names, .env in the working directory is read
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	env, err = Read()
+	return err == nil && env["SHARED"] == "base" && os.Getenv("ONLY_BASE") == ""
+}
+
+func testLoad() bool {
+	dir := writeFiles(map[string]string{
+		"first.env":  "DOTENV_A=from-first\nDOTENV_B=from-first\n",
+		"second.env": "DOTENV_B=from-second\nDOTENV_C=from-second\n",
+	})
+	defer os.RemoveAll(dir)
+	defer unsetAll("DOTENV_A", "DOTENV_B", "DOTENV_C")
+
+	os.Setenv("DOTENV_A", "already-set")
+	if err := Load(filepath.Join(dir, "first.env"), filepath.Join(dir, "second.env")); err != nil {
+		return false
+	}
+	// Existing variables win, then earlier files
+	return os.Getenv("DOTENV_A") == "already-set" &&
+		os.Getenv("DOTENV_B") == "from-first" &&
+		os.Getenv("DOTENV_C") == "from-second" &&
+		Load(filepath.Join(dir, "missing.env")) != nil
+}
+
+func testOverload() bool {
+	dir := writeFiles(map[string]string{
+		"first.env":  "DOTENV_A=from-first\nDOTENV_B=from-first\n",
+		"second.env": "DOTENV_B=from-second\n",
+	})
+	defer os.RemoveAll(dir)
+	defer unsetAll("DOTENV_A", "DOTENV_B")
+
+	os.Setenv("DOTENV_A", "already-set")
+	if err := Overload(filepath.Join(dir, "first.env"), filepath.Join(dir, "second.env")); err != nil {
+		return false
+	}
+	// Files replace existing variables, and later files win
+	return os.Getenv("DOTENV_A") == "from-first" && os.Getenv("DOTENV_B") == "from-second"
+}
+
+func testMarshal() bool {
+	out, err := Marshal(map[string]string{
+		"PORT":    "8080",
+		"NAME":    "my app",
+		"QUOTES":  `say "hi"`,
+		"MULTI":   "a\nb",
+		"DOLLARS": "$HOME costs $5!",
+		"EMPTY":   "",
+	})
+	want := `DOLLARS="\$HOME costs \$5\!"
+EMPTY=""
+MULTI="a\nb"
+NAME="my app"
+PORT=8080
+QUOTES="say \"hi\""`
+	return err == nil && out == want
+}
+
+func testWriteRoundTrip() bool {
+	dir := writeFiles(nil)
+	defer os.RemoveAll(dir)
+
+	in := map[string]string{
+		"PORT":     "8080",
+		"PASSWORD": `p@$$w0rd!"\`,
+		"CERT":     "-----BEGIN-----\nabc\n-----END-----",
+		"PATH_VAR": "${NOT_EXPANDED}",
+		"SPACES":   "  kept  ",
+		"ZERO":     "007",
+	}
+	path := filepath.Join(dir, "out.env")
+	if err := Write(in, path); err != nil {
+		return false
+	}
+	data, _ := os.ReadFile(path)
+	out, err := Read(path)
+	return err == nil && reflect.DeepEqual(in, out) && strings.HasSuffix(string(data), "\n")
+}
+
+func testCodec() bool {
+	// Codec plugs into the Viper emulator's RegisterCodec
+	var codec interface {
+		Encode(v map[string]interface{}) ([]byte, error)
+		Decode(data []byte, v map[string]interface{}) error
+	} = Codec{}
+
+	config := map[string]interface{}{}
+	if err := codec.Decode([]byte("export APP_PORT=8080\nAPP_URL=\"http://localhost:${APP_PORT}\"\nLog_Level=debug\n"), config); err != nil {
+		return false
+	}
+	if config["app_port"] != "8080" || config["log_level"] != "debug" || config["app_url"] != "http://localhost:8080" || len(config) != 3 {
+		return false
+	}
+	encoded, err := codec.Encode(map[string]interface{}{
+		"name": "api",
+		"server": map[string]interface{}{
+			"port":     8080,
+			"log-file": "/var/log/api.log",
+		},
+		"hosts": []interface{}{"a", "b"},
+		"debug": true,
+	})
+	want := "DEBUG=\"true\"\nHOSTS=\"a,b\"\nNAME=\"api\"\nSERVER_LOG_FILE=\"/var/log/api.log\"\nSERVER_PORT=8080\n"
+	return err == nil && string(encoded) == want && codec.Decode([]byte("BAD KEY=1"), config) != nil
+}
+
+func main() {
+	fmt.Println("Running godotenv Emulator Tests...")
+	fmt.Println("==================================")
+
+	runTest("Parse Basics", testParseBasics)
+	runTest("Quoting", testQuoting)
+	runTest("Expansion", testExpansion)
+	runTest("Parse Errors", testParseErrors)
+	runTest("Line Endings", testLineEndings)
+	runTest("Read", testRead)
+	runTest("Load", testLoad)
+	runTest("Overload", testOverload)
+	runTest("Marshal", testMarshal)
+	runTest("Write Round Trip", testWriteRoundTrip)
+	runTest("Codec", testCodec)
+
+	fmt.Println("==================================")
+	fmt.Println("All tests completed!")
+}
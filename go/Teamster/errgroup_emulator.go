@@ -0,0 +1,605 @@
+package main
+
+// Developed by PowerShield, as an alternative to errgroup and Go worker pool libraries
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Error groups, as in golang.org/x/sync/errgroup
+
+// PanicError is what Wait panics with when a goroutine of the group
+// panicked with an error
+type PanicError struct {
+	Recovered error
+	Stack     []byte
+}
+
+func (p PanicError) Error() string {
+	return fmt.Sprintf("recovered from errgroup.Group: %v\n%s", p.Recovered, p.Stack)
+}
+
+func (p PanicError) Unwrap() error { return p.Recovered }
+
+// PanicValue is what Wait panics with when a goroutine of the group
+// panicked with something other than an error
+type PanicValue struct {
+	Recovered interface{}
+	Stack     []byte
+}
+
+func (p PanicValue) String() string {
+	if len(p.Stack) > 0 {
+		return fmt.Sprintf("recovered from errgroup.Group: %v\n%s", p.Recovered, p.Stack)
+	}
+	return fmt.Sprintf("recovered from errgroup.Group: %v", p.Recovered)
+}
+
+// Group runs goroutines working on subtasks of a common task. The zero
+// value is a valid Group with no limit that does not cancel anything.
+type Group struct {
+	cancel func(error)
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	errOnce sync.Once
+	err     error
+
+	mu    sync.Mutex
+	panic interface{}
+}
+
+// WithContext returns a Group and a context derived from ctx that is
+// canceled when a goroutine of the group first returns an error or
+// panics, or when Wait returns, whichever happens first
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// Go runs f in a new goroutine. It blocks while the group has as many
+// active goroutines as its limit. The first error f returns cancels the
+// group's context and is returned by Wait.
+func (g *Group) Go(f func() error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.wg.Add(1)
+	go g.run(f)
+}
+
+// TryGo runs f in a new goroutine if the group is below its limit, and
+// reports whether it did
+func (g *Group) TryGo(f func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.wg.Add(1)
+	go g.run(f)
+	return true
+}
+
+func (g *Group) run(f func() error) {
+	defer g.done()
+	normalReturn := false
+	defer func() {
+		if normalReturn {
+			return
+		}
+		v := recover()
+		g.mu.Lock()
+		if g.panic == nil {
+			stack := debug.Stack()
+			if err, ok := v.(error); ok {
+				g.panic = PanicError{Recovered: err, Stack: stack}
+			} else {
+				g.panic = PanicValue{Recovered: v, Stack: stack}
+			}
+		}
+		g.mu.Unlock()
+		if g.cancel != nil {
+			g.cancel(fmt.Errorf("errgroup: goroutine panicked: %v", v))
+		}
+	}()
+
+	err := f()
+	normalReturn = true
+	if err != nil {
+		g.errOnce.Do(func() {
+			g.err = err
+			if g.cancel != nil {
+				g.cancel(g.err)
+			}
+		})
+	}
+}
+
+// Wait blocks until every goroutine of the group has returned, then
+// returns the first error, if any. If a goroutine panicked, Wait panics
+// with a PanicError or PanicValue instead.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	if g.panic != nil {
+		panic(g.panic)
+	}
+	return g.err
+}
+
+// SetLimit limits the group to n active goroutines; a negative n means
+// no limit. It panics if goroutines are active.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if g.sem != nil && len(g.sem) != 0 {
+		panic(fmt.Errorf("errgroup: modify limit while %v goroutines in the group are still active", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Worker pools
+
+// ErrPoolStopped is returned when submitting to a stopped pool
+var ErrPoolStopped = errors.New("worker pool has been stopped and is no longer accepting tasks")
+
+const defaultIdleTimeout = 5 * time.Second
+
+// PoolOption configures a Pool
+type PoolOption func(*Pool)
+
+// MinWorkers keeps at least n workers running, even when idle
+func MinWorkers(n int) PoolOption {
+	return func(p *Pool) { p.minWorkers = n }
+}
+
+// IdleTimeout is how long a worker above the minimum waits for a task
+// before exiting. The default is 5 seconds.
+func IdleTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) { p.idleTimeout = d }
+}
+
+// PanicHandler is called with the value a task panicked with. The
+// default prints it and the stack to stderr.
+func PanicHandler(handler func(interface{})) PoolOption {
+	return func(p *Pool) { p.panicHandler = handler }
+}
+
+// Context stops the pool, discarding its queued tasks, when ctx is done
+func Context(ctx context.Context) PoolOption {
+	return func(p *Pool) { p.ctx = ctx }
+}
+
+func defaultPanicHandler(v interface{}) {
+	fmt.Fprintf(os.Stderr, "Worker exits from a panic: %v\nStack trace: %s\n", v, debug.Stack())
+}
+
+// Pool runs tasks on a bounded number of workers. Workers are started as
+// tasks arrive, up to the maximum, and exit after an idle timeout down
+// to the minimum. Tasks that find every worker busy wait in a queue of
+// bounded capacity.
+type Pool struct {
+	maxWorkers   int
+	maxCapacity  int
+	minWorkers   int
+	idleTimeout  time.Duration
+	panicHandler func(interface{})
+	ctx          context.Context
+
+	mu       sync.Mutex
+	workCond *sync.Cond
+	roomCond *sync.Cond
+	queue    []func()
+	running  int
+	idle     int
+	stopped  bool
+	stopCh   chan struct{}
+	workers  sync.WaitGroup
+	// busy counts the tasks queued or running
+	busy     int
+	idleCond *sync.Cond
+
+	submitted  uint64
+	successful uint64
+	failed     uint64
+}
+
+// NewPool returns a pool of at most maxWorkers workers, with room for
+// maxCapacity waiting tasks. A maxWorkers below 1 means the number of
+// CPUs.
+func NewPool(maxWorkers, maxCapacity int, options ...PoolOption) *Pool {
+	if maxWorkers < 1 {
+		maxWorkers = runtime.NumCPU()
+	}
+	if maxCapacity < 0 {
+		maxCapacity = 0
+	}
+	p := &Pool{
+		maxWorkers:   maxWorkers,
+		maxCapacity:  maxCapacity,
+		idleTimeout:  defaultIdleTimeout,
+		panicHandler: defaultPanicHandler,
+		ctx:          context.Background(),
+		stopCh:       make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(p)
+	}
+	if p.minWorkers > p.maxWorkers {
+		p.minWorkers = p.maxWorkers
+	}
+	p.workCond = sync.NewCond(&p.mu)
+	p.roomCond = sync.NewCond(&p.mu)
+	p.idleCond = sync.NewCond(&p.mu)
+
+	p.mu.Lock()
+	for p.running < p.minWorkers {
+		p.startWorker()
+	}
+	p.mu.Unlock()
+	if p.ctx.Done() != nil {
+		go func() {
+			select {
+			case <-p.ctx.Done():
+				p.Stop()
+			case <-p.stopCh:
+			}
+		}()
+	}
+	return p
+}
+
+// startWorker starts a worker; the caller holds p.mu
+func (p *Pool) startWorker() {
+	p.running++
+	p.workers.Add(1)
+	go p.worker()
+}
+
+// hasRoom reports whether a task can be accepted now, starting a worker
+// for it if needed; the caller holds p.mu
+func (p *Pool) hasRoom() bool {
+	if len(p.queue) < p.idle {
+		return true
+	}
+	if p.running < p.maxWorkers {
+		p.startWorker()
+		return true
+	}
+	return len(p.queue)-p.idle < p.maxCapacity
+}
+
+// Submit queues task, waiting while the pool is full. It returns
+// ErrPoolStopped if the pool is stopped before task is queued.
+func (p *Pool) Submit(task func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.stopped && !p.hasRoom() {
+		p.roomCond.Wait()
+	}
+	if p.stopped {
+		return ErrPoolStopped
+	}
+	p.enqueue(task)
+	return nil
+}
+
+// TrySubmit queues task if the pool has room, and reports whether it did
+func (p *Pool) TrySubmit(task func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped || !p.hasRoom() {
+		return false
+	}
+	p.enqueue(task)
+	return true
+}
+
+// SubmitAndWait queues task and waits for it to finish
+func (p *Pool) SubmitAndWait(task func()) error {
+	done := make(chan struct{})
+	if err := p.Submit(func() {
+		defer close(done)
+		task()
+	}); err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// enqueue adds a task for the workers; the caller holds p.mu
+func (p *Pool) enqueue(task func()) {
+	p.queue = append(p.queue, task)
+	p.submitted++
+	p.busy++
+	p.workCond.Signal()
+}
+
+// worker runs queued tasks until the pool stops with an empty queue, or
+// it has been idle for the idle timeout and is above the minimum
+func (p *Pool) worker() {
+	defer p.workers.Done()
+	p.mu.Lock()
+	for {
+		deadline := time.Now().Add(p.idleTimeout)
+		for len(p.queue) == 0 && !p.stopped {
+			if !time.Now().Before(deadline) && p.running > p.minWorkers {
+				p.running--
+				p.mu.Unlock()
+				return
+			}
+			p.idle++
+			timer := time.AfterFunc(time.Until(deadline), p.workCond.Broadcast)
+			p.workCond.Wait()
+			timer.Stop()
+			p.idle--
+		}
+		if len(p.queue) == 0 {
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+		task := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.roomCond.Signal()
+		p.mu.Unlock()
+
+		ok := p.run(task)
+
+		p.mu.Lock()
+		if ok {
+			p.successful++
+		} else {
+			p.failed++
+		}
+		p.busy--
+		if p.busy == 0 {
+			p.idleCond.Broadcast()
+		}
+	}
+}
+
+// run runs a task, handing a panic to the panic handler
+func (p *Pool) run(task func()) (ok bool) {
+	defer func() {
+		if !ok {
+			p.panicHandler(recover())
+		}
+	}()
+	task()
+	return true
+}
+
+// stop stops accepting tasks, discarding the queue if discard is set
+func (p *Pool) stop(discard bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.stopCh)
+	}
+	if discard {
+		p.busy -= len(p.queue)
+		p.queue = nil
+		if p.busy == 0 {
+			p.idleCond.Broadcast()
+		}
+	}
+	p.workCond.Broadcast()
+	p.roomCond.Broadcast()
+}
+
+// Stop stops the pool: it accepts no more tasks and drops the queued
+// ones. Running tasks finish in the background.
+func (p *Pool) Stop() {
+	p.stop(true)
+}
+
+// StopAndWait stops the pool and drains it: the queued tasks still run,
+// and StopAndWait returns once every task and worker is done
+func (p *Pool) StopAndWait() {
+	p.stop(false)
+	p.workers.Wait()
+}
+
+// StopAndWaitFor drains the pool as StopAndWait does, for at most
+// timeout. Tasks still queued then are dropped, and running ones finish
+// in the background.
+func (p *Pool) StopAndWaitFor(timeout time.Duration) {
+	p.stop(false)
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		p.stop(true)
+	}
+}
+
+// Wait blocks until no task is queued or running, without stopping the
+// pool
+func (p *Pool) Wait() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.busy > 0 {
+		p.idleCond.Wait()
+	}
+}
+
+// Stopped reports whether the pool has been stopped
+func (p *Pool) Stopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+// Pool metrics
+
+// MinWorkers returns the minimum number of workers
+func (p *Pool) MinWorkers() int { return p.minWorkers }
+
+// MaxWorkers returns the maximum number of workers
+func (p *Pool) MaxWorkers() int { return p.maxWorkers }
+
+// MaxCapacity returns the number of tasks that can wait in the queue
+func (p *Pool) MaxCapacity() int { return p.maxCapacity }
+
+// RunningWorkers returns the number of workers, busy or idle
+func (p *Pool) RunningWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
+
+// IdleWorkers returns the number of workers waiting for a task
+func (p *Pool) IdleWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.idle
+}
+
+// SubmittedTasks returns the number of tasks accepted so far
+func (p *Pool) SubmittedTasks() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.submitted
+}
+
+// WaitingTasks returns the number of queued tasks
+func (p *Pool) WaitingTasks() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return uint64(len(p.queue))
+}
+
+// SuccessfulTasks returns the number of tasks that returned normally
+func (p *Pool) SuccessfulTasks() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.successful
+}
+
+// FailedTasks returns the number of tasks that panicked
+func (p *Pool) FailedTasks() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failed
+}
+
+// CompletedTasks returns the number of tasks that ran, successfully or
+// not
+func (p *Pool) CompletedTasks() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.successful + p.failed
+}
+
+// Task groups
+
+// TaskGroup tracks a set of tasks submitted to a pool
+type TaskGroup struct {
+	pool *Pool
+	wg   sync.WaitGroup
+}
+
+// Group returns a new, empty group of tasks on the pool
+func (p *Pool) Group() *TaskGroup {
+	return &TaskGroup{pool: p}
+}
+
+// Submit adds a task to the group and the pool
+func (g *TaskGroup) Submit(task func()) error {
+	g.wg.Add(1)
+	err := g.pool.Submit(func() {
+		defer g.wg.Done()
+		task()
+	})
+	if err != nil {
+		g.wg.Done()
+	}
+	return err
+}
+
+// Wait blocks until every task of the group has finished
+func (g *TaskGroup) Wait() {
+	g.wg.Wait()
+}
+
+// TaskGroupWithContext tracks tasks that can fail: the first error
+// cancels the group's context, and tasks that have not started by then
+// are skipped
+type TaskGroupWithContext struct {
+	pool   *Pool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// GroupContext returns a new, empty group of tasks on the pool, and the
+// context it cancels on the first error
+func (p *Pool) GroupContext(ctx context.Context) (*TaskGroupWithContext, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &TaskGroupWithContext{pool: p, ctx: ctx, cancel: cancel}, ctx
+}
+
+// Submit adds a task to the group and the pool
+func (g *TaskGroupWithContext) Submit(task func() error) error {
+	g.wg.Add(1)
+	err := g.pool.Submit(func() {
+		defer g.wg.Done()
+		if err := g.ctx.Err(); err != nil {
+			g.fail(err)
+			return
+		}
+		if err := task(); err != nil {
+			g.fail(err)
+		}
+	})
+	if err != nil {
+		g.wg.Done()
+	}
+	return err
+}
+
+func (g *TaskGroupWithContext) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
+
+// Wait blocks until every task of the group has finished or been
+// skipped, and returns the first error
+func (g *TaskGroupWithContext) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
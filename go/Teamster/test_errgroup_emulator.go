@@ -0,0 +1,450 @@
+package main
+
+// Developed by PowerShield, as an alternative to errgroup and Go worker pool libraries
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+// recoverWait calls Wait and returns what it panicked with, if anything
+func recoverWait(g *Group) (err error, recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	return g.Wait(), nil
+}
+
+func testGroupWait() bool {
+	var g Group
+	var count int32
+	for i := 0; i < 10; i++ {
+		g.Go(func() error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return false
+	}
+	return atomic.LoadInt32(&count) == 10
+}
+
+func testGroupFirstError() bool {
+	var g Group
+	first := errors.New("first")
+	started := make(chan struct{})
+	g.Go(func() error {
+		close(started)
+		return first
+	})
+	g.Go(func() error {
+		<-started
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("second")
+	})
+	return g.Wait() == first
+}
+
+func testWithContextCancel() bool {
+	g, ctx := WithContext(context.Background())
+	boom := errors.New("boom")
+	g.Go(func() error {
+		return boom
+	})
+	g.Go(func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("not canceled")
+		}
+	})
+	if err := g.Wait(); err != boom {
+		return false
+	}
+	if context.Cause(ctx) != boom {
+		return false
+	}
+
+	// Wait cancels the context even without errors
+	g, ctx = WithContext(context.Background())
+	g.Go(func() error { return nil })
+	if g.Wait() != nil {
+		return false
+	}
+	return ctx.Err() == context.Canceled
+}
+
+func testSetLimit() bool {
+	var g Group
+	g.SetLimit(2)
+	var active, peak int32
+	for i := 0; i < 8; i++ {
+		g.Go(func() error {
+			n := atomic.AddInt32(&active, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			return nil
+		})
+	}
+	if g.Wait() != nil || atomic.LoadInt32(&peak) != 2 {
+		return false
+	}
+
+	// The limit cannot change while goroutines run
+	release := make(chan struct{})
+	g.Go(func() error {
+		<-release
+		return nil
+	})
+	panicked := func() (p bool) {
+		defer func() { p = recover() != nil }()
+		g.SetLimit(4)
+		return false
+	}()
+	close(release)
+	g.Wait()
+	g.SetLimit(-1)
+	return panicked
+}
+
+func testTryGo() bool {
+	var g Group
+	g.SetLimit(1)
+	release := make(chan struct{})
+	if !g.TryGo(func() error {
+		<-release
+		return nil
+	}) {
+		return false
+	}
+	if g.TryGo(func() error { return nil }) {
+		return false
+	}
+	close(release)
+	g.Wait()
+	ran := false
+	if !g.TryGo(func() error {
+		ran = true
+		return nil
+	}) {
+		return false
+	}
+	return g.Wait() == nil && ran
+}
+
+func testGroupPanic() bool {
+	g, ctx := WithContext(context.Background())
+	g.Go(func() error {
+		panic("kaboom")
+	})
+	_, recovered := recoverWait(g)
+	pv, ok := recovered.(PanicValue)
+	if !ok || pv.Recovered != "kaboom" || len(pv.Stack) == 0 {
+		return false
+	}
+	if ctx.Err() == nil {
+		return false
+	}
+
+	var g2 Group
+	cause := errors.New("bad state")
+	g2.Go(func() error {
+		panic(cause)
+	})
+	_, recovered = recoverWait(&g2)
+	pe, ok := recovered.(PanicError)
+	return ok && errors.Is(pe, cause) && strings.Contains(pe.Error(), "bad state")
+}
+
+func testPoolRunsTasks() bool {
+	pool := NewPool(4, 100)
+	var sum int64
+	for i := 1; i <= 100; i++ {
+		n := int64(i)
+		if err := pool.Submit(func() {
+			atomic.AddInt64(&sum, n)
+		}); err != nil {
+			return false
+		}
+	}
+	pool.StopAndWait()
+	if atomic.LoadInt64(&sum) != 5050 {
+		return false
+	}
+	return pool.SubmittedTasks() == 100 && pool.CompletedTasks() == 100 &&
+		pool.SuccessfulTasks() == 100 && pool.RunningWorkers() == 0
+}
+
+func testPoolBoundsWorkers() bool {
+	pool := NewPool(3, 10)
+	var active, peak int32
+	var mu sync.Mutex
+	for i := 0; i < 12; i++ {
+		pool.Submit(func() {
+			n := atomic.AddInt32(&active, 1)
+			mu.Lock()
+			if n > peak {
+				peak = n
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		})
+	}
+	if pool.RunningWorkers() != 3 {
+		return false
+	}
+	pool.StopAndWait()
+	return peak == 3 && pool.MaxWorkers() == 3 && pool.MaxCapacity() == 10
+}
+
+func testTrySubmitFull() bool {
+	pool := NewPool(1, 1)
+	release := make(chan struct{})
+	if !pool.TrySubmit(func() { <-release }) {
+		return false
+	}
+	if !waitFor(func() bool { return pool.WaitingTasks() == 0 }) {
+		return false
+	}
+	// One running, one queued, then full
+	if !pool.TrySubmit(func() {}) {
+		return false
+	}
+	if pool.TrySubmit(func() {}) {
+		return false
+	}
+	if pool.WaitingTasks() != 1 {
+		return false
+	}
+
+	// Submit waits for room instead
+	submitted := make(chan error)
+	go func() {
+		submitted <- pool.Submit(func() {})
+	}()
+	select {
+	case <-submitted:
+		return false
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if <-submitted != nil {
+		return false
+	}
+	pool.StopAndWait()
+	return pool.CompletedTasks() == 3
+}
+
+func testPoolPanicCapture() bool {
+	var mu sync.Mutex
+	var captured []interface{}
+	pool := NewPool(2, 10, PanicHandler(func(v interface{}) {
+		mu.Lock()
+		captured = append(captured, v)
+		mu.Unlock()
+	}))
+	pool.Submit(func() { panic("task failed") })
+	var ran int32
+	for i := 0; i < 5; i++ {
+		pool.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	pool.StopAndWait()
+	if len(captured) != 1 || captured[0] != "task failed" {
+		return false
+	}
+	// The worker survives the panic
+	return atomic.LoadInt32(&ran) == 5 && pool.FailedTasks() == 1 && pool.SuccessfulTasks() == 5
+}
+
+func testGracefulDrain() bool {
+	pool := NewPool(1, 10)
+	var ran int32
+	for i := 0; i < 5; i++ {
+		pool.Submit(func() {
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+	pool.StopAndWait()
+	if atomic.LoadInt32(&ran) != 5 || !pool.Stopped() {
+		return false
+	}
+	if pool.Submit(func() {}) != ErrPoolStopped || pool.TrySubmit(func() {}) {
+		return false
+	}
+
+	// StopAndWaitFor drops what is still queued at the deadline
+	pool = NewPool(1, 10)
+	release := make(chan struct{})
+	ran = 0
+	pool.Submit(func() { <-release })
+	if !waitFor(func() bool { return pool.WaitingTasks() == 0 }) {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		pool.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	pool.StopAndWaitFor(20 * time.Millisecond)
+	close(release)
+	if !waitFor(func() bool { return pool.RunningWorkers() == 0 }) {
+		return false
+	}
+	return atomic.LoadInt32(&ran) == 0 && pool.WaitingTasks() == 0
+}
+
+func testStopDiscards() bool {
+	pool := NewPool(1, 10)
+	release := make(chan struct{})
+	var ran int32
+	pool.Submit(func() {
+		<-release
+		atomic.AddInt32(&ran, 1)
+	})
+	if !waitFor(func() bool { return pool.WaitingTasks() == 0 }) {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		pool.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	pool.Stop()
+	close(release)
+	if !waitFor(func() bool { return pool.RunningWorkers() == 0 }) {
+		return false
+	}
+	if atomic.LoadInt32(&ran) != 1 {
+		return false
+	}
+
+	// A canceled pool context stops the pool too
+	ctx, cancel := context.WithCancel(context.Background())
+	pool = NewPool(2, 10, Context(ctx))
+	cancel()
+	return waitFor(pool.Stopped)
+}
+
+func testIdleWorkers() bool {
+	pool := NewPool(4, 10, MinWorkers(1), IdleTimeout(10*time.Millisecond))
+	if pool.RunningWorkers() != 1 {
+		return false
+	}
+	release := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		pool.Submit(func() { <-release })
+	}
+	if pool.RunningWorkers() != 4 {
+		return false
+	}
+	close(release)
+	pool.Wait()
+	// Workers above the minimum exit once idle
+	if !waitFor(func() bool { return pool.RunningWorkers() == 1 }) {
+		return false
+	}
+	if !waitFor(func() bool { return pool.IdleWorkers() == 1 }) {
+		return false
+	}
+	ran := false
+	pool.SubmitAndWait(func() { ran = true })
+	pool.StopAndWait()
+	return ran && pool.RunningWorkers() == 0
+}
+
+func testTaskGroup() bool {
+	pool := NewPool(4, 20)
+	defer pool.StopAndWait()
+	group := pool.Group()
+	results := make([]int, 10)
+	for i := range results {
+		i := i
+		group.Submit(func() {
+			results[i] = i * i
+		})
+	}
+	group.Wait()
+	for i, r := range results {
+		if r != i*i {
+			return false
+		}
+	}
+	return true
+}
+
+func testTaskGroupContext() bool {
+	pool := NewPool(1, 20)
+	defer pool.StopAndWait()
+	group, ctx := pool.GroupContext(context.Background())
+	boom := errors.New("boom")
+	var ran int32
+	group.Submit(func() error {
+		return boom
+	})
+	for i := 0; i < 5; i++ {
+		group.Submit(func() error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != boom {
+		return false
+	}
+	// With one worker the later tasks start after the failure, and are skipped
+	return atomic.LoadInt32(&ran) == 0 && ctx.Err() != nil
+}
+
+func main() {
+	fmt.Println("Running errgroup Emulator Tests...")
+	fmt.Println("==================================")
+
+	runTest("Group Wait", testGroupWait)
+	runTest("Group First Error", testGroupFirstError)
+	runTest("WithContext Cancel", testWithContextCancel)
+	runTest("SetLimit", testSetLimit)
+	runTest("TryGo", testTryGo)
+	runTest("Group Panic", testGroupPanic)
+	runTest("Pool Runs Tasks", testPoolRunsTasks)
+	runTest("Pool Bounds Workers", testPoolBoundsWorkers)
+	runTest("TrySubmit Full", testTrySubmitFull)
+	runTest("Pool Panic Capture", testPoolPanicCapture)
+	runTest("Graceful Drain", testGracefulDrain)
+	runTest("Stop Discards", testStopDiscards)
+	runTest("Idle Workers", testIdleWorkers)
+	runTest("Task Group", testTaskGroup)
+	runTest("Task Group Context", testTaskGroupContext)
+
+	fmt.Println("==================================")
+	fmt.Println("All tests completed!")
+}
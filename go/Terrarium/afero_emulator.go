@@ -0,0 +1,1049 @@
+package main
+
+// Developed by PowerShield, as an alternative to afero
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// File is an open file, as returned by an Fs. *os.File implements it.
+type File interface {
+	io.Closer
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Writer
+	io.WriterAt
+
+	Name() string
+	Readdir(count int) ([]os.FileInfo, error)
+	Readdirnames(n int) ([]string, error)
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+	WriteString(s string) (ret int, err error)
+}
+
+// Fs is a file system, with the operations of package os
+type Fs interface {
+	// Create creates or truncates the named file
+	Create(name string) (File, error)
+	// Mkdir creates a directory whose parent exists
+	Mkdir(name string, perm os.FileMode) error
+	// MkdirAll creates a directory and any missing parents
+	MkdirAll(path string, perm os.FileMode) error
+	// Open opens a file for reading
+	Open(name string) (File, error)
+	// OpenFile opens a file with os.O_* flags and creates it with perm
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// Remove removes a file or an empty directory
+	Remove(name string) error
+	// RemoveAll removes a path and everything below it
+	RemoveAll(path string) error
+	// Rename moves a file or directory
+	Rename(oldname, newname string) error
+	// Stat describes the named file
+	Stat(name string) (os.FileInfo, error)
+	// Name names the file system
+	Name() string
+	// Chmod changes the permission bits
+	Chmod(name string, mode os.FileMode) error
+	// Chown changes the owner and group
+	Chown(name string, uid, gid int) error
+	// Chtimes changes the access and modification times
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+var (
+	// ErrFileClosed is returned by operations on a closed file
+	ErrFileClosed = errors.New("File is closed")
+	// ErrOutOfRange is returned by seeks before the start of a file
+	ErrOutOfRange = errors.New("out of range")
+	// ErrTooLarge is returned when a file would grow too large
+	ErrTooLarge = errors.New("too large")
+	// ErrFileNotFound is returned for missing files
+	ErrFileNotFound = os.ErrNotExist
+	// ErrFileExists is returned for files that should not exist yet
+	ErrFileExists = os.ErrExist
+	// ErrDestinationExists is returned when renaming onto a non-empty
+	// directory
+	ErrDestinationExists = os.ErrExist
+)
+
+// OS file system
+
+// OsFs is the file system of the operating system, through package os
+type OsFs struct{}
+
+// NewOsFs returns the operating system's file system
+func NewOsFs() Fs {
+	return &OsFs{}
+}
+
+// Name returns "OsFs"
+func (OsFs) Name() string { return "OsFs" }
+
+// Create creates or truncates the named file
+func (OsFs) Create(name string) (File, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Mkdir creates a directory
+func (OsFs) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+// MkdirAll creates a directory and any missing parents
+func (OsFs) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Open opens a file for reading
+func (OsFs) Open(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// OpenFile opens a file with os.O_* flags
+func (OsFs) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Remove removes a file or an empty directory
+func (OsFs) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// RemoveAll removes a path and everything below it
+func (OsFs) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// Rename moves a file or directory
+func (OsFs) Rename(oldname, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+// Stat describes the named file
+func (OsFs) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Chmod changes the permission bits
+func (OsFs) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Chown changes the owner and group
+func (OsFs) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+// Chtimes changes the access and modification times
+func (OsFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// In-memory file system
+
+// fileData is a file or directory of a MemMapFs. Its mutex guards the
+// contents, which open handles share.
+type fileData struct {
+	mu       sync.Mutex
+	name     string
+	data     []byte
+	dir      bool
+	mode     os.FileMode
+	modTime  time.Time
+	uid, gid int
+}
+
+func (d *fileData) info() os.FileInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &fileInfo{
+		name:    filepath.Base(d.name),
+		size:    int64(len(d.data)),
+		mode:    d.mode,
+		modTime: d.modTime,
+	}
+}
+
+// fileInfo describes a file of a MemMapFs
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+// MemMapFs keeps files in memory, keyed by their cleaned absolute path.
+// Relative names are taken from the root, so "a/b" and "/a/b" are the
+// same file.
+type MemMapFs struct {
+	mu   sync.RWMutex
+	data map[string]*fileData
+}
+
+// NewMemMapFs returns an empty in-memory file system
+func NewMemMapFs() Fs {
+	return &MemMapFs{}
+}
+
+// Name returns "MemMapFS"
+func (m *MemMapFs) Name() string { return "MemMapFS" }
+
+// getData returns the files, creating the root directory on first use.
+// The caller holds m.mu.
+func (m *MemMapFs) getData() map[string]*fileData {
+	if m.data == nil {
+		root := string(filepath.Separator)
+		m.data = map[string]*fileData{
+			root: {name: root, dir: true, mode: os.ModeDir | 0755, modTime: time.Now()},
+		}
+	}
+	return m.data
+}
+
+// normalizePath cleans name and makes it absolute
+func normalizePath(name string) string {
+	name = filepath.Clean(filepath.FromSlash(name))
+	if !strings.HasPrefix(name, string(filepath.Separator)) {
+		name = string(filepath.Separator) + name
+	}
+	return filepath.Clean(name)
+}
+
+// children returns the files directly in dir, sorted by name. The caller
+// holds m.mu.
+func (m *MemMapFs) children(dir string) []*fileData {
+	var list []*fileData
+	for name, d := range m.getData() {
+		if name != dir && filepath.Dir(name) == dir {
+			list = append(list, d)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// parentDir reports whether the parent of name is a directory. The
+// caller holds m.mu.
+func (m *MemMapFs) parentDir(name string) bool {
+	parent, ok := m.getData()[filepath.Dir(name)]
+	return ok && parent.dir
+}
+
+// Create creates or truncates the named file
+func (m *MemMapFs) Create(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// Open opens a file for reading
+func (m *MemMapFs) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens a file with os.O_* flags, creating it with perm
+func (m *MemMapFs) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writing := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	d, ok := m.getData()[name]
+	switch {
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrFileNotFound}
+	case !ok:
+		if !m.parentDir(name) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: ErrFileNotFound}
+		}
+		d = &fileData{name: name, mode: perm.Perm(), modTime: time.Now()}
+		m.data[name] = d
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrFileExists}
+	case d.dir && writing:
+		return nil, &os.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case flag&os.O_TRUNC != 0 && writing:
+		d.mu.Lock()
+		d.data = nil
+		d.modTime = time.Now()
+		d.mu.Unlock()
+	}
+	return &memFile{
+		fs:         m,
+		data:       d,
+		readable:   flag&os.O_WRONLY == 0,
+		writable:   writing,
+		appendMode: flag&os.O_APPEND != 0,
+	}, nil
+}
+
+// Mkdir creates a directory whose parent exists
+func (m *MemMapFs) Mkdir(name string, perm os.FileMode) error {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.getData()[name]; ok {
+		return &os.PathError{Op: "mkdir", Path: name, Err: ErrFileExists}
+	}
+	if !m.parentDir(name) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: ErrFileNotFound}
+	}
+	m.data[name] = &fileData{name: name, dir: true, mode: os.ModeDir | perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+// MkdirAll creates a directory and any missing parents. It succeeds if
+// the directory already exists.
+func (m *MemMapFs) MkdirAll(path string, perm os.FileMode) error {
+	path = normalizePath(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var missing []string
+	for p := path; ; p = filepath.Dir(p) {
+		d, ok := m.getData()[p]
+		if ok {
+			if !d.dir {
+				return &os.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+			}
+			break
+		}
+		missing = append(missing, p)
+	}
+	now := time.Now()
+	for i := len(missing) - 1; i >= 0; i-- {
+		p := missing[i]
+		m.data[p] = &fileData{name: p, dir: true, mode: os.ModeDir | perm.Perm(), modTime: now}
+	}
+	return nil
+}
+
+// Remove removes a file or an empty directory
+func (m *MemMapFs) Remove(name string) error {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.getData()[name]
+	if !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrFileNotFound}
+	}
+	if d.dir && len(m.children(name)) > 0 {
+		return &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.data, name)
+	return nil
+}
+
+// RemoveAll removes a path and everything below it. A missing path is
+// not an error.
+func (m *MemMapFs) RemoveAll(path string) error {
+	path = normalizePath(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.getData() {
+		if name == path || isBelow(name, path) {
+			delete(m.data, name)
+		}
+	}
+	return nil
+}
+
+// Rename moves a file or directory, with everything below it. An
+// existing file at newname is replaced.
+func (m *MemMapFs) Rename(oldname, newname string) error {
+	oldname = normalizePath(oldname)
+	newname = normalizePath(newname)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.getData()[oldname]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrFileNotFound}
+	}
+	if oldname == newname {
+		return nil
+	}
+	if !m.parentDir(newname) || isBelow(newname, oldname) {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrFileNotFound}
+	}
+	if target, ok := m.data[newname]; ok {
+		if target.dir != d.dir || len(m.children(newname)) > 0 {
+			return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: ErrDestinationExists}
+		}
+	}
+	for name, fd := range m.data {
+		if name != oldname && !isBelow(name, oldname) {
+			continue
+		}
+		moved := newname + strings.TrimPrefix(name, oldname)
+		delete(m.data, name)
+		fd.mu.Lock()
+		fd.name = moved
+		fd.mu.Unlock()
+		m.data[moved] = fd
+	}
+	return nil
+}
+
+// Stat describes the named file
+func (m *MemMapFs) Stat(name string) (os.FileInfo, error) {
+	d, err := m.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return d.info(), nil
+}
+
+// Chmod changes the permission bits, keeping the file type
+func (m *MemMapFs) Chmod(name string, mode os.FileMode) error {
+	d, err := m.lookup("chmod", name)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.mode = d.mode&os.ModeType | mode.Perm()
+	d.mu.Unlock()
+	return nil
+}
+
+// Chown records the owner and group
+func (m *MemMapFs) Chown(name string, uid, gid int) error {
+	d, err := m.lookup("chown", name)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.uid, d.gid = uid, gid
+	d.mu.Unlock()
+	return nil
+}
+
+// Chtimes sets the modification time; access times are not kept
+func (m *MemMapFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	d, err := m.lookup("chtimes", name)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.modTime = mtime
+	d.mu.Unlock()
+	return nil
+}
+
+func (m *MemMapFs) lookup(op, name string) (*fileData, error) {
+	name = normalizePath(name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.getData()[name]
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: name, Err: ErrFileNotFound}
+	}
+	return d, nil
+}
+
+// isBelow reports whether name is inside dir
+func isBelow(name, dir string) bool {
+	if dir == string(filepath.Separator) {
+		return name != dir
+	}
+	return strings.HasPrefix(name, dir+string(filepath.Separator))
+}
+
+// memFile is an open handle on a MemMapFs file
+type memFile struct {
+	fs         *MemMapFs
+	data       *fileData
+	readable   bool
+	writable   bool
+	appendMode bool
+
+	mu        sync.Mutex
+	at        int64
+	dirOffset int
+	closed    bool
+}
+
+// Name returns the file's path
+func (f *memFile) Name() string {
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+	return f.data.name
+}
+
+// Close closes the handle; closing it again is an error
+func (f *memFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrFileClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// check returns the error for an operation the handle cannot do. The
+// caller holds f.mu.
+func (f *memFile) check(op string, write bool) error {
+	switch {
+	case f.closed:
+		return ErrFileClosed
+	case write && !f.writable, !write && !f.readable:
+		return &os.PathError{Op: op, Path: f.data.name, Err: syscall.EBADF}
+	case f.data.dir:
+		return &os.PathError{Op: op, Path: f.data.name, Err: syscall.EISDIR}
+	}
+	return nil
+}
+
+// Read reads from the current offset
+func (f *memFile) Read(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	n, err := f.readAt(b, f.at)
+	f.at += int64(n)
+	return n, err
+}
+
+// ReadAt reads from off without moving the offset
+func (f *memFile) ReadAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("readat", false); err != nil {
+		return 0, err
+	}
+	n, err := f.readAt(b, off)
+	if err == nil && n < len(b) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *memFile) readAt(b []byte, off int64) (int, error) {
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+	if off < 0 {
+		return 0, &os.PathError{Op: "readat", Path: f.data.name, Err: ErrOutOfRange}
+	}
+	if off >= int64(len(f.data.data)) {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	return copy(b, f.data.data[off:]), nil
+}
+
+// Seek sets the offset for the next Read or Write
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, ErrFileClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.at
+	case io.SeekEnd:
+		f.data.mu.Lock()
+		offset += int64(len(f.data.data))
+		f.data.mu.Unlock()
+	default:
+		return 0, &os.PathError{Op: "seek", Path: f.data.name, Err: syscall.EINVAL}
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.data.name, Err: ErrOutOfRange}
+	}
+	f.at = offset
+	return offset, nil
+}
+
+// Write writes at the current offset, or at the end in append mode
+func (f *memFile) Write(b []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("write", true); err != nil {
+		return 0, err
+	}
+	if f.appendMode {
+		f.data.mu.Lock()
+		f.at = int64(len(f.data.data))
+		f.data.mu.Unlock()
+	}
+	n := f.writeAt(b, f.at)
+	f.at += int64(n)
+	return n, nil
+}
+
+// WriteAt writes at off without moving the offset
+func (f *memFile) WriteAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("writeat", true); err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, &os.PathError{Op: "writeat", Path: f.data.name, Err: ErrOutOfRange}
+	}
+	if f.appendMode {
+		return 0, errors.New("afero: invalid use of WriteAt on file opened with O_APPEND")
+	}
+	return f.writeAt(b, off), nil
+}
+
+func (f *memFile) writeAt(b []byte, off int64) int {
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+	end := off + int64(len(b))
+	if end > int64(len(f.data.data)) {
+		grown := make([]byte, end)
+		copy(grown, f.data.data)
+		f.data.data = grown
+	}
+	copy(f.data.data[off:], b)
+	f.data.modTime = time.Now()
+	return len(b)
+}
+
+// WriteString writes s at the current offset
+func (f *memFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// Truncate changes the size of the file, padding with zeros
+func (f *memFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check("truncate", true); err != nil {
+		return err
+	}
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: f.data.name, Err: ErrOutOfRange}
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+	if size > int64(len(f.data.data)) {
+		grown := make([]byte, size)
+		copy(grown, f.data.data)
+		f.data.data = grown
+	} else {
+		f.data.data = f.data.data[:size]
+	}
+	f.data.modTime = time.Now()
+	return nil
+}
+
+// Sync does nothing; writes are visible at once
+func (f *memFile) Sync() error {
+	return nil
+}
+
+// Stat describes the file
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+	if closed {
+		return nil, ErrFileClosed
+	}
+	return f.data.info(), nil
+}
+
+// Readdir returns the next count entries of a directory, or all the rest
+// if count <= 0. With count > 0 it returns io.EOF at the end.
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, ErrFileClosed
+	}
+	if !f.data.dir {
+		return nil, &os.PathError{Op: "readdir", Path: f.data.name, Err: syscall.ENOTDIR}
+	}
+
+	f.fs.mu.RLock()
+	children := f.fs.children(f.data.name)
+	f.fs.mu.RUnlock()
+
+	if f.dirOffset > len(children) {
+		f.dirOffset = len(children)
+	}
+	rest := children[f.dirOffset:]
+	if count > 0 {
+		if len(rest) == 0 {
+			return nil, io.EOF
+		}
+		if len(rest) > count {
+			rest = rest[:count]
+		}
+	}
+	f.dirOffset += len(rest)
+	infos := make([]os.FileInfo, len(rest))
+	for i, d := range rest {
+		infos[i] = d.info()
+	}
+	return infos, nil
+}
+
+// Readdirnames is Readdir returning only names
+func (f *memFile) Readdirnames(n int) ([]string, error) {
+	infos, err := f.Readdir(n)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, err
+}
+
+// Read-only file system
+
+// ReadOnlyFs lets reads through to its base file system and refuses every
+// change with EPERM
+type ReadOnlyFs struct {
+	source Fs
+}
+
+// NewReadOnlyFs returns a read-only view of source
+func NewReadOnlyFs(source Fs) Fs {
+	return &ReadOnlyFs{source: source}
+}
+
+// Name returns "ReadOnlyFilter"
+func (r *ReadOnlyFs) Name() string { return "ReadOnlyFilter" }
+
+// Open opens a file for reading
+func (r *ReadOnlyFs) Open(name string) (File, error) {
+	return r.source.Open(name)
+}
+
+// OpenFile opens a file, unless flag asks to write or create it
+func (r *ReadOnlyFs) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, syscall.EPERM
+	}
+	return r.source.OpenFile(name, flag, perm)
+}
+
+// Stat describes the named file
+func (r *ReadOnlyFs) Stat(name string) (os.FileInfo, error) {
+	return r.source.Stat(name)
+}
+
+// Create fails with EPERM
+func (r *ReadOnlyFs) Create(name string) (File, error) { return nil, syscall.EPERM }
+
+// Mkdir fails with EPERM
+func (r *ReadOnlyFs) Mkdir(name string, perm os.FileMode) error { return syscall.EPERM }
+
+// MkdirAll fails with EPERM
+func (r *ReadOnlyFs) MkdirAll(path string, perm os.FileMode) error { return syscall.EPERM }
+
+// Remove fails with EPERM
+func (r *ReadOnlyFs) Remove(name string) error { return syscall.EPERM }
+
+// RemoveAll fails with EPERM
+func (r *ReadOnlyFs) RemoveAll(path string) error { return syscall.EPERM }
+
+// Rename fails with EPERM
+func (r *ReadOnlyFs) Rename(oldname, newname string) error { return syscall.EPERM }
+
+// Chmod fails with EPERM
+func (r *ReadOnlyFs) Chmod(name string, mode os.FileMode) error { return syscall.EPERM }
+
+// Chown fails with EPERM
+func (r *ReadOnlyFs) Chown(name string, uid, gid int) error { return syscall.EPERM }
+
+// Chtimes fails with EPERM
+func (r *ReadOnlyFs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return syscall.EPERM
+}
+
+// Utilities
+
+// ReadFile returns the contents of the named file
+func ReadFile(fs Fs, filename string) ([]byte, error) {
+	f, err := fs.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile writes data to the named file, creating it with perm or
+// truncating it
+func WriteFile(fs Fs, filename string, data []byte, perm os.FileMode) error {
+	f, err := fs.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadDir returns the entries of a directory sorted by name
+func ReadDir(fs Fs, dirname string) ([]os.FileInfo, error) {
+	f, err := fs.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list, err := f.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, nil
+}
+
+// Exists reports whether the path exists
+func Exists(fs Fs, path string) (bool, error) {
+	_, err := fs.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// DirExists reports whether the path exists and is a directory
+func DirExists(fs Fs, path string) (bool, error) {
+	fi, err := fs.Stat(path)
+	if err == nil && fi.IsDir() {
+		return true, nil
+	}
+	if err == nil || os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// IsDir reports whether the path is a directory
+func IsDir(fs Fs, path string) (bool, error) {
+	fi, err := fs.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return fi.IsDir(), nil
+}
+
+// Walk calls walkFn for root and everything below it in lexical order,
+// like filepath.Walk. Returning filepath.SkipDir from walkFn skips a
+// directory.
+func Walk(fs Fs, root string, walkFn filepath.WalkFunc) error {
+	info, err := fs.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fs, root, info, walkFn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walk(fs Fs, path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+	entries, err := ReadDir(fs, path)
+	err1 := walkFn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+	for _, entry := range entries {
+		err := walk(fs, filepath.Join(path, entry.Name()), entry, walkFn)
+		if err != nil {
+			if !entry.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Glob returns the names matching pattern, with the syntax of
+// filepath.Match, which may have wildcards in any path element
+func Glob(fs Fs, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, err := fs.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := filepath.Split(pattern)
+	dir = cleanGlobPath(dir)
+	if !hasMeta(dir) {
+		return glob(fs, dir, file, nil)
+	}
+
+	dirs, err := Glob(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, d := range dirs {
+		matches, err = glob(fs, d, file, matches)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// glob appends the entries of dir matching pattern to matches
+func glob(fs Fs, dir, pattern string, matches []string) ([]string, error) {
+	info, err := fs.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return matches, nil
+	}
+	entries, err := ReadDir(fs, dir)
+	if err != nil {
+		return matches, nil
+	}
+	for _, entry := range entries {
+		if matched, err := filepath.Match(pattern, entry.Name()); err != nil {
+			return matches, err
+		} else if matched {
+			matches = append(matches, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return matches, nil
+}
+
+func cleanGlobPath(path string) string {
+	switch path {
+	case "":
+		return "."
+	case string(filepath.Separator):
+		return path
+	default:
+		return path[:len(path)-1]
+	}
+}
+
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[\`)
+}
+
+// Afero wraps an Fs with the utility functions as methods
+type Afero struct {
+	Fs
+}
+
+// ReadFile returns the contents of the named file
+func (a Afero) ReadFile(filename string) ([]byte, error) {
+	return ReadFile(a.Fs, filename)
+}
+
+// WriteFile writes data to the named file
+func (a Afero) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return WriteFile(a.Fs, filename, data, perm)
+}
+
+// ReadDir returns the entries of a directory sorted by name
+func (a Afero) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ReadDir(a.Fs, dirname)
+}
+
+// Walk walks the tree at root
+func (a Afero) Walk(root string, walkFn filepath.WalkFunc) error {
+	return Walk(a.Fs, root, walkFn)
+}
+
+// Exists reports whether the path exists
+func (a Afero) Exists(path string) (bool, error) {
+	return Exists(a.Fs, path)
+}
+
+// DirExists reports whether the path is an existing directory
+func (a Afero) DirExists(path string) (bool, error) {
+	return DirExists(a.Fs, path)
+}
+
+// IsDir reports whether the path is a directory
+func (a Afero) IsDir(path string) (bool, error) {
+	return IsDir(a.Fs, path)
+}
+
+// HTTP file systems
+
+// HttpFs serves an Fs over net/http, for http.FileServer or the Gin
+// emulator's StaticFS
+type HttpFs struct {
+	source Fs
+}
+
+// NewHttpFs returns source as a http.FileSystem
+func NewHttpFs(source Fs) *HttpFs {
+	return &HttpFs{source: source}
+}
+
+// Dir returns a http.FileSystem serving the files below dir
+func (h HttpFs) Dir(dir string) http.FileSystem {
+	return &httpDir{basePath: dir, fs: h}
+}
+
+// Open implements http.FileSystem
+func (h HttpFs) Open(name string) (http.File, error) {
+	f, err := h.source.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+type httpDir struct {
+	basePath string
+	fs       HttpFs
+}
+
+// Open implements http.FileSystem, keeping name below the base path
+func (d httpDir) Open(name string) (http.File, error) {
+	dir := d.basePath
+	if dir == "" {
+		dir = "."
+	}
+	return d.fs.Open(filepath.Join(dir, filepath.FromSlash(path.Clean("/"+name))))
+}
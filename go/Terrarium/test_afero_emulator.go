@@ -0,0 +1,424 @@
+package main
+
+// Developed by PowerShield, as an alternative to afero
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+func testCreateAndRead() bool {
+	fs := NewMemMapFs()
+	f, err := fs.Create("/notes.txt")
+	if err != nil {
+		return false
+	}
+	f.WriteString("hello ")
+	f.Write([]byte("world"))
+	f.Close()
+	if f.Close() != ErrFileClosed {
+		return false
+	}
+	data, err := ReadFile(fs, "notes.txt")
+	if err != nil || string(data) != "hello world" {
+		return false
+	}
+	_, err = fs.Open("/missing.txt")
+	return os.IsNotExist(err) && fs.Name() == "MemMapFS"
+}
+
+func testOpenFileFlags() bool {
+	fs := NewMemMapFs()
+	WriteFile(fs, "/log.txt", []byte("one\n"), 0644)
+
+	if _, err := fs.OpenFile("/log.txt", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644); !os.IsExist(err) {
+		return false
+	}
+	f, _ := fs.OpenFile("/log.txt", os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("two\n")
+	if _, err := f.Read(make([]byte, 1)); err == nil {
+		return false
+	}
+	f.Close()
+	if data, _ := ReadFile(fs, "/log.txt"); string(data) != "one\ntwo\n" {
+		return false
+	}
+
+	r, _ := fs.Open("/log.txt")
+	if _, err := r.Write([]byte("x")); err == nil {
+		return false
+	}
+	r.Close()
+
+	f, _ = fs.OpenFile("/log.txt", os.O_TRUNC|os.O_RDWR, 0)
+	f.Close()
+	info, _ := fs.Stat("/log.txt")
+	// Files cannot be created in missing directories
+	_, err := fs.Create("/no/such/dir/file")
+	return info.Size() == 0 && os.IsNotExist(err)
+}
+
+func testMkdir() bool {
+	fs := NewMemMapFs()
+	if err := fs.Mkdir("/a/b", 0755); !os.IsNotExist(err) {
+		return false
+	}
+	if err := fs.MkdirAll("/a/b/c", 0750); err != nil {
+		return false
+	}
+	if err := fs.MkdirAll("/a/b", 0755); err != nil {
+		return false
+	}
+	if err := fs.Mkdir("/a", 0755); !os.IsExist(err) {
+		return false
+	}
+	WriteFile(fs, "/a/file", nil, 0644)
+	err := fs.MkdirAll("/a/file/sub", 0755)
+	info, _ := fs.Stat("/a/b/c")
+	return errors.Is(err, syscall.ENOTDIR) && info.IsDir() && info.Mode().Perm() == 0750 &&
+		info.Mode()&os.ModeDir != 0
+}
+
+func testRemove() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/data/cache", 0755)
+	WriteFile(fs, "/data/cache/item", []byte("x"), 0644)
+	if err := fs.Remove("/data/cache"); !errors.Is(err, syscall.ENOTEMPTY) {
+		return false
+	}
+	if err := fs.Remove("/data/cache/item"); err != nil {
+		return false
+	}
+	if err := fs.Remove("/data/cache/item"); !os.IsNotExist(err) {
+		return false
+	}
+	WriteFile(fs, "/data/cache/again", []byte("x"), 0644)
+	if fs.RemoveAll("/data") != nil || fs.RemoveAll("/data") != nil {
+		return false
+	}
+	ok, _ := Exists(fs, "/data/cache/again")
+	return !ok
+}
+
+func testRename() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/src/pkg", 0755)
+	WriteFile(fs, "/src/pkg/main.go", []byte("package main"), 0644)
+	WriteFile(fs, "/src/README", []byte("readme"), 0644)
+
+	// Renaming a directory moves everything below it
+	if err := fs.Rename("/src", "/lib"); err != nil {
+		return false
+	}
+	data, err := ReadFile(fs, "/lib/pkg/main.go")
+	if err != nil || string(data) != "package main" {
+		return false
+	}
+	if ok, _ := Exists(fs, "/src/pkg/main.go"); ok {
+		return false
+	}
+	// A file replaces a file; a non-empty directory is not replaced
+	WriteFile(fs, "/lib/old", []byte("old"), 0644)
+	if err := fs.Rename("/lib/README", "/lib/old"); err != nil {
+		return false
+	}
+	if data, _ := ReadFile(fs, "/lib/old"); string(data) != "readme" {
+		return false
+	}
+	fs.Mkdir("/other", 0755)
+	if err := fs.Rename("/other", "/lib"); err == nil {
+		return false
+	}
+	err = fs.Rename("/missing", "/x")
+	var linkErr *os.LinkError
+	return errors.As(err, &linkErr) && os.IsNotExist(err)
+}
+
+func testStatChmodChtimes() bool {
+	fs := NewMemMapFs()
+	WriteFile(fs, "/run.sh", []byte("echo hi"), 0644)
+	fs.Chmod("/run.sh", 0755)
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs.Chtimes("/run.sh", when, when)
+	if fs.Chown("/run.sh", 1000, 1000) != nil {
+		return false
+	}
+	info, err := fs.Stat("/run.sh")
+	if err != nil {
+		return false
+	}
+	_, err = fs.Stat("/nope")
+	var pathErr *os.PathError
+	return info.Name() == "run.sh" && info.Size() == 7 && info.Mode() == 0755 &&
+		info.ModTime().Equal(when) && !info.IsDir() && errors.As(err, &pathErr) && pathErr.Op == "stat"
+}
+
+func testSeekAndOffsets() bool {
+	fs := NewMemMapFs()
+	f, _ := fs.Create("/data.bin")
+	defer f.Close()
+	f.WriteString("0123456789")
+
+	if pos, _ := f.Seek(-3, io.SeekEnd); pos != 7 {
+		return false
+	}
+	buf := make([]byte, 5)
+	n, _ := f.Read(buf)
+	if string(buf[:n]) != "789" {
+		return false
+	}
+	if _, err := f.Read(buf); err != io.EOF {
+		return false
+	}
+	if n, err := f.ReadAt(buf, 8); n != 2 || err != io.EOF {
+		return false
+	}
+	f.WriteAt([]byte("ab"), 2)
+	f.Truncate(6)
+	if _, err := f.Seek(-1, io.SeekStart); err == nil {
+		return false
+	}
+	// Writing past the end fills the gap with zeros
+	f.WriteAt([]byte("!"), 8)
+	data, _ := ReadFile(fs, "/data.bin")
+	return string(data) == "01ab45\x00\x00!"
+}
+
+func testReaddir() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/dir/sub", 0755)
+	for _, name := range []string{"c.txt", "a.txt", "b.txt"} {
+		WriteFile(fs, "/dir/"+name, []byte(name), 0644)
+	}
+	WriteFile(fs, "/dir/sub/deep.txt", nil, 0644)
+
+	d, _ := fs.Open("/dir")
+	defer d.Close()
+	first, err := d.Readdirnames(2)
+	if err != nil || strings.Join(first, ",") != "a.txt,b.txt" {
+		return false
+	}
+	rest, _ := d.Readdirnames(10)
+	if strings.Join(rest, ",") != "c.txt,sub" {
+		return false
+	}
+	if _, err := d.Readdir(1); err != io.EOF {
+		return false
+	}
+	if _, err := d.Read(make([]byte, 1)); !errors.Is(err, syscall.EISDIR) {
+		return false
+	}
+	if _, err := fs.OpenFile("/dir", os.O_WRONLY, 0); !errors.Is(err, syscall.EISDIR) {
+		return false
+	}
+	f, _ := fs.Open("/dir/a.txt")
+	defer f.Close()
+	_, err = f.Readdir(-1)
+	return errors.Is(err, syscall.ENOTDIR)
+}
+
+func testUtilities() bool {
+	fs := NewMemMapFs()
+	afs := Afero{Fs: fs}
+	afs.MkdirAll("/etc/app", 0755)
+	afs.WriteFile("/etc/app/config.yaml", []byte("port: 8080"), 0644)
+	afs.WriteFile("/etc/app/.env", []byte("A=1"), 0600)
+
+	entries, err := afs.ReadDir("/etc/app")
+	if err != nil || len(entries) != 2 || entries[0].Name() != ".env" {
+		return false
+	}
+	exists, _ := afs.Exists("/etc/app/config.yaml")
+	dirExists, _ := afs.DirExists("/etc/app/config.yaml")
+	isDir, _ := afs.IsDir("/etc/app")
+	_, isDirErr := afs.IsDir("/nope")
+	data, _ := afs.ReadFile("/etc/app/config.yaml")
+	return exists && !dirExists && isDir && os.IsNotExist(isDirErr) && string(data) == "port: 8080"
+}
+
+func testWalk() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/site/assets/img", 0755)
+	fs.MkdirAll("/site/node_modules/pkg", 0755)
+	WriteFile(fs, "/site/index.html", nil, 0644)
+	WriteFile(fs, "/site/assets/app.css", nil, 0644)
+	WriteFile(fs, "/site/assets/img/logo.png", nil, 0644)
+	WriteFile(fs, "/site/node_modules/pkg/index.js", nil, 0644)
+
+	var visited []string
+	err := Walk(fs, "/site", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == "node_modules" {
+			return filepath.SkipDir
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	expected := "/site,/site/assets,/site/assets/app.css,/site/assets/img,/site/assets/img/logo.png,/site/index.html"
+	if err != nil || strings.Join(visited, ",") != expected {
+		return false
+	}
+	// A missing root is reported to walkFn
+	var rootErr error
+	Walk(fs, "/missing", func(path string, info os.FileInfo, err error) error {
+		rootErr = err
+		return nil
+	})
+	return os.IsNotExist(rootErr)
+}
+
+func testGlob() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/conf/a", 0755)
+	fs.MkdirAll("/conf/b", 0755)
+	WriteFile(fs, "/conf/a/app.yaml", nil, 0644)
+	WriteFile(fs, "/conf/a/app.json", nil, 0644)
+	WriteFile(fs, "/conf/b/db.yaml", nil, 0644)
+	WriteFile(fs, "/conf/base.yaml", nil, 0644)
+
+	top, _ := Glob(fs, "/conf/*.yaml")
+	nested, _ := Glob(fs, "/conf/*/*.yaml")
+	literal, _ := Glob(fs, "/conf/base.yaml")
+	none, _ := Glob(fs, "/conf/*.toml")
+	_, err := Glob(fs, "/conf/[")
+	return strings.Join(top, ",") == "/conf/base.yaml" &&
+		strings.Join(nested, ",") == "/conf/a/app.yaml,/conf/b/db.yaml" &&
+		len(literal) == 1 && len(none) == 0 && err == filepath.ErrBadPattern
+}
+
+func testReadOnlyFs() bool {
+	base := NewMemMapFs()
+	WriteFile(base, "/config.json", []byte("{}"), 0644)
+	ro := NewReadOnlyFs(base)
+
+	data, err := ReadFile(ro, "/config.json")
+	if err != nil || string(data) != "{}" {
+		return false
+	}
+	if err := WriteFile(ro, "/config.json", []byte("x"), 0644); err != syscall.EPERM {
+		return false
+	}
+	if _, err := ro.Create("/new"); err != syscall.EPERM {
+		return false
+	}
+	if ro.Mkdir("/d", 0755) != syscall.EPERM || ro.Remove("/config.json") != syscall.EPERM ||
+		ro.Rename("/config.json", "/x") != syscall.EPERM || ro.Chmod("/config.json", 0600) != syscall.EPERM {
+		return false
+	}
+	// Changes to the base show through
+	WriteFile(base, "/config.json", []byte(`{"a":1}`), 0644)
+	data, _ = ReadFile(ro, "/config.json")
+	return string(data) == `{"a":1}` && ro.Name() == "ReadOnlyFilter"
+}
+
+func testOsFs() bool {
+	dir, err := os.MkdirTemp("", "afero-test")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(dir)
+	fs := NewOsFs()
+	path := filepath.Join(dir, "sub", "file.txt")
+	if err := fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false
+	}
+	if err := WriteFile(fs, path, []byte("on disk"), 0644); err != nil {
+		return false
+	}
+	onDisk, _ := os.ReadFile(path)
+	if string(onDisk) != "on disk" {
+		return false
+	}
+	matches, _ := Glob(fs, filepath.Join(dir, "*", "*.txt"))
+	_, err = fs.Open(filepath.Join(dir, "missing"))
+	return len(matches) == 1 && matches[0] == path && os.IsNotExist(err) && fs.Name() == "OsFs"
+}
+
+func testHttpFs() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/public/css", 0755)
+	WriteFile(fs, "/public/css/site.css", []byte("body{}"), 0644)
+	WriteFile(fs, "/secret.txt", []byte("hidden"), 0644)
+
+	server := httptest.NewServer(http.FileServer(NewHttpFs(fs).Dir("/public")))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/css/site.css")
+	if err != nil {
+		return false
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "body{}" {
+		return false
+	}
+	// Paths cannot climb out of the directory
+	resp, err = http.Get(server.URL + "/../secret.txt")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusNotFound
+}
+
+func testConcurrentUse() bool {
+	fs := NewMemMapFs()
+	fs.MkdirAll("/jobs", 0755)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("/jobs/%02d", i)
+			WriteFile(fs, name, []byte(name), 0644)
+			ReadFile(fs, name)
+			ReadDir(fs, "/jobs")
+		}(i)
+	}
+	wg.Wait()
+	entries, _ := ReadDir(fs, "/jobs")
+	return len(entries) == 20
+}
+
+func main() {
+	fmt.Println("Running afero Emulator Tests...")
+	fmt.Println("===============================")
+
+	runTest("Create and Read", testCreateAndRead)
+	runTest("OpenFile Flags", testOpenFileFlags)
+	runTest("Mkdir", testMkdir)
+	runTest("Remove", testRemove)
+	runTest("Rename", testRename)
+	runTest("Stat, Chmod and Chtimes", testStatChmodChtimes)
+	runTest("Seek and Offsets", testSeekAndOffsets)
+	runTest("Readdir", testReaddir)
+	runTest("Utilities", testUtilities)
+	runTest("Walk", testWalk)
+	runTest("Glob", testGlob)
+	runTest("ReadOnlyFs", testReadOnlyFs)
+	runTest("OsFs", testOsFs)
+	runTest("HttpFs", testHttpFs)
+	runTest("Concurrent Use", testConcurrentUse)
+
+	fmt.Println("===============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,352 @@
+package main
+
+// Developed by PowerShield, as an alternative to sony/gobreaker
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+// The states of a circuit breaker
+const (
+	// StateClosed lets requests through and counts their outcomes
+	StateClosed State = iota
+	// StateHalfOpen lets a few trial requests through after a timeout
+	StateHalfOpen
+	// StateOpen rejects every request until the timeout passes
+	StateOpen
+)
+
+// String returns "closed", "half-open" or "open"
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return fmt.Sprintf("unknown state: %d", s)
+	}
+}
+
+var (
+	// ErrTooManyRequests is returned when the breaker is half-open and
+	// already has MaxRequests trial requests
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrOpenState is returned when the breaker is open
+	ErrOpenState = errors.New("circuit breaker is open")
+)
+
+// Counts holds the numbers of requests and their outcomes. They are
+// cleared whenever the state changes, and every Interval while closed.
+type Counts struct {
+	Requests             uint32
+	TotalSuccesses       uint32
+	TotalFailures        uint32
+	ConsecutiveSuccesses uint32
+	ConsecutiveFailures  uint32
+}
+
+func (c *Counts) onRequest() {
+	c.Requests++
+}
+
+func (c *Counts) onSuccess() {
+	c.TotalSuccesses++
+	c.ConsecutiveSuccesses++
+	c.ConsecutiveFailures = 0
+}
+
+func (c *Counts) onFailure() {
+	c.TotalFailures++
+	c.ConsecutiveFailures++
+	c.ConsecutiveSuccesses = 0
+}
+
+func (c *Counts) clear() {
+	*c = Counts{}
+}
+
+// Settings configures a CircuitBreaker
+type Settings struct {
+	// Name identifies the breaker in OnStateChange
+	Name string
+	// MaxRequests is the number of requests allowed while half-open, and
+	// of consecutive successes that close the breaker again; 0 means 1
+	MaxRequests uint32
+	// Interval is how often the counts are cleared while closed; 0 means
+	// never
+	Interval time.Duration
+	// Timeout is how long the breaker stays open before going half-open;
+	// 0 means 60 seconds
+	Timeout time.Duration
+	// ReadyToTrip is called with the counts after each failure while
+	// closed, and opens the breaker by returning true. The default trips
+	// after more than 5 consecutive failures.
+	ReadyToTrip func(counts Counts) bool
+	// OnStateChange is called on every state change
+	OnStateChange func(name string, from State, to State)
+	// IsSuccessful decides whether an error counts as a success; by
+	// default only nil does
+	IsSuccessful func(err error) bool
+}
+
+const defaultTimeout = 60 * time.Second
+
+func defaultReadyToTrip(counts Counts) bool {
+	return counts.ConsecutiveFailures > 5
+}
+
+func defaultIsSuccessful(err error) bool {
+	return err == nil
+}
+
+// CircuitBreaker stops calling a failing dependency for a while. Closed,
+// it counts outcomes and opens when ReadyToTrip says so. Open, it fails
+// requests at once. After Timeout it goes half-open and lets MaxRequests
+// through: that many successes close it, and any failure opens it again.
+type CircuitBreaker struct {
+	name          string
+	maxRequests   uint32
+	interval      time.Duration
+	timeout       time.Duration
+	readyToTrip   func(counts Counts) bool
+	isSuccessful  func(err error) bool
+	onStateChange func(name string, from State, to State)
+	now           func() time.Time
+
+	mutex      sync.Mutex
+	state      State
+	generation uint64
+	counts     Counts
+	// expiry is when the counts are cleared while closed, or when the
+	// breaker goes half-open while open
+	expiry time.Time
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker configured by st
+func NewCircuitBreaker(st Settings) *CircuitBreaker {
+	cb := &CircuitBreaker{
+		name:          st.Name,
+		onStateChange: st.OnStateChange,
+		maxRequests:   st.MaxRequests,
+		interval:      st.Interval,
+		timeout:       st.Timeout,
+		readyToTrip:   st.ReadyToTrip,
+		isSuccessful:  st.IsSuccessful,
+		now:           time.Now,
+	}
+	if cb.maxRequests == 0 {
+		cb.maxRequests = 1
+	}
+	if cb.interval < 0 {
+		cb.interval = 0
+	}
+	if cb.timeout <= 0 {
+		cb.timeout = defaultTimeout
+	}
+	if cb.readyToTrip == nil {
+		cb.readyToTrip = defaultReadyToTrip
+	}
+	if cb.isSuccessful == nil {
+		cb.isSuccessful = defaultIsSuccessful
+	}
+	cb.toNewGeneration(cb.now())
+	return cb
+}
+
+// Name returns the name of the breaker
+func (cb *CircuitBreaker) Name() string {
+	return cb.name
+}
+
+// State returns the current state, going half-open or clearing the
+// counts first if their time has come
+func (cb *CircuitBreaker) State() State {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	state, _ := cb.currentState(cb.now())
+	return state
+}
+
+// Counts returns the counts of the current state
+func (cb *CircuitBreaker) Counts() Counts {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	return cb.counts
+}
+
+// Execute calls req if the breaker lets it through, and counts its
+// outcome; otherwise it returns ErrOpenState or ErrTooManyRequests. A
+// panic in req counts as a failure and is re-raised.
+func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{}, error) {
+	generation, err := cb.beforeRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		e := recover()
+		if e != nil {
+			cb.afterRequest(generation, false)
+			panic(e)
+		}
+	}()
+
+	result, err := req()
+	cb.afterRequest(generation, cb.isSuccessful(err))
+	return result, err
+}
+
+func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.now()
+	state, generation := cb.currentState(now)
+	if state == StateOpen {
+		return generation, ErrOpenState
+	} else if state == StateHalfOpen && cb.counts.Requests >= cb.maxRequests {
+		return generation, ErrTooManyRequests
+	}
+	cb.counts.onRequest()
+	return generation, nil
+}
+
+// afterRequest counts an outcome, unless the state changed since the
+// request started
+func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.now()
+	state, generation := cb.currentState(now)
+	if generation != before {
+		return
+	}
+	if success {
+		cb.onSuccess(state, now)
+	} else {
+		cb.onFailure(state, now)
+	}
+}
+
+func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
+	switch state {
+	case StateClosed:
+		cb.counts.onSuccess()
+	case StateHalfOpen:
+		cb.counts.onSuccess()
+		if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
+			cb.setState(StateClosed, now)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
+	switch state {
+	case StateClosed:
+		cb.counts.onFailure()
+		if cb.readyToTrip(cb.counts) {
+			cb.setState(StateOpen, now)
+		}
+	case StateHalfOpen:
+		cb.setState(StateOpen, now)
+	}
+}
+
+// currentState moves on to a new generation if the current one expired
+func (cb *CircuitBreaker) currentState(now time.Time) (State, uint64) {
+	switch cb.state {
+	case StateClosed:
+		if !cb.expiry.IsZero() && cb.expiry.Before(now) {
+			cb.toNewGeneration(now)
+		}
+	case StateOpen:
+		if cb.expiry.Before(now) {
+			cb.setState(StateHalfOpen, now)
+		}
+	}
+	return cb.state, cb.generation
+}
+
+func (cb *CircuitBreaker) setState(state State, now time.Time) {
+	if cb.state == state {
+		return
+	}
+	prev := cb.state
+	cb.state = state
+	cb.toNewGeneration(now)
+	if cb.onStateChange != nil {
+		cb.onStateChange(cb.name, prev, state)
+	}
+}
+
+// toNewGeneration clears the counts and sets when the new generation
+// expires
+func (cb *CircuitBreaker) toNewGeneration(now time.Time) {
+	cb.generation++
+	cb.counts.clear()
+
+	var zero time.Time
+	switch cb.state {
+	case StateClosed:
+		if cb.interval == 0 {
+			cb.expiry = zero
+		} else {
+			cb.expiry = now.Add(cb.interval)
+		}
+	case StateOpen:
+		cb.expiry = now.Add(cb.timeout)
+	default:
+		cb.expiry = zero
+	}
+}
+
+// Two-step breakers
+
+// TwoStepCircuitBreaker is a CircuitBreaker for code that cannot wrap
+// its request in a function: Allow checks the breaker, and the returned
+// callback reports the outcome
+type TwoStepCircuitBreaker struct {
+	cb *CircuitBreaker
+}
+
+// NewTwoStepCircuitBreaker returns a closed TwoStepCircuitBreaker
+// configured by st
+func NewTwoStepCircuitBreaker(st Settings) *TwoStepCircuitBreaker {
+	return &TwoStepCircuitBreaker{cb: NewCircuitBreaker(st)}
+}
+
+// Name returns the name of the breaker
+func (tscb *TwoStepCircuitBreaker) Name() string {
+	return tscb.cb.Name()
+}
+
+// State returns the current state
+func (tscb *TwoStepCircuitBreaker) State() State {
+	return tscb.cb.State()
+}
+
+// Counts returns the counts of the current state
+func (tscb *TwoStepCircuitBreaker) Counts() Counts {
+	return tscb.cb.Counts()
+}
+
+// Allow returns a callback to report whether the request succeeded, or
+// ErrOpenState or ErrTooManyRequests if the request must not be made
+func (tscb *TwoStepCircuitBreaker) Allow() (done func(success bool), err error) {
+	generation, err := tscb.cb.beforeRequest()
+	if err != nil {
+		return nil, err
+	}
+	return func(success bool) {
+		tscb.cb.afterRequest(generation, success)
+	}, nil
+}
@@ -0,0 +1,366 @@
+package main
+
+// Developed by PowerShield, as an alternative to sony/gobreaker
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// testClock is moved by hand and installed in breakers under test
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// newTestBreaker returns a breaker running on clock
+func newTestBreaker(st Settings, clock *testClock) *CircuitBreaker {
+	cb := NewCircuitBreaker(st)
+	cb.now = clock.Now
+	cb.toNewGeneration(clock.Now())
+	return cb
+}
+
+var errBoom = errors.New("boom")
+
+func succeed() (interface{}, error) { return "ok", nil }
+
+func fail() (interface{}, error) { return nil, errBoom }
+
+func testStateStrings() bool {
+	return StateClosed.String() == "closed" && StateHalfOpen.String() == "half-open" &&
+		StateOpen.String() == "open" && State(7).String() == "unknown state: 7"
+}
+
+func testClosedPassesThrough() bool {
+	cb := NewCircuitBreaker(Settings{Name: "db"})
+	result, err := cb.Execute(succeed)
+	if err != nil || result != "ok" {
+		return false
+	}
+	_, err = cb.Execute(fail)
+	if err != errBoom {
+		return false
+	}
+	counts := cb.Counts()
+	return cb.Name() == "db" && cb.State() == StateClosed &&
+		counts == Counts{Requests: 2, TotalSuccesses: 1, TotalFailures: 1, ConsecutiveFailures: 1}
+}
+
+func testDefaultTrip() bool {
+	cb := NewCircuitBreaker(Settings{})
+	// Five consecutive failures are tolerated, the sixth trips
+	for i := 0; i < 5; i++ {
+		cb.Execute(fail)
+	}
+	if cb.State() != StateClosed {
+		return false
+	}
+	// A success resets the consecutive count
+	cb.Execute(succeed)
+	for i := 0; i < 5; i++ {
+		cb.Execute(fail)
+	}
+	if cb.State() != StateClosed {
+		return false
+	}
+	cb.Execute(fail)
+	return cb.State() == StateOpen && cb.Counts() == Counts{}
+}
+
+func testOpenRejects() bool {
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 1 }})
+	cb.Execute(fail)
+	var called int32
+	_, err := cb.Execute(func() (interface{}, error) {
+		atomic.AddInt32(&called, 1)
+		return nil, nil
+	})
+	return err == ErrOpenState && called == 0 && err.Error() == "circuit breaker is open"
+}
+
+func testCustomReadyToTrip() bool {
+	// Trip on a failure ratio once there is enough traffic
+	cb := NewCircuitBreaker(Settings{
+		ReadyToTrip: func(c Counts) bool {
+			return c.Requests >= 4 && float64(c.TotalFailures)/float64(c.Requests) >= 0.5
+		},
+	})
+	cb.Execute(fail)
+	cb.Execute(succeed)
+	cb.Execute(fail)
+	if cb.State() != StateClosed {
+		return false
+	}
+	cb.Execute(fail)
+	return cb.State() == StateOpen
+}
+
+func testHalfOpenAfterTimeout() bool {
+	clock := newTestClock()
+	cb := newTestBreaker(Settings{
+		Timeout:     30 * time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 2 },
+	}, clock)
+	cb.Execute(fail)
+	cb.Execute(fail)
+	if cb.State() != StateOpen {
+		return false
+	}
+	clock.Add(29 * time.Second)
+	if cb.State() != StateOpen {
+		return false
+	}
+	clock.Add(2 * time.Second)
+	if cb.State() != StateHalfOpen {
+		return false
+	}
+	// A trial success closes it
+	if _, err := cb.Execute(succeed); err != nil {
+		return false
+	}
+	return cb.State() == StateClosed
+}
+
+func testHalfOpenFailureReopens() bool {
+	clock := newTestClock()
+	cb := newTestBreaker(Settings{
+		Timeout:     time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 1 },
+	}, clock)
+	cb.Execute(fail)
+	clock.Add(2 * time.Second)
+	if cb.State() != StateHalfOpen {
+		return false
+	}
+	cb.Execute(fail)
+	if cb.State() != StateOpen {
+		return false
+	}
+	// The timeout starts over
+	clock.Add(500 * time.Millisecond)
+	_, err := cb.Execute(succeed)
+	return err == ErrOpenState
+}
+
+func testMaxRequests() bool {
+	clock := newTestClock()
+	cb := newTestBreaker(Settings{
+		MaxRequests: 3,
+		Timeout:     time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 1 },
+	}, clock)
+	cb.Execute(fail)
+	clock.Add(2 * time.Second)
+
+	// Three trial requests at once; a fourth is turned away
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cb.Execute(func() (interface{}, error) {
+				started <- struct{}{}
+				<-release
+				return "ok", nil
+			})
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	_, err := cb.Execute(succeed)
+	close(release)
+	wg.Wait()
+	if err != ErrTooManyRequests {
+		return false
+	}
+	// Three consecutive successes closed it
+	return cb.State() == StateClosed
+}
+
+func testInterval() bool {
+	clock := newTestClock()
+	cb := newTestBreaker(Settings{Interval: time.Minute}, clock)
+	for i := 0; i < 4; i++ {
+		cb.Execute(fail)
+	}
+	if cb.Counts().ConsecutiveFailures != 4 {
+		return false
+	}
+	clock.Add(61 * time.Second)
+	if cb.State() != StateClosed || cb.Counts() != (Counts{}) {
+		return false
+	}
+	// Without an Interval the counts are never cleared while closed
+	plain := newTestBreaker(Settings{}, clock)
+	plain.Execute(fail)
+	clock.Add(time.Hour)
+	plain.State()
+	return plain.Counts().TotalFailures == 1
+}
+
+func testOnStateChange() bool {
+	clock := newTestClock()
+	var transitions []string
+	cb := newTestBreaker(Settings{
+		Name:        "payments",
+		Timeout:     time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 1 },
+		OnStateChange: func(name string, from, to State) {
+			transitions = append(transitions, fmt.Sprintf("%s: %v -> %v", name, from, to))
+		},
+	}, clock)
+	cb.Execute(fail)
+	clock.Add(2 * time.Second)
+	cb.Execute(succeed)
+	expected := []string{
+		"payments: closed -> open",
+		"payments: open -> half-open",
+		"payments: half-open -> closed",
+	}
+	return strings.Join(transitions, "; ") == strings.Join(expected, "; ")
+}
+
+func testIsSuccessful() bool {
+	notFound := errors.New("not found")
+	cb := NewCircuitBreaker(Settings{
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 2 },
+		// A missing record says nothing about the health of the database
+		IsSuccessful: func(err error) bool { return err == nil || err == notFound },
+	})
+	for i := 0; i < 5; i++ {
+		if _, err := cb.Execute(func() (interface{}, error) { return nil, notFound }); err != notFound {
+			return false
+		}
+	}
+	return cb.State() == StateClosed && cb.Counts().TotalSuccesses == 5
+}
+
+func testPanicCountsAsFailure() bool {
+	cb := NewCircuitBreaker(Settings{ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 1 }})
+	recovered := func() (r interface{}) {
+		defer func() { r = recover() }()
+		cb.Execute(func() (interface{}, error) {
+			panic("crash")
+		})
+		return nil
+	}()
+	return recovered == "crash" && cb.State() == StateOpen
+}
+
+func testStaleOutcomesIgnored() bool {
+	clock := newTestClock()
+	cb := newTestBreaker(Settings{
+		Timeout:     time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 1 },
+	}, clock)
+	slowDone := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		cb.Execute(func() (interface{}, error) {
+			<-release
+			return nil, errBoom
+		})
+	}()
+	for cb.Counts().Requests == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Another request trips the breaker; then the breaker recovers
+	cb.Execute(fail)
+	clock.Add(2 * time.Second)
+	cb.Execute(succeed)
+	if cb.State() != StateClosed {
+		return false
+	}
+	// The slow request's failure belongs to an old generation
+	close(release)
+	<-slowDone
+	return cb.State() == StateClosed && cb.Counts().TotalFailures == 0
+}
+
+func testTwoStep() bool {
+	clock := newTestClock()
+	tscb := NewTwoStepCircuitBreaker(Settings{
+		Name:        "queue",
+		Timeout:     time.Second,
+		ReadyToTrip: func(c Counts) bool { return c.ConsecutiveFailures >= 2 },
+	})
+	tscb.cb.now = clock.Now
+
+	for i := 0; i < 2; i++ {
+		done, err := tscb.Allow()
+		if err != nil {
+			return false
+		}
+		done(false)
+	}
+	if _, err := tscb.Allow(); err != ErrOpenState || tscb.State() != StateOpen {
+		return false
+	}
+	clock.Add(2 * time.Second)
+	done, err := tscb.Allow()
+	if err != nil {
+		return false
+	}
+	// Only one trial at a time by default
+	if _, err := tscb.Allow(); err != ErrTooManyRequests {
+		return false
+	}
+	done(true)
+	return tscb.State() == StateClosed && tscb.Name() == "queue" && tscb.Counts() == Counts{}
+}
+
+func main() {
+	fmt.Println("Running gobreaker Emulator Tests...")
+	fmt.Println("===================================")
+
+	runTest("State Strings", testStateStrings)
+	runTest("Closed Passes Through", testClosedPassesThrough)
+	runTest("Default Trip", testDefaultTrip)
+	runTest("Open Rejects", testOpenRejects)
+	runTest("Custom ReadyToTrip", testCustomReadyToTrip)
+	runTest("Half-Open After Timeout", testHalfOpenAfterTimeout)
+	runTest("Half-Open Failure Reopens", testHalfOpenFailureReopens)
+	runTest("Max Requests", testMaxRequests)
+	runTest("Interval", testInterval)
+	runTest("OnStateChange", testOnStateChange)
+	runTest("IsSuccessful", testIsSuccessful)
+	runTest("Panic Counts As Failure", testPanicCountsAsFailure)
+	runTest("Stale Outcomes Ignored", testStaleOutcomesIgnored)
+	runTest("Two-Step Breaker", testTwoStep)
+
+	fmt.Println("===================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1641 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang.org/x/oauth2 and go-oidc
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeNow is the clock of servers, tokens and verifiers
+var timeNow = time.Now
+
+// expiryDelta is how long before its expiry a token is treated as
+// expired, so it isn't used as it runs out
+const expiryDelta = 10 * time.Second
+
+// Tokens, as in the oauth2 package
+
+// Token is an access token, with the refresh token and expiry that came
+// with it
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+
+	raw map[string]interface{}
+}
+
+// Type returns the token type, Bearer by default
+func (t *Token) Type() string {
+	switch strings.ToLower(t.TokenType) {
+	case "", "bearer":
+		return "Bearer"
+	case "mac":
+		return "MAC"
+	case "basic":
+		return "Basic"
+	}
+	return t.TokenType
+}
+
+// SetAuthHeader sets the Authorization header of r
+func (t *Token) SetAuthHeader(r *http.Request) {
+	r.Header.Set("Authorization", t.Type()+" "+t.AccessToken)
+}
+
+// WithExtra returns a copy of t with extra fields, as read by Extra
+func (t *Token) WithExtra(extra map[string]interface{}) *Token {
+	t2 := *t
+	t2.raw = extra
+	return &t2
+}
+
+// Extra returns a field of the token response other than the standard
+// ones, such as "id_token" or "scope"
+func (t *Token) Extra(key string) interface{} {
+	if t.raw == nil {
+		return nil
+	}
+	return t.raw[key]
+}
+
+// Valid reports whether t has an access token that hasn't expired
+func (t *Token) Valid() bool {
+	return t != nil && t.AccessToken != "" && !t.expired()
+}
+
+func (t *Token) expired() bool {
+	if t.Expiry.IsZero() {
+		return false
+	}
+	return t.Expiry.Round(0).Add(-expiryDelta).Before(timeNow())
+}
+
+// TokenSource supplies tokens
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+type staticTokenSource struct{ t *Token }
+
+func (s staticTokenSource) Token() (*Token, error) { return s.t, nil }
+
+// StaticTokenSource always returns t, even once it expires
+func StaticTokenSource(t *Token) TokenSource { return staticTokenSource{t} }
+
+// reuseTokenSource returns its token until it expires, then gets a new
+// one from src
+type reuseTokenSource struct {
+	new TokenSource
+	mu  sync.Mutex
+	t   *Token
+}
+
+// ReuseTokenSource returns t while it is valid, and tokens from src after
+func ReuseTokenSource(t *Token, src TokenSource) TokenSource {
+	if rt, ok := src.(*reuseTokenSource); ok {
+		if t == nil {
+			return rt
+		}
+		src = rt.new
+	}
+	return &reuseTokenSource{t: t, new: src}
+}
+
+func (s *reuseTokenSource) Token() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.t.Valid() {
+		return s.t, nil
+	}
+	t, err := s.new.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.t = t
+	return t, nil
+}
+
+type contextKey struct{ name string }
+
+// HTTPClient is the context key for the *http.Client token requests use,
+// such as an AuthServer's in-memory client
+var HTTPClient = &contextKey{"oauth2 http client"}
+
+func contextClient(ctx context.Context) *http.Client {
+	if ctx != nil {
+		if c, ok := ctx.Value(HTTPClient).(*http.Client); ok {
+			return c
+		}
+	}
+	return http.DefaultClient
+}
+
+// Transport adds tokens from Source to requests made through Base
+type Transport struct {
+	Source TokenSource
+	Base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Source == nil {
+		return nil, errors.New("oauth2: Transport's Source is nil")
+	}
+	token, err := t.Source.Token()
+	if err != nil {
+		return nil, err
+	}
+	req2 := req.Clone(req.Context())
+	token.SetAuthHeader(req2)
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req2)
+}
+
+// NewClient returns a client adding tokens from src to its requests. Its
+// transport is based on the HTTPClient in ctx, if any.
+func NewClient(ctx context.Context, src TokenSource) *http.Client {
+	if src == nil {
+		return contextClient(ctx)
+	}
+	return &http.Client{Transport: &Transport{Source: ReuseTokenSource(nil, src), Base: contextClient(ctx).Transport}}
+}
+
+// RetrieveError is a token endpoint's error response
+type RetrieveError struct {
+	Response         *http.Response
+	Body             []byte
+	ErrorCode        string
+	ErrorDescription string
+	ErrorURI         string
+}
+
+func (r *RetrieveError) Error() string {
+	if r.ErrorCode != "" {
+		s := fmt.Sprintf("oauth2: %q", r.ErrorCode)
+		if r.ErrorDescription != "" {
+			s += fmt.Sprintf(" %q", r.ErrorDescription)
+		}
+		return s
+	}
+	return fmt.Sprintf("oauth2: cannot fetch token: %v\nResponse: %s", r.Response.Status, r.Body)
+}
+
+// Configs
+
+// AuthStyle is how a client sends its ID and secret to the token endpoint
+type AuthStyle int
+
+const (
+	// AuthStyleAutoDetect tries the header, then the parameters
+	AuthStyleAutoDetect AuthStyle = 0
+	// AuthStyleInParams sends client_id and client_secret in the body
+	AuthStyleInParams AuthStyle = 1
+	// AuthStyleInHeader sends HTTP Basic authentication
+	AuthStyleInHeader AuthStyle = 2
+)
+
+// Endpoint is an authorization server's authorization and token URLs
+type Endpoint struct {
+	AuthURL   string
+	TokenURL  string
+	AuthStyle AuthStyle
+}
+
+// AuthCodeOption adds a parameter to an authorization or token request
+type AuthCodeOption interface {
+	setValue(url.Values)
+}
+
+type setParam struct{ k, v string }
+
+func (p setParam) setValue(m url.Values) { m.Set(p.k, p.v) }
+
+// SetAuthURLParam sets any parameter
+func SetAuthURLParam(key, value string) AuthCodeOption {
+	return setParam{key, value}
+}
+
+var (
+	// AccessTypeOnline and AccessTypeOffline set access_type, which some
+	// servers use to decide whether to issue a refresh token
+	AccessTypeOnline  AuthCodeOption = SetAuthURLParam("access_type", "online")
+	AccessTypeOffline AuthCodeOption = SetAuthURLParam("access_type", "offline")
+	// ApprovalForce asks the user to approve the request again
+	ApprovalForce AuthCodeOption = SetAuthURLParam("prompt", "consent")
+)
+
+// GenerateVerifier returns a new PKCE code verifier, 32 random bytes
+// encoded as 43 characters
+func GenerateVerifier() string {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// S256ChallengeFromVerifier returns the S256 PKCE challenge for verifier
+func S256ChallengeFromVerifier(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// S256ChallengeOption adds the PKCE challenge for verifier to AuthCodeURL
+func S256ChallengeOption(verifier string) AuthCodeOption {
+	return challengeOption{verifier}
+}
+
+type challengeOption struct{ verifier string }
+
+func (o challengeOption) setValue(m url.Values) {
+	m.Set("code_challenge_method", "S256")
+	m.Set("code_challenge", S256ChallengeFromVerifier(o.verifier))
+}
+
+// VerifierOption sends the PKCE verifier with Exchange
+func VerifierOption(verifier string) AuthCodeOption {
+	return setParam{"code_verifier", verifier}
+}
+
+// Config is an OAuth2 client using the authorization code flow
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	Endpoint     Endpoint
+	RedirectURL  string
+	Scopes       []string
+}
+
+// AuthCodeURL returns the URL to send the user to, to log in and approve
+// the client. state is returned with the code, to check the redirect
+// belongs to this login.
+func (c *Config) AuthCodeURL(state string, opts ...AuthCodeOption) string {
+	v := url.Values{"response_type": {"code"}, "client_id": {c.ClientID}}
+	if c.RedirectURL != "" {
+		v.Set("redirect_uri", c.RedirectURL)
+	}
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if state != "" {
+		v.Set("state", state)
+	}
+	for _, opt := range opts {
+		opt.setValue(v)
+	}
+	sep := "?"
+	if strings.Contains(c.Endpoint.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.Endpoint.AuthURL + sep + v.Encode()
+}
+
+// Exchange trades an authorization code for a token
+func (c *Config) Exchange(ctx context.Context, code string, opts ...AuthCodeOption) (*Token, error) {
+	v := url.Values{"grant_type": {"authorization_code"}, "code": {code}}
+	if c.RedirectURL != "" {
+		v.Set("redirect_uri", c.RedirectURL)
+	}
+	for _, opt := range opts {
+		opt.setValue(v)
+	}
+	return retrieveToken(ctx, c.ClientID, c.ClientSecret, c.Endpoint.TokenURL, c.Endpoint.AuthStyle, v)
+}
+
+// TokenSource returns t while it is valid, then refreshes it with its
+// refresh token
+func (c *Config) TokenSource(ctx context.Context, t *Token) TokenSource {
+	tkr := &tokenRefresher{ctx: ctx, conf: c}
+	if t != nil {
+		tkr.refreshToken = t.RefreshToken
+	}
+	return &reuseTokenSource{t: t, new: tkr}
+}
+
+// Client returns an HTTP client using t, refreshed as needed
+func (c *Config) Client(ctx context.Context, t *Token) *http.Client {
+	return NewClient(ctx, c.TokenSource(ctx, t))
+}
+
+type tokenRefresher struct {
+	ctx          context.Context
+	conf         *Config
+	refreshToken string
+}
+
+func (tf *tokenRefresher) Token() (*Token, error) {
+	if tf.refreshToken == "" {
+		return nil, errors.New("oauth2: token expired and refresh token is not set")
+	}
+	v := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {tf.refreshToken}}
+	tk, err := retrieveToken(tf.ctx, tf.conf.ClientID, tf.conf.ClientSecret, tf.conf.Endpoint.TokenURL, tf.conf.Endpoint.AuthStyle, v)
+	if err != nil {
+		return nil, err
+	}
+	// Servers that don't rotate refresh tokens keep the old one working
+	if tk.RefreshToken == "" {
+		tk.RefreshToken = tf.refreshToken
+	}
+	tf.refreshToken = tk.RefreshToken
+	return tk, nil
+}
+
+// ClientCredentialsConfig is a client getting tokens for itself, as in the
+// clientcredentials package
+type ClientCredentialsConfig struct {
+	ClientID       string
+	ClientSecret   string
+	TokenURL       string
+	Scopes         []string
+	EndpointParams url.Values
+	AuthStyle      AuthStyle
+}
+
+// Token gets a new token
+func (c *ClientCredentialsConfig) Token(ctx context.Context) (*Token, error) {
+	v := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	for k, p := range c.EndpointParams {
+		v[k] = p
+	}
+	return retrieveToken(ctx, c.ClientID, c.ClientSecret, c.TokenURL, c.AuthStyle, v)
+}
+
+// TokenSource returns a token while it is valid, then gets a new one
+func (c *ClientCredentialsConfig) TokenSource(ctx context.Context) TokenSource {
+	return ReuseTokenSource(nil, ccTokenSource{ctx, c})
+}
+
+// Client returns an HTTP client getting tokens as needed
+func (c *ClientCredentialsConfig) Client(ctx context.Context) *http.Client {
+	return NewClient(ctx, c.TokenSource(ctx))
+}
+
+type ccTokenSource struct {
+	ctx  context.Context
+	conf *ClientCredentialsConfig
+}
+
+func (s ccTokenSource) Token() (*Token, error) { return s.conf.Token(s.ctx) }
+
+// retrieveToken posts to a token endpoint. Auto-detected auth styles try
+// Basic authentication first, and the parameters if it is refused.
+func retrieveToken(ctx context.Context, clientID, clientSecret, tokenURL string, style AuthStyle, v url.Values) (*Token, error) {
+	if style == AuthStyleAutoDetect {
+		tk, err := doTokenRequest(ctx, clientID, clientSecret, tokenURL, AuthStyleInHeader, v)
+		var rErr *RetrieveError
+		if err == nil || !errors.As(err, &rErr) || rErr.ErrorCode != "invalid_client" {
+			return tk, err
+		}
+		return doTokenRequest(ctx, clientID, clientSecret, tokenURL, AuthStyleInParams, v)
+	}
+	return doTokenRequest(ctx, clientID, clientSecret, tokenURL, style, v)
+}
+
+func doTokenRequest(ctx context.Context, clientID, clientSecret, tokenURL string, style AuthStyle, v url.Values) (*Token, error) {
+	params := url.Values{}
+	for k, p := range v {
+		params[k] = p
+	}
+	if style == AuthStyleInParams {
+		params.Set("client_id", clientID)
+		if clientSecret != "" {
+			params.Set("client_secret", clientSecret)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if style == AuthStyleInHeader {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+	resp, err := contextClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
+	}
+
+	var raw map[string]interface{}
+	jsonErr := json.Unmarshal(body, &raw)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		rErr := &RetrieveError{Response: resp, Body: body}
+		if jsonErr == nil {
+			rErr.ErrorCode, _ = raw["error"].(string)
+			rErr.ErrorDescription, _ = raw["error_description"].(string)
+			rErr.ErrorURI, _ = raw["error_uri"].(string)
+		}
+		return nil, rErr
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("oauth2: cannot parse json: %v", jsonErr)
+	}
+	tk := &Token{raw: raw}
+	tk.AccessToken, _ = raw["access_token"].(string)
+	tk.TokenType, _ = raw["token_type"].(string)
+	tk.RefreshToken, _ = raw["refresh_token"].(string)
+	if n, ok := raw["expires_in"].(float64); ok && n > 0 {
+		tk.ExpiresIn = int64(n)
+		tk.Expiry = timeNow().Add(time.Duration(n) * time.Second)
+	}
+	if tk.AccessToken == "" {
+		return nil, errors.New("oauth2: server response missing access_token")
+	}
+	return tk, nil
+}
+
+// JSON Web Tokens
+
+// jsonWebKey is an RSA public key in a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func toJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func (k jsonWebKey) publicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+	e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+	if err1 != nil || err2 != nil {
+		return nil, errors.New("malformed RSA key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func signJWT(kid string, key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyJWT checks an RS256 signature with the key lookup returns for the
+// token's key ID, and returns the token's payload
+func verifyJWT(raw string, lookup func(kid string) (*rsa.PublicKey, error)) ([]byte, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed jwt, expected 3 parts")
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt header: %v", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("malformed jwt header: %v", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unexpected signing algorithm %q", header.Alg)
+	}
+	key, err := lookup(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt signature: %v", err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return nil, errors.New("failed to verify signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt payload: %v", err)
+	}
+	return payload, nil
+}
+
+// Authorization server
+
+// ClientRegistration is a client the AuthServer knows
+type ClientRegistration struct {
+	ID     string
+	Secret string
+	// RedirectURIs are the only URIs codes are sent to
+	RedirectURIs []string
+	// Scopes limits the scopes the client may ask for; empty allows any
+	Scopes []string
+	// Public clients, such as mobile and single-page apps, have no secret
+	// and must use PKCE
+	Public bool
+}
+
+// User is someone who can log in to an AuthServer
+type User struct {
+	Subject       string
+	Name          string
+	Email         string
+	EmailVerified bool
+	// Claims are added to ID tokens and userinfo
+	Claims map[string]interface{}
+}
+
+type authCode struct {
+	clientID    string
+	subject     string
+	redirectURI string
+	scope       []string
+	challenge   string
+	method      string
+	nonce       string
+	expiry      time.Time
+	authTime    time.Time
+	used        bool
+	grantID     string
+}
+
+// grant is what one authorization, or one client credentials request,
+// allows; its tokens stop working when it is revoked
+type grant struct {
+	id           string
+	clientID     string
+	subject      string
+	scope        []string
+	nonce        string
+	authTime     time.Time
+	refreshToken string
+	revoked      bool
+}
+
+type signingKey struct {
+	id  string
+	key *rsa.PrivateKey
+}
+
+// AuthServer is an in-memory OAuth2 authorization server and OpenID
+// Connect provider. It serves the authorization, token, JWKS, userinfo,
+// introspection, revocation and discovery endpoints under its issuer URL.
+type AuthServer struct {
+	issuer *url.URL
+
+	mu             sync.Mutex
+	keys           []signingKey // newest first
+	clients        map[string]*ClientRegistration
+	users          map[string]*User
+	defaultUser    string
+	codes          map[string]*authCode
+	grants         map[string]*grant
+	refreshTokens  map[string]string // refresh token to grant ID
+	accessTokenTTL time.Duration
+	nextID         int
+}
+
+// NewAuthServer returns a server for issuer, such as
+// "https://auth.example.com", with a new RSA signing key
+func NewAuthServer(issuer string) *AuthServer {
+	u, err := url.Parse(strings.TrimSuffix(issuer, "/"))
+	if err != nil || u.Host == "" {
+		panic(fmt.Sprintf("oauth2: invalid issuer %q", issuer))
+	}
+	s := &AuthServer{
+		issuer:         u,
+		clients:        map[string]*ClientRegistration{},
+		users:          map[string]*User{},
+		codes:          map[string]*authCode{},
+		grants:         map[string]*grant{},
+		refreshTokens:  map[string]string{},
+		accessTokenTTL: time.Hour,
+	}
+	s.RotateKey()
+	return s
+}
+
+// Issuer returns the issuer URL
+func (s *AuthServer) Issuer() string { return s.issuer.String() }
+
+// Endpoint returns the authorization and token URLs
+func (s *AuthServer) Endpoint() Endpoint {
+	return Endpoint{AuthURL: s.url("/authorize"), TokenURL: s.url("/token")}
+}
+
+func (s *AuthServer) url(path string) string { return s.Issuer() + path }
+
+// RegisterClient adds or replaces a client
+func (s *AuthServer) RegisterClient(c ClientRegistration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[c.ID] = &c
+}
+
+// AddUser adds or replaces a user. The first user added logs in when
+// authorization requests don't say who.
+func (s *AuthServer) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.Subject] = &u
+	if s.defaultUser == "" {
+		s.defaultUser = u.Subject
+	}
+}
+
+// SetDefaultUser sets who logs in when authorization requests don't say;
+// "" makes such requests fail with access_denied
+func (s *AuthServer) SetDefaultUser(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultUser = subject
+}
+
+// SetAccessTokenTTL sets how long new access tokens last
+func (s *AuthServer) SetAccessTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokenTTL = ttl
+}
+
+// RotateKey signs new tokens with a new key. Old keys stay in the JWKS,
+// so tokens they signed can still be verified.
+func (s *AuthServer) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append([]signingKey{{id: "key-" + strconv.Itoa(len(s.keys)+1), key: key}}, s.keys...)
+}
+
+// PublicKey returns the key new tokens are signed with, for verifying
+// them with a JWT library
+func (s *AuthServer) PublicKey() *rsa.PublicKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &s.keys[0].key.PublicKey
+}
+
+func (s *AuthServer) newID(prefix string) string {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+	s.nextID++
+	return prefix + strconv.Itoa(s.nextID) + "_" + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// HTTPClient returns a client whose requests to the issuer's host are
+// served in memory; other hosts are reached over the network
+func (s *AuthServer) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &handlerTransport{host: s.issuer.Host, handler: s, base: http.DefaultTransport},
+		// Authorization redirects go to the client's redirect URI
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// Context returns ctx with HTTPClient set, so configs' token requests and
+// NewProvider reach the server
+func (s *AuthServer) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, HTTPClient, s.HTTPClient())
+}
+
+type handlerTransport struct {
+	host    string
+	handler http.Handler
+	base    http.RoundTripper
+}
+
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	r.RequestURI = req.URL.RequestURI()
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, r)
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// Authorize stands in for a user logging in at authCodeURL and approving
+// the client. It returns where the user is redirected: the redirect URI
+// with a code and state, or with an error such as access_denied.
+func (s *AuthServer) Authorize(authCodeURL, subject string) (*url.URL, error) {
+	u, err := url.Parse(authCodeURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != s.issuer.Host {
+		return nil, fmt.Errorf("oauth2: %s is not this server's", authCodeURL)
+	}
+	if subject != "" {
+		q := u.Query()
+		q.Set("login_hint", subject)
+		u.RawQuery = q.Encode()
+	}
+	resp, err := s.HTTPClient().Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("oauth2: authorization failed: %s", strings.TrimSpace(string(body)))
+	}
+	return url.Parse(resp.Header.Get("Location"))
+}
+
+// ServeHTTP serves the server's endpoints
+func (s *AuthServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, s.issuer.Path)
+	switch path {
+	case "/.well-known/openid-configuration":
+		s.serveDiscovery(w)
+	case "/jwks":
+		s.serveJWKS(w)
+	case "/authorize":
+		s.serveAuthorize(w, r)
+	case "/token":
+		s.serveToken(w, r)
+	case "/userinfo":
+		s.serveUserInfo(w, r)
+	case "/introspect":
+		s.serveIntrospect(w, r)
+	case "/revoke":
+		s.serveRevoke(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// oauthError is an error response of RFC 6749
+type oauthError struct {
+	status      int
+	code        string
+	description string
+}
+
+func (e *oauthError) Error() string { return e.code + ": " + e.description }
+
+func newOAuthError(status int, code, format string, args ...interface{}) *oauthError {
+	return &oauthError{status: status, code: code, description: fmt.Sprintf(format, args...)}
+}
+
+func writeOAuthError(w http.ResponseWriter, e *oauthError) {
+	if e.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+	}
+	writeJSON(w, e.status, map[string]string{"error": e.code, "error_description": e.description})
+}
+
+func (s *AuthServer) serveDiscovery(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer(),
+		"authorization_endpoint":                s.url("/authorize"),
+		"token_endpoint":                        s.url("/token"),
+		"userinfo_endpoint":                     s.url("/userinfo"),
+		"jwks_uri":                              s.url("/jwks"),
+		"introspection_endpoint":                s.url("/introspect"),
+		"revocation_endpoint":                   s.url("/revoke"),
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+func (s *AuthServer) serveJWKS(w http.ResponseWriter) {
+	s.mu.Lock()
+	keys := make([]jsonWebKey, len(s.keys))
+	for i, k := range s.keys {
+		keys[i] = toJWK(k.id, &k.key.PublicKey)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+func (s *AuthServer) serveAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Without a known client and redirect URI, the user can't be sent
+	// back, so the error is shown to them
+	client := s.clients[q.Get("client_id")]
+	if client == nil {
+		http.Error(w, "invalid_request: unknown client", http.StatusBadRequest)
+		return
+	}
+	redirectURI := q.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !contains(client.RedirectURIs, redirectURI) {
+		http.Error(w, "invalid_request: redirect_uri is not registered", http.StatusBadRequest)
+		return
+	}
+
+	redirect := func(params url.Values) {
+		if state := q.Get("state"); state != "" {
+			params.Set("state", state)
+		}
+		target, _ := url.Parse(redirectURI)
+		values := target.Query()
+		for k, v := range params {
+			values[k] = v
+		}
+		target.RawQuery = values.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	}
+	fail := func(code, description string) {
+		redirect(url.Values{"error": {code}, "error_description": {description}})
+	}
+
+	if q.Get("response_type") != "code" {
+		fail("unsupported_response_type", "only the code response type is supported")
+		return
+	}
+	scope, ok := allowedScope(client, q.Get("scope"))
+	if !ok {
+		fail("invalid_scope", "the client may not request "+q.Get("scope"))
+		return
+	}
+	challenge, method := q.Get("code_challenge"), q.Get("code_challenge_method")
+	if method == "" && challenge != "" {
+		method = "plain"
+	}
+	if method != "" && method != "S256" && method != "plain" {
+		fail("invalid_request", "unsupported code_challenge_method "+method)
+		return
+	}
+	if client.Public && challenge == "" {
+		fail("invalid_request", "public clients must use PKCE")
+		return
+	}
+	subject := q.Get("login_hint")
+	if subject == "" {
+		subject = s.defaultUser
+	}
+	if s.users[subject] == nil {
+		fail("access_denied", "the user did not log in")
+		return
+	}
+
+	code := s.newID("code_")
+	s.codes[code] = &authCode{
+		clientID:    client.ID,
+		subject:     subject,
+		redirectURI: q.Get("redirect_uri"),
+		scope:       scope,
+		challenge:   challenge,
+		method:      method,
+		nonce:       q.Get("nonce"),
+		expiry:      timeNow().Add(10 * time.Minute),
+		authTime:    timeNow(),
+	}
+	redirect(url.Values{"code": {code}})
+}
+
+// allowedScope splits a requested scope, checking the client may ask for
+// it. An empty request gets every scope the client may have.
+func allowedScope(client *ClientRegistration, requested string) ([]string, bool) {
+	scope := strings.Fields(requested)
+	if len(scope) == 0 {
+		return append([]string(nil), client.Scopes...), true
+	}
+	if len(client.Scopes) == 0 {
+		return scope, true
+	}
+	for _, sc := range scope {
+		if !contains(client.Scopes, sc) {
+			return nil, false
+		}
+	}
+	return scope, true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateClient checks the client's Basic or form credentials.
+// Public clients send only their ID.
+func (s *AuthServer) authenticateClient(r *http.Request) (*ClientRegistration, *oauthError) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client := s.clients[id]
+	if client == nil {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "unknown client")
+	}
+	if client.Public {
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(client.Secret)) != 1 {
+		return nil, newOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+func (s *AuthServer) parseForm(r *http.Request) *oauthError {
+	if r.Method != http.MethodPost {
+		return newOAuthError(http.StatusMethodNotAllowed, "invalid_request", "use POST")
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" {
+		return newOAuthError(http.StatusBadRequest, "invalid_request", "the body must be form-encoded")
+	}
+	if err := r.ParseForm(); err != nil {
+		return newOAuthError(http.StatusBadRequest, "invalid_request", "%v", err)
+	}
+	return nil
+}
+
+func (s *AuthServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := s.parseForm(r); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, authErr := s.authenticateClient(r)
+	if authErr != nil {
+		writeOAuthError(w, authErr)
+		return
+	}
+
+	var resp map[string]interface{}
+	var err *oauthError
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "authorization_code":
+		resp, err = s.exchangeCode(client, r.PostForm)
+	case "refresh_token":
+		resp, err = s.refresh(client, r.PostForm)
+	case "client_credentials":
+		resp, err = s.clientCredentials(client, r.PostForm)
+	case "":
+		err = newOAuthError(http.StatusBadRequest, "invalid_request", "grant_type is required")
+	default:
+		err = newOAuthError(http.StatusBadRequest, "unsupported_grant_type", "%s is not supported", grantType)
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func invalidGrant(format string, args ...interface{}) *oauthError {
+	return newOAuthError(http.StatusBadRequest, "invalid_grant", format, args...)
+}
+
+func (s *AuthServer) exchangeCode(client *ClientRegistration, form url.Values) (map[string]interface{}, *oauthError) {
+	code := s.codes[form.Get("code")]
+	if code == nil || code.clientID != client.ID {
+		return nil, invalidGrant("unknown authorization code")
+	}
+	if code.used {
+		// A code used twice may have been stolen, so what it gave is
+		// revoked too
+		if g := s.grants[code.grantID]; g != nil {
+			s.revokeGrant(g)
+		}
+		return nil, invalidGrant("authorization code was already used")
+	}
+	if timeNow().After(code.expiry) {
+		return nil, invalidGrant("authorization code expired")
+	}
+	if code.redirectURI != "" && form.Get("redirect_uri") != code.redirectURI {
+		return nil, invalidGrant("redirect_uri does not match the authorization request")
+	}
+	if code.challenge != "" {
+		verifier := form.Get("code_verifier")
+		if verifier == "" {
+			return nil, invalidGrant("code_verifier is required")
+		}
+		expected := verifier
+		if code.method == "S256" {
+			expected = S256ChallengeFromVerifier(verifier)
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code.challenge)) != 1 {
+			return nil, invalidGrant("code_verifier does not match the code challenge")
+		}
+	} else if form.Get("code_verifier") != "" {
+		return nil, invalidGrant("code_verifier was sent but no code challenge was")
+	}
+
+	code.used = true
+	g := &grant{
+		id:       s.newID("grant_"),
+		clientID: client.ID,
+		subject:  code.subject,
+		scope:    code.scope,
+		nonce:    code.nonce,
+		authTime: code.authTime,
+	}
+	g.refreshToken = s.newID("rt_")
+	s.grants[g.id] = g
+	s.refreshTokens[g.refreshToken] = g.id
+	code.grantID = g.id
+	return s.tokenResponse(g, true)
+}
+
+func (s *AuthServer) refresh(client *ClientRegistration, form url.Values) (map[string]interface{}, *oauthError) {
+	g := s.grants[s.refreshTokens[form.Get("refresh_token")]]
+	if g == nil || g.revoked || g.clientID != client.ID {
+		return nil, invalidGrant("unknown refresh token")
+	}
+	scope := g.scope
+	if requested := strings.Fields(form.Get("scope")); len(requested) > 0 {
+		for _, sc := range requested {
+			if !contains(g.scope, sc) {
+				return nil, newOAuthError(http.StatusBadRequest, "invalid_scope", "%s was not granted", sc)
+			}
+		}
+		scope = requested
+	}
+
+	// Refresh tokens are rotated: the old one stops working
+	delete(s.refreshTokens, g.refreshToken)
+	g.refreshToken = s.newID("rt_")
+	s.refreshTokens[g.refreshToken] = g.id
+	narrowed := *g
+	narrowed.scope = scope
+	return s.tokenResponse(&narrowed, contains(scope, "openid"))
+}
+
+func (s *AuthServer) clientCredentials(client *ClientRegistration, form url.Values) (map[string]interface{}, *oauthError) {
+	if client.Public {
+		return nil, newOAuthError(http.StatusBadRequest, "unauthorized_client", "public clients can't use client credentials")
+	}
+	scope, ok := allowedScope(client, form.Get("scope"))
+	if !ok {
+		return nil, newOAuthError(http.StatusBadRequest, "invalid_scope", "the client may not request %s", form.Get("scope"))
+	}
+	g := &grant{id: s.newID("grant_"), clientID: client.ID, subject: client.ID, scope: scope}
+	s.grants[g.id] = g
+	return s.tokenResponse(g, false)
+}
+
+// tokenResponse issues an access token for g, with an ID token if the
+// openid scope was granted
+func (s *AuthServer) tokenResponse(g *grant, withIDToken bool) (map[string]interface{}, *oauthError) {
+	now := timeNow()
+	key := s.keys[0]
+	claims := map[string]interface{}{
+		"iss":       s.Issuer(),
+		"sub":       g.subject,
+		"client_id": g.clientID,
+		"scope":     strings.Join(g.scope, " "),
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.accessTokenTTL).Unix(),
+		"jti":       g.id + "." + s.newID("at_"),
+	}
+	accessToken, err := signJWT(key.id, key.key, claims)
+	if err != nil {
+		return nil, newOAuthError(http.StatusInternalServerError, "server_error", "%v", err)
+	}
+	resp := map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(s.accessTokenTTL / time.Second),
+		"scope":        strings.Join(g.scope, " "),
+	}
+	if g.refreshToken != "" {
+		resp["refresh_token"] = g.refreshToken
+	}
+	if withIDToken && contains(g.scope, "openid") {
+		idClaims := map[string]interface{}{
+			"iss":       s.Issuer(),
+			"sub":       g.subject,
+			"aud":       g.clientID,
+			"iat":       now.Unix(),
+			"exp":       now.Add(s.accessTokenTTL).Unix(),
+			"auth_time": g.authTime.Unix(),
+			"at_hash":   atHash(accessToken),
+		}
+		if g.nonce != "" {
+			idClaims["nonce"] = g.nonce
+		}
+		for k, v := range s.userClaims(g.subject, g.scope) {
+			idClaims[k] = v
+		}
+		idToken, err := signJWT(key.id, key.key, idClaims)
+		if err != nil {
+			return nil, newOAuthError(http.StatusInternalServerError, "server_error", "%v", err)
+		}
+		resp["id_token"] = idToken
+	}
+	return resp, nil
+}
+
+// atHash is the left half of the access token's SHA-256, as ID tokens
+// carry it
+func atHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// userClaims returns the claims of a user the scopes allow
+func (s *AuthServer) userClaims(subject string, scope []string) map[string]interface{} {
+	u := s.users[subject]
+	claims := map[string]interface{}{"sub": subject}
+	if u == nil {
+		return claims
+	}
+	if contains(scope, "profile") && u.Name != "" {
+		claims["name"] = u.Name
+	}
+	if contains(scope, "email") && u.Email != "" {
+		claims["email"] = u.Email
+		claims["email_verified"] = u.EmailVerified
+	}
+	for k, v := range u.Claims {
+		claims[k] = v
+	}
+	return claims
+}
+
+// Introspection is what an AuthServer knows about a token, as RFC 7662
+// describes it
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// HasScope reports whether the token was granted scope
+func (i Introspection) HasScope(scope string) bool {
+	return contains(strings.Fields(i.Scope), scope)
+}
+
+// Introspect checks an access or refresh token, as resource servers do.
+// Expired, revoked and forged tokens are inactive.
+func (s *AuthServer) Introspect(token string) Introspection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.introspectLocked(token)
+}
+
+func (s *AuthServer) introspectLocked(token string) Introspection {
+	if g := s.grants[s.refreshTokens[token]]; g != nil && !g.revoked {
+		return Introspection{Active: true, Scope: strings.Join(g.scope, " "), ClientID: g.clientID, Subject: g.subject, TokenType: "refresh_token"}
+	}
+	claims, g := s.parseAccessToken(token)
+	if claims == nil || g == nil || g.revoked {
+		return Introspection{}
+	}
+	exp, _ := claims["exp"].(float64)
+	if timeNow().Unix() >= int64(exp) {
+		return Introspection{}
+	}
+	iat, _ := claims["iat"].(float64)
+	scope, _ := claims["scope"].(string)
+	return Introspection{
+		Active:    true,
+		Scope:     scope,
+		ClientID:  g.clientID,
+		Subject:   g.subject,
+		TokenType: "access_token",
+		ExpiresAt: int64(exp),
+		IssuedAt:  int64(iat),
+	}
+}
+
+// parseAccessToken verifies an access token's signature and returns its
+// claims and grant
+func (s *AuthServer) parseAccessToken(token string) (map[string]interface{}, *grant) {
+	payload, err := verifyJWT(token, func(kid string) (*rsa.PublicKey, error) {
+		for _, k := range s.keys {
+			if k.id == kid {
+				return &k.key.PublicKey, nil
+			}
+		}
+		return nil, errors.New("unknown key")
+	})
+	if err != nil {
+		return nil, nil
+	}
+	var claims map[string]interface{}
+	if json.Unmarshal(payload, &claims) != nil {
+		return nil, nil
+	}
+	jti, _ := claims["jti"].(string)
+	grantID := strings.SplitN(jti, ".", 2)[0]
+	return claims, s.grants[grantID]
+}
+
+// Revoke revokes a refresh token or access token, and every token from
+// the same authorization
+func (s *AuthServer) Revoke(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokeLocked(token)
+}
+
+func (s *AuthServer) revokeLocked(token string) {
+	if g := s.grants[s.refreshTokens[token]]; g != nil {
+		s.revokeGrant(g)
+		return
+	}
+	if _, g := s.parseAccessToken(token); g != nil {
+		s.revokeGrant(g)
+	}
+}
+
+func (s *AuthServer) revokeGrant(g *grant) {
+	g.revoked = true
+	delete(s.refreshTokens, g.refreshToken)
+}
+
+func (s *AuthServer) serveIntrospect(w http.ResponseWriter, r *http.Request) {
+	if err := s.parseForm(r); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.authenticateClient(r); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.introspectLocked(r.PostForm.Get("token")))
+}
+
+func (s *AuthServer) serveRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := s.parseForm(r); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, err := s.authenticateClient(r)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	// Clients may only revoke their own tokens; others are ignored, as
+	// unknown tokens are
+	token := r.PostForm.Get("token")
+	if info := s.introspectLocked(token); info.Active && info.ClientID == client.ID {
+		s.revokeLocked(token)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *AuthServer) serveUserInfo(w http.ResponseWriter, r *http.Request) {
+	unauthorized := func(description string) {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, description))
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token", "error_description": description})
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		unauthorized("a bearer token is required")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := s.introspectLocked(strings.TrimPrefix(auth, "Bearer "))
+	if !info.Active || info.TokenType != "access_token" {
+		unauthorized("the access token is invalid or expired")
+		return
+	}
+	if !info.HasScope("openid") {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "insufficient_scope"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.userClaims(info.Subject, strings.Fields(info.Scope)))
+}
+
+// OpenID Connect relying parties, as in the go-oidc package
+
+// Provider is an OpenID Connect provider, as its discovery document
+// describes it
+type Provider struct {
+	issuer      string
+	authURL     string
+	tokenURL    string
+	userInfoURL string
+	jwksURL     string
+	keySet      *remoteKeySet
+}
+
+// NewProvider reads the discovery document of issuer, using the
+// HTTPClient in ctx
+func NewProvider(ctx context.Context, issuer string) (*Provider, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	var doc struct {
+		Issuer      string `json:"issuer"`
+		AuthURL     string `json:"authorization_endpoint"`
+		TokenURL    string `json:"token_endpoint"`
+		UserInfoURL string `json:"userinfo_endpoint"`
+		JWKSURL     string `json:"jwks_uri"`
+	}
+	if err := getJSON(ctx, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("oidc: %v", err)
+	}
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("oidc: issuer did not match the issuer returned by provider, expected %q got %q", issuer, doc.Issuer)
+	}
+	return &Provider{
+		issuer:      doc.Issuer,
+		authURL:     doc.AuthURL,
+		tokenURL:    doc.TokenURL,
+		userInfoURL: doc.UserInfoURL,
+		jwksURL:     doc.JWKSURL,
+		keySet:      &remoteKeySet{ctx: ctx, jwksURL: doc.JWKSURL, keys: map[string]*rsa.PublicKey{}},
+	}, nil
+}
+
+func getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := contextClient(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Endpoint returns the provider's authorization and token URLs, for a
+// Config
+func (p *Provider) Endpoint() Endpoint {
+	return Endpoint{AuthURL: p.authURL, TokenURL: p.tokenURL}
+}
+
+// remoteKeySet caches a JWKS, fetching it again for keys it hasn't seen,
+// as after the provider rotates its key
+type remoteKeySet struct {
+	ctx     context.Context
+	jwksURL string
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+}
+
+func (r *remoteKeySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key := r.keys[kid]; key != nil {
+		return key, nil
+	}
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, r.jwksURL, &doc); err != nil {
+		return nil, fmt.Errorf("fetching keys %v", err)
+	}
+	for _, k := range doc.Keys {
+		if key, err := k.publicKey(); err == nil {
+			r.keys[k.Kid] = key
+		}
+	}
+	if key := r.keys[kid]; key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key with id %q", kid)
+}
+
+// VerifierConfig configures an IDTokenVerifier
+type VerifierConfig struct {
+	// ClientID is the audience ID tokens must have
+	ClientID string
+	// SkipClientIDCheck accepts any audience
+	SkipClientIDCheck bool
+	// SkipExpiryCheck accepts expired tokens
+	SkipExpiryCheck bool
+	// Now replaces the clock
+	Now func() time.Time
+}
+
+// IDTokenVerifier checks ID tokens' signatures and claims
+type IDTokenVerifier struct {
+	provider *Provider
+	config   VerifierConfig
+}
+
+// Verifier returns a verifier for ID tokens the provider issues
+func (p *Provider) Verifier(config *VerifierConfig) *IDTokenVerifier {
+	return &IDTokenVerifier{provider: p, config: *config}
+}
+
+// IDToken is a verified ID token
+type IDToken struct {
+	Issuer          string
+	Audience        []string
+	Subject         string
+	Expiry          time.Time
+	IssuedAt        time.Time
+	Nonce           string
+	AccessTokenHash string
+
+	claims []byte
+}
+
+// Claims decodes the token's claims into v
+func (t *IDToken) Claims(v interface{}) error {
+	return json.Unmarshal(t.claims, v)
+}
+
+// VerifyAccessToken checks that accessToken is the one issued with the ID
+// token
+func (t *IDToken) VerifyAccessToken(accessToken string) error {
+	if t.AccessTokenHash == "" {
+		return errors.New("oidc: id token did not have an access token hash")
+	}
+	if atHash(accessToken) != t.AccessTokenHash {
+		return errors.New("oidc: access token hash does not match value in ID token")
+	}
+	return nil
+}
+
+// Verify checks rawIDToken's signature against the provider's keys, then
+// its issuer, audience and expiry
+func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	payload, err := verifyJWT(rawIDToken, func(kid string) (*rsa.PublicKey, error) {
+		return v.provider.keySet.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %v", err)
+	}
+	var claims struct {
+		Issuer   string          `json:"iss"`
+		Subject  string          `json:"sub"`
+		Audience json.RawMessage `json:"aud"`
+		Expiry   int64           `json:"exp"`
+		IssuedAt int64           `json:"iat"`
+		Nonce    string          `json:"nonce"`
+		AtHash   string          `json:"at_hash"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+	var audience []string
+	if json.Unmarshal(claims.Audience, &audience) != nil {
+		var single string
+		json.Unmarshal(claims.Audience, &single)
+		audience = []string{single}
+	}
+	t := &IDToken{
+		Issuer:          claims.Issuer,
+		Audience:        audience,
+		Subject:         claims.Subject,
+		Expiry:          time.Unix(claims.Expiry, 0),
+		IssuedAt:        time.Unix(claims.IssuedAt, 0),
+		Nonce:           claims.Nonce,
+		AccessTokenHash: claims.AtHash,
+		claims:          payload,
+	}
+	if t.Issuer != v.provider.issuer {
+		return nil, fmt.Errorf("oidc: id token issued by a different provider, expected %q got %q", v.provider.issuer, t.Issuer)
+	}
+	if !v.config.SkipClientIDCheck {
+		if v.config.ClientID == "" {
+			return nil, errors.New("oidc: invalid configuration, clientID must be provided or SkipClientIDCheck must be set")
+		}
+		if !contains(t.Audience, v.config.ClientID) {
+			return nil, fmt.Errorf("oidc: expected audience %q got %q", v.config.ClientID, t.Audience)
+		}
+	}
+	if !v.config.SkipExpiryCheck {
+		now := timeNow
+		if v.config.Now != nil {
+			now = v.config.Now
+		}
+		if t.Expiry.Before(now()) {
+			return nil, fmt.Errorf("oidc: token is expired (Token Expiry: %v)", t.Expiry)
+		}
+	}
+	return t, nil
+}
+
+// UserInfo is the userinfo endpoint's answer
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Profile       string `json:"profile"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+
+	claims []byte
+}
+
+// Claims decodes every claim into v
+func (u *UserInfo) Claims(v interface{}) error {
+	return json.Unmarshal(u.claims, v)
+}
+
+// UserInfo asks the userinfo endpoint about the user tokens from ts
+// belong to
+func (p *Provider) UserInfo(ctx context.Context, ts TokenSource) (*UserInfo, error) {
+	if p.userInfoURL == "" {
+		return nil, errors.New("oidc: user info endpoint is not supported by this provider")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := NewClient(ctx, ts).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	u := &UserInfo{claims: body}
+	if err := json.Unmarshal(body, u); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode userinfo: %v", err)
+	}
+	return u, nil
+}
@@ -0,0 +1,766 @@
+package main
+
+// Developed by PowerShield, as an alternative to golang.org/x/oauth2 and go-oidc
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+const issuer = "https://auth.example.com"
+
+// newTestServer returns a server with a web app, a public mobile app, a
+// back-end service and two users
+func newTestServer() *AuthServer {
+	srv := NewAuthServer(issuer)
+	srv.RegisterClient(ClientRegistration{
+		ID:           "web",
+		Secret:       "web-secret",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+	})
+	srv.RegisterClient(ClientRegistration{
+		ID:           "mobile",
+		RedirectURIs: []string{"com.example.app:/callback"},
+		Public:       true,
+	})
+	srv.RegisterClient(ClientRegistration{
+		ID:     "billing",
+		Secret: "billing-secret",
+		Scopes: []string{"invoices:read", "invoices:write"},
+	})
+	srv.AddUser(User{Subject: "ann", Name: "Ann", Email: "ann@example.com", EmailVerified: true,
+		Claims: map[string]interface{}{"groups": []string{"admins"}}})
+	srv.AddUser(User{Subject: "ben", Name: "Ben"})
+	return srv
+}
+
+func webConfig(srv *AuthServer, scopes ...string) *Config {
+	return &Config{
+		ClientID:     "web",
+		ClientSecret: "web-secret",
+		Endpoint:     srv.Endpoint(),
+		RedirectURL:  "https://app.example.com/callback",
+		Scopes:       scopes,
+	}
+}
+
+// login runs the authorization code flow up to the code
+func login(srv *AuthServer, conf *Config, subject string, opts ...AuthCodeOption) (string, error) {
+	redirect, err := srv.Authorize(conf.AuthCodeURL("xyz", opts...), subject)
+	if err != nil {
+		return "", err
+	}
+	if e := redirect.Query().Get("error"); e != "" {
+		return "", errors.New(e)
+	}
+	return redirect.Query().Get("code"), nil
+}
+
+func errorCode(err error) string {
+	var rErr *RetrieveError
+	if errors.As(err, &rErr) {
+		return rErr.ErrorCode
+	}
+	return ""
+}
+
+// hostTransport serves one host with a handler, and sends the rest to base
+type hostTransport struct {
+	host    string
+	handler http.Handler
+	base    http.RoundTripper
+}
+
+func (t hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	w := httptest.NewRecorder()
+	t.handler.ServeHTTP(w, req)
+	return w.Result(), nil
+}
+
+func testAuthorizationCodeFlow() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	conf := webConfig(srv, "profile")
+
+	authURL, _ := url.Parse(conf.AuthCodeURL("state-1", AccessTypeOffline))
+	q := authURL.Query()
+	if authURL.Host != "auth.example.com" || authURL.Path != "/authorize" || q.Get("client_id") != "web" ||
+		q.Get("response_type") != "code" || q.Get("scope") != "profile" || q.Get("state") != "state-1" ||
+		q.Get("access_type") != "offline" {
+		return false
+	}
+
+	redirect, err := srv.Authorize(authURL.String(), "ben")
+	if err != nil || redirect.Host != "app.example.com" || redirect.Query().Get("state") != "state-1" {
+		return false
+	}
+	tok, err := conf.Exchange(ctx, redirect.Query().Get("code"))
+	if err != nil || !tok.Valid() || tok.Type() != "Bearer" || tok.RefreshToken == "" || tok.Extra("scope") != "profile" {
+		return false
+	}
+	if tok.ExpiresIn != 3600 || tok.Expiry.Sub(time.Now()) < 59*time.Minute {
+		return false
+	}
+	info := srv.Introspect(tok.AccessToken)
+	return info.Active && info.Subject == "ben" && info.ClientID == "web" && info.HasScope("profile") &&
+		info.TokenType == "access_token" && tok.Extra("id_token") == nil
+}
+
+func testPKCE() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	conf := &Config{ClientID: "mobile", Endpoint: srv.Endpoint(), RedirectURL: "com.example.app:/callback"}
+
+	verifier := GenerateVerifier()
+	if len(verifier) != 43 {
+		return false
+	}
+	// The example of RFC 7636
+	if S256ChallengeFromVerifier("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk") != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		return false
+	}
+
+	// Public clients must use PKCE
+	if _, err := login(srv, conf, ""); err == nil || err.Error() != "invalid_request" {
+		return false
+	}
+
+	code, err := login(srv, conf, "", S256ChallengeOption(verifier))
+	if err != nil {
+		return false
+	}
+	if _, err := conf.Exchange(ctx, code); errorCode(err) != "invalid_grant" {
+		return false
+	}
+	code, _ = login(srv, conf, "", S256ChallengeOption(verifier))
+	if _, err := conf.Exchange(ctx, code, VerifierOption(GenerateVerifier())); errorCode(err) != "invalid_grant" {
+		return false
+	}
+	code, _ = login(srv, conf, "", S256ChallengeOption(verifier))
+	tok, err := conf.Exchange(ctx, code, VerifierOption(verifier))
+	if err != nil || !tok.Valid() || srv.Introspect(tok.AccessToken).Subject != "ann" {
+		return false
+	}
+
+	// The plain method compares the verifier itself
+	code, _ = login(srv, conf, "", SetAuthURLParam("code_challenge", "plain-verifier"))
+	_, err = conf.Exchange(ctx, code, VerifierOption("plain-verifier"))
+	return err == nil
+}
+
+func testCodeReuse() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	conf := webConfig(srv)
+
+	code, _ := login(srv, conf, "ann")
+	tok, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return false
+	}
+	_, err = conf.Exchange(ctx, code)
+	if errorCode(err) != "invalid_grant" || !strings.Contains(err.Error(), "already used") {
+		return false
+	}
+	// Tokens from a code used twice may be in the wrong hands
+	if srv.Introspect(tok.AccessToken).Active || srv.Introspect(tok.RefreshToken).Active {
+		return false
+	}
+
+	// Codes expire after ten minutes
+	code, _ = login(srv, conf, "ann")
+	timeNow = func() time.Time { return time.Now().Add(11 * time.Minute) }
+	defer func() { timeNow = time.Now }()
+	_, err = conf.Exchange(ctx, code)
+	return errorCode(err) == "invalid_grant" && strings.Contains(err.Error(), "expired")
+}
+
+func testRedirectURIs() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+
+	// Unregistered redirect URIs get an error page, not a redirect
+	evil := webConfig(srv)
+	evil.RedirectURL = "https://evil.example.com/callback"
+	if _, err := srv.Authorize(evil.AuthCodeURL("s"), "ann"); err == nil || !strings.Contains(err.Error(), "redirect_uri") {
+		return false
+	}
+	unknown := webConfig(srv)
+	unknown.ClientID = "nobody"
+	if _, err := srv.Authorize(unknown.AuthCodeURL("s"), "ann"); err == nil {
+		return false
+	}
+
+	// A client with one redirect URI may leave it out, and then must
+	// leave it out of the exchange too
+	implicit := webConfig(srv)
+	implicit.RedirectURL = ""
+	redirect, err := srv.Authorize(implicit.AuthCodeURL("s"), "ann")
+	if err != nil || redirect.String() != "https://app.example.com/callback?code="+url.QueryEscape(redirect.Query().Get("code"))+"&state=s" {
+		return false
+	}
+	if _, err := implicit.Exchange(ctx, redirect.Query().Get("code")); err != nil {
+		return false
+	}
+
+	// The exchange must name the redirect URI the authorization did
+	code, _ := login(srv, webConfig(srv), "ann")
+	other := webConfig(srv)
+	other.RedirectURL = "https://app.example.com/other"
+	_, err = other.Exchange(ctx, code)
+	return errorCode(err) == "invalid_grant"
+}
+
+func testAuthorizationErrors() bool {
+	srv := newTestServer()
+	srv.SetDefaultUser("")
+	conf := &Config{ClientID: "billing", Endpoint: srv.Endpoint()}
+	srv.RegisterClient(ClientRegistration{ID: "billing", Secret: "billing-secret",
+		RedirectURIs: []string{"https://billing.example.com/cb"}, Scopes: []string{"invoices:read"}})
+
+	// No one logged in
+	if _, err := login(srv, conf, ""); err == nil || err.Error() != "access_denied" {
+		return false
+	}
+	if _, err := login(srv, conf, "zed"); err == nil || err.Error() != "access_denied" {
+		return false
+	}
+	conf.Scopes = []string{"invoices:read", "admin"}
+	if _, err := login(srv, conf, "ann"); err == nil || err.Error() != "invalid_scope" {
+		return false
+	}
+	conf.Scopes = nil
+	redirect, _ := srv.Authorize(conf.AuthCodeURL("s", SetAuthURLParam("response_type", "token")), "ann")
+	if redirect.Query().Get("error") != "unsupported_response_type" || redirect.Query().Get("state") != "s" {
+		return false
+	}
+	redirect, _ = srv.Authorize(conf.AuthCodeURL("s", SetAuthURLParam("code_challenge", "c"),
+		SetAuthURLParam("code_challenge_method", "S512")), "ann")
+	return redirect.Query().Get("error") == "invalid_request" &&
+		strings.Contains(redirect.Query().Get("error_description"), "S512")
+}
+
+func testClientAuthentication() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+
+	for _, style := range []AuthStyle{AuthStyleAutoDetect, AuthStyleInHeader, AuthStyleInParams} {
+		conf := webConfig(srv)
+		conf.Endpoint.AuthStyle = style
+		code, _ := login(srv, conf, "ann")
+		if _, err := conf.Exchange(ctx, code); err != nil {
+			return false
+		}
+	}
+
+	conf := webConfig(srv)
+	conf.ClientSecret = "wrong"
+	code, _ := login(srv, conf, "ann")
+	_, err := conf.Exchange(ctx, code)
+	var rErr *RetrieveError
+	if !errors.As(err, &rErr) || rErr.ErrorCode != "invalid_client" || rErr.Response.StatusCode != http.StatusUnauthorized ||
+		err.Error() != `oauth2: "invalid_client" "client authentication failed"` {
+		return false
+	}
+
+	// Token requests must be form-encoded POSTs
+	client := srv.HTTPClient()
+	resp, err := client.Get(issuer + "/token")
+	if err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		return false
+	}
+	resp, err = client.Post(issuer+"/token", "application/json", strings.NewReader(`{}`))
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	req, _ := http.NewRequest(http.MethodPost, issuer+"/token", strings.NewReader("grant_type=password"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("web", "web-secret")
+	resp, err = client.Do(req)
+	if err != nil {
+		return false
+	}
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode == http.StatusBadRequest && body["error"] == "unsupported_grant_type"
+}
+
+func testClientCredentials() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+
+	conf := &ClientCredentialsConfig{ClientID: "billing", ClientSecret: "billing-secret", TokenURL: srv.Endpoint().TokenURL,
+		Scopes: []string{"invoices:read"}}
+	tok, err := conf.Token(ctx)
+	if err != nil || tok.RefreshToken != "" || tok.Extra("scope") != "invoices:read" {
+		return false
+	}
+	info := srv.Introspect(tok.AccessToken)
+	if !info.Active || info.Subject != "billing" || !info.HasScope("invoices:read") || info.HasScope("invoices:write") {
+		return false
+	}
+
+	// Without scopes, the client gets all it may have
+	conf.Scopes = nil
+	tok, _ = conf.Token(ctx)
+	if tok.Extra("scope") != "invoices:read invoices:write" {
+		return false
+	}
+	conf.Scopes = []string{"payroll"}
+	if _, err := conf.Token(ctx); errorCode(err) != "invalid_scope" {
+		return false
+	}
+
+	// The token source reuses its token until it expires
+	conf.Scopes = nil
+	ts := conf.TokenSource(ctx)
+	first, _ := ts.Token()
+	second, _ := ts.Token()
+	if first != second {
+		return false
+	}
+
+	public := &ClientCredentialsConfig{ClientID: "mobile", TokenURL: srv.Endpoint().TokenURL}
+	_, err = public.Token(ctx)
+	return errorCode(err) == "unauthorized_client"
+}
+
+func testRefreshTokens() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	conf := webConfig(srv, "openid", "profile")
+
+	code, _ := login(srv, conf, "ann")
+	tok, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return false
+	}
+	ts := conf.TokenSource(ctx, tok)
+	if same, _ := ts.Token(); same != tok {
+		return false
+	}
+
+	// An hour later, the token source refreshes
+	timeNow = func() time.Time { return time.Now().Add(time.Hour) }
+	defer func() { timeNow = time.Now }()
+	if srv.Introspect(tok.AccessToken).Active {
+		return false
+	}
+	fresh, err := ts.Token()
+	if err != nil || fresh.AccessToken == tok.AccessToken || !srv.Introspect(fresh.AccessToken).Active {
+		return false
+	}
+	// Refresh tokens rotate, and the old one stops working
+	if fresh.RefreshToken == tok.RefreshToken || srv.Introspect(tok.RefreshToken).Active {
+		return false
+	}
+	if fresh.Extra("id_token") == nil {
+		return false
+	}
+	_, err = conf.TokenSource(ctx, &Token{RefreshToken: tok.RefreshToken}).Token()
+	if errorCode(err) != "invalid_grant" {
+		return false
+	}
+
+	// A refresh may narrow the scope, but not widen it
+	v := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {fresh.RefreshToken}, "scope": {"profile"}}
+	narrowed, err := retrieveToken(ctx, "web", "web-secret", conf.Endpoint.TokenURL, AuthStyleInHeader, v)
+	if err != nil || narrowed.Extra("scope") != "profile" || narrowed.Extra("id_token") != nil {
+		return false
+	}
+	v = url.Values{"grant_type": {"refresh_token"}, "refresh_token": {narrowed.RefreshToken}, "scope": {"email"}}
+	if _, err := retrieveToken(ctx, "web", "web-secret", conf.Endpoint.TokenURL, AuthStyleInHeader, v); errorCode(err) != "invalid_scope" {
+		return false
+	}
+
+	// Without a refresh token, an expired token can't be renewed
+	_, err = conf.TokenSource(ctx, &Token{AccessToken: "old", Expiry: time.Now()}).Token()
+	return err != nil && strings.Contains(err.Error(), "refresh token is not set")
+}
+
+func testAuthenticatedClient() bool {
+	srv := newTestServer()
+
+	// The API trusts tokens the server says are active
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := srv.Introspect(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if !info.Active {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, "hello %s", info.Subject)
+	})
+	base := srv.HTTPClient()
+	base.Transport = hostTransport{host: "api.example.com", handler: api, base: base.Transport}
+	ctx := context.WithValue(context.Background(), HTTPClient, base)
+
+	conf := webConfig(srv)
+	code, _ := login(srv, conf, "ben")
+	tok, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return false
+	}
+	client := conf.Client(ctx, tok)
+	resp, err := client.Get("https://api.example.com/me")
+	if err != nil {
+		return false
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello ben" {
+		return false
+	}
+
+	// Once revoked, the API refuses the token
+	srv.Revoke(tok.AccessToken)
+	resp, err = NewClient(ctx, StaticTokenSource(tok)).Get("https://api.example.com/me")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	// And the refresh token went with it
+	return !srv.Introspect(tok.RefreshToken).Active
+}
+
+func testIDTokens() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+
+	provider, err := NewProvider(ctx, issuer)
+	if err != nil || provider.Endpoint() != srv.Endpoint() {
+		return false
+	}
+	conf := webConfig(srv, "openid", "profile", "email")
+	conf.Endpoint = provider.Endpoint()
+	code, _ := login(srv, conf, "ann", SetAuthURLParam("nonce", "n-0S6"))
+	tok, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return false
+	}
+	rawIDToken, ok := tok.Extra("id_token").(string)
+	if !ok {
+		return false
+	}
+
+	idToken, err := provider.Verifier(&VerifierConfig{ClientID: "web"}).Verify(ctx, rawIDToken)
+	if err != nil || idToken.Issuer != issuer || idToken.Subject != "ann" || idToken.Nonce != "n-0S6" ||
+		len(idToken.Audience) != 1 || idToken.Audience[0] != "web" || idToken.Expiry.Before(time.Now()) {
+		return false
+	}
+	if idToken.VerifyAccessToken(tok.AccessToken) != nil || idToken.VerifyAccessToken("other") == nil {
+		return false
+	}
+	var claims struct {
+		Name          string   `json:"name"`
+		Email         string   `json:"email"`
+		EmailVerified bool     `json:"email_verified"`
+		Groups        []string `json:"groups"`
+	}
+	if err := idToken.Claims(&claims); err != nil || claims.Name != "Ann" || claims.Email != "ann@example.com" ||
+		!claims.EmailVerified || len(claims.Groups) != 1 {
+		return false
+	}
+
+	// Other audiences, expired tokens and tampering are refused
+	if _, err := provider.Verifier(&VerifierConfig{ClientID: "mobile"}).Verify(ctx, rawIDToken); err == nil ||
+		!strings.Contains(err.Error(), "expected audience") {
+		return false
+	}
+	later := func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := provider.Verifier(&VerifierConfig{ClientID: "web", Now: later}).Verify(ctx, rawIDToken); err == nil ||
+		!strings.Contains(err.Error(), "expired") {
+		return false
+	}
+	if _, err := provider.Verifier(&VerifierConfig{SkipClientIDCheck: true, SkipExpiryCheck: true, Now: later}).Verify(ctx, rawIDToken); err != nil {
+		return false
+	}
+	parts := strings.Split(rawIDToken, ".")
+	forged := parts[0] + "." + strings.TrimRight(parts[1], "A") + "B." + parts[2]
+	if _, err := provider.Verifier(&VerifierConfig{ClientID: "web"}).Verify(ctx, forged); err == nil {
+		return false
+	}
+
+	// Without the openid scope, there is no ID token
+	code, _ = login(srv, webConfig(srv, "profile"), "ann")
+	tok, _ = webConfig(srv, "profile").Exchange(ctx, code)
+	return tok.Extra("id_token") == nil
+}
+
+func testKeyRotation() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	provider, _ := NewProvider(ctx, issuer)
+	verifier := provider.Verifier(&VerifierConfig{ClientID: "web"})
+	conf := webConfig(srv, "openid")
+
+	code, _ := login(srv, conf, "ann")
+	before, _ := conf.Exchange(ctx, code)
+	if _, err := verifier.Verify(ctx, before.Extra("id_token").(string)); err != nil {
+		return false
+	}
+	oldKey := srv.PublicKey()
+	srv.RotateKey()
+	if srv.PublicKey().N.Cmp(oldKey.N) == 0 {
+		return false
+	}
+
+	// The verifier fetches the JWKS again for the new key
+	code, _ = login(srv, conf, "ann")
+	after, _ := conf.Exchange(ctx, code)
+	if _, err := verifier.Verify(ctx, after.Extra("id_token").(string)); err != nil {
+		return false
+	}
+	// Tokens signed with the old key still verify, and still work
+	if _, err := verifier.Verify(ctx, before.Extra("id_token").(string)); err != nil || !srv.Introspect(before.AccessToken).Active {
+		return false
+	}
+
+	resp, err := srv.HTTPClient().Get(issuer + "/jwks")
+	if err != nil {
+		return false
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	json.NewDecoder(resp.Body).Decode(&jwks)
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "key-2" || jwks.Keys[0].Alg != "RS256" || jwks.Keys[1].Kid != "key-1" {
+		return false
+	}
+	key, err := jwks.Keys[0].publicKey()
+	if err != nil || key.N.Cmp(srv.PublicKey().N) != 0 || key.E != srv.PublicKey().E {
+		return false
+	}
+
+	// A token from another server is refused
+	other := newTestServer()
+	code, _ = login(other, conf, "ann")
+	foreign, _ := conf.Exchange(other.Context(context.Background()), code)
+	if _, err := verifier.Verify(ctx, foreign.Extra("id_token").(string)); err == nil {
+		return false
+	}
+	return !srv.Introspect(foreign.AccessToken).Active
+}
+
+func testUserInfo() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	provider, _ := NewProvider(ctx, issuer)
+
+	conf := webConfig(srv, "openid", "email")
+	code, _ := login(srv, conf, "ann")
+	tok, _ := conf.Exchange(ctx, code)
+	info, err := provider.UserInfo(ctx, StaticTokenSource(tok))
+	if err != nil || info.Subject != "ann" || info.Email != "ann@example.com" || !info.EmailVerified {
+		return false
+	}
+	var claims map[string]interface{}
+	info.Claims(&claims)
+	// The profile scope wasn't granted
+	if _, ok := claims["name"]; ok || claims["groups"] == nil {
+		return false
+	}
+
+	// Tokens without openid are refused
+	conf = webConfig(srv, "profile")
+	code, _ = login(srv, conf, "ann")
+	tok, _ = conf.Exchange(ctx, code)
+	if _, err := provider.UserInfo(ctx, StaticTokenSource(tok)); err == nil || !strings.Contains(err.Error(), "403") {
+		return false
+	}
+
+	resp, err := srv.HTTPClient().Get(issuer + "/userinfo")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized ||
+		!strings.Contains(resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`) {
+		return false
+	}
+	_, err = provider.UserInfo(ctx, StaticTokenSource(&Token{AccessToken: "junk"}))
+	return err != nil && strings.Contains(err.Error(), "401")
+}
+
+func testIntrospectionAndRevocation() bool {
+	srv := newTestServer()
+	ctx := srv.Context(context.Background())
+	client := srv.HTTPClient()
+	conf := webConfig(srv)
+	code, _ := login(srv, conf, "ann")
+	tok, _ := conf.Exchange(ctx, code)
+
+	post := func(path, id, secret, token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, issuer+path, strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(id, secret)
+		resp, err := client.Do(req)
+		if err != nil {
+			panic(err)
+		}
+		return resp
+	}
+	introspect := func(token string) Introspection {
+		var info Introspection
+		json.NewDecoder(post("/introspect", "billing", "billing-secret", token).Body).Decode(&info)
+		return info
+	}
+
+	info := introspect(tok.AccessToken)
+	if !info.Active || info.Subject != "ann" || info.ExpiresAt <= info.IssuedAt {
+		return false
+	}
+	if refresh := introspect(tok.RefreshToken); !refresh.Active || refresh.TokenType != "refresh_token" {
+		return false
+	}
+	if introspect("junk").Active {
+		return false
+	}
+	if post("/introspect", "billing", "wrong", tok.AccessToken).StatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	// Clients can't revoke each other's tokens
+	if post("/revoke", "billing", "billing-secret", tok.RefreshToken).StatusCode != http.StatusOK || !introspect(tok.RefreshToken).Active {
+		return false
+	}
+	if post("/revoke", "web", "web-secret", tok.RefreshToken).StatusCode != http.StatusOK {
+		return false
+	}
+	if introspect(tok.RefreshToken).Active || introspect(tok.AccessToken).Active {
+		return false
+	}
+	// Unknown tokens are accepted and ignored
+	return post("/revoke", "web", "web-secret", "junk").StatusCode == http.StatusOK
+}
+
+func testDiscovery() bool {
+	srv := newTestServer()
+	resp, err := srv.HTTPClient().Get(issuer + "/.well-known/openid-configuration")
+	if err != nil || resp.Header.Get("Content-Type") != "application/json" {
+		return false
+	}
+	var doc map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&doc)
+	if doc["issuer"] != issuer || doc["token_endpoint"] != issuer+"/token" || doc["jwks_uri"] != issuer+"/jwks" ||
+		doc["authorization_endpoint"] != issuer+"/authorize" {
+		return false
+	}
+
+	// A discovery document for another issuer is refused
+	ctx := srv.Context(context.Background())
+	if _, err := NewProvider(ctx, issuer+"/"); err == nil || !strings.Contains(err.Error(), "issuer did not match") {
+		return false
+	}
+
+	// Issuers may have a path
+	tenant := NewAuthServer("https://login.example.com/tenant-1/")
+	tenant.RegisterClient(ClientRegistration{ID: "web", Secret: "s", RedirectURIs: []string{"https://app.example.com/cb"}})
+	tenant.AddUser(User{Subject: "ann"})
+	tctx := tenant.Context(context.Background())
+	provider, err := NewProvider(tctx, "https://login.example.com/tenant-1")
+	if err != nil || provider.Endpoint().TokenURL != "https://login.example.com/tenant-1/token" {
+		return false
+	}
+	conf := &Config{ClientID: "web", ClientSecret: "s", Endpoint: provider.Endpoint(), Scopes: []string{"openid"}}
+	code, err := login(tenant, conf, "")
+	if err != nil {
+		return false
+	}
+	tok, err := conf.Exchange(tctx, code)
+	if err != nil {
+		return false
+	}
+	_, err = provider.Verifier(&VerifierConfig{ClientID: "web"}).Verify(tctx, tok.Extra("id_token").(string))
+	return err == nil
+}
+
+func testTokenHelpers() bool {
+	if (&Token{}).Valid() || (*Token)(nil).Valid() {
+		return false
+	}
+	if !(&Token{AccessToken: "a"}).Valid() {
+		return false
+	}
+	// Tokens about to expire are treated as expired
+	if (&Token{AccessToken: "a", Expiry: time.Now().Add(5 * time.Second)}).Valid() ||
+		!(&Token{AccessToken: "a", Expiry: time.Now().Add(time.Minute)}).Valid() {
+		return false
+	}
+	if (&Token{TokenType: "bearer"}).Type() != "Bearer" || (&Token{TokenType: "mac"}).Type() != "MAC" ||
+		(&Token{TokenType: "DPoP"}).Type() != "DPoP" {
+		return false
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+	(&Token{AccessToken: "abc"}).SetAuthHeader(req)
+	if req.Header.Get("Authorization") != "Bearer abc" {
+		return false
+	}
+	tok := (&Token{AccessToken: "a"}).WithExtra(map[string]interface{}{"id_token": "x"})
+	if tok.Extra("id_token") != "x" || tok.Extra("missing") != nil || (&Token{}).Extra("id_token") != nil {
+		return false
+	}
+
+	// ReuseTokenSource only asks its source once the token runs out
+	calls := 0
+	src := tokenSourceFunc(func() (*Token, error) {
+		calls++
+		return &Token{AccessToken: fmt.Sprintf("t%d", calls), Expiry: time.Now().Add(time.Hour)}, nil
+	})
+	ts := ReuseTokenSource(&Token{AccessToken: "t0", Expiry: time.Now().Add(-time.Minute)}, src)
+	first, _ := ts.Token()
+	second, _ := ts.Token()
+	if first.AccessToken != "t1" || second.AccessToken != "t1" || calls != 1 {
+		return false
+	}
+	if st, _ := StaticTokenSource(&Token{AccessToken: "s"}).Token(); st.AccessToken != "s" {
+		return false
+	}
+
+	conf := &Config{ClientID: "c", Endpoint: Endpoint{AuthURL: "https://auth.example.com/authorize?tenant=1"},
+		Scopes: []string{"a", "b"}}
+	authURL, _ := url.Parse(conf.AuthCodeURL("", ApprovalForce))
+	q := authURL.Query()
+	return q.Get("tenant") == "1" && q.Get("scope") == "a b" && q.Get("prompt") == "consent" && !q.Has("state") &&
+		!q.Has("redirect_uri")
+}
+
+type tokenSourceFunc func() (*Token, error)
+
+func (f tokenSourceFunc) Token() (*Token, error) { return f() }
+
+func main() {
+	fmt.Println("Running OAuth2 Emulator Tests...")
+	fmt.Println("================================")
+
+	runTest("Authorization Code Flow", testAuthorizationCodeFlow)
+	runTest("PKCE", testPKCE)
+	runTest("Code Reuse and Expiry", testCodeReuse)
+	runTest("Redirect URIs", testRedirectURIs)
+	runTest("Authorization Errors", testAuthorizationErrors)
+	runTest("Client Authentication", testClientAuthentication)
+	runTest("Client Credentials", testClientCredentials)
+	runTest("Refresh Tokens", testRefreshTokens)
+	runTest("Authenticated Client", testAuthenticatedClient)
+	runTest("ID Tokens", testIDTokens)
+	runTest("Key Rotation", testKeyRotation)
+	runTest("UserInfo", testUserInfo)
+	runTest("Introspection and Revocation", testIntrospectionAndRevocation)
+	runTest("Discovery", testDiscovery)
+	runTest("Token Helpers", testTokenHelpers)
+
+	fmt.Println("================================")
+	fmt.Println("All tests completed!")
+}
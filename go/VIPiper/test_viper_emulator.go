@@ -2,8 +2,12 @@ package main
 
 // Developed by PowerShield, as an alternative to Viper
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Helper function to run a test
@@ -253,6 +257,64 @@ func testUnmarshalKey() bool {
 	return db.Host == "localhost" && db.Port == 5432
 }
 
+// upperDecoder records what it is given and upper-cases string settings
+type upperDecoder struct {
+	inputs []interface{}
+}
+
+func (d *upperDecoder) Decode(input interface{}, output interface{}) error {
+	d.inputs = append(d.inputs, input)
+	settings, ok := input.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected settings, got %T", input)
+	}
+	upper := make(map[string]interface{}, len(settings))
+	for k, val := range settings {
+		if s, ok := val.(string); ok {
+			val = strings.ToUpper(s)
+		}
+		upper[k] = val
+	}
+	return jsonDecoder{}.Decode(upper, output)
+}
+
+// Test SetDecoder replaces the JSON round trip in Unmarshal and UnmarshalKey
+func testSetDecoder() bool {
+	decoder := &upperDecoder{}
+	v := New()
+	v.SetDecoder(decoder)
+	v.SetDefault("server.port", 8080)
+	v.Set("server", map[string]interface{}{"host": "localhost"})
+	v.Set("name", "api")
+	
+	var cfg struct {
+		Name   string
+		Server struct {
+			Host string
+			Port int
+		}
+	}
+	if err := v.Unmarshal(&cfg); err != nil || cfg.Name != "API" || cfg.Server.Port != 8080 {
+		return false
+	}
+	var server struct{ Host string }
+	if err := v.UnmarshalKey("server", &server); err != nil || server.Host != "LOCALHOST" {
+		return false
+	}
+	if v.UnmarshalKey("name", &server) == nil || len(decoder.inputs) != 3 {
+		return false
+	}
+	
+	// Snapshots keep the decoder and Reset goes back to JSON
+	var snapped struct{ Name string }
+	if v.Snapshot().Unmarshal(&snapped) != nil || snapped.Name != "API" || len(decoder.inputs) != 4 {
+		return false
+	}
+	v.Reset()
+	v.Set("name", "api")
+	return v.Unmarshal(&snapped) == nil && snapped.Name == "api" && len(decoder.inputs) == 4
+}
+
 // Test Reset
 func testReset() bool {
 	v := New()
@@ -365,6 +427,762 @@ func testConfigPrecedence() bool {
 	return true
 }
 
+// Test writing and reading YAML
+func testWriteAndReadYAML() bool {
+	v := New()
+	v.Set("name", "TestApp")
+	v.Set("port", 8080)
+	v.Set("tags", []string{"api", "web"})
+	v.Set("database", map[string]interface{}{
+		"host": "localhost",
+		"port": 5432,
+	})
+	
+	err := v.WriteConfigAs("/tmp/test_config.yaml")
+	if err != nil {
+		fmt.Printf("Write error: %v\n", err)
+		return false
+	}
+	defer os.Remove("/tmp/test_config.yaml")
+	
+	v2 := New()
+	v2.SetConfigFile("/tmp/test_config.yaml")
+	if err := v2.ReadInConfig(); err != nil {
+		fmt.Printf("Read error: %v\n", err)
+		return false
+	}
+	
+	db := v2.Sub("database")
+	return v2.GetString("name") == "TestApp" &&
+		v2.GetInt("port") == 8080 &&
+		len(v2.GetStringSlice("tags")) == 2 &&
+		db != nil && db.GetString("host") == "localhost" && db.GetInt("port") == 5432
+}
+
+// Test writing and reading TOML
+func testWriteAndReadTOML() bool {
+	v := New()
+	v.Set("title", "My App")
+	v.Set("debug", true)
+	v.Set("server", map[string]interface{}{
+		"host": "0.0.0.0",
+		"ports": []int{80, 443},
+	})
+	
+	err := v.WriteConfigAs("/tmp/test_config.toml")
+	if err != nil {
+		fmt.Printf("Write error: %v\n", err)
+		return false
+	}
+	defer os.Remove("/tmp/test_config.toml")
+	
+	v2 := New()
+	v2.SetConfigFile("/tmp/test_config.toml")
+	if err := v2.ReadInConfig(); err != nil {
+		fmt.Printf("Read error: %v\n", err)
+		return false
+	}
+	
+	server := v2.Sub("server")
+	return v2.GetString("title") == "My App" &&
+		v2.GetBool("debug") &&
+		server != nil && server.GetString("host") == "0.0.0.0" &&
+		len(server.GetStringSlice("ports")) == 2
+}
+
+// Test writing env files
+func testWriteEnv() bool {
+	v := New()
+	v.Set("app_name", "MyApp")
+	v.Set("database", map[string]interface{}{
+		"host": "localhost",
+	})
+	
+	err := v.WriteConfigAs("/tmp/test_config.env")
+	if err != nil {
+		return false
+	}
+	defer os.Remove("/tmp/test_config.env")
+	
+	data, _ := os.ReadFile("/tmp/test_config.env")
+	if string(data) != "APP_NAME=MyApp\nDATABASE_HOST=localhost\n" {
+		fmt.Printf("Unexpected env output: %q\n", string(data))
+		return false
+	}
+	
+	v2 := New()
+	v2.SetConfigFile("/tmp/test_config.env")
+	if err := v2.ReadInConfig(); err != nil {
+		return false
+	}
+	return v2.GetString("app_name") == "MyApp" && v2.GetString("database_host") == "localhost"
+}
+
+// Test converting a JSON config to YAML
+func testFormatConversion() bool {
+	json := `{"name": "Legacy", "server": {"port": 9000, "hosts": ["a", "b"]}}`
+	os.WriteFile("/tmp/legacy_config.json", []byte(json), 0644)
+	defer os.Remove("/tmp/legacy_config.json")
+	
+	v := New()
+	v.SetConfigFile("/tmp/legacy_config.json")
+	if err := v.ReadInConfig(); err != nil {
+		return false
+	}
+	if err := v.WriteConfigAs("/tmp/migrated_config.yml"); err != nil {
+		return false
+	}
+	defer os.Remove("/tmp/migrated_config.yml")
+	
+	v2 := New()
+	v2.SetConfigFile("/tmp/migrated_config.yml")
+	if err := v2.ReadInConfig(); err != nil {
+		return false
+	}
+	
+	server := v2.Sub("server")
+	return v2.GetString("name") == "Legacy" &&
+		server != nil && server.GetInt("port") == 9000 &&
+		len(server.GetStringSlice("hosts")) == 2
+}
+
+// Test reading hand-written YAML
+func testReadYAMLDocument() bool {
+	doc := `# service configuration
+name: "orders"
+replicas: 3
+ratio: 0.5
+enabled: yes
+servers:
+  - host: a.example.com
+    port: 80
+  - host: b.example.com
+    port: 81
+limits:
+  cpu: 500m
+  regions: [us, eu]
+`
+	os.WriteFile("/tmp/doc_config.yaml", []byte(doc), 0644)
+	defer os.Remove("/tmp/doc_config.yaml")
+	
+	v := New()
+	v.SetConfigFile("/tmp/doc_config.yaml")
+	if err := v.ReadInConfig(); err != nil {
+		fmt.Printf("Read error: %v\n", err)
+		return false
+	}
+	
+	servers, ok := v.Get("servers").([]interface{})
+	if !ok || len(servers) != 2 {
+		return false
+	}
+	second, ok := servers[1].(map[string]interface{})
+	limits := v.Sub("limits")
+	return ok && second["host"] == "b.example.com" && second["port"] == 81 &&
+		v.GetString("name") == "orders" &&
+		v.GetInt("replicas") == 3 &&
+		v.GetFloat64("ratio") == 0.5 &&
+		v.GetBool("enabled") &&
+		limits != nil && limits.GetString("cpu") == "500m" &&
+		len(limits.GetStringSlice("regions")) == 2
+}
+
+// Test unsupported config types
+func testUnsupportedConfigType() bool {
+	v := New()
+	v.Set("name", "test")
+	err := v.WriteConfigAs("/tmp/test_config.hcl")
+	_, isUnsupported := err.(UnsupportedConfigError)
+	return isUnsupported
+}
+
+// Test SetDefaultsFromStruct
+func testSetDefaultsFromStruct() bool {
+	type Server struct {
+		Host    string        `json:"host" default:"localhost"`
+		Port    int           `json:"port" default:"8080"`
+		Timeout time.Duration `json:"timeout" default:"30s"`
+	}
+	type Config struct {
+		Name   string   `json:"name" default:"MyApp"`
+		Debug  bool     `default:"true"`
+		Tags   []string `json:"tags" default:"api,web"`
+		Secret string   `json:"secret"`
+		Server Server   `json:"server"`
+	}
+	
+	v := New()
+	if err := v.SetDefaultsFromStruct(&Config{}); err != nil {
+		fmt.Printf("SetDefaultsFromStruct error: %v\n", err)
+		return false
+	}
+	
+	return v.GetString("name") == "MyApp" &&
+		v.GetBool("debug") &&
+		len(v.GetStringSlice("tags")) == 2 &&
+		!v.IsSet("secret") &&
+		v.GetString("server.host") == "localhost" &&
+		v.GetInt("server.port") == 8080 &&
+		v.Get("server.timeout") == 30*time.Second
+}
+
+// Test struct defaults round-trip through Unmarshal
+func testStructDefaultsUnmarshal() bool {
+	type Server struct {
+		Host string `json:"host" default:"localhost"`
+		Port int    `json:"port" default:"8080"`
+	}
+	type Config struct {
+		Name   string `json:"name" default:"MyApp"`
+		Server Server `json:"server"`
+	}
+	
+	v := New()
+	v.SetDefaultsFromStruct(Config{})
+	v.Set("server", map[string]interface{}{"port": 9090})
+	
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		fmt.Printf("Unmarshal error: %v\n", err)
+		return false
+	}
+	return config.Name == "MyApp" && config.Server.Host == "localhost" && config.Server.Port == 9090
+}
+
+// Test SetDefaultsFromStruct errors
+func testSetDefaultsFromStructErrors() bool {
+	type Bad struct {
+		Port int `default:"not-a-number"`
+	}
+	v := New()
+	return v.SetDefaultsFromStruct("not a struct") != nil &&
+		v.SetDefaultsFromStruct(Bad{}) != nil
+}
+
+// Test dotted key lookup into nested maps
+func testDottedKeyLookup() bool {
+	v := New()
+	v.Set("database", map[string]interface{}{
+		"host": "localhost",
+		"pool": map[string]interface{}{"size": 10},
+	})
+	return v.GetString("database.host") == "localhost" &&
+		v.GetInt("database.pool.size") == 10 &&
+		v.Get("database.missing") == nil
+}
+
+// Test environment variable expansion in config files
+func testEnvExpansion() bool {
+	os.Setenv("TEST_DB_HOST", "db.internal")
+	defer os.Unsetenv("TEST_DB_HOST")
+	os.Unsetenv("TEST_DB_PORT")
+	
+	doc := `database:
+  host: ${TEST_DB_HOST}
+  port: ${TEST_DB_PORT:-5432}
+  url: "postgres://${TEST_DB_HOST}:${TEST_DB_PORT:-5432}/app"
+hosts: ["${TEST_DB_HOST}", "static"]
+`
+	os.WriteFile("/tmp/expand_config.yaml", []byte(doc), 0644)
+	defer os.Remove("/tmp/expand_config.yaml")
+	
+	v := New()
+	v.AllowEnvExpansion(true)
+	v.SetConfigFile("/tmp/expand_config.yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return false
+	}
+	
+	hosts := v.GetStringSlice("hosts")
+	return v.GetString("database.host") == "db.internal" &&
+		v.GetInt("database.port") == 5432 &&
+		v.GetString("database.url") == "postgres://db.internal:5432/app" &&
+		len(hosts) == 2 && hosts[0] == "db.internal"
+}
+
+// Test environment variable expansion is opt-in
+func testEnvExpansionDisabled() bool {
+	os.Setenv("TEST_APP_NAME", "expanded")
+	defer os.Unsetenv("TEST_APP_NAME")
+	
+	os.WriteFile("/tmp/noexpand_config.json", []byte(`{"name": "${TEST_APP_NAME}"}`), 0644)
+	defer os.Remove("/tmp/noexpand_config.json")
+	
+	v := New()
+	v.SetConfigFile("/tmp/noexpand_config.json")
+	if err := v.ReadInConfig(); err != nil {
+		return false
+	}
+	return v.GetString("name") == "${TEST_APP_NAME}"
+}
+
+// Test AllSettings returns deep copies
+func testAllSettingsDeepCopy() bool {
+	v := New()
+	v.Set("server", map[string]interface{}{
+		"host":  "localhost",
+		"ports": []interface{}{80, 443},
+	})
+	v.Set("tags", []string{"a", "b"})
+	
+	settings := v.AllSettings()
+	server := settings["server"].(map[string]interface{})
+	server["host"] = "mutated"
+	server["ports"].([]interface{})[0] = 8080
+	settings["tags"].([]string)[0] = "mutated"
+	
+	return v.GetString("server.host") == "localhost" &&
+		v.GetStringMap("server")["ports"].([]interface{})[0] == 80 &&
+		v.GetStringSlice("tags")[0] == "a"
+}
+
+// Test Snapshot is isolated from later changes
+func testSnapshot() bool {
+	os.Setenv("TEST_SNAPSHOT_LEVEL", "debug")
+	defer os.Unsetenv("TEST_SNAPSHOT_LEVEL")
+	
+	v := New()
+	v.SetDefault("port", 8080)
+	v.Set("database", map[string]interface{}{"host": "localhost"})
+	v.BindEnv("log_level", "TEST_SNAPSHOT_LEVEL")
+	
+	snap := v.Snapshot()
+	
+	// Reload-style changes after the snapshot was taken
+	v.Set("port", 9090)
+	v.GetStringMap("database")["host"] = "reloaded"
+	os.Setenv("TEST_SNAPSHOT_LEVEL", "info")
+	
+	// Mutating values read from the snapshot must not affect it
+	snap.GetStringMap("database")["host"] = "mutated"
+	snap.AllSettings()["port"] = 1
+	
+	return snap.GetInt("port") == 8080 &&
+		snap.GetString("database.host") == "localhost" &&
+		snap.GetString("log_level") == "debug" &&
+		v.GetInt("port") == 9090
+}
+
+// memRemoteStore is a RemoteStore serving documents from memory; writes
+// reach its watchers
+type memRemoteStore struct {
+	mu       sync.Mutex
+	docs     map[string][]byte
+	watchers map[string][]chan []byte
+}
+
+func newMemRemoteStore() *memRemoteStore {
+	return &memRemoteStore{docs: make(map[string][]byte), watchers: make(map[string][]chan []byte)}
+}
+
+func (m *memRemoteStore) Get(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.docs[path]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return data, nil
+}
+
+func (m *memRemoteStore) Watch(path string, stop <-chan struct{}) <-chan []byte {
+	in := make(chan []byte, 8)
+	out := make(chan []byte)
+	m.mu.Lock()
+	m.watchers[path] = append(m.watchers[path], in)
+	m.mu.Unlock()
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case data := <-in:
+				select {
+				case out <- data:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (m *memRemoteStore) put(path, doc string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs[path] = []byte(doc)
+	for _, ch := range m.watchers[path] {
+		ch <- []byte(doc)
+	}
+}
+
+// Test reading configuration from a remote provider
+func testRemoteConfig() bool {
+	store := newMemRemoteStore()
+	store.put("/config/app.yaml", "port: 8080\nlog_level: info\ndatabase:\n  host: db.internal\n")
+	RegisterRemoteStore("etcd3", "http://127.0.0.1:4001", store)
+	
+	v := New()
+	v.SetDefault("port", 80)
+	v.SetDefault("timeout", 30)
+	if err := v.AddRemoteProvider("etcd3", "http://127.0.0.1:4001", "/config/app.yaml"); err != nil {
+		return false
+	}
+	if err := v.ReadRemoteConfig(); err != nil {
+		return false
+	}
+	
+	// Remote values override defaults, and Set overrides remote values
+	if v.GetInt("port") != 8080 || v.GetInt("timeout") != 30 || v.GetString("database.host") != "db.internal" {
+		return false
+	}
+	v.Set("log_level", "debug")
+	if v.GetString("log_level") != "debug" || !v.IsSet("database") {
+		return false
+	}
+	
+	var cfg struct {
+		Port     int `json:"port"`
+		Timeout  int `json:"timeout"`
+		Database struct {
+			Host string `json:"host"`
+		} `json:"database"`
+	}
+	if err := v.Unmarshal(&cfg); err != nil || cfg.Port != 8080 || cfg.Database.Host != "db.internal" {
+		return false
+	}
+	if len(v.AllKeys()) != 4 || v.AllSettings()["port"] != 8080 {
+		return false
+	}
+	
+	// An explicit config type wins over the path's extension
+	store.put("/config/app", `{"port": 9090}`)
+	w := New()
+	w.SetConfigType("json")
+	w.AddRemoteProvider("etcd3", "http://127.0.0.1:4001", "/config/app")
+	if err := w.ReadRemoteConfig(); err != nil || w.GetInt("port") != 9090 {
+		return false
+	}
+	snap := w.Snapshot()
+	w.Reset()
+	return w.Get("port") == nil && snap.GetInt("port") == 9090
+}
+
+// Test remote provider errors and fallbacks
+func testRemoteConfigErrors() bool {
+	v := New()
+	err := v.AddRemoteProvider("zookeeper", "localhost:2181", "/config")
+	if _, ok := err.(UnsupportedRemoteProviderError); !ok || err.Error() != `Unsupported Remote Provider Type "zookeeper"` {
+		return false
+	}
+	err = v.ReadRemoteConfig()
+	if _, ok := err.(RemoteConfigError); !ok || err.Error() != "Remote Configurations Error: No Files Found" {
+		return false
+	}
+	
+	// Providers that are unreachable or lack the document are skipped
+	store := newMemRemoteStore()
+	store.put("/config/good.json", `{"source": "consul"}`)
+	store.put("/config/bad.json", `not json`)
+	RegisterRemoteStore("consul", "localhost:8500", store)
+	v.AddRemoteProvider("etcd", "http://unreachable:2379", "/config/app.json")
+	v.AddRemoteProvider("consul", "localhost:8500", "/config/missing.json")
+	v.AddRemoteProvider("consul", "localhost:8500", "/config/bad.json")
+	v.AddSecureRemoteProvider("consul", "localhost:8500", "/config/good.json", "/etc/secrets/keyring.gpg")
+	if err := v.ReadRemoteConfig(); err != nil {
+		return false
+	}
+	return v.GetString("source") == "consul"
+}
+
+// Test watching a remote provider for changes
+func testWatchRemoteConfig() bool {
+	store := newMemRemoteStore()
+	store.put("/config/live.json", `{"feature": "off"}`)
+	RegisterRemoteStore("etcd3", "localhost:2379", store)
+	
+	v := New()
+	v.AddRemoteProvider("etcd3", "localhost:2379", "/config/live.json")
+	if err := v.ReadRemoteConfig(); err != nil {
+		return false
+	}
+	if err := v.WatchRemoteConfigOnChannel(); err != nil {
+		return false
+	}
+	
+	store.put("/config/live.json", `{"feature": "on"}`)
+	deadline := time.Now().Add(time.Second)
+	for v.GetString("feature") != "on" {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	
+	// WatchRemoteConfig re-reads on demand
+	store.mu.Lock()
+	store.docs["/config/live.json"] = []byte(`{"feature": "beta"}`)
+	store.mu.Unlock()
+	if err := v.WatchRemoteConfig(); err != nil || v.GetString("feature") != "beta" {
+		return false
+	}
+	
+	// Reset stops the watch
+	v.Reset()
+	store.put("/config/live.json", `{"feature": "off"}`)
+	time.Sleep(20 * time.Millisecond)
+	return v.Get("feature") == nil && New().WatchRemoteConfigOnChannel() != nil
+}
+
+// memFs is an Fs keeping files in a map
+type memFs struct {
+	files map[string][]byte
+}
+
+func (m *memFs) ReadFile(filename string) ([]byte, error) {
+	data, ok := m.files[filename]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	return data, nil
+}
+
+func (m *memFs) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	m.files[filename] = data
+	return nil
+}
+
+func (m *memFs) Stat(name string) (os.FileInfo, error) {
+	if _, ok := m.files[name]; !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	// Only the error matters to viper
+	return nil, nil
+}
+
+// Test SetFs reads and writes config files without touching the disk
+func testSetFs() bool {
+	fs := &memFs{files: map[string][]byte{"/etc/app/config.yaml": []byte("port: 9090\n")}}
+	
+	v := New()
+	v.SetFs(fs)
+	v.SetConfigFile("/etc/app/config.yaml")
+	if err := v.ReadInConfig(); err != nil || v.GetInt("port") != 9090 {
+		return false
+	}
+	if _, err := os.Stat("/etc/app/config.yaml"); err == nil {
+		return false
+	}
+	
+	v.Set("host", "example.com")
+	if err := v.WriteConfigAs("/etc/app/out.json"); err != nil {
+		return false
+	}
+	if v.SafeWriteConfig() == nil {
+		return false
+	}
+	
+	v2 := New()
+	v2.SetFs(fs)
+	v2.SetConfigFile("/etc/app/out.json")
+	if err := v2.ReadInConfig(); err != nil || v2.GetString("host") != "example.com" {
+		return false
+	}
+	
+	// Reset goes back to the disk
+	v2.Reset()
+	v2.SetConfigFile("/etc/app/out.json")
+	return v2.ReadInConfig() != nil
+}
+
+// propsCodec reads and writes flat key=value files
+type propsCodec struct {
+	decoded int
+}
+
+func (c *propsCodec) Encode(v map[string]interface{}) ([]byte, error) {
+	var out string
+	for _, key := range sortedKeys(v) {
+		out += fmt.Sprintf("%s=%v\n", key, v[key])
+	}
+	return []byte(out), nil
+}
+
+func (c *propsCodec) Decode(data []byte, v map[string]interface{}) error {
+	c.decoded++
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("props: bad line %q", line)
+		}
+		v[parts[0]] = parts[1]
+	}
+	return nil
+}
+
+// Test RegisterCodec adds formats and replaces built-in ones
+func testRegisterCodec() bool {
+	codec := &propsCodec{}
+	RegisterCodec("props", codec)
+	fs := &memFs{files: map[string][]byte{"/app.PROPS": []byte("name=api\nport=8080\n")}}
+	
+	v := New()
+	v.SetFs(fs)
+	v.SetConfigFile("/app.PROPS")
+	if err := v.ReadInConfig(); err != nil || v.GetString("name") != "api" || v.GetInt("port") != 8080 {
+		return false
+	}
+	v.Set("debug", true)
+	if err := v.WriteConfigAs("/out.props"); err != nil || string(fs.files["/out.props"]) != "debug=true\nname=api\nport=8080\n" {
+		return false
+	}
+	
+	// A registered codec takes over a built-in format until it is removed
+	fs.files["/app.yaml"] = []byte("port: 9090\n")
+	RegisterCodec("yaml", codec)
+	v2 := New()
+	v2.SetFs(fs)
+	v2.SetConfigFile("/app.yaml")
+	err := v2.ReadInConfig()
+	RegisterCodec("yaml", nil)
+	if err == nil || codec.decoded != 2 {
+		return false
+	}
+	return v2.ReadInConfig() == nil && v2.GetInt("port") == 9090
+}
+
+// fakeFileWatcher is a FileWatcher whose events are sent by hand
+type fakeFileWatcher struct {
+	mu     sync.Mutex
+	paths  []string
+	events func(name string, op uint32)
+	closed bool
+}
+
+func (f *fakeFileWatcher) Add(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, name)
+	return nil
+}
+
+func (f *fakeFileWatcher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeFileWatcher) Notify(events func(name string, op uint32), errors func(err error)) {
+	f.events = events
+}
+
+func (f *fakeFileWatcher) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// Test WatchConfig
+func testWatchConfig() bool {
+	dir, _ := os.MkdirTemp("", "viper-watch")
+	defer os.RemoveAll(dir)
+	configFile := dir + "/app.json"
+	os.WriteFile(configFile, []byte(`{"level": "info"}`), 0644)
+	
+	watcher := &fakeFileWatcher{}
+	RegisterFileWatcher(func() (FileWatcher, error) { return watcher, nil })
+	defer RegisterFileWatcher(nil)
+	
+	v := New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return false
+	}
+	var changes []Event
+	v.OnConfigChange(func(in Event) { changes = append(changes, in) })
+	v.WatchConfig()
+	if len(watcher.paths) != 1 || watcher.paths[0] != dir {
+		return false
+	}
+	
+	// Other files in the directory are ignored
+	watcher.events(dir+"/other.json", uint32(Write))
+	if len(changes) != 0 {
+		return false
+	}
+	
+	os.WriteFile(configFile, []byte(`{"level": "debug"}`), 0644)
+	watcher.events(configFile, uint32(Write))
+	if len(changes) != 1 || !changes[0].Has(Write) || v.GetString("level") != "debug" {
+		return false
+	}
+	
+	// Removing the config file stops the watch
+	watcher.events(configFile, uint32(Remove))
+	deadline := time.Now().Add(time.Second)
+	for !watcher.isClosed() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return len(changes) == 1
+}
+
+// Test snapshots and reads while WatchConfig reloads the file, which
+// the race detector checks
+func testWatchConfigConcurrentReads() bool {
+	dir, _ := os.MkdirTemp("", "viper-watch")
+	defer os.RemoveAll(dir)
+	configFile := dir + "/app.json"
+	os.WriteFile(configFile, []byte(`{"version": 0, "build": 0}`), 0644)
+	
+	watcher := &fakeFileWatcher{}
+	RegisterFileWatcher(func() (FileWatcher, error) { return watcher, nil })
+	defer RegisterFileWatcher(nil)
+	
+	v := New()
+	v.SetConfigFile(configFile)
+	v.SetDefault("region", "eu")
+	if err := v.ReadInConfig(); err != nil {
+		return false
+	}
+	v.WatchConfig()
+	
+	// Each reload writes version and build together
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			os.WriteFile(configFile, []byte(fmt.Sprintf(`{"version": %d, "build": %d}`, i, i)), 0644)
+			watcher.events(configFile, uint32(Write))
+		}
+	}()
+	
+	consistent := true
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		snap := v.Snapshot()
+		if snap.GetInt("version") != snap.GetInt("build") || snap.GetString("region") != "eu" {
+			consistent = false
+		}
+		var settings struct {
+			Version int `json:"version"`
+		}
+		v.Unmarshal(&settings)
+		v.Get("version")
+		v.AllSettings()
+	}
+	return consistent && v.GetInt("version") == 50 && v.Snapshot().GetInt("build") == 50
+}
+
 // Test GetViper
 func testGetViper() bool {
 	v := GetViper()
@@ -394,6 +1212,7 @@ func main() {
 	runTest("SafeWriteConfig", testSafeWriteConfig)
 	runTest("Unmarshal", testUnmarshal)
 	runTest("UnmarshalKey", testUnmarshalKey)
+	runTest("SetDecoder", testSetDecoder)
 	runTest("Reset", testReset)
 	runTest("Global Functions", testGlobalFunctions)
 	runTest("Type Conversions", testTypeConversions)
@@ -401,6 +1220,27 @@ func main() {
 	runTest("Multiple Types", testMultipleTypes)
 	runTest("Config Precedence", testConfigPrecedence)
 	runTest("GetViper", testGetViper)
+	runTest("Write and Read YAML", testWriteAndReadYAML)
+	runTest("Write and Read TOML", testWriteAndReadTOML)
+	runTest("Write Env", testWriteEnv)
+	runTest("Format Conversion", testFormatConversion)
+	runTest("Read YAML Document", testReadYAMLDocument)
+	runTest("Unsupported Config Type", testUnsupportedConfigType)
+	runTest("SetDefaultsFromStruct", testSetDefaultsFromStruct)
+	runTest("Struct Defaults Unmarshal", testStructDefaultsUnmarshal)
+	runTest("SetDefaultsFromStruct Errors", testSetDefaultsFromStructErrors)
+	runTest("Dotted Key Lookup", testDottedKeyLookup)
+	runTest("Env Expansion", testEnvExpansion)
+	runTest("Env Expansion Disabled", testEnvExpansionDisabled)
+	runTest("AllSettings Deep Copy", testAllSettingsDeepCopy)
+	runTest("Snapshot", testSnapshot)
+	runTest("Remote Config", testRemoteConfig)
+	runTest("Remote Config Errors", testRemoteConfigErrors)
+	runTest("Watch Remote Config", testWatchRemoteConfig)
+	runTest("SetFs", testSetFs)
+	runTest("Watch Config", testWatchConfig)
+	runTest("Watch Config Concurrent Reads", testWatchConfigConcurrentReads)
+	runTest("Register Codec", testRegisterCodec)
 
 	fmt.Println("==============================")
 	fmt.Println("All tests completed!")
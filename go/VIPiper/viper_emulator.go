@@ -5,15 +5,52 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// SupportedExts lists the configuration formats the emulator can read and write
+var SupportedExts = []string{"json", "yaml", "yml", "toml", "env", "dotenv"}
+
+// UnsupportedConfigError is returned when a config format is not supported
+type UnsupportedConfigError string
+
+func (e UnsupportedConfigError) Error() string {
+	return fmt.Sprintf("Unsupported Config Type %q", string(e))
+}
+
 // Viper is the main configuration manager
 type Viper struct {
+	// mu guards config, defaults and env, which WatchConfig reloads in
+	// the background. It is taken before kvMu when both are needed.
+	mu        sync.RWMutex
 	config    map[string]interface{}
 	defaults  map[string]interface{}
 	env       map[string]string
 	configFile string
 	configType string
+	fs         Fs
+	decoder    StructDecoder
+	
+	// expandEnv is read by background reloads, so it is guarded by mu
+	expandEnv bool
+	
+	// kvstore holds the configuration read from remote providers. It is
+	// guarded by kvMu because WatchRemoteConfigOnChannel replaces it in
+	// the background.
+	kvMu            sync.RWMutex
+	kvstore         map[string]interface{}
+	remoteProviders []*defaultRemoteProvider
+	remoteStop      chan struct{}
+	
+	// onConfigChange and watcher belong to WatchConfig
+	onConfigChange func(in Event)
+	watcher        FileWatcher
 }
 
 // New creates a new Viper instance
@@ -22,6 +59,9 @@ func New() *Viper {
 		config:   make(map[string]interface{}),
 		defaults: make(map[string]interface{}),
 		env:      make(map[string]string),
+		kvstore:  make(map[string]interface{}),
+		fs:       osFs{},
+		decoder:  jsonDecoder{},
 	}
 }
 
@@ -30,11 +70,16 @@ var globalViper = New()
 
 // Set sets a configuration value
 func (v *Viper) Set(key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	v.config[key] = value
 }
 
 // Get retrieves a configuration value
 func (v *Viper) Get(key string) interface{} {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	
 	// Check environment variables first (highest priority)
 	if envKey, ok := v.env[key]; ok {
 		if envVal := os.Getenv(envKey); envVal != "" {
@@ -43,18 +88,56 @@ func (v *Viper) Get(key string) interface{} {
 	}
 	
 	// Check config
-	if val, ok := v.config[key]; ok {
+	if val, ok := searchMap(v.config, key); ok {
+		return val
+	}
+	
+	// Check remote key/value stores
+	v.kvMu.RLock()
+	val, ok := searchMap(v.kvstore, key)
+	v.kvMu.RUnlock()
+	if ok {
 		return val
 	}
 	
 	// Check defaults
-	if val, ok := v.defaults[key]; ok {
+	if val, ok := searchMap(v.defaults, key); ok {
 		return val
 	}
 	
 	return nil
 }
 
+// searchMap looks up a key, falling back to walking nested maps for
+// dotted keys such as "server.port"
+func searchMap(m map[string]interface{}, key string) (interface{}, bool) {
+	if val, ok := m[key]; ok {
+		return val, true
+	}
+	
+	path := strings.Split(key, ".")
+	if len(path) < 2 {
+		return nil, false
+	}
+	
+	current := m
+	for i, part := range path {
+		val, ok := current[part]
+		if !ok {
+			return nil, false
+		}
+		if i == len(path)-1 {
+			return val, true
+		}
+		next, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return nil, false
+}
+
 // GetString gets a string configuration value
 func (v *Viper) GetString(key string) string {
 	val := v.Get(key)
@@ -166,15 +249,120 @@ func (v *Viper) GetStringMap(key string) map[string]interface{} {
 
 // SetDefault sets a default value
 func (v *Viper) SetDefault(key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	v.defaults[key] = value
 }
 
+// SetDefaultsFromStruct registers a default for every field of a struct
+// carrying a `default:"..."` tag. Keys follow the json tag (or the lowercased
+// field name) and nested structs produce dotted keys such as "server.port".
+func (v *Viper) SetDefaultsFromStruct(s interface{}) error {
+	t := reflect.TypeOf(s)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("SetDefaultsFromStruct expects a struct, got %T", s)
+	}
+	return v.setDefaultsFromType(t, "")
+}
+
+// setDefaultsFromType walks the fields of a struct type
+func (v *Viper) setDefaultsFromType(t reflect.Type, prefix string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // unexported
+		}
+		
+		name := strings.ToLower(field.Name)
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		
+		if fieldType.Kind() == reflect.Struct && fieldType != reflect.TypeOf(time.Time{}) {
+			nestedPrefix := prefix + name + "."
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				// Embedded structs are flattened into the parent
+				nestedPrefix = prefix
+			}
+			if err := v.setDefaultsFromType(fieldType, nestedPrefix); err != nil {
+				return err
+			}
+			continue
+		}
+		
+		raw, ok := field.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		
+		val, err := parseDefault(raw, fieldType)
+		if err != nil {
+			return fmt.Errorf("invalid default for %s%s: %v", prefix, name, err)
+		}
+		v.SetDefault(prefix+name, val)
+	}
+	return nil
+}
+
+// parseDefault converts a default tag value to the field's type
+func parseDefault(raw string, t reflect.Type) (interface{}, error) {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return time.ParseDuration(raw)
+	}
+	
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		return int(i), err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(raw, 10, 64)
+		return int(u), err
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	case reflect.Slice:
+		result := []interface{}{}
+		if raw == "" {
+			return result, nil
+		}
+		for _, part := range strings.Split(raw, ",") {
+			item, err := parseDefault(strings.TrimSpace(part), t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, item)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
 // BindEnv binds a configuration key to an environment variable
 func (v *Viper) BindEnv(key string, envVars ...string) error {
 	envKey := key
 	if len(envVars) > 0 {
 		envKey = envVars[0]
 	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	v.env[key] = envKey
 	return nil
 }
@@ -193,6 +381,36 @@ func (v *Viper) AutomaticEnv() {
 	// For this emulator, use explicit BindEnv() for specific keys instead
 }
 
+// Fs is the file system config files are read from and written to. The
+// afero emulator's Afero implements it, as can any file system with these
+// methods.
+type Fs interface {
+	ReadFile(filename string) ([]byte, error)
+	WriteFile(filename string, data []byte, perm os.FileMode) error
+	Stat(name string) (os.FileInfo, error)
+}
+
+// osFs is the file system of the operating system
+type osFs struct{}
+
+func (osFs) ReadFile(filename string) ([]byte, error) {
+	return os.ReadFile(filename)
+}
+
+func (osFs) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(filename, data, perm)
+}
+
+func (osFs) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// SetFs sets the file system for config files, such as an in-memory one
+// for tests
+func (v *Viper) SetFs(fs Fs) {
+	v.fs = fs
+}
+
 // SetConfigFile sets the configuration file path
 func (v *Viper) SetConfigFile(in string) {
 	v.configFile = in
@@ -219,55 +437,736 @@ func (v *Viper) ReadInConfig() error {
 		return fmt.Errorf("config file not set")
 	}
 	
-	data, err := os.ReadFile(v.configFile)
+	data, err := v.fs.ReadFile(v.configFile)
 	if err != nil {
 		return err
 	}
 	
-	// Parse based on config type
-	switch v.configType {
-	case "json":
-		return v.readJSON(data)
+	// Parse based on config type, falling back to the file extension
+	configType := v.configType
+	if configType == "" {
+		configType = configTypeFromPath(v.configFile)
+	}
+	
+	return v.mergeConfig(decodeConfig(data, configType))
+}
+
+// Codec encodes and decodes one configuration format. The yaml emulator's
+// Codec implements it, as can any encoder with these methods.
+type Codec interface {
+	Encode(v map[string]interface{}) ([]byte, error)
+	Decode(data []byte, v map[string]interface{}) error
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+// RegisterCodec makes codec read and write the given format, replacing the
+// built-in support for it if there is any. A nil codec restores the
+// built-in support.
+func RegisterCodec(format string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[strings.ToLower(format)] = codec
+}
+
+func lookupCodec(format string) Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return codecs[format]
+}
+
+// decodeConfig parses data in the given format
+func decodeConfig(data []byte, configType string) (map[string]interface{}, error) {
+	if codec := lookupCodec(configType); codec != nil {
+		config := make(map[string]interface{})
+		if err := codec.Decode(data, config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	}
+	
+	switch configType {
+	case "json", "":
+		var config map[string]interface{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	case "yaml", "yml":
+		return decodeYAML(data)
+	case "toml":
+		return decodeTOML(data)
+	case "env", "dotenv":
+		return decodeEnv(data)
 	default:
-		// Try JSON as default
-		return v.readJSON(data)
+		return nil, UnsupportedConfigError(configType)
 	}
 }
 
-// readJSON reads JSON configuration
-func (v *Viper) readJSON(data []byte) error {
-	var config map[string]interface{}
-	err := json.Unmarshal(data, &config)
+// configTypeFromPath derives a config type from a file extension
+func configTypeFromPath(path string) string {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(ext, "."))
+}
+
+// mergeConfig merges decoded configuration into the existing config
+func (v *Viper) mergeConfig(config map[string]interface{}, err error) error {
 	if err != nil {
 		return err
 	}
 	
-	// Merge with existing config
+	// Merge under one lock so readers see all of a reload or none of it
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	for k, val := range config {
+		if v.expandEnv {
+			val = expandEnvValue(val)
+		}
 		v.config[k] = val
 	}
 	
 	return nil
 }
 
+// AllowEnvExpansion enables ${VAR} and ${VAR:-fallback} expansion in string
+// values read from config files
+func (v *Viper) AllowEnvExpansion(allow bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.expandEnv = allow
+}
+
+// expandEnvValue expands environment references in strings, recursing into
+// maps and slices
+func expandEnvValue(val interface{}) interface{} {
+	switch typed := val.(type) {
+	case string:
+		return expandEnvString(typed)
+	case map[string]interface{}:
+		for k, item := range typed {
+			typed[k] = expandEnvValue(item)
+		}
+		return typed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = expandEnvValue(item)
+		}
+		return typed
+	default:
+		return val
+	}
+}
+
+// expandEnvString replaces ${VAR} with the variable's value and
+// ${VAR:-fallback} with the fallback when VAR is unset or empty
+func expandEnvString(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end += start
+		
+		b.WriteString(s[:start])
+		name, fallback := s[start+2:end], ""
+		hasFallback := false
+		if idx := strings.Index(name, ":-"); idx >= 0 {
+			name, fallback, hasFallback = name[:idx], name[idx+2:], true
+		}
+		
+		value := os.Getenv(name)
+		if value == "" && hasFallback {
+			value = fallback
+		}
+		b.WriteString(value)
+		s = s[end+1:]
+	}
+}
+
 // WriteConfig writes the current configuration to file
 func (v *Viper) WriteConfig() error {
 	return v.WriteConfigAs(v.configFile)
 }
 
 // WriteConfigAs writes the configuration to a specific file
+// The output format is chosen from the file extension, falling back to the
+// configured type and then JSON, so a config read in one format can be
+// written out in another.
 func (v *Viper) WriteConfigAs(filename string) error {
-	data, err := json.MarshalIndent(v.config, "", "  ")
+	configType := configTypeFromPath(filename)
+	if configType == "" {
+		configType = v.configType
+	}
+	
+	v.mu.RLock()
+	data, err := encodeConfig(v.config, configType)
+	v.mu.RUnlock()
 	if err != nil {
 		return err
 	}
 	
-	return os.WriteFile(filename, data, 0644)
+	return v.fs.WriteFile(filename, data, 0644)
+}
+
+// encodeConfig serializes a configuration map in the given format
+func encodeConfig(config map[string]interface{}, configType string) ([]byte, error) {
+	if codec := lookupCodec(configType); codec != nil {
+		return codec.Encode(config)
+	}
+	
+	switch configType {
+	case "json", "":
+		return json.MarshalIndent(config, "", "  ")
+	case "yaml", "yml":
+		return encodeYAML(config), nil
+	case "toml":
+		return encodeTOML(config), nil
+	case "env", "dotenv":
+		return encodeEnv(config), nil
+	default:
+		return nil, UnsupportedConfigError(configType)
+	}
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toStringMap converts supported map types to map[string]interface{}
+func toStringMap(val interface{}) (map[string]interface{}, bool) {
+	switch m := val.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[string]string:
+		result := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// toSlice converts supported slice types to []interface{}
+func toSlice(val interface{}) ([]interface{}, bool) {
+	switch s := val.(type) {
+	case []interface{}:
+		return s, true
+	case []string:
+		result := make([]interface{}, len(s))
+		for i, item := range s {
+			result[i] = item
+		}
+		return result, true
+	case []int:
+		result := make([]interface{}, len(s))
+		for i, item := range s {
+			result[i] = item
+		}
+		return result, true
+	case []float64:
+		result := make([]interface{}, len(s))
+		for i, item := range s {
+			result[i] = item
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// parseScalar converts a raw scalar token into a typed value
+func parseScalar(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 {
+		if raw[0] == '"' && raw[len(raw)-1] == '"' {
+			if unquoted, err := strconv.Unquote(raw); err == nil {
+				return unquoted
+			}
+			return raw[1 : len(raw)-1]
+		}
+		if raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+			return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'")
+		}
+	}
+	
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "~", "":
+		return nil
+	}
+	
+	if i, err := strconv.Atoi(raw); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f
+	}
+	return raw
+}
+
+// stripComment removes a trailing # comment that is not inside quotes
+func stripComment(line string) string {
+	inSingle, inDouble := false, false
+	for i, c := range line {
+		switch c {
+		case '\'':
+			if !inDouble {
+				inSingle = !inSingle
+			}
+		case '"':
+			if !inSingle {
+				inDouble = !inDouble
+			}
+		case '#':
+			if !inSingle && !inDouble && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// splitInlineList splits the body of an inline [a, b] list on top-level commas
+func splitInlineList(body string) []string {
+	var parts []string
+	depth := 0
+	inSingle, inDouble := false, false
+	start := 0
+	for i, c := range body {
+		switch c {
+		case '\'':
+			if !inDouble {
+				inSingle = !inSingle
+			}
+		case '"':
+			if !inSingle {
+				inDouble = !inDouble
+			}
+		case '[':
+			if !inSingle && !inDouble {
+				depth++
+			}
+		case ']':
+			if !inSingle && !inDouble {
+				depth--
+			}
+		case ',':
+			if depth == 0 && !inSingle && !inDouble {
+				parts = append(parts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(body[start:]) != "" {
+		parts = append(parts, body[start:])
+	}
+	return parts
+}
+
+// parseInlineValue parses a scalar or an inline [a, b] list
+func parseInlineValue(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]") {
+		result := []interface{}{}
+		for _, part := range splitInlineList(raw[1 : len(raw)-1]) {
+			result = append(result, parseInlineValue(part))
+		}
+		return result
+	}
+	return parseScalar(raw)
+}
+
+// formatScalar renders a scalar value, quoting strings when needed
+func formatScalar(val interface{}) string {
+	switch s := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, ":#[]{},\"'\n") {
+			return strconv.Quote(s)
+		}
+		if _, isString := parseScalar(s).(string); !isString {
+			return strconv.Quote(s)
+		}
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", s)
+	}
+}
+
+// yamlLine is a significant line of a YAML document
+type yamlLine struct {
+	indent int
+	text   string
+}
+
+// decodeYAML parses the block-style YAML subset used by config files:
+// nested mappings, sequences, inline [a, b] lists and scalars
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for _, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(stripComment(strings.TrimRight(raw, "\r")), " \t")
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: trimmed})
+	}
+	
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	
+	pos := 0
+	val, err := parseYAMLBlock(lines, &pos, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if pos < len(lines) {
+		return nil, fmt.Errorf("yaml: unexpected indentation at %q", lines[pos].text)
+	}
+	
+	config, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml: top-level value must be a mapping")
+	}
+	return config, nil
+}
+
+// parseYAMLBlock parses a mapping or sequence whose lines share indent
+func parseYAMLBlock(lines []yamlLine, pos *int, indent int) (interface{}, error) {
+	if strings.HasPrefix(lines[*pos].text, "- ") || lines[*pos].text == "-" {
+		return parseYAMLSequence(lines, pos, indent)
+	}
+	return parseYAMLMapping(lines, pos, indent)
+}
+
+// parseYAMLMapping parses "key: value" lines at the given indent
+func parseYAMLMapping(lines []yamlLine, pos *int, indent int) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for *pos < len(lines) && lines[*pos].indent == indent {
+		line := lines[*pos]
+		idx := strings.Index(line.text, ":")
+		if idx <= 0 || (idx+1 < len(line.text) && line.text[idx+1] != ' ') {
+			return nil, fmt.Errorf("yaml: expected key: value, got %q", line.text)
+		}
+		key, _ := parseScalar(line.text[:idx]).(string)
+		if key == "" {
+			key = strings.TrimSpace(line.text[:idx])
+		}
+		rest := strings.TrimSpace(line.text[idx+1:])
+		*pos++
+		
+		if rest != "" {
+			result[key] = parseInlineValue(rest)
+			continue
+		}
+		
+		// A nested block follows on deeper-indented lines; a sequence may
+		// also sit at the same indent as its key
+		if *pos < len(lines) && (lines[*pos].indent > indent ||
+			(lines[*pos].indent == indent && strings.HasPrefix(lines[*pos].text, "-"))) {
+			child, err := parseYAMLBlock(lines, pos, lines[*pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = child
+		} else {
+			result[key] = nil
+		}
+	}
+	return result, nil
+}
+
+// parseYAMLSequence parses "- item" lines at the given indent
+func parseYAMLSequence(lines []yamlLine, pos *int, indent int) ([]interface{}, error) {
+	result := []interface{}{}
+	for *pos < len(lines) && lines[*pos].indent == indent && strings.HasPrefix(lines[*pos].text, "-") {
+		item := strings.TrimSpace(strings.TrimPrefix(lines[*pos].text, "-"))
+		
+		if item == "" {
+			*pos++
+			if *pos < len(lines) && lines[*pos].indent > indent {
+				child, err := parseYAMLBlock(lines, pos, lines[*pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, child)
+			} else {
+				result = append(result, nil)
+			}
+			continue
+		}
+		
+		if idx := strings.Index(item, ": "); idx > 0 || strings.HasSuffix(item, ":") {
+			// "- key: value" starts a mapping; rewrite the line in place so
+			// the mapping parser sees it at the item's indent
+			itemIndent := indent + (len(lines[*pos].text) - len(item))
+			lines[*pos] = yamlLine{indent: itemIndent, text: item}
+			child, err := parseYAMLMapping(lines, pos, itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, child)
+			continue
+		}
+		
+		result = append(result, parseInlineValue(item))
+		*pos++
+	}
+	return result, nil
+}
+
+// encodeYAML serializes a configuration map as block-style YAML
+func encodeYAML(config map[string]interface{}) []byte {
+	var b strings.Builder
+	writeYAMLMap(&b, config, 0)
+	return []byte(b.String())
+}
+
+// writeYAMLMap writes a mapping at the given indent
+func writeYAMLMap(b *strings.Builder, m map[string]interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, k := range sortedKeys(m) {
+		val := m[k]
+		if nested, ok := toStringMap(val); ok {
+			if len(nested) == 0 {
+				fmt.Fprintf(b, "%s%s: {}\n", pad, formatScalar(k))
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, formatScalar(k))
+			writeYAMLMap(b, nested, indent+1)
+			continue
+		}
+		if list, ok := toSlice(val); ok {
+			if len(list) == 0 {
+				fmt.Fprintf(b, "%s%s: []\n", pad, formatScalar(k))
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, formatScalar(k))
+			writeYAMLList(b, list, indent+1)
+			continue
+		}
+		fmt.Fprintf(b, "%s%s: %s\n", pad, formatScalar(k), formatScalar(val))
+	}
+}
+
+// writeYAMLList writes a sequence at the given indent
+func writeYAMLList(b *strings.Builder, list []interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for _, item := range list {
+		if nested, ok := toStringMap(item); ok && len(nested) > 0 {
+			fmt.Fprintf(b, "%s-\n", pad)
+			writeYAMLMap(b, nested, indent+1)
+			continue
+		}
+		if inner, ok := toSlice(item); ok {
+			fmt.Fprintf(b, "%s- %s\n", pad, formatInlineList(inner))
+			continue
+		}
+		fmt.Fprintf(b, "%s- %s\n", pad, formatScalar(item))
+	}
+}
+
+// formatInlineList renders a list in inline [a, b] form
+func formatInlineList(list []interface{}) string {
+	parts := make([]string, len(list))
+	for i, item := range list {
+		if inner, ok := toSlice(item); ok {
+			parts[i] = formatInlineList(inner)
+		} else if s, ok := item.(string); ok {
+			parts[i] = strconv.Quote(s)
+		} else {
+			parts[i] = formatScalar(item)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// decodeTOML parses the TOML subset used by config files:
+// [table] headers, key = value pairs, strings, numbers, booleans and arrays
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	current := result
+	
+	for n, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+		
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = result
+			for _, part := range strings.Split(strings.Trim(line, "[]"), ".") {
+				part = strings.Trim(strings.TrimSpace(part), "\"")
+				next, ok := current[part].(map[string]interface{})
+				if !ok {
+					next = make(map[string]interface{})
+					current[part] = next
+				}
+				current = next
+			}
+			continue
+		}
+		
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("toml: line %d: expected key = value", n+1)
+		}
+		key := strings.Trim(strings.TrimSpace(line[:idx]), "\"")
+		current[key] = parseInlineValue(line[idx+1:])
+	}
+	
+	return result, nil
+}
+
+// encodeTOML serializes a configuration map as TOML
+func encodeTOML(config map[string]interface{}) []byte {
+	var b strings.Builder
+	writeTOMLTable(&b, config, "")
+	return []byte(strings.TrimLeft(b.String(), "\n"))
+}
+
+// writeTOMLTable writes the scalar keys of a table followed by its sub-tables
+func writeTOMLTable(b *strings.Builder, m map[string]interface{}, prefix string) {
+	var tables []string
+	for _, k := range sortedKeys(m) {
+		val := m[k]
+		if _, ok := toStringMap(val); ok {
+			tables = append(tables, k)
+			continue
+		}
+		if val == nil {
+			// TOML has no null; omit unset values
+			continue
+		}
+		if list, ok := toSlice(val); ok {
+			fmt.Fprintf(b, "%s = %s\n", tomlKey(k), formatInlineList(list))
+			continue
+		}
+		if s, ok := val.(string); ok {
+			fmt.Fprintf(b, "%s = %s\n", tomlKey(k), strconv.Quote(s))
+			continue
+		}
+		fmt.Fprintf(b, "%s = %s\n", tomlKey(k), formatScalar(val))
+	}
+	
+	for _, k := range tables {
+		name := tomlKey(k)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fmt.Fprintf(b, "\n[%s]\n", name)
+		nested, _ := toStringMap(m[k])
+		writeTOMLTable(b, nested, name)
+	}
+}
+
+// tomlKey quotes keys that are not valid bare TOML keys
+func tomlKey(k string) string {
+	for _, c := range k {
+		if !(c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			return strconv.Quote(k)
+		}
+	}
+	return k
+}
+
+// decodeEnv parses KEY=value lines; keys are lowercased to match config keys
+func decodeEnv(data []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for n, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("env: line %d: expected KEY=value", n+1)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:idx]))
+		val := strings.TrimSpace(line[idx+1:])
+		if unquoted, ok := parseScalar(val).(string); ok && len(val) >= 2 && (val[0] == '"' || val[0] == '\'') {
+			val = unquoted
+		}
+		result[key] = val
+	}
+	return result, nil
+}
+
+// encodeEnv serializes a configuration map as KEY=value lines
+// Nested maps are flattened with "_" separators, as env files are flat.
+func encodeEnv(config map[string]interface{}) []byte {
+	flat := make(map[string]interface{})
+	flattenForEnv(config, "", flat)
+	
+	var b strings.Builder
+	for _, k := range sortedKeys(flat) {
+		val := flat[k]
+		var s string
+		if list, ok := toSlice(val); ok {
+			parts := make([]string, len(list))
+			for i, item := range list {
+				parts[i] = fmt.Sprintf("%v", item)
+			}
+			s = strings.Join(parts, ",")
+		} else if val != nil {
+			s = fmt.Sprintf("%v", val)
+		}
+		if strings.ContainsAny(s, " #\"'\n") {
+			s = strconv.Quote(s)
+		}
+		fmt.Fprintf(&b, "%s=%s\n", k, s)
+	}
+	return []byte(b.String())
+}
+
+// flattenForEnv flattens nested maps into upper-cased env keys
+func flattenForEnv(m map[string]interface{}, prefix string, out map[string]interface{}) {
+	for k, val := range m {
+		key := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(k))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		if nested, ok := toStringMap(val); ok {
+			flattenForEnv(nested, key, out)
+			continue
+		}
+		out[key] = val
+	}
 }
 
 // SafeWriteConfig writes config if file doesn't exist
 func (v *Viper) SafeWriteConfig() error {
-	if _, err := os.Stat(v.configFile); err == nil {
+	if _, err := v.fs.Stat(v.configFile); err == nil {
 		return fmt.Errorf("config file already exists")
 	}
 	return v.WriteConfig()
@@ -280,11 +1179,18 @@ func (v *Viper) IsSet(key string) bool {
 
 // AllKeys returns all keys in the config
 func (v *Viper) AllKeys() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 	keys := make(map[string]bool)
 	
 	for k := range v.config {
 		keys[k] = true
 	}
+	v.kvMu.RLock()
+	for k := range v.kvstore {
+		keys[k] = true
+	}
+	v.kvMu.RUnlock()
 	for k := range v.defaults {
 		keys[k] = true
 	}
@@ -299,21 +1205,174 @@ func (v *Viper) AllKeys() []string {
 
 // AllSettings returns all settings as a map
 func (v *Viper) AllSettings() map[string]interface{} {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 	result := make(map[string]interface{})
 	
 	// Copy defaults first
 	for k, v := range v.defaults {
-		result[k] = v
+		result[k] = deepCopyValue(v)
 	}
 	
+	// Then remote key/value stores
+	v.kvMu.RLock()
+	for k, val := range v.kvstore {
+		result[k] = deepCopyValue(val)
+	}
+	v.kvMu.RUnlock()
+	
 	// Override with config
 	for k, v := range v.config {
-		result[k] = v
+		result[k] = deepCopyValue(v)
 	}
 	
 	return result
 }
 
+// deepCopyValue copies maps and slices recursively so the result shares no
+// mutable state with the original
+func deepCopyValue(val interface{}) interface{} {
+	switch typed := val.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(typed))
+		for k, item := range typed {
+			result[k] = deepCopyValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(typed))
+		for i, item := range typed {
+			result[i] = deepCopyValue(item)
+		}
+		return result
+	}
+	
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return val
+		}
+		result := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), copyReflectValue(iter.Value(), rv.Type().Elem()))
+		}
+		return result.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return val
+		}
+		result := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			result.Index(i).Set(copyReflectValue(rv.Index(i), rv.Type().Elem()))
+		}
+		return result.Interface()
+	default:
+		return val
+	}
+}
+
+// copyReflectValue deep-copies a reflected element of the given type
+func copyReflectValue(val reflect.Value, elemType reflect.Type) reflect.Value {
+	copied := deepCopyValue(val.Interface())
+	if copied == nil {
+		return reflect.Zero(elemType)
+	}
+	return reflect.ValueOf(copied)
+}
+
+// ConfigSnapshot is an immutable, point-in-time view of a Viper's settings.
+// It is safe to hold while the source configuration is changed or reloaded.
+type ConfigSnapshot struct {
+	v *Viper
+}
+
+// Snapshot captures the current configuration, including values resolved
+// from bound environment variables, in an immutable view. Defaults, config,
+// bindings and remote values are copied under the read lock, so a Set or a
+// reload by WatchConfig is either wholly in the snapshot or not at all.
+func (v *Viper) Snapshot() *ConfigSnapshot {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	
+	frozen := New()
+	frozen.decoder = v.decoder
+	for k, val := range v.defaults {
+		frozen.defaults[k] = deepCopyValue(val)
+	}
+	v.kvMu.RLock()
+	for k, val := range v.kvstore {
+		frozen.kvstore[k] = deepCopyValue(val)
+	}
+	v.kvMu.RUnlock()
+	for k, val := range v.config {
+		frozen.config[k] = deepCopyValue(val)
+	}
+	// Environment variables are resolved now so later changes don't leak in
+	for k, envKey := range v.env {
+		if envVal := os.Getenv(envKey); envVal != "" {
+			frozen.config[k] = envVal
+		}
+	}
+	return &ConfigSnapshot{v: frozen}
+}
+
+// Get retrieves a copy of a configuration value
+func (s *ConfigSnapshot) Get(key string) interface{} {
+	return deepCopyValue(s.v.Get(key))
+}
+
+// GetString gets a string configuration value
+func (s *ConfigSnapshot) GetString(key string) string {
+	return s.v.GetString(key)
+}
+
+// GetInt gets an integer configuration value
+func (s *ConfigSnapshot) GetInt(key string) int {
+	return s.v.GetInt(key)
+}
+
+// GetBool gets a boolean configuration value
+func (s *ConfigSnapshot) GetBool(key string) bool {
+	return s.v.GetBool(key)
+}
+
+// GetFloat64 gets a float64 configuration value
+func (s *ConfigSnapshot) GetFloat64(key string) float64 {
+	return s.v.GetFloat64(key)
+}
+
+// GetStringSlice gets a copy of a string slice configuration value
+func (s *ConfigSnapshot) GetStringSlice(key string) []string {
+	return deepCopyValue(s.v.GetStringSlice(key)).([]string)
+}
+
+// GetStringMap gets a copy of a string map configuration value
+func (s *ConfigSnapshot) GetStringMap(key string) map[string]interface{} {
+	return deepCopyValue(s.v.GetStringMap(key)).(map[string]interface{})
+}
+
+// IsSet checks if a key is set
+func (s *ConfigSnapshot) IsSet(key string) bool {
+	return s.v.IsSet(key)
+}
+
+// AllKeys returns all keys in the snapshot
+func (s *ConfigSnapshot) AllKeys() []string {
+	return s.v.AllKeys()
+}
+
+// AllSettings returns a copy of all settings in the snapshot
+func (s *ConfigSnapshot) AllSettings() map[string]interface{} {
+	return s.v.AllSettings()
+}
+
+// Unmarshal unmarshals the snapshot into a struct
+func (s *ConfigSnapshot) Unmarshal(rawVal interface{}) error {
+	return s.v.Unmarshal(rawVal)
+}
+
 // Sub returns a sub-configuration
 func (v *Viper) Sub(key string) *Viper {
 	val := v.Get(key)
@@ -330,15 +1389,88 @@ func (v *Viper) Sub(key string) *Viper {
 	return nil
 }
 
-// Unmarshal unmarshals config into a struct
-func (v *Viper) Unmarshal(rawVal interface{}) error {
-	// Convert config to JSON and back to unmarshal into struct
-	data, err := json.Marshal(v.AllSettings())
+// StructDecoder fills a struct from settings for Unmarshal and
+// UnmarshalKey. The mapstructure emulator's ConfigDecoder implements it,
+// as can any decoder with this method.
+type StructDecoder interface {
+	Decode(input interface{}, output interface{}) error
+}
+
+// jsonDecoder converts settings to JSON and back, so fields follow `json`
+// tags
+type jsonDecoder struct{}
+
+func (jsonDecoder) Decode(input interface{}, output interface{}) error {
+	data, err := json.Marshal(input)
 	if err != nil {
 		return err
 	}
-	
-	return json.Unmarshal(data, rawVal)
+	return json.Unmarshal(data, output)
+}
+
+// SetDecoder sets the decoder Unmarshal and UnmarshalKey use, such as one
+// that reads `mapstructure` tags and converts strings to durations
+func (v *Viper) SetDecoder(decoder StructDecoder) {
+	v.decoder = decoder
+}
+
+// Unmarshal unmarshals config into a struct
+func (v *Viper) Unmarshal(rawVal interface{}) error {
+	return v.decoder.Decode(v.nestedSettings(), rawVal)
+}
+
+// nestedSettings layers config over defaults with dotted keys expanded,
+// so "server.port" fills a nested Server struct when unmarshaling
+func (v *Viper) nestedSettings() map[string]interface{} {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	result := make(map[string]interface{})
+	mergeNested(result, expandDottedKeys(v.defaults))
+	v.kvMu.RLock()
+	mergeNested(result, expandDottedKeys(v.kvstore))
+	v.kvMu.RUnlock()
+	mergeNested(result, expandDottedKeys(v.config))
+	return result
+}
+
+// expandDottedKeys returns a copy of m with dotted keys nested into maps
+func expandDottedKeys(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, val := range m {
+		if !strings.Contains(k, ".") {
+			result[k] = deepCopyValue(val)
+		}
+	}
+	for _, k := range sortedKeys(m) {
+		if !strings.Contains(k, ".") {
+			continue
+		}
+		path := strings.Split(k, ".")
+		current := result
+		for _, part := range path[:len(path)-1] {
+			next, ok := current[part].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[part] = next
+			}
+			current = next
+		}
+		current[path[len(path)-1]] = deepCopyValue(m[k])
+	}
+	return result
+}
+
+// mergeNested merges src into dst, recursing into maps present in both
+func mergeNested(dst, src map[string]interface{}) {
+	for k, val := range src {
+		if srcMap, ok := val.(map[string]interface{}); ok {
+			if dstMap, ok := dst[k].(map[string]interface{}); ok {
+				mergeNested(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = val
+	}
 }
 
 // UnmarshalKey unmarshals a specific key into a struct
@@ -348,21 +1480,350 @@ func (v *Viper) UnmarshalKey(key string, rawVal interface{}) error {
 		return fmt.Errorf("key not found: %s", key)
 	}
 	
-	data, err := json.Marshal(val)
-	if err != nil {
-		return err
-	}
-	
-	return json.Unmarshal(data, rawVal)
+	return v.decoder.Decode(val, rawVal)
 }
 
 // Reset clears all configuration
 func (v *Viper) Reset() {
+	v.mu.Lock()
 	v.config = make(map[string]interface{})
 	v.defaults = make(map[string]interface{})
 	v.env = make(map[string]string)
+	v.onConfigChange = nil
+	v.expandEnv = false
+	v.mu.Unlock()
 	v.configFile = ""
 	v.configType = ""
+	v.fs = osFs{}
+	v.decoder = jsonDecoder{}
+	
+	v.kvMu.Lock()
+	if v.remoteStop != nil {
+		close(v.remoteStop)
+		v.remoteStop = nil
+	}
+	v.kvstore = make(map[string]interface{})
+	v.remoteProviders = nil
+	v.kvMu.Unlock()
+	
+	if v.watcher != nil {
+		v.watcher.Close()
+		v.watcher = nil
+	}
+}
+
+// Remote providers
+
+// SupportedRemoteProviders lists the remote providers AddRemoteProvider accepts
+var SupportedRemoteProviders = []string{"etcd", "etcd3", "consul", "firestore", "nats"}
+
+// UnsupportedRemoteProviderError is returned for an unknown remote provider
+type UnsupportedRemoteProviderError string
+
+func (e UnsupportedRemoteProviderError) Error() string {
+	return fmt.Sprintf("Unsupported Remote Provider Type %q", string(e))
+}
+
+// RemoteConfigError is returned when no remote provider yields configuration
+type RemoteConfigError string
+
+func (e RemoteConfigError) Error() string {
+	return fmt.Sprintf("Remote Configurations Error: %s", string(e))
+}
+
+// RemoteProvider describes a remote configuration source
+type RemoteProvider interface {
+	Provider() string
+	Endpoint() string
+	Path() string
+	SecretKeyring() string
+}
+
+type defaultRemoteProvider struct {
+	provider      string
+	endpoint      string
+	path          string
+	secretKeyring string
+}
+
+func (rp defaultRemoteProvider) Provider() string      { return rp.provider }
+func (rp defaultRemoteProvider) Endpoint() string      { return rp.endpoint }
+func (rp defaultRemoteProvider) Path() string          { return rp.path }
+func (rp defaultRemoteProvider) SecretKeyring() string { return rp.secretKeyring }
+
+// RemoteStore fetches and watches the configuration documents of a remote
+// provider. The etcd and Consul emulators' ViperStores implement it, as can
+// any store with these methods.
+type RemoteStore interface {
+	// Get returns the document stored at path
+	Get(path string) ([]byte, error)
+	// Watch sends each new version of the document at path until stop is
+	// closed
+	Watch(path string, stop <-chan struct{}) <-chan []byte
+}
+
+var (
+	remoteStoresMu sync.Mutex
+	remoteStores   = make(map[string]RemoteStore)
+)
+
+// RegisterRemoteStore makes store serve the given provider at endpoint,
+// standing in for the network client viper's remote package would use
+func RegisterRemoteStore(provider, endpoint string, store RemoteStore) {
+	remoteStoresMu.Lock()
+	defer remoteStoresMu.Unlock()
+	remoteStores[provider+"|"+endpoint] = store
+}
+
+func lookupRemoteStore(rp RemoteProvider) RemoteStore {
+	remoteStoresMu.Lock()
+	defer remoteStoresMu.Unlock()
+	return remoteStores[rp.Provider()+"|"+rp.Endpoint()]
+}
+
+// AddRemoteProvider adds a remote configuration source, where path is the
+// key of a configuration document such as "/config/app.json". Sources are
+// tried in the order they were added.
+func (v *Viper) AddRemoteProvider(provider, endpoint, path string) error {
+	return v.AddSecureRemoteProvider(provider, endpoint, path, "")
+}
+
+// AddSecureRemoteProvider is AddRemoteProvider for documents encrypted with
+// the keys in secretKeyring. The emulator records the keyring but reads
+// documents as they are stored.
+func (v *Viper) AddSecureRemoteProvider(provider, endpoint, path, secretKeyring string) error {
+	supported := false
+	for _, p := range SupportedRemoteProviders {
+		if p == provider {
+			supported = true
+		}
+	}
+	if !supported {
+		return UnsupportedRemoteProviderError(provider)
+	}
+	if endpoint == "" {
+		return nil
+	}
+	rp := &defaultRemoteProvider{provider: provider, endpoint: endpoint, path: path, secretKeyring: secretKeyring}
+	v.kvMu.Lock()
+	defer v.kvMu.Unlock()
+	for _, existing := range v.remoteProviders {
+		if existing.path == path {
+			return nil
+		}
+	}
+	v.remoteProviders = append(v.remoteProviders, rp)
+	return nil
+}
+
+// remoteConfigType returns the format of a remote document: the config
+// type if set, otherwise the extension of its path, otherwise JSON
+func (v *Viper) remoteConfigType(rp RemoteProvider) string {
+	if v.configType != "" {
+		return v.configType
+	}
+	return configTypeFromPath(rp.Path())
+}
+
+// decodeRemote parses a remote document, expanding environment references
+// if enabled
+func (v *Viper) decodeRemote(rp RemoteProvider, data []byte) (map[string]interface{}, error) {
+	config, err := decodeConfig(data, v.remoteConfigType(rp))
+	if err != nil {
+		return nil, err
+	}
+	v.mu.RLock()
+	expand := v.expandEnv
+	v.mu.RUnlock()
+	if expand {
+		for k, val := range config {
+			config[k] = expandEnvValue(val)
+		}
+	}
+	return config, nil
+}
+
+// ReadRemoteConfig reads the configuration of the first remote provider
+// whose document can be fetched and parsed. It ranks below config files and
+// Set, and above defaults.
+func (v *Viper) ReadRemoteConfig() error {
+	v.kvMu.RLock()
+	providers := v.remoteProviders
+	v.kvMu.RUnlock()
+	
+	for _, rp := range providers {
+		store := lookupRemoteStore(rp)
+		if store == nil {
+			continue
+		}
+		data, err := store.Get(rp.Path())
+		if err != nil {
+			continue
+		}
+		config, err := v.decodeRemote(rp, data)
+		if err != nil {
+			continue
+		}
+		v.kvMu.Lock()
+		v.kvstore = config
+		v.kvMu.Unlock()
+		return nil
+	}
+	return RemoteConfigError("No Files Found")
+}
+
+// WatchRemoteConfig re-reads the remote configuration, for callers that
+// poll
+func (v *Viper) WatchRemoteConfig() error {
+	return v.ReadRemoteConfig()
+}
+
+// WatchRemoteConfigOnChannel keeps the remote configuration of the first
+// remote provider up to date in the background until Reset is called
+func (v *Viper) WatchRemoteConfigOnChannel() error {
+	v.kvMu.Lock()
+	defer v.kvMu.Unlock()
+	
+	for _, rp := range v.remoteProviders {
+		store := lookupRemoteStore(rp)
+		if store == nil {
+			continue
+		}
+		if v.remoteStop == nil {
+			v.remoteStop = make(chan struct{})
+		}
+		stop := v.remoteStop
+		updates := store.Watch(rp.Path(), stop)
+		go func(rp RemoteProvider) {
+			for data := range updates {
+				config, err := v.decodeRemote(rp, data)
+				if err != nil {
+					continue
+				}
+				v.kvMu.Lock()
+				select {
+				case <-stop:
+				default:
+					v.kvstore = config
+				}
+				v.kvMu.Unlock()
+			}
+		}(rp)
+		return nil
+	}
+	return RemoteConfigError("No Files Found")
+}
+
+// Op is a set of file operations, with the bits of fsnotify.Op
+type Op uint32
+
+// The operations a config file Event can report
+const (
+	Create Op = 1 << iota
+	Write
+	Remove
+	Rename
+	Chmod
+)
+
+// Event is a change to the config file, standing in for fsnotify.Event
+type Event struct {
+	Name string
+	Op   Op
+}
+
+// Has reports whether the event includes op
+func (e Event) Has(op Op) bool {
+	return e.Op&op != 0
+}
+
+// FileWatcher reports changes to files for WatchConfig. The fsnotify
+// emulator's Watcher implements it, as can any watcher with these methods.
+type FileWatcher interface {
+	// Add starts watching the named file or directory
+	Add(name string) error
+	// Close stops watching
+	Close() error
+	// Notify calls events with the name and Op bits of each change, and
+	// errors with each error
+	Notify(events func(name string, op uint32), errors func(err error))
+}
+
+var (
+	fileWatcherMu  sync.Mutex
+	newFileWatcher func() (FileWatcher, error)
+)
+
+// RegisterFileWatcher makes WatchConfig watch with watchers from
+// newWatcher, standing in for the fsnotify package viper imports
+func RegisterFileWatcher(newWatcher func() (FileWatcher, error)) {
+	fileWatcherMu.Lock()
+	newFileWatcher = newWatcher
+	fileWatcherMu.Unlock()
+}
+
+// OnConfigChange sets a function to call after WatchConfig re-reads the
+// config file
+func (v *Viper) OnConfigChange(run func(in Event)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onConfigChange = run
+}
+
+// WatchConfig re-reads the config file whenever it is written or created
+// again, until it is removed or Reset is called. Like viper, it watches the
+// file's directory, so editors that replace the file are followed, and
+// reports problems on stderr instead of returning them.
+func (v *Viper) WatchConfig() {
+	fileWatcherMu.Lock()
+	newWatcher := newFileWatcher
+	fileWatcherMu.Unlock()
+	if newWatcher == nil {
+		fmt.Fprintln(os.Stderr, "viper: no file watcher registered")
+		return
+	}
+	if v.configFile == "" {
+		fmt.Fprintln(os.Stderr, "viper: config file not set")
+		return
+	}
+	
+	watcher, err := newWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "viper: failed to create watcher: %s\n", err)
+		return
+	}
+	configFile := filepath.Clean(v.configFile)
+	watcher.Notify(func(name string, op uint32) {
+		event := Event{Name: name, Op: Op(op)}
+		if filepath.Clean(name) != configFile {
+			return
+		}
+		if event.Has(Write) || event.Has(Create) {
+			if err := v.ReadInConfig(); err != nil {
+				fmt.Fprintf(os.Stderr, "viper: read config file: %s\n", err)
+			}
+			v.mu.RLock()
+			onConfigChange := v.onConfigChange
+			v.mu.RUnlock()
+			if onConfigChange != nil {
+				onConfigChange(event)
+			}
+		} else if event.Has(Remove) {
+			// Close waits for this function to return
+			go watcher.Close()
+		}
+	}, func(err error) {
+		fmt.Fprintf(os.Stderr, "viper: watcher error: %s\n", err)
+	})
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		fmt.Fprintf(os.Stderr, "viper: failed to add watcher: %s\n", err)
+		watcher.Close()
+		return
+	}
+	if v.watcher != nil {
+		v.watcher.Close()
+	}
+	v.watcher = watcher
 }
 
 // GetViper returns the global viper instance
@@ -408,6 +1869,10 @@ func SetDefault(key string, value interface{}) {
 	globalViper.SetDefault(key, value)
 }
 
+func SetDefaultsFromStruct(s interface{}) error {
+	return globalViper.SetDefaultsFromStruct(s)
+}
+
 func BindEnv(key string, envVars ...string) error {
 	return globalViper.BindEnv(key, envVars...)
 }
@@ -420,10 +1885,22 @@ func AutomaticEnv() {
 	globalViper.AutomaticEnv()
 }
 
+func AllowEnvExpansion(allow bool) {
+	globalViper.AllowEnvExpansion(allow)
+}
+
 func SetConfigFile(in string) {
 	globalViper.SetConfigFile(in)
 }
 
+func SetFs(fs Fs) {
+	globalViper.SetFs(fs)
+}
+
+func SetDecoder(decoder StructDecoder) {
+	globalViper.SetDecoder(decoder)
+}
+
 func SetConfigName(in string) {
 	globalViper.SetConfigName(in)
 }
@@ -464,6 +1941,10 @@ func AllSettings() map[string]interface{} {
 	return globalViper.AllSettings()
 }
 
+func Snapshot() *ConfigSnapshot {
+	return globalViper.Snapshot()
+}
+
 func Sub(key string) *Viper {
 	return globalViper.Sub(key)
 }
@@ -479,3 +1960,31 @@ func UnmarshalKey(key string, rawVal interface{}) error {
 func Reset() {
 	globalViper.Reset()
 }
+
+func AddRemoteProvider(provider, endpoint, path string) error {
+	return globalViper.AddRemoteProvider(provider, endpoint, path)
+}
+
+func AddSecureRemoteProvider(provider, endpoint, path, secretKeyring string) error {
+	return globalViper.AddSecureRemoteProvider(provider, endpoint, path, secretKeyring)
+}
+
+func ReadRemoteConfig() error {
+	return globalViper.ReadRemoteConfig()
+}
+
+func WatchRemoteConfig() error {
+	return globalViper.WatchRemoteConfig()
+}
+
+func WatchRemoteConfigOnChannel() error {
+	return globalViper.WatchRemoteConfigOnChannel()
+}
+
+func OnConfigChange(run func(in Event)) {
+	globalViper.OnConfigChange(run)
+}
+
+func WatchConfig() {
+	globalViper.WatchConfig()
+}
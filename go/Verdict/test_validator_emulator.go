@@ -0,0 +1,629 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-playground/validator
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+// failures maps the namespace of each failed field to its tag
+func failures(err error) map[string]string {
+	failed := make(map[string]string)
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		for _, fe := range errs {
+			failed[fe.Namespace()] = fe.Tag()
+		}
+	}
+	return failed
+}
+
+// sameFailures reports whether err failed exactly the expected fields
+func sameFailures(err error, expected map[string]string) bool {
+	return reflect.DeepEqual(failures(err), expected)
+}
+
+// panics reports whether fn panics with a message containing want
+func panics(want string, fn func()) (ok bool) {
+	defer func() {
+		r := recover()
+		ok = r != nil && strings.Contains(fmt.Sprint(r), want)
+	}()
+	fn()
+	return false
+}
+
+// Test required, omitempty and nil pointers
+func testRequiredAndOmitempty() bool {
+	type Profile struct {
+		Name     string            `validate:"required"`
+		Nickname string            `validate:"omitempty,min=3"`
+		Age      *int              `validate:"required"`
+		Bio      *string           `validate:"omitempty,max=5"`
+		Website  *string           `validate:"min=1"`
+		Tags     []string          `validate:"required"`
+		Meta     map[string]string `validate:"omitnil,min=1"`
+	}
+	v := New()
+
+	err := v.Struct(Profile{Nickname: "al"})
+	if !sameFailures(err, map[string]string{
+		"Profile.Name":     "required",
+		"Profile.Nickname": "min",
+		"Profile.Age":      "required",
+		"Profile.Website":  "min",
+		"Profile.Tags":     "required",
+	}) {
+		return false
+	}
+
+	// A non-nil pointer is present even if it points to a zero value, and
+	// an empty but non-nil slice is present too
+	zero, empty, site := 0, "", "x"
+	if err := v.Struct(&Profile{Name: "Ann", Age: &zero, Bio: &empty, Website: &site, Tags: []string{}}); err != nil {
+		return false
+	}
+	long := "far too long"
+	err = v.Struct(&Profile{Name: "Ann", Age: &zero, Bio: &long, Website: &site, Tags: []string{}, Meta: map[string]string{}})
+	return sameFailures(err, map[string]string{"Profile.Bio": "max", "Profile.Meta": "min"})
+}
+
+// Test len, min, max, gt, gte, lt, lte, eq and ne on each kind
+func testSizeRules() bool {
+	type Limits struct {
+		Code    string        `validate:"len=3"`
+		Name    string        `validate:"min=2,max=5"`
+		Age     int           `validate:"gte=18,lte=130"`
+		Score   float64       `validate:"gt=0,lt=1"`
+		Count   uint          `validate:"ne=0"`
+		Items   []int         `validate:"min=1,max=3"`
+		Timeout time.Duration `validate:"gte=1s,lte=1m"`
+		Expires time.Time     `validate:"gt"`
+		Status  string        `validate:"eq=active"`
+		Enabled bool          `validate:"eq=true"`
+	}
+	v := New()
+	valid := Limits{Code: "ÅÆØ", Name: "Zoë", Age: 18, Score: 0.5, Count: 1, Items: []int{1},
+		Timeout: 30 * time.Second, Expires: time.Now().Add(time.Hour), Status: "active", Enabled: true}
+	if err := v.Struct(valid); err != nil {
+		return false
+	}
+	invalid := Limits{Code: "ABCD", Name: "Z", Age: 131, Score: 1, Items: []int{1, 2, 3, 4},
+		Timeout: time.Hour, Expires: time.Now().Add(-time.Hour), Status: "archived"}
+	if !sameFailures(v.Struct(invalid), map[string]string{
+		"Limits.Code": "len", "Limits.Name": "min", "Limits.Age": "lte", "Limits.Score": "lt",
+		"Limits.Count": "ne", "Limits.Items": "max", "Limits.Timeout": "lte", "Limits.Expires": "gt",
+		"Limits.Status": "eq", "Limits.Enabled": "eq",
+	}) {
+		return false
+	}
+	return v.Var(-1, "gte=0") != nil && v.Var(uint8(7), "max=7") == nil && v.Var(map[string]int{"a": 1}, "len=1") == nil
+}
+
+// Test string format rules, oneof and or-groups
+func testFormatRules() bool {
+	v := New()
+	cases := []struct {
+		value interface{}
+		tag   string
+		valid bool
+	}{
+		{"ann@example.com", "email", true},
+		{"ann@", "email", false},
+		{"https://example.com/a?b=c", "url", true},
+		{"example.com", "url", false},
+		{"/users/42", "uri", true},
+		{"7c9e6679-7425-40de-944b-e07fc1f90ae7", "uuid4", true},
+		{"7c9e6679-7425-10de-944b-e07fc1f90ae7", "uuid4", false},
+		{"7c9e6679-7425-10de-944b-e07fc1f90ae7", "uuid", true},
+		{"192.168.1.1", "ipv4", true},
+		{"::1", "ipv4", false},
+		{"::1", "ipv6", true},
+		{"10.0.0.0/8", "cidr", true},
+		{"#ff00aa", "hexcolor", true},
+		{"#ff00aa", "iscolor", true},
+		{"abc", "alpha", true},
+		{"abc1", "alpha", false},
+		{"abc1", "alphanum", true},
+		{"-12.5", "numeric", true},
+		{"12a", "numeric", false},
+		{"0042", "number", true},
+		{"0xBEEF", "hexadecimal", true},
+		{"api.example.com", "hostname", true},
+		{"+4712345678", "e164", true},
+		{"shout", "lowercase", true},
+		{"SHOUT", "uppercase", true},
+		{"hello world", "contains=o w", true},
+		{"hello", "containsany=xyz", false},
+		{"hello", "excludes=ell", false},
+		{"img_1.png", "startswith=img_,endswith=.png", true},
+		{"2024-02-30", "datetime=2006-01-02", false},
+		{"2024-02-28", "datetime=2006-01-02", true},
+		{"yes", "boolean", false},
+		{"true", "boolean", true},
+		{`{"a": [1, 2]}`, "json", true},
+		{`{"a": }`, "json", false},
+		{"blue", "oneof=red green blue", true},
+		{"dark red", "oneof='dark red' 'light red'", true},
+		{"red", "oneof='dark red' 'light red'", false},
+		{3, "oneof=1 3 5", true},
+		{"a,b", "contains=0x2C", true},
+		{"#fff", "uuid|hexcolor", true},
+		{"nope", "uuid|hexcolor", false},
+		{[]int{1, 2, 3}, "unique", true},
+		{[]string{"a", "b", "a"}, "unique", false},
+		{map[string]int{"a": 1, "b": 1}, "unique", false},
+	}
+	for _, c := range cases {
+		if err := v.Var(c.value, c.tag); (err == nil) != c.valid {
+			return false
+		}
+	}
+	err := v.Var("nope", "uuid|hexcolor")
+	fe := err.(ValidationErrors)[0]
+	return fe.Tag() == "uuid|hexcolor" && fe.ActualTag() == "uuid|hexcolor"
+}
+
+// Test nested, pointer and embedded structs
+func testNestedStructs() bool {
+	type Address struct {
+		City string `validate:"required"`
+		Zip  string `validate:"len=4,number"`
+	}
+	type Audit struct {
+		CreatedBy string `validate:"required"`
+	}
+	type Customer struct {
+		Audit
+		Name     string `validate:"required"`
+		Home     Address
+		Work     *Address
+		Billing  *Address `validate:"required"`
+		internal Address
+	}
+	v := New()
+
+	err := v.Struct(Customer{Home: Address{Zip: "12"}, Work: &Address{City: "Oslo", Zip: "0150"}})
+	if !sameFailures(err, map[string]string{
+		"Customer.Audit.CreatedBy": "required",
+		"Customer.Name":            "required",
+		"Customer.Home.City":       "required",
+		"Customer.Home.Zip":        "len",
+		"Customer.Billing":         "required",
+	}) {
+		return false
+	}
+
+	err = v.Struct(&Customer{Audit: Audit{"sys"}, Name: "Ann", Home: Address{"Oslo", "0150"}, Billing: &Address{Zip: "01x0"}})
+	if !sameFailures(err, map[string]string{"Customer.Billing.City": "required", "Customer.Billing.Zip": "number"}) {
+		return false
+	}
+
+	var nilCustomer *Customer
+	var invalid *InvalidValidationError
+	if !errors.As(v.Struct(nilCustomer), &invalid) || v.Struct(42).Error() != "validator: (nil int)" {
+		return false
+	}
+	return v.Struct(nil).Error() == "validator: (nil)"
+}
+
+// Test dive into slices, maps and nested collections
+func testDive() bool {
+	type Item struct {
+		SKU string `validate:"required,alphanum"`
+		Qty int    `validate:"min=1"`
+	}
+	type Order struct {
+		Items    []Item            `validate:"required,min=1,dive"`
+		Pointers []*Item           `validate:"dive,required"`
+		Emails   []string          `validate:"max=3,dive,email"`
+		Matrix   [][]int           `validate:"dive,min=1,dive,gte=0"`
+		Labels   map[string]string `validate:"dive,keys,lowercase,endkeys,required"`
+		Skipped  []Item
+	}
+	v := New()
+
+	order := Order{
+		Items:    []Item{{SKU: "A1", Qty: 1}, {SKU: "b-2", Qty: 0}},
+		Pointers: []*Item{{SKU: "C3", Qty: 2}, nil},
+		Emails:   []string{"ann@example.com", "bob"},
+		Matrix:   [][]int{{1, 2}, {}, {3, -1}},
+		Labels:   map[string]string{"env": "prod", "Team": "core", "tier": ""},
+		Skipped:  []Item{{}},
+	}
+	err := v.Struct(order)
+	if !sameFailures(err, map[string]string{
+		"Order.Items[1].SKU": "alphanum",
+		"Order.Items[1].Qty": "min",
+		"Order.Pointers[1]":  "required",
+		"Order.Emails[1]":    "email",
+		"Order.Matrix[1]":    "min",
+		"Order.Matrix[2][1]": "gte",
+		"Order.Labels[Team]": "lowercase",
+		"Order.Labels[tier]": "required",
+	}) {
+		return false
+	}
+	for _, fe := range err.(ValidationErrors) {
+		if fe.Namespace() == "Order.Items[1].Qty" && (fe.Field() != "Qty" || fe.Value() != 0) {
+			return false
+		}
+		if fe.Namespace() == "Order.Emails[1]" && fe.Field() != "Emails[1]" {
+			return false
+		}
+	}
+
+	if !sameFailures(v.Struct(Order{Pointers: []*Item{}}), map[string]string{"Order.Items": "required"}) {
+		return false
+	}
+	return v.Var([]string{"a", ""}, "dive,required") != nil
+}
+
+// Test cross-field and conditional rules
+func testCrossField() bool {
+	type Signup struct {
+		Password string    `validate:"required,min=8"`
+		Confirm  string    `validate:"eqfield=Password"`
+		Username string    `validate:"nefield=Password"`
+		Start    time.Time `validate:"required"`
+		End      time.Time `validate:"gtfield=Start"`
+		Min      int
+		Max      int    `validate:"gtefield=Min"`
+		Contact  string `validate:"required,oneof=email phone"`
+		Email    string `validate:"required_if=Contact email,omitempty,email"`
+		Phone    string `validate:"required_if=Contact phone"`
+		Street   string
+		City     string `validate:"required_with=Street"`
+		Company  string
+		Personal string `validate:"required_without=Company"`
+	}
+	v := New()
+	now := time.Now()
+	valid := Signup{Password: "s3cret-pw", Confirm: "s3cret-pw", Username: "ann", Start: now, End: now.Add(time.Hour),
+		Min: 1, Max: 1, Contact: "email", Email: "ann@example.com", Company: "Acme"}
+	if err := v.Struct(valid); err != nil {
+		return false
+	}
+
+	invalid := Signup{Password: "s3cret-pw", Confirm: "s3cret-px", Username: "s3cret-pw", Start: now, End: now,
+		Min: 2, Max: 1, Contact: "phone", Street: "Main St"}
+	if !sameFailures(v.Struct(invalid), map[string]string{
+		"Signup.Confirm":  "eqfield",
+		"Signup.Username": "nefield",
+		"Signup.End":      "gtfield",
+		"Signup.Max":      "gtefield",
+		"Signup.Phone":    "required_if",
+		"Signup.City":     "required_with",
+		"Signup.Personal": "required_without",
+	}) {
+		return false
+	}
+
+	if v.VarWithValue("pw-1", "pw-1", "eqfield") != nil || v.VarWithValue("pw-1", "pw-2", "eqfield") == nil {
+		return false
+	}
+	return v.VarWithValue(10, 5, "gtfield") == nil && v.VarWithValue(3, 5, "gtfield") != nil
+}
+
+type sku string
+
+type Money struct {
+	Cents    int64
+	Currency string
+}
+
+type Payment struct {
+	Amount  Money          `validate:"required"`
+	Refund  Money          `validate:"-"`
+	Code    sku            `validate:"sku"`
+	Coupon  *string        `validate:"notblank"`
+	User    string         `validate:"username"`
+	Account sql.NullString `validate:"required,min=4"`
+}
+
+// Test custom rules, aliases, struct-level rules and custom types
+func testCustomValidators() bool {
+	v := New()
+	err := v.RegisterValidation("sku", func(fl FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "SKU-") && fl.GetTag() == "sku"
+	})
+	if err != nil {
+		return false
+	}
+	v.RegisterValidation("notblank", func(fl FieldLevel) bool {
+		if fl.Field().Kind() == reflect.Ptr {
+			return false // nil
+		}
+		return strings.TrimSpace(fl.Field().String()) != ""
+	}, true)
+	v.RegisterAlias("username", "min=3,max=12,alphanum")
+	v.RegisterStructValidation(func(sl StructLevel) {
+		m := sl.Current().Interface().(Money)
+		if m.Cents < 0 {
+			sl.ReportError(m.Cents, "Cents", "Cents", "positive", "")
+		}
+		if len(m.Currency) != 3 {
+			sl.ReportError(m.Currency, "Currency", "Currency", "iso4217", "")
+		}
+	}, Money{})
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if value, ok := field.Interface().(driver.Valuer); ok {
+			if val, err := value.Value(); err == nil {
+				return val
+			}
+		}
+		return nil
+	}, sql.NullString{})
+
+	blank := "  "
+	err = v.Struct(Payment{Amount: Money{Cents: -5, Currency: "EURO"}, Refund: Money{Cents: -1}, Code: "ABC", Coupon: &blank,
+		User: "a!", Account: sql.NullString{String: "12", Valid: true}})
+	if !sameFailures(err, map[string]string{
+		"Payment.Amount.Cents":    "positive",
+		"Payment.Amount.Currency": "iso4217",
+		"Payment.Code":            "sku",
+		"Payment.Coupon":          "notblank",
+		"Payment.User":            "username",
+		"Payment.Account":         "min",
+	}) {
+		return false
+	}
+	for _, fe := range err.(ValidationErrors) {
+		if fe.Tag() == "username" && fe.ActualTag() != "min" {
+			return false
+		}
+	}
+
+	code := "SAVE10"
+	err = v.Struct(Payment{Amount: Money{Cents: 100, Currency: "EUR"}, Code: "SKU-1", Coupon: &code, User: "ann",
+		Account: sql.NullString{}})
+	if !sameFailures(err, map[string]string{"Payment.Account": "required"}) {
+		return false
+	}
+
+	if v.RegisterValidation("dive", func(FieldLevel) bool { return true }) == nil || v.RegisterValidation("a|b", func(FieldLevel) bool { return true }) == nil {
+		return false
+	}
+	return v.RegisterValidation("", nil) != nil
+}
+
+// Test the FieldError API and error names from json tags
+func testFieldErrors() bool {
+	type Contact struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	type User struct {
+		Name     string    `json:"name" validate:"min=3"`
+		Contacts []Contact `json:"contacts" validate:"dive"`
+		Secret   string    `json:"-" validate:"required"`
+	}
+	v := New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return "-"
+		}
+		return name
+	})
+
+	err := v.Struct(User{Name: "Al", Contacts: []Contact{{Email: "nope"}}})
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 2 {
+		return false
+	}
+	name, email := errs[0], errs[1]
+	if name.Namespace() != "User.name" || name.StructNamespace() != "User.Name" || name.Field() != "name" || name.StructField() != "Name" {
+		return false
+	}
+	if name.Tag() != "min" || name.Param() != "3" || name.Value() != "Al" || name.Kind() != reflect.String || name.Type() != reflect.TypeOf("") {
+		return false
+	}
+	if email.Namespace() != "User.contacts[0].email" || email.StructNamespace() != "User.Contacts[0].Email" {
+		return false
+	}
+	if name.Error() != "Key: 'User.name' Error:Field validation for 'name' failed on the 'min' tag" {
+		return false
+	}
+	if !strings.Contains(err.Error(), "\n") {
+		return false
+	}
+
+	err = v.Var("not-an-email", "required,email")
+	fe := err.(ValidationErrors)[0]
+	return fe.Namespace() == "" && fe.Tag() == "email" && v.Var("", "") == nil && v.Var(nil, "required") != nil
+}
+
+// Test English messages, custom locales and fallbacks
+func testTranslations() bool {
+	type Account struct {
+		Name   string   `validate:"required"`
+		Code   string   `validate:"min=1"`
+		Handle string   `validate:"max=4"`
+		Age    int      `validate:"gte=18"`
+		Roles  []string `validate:"min=2"`
+		Plan   string   `validate:"oneof=free pro"`
+		Email  string   `validate:"omitempty,email"`
+		Ends   time.Time
+	}
+	v := New()
+	uni := NewUniversalTranslator("en", "nb")
+	en, found := uni.GetTranslator("en")
+	if !found || en.Locale() != "en" {
+		return false
+	}
+	if err := RegisterDefaultTranslations(v, en); err != nil {
+		return false
+	}
+
+	err := v.Struct(Account{Handle: "toolong", Age: 16, Roles: []string{"a"}, Plan: "gold", Email: "x"})
+	messages := err.(ValidationErrors).Translate(en)
+	expected := ValidationErrorsTranslations{
+		"Account.Name":   "Name is a required field",
+		"Account.Code":   "Code must be at least 1 character in length",
+		"Account.Handle": "Handle must be a maximum of 4 characters in length",
+		"Account.Age":    "Age must be 18 or greater",
+		"Account.Roles":  "Roles must contain at least 2 items",
+		"Account.Plan":   "Plan must be one of [free pro]",
+		"Account.Email":  "Email must be a valid email address",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		return false
+	}
+
+	nb, _ := uni.FindTranslator("de", "nb")
+	if nb.Locale() != "nb" {
+		return false
+	}
+	err = v.RegisterTranslation("required", nb, func(ut Translator) error {
+		return ut.Add("required", "{0} må fylles ut", true)
+	}, func(ut Translator, fe FieldError) string {
+		text, _ := ut.T("required", fe.Field())
+		return text
+	})
+	if err != nil {
+		return false
+	}
+	fe := v.Struct(Account{Code: "x", Plan: "pro", Roles: []string{"a", "b"}, Age: 20}).(ValidationErrors)[0]
+	if fe.Translate(nb) != "Name må fylles ut" {
+		return false
+	}
+	// No Norwegian text for min, so the error message is used
+	minErr := v.Var("", "min=1").(ValidationErrors)[0]
+	if minErr.Translate(nb) != minErr.Error() {
+		return false
+	}
+
+	if _, err := en.T("no-such-key"); err != ErrUnknownTranslation {
+		return false
+	}
+	var conflict *ErrConflictingTranslation
+	if !errors.As(en.Add("required", "again", false), &conflict) {
+		return false
+	}
+	fallback, found := uni.GetTranslator("fr")
+	return !found && fallback == uni.GetFallback()
+}
+
+// Test that misconfigured tags panic
+func testTagPanics() bool {
+	type Unknown struct {
+		Name string `validate:"required,bogus"`
+	}
+	type DiveOnString struct {
+		Name string `validate:"dive,required"`
+	}
+	type OpenKeys struct {
+		Labels map[string]string `validate:"dive,keys,required"`
+	}
+	v := New()
+	return panics("Undefined validation function 'bogus' on field 'Name'", func() { v.Struct(Unknown{}) }) &&
+		panics("can't dive on a non slice or map", func() { v.Struct(DiveOnString{Name: "x"}) }) &&
+		panics("'endkeys'", func() { v.Struct(OpenKeys{}) }) &&
+		panics("bad parameter", func() { v.Var("abc", "min=three") })
+}
+
+// Test the adapter for the Gin emulator's binding layer
+func testGinBindingValidator() bool {
+	type CreateUser struct {
+		Name  string   `json:"name" binding:"required,min=2" validate:"max=1"`
+		Email string   `json:"email" binding:"required,email"`
+		Tags  []string `json:"tags" binding:"dive,required"`
+		Team  string   `json:"team" binding:"team"`
+	}
+	binding := &BindingValidator{}
+	engine, ok := binding.Engine().(*Validate)
+	if !ok || engine != binding.Engine() {
+		return false
+	}
+	engine.RegisterValidation("team", func(fl FieldLevel) bool { return fl.Field().String() != "root" })
+
+	if err := binding.ValidateStruct(&CreateUser{Name: "Ann", Email: "ann@example.com", Tags: []string{"a"}}); err != nil {
+		return false
+	}
+	err := binding.ValidateStruct(&CreateUser{Name: "A", Tags: []string{""}, Team: "root"})
+	if !sameFailures(err, map[string]string{
+		"CreateUser.Name":    "min",
+		"CreateUser.Email":   "required",
+		"CreateUser.Tags[0]": "required",
+		"CreateUser.Team":    "team",
+	}) {
+		return false
+	}
+
+	batch := []CreateUser{{Name: "Ann", Email: "ann@example.com"}, {Name: "B"}}
+	sliceErr, ok := binding.ValidateStruct(batch).(SliceValidationError)
+	if !ok || sliceErr[0] != nil || sliceErr[1] == nil || !strings.HasPrefix(sliceErr.Error(), "[1]: Key: 'CreateUser.Name'") {
+		return false
+	}
+	var nilUser *CreateUser
+	return binding.ValidateStruct(nilUser) == nil && binding.ValidateStruct("text") == nil && binding.ValidateStruct(nil) == nil
+}
+
+// Test validating from many goroutines with one Validate
+func testConcurrentValidation() bool {
+	type Event struct {
+		ID    string   `validate:"required,uuid4"`
+		Kind  string   `validate:"oneof=created deleted"`
+		Items []string `validate:"dive,min=2"`
+	}
+	v := New()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				event := Event{ID: "7c9e6679-7425-40de-944b-e07fc1f90ae7", Kind: "created", Items: []string{"ab"}}
+				if (i+j)%2 == 1 {
+					event.Items = append(event.Items, "x")
+				}
+				err := v.Struct(event)
+				if (err != nil) != ((i+j)%2 == 1) {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	return failed == 0
+}
+
+func main() {
+	fmt.Println("Running Validator Emulator Tests...")
+	fmt.Println("===================================")
+
+	runTest("Required And Omitempty", testRequiredAndOmitempty)
+	runTest("Size Rules", testSizeRules)
+	runTest("Format Rules", testFormatRules)
+	runTest("Nested Structs", testNestedStructs)
+	runTest("Dive", testDive)
+	runTest("Cross-Field Rules", testCrossField)
+	runTest("Custom Validators", testCustomValidators)
+	runTest("Field Errors", testFieldErrors)
+	runTest("Translations", testTranslations)
+	runTest("Tag Panics", testTagPanics)
+	runTest("Gin Binding Validator", testGinBindingValidator)
+	runTest("Concurrent Validation", testConcurrentValidation)
+
+	fmt.Println("===================================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,1521 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-playground/validator
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultTagName = "validate"
+
+var timeType = reflect.TypeOf(time.Time{})
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Func validates one field; it reports whether the field is valid
+type Func func(fl FieldLevel) bool
+
+// FieldLevel is what a Func sees of the field being validated
+type FieldLevel interface {
+	// Top is the value passed to Struct or Var
+	Top() reflect.Value
+	// Parent is the struct holding the field
+	Parent() reflect.Value
+	// Field is the field's value, with pointers dereferenced
+	Field() reflect.Value
+	// FieldName is the field's name, from the TagNameFunc if one is set
+	FieldName() string
+	// StructFieldName is the field's Go name
+	StructFieldName() string
+	// Param is the tag's parameter, e.g. "3" for min=3
+	Param() string
+	// GetTag is the tag being validated
+	GetTag() string
+}
+
+// StructLevelFunc validates a whole struct, reporting errors through sl
+type StructLevelFunc func(sl StructLevel)
+
+// StructLevel is what a StructLevelFunc sees of the struct
+type StructLevel interface {
+	Validator() *Validate
+	Top() reflect.Value
+	Parent() reflect.Value
+	Current() reflect.Value
+	// ReportError records a failure for one of the struct's fields
+	ReportError(field interface{}, fieldName, structFieldName, tag, param string)
+}
+
+// TagNameFunc names fields in errors, e.g. from their json tag. Returning
+// "-" skips the field and "" keeps the Go name.
+type TagNameFunc func(field reflect.StructField) string
+
+// CustomTypeFunc converts a value of a registered type, such as
+// sql.NullString, into the value to validate
+type CustomTypeFunc func(field reflect.Value) interface{}
+
+type internalValidation struct {
+	fn         Func
+	runWhenNil bool
+}
+
+// Validate validates structs and variables against tags. It is safe for
+// concurrent use once its rules, aliases and translations are registered.
+type Validate struct {
+	tagName      string
+	tagNameFunc  TagNameFunc
+	validations  map[string]internalValidation
+	aliases      map[string]string
+	structLevel  map[reflect.Type]StructLevelFunc
+	customTypes  map[reflect.Type]CustomTypeFunc
+	translations map[Translator]map[string]TranslationFunc
+	tagCache     sync.Map
+}
+
+// New creates a Validate with the built-in rules and aliases
+func New() *Validate {
+	v := &Validate{
+		tagName:      defaultTagName,
+		validations:  make(map[string]internalValidation, len(bakedInValidators)),
+		aliases:      make(map[string]string, len(bakedInAliases)),
+		structLevel:  make(map[reflect.Type]StructLevelFunc),
+		customTypes:  make(map[reflect.Type]CustomTypeFunc),
+		translations: make(map[Translator]map[string]TranslationFunc),
+	}
+	for tag, fn := range bakedInValidators {
+		v.validations[tag] = internalValidation{fn: fn, runWhenNil: runWhenNil[tag]}
+	}
+	for alias, tags := range bakedInAliases {
+		v.aliases[alias] = tags
+	}
+	return v
+}
+
+// SetTagName changes the struct tag read for rules, "validate" by default
+func (v *Validate) SetTagName(name string) {
+	v.tagName = name
+	v.tagCache = sync.Map{}
+}
+
+// RegisterTagNameFunc sets how fields are named in errors
+func (v *Validate) RegisterTagNameFunc(fn TagNameFunc) {
+	v.tagNameFunc = fn
+}
+
+// RegisterValidation adds or replaces a rule. With callValidationEvenIfNull
+// the rule also runs for nil pointers, as "required" does.
+func (v *Validate) RegisterValidation(tag string, fn Func, callValidationEvenIfNull ...bool) error {
+	if tag == "" {
+		return errors.New("function Key cannot be empty")
+	}
+	if fn == nil {
+		return errors.New("function cannot be empty")
+	}
+	if restrictedTags[tag] || strings.ContainsAny(tag, ",|=") {
+		return fmt.Errorf("tag '%s' either contains restricted characters or is the same as a restricted tag needed for normal operation", tag)
+	}
+	v.validations[tag] = internalValidation{fn: fn, runWhenNil: len(callValidationEvenIfNull) > 0 && callValidationEvenIfNull[0]}
+	v.tagCache = sync.Map{}
+	return nil
+}
+
+// RegisterAlias makes alias stand for a list of tags, e.g.
+// RegisterAlias("username", "min=3,max=20,alphanum"). Errors report the
+// alias as their Tag.
+func (v *Validate) RegisterAlias(alias, tags string) {
+	if restrictedTags[alias] {
+		panic(fmt.Sprintf("Alias '%s' either contains restricted characters or is the same as a restricted tag needed for normal operation", alias))
+	}
+	v.aliases[alias] = tags
+	v.tagCache = sync.Map{}
+}
+
+// RegisterStructValidation runs fn for every struct of the given types,
+// after their fields are validated
+func (v *Validate) RegisterStructValidation(fn StructLevelFunc, types ...interface{}) {
+	for _, t := range types {
+		typ := reflect.TypeOf(t)
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		v.structLevel[typ] = fn
+	}
+}
+
+// RegisterCustomTypeFunc validates values of the given types as the
+// value fn returns for them
+func (v *Validate) RegisterCustomTypeFunc(fn CustomTypeFunc, types ...interface{}) {
+	for _, t := range types {
+		v.customTypes[reflect.TypeOf(t)] = fn
+	}
+}
+
+// InvalidValidationError is returned when Struct is given something
+// other than a struct or a non-nil pointer to one
+type InvalidValidationError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidValidationError) Error() string {
+	if e.Type == nil {
+		return "validator: (nil)"
+	}
+	return "validator: (nil " + e.Type.String() + ")"
+}
+
+// Struct validates the exported fields of s, recursing into nested
+// structs. It returns ValidationErrors when rules fail.
+func (v *Validate) Struct(s interface{}) error {
+	top := reflect.ValueOf(s)
+	value := top
+	if value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct || value.Type() == timeType {
+		return &InvalidValidationError{Type: reflect.TypeOf(s)}
+	}
+
+	run := &validation{v: v, top: top}
+	name := value.Type().Name()
+	run.validateStruct(value, value, name, name)
+	return run.result()
+}
+
+// Var validates a single value against tag, e.g. Var(email, "required,email")
+func (v *Validate) Var(field interface{}, tag string) error {
+	return v.VarWithValue(field, nil, tag)
+}
+
+// VarWithValue validates field against tag with other standing in for
+// the parent struct, so "eqfield" compares field with other
+func (v *Validate) VarWithValue(field, other interface{}, tag string) error {
+	if tag == "" || tag == skipValidationTag {
+		return nil
+	}
+	value := reflect.ValueOf(field)
+	run := &validation{v: v, top: value}
+	run.validateValue(reflect.ValueOf(other), value, "", "", "", "", v.parseTag(tag, ""))
+	return run.result()
+}
+
+// Tags
+
+const (
+	skipValidationTag = "-"
+	omitemptyTag      = "omitempty"
+	omitnilTag        = "omitnil"
+	diveTag           = "dive"
+	keysTag           = "keys"
+	endKeysTag        = "endkeys"
+	orSeparator       = "|"
+)
+
+var restrictedTags = map[string]bool{
+	skipValidationTag: true, omitemptyTag: true, omitnilTag: true,
+	diveTag: true, keysTag: true, endKeysTag: true,
+}
+
+type tagType int
+
+const (
+	typeDefault tagType = iota
+	typeOmitEmpty
+	typeOmitNil
+	typeDive
+)
+
+// alternative is one rule of a tag; or-groups like "hexcolor|uuid" have
+// several
+type alternative struct {
+	tag        string
+	param      string
+	fn         Func
+	runWhenNil bool
+}
+
+// cTag is a parsed tag
+type cTag struct {
+	typ          tagType
+	aliasTag     string
+	alternatives []alternative
+	keys         []*cTag
+}
+
+// name is the tag reported in errors
+func (ct *cTag) name() string {
+	if ct.aliasTag != "" {
+		return ct.aliasTag
+	}
+	return ct.actualTag()
+}
+
+func (ct *cTag) actualTag() string {
+	names := make([]string, len(ct.alternatives))
+	for i, alt := range ct.alternatives {
+		names[i] = alt.tag
+	}
+	return strings.Join(names, orSeparator)
+}
+
+func (ct *cTag) param() string {
+	if len(ct.alternatives) == 1 {
+		return ct.alternatives[0].param
+	}
+	return ""
+}
+
+func (ct *cTag) runsWhenNil() bool {
+	for _, alt := range ct.alternatives {
+		if alt.runWhenNil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTag parses and caches a tag string. Unknown rules panic, as they
+// are programming errors.
+func (v *Validate) parseTag(tag, fieldName string) []*cTag {
+	if cached, ok := v.tagCache.Load(tag); ok {
+		return cached.([]*cTag)
+	}
+	tags := v.parseTagList(strings.Split(tag, ","), "", fieldName)
+	v.tagCache.Store(tag, tags)
+	return tags
+}
+
+func (v *Validate) parseTagList(tokens []string, alias, fieldName string) []*cTag {
+	var tags []*cTag
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token {
+		case omitemptyTag:
+			tags = append(tags, &cTag{typ: typeOmitEmpty, aliasTag: alias})
+			continue
+		case omitnilTag:
+			tags = append(tags, &cTag{typ: typeOmitNil, aliasTag: alias})
+			continue
+		case diveTag:
+			dive := &cTag{typ: typeDive, aliasTag: alias}
+			if i+1 < len(tokens) && tokens[i+1] == keysTag {
+				end := i + 2
+				for end < len(tokens) && tokens[end] != endKeysTag {
+					end++
+				}
+				if end == len(tokens) {
+					panic("'keys' tag encountered without a corresponding 'endkeys' tag")
+				}
+				dive.keys = v.parseTagList(tokens[i+2:end], alias, fieldName)
+				i = end
+			}
+			tags = append(tags, dive)
+			continue
+		case keysTag:
+			panic("'keys' tag must be immediately preceded by the 'dive' tag")
+		case endKeysTag:
+			panic("'endkeys' tag encountered without a corresponding 'keys' tag")
+		}
+
+		if aliased, ok := v.aliases[token]; ok && alias == "" {
+			tags = append(tags, v.parseTagList(strings.Split(aliased, ","), token, fieldName)...)
+			continue
+		}
+
+		ct := &cTag{typ: typeDefault, aliasTag: alias}
+		for _, part := range strings.Split(token, orSeparator) {
+			name, param := part, ""
+			if idx := strings.Index(part, "="); idx >= 0 {
+				name, param = part[:idx], part[idx+1:]
+				param = strings.NewReplacer("0x2C", ",", "0x7C", "|").Replace(param)
+			}
+			validation, ok := v.validations[name]
+			if !ok {
+				panic(fmt.Sprintf("Undefined validation function '%s' on field '%s'", name, fieldName))
+			}
+			ct.alternatives = append(ct.alternatives, alternative{tag: name, param: param, fn: validation.fn, runWhenNil: validation.runWhenNil})
+		}
+		tags = append(tags, ct)
+	}
+	return tags
+}
+
+// Validation
+
+// validation is one call to Struct or Var
+type validation struct {
+	v    *Validate
+	top  reflect.Value
+	errs ValidationErrors
+}
+
+func (run *validation) result() error {
+	if len(run.errs) == 0 {
+		return nil
+	}
+	return run.errs
+}
+
+// extract dereferences pointers and interfaces and applies custom type
+// funcs, reporting whether a nil was found on the way
+func (run *validation) extract(current reflect.Value) (reflect.Value, bool, bool) {
+	isPointer := false
+	for {
+		switch current.Kind() {
+		case reflect.Invalid:
+			return current, isPointer, true
+		case reflect.Ptr, reflect.Interface:
+			if current.IsNil() {
+				return current, isPointer, true
+			}
+			isPointer = isPointer || current.Kind() == reflect.Ptr
+			current = current.Elem()
+			continue
+		}
+		if fn, ok := run.v.customTypes[current.Type()]; ok {
+			current = reflect.ValueOf(fn(current))
+			continue
+		}
+		return current, isPointer, false
+	}
+}
+
+// validateStruct validates the fields of current, then its struct-level rule
+func (run *validation) validateStruct(parent, current reflect.Value, ns, structNs string) {
+	t := current.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get(run.v.tagName)
+		if tag == skipValidationTag {
+			continue
+		}
+		name := field.Name
+		if run.v.tagNameFunc != nil {
+			switch alt := run.v.tagNameFunc(field); alt {
+			case skipValidationTag:
+				continue
+			case "":
+			default:
+				name = alt
+			}
+		}
+
+		var tags []*cTag
+		if tag != "" {
+			tags = run.v.parseTag(tag, field.Name)
+		} else if !isStructType(field.Type) {
+			continue
+		}
+		run.validateValue(current, current.Field(i), ns+"."+name, structNs+"."+field.Name, name, field.Name, tags)
+	}
+
+	if fn, ok := run.v.structLevel[t]; ok {
+		fn(&structLevel{run: run, parent: parent, current: current, ns: ns, structNs: structNs})
+	}
+}
+
+// isStructType reports whether untagged fields of t are recursed into
+func isStructType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// validateValue applies tags to one value. Only the first failing rule of
+// each value is reported.
+func (run *validation) validateValue(parent, original reflect.Value, ns, structNs, name, structName string, tags []*cTag) {
+	current, isPointer, isNil := run.extract(original)
+	fl := &fieldLevel{run: run, parent: parent, field: current, isPointer: isPointer, name: name, structName: structName}
+
+	if isNil {
+		for _, ct := range tags {
+			switch ct.typ {
+			case typeOmitEmpty, typeOmitNil:
+				return
+			case typeDive:
+				return
+			}
+			if ct.runsWhenNil() && run.check(fl, ct) {
+				continue
+			}
+			run.report(ct, original, ns, structNs, name, structName)
+			return
+		}
+		return
+	}
+
+	isStruct := current.Kind() == reflect.Struct && current.Type() != timeType
+	for i, ct := range tags {
+		switch ct.typ {
+		case typeOmitEmpty:
+			if !hasValue(fl) {
+				return
+			}
+		case typeOmitNil:
+			switch current.Kind() {
+			case reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+				if current.IsNil() {
+					return
+				}
+			}
+		case typeDive:
+			run.dive(parent, current, ns, structNs, name, structName, ct.keys, tags[i+1:])
+			return
+		default:
+			if !run.check(fl, ct) {
+				run.report(ct, current, ns, structNs, name, structName)
+				return
+			}
+		}
+	}
+	if isStruct {
+		run.validateStruct(parent, current, ns, structNs)
+	}
+}
+
+// dive validates the elements of a slice, array or map, and the keys of a
+// map when keys tags are given
+func (run *validation) dive(parent, current reflect.Value, ns, structNs, name, structName string, keys, tags []*cTag) {
+	switch current.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < current.Len(); i++ {
+			index := fmt.Sprintf("[%d]", i)
+			run.validateValue(parent, current.Index(i), ns+index, structNs+index, name+index, structName+index, tags)
+		}
+	case reflect.Map:
+		mapKeys := current.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return fmt.Sprint(mapKeys[i].Interface()) < fmt.Sprint(mapKeys[j].Interface())
+		})
+		for _, key := range mapKeys {
+			index := fmt.Sprintf("[%v]", key.Interface())
+			if keys != nil {
+				run.validateValue(parent, key, ns+index, structNs+index, name+index, structName+index, keys)
+			}
+			run.validateValue(parent, current.MapIndex(key), ns+index, structNs+index, name+index, structName+index, tags)
+		}
+	default:
+		panic("dive error! can't dive on a non slice or map")
+	}
+}
+
+// check runs a tag's rules; an or-group passes if any rule does
+func (run *validation) check(fl *fieldLevel, ct *cTag) bool {
+	for _, alt := range ct.alternatives {
+		fl.tag, fl.param = alt.tag, alt.param
+		if alt.fn(fl) {
+			return true
+		}
+	}
+	return false
+}
+
+func (run *validation) report(ct *cTag, value reflect.Value, ns, structNs, name, structName string) {
+	fe := &fieldError{
+		v:          run.v,
+		tag:        ct.name(),
+		actualTag:  ct.actualTag(),
+		ns:         ns,
+		structNs:   structNs,
+		field:      name,
+		structName: structName,
+		param:      ct.param(),
+		kind:       value.Kind(),
+	}
+	if value.IsValid() {
+		fe.value = value.Interface()
+		fe.typ = value.Type()
+	}
+	run.errs = append(run.errs, fe)
+}
+
+type fieldLevel struct {
+	run        *validation
+	parent     reflect.Value
+	field      reflect.Value
+	isPointer  bool
+	name       string
+	structName string
+	tag        string
+	param      string
+}
+
+func (fl *fieldLevel) Top() reflect.Value      { return fl.run.top }
+func (fl *fieldLevel) Parent() reflect.Value   { return fl.parent }
+func (fl *fieldLevel) Field() reflect.Value    { return fl.field }
+func (fl *fieldLevel) FieldName() string       { return fl.name }
+func (fl *fieldLevel) StructFieldName() string { return fl.structName }
+func (fl *fieldLevel) Param() string           { return fl.param }
+func (fl *fieldLevel) GetTag() string          { return fl.tag }
+
+type structLevel struct {
+	run      *validation
+	parent   reflect.Value
+	current  reflect.Value
+	ns       string
+	structNs string
+}
+
+func (sl *structLevel) Validator() *Validate   { return sl.run.v }
+func (sl *structLevel) Top() reflect.Value     { return sl.run.top }
+func (sl *structLevel) Parent() reflect.Value  { return sl.parent }
+func (sl *structLevel) Current() reflect.Value { return sl.current }
+
+func (sl *structLevel) ReportError(field interface{}, fieldName, structFieldName, tag, param string) {
+	value := reflect.ValueOf(field)
+	fe := &fieldError{
+		v:          sl.run.v,
+		tag:        tag,
+		actualTag:  tag,
+		ns:         sl.ns + "." + fieldName,
+		structNs:   sl.structNs + "." + structFieldName,
+		field:      fieldName,
+		structName: structFieldName,
+		value:      field,
+		param:      param,
+		kind:       value.Kind(),
+	}
+	if value.IsValid() {
+		fe.typ = value.Type()
+	}
+	sl.run.errs = append(sl.run.errs, fe)
+}
+
+// Errors
+
+// FieldError describes one failed rule
+type FieldError interface {
+	// Tag is the failed tag, or the alias it came from
+	Tag() string
+	// ActualTag is the failed tag with aliases resolved
+	ActualTag() string
+	// Namespace is the field's path using FieldName, e.g. "User.addresses[0].city"
+	Namespace() string
+	// StructNamespace is the field's path using Go names
+	StructNamespace() string
+	Field() string
+	StructField() string
+	Value() interface{}
+	Param() string
+	Kind() reflect.Kind
+	Type() reflect.Type
+	// Translate renders the error with trans, falling back to Error
+	Translate(trans Translator) string
+	Error() string
+}
+
+type fieldError struct {
+	v          *Validate
+	tag        string
+	actualTag  string
+	ns         string
+	structNs   string
+	field      string
+	structName string
+	value      interface{}
+	param      string
+	kind       reflect.Kind
+	typ        reflect.Type
+}
+
+func (fe *fieldError) Tag() string             { return fe.tag }
+func (fe *fieldError) ActualTag() string       { return fe.actualTag }
+func (fe *fieldError) Namespace() string       { return fe.ns }
+func (fe *fieldError) StructNamespace() string { return fe.structNs }
+func (fe *fieldError) Field() string           { return fe.field }
+func (fe *fieldError) StructField() string     { return fe.structName }
+func (fe *fieldError) Value() interface{}      { return fe.value }
+func (fe *fieldError) Param() string           { return fe.param }
+func (fe *fieldError) Kind() reflect.Kind      { return fe.kind }
+func (fe *fieldError) Type() reflect.Type      { return fe.typ }
+
+func (fe *fieldError) Error() string {
+	return fmt.Sprintf("Key: '%s' Error:Field validation for '%s' failed on the '%s' tag", fe.ns, fe.field, fe.tag)
+}
+
+func (fe *fieldError) Translate(trans Translator) string {
+	funcs, ok := fe.v.translations[trans]
+	if !ok {
+		return fe.Error()
+	}
+	fn, ok := funcs[fe.tag]
+	if !ok {
+		if fn, ok = funcs[fe.actualTag]; !ok {
+			return fe.Error()
+		}
+	}
+	return fn(trans, fe)
+}
+
+// ValidationErrors is returned by Struct and Var when rules fail
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, len(ve))
+	for i, fe := range ve {
+		messages[i] = fe.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// ValidationErrorsTranslations maps namespaces to translated messages
+type ValidationErrorsTranslations map[string]string
+
+// Translate translates every error, keyed by namespace
+func (ve ValidationErrors) Translate(trans Translator) ValidationErrorsTranslations {
+	translations := make(ValidationErrorsTranslations, len(ve))
+	for _, fe := range ve {
+		translations[fe.Namespace()] = fe.Translate(trans)
+	}
+	return translations
+}
+
+// Built-in rules
+
+var (
+	alphaRegex       = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alphaNumRegex    = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	numericRegex     = regexp.MustCompile(`^[-+]?[0-9]+(?:\.[0-9]+)?$`)
+	numberRegex      = regexp.MustCompile(`^[0-9]+$`)
+	hexadecimalRegex = regexp.MustCompile(`^(0[xX])?[0-9a-fA-F]+$`)
+	hexColorRegex    = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	emailRegex       = regexp.MustCompile(`^[a-zA-Z0-9.!#$%&'*+/=?^_{|}~\-]+@[a-zA-Z0-9](?:[a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?)+$`)
+	uuidRegex        = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	uuid4Regex       = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	hostnameRegex    = regexp.MustCompile(`^[a-zA-Z]([a-zA-Z0-9\-]+[\.]?)*[a-zA-Z0-9]$`)
+	e164Regex        = regexp.MustCompile(`^\+[1-9]?[0-9]{7,14}$`)
+	asciiRegex       = regexp.MustCompile(`^[\x00-\x7F]*$`)
+	oneofParamRegex  = regexp.MustCompile(`'[^']*'|\S+`)
+)
+
+var bakedInAliases = map[string]string{
+	"iscolor": "hexcolor",
+}
+
+// runWhenNil lists the built-in rules that also run for nil pointers
+var runWhenNil = map[string]bool{
+	"required": true, "required_if": true, "required_unless": true,
+	"required_with": true, "required_without": true, "isdefault": true,
+}
+
+var bakedInValidators map[string]Func
+
+func init() {
+	bakedInValidators = map[string]Func{
+		"required":         hasValue,
+		"required_if":      requiredIf,
+		"required_unless":  requiredUnless,
+		"required_with":    requiredWith,
+		"required_without": requiredWithout,
+		"isdefault":        func(fl FieldLevel) bool { return !hasValue(fl) },
+		"len":              compareParam(func(c int) bool { return c == 0 }),
+		"min":              compareParam(func(c int) bool { return c >= 0 }),
+		"max":              compareParam(func(c int) bool { return c <= 0 }),
+		"gt":               compareParam(func(c int) bool { return c > 0 }),
+		"gte":              compareParam(func(c int) bool { return c >= 0 }),
+		"lt":               compareParam(func(c int) bool { return c < 0 }),
+		"lte":              compareParam(func(c int) bool { return c <= 0 }),
+		"eq":               isEq,
+		"ne":               func(fl FieldLevel) bool { return !isEq(fl) },
+		"oneof":            isOneOf,
+		"eqfield":          isEqField,
+		"nefield":          func(fl FieldLevel) bool { return !isEqField(fl) },
+		"gtfield":          compareField(func(c int) bool { return c > 0 }),
+		"gtefield":         compareField(func(c int) bool { return c >= 0 }),
+		"ltfield":          compareField(func(c int) bool { return c < 0 }),
+		"ltefield":         compareField(func(c int) bool { return c <= 0 }),
+		"unique":           isUnique,
+		"email":            matches(emailRegex),
+		"alpha":            matches(alphaRegex),
+		"alphanum":         matches(alphaNumRegex),
+		"numeric":          isNumeric,
+		"number":           isNumber,
+		"hexadecimal":      matches(hexadecimalRegex),
+		"hexcolor":         matches(hexColorRegex),
+		"uuid":             matches(uuidRegex),
+		"uuid4":            matches(uuid4Regex),
+		"hostname":         matches(hostnameRegex),
+		"e164":             matches(e164Regex),
+		"ascii":            matches(asciiRegex),
+		"lowercase":        func(fl FieldLevel) bool { s := fl.Field().String(); return s != "" && s == strings.ToLower(s) },
+		"uppercase":        func(fl FieldLevel) bool { s := fl.Field().String(); return s != "" && s == strings.ToUpper(s) },
+		"contains":         func(fl FieldLevel) bool { return strings.Contains(fl.Field().String(), fl.Param()) },
+		"containsany":      func(fl FieldLevel) bool { return strings.ContainsAny(fl.Field().String(), fl.Param()) },
+		"excludes":         func(fl FieldLevel) bool { return !strings.Contains(fl.Field().String(), fl.Param()) },
+		"startswith":       func(fl FieldLevel) bool { return strings.HasPrefix(fl.Field().String(), fl.Param()) },
+		"endswith":         func(fl FieldLevel) bool { return strings.HasSuffix(fl.Field().String(), fl.Param()) },
+		"url":              isURL,
+		"uri":              isURI,
+		"ip":               func(fl FieldLevel) bool { return net.ParseIP(fl.Field().String()) != nil },
+		"ipv4":             isIPv4,
+		"ipv6":             isIPv6,
+		"cidr":             func(fl FieldLevel) bool { _, _, err := net.ParseCIDR(fl.Field().String()); return err == nil },
+		"datetime":         isDatetime,
+		"boolean":          isBoolean,
+		"json":             isJSON,
+	}
+}
+
+// hasValue is "required": non-nil for references, non-zero otherwise. A
+// non-nil pointer has a value even if it points to a zero value.
+func hasValue(fl FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Chan, reflect.Func:
+		return !field.IsNil()
+	case reflect.Invalid:
+		return false
+	default:
+		if f, ok := fl.(*fieldLevel); ok && f.isPointer {
+			return true
+		}
+		return !field.IsZero()
+	}
+}
+
+// parseOneOfParam splits a space-separated list, keeping 'quoted values'
+func parseOneOfParam(param string) []string {
+	values := oneofParamRegex.FindAllString(param, -1)
+	for i, v := range values {
+		values[i] = strings.Trim(v, "'")
+	}
+	return values
+}
+
+// lookupField finds a field of the parent by dotted path; an empty path
+// is the parent itself
+func lookupField(fl FieldLevel, path string) (reflect.Value, bool) {
+	current := fl.Parent()
+	if path != "" {
+		for _, name := range strings.Split(path, ".") {
+			for current.Kind() == reflect.Ptr || current.Kind() == reflect.Interface {
+				if current.IsNil() {
+					return current, false
+				}
+				current = current.Elem()
+			}
+			if current.Kind() != reflect.Struct {
+				return current, false
+			}
+			current = current.FieldByName(name)
+		}
+	}
+	for current.Kind() == reflect.Ptr || current.Kind() == reflect.Interface {
+		if current.IsNil() {
+			return current, false
+		}
+		current = current.Elem()
+	}
+	return current, current.IsValid()
+}
+
+func fieldHasValue(fl FieldLevel, path string) bool {
+	other, ok := lookupField(fl, path)
+	return ok && hasValue(&fieldLevel{field: other, run: &validation{}})
+}
+
+func conditionsMatch(fl FieldLevel) bool {
+	params := parseOneOfParam(fl.Param())
+	if len(params)%2 != 0 {
+		panic(fmt.Sprintf("Bad param number for %s %s", fl.GetTag(), fl.FieldName()))
+	}
+	for i := 0; i < len(params); i += 2 {
+		other, ok := lookupField(fl, params[i])
+		if !ok || fmt.Sprint(other.Interface()) != params[i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+func requiredIf(fl FieldLevel) bool {
+	return !conditionsMatch(fl) || hasValue(fl)
+}
+
+func requiredUnless(fl FieldLevel) bool {
+	return conditionsMatch(fl) || hasValue(fl)
+}
+
+func requiredWith(fl FieldLevel) bool {
+	for _, path := range strings.Fields(fl.Param()) {
+		if fieldHasValue(fl, path) {
+			return hasValue(fl)
+		}
+	}
+	return true
+}
+
+func requiredWithout(fl FieldLevel) bool {
+	for _, path := range strings.Fields(fl.Param()) {
+		if !fieldHasValue(fl, path) {
+			return hasValue(fl)
+		}
+	}
+	return true
+}
+
+func asInt(param string) int64 {
+	i, err := strconv.ParseInt(param, 0, 64)
+	if err != nil {
+		panic(fmt.Sprintf("bad parameter '%s': %v", param, err))
+	}
+	return i
+}
+
+func asUint(param string) uint64 {
+	i, err := strconv.ParseUint(param, 0, 64)
+	if err != nil {
+		panic(fmt.Sprintf("bad parameter '%s': %v", param, err))
+	}
+	return i
+}
+
+func asFloat(param string) float64 {
+	f, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("bad parameter '%s': %v", param, err))
+	}
+	return f
+}
+
+// asIntFromType reads durations like "1h" for time.Duration fields
+func asIntFromType(t reflect.Type, param string) int64 {
+	if t == durationType {
+		if d, err := time.ParseDuration(param); err == nil {
+			return int64(d)
+		}
+	}
+	return asInt(param)
+}
+
+func cmpInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareParam builds the size rules: strings compare their length in
+// runes, collections their length, numbers their value and times the
+// current time
+func compareParam(ok func(int) bool) Func {
+	return func(fl FieldLevel) bool {
+		field := fl.Field()
+		param := fl.Param()
+		switch field.Kind() {
+		case reflect.String:
+			return ok(cmpInt(int64(utf8.RuneCountInString(field.String())), asInt(param)))
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return ok(cmpInt(int64(field.Len()), asInt(param)))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return ok(cmpInt(field.Int(), asIntFromType(field.Type(), param)))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return ok(cmpUint(field.Uint(), asUint(param)))
+		case reflect.Float32, reflect.Float64:
+			return ok(cmpFloat(field.Float(), asFloat(param)))
+		case reflect.Struct:
+			if field.Type() == timeType {
+				t := field.Interface().(time.Time)
+				return ok(t.Compare(time.Now()))
+			}
+		}
+		panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+	}
+}
+
+func isEq(fl FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		return field.String() == fl.Param()
+	case reflect.Bool:
+		b, err := strconv.ParseBool(fl.Param())
+		return err == nil && field.Bool() == b
+	}
+	return compareParam(func(c int) bool { return c == 0 })(fl)
+}
+
+func isOneOf(fl FieldLevel) bool {
+	field := fl.Field()
+	var value string
+	switch field.Kind() {
+	case reflect.String:
+		value = field.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strconv.FormatUint(field.Uint(), 10)
+	default:
+		panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+	}
+	for _, option := range parseOneOfParam(fl.Param()) {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isEqField(fl FieldLevel) bool {
+	field := fl.Field()
+	other, ok := lookupField(fl, fl.Param())
+	if !ok || other.Kind() != field.Kind() {
+		return false
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return field.Len() == other.Len()
+	case reflect.Struct:
+		if field.Type() == timeType && other.Type() == timeType {
+			return field.Interface().(time.Time).Equal(other.Interface().(time.Time))
+		}
+	}
+	return reflect.DeepEqual(field.Interface(), other.Interface())
+}
+
+// compareField builds the cross-field ordering rules. Strings compare
+// their length, as the size rules do.
+func compareField(ok func(int) bool) Func {
+	return func(fl FieldLevel) bool {
+		field := fl.Field()
+		other, found := lookupField(fl, fl.Param())
+		if !found || other.Kind() != field.Kind() {
+			return false
+		}
+		switch field.Kind() {
+		case reflect.String:
+			return ok(cmpInt(int64(utf8.RuneCountInString(field.String())), int64(utf8.RuneCountInString(other.String()))))
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return ok(cmpInt(int64(field.Len()), int64(other.Len())))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return ok(cmpInt(field.Int(), other.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return ok(cmpUint(field.Uint(), other.Uint()))
+		case reflect.Float32, reflect.Float64:
+			return ok(cmpFloat(field.Float(), other.Float()))
+		case reflect.Struct:
+			if field.Type() == timeType && other.Type() == timeType {
+				return ok(field.Interface().(time.Time).Compare(other.Interface().(time.Time)))
+			}
+		}
+		return false
+	}
+}
+
+// isUnique checks slice and array elements, or map values, for
+// duplicates; with a param, slices of structs compare that field
+func isUnique(fl FieldLevel) bool {
+	field := fl.Field()
+	seen := make(map[interface{}]bool)
+	add := func(v reflect.Value) bool {
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if fl.Param() != "" {
+			v = v.FieldByName(fl.Param())
+		}
+		key := v.Interface()
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		return true
+	}
+	switch field.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			if !add(field.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		iter := field.MapRange()
+		for iter.Next() {
+			if !add(iter.Value()) {
+				return false
+			}
+		}
+		return true
+	}
+	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+}
+
+func matches(re *regexp.Regexp) Func {
+	return func(fl FieldLevel) bool {
+		return re.MatchString(fl.Field().String())
+	}
+}
+
+// isNumeric accepts numeric kinds, and strings holding a decimal number
+func isNumeric(fl FieldLevel) bool {
+	switch fl.Field().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return numericRegex.MatchString(fl.Field().String())
+}
+
+// isNumber accepts integer kinds, and strings of digits
+func isNumber(fl FieldLevel) bool {
+	switch fl.Field().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return numberRegex.MatchString(fl.Field().String())
+}
+
+func isURL(fl FieldLevel) bool {
+	s := strings.ToLower(fl.Field().String())
+	if s == "" {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	return u.Host != "" || u.Fragment != "" || u.Opaque != "" || u.Scheme == "file"
+}
+
+func isURI(fl FieldLevel) bool {
+	s := fl.Field().String()
+	if i := strings.Index(s, "#"); i > -1 {
+		s = s[:i]
+	}
+	_, err := url.ParseRequestURI(s)
+	return s != "" && err == nil
+}
+
+func isIPv4(fl FieldLevel) bool {
+	ip := net.ParseIP(fl.Field().String())
+	return ip != nil && ip.To4() != nil && !strings.Contains(fl.Field().String(), ":")
+}
+
+func isIPv6(fl FieldLevel) bool {
+	ip := net.ParseIP(fl.Field().String())
+	return ip != nil && ip.To4() == nil
+}
+
+func isDatetime(fl FieldLevel) bool {
+	_, err := time.Parse(fl.Param(), fl.Field().String())
+	return err == nil
+}
+
+func isBoolean(fl FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() == reflect.Bool {
+		return true
+	}
+	_, err := strconv.ParseBool(field.String())
+	return err == nil
+}
+
+func isJSON(fl FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+		return json.Valid(field.Bytes())
+	}
+	return json.Valid([]byte(field.String()))
+}
+
+// Translations
+
+// Translator holds the messages of one locale. Texts use {0}, {1}, ...
+// placeholders for their parameters.
+type Translator interface {
+	Locale() string
+	// Add registers text for key; without override an existing key is
+	// an error
+	Add(key interface{}, text string, override bool) error
+	// T renders the text for key with params
+	T(key interface{}, params ...string) (string, error)
+}
+
+// ErrUnknownTranslation is returned by T for keys with no text
+var ErrUnknownTranslation = errors.New("Unknown Translation")
+
+// ErrConflictingTranslation is returned by Add for existing keys
+type ErrConflictingTranslation struct {
+	locale string
+	key    interface{}
+	text   string
+}
+
+func (e *ErrConflictingTranslation) Error() string {
+	return fmt.Sprintf("Conflicting key '%#v' rule 'Unknown' with text '%s' for locale '%s', value being ignored", e.key, e.text, e.locale)
+}
+
+type translator struct {
+	locale string
+	mu     sync.RWMutex
+	texts  map[interface{}]string
+}
+
+// NewTranslator creates an empty Translator for locale
+func NewTranslator(locale string) Translator {
+	return &translator{locale: locale, texts: make(map[interface{}]string)}
+}
+
+func (t *translator) Locale() string {
+	return t.locale
+}
+
+func (t *translator) Add(key interface{}, text string, override bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.texts[key]; ok && !override {
+		return &ErrConflictingTranslation{locale: t.locale, key: key, text: text}
+	}
+	t.texts[key] = text
+	return nil
+}
+
+func (t *translator) T(key interface{}, params ...string) (string, error) {
+	t.mu.RLock()
+	text, ok := t.texts[key]
+	t.mu.RUnlock()
+	if !ok {
+		return "", ErrUnknownTranslation
+	}
+	for i, param := range params {
+		text = strings.ReplaceAll(text, "{"+strconv.Itoa(i)+"}", param)
+	}
+	return text, nil
+}
+
+// UniversalTranslator holds the Translators of several locales with a
+// fallback
+type UniversalTranslator struct {
+	fallback    Translator
+	translators map[string]Translator
+}
+
+// NewUniversalTranslator creates Translators for the fallback locale and
+// the supported ones
+func NewUniversalTranslator(fallback string, supportedLocales ...string) *UniversalTranslator {
+	uni := &UniversalTranslator{translators: make(map[string]Translator)}
+	uni.fallback = uni.AddTranslator(fallback)
+	for _, locale := range supportedLocales {
+		uni.AddTranslator(locale)
+	}
+	return uni
+}
+
+// AddTranslator returns the Translator for locale, creating it if needed
+func (uni *UniversalTranslator) AddTranslator(locale string) Translator {
+	locale = strings.ToLower(locale)
+	if t, ok := uni.translators[locale]; ok {
+		return t
+	}
+	t := NewTranslator(locale)
+	uni.translators[locale] = t
+	return t
+}
+
+// GetTranslator returns the Translator for locale, or the fallback with
+// found false
+func (uni *UniversalTranslator) GetTranslator(locale string) (Translator, bool) {
+	if t, ok := uni.translators[strings.ToLower(locale)]; ok {
+		return t, true
+	}
+	return uni.fallback, false
+}
+
+// FindTranslator returns the Translator of the first supported locale,
+// e.g. from an Accept-Language header
+func (uni *UniversalTranslator) FindTranslator(locales ...string) (Translator, bool) {
+	for _, locale := range locales {
+		if t, ok := uni.GetTranslator(locale); ok {
+			return t, true
+		}
+	}
+	return uni.fallback, false
+}
+
+// GetFallback returns the fallback Translator
+func (uni *UniversalTranslator) GetFallback() Translator {
+	return uni.fallback
+}
+
+// RegisterTranslationsFunc adds a tag's texts to a Translator
+type RegisterTranslationsFunc func(trans Translator) error
+
+// TranslationFunc renders a FieldError with a Translator
+type TranslationFunc func(trans Translator, fe FieldError) string
+
+// RegisterTranslation registers how errors for tag are translated by trans
+func (v *Validate) RegisterTranslation(tag string, trans Translator, registerFn RegisterTranslationsFunc, translationFn TranslationFunc) error {
+	if err := registerFn(trans); err != nil {
+		return err
+	}
+	if v.translations[trans] == nil {
+		v.translations[trans] = make(map[string]TranslationFunc)
+	}
+	v.translations[trans][tag] = translationFn
+	return nil
+}
+
+// simpleTranslation renders key with the field name and the param
+func simpleTranslation(key string) TranslationFunc {
+	return func(trans Translator, fe FieldError) string {
+		text, err := trans.T(key, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return text
+	}
+}
+
+// sizeTranslation renders the size rules, which read differently for
+// strings, collections, numbers and times
+func sizeTranslation(tag string) TranslationFunc {
+	return func(trans Translator, fe FieldError) string {
+		var key, param string
+		var err error
+		switch fe.Kind() {
+		case reflect.String:
+			key = tag + "-string"
+			param, err = pluralize(trans, "character", fe.Param())
+		case reflect.Slice, reflect.Map, reflect.Array:
+			key = tag + "-items"
+			param, err = pluralize(trans, "item", fe.Param())
+		case reflect.Struct:
+			key, param = tag+"-datetime", fe.Param()
+		default:
+			key, param = tag+"-number", fe.Param()
+		}
+		if err != nil {
+			return fe.Error()
+		}
+		text, err := trans.T(key, fe.Field(), param)
+		if err != nil {
+			return fe.Error()
+		}
+		return text
+	}
+}
+
+// pluralize renders a count with the unit's "-one" or "-other" text
+func pluralize(trans Translator, unit, count string) (string, error) {
+	key := unit + "-other"
+	if count == "1" {
+		key = unit + "-one"
+	}
+	return trans.T(key, count)
+}
+
+// enTranslations are the default English texts, keyed by tag
+var enTranslations = map[string]string{
+	"required":         "{0} is a required field",
+	"required_if":      "{0} is a required field",
+	"required_unless":  "{0} is a required field",
+	"required_with":    "{0} is a required field",
+	"required_without": "{0} is a required field",
+	"isdefault":        "{0} must be default value",
+	"eq":               "{0} is not equal to {1}",
+	"ne":               "{0} should not be equal to {1}",
+	"oneof":            "{0} must be one of [{1}]",
+	"eqfield":          "{0} must be equal to {1}",
+	"nefield":          "{0} cannot be equal to {1}",
+	"gtfield":          "{0} must be greater than {1}",
+	"gtefield":         "{0} must be greater than or equal to {1}",
+	"ltfield":          "{0} must be less than {1}",
+	"ltefield":         "{0} must be less than or equal to {1}",
+	"unique":           "{0} must contain unique values",
+	"email":            "{0} must be a valid email address",
+	"alpha":            "{0} can only contain alphabetic characters",
+	"alphanum":         "{0} can only contain alphanumeric characters",
+	"numeric":          "{0} must be a valid numeric value",
+	"number":           "{0} must be a valid number",
+	"hexadecimal":      "{0} must be a valid hexadecimal",
+	"hexcolor":         "{0} must be a valid HEX color",
+	"iscolor":          "{0} must be a valid color",
+	"uuid":             "{0} must be a valid UUID",
+	"uuid4":            "{0} must be a valid version 4 UUID",
+	"hostname":         "{0} must be a valid hostname",
+	"e164":             "{0} must be a valid E.164 formatted phone number",
+	"ascii":            "{0} must contain only ascii characters",
+	"lowercase":        "{0} must be a lowercase string",
+	"uppercase":        "{0} must be an uppercase string",
+	"contains":         "{0} must contain the text '{1}'",
+	"containsany":      "{0} must contain at least one of the following characters '{1}'",
+	"excludes":         "{0} cannot contain the text '{1}'",
+	"startswith":       "{0} must start with text '{1}'",
+	"endswith":         "{0} must end with text '{1}'",
+	"url":              "{0} must be a valid URL",
+	"uri":              "{0} must be a valid URI",
+	"ip":               "{0} must be a valid IP address",
+	"ipv4":             "{0} must be a valid IPv4 address",
+	"ipv6":             "{0} must be a valid IPv6 address",
+	"cidr":             "{0} must contain a valid CIDR notation",
+	"datetime":         "{0} does not match the {1} format",
+	"boolean":          "{0} must be a valid boolean value",
+	"json":             "{0} must be a valid json string",
+}
+
+// enSizeTranslations are the English texts of the size rules
+var enSizeTranslations = map[string]string{
+	"character-one":   "{0} character",
+	"character-other": "{0} characters",
+	"item-one":        "{0} item",
+	"item-other":      "{0} items",
+
+	"len-string": "{0} must be {1} in length",
+	"len-items":  "{0} must contain {1}",
+	"len-number": "{0} must be equal to {1}",
+
+	"min-string": "{0} must be at least {1} in length",
+	"min-items":  "{0} must contain at least {1}",
+	"min-number": "{0} must be {1} or greater",
+
+	"max-string": "{0} must be a maximum of {1} in length",
+	"max-items":  "{0} must contain at maximum {1}",
+	"max-number": "{0} must be {1} or less",
+
+	"gt-string":   "{0} must be greater than {1} in length",
+	"gt-items":    "{0} must contain more than {1}",
+	"gt-number":   "{0} must be greater than {1}",
+	"gt-datetime": "{0} must be greater than the current Date & Time",
+
+	"gte-string":   "{0} must be at least {1} in length",
+	"gte-items":    "{0} must contain at least {1}",
+	"gte-number":   "{0} must be {1} or greater",
+	"gte-datetime": "{0} must be greater than or equal to the current Date & Time",
+
+	"lt-string":   "{0} must be less than {1} in length",
+	"lt-items":    "{0} must contain less than {1}",
+	"lt-number":   "{0} must be less than {1}",
+	"lt-datetime": "{0} must be less than the current Date & Time",
+
+	"lte-string":   "{0} must be at maximum {1} in length",
+	"lte-items":    "{0} must contain at maximum {1}",
+	"lte-number":   "{0} must be {1} or less",
+	"lte-datetime": "{0} must be less than or equal to the current Date & Time",
+}
+
+// RegisterDefaultTranslations registers English messages for the
+// built-in rules with trans, as en_translations does
+func RegisterDefaultTranslations(v *Validate, trans Translator) error {
+	for key, text := range enSizeTranslations {
+		if err := trans.Add(key, text, false); err != nil {
+			return err
+		}
+	}
+	for _, tag := range []string{"len", "min", "max", "gt", "gte", "lt", "lte"} {
+		err := v.RegisterTranslation(tag, trans, func(Translator) error { return nil }, sizeTranslation(tag))
+		if err != nil {
+			return err
+		}
+	}
+	for tag, text := range enTranslations {
+		tag, text := tag, text
+		err := v.RegisterTranslation(tag, trans, func(ut Translator) error {
+			return ut.Add(tag, text, false)
+		}, simpleTranslation(tag))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gin binding
+
+// SliceValidationError collects the errors of a bound slice by index
+type SliceValidationError []error
+
+func (err SliceValidationError) Error() string {
+	var messages []string
+	for i, e := range err {
+		if e != nil {
+			messages = append(messages, fmt.Sprintf("[%d]: %s", i, e.Error()))
+		}
+	}
+	return strings.Join(messages, "\n")
+}
+
+// BindingValidator adapts Validate to the Gin emulator's StructValidator,
+// reading `binding` tags as Gin's default validator does. Install it with
+// Validator = &BindingValidator{} on the Gin side.
+type BindingValidator struct {
+	once     sync.Once
+	validate *Validate
+}
+
+// ValidateStruct validates structs, pointers to them and slices of them;
+// other values pass
+func (b *BindingValidator) ValidateStruct(obj interface{}) error {
+	if obj == nil {
+		return nil
+	}
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return b.ValidateStruct(value.Elem().Interface())
+	case reflect.Struct:
+		return b.Engine().(*Validate).Struct(obj)
+	case reflect.Slice, reflect.Array:
+		errs := make(SliceValidationError, value.Len())
+		failed := false
+		for i := 0; i < value.Len(); i++ {
+			if err := b.ValidateStruct(value.Index(i).Interface()); err != nil {
+				errs[i] = err
+				failed = true
+			}
+		}
+		if failed {
+			return errs
+		}
+	}
+	return nil
+}
+
+// Engine returns the *Validate, for registering rules and translations
+func (b *BindingValidator) Engine() interface{} {
+	b.once.Do(func() {
+		b.validate = New()
+		b.validate.SetTagName("binding")
+	})
+	return b.validate
+}
@@ -0,0 +1,506 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-yaml
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Helper function to run a test
+func runTest(name string, testFunc func() bool) {
+	result := "PASS"
+	if !testFunc() {
+		result = "FAIL"
+	}
+	fmt.Printf("[%s] %s\n", result, name)
+}
+
+type Database struct {
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	Replicas []string      `yaml:"replicas,omitempty"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+type Config struct {
+	Name     string            `yaml:"name"`
+	Debug    bool              `yaml:"debug"`
+	Database Database          `yaml:"database"`
+	Labels   map[string]string `yaml:"labels,flow"`
+	Ignored  string            `yaml:"-"`
+	Version  float64
+}
+
+// Level decodes and encodes itself by name
+type Level int
+
+func (l *Level) UnmarshalYAML(value *Node) error {
+	switch value.Value {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", value.Value)
+	}
+	return nil
+}
+
+func (l Level) MarshalYAML() (interface{}, error) {
+	return map[Level]string{1: "low", 2: "high"}[l], nil
+}
+
+// Legacy uses the yaml.v2 form of UnmarshalYAML
+type Legacy struct {
+	Parts []string
+}
+
+func (l *Legacy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var joined string
+	if err := unmarshal(&joined); err != nil {
+		return err
+	}
+	l.Parts = strings.Split(joined, ",")
+	return nil
+}
+
+func testUnmarshalStruct() bool {
+	data := `
+name: api
+debug: yes
+version: 1.5
+ignored: nope
+database:
+  host: db.local
+  port: 5432
+  replicas:
+    - r1
+    - r2
+  timeout: 1m30s
+labels: {team: core, tier: "1"}
+`
+	var cfg Config
+	if err := Unmarshal([]byte(data), &cfg); err != nil {
+		return false
+	}
+	return cfg.Name == "api" && cfg.Debug && cfg.Version == 1.5 && cfg.Ignored == "" &&
+		cfg.Database.Host == "db.local" && cfg.Database.Port == 5432 &&
+		reflect.DeepEqual(cfg.Database.Replicas, []string{"r1", "r2"}) &&
+		cfg.Database.Timeout == 90*time.Second &&
+		cfg.Labels["team"] == "core" && cfg.Labels["tier"] == "1"
+}
+
+func testUnmarshalGeneric() bool {
+	data := `
+string: hello
+quoted: "123"
+int: 42
+hex: 0x1F
+float: 2.5
+inf: -.inf
+bool: true
+null_value: ~
+date: 2024-01-02
+list: [1, two, 3.0]
+nested:
+  - a: 1
+  - [x, y]
+1: numeric key
+`
+	var out map[string]interface{}
+	if err := Unmarshal([]byte(data), &out); err != nil {
+		return false
+	}
+	var generic interface{}
+	Unmarshal([]byte("1: one\ntwo: 2\n"), &generic)
+	_, nonStringKeys := generic.(map[interface{}]interface{})
+
+	return out["string"] == "hello" && out["quoted"] == "123" && out["int"] == 42 && out["hex"] == 31 &&
+		out["float"] == 2.5 && math.IsInf(out["inf"].(float64), -1) && out["bool"] == true &&
+		out["null_value"] == nil && out["date"] == "2024-01-02" &&
+		reflect.DeepEqual(out["list"], []interface{}{1, "two", 3.0}) &&
+		reflect.DeepEqual(out["nested"], []interface{}{map[string]interface{}{"a": 1}, []interface{}{"x", "y"}}) &&
+		out["1"] == "numeric key" && nonStringKeys
+}
+
+func testQuotedAndBlockScalars() bool {
+	data := `
+double: "tab\there \"quoted\" é"
+single: 'it''s # not a comment'
+folded_quote: "one
+  two"
+literal: |
+  line one
+    indented
+  line three
+folded: >
+  joined
+  together
+
+  new paragraph
+strip: |-
+  no newline
+keep: |+
+  kept
+
+plain: this plain
+  scalar continues
+`
+	var out map[string]string
+	if err := Unmarshal([]byte(data), &out); err != nil {
+		return false
+	}
+	return out["double"] == "tab\there \"quoted\" é" &&
+		out["single"] == "it's # not a comment" &&
+		out["folded_quote"] == "one two" &&
+		out["literal"] == "line one\n  indented\nline three\n" &&
+		out["folded"] == "joined together\nnew paragraph\n" &&
+		out["strip"] == "no newline" &&
+		out["keep"] == "kept\n\n" &&
+		out["plain"] == "this plain scalar continues"
+}
+
+func testAnchorsAndMerge() bool {
+	data := `
+defaults: &defaults
+  adapter: postgres
+  host: localhost
+  pool: 5
+development:
+  <<: *defaults
+  database: dev
+production:
+  <<: [*defaults, {pool: 50, ssl: true}]
+  host: db.prod
+hosts: &hosts [a, b]
+backup_hosts: *hosts
+`
+	var out map[string]map[string]interface{}
+	var raw map[string]interface{}
+	if Unmarshal([]byte(data), &raw) != nil {
+		return false
+	}
+	if err := Unmarshal([]byte(strings.Split(data, "hosts:")[0]), &out); err != nil {
+		return false
+	}
+	dev, prod := out["development"], out["production"]
+	return dev["adapter"] == "postgres" && dev["database"] == "dev" && dev["pool"] == 5 &&
+		prod["host"] == "db.prod" && prod["pool"] == 5 && prod["ssl"] == true &&
+		reflect.DeepEqual(raw["backup_hosts"], []interface{}{"a", "b"})
+}
+
+func testMarshal() bool {
+	cfg := Config{
+		Name:    "api",
+		Debug:   true,
+		Version: 2,
+		Database: Database{
+			Host:    "db.local",
+			Port:    5432,
+			Timeout: 5 * time.Second,
+		},
+		Labels: map[string]string{"tier": "1", "team": "core"},
+	}
+	data, err := Marshal(cfg)
+	if err != nil {
+		return false
+	}
+	want := `name: api
+debug: true
+database:
+    host: db.local
+    port: 5432
+    timeout: 5s
+labels: {team: core, tier: "1"}
+version: 2
+`
+	return string(data) == want
+}
+
+func testMarshalScalars() bool {
+	data, err := Marshal(map[string]interface{}{
+		"empty":   "",
+		"number":  "123",
+		"boolish": "yes",
+		"colon":   "key: value",
+		"spaces":  "  padded",
+		"multi":   "first\nsecond\n",
+		"control": "bell\a",
+		"nil":     nil,
+		"list":    []int{},
+		"map":     map[string]int{},
+		"item10":  1,
+		"item2":   2,
+	})
+	if err != nil {
+		return false
+	}
+	want := `boolish: "yes"
+colon: "key: value"
+control: "bell\x07"
+empty: ""
+item2: 2
+item10: 1
+list: []
+map: {}
+multi: |
+    first
+    second
+nil: null
+number: "123"
+spaces: "  padded"
+`
+	return string(data) == want
+}
+
+func testRoundTrip() bool {
+	in := map[string]interface{}{
+		"service": map[string]interface{}{
+			"name":  "billing",
+			"ports": []interface{}{8080, 8443},
+			"env": []interface{}{
+				map[string]interface{}{"name": "MODE", "value": "live"},
+				map[string]interface{}{"name": "DEBUG", "value": "false"},
+			},
+		},
+		"notes":   "line one\nline two",
+		"ratio":   0.25,
+		"enabled": false,
+		"matrix":  []interface{}{[]interface{}{1, 2}, []interface{}{3, 4}},
+	}
+	for _, indent := range []int{2, 4} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent(indent)
+		if enc.Encode(in) != nil || enc.Close() != nil {
+			return false
+		}
+		var out map[string]interface{}
+		if err := Unmarshal(buf.Bytes(), &out); err != nil || !reflect.DeepEqual(in, out) {
+			return false
+		}
+	}
+	return true
+}
+
+func testCustomMarshalers() bool {
+	var out struct {
+		Levels []Level
+		Legacy Legacy
+		When   time.Time
+	}
+	data := "levels: [low, high]\nlegacy: a,b,c\nwhen: 2024-05-01T10:00:00Z\n"
+	if err := Unmarshal([]byte(data), &out); err != nil {
+		return false
+	}
+	if !reflect.DeepEqual(out.Levels, []Level{1, 2}) || !reflect.DeepEqual(out.Legacy.Parts, []string{"a", "b", "c"}) ||
+		!out.When.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		return false
+	}
+	encoded, err := Marshal(struct {
+		Levels []Level `yaml:"levels,flow"`
+		When   time.Time
+	}{[]Level{2, 1}, out.When})
+	if err != nil || string(encoded) != "levels: [high, low]\nwhen: 2024-05-01T10:00:00Z\n" {
+		return false
+	}
+	return Unmarshal([]byte("levels: [medium]"), &out) != nil
+}
+
+func testInlineFields() bool {
+	type Meta struct {
+		Name string `yaml:"name"`
+		Kind string `yaml:"kind"`
+	}
+	type Resource struct {
+		Meta  `yaml:",inline"`
+		Spec  map[string]int         `yaml:"spec"`
+		Extra map[string]interface{} `yaml:",inline"`
+	}
+	var r Resource
+	data := "name: web\nkind: Deployment\nspec: {replicas: 3}\nowner: ops\n"
+	if err := Unmarshal([]byte(data), &r); err != nil {
+		return false
+	}
+	if r.Name != "web" || r.Kind != "Deployment" || r.Spec["replicas"] != 3 || r.Extra["owner"] != "ops" {
+		return false
+	}
+	encoded, err := Marshal(r)
+	return err == nil && string(encoded) == "name: web\nkind: Deployment\nspec:\n    replicas: 3\nowner: ops\n"
+}
+
+func testTypeErrors() bool {
+	var cfg Config
+	err := Unmarshal([]byte("name: [a, b]\ndatabase:\n  port: many\n  host: ok\n"), &cfg)
+	terr, ok := err.(*TypeError)
+	if !ok || len(terr.Errors) != 2 || cfg.Database.Host != "ok" {
+		return false
+	}
+	var small struct{ N int8 }
+	err = Unmarshal([]byte("n: 300"), &small)
+	return strings.Contains(terr.Errors[0], "line 1: cannot unmarshal !!seq into string") &&
+		strings.Contains(terr.Errors[1], "line 3: cannot unmarshal !!str `many` into int") &&
+		err != nil && strings.Contains(err.Error(), "into int8")
+}
+
+func testSyntaxErrors() bool {
+	cases := map[string]string{
+		"a: 1\n  b: 2\n":    "line 2: mapping values are not allowed",
+		"a: [1, 2\n":        "did not find expected ',' or ']'",
+		"a: \"open\n":       "line 1: found unexpected end of stream",
+		"a: *missing\n":     "line 1: unknown anchor 'missing' referenced",
+		"a: 1\na: 2\n":      "line 2: mapping key \"a\" already defined at line 1",
+		"a: 1\n\tb: 2\n":    "line 2: found a tab character",
+		"list:\n- a\nb c\n": "line 3: could not find expected ':'",
+	}
+	for data, want := range cases {
+		var out interface{}
+		err := Unmarshal([]byte(data), &out)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			fmt.Printf("  %q: %v\n", data, err)
+			return false
+		}
+	}
+	return Unmarshal([]byte("a: 1"), map[string]int{}) != nil
+}
+
+func testStreams() bool {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Encode(map[string]int{"a": 1})
+	enc.Encode([]string{"x"})
+	if buf.String() != "a: 1\n---\n- x\n" {
+		return false
+	}
+
+	dec := NewDecoder(strings.NewReader("%YAML 1.2\n---\na: 1\n...\n---\n# empty\n---\nb: 2\n"))
+	var docs []interface{}
+	for {
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false
+		}
+		docs = append(docs, doc)
+	}
+	strict := NewDecoder(strings.NewReader("host: x\nhots: y\n"))
+	strict.KnownFields(true)
+	var db Database
+	err := strict.Decode(&db)
+	return len(docs) == 3 && docs[1] == nil && reflect.DeepEqual(docs[2], map[string]interface{}{"b": 2}) &&
+		err != nil && strings.Contains(err.Error(), "field hots not found in type main.Database")
+}
+
+func testNodes() bool {
+	var doc Node
+	data := "# service\nname: api\nports: [80, 443]\n"
+	if err := Unmarshal([]byte(data), &doc); err != nil || doc.Kind != DocumentNode {
+		return false
+	}
+	root := doc.Content[0]
+	if root.Kind != MappingNode || root.Content[0].Value != "name" || root.Content[2].Line != 3 ||
+		root.Content[3].Style != FlowStyle || root.Content[3].Content[1].ShortTag() != "!!int" {
+		return false
+	}
+	var ports []int
+	if root.Content[3].Decode(&ports) != nil || !reflect.DeepEqual(ports, []int{80, 443}) {
+		return false
+	}
+
+	// Edit the tree and write it back
+	root.Content[1].Value = "gateway"
+	var extra Node
+	extra.Encode(map[string]bool{"tls": true})
+	root.Content = append(root.Content, &Node{Kind: ScalarNode, Value: "options"}, &extra)
+	out, err := Marshal(&doc)
+
+	var delayed struct {
+		Name string
+		Raw  Node
+	}
+	Unmarshal([]byte("name: x\nraw: {a: 1}\n"), &delayed)
+	var raw map[string]int
+	return err == nil && string(out) == "name: gateway\nports: [80, 443]\noptions:\n    tls: true\n" &&
+		delayed.Raw.Decode(&raw) == nil && raw["a"] == 1
+}
+
+func testCodec() bool {
+	// Codec plugs into the Viper, Gin and Testify emulators
+	var viper interface {
+		Encode(v map[string]interface{}) ([]byte, error)
+		Decode(data []byte, v map[string]interface{}) error
+	} = Codec{}
+	var gin interface {
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	} = Codec{}
+
+	config := map[string]interface{}{}
+	if err := viper.Decode([]byte("server:\n  port: 8080\n"), config); err != nil {
+		return false
+	}
+	encoded, err := viper.Encode(config)
+	if err != nil || string(encoded) != "server:\n    port: 8080\n" {
+		return false
+	}
+	var out struct{ Port int }
+	body, _ := gin.Marshal(map[string]int{"port": 9090})
+	return gin.Unmarshal(body, &out) == nil && out.Port == 9090
+}
+
+func testConcurrentUse() bool {
+	type Event struct {
+		ID   int               `yaml:"id"`
+		Tags map[string]string `yaml:"tags"`
+	}
+	var wg sync.WaitGroup
+	failures := make(chan int, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			in := Event{ID: i, Tags: map[string]string{"n": fmt.Sprint(i)}}
+			data, err := Marshal(in)
+			var out Event
+			if err != nil || Unmarshal(data, &out) != nil || !reflect.DeepEqual(in, out) {
+				failures <- i
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(failures)
+	return len(failures) == 0
+}
+
+func main() {
+	fmt.Println("Running yaml Emulator Tests...")
+	fmt.Println("==============================")
+
+	runTest("Unmarshal Struct", testUnmarshalStruct)
+	runTest("Unmarshal Generic", testUnmarshalGeneric)
+	runTest("Quoted and Block Scalars", testQuotedAndBlockScalars)
+	runTest("Anchors and Merge", testAnchorsAndMerge)
+	runTest("Marshal", testMarshal)
+	runTest("Marshal Scalars", testMarshalScalars)
+	runTest("Round Trip", testRoundTrip)
+	runTest("Custom Marshalers", testCustomMarshalers)
+	runTest("Inline Fields", testInlineFields)
+	runTest("Type Errors", testTypeErrors)
+	runTest("Syntax Errors", testSyntaxErrors)
+	runTest("Streams", testStreams)
+	runTest("Nodes", testNodes)
+	runTest("Codec", testCodec)
+	runTest("Concurrent Use", testConcurrentUse)
+
+	fmt.Println("==============================")
+	fmt.Println("All tests completed!")
+}
@@ -0,0 +1,2527 @@
+package main
+
+// Developed by PowerShield, as an alternative to go-yaml
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Nodes
+
+// Kind identifies the type of a Node
+type Kind uint32
+
+const (
+	DocumentNode Kind = 1 << iota
+	SequenceNode
+	MappingNode
+	ScalarNode
+	AliasNode
+)
+
+// Style controls how a Node is written
+type Style uint32
+
+const (
+	TaggedStyle Style = 1 << iota
+	DoubleQuotedStyle
+	SingleQuotedStyle
+	LiteralStyle
+	FoldedStyle
+	FlowStyle
+)
+
+// Node is a YAML document parsed but not yet decoded. Mappings keep their
+// keys and values alternating in Content, in document order.
+type Node struct {
+	Kind  Kind
+	Style Style
+	// Tag is the short tag, such as "!!str", if one was given or set
+	Tag string
+	// Value is the text of a scalar or the name of an alias
+	Value   string
+	Anchor  string
+	Alias   *Node
+	Content []*Node
+
+	Line   int
+	Column int
+}
+
+// Short tags for the core schema
+const (
+	nullTag      = "!!null"
+	boolTag      = "!!bool"
+	strTag       = "!!str"
+	intTag       = "!!int"
+	floatTag     = "!!float"
+	timestampTag = "!!timestamp"
+	seqTag       = "!!seq"
+	mapTag       = "!!map"
+	binaryTag    = "!!binary"
+	mergeTag     = "!!merge"
+)
+
+// ShortTag returns the node's tag, resolving implicit scalar tags
+func (n *Node) ShortTag() string {
+	if n.Tag != "" && n.Tag != "!" {
+		if strings.HasPrefix(n.Tag, "tag:yaml.org,2002:") {
+			return "!!" + strings.TrimPrefix(n.Tag, "tag:yaml.org,2002:")
+		}
+		return n.Tag
+	}
+	switch n.Kind {
+	case MappingNode:
+		return mapTag
+	case SequenceNode:
+		return seqTag
+	case AliasNode:
+		if n.Alias != nil {
+			return n.Alias.ShortTag()
+		}
+	case ScalarNode:
+		tag, _ := resolve(n)
+		return tag
+	}
+	return ""
+}
+
+// IsZero reports whether the node is unset
+func (n *Node) IsZero() bool {
+	return n.Kind == 0 && n.Style == 0 && n.Tag == "" && n.Value == "" && n.Anchor == "" &&
+		n.Alias == nil && n.Content == nil && n.Line == 0 && n.Column == 0
+}
+
+// Decode decodes the node into v, like Unmarshal
+func (n *Node) Decode(v interface{}) error {
+	d := newDecoder()
+	out := reflect.ValueOf(v)
+	if out.Kind() != reflect.Ptr || out.IsNil() {
+		return fmt.Errorf("yaml: Decode requires a non-nil pointer, got %T", v)
+	}
+	return d.finish(d.unmarshal(n, out.Elem()))
+}
+
+// Encode sets the node to the encoding of v
+func (n *Node) Encode(v interface{}) error {
+	encoded, err := encodeValue(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	*n = *encoded
+	return nil
+}
+
+// Marshaler is implemented by types that encode themselves; the returned
+// value is encoded in their place
+type Marshaler interface {
+	MarshalYAML() (interface{}, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves from a node
+type Unmarshaler interface {
+	UnmarshalYAML(value *Node) error
+}
+
+// obsoleteUnmarshaler is the yaml.v2 form of Unmarshaler, still accepted
+type obsoleteUnmarshaler interface {
+	UnmarshalYAML(unmarshal func(interface{}) error) error
+}
+
+// IsZeroer is implemented by types that say when omitempty should omit them
+type IsZeroer interface {
+	IsZero() bool
+}
+
+// TypeError reports values that could not be decoded into their targets.
+// Decoding continues past them, so the rest of the value is still filled.
+type TypeError struct {
+	Errors []string
+}
+
+func (e *TypeError) Error() string {
+	return "yaml: unmarshal errors:\n  " + strings.Join(e.Errors, "\n  ")
+}
+
+// Scalar resolution
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?(0b[01_]+|0o[0-7_]+|0x[0-9a-fA-F_]+|[0-9][0-9_]*)$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9][0-9_]*(\.[0-9_]*)?)([eE][-+]?[0-9]+)?$`)
+	timePattern  = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}([Tt ]|$)`)
+)
+
+// timeFormats are the timestamp layouts YAML allows
+var timeFormats = []string{
+	"2006-1-2T15:4:5.999999999Z07:00",
+	"2006-1-2t15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999",
+	"2006-1-2",
+}
+
+// parseTimestamp parses the YAML timestamp forms
+func parseTimestamp(s string) (time.Time, bool) {
+	if !timePattern.MatchString(s) {
+		return time.Time{}, false
+	}
+	for _, format := range timeFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// resolve returns a scalar's tag and its value as a Go value: nil, bool,
+// int, int64, uint64, float64, string, time.Time or []byte
+func resolve(n *Node) (string, interface{}) {
+	tag := n.Tag
+	if strings.HasPrefix(tag, "tag:yaml.org,2002:") {
+		tag = "!!" + strings.TrimPrefix(tag, "tag:yaml.org,2002:")
+	}
+	value := n.Value
+	if tag == "" || tag == "!" {
+		if tag == "!" || n.Style&(DoubleQuotedStyle|SingleQuotedStyle|LiteralStyle|FoldedStyle) != 0 {
+			return strTag, value
+		}
+		return resolvePlain(value)
+	}
+
+	switch tag {
+	case strTag:
+		return tag, value
+	case binaryTag:
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return tag, nil
+		}
+		return tag, data
+	case nullTag, boolTag, intTag, floatTag, timestampTag:
+		resolved, v := resolvePlain(value)
+		if resolved == tag {
+			return tag, v
+		}
+		if tag == floatTag && resolved == intTag {
+			f, _ := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64)
+			return tag, f
+		}
+		if tag == timestampTag {
+			if t, ok := parseTimestamp(value); ok {
+				return tag, t
+			}
+		}
+		return tag, errInvalidScalar
+	}
+	return tag, value
+}
+
+// errInvalidScalar marks a scalar whose explicit tag does not fit its text
+var errInvalidScalar = errors.New("invalid scalar")
+
+// resolvePlain resolves an untagged plain scalar with the core schema
+func resolvePlain(value string) (string, interface{}) {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return nullTag, nil
+	case "true", "True", "TRUE":
+		return boolTag, true
+	case "false", "False", "FALSE":
+		return boolTag, false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return floatTag, math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return floatTag, math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return floatTag, math.NaN()
+	case "<<":
+		return mergeTag, value
+	}
+
+	if intPattern.MatchString(value) {
+		plain := strings.ReplaceAll(value, "_", "")
+		if i, err := strconv.ParseInt(plain, 0, 64); err == nil {
+			if i == int64(int(i)) {
+				return intTag, int(i)
+			}
+			return intTag, i
+		}
+		if u, err := strconv.ParseUint(strings.TrimPrefix(plain, "+"), 0, 64); err == nil {
+			return intTag, u
+		}
+	}
+	if floatPattern.MatchString(value) {
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+			return floatTag, f
+		}
+	}
+	if t, ok := parseTimestamp(value); ok {
+		return timestampTag, t
+	}
+	return strTag, value
+}
+
+// isOldBool reports whether s is a YAML 1.1 boolean such as "yes", which
+// still decodes into bool targets and is quoted when encoded
+func isOldBool(s string) (value, ok bool) {
+	switch s {
+	case "y", "Y", "yes", "Yes", "YES", "on", "On", "ON":
+		return true, true
+	case "n", "N", "no", "No", "NO", "off", "Off", "OFF":
+		return false, true
+	}
+	return false, false
+}
+
+// Parsing
+
+// parser reads the block and flow styles of YAML a line at a time, keeping
+// the row and column of the next unread character
+type parser struct {
+	lines   []string
+	row     int
+	col     int
+	anchors map[string]*Node
+}
+
+// syntaxError is raised inside the parser and returned by parse
+type syntaxError struct {
+	line int
+	msg  string
+}
+
+func (e *syntaxError) Error() string {
+	return fmt.Sprintf("yaml: line %d: %s", e.line, e.msg)
+}
+
+// fail aborts parsing with a syntax error at the current line
+func (p *parser) fail(format string, args ...interface{}) {
+	line := p.row + 1
+	if line > len(p.lines) {
+		line = len(p.lines)
+	}
+	panic(&syntaxError{line: line, msg: fmt.Sprintf(format, args...)})
+}
+
+// parse splits data into documents and parses each one
+func parse(data []byte) (docs []*Node, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			serr, ok := r.(*syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = serr
+		}
+	}()
+
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	p := &parser{lines: strings.Split(text, "\n")}
+	for {
+		doc, ok := p.document()
+		if !ok {
+			return docs, nil
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// document parses the next document, reporting false at the end of input
+func (p *parser) document() (*Node, bool) {
+	p.anchors = make(map[string]*Node)
+	explicit := false
+	for {
+		p.skipBlank()
+		if p.row >= len(p.lines) {
+			if explicit {
+				return &Node{Kind: DocumentNode, Line: p.row, Column: 1}, true
+			}
+			return nil, false
+		}
+		line := p.lines[p.row]
+		if strings.HasPrefix(line, "%") {
+			p.row++
+			continue
+		}
+		if isDocEnd(line) {
+			p.row++
+			if explicit {
+				return &Node{Kind: DocumentNode, Line: p.row, Column: 1}, true
+			}
+			continue
+		}
+		if isDocStart(line) {
+			if explicit {
+				return &Node{Kind: DocumentNode, Line: p.row, Column: 1}, true
+			}
+			explicit = true
+			if isBlank(line[3:]) {
+				p.row++
+				continue
+			}
+			p.lines[p.row] = "   " + line[3:]
+		}
+		break
+	}
+
+	doc := &Node{Kind: DocumentNode, Line: p.row + 1, Column: 1}
+	doc.Content = []*Node{p.blockNode(-1)}
+	p.skipBlank()
+	if p.row < len(p.lines) {
+		line := p.lines[p.row]
+		switch {
+		case isDocStart(line):
+		case isDocEnd(line):
+			p.row++
+		default:
+			p.fail("did not find expected <document start>")
+		}
+	}
+	return doc, true
+}
+
+// isDocStart reports whether line is a "---" marker
+func isDocStart(line string) bool {
+	return line == "---" || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "---\t")
+}
+
+// isDocEnd reports whether line is a "..." marker
+func isDocEnd(line string) bool {
+	return line == "..." || strings.HasPrefix(line, "... ") || strings.HasPrefix(line, "...\t")
+}
+
+// isBlank reports whether text is empty, whitespace or a comment
+func isBlank(text string) bool {
+	text = strings.TrimLeft(text, " \t")
+	return text == "" || text[0] == '#'
+}
+
+// indentOf counts the spaces that start line
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isSeqEntry reports whether text starts a block sequence entry
+func isSeqEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// skipBlank moves to the first column of the next line with content
+func (p *parser) skipBlank() {
+	for p.row < len(p.lines) && isBlank(p.lines[p.row]) {
+		p.row++
+	}
+	p.col = 0
+	if p.row < len(p.lines) {
+		line := p.lines[p.row]
+		p.col = indentOf(line)
+		if line[p.col] == '\t' {
+			p.fail("found a tab character that violates indentation")
+		}
+	}
+}
+
+// atDocMarker reports whether the current line starts or ends a document
+func (p *parser) atDocMarker() bool {
+	line := p.lines[p.row]
+	return isDocStart(line) || isDocEnd(line)
+}
+
+// rest returns the unread part of the current line
+func (p *parser) rest() string {
+	if p.row >= len(p.lines) || p.col >= len(p.lines[p.row]) {
+		return ""
+	}
+	return p.lines[p.row][p.col:]
+}
+
+// peek returns the next character on the current line, or 0
+func (p *parser) peek() byte {
+	if rest := p.rest(); rest != "" {
+		return rest[0]
+	}
+	return 0
+}
+
+// skipSpaces moves past spaces and tabs on the current line
+func (p *parser) skipSpaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.col++
+	}
+}
+
+// endOfLine checks that only a comment follows and moves to the next line
+func (p *parser) endOfLine() {
+	p.skipSpaces()
+	if rest := p.rest(); rest != "" && rest[0] != '#' {
+		if rest[0] == ':' {
+			p.fail("mapping values are not allowed in this context")
+		}
+		p.fail("did not find expected key")
+	}
+	p.row++
+	p.col = 0
+}
+
+// scalarNode starts a scalar at the current position
+func (p *parser) scalarNode(style Style) *Node {
+	return &Node{Kind: ScalarNode, Style: style, Line: p.row + 1, Column: p.col + 1}
+}
+
+// blockNode parses the node starting on the next line with content, which
+// must be indented more than parent; a missing node is an empty scalar
+func (p *parser) blockNode(parent int) *Node {
+	p.skipBlank()
+	if p.row >= len(p.lines) || p.atDocMarker() || p.col <= parent {
+		return p.scalarNode(0)
+	}
+	return p.blockValue(parent, p.col)
+}
+
+// nested parses the value of a mapping entry whose key ended its line;
+// sequences may sit at the key's own indent
+func (p *parser) nested(indent int) *Node {
+	p.skipBlank()
+	if p.row < len(p.lines) && !p.atDocMarker() && p.col == indent && isSeqEntry(p.rest()) {
+		return p.sequence(indent)
+	}
+	return p.blockNode(indent)
+}
+
+// blockValue parses the block node at the current position, indent being
+// the column its entries line up on
+func (p *parser) blockValue(parent, indent int) *Node {
+	if isSeqEntry(p.rest()) {
+		return p.sequence(indent)
+	}
+	start := p.col
+	anchor, tag := p.properties()
+	if (anchor != "" || tag != "") && mappingColon(p.rest()) >= 0 {
+		return p.withProperties(p.mapping(indent), anchor, tag)
+	}
+	p.col = start
+	if mappingColon(p.rest()) >= 0 {
+		return p.mapping(indent)
+	}
+	return p.inline(parent, false)
+}
+
+// withProperties applies an anchor and tag to node
+func (p *parser) withProperties(node *Node, anchor, tag string) *Node {
+	if tag != "" {
+		node.Tag = tag
+		node.Style |= TaggedStyle
+	}
+	if anchor != "" {
+		node.Anchor = anchor
+		p.anchors[anchor] = node
+	}
+	return node
+}
+
+// properties reads an optional anchor and tag
+func (p *parser) properties() (anchor, tag string) {
+	for {
+		p.skipSpaces()
+		switch p.peek() {
+		case '&':
+			p.col++
+			anchor = p.name()
+		case '!':
+			tag = normalizeTag(p.name())
+		default:
+			return anchor, tag
+		}
+	}
+}
+
+// name reads an anchor, alias or tag name
+func (p *parser) name() string {
+	rest := p.rest()
+	end := strings.IndexAny(rest, " \t,[]{}")
+	if end < 0 {
+		end = len(rest)
+	}
+	p.col += end
+	if end == 0 {
+		p.fail("did not find expected alphabetic or numeric character")
+	}
+	return rest[:end]
+}
+
+// normalizeTag shortens tags in the yaml.org namespace to the !! form
+func normalizeTag(tag string) string {
+	if strings.HasPrefix(tag, "!<") && strings.HasSuffix(tag, ">") {
+		tag = tag[2 : len(tag)-1]
+	}
+	if strings.HasPrefix(tag, "tag:yaml.org,2002:") {
+		return "!!" + strings.TrimPrefix(tag, "tag:yaml.org,2002:")
+	}
+	return tag
+}
+
+// mappingColon returns the index of the ':' ending a mapping key at the
+// start of text, or -1 if text does not start a mapping entry
+func mappingColon(text string) int {
+	if text == "" {
+		return -1
+	}
+	i := 0
+	switch text[0] {
+	case '"', '\'':
+		i = quotedEnd(text)
+		if i < 0 {
+			return -1
+		}
+		for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+			i++
+		}
+		if i < len(text) && text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			return i
+		}
+		return -1
+	case '[', '{', '#', '*', '|', '>', '&', '!', '%', '@', '`':
+		return -1
+	case '-', '?':
+		if len(text) == 1 || text[1] == ' ' || text[1] == '\t' {
+			return -1
+		}
+	}
+	for ; i < len(text); i++ {
+		switch text[i] {
+		case '#':
+			if i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+				return -1
+			}
+		case ':':
+			if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t' {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// quotedEnd returns the index after the quote closing the quoted scalar
+// at the start of text, or -1 if it does not close on this line
+func quotedEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// sequence parses block sequence entries at indent
+func (p *parser) sequence(indent int) *Node {
+	node := &Node{Kind: SequenceNode, Line: p.row + 1, Column: indent + 1}
+	for {
+		p.skipBlank()
+		if p.row >= len(p.lines) || p.atDocMarker() || p.col < indent {
+			return node
+		}
+		if p.col > indent {
+			p.fail("bad indentation of a sequence entry")
+		}
+		if !isSeqEntry(p.rest()) {
+			return node
+		}
+
+		line := p.lines[p.row]
+		var item *Node
+		if isBlank(line[indent+1:]) {
+			p.row++
+			item = p.blockNode(indent)
+		} else {
+			// Blank out the dash so the entry's own block lines up
+			content := indent + 1 + len(line[indent+1:]) - len(strings.TrimLeft(line[indent+1:], " \t"))
+			p.lines[p.row] = strings.Repeat(" ", content) + line[content:]
+			p.col = content
+			item = p.blockValue(indent, content)
+		}
+		node.Content = append(node.Content, item)
+	}
+}
+
+// mapping parses block mapping entries at indent, the first of which
+// starts at the current position
+func (p *parser) mapping(indent int) *Node {
+	node := &Node{Kind: MappingNode, Line: p.row + 1, Column: p.col + 1}
+	for first := true; ; first = false {
+		if !first {
+			p.skipBlank()
+			if p.row >= len(p.lines) || p.atDocMarker() || p.col < indent {
+				return node
+			}
+			if p.col > indent {
+				p.fail("bad indentation of a mapping entry")
+			}
+			if mappingColon(p.rest()) < 0 {
+				if isSeqEntry(p.rest()) {
+					return node
+				}
+				p.fail("could not find expected ':'")
+			}
+		}
+
+		key := p.key()
+		var value *Node
+		if isBlank(p.rest()) {
+			p.row++
+			value = p.nested(indent)
+		} else {
+			value = p.inline(indent, true)
+		}
+		node.Content = append(node.Content, key, value)
+	}
+}
+
+// key parses a mapping key and the ':' after it
+func (p *parser) key() *Node {
+	var key *Node
+	switch p.peek() {
+	case '"':
+		key = p.doubleQuoted()
+	case '\'':
+		key = p.singleQuoted()
+	default:
+		key = p.scalarNode(0)
+		colon := mappingColon(p.rest())
+		key.Value = strings.TrimRight(p.rest()[:colon], " \t")
+		p.col += colon
+	}
+	p.skipSpaces()
+	p.col++ // the ':'
+	return key
+}
+
+// inline parses a node that starts on the current line: an alias, a flow
+// collection or a scalar, with optional properties
+func (p *parser) inline(parent int, compact bool) *Node {
+	anchor, tag := p.properties()
+	if (anchor != "" || tag != "") && isBlank(p.rest()) {
+		p.row++
+		var node *Node
+		if compact {
+			node = p.nested(parent)
+		} else {
+			node = p.blockNode(parent)
+		}
+		return p.withProperties(node, anchor, tag)
+	}
+
+	var node *Node
+	switch p.peek() {
+	case '*':
+		node = p.scalarNode(0)
+		node.Kind = AliasNode
+		p.col++
+		node.Value = p.name()
+		node.Alias = p.anchors[node.Value]
+		if node.Alias == nil {
+			p.fail("unknown anchor '%s' referenced", node.Value)
+		}
+		if anchor != "" || tag != "" {
+			p.fail("an alias cannot have properties")
+		}
+	case '[', '{':
+		node = p.flowNode()
+	case '"':
+		node = p.doubleQuoted()
+	case '\'':
+		node = p.singleQuoted()
+	case '|', '>':
+		return p.withProperties(p.blockScalar(parent), anchor, tag)
+	default:
+		node = p.plain(parent)
+	}
+	p.endOfLine()
+	return p.withProperties(node, anchor, tag)
+}
+
+// plain parses a plain scalar in block context, including continuation
+// lines indented more than parent
+func (p *parser) plain(parent int) *Node {
+	node := p.scalarNode(0)
+	rest := p.rest()
+	end := len(rest)
+	if i := strings.Index(rest, " #"); i >= 0 {
+		end = i
+	}
+	if i := strings.Index(rest, "\t#"); i >= 0 && i < end {
+		end = i
+	}
+	if i := mappingColon(" " + rest); i > 0 && i-1 < end {
+		p.fail("mapping values are not allowed in this context")
+	}
+	value := strings.TrimRight(rest[:end], " \t")
+	p.col += end
+	if end < len(rest) {
+		node.Value = value
+		return node
+	}
+
+	// Fold continuation lines, keeping a newline for each blank line
+	breaks := 0
+	for next := p.row + 1; next < len(p.lines); next++ {
+		line := p.lines[next]
+		if strings.TrimSpace(line) == "" {
+			breaks++
+			continue
+		}
+		if indentOf(line) <= parent || isDocStart(line) || isDocEnd(line) || isBlank(line) {
+			break
+		}
+		text := strings.TrimSpace(line)
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = strings.TrimRight(text[:i], " \t")
+		}
+		if mappingColon(text) >= 0 {
+			p.row = next
+			p.fail("mapping values are not allowed in this context")
+		}
+		if breaks == 0 {
+			value += " " + text
+		} else {
+			value += strings.Repeat("\n", breaks) + text
+		}
+		breaks = 0
+		p.row = next
+		p.col = len(line)
+		if strings.Contains(line, " #") {
+			break
+		}
+	}
+	node.Value = value
+	return node
+}
+
+// doubleQuoted parses a double-quoted scalar, which may span lines
+func (p *parser) doubleQuoted() *Node {
+	node := p.scalarNode(DoubleQuotedStyle)
+	start := p.row
+	var buf []byte
+	p.col++
+	for {
+		if p.row >= len(p.lines) {
+			p.row = start
+			p.fail("found unexpected end of stream")
+		}
+		line := p.lines[p.row]
+		escapedBreak := false
+		for p.col < len(line) && !escapedBreak {
+			c := line[p.col]
+			switch c {
+			case '"':
+				p.col++
+				node.Value = string(buf)
+				return node
+			case '\\':
+				if p.col+1 == len(line) {
+					escapedBreak = true
+					break
+				}
+				buf = p.escape(buf, line)
+			default:
+				buf = append(buf, c)
+				p.col++
+			}
+		}
+
+		p.row++
+		if !escapedBreak {
+			buf = bytes.TrimRight(buf, " \t")
+			breaks := 0
+			for p.row < len(p.lines) && strings.TrimSpace(p.lines[p.row]) == "" {
+				breaks++
+				p.row++
+			}
+			if breaks == 0 {
+				buf = append(buf, ' ')
+			} else {
+				buf = append(buf, strings.Repeat("\n", breaks)...)
+			}
+		}
+		if p.row < len(p.lines) {
+			next := p.lines[p.row]
+			p.col = len(next) - len(strings.TrimLeft(next, " \t"))
+		}
+	}
+}
+
+// escapes maps single-character escapes to what they stand for
+var escapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v",
+	'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// escape decodes the escape sequence at the current position
+func (p *parser) escape(buf []byte, line string) []byte {
+	c := line[p.col+1]
+	if s, ok := escapes[c]; ok {
+		p.col += 2
+		return append(buf, s...)
+	}
+	size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if size == 0 || p.col+2+size > len(line) {
+		p.fail("found unknown escape character while parsing a quoted scalar")
+	}
+	code, err := strconv.ParseUint(line[p.col+2:p.col+2+size], 16, 32)
+	if err != nil {
+		p.fail("did not find expected hexdecimal number")
+	}
+	p.col += 2 + size
+	if c == 'x' {
+		return append(buf, byte(code))
+	}
+	return append(buf, string(rune(code))...)
+}
+
+// singleQuoted parses a single-quoted scalar, which may span lines
+func (p *parser) singleQuoted() *Node {
+	node := p.scalarNode(SingleQuotedStyle)
+	start := p.row
+	var buf []byte
+	p.col++
+	for {
+		if p.row >= len(p.lines) {
+			p.row = start
+			p.fail("found unexpected end of stream")
+		}
+		line := p.lines[p.row]
+		for p.col < len(line) {
+			if line[p.col] == '\'' {
+				if p.col+1 < len(line) && line[p.col+1] == '\'' {
+					buf = append(buf, '\'')
+					p.col += 2
+					continue
+				}
+				p.col++
+				node.Value = string(buf)
+				return node
+			}
+			buf = append(buf, line[p.col])
+			p.col++
+		}
+
+		buf = bytes.TrimRight(buf, " \t")
+		p.row++
+		breaks := 0
+		for p.row < len(p.lines) && strings.TrimSpace(p.lines[p.row]) == "" {
+			breaks++
+			p.row++
+		}
+		if breaks == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, strings.Repeat("\n", breaks)...)
+		}
+		if p.row < len(p.lines) {
+			next := p.lines[p.row]
+			p.col = len(next) - len(strings.TrimLeft(next, " \t"))
+		}
+	}
+}
+
+// blockScalar parses a literal (|) or folded (>) scalar whose lines are
+// indented more than parent
+func (p *parser) blockScalar(parent int) *Node {
+	literal := p.peek() == '|'
+	node := p.scalarNode(FoldedStyle)
+	if literal {
+		node.Style = LiteralStyle
+	}
+	p.col++
+
+	chomp, explicit := byte(0), 0
+	for c := p.peek(); c == '+' || c == '-' || (c >= '1' && c <= '9'); c = p.peek() {
+		if c >= '1' && c <= '9' {
+			explicit = int(c - '0')
+		} else {
+			chomp = c
+		}
+		p.col++
+	}
+	p.endOfLine()
+
+	indent := 0
+	if explicit > 0 {
+		if parent > 0 {
+			indent = parent
+		}
+		indent += explicit
+	} else {
+		for next := p.row; next < len(p.lines); next++ {
+			if strings.TrimSpace(p.lines[next]) != "" {
+				indent = indentOf(p.lines[next])
+				break
+			}
+		}
+		if indent <= parent {
+			indent = parent + 1
+		}
+	}
+
+	var lines []string
+	for ; p.row < len(p.lines); p.row++ {
+		line := p.lines[p.row]
+		if strings.TrimSpace(line) == "" {
+			if len(line) > indent {
+				lines = append(lines, line[indent:])
+			} else {
+				lines = append(lines, "")
+			}
+			continue
+		}
+		if indentOf(line) < indent || (indent == 0 && (isDocStart(line) || isDocEnd(line))) {
+			break
+		}
+		lines = append(lines, line[indent:])
+	}
+	p.col = 0
+
+	body := len(lines)
+	for body > 0 && lines[body-1] == "" {
+		body--
+	}
+	trailing := len(lines) - body
+	lines = lines[:body]
+	// Blank lines after the scalar belong to whatever follows it
+	p.row -= trailing
+
+	var text string
+	if literal {
+		text = strings.Join(lines, "\n")
+	} else {
+		text = fold(lines)
+	}
+	switch {
+	case chomp == '-' || (body == 0 && chomp != '+'):
+	case chomp == '+':
+		text += strings.Repeat("\n", trailing+1)
+		if body == 0 {
+			text = strings.Repeat("\n", trailing)
+		}
+	default:
+		text += "\n"
+	}
+	node.Value = text
+	return node
+}
+
+// fold joins the lines of a folded scalar: breaks between lines of text
+// become spaces, blank lines become newlines, and more-indented lines
+// keep their breaks
+func fold(lines []string) string {
+	var b strings.Builder
+	breaks := 0
+	prevNormal := false
+	for i, line := range lines {
+		if line == "" {
+			breaks++
+			continue
+		}
+		normal := line[0] != ' ' && line[0] != '\t'
+		switch {
+		case i == breaks:
+			b.WriteString(strings.Repeat("\n", breaks))
+		case prevNormal && normal:
+			if breaks == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(strings.Repeat("\n", breaks))
+			}
+		default:
+			b.WriteString(strings.Repeat("\n", breaks+1))
+		}
+		b.WriteString(line)
+		breaks = 0
+		prevNormal = normal
+	}
+	return b.String()
+}
+
+// flowSkip moves past whitespace, line breaks and comments inside a flow
+// collection
+func (p *parser) flowSkip(closing byte) {
+	for {
+		if p.row >= len(p.lines) {
+			p.row = len(p.lines) - 1
+			p.fail("did not find expected ',' or '%c'", closing)
+		}
+		p.skipSpaces()
+		if rest := p.rest(); rest != "" && rest[0] != '#' {
+			return
+		}
+		p.row++
+		p.col = 0
+	}
+}
+
+// flowNode parses a node inside or at the start of a flow collection
+func (p *parser) flowNode() *Node {
+	anchor, tag := p.properties()
+	var node *Node
+	switch p.peek() {
+	case '[':
+		node = p.flowSequence()
+	case '{':
+		node = p.flowMapping()
+	case '"':
+		node = p.doubleQuoted()
+	case '\'':
+		node = p.singleQuoted()
+	case '*':
+		node = p.scalarNode(0)
+		node.Kind = AliasNode
+		p.col++
+		node.Value = p.name()
+		node.Alias = p.anchors[node.Value]
+		if node.Alias == nil {
+			p.fail("unknown anchor '%s' referenced", node.Value)
+		}
+	default:
+		node = p.scalarNode(0)
+		rest := p.rest()
+		end := 0
+		for end < len(rest) {
+			c := rest[end]
+			if strings.IndexByte(",[]{}", c) >= 0 {
+				break
+			}
+			if c == ':' && (end+1 == len(rest) || strings.IndexByte(" \t,[]{}", rest[end+1]) >= 0) {
+				break
+			}
+			if c == '#' && end > 0 && (rest[end-1] == ' ' || rest[end-1] == '\t') {
+				break
+			}
+			end++
+		}
+		node.Value = strings.TrimSpace(rest[:end])
+		p.col += end
+	}
+	return p.withProperties(node, anchor, tag)
+}
+
+// flowSequence parses [a, b, c]
+func (p *parser) flowSequence() *Node {
+	node := &Node{Kind: SequenceNode, Style: FlowStyle, Line: p.row + 1, Column: p.col + 1}
+	p.col++
+	for {
+		p.flowSkip(']')
+		if p.peek() == ']' {
+			p.col++
+			return node
+		}
+		item := p.flowNode()
+		p.flowSkip(']')
+		if p.peek() == ':' {
+			// A single pair such as [a: 1]
+			p.col++
+			p.flowSkip(']')
+			pair := &Node{Kind: MappingNode, Style: FlowStyle, Line: item.Line, Column: item.Column}
+			value := p.scalarNode(0)
+			if c := p.peek(); c != ',' && c != ']' {
+				value = p.flowNode()
+			}
+			pair.Content = []*Node{item, value}
+			item = pair
+			p.flowSkip(']')
+		}
+		node.Content = append(node.Content, item)
+		switch p.peek() {
+		case ',':
+			p.col++
+		case ']':
+		default:
+			p.fail("did not find expected ',' or ']'")
+		}
+	}
+}
+
+// flowMapping parses {a: 1, b: 2}
+func (p *parser) flowMapping() *Node {
+	node := &Node{Kind: MappingNode, Style: FlowStyle, Line: p.row + 1, Column: p.col + 1}
+	p.col++
+	for {
+		p.flowSkip('}')
+		if p.peek() == '}' {
+			p.col++
+			return node
+		}
+		key := p.flowNode()
+		p.flowSkip('}')
+		value := p.scalarNode(0)
+		if p.peek() == ':' {
+			p.col++
+			p.flowSkip('}')
+			if c := p.peek(); c != ',' && c != '}' {
+				value = p.flowNode()
+				p.flowSkip('}')
+			}
+		}
+		node.Content = append(node.Content, key, value)
+		switch p.peek() {
+		case ',':
+			p.col++
+		case '}':
+		default:
+			p.fail("did not find expected ',' or '}'")
+		}
+	}
+}
+
+// Decoding
+
+var (
+	nodeType       = reflect.TypeOf(Node{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	timeType       = reflect.TypeOf(time.Time{})
+	stringMapType  = reflect.TypeOf(map[string]interface{}{})
+	generalMapType = reflect.TypeOf(map[interface{}]interface{}{})
+)
+
+// decoder turns nodes into Go values, collecting type errors as it goes
+type decoder struct {
+	errors      []string
+	knownFields bool
+	// aliases holds the aliases being expanded, to catch cycles
+	aliases map[*Node]bool
+}
+
+func newDecoder() *decoder {
+	return &decoder{aliases: make(map[*Node]bool)}
+}
+
+// finish combines a fatal error with the collected type errors
+func (d *decoder) finish(err error) error {
+	if err != nil {
+		return err
+	}
+	if len(d.errors) > 0 {
+		return &TypeError{Errors: d.errors}
+	}
+	return nil
+}
+
+// terror records a node that does not fit out
+func (d *decoder) terror(n *Node, tag string, out reflect.Value) {
+	value := ""
+	if n.Kind == ScalarNode {
+		value = n.Value
+		if len(value) > 10 {
+			value = value[:7] + "..."
+		}
+		value = " `" + value + "`"
+	}
+	d.errors = append(d.errors, fmt.Sprintf("line %d: cannot unmarshal %s%s into %s", n.Line, tag, value, out.Type()))
+}
+
+// absorb adds the errors of a TypeError from an Unmarshaler to the
+// decoder's own and passes other errors on
+func (d *decoder) absorb(err error) error {
+	var terr *TypeError
+	if errors.As(err, &terr) {
+		d.errors = append(d.errors, terr.Errors...)
+		return nil
+	}
+	return err
+}
+
+// isNull reports whether n is a null scalar
+func isNull(n *Node) bool {
+	if n.Kind == AliasNode && n.Alias != nil {
+		return isNull(n.Alias)
+	}
+	return n.Kind == ScalarNode && n.ShortTag() == nullTag
+}
+
+// unmarshal decodes n into out
+func (d *decoder) unmarshal(n *Node, out reflect.Value) error {
+	if out.Type() == nodeType {
+		out.Set(reflect.ValueOf(*n))
+		return nil
+	}
+	switch n.Kind {
+	case DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return d.unmarshal(n.Content[0], out)
+	case AliasNode:
+		if d.aliases[n] {
+			return fmt.Errorf("yaml: anchor '%s' value contains itself", n.Value)
+		}
+		d.aliases[n] = true
+		defer delete(d.aliases, n)
+		return d.unmarshal(n.Alias, out)
+	}
+
+	if called, err := d.callUnmarshaler(n, out); called {
+		return err
+	}
+
+	switch out.Kind() {
+	case reflect.Ptr:
+		if isNull(n) {
+			out.Set(reflect.Zero(out.Type()))
+			return nil
+		}
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+		return d.unmarshal(n, out.Elem())
+	case reflect.Interface:
+		if isNull(n) {
+			out.Set(reflect.Zero(out.Type()))
+			return nil
+		}
+		if out.NumMethod() > 0 {
+			d.terror(n, n.ShortTag(), out)
+			return nil
+		}
+	}
+
+	switch n.Kind {
+	case ScalarNode:
+		return d.scalar(n, out)
+	case MappingNode:
+		return d.mapping(n, out)
+	case SequenceNode:
+		return d.sequence(n, out)
+	}
+	return fmt.Errorf("yaml: line %d: cannot decode node of kind %d", n.Line, n.Kind)
+}
+
+// callUnmarshaler lets out decode itself if its pointer implements
+// Unmarshaler or the yaml.v2 form of it
+func (d *decoder) callUnmarshaler(n *Node, out reflect.Value) (bool, error) {
+	if out.Kind() == reflect.Ptr || !out.CanAddr() {
+		return false, nil
+	}
+	switch u := out.Addr().Interface().(type) {
+	case Unmarshaler:
+		return true, d.absorb(u.UnmarshalYAML(n))
+	case obsoleteUnmarshaler:
+		return true, d.absorb(u.UnmarshalYAML(func(v interface{}) error {
+			target := reflect.ValueOf(v)
+			if target.Kind() != reflect.Ptr || target.IsNil() {
+				return fmt.Errorf("yaml: unmarshal requires a non-nil pointer, got %T", v)
+			}
+			before := len(d.errors)
+			if err := d.unmarshal(n, target.Elem()); err != nil {
+				return err
+			}
+			if len(d.errors) > before {
+				terr := &TypeError{Errors: append([]string(nil), d.errors[before:]...)}
+				d.errors = d.errors[:before]
+				return terr
+			}
+			return nil
+		}))
+	}
+	return false, nil
+}
+
+// scalar decodes a scalar into out
+func (d *decoder) scalar(n *Node, out reflect.Value) error {
+	tag, resolved := resolve(n)
+	if resolved == errInvalidScalar {
+		d.errors = append(d.errors, fmt.Sprintf("line %d: cannot decode %s `%s` as a %s", n.Line, strTag, n.Value, tag))
+		return nil
+	}
+	if tag == nullTag {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	if resolved != nil && reflect.TypeOf(resolved) == out.Type() && out.Kind() != reflect.Interface {
+		out.Set(reflect.ValueOf(resolved))
+		return nil
+	}
+	if out.CanAddr() {
+		if u, ok := out.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			text := []byte(n.Value)
+			if data, isBytes := resolved.([]byte); isBytes {
+				text = data
+			}
+			return u.UnmarshalText(text)
+		}
+	}
+
+	switch out.Kind() {
+	case reflect.Interface:
+		switch v := resolved.(type) {
+		case time.Time:
+			// Like yaml.v3, only explicit timestamps become time.Time
+			if n.Tag == "" {
+				out.Set(reflect.ValueOf(n.Value))
+				return nil
+			}
+		case []byte:
+			out.Set(reflect.ValueOf(string(v)))
+			return nil
+		}
+		out.Set(reflect.ValueOf(resolved))
+		return nil
+	case reflect.String:
+		if data, ok := resolved.([]byte); ok {
+			out.SetString(string(data))
+		} else {
+			out.SetString(n.Value)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if out.Type() == durationType && tag == strTag {
+			if duration, err := time.ParseDuration(n.Value); err == nil {
+				out.SetInt(int64(duration))
+				return nil
+			}
+		}
+		var i int64
+		ok := true
+		switch v := resolved.(type) {
+		case int:
+			i = int64(v)
+		case int64:
+			i = v
+		case uint64:
+			i, ok = int64(v), v <= math.MaxInt64
+		case float64:
+			i, ok = int64(v), v == math.Trunc(v) && v >= math.MinInt64 && v <= math.MaxInt64
+		default:
+			ok = false
+		}
+		if ok && !out.OverflowInt(i) {
+			out.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		ok := true
+		switch v := resolved.(type) {
+		case int:
+			u, ok = uint64(v), v >= 0
+		case int64:
+			u, ok = uint64(v), v >= 0
+		case uint64:
+			u = v
+		case float64:
+			u, ok = uint64(v), v == math.Trunc(v) && v >= 0 && v <= math.MaxUint64
+		default:
+			ok = false
+		}
+		if ok && !out.OverflowUint(u) {
+			out.SetUint(u)
+			return nil
+		}
+	case reflect.Bool:
+		switch v := resolved.(type) {
+		case bool:
+			out.SetBool(v)
+			return nil
+		case string:
+			// YAML 1.1 booleans still fill bool fields
+			if b, ok := isOldBool(v); ok && n.Style&(DoubleQuotedStyle|SingleQuotedStyle) == 0 {
+				out.SetBool(b)
+				return nil
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		ok := true
+		switch v := resolved.(type) {
+		case int:
+			f = float64(v)
+		case int64:
+			f = float64(v)
+		case uint64:
+			f = float64(v)
+		case float64:
+			f = v
+		default:
+			ok = false
+		}
+		if ok && (out.Kind() == reflect.Float64 || !out.OverflowFloat(f)) {
+			out.SetFloat(f)
+			return nil
+		}
+	case reflect.Slice:
+		if out.Type().Elem().Kind() == reflect.Uint8 {
+			data, ok := resolved.([]byte)
+			if !ok {
+				data = []byte(n.Value)
+			}
+			out.SetBytes(data)
+			return nil
+		}
+	}
+	d.terror(n, tag, out)
+	return nil
+}
+
+// isStringMap reports whether every key of a mapping is a string
+func isStringMap(n *Node) bool {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch n.Content[i].ShortTag() {
+		case strTag, mergeTag:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isMerge reports whether key is the "<<" merge key
+func isMerge(key *Node) bool {
+	return key.Kind == ScalarNode && key.ShortTag() == mergeTag
+}
+
+// followAlias returns the node an alias refers to
+func followAlias(n *Node) *Node {
+	for n.Kind == AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// eachPair calls fn with each key and value of a mapping. Merged mappings
+// go first, so the mapping's own keys override theirs.
+func (d *decoder) eachPair(n *Node, fn func(key, value *Node) error) error {
+	seen := make(map[string]int)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i]
+		if key.Kind != ScalarNode || isMerge(key) {
+			continue
+		}
+		if line, dup := seen[key.Value]; dup {
+			return fmt.Errorf("yaml: line %d: mapping key %q already defined at line %d", key.Line, key.Value, line)
+		}
+		seen[key.Value] = key.Line
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if isMerge(n.Content[i]) {
+			if err := d.merge(n.Content[i+1], fn); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if !isMerge(n.Content[i]) {
+			if err := fn(n.Content[i], n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge applies the value of a "<<" key: a mapping or a sequence of them,
+// where earlier mappings win
+func (d *decoder) merge(value *Node, fn func(key, value *Node) error) error {
+	target := followAlias(value)
+	switch target.Kind {
+	case MappingNode:
+		return d.eachPair(target, fn)
+	case SequenceNode:
+		valid := true
+		for _, item := range target.Content {
+			valid = valid && followAlias(item).Kind == MappingNode
+		}
+		if valid {
+			for i := len(target.Content) - 1; i >= 0; i-- {
+				if err := d.eachPair(followAlias(target.Content[i]), fn); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("yaml: line %d: map merge requires map or sequence of maps as the value", value.Line)
+}
+
+// mapping decodes a mapping into a map, struct or interface
+func (d *decoder) mapping(n *Node, out reflect.Value) error {
+	switch out.Kind() {
+	case reflect.Struct:
+		return d.mappingStruct(n, out)
+	case reflect.Interface:
+		mapType := stringMapType
+		if !isStringMap(n) {
+			mapType = generalMapType
+		}
+		m := reflect.MakeMap(mapType)
+		if err := d.mapping(n, m); err != nil {
+			return err
+		}
+		out.Set(m)
+		return nil
+	case reflect.Map:
+	default:
+		d.terror(n, mapTag, out)
+		return nil
+	}
+
+	if out.IsNil() {
+		out.Set(reflect.MakeMap(out.Type()))
+	}
+	keyType, elemType := out.Type().Key(), out.Type().Elem()
+	return d.eachPair(n, func(key, value *Node) error {
+		k := reflect.New(keyType).Elem()
+		if err := d.unmarshal(key, k); err != nil {
+			return err
+		}
+		if k.Kind() == reflect.Interface && k.Elem().IsValid() && !k.Elem().Type().Comparable() {
+			return fmt.Errorf("yaml: line %d: invalid map key: %#v", key.Line, k.Interface())
+		}
+		v := reflect.New(elemType).Elem()
+		if err := d.unmarshal(value, v); err != nil {
+			return err
+		}
+		out.SetMapIndex(k, v)
+		return nil
+	})
+}
+
+// mappingStruct decodes a mapping into the fields of a struct
+func (d *decoder) mappingStruct(n *Node, out reflect.Value) error {
+	info, err := getStructInfo(out.Type())
+	if err != nil {
+		return err
+	}
+	var inlineMap reflect.Value
+	if info.inlineMap >= 0 {
+		inlineMap = out.Field(info.inlineMap)
+		if inlineMap.IsNil() {
+			inlineMap.Set(reflect.MakeMap(inlineMap.Type()))
+		}
+	}
+
+	return d.eachPair(n, func(key, value *Node) error {
+		var name string
+		if err := d.unmarshal(key, reflect.ValueOf(&name).Elem()); err != nil {
+			return err
+		}
+		if field, ok := info.fields[name]; ok {
+			return d.unmarshal(value, fieldForWrite(out, field.index))
+		}
+		if inlineMap.IsValid() {
+			v := reflect.New(inlineMap.Type().Elem()).Elem()
+			if err := d.unmarshal(value, v); err != nil {
+				return err
+			}
+			inlineMap.SetMapIndex(reflect.ValueOf(name).Convert(inlineMap.Type().Key()), v)
+		} else if d.knownFields {
+			d.errors = append(d.errors, fmt.Sprintf("line %d: field %s not found in type %s", key.Line, name, out.Type()))
+		}
+		return nil
+	})
+}
+
+// sequence decodes a sequence into a slice, array or interface
+func (d *decoder) sequence(n *Node, out reflect.Value) error {
+	length := len(n.Content)
+	switch out.Kind() {
+	case reflect.Slice:
+		out.Set(reflect.MakeSlice(out.Type(), length, length))
+	case reflect.Array:
+		if length != out.Len() {
+			return fmt.Errorf("yaml: line %d: invalid array: want %d elements but got %d", n.Line, out.Len(), length)
+		}
+	case reflect.Interface:
+		items := reflect.New(reflect.TypeOf([]interface{}{})).Elem()
+		if err := d.sequence(n, items); err != nil {
+			return err
+		}
+		out.Set(items)
+		return nil
+	default:
+		d.terror(n, seqTag, out)
+		return nil
+	}
+	for i, item := range n.Content {
+		if err := d.unmarshal(item, out.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Struct fields
+
+// fieldInfo describes a struct field and how it is keyed
+type fieldInfo struct {
+	key       string
+	index     []int
+	omitEmpty bool
+	flow      bool
+}
+
+// structInfo describes the keyed fields of a struct type
+type structInfo struct {
+	fields map[string]fieldInfo
+	list   []fieldInfo
+	// inlineMap is the index of a ",inline" map field, or -1
+	inlineMap int
+}
+
+var structInfoCache sync.Map
+
+// getStructInfo reads the `yaml` tags of t. Fields are keyed by their tag
+// name or their lowercased Go name; ",inline" structs lend their fields to
+// the parent and an ",inline" map collects unknown keys.
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if cached, ok := structInfoCache.Load(t); ok {
+		return cached.(*structInfo), nil
+	}
+
+	info := &structInfo{fields: make(map[string]fieldInfo), inlineMap: -1}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // unexported
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "" && !strings.Contains(string(field.Tag), ":") {
+			tag = string(field.Tag)
+		}
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		f := fieldInfo{key: parts[0], index: []int{i}}
+		inline := false
+		for _, flag := range parts[1:] {
+			switch flag {
+			case "omitempty":
+				f.omitEmpty = true
+			case "flow":
+				f.flow = true
+			case "inline":
+				inline = true
+			default:
+				return nil, fmt.Errorf("yaml: unsupported flag %q in tag %q of type %s", flag, tag, t)
+			}
+		}
+
+		if inline {
+			fieldType := field.Type
+			switch {
+			case fieldType.Kind() == reflect.Map:
+				if info.inlineMap >= 0 {
+					return nil, fmt.Errorf("yaml: multiple ,inline maps in struct %s", t)
+				}
+				if fieldType.Key().Kind() != reflect.String {
+					return nil, fmt.Errorf("yaml: option ,inline needs a map with string keys in struct %s", t)
+				}
+				info.inlineMap = i
+			case fieldType.Kind() == reflect.Struct || (fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct):
+				if fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				inner, err := getStructInfo(fieldType)
+				if err != nil {
+					return nil, err
+				}
+				for _, innerField := range inner.list {
+					innerField.index = append([]int{i}, innerField.index...)
+					if err := info.add(t, innerField); err != nil {
+						return nil, err
+					}
+				}
+			default:
+				return nil, fmt.Errorf("yaml: option ,inline needs a struct value or map field in struct %s", t)
+			}
+			continue
+		}
+
+		if f.key == "" {
+			f.key = strings.ToLower(field.Name)
+		}
+		if err := info.add(t, f); err != nil {
+			return nil, err
+		}
+	}
+
+	cached, _ := structInfoCache.LoadOrStore(t, info)
+	return cached.(*structInfo), nil
+}
+
+// add registers a field, rejecting duplicate keys
+func (info *structInfo) add(t reflect.Type, f fieldInfo) error {
+	if _, dup := info.fields[f.key]; dup {
+		return fmt.Errorf("yaml: duplicated key '%s' in struct %s", f.key, t)
+	}
+	info.fields[f.key] = f
+	info.list = append(info.list, f)
+	return nil
+}
+
+// fieldForWrite returns the field at index, allocating inlined pointers
+func fieldForWrite(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldForRead returns the field at index, or false if an inlined pointer
+// on the way is nil
+func fieldForRead(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// Encoding
+
+// encodeValue builds the node for v
+func encodeValue(in reflect.Value) (*Node, error) {
+	if !in.IsValid() {
+		return &Node{Kind: ScalarNode, Tag: nullTag, Value: "null"}, nil
+	}
+	switch in.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if in.IsNil() {
+			return &Node{Kind: ScalarNode, Tag: nullTag, Value: "null"}, nil
+		}
+	}
+
+	switch value := in.Interface().(type) {
+	case Node:
+		return &value, nil
+	case *Node:
+		return value, nil
+	case Marshaler:
+		replacement, err := value.MarshalYAML()
+		if err != nil {
+			return nil, err
+		}
+		return encodeValue(reflect.ValueOf(replacement))
+	case time.Duration:
+		return &Node{Kind: ScalarNode, Tag: strTag, Value: value.String()}, nil
+	case time.Time:
+		return &Node{Kind: ScalarNode, Tag: timestampTag, Value: value.Format(time.RFC3339Nano)}, nil
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Kind: ScalarNode, Tag: strTag, Value: string(text)}, nil
+	}
+
+	switch in.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return encodeValue(in.Elem())
+	case reflect.Map:
+		return encodeMap(in)
+	case reflect.Struct:
+		return encodeStruct(in)
+	case reflect.Slice, reflect.Array:
+		if in.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, in.Len())
+			reflect.Copy(reflect.ValueOf(data), in)
+			if utf8.Valid(data) {
+				return &Node{Kind: ScalarNode, Tag: strTag, Value: string(data)}, nil
+			}
+			return &Node{Kind: ScalarNode, Tag: binaryTag, Value: base64.StdEncoding.EncodeToString(data)}, nil
+		}
+		node := &Node{Kind: SequenceNode, Tag: seqTag}
+		for i := 0; i < in.Len(); i++ {
+			item, err := encodeValue(in.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node, nil
+	case reflect.String:
+		return &Node{Kind: ScalarNode, Tag: strTag, Value: in.String()}, nil
+	case reflect.Bool:
+		return &Node{Kind: ScalarNode, Tag: boolTag, Value: strconv.FormatBool(in.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Node{Kind: ScalarNode, Tag: intTag, Value: strconv.FormatInt(in.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Node{Kind: ScalarNode, Tag: intTag, Value: strconv.FormatUint(in.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Node{Kind: ScalarNode, Tag: floatTag, Value: formatFloat(in.Float(), in.Type().Bits())}, nil
+	}
+	return nil, fmt.Errorf("yaml: cannot marshal type: %s", in.Type())
+}
+
+// formatFloat writes a float the way YAML spells it
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	case math.IsNaN(f):
+		return ".nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits)
+}
+
+// encodeMap builds a mapping with its keys in natural order
+func encodeMap(in reflect.Value) (*Node, error) {
+	keys := in.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	node := &Node{Kind: MappingNode, Tag: mapTag}
+	for _, k := range keys {
+		key, err := encodeValue(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := encodeValue(in.MapIndex(k))
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, key, value)
+	}
+	return node, nil
+}
+
+// keyLess orders map keys: numbers by value, strings with runs of digits
+// compared as numbers, then everything else by its printed form
+func keyLess(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Interface || a.Kind() == reflect.Ptr {
+		if a.IsNil() {
+			return !b.IsValid() || !(b.Kind() == reflect.Interface || b.Kind() == reflect.Ptr) || !b.IsNil()
+		}
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface || b.Kind() == reflect.Ptr {
+		if b.IsNil() {
+			return false
+		}
+		b = b.Elem()
+	}
+	af, aNum := number(a)
+	bf, bNum := number(b)
+	switch {
+	case aNum && bNum:
+		return af < bf
+	case aNum != bNum:
+		return aNum
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return naturalLess(a.String(), b.String())
+	case a.Kind() == reflect.Bool && b.Kind() == reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// number returns a numeric value as a float64
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// naturalLess compares strings, treating runs of digits as numbers so that
+// "item2" sorts before "item10"
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitRun(a), digitRun(b)
+		if da > 0 && db > 0 {
+			na, nb := strings.TrimLeft(a[:da], "0"), strings.TrimLeft(b[:db], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return ra < rb
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return len(a) < len(b)
+}
+
+// digitRun counts the digits that start s
+func digitRun(s string) int {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// encodeStruct builds a mapping from a struct's fields in order, then any
+// ",inline" map's keys
+func encodeStruct(in reflect.Value) (*Node, error) {
+	info, err := getStructInfo(in.Type())
+	if err != nil {
+		return nil, err
+	}
+	node := &Node{Kind: MappingNode, Tag: mapTag}
+	for _, f := range info.list {
+		field, ok := fieldForRead(in, f.index)
+		if !ok || (f.omitEmpty && isZero(field)) {
+			continue
+		}
+		value, err := encodeValue(field)
+		if err != nil {
+			return nil, err
+		}
+		if f.flow && (value.Kind == MappingNode || value.Kind == SequenceNode) {
+			value.Style |= FlowStyle
+		}
+		node.Content = append(node.Content, &Node{Kind: ScalarNode, Tag: strTag, Value: f.key}, value)
+	}
+	if info.inlineMap >= 0 {
+		extra, err := encodeMap(in.Field(info.inlineMap))
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(extra.Content); i += 2 {
+			if _, clash := info.fields[extra.Content[i].Value]; clash {
+				return nil, fmt.Errorf("yaml: cannot have key %q in inlined map: conflicts with struct field", extra.Content[i].Value)
+			}
+		}
+		node.Content = append(node.Content, extra.Content...)
+	}
+	return node, nil
+}
+
+// isZero decides whether omitempty leaves a value out
+func isZero(v reflect.Value) bool {
+	if v.CanInterface() {
+		if z, ok := v.Interface().(IsZeroer); ok {
+			if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+				return true
+			}
+			return z.IsZero()
+		}
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Array:
+		return v.IsZero()
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && !isZero(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return v.IsZero()
+}
+
+// Emitting
+
+// emitter writes nodes as block-style YAML
+type emitter struct {
+	buf    bytes.Buffer
+	indent int
+}
+
+// document writes a whole document
+func (em *emitter) document(n *Node) error {
+	if n.Kind == DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	switch {
+	case isBlockCollection(n):
+		if props := properties(n); props != "" {
+			em.buf.WriteString(strings.TrimSpace(props) + "\n")
+		}
+		if n.Kind == MappingNode {
+			return em.mapping(n, 0, false)
+		}
+		return em.sequence(n, 0, false)
+	default:
+		return em.value(n, -em.indent, "")
+	}
+}
+
+// isBlockCollection reports whether n is written over several lines
+func isBlockCollection(n *Node) bool {
+	return (n.Kind == MappingNode || n.Kind == SequenceNode) && n.Style&FlowStyle == 0 && len(n.Content) > 0
+}
+
+// properties renders a node's anchor and explicit tag, each followed by
+// a space
+func properties(n *Node) string {
+	var props string
+	if n.Anchor != "" {
+		props = "&" + n.Anchor + " "
+	}
+	if n.Tag != "" && (n.Style&TaggedStyle != 0 || !strings.HasPrefix(n.Tag, "!!")) && n.Tag != "!" {
+		props += n.Tag + " "
+	}
+	return props
+}
+
+// mapping writes a block mapping whose keys line up at indent; the first
+// key continues the current line if inline is set
+func (em *emitter) mapping(n *Node, indent int, inline bool) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if i > 0 || !inline {
+			em.buf.WriteString(strings.Repeat(" ", indent))
+		}
+		key, err := em.key(n.Content[i])
+		if err != nil {
+			return err
+		}
+		em.buf.WriteString(key + ":")
+		if err := em.value(n.Content[i+1], indent, " "); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sequence writes a block sequence whose dashes line up at indent; the
+// first entry continues the current line if inline is set
+func (em *emitter) sequence(n *Node, indent int, inline bool) error {
+	for i, item := range n.Content {
+		if i > 0 || !inline {
+			em.buf.WriteString(strings.Repeat(" ", indent))
+		}
+		em.buf.WriteString("-")
+		if err := em.value(item, indent, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value writes the rest of a line after "key:" (sep is " ") or "-" (sep
+// is ""), with any block content on the following lines
+func (em *emitter) value(n *Node, indent int, sep string) error {
+	props := properties(n)
+	item := sep == ""
+	switch {
+	case isBlockCollection(n) && item && props == "":
+		em.buf.WriteString(" ")
+		if n.Kind == MappingNode {
+			return em.mapping(n, indent+2, true)
+		}
+		return em.sequence(n, indent+2, true)
+	case isBlockCollection(n):
+		em.buf.WriteString(strings.TrimRight(" "+props, " ") + "\n")
+		next := indent + em.indent
+		if item {
+			next = indent + 2
+		}
+		if n.Kind == MappingNode {
+			return em.mapping(n, next, false)
+		}
+		return em.sequence(n, next, false)
+	}
+
+	if n.Kind == ScalarNode && literalStyle(n) {
+		em.buf.WriteString(" " + props + literalHeader(n.Value) + "\n")
+		content := indent + em.indent
+		body := strings.TrimRight(n.Value, "\n")
+		for _, line := range strings.Split(body, "\n") {
+			if line != "" {
+				em.buf.WriteString(strings.Repeat(" ", content) + line)
+			}
+			em.buf.WriteString("\n")
+		}
+		if trailing := len(n.Value) - len(body); trailing > 1 {
+			em.buf.WriteString(strings.Repeat("\n", trailing-1))
+		}
+		return nil
+	}
+
+	text, err := em.flow(n)
+	if err != nil {
+		return err
+	}
+	if indent >= 0 || item {
+		text = " " + text
+	}
+	em.buf.WriteString(text + "\n")
+	return nil
+}
+
+// key renders a mapping key on one line
+func (em *emitter) key(n *Node) (string, error) {
+	if n.Kind == ScalarNode && literalStyle(n) {
+		return properties(n) + doubleQuote(n.Value), nil
+	}
+	return em.flow(n)
+}
+
+// flow renders a node on one line, using flow style for collections
+func (em *emitter) flow(n *Node) (string, error) {
+	props := properties(n)
+	switch n.Kind {
+	case AliasNode:
+		return "*" + n.Value, nil
+	case ScalarNode:
+		return props + scalarText(n), nil
+	case SequenceNode, MappingNode:
+		var parts []string
+		step := 1
+		if n.Kind == MappingNode {
+			step = 2
+		}
+		for i := 0; i+step-1 < len(n.Content); i += step {
+			text, err := em.flow(n.Content[i])
+			if err != nil {
+				return "", err
+			}
+			if step == 2 {
+				value, err := em.flow(n.Content[i+1])
+				if err != nil {
+					return "", err
+				}
+				text += ": " + value
+			}
+			parts = append(parts, text)
+		}
+		if n.Kind == MappingNode {
+			return props + "{" + strings.Join(parts, ", ") + "}", nil
+		}
+		return props + "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("yaml: cannot encode node of kind %d", n.Kind)
+}
+
+// literalStyle reports whether a scalar is written as a | block
+func literalStyle(n *Node) bool {
+	if n.Style&(DoubleQuotedStyle|SingleQuotedStyle) != 0 || n.ShortTag() != strTag {
+		return false
+	}
+	if !strings.Contains(n.Value, "\n") && n.Style&(LiteralStyle|FoldedStyle) == 0 {
+		return false
+	}
+	first := strings.TrimLeft(n.Value, "\n")
+	if first == "" || first[0] == ' ' || first[0] == '\t' {
+		return false
+	}
+	for _, r := range n.Value {
+		if r != '\n' && r != '\t' && !isPrintable(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// literalHeader picks the chomping indicator that keeps value's trailing
+// newlines
+func literalHeader(value string) string {
+	switch trailing := len(value) - len(strings.TrimRight(value, "\n")); {
+	case trailing == 0:
+		return "|-"
+	case trailing == 1:
+		return "|"
+	default:
+		return "|+"
+	}
+}
+
+// scalarText renders a scalar inline, quoting strings that would
+// otherwise read back as something else
+func scalarText(n *Node) string {
+	value := n.Value
+	switch {
+	case n.Style&DoubleQuotedStyle != 0:
+		return doubleQuote(value)
+	case n.Style&SingleQuotedStyle != 0:
+		if strings.ContainsAny(value, "\n") {
+			return doubleQuote(value)
+		}
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case n.Tag == binaryTag:
+		return "!!binary " + value
+	case n.ShortTag() == strTag && needsQuotes(value):
+		return doubleQuote(value)
+	}
+	return value
+}
+
+// needsQuotes reports whether a string cannot be written as a plain scalar
+func needsQuotes(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return true
+	}
+	if tag, _ := resolvePlain(s); tag != strTag {
+		return true
+	}
+	if _, old := isOldBool(s); old {
+		return true
+	}
+	if strings.ContainsRune(",[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+	if strings.ContainsRune("-?:", rune(s[0])) && (len(s) == 1 || s[1] == ' ' || s[1] == '\t') {
+		return true
+	}
+	if strings.HasPrefix(s, "---") || strings.HasPrefix(s, "...") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r == '\t' || !isPrintable(r) {
+			return true
+		}
+	}
+	return !utf8.ValidString(s)
+}
+
+// isPrintable reports whether r may appear unescaped in a YAML scalar
+func isPrintable(r rune) bool {
+	switch {
+	case r == utf8.RuneError, r == 0x85, r == 0x2028, r == 0x2029, r == 0xFEFF:
+		return false
+	case r < 0x20, r == 0x7F, r >= 0x80 && r < 0xA0:
+		return false
+	}
+	return true
+}
+
+// doubleQuote writes s as a double-quoted scalar
+func doubleQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, "\\x%02X", s[i])
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString("\\n")
+		case r == '\t':
+			b.WriteString("\\t")
+		case r == '\r':
+			b.WriteString("\\r")
+		case r == 0:
+			b.WriteString("\\0")
+		case r == 0x85:
+			b.WriteString("\\N")
+		case r == 0x2028:
+			b.WriteString("\\L")
+		case r == 0x2029:
+			b.WriteString("\\P")
+		case r < 0x20 || r == 0x7F || (r >= 0x80 && r < 0xA0):
+			fmt.Fprintf(&b, "\\x%02X", r)
+		case r == 0xFEFF:
+			b.WriteString("\\uFEFF")
+		default:
+			b.WriteRune(r)
+		}
+		i += size
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// API
+
+// Marshal encodes v as a YAML document. Structs are written in field
+// order, keyed by their `yaml` tags or lowercased field names, and maps in
+// natural key order.
+func Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes the first document in data into out, which must be a
+// non-nil pointer. Values that do not fit their targets are reported
+// together in a *TypeError after the rest has been decoded.
+func Unmarshal(data []byte, out interface{}) error {
+	return unmarshal(data, out, false)
+}
+
+// unmarshal decodes the first document, optionally rejecting unknown
+// struct fields
+func unmarshal(data []byte, out interface{}, knownFields bool) error {
+	docs, err := parse(data)
+	if err != nil {
+		return err
+	}
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("yaml: Unmarshal requires a non-nil pointer, got %T", out)
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	d := newDecoder()
+	d.knownFields = knownFields
+	return d.finish(d.unmarshal(docs[0], target.Elem()))
+}
+
+// Encoder writes YAML documents to a stream, separated by "---"
+type Encoder struct {
+	w      io.Writer
+	indent int
+	count  int
+}
+
+// NewEncoder returns an encoder writing to w with four-space indentation
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, indent: 4}
+}
+
+// SetIndent sets the number of spaces per indentation level
+func (e *Encoder) SetIndent(spaces int) {
+	if spaces < 1 {
+		panic("yaml: cannot indent to a negative number of spaces")
+	}
+	e.indent = spaces
+}
+
+// Encode writes v as the next document
+func (e *Encoder) Encode(v interface{}) error {
+	node, err := encodeValue(reflect.ValueOf(v))
+	if err != nil {
+		return err
+	}
+	em := &emitter{indent: e.indent}
+	if e.count > 0 {
+		em.buf.WriteString("---\n")
+	}
+	if err := em.document(node); err != nil {
+		return err
+	}
+	e.count++
+	_, err = e.w.Write(em.buf.Bytes())
+	return err
+}
+
+// Close finishes the stream; documents are written as they are encoded
+func (e *Encoder) Close() error {
+	return nil
+}
+
+// Decoder reads YAML documents from a stream
+type Decoder struct {
+	r           io.Reader
+	docs        []*Node
+	read        bool
+	err         error
+	knownFields bool
+}
+
+// NewDecoder returns a decoder reading from r. The stream is read to its
+// end before the first document is decoded.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// KnownFields makes decoding fail on keys that match no struct field
+func (dec *Decoder) KnownFields(enable bool) {
+	dec.knownFields = enable
+}
+
+// Decode decodes the next document into v, returning io.EOF when there
+// are no more
+func (dec *Decoder) Decode(v interface{}) error {
+	if !dec.read {
+		dec.read = true
+		data, err := io.ReadAll(dec.r)
+		if err != nil {
+			dec.err = err
+		} else {
+			dec.docs, dec.err = parse(data)
+		}
+	}
+	if dec.err != nil {
+		return dec.err
+	}
+	if len(dec.docs) == 0 {
+		return io.EOF
+	}
+	doc := dec.docs[0]
+	dec.docs = dec.docs[1:]
+
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("yaml: Decode requires a non-nil pointer, got %T", v)
+	}
+	d := newDecoder()
+	d.knownFields = dec.knownFields
+	return d.finish(d.unmarshal(doc, target.Elem()))
+}
+
+// Codec offers Marshal and Unmarshal as methods, for emulators that take
+// a YAML implementation through an interface: the Gin emulator's
+// YAMLCodec, the Testify emulator's YAMLUnmarshaler and, through Encode
+// and Decode, the Viper emulator's Codec
+type Codec struct{}
+
+// Marshal is Marshal
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+// Unmarshal is Unmarshal
+func (Codec) Unmarshal(data []byte, out interface{}) error {
+	return Unmarshal(data, out)
+}
+
+// Encode encodes a configuration map
+func (Codec) Encode(v map[string]interface{}) ([]byte, error) {
+	return Marshal(v)
+}
+
+// Decode decodes a configuration document into v
+func (Codec) Decode(data []byte, v map[string]interface{}) error {
+	return Unmarshal(data, &v)
+}